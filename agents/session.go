@@ -1,4 +1,6 @@
-package main
+// Package agents is a to-do list agent with memory that calls tools from
+// natural language commands. It is run by `wikillm todo chat`.
+package agents
 
 import (
 	"bufio"
@@ -14,36 +16,10 @@ import (
 	"github.com/kbutz/wikillm/agents/tools"
 )
 
-// Parse command line flags
-func parseFlags() Config {
-	modelName := flag.String("model", "default", "Name of the LLM model to use")
-	modelProvider := flag.String("provider", "lmstudio", "Model provider to use (lmstudio or ollama)")
-	port := flag.Int("port", 0, "HTTP server port (0 to disable)")
-	todoFilePath := flag.String("todo-file", "todo.txt", "Path to the to-do list file")
-	memoryFilePath := flag.String("memory-file", "memory.txt", "Path to the memory file")
-	memoryDir := flag.String("memory-dir", "memory", "Directory for enhanced memory storage")
-	useEnhancedMemory := flag.Bool("enhanced-memory", true, "Use enhanced memory system")
-	debug := flag.Bool("debug", false, "Enable debug mode")
-
-	flag.Parse()
-
-	return Config{
-		ModelName:         *modelName,
-		ModelProvider:     *modelProvider,
-		Port:              *port,
-		TodoFilePath:      *todoFilePath,
-		MemoryFilePath:    *memoryFilePath,
-		MemoryDir:         *memoryDir,
-		UseEnhancedMemory: *useEnhancedMemory,
-		Debug:             *debug,
-	}
-}
-
 // Config Configuration options for the application
 type Config struct {
 	ModelName         string // Name of the LLM model to use
 	ModelProvider     string // Provider to use (lmstudio or ollama)
-	Port              int    // HTTP server port
 	TodoFilePath      string // Path to the to-do list file
 	MemoryFilePath    string // Path to the memory file
 	MemoryDir         string // Directory for enhanced memory storage
@@ -51,54 +27,60 @@ type Config struct {
 	Debug             bool   // Enable debug mode
 }
 
-func main() {
-	// Parse command line arguments
-	config := parseFlags()
-
-	model, err := models.New(config.ModelName, config.ModelProvider, config.Debug)
-	if err != nil {
-		log.Fatalf("Failed to initialize model: %v", err)
-		return
-	}
+// Register adds the model, to-do list and memory flags to fs
+func (c *Config) Register(fs *flag.FlagSet) {
+	fs.StringVar(&c.ModelName, "model", "default", "Name of the LLM model to use")
+	fs.StringVar(&c.ModelProvider, "provider", "lmstudio", "Model provider to use (lmstudio or ollama)")
+	fs.StringVar(&c.TodoFilePath, "todo-file", "todo.txt", "Path to the to-do list file")
+	fs.StringVar(&c.MemoryFilePath, "memory-file", "memory.txt", "Path to the memory file")
+	fs.StringVar(&c.MemoryDir, "memory-dir", "memory", "Directory for enhanced memory storage")
+	fs.BoolVar(&c.UseEnhancedMemory, "enhanced-memory", true, "Use enhanced memory system")
+	fs.BoolVar(&c.Debug, "debug-model", false, "Log the model's requests and responses")
+}
 
-	// Initialize the tools
+// Chat runs the agent on questions read from stdin until the user types exit
+func Chat(config Config) error {
 	todoTool := tools.NewTodoListTool(config.TodoFilePath)
-	
+
 	// Use enhanced memory tool if enabled
 	if config.UseEnhancedMemory {
 		enhancedMemoryTool := tools.NewEnhancedMemoryTool(config.MemoryDir)
-		
+
 		// Create memory-enabled model
 		memoryModel, err := models.NewMemoryEnabledModel(config.ModelName, config.ModelProvider, config.Debug)
 		if err != nil {
-			log.Fatalf("Failed to initialize memory-enabled model: %v", err)
-			return
+			return fmt.Errorf("failed to initialize memory-enabled model: %w", err)
 		}
-		
+
 		// Create memory-enabled agent
 		memAgent := NewMemoryEnabledAgent(
 			memoryModel,
 			[]models.Tool{todoTool, enhancedMemoryTool},
 			enhancedMemoryTool,
 		)
-		
+
 		// Initialize context
 		if err := memAgent.InitializeContext(context.Background()); err != nil {
 			log.Printf("Warning: Failed to initialize context: %v", err)
 		}
-		
+
 		// Run enhanced agent
 		RunEnhancedAgent(memAgent)
-	} else {
-		// Use basic file memory tool
-		memoryTool := tools.NewFileMemoryTool(config.MemoryFilePath)
-		
-		// Create the agent with all tools
-		agent := NewAgent(model, []models.Tool{todoTool, memoryTool})
-		
-		// Start the interactive session
-		agent.Run()
+		return nil
 	}
+
+	model, err := models.New(config.ModelName, config.ModelProvider, config.Debug)
+	if err != nil {
+		return fmt.Errorf("failed to initialize model: %w", err)
+	}
+
+	// Use basic file memory tool
+	memoryTool := tools.NewFileMemoryTool(config.MemoryFilePath)
+
+	// Create the agent with all tools and start the interactive session
+	agent := NewAgent(model, []models.Tool{todoTool, memoryTool})
+	agent.Run()
+	return nil
 }
 
 // RunEnhancedAgent runs the interactive session with the memory-enabled agent
@@ -0,0 +1,195 @@
+// Package inmemory answers questions about Wikipedia with a local LLM and a
+// Bleve full-text index of an offline dump. It is run by `wikillm naivelocal`.
+package inmemory
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config Configuration options for the application
+type Config struct {
+	ModelName      string // Name of the LLM model to use
+	ModelProvider  string // Provider to use (lmstudio or ollama)
+	IndexDirectory string // Directory to store the search index
+	SearchLimit    int    // Maximum number of search results to return
+}
+
+// Register adds the model and index flags to fs
+func (c *Config) Register(fs *flag.FlagSet) {
+	fs.StringVar(&c.ModelName, "model", "default", "Name of the LLM model to use")
+	fs.StringVar(&c.ModelProvider, "provider", "lmstudio", "Model provider to use (lmstudio or ollama)")
+	fs.StringVar(&c.IndexDirectory, "index", "./wikipedia_index", "Directory to store the search index")
+	fs.IntVar(&c.SearchLimit, "limit", 5, "Maximum number of search results to return")
+}
+
+// Index adds the pages of a Wikipedia XML dump to the search index
+func Index(config Config, dumpPath string) error {
+	wikiIndex, err := NewWikipediaIndex(config.IndexDirectory)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Wikipedia index: %w", err)
+	}
+	defer wikiIndex.Close()
+
+	log.Println("Creating new index from Wikipedia dump...")
+	if err := wikiIndex.IndexWikipediaDump(dumpPath); err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+	log.Println("Index created successfully.")
+	return nil
+}
+
+// Answer is the reply to a question and the titles of the pages it drew on
+type Answer struct {
+	Answer  string   `json:"answer"`
+	Sources []string `json:"sources"`
+}
+
+// Ask answers a single question
+func Ask(ctx context.Context, config Config, question string) (*Answer, error) {
+	model, err := newModel(config)
+	if err != nil {
+		return nil, err
+	}
+	wikiIndex, err := NewWikipediaIndex(config.IndexDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Wikipedia index: %w", err)
+	}
+	defer wikiIndex.Close()
+	return processQuery(ctx, model, wikiIndex, question, config.SearchLimit)
+}
+
+// Chat answers questions read from stdin until the user types exit
+func Chat(config Config) error {
+	model, err := newModel(config)
+	if err != nil {
+		return err
+	}
+
+	// Initialize the Wikipedia index
+	wikiIndex, err := NewWikipediaIndex(config.IndexDirectory)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Wikipedia index: %w", err)
+	}
+	defer func() {
+		if err := wikiIndex.Close(); err != nil {
+			log.Printf("Error closing Wikipedia index: %v", err)
+		}
+	}()
+
+	startInteractiveSession(model, wikiIndex, config.SearchLimit)
+	return nil
+}
+
+// newModel initializes the model based on the selected provider
+func newModel(config Config) (LLMModel, error) {
+	switch strings.ToLower(config.ModelProvider) {
+	case "lmstudio":
+		model, err := NewLMStudioModel(config.ModelName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize LM Studio model: %w", err)
+		}
+		return model, nil
+	case "ollama":
+		model, err := NewOllamaModel(config.ModelName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Ollama model: %w", err)
+		}
+		return model, nil
+	default:
+		log.Printf("Unknown model provider: %s. Defaulting to LM Studio.", config.ModelProvider)
+		model, err := NewLMStudioModel(config.ModelName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize LM Studio model: %w", err)
+		}
+		return model, nil
+	}
+}
+
+// Start an interactive session with the user
+func startInteractiveSession(model LLMModel, wikiIndex *WikipediaIndex, searchLimit int) {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Println("WikiLLM Interactive Session")
+	fmt.Printf("Using model: %s\n", model.Name())
+	fmt.Println("Type 'exit' to quit")
+
+	for {
+		fmt.Print("> ")
+		if !scanner.Scan() {
+			break
+		}
+
+		query := scanner.Text()
+		if strings.ToLower(query) == "exit" {
+			break
+		}
+
+		// Process the query
+		fmt.Println("Searching Wikipedia and generating response...")
+		startTime := time.Now()
+
+		response, err := processQuery(context.Background(), model, wikiIndex, query, searchLimit)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+
+		elapsed := time.Since(startTime)
+		fmt.Printf("\nResponse (generated in %.2f seconds):\n%s\n", elapsed.Seconds(), response.Answer)
+	}
+}
+
+// Process a user query
+func processQuery(ctx context.Context, model LLMModel, wikiIndex *WikipediaIndex, query string, limit int) (*Answer, error) {
+	// Search Wikipedia for relevant content
+	results, err := wikiIndex.Search(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search error: %w", err)
+	}
+
+	answer := &Answer{Sources: []string{}}
+	if len(results) == 0 {
+		// If no results found, ask the model directly
+		answer.Answer, err = model.Query(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return answer, nil
+	}
+
+	// Format the search results for the model
+	var promptBuilder strings.Builder
+
+	promptBuilder.WriteString("I want you to answer the following question based on the Wikipedia information provided below.\n\n")
+	promptBuilder.WriteString("Question: " + query + "\n\n")
+	promptBuilder.WriteString("Wikipedia Information:\n")
+
+	for i, result := range results {
+		title, _ := result["title"].(string)
+		content, _ := result["content"].(string)
+		answer.Sources = append(answer.Sources, title)
+
+		// Truncate content if it's too long
+		if len(content) > 1000 {
+			content = content[:1000] + "..."
+		}
+
+		promptBuilder.WriteString(fmt.Sprintf("%d. %s\n%s\n\n", i+1, title, content))
+	}
+
+	promptBuilder.WriteString("Please provide a comprehensive answer to the question based on the information above.")
+
+	// Send the prompt to the model
+	answer.Answer, err = model.Query(ctx, promptBuilder.String())
+	if err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
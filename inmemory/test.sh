@@ -28,7 +28,7 @@ if ! curl -s http://localhost:11434/api/tags &> /dev/null; then
 fi
 
 echo "Building WikiLLM..."
-go build -o wikillm
+(cd ../wikillm && go build -o ../inmemory/wikillm)
 
 # Check if we have a test Wikipedia dump
 TEST_DUMP="test_wikipedia.xml"
@@ -72,4 +72,5 @@ echo "Press Ctrl+C to exit the test."
 echo ""
 
 # Run WikiLLM with the test dump
-./wikillm -wikipedia "$TEST_DUMP" -model llama2
\ No newline at end of file
+./wikillm naivelocal index "$TEST_DUMP"
+./wikillm naivelocal chat --provider ollama --model llama2
\ No newline at end of file
@@ -1,4 +1,4 @@
-package main
+package inmemory
 
 import (
 	"encoding/xml"
@@ -0,0 +1,197 @@
+// Package access decides which tools and side-effecting actions agents may
+// use, and on behalf of which users. A Policy holds an allow-list for each
+// agent and for each user role; WrapTool enforces it where tools run, agents
+// check it before actions, and every denial is audited.
+package access
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+var logger = logging.For("access")
+
+// Built-in user roles
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleGuest  = "guest"
+)
+
+// Any in an allow-list allows every tool or action; as a key of
+// Policy.Agents it covers the agents not listed
+const Any = "*"
+
+// ErrDenied is returned for tool calls and actions a policy does not allow
+var ErrDenied = errors.New("access denied")
+
+// Rules are the tools and actions an agent or role may use
+type Rules struct {
+	Tools   []string `json:"tools"`
+	Actions []string `json:"actions"`
+}
+
+// allows reports whether list allows name
+func allows(list []string, name string) bool {
+	return slices.Contains(list, Any) || slices.Contains(list, name)
+}
+
+// Policy holds the allow-lists. A call or action has to be allowed both for
+// the agent making it and for the role of the user it is made for. A nil
+// Policy allows everything.
+type Policy struct {
+	// Agents maps agent IDs to their allow-lists; agents not listed follow
+	// the Any entry, and without one are unrestricted
+	Agents map[multiagent.AgentID]Rules `json:"agents,omitempty"`
+	// Roles maps user roles to their allow-lists; roles not listed are
+	// unrestricted
+	Roles map[string]Rules `json:"roles,omitempty"`
+	// Users gives users their roles
+	Users map[string]string `json:"users,omitempty"`
+	// DefaultRole is the role of users not in Users (default RoleMember)
+	DefaultRole string `json:"default_role,omitempty"`
+}
+
+// DefaultPolicy lets only the agent responsible for each action take it:
+// the communication manager sends email and deletes message templates, the
+// task manager deletes tasks, the scheduler cancels events, the project
+// manager deletes project templates and milestones, and the research
+// assistant stops research monitors. Every agent may call every tool.
+// Admins and members may do everything; guests may only search, and take no
+// actions.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Agents: map[multiagent.AgentID]Rules{
+			Any:                           {Tools: []string{Any}, Actions: []string{}},
+			"communication_manager_agent": {Tools: []string{Any}, Actions: []string{"send_email", "delete_message_template"}},
+			"task_manager_agent":          {Tools: []string{Any}, Actions: []string{"delete_task"}},
+			"scheduler_agent":             {Tools: []string{Any}, Actions: []string{"cancel_event"}},
+			"project_manager_agent":       {Tools: []string{Any}, Actions: []string{"delete_project_template", "delete_milestone"}},
+			"research_assistant_agent":    {Tools: []string{Any}, Actions: []string{"stop_research_monitor"}},
+		},
+		Roles: map[string]Rules{
+			RoleAdmin:  {Tools: []string{Any}, Actions: []string{Any}},
+			RoleMember: {Tools: []string{Any}, Actions: []string{Any}},
+			RoleGuest:  {Tools: []string{"web_search", "wikipedia", "notes"}, Actions: []string{}},
+		},
+		DefaultRole: RoleMember,
+	}
+}
+
+// LoadPolicy reads a policy from a JSON file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access policy: %w", err)
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse access policy %s: %w", path, err)
+	}
+	return &policy, nil
+}
+
+// Role returns userID's role
+func (p *Policy) Role(userID string) string {
+	if p == nil {
+		return ""
+	}
+	if role, ok := p.Users[userID]; ok {
+		return role
+	}
+	if p.DefaultRole != "" {
+		return p.DefaultRole
+	}
+	return RoleMember
+}
+
+// CheckTool returns an error wrapping ErrDenied unless agentID may call tool
+// for userID
+func (p *Policy) CheckTool(agentID multiagent.AgentID, userID, tool string) error {
+	return p.check(agentID, userID, "call the "+tool+" tool", func(rules Rules) bool {
+		return allows(rules.Tools, tool)
+	})
+}
+
+// CheckAction returns an error wrapping ErrDenied unless agentID may take
+// action for userID
+func (p *Policy) CheckAction(agentID multiagent.AgentID, userID, action string) error {
+	return p.check(agentID, userID, action, func(rules Rules) bool {
+		return allows(rules.Actions, action)
+	})
+}
+
+func (p *Policy) check(agentID multiagent.AgentID, userID, what string, allowed func(Rules) bool) error {
+	if p == nil {
+		return nil
+	}
+	rules, ok := p.Agents[agentID]
+	if !ok {
+		rules, ok = p.Agents[Any]
+	}
+	if ok && !allowed(rules) {
+		return fmt.Errorf("%w: %s may not %s", ErrDenied, agentID, what)
+	}
+	// Work nobody asked for, such as the service's own, has no user
+	if userID == "" {
+		return nil
+	}
+	role := p.Role(userID)
+	if rules, ok := p.Roles[role]; ok && !allowed(rules) {
+		return fmt.Errorf("%w: users with the %s role may not %s", ErrDenied, role, what)
+	}
+	return nil
+}
+
+// guardedTool is a tool as one agent sees it under a policy
+type guardedTool struct {
+	multiagent.Tool
+	agentID  multiagent.AgentID
+	policy   *Policy
+	recorder audit.Recorder
+}
+
+// WrapTool returns tool as agentID may use it: calls the policy does not
+// allow, for the agent or for the user ctx acts for, fail with ErrDenied
+// and are recorded with recorder. A nil policy leaves tool as it is.
+func WrapTool(tool multiagent.Tool, agentID multiagent.AgentID, policy *Policy, recorder audit.Recorder) multiagent.Tool {
+	if policy == nil {
+		return tool
+	}
+	return guardedTool{Tool: tool, agentID: agentID, policy: policy, recorder: recorder}
+}
+
+// Execute runs the wrapped tool if the policy allows the call
+func (t guardedTool) Execute(ctx context.Context, args string) (string, error) {
+	userID := multiagent.UserIDFromContext(ctx)
+	if err := t.policy.CheckTool(t.agentID, userID, t.Name()); err != nil {
+		logger.WarnContext(ctx, "Tool call denied", logging.KeyAgentID, t.agentID, "tool", t.Name(), "error", err)
+		if t.recorder != nil {
+			event := audit.Event{
+				Type:           audit.AccessDenied,
+				Actor:          t.agentID,
+				Subject:        t.Name(),
+				ConversationID: logging.Field(ctx, logging.KeyConversationID),
+				Payload: map[string]interface{}{
+					"tool":    t.Name(),
+					"user_id": userID,
+					"role":    t.policy.Role(userID),
+					"reason":  err.Error(),
+				},
+			}
+			if recordErr := t.recorder.Record(ctx, event); recordErr != nil {
+				logger.WarnContext(ctx, "Failed to audit denied tool call", "tool", t.Name(), "error", recordErr)
+			}
+		}
+		return "", err
+	}
+	return t.Tool.Execute(ctx, args)
+}
@@ -0,0 +1,148 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.Users = map[string]string{"gus": RoleGuest, "root": RoleAdmin}
+
+	tests := []struct {
+		agent   multiagent.AgentID
+		user    string
+		action  string
+		allowed bool
+	}{
+		{"task_manager_agent", "alice", "delete_task", true},
+		{"task_manager_agent", "root", "delete_task", true},
+		{"task_manager_agent", "gus", "delete_task", false},
+		{"task_manager_agent", "alice", "send_email", false},
+		{"communication_manager_agent", "alice", "send_email", true},
+		{"research_assistant_agent", "alice", "send_email", false},
+		{"scheduler_agent", "", "cancel_event", true},
+		{"scheduler_agent", "gus", "cancel_event", false},
+		{"project_manager_agent", "alice", "delete_project_template", true},
+		{"project_manager_agent", "gus", "delete_milestone", false},
+		{"communication_manager_agent", "gus", "delete_message_template", false},
+		{"research_assistant_agent", "alice", "stop_research_monitor", true},
+		{"task_manager_agent", "alice", "stop_research_monitor", false},
+	}
+	for _, test := range tests {
+		err := policy.CheckAction(test.agent, test.user, test.action)
+		if allowed := err == nil; allowed != test.allowed {
+			t.Errorf("CheckAction(%s, %q, %s) = %v, want allowed %v", test.agent, test.user, test.action, err, test.allowed)
+		}
+		if err != nil && !errors.Is(err, ErrDenied) {
+			t.Errorf("CheckAction(%s, %q, %s) = %v, want ErrDenied", test.agent, test.user, test.action, err)
+		}
+	}
+
+	if err := policy.CheckTool("research_assistant_agent", "gus", "wikipedia"); err != nil {
+		t.Errorf("guest wikipedia search denied: %v", err)
+	}
+	err := policy.CheckTool("research_assistant_agent", "gus", "shell")
+	if err == nil || err.Error() != "access denied: users with the guest role may not call the shell tool" {
+		t.Errorf("guest shell call = %v", err)
+	}
+	if role := policy.Role("bob"); role != RoleMember {
+		t.Errorf("Role(bob) = %q, want %q", role, RoleMember)
+	}
+}
+
+func TestNilPolicyAllowsEverything(t *testing.T) {
+	var policy *Policy
+	if err := policy.CheckAction("task_manager_agent", "gus", "delete_task"); err != nil {
+		t.Errorf("CheckAction: %v", err)
+	}
+	if err := policy.CheckTool("task_manager_agent", "gus", "shell"); err != nil {
+		t.Errorf("CheckTool: %v", err)
+	}
+	tool := stubTool{name: "shell"}
+	if wrapped := WrapTool(tool, "task_manager_agent", nil, nil); wrapped != multiagent.Tool(tool) {
+		t.Errorf("WrapTool wrapped the tool without a policy")
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.json")
+	data := `{
+		"agents": {"*": {"tools": ["wikipedia"], "actions": []}},
+		"roles": {"guest": {"tools": [], "actions": []}},
+		"users": {"gus": "guest"},
+		"default_role": "admin"
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadPolicy: %v", err)
+	}
+	if role := policy.Role("alice"); role != RoleAdmin {
+		t.Errorf("Role(alice) = %q, want %q", role, RoleAdmin)
+	}
+	if err := policy.CheckTool("scheduler_agent", "alice", "wikipedia"); err != nil {
+		t.Errorf("CheckTool(wikipedia): %v", err)
+	}
+	if err := policy.CheckTool("scheduler_agent", "alice", "web_search"); err == nil {
+		t.Error("CheckTool(web_search) allowed, want denied for every agent")
+	}
+	if err := policy.CheckTool("scheduler_agent", "gus", "wikipedia"); err == nil {
+		t.Error("CheckTool(wikipedia) allowed for a guest")
+	}
+
+	if _, err := LoadPolicy(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadPolicy of a missing file succeeded")
+	}
+}
+
+func TestWrapToolAuditsDenials(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.Agents["scheduler_agent"] = Rules{Tools: []string{"wikipedia"}}
+	recorder := &eventRecorder{}
+	tool := WrapTool(stubTool{name: "web_search"}, "scheduler_agent", policy, recorder)
+
+	ctx := multiagent.WithUserID(context.Background(), "alice")
+	if _, err := tool.Execute(ctx, "{}"); !errors.Is(err, ErrDenied) {
+		t.Fatalf("Execute = %v, want ErrDenied", err)
+	}
+	if len(recorder.events) != 1 {
+		t.Fatalf("recorded %d events, want 1", len(recorder.events))
+	}
+	event := recorder.events[0]
+	if event.Type != audit.AccessDenied || event.Actor != "scheduler_agent" || event.Subject != "web_search" || event.Payload["user_id"] != "alice" {
+		t.Errorf("recorded %+v", event)
+	}
+
+	allowed := WrapTool(stubTool{name: "wikipedia"}, "scheduler_agent", policy, recorder)
+	if output, err := allowed.Execute(ctx, "{}"); err != nil || output != "wikipedia ran" {
+		t.Errorf("Execute = %q, %v", output, err)
+	}
+	if len(recorder.events) != 1 {
+		t.Errorf("an allowed call was audited as denied")
+	}
+}
+
+type stubTool struct{ name string }
+
+func (t stubTool) Name() string                       { return t.name }
+func (t stubTool) Description() string                { return t.name }
+func (t stubTool) Parameters() map[string]interface{} { return nil }
+func (t stubTool) Execute(ctx context.Context, args string) (string, error) {
+	return t.name + " ran", nil
+}
+
+type eventRecorder struct{ events []audit.Event }
+
+func (r *eventRecorder) Record(ctx context.Context, event audit.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
@@ -0,0 +1,58 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+)
+
+// Destructive actions the access policy gates without asking the user's
+// approval first
+const (
+	ActionDeleteProjectTemplate = "delete_project_template"
+	ActionDeleteMessageTemplate = "delete_message_template"
+	ActionDeleteMilestone       = "delete_milestone"
+	ActionStopResearchMonitor   = "stop_research_monitor"
+)
+
+// checkAction returns an error wrapping access.ErrDenied when the access
+// policy does not let the agent take action for the user ctx acts for; the
+// denial is audited against subject
+func (a *BaseAgent) checkAction(ctx context.Context, msg *multiagent.Message, action, subject string) error {
+	userID := multiagent.UserIDFromContext(ctx)
+	err := a.access.CheckAction(a.id, userID, action)
+	if err != nil {
+		a.logger.WarnContext(ctx, "Action denied", "action", action, "subject", subject, "error", err)
+		a.recordAudit(ctx, msg, audit.AccessDenied, subject, map[string]interface{}{
+			"action":  action,
+			"user_id": userID,
+			"role":    a.access.Role(userID),
+			"reason":  err.Error(),
+		})
+	}
+	return err
+}
+
+// authorize reports whether the agent may take action while handling msg;
+// when it may not, the returned message tells the requester so. summary
+// describes the action, e.g. "delete the task 'Buy milk'".
+func (a *BaseAgent) authorize(ctx context.Context, msg *multiagent.Message, action, subject, summary string) (bool, *multiagent.Message) {
+	if err := a.checkAction(ctx, msg, action, subject); err != nil {
+		return false, &multiagent.Message{
+			ID:        a.messageID(),
+			From:      a.id,
+			To:        []multiagent.AgentID{msg.From},
+			Type:      multiagent.MessageTypeResponse,
+			Content:   fmt.Sprintf("🚫 I can't %s: %v.", summary, err),
+			ReplyTo:   msg.ID,
+			Timestamp: a.now(),
+			Context: map[string]interface{}{
+				"action":        "access_denied",
+				"denied_action": action,
+			},
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,29 @@
+package agents
+
+import (
+	"context"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+)
+
+// recordAudit appends an action taken while handling msg to the audit log;
+// failures are logged since auditing must not block the user's request
+func (a *BaseAgent) recordAudit(ctx context.Context, msg *multiagent.Message, eventType audit.EventType, subject string, payload map[string]interface{}) {
+	if a.auditLog == nil {
+		return
+	}
+
+	event := audit.Event{
+		Type:    eventType,
+		Actor:   a.id,
+		Subject: subject,
+		Payload: payload,
+	}
+	if msg != nil {
+		event.ConversationID, _ = msg.Context["conversation_id"].(string)
+	}
+	if err := a.auditLog.Record(ctx, event); err != nil {
+		a.logger.WarnContext(ctx, "Failed to record audit event", "event_type", eventType, "error", err)
+	}
+}
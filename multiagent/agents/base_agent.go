@@ -4,11 +4,22 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/access"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/email"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/llmprovider"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+	"github.com/kbutz/wikillm/multiagent/reminders"
+	"github.com/kbutz/wikillm/multiagent/usage"
 )
 
 // BaseAgent provides common functionality for all agents
@@ -27,6 +38,22 @@ type BaseAgent struct {
 	memoryStore  multiagent.MemoryStore
 	orchestrator multiagent.Orchestrator
 	running      bool // Add explicit running flag
+	logger       *slog.Logger
+	auditLog     audit.Recorder
+
+	// Shared reminder scheduling; named apart from agents' reminder maps
+	reminderEngine *reminders.Engine
+	confirmations  ConfirmationPolicy
+	access         *access.Policy
+	usage          *usage.Tracker
+	prompts        *prompts.Registry
+	clock          multiagent.Clock
+	ids            multiagent.IDGenerator
+
+	// Outstanding Request futures keyed by request message ID
+	pending        map[string]*Future
+	pendingMu      sync.Mutex
+	requestTimeout time.Duration
 }
 
 // BaseAgentConfig holds configuration for creating a base agent
@@ -40,10 +67,61 @@ type BaseAgentConfig struct {
 	LLMProvider  multiagent.LLMProvider
 	MemoryStore  multiagent.MemoryStore
 	Orchestrator multiagent.Orchestrator
+	// RequestTimeout bounds how long Request waits for a reply (default 60s)
+	RequestTimeout time.Duration
+	// Audit, if set, receives the agent's significant actions
+	Audit audit.Recorder
+	// Notifier delivers reminders when the agent starts its own engine
+	Notifier notify.Notifier
+	// Reminders is the engine agents schedule reminders with; agents that
+	// need one start their own when it is not set
+	Reminders *reminders.Engine
+	// Email sends the messages the communication manager composes; without
+	// it they stay drafts
+	Email email.Sender
+	// Confirmations names the actions that wait for the user's approval
+	// (defaults to DefaultConfirmationPolicy; empty confirms nothing)
+	Confirmations ConfirmationPolicy
+	// Access limits the actions the agent may take, and for which users'
+	// roles; nil allows every one. Tools are limited where they are
+	// wrapped, see access.WrapTool.
+	Access *access.Policy
+	// RoutingLLMProvider classifies the agent's requests, typically a small
+	// fast model (defaults to LLMProvider)
+	RoutingLLMProvider multiagent.LLMProvider
+	// Usage, if set, tells the agent which conversations are over their
+	// token budget, so it can skip optional LLM calls for them
+	Usage *usage.Tracker
+	// Prompts renders the agent's LLM prompts by name (defaults to
+	// prompts.Default, the built-in prompts)
+	Prompts *prompts.Registry
+	// Clock stamps the agent's messages and records (default the system
+	// clock)
+	Clock multiagent.Clock
+	// IDs names the agent's messages and records (default ULIDs)
+	IDs multiagent.IDGenerator
+}
+
+// routingProvider returns the provider config classifies requests with
+func routingProvider(config BaseAgentConfig) multiagent.LLMProvider {
+	if config.RoutingLLMProvider != nil {
+		return config.RoutingLLMProvider
+	}
+	return config.LLMProvider
 }
 
 // NewBaseAgent creates a new base agent
 func NewBaseAgent(config BaseAgentConfig) *BaseAgent {
+	if config.RequestTimeout == 0 {
+		config.RequestTimeout = DefaultRequestTimeout
+	}
+	if config.Confirmations == nil {
+		config.Confirmations = DefaultConfirmationPolicy()
+	}
+	if config.Prompts == nil {
+		config.Prompts = prompts.Default()
+	}
+
 	return &BaseAgent{
 		id:           config.ID,
 		agentType:    config.Type,
@@ -57,6 +135,18 @@ func NewBaseAgent(config BaseAgentConfig) *BaseAgent {
 		messageChan:  make(chan *multiagent.Message, 100),
 		stopChan:     make(chan struct{}),
 		running:      false,
+		pending:      make(map[string]*Future),
+		logger:       logging.For(string(config.Type)),
+		auditLog:     config.Audit,
+
+		requestTimeout: config.RequestTimeout,
+		reminderEngine: config.Reminders,
+		confirmations:  config.Confirmations,
+		access:         config.Access,
+		usage:          config.Usage,
+		prompts:        config.Prompts,
+		clock:          ids.ClockOrSystem(config.Clock),
+		ids:            ids.OrDefault(config.IDs),
 		state: multiagent.AgentState{
 			Status:       multiagent.AgentStatusOffline,
 			Capabilities: config.Capabilities,
@@ -66,6 +156,21 @@ func NewBaseAgent(config BaseAgentConfig) *BaseAgent {
 	}
 }
 
+// now returns the time on the agent's clock
+func (a *BaseAgent) now() time.Time {
+	return a.clock.Now()
+}
+
+// newID returns a new ID starting with prefix
+func (a *BaseAgent) newID(prefix string) string {
+	return a.ids.NewID(prefix)
+}
+
+// messageID returns a new ID for a message the agent sends
+func (a *BaseAgent) messageID() string {
+	return a.ids.NewID("msg_" + string(a.id))
+}
+
 // ID returns the agent's unique identifier
 func (a *BaseAgent) ID() multiagent.AgentID {
 	return a.id
@@ -93,14 +198,14 @@ func (a *BaseAgent) Initialize(ctx context.Context) error {
 
 	// Update state
 	a.state.Status = multiagent.AgentStatusStarting
-	a.state.LastActivity = time.Now()
+	a.state.LastActivity = a.now()
 
 	// Store agent initialization in memory
 	if a.memoryStore != nil {
 		initData := map[string]interface{}{
 			"agent_id":     a.id,
 			"agent_type":   a.agentType,
-			"initialized":  time.Now(),
+			"initialized":  a.now(),
 			"capabilities": a.capabilities,
 		}
 
@@ -125,7 +230,7 @@ func (a *BaseAgent) Start(ctx context.Context) error {
 
 	// Reset state for fresh start
 	a.state.Status = multiagent.AgentStatusIdle
-	a.state.LastActivity = time.Now()
+	a.state.LastActivity = a.now()
 	a.running = true
 
 	// Create new channels to ensure clean state
@@ -149,9 +254,12 @@ func (a *BaseAgent) Stop(ctx context.Context) error {
 
 	// Update state
 	a.state.Status = multiagent.AgentStatusOffline
-	a.state.LastActivity = time.Now()
+	a.state.LastActivity = a.now()
 	a.running = false
 
+	// Waiters on Request would otherwise block until their timeout
+	a.failPending(fmt.Errorf("agent %s stopped", a.id))
+
 	// Close stop channel to signal message loop to exit
 	select {
 	case <-a.stopChan:
@@ -164,15 +272,15 @@ func (a *BaseAgent) Stop(ctx context.Context) error {
 	if a.memoryStore != nil {
 		shutdownData := map[string]interface{}{
 			"agent_id":  a.id,
-			"shutdown":  time.Now(),
+			"shutdown":  a.now(),
 			"workload":  a.state.Workload,
 			"last_task": a.state.CurrentTask,
 		}
 
-		key := fmt.Sprintf("agent:%s:shutdown:%d", a.id, time.Now().Unix())
+		key := fmt.Sprintf("agent:%s:shutdown:%d", a.id, a.now().Unix())
 		if err := a.memoryStore.Store(ctx, key, shutdownData); err != nil {
 			// Log error but don't fail shutdown
-			fmt.Printf("Failed to store shutdown data: %v\n", err)
+			a.logger.WarnContext(ctx, "Failed to store shutdown data", "error", err)
 		}
 	}
 
@@ -194,6 +302,27 @@ func (a *BaseAgent) GetState() multiagent.AgentState {
 	return stateCopy
 }
 
+// Ping reports whether the agent is running, failing if its state stays
+// locked until ctx ends, e.g. by a handler that is stuck
+func (a *BaseAgent) Ping(ctx context.Context) error {
+	done := make(chan bool, 1)
+	go func() {
+		a.mu.RLock()
+		defer a.mu.RUnlock()
+		done <- a.running
+	}()
+
+	select {
+	case running := <-done:
+		if !running {
+			return fmt.Errorf("agent %s is not running", a.id)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("agent %s did not answer: %w", a.id, ctx.Err())
+	}
+}
+
 // SendMessage sends a message through the orchestrator
 func (a *BaseAgent) SendMessage(ctx context.Context, msg *multiagent.Message) error {
 	if a.orchestrator == nil {
@@ -207,13 +336,37 @@ func (a *BaseAgent) SendMessage(ctx context.Context, msg *multiagent.Message) er
 
 	// Set timestamp if not already set
 	if msg.Timestamp.IsZero() {
-		msg.Timestamp = time.Now()
+		msg.Timestamp = a.now()
 	}
 
 	// Route through orchestrator
 	return a.orchestrator.RouteMessage(ctx, msg)
 }
 
+// Publish sends a message to every agent subscribed to topic
+func (a *BaseAgent) Publish(ctx context.Context, topic string, msg *multiagent.Message) (int, error) {
+	if a.orchestrator == nil {
+		return 0, fmt.Errorf("no orchestrator configured")
+	}
+
+	if msg.From == "" {
+		msg.From = a.id
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = a.now()
+	}
+
+	return a.orchestrator.Publish(ctx, topic, msg)
+}
+
+// Subscribe asks the orchestrator to deliver messages published on topics matching pattern
+func (a *BaseAgent) Subscribe(pattern string) error {
+	if a.orchestrator == nil {
+		return fmt.Errorf("no orchestrator configured")
+	}
+	return a.orchestrator.Subscribe(a.id, pattern)
+}
+
 // ReceiveMessage receives a message from the agent's message channel
 func (a *BaseAgent) ReceiveMessage(ctx context.Context) (*multiagent.Message, error) {
 	select {
@@ -226,8 +379,13 @@ func (a *BaseAgent) ReceiveMessage(ctx context.Context) (*multiagent.Message, er
 
 // HandleMessage processes an incoming message
 func (a *BaseAgent) HandleMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	// Replies to our own Request calls complete their futures
+	if a.resolveReply(msg) {
+		return nil, nil
+	}
+
 	a.mu.Lock()
-	a.state.LastActivity = time.Now()
+	a.state.LastActivity = a.now()
 	currentWorkload := a.state.Workload
 	a.state.Workload = min(currentWorkload+10, 100)
 	a.mu.Unlock()
@@ -273,6 +431,21 @@ func (a *BaseAgent) GetCapabilities() []string {
 	return capabilities
 }
 
+// renderPrompt renders the prompt registered as name, shrunk to fit the
+// context window of the agent's model
+func (a *BaseAgent) renderPrompt(ctx context.Context, name string, vars prompts.Vars) (string, error) {
+	return a.prompts.RenderWithin(ctx, name, vars, prompts.Budget{
+		MaxTokens: llmprovider.PromptBudget(a.llmProvider),
+		Count:     llmprovider.EstimateTokens,
+	})
+}
+
+// overBudget reports whether a conversation has spent its daily token
+// budget
+func (a *BaseAgent) overBudget(ctx context.Context, conversationID string) bool {
+	return a.usage != nil && a.usage.Exceeded(ctx, conversationID)
+}
+
 // CanHandle checks if the agent can handle a specific message type
 func (a *BaseAgent) CanHandle(messageType multiagent.MessageType) bool {
 	switch messageType {
@@ -288,6 +461,18 @@ func (a *BaseAgent) CanHandle(messageType multiagent.MessageType) bool {
 // Internal helper methods
 
 func (a *BaseAgent) messageLoop(ctx context.Context) {
+	// A panic stops the loop and flags the agent so the orchestrator's
+	// supervisor restarts it
+	defer func() {
+		if r := recover(); r != nil {
+			a.logger.Error("Message loop crashed", logging.KeyAgentID, a.id, "panic", r)
+			a.mu.Lock()
+			a.state.Status = multiagent.AgentStatusError
+			a.running = false
+			a.mu.Unlock()
+		}
+	}()
+
 	for {
 		select {
 		case msg := <-a.messageChan:
@@ -295,13 +480,13 @@ func (a *BaseAgent) messageLoop(ctx context.Context) {
 			if err != nil {
 				// Send error response
 				errorResponse := &multiagent.Message{
-					ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+					ID:        a.messageID(),
 					From:      a.id,
 					To:        []multiagent.AgentID{msg.From},
 					Type:      multiagent.MessageTypeError,
 					Content:   fmt.Sprintf("Error processing message: %v", err),
 					ReplyTo:   msg.ID,
-					Timestamp: time.Now(),
+					Timestamp: a.now(),
 				}
 				a.SendMessage(ctx, errorResponse)
 			} else if response != nil && msg.RequiresACK {
@@ -318,9 +503,22 @@ func (a *BaseAgent) messageLoop(ctx context.Context) {
 	}
 }
 
+// tool returns the agent's tool called name, if it was given one
+func (a *BaseAgent) tool(name string) (multiagent.Tool, bool) {
+	for _, tool := range a.tools {
+		if tool.Name() == name {
+			return tool, true
+		}
+	}
+	return nil, false
+}
+
 func (a *BaseAgent) handleRequest(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Build context for LLM
-	contextPrompt := a.buildContextPrompt(ctx, msg)
+	contextPrompt, err := a.buildContextPrompt(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
 
 	// Query LLM with available tools
 	response, err := a.llmProvider.QueryWithTools(ctx, contextPrompt, a.tools)
@@ -345,8 +543,8 @@ func (a *BaseAgent) handleRequest(ctx context.Context, msg *multiagent.Message)
 				conversation = multiagent.ConversationContext{
 					ID:           conversationID,
 					UserID:       string(msg.From),
-					StartTime:    time.Now(),
-					LastActivity: time.Now(),
+					StartTime:    a.now(),
+					LastActivity: a.now(),
 					Messages:     []multiagent.ConversationMessage{},
 					Context:      make(map[string]interface{}),
 					ActiveAgents: []multiagent.AgentID{a.id},
@@ -366,27 +564,17 @@ func (a *BaseAgent) handleRequest(ctx context.Context, msg *multiagent.Message)
 			conversation.Messages = append(conversation.Messages, multiagent.ConversationMessage{
 				Role:      "assistant",
 				Content:   response,
-				Timestamp: time.Now(),
+				Timestamp: a.now(),
 				AgentID:   a.id,
 			})
-			conversation.LastActivity = time.Now()
+			conversation.LastActivity = a.now()
 
 			// Store updated conversation
 			a.memoryStore.Store(ctx, convKey, conversation)
 		}
 	}
 
-	// Create response message
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   response,
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-		Context:   msg.Context,
-	}, nil
+	return a.respond(msg, response, msg.Context), nil
 }
 
 func (a *BaseAgent) handleQuery(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
@@ -397,7 +585,13 @@ func (a *BaseAgent) handleQuery(ctx context.Context, msg *multiagent.Message) (*
 	}
 
 	// Build response with memory context
-	contextPrompt := fmt.Sprintf("Based on the following context and query, provide a helpful response.\n\nContext:\n%s\n\nQuery: %s", results, msg.Content)
+	contextPrompt, err := a.renderPrompt(ctx, "agent.query", prompts.Vars{
+		"Context": results,
+		"Query":   msg.Content,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	response, err := a.llmProvider.Query(ctx, contextPrompt)
 	if err != nil {
@@ -405,13 +599,13 @@ func (a *BaseAgent) handleQuery(ctx context.Context, msg *multiagent.Message) (*
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   response,
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
@@ -436,54 +630,54 @@ func (a *BaseAgent) handleCommand(ctx context.Context, msg *multiagent.Message)
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeReport,
 		Content:   result,
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
-func (a *BaseAgent) createAcknowledgment(msg *multiagent.Message) *multiagent.Message {
+// respond builds a reply to msg
+func (a *BaseAgent) respond(msg *multiagent.Message, content string, context map[string]interface{}) *multiagent.Message {
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
-		Content:   fmt.Sprintf("Message %s received and acknowledged", msg.ID),
+		Content:   content,
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
+		Context:   context,
 	}
 }
 
-func (a *BaseAgent) buildContextPrompt(ctx context.Context, msg *multiagent.Message) string {
-	var contextBuilder strings.Builder
-
-	contextBuilder.WriteString(fmt.Sprintf("You are %s, a %s agent.\n", a.name, a.agentType))
-	contextBuilder.WriteString(fmt.Sprintf("Description: %s\n\n", a.description))
-
-	// Add message context
-	contextBuilder.WriteString(fmt.Sprintf("Request from %s: %s\n", msg.From, msg.Content))
-
-	// Add any additional context from the message
-	if len(msg.Context) > 0 {
-		contextBuilder.WriteString("\nAdditional Context:\n")
-		for k, v := range msg.Context {
-			contextBuilder.WriteString(fmt.Sprintf("- %s: %v\n", k, v))
-		}
-	}
-
-	// Add recent memory context
-	if memories := a.getRecentMemories(ctx, 5); len(memories) > 0 {
-		contextBuilder.WriteString("\nRecent Memory:\n")
-		for _, mem := range memories {
-			contextBuilder.WriteString(fmt.Sprintf("- %v\n", mem.Value))
-		}
+func (a *BaseAgent) createAcknowledgment(msg *multiagent.Message) *multiagent.Message {
+	return &multiagent.Message{
+		ID:        a.messageID(),
+		From:      a.id,
+		To:        []multiagent.AgentID{msg.From},
+		Type:      multiagent.MessageTypeResponse,
+		Content:   fmt.Sprintf("Message %s received and acknowledged", msg.ID),
+		ReplyTo:   msg.ID,
+		Timestamp: a.now(),
 	}
+}
 
-	return contextBuilder.String()
+func (a *BaseAgent) buildContextPrompt(ctx context.Context, msg *multiagent.Message) (string, error) {
+	return a.renderPrompt(ctx, "agent.request", prompts.Vars{
+		"Name":        a.name,
+		"Type":        a.agentType,
+		"Description": a.description,
+		// What other specialists already learned in this conversation
+		"SharedContext": a.sharedContext(ctx, msg),
+		"From":          msg.From,
+		"Request":       msg.Content,
+		"Context":       msg.Context,
+		"Memories":      a.getRecentMemories(ctx, 5),
+	})
 }
 
 func (a *BaseAgent) searchRelevantMemory(ctx context.Context, query string) (string, error) {
@@ -491,6 +685,17 @@ func (a *BaseAgent) searchRelevantMemory(ctx context.Context, query string) (str
 		return "", nil
 	}
 
+	// Prefer semantic recall when the store supports it
+	if vectorStore, ok := a.memoryStore.(multiagent.VectorMemoryStore); ok {
+		if similar, err := vectorStore.SearchSimilar(ctx, query, 10); err == nil && len(similar) > 0 {
+			var contextBuilder strings.Builder
+			for i, hit := range similar {
+				contextBuilder.WriteString(fmt.Sprintf("%d. %s\n", i+1, hit.Text))
+			}
+			return contextBuilder.String(), nil
+		}
+	}
+
 	results, err := a.memoryStore.Search(ctx, query, 10)
 	if err != nil {
 		return "", err
@@ -532,6 +737,24 @@ func (a *BaseAgent) getRecentMemories(ctx context.Context, limit int) []multiage
 	return entries
 }
 
+// indexForRecall makes text available to semantic search when the memory
+// store supports embeddings; it is a no-op for plain stores
+func (a *BaseAgent) indexForRecall(ctx context.Context, key string, text string, metadata map[string]interface{}) {
+	vectorStore, ok := a.memoryStore.(multiagent.VectorMemoryStore)
+	if !ok || strings.TrimSpace(text) == "" {
+		return
+	}
+
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["agent_id"] = string(a.id)
+
+	if err := vectorStore.StoreEmbedding(ctx, key, text, metadata); err != nil {
+		a.logger.WarnContext(ctx, "Failed to index for recall", "key", key, "error", err)
+	}
+}
+
 func (a *BaseAgent) executeCommand(ctx context.Context, msg *multiagent.Message) (string, error) {
 	// This is a placeholder - specific agents will override this method
 	return fmt.Sprintf("Command '%s' executed successfully by %s", msg.Content, a.name), nil
@@ -0,0 +1,56 @@
+package agents
+
+import (
+	"context"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+// blackboard returns the shared context of msg's conversation, or nil when
+// the message is not part of one
+func (a *BaseAgent) blackboard(msg *multiagent.Message) *memory.Blackboard {
+	conversationID, _ := msg.Context["conversation_id"].(string)
+	if conversationID == "" || a.memoryStore == nil {
+		return nil
+	}
+	return memory.NewBlackboard(a.memoryStore, conversationID)
+}
+
+// sharedContext renders the conversation blackboard as a prompt preamble so
+// specialists reuse what others already learned instead of re-asking the user
+func (a *BaseAgent) sharedContext(ctx context.Context, msg *multiagent.Message) string {
+	board := a.blackboard(msg)
+	if board == nil {
+		return ""
+	}
+
+	snapshot, err := board.Read(ctx)
+	if err != nil {
+		a.logger.WarnContext(ctx, "Failed to read shared context", "error", err)
+		return ""
+	}
+	rendered := snapshot.String()
+	if rendered == "" {
+		return ""
+	}
+	return "Shared conversation context (recorded by other specialists):\n" + rendered + "\n"
+}
+
+// recordShared appends entries to the conversation blackboard; failures are
+// logged since shared context is best effort
+func (a *BaseAgent) recordShared(ctx context.Context, msg *multiagent.Message, entries ...memory.BlackboardEntry) {
+	board := a.blackboard(msg)
+	if board == nil {
+		return
+	}
+
+	for i := range entries {
+		if entries[i].Source == "" {
+			entries[i].Source = a.id
+		}
+	}
+	if err := board.Append(ctx, entries...); err != nil {
+		a.logger.WarnContext(ctx, "Failed to record shared context", "error", err)
+	}
+}
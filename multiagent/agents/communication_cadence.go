@@ -0,0 +1,545 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+	"github.com/kbutz/wikillm/multiagent/reminders"
+)
+
+// defaultReconnectHour is the hour of the day, in the user's timezone, the
+// reconnect check runs at
+const defaultReconnectHour = 9
+
+// reconnectLookahead is how far ahead suggestions include contacts coming
+// due
+const reconnectLookahead = 7 * 24 * time.Hour
+
+var (
+	// cadencePhrases map what users call a cadence onto one, most specific
+	// first
+	cadencePhrases = []struct {
+		pattern   *regexp.Regexp
+		frequency ContactFrequency
+	}{
+		{regexp.MustCompile(`\b(as[ _]needed|no cadence|stop reminding|don'?t remind)\b`), ContactFrequencyAsNeeded},
+		{regexp.MustCompile(`\b(quarterly|every (quarter|3 months|three months))\b`), ContactFrequencyQuarterly},
+		{regexp.MustCompile(`\b(daily|every day|each day)\b`), ContactFrequencyDaily},
+		{regexp.MustCompile(`\b(weekly|every week|once a week)\b`), ContactFrequencyWeekly},
+		{regexp.MustCompile(`\b(monthly|every month|once a month)\b`), ContactFrequencyMonthly},
+		{regexp.MustCompile(`\b(yearly|annually|every year|once a year)\b`), ContactFrequencyYearly},
+	}
+	// relationshipGroup matches a relationship named as a group, e.g. "my
+	// mentors"
+	relationshipGroup = regexp.MustCompile(`\b(family|friends?|colleagues?|clients?|vendors?|mentors?|professional contacts?)\b`)
+	// outreachPhrase marks a request to write to a contact
+	outreachPhrase = regexp.MustCompile(`\b(draft|write|compose|reach out|message|email)\b`)
+)
+
+// reconnectSuggestion is a contact due to hear from the user
+type reconnectSuggestion struct {
+	Contact Contact
+	// Since is the last contact, or when the contact was added if never
+	Since time.Time
+	Due   time.Time
+}
+
+// overdue reports whether the suggestion was due by now
+func (s reconnectSuggestion) overdue(now time.Time) bool {
+	return !s.Due.After(now)
+}
+
+// cadenceInterval is how long a contact can go without hearing from the user
+// at frequency; zero for as_needed
+func cadenceInterval(frequency ContactFrequency) time.Duration {
+	day := 24 * time.Hour
+	switch frequency {
+	case ContactFrequencyDaily:
+		return day
+	case ContactFrequencyWeekly:
+		return 7 * day
+	case ContactFrequencyMonthly:
+		return 30 * day
+	case ContactFrequencyQuarterly:
+		return 91 * day
+	case ContactFrequencyYearly:
+		return 365 * day
+	}
+	return 0
+}
+
+// parseContactFrequency finds the cadence named in text, or ""
+func parseContactFrequency(text string) ContactFrequency {
+	text = strings.ToLower(text)
+	for _, phrase := range cadencePhrases {
+		if phrase.pattern.MatchString(text) {
+			return phrase.frequency
+		}
+	}
+	if frequency := ContactFrequency(strings.ReplaceAll(strings.TrimSpace(text), " ", "_")); frequency == ContactFrequencyAsNeeded || cadenceInterval(frequency) > 0 {
+		return frequency
+	}
+	return ""
+}
+
+// parseRelationshipGroup finds a relationship named as a group in text, e.g.
+// "mentors", or ""
+func parseRelationshipGroup(text string) RelationshipType {
+	match := relationshipGroup.FindString(strings.ToLower(text))
+	switch {
+	case match == "":
+		return ""
+	case strings.HasPrefix(match, "professional"):
+		return RelationshipTypeProfessional
+	}
+	return RelationshipType(strings.TrimSuffix(match, "s"))
+}
+
+// reconnectDue is when the contact is next due to hear from the user, if
+// they have a cadence
+func (c *Contact) reconnectDue() (time.Time, time.Time, bool) {
+	interval := cadenceInterval(c.ContactFreq)
+	if interval == 0 || (c.Status != "" && c.Status != ContactStatusActive) {
+		return time.Time{}, time.Time{}, false
+	}
+	since := c.CreatedAt
+	if c.LastContact != nil {
+		since = *c.LastContact
+	}
+	return since, since.Add(interval), true
+}
+
+// reconnectSuggestions lists the user's contacts due by until, most overdue
+// first; callers hold commMutex
+func (a *CommunicationManagerAgent) reconnectSuggestions(ctx context.Context, until time.Time) []reconnectSuggestion {
+	var suggestions []reconnectSuggestion
+	for _, contact := range a.contacts {
+		if !ownedBy(ctx, contact.UserID) {
+			continue
+		}
+		if since, due, ok := contact.reconnectDue(); ok && !due.After(until) {
+			suggestions = append(suggestions, reconnectSuggestion{Contact: *contact, Since: since, Due: due})
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if !suggestions[i].Due.Equal(suggestions[j].Due) {
+			return suggestions[i].Due.Before(suggestions[j].Due)
+		}
+		return a.getPriorityWeight(suggestions[i].Contact.Priority) > a.getPriorityWeight(suggestions[j].Contact.Priority)
+	})
+	return suggestions
+}
+
+// hasCadences reports whether any of the user's contacts has a cadence;
+// callers hold commMutex
+func (a *CommunicationManagerAgent) hasCadences(ctx context.Context) bool {
+	for _, contact := range a.contacts {
+		if _, _, ok := contact.reconnectDue(); ok && ownedBy(ctx, contact.UserID) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeSuggestion writes one line of the reconnect list, e.g. "Jane Doe
+// (mentor) — monthly, last contact 45 days ago, 15 days overdue"
+func describeSuggestion(suggestion reconnectSuggestion, now time.Time) string {
+	contact := suggestion.Contact
+	line := contact.Name
+	if contact.Relationship != "" {
+		line += fmt.Sprintf(" (%s)", contact.Relationship)
+	}
+	line += fmt.Sprintf(" — %s, ", contact.ContactFreq)
+	if contact.LastContact != nil {
+		line += fmt.Sprintf("last contact %d day(s) ago, ", int(now.Sub(suggestion.Since).Hours()/24))
+	} else {
+		line += "no contact logged yet, "
+	}
+	if suggestion.overdue(now) {
+		return line + fmt.Sprintf("%d day(s) overdue", int(now.Sub(suggestion.Due).Hours()/24))
+	}
+	return line + fmt.Sprintf("due %s", suggestion.Due.Format("Mon Jan 2"))
+}
+
+// handleRelationshipManagement keeps the user in touch with their network:
+// it sets how often to reconnect with a contact or a whole relationship,
+// e.g. "stay in touch with my mentors monthly", drafts outreach to a
+// contact, and otherwise lists who is overdue for a reconnect
+func (a *CommunicationManagerAgent) handleRelationshipManagement(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadContactsFromMemory(ctx)
+
+	prompt, err := a.renderPrompt(ctx, "communication.relationships", prompts.Vars{"Request": msg.Content})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Action       string `json:"action"`
+		Contact      string `json:"contact"`
+		Relationship string `json:"relationship"`
+		Frequency    string `json:"frequency"`
+	}
+	requestSchema := objectSchema(map[string]string{
+		"action":       "string",
+		"contact":      "string",
+		"relationship": "string",
+		"frequency":    "string",
+	}, "action")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, requestSchema, &data); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse relationship request", "error", err)
+		lower := strings.ToLower(msg.Content)
+		data.Action = "suggest"
+		if contact := a.mentionedContact(ctx, lower); contact != nil {
+			data.Contact = contact.Name
+		}
+		data.Relationship = string(parseRelationshipGroup(lower))
+		if data.Frequency = string(parseContactFrequency(lower)); data.Frequency != "" {
+			data.Action = "set_cadence"
+		} else if data.Contact != "" && outreachPhrase.MatchString(lower) {
+			data.Action = "draft"
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(data.Action)) {
+	case "set_cadence", "cadence":
+		return a.setCadence(ctx, msg, data.Contact, parseRelationshipGroup(data.Relationship), parseContactFrequency(data.Frequency))
+	case "draft", "write", "reach_out":
+		return a.draftOutreach(ctx, msg, data.Contact)
+	}
+	return a.listReconnects(ctx, msg)
+}
+
+// mentionedContact returns the user's contact whose name, or first name,
+// text mentions, preferring the longest match
+func (a *CommunicationManagerAgent) mentionedContact(ctx context.Context, text string) *Contact {
+	a.commMutex.RLock()
+	defer a.commMutex.RUnlock()
+	var best *Contact
+	bestLength := 0
+	for _, contact := range a.contacts {
+		if !ownedBy(ctx, contact.UserID) {
+			continue
+		}
+		fields := strings.Fields(strings.ToLower(contact.Name))
+		if len(fields) == 0 {
+			continue
+		}
+		for _, candidate := range []string{strings.Join(fields, " "), fields[0]} {
+			if len(candidate) > bestLength && regexp.MustCompile(`\b`+regexp.QuoteMeta(candidate)+`\b`).MatchString(text) {
+				best, bestLength = contact, len(candidate)
+			}
+		}
+	}
+	return best
+}
+
+// listReconnects answers "who should I reconnect with?"
+func (a *CommunicationManagerAgent) listReconnects(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	now := a.now().In(userLocation(ctx, a.memoryStore))
+	a.commMutex.RLock()
+	suggestions := a.reconnectSuggestions(ctx, now.Add(reconnectLookahead))
+	hasCadences := a.hasCadences(ctx)
+	a.commMutex.RUnlock()
+
+	if !hasCadences {
+		return a.respond(msg, "🤝 None of your contacts has a stay-in-touch cadence yet. Say something like \"stay in touch with my mentors monthly\" or \"keep in touch with Jane quarterly\" and I'll tell you when it's time to reconnect.", map[string]interface{}{
+			"action": "reconnects_listed",
+		}), nil
+	}
+	if len(suggestions) == 0 {
+		return a.respond(msg, "🤝 You're in touch with everyone on your cadences — nobody is due this week.", map[string]interface{}{
+			"action": "reconnects_listed",
+		}), nil
+	}
+
+	var overdue, upcoming []string
+	var ids []string
+	for _, suggestion := range suggestions {
+		line := "• " + describeSuggestion(suggestion, now)
+		if suggestion.overdue(now) {
+			overdue = append(overdue, line)
+			ids = append(ids, suggestion.Contact.ID)
+		} else {
+			upcoming = append(upcoming, line)
+		}
+	}
+	var b strings.Builder
+	b.WriteString("🤝 **Time to Reconnect**\n")
+	if len(overdue) > 0 {
+		fmt.Fprintf(&b, "\n**Overdue**\n%s\n", strings.Join(overdue, "\n"))
+	}
+	if len(upcoming) > 0 {
+		fmt.Fprintf(&b, "\n**Coming up this week**\n%s\n", strings.Join(upcoming, "\n"))
+	}
+	fmt.Fprintf(&b, "\nSay \"draft a reconnect message to %s\" and I'll write one.", suggestions[0].Contact.Name)
+	return a.respond(msg, b.String(), map[string]interface{}{
+		"overdue": ids,
+		"action":  "reconnects_listed",
+	}), nil
+}
+
+// setCadence sets how often the user keeps in touch with a contact, or with
+// every contact of a relationship, and starts the daily reconnect check
+func (a *CommunicationManagerAgent) setCadence(ctx context.Context, msg *multiagent.Message, name string, relationship RelationshipType, frequency ContactFrequency) (*multiagent.Message, error) {
+	if frequency == "" {
+		return a.respond(msg, "🤝 How often? Say daily, weekly, monthly, quarterly, yearly or as needed, e.g. \"keep in touch with Jane monthly\".", nil), nil
+	}
+
+	var target *Contact
+	if name != "" {
+		if target = a.findContactByName(ctx, name); target == nil {
+			return a.respond(msg, fmt.Sprintf("❌ Contact '%s' not found. Add them as a contact first.", name), nil), nil
+		}
+	} else if relationship == "" {
+		return a.respond(msg, "🤝 Who should I keep you in touch with? Name a contact, or a group like your mentors or clients.", nil), nil
+	}
+
+	now := a.now()
+	a.commMutex.Lock()
+	var updated []Contact
+	for _, contact := range a.contacts {
+		if !ownedBy(ctx, contact.UserID) {
+			continue
+		}
+		if (target != nil && contact.ID != target.ID) || (target == nil && contact.Relationship != relationship) {
+			continue
+		}
+		contact.ContactFreq = frequency
+		contact.UpdatedAt = now
+		updated = append(updated, *contact)
+	}
+	a.commMutex.Unlock()
+	if len(updated) == 0 {
+		return a.respond(msg, fmt.Sprintf("❌ You have no %s contacts yet.", relationship), nil), nil
+	}
+
+	names := make([]string, len(updated))
+	ids := make([]string, len(updated))
+	for i := range updated {
+		if err := a.saveContact(ctx, &updated[i]); err != nil {
+			return nil, err
+		}
+		names[i], ids[i] = updated[i].Name, updated[i].ID
+		a.recordAudit(ctx, msg, audit.ContactCadenceSet, updated[i].ID, map[string]interface{}{
+			"name":      updated[i].Name,
+			"frequency": frequency,
+		})
+	}
+	sort.Strings(names)
+
+	who := strings.Join(names, ", ")
+	if target == nil {
+		who = fmt.Sprintf("your %ss (%s)", relationship, who)
+	}
+	replyContext := map[string]interface{}{
+		"contact_ids": ids,
+		"frequency":   frequency,
+		"action":      "cadence_set",
+	}
+	if frequency == ContactFrequencyAsNeeded {
+		return a.respond(msg, fmt.Sprintf("🤝 I'll stop reminding you to reconnect with %s.", who), replyContext), nil
+	}
+
+	next := a.scheduleReconnectCheck(ctx)
+	reply := fmt.Sprintf("🤝 I'll keep you in touch with %s %s.", who, frequency)
+	if target != nil {
+		since, due, _ := updated[0].reconnectDue()
+		suggestion := reconnectSuggestion{Contact: updated[0], Since: since, Due: due}
+		reply += "\n\n" + describeSuggestion(suggestion, now) + "."
+	}
+	if !next.IsZero() {
+		reply += fmt.Sprintf("\n\nI check who's due every morning and will let you know, next on %s.", next.Format("Mon Jan 2 at 15:04"))
+	}
+	return a.respond(msg, reply, replyContext), nil
+}
+
+// draftOutreach drafts a reconnect message to a contact from the user's
+// networking or follow-up template, or writes one when they have neither
+func (a *CommunicationManagerAgent) draftOutreach(ctx context.Context, msg *multiagent.Message, name string) (*multiagent.Message, error) {
+	contact := a.findContactByName(ctx, name)
+	if name == "" || contact == nil {
+		return a.respond(msg, fmt.Sprintf("❌ Contact '%s' not found. Say \"who should I reconnect with?\" to see who's due.", name), nil), nil
+	}
+
+	a.loadTemplatesFromMemory(ctx)
+	a.commMutex.Lock()
+	snapshot := *contact
+	var template *MessageTemplate
+	var subject, content string
+	var missing []string
+	for _, category := range []TemplateCategory{TemplateCategoryNetworking, TemplateCategoryFollowUp} {
+		if found := a.templateForCategory(ctx, category); found != nil {
+			subject, content, missing = a.useTemplate(ctx, found, &snapshot, nil)
+			copied := *found
+			template = &copied
+			break
+		}
+	}
+	a.commMutex.Unlock()
+
+	if template != nil {
+		if err := a.saveTemplate(ctx, template); err != nil {
+			return nil, err
+		}
+	} else {
+		lastContact := "no contact logged yet"
+		if snapshot.LastContact != nil {
+			lastContact = fmt.Sprintf("last in touch %s", snapshot.LastContact.Format("January 2, 2006"))
+		}
+		prompt, err := a.renderPrompt(ctx, "communication.draft_outreach", prompts.Vars{
+			"Name":         snapshot.Name,
+			"Relationship": snapshot.Relationship,
+			"Title":        snapshot.Title,
+			"Organization": snapshot.Organization,
+			"LastContact":  lastContact,
+			"Notes":        snapshot.Notes,
+		})
+		if err != nil {
+			return nil, err
+		}
+		written, err := a.llmProvider.Query(ctx, prompt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write reconnect message: %w", err)
+		}
+		subject, content = "Catching up", strings.TrimSpace(written)
+	}
+
+	method := snapshot.PreferredComm
+	if template != nil && template.Method != "" {
+		method = template.Method
+	}
+	message := &CommunicationMessage{
+		ID:        a.newID("msg"),
+		ContactID: snapshot.ID,
+		Subject:   subject,
+		Content:   content,
+		Method:    method,
+		Direction: MessageDirectionOutbound,
+		Status:    MessageStatusDraft,
+		Priority:  multiagent.PriorityMedium,
+		Tags:      []string{"reconnect"},
+		CreatedAt: a.now(),
+		UpdatedAt: a.now(),
+		Metadata:  make(map[string]interface{}),
+		UserID:    multiagent.UserIDFromContext(ctx),
+	}
+	if template != nil {
+		message.TemplateID = template.ID
+	}
+	a.commMutex.Lock()
+	a.messages[message.ID] = message
+	a.commMutex.Unlock()
+	if a.memoryStore != nil {
+		a.memoryStore.Store(ctx, fmt.Sprintf("communication_message:%s", message.ID), message)
+	}
+	a.recordAudit(ctx, msg, audit.MessageDrafted, message.ID, map[string]interface{}{
+		"contact_id":  snapshot.ID,
+		"subject":     message.Subject,
+		"method":      message.Method,
+		"template_id": message.TemplateID,
+	})
+
+	reply := fmt.Sprintf("✉️ **Reconnect Message Drafted**\n\n**To:** %s (%s)\n**Subject:** %s\n\n%s\n\n---\n\n", snapshot.Name, snapshot.Email, subject, content)
+	if len(missing) > 0 {
+		reply += fmt.Sprintf("⚠️ Fill in: %s\n\n", strings.Join(missing, ", "))
+	}
+	if template != nil {
+		reply += fmt.Sprintf("*Written from your template '%s' and saved as draft. Say 'send draft %s' to email it.*", template.Name, message.ID)
+	} else {
+		reply += fmt.Sprintf("*Saved as draft. Say 'send draft %s' to email it, or save a networking template to write these your way.*", message.ID)
+	}
+	return a.respond(msg, reply, map[string]interface{}{
+		"message_id": message.ID,
+		"contact_id": snapshot.ID,
+		"missing":    missing,
+		"action":     "reconnect_drafted",
+	}), nil
+}
+
+// reconnectReminderID names the user's daily reconnect check
+func reconnectReminderID(userID string) string {
+	return "reconnect_" + userID
+}
+
+// scheduleReconnectCheck starts the user's daily reconnect check unless it
+// is running, returning when it runs next
+func (a *CommunicationManagerAgent) scheduleReconnectCheck(ctx context.Context) time.Time {
+	userID := multiagent.UserIDFromContext(ctx)
+	id := reconnectReminderID(userID)
+	if scheduled, ok := a.reminderEngine.Get(id); ok {
+		return scheduled.Due()
+	}
+
+	now := a.now().In(userLocation(ctx, a.memoryStore))
+	next := time.Date(now.Year(), now.Month(), now.Day(), defaultReconnectHour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	err := a.reminderEngine.Schedule(ctx, reminders.Reminder{
+		ID:        id,
+		UserID:    userID,
+		Source:    reconnectReminderSource,
+		Title:     "🤝 Time to reconnect",
+		Kind:      notify.KindReconnect,
+		Priority:  multiagent.PriorityLow,
+		TriggerAt: next,
+	})
+	if err != nil {
+		a.logger.WarnContext(ctx, "Failed to schedule reconnect check", "error", err)
+		return time.Time{}
+	}
+	return next
+}
+
+// fireReconnectCheck is the engine's handler for the daily reconnect check:
+// it suggests reconnecting when contacts have come due since the last
+// check, stays silent otherwise, and stops once no contact has a cadence
+func (a *CommunicationManagerAgent) fireReconnectCheck(ctx context.Context, scheduled reminders.Reminder, now time.Time) (*notify.Notification, time.Time) {
+	a.loadContactsFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	now = now.In(loc)
+
+	a.commMutex.RLock()
+	suggestions := a.reconnectSuggestions(ctx, now)
+	hasCadences := a.hasCadences(ctx)
+	a.commMutex.RUnlock()
+	if !hasCadences {
+		return nil, time.Time{}
+	}
+
+	// Step by calendar days in the user's timezone so the check keeps its
+	// time of day across daylight saving changes
+	next := scheduled.TriggerAt.In(loc)
+	for !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+
+	newlyDue := false
+	lines := make([]string, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		if scheduled.LastFired == nil || suggestion.Due.After(*scheduled.LastFired) {
+			newlyDue = true
+		}
+		lines = append(lines, "• "+describeSuggestion(suggestion, now))
+	}
+	if !newlyDue {
+		return nil, next
+	}
+	return &notify.Notification{
+		UserID:   scheduled.UserID,
+		Kind:     notify.KindReconnect,
+		Title:    scheduled.Title,
+		Body:     strings.Join(lines, "\n") + "\n\nAsk me to draft a reconnect message to any of them.",
+		Priority: scheduled.Priority,
+		At:       now,
+		Subject:  scheduled.ID,
+	}, next
+}
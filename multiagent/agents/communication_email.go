@@ -0,0 +1,313 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/email"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/notify"
+)
+
+// sendRequestedKey marks, in a draft's metadata, when the user was shown it
+// ready to send
+const sendRequestedKey = "send_requested_at"
+
+var (
+	// draftIDPhrase finds the ID of a draft in "send draft msg_123"
+	draftIDPhrase = regexp.MustCompile(`\bmsg_\d+\b`)
+	// draftToPhrase finds the recipient in "send the draft to Bob"
+	draftToPhrase = regexp.MustCompile(`(?i)\b(?:to|for)\s+(.+?)[.!]*$`)
+	// confirmSendPhrase recognises the user confirming a send
+	confirmSendPhrase = regexp.MustCompile(`(?i)\bconfirm(?:ed)?\b`)
+)
+
+// handleSendMessage emails a draft in two steps: asked to send one, it
+// shows what would go out and to whom, and only "confirm send" sends it.
+// Without ActionSendEmail in the confirmation policy it sends at once.
+// Which draft is meant is read without the LLM, so a misread request
+// can't send the wrong message.
+func (a *CommunicationManagerAgent) handleSendMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadContactsFromMemory(ctx)
+	a.loadMessagesFromMemory(ctx)
+	confirm := confirmSendPhrase.MatchString(msg.Content)
+
+	message, contact := a.findDraft(ctx, msg.Content, confirm)
+	if message == nil {
+		return a.respond(msg, "✉️ Which draft should I send? Say \"send draft msg_123\" or \"send the draft to Bob\"; compose one first if you have none.", nil), nil
+	}
+	if message.Status != MessageStatusDraft && message.Status != MessageStatusFailed {
+		return a.respond(msg, fmt.Sprintf("✉️ %s is %s, not a draft, so there is nothing to send.", message.ID, message.Status), nil), nil
+	}
+	if message.Method != "" && message.Method != CommunicationMethodEmail {
+		return a.respond(msg, fmt.Sprintf("✉️ %s is a %s message; I can only send email.", message.ID, message.Method), nil), nil
+	}
+	if contact == nil || contact.Email == "" {
+		return a.respond(msg, fmt.Sprintf("✉️ I have no email address for the recipient of %s. Add one to the contact first.", message.ID), nil), nil
+	}
+	if allowed, reply := a.authorize(ctx, msg, ActionSendEmail, message.ID, fmt.Sprintf("send '%s'", message.Subject)); !allowed {
+		return reply, nil
+	}
+	userID := multiagent.UserIDFromContext(ctx)
+	from, ok := "", false
+	if a.mailer != nil {
+		from, ok = a.mailer.From(userID)
+	}
+	if !ok {
+		return a.respond(msg, "✉️ Email sending isn't set up for you, so the message stays a draft. Ask the administrator to add your SMTP account.", map[string]interface{}{
+			"message_id": message.ID,
+		}), nil
+	}
+
+	requiresConfirmation := a.confirmations.Requires(ActionSendEmail)
+	if !confirm && requiresConfirmation {
+		now := a.now()
+		a.commMutex.Lock()
+		if message.Metadata == nil {
+			message.Metadata = make(map[string]interface{})
+		}
+		message.Metadata[sendRequestedKey] = now.Format(time.RFC3339)
+		message.UpdatedAt = now
+		snapshot := *message
+		a.commMutex.Unlock()
+		if err := a.saveMessage(ctx, &snapshot); err != nil {
+			return nil, err
+		}
+		a.recordAudit(ctx, msg, audit.ActionProposed, snapshot.ID, map[string]interface{}{
+			"action":  ActionSendEmail,
+			"summary": fmt.Sprintf("send '%s' to %s", snapshot.Subject, contact.Email),
+		})
+		content := fmt.Sprintf("✉️ **Ready to send**\n\n**From:** %s\n**To:** %s <%s>\n**Subject:** %s\n\n%s\n\n---\n\nSay 'confirm send %s' to send it.",
+			from, contact.Name, contact.Email, snapshot.Subject, snapshot.Content, snapshot.ID)
+		return a.respond(msg, content, map[string]interface{}{
+			"message_id": snapshot.ID,
+			"action":     "send_confirmation_requested",
+		}), nil
+	}
+
+	if requiresConfirmation {
+		a.recordAudit(ctx, msg, audit.ActionApproved, message.ID, map[string]interface{}{
+			"action": ActionSendEmail,
+			"answer": msg.Content,
+		})
+	}
+
+	snapshot, sendErr := a.deliver(ctx, msg, message, contact, deliveryEmail)
+	if sendErr != nil {
+		reason := sendErr.Error()
+		if errors.Is(sendErr, email.ErrNoAccount) {
+			reason = "no email account is configured for you"
+		}
+		return a.respond(msg, fmt.Sprintf("❌ Sending %s to %s failed: %s\nIt is kept; say 'confirm send %s' to try again.", snapshot.ID, contact.Email, reason, snapshot.ID), map[string]interface{}{
+			"message_id": snapshot.ID,
+			"action":     "message_send_failed",
+		}), nil
+	}
+	return a.respond(msg, fmt.Sprintf("📤 Sent '%s' to %s <%s>. The mail server accepted it as %s.", snapshot.Subject, contact.Name, contact.Email, snapshot.Metadata["smtp_message_id"]), map[string]interface{}{
+		"message_id":      snapshot.ID,
+		"smtp_message_id": snapshot.Metadata["smtp_message_id"],
+		"action":          "message_sent",
+	}), nil
+}
+
+// deliver sends message to contact over channel, email or the contact's
+// webhook, and records the outcome on both; the error is the delivery's.
+// msg is nil for deliveries nobody asked for just now, e.g. scheduled ones.
+func (a *CommunicationManagerAgent) deliver(ctx context.Context, msg *multiagent.Message, message *CommunicationMessage, contact *Contact, channel string) (CommunicationMessage, error) {
+	userID := multiagent.UserIDFromContext(ctx)
+	var receipt email.Receipt
+	// Scheduled messages are checked again when they go out, since the
+	// user's role may have changed meanwhile
+	sendErr := a.checkAction(ctx, msg, ActionSendEmail, message.ID)
+	switch {
+	case sendErr != nil:
+	case channel == deliveryWebhook:
+		webhook := &notify.WebhookChannel{URL: contact.SocialProfiles[webhookProfile]}
+		receipt.At = a.now()
+		sendErr = webhook.Send(ctx, notify.Notification{
+			UserID:   userID,
+			Kind:     webhookMessageKind,
+			Title:    message.Subject,
+			Body:     message.Content,
+			Priority: message.Priority,
+			At:       receipt.At,
+			Subject:  message.ID,
+		})
+	default:
+		receipt, sendErr = a.mailer.Send(ctx, userID, email.Message{
+			To:      []string{fmt.Sprintf("%s <%s>", contact.Name, contact.Email)},
+			Subject: message.Subject,
+			Body:    message.Content,
+		})
+	}
+
+	now := a.now()
+	a.commMutex.Lock()
+	if message.Metadata == nil {
+		message.Metadata = make(map[string]interface{})
+	}
+	message.UpdatedAt = now
+	if channel == deliveryEmail {
+		message.Method = CommunicationMethodEmail
+	}
+	delete(message.Metadata, sendRequestedKey)
+	if sendErr != nil {
+		message.Status = MessageStatusFailed
+		message.Metadata["delivery_error"] = sendErr.Error()
+	} else {
+		message.Status = MessageStatusSent
+		message.SentAt = &receipt.At
+		message.Metadata["delivered_via"] = channel
+		if channel == deliveryEmail {
+			message.Metadata["smtp_message_id"] = receipt.MessageID
+			message.Metadata["smtp_server"] = receipt.Server
+		}
+		delete(message.Metadata, "delivery_error")
+		contact.LastContact = &receipt.At
+		contact.UpdatedAt = now
+	}
+	snapshot := *message
+	contactSnapshot := *contact
+	a.commMutex.Unlock()
+
+	if err := a.saveMessage(ctx, &snapshot); err != nil {
+		a.logger.WarnContext(ctx, "Failed to save delivered message", "message_id", snapshot.ID, "error", err)
+	}
+	if sendErr != nil {
+		a.logger.WarnContext(ctx, "Failed to deliver message", "message_id", snapshot.ID, "channel", channel, "error", sendErr)
+		failed := audit.EmailFailed
+		if channel == deliveryWebhook {
+			failed = audit.MessageFailed
+		}
+		a.recordAudit(ctx, msg, failed, snapshot.ID, map[string]interface{}{
+			"contact_id": contact.ID,
+			"error":      sendErr.Error(),
+		})
+		return snapshot, sendErr
+	}
+
+	if err := a.saveContact(ctx, &contactSnapshot); err != nil {
+		a.logger.WarnContext(ctx, "Failed to save contact", "contact_id", contactSnapshot.ID, "error", err)
+	}
+	if msg != nil {
+		verb := "emailed"
+		if channel == deliveryWebhook {
+			verb = "messaged"
+		}
+		a.recordShared(ctx, msg, memory.BlackboardEntry{
+			Section: memory.BlackboardFacts,
+			Key:     "communication_message:" + snapshot.ID,
+			Value:   fmt.Sprintf("The user %s %s about '%s' on %s", verb, contact.Name, snapshot.Subject, receipt.At.Format("2006-01-02")),
+		})
+	}
+	if channel == deliveryWebhook {
+		a.recordAudit(ctx, msg, audit.MessageSent, snapshot.ID, map[string]interface{}{
+			"contact_id": contact.ID,
+			"channel":    channel,
+		})
+		return snapshot, nil
+	}
+	a.recordAudit(ctx, msg, audit.EmailSent, snapshot.ID, map[string]interface{}{
+		"contact_id":      contact.ID,
+		"smtp_message_id": receipt.MessageID,
+		"server":          receipt.Server,
+	})
+	return snapshot, nil
+}
+
+// findDraft picks the draft a send request means: the one it names by ID,
+// else the latest to the contact it names, else the latest one. A
+// confirmation without either only matches a draft shown ready to send.
+func (a *CommunicationManagerAgent) findDraft(ctx context.Context, content string, confirm bool) (*CommunicationMessage, *Contact) {
+	a.commMutex.RLock()
+	defer a.commMutex.RUnlock()
+
+	sendable := func(message *CommunicationMessage) bool {
+		return ownedBy(ctx, message.UserID) && message.Direction == MessageDirectionOutbound
+	}
+	if id := draftIDPhrase.FindString(content); id != "" {
+		message, ok := a.messages[id]
+		if !ok || !sendable(message) {
+			return nil, nil
+		}
+		return message, a.contacts[message.ContactID]
+	}
+
+	name := ""
+	if match := draftToPhrase.FindStringSubmatch(content); match != nil {
+		name = strings.ToLower(strings.TrimSpace(match[1]))
+	}
+	var latest *CommunicationMessage
+	for _, message := range a.messages {
+		if !sendable(message) || (message.Status != MessageStatusDraft && message.Status != MessageStatusFailed) {
+			continue
+		}
+		if confirm && message.Metadata[sendRequestedKey] == nil {
+			continue
+		}
+		if name != "" {
+			contact, ok := a.contacts[message.ContactID]
+			if !ok || !strings.Contains(strings.ToLower(contact.Name), name) {
+				continue
+			}
+		}
+		if latest == nil || message.UpdatedAt.After(latest.UpdatedAt) {
+			latest = message
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	return latest, a.contacts[latest.ContactID]
+}
+
+// saveMessage writes a message to memory
+func (a *CommunicationManagerAgent) saveMessage(ctx context.Context, message *CommunicationMessage) error {
+	if a.memoryStore == nil {
+		return nil
+	}
+	if err := a.memoryStore.Store(ctx, fmt.Sprintf("communication_message:%s", message.ID), message); err != nil {
+		return fmt.Errorf("failed to save message %s: %w", message.ID, err)
+	}
+	return nil
+}
+
+// loadMessagesFromMemory reads the user's messages into the agent
+func (a *CommunicationManagerAgent) loadMessagesFromMemory(ctx context.Context) {
+	if a.memoryStore == nil {
+		return
+	}
+	keys, err := a.memoryStore.List(ctx, "communication_message:", 1000)
+	if err != nil {
+		return
+	}
+	values, err := a.memoryStore.GetMultiple(ctx, keys)
+	if err != nil {
+		return
+	}
+
+	a.commMutex.Lock()
+	defer a.commMutex.Unlock()
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		var message CommunicationMessage
+		if err := json.Unmarshal(data, &message); err != nil || message.ID == "" {
+			continue
+		}
+		if message.Metadata == nil {
+			message.Metadata = make(map[string]interface{})
+		}
+		message.UserID = multiagent.UserIDFromContext(ctx)
+		a.messages[message.ID] = &message
+	}
+}
@@ -0,0 +1,599 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+	"github.com/kbutz/wikillm/multiagent/reminders"
+)
+
+// followUpPrefix is the memory key prefix follow-ups are stored under
+const followUpPrefix = "follow_up:"
+
+// followUpWaitDays is how long a reply is waited for before the user is
+// reminded, unless they say when
+const followUpWaitDays = 3
+
+// Follow-up commands a user can give
+const (
+	followUpTrack = "track"
+	followUpList  = "list"
+	followUpClose = "close"
+)
+
+// FollowUpStatus is whether a reply is still awaited
+type FollowUpStatus string
+
+const (
+	FollowUpStatusOpen   FollowUpStatus = "open"   // Still waiting on a reply
+	FollowUpStatusClosed FollowUpStatus = "closed" // Replied to, or closed by the user
+)
+
+// FollowUp is a reply the user is waiting on from a contact
+type FollowUp struct {
+	ID          string         `json:"id"`
+	ContactID   string         `json:"contact_id,omitempty"`
+	ContactName string         `json:"contact_name"`
+	Subject     string         `json:"subject,omitempty"`
+	MessageID   string         `json:"message_id,omitempty"` // The outbound message awaiting a reply, if known
+	Since       time.Time      `json:"since"`
+	RemindAt    time.Time      `json:"remind_at"`
+	Status      FollowUpStatus `json:"status"`
+	ClosedAt    *time.Time     `json:"closed_at,omitempty"`
+	ReplyID     string         `json:"reply_id,omitempty"` // The inbound message that closed it
+	CreatedAt   time.Time      `json:"created_at"`
+	UserID      string         `json:"user_id,omitempty"`
+}
+
+var (
+	// waitingPhrase matches "waiting on a reply from Bob about the proposal
+	// since Monday, remind me in 3 days" when the LLM can't read the request
+	waitingPhrase = regexp.MustCompile(`(?i)\bwaiting\s+(?:on|for)\s+(?:an?\s+)?(?:reply|response|answer|word|hear\s+back)?\s*(?:from\s+)?(.+?)(?:\s+(?:about|regarding|re)\s+(.+?))?(?:\s+since\s+(.+?))?(?:\s*,?\s+(?:and\s+)?remind\s+me\s+(.+?))?[.!]*$`)
+	// followUpWithPhrase matches "follow up with Bob about the proposal on
+	// Friday"
+	followUpWithPhrase = regexp.MustCompile(`(?i)^(?:remind\s+me\s+to\s+)?follow[\s-]*up\s+with\s+(.+?)(?:\s+(?:about|regarding|re)\s+(.+?))?(?:\s+((?:in|on|by|next|tomorrow|this)\b.*?))?[.!]*$`)
+	// followUpClosePhrase matches "close the follow-up with Bob"
+	followUpClosePhrase = regexp.MustCompile(`(?i)^(?:close|cancel|drop|stop|done\s+with)\s+(?:the\s+|my\s+)?follow[\s-]*ups?\s+(?:with|for|on|from)\s+(.+?)[.!]*$`)
+	// repliedPhrase matches "Bob replied about the proposal"
+	repliedPhrase = regexp.MustCompile(`(?i)^(.+?)\s+(?:replied|responded|answered|wrote\s+back|got\s+back\s+to\s+me)(?:\s+(?:about|regarding|re|to)\s+(.+?))?[.!]*$`)
+	// receivedPhrase matches "got a reply from Bob about the proposal: ..."
+	// and "heard back from Bob"
+	receivedPhrase = regexp.MustCompile(`(?is)\b(?:got|received|heard\s+back|log(?:ged)?)\s+(?:an?\s+)?(?:reply|response|message|email|answer|text|call)?\s*from\s+(.+?)(?:\s+(?:about|regarding|re)\s+(.+?))?(?:\s*:\s*(.+?))?[.!]*$`)
+)
+
+// followUpReminderID names the reminder of a follow-up in the engine
+func followUpReminderID(followUp *FollowUp) string {
+	return "follow_up_" + followUp.ID
+}
+
+// isStale reports whether the reply is overdue at now: its reminder is
+// due, or it has been awaited longer than followUpWaitDays
+func (f *FollowUp) isStale(now time.Time) bool {
+	return f.Status == FollowUpStatusOpen && (!now.Before(f.RemindAt) || f.daysWaiting(now) >= followUpWaitDays)
+}
+
+// daysWaiting is how many whole days the user has waited at now
+func (f *FollowUp) daysWaiting(now time.Time) int {
+	return max(int(math.Floor(now.Sub(f.Since).Hours()/24)), 0)
+}
+
+// about names what a follow-up is waiting on, for messages
+func (f *FollowUp) about() string {
+	if f.Subject == "" {
+		return f.ContactName
+	}
+	return fmt.Sprintf("%s about %s", f.ContactName, f.Subject)
+}
+
+// matchesContact reports whether a message from the contact with ID
+// contactID, called name, answers the follow-up
+func (f *FollowUp) matchesContact(contactID, name string) bool {
+	if contactID != "" && f.ContactID == contactID {
+		return true
+	}
+	name, other := strings.ToLower(strings.TrimSpace(name)), strings.ToLower(f.ContactName)
+	return name != "" && other != "" && (strings.Contains(other, name) || strings.Contains(name, other))
+}
+
+// handleFollowUp tracks replies the user is waiting on: it starts a
+// follow-up with a reminder, lists open and stale ones, or closes one
+func (a *CommunicationManagerAgent) handleFollowUp(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadContactsFromMemory(ctx)
+	a.loadFollowUpsFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now().In(loc)
+
+	prompt, err := a.renderPrompt(ctx, "communication.follow_up", prompts.Vars{
+		"Request":  msg.Content,
+		"Now":      now.Format("2006-01-02 15:04 (Monday)"),
+		"Timezone": loc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Action   string `json:"action"`
+		Contact  string `json:"contact"`
+		Subject  string `json:"subject"`
+		Since    string `json:"since"`
+		RemindAt string `json:"remind_at"`
+	}
+	followUpSchema := objectSchema(map[string]string{
+		"action":    "string",
+		"contact":   "string",
+		"subject":   "string",
+		"since":     "string",
+		"remind_at": "string",
+	}, "action")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, followUpSchema, &data); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse follow-up request", "error", err)
+		content := strings.TrimSpace(msg.Content)
+		if match := followUpClosePhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.Contact = followUpClose, match[1]
+		} else if match := waitingPhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.Contact, data.Subject, data.Since, data.RemindAt = followUpTrack, match[1], match[2], match[3], match[4]
+		} else if match := followUpWithPhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.Contact, data.Subject, data.RemindAt = followUpTrack, match[1], match[2], match[3]
+		} else {
+			data.Action = followUpList
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(data.Action)) {
+	case followUpTrack:
+		return a.trackFollowUp(ctx, msg, data.Contact, data.Subject, data.Since, data.RemindAt, now)
+	case followUpClose:
+		return a.closeFollowUp(ctx, msg, data.Contact, now)
+	default:
+		return a.listFollowUps(ctx, msg, now)
+	}
+}
+
+// trackFollowUp starts waiting on a reply from contact, reminding the user
+// at remindAt or followUpWaitDays after since
+func (a *CommunicationManagerAgent) trackFollowUp(ctx context.Context, msg *multiagent.Message, name, subject, since, remindAt string, now time.Time) (*multiagent.Message, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return a.respond(msg, "🔄 Who are you waiting to hear from? Say something like \"waiting on a reply from Bob about the proposal since Monday\".", nil), nil
+	}
+
+	followUp := &FollowUp{
+		ID:          a.newID("follow_up"),
+		ContactName: name,
+		Subject:     strings.TrimSpace(subject),
+		Since:       now,
+		Status:      FollowUpStatusOpen,
+		CreatedAt:   now,
+		UserID:      multiagent.UserIDFromContext(ctx),
+	}
+	if contact := a.findContactByName(ctx, name); contact != nil {
+		followUp.ContactID, followUp.ContactName = contact.ID, contact.Name
+		followUp.MessageID = a.lastMessageTo(ctx, contact.ID)
+	}
+	if strings.TrimSpace(since) != "" {
+		if day, err := resolveDate(since, now); err == nil {
+			if day.After(now) && day.Before(now.AddDate(0, 0, 7)) {
+				// "since Monday" means the last Monday, not the next
+				day = day.AddDate(0, 0, -7)
+			}
+			if day.Before(now) {
+				followUp.Since = day
+			}
+		}
+	}
+	followUp.RemindAt = startOfDay(followUp.Since).AddDate(0, 0, followUpWaitDays).Add(defaultHour * time.Hour)
+	if strings.TrimSpace(remindAt) != "" {
+		if at, err := resolveTime(remindAt, "", now); err == nil {
+			followUp.RemindAt = at
+		}
+	}
+	if !followUp.RemindAt.After(now) {
+		// Already overdue; nudge soon rather than never
+		followUp.RemindAt = now.Add(time.Hour)
+	}
+
+	a.commMutex.Lock()
+	a.followUps[followUp.ID] = followUp
+	a.commMutex.Unlock()
+	if err := a.saveFollowUp(ctx, followUp); err != nil {
+		return nil, err
+	}
+	a.scheduleFollowUpReminder(ctx, followUp)
+
+	a.recordShared(ctx, msg, memory.BlackboardEntry{
+		Section: memory.BlackboardFacts,
+		Key:     "follow_up:" + followUp.ID,
+		Value:   fmt.Sprintf("The user is waiting on a reply from %s since %s", followUp.about(), followUp.Since.Format("2006-01-02")),
+	})
+	a.recordAudit(ctx, msg, audit.FollowUpOpened, followUp.ID, map[string]interface{}{
+		"contact_id": followUp.ContactID,
+		"contact":    followUp.ContactName,
+		"subject":    followUp.Subject,
+		"remind_at":  followUp.RemindAt,
+	})
+
+	content := fmt.Sprintf("🔄 Waiting on a reply from %s since %s.\n⏰ I'll remind you %s if you haven't heard back; logging their reply closes this.",
+		followUp.about(), followUp.Since.Format("Mon Jan 2"), followUp.RemindAt.Format("Mon Jan 2 15:04"))
+	return a.respond(msg, content, map[string]interface{}{
+		"follow_up_id": followUp.ID,
+		"contact_id":   followUp.ContactID,
+		"action":       "follow_up_tracked",
+	}), nil
+}
+
+// listFollowUps lists the user's open follow-ups, longest waiting first,
+// flagging the stale ones
+func (a *CommunicationManagerAgent) listFollowUps(ctx context.Context, msg *multiagent.Message, now time.Time) (*multiagent.Message, error) {
+	staleOnly := strings.Contains(strings.ToLower(msg.Content), "stale") || strings.Contains(strings.ToLower(msg.Content), "overdue")
+
+	a.commMutex.RLock()
+	var open []FollowUp
+	for _, followUp := range a.followUps {
+		if ownedBy(ctx, followUp.UserID) && followUp.Status == FollowUpStatusOpen && (!staleOnly || followUp.isStale(now)) {
+			open = append(open, *followUp)
+		}
+	}
+	a.commMutex.RUnlock()
+	sort.Slice(open, func(i, j int) bool { return open[i].Since.Before(open[j].Since) })
+
+	if len(open) == 0 {
+		if staleOnly {
+			return a.respond(msg, "✅ No stale follow-ups — nobody owes you a reply past its reminder.", nil), nil
+		}
+		return a.respond(msg, "✅ You're not waiting on any replies. Say \"waiting on a reply from Bob about the proposal\" to track one.", nil), nil
+	}
+
+	var b strings.Builder
+	stale := 0
+	for _, followUp := range open {
+		icon := "⏳"
+		if followUp.isStale(now) {
+			icon = "⚠️"
+			stale++
+		}
+		b.WriteString(fmt.Sprintf("%s %s — waiting %d day(s), since %s", icon, followUp.about(), followUp.daysWaiting(now), followUp.Since.In(now.Location()).Format("Mon Jan 2")))
+		if !followUp.isStale(now) {
+			b.WriteString(fmt.Sprintf(", reminder %s", followUp.RemindAt.In(now.Location()).Format("Mon Jan 2 15:04")))
+		}
+		b.WriteString("\n")
+	}
+	header := fmt.Sprintf("🔄 **Follow-ups** (%d open, %d stale)\n\n", len(open), stale)
+	if staleOnly {
+		header = fmt.Sprintf("⚠️ **Stale Follow-ups** (%d)\n\n", stale)
+	}
+	return a.respond(msg, header+b.String(), map[string]interface{}{
+		"action": "follow_ups_listed",
+		"open":   len(open),
+		"stale":  stale,
+	}), nil
+}
+
+// closeFollowUp stops waiting on replies from the contact name names
+func (a *CommunicationManagerAgent) closeFollowUp(ctx context.Context, msg *multiagent.Message, name string, now time.Time) (*multiagent.Message, error) {
+	contactID := ""
+	if contact := a.findContactByName(ctx, name); contact != nil {
+		contactID, name = contact.ID, contact.Name
+	}
+	closed := a.closeFollowUps(ctx, msg, contactID, name, "", now)
+	if len(closed) == 0 {
+		return a.respond(msg, fmt.Sprintf("❓ You're not waiting on a reply from %s.", name), nil), nil
+	}
+	return a.respond(msg, fmt.Sprintf("✅ Stopped waiting on %s.", strings.Join(closed, "; ")), map[string]interface{}{
+		"action": "follow_up_closed",
+		"closed": closed,
+	}), nil
+}
+
+// handleLogInbound records a message the user received from a contact,
+// closing the follow-ups that waited on it
+func (a *CommunicationManagerAgent) handleLogInbound(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadContactsFromMemory(ctx)
+	a.loadFollowUpsFromMemory(ctx)
+
+	prompt, err := a.renderPrompt(ctx, "communication.log_inbound", prompts.Vars{"Request": msg.Content})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Contact string `json:"contact"`
+		Subject string `json:"subject"`
+		Content string `json:"content"`
+		Method  string `json:"method"`
+	}
+	inboundSchema := objectSchema(map[string]string{
+		"contact": "string",
+		"subject": "string",
+		"content": "string",
+		"method":  "string",
+	}, "contact")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, inboundSchema, &data); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse inbound message", "error", err)
+		content := strings.TrimSpace(msg.Content)
+		if match := receivedPhrase.FindStringSubmatch(content); match != nil {
+			data.Contact, data.Subject, data.Content = match[1], match[2], match[3]
+		} else if match := repliedPhrase.FindStringSubmatch(content); match != nil {
+			data.Contact, data.Subject = match[1], match[2]
+		}
+	}
+	if strings.TrimSpace(data.Contact) == "" {
+		return a.respond(msg, "📥 Who was the message from? Say something like \"Bob replied about the proposal\".", nil), nil
+	}
+
+	message, closed, err := a.logInboundMessage(ctx, msg, inboundMessage{
+		From:       data.Contact,
+		Subject:    data.Subject,
+		Content:    data.Content,
+		Method:     CommunicationMethod(data.Method),
+		ReceivedAt: a.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	content := fmt.Sprintf("📥 Logged a message from %s.", message.Metadata["from"])
+	if len(closed) > 0 {
+		content += fmt.Sprintf("\n✅ Closed follow-up(s): %s", strings.Join(closed, "; "))
+	}
+	return a.respond(msg, content, map[string]interface{}{
+		"message_id": message.ID,
+		"action":     "message_logged",
+		"closed":     closed,
+	}), nil
+}
+
+// inboundMessage is a message the user received, as logged
+type inboundMessage struct {
+	From       string // The sender's name, or part of it
+	Address    string // The sender's email address, if known
+	ContactID  string // The contact, when the caller already knows them
+	ParentID   string // The message this replies to, if known
+	Subject    string
+	Content    string
+	Method     CommunicationMethod
+	ReceivedAt time.Time
+	Metadata   map[string]interface{}
+}
+
+// logInboundMessage records a message received from a contact, matched by
+// address or else by name, and closes the follow-ups waiting on them,
+// returning the message and what was closed
+func (a *CommunicationManagerAgent) logInboundMessage(ctx context.Context, msg *multiagent.Message, inbound inboundMessage) (*CommunicationMessage, []string, error) {
+	now := a.now()
+	contactID, name := "", strings.TrimSpace(inbound.From)
+	if name == "" {
+		name = inbound.Address
+	}
+	a.commMutex.Lock()
+	contact := a.contacts[inbound.ContactID]
+	if contact == nil || !ownedBy(ctx, contact.UserID) {
+		contact = a.contactFrom(ctx, inbound.From, inbound.Address)
+	}
+	var contactSnapshot Contact
+	if contact != nil {
+		contactID, name = contact.ID, contact.Name
+		if contact.LastContact == nil || inbound.ReceivedAt.After(*contact.LastContact) {
+			contact.LastContact = &inbound.ReceivedAt
+		}
+		contact.UpdatedAt = now
+		contactSnapshot = *contact
+		if inbound.Method == "" {
+			inbound.Method = contact.PreferredComm
+		}
+	}
+	metadata := map[string]interface{}{"from": name}
+	for key, value := range inbound.Metadata {
+		metadata[key] = value
+	}
+	message := &CommunicationMessage{
+		ID:         a.newID("msg"),
+		ContactID:  contactID,
+		Subject:    inbound.Subject,
+		Content:    inbound.Content,
+		Method:     inbound.Method,
+		Direction:  MessageDirectionInbound,
+		Status:     MessageStatusRead,
+		Priority:   multiagent.PriorityMedium,
+		ReceivedAt: &inbound.ReceivedAt,
+		Tags:       []string{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Metadata:   metadata,
+		UserID:     multiagent.UserIDFromContext(ctx),
+	}
+	if parent, ok := a.messages[inbound.ParentID]; ok {
+		message.ParentID = parent.ID
+		message.ThreadID = parent.ThreadID
+		if message.ThreadID == "" {
+			message.ThreadID = parent.ID
+		}
+	}
+	a.messages[message.ID] = message
+	a.commMutex.Unlock()
+
+	if a.memoryStore != nil {
+		if err := a.memoryStore.Store(ctx, fmt.Sprintf("communication_message:%s", message.ID), message); err != nil {
+			return nil, nil, fmt.Errorf("failed to save message %s: %w", message.ID, err)
+		}
+		if contactID != "" {
+			a.memoryStore.Store(ctx, fmt.Sprintf("contact:%s", contactID), &contactSnapshot)
+		}
+	}
+	a.recordAudit(ctx, msg, audit.MessageReceived, message.ID, map[string]interface{}{
+		"contact_id": contactID,
+		"from":       name,
+		"subject":    inbound.Subject,
+		"method":     inbound.Method,
+	})
+	return message, a.closeFollowUps(ctx, msg, contactID, name, message.ID, now), nil
+}
+
+// contactFrom returns the user's contact with the address, or else the
+// first whose name contains name; the caller holds commMutex
+func (a *CommunicationManagerAgent) contactFrom(ctx context.Context, name, address string) *Contact {
+	address = strings.ToLower(strings.TrimSpace(address))
+	name = strings.ToLower(strings.TrimSpace(name))
+	var byName *Contact
+	for _, contact := range a.contacts {
+		if !ownedBy(ctx, contact.UserID) {
+			continue
+		}
+		if address != "" && strings.EqualFold(strings.TrimSpace(contact.Email), address) {
+			return contact
+		}
+		if byName == nil && name != "" && strings.Contains(strings.ToLower(contact.Name), name) {
+			byName = contact
+		}
+	}
+	return byName
+}
+
+// closeFollowUps closes the user's open follow-ups with a contact, by ID or
+// name, cancelling their reminders, and returns what they were about
+func (a *CommunicationManagerAgent) closeFollowUps(ctx context.Context, msg *multiagent.Message, contactID, name, replyID string, now time.Time) []string {
+	a.commMutex.Lock()
+	var closed []FollowUp
+	for _, followUp := range a.followUps {
+		if !ownedBy(ctx, followUp.UserID) || followUp.Status != FollowUpStatusOpen || !followUp.matchesContact(contactID, name) {
+			continue
+		}
+		followUp.Status = FollowUpStatusClosed
+		followUp.ClosedAt = &now
+		followUp.ReplyID = replyID
+		closed = append(closed, *followUp)
+	}
+	a.commMutex.Unlock()
+
+	var about []string
+	for i := range closed {
+		followUp := &closed[i]
+		if err := a.saveFollowUp(ctx, followUp); err != nil {
+			a.logger.WarnContext(ctx, "Failed to save follow-up", "follow_up_id", followUp.ID, "error", err)
+		}
+		if err := a.reminderEngine.Cancel(ctx, followUpReminderID(followUp)); err != nil && err != reminders.ErrNotFound {
+			a.logger.WarnContext(ctx, "Failed to cancel follow-up reminder", "follow_up_id", followUp.ID, "error", err)
+		}
+		a.recordAudit(ctx, msg, audit.FollowUpClosed, followUp.ID, map[string]interface{}{
+			"contact_id": followUp.ContactID,
+			"reply_id":   replyID,
+			"days":       followUp.daysWaiting(now),
+		})
+		about = append(about, followUp.about())
+	}
+	sort.Strings(about)
+	return about
+}
+
+// lastMessageTo returns the ID of the latest message sent or drafted to
+// the contact, or ""
+func (a *CommunicationManagerAgent) lastMessageTo(ctx context.Context, contactID string) string {
+	a.commMutex.RLock()
+	defer a.commMutex.RUnlock()
+	var last *CommunicationMessage
+	for _, message := range a.messages {
+		if ownedBy(ctx, message.UserID) && message.ContactID == contactID && message.Direction == MessageDirectionOutbound &&
+			(last == nil || message.CreatedAt.After(last.CreatedAt)) {
+			last = message
+		}
+	}
+	if last == nil {
+		return ""
+	}
+	return last.ID
+}
+
+// scheduleFollowUpReminder has the engine remind the user when the reply
+// is overdue
+func (a *CommunicationManagerAgent) scheduleFollowUpReminder(ctx context.Context, followUp *FollowUp) {
+	err := a.reminderEngine.Schedule(ctx, reminders.Reminder{
+		ID:        followUpReminderID(followUp),
+		UserID:    followUp.UserID,
+		Source:    followUpReminderSource,
+		Subject:   followUp.ID,
+		Title:     "🔄 Follow up with " + followUp.ContactName,
+		Kind:      notify.KindFollowUp,
+		Priority:  multiagent.PriorityMedium,
+		TriggerAt: followUp.RemindAt,
+		Data:      map[string]string{"follow_up_id": followUp.ID},
+	})
+	if err != nil {
+		a.logger.WarnContext(ctx, "Failed to schedule follow-up reminder", "follow_up_id", followUp.ID, "error", err)
+	}
+}
+
+// fireFollowUpReminder is the engine's handler for follow-up reminders: it
+// stays silent once the reply has come
+func (a *CommunicationManagerAgent) fireFollowUpReminder(ctx context.Context, scheduled reminders.Reminder, now time.Time) (*notify.Notification, time.Time) {
+	a.loadFollowUpsFromMemory(ctx)
+	a.commMutex.RLock()
+	followUp, ok := a.followUps[scheduled.Data["follow_up_id"]]
+	if !ok || followUp.Status != FollowUpStatusOpen {
+		a.commMutex.RUnlock()
+		return nil, time.Time{}
+	}
+	snapshot := *followUp
+	a.commMutex.RUnlock()
+
+	loc := userLocation(ctx, a.memoryStore)
+	body := fmt.Sprintf("No reply from %s since %s (%d day(s)).", snapshot.about(), snapshot.Since.In(loc).Format("Mon Jan 2"), snapshot.daysWaiting(now))
+	return &notify.Notification{
+		Kind:     notify.KindFollowUp,
+		Title:    scheduled.Title,
+		Body:     body,
+		Priority: scheduled.Priority,
+		At:       now,
+		Subject:  snapshot.ID,
+	}, time.Time{}
+}
+
+// saveFollowUp writes a follow-up to memory
+func (a *CommunicationManagerAgent) saveFollowUp(ctx context.Context, followUp *FollowUp) error {
+	if a.memoryStore == nil {
+		return nil
+	}
+	if err := a.memoryStore.Store(ownerContext(ctx, followUp.UserID), followUpPrefix+followUp.ID, followUp); err != nil {
+		return fmt.Errorf("failed to save follow-up %s: %w", followUp.ID, err)
+	}
+	return nil
+}
+
+// loadFollowUpsFromMemory reads the user's follow-ups into the agent
+func (a *CommunicationManagerAgent) loadFollowUpsFromMemory(ctx context.Context) {
+	if a.memoryStore == nil {
+		return
+	}
+	keys, err := a.memoryStore.List(ctx, followUpPrefix, 1000)
+	if err != nil {
+		return
+	}
+	values, err := a.memoryStore.GetMultiple(ctx, keys)
+	if err != nil {
+		return
+	}
+
+	a.commMutex.Lock()
+	defer a.commMutex.Unlock()
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		var followUp FollowUp
+		if err := json.Unmarshal(data, &followUp); err != nil || followUp.ID == "" {
+			continue
+		}
+		followUp.UserID = multiagent.UserIDFromContext(ctx)
+		a.followUps[followUp.ID] = &followUp
+	}
+}
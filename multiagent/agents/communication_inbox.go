@@ -0,0 +1,109 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/email"
+)
+
+// ReplyReceivedTopic is published on when email from a contact, or a reply
+// to a message the user sent, arrives in their mailbox
+const ReplyReceivedTopic = "communication.reply_received"
+
+// LogInboundEmail implements email.Inbox: mail from one of the user's
+// contacts, or replying to a message they sent, is logged as an inbound
+// message, updating the contact's last contact and closing the follow-ups
+// waiting on them. Other mail, and mail already logged, is left alone.
+func (a *CommunicationManagerAgent) LogInboundEmail(ctx context.Context, inbound email.InboundEmail) (bool, error) {
+	a.loadContactsFromMemory(ctx)
+	a.loadMessagesFromMemory(ctx)
+	a.loadFollowUpsFromMemory(ctx)
+
+	a.commMutex.RLock()
+	contactID, parentID := "", ""
+	if contact := a.contactFrom(ctx, "", inbound.FromAddr); contact != nil {
+		contactID = contact.ID
+	}
+	for _, message := range a.messages {
+		if !ownedBy(ctx, message.UserID) {
+			continue
+		}
+		if inbound.MessageID != "" && message.Metadata["email_message_id"] == inbound.MessageID {
+			a.commMutex.RUnlock()
+			return false, nil
+		}
+		if message.Direction != MessageDirectionOutbound {
+			continue
+		}
+		sentID, _ := message.Metadata["smtp_message_id"].(string)
+		for _, reference := range inbound.InReplyTo {
+			if sentID != "" && reference == sentID {
+				parentID = message.ID
+				if contactID == "" {
+					contactID = message.ContactID
+				}
+			}
+		}
+	}
+	a.commMutex.RUnlock()
+	if contactID == "" && parentID == "" {
+		return false, nil
+	}
+
+	from := inbound.FromName
+	if from == "" {
+		from = inbound.FromAddr
+	}
+	message, closed, err := a.logInboundMessage(ctx, nil, inboundMessage{
+		From:       from,
+		Address:    inbound.FromAddr,
+		ContactID:  contactID,
+		ParentID:   parentID,
+		Subject:    inbound.Subject,
+		Content:    inbound.Body,
+		Method:     CommunicationMethodEmail,
+		ReceivedAt: inbound.Date,
+		Metadata: map[string]interface{}{
+			"email_message_id": inbound.MessageID,
+			"from_address":     inbound.FromAddr,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	a.publishReplyReceived(ctx, message, closed)
+	return true, nil
+}
+
+// publishReplyReceived tells the other agents a reply arrived, e.g. "reply
+// received from Jane"
+func (a *CommunicationManagerAgent) publishReplyReceived(ctx context.Context, message *CommunicationMessage, closed []string) {
+	if a.orchestrator == nil {
+		return
+	}
+	content := fmt.Sprintf("reply received from %s", message.Metadata["from"])
+	if message.Subject != "" {
+		content += fmt.Sprintf(": %s", message.Subject)
+	}
+	if len(closed) > 0 {
+		content += fmt.Sprintf(" (closed follow-ups: %s)", strings.Join(closed, "; "))
+	}
+	msg := &multiagent.Message{
+		ID:      a.messageID(),
+		Type:    multiagent.MessageTypeNotification,
+		Content: content,
+		Context: map[string]interface{}{
+			multiagent.ContextUserID: multiagent.UserIDFromContext(ctx),
+			"contact_id":             message.ContactID,
+			"message_id":             message.ID,
+			"parent_id":              message.ParentID,
+			"closed":                 closed,
+		},
+	}
+	if _, err := a.Publish(ctx, ReplyReceivedTopic, msg); err != nil {
+		a.logger.WarnContext(ctx, "Failed to publish reply", "message_id", message.ID, "error", err)
+	}
+}
@@ -0,0 +1,147 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+)
+
+// inviteAttachment names the invitation attached to drafted invite emails
+const inviteAttachment = "invite.ics"
+
+// handleDraftInvite drafts an invitation email to each attendee of an event
+// the scheduler booked, carrying its iCalendar invitation
+func (a *CommunicationManagerAgent) handleDraftInvite(ctx context.Context, msg *multiagent.Message, invite *EventInvite) (*multiagent.Message, error) {
+	var drafted, missing []string
+	var messageIDs []string
+	for _, attendee := range invite.Attendees {
+		contact := a.findContactForAttendee(ctx, attendee)
+		email, name, contactID := attendee.Email, attendee.Name, ""
+		if contact != nil {
+			contactID = contact.ID
+			if email == "" {
+				email = contact.Email
+			}
+			if name == "" || name == email {
+				name = contact.Name
+			}
+		}
+		if email == "" {
+			missing = append(missing, name)
+			continue
+		}
+
+		message := &CommunicationMessage{
+			ID:          a.newID("msg"),
+			ContactID:   contactID,
+			Subject:     fmt.Sprintf("Invitation: %s @ %s", invite.Title, invite.When),
+			Content:     inviteBody(name, invite),
+			Method:      CommunicationMethodEmail,
+			Direction:   MessageDirectionOutbound,
+			Status:      MessageStatusDraft,
+			Priority:    multiagent.PriorityMedium,
+			Tags:        []string{"meeting", "invite"},
+			Attachments: []string{inviteAttachment},
+			CreatedAt:   a.now(),
+			UpdatedAt:   a.now(),
+			Metadata: map[string]interface{}{
+				"to":             email,
+				"event_id":       invite.EventID,
+				"attachment_ics": invite.ICS,
+			},
+			UserID: multiagent.UserIDFromContext(ctx),
+		}
+
+		a.commMutex.Lock()
+		a.messages[message.ID] = message
+		a.commMutex.Unlock()
+		if a.memoryStore != nil {
+			a.memoryStore.Store(ctx, fmt.Sprintf("communication_message:%s", message.ID), message)
+		}
+		a.recordAudit(ctx, msg, audit.MessageDrafted, message.ID, map[string]interface{}{
+			"contact_id": contactID,
+			"subject":    message.Subject,
+			"method":     message.Method,
+		})
+
+		recipient := email
+		if name != "" && name != email {
+			recipient = fmt.Sprintf("%s <%s>", name, email)
+		}
+		drafted = append(drafted, recipient)
+		messageIDs = append(messageIDs, message.ID)
+	}
+
+	var reply strings.Builder
+	if len(drafted) > 0 {
+		fmt.Fprintf(&reply, "✉️ **Invitations Drafted** with %s attached:\n", inviteAttachment)
+		for _, recipient := range drafted {
+			fmt.Fprintf(&reply, "• %s\n", recipient)
+		}
+		reply.WriteString("\n*Saved as drafts for you to review and send.*")
+	}
+	if len(missing) > 0 {
+		if reply.Len() > 0 {
+			reply.WriteString("\n\n")
+		}
+		fmt.Fprintf(&reply, "⚠️ No email address for %s; add them as contacts to invite them.", strings.Join(missing, ", "))
+	}
+
+	return &multiagent.Message{
+		ID:        a.messageID(),
+		From:      a.id,
+		To:        []multiagent.AgentID{msg.From},
+		Type:      multiagent.MessageTypeResponse,
+		Content:   reply.String(),
+		ReplyTo:   msg.ID,
+		Timestamp: a.now(),
+		Context: map[string]interface{}{
+			"action":      "invites_drafted",
+			"message_ids": messageIDs,
+			"missing":     missing,
+		},
+	}, nil
+}
+
+// findContactForAttendee returns the contact with the attendee's email, or
+// else their name
+func (a *CommunicationManagerAgent) findContactForAttendee(ctx context.Context, attendee Attendee) *Contact {
+	if attendee.Email != "" {
+		a.commMutex.RLock()
+		for _, contact := range a.contacts {
+			if ownedBy(ctx, contact.UserID) && strings.EqualFold(contact.Email, attendee.Email) {
+				a.commMutex.RUnlock()
+				return contact
+			}
+		}
+		a.commMutex.RUnlock()
+	}
+	if attendee.Name == "" || attendee.Name == attendee.Email {
+		return nil
+	}
+	return a.findContactByName(ctx, attendee.Name)
+}
+
+// inviteBody writes the text of an invitation email
+func inviteBody(name string, invite *EventInvite) string {
+	var b strings.Builder
+	greeting := "Hi"
+	if fields := strings.Fields(name); len(fields) > 0 && !strings.Contains(name, "@") {
+		greeting += " " + fields[0]
+	}
+	fmt.Fprintf(&b, "%s,\n\nYou're invited to %s.\n\nWhen: %s\n", greeting, invite.Title, invite.When)
+	if invite.Location != "" {
+		fmt.Fprintf(&b, "Where: %s\n", invite.Location)
+	}
+	if invite.Details != "" {
+		fmt.Fprintf(&b, "\n%s\n", invite.Details)
+	}
+	b.WriteString("\nThe attached invitation adds it to your calendar. Please let me know if the time doesn't work for you.\n\nBest regards")
+	if invite.Organizer != "" {
+		fmt.Fprintf(&b, ",\n%s", invite.Organizer)
+	}
+	return b.String()
+}
@@ -4,13 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/email"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
 )
 
 // CommunicationManagerAgent specializes in managing communications, messages, and relationships
@@ -19,7 +22,13 @@ type CommunicationManagerAgent struct {
 	contacts  map[string]*Contact
 	messages  map[string]*CommunicationMessage
 	templates map[string]*MessageTemplate
+	followUps map[string]*FollowUp
+	mailer    email.Sender
 	commMutex sync.RWMutex
+	intents   *IntentRouter
+
+	// contactMerges are imported contacts awaiting a merge decision
+	contactMerges map[string]*ContactMerge
 }
 
 // Contact represents a person or entity in the communication system
@@ -34,6 +43,7 @@ type Contact struct {
 	Priority       ContactPriority        `json:"priority"`
 	PreferredComm  CommunicationMethod    `json:"preferred_communication"`
 	TimeZone       string                 `json:"time_zone"`
+	QuietHours     *QuietHours            `json:"quiet_hours,omitempty"`
 	Tags           []string               `json:"tags"`
 	Notes          string                 `json:"notes"`
 	SocialProfiles map[string]string      `json:"social_profiles"`
@@ -43,6 +53,7 @@ type Contact struct {
 	CreatedAt      time.Time              `json:"created_at"`
 	UpdatedAt      time.Time              `json:"updated_at"`
 	Metadata       map[string]interface{} `json:"metadata"`
+	UserID         string                 `json:"user_id,omitempty"`
 }
 
 // RelationshipType defines the type of relationship
@@ -126,6 +137,7 @@ type CommunicationMessage struct {
 	CreatedAt    time.Time              `json:"created_at"`
 	UpdatedAt    time.Time              `json:"updated_at"`
 	Metadata     map[string]interface{} `json:"metadata"`
+	UserID       string                 `json:"user_id,omitempty"`
 }
 
 // MessageDirection defines the direction of communication
@@ -163,6 +175,7 @@ type MessageTemplate struct {
 	CreatedAt  time.Time              `json:"created_at"`
 	UpdatedAt  time.Time              `json:"updated_at"`
 	Metadata   map[string]interface{} `json:"metadata"`
+	UserID     string                 `json:"user_id,omitempty"`
 }
 
 // TemplateCategory defines categories of message templates
@@ -219,13 +232,42 @@ func NewCommunicationManagerAgent(config BaseAgentConfig) *CommunicationManagerA
 		"social_media_coordination",
 		"communication_analytics",
 	)
+	ensureReminderEngine(&config)
 
-	return &CommunicationManagerAgent{
+	agent := &CommunicationManagerAgent{
 		BaseAgent: NewBaseAgent(config),
 		contacts:  make(map[string]*Contact),
 		messages:  make(map[string]*CommunicationMessage),
 		templates: make(map[string]*MessageTemplate),
-	}
+		followUps: make(map[string]*FollowUp),
+		mailer:    config.Email,
+		intents: NewIntentRouter(IntentRouterConfig{
+			Name:        "CommunicationManagerAgent",
+			LLMProvider: routingProvider(config),
+			Prompts:     config.Prompts,
+			Default:     "general",
+			Intents: []Intent{
+				{Label: "add_contact", Description: "save a new contact", Keywords: []string{"add contact", "new contact"}},
+				{Label: "import_contacts", Description: "import contacts from a vCard or CSV file", Keywords: []string{"import contact", "begin:vcard"}},
+				{Label: "export_contacts", Description: "export contacts as vCard or CSV", Keywords: []string{"export contact", "contacts&export", "contacts&vcard", "contacts&csv"}},
+				{Label: "contact_merges", Description: "review, merge, keep or skip duplicate contacts found while importing", Keywords: []string{"duplicate", "merge", "keep both"}},
+				{Label: "send_message", Description: "send a drafted message, or confirm sending it", Keywords: []string{"send draft", "send the draft", "send my draft", "confirm send", "send msg_"}},
+				{Label: "schedule_message", Description: "send a message or email later, or list, move, edit or cancel scheduled ones; a contact's quiet hours, timezone or webhook", Keywords: []string{"schedule message", "schedule email", "schedule draft", "schedule the", "schedule msg_", "reschedule msg_", "reschedule&message", "reschedule&email", "unschedule", "scheduled", "send later", "send queue", "quiet hours", "webhook"}},
+				{Label: "compose_message", Description: "write or send a message or email", Keywords: []string{"compose", "write message", "send message"}},
+				{Label: "relationships", Description: "how often to stay in touch with contacts, who is due a reconnect, or drafting outreach to them", Keywords: []string{"reconnect", "stay in touch", "keep in touch", "lost touch", "cadence", "relationship", "networking"}},
+				{Label: "templates", Description: "create or use message templates", Keywords: []string{"template"}},
+				{Label: "list_contacts", Description: "show contacts", Keywords: []string{"contacts"}},
+				{Label: "log_inbound", Description: "record a reply or message received from a contact", Keywords: []string{"replied", "responded", "got a reply", "heard back", "wrote back", "received&from"}},
+				{Label: "follow_up", Description: "replies the user is waiting on from contacts", Keywords: []string{"follow up", "follow-up", "followup", "waiting on", "waiting for"}},
+				{Label: "communication_stats", Description: "communication statistics", Keywords: []string{"communication stats", "comm stats"}},
+			},
+		}),
+		contactMerges: make(map[string]*ContactMerge),
+	}
+	agent.reminderEngine.Register(followUpReminderSource, agent.fireFollowUpReminder)
+	agent.reminderEngine.Register(reconnectReminderSource, agent.fireReconnectCheck)
+	agent.reminderEngine.Register(scheduledSendSource, agent.fireScheduledSend)
+	return agent
 }
 
 // HandleMessage processes incoming communication management requests
@@ -254,26 +296,41 @@ func (a *CommunicationManagerAgent) HandleMessage(ctx context.Context, msg *mult
 		return nil, nil // Return nil to prevent further response loops
 	}
 
-	content := strings.ToLower(msg.Content)
+	// The scheduler asks for invitations to the meetings it books
+	if invite, ok := decodeInvite(msg); ok {
+		a.loadContactsFromMemory(ctx)
+		return a.handleDraftInvite(ctx, msg, invite)
+	}
 
-	// Route to appropriate handler based on content
-	if strings.Contains(content, "add contact") || strings.Contains(content, "new contact") {
+	// Route to appropriate handler based on intent
+	switch a.intents.Classify(ctx, msg.Content).Label {
+	case "add_contact":
 		return a.handleAddContact(ctx, msg)
-	} else if strings.Contains(content, "compose") || strings.Contains(content, "write message") || strings.Contains(content, "send message") {
+	case "import_contacts":
+		return a.handleImportContacts(ctx, msg)
+	case "export_contacts":
+		return a.handleExportContacts(ctx, msg)
+	case "contact_merges":
+		return a.handleContactMerges(ctx, msg)
+	case "send_message":
+		return a.handleSendMessage(ctx, msg)
+	case "compose_message":
 		return a.handleComposeMessage(ctx, msg)
-	} else if strings.Contains(content, "template") {
+	case "templates":
 		return a.handleTemplateManagement(ctx, msg)
-	} else if strings.Contains(content, "contacts") || strings.Contains(content, "list contacts") {
+	case "list_contacts":
 		return a.handleListContacts(ctx, msg)
-	} else if strings.Contains(content, "follow up") || strings.Contains(content, "followup") {
+	case "log_inbound":
+		return a.handleLogInbound(ctx, msg)
+	case "follow_up":
 		return a.handleFollowUp(ctx, msg)
-	} else if strings.Contains(content, "schedule message") || strings.Contains(content, "schedule email") {
+	case "schedule_message":
 		return a.handleScheduleMessage(ctx, msg)
-	} else if strings.Contains(content, "communication stats") || strings.Contains(content, "comm stats") {
+	case "communication_stats":
 		return a.handleCommunicationStats(ctx, msg)
-	} else if strings.Contains(content, "relationship") || strings.Contains(content, "networking") {
+	case "relationships":
 		return a.handleRelationshipManagement(ctx, msg)
-	} else {
+	default:
 		// Use LLM for general communication queries
 		return a.handleGeneralQuery(ctx, msg)
 	}
@@ -282,28 +339,9 @@ func (a *CommunicationManagerAgent) HandleMessage(ctx context.Context, msg *mult
 // handleAddContact adds a new contact to the system
 func (a *CommunicationManagerAgent) handleAddContact(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Use LLM to extract contact details
-	contextPrompt := fmt.Sprintf(`
-Extract contact information from this request: "%s"
-
-Provide response in JSON format:
-{
-  "name": "contact name",
-  "email": "email address if mentioned",
-  "phone": "phone number if mentioned",
-  "organization": "company/organization if mentioned",
-  "title": "job title if mentioned",
-  "relationship": "family|friend|colleague|client|vendor|mentor|networking|professional",
-  "priority": "vip|high|medium|low",
-  "preferred_communication": "email|phone|text|slack|teams|linkedin|in_person",
-  "tags": ["tag1", "tag2"] if any categories mentioned,
-  "notes": "any additional notes or context"
-}
-
-Make reasonable assumptions for missing information.`, msg.Content)
-
-	response, err := a.llmProvider.Query(ctx, contextPrompt)
+	contextPrompt, err := a.renderPrompt(ctx, "communication.add_contact", prompts.Vars{"Request": msg.Content})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse contact details: %w", err)
+		return nil, err
 	}
 
 	var contactData struct {
@@ -319,13 +357,19 @@ Make reasonable assumptions for missing information.`, msg.Content)
 		Notes                  string   `json:"notes"`
 	}
 
-	if err := json.Unmarshal([]byte(response), &contactData); err != nil {
-		return nil, fmt.Errorf("failed to parse contact JSON: %w", err)
+	contactSchema := objectSchema(map[string]string{
+		"name":  "string",
+		"email": "string",
+		"phone": "string",
+		"tags":  "array",
+	}, "name")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+contextPrompt, contactSchema, &contactData); err != nil {
+		return nil, fmt.Errorf("failed to parse contact details: %w", err)
 	}
 
 	// Create contact
 	contact := &Contact{
-		ID:             fmt.Sprintf("contact_%d", time.Now().UnixNano()),
+		ID:             a.newID("contact"),
 		Name:           contactData.Name,
 		Email:          contactData.Email,
 		Phone:          contactData.Phone,
@@ -339,9 +383,10 @@ Make reasonable assumptions for missing information.`, msg.Content)
 		Status:         ContactStatusActive,
 		ContactFreq:    ContactFrequencyAsNeeded,
 		SocialProfiles: make(map[string]string),
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+		CreatedAt:      a.now(),
+		UpdatedAt:      a.now(),
 		Metadata:       make(map[string]interface{}),
+		UserID:         multiagent.UserIDFromContext(ctx),
 	}
 
 	// Store contact
@@ -355,14 +400,24 @@ Make reasonable assumptions for missing information.`, msg.Content)
 		a.memoryStore.Store(ctx, contactKey, contact)
 	}
 
+	contactEntity := "Contact: " + contact.Name
+	if contact.Email != "" {
+		contactEntity += fmt.Sprintf(" <%s>", contact.Email)
+	}
+	a.recordShared(ctx, msg, memory.BlackboardEntry{Section: memory.BlackboardEntities, Key: "contact:" + contact.ID, Value: contactEntity})
+	a.recordAudit(ctx, msg, audit.ContactAdded, contact.ID, map[string]interface{}{
+		"name":  contact.Name,
+		"email": contact.Email,
+	})
+
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   fmt.Sprintf("✅ **Contact Added Successfully!**\n\n👤 **%s**\n🏢 %s\n📧 %s\n📱 %s\n🔗 %s\n⚡ Priority: %s\n📞 Preferred: %s\n\nContact ID: %s", contact.Name, contact.Organization, contact.Email, contact.Phone, contact.Relationship, contact.Priority, contact.PreferredComm, contact.ID),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"contact_id": contact.ID,
 			"action":     "contact_added",
@@ -373,25 +428,9 @@ Make reasonable assumptions for missing information.`, msg.Content)
 // handleComposeMessage helps compose and send messages
 func (a *CommunicationManagerAgent) handleComposeMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Use LLM to extract message details
-	contextPrompt := fmt.Sprintf(`
-Extract message composition details from: "%s"
-
-Provide response in JSON format:
-{
-  "recipient": "name or identifier of recipient",
-  "subject": "message subject if mentioned",
-  "content": "message content or main points",
-  "method": "email|phone|text|slack|teams|linkedin|in_person",
-  "priority": "low|medium|high|critical",
-  "tone": "formal|casual|friendly|professional",
-  "purpose": "introduction|follow_up|meeting|thank_you|reminder|networking"
-}
-
-If content is not fully specified, indicate what should be included.`, msg.Content)
-
-	response, err := a.llmProvider.Query(ctx, contextPrompt)
+	contextPrompt, err := a.renderPrompt(ctx, "communication.compose_details", prompts.Vars{"Request": msg.Content})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse message details: %w", err)
+		return nil, err
 	}
 
 	var messageData struct {
@@ -404,37 +443,68 @@ If content is not fully specified, indicate what should be included.`, msg.Conte
 		Purpose   string `json:"purpose"`
 	}
 
-	if err := json.Unmarshal([]byte(response), &messageData); err != nil {
-		return nil, fmt.Errorf("failed to parse message JSON: %w", err)
+	messageSchema := objectSchema(map[string]string{
+		"recipient": "string",
+		"subject":   "string",
+		"content":   "string",
+		"method":    "string",
+	}, "recipient")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+contextPrompt, messageSchema, &messageData); err != nil {
+		return nil, fmt.Errorf("failed to parse message details: %w", err)
 	}
 
 	// Find the contact
-	contact := a.findContactByName(messageData.Recipient)
+	contact := a.findContactByName(ctx, messageData.Recipient)
 	if contact == nil {
 		return &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+			ID:        a.messageID(),
 			From:      a.id,
 			To:        []multiagent.AgentID{msg.From},
 			Type:      multiagent.MessageTypeResponse,
 			Content:   fmt.Sprintf("❌ Contact '%s' not found. Would you like me to:\n1. Add this as a new contact\n2. Search for similar contacts\n3. Compose the message anyway", messageData.Recipient),
 			ReplyTo:   msg.ID,
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 		}, nil
 	}
 
+	// The user's template for the message's purpose writes it when no text
+	// was given
+	var template *MessageTemplate
+	var missing []string
+	if category := parseTemplateCategory(messageData.Purpose); category != "" && len(messageData.Content) < 20 {
+		a.loadTemplatesFromMemory(ctx)
+		a.commMutex.Lock()
+		if template = a.templateForCategory(ctx, category); template != nil {
+			var subject string
+			subject, messageData.Content, missing = a.useTemplate(ctx, template, contact, nil)
+			if messageData.Subject == "" {
+				messageData.Subject = subject
+			}
+			snapshot := *template
+			template = &snapshot
+		}
+		a.commMutex.Unlock()
+		if template != nil {
+			if err := a.saveTemplate(ctx, template); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Generate message content if not fully specified
 	if messageData.Content == "" || len(messageData.Content) < 20 {
-		messagePrompt := fmt.Sprintf(`
-Compose a %s %s message for the following:
-
-Recipient: %s (%s at %s)
-Subject: %s
-Purpose: %s
-Tone: %s
-Context: %s
-
-Write a complete, professional message that serves the intended purpose.
-Include appropriate greeting, body, and closing.`, messageData.Tone, messageData.Purpose, contact.Name, contact.Title, contact.Organization, messageData.Subject, messageData.Purpose, messageData.Tone, msg.Content)
+		messagePrompt, err := a.renderPrompt(ctx, "communication.compose_message", prompts.Vars{
+			"Tone":                messageData.Tone,
+			"Purpose":             messageData.Purpose,
+			"ContactName":         contact.Name,
+			"ContactTitle":        contact.Title,
+			"ContactOrganization": contact.Organization,
+			"Subject":             messageData.Subject,
+			"Request":             msg.Content,
+		})
+		if err != nil {
+			return nil, err
+		}
 
 		composedContent, err := a.llmProvider.Query(ctx, messagePrompt)
 		if err != nil {
@@ -445,7 +515,7 @@ Include appropriate greeting, body, and closing.`, messageData.Tone, messageData
 
 	// Create message record
 	message := &CommunicationMessage{
-		ID:        fmt.Sprintf("msg_%d", time.Now().UnixNano()),
+		ID:        a.newID("msg"),
 		ContactID: contact.ID,
 		Subject:   messageData.Subject,
 		Content:   messageData.Content,
@@ -454,9 +524,18 @@ Include appropriate greeting, body, and closing.`, messageData.Tone, messageData
 		Status:    MessageStatusDraft,
 		Priority:  a.parsePriority(messageData.Priority),
 		Tags:      []string{messageData.Purpose},
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: a.now(),
+		UpdatedAt: a.now(),
 		Metadata:  make(map[string]interface{}),
+		UserID:    multiagent.UserIDFromContext(ctx),
+	}
+	footer := fmt.Sprintf("*Message saved as draft. Say 'send draft %s' to email it, or ask for changes.*", message.ID)
+	if template != nil {
+		message.TemplateID = template.ID
+		footer = fmt.Sprintf("*Written from your template '%s' and saved as draft.*", template.Name)
+		if len(missing) > 0 {
+			footer = fmt.Sprintf("⚠️ Fill in: %s\n\n", strings.Join(missing, ", ")) + footer
+		}
 	}
 
 	// Store message
@@ -469,15 +548,20 @@ Include appropriate greeting, body, and closing.`, messageData.Tone, messageData
 		messageKey := fmt.Sprintf("communication_message:%s", message.ID)
 		a.memoryStore.Store(ctx, messageKey, message)
 	}
+	a.recordAudit(ctx, msg, audit.MessageDrafted, message.ID, map[string]interface{}{
+		"contact_id": contact.ID,
+		"subject":    message.Subject,
+		"method":     message.Method,
+	})
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
-		Content:   fmt.Sprintf("✉️ **Message Composed**\n\n**To:** %s (%s)\n**Subject:** %s\n**Method:** %s\n**Priority:** %s\n\n**Content:**\n%s\n\n---\n\n*Message saved as draft. Would you like me to send it or make any changes?*", contact.Name, contact.Email, message.Subject, message.Method, message.Priority, message.Content),
+		Content:   fmt.Sprintf("✉️ **Message Composed**\n\n**To:** %s (%s)\n**Subject:** %s\n**Method:** %s\n**Priority:** %s\n\n**Content:**\n%s\n\n---\n\n%s", contact.Name, contact.Email, message.Subject, message.Method, message.Priority, message.Content, footer),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"message_id": message.ID,
 			"contact_id": contact.ID,
@@ -499,6 +583,9 @@ func (a *CommunicationManagerAgent) handleListContacts(ctx context.Context, msg
 
 	// Apply filters based on request
 	for _, contact := range a.contacts {
+		if !ownedBy(ctx, contact.UserID) {
+			continue
+		}
 		include := true
 
 		// Filter by relationship
@@ -529,13 +616,13 @@ func (a *CommunicationManagerAgent) handleListContacts(ctx context.Context, msg
 
 	if len(filteredContacts) == 0 {
 		return &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+			ID:        a.messageID(),
 			From:      a.id,
 			To:        []multiagent.AgentID{msg.From},
 			Type:      multiagent.MessageTypeResponse,
 			Content:   "👥 No contacts found matching your criteria. Use 'add contact' to start building your network!",
 			ReplyTo:   msg.ID,
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 		}, nil
 	}
 
@@ -584,56 +671,20 @@ func (a *CommunicationManagerAgent) handleListContacts(ctx context.Context, msg
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   contactsBuilder.String(),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
 // Additional handler methods (simplified for space)
 
-func (a *CommunicationManagerAgent) handleTemplateManagement(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "📝 Message template management is available. I can help you create, edit, and use message templates for common communications.",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
-func (a *CommunicationManagerAgent) handleFollowUp(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "🔄 Follow-up management is available. I can track pending responses, schedule follow-ups, and remind you to maintain important relationships.",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
-func (a *CommunicationManagerAgent) handleScheduleMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "⏰ Message scheduling is available. I can schedule emails and messages to be sent at optimal times.",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
 func (a *CommunicationManagerAgent) handleCommunicationStats(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	stats := a.calculateCommunicationStats()
+	stats := a.calculateCommunicationStats(ctx)
 
 	statsContent := fmt.Sprintf("📊 **Communication Statistics**\n\n"+
 		"👥 **Contacts:** %d total, %d active\n"+
@@ -657,31 +708,22 @@ func (a *CommunicationManagerAgent) handleCommunicationStats(ctx context.Context
 		stats.LastUpdated.Format("2006-01-02 15:04"))
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   statsContent,
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
-func (a *CommunicationManagerAgent) handleRelationshipManagement(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "🤝 Relationship management tools are available. I can help you maintain professional networks, track relationship history, and suggest networking opportunities.",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
 func (a *CommunicationManagerAgent) handleGeneralQuery(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Build context with communication information
-	contextPrompt := a.buildCommunicationContext(ctx, msg)
+	contextPrompt, err := a.buildCommunicationContext(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
 
 	response, err := a.llmProvider.Query(ctx, contextPrompt)
 	if err != nil {
@@ -692,20 +734,20 @@ func (a *CommunicationManagerAgent) handleGeneralQuery(ctx context.Context, msg
 	responseContext := make(map[string]interface{})
 	if coordID, ok := msg.Context["coordination_id"]; ok {
 		responseContext["coordination_id"] = coordID
-		log.Printf("CommunicationManagerAgent: Preserving coordination context: %v", coordID)
+		a.logger.DebugContext(ctx, "Preserving coordination context", "coordination_id", coordID)
 	}
 	if convID, ok := msg.Context["conversation_id"]; ok {
 		responseContext["conversation_id"] = convID
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   response,
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context:   responseContext,
 	}, nil
 }
@@ -738,14 +780,14 @@ func (a *CommunicationManagerAgent) parsePriority(priority string) multiagent.Pr
 	}
 }
 
-func (a *CommunicationManagerAgent) findContactByName(name string) *Contact {
+func (a *CommunicationManagerAgent) findContactByName(ctx context.Context, name string) *Contact {
 	nameLower := strings.ToLower(name)
 
 	a.commMutex.RLock()
 	defer a.commMutex.RUnlock()
 
 	for _, contact := range a.contacts {
-		if strings.Contains(strings.ToLower(contact.Name), nameLower) {
+		if ownedBy(ctx, contact.UserID) && strings.Contains(strings.ToLower(contact.Name), nameLower) {
 			return contact
 		}
 	}
@@ -825,24 +867,68 @@ func (a *CommunicationManagerAgent) loadContactsFromMemory(ctx context.Context)
 		var contact Contact
 		if contactData, err := json.Marshal(contactInterface); err == nil {
 			if err := json.Unmarshal(contactData, &contact); err == nil {
+				contact.UserID = multiagent.UserIDFromContext(ctx)
 				a.contacts[contact.ID] = &contact
 			}
 		}
 	}
 }
 
-func (a *CommunicationManagerAgent) calculateCommunicationStats() CommunicationStats {
+// PurgeUser forgets userID's contacts, messages, templates, follow-ups and
+// pending contact merges
+func (a *CommunicationManagerAgent) PurgeUser(userID string) int {
+	a.commMutex.Lock()
+	defer a.commMutex.Unlock()
+
+	purged := 0
+	for id, contact := range a.contacts {
+		if contact.UserID == userID {
+			delete(a.contacts, id)
+			purged++
+		}
+	}
+	for id, message := range a.messages {
+		if message.UserID == userID {
+			delete(a.messages, id)
+			purged++
+		}
+	}
+	for id, template := range a.templates {
+		if template.UserID == userID {
+			delete(a.templates, id)
+			purged++
+		}
+	}
+	for id, followUp := range a.followUps {
+		if followUp.UserID == userID {
+			delete(a.followUps, id)
+			purged++
+		}
+	}
+	for id, merge := range a.contactMerges {
+		if merge.UserID == userID {
+			delete(a.contactMerges, id)
+			purged++
+		}
+	}
+	return purged
+}
+
+func (a *CommunicationManagerAgent) calculateCommunicationStats(ctx context.Context) CommunicationStats {
 	a.commMutex.RLock()
 	defer a.commMutex.RUnlock()
 
 	stats := CommunicationStats{
 		ContactsByPriority: make(map[ContactPriority]int),
 		MessagesByMethod:   make(map[CommunicationMethod]int),
-		LastUpdated:        time.Now(),
+		LastUpdated:        a.now(),
 	}
 
 	// Count contacts
 	for _, contact := range a.contacts {
+		if !ownedBy(ctx, contact.UserID) {
+			continue
+		}
 		stats.TotalContacts++
 		if contact.Status == ContactStatusActive {
 			stats.ActiveContacts++
@@ -852,6 +938,9 @@ func (a *CommunicationManagerAgent) calculateCommunicationStats() CommunicationS
 
 	// Count messages
 	for _, message := range a.messages {
+		if !ownedBy(ctx, message.UserID) {
+			continue
+		}
 		if message.Direction == MessageDirectionOutbound {
 			stats.MessagesSent++
 		} else {
@@ -899,29 +988,17 @@ func (a *CommunicationManagerAgent) shouldIgnoreMessage(msg *multiagent.Message)
 	return false
 }
 
-func (a *CommunicationManagerAgent) buildCommunicationContext(ctx context.Context, msg *multiagent.Message) string {
-	var contextBuilder strings.Builder
-
-	contextBuilder.WriteString(fmt.Sprintf("You are %s, a communication and relationship management specialist.\n\n", a.name))
-	contextBuilder.WriteString("You help users manage their contacts, compose messages, maintain relationships, and optimize their communication strategies.\n\n")
-
+func (a *CommunicationManagerAgent) buildCommunicationContext(ctx context.Context, msg *multiagent.Message) (string, error) {
 	// Add contact summary
-	a.commMutex.RLock()
-	if len(a.contacts) > 0 {
-		contextBuilder.WriteString("Contact Summary:\n")
-		stats := a.calculateCommunicationStats()
-		contextBuilder.WriteString(fmt.Sprintf("- Total contacts: %d (%d active)\n", stats.TotalContacts, stats.ActiveContacts))
-		contextBuilder.WriteString(fmt.Sprintf("- VIP: %d, High: %d, Medium: %d, Low: %d\n",
-			stats.ContactsByPriority[ContactPriorityVIP],
-			stats.ContactsByPriority[ContactPriorityHigh],
-			stats.ContactsByPriority[ContactPriorityMedium],
-			stats.ContactsByPriority[ContactPriorityLow]))
-		contextBuilder.WriteString("\n")
-	}
-	a.commMutex.RUnlock()
-
-	contextBuilder.WriteString(fmt.Sprintf("User request: %s\n\n", msg.Content))
-	contextBuilder.WriteString("Please provide helpful communication assistance, relationship management advice, or execute the requested action.")
-
-	return contextBuilder.String()
+	stats := a.calculateCommunicationStats(ctx)
+	return a.renderPrompt(ctx, "communication.general", prompts.Vars{
+		"Name":           a.name,
+		"TotalContacts":  stats.TotalContacts,
+		"ActiveContacts": stats.ActiveContacts,
+		"VIP":            stats.ContactsByPriority[ContactPriorityVIP],
+		"High":           stats.ContactsByPriority[ContactPriorityHigh],
+		"Medium":         stats.ContactsByPriority[ContactPriorityMedium],
+		"Low":            stats.ContactsByPriority[ContactPriorityLow],
+		"Request":        msg.Content,
+	})
 }
@@ -0,0 +1,575 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/reminders"
+	"github.com/kbutz/wikillm/multiagent/timeparse"
+)
+
+// Channels messages are delivered over
+const (
+	deliveryEmail   = "email"
+	deliveryWebhook = "webhook"
+)
+
+const (
+	// webhookProfile is the social profile holding a contact's webhook URL,
+	// e.g. a Slack or Teams incoming webhook, that non-email messages are
+	// posted to
+	webhookProfile = "webhook"
+	// webhookMessageKind is the kind of the notifications messages are
+	// posted to webhooks as
+	webhookMessageKind = "message"
+	// defaultSendHour is when a message scheduled for a day without a time
+	// goes out
+	defaultSendHour = 9
+)
+
+var (
+	// scheduleCancelPhrase recognises taking a message off the queue
+	scheduleCancelPhrase = regexp.MustCompile(`(?i)\b(cancel|unschedule|don'?t send|do not send)\b`)
+	// scheduleListPhrase recognises asking what is queued
+	scheduleListPhrase = regexp.MustCompile(`(?i)\b(list|show|what'?s|what is|queue|pending)\b`)
+	// scheduleEditPhrase matches "edit scheduled msg_123: new text"
+	scheduleEditPhrase = regexp.MustCompile(`(?is)^\s*(?:edit|update|change)\s+(?:the\s+)?(?:scheduled\s+)?(?:message\s+|email\s+)?(msg_\d+)\s*:\s*(.+)$`)
+	// quietHoursPhrase matches "quiet hours for Bob 10pm-7am" and the
+	// clearing of them
+	quietHoursPhrase = regexp.MustCompile(`(?i)\bquiet hours\b`)
+	// clockRange matches "22:00-07:00" or "10pm to 7am"
+	clockRange = regexp.MustCompile(`(?i)\b(\d{1,2})(?::(\d{2}))?\s*(am|pm)?\s*(?:-|–|to|until)\s*(\d{1,2})(?::(\d{2}))?\s*(am|pm)?\b`)
+	// timeZoneName matches an IANA zone such as Europe/London, or UTC
+	timeZoneName = regexp.MustCompile(`\b(UTC|[A-Z][A-Za-z]+/[A-Za-z_]+(?:/[A-Za-z_]+)?)\b`)
+	// webhookURL finds the URL in "set Bob's webhook to https://..."
+	webhookURL = regexp.MustCompile(`https?://\S+`)
+	// theirTimePhrase asks for a time in the recipient's timezone
+	theirTimePhrase = regexp.MustCompile(`(?i)\b(their|his|her|recipient'?s?|contact'?s?|local)\s+(time|timezone|time zone)\b`)
+)
+
+// QuietHours is a daily window, in the contact's timezone, when messages
+// to them are held back; Start after End wraps past midnight
+type QuietHours struct {
+	Start string `json:"start"` // e.g. "22:00"
+	End   string `json:"end"`   // e.g. "07:00"
+}
+
+// minutes reads "22:00" as minutes after midnight
+func (q *QuietHours) minutes(clock string) (int, bool) {
+	t, ok := parseClock(clock)
+	return t.Hour()*60 + t.Minute(), ok
+}
+
+// Release returns t, or the end of the quiet window t falls in, in t's
+// location
+func (q *QuietHours) Release(t time.Time) time.Time {
+	start, ok := q.minutes(q.Start)
+	end, ok2 := q.minutes(q.End)
+	if !ok || !ok2 || start == end {
+		return t
+	}
+	minute := t.Hour()*60 + t.Minute()
+	quiet := (start < end && minute >= start && minute < end) || (start > end && (minute >= start || minute < end))
+	if !quiet {
+		return t
+	}
+	release := time.Date(t.Year(), t.Month(), t.Day(), end/60, end%60, 0, 0, t.Location())
+	if !release.After(t) {
+		release = release.AddDate(0, 0, 1)
+	}
+	return release
+}
+
+// String renders the window, e.g. "22:00–07:00"
+func (q *QuietHours) String() string {
+	return q.Start + "–" + q.End
+}
+
+// spokenClock reads an hour, optional minutes and am/pm as "15:04"
+func spokenClock(hour, minute, meridiem string) (string, bool) {
+	h, err := strconv.Atoi(hour)
+	if err != nil {
+		return "", false
+	}
+	m := 0
+	if minute != "" {
+		m, _ = strconv.Atoi(minute)
+	}
+	switch strings.ToLower(meridiem) {
+	case "am":
+		if h == 12 {
+			h = 0
+		}
+	case "pm":
+		if h < 12 {
+			h += 12
+		}
+	}
+	if h > 23 || m > 59 {
+		return "", false
+	}
+	return fmt.Sprintf("%02d:%02d", h, m), true
+}
+
+// contactLocation is the contact's timezone, or the user's when they have
+// none
+func (a *CommunicationManagerAgent) contactLocation(ctx context.Context, contact *Contact) *time.Location {
+	if contact != nil && contact.TimeZone != "" {
+		if loc, err := time.LoadLocation(contact.TimeZone); err == nil {
+			return loc
+		}
+	}
+	return userLocation(ctx, a.memoryStore)
+}
+
+// sendTime moves at out of the contact's quiet hours
+func (a *CommunicationManagerAgent) sendTime(ctx context.Context, contact *Contact, at time.Time) time.Time {
+	if contact == nil || contact.QuietHours == nil {
+		return at
+	}
+	return contact.QuietHours.Release(at.In(a.contactLocation(ctx, contact)))
+}
+
+// deliveryChannel picks how message reaches contact: email, unless the
+// message is for another method and the contact has a webhook. When it
+// can't be delivered the reason is returned instead.
+func (a *CommunicationManagerAgent) deliveryChannel(ctx context.Context, message *CommunicationMessage, contact *Contact) (string, string) {
+	if contact == nil {
+		return "", fmt.Sprintf("✉️ The recipient of %s is no longer one of your contacts.", message.ID)
+	}
+	hasWebhook := contact.SocialProfiles[webhookProfile] != ""
+	if message.Method != "" && message.Method != CommunicationMethodEmail {
+		if hasWebhook {
+			return deliveryWebhook, ""
+		}
+		return "", fmt.Sprintf("✉️ %s is a %s message and %s has no webhook to post it to. Say \"set %s's webhook to https://...\" to add one.", message.ID, message.Method, contact.Name, contact.Name)
+	}
+	if contact.Email == "" {
+		if hasWebhook {
+			return deliveryWebhook, ""
+		}
+		return "", fmt.Sprintf("✉️ I have no email address for %s. Add one to the contact first.", contact.Name)
+	}
+	if a.mailer == nil {
+		return "", "✉️ Email sending isn't set up for you. Ask the administrator to add your SMTP account."
+	}
+	if _, ok := a.mailer.From(multiagent.UserIDFromContext(ctx)); !ok {
+		return "", "✉️ Email sending isn't set up for you. Ask the administrator to add your SMTP account."
+	}
+	return deliveryEmail, ""
+}
+
+// scheduledSendID names the reminder that sends a queued message
+func scheduledSendID(messageID string) string {
+	return "send_" + messageID
+}
+
+// handleScheduleMessage runs the send-later queue: it schedules a draft
+// ("schedule draft msg_123 for Friday 9am", "schedule the email to Bob for
+// tomorrow 8am their time"), reschedules, edits, cancels and lists queued
+// messages, and sets a contact's quiet hours, timezone and webhook. Like
+// sending, which message is meant is read without the LLM.
+func (a *CommunicationManagerAgent) handleScheduleMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadContactsFromMemory(ctx)
+	a.loadMessagesFromMemory(ctx)
+	content := strings.TrimSpace(msg.Content)
+	lower := strings.ToLower(content)
+
+	switch {
+	case quietHoursPhrase.MatchString(lower), strings.Contains(lower, "webhook") && webhookURL.MatchString(content):
+		return a.setDeliveryPreferences(ctx, msg, content)
+	case scheduleEditPhrase.MatchString(content):
+		match := scheduleEditPhrase.FindStringSubmatch(content)
+		return a.editQueuedMessage(ctx, msg, match[1], strings.TrimSpace(match[2]))
+	case scheduleCancelPhrase.MatchString(lower):
+		return a.cancelQueuedMessage(ctx, msg, lower)
+	}
+
+	message, contact := a.findQueuedMessage(ctx, lower, MessageStatusDraft, MessageStatusFailed, MessageStatusScheduled)
+	stripped := draftIDPhrase.ReplaceAllString(content, "")
+	_, hasTime := timeparse.Extract(stripped, a.now())
+	if !hasTime && draftIDPhrase.FindString(lower) == "" && (message == nil || scheduleListPhrase.MatchString(lower)) {
+		return a.listQueuedMessages(ctx, msg)
+	}
+	if message == nil {
+		return a.respond(msg, "⏰ Which draft should I schedule? Say \"schedule draft msg_123 for Friday at 9am\"; compose one first if you have none.", nil), nil
+	}
+
+	channel, reason := a.deliveryChannel(ctx, message, contact)
+	if channel == "" {
+		return a.respond(msg, reason, map[string]interface{}{"message_id": message.ID}), nil
+	}
+
+	userLoc := userLocation(ctx, a.memoryStore)
+	loc := userLoc
+	if theirTimePhrase.MatchString(content) {
+		loc = a.contactLocation(ctx, contact)
+	}
+	now := a.now().In(loc)
+	found, ok := timeparse.Extract(stripped, now)
+	if !ok {
+		return a.respond(msg, fmt.Sprintf("⏰ When should I send %s? Say something like \"schedule %s for tomorrow at 9am\".", message.ID, message.ID), nil), nil
+	}
+	requested := found.Time
+	if !found.HasTime {
+		requested = time.Date(requested.Year(), requested.Month(), requested.Day(), defaultSendHour, 0, 0, 0, loc)
+	}
+	if !requested.After(now) {
+		return a.respond(msg, fmt.Sprintf("⏰ %s has already passed. Pick a later time, or say 'send draft %s' to send it now.", requested.Format("Mon Jan 2 at 15:04"), message.ID), nil), nil
+	}
+	at := a.sendTime(ctx, contact, requested)
+
+	a.commMutex.Lock()
+	rescheduled := message.Status == MessageStatusScheduled
+	message.Status = MessageStatusScheduled
+	message.ScheduledFor = &at
+	message.UpdatedAt = a.now()
+	if message.Metadata == nil {
+		message.Metadata = make(map[string]interface{})
+	}
+	delete(message.Metadata, sendRequestedKey)
+	delete(message.Metadata, "delivery_error")
+	snapshot := *message
+	contactSnapshot := *contact
+	a.commMutex.Unlock()
+
+	if err := a.saveMessage(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	err := a.reminderEngine.Schedule(ctx, reminders.Reminder{
+		ID:        scheduledSendID(snapshot.ID),
+		UserID:    multiagent.UserIDFromContext(ctx),
+		Source:    scheduledSendSource,
+		Subject:   snapshot.ID,
+		Title:     "📤 Scheduled message to " + contactSnapshot.Name,
+		Kind:      notify.KindScheduledSend,
+		Priority:  snapshot.Priority,
+		TriggerAt: at,
+		Data:      map[string]string{"message_id": snapshot.ID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule message %s: %w", snapshot.ID, err)
+	}
+	a.recordAudit(ctx, msg, audit.MessageScheduled, snapshot.ID, map[string]interface{}{
+		"contact_id":    contactSnapshot.ID,
+		"scheduled_for": at,
+		"channel":       channel,
+		"rescheduled":   rescheduled,
+	})
+
+	verb := "Scheduled"
+	if rescheduled {
+		verb = "Rescheduled"
+	}
+	to := contactSnapshot.Name
+	if channel == deliveryEmail {
+		to += fmt.Sprintf(" <%s>", contactSnapshot.Email)
+	} else {
+		to += " via their webhook"
+	}
+	reply := fmt.Sprintf("⏰ %s '%s' to %s for %s.", verb, snapshot.Subject, to, describeSendTime(at, userLoc, a.contactLocation(ctx, &contactSnapshot)))
+	if !at.Equal(requested) {
+		reply += fmt.Sprintf(" %s falls in %s's quiet hours (%s), so it waits until they end.", requested.Format("15:04"), contactSnapshot.Name, contactSnapshot.QuietHours)
+	}
+	reply += fmt.Sprintf("\n\nSay 'cancel scheduled %s' to stop it, 'reschedule %s to <time>' to move it, or 'edit scheduled %s: <new text>'.", snapshot.ID, snapshot.ID, snapshot.ID)
+	return a.respond(msg, reply, map[string]interface{}{
+		"message_id":    snapshot.ID,
+		"scheduled_for": at,
+		"channel":       channel,
+		"action":        "message_scheduled",
+	}), nil
+}
+
+// describeSendTime renders when a message goes out in the user's time, and
+// the recipient's when theirs differs
+func describeSendTime(at time.Time, userLoc, contactLoc *time.Location) string {
+	text := at.In(userLoc).Format("Mon Jan 2 at 15:04")
+	if local := at.In(contactLoc); local.Format("15:04 Mon") != at.In(userLoc).Format("15:04 Mon") {
+		text += fmt.Sprintf(" (%s their time)", local.Format("Mon 15:04"))
+	}
+	return text
+}
+
+// findQueuedMessage picks the outbound message in one of statuses a
+// request means: the one it names by ID, else the latest to the contact it
+// mentions, else the latest one
+func (a *CommunicationManagerAgent) findQueuedMessage(ctx context.Context, lower string, statuses ...MessageStatus) (*CommunicationMessage, *Contact) {
+	var named *Contact
+	if draftIDPhrase.FindString(lower) == "" {
+		named = a.mentionedContact(ctx, lower)
+	}
+
+	a.commMutex.RLock()
+	defer a.commMutex.RUnlock()
+	matches := func(message *CommunicationMessage) bool {
+		if !ownedBy(ctx, message.UserID) || message.Direction != MessageDirectionOutbound {
+			return false
+		}
+		for _, status := range statuses {
+			if message.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+	if id := draftIDPhrase.FindString(lower); id != "" {
+		message, ok := a.messages[id]
+		if !ok || !matches(message) {
+			return nil, nil
+		}
+		return message, a.contacts[message.ContactID]
+	}
+	var latest *CommunicationMessage
+	for _, message := range a.messages {
+		if !matches(message) || (named != nil && message.ContactID != named.ID) {
+			continue
+		}
+		if latest == nil || message.UpdatedAt.After(latest.UpdatedAt) {
+			latest = message
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	return latest, a.contacts[latest.ContactID]
+}
+
+// listQueuedMessages shows the user's scheduled messages, soonest first
+func (a *CommunicationManagerAgent) listQueuedMessages(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	loc := userLocation(ctx, a.memoryStore)
+	a.commMutex.RLock()
+	var lines, ids []string
+	var queued []*CommunicationMessage
+	for _, message := range a.messages {
+		if ownedBy(ctx, message.UserID) && message.Status == MessageStatusScheduled && message.ScheduledFor != nil {
+			queued = append(queued, message)
+		}
+	}
+	sort.Slice(queued, func(i, j int) bool { return queued[i].ScheduledFor.Before(*queued[j].ScheduledFor) })
+	for _, message := range queued {
+		name := message.ContactID
+		contactLoc := loc
+		if contact, ok := a.contacts[message.ContactID]; ok {
+			name = contact.Name
+			contactLoc = a.contactLocation(ctx, contact)
+		}
+		lines = append(lines, fmt.Sprintf("• %s — '%s' to %s, %s", message.ID, message.Subject, name, describeSendTime(*message.ScheduledFor, loc, contactLoc)))
+		ids = append(ids, message.ID)
+	}
+	a.commMutex.RUnlock()
+
+	if len(lines) == 0 {
+		return a.respond(msg, "⏰ Nothing is scheduled to send. Compose a draft, then say something like \"schedule draft msg_123 for Friday at 9am\".", map[string]interface{}{
+			"action": "scheduled_messages_listed",
+		}), nil
+	}
+	return a.respond(msg, "⏰ **Scheduled Messages**\n\n"+strings.Join(lines, "\n"), map[string]interface{}{
+		"message_ids": ids,
+		"action":      "scheduled_messages_listed",
+	}), nil
+}
+
+// cancelQueuedMessage takes a message off the queue, back to a draft
+func (a *CommunicationManagerAgent) cancelQueuedMessage(ctx context.Context, msg *multiagent.Message, lower string) (*multiagent.Message, error) {
+	message, _ := a.findQueuedMessage(ctx, lower, MessageStatusScheduled)
+	if message == nil {
+		return a.respond(msg, "⏰ I couldn't find that scheduled message. Say \"show scheduled messages\" to see the queue.", nil), nil
+	}
+
+	a.commMutex.Lock()
+	message.Status = MessageStatusDraft
+	message.ScheduledFor = nil
+	message.UpdatedAt = a.now()
+	snapshot := *message
+	a.commMutex.Unlock()
+	if err := a.saveMessage(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	if err := a.reminderEngine.Cancel(ctx, scheduledSendID(snapshot.ID)); err != nil {
+		return nil, fmt.Errorf("failed to cancel scheduled message %s: %w", snapshot.ID, err)
+	}
+	a.recordAudit(ctx, msg, audit.MessageScheduleCancelled, snapshot.ID, map[string]interface{}{
+		"contact_id": snapshot.ContactID,
+	})
+	return a.respond(msg, fmt.Sprintf("⏰ Cancelled sending '%s'. It's a draft again (%s).", snapshot.Subject, snapshot.ID), map[string]interface{}{
+		"message_id": snapshot.ID,
+		"action":     "scheduled_message_cancelled",
+	}), nil
+}
+
+// editQueuedMessage replaces the text of a scheduled message or draft,
+// keeping when it goes out
+func (a *CommunicationManagerAgent) editQueuedMessage(ctx context.Context, msg *multiagent.Message, id, content string) (*multiagent.Message, error) {
+	message, _ := a.findQueuedMessage(ctx, id, MessageStatusScheduled, MessageStatusDraft, MessageStatusFailed)
+	if message == nil {
+		return a.respond(msg, fmt.Sprintf("⏰ %s isn't a draft or scheduled message of yours.", id), nil), nil
+	}
+
+	a.commMutex.Lock()
+	message.Content = content
+	message.UpdatedAt = a.now()
+	snapshot := *message
+	a.commMutex.Unlock()
+	if err := a.saveMessage(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	a.recordAudit(ctx, msg, audit.MessageScheduled, snapshot.ID, map[string]interface{}{
+		"contact_id": snapshot.ContactID,
+		"changes":    []string{"content"},
+	})
+	reply := fmt.Sprintf("📝 Updated %s:\n\n%s", snapshot.ID, snapshot.Content)
+	if snapshot.ScheduledFor != nil {
+		reply += fmt.Sprintf("\n\nIt still goes out %s.", snapshot.ScheduledFor.In(userLocation(ctx, a.memoryStore)).Format("Mon Jan 2 at 15:04"))
+	}
+	return a.respond(msg, reply, map[string]interface{}{
+		"message_id": snapshot.ID,
+		"action":     "scheduled_message_updated",
+	}), nil
+}
+
+// setDeliveryPreferences sets a contact's quiet hours, e.g. "quiet hours
+// for Bob 10pm to 7am Europe/London", clears them ("no quiet hours for
+// Bob"), or sets their webhook
+func (a *CommunicationManagerAgent) setDeliveryPreferences(ctx context.Context, msg *multiagent.Message, content string) (*multiagent.Message, error) {
+	contact := a.mentionedContact(ctx, strings.ToLower(content))
+	if contact == nil {
+		return a.respond(msg, "⏰ Which contact? Say something like \"quiet hours for Bob 10pm to 7am\" or \"set Bob's webhook to https://...\".", nil), nil
+	}
+
+	a.commMutex.Lock()
+	var changes []string
+	var problem string
+	if url := webhookURL.FindString(content); url != "" && strings.Contains(strings.ToLower(content), "webhook") {
+		if contact.SocialProfiles == nil {
+			contact.SocialProfiles = make(map[string]string)
+		}
+		contact.SocialProfiles[webhookProfile] = strings.TrimRight(url, ".,;")
+		changes = append(changes, "webhook")
+	}
+	if quietHoursPhrase.MatchString(content) {
+		lower := strings.ToLower(content)
+		if match := clockRange.FindStringSubmatch(content); match != nil {
+			start, ok := spokenClock(match[1], match[2], match[3])
+			end, ok2 := spokenClock(match[4], match[5], match[6])
+			if ok && ok2 && start != end {
+				contact.QuietHours = &QuietHours{Start: start, End: end}
+				changes = append(changes, "quiet hours "+contact.QuietHours.String())
+			} else {
+				problem = "⏰ I couldn't read those hours. Say something like \"quiet hours for Bob 22:00-07:00\"."
+			}
+		} else if strings.Contains(lower, "no quiet") || strings.Contains(lower, "clear") || strings.Contains(lower, "remove") {
+			contact.QuietHours = nil
+			changes = append(changes, "no quiet hours")
+		}
+	}
+	if zone := timeZoneName.FindString(webhookURL.ReplaceAllString(content, "")); zone != "" {
+		if _, err := time.LoadLocation(zone); err == nil {
+			contact.TimeZone = zone
+			changes = append(changes, "timezone "+zone)
+		} else if problem == "" {
+			problem = fmt.Sprintf("⏰ I don't know the timezone %s; use a name like Europe/London.", zone)
+		}
+	}
+	if problem != "" || len(changes) == 0 {
+		a.commMutex.Unlock()
+		if problem == "" {
+			problem = "⏰ Say something like \"quiet hours for Bob 10pm to 7am America/New_York\", \"no quiet hours for Bob\" or \"set Bob's webhook to https://...\"."
+		}
+		return a.respond(msg, problem, nil), nil
+	}
+	contact.UpdatedAt = a.now()
+	snapshot := *contact
+	a.commMutex.Unlock()
+
+	if err := a.saveContact(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	reply := fmt.Sprintf("⏰ Updated %s: %s.", snapshot.Name, strings.Join(changes, ", "))
+	if snapshot.QuietHours != nil {
+		reply += fmt.Sprintf(" Messages scheduled for %s's quiet hours wait until %s their time (%s).", snapshot.Name, snapshot.QuietHours.End, a.contactLocation(ctx, &snapshot))
+	}
+	return a.respond(msg, reply, map[string]interface{}{
+		"contact_id": snapshot.ID,
+		"changes":    changes,
+		"action":     "delivery_preferences_set",
+	}), nil
+}
+
+// fireScheduledSend is the engine's handler for queued messages: it
+// delivers the message unless it was cancelled or sent meanwhile, holds it
+// while the recipient's quiet hours last, and tells the user how it went
+func (a *CommunicationManagerAgent) fireScheduledSend(ctx context.Context, scheduled reminders.Reminder, now time.Time) (*notify.Notification, time.Time) {
+	a.loadContactsFromMemory(ctx)
+	a.loadMessagesFromMemory(ctx)
+
+	a.commMutex.RLock()
+	message, ok := a.messages[scheduled.Data["message_id"]]
+	if !ok || !ownedBy(ctx, message.UserID) || message.Status != MessageStatusScheduled {
+		a.commMutex.RUnlock()
+		return nil, time.Time{}
+	}
+	contact := a.contacts[message.ContactID]
+	var release time.Time
+	if contact != nil {
+		release = a.sendTime(ctx, contact, now)
+	}
+	a.commMutex.RUnlock()
+
+	// Quiet hours set since it was scheduled still hold it back
+	if release.After(now.Add(time.Minute)) {
+		return nil, release
+	}
+
+	channel, reason := a.deliveryChannel(ctx, message, contact)
+	var sendErr error
+	var snapshot CommunicationMessage
+	if channel == "" {
+		a.commMutex.Lock()
+		message.Status = MessageStatusFailed
+		if message.Metadata == nil {
+			message.Metadata = make(map[string]interface{})
+		}
+		message.Metadata["delivery_error"] = reason
+		message.UpdatedAt = now
+		snapshot = *message
+		a.commMutex.Unlock()
+		if err := a.saveMessage(ctx, &snapshot); err != nil {
+			a.logger.WarnContext(ctx, "Failed to save message", "message_id", snapshot.ID, "error", err)
+		}
+		sendErr = errors.New(strings.TrimPrefix(reason, "✉️ "))
+	} else {
+		snapshot, sendErr = a.deliver(ctx, nil, message, contact, channel)
+	}
+
+	name := snapshot.ContactID
+	if contact != nil {
+		name = contact.Name
+	}
+	if sendErr != nil {
+		return &notify.Notification{
+			Kind:     notify.KindScheduledSend,
+			Title:    fmt.Sprintf("❌ Scheduled message to %s failed", name),
+			Body:     fmt.Sprintf("'%s' (%s) wasn't sent: %v\nSay 'confirm send %s' to try again.", snapshot.Subject, snapshot.ID, sendErr, snapshot.ID),
+			Priority: multiagent.PriorityHigh,
+			At:       now,
+			Subject:  snapshot.ID,
+		}, time.Time{}
+	}
+	return &notify.Notification{
+		Kind:     notify.KindScheduledSend,
+		Title:    fmt.Sprintf("📤 Sent '%s' to %s", snapshot.Subject, name),
+		Body:     fmt.Sprintf("Your scheduled message %s went out by %s.", snapshot.ID, channel),
+		Priority: multiagent.PriorityLow,
+		At:       now,
+		Subject:  snapshot.ID,
+	}, time.Time{}
+}
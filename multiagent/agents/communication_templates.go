@@ -0,0 +1,601 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// messageTemplatePrefix is the memory key prefix message templates are
+// stored under
+const messageTemplatePrefix = "message_template:"
+
+// Message template commands, besides those shared with project templates
+const (
+	templateEdit = "edit"
+	templateUse  = "use"
+)
+
+var (
+	// templatePlaceholder matches {{name}} or {{name|default}} in a template
+	templatePlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][\w.]*)\s*(?:\|\s*([^}]*?)\s*)?\}\}`)
+	// messageTemplateCreatePhrase matches "create follow_up template checkin:
+	// Hi {{first_name}}, ..." when the LLM can't read the request
+	messageTemplateCreatePhrase = regexp.MustCompile(`(?is)^(?:create|add|new|save)\s+(?:an?\s+)?(?:([\w-]+)\s+)?template\s+(?:called\s+|named\s+)?"?(.+?)"?\s*(?:with\s+subject\s+"([^"]*)"\s*)?:\s*(.+)$`)
+	// messageTemplateEditPhrase matches "edit template checkin: new text"
+	messageTemplateEditPhrase = regexp.MustCompile(`(?is)^(?:edit|update|change)\s+(?:the\s+)?template\s+"?(.+?)"?\s*:\s*(.+)$`)
+	// messageTemplateUsePhrase matches "use template checkin for Bob with
+	// topic=the roadmap"
+	messageTemplateUsePhrase = regexp.MustCompile(`(?i)^(?:use|apply|send)\s+(?:the\s+)?template\s+"?(.+?)"?\s+(?:for|to|with)\s+(.+?)(?:\s+with\s+(.+))?[.!]*$`)
+	// messageTemplateNamePhrase matches "show template checkin" and
+	// "delete template checkin"
+	messageTemplateNamePhrase = regexp.MustCompile(`(?i)^(show|view|display|delete|remove)\s+(?:the\s+)?template\s+"?(.+?)"?[.!?]*$`)
+	// templateAssignment matches "topic=the roadmap" in a list of values
+	templateAssignment = regexp.MustCompile(`([A-Za-z_][\w.]*)\s*[=:]\s*("[^"]*"|[^,;]+)`)
+)
+
+// templateBuiltins describes the variables filled in from the recipient and
+// the date, which templates need not declare
+var templateBuiltins = map[string]string{
+	"name":         "the recipient's full name",
+	"first_name":   "the recipient's first name",
+	"last_name":    "the recipient's last name",
+	"organization": "the recipient's organization",
+	"company":      "the recipient's organization",
+	"title":        "the recipient's job title",
+	"email":        "the recipient's email address",
+	"phone":        "the recipient's phone number",
+	"date":         "today's date",
+	"today":        "today's date",
+	"tomorrow":     "tomorrow's date",
+	"weekday":      "today's day of the week",
+	"time":         "the current time",
+	"month":        "the current month",
+	"year":         "the current year",
+}
+
+// templateVariables declares the placeholders in subject and content that
+// are not built in, required unless given a default
+func templateVariables(subject, content string) []TemplateVariable {
+	seen := make(map[string]bool)
+	var variables []TemplateVariable
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(subject+"\n"+content, -1) {
+		name := variableName(match[1])
+		if _, builtin := templateBuiltins[name]; builtin || seen[name] {
+			continue
+		}
+		seen[name] = true
+		variables = append(variables, TemplateVariable{
+			Name:         name,
+			Required:     match[2] == "",
+			DefaultValue: match[2],
+		})
+	}
+	return variables
+}
+
+// variableName normalizes a placeholder name: {{Contact.Name}} and
+// {{name}} are the same variable
+func variableName(name string) string {
+	return strings.TrimPrefix(strings.ToLower(name), "contact.")
+}
+
+// templateValues are the built-in variables for contact, which may be nil,
+// at now
+func templateValues(contact *Contact, now time.Time) map[string]string {
+	values := map[string]string{
+		"date":     now.Format("January 2, 2006"),
+		"today":    now.Format("January 2, 2006"),
+		"tomorrow": now.AddDate(0, 0, 1).Format("January 2, 2006"),
+		"weekday":  now.Weekday().String(),
+		"time":     now.Format("15:04"),
+		"month":    now.Month().String(),
+		"year":     fmt.Sprint(now.Year()),
+	}
+	if contact == nil {
+		return values
+	}
+	values["name"] = contact.Name
+	if fields := strings.Fields(contact.Name); len(fields) > 0 {
+		values["first_name"] = fields[0]
+		if len(fields) > 1 {
+			values["last_name"] = fields[len(fields)-1]
+		}
+	}
+	values["organization"] = contact.Organization
+	values["company"] = contact.Organization
+	values["title"] = contact.Title
+	values["email"] = contact.Email
+	values["phone"] = contact.Phone
+	return values
+}
+
+// renderTemplate fills the template's placeholders from values, then their
+// defaults. Placeholders left without a value read "[name]" and are
+// returned as missing.
+func renderTemplate(template *MessageTemplate, values map[string]string) (string, string, []string) {
+	var missing []string
+	seen := make(map[string]bool)
+	fill := func(text string) string {
+		return templatePlaceholder.ReplaceAllStringFunc(text, func(placeholder string) string {
+			match := templatePlaceholder.FindStringSubmatch(placeholder)
+			name := variableName(match[1])
+			if value := values[name]; value != "" {
+				return value
+			}
+			if match[2] != "" {
+				return match[2]
+			}
+			for _, variable := range template.Variables {
+				if variable.Name == name && variable.DefaultValue != "" {
+					return variable.DefaultValue
+				}
+			}
+			if !seen[name] {
+				seen[name] = true
+				missing = append(missing, name)
+			}
+			return "[" + name + "]"
+		})
+	}
+	return fill(template.Subject), fill(template.Content), missing
+}
+
+// parseTemplateValues reads "topic=the roadmap, day=Friday" into values
+func parseTemplateValues(text string) map[string]string {
+	values := make(map[string]string)
+	for _, match := range templateAssignment.FindAllStringSubmatch(text, -1) {
+		values[variableName(match[1])] = strings.Trim(strings.TrimSpace(match[2]), `"`)
+	}
+	return values
+}
+
+// parseTemplateCategory maps what the user called a category onto one,
+// or "" when it is none of them
+func parseTemplateCategory(category string) TemplateCategory {
+	category = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(category)), "-", "_")
+	switch category {
+	case "intro":
+		return TemplateCategoryIntroduction
+	case "followup":
+		return TemplateCategoryFollowUp
+	case "thanks", "thankyou":
+		return TemplateCategoryThankYou
+	}
+	switch c := TemplateCategory(category); c {
+	case TemplateCategoryIntroduction, TemplateCategoryFollowUp, TemplateCategoryMeeting, TemplateCategoryThankYou,
+		TemplateCategoryApology, TemplateCategoryReminder, TemplateCategoryNetworking, TemplateCategorySales, TemplateCategorySupport:
+		return c
+	}
+	return ""
+}
+
+// findTemplate returns the user's template named ref, by ID or name;
+// callers hold commMutex
+func (a *CommunicationManagerAgent) findTemplate(ctx context.Context, ref string) *MessageTemplate {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if ref == "" {
+		return nil
+	}
+	var partial *MessageTemplate
+	for _, template := range a.templates {
+		if !ownedBy(ctx, template.UserID) {
+			continue
+		}
+		name := strings.ToLower(template.Name)
+		if template.ID == ref || name == ref {
+			return template
+		}
+		if partial == nil && (strings.Contains(name, ref) || strings.Contains(ref, name)) {
+			partial = template
+		}
+	}
+	return partial
+}
+
+// templateForCategory returns the user's most used template of category,
+// or nil; callers hold commMutex
+func (a *CommunicationManagerAgent) templateForCategory(ctx context.Context, category TemplateCategory) *MessageTemplate {
+	var best *MessageTemplate
+	for _, template := range a.templates {
+		if !ownedBy(ctx, template.UserID) || template.Category != category {
+			continue
+		}
+		if best == nil || template.UsageCount > best.UsageCount ||
+			(template.UsageCount == best.UsageCount && template.UpdatedAt.After(best.UpdatedAt)) {
+			best = template
+		}
+	}
+	return best
+}
+
+// saveTemplate writes a template to memory
+func (a *CommunicationManagerAgent) saveTemplate(ctx context.Context, template *MessageTemplate) error {
+	if a.memoryStore == nil {
+		return nil
+	}
+	if err := a.memoryStore.Store(ownerContext(ctx, template.UserID), messageTemplatePrefix+template.ID, template); err != nil {
+		return fmt.Errorf("failed to save template %s: %w", template.ID, err)
+	}
+	return nil
+}
+
+// loadTemplatesFromMemory reads the user's templates into the agent
+func (a *CommunicationManagerAgent) loadTemplatesFromMemory(ctx context.Context) {
+	if a.memoryStore == nil {
+		return
+	}
+	keys, err := a.memoryStore.List(ctx, messageTemplatePrefix, 1000)
+	if err != nil {
+		return
+	}
+	values, err := a.memoryStore.GetMultiple(ctx, keys)
+	if err != nil {
+		return
+	}
+
+	a.commMutex.Lock()
+	defer a.commMutex.Unlock()
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		var template MessageTemplate
+		if err := json.Unmarshal(data, &template); err != nil || template.ID == "" {
+			continue
+		}
+		template.UserID = multiagent.UserIDFromContext(ctx)
+		a.templates[template.ID] = &template
+	}
+}
+
+// useTemplate renders template for contact and records the use; callers
+// hold commMutex
+func (a *CommunicationManagerAgent) useTemplate(ctx context.Context, template *MessageTemplate, contact *Contact, values map[string]string) (string, string, []string) {
+	filled := templateValues(contact, a.now().In(userLocation(ctx, a.memoryStore)))
+	for name, value := range values {
+		filled[name] = value
+	}
+	subject, content, missing := renderTemplate(template, filled)
+	template.UsageCount++
+	template.UpdatedAt = a.now()
+	return subject, content, missing
+}
+
+// handleTemplateManagement creates, lists, shows, edits, deletes and uses
+// message templates, whose {{variables}} are filled from the recipient,
+// the date and values the user gives
+func (a *CommunicationManagerAgent) handleTemplateManagement(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTemplatesFromMemory(ctx)
+	a.loadContactsFromMemory(ctx)
+
+	prompt, err := a.renderPrompt(ctx, "communication.template", prompts.Vars{"Request": msg.Content})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Action    string `json:"action"`
+		Name      string `json:"name"`
+		Category  string `json:"category"`
+		Subject   string `json:"subject"`
+		Content   string `json:"content"`
+		Method    string `json:"method"`
+		Recipient string `json:"recipient"`
+		Values    string `json:"values"`
+	}
+	templateSchema := objectSchema(map[string]string{
+		"action":    "string",
+		"name":      "string",
+		"category":  "string",
+		"subject":   "string",
+		"content":   "string",
+		"method":    "string",
+		"recipient": "string",
+		"values":    "string",
+	}, "action")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, templateSchema, &data); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse template request", "error", err)
+		content := strings.TrimSpace(msg.Content)
+		lower := strings.ToLower(content)
+		if match := messageTemplateCreatePhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.Category, data.Name, data.Subject, data.Content = templateCreate, match[1], match[2], match[3], match[4]
+		} else if match := messageTemplateEditPhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.Name, data.Content = templateEdit, match[1], match[2]
+		} else if match := messageTemplateUsePhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.Name, data.Recipient, data.Values = templateUse, match[1], match[2], match[3]
+		} else if match := messageTemplateNamePhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.Name = strings.ToLower(match[1]), match[2]
+		} else if strings.Contains(lower, "templates") {
+			data.Action = templateList
+		}
+	}
+
+	action := strings.ToLower(strings.TrimSpace(data.Action))
+	switch action {
+	case "add", "new", "save":
+		action = templateCreate
+	case "view", "display":
+		action = templateShow
+	case "update", "change":
+		action = templateEdit
+	case "remove":
+		action = templateDelete
+	case "apply", "send":
+		action = templateUse
+	}
+
+	switch action {
+	case templateCreate:
+		return a.createTemplate(ctx, msg, data.Name, data.Category, data.Subject, data.Content, data.Method)
+	case templateList:
+		return a.listTemplates(ctx, msg)
+	case templateShow, templateEdit, templateDelete, templateUse:
+	default:
+		return a.respond(msg, "📝 I can create, list, show, edit, delete or use message templates. Say something like \"create follow_up template checkin: Hi {{first_name}}, following up on {{topic}}\" or \"use template checkin for Bob with topic=the proposal\".", nil), nil
+	}
+
+	a.commMutex.Lock()
+	template := a.findTemplate(ctx, data.Name)
+	if template == nil {
+		a.commMutex.Unlock()
+		return a.respond(msg, fmt.Sprintf("❌ Template '%s' not found. Say \"list templates\" to see yours.", data.Name), nil), nil
+	}
+
+	switch action {
+	case templateShow:
+		content := describeTemplate(template)
+		templateID := template.ID
+		a.commMutex.Unlock()
+		return a.respond(msg, content, map[string]interface{}{
+			"template_id": templateID,
+			"action":      "template_shown",
+		}), nil
+
+	case templateDelete:
+		snapshot := *template
+		a.commMutex.Unlock()
+		if allowed, reply := a.authorize(ctx, msg, ActionDeleteMessageTemplate, snapshot.ID, fmt.Sprintf("delete the template '%s'", snapshot.Name)); !allowed {
+			return reply, nil
+		}
+		a.commMutex.Lock()
+		delete(a.templates, snapshot.ID)
+		a.commMutex.Unlock()
+		if a.memoryStore != nil {
+			if err := a.memoryStore.Delete(ownerContext(ctx, snapshot.UserID), messageTemplatePrefix+snapshot.ID); err != nil {
+				return nil, fmt.Errorf("failed to delete template %s: %w", snapshot.ID, err)
+			}
+		}
+		a.recordAudit(ctx, msg, audit.MessageTemplateDeleted, snapshot.ID, map[string]interface{}{"name": snapshot.Name})
+		return a.respond(msg, fmt.Sprintf("🗑️ Deleted template '%s'.", snapshot.Name), map[string]interface{}{
+			"template_id": snapshot.ID,
+			"action":      "template_deleted",
+		}), nil
+
+	case templateEdit:
+		var changes []string
+		if data.Content != "" && data.Content != template.Content {
+			template.Content = data.Content
+			changes = append(changes, "content")
+		}
+		if data.Subject != "" && data.Subject != template.Subject {
+			template.Subject = data.Subject
+			changes = append(changes, "subject")
+		}
+		if category := parseTemplateCategory(data.Category); category != "" && category != template.Category {
+			template.Category = category
+			changes = append(changes, "category")
+		}
+		if data.Method != "" && CommunicationMethod(data.Method) != template.Method {
+			template.Method = CommunicationMethod(data.Method)
+			changes = append(changes, "method")
+		}
+		if len(changes) == 0 {
+			name := template.Name
+			a.commMutex.Unlock()
+			return a.respond(msg, fmt.Sprintf("📝 What should change in '%s'? Say something like \"edit template %s: <new text>\".", name, name), nil), nil
+		}
+		template.Variables = templateVariables(template.Subject, template.Content)
+		template.UpdatedAt = a.now()
+		snapshot := *template
+		a.commMutex.Unlock()
+		if err := a.saveTemplate(ctx, &snapshot); err != nil {
+			return nil, err
+		}
+		a.recordAudit(ctx, msg, audit.MessageTemplateSaved, snapshot.ID, map[string]interface{}{
+			"name":    snapshot.Name,
+			"changes": changes,
+		})
+		return a.respond(msg, fmt.Sprintf("📝 Updated the %s of template '%s'.\n\n%s", strings.Join(changes, ", "), snapshot.Name, describeTemplate(&snapshot)), map[string]interface{}{
+			"template_id": snapshot.ID,
+			"action":      "template_updated",
+		}), nil
+	}
+
+	// Use the template for a contact
+	a.commMutex.Unlock()
+	contact := a.findContactByName(ctx, data.Recipient)
+	if contact == nil {
+		return a.respond(msg, fmt.Sprintf("❌ Contact '%s' not found. Add them as a contact first.", data.Recipient), nil), nil
+	}
+	a.commMutex.Lock()
+	subject, content, missing := a.useTemplate(ctx, template, contact, parseTemplateValues(data.Values))
+	snapshot := *template
+	a.commMutex.Unlock()
+	if err := a.saveTemplate(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+
+	method := snapshot.Method
+	if method == "" {
+		method = contact.PreferredComm
+	}
+	message := &CommunicationMessage{
+		ID:         a.newID("msg"),
+		ContactID:  contact.ID,
+		Subject:    subject,
+		Content:    content,
+		Method:     method,
+		Direction:  MessageDirectionOutbound,
+		Status:     MessageStatusDraft,
+		Priority:   multiagent.PriorityMedium,
+		TemplateID: snapshot.ID,
+		Tags:       []string{string(snapshot.Category)},
+		CreatedAt:  a.now(),
+		UpdatedAt:  a.now(),
+		Metadata:   make(map[string]interface{}),
+		UserID:     multiagent.UserIDFromContext(ctx),
+	}
+	a.commMutex.Lock()
+	a.messages[message.ID] = message
+	a.commMutex.Unlock()
+	if a.memoryStore != nil {
+		a.memoryStore.Store(ctx, fmt.Sprintf("communication_message:%s", message.ID), message)
+	}
+	a.recordAudit(ctx, msg, audit.MessageDrafted, message.ID, map[string]interface{}{
+		"contact_id":  contact.ID,
+		"subject":     message.Subject,
+		"method":      message.Method,
+		"template_id": snapshot.ID,
+	})
+
+	reply := fmt.Sprintf("✉️ **Message Drafted from '%s'**\n\n**To:** %s (%s)\n**Subject:** %s\n\n%s", snapshot.Name, contact.Name, contact.Email, subject, content)
+	if len(missing) > 0 {
+		reply += fmt.Sprintf("\n\n⚠️ Fill in: %s", strings.Join(missing, ", "))
+	}
+	reply += "\n\n---\n\n*Message saved as draft.*"
+	return a.respond(msg, reply, map[string]interface{}{
+		"message_id":  message.ID,
+		"contact_id":  contact.ID,
+		"template_id": snapshot.ID,
+		"missing":     missing,
+		"action":      "template_used",
+	}), nil
+}
+
+// createTemplate saves a new template, or replaces the content of the
+// user's template with the same name
+func (a *CommunicationManagerAgent) createTemplate(ctx context.Context, msg *multiagent.Message, name, category, subject, content, method string) (*multiagent.Message, error) {
+	name, content = strings.TrimSpace(name), strings.TrimSpace(content)
+	if name == "" || content == "" {
+		return a.respond(msg, "📝 A template needs a name and text. Say something like \"create follow_up template checkin: Hi {{first_name}}, following up on {{topic}}\".", nil), nil
+	}
+
+	now := a.now()
+	a.commMutex.Lock()
+	template := a.findTemplate(ctx, name)
+	if template == nil || !strings.EqualFold(template.Name, name) {
+		template = &MessageTemplate{
+			ID:        a.newID("template"),
+			Name:      name,
+			Category:  TemplateCategoryFollowUp,
+			Tags:      []string{},
+			CreatedAt: now,
+			Metadata:  make(map[string]interface{}),
+			UserID:    multiagent.UserIDFromContext(ctx),
+		}
+		a.templates[template.ID] = template
+	}
+	if c := parseTemplateCategory(category); c != "" {
+		template.Category = c
+	}
+	template.Subject = strings.TrimSpace(subject)
+	template.Content = content
+	if method != "" {
+		template.Method = CommunicationMethod(method)
+	}
+	template.Variables = templateVariables(template.Subject, template.Content)
+	template.UpdatedAt = now
+	snapshot := *template
+	a.commMutex.Unlock()
+
+	if err := a.saveTemplate(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	a.recordAudit(ctx, msg, audit.MessageTemplateSaved, snapshot.ID, map[string]interface{}{
+		"name":     snapshot.Name,
+		"category": snapshot.Category,
+	})
+	return a.respond(msg, fmt.Sprintf("📝 Saved template '%s'.\n\n%s", snapshot.Name, describeTemplate(&snapshot)), map[string]interface{}{
+		"template_id": snapshot.ID,
+		"action":      "template_saved",
+	}), nil
+}
+
+// listTemplates lists the user's templates by category
+func (a *CommunicationManagerAgent) listTemplates(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.commMutex.RLock()
+	var templates []MessageTemplate
+	for _, template := range a.templates {
+		if ownedBy(ctx, template.UserID) {
+			templates = append(templates, *template)
+		}
+	}
+	a.commMutex.RUnlock()
+
+	if len(templates) == 0 {
+		return a.respond(msg, "📝 You have no message templates yet. Say something like \"create follow_up template checkin: Hi {{first_name}}, following up on {{topic}}\".", nil), nil
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Category != templates[j].Category {
+			return templates[i].Category < templates[j].Category
+		}
+		return strings.ToLower(templates[i].Name) < strings.ToLower(templates[j].Name)
+	})
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("📝 **Message Templates** (%d)\n", len(templates)))
+	category := TemplateCategory("-")
+	for _, template := range templates {
+		if template.Category != category {
+			category = template.Category
+			b.WriteString(fmt.Sprintf("\n**%s**\n", strings.ReplaceAll(string(category), "_", " ")))
+		}
+		b.WriteString(fmt.Sprintf("• %s — used %d time(s)", template.Name, template.UsageCount))
+		if names := variableNames(template.Variables); names != "" {
+			b.WriteString(" — needs " + names)
+		}
+		b.WriteString("\n")
+	}
+	return a.respond(msg, b.String(), map[string]interface{}{
+		"action": "templates_listed",
+		"count":  len(templates),
+	}), nil
+}
+
+// describeTemplate shows a template's text, variables and usage
+func describeTemplate(template *MessageTemplate) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("**%s** (%s)\n", template.Name, strings.ReplaceAll(string(template.Category), "_", " ")))
+	if template.Subject != "" {
+		b.WriteString(fmt.Sprintf("**Subject:** %s\n", template.Subject))
+	}
+	b.WriteString(fmt.Sprintf("\n%s\n", template.Content))
+	if names := variableNames(template.Variables); names != "" {
+		b.WriteString(fmt.Sprintf("\n🔤 Variables: %s", names))
+	}
+	b.WriteString(fmt.Sprintf("\n📈 Used %d time(s)", template.UsageCount))
+	return b.String()
+}
+
+// variableNames lists template variables, marking the optional ones
+func variableNames(variables []TemplateVariable) string {
+	names := make([]string, 0, len(variables))
+	for _, variable := range variables {
+		if variable.Required {
+			names = append(names, variable.Name)
+		} else {
+			names = append(names, variable.Name+" (optional)")
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,176 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+)
+
+// Side-effecting actions a ConfirmationPolicy can hold for the user's approval
+const (
+	ActionSendEmail   = "send_email"
+	ActionDeleteTask  = "delete_task"
+	ActionCancelEvent = "cancel_event"
+)
+
+// confirmableActions are the actions agents ask approval for
+var confirmableActions = []string{ActionSendEmail, ActionDeleteTask, ActionCancelEvent}
+
+// approvalPhrase recognises the user approving a proposed action; any other
+// answer declines it
+var approvalPhrase = regexp.MustCompile(`(?i)^\s*(y|yes|yep|yeah|sure|ok(ay)?|confirm(ed)?|approved?|go ahead|do it|please do)\b`)
+
+// ConfirmationPolicy names the actions that wait for the user's approval
+// before they run
+type ConfirmationPolicy map[string]bool
+
+// DefaultConfirmationPolicy holds every confirmable action for approval
+func DefaultConfirmationPolicy() ConfirmationPolicy {
+	policy := make(ConfirmationPolicy, len(confirmableActions))
+	for _, action := range confirmableActions {
+		policy[action] = true
+	}
+	return policy
+}
+
+// ParseConfirmationPolicy reads a comma-separated list of actions, e.g.
+// "send_email,cancel_event"; "none" confirms nothing and "all" everything
+func ParseConfirmationPolicy(list string) (ConfirmationPolicy, error) {
+	switch strings.TrimSpace(list) {
+	case "all":
+		return DefaultConfirmationPolicy(), nil
+	case "none", "":
+		return ConfirmationPolicy{}, nil
+	}
+
+	policy := ConfirmationPolicy{}
+	known := DefaultConfirmationPolicy()
+	for _, action := range strings.Split(list, ",") {
+		action = strings.TrimSpace(action)
+		if !known[action] {
+			return nil, fmt.Errorf("unknown action %q (want %s)", action, strings.Join(confirmableActions, ", "))
+		}
+		policy[action] = true
+	}
+	return policy, nil
+}
+
+// Requires reports whether action waits for the user's approval
+func (p ConfirmationPolicy) Requires(action string) bool {
+	return p[action]
+}
+
+// String lists the actions the policy holds for approval
+func (p ConfirmationPolicy) String() string {
+	var actions []string
+	for action, required := range p {
+		if required {
+			actions = append(actions, action)
+		}
+	}
+	if len(actions) == 0 {
+		return "none"
+	}
+	sort.Strings(actions)
+	return strings.Join(actions, ",")
+}
+
+// ProposedAction is the structured payload of a request for the user's
+// approval
+type ProposedAction struct {
+	ID     string             `json:"id"`
+	Action string             `json:"action"`
+	Agent  multiagent.AgentID `json:"agent"`
+	// Subject is the ID of what the action touches, e.g. a task ID
+	Subject    string            `json:"subject"`
+	Summary    string            `json:"summary"`
+	Details    map[string]string `json:"details,omitempty"`
+	ProposedAt time.Time         `json:"proposed_at"`
+}
+
+// confirmAction reports whether proposal may run now. Actions the access
+// policy denies are refused with the returned reply. Actions the
+// confirmation policy holds are first proposed to the user as a question,
+// and the returned message is the reply; the request resumes with their
+// answer, which approves the action or declines it with a reply saying so.
+// Every proposal and answer is audited.
+func (a *BaseAgent) confirmAction(ctx context.Context, msg *multiagent.Message, proposal ProposedAction) (bool, *multiagent.Message) {
+	if allowed, reply := a.authorize(ctx, msg, proposal.Action, proposal.Subject, proposal.Summary); !allowed {
+		return false, reply
+	}
+	if !a.confirmations.Requires(proposal.Action) {
+		return true, nil
+	}
+	proposal.Agent = a.id
+
+	if answered, ok := answeredProposal(msg); ok && answered.Action == proposal.Action && answered.Subject == proposal.Subject {
+		answer, _ := userAnswer(msg)
+		payload := map[string]interface{}{"proposal_id": answered.ID, "action": answered.Action, "answer": answer}
+		if approvalPhrase.MatchString(answer) {
+			a.recordAudit(ctx, msg, audit.ActionApproved, answered.Subject, payload)
+			return true, nil
+		}
+		a.recordAudit(ctx, msg, audit.ActionDeclined, answered.Subject, payload)
+		return false, &multiagent.Message{
+			ID:        a.messageID(),
+			From:      a.id,
+			To:        []multiagent.AgentID{msg.From},
+			Type:      multiagent.MessageTypeResponse,
+			Content:   fmt.Sprintf("👍 Okay, I won't %s.", answered.Summary),
+			ReplyTo:   msg.ID,
+			Timestamp: a.now(),
+			Context: map[string]interface{}{
+				"action":      "action_declined",
+				"proposal_id": answered.ID,
+			},
+		}
+	}
+
+	proposal.ID = a.newID("proposal")
+	proposal.ProposedAt = a.now()
+	a.recordAudit(ctx, msg, audit.ActionProposed, proposal.Subject, map[string]interface{}{
+		"proposal_id": proposal.ID,
+		"action":      proposal.Action,
+		"summary":     proposal.Summary,
+	})
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "⚠️ **Please confirm**\n\nShould I %s?\n", proposal.Summary)
+	keys := make([]string, 0, len(proposal.Details))
+	for key := range proposal.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&content, "• %s: %s\n", key, proposal.Details[key])
+	}
+	content.WriteString("\nReply yes to go ahead, or no to leave it.")
+
+	question := a.askUser(msg, content.String())
+	question.Context["action"] = "confirmation_required"
+	question.Context["proposed_action"] = proposal
+	return false, question
+}
+
+// answeredProposal returns the proposed action msg resumes with the user's
+// answer to, if any
+func answeredProposal(msg *multiagent.Message) (ProposedAction, bool) {
+	var proposal ProposedAction
+	asked, ok := msg.Context["question_context"].(map[string]interface{})
+	if !ok || asked["proposed_action"] == nil {
+		return proposal, false
+	}
+	// The proposal may have been stored as JSON while the request was paused
+	data, err := json.Marshal(asked["proposed_action"])
+	if err != nil || json.Unmarshal(data, &proposal) != nil {
+		return proposal, false
+	}
+	return proposal, proposal.ID != ""
+}
@@ -0,0 +1,688 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/contactio"
+)
+
+// Formats contacts can be exported in and imported from
+const (
+	ContactFormatVCard = "vcard"
+	ContactFormatCSV   = "csv"
+)
+
+// Contact metadata keys: the ID a contact had in the file it was imported
+// from, so importing it again updates it, and the addresses and numbers
+// beyond its primary Email and Phone
+const (
+	contactImportIDKey    = "import_id"
+	contactOtherEmailsKey = "other_emails"
+	contactOtherPhonesKey = "other_phones"
+)
+
+// contactMergePrefix is the key prefix of imported contacts awaiting a
+// merge decision
+const contactMergePrefix = "contact_merge:"
+
+var (
+	// contactMergeID matches the IDs of pending merges
+	contactMergeID = regexp.MustCompile(`\bmerge_\d+_\d+\b`)
+	// allPhrase matches "all" as in "merge all"
+	allPhrase = regexp.MustCompile(`\ball\b`)
+)
+
+// ContactExchanger is implemented by agents whose contacts can be exported
+// to and imported from other address books
+type ContactExchanger interface {
+	// ExportContacts returns the contacts of the user ctx acts for in format
+	ExportContacts(ctx context.Context, format string) ([]byte, error)
+	// ImportContacts adds the contacts in data, which is in format, to the
+	// address book of the user ctx acts for
+	ImportContacts(ctx context.Context, format string, data []byte) (*ContactImportResult, error)
+}
+
+// ContactImportResult reports what ImportContacts did
+type ContactImportResult struct {
+	Imported int `json:"imported"`
+	Updated  int `json:"updated"`
+	// Unchanged counts contacts the address book already had in full
+	Unchanged int `json:"unchanged"`
+	// Duplicates are contacts that look like ones the user has, awaiting
+	// their decision
+	Duplicates []ContactMerge `json:"duplicates,omitempty"`
+}
+
+// ContactMerge is an imported contact sharing an email address or phone
+// number with one the user has. The user merges the two, keeps both or
+// skips the import.
+type ContactMerge struct {
+	ID        string    `json:"id"`
+	ContactID string    `json:"contact_id"` // The contact the user has
+	Incoming  Contact   `json:"incoming"`
+	Reason    string    `json:"reason"` // e.g. "same email jane@example.com"
+	CreatedAt time.Time `json:"created_at"`
+	UserID    string    `json:"user_id,omitempty"`
+}
+
+// ExportContacts returns the user's contacts as vCard or Google Contacts
+// CSV, sorted by name
+func (a *CommunicationManagerAgent) ExportContacts(ctx context.Context, format string) ([]byte, error) {
+	a.loadContactsFromMemory(ctx)
+
+	a.commMutex.RLock()
+	var exported []contactio.Contact
+	for _, contact := range a.contacts {
+		if ownedBy(ctx, contact.UserID) {
+			exported = append(exported, contactio.Contact{
+				ID:           contact.ID,
+				Name:         contact.Name,
+				Emails:       contactEmails(contact),
+				Phones:       contactPhones(contact),
+				Organization: contact.Organization,
+				Title:        contact.Title,
+				Notes:        contact.Notes,
+				Labels:       append([]string(nil), contact.Tags...),
+			})
+		}
+	}
+	a.commMutex.RUnlock()
+	sort.SliceStable(exported, func(i, j int) bool {
+		return strings.ToLower(exported[i].Name) < strings.ToLower(exported[j].Name)
+	})
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case ContactFormatVCard:
+		err = contactio.EncodeVCard(&buf, exported)
+	case ContactFormatCSV:
+		err = contactio.EncodeCSV(&buf, exported)
+	default:
+		return nil, fmt.Errorf("unknown contact format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to export contacts: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ImportContacts adds the contacts in a vCard or CSV file to the user's
+// address book. Contacts imported before, or exported from here, are
+// updated with what the file adds; contacts sharing an email address or
+// phone number with one the user has wait for them to decide on a merge.
+func (a *CommunicationManagerAgent) ImportContacts(ctx context.Context, format string, data []byte) (*ContactImportResult, error) {
+	var sources []contactio.Contact
+	var err error
+	switch format {
+	case ContactFormatVCard:
+		sources, err = contactio.DecodeVCard(bytes.NewReader(data))
+	case ContactFormatCSV:
+		sources, err = contactio.DecodeCSV(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unknown contact format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to import contacts: %w", err)
+	}
+
+	a.loadContactsFromMemory(ctx)
+	a.loadContactMergesFromMemory(ctx)
+	now := a.now()
+	result := &ContactImportResult{}
+
+	a.commMutex.Lock()
+	var changed []Contact
+	for _, source := range sources {
+		incoming := importedContact(ctx, source, a.newID("contact"), now)
+		if existing := a.contactByImportID(ctx, source.ID); existing != nil {
+			if mergeContact(existing, incoming) {
+				existing.UpdatedAt = now
+				changed = append(changed, cloneContact(existing))
+				result.Updated++
+			} else {
+				result.Unchanged++
+			}
+			continue
+		}
+
+		existing, reason := a.duplicateOf(ctx, incoming)
+		if existing == nil {
+			a.contacts[incoming.ID] = incoming
+			changed = append(changed, cloneContact(incoming))
+			result.Imported++
+			continue
+		}
+		if probe := cloneContact(existing); !mergeContact(&probe, incoming) || a.mergePending(ctx, existing.ID, incoming) {
+			result.Unchanged++
+			continue
+		}
+		merge := &ContactMerge{
+			ID:        a.newID("merge"),
+			ContactID: existing.ID,
+			Incoming:  *incoming,
+			Reason:    reason,
+			CreatedAt: now,
+			UserID:    multiagent.UserIDFromContext(ctx),
+		}
+		a.contactMerges[merge.ID] = merge
+		result.Duplicates = append(result.Duplicates, *merge)
+	}
+	a.commMutex.Unlock()
+
+	for i := range changed {
+		if err := a.saveContact(ctx, &changed[i]); err != nil {
+			return result, err
+		}
+	}
+	for i := range result.Duplicates {
+		if err := a.saveContactMerge(ctx, &result.Duplicates[i]); err != nil {
+			return result, err
+		}
+	}
+
+	a.recordAudit(ctx, nil, audit.ContactsImported, "", map[string]interface{}{
+		"format":     format,
+		"imported":   result.Imported,
+		"updated":    result.Updated,
+		"unchanged":  result.Unchanged,
+		"duplicates": len(result.Duplicates),
+	})
+	return result, nil
+}
+
+// handleExportContacts replies with the user's contacts as vCard, or as
+// Google Contacts CSV when asked for a spreadsheet
+func (a *CommunicationManagerAgent) handleExportContacts(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	content := strings.ToLower(msg.Content)
+	format, kind := ContactFormatVCard, "vCard (.vcf)"
+	if strings.Contains(content, "csv") || strings.Contains(content, "google") || strings.Contains(content, "spreadsheet") {
+		format, kind = ContactFormatCSV, "Google Contacts CSV"
+	}
+
+	data, err := a.ExportContacts(ctx, format)
+	if err != nil {
+		return nil, err
+	}
+	return a.respond(msg, fmt.Sprintf("📤 **Contact Export — %s**\n\nSave the following to import it into another address book:\n\n```\n%s```", kind, data), map[string]interface{}{
+		"action": "contacts_exported",
+		"format": format,
+		"data":   string(data),
+	}), nil
+}
+
+// handleImportContacts imports the vCards pasted into the message
+func (a *CommunicationManagerAgent) handleImportContacts(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	start := strings.Index(strings.ToUpper(msg.Content), "BEGIN:VCARD")
+	if start < 0 {
+		return a.respond(msg, "📥 Paste the contacts' vCard text (BEGIN:VCARD … END:VCARD) after \"import contacts\", or upload a .vcf file or a Google Contacts CSV export to POST /contacts/import.", nil), nil
+	}
+
+	result, err := a.ImportContacts(ctx, ContactFormatVCard, []byte(msg.Content[start:]))
+	if err != nil {
+		return a.respond(msg, fmt.Sprintf("❌ I couldn't read those contacts: %v", err), nil), nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "📥 **Contacts Imported**\n\n✅ New: %d\n🔄 Updated: %d\n➖ Already known: %d\n", result.Imported, result.Updated, result.Unchanged)
+	if len(result.Duplicates) > 0 {
+		fmt.Fprintf(&b, "\n👥 %d look like contacts you have:\n", len(result.Duplicates))
+		a.commMutex.RLock()
+		for _, merge := range result.Duplicates {
+			b.WriteString(a.describeMerge(&merge))
+		}
+		a.commMutex.RUnlock()
+		b.WriteString(mergeInstructions)
+	}
+	return a.respond(msg, strings.TrimSpace(b.String()), map[string]interface{}{
+		"action":     "contacts_imported",
+		"imported":   result.Imported,
+		"updated":    result.Updated,
+		"unchanged":  result.Unchanged,
+		"duplicates": len(result.Duplicates),
+	}), nil
+}
+
+// mergeInstructions tells the user how to settle duplicates
+const mergeInstructions = "\nSay \"merge merge_x\" to combine the two, \"keep both merge_x\" to add it as a new contact, or \"skip merge_x\" to drop it; \"all\" in place of an ID settles every one."
+
+// handleContactMerges lists the duplicates awaiting the user's decision,
+// or merges, keeps or skips the ones they name
+func (a *CommunicationManagerAgent) handleContactMerges(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadContactsFromMemory(ctx)
+	a.loadContactMergesFromMemory(ctx)
+	content := strings.ToLower(msg.Content)
+
+	a.commMutex.RLock()
+	var pending []*ContactMerge
+	for _, merge := range a.contactMerges {
+		if ownedBy(ctx, merge.UserID) {
+			pending = append(pending, merge)
+		}
+	}
+	a.commMutex.RUnlock()
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+	if len(pending) == 0 {
+		return a.respond(msg, "👥 No duplicate contacts are waiting for you.", nil), nil
+	}
+
+	var action string
+	switch {
+	case strings.Contains(content, "keep both") || strings.Contains(content, "keep it") || strings.Contains(content, "keep "):
+		action = "keep"
+	case strings.Contains(content, "skip") || strings.Contains(content, "discard") || strings.Contains(content, "drop") || strings.Contains(content, "ignore"):
+		action = "skip"
+	case strings.Contains(content, "merge") && !strings.Contains(content, "show") && !strings.Contains(content, "list") && !strings.Contains(content, "review"):
+		action = "merge"
+	}
+
+	var chosen []*ContactMerge
+	ids := contactMergeID.FindAllString(content, -1)
+	for _, merge := range pending {
+		for _, id := range ids {
+			if merge.ID == id {
+				chosen = append(chosen, merge)
+			}
+		}
+	}
+	if len(ids) == 0 && (allPhrase.MatchString(content) || len(pending) == 1) {
+		chosen = pending
+	}
+
+	if action == "" || len(chosen) == 0 {
+		var b strings.Builder
+		fmt.Fprintf(&b, "👥 **Possible Duplicates** (%d)\n\n", len(pending))
+		a.commMutex.RLock()
+		for _, merge := range pending {
+			b.WriteString(a.describeMerge(merge))
+		}
+		a.commMutex.RUnlock()
+		b.WriteString(mergeInstructions)
+		return a.respond(msg, b.String(), map[string]interface{}{
+			"action":  "contact_merges_listed",
+			"pending": len(pending),
+		}), nil
+	}
+
+	var lines []string
+	for _, merge := range chosen {
+		line, err := a.settleMerge(ctx, msg, merge, action)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	if left := len(pending) - len(chosen); left > 0 {
+		lines = append(lines, fmt.Sprintf("\n👥 %d more duplicate(s) waiting; say \"show duplicates\" to review them.", left))
+	}
+	return a.respond(msg, strings.Join(lines, "\n"), map[string]interface{}{
+		"action":  "contact_merges_settled",
+		"choice":  action,
+		"settled": len(chosen),
+	}), nil
+}
+
+// settleMerge merges, keeps or skips an imported duplicate and forgets it
+func (a *CommunicationManagerAgent) settleMerge(ctx context.Context, msg *multiagent.Message, merge *ContactMerge, action string) (string, error) {
+	now := a.now()
+	a.commMutex.Lock()
+	existing := a.contacts[merge.ContactID]
+	if existing == nil || !ownedBy(ctx, existing.UserID) {
+		// The contact was removed since; the import stands on its own
+		existing = nil
+		if action == "merge" {
+			action = "keep"
+		}
+	}
+	var saved *Contact
+	switch action {
+	case "merge":
+		mergeContact(existing, &merge.Incoming)
+		existing.UpdatedAt = now
+		snapshot := cloneContact(existing)
+		saved = &snapshot
+	case "keep":
+		incoming := cloneContact(&merge.Incoming)
+		incoming.CreatedAt, incoming.UpdatedAt = now, now
+		a.contacts[incoming.ID] = &incoming
+		saved = &incoming
+	}
+	delete(a.contactMerges, merge.ID)
+	a.commMutex.Unlock()
+
+	if saved != nil {
+		if err := a.saveContact(ctx, saved); err != nil {
+			return "", err
+		}
+	}
+	if a.memoryStore != nil {
+		if err := a.memoryStore.Delete(ownerContext(ctx, merge.UserID), contactMergePrefix+merge.ID); err != nil {
+			a.logger.WarnContext(ctx, "Failed to delete contact merge", "merge_id", merge.ID, "error", err)
+		}
+	}
+
+	switch action {
+	case "merge":
+		a.recordAudit(ctx, msg, audit.ContactsMerged, saved.ID, map[string]interface{}{
+			"merge_id": merge.ID,
+			"reason":   merge.Reason,
+		})
+		return fmt.Sprintf("🔗 Merged %s into %s.", merge.Incoming.Name, saved.Name), nil
+	case "keep":
+		a.recordAudit(ctx, msg, audit.ContactAdded, saved.ID, map[string]interface{}{
+			"name":   saved.Name,
+			"email":  saved.Email,
+			"source": "import",
+		})
+		return fmt.Sprintf("➕ Added %s as a separate contact.", saved.Name), nil
+	default:
+		return fmt.Sprintf("⏭️ Skipped the imported %s.", merge.Incoming.Name), nil
+	}
+}
+
+// describeMerge shows a pending merge side by side with the contact it
+// duplicates; callers hold commMutex
+func (a *CommunicationManagerAgent) describeMerge(merge *ContactMerge) string {
+	existing := "a removed contact"
+	if contact := a.contacts[merge.ContactID]; contact != nil {
+		existing = describeContact(contact)
+	}
+	return fmt.Sprintf("• **%s** (%s)\n   yours: %s\n   imported: %s\n", merge.ID, merge.Reason, existing, describeContact(&merge.Incoming))
+}
+
+// describeContact sums a contact up in one line
+func describeContact(contact *Contact) string {
+	parts := []string{contact.Name}
+	parts = append(parts, contactEmails(contact)...)
+	parts = append(parts, contactPhones(contact)...)
+	if contact.Organization != "" {
+		parts = append(parts, contact.Organization)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// importedContact builds the contact a file's entry becomes
+func importedContact(ctx context.Context, source contactio.Contact, id string, now time.Time) *Contact {
+	contact := &Contact{
+		ID:             id,
+		Name:           source.DisplayName(),
+		Organization:   source.Organization,
+		Title:          source.Title,
+		Priority:       ContactPriorityMedium,
+		Tags:           append([]string{}, source.Labels...),
+		Notes:          source.Notes,
+		Status:         ContactStatusActive,
+		ContactFreq:    ContactFrequencyAsNeeded,
+		SocialProfiles: make(map[string]string),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Metadata:       make(map[string]interface{}),
+		UserID:         multiagent.UserIDFromContext(ctx),
+	}
+	if source.ID != "" {
+		contact.Metadata[contactImportIDKey] = source.ID
+	}
+	if len(source.Emails) > 0 {
+		contact.Email = source.Emails[0]
+		if len(source.Emails) > 1 {
+			contact.Metadata[contactOtherEmailsKey] = append([]string(nil), source.Emails[1:]...)
+		}
+	}
+	if len(source.Phones) > 0 {
+		contact.Phone = source.Phones[0]
+		if len(source.Phones) > 1 {
+			contact.Metadata[contactOtherPhonesKey] = append([]string(nil), source.Phones[1:]...)
+		}
+	}
+	return contact
+}
+
+// contactByImportID returns the user's contact a file's entry came from or
+// was imported as before; callers hold commMutex
+func (a *CommunicationManagerAgent) contactByImportID(ctx context.Context, id string) *Contact {
+	if id == "" {
+		return nil
+	}
+	for _, contact := range a.contacts {
+		if !ownedBy(ctx, contact.UserID) {
+			continue
+		}
+		if importID, _ := contact.Metadata[contactImportIDKey].(string); contact.ID == id || importID == id {
+			return contact
+		}
+	}
+	return nil
+}
+
+// duplicateOf returns the user's contact sharing an email address or phone
+// number with contact, and which; callers hold commMutex
+func (a *CommunicationManagerAgent) duplicateOf(ctx context.Context, contact *Contact) (*Contact, string) {
+	emails := make(map[string]bool)
+	for _, email := range contactEmails(contact) {
+		emails[contactio.NormalizeEmail(email)] = true
+	}
+	phones := make(map[string]bool)
+	for _, phone := range contactPhones(contact) {
+		if normalized := contactio.NormalizePhone(phone); normalized != "" {
+			phones[normalized] = true
+		}
+	}
+
+	ids := make([]string, 0, len(a.contacts))
+	for id := range a.contacts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		existing := a.contacts[id]
+		if !ownedBy(ctx, existing.UserID) || existing.ID == contact.ID {
+			continue
+		}
+		for _, email := range contactEmails(existing) {
+			if emails[contactio.NormalizeEmail(email)] {
+				return existing, "same email " + email
+			}
+		}
+		for _, phone := range contactPhones(existing) {
+			if phones[contactio.NormalizePhone(phone)] {
+				return existing, "same phone " + phone
+			}
+		}
+	}
+	return nil, ""
+}
+
+// mergePending reports whether the same contact already waits to be
+// merged into contactID, as when a file is imported twice; callers hold
+// commMutex
+func (a *CommunicationManagerAgent) mergePending(ctx context.Context, contactID string, incoming *Contact) bool {
+	for _, merge := range a.contactMerges {
+		if ownedBy(ctx, merge.UserID) && merge.ContactID == contactID && describeContact(&merge.Incoming) == describeContact(incoming) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeContact adds to into what from knows that it doesn't, keeping into's
+// name, and reports whether anything was added
+func mergeContact(into, from *Contact) bool {
+	changed := false
+	fill := func(field *string, value string) {
+		if strings.TrimSpace(*field) == "" && strings.TrimSpace(value) != "" {
+			*field = value
+			changed = true
+		}
+	}
+	fill(&into.Name, from.Name)
+	fill(&into.Organization, from.Organization)
+	fill(&into.Title, from.Title)
+	if notes := strings.TrimSpace(from.Notes); notes != "" && !strings.Contains(into.Notes, notes) {
+		into.Notes = strings.TrimSpace(into.Notes + "\n\n" + notes)
+		changed = true
+	}
+
+	emails := addContactValues(contactEmails(into), contactio.NormalizeEmail, contactEmails(from))
+	phones := addContactValues(contactPhones(into), contactio.NormalizePhone, contactPhones(from))
+	if len(emails) > len(contactEmails(into)) || len(phones) > len(contactPhones(into)) {
+		setContactValues(into, emails, phones)
+		changed = true
+	}
+
+	for _, tag := range from.Tags {
+		known := false
+		for _, existing := range into.Tags {
+			known = known || strings.EqualFold(existing, tag)
+		}
+		if !known {
+			into.Tags = append(into.Tags, tag)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// addContactValues appends the values not already in list, compared by key
+func addContactValues(list []string, key func(string) string, values []string) []string {
+	list = append([]string(nil), list...)
+	for _, value := range values {
+		known := false
+		for _, existing := range list {
+			known = known || existing == value || (key(value) != "" && key(existing) == key(value))
+		}
+		if !known {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// setContactValues stores emails and phones as the contact's primary
+// Email and Phone and the rest in its metadata
+func setContactValues(contact *Contact, emails, phones []string) {
+	if contact.Metadata == nil {
+		contact.Metadata = make(map[string]interface{})
+	}
+	contact.Email, contact.Phone = "", ""
+	delete(contact.Metadata, contactOtherEmailsKey)
+	delete(contact.Metadata, contactOtherPhonesKey)
+	if len(emails) > 0 {
+		contact.Email = emails[0]
+		if len(emails) > 1 {
+			contact.Metadata[contactOtherEmailsKey] = emails[1:]
+		}
+	}
+	if len(phones) > 0 {
+		contact.Phone = phones[0]
+		if len(phones) > 1 {
+			contact.Metadata[contactOtherPhonesKey] = phones[1:]
+		}
+	}
+}
+
+// contactEmails returns every email address of contact, primary first
+func contactEmails(contact *Contact) []string {
+	return contactValues(contact.Email, contact.Metadata[contactOtherEmailsKey])
+}
+
+// contactPhones returns every phone number of contact, primary first
+func contactPhones(contact *Contact) []string {
+	return contactValues(contact.Phone, contact.Metadata[contactOtherPhonesKey])
+}
+
+// contactValues lists primary and the others, which are a []string or,
+// once read back from memory, a []interface{}
+func contactValues(primary string, others interface{}) []string {
+	var values []string
+	if strings.TrimSpace(primary) != "" {
+		values = append(values, primary)
+	}
+	switch others := others.(type) {
+	case []string:
+		values = append(values, others...)
+	case []interface{}:
+		for _, other := range others {
+			if s, ok := other.(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// cloneContact copies contact deeply enough to save outside commMutex
+func cloneContact(contact *Contact) Contact {
+	clone := *contact
+	clone.Tags = append([]string(nil), contact.Tags...)
+	clone.SocialProfiles = make(map[string]string, len(contact.SocialProfiles))
+	for key, value := range contact.SocialProfiles {
+		clone.SocialProfiles[key] = value
+	}
+	clone.Metadata = make(map[string]interface{}, len(contact.Metadata))
+	for key, value := range contact.Metadata {
+		clone.Metadata[key] = value
+	}
+	return clone
+}
+
+// saveContact persists a contact for its owner
+func (a *CommunicationManagerAgent) saveContact(ctx context.Context, contact *Contact) error {
+	if a.memoryStore == nil {
+		return nil
+	}
+	if err := a.memoryStore.Store(ownerContext(ctx, contact.UserID), fmt.Sprintf("contact:%s", contact.ID), contact); err != nil {
+		return fmt.Errorf("failed to save contact %s: %w", contact.ID, err)
+	}
+	return nil
+}
+
+// saveContactMerge persists a pending merge for its owner
+func (a *CommunicationManagerAgent) saveContactMerge(ctx context.Context, merge *ContactMerge) error {
+	if a.memoryStore == nil {
+		return nil
+	}
+	if err := a.memoryStore.Store(ownerContext(ctx, merge.UserID), contactMergePrefix+merge.ID, merge); err != nil {
+		return fmt.Errorf("failed to save contact merge %s: %w", merge.ID, err)
+	}
+	return nil
+}
+
+// loadContactMergesFromMemory reads the user's pending merges into the
+// agent
+func (a *CommunicationManagerAgent) loadContactMergesFromMemory(ctx context.Context) {
+	if a.memoryStore == nil {
+		return
+	}
+	keys, err := a.memoryStore.List(ctx, contactMergePrefix, 1000)
+	if err != nil {
+		return
+	}
+	values, err := a.memoryStore.GetMultiple(ctx, keys)
+	if err != nil {
+		return
+	}
+
+	a.commMutex.Lock()
+	defer a.commMutex.Unlock()
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		var merge ContactMerge
+		if err := json.Unmarshal(data, &merge); err != nil || merge.ID == "" {
+			continue
+		}
+		merge.UserID = multiagent.UserIDFromContext(ctx)
+		merge.Incoming.UserID = merge.UserID
+		a.contactMerges[merge.ID] = &merge
+	}
+}
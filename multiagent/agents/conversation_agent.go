@@ -4,17 +4,21 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
 )
 
 // ConversationAgent specializes in natural language interactions with users
 type ConversationAgent struct {
 	*BaseAgent
 	conversations map[string]*multiagent.ConversationContext
+	convMutex     sync.Mutex
+	intents       *IntentRouter
 }
 
 // NewConversationAgent creates a new conversation agent
@@ -33,6 +37,25 @@ func NewConversationAgent(config BaseAgentConfig) *ConversationAgent {
 	return &ConversationAgent{
 		BaseAgent:     NewBaseAgent(config),
 		conversations: make(map[string]*multiagent.ConversationContext),
+		intents: NewIntentRouter(IntentRouterConfig{
+			Name:        "ConversationAgent",
+			LLMProvider: routingProvider(config),
+			Prompts:     config.Prompts,
+			Default:     "chat",
+			// Keyword matches sit exactly at the threshold, so they route
+			// without asking
+			MinConfidence: routeConfidence,
+			Intents: []Intent{
+				{Label: "research", Description: "find, investigate, or verify information", Keywords: []string{"research", "find information", "look up", "search for", "information about", "investigate", "analyze data", "fact check", "verify", "my notes", "my documents"}},
+				{Label: "task", Description: "personal tasks, to-dos, and reminders", Keywords: []string{"create task", "add task", "task", "todo", "to-do", "to do", "remind me", "reminder", "productivity", "time block", "timebox", "block out time"}},
+				{Label: "project", Description: "projects, milestones, and planning", Keywords: []string{"create project", "new project", "project", "plan", "planning", "milestone", "timeline", "manage", "track progress"}},
+				{Label: "schedule", Description: "calendar events, meetings, and availability", Keywords: []string{"schedule", "calendar", "appointment", "meeting", "book", "available", "free time", "time slot"}},
+				{Label: "communication", Description: "contacts, emails, and messages to other people", Keywords: []string{"email", "message", "contact", "send", "compose", "draft", "write email", "communication", "follow up"}},
+				{Label: "coder", Description: "writing or debugging code", Keywords: []string{"write code", "programming", "function", "algorithm", "write a program", "debug", "script", "software"}},
+				{Label: "analyst", Description: "data analysis, metrics, and trends", Keywords: []string{"analyze", "data analysis", "statistics", "trends", "patterns", "insights", "metrics", "performance"}},
+				{Label: "writer", Description: "long-form writing such as articles and reports", Keywords: []string{"write article", "draft", "compose", "blog post", "document", "report", "essay", "outline"}},
+			},
+		}),
 	}
 }
 
@@ -73,6 +96,7 @@ func (a *ConversationAgent) HandleMessage(ctx context.Context, msg *multiagent.M
 func (a *ConversationAgent) handleConversation(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Get or create conversation context
 	conversationID := a.getConversationID(msg)
+	ctx = logging.WithFields(ctx, logging.KeyConversationID, conversationID)
 	conversation := a.getOrCreateConversation(ctx, conversationID, msg)
 
 	// Add user message to conversation
@@ -81,21 +105,37 @@ func (a *ConversationAgent) handleConversation(ctx context.Context, msg *multiag
 		Content:   msg.Content,
 		Timestamp: msg.Timestamp,
 	})
-	conversation.LastActivity = time.Now()
+	conversation.LastActivity = a.now()
 
 	// Update conversation in memory
 	a.updateConversation(ctx, conversation)
 
-	// Check if we need to delegate to other agents
-	if a.shouldDelegate(msg.Content) {
-		log.Printf("ConversationAgent: Delegating message to specialists: %s", msg.Content[:min(50, len(msg.Content))])
-		return a.delegateToSpecialists(ctx, msg, conversation)
+	// Route specialist work through the coordinator, or ask what was meant
+	if available := a.availableSpecialists(); len(available) > 0 {
+		if request, route, ok := a.resolveClarification(ctx, conversation, msg.Content, available); ok {
+			a.logger.InfoContext(ctx, "Delegating clarified request to specialists", "specialists", route.Specialists)
+			return a.delegateToSpecialists(ctx, msg, conversation, request, route)
+		}
+		route := a.routeRequest(ctx, msg.Content, available)
+		switch {
+		case len(route.Specialists) > 0:
+			a.logger.InfoContext(ctx, "Delegating message to specialists", "content", msg.Content[:min(50, len(msg.Content))])
+			return a.delegateToSpecialists(ctx, msg, conversation, msg.Content, route)
+		case len(route.Clarify) > 0:
+			return a.askClarification(ctx, msg, conversation, route), nil
+		}
 	}
 
-	log.Printf("ConversationAgent: Handling message directly with LLM: %s", msg.Content[:min(50, len(msg.Content))])
+	a.logger.InfoContext(ctx, "Handling message directly with LLM", "content", msg.Content[:min(50, len(msg.Content))])
+
+	// Fold older messages into the summary before the history outgrows the prompt
+	a.summarizeIfLong(ctx, conversation)
 
 	// Build context for LLM
-	contextPrompt := a.buildConversationPrompt(conversation)
+	contextPrompt, err := a.buildConversationPrompt(ctx, conversation)
+	if err != nil {
+		return nil, err
+	}
 
 	// Query LLM
 	response, err := a.llmProvider.Query(ctx, contextPrompt)
@@ -107,23 +147,23 @@ func (a *ConversationAgent) handleConversation(ctx context.Context, msg *multiag
 	conversation.Messages = append(conversation.Messages, multiagent.ConversationMessage{
 		Role:      "assistant",
 		Content:   response,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		AgentID:   a.id,
 	})
-	conversation.LastActivity = time.Now()
+	conversation.LastActivity = a.now()
 
 	// Update conversation in memory
 	a.updateConversation(ctx, conversation)
 
 	// Create response message
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   response,
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"conversation_id": conversationID,
 		},
@@ -142,7 +182,9 @@ func (a *ConversationAgent) handleQuery(ctx context.Context, msg *multiagent.Mes
 		}
 
 		// Get conversation
+		a.convMutex.Lock()
 		conversation, exists := a.conversations[conversationID]
+		a.convMutex.Unlock()
 		if !exists {
 			// Try to load from memory
 			convInterface, err := a.memoryStore.Get(ctx, fmt.Sprintf("conversation:%s", conversationID))
@@ -172,13 +214,13 @@ func (a *ConversationAgent) handleQuery(ctx context.Context, msg *multiagent.Mes
 		}
 
 		return &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+			ID:        a.messageID(),
 			From:      a.id,
 			To:        []multiagent.AgentID{msg.From},
 			Type:      multiagent.MessageTypeResponse,
 			Content:   history.String(),
 			ReplyTo:   msg.ID,
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 		}, nil
 	}
 
@@ -190,7 +232,7 @@ func (a *ConversationAgent) handleQuery(ctx context.Context, msg *multiagent.Mes
 func (a *ConversationAgent) getConversationID(msg *multiagent.Message) string {
 	// First, check if conversation ID is explicitly provided in the message context
 	if ctxID, ok := msg.Context["conversation_id"].(string); ok {
-		log.Printf("ConversationAgent: Using provided conversation ID: %s", ctxID)
+		a.logger.Debug("Using provided conversation ID", logging.KeyConversationID, ctxID)
 		return ctxID
 	}
 
@@ -198,80 +240,84 @@ func (a *ConversationAgent) getConversationID(msg *multiagent.Message) string {
 	if userID, ok := msg.Context["user_id"].(string); ok {
 		// Use a consistent conversation ID based on the actual user ID
 		conversationID := fmt.Sprintf("conv_%s", userID)
-		log.Printf("ConversationAgent: Using user-based conversation ID: %s", conversationID)
+		a.logger.Debug("Using user-based conversation ID", logging.KeyConversationID, conversationID)
 		return conversationID
 	}
 
 	// Check if this is a reply to an existing conversation
 	if msg.ReplyTo != "" {
 		// Try to find the conversation ID from the original message
+		a.convMutex.Lock()
+		defer a.convMutex.Unlock()
 		for id, conv := range a.conversations {
 			for _, m := range conv.Messages {
 				if strings.Contains(m.Content, msg.ReplyTo) {
-					log.Printf("ConversationAgent: Found conversation ID from reply: %s", id)
+					a.logger.Debug("Found conversation ID from reply", logging.KeyConversationID, id)
 					return id
 				}
 			}
 		}
 	}
 
-	// Fallback: Generate a new conversation ID based on the sender
-	// But clean up the sender ID to remove response key prefixes
-	senderID := string(msg.From)
-	if strings.HasPrefix(senderID, "user_response_") {
-		// Extract the actual user ID from the response key
-		parts := strings.Split(senderID, "_")
-		if len(parts) >= 3 {
-			// user_response_user_123_456 -> user_123
-			senderID = strings.Join(parts[2:len(parts)-1], "_")
-		}
+	// Fallback: Generate a new conversation ID based on the user, or the
+	// sender when the message doesn't name one
+	senderID := multiagent.UserIDFromMessage(msg)
+	if senderID == "" {
+		senderID = string(msg.From)
 	}
 	conversationID := fmt.Sprintf("conv_%s", senderID)
-	log.Printf("ConversationAgent: Generated new conversation ID: %s", conversationID)
+	a.logger.Debug("Generated new conversation ID", logging.KeyConversationID, conversationID)
 	return conversationID
 }
 
 // getOrCreateConversation retrieves an existing conversation or creates a new one
 func (a *ConversationAgent) getOrCreateConversation(ctx context.Context, conversationID string, msg *multiagent.Message) *multiagent.ConversationContext {
+	a.convMutex.Lock()
+	defer a.convMutex.Unlock()
+
 	// Check if conversation exists in memory
 	if conv, exists := a.conversations[conversationID]; exists {
-		log.Printf("ConversationAgent: Found conversation in memory: %s (has %d messages)", conversationID, len(conv.Messages))
+		a.logger.DebugContext(ctx, "Found conversation in memory", "messages", len(conv.Messages))
 		return conv
 	}
 
 	// Try to load from persistent storage
 	if a.memoryStore != nil {
 		convKey := fmt.Sprintf("conversation:%s", conversationID)
-		log.Printf("ConversationAgent: Attempting to load conversation from storage: %s", convKey)
+		a.logger.DebugContext(ctx, "Loading conversation from storage", "key", convKey)
 		convInterface, err := a.memoryStore.Get(ctx, convKey)
 		if err == nil {
-			log.Printf("ConversationAgent: Successfully loaded conversation from storage: %s", convKey)
+			a.logger.DebugContext(ctx, "Loaded conversation from storage", "key", convKey)
 			// Convert to ConversationContext
 			var conv multiagent.ConversationContext
 			convData, err := json.Marshal(convInterface)
 			if err == nil {
 				if err := json.Unmarshal(convData, &conv); err == nil {
-					log.Printf("ConversationAgent: Restored conversation with %d messages", len(conv.Messages))
+					a.logger.InfoContext(ctx, "Restored conversation", "messages", len(conv.Messages))
 					a.conversations[conversationID] = &conv
 					return &conv
 				} else {
-					log.Printf("ConversationAgent: Failed to unmarshal conversation: %v", err)
+					a.logger.ErrorContext(ctx, "Failed to unmarshal conversation", "error", err)
 				}
 			} else {
-				log.Printf("ConversationAgent: Failed to marshal conversation: %v", err)
+				a.logger.ErrorContext(ctx, "Failed to marshal conversation", "error", err)
 			}
 		} else {
-			log.Printf("ConversationAgent: Could not load conversation from storage: %v", err)
+			a.logger.DebugContext(ctx, "Could not load conversation from storage", "error", err)
 		}
 	}
 
 	// Create new conversation
-	log.Printf("ConversationAgent: Creating new conversation: %s", conversationID)
+	a.logger.InfoContext(ctx, "Creating new conversation")
+	userID := multiagent.UserIDFromContext(ctx)
+	if userID == "" {
+		userID = string(msg.From)
+	}
 	conv := &multiagent.ConversationContext{
 		ID:           conversationID,
-		UserID:       string(msg.From),
-		StartTime:    time.Now(),
-		LastActivity: time.Now(),
+		UserID:       userID,
+		StartTime:    a.now(),
+		LastActivity: a.now(),
 		Messages:     []multiagent.ConversationMessage{},
 		Context:      make(map[string]interface{}),
 		ActiveAgents: []multiagent.AgentID{a.id},
@@ -281,7 +327,7 @@ func (a *ConversationAgent) getOrCreateConversation(ctx context.Context, convers
 	conv.Messages = append(conv.Messages, multiagent.ConversationMessage{
 		Role:      "system",
 		Content:   fmt.Sprintf("Conversation started with %s. I'm here to help you with any questions or tasks.", a.name),
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		AgentID:   a.id,
 	})
 
@@ -289,140 +335,71 @@ func (a *ConversationAgent) getOrCreateConversation(ctx context.Context, convers
 	return conv
 }
 
+// PurgeUser forgets userID's cached conversations
+func (a *ConversationAgent) PurgeUser(userID string) int {
+	a.convMutex.Lock()
+	defer a.convMutex.Unlock()
+
+	purged := 0
+	for id, conv := range a.conversations {
+		if conv.UserID == userID {
+			delete(a.conversations, id)
+			purged++
+		}
+	}
+	return purged
+}
+
 // updateConversation persists the conversation to memory
 func (a *ConversationAgent) updateConversation(ctx context.Context, conversation *multiagent.ConversationContext) {
 	if a.memoryStore != nil {
 		convKey := fmt.Sprintf("conversation:%s", conversation.ID)
-		log.Printf("ConversationAgent: Saving conversation to storage: %s (has %d messages)", convKey, len(conversation.Messages))
+		a.logger.DebugContext(ctx, "Saving conversation to storage", "key", convKey, "messages", len(conversation.Messages))
 		if err := a.memoryStore.Store(ctx, convKey, conversation); err != nil {
-			log.Printf("ConversationAgent: Failed to save conversation: %v", err)
+			a.logger.ErrorContext(ctx, "Failed to save conversation", "key", convKey, "error", err)
 		} else {
-			log.Printf("ConversationAgent: Successfully saved conversation: %s", convKey)
-		}
-	}
-}
-
-// shouldDelegate determines if the request should be delegated to specialist agents
-func (a *ConversationAgent) shouldDelegate(content string) bool {
-	// First check if we have an orchestrator and if specialist agents exist
-	if a.orchestrator == nil {
-		return false
-	}
-
-	// Get all available agents
-	allAgents := a.orchestrator.ListAgents()
-	hasSpecialists := false
-
-	// Check if any specialist agents are available (other than conversation and coordinator)
-	for _, agent := range allAgents {
-		agentType := agent.Type()
-		if agentType != multiagent.AgentTypeConversation && agentType != multiagent.AgentTypeCoordinator {
-			hasSpecialists = true
-			break
+			a.logger.DebugContext(ctx, "Saved conversation", "key", convKey)
 		}
-	}
 
-	// If no specialists are available, don't delegate
-	if !hasSpecialists {
-		return false
-	}
-
-	contentLower := strings.ToLower(content)
-
-	// Check for specialized topics that specifically require delegation
-	specialistKeywords := map[string][]string{
-		"research":     {"research", "find information", "look up", "search for", "information about", "investigate", "analyze data", "fact check", "verify"},
-		"task":        {"create task", "add task", "task", "todo", "to-do", "to do", "remind me", "reminder", "productivity"},
-		"project":     {"create project", "new project", "project", "plan", "planning", "milestone", "timeline", "manage", "track progress"},
-		"schedule":    {"schedule", "calendar", "appointment", "meeting", "book", "available", "free time", "time slot"},
-		"communication": {"email", "message", "contact", "send", "compose", "draft", "write email", "communication", "follow up"},
-		"coder":       {"write code", "programming", "function", "algorithm", "write a program", "debug", "script", "software"},
-		"analyst":     {"analyze", "data analysis", "statistics", "trends", "patterns", "insights", "metrics", "performance"},
-		"writer":      {"write article", "draft", "compose", "blog post", "document", "report", "essay", "outline"},
-	}
-
-	// Only delegate if there are strong indicators for specialist work
-	for _, keywords := range specialistKeywords {
-		for _, keyword := range keywords {
-			if strings.Contains(contentLower, keyword) {
-				return true
-			}
+		// Index the latest turn so past conversations can be recalled semantically
+		if n := len(conversation.Messages); n > 0 {
+			last := conversation.Messages[n-1]
+			turnKey := fmt.Sprintf("conversation_turn:%s:%d", conversation.ID, n-1)
+			a.indexForRecall(ctx, turnKey, fmt.Sprintf("%s: %s", last.Role, last.Content), map[string]interface{}{
+				"conversation_id": conversation.ID,
+				"role":            last.Role,
+			})
 		}
 	}
-
-	// Don't delegate based on length alone - most conversations should be handled directly
-	return false
 }
 
-// delegateToSpecialists routes the request to appropriate specialist agents
-func (a *ConversationAgent) delegateToSpecialists(ctx context.Context, msg *multiagent.Message, conversation *multiagent.ConversationContext) (*multiagent.Message, error) {
-	contentLower := strings.ToLower(msg.Content)
-
-	// Determine which specialists to involve
-	specialists := []multiagent.AgentType{}
-	log.Printf("ConversationAgent: Analyzing content for delegation: %s", contentLower)
-
-	if containsAny(contentLower, []string{"research", "find information", "look up", "search for", "investigate", "fact check", "verify"}) {
-		specialists = append(specialists, multiagent.AgentTypeResearch)
-		log.Printf("ConversationAgent: Added research specialist")
-	}
-
-	if containsAny(contentLower, []string{"create task", "add task", "task", "todo", "to-do", "to do", "remind me", "reminder", "productivity"}) {
-		specialists = append(specialists, multiagent.AgentTypeTask)
-	}
-
-	if containsAny(contentLower, []string{"create project", "new project", "project", "plan", "planning", "milestone", "timeline", "manage", "track progress"}) {
-		specialists = append(specialists, multiagent.AgentTypeProjectManager)
-	}
-
-	if containsAny(contentLower, []string{"schedule", "calendar", "appointment", "meeting", "book", "available", "free time", "time slot"}) {
-		specialists = append(specialists, multiagent.AgentTypeScheduler)
-	}
-
-	if containsAny(contentLower, []string{"email", "message", "contact", "send", "compose", "draft", "write email", "communication", "follow up"}) {
-		specialists = append(specialists, multiagent.AgentTypeCommunicationManager)
-		log.Printf("ConversationAgent: Added communication manager specialist")
-	}
-
-	if containsAny(contentLower, []string{"write code", "programming", "function", "algorithm", "debug", "script", "software"}) {
-		specialists = append(specialists, multiagent.AgentTypeCoder)
-	}
-
-	if containsAny(contentLower, []string{"analyze", "data analysis", "statistics", "trends", "patterns", "insights", "metrics"}) {
-		specialists = append(specialists, multiagent.AgentTypeAnalyst)
-	}
-
-	if containsAny(contentLower, []string{"write article", "draft", "compose", "blog post", "document", "report", "essay", "outline"}) {
-		specialists = append(specialists, multiagent.AgentTypeWriter)
-	}
-
-	log.Printf("ConversationAgent: Selected specialists: %v", specialists)
-
-	// If no specialists matched, use the coordinator
-	if len(specialists) == 0 {
-		specialists = append(specialists, multiagent.AgentTypeCoordinator)
-		log.Printf("ConversationAgent: No specific specialists matched, using coordinator")
-	}
+// delegateToSpecialists hands request to the routed specialists through the
+// coordinator, which fans it out when there are several
+func (a *ConversationAgent) delegateToSpecialists(ctx context.Context, msg *multiagent.Message, conversation *multiagent.ConversationContext, request string, route requestRoute) (*multiagent.Message, error) {
+	specialists := route.Specialists
+	a.logger.InfoContext(ctx, "Selected specialists", "specialists", specialists)
 
 	// Create a task for the coordinator to handle
 	if a.orchestrator != nil {
 	// Extract the response key from the original message sender
 	responseKey := string(msg.From)
-	log.Printf("ConversationAgent: Extracted response key: %s", responseKey)
+	a.logger.DebugContext(ctx, "Extracted response key", "response_key", responseKey)
 	
 	task := multiagent.Task{
-	ID:          fmt.Sprintf("task_%s_%d", a.id, time.Now().UnixNano()),
+	ID:          a.newID("task_" + string(a.id)),
 	Type:        "user_request",
-	Description: fmt.Sprintf("Handle user request: %s", msg.Content),
+	Description: fmt.Sprintf("Handle user request: %s", request),
 	Priority:    msg.Priority,
 	Requester:   a.id,
 	Assignee:    multiagent.AgentID("coordinator_agent"), // Explicitly assign to coordinator_agent
 	Status:      multiagent.TaskStatusPending,
-	CreatedAt:   time.Now(),
+	CreatedAt:   a.now(),
 	Input: map[string]interface{}{
-	"user_message":    msg.Content,
+	"user_message":    request,
 	"conversation_id": conversation.ID,
 	"specialists":     specialists,
+	"intents":         route.Intents,
+	"mode":            delegationMode(route),
 	"response_key":    responseKey, // Add the response key for final response routing
 	},
 	Output: make(map[string]interface{}), // Ensure Output is properly initialized
@@ -436,56 +413,59 @@ func (a *ConversationAgent) delegateToSpecialists(ctx context.Context, msg *mult
 
 		// Return immediate acknowledgment
 		return &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+			ID:        a.messageID(),
 			From:      a.id,
 			To:        []multiagent.AgentID{msg.From},
 			Type:      multiagent.MessageTypeResponse,
 			Content:   "I'm working on your request and consulting with specialists. I'll get back to you shortly.",
 			ReplyTo:   msg.ID,
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 			Context: map[string]interface{}{
-				"conversation_id": conversation.ID,
-				"task_id":         task.ID,
+				"conversation_id":         conversation.ID,
+				"task_id":                 task.ID,
+				"intents":                 route.Intents,
+				multiagent.ContextInterim: true,
 			},
 		}, nil
 	}
 
-	// If no orchestrator, handle directly
-	return a.handleConversation(ctx, msg)
+	return nil, fmt.Errorf("no orchestrator to delegate to")
 }
 
-// buildConversationPrompt creates a prompt with conversation history
-func (a *ConversationAgent) buildConversationPrompt(conversation *multiagent.ConversationContext) string {
-	var prompt strings.Builder
-
-	// Add agent identity
-	prompt.WriteString(fmt.Sprintf("You are %s, a conversation agent designed to help users.\n\n", a.name))
-
-	// Add conversation history
-	prompt.WriteString("Conversation history:\n")
-
-	// Get the last 10 messages or all if fewer
-	startIdx := 0
-	if len(conversation.Messages) > 10 {
-		startIdx = len(conversation.Messages) - 10
+// buildConversationPrompt creates a prompt with the facts the user approved,
+// the conversation's summary, its latest messages, and the summarized ones
+// the latest message asks about
+func (a *ConversationAgent) buildConversationPrompt(ctx context.Context, conversation *multiagent.ConversationContext) (string, error) {
+	// Get the last messages past the summary, or all if fewer
+	recent := conversation.Messages[conversation.SummarizedThrough:]
+	if len(recent) > historyInPrompt {
+		recent = recent[len(recent)-historyInPrompt:]
 	}
 
-	for _, msg := range conversation.Messages[startIdx:] {
-		prompt.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+	var recalled []multiagent.ConversationMessage
+	if n := len(conversation.Messages); n > 0 && conversation.SummarizedThrough > 0 {
+		recalled = recallSummarized(conversation, conversation.Messages[n-1].Content)
 	}
 
-	// Add context information if available
-	if len(conversation.Context) > 0 {
-		prompt.WriteString("\nAdditional context:\n")
-		for k, v := range conversation.Context {
-			prompt.WriteString(fmt.Sprintf("- %s: %v\n", k, v))
+	var facts []string
+	if a.memoryStore != nil {
+		approved, err := memory.LoadFacts(ctx, a.memoryStore, memory.FactApproved)
+		if err != nil {
+			a.logger.WarnContext(ctx, "Failed to load the user's facts", "error", err)
+		}
+		for _, fact := range approved {
+			facts = append(facts, fact.Statement)
 		}
 	}
 
-	// Add instruction
-	prompt.WriteString("\nPlease provide a helpful, accurate, and concise response to the user's latest message.")
-
-	return prompt.String()
+	return a.renderPrompt(ctx, "conversation.reply", prompts.Vars{
+		"Name":     a.name,
+		"Facts":    facts,
+		"Summary":  conversation.Summary,
+		"Recalled": recalled,
+		"Messages": recent,
+		"Context":  conversation.Context,
+	})
 }
 
 // Helper function to check if a string contains any of the keywords
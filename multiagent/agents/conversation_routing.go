@@ -0,0 +1,220 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// specialistRoutes maps the conversation agent's intents to the specialists
+// that handle them; "chat" has none and is answered directly
+var specialistRoutes = map[string]multiagent.AgentType{
+	"research":      multiagent.AgentTypeResearch,
+	"task":          multiagent.AgentTypeTask,
+	"project":       multiagent.AgentTypeProjectManager,
+	"schedule":      multiagent.AgentTypeScheduler,
+	"communication": multiagent.AgentTypeCommunicationManager,
+	"coder":         multiagent.AgentTypeCoder,
+	"analyst":       multiagent.AgentTypeAnalyst,
+	"writer":        multiagent.AgentTypeWriter,
+}
+
+const (
+	// routeConfidence is the least confidence an intent needs to be routed
+	// to its specialist without asking; keyword matches have exactly this
+	routeConfidence = 0.5
+	// clarifyConfidence is the least confidence an intent needs to be
+	// offered when asking what the user meant
+	clarifyConfidence = 0.2
+	// maxClarifyOptions bounds the choices a clarification offers
+	maxClarifyOptions = 3
+
+	// Conversation context keys of a request waiting on the user to say
+	// what they meant
+	clarifyRequestKey = "clarify_request"
+	clarifyIntentsKey = "clarify_intents"
+)
+
+var (
+	// clarifyChoice finds the numbered options picked in "1", "2 and 3"
+	clarifyChoice = regexp.MustCompile(`\b[1-9]\b`)
+	// clarifyAll picks every option offered
+	clarifyAll = regexp.MustCompile(`(?i)\b(both|all( of them)?|everything)\b`)
+	// clarifyYes accepts the only option offered
+	clarifyYes = regexp.MustCompile(`(?i)^\s*(yes|yep|yeah|sure|ok(ay)?|please|correct|right)\b`)
+)
+
+// requestRoute is where the conversation agent sends a message
+type requestRoute struct {
+	// Intents are all the intents found, most confident first
+	Intents []IntentResult
+	// Specialists handle the confident intents, in order
+	Specialists []multiagent.AgentType
+	// Clarify lists the intents to ask the user to choose between when none
+	// was confident enough to route
+	Clarify []IntentResult
+}
+
+// availableSpecialists returns the types of the specialists registered with
+// the orchestrator
+func (a *ConversationAgent) availableSpecialists() map[multiagent.AgentType]bool {
+	available := make(map[multiagent.AgentType]bool)
+	if a.orchestrator == nil {
+		return available
+	}
+	for _, agent := range a.orchestrator.ListAgents() {
+		if agentType := agent.Type(); agentType != multiagent.AgentTypeConversation && agentType != multiagent.AgentTypeCoordinator {
+			available[agentType] = true
+		}
+	}
+	return available
+}
+
+// routeRequest classifies content into intents and maps the confident ones
+// onto the available specialists. When the most likely intent is
+// specialist work but nothing is confident, the candidates are offered for
+// clarification instead; when it is chat, nothing is routed.
+func (a *ConversationAgent) routeRequest(ctx context.Context, content string, available map[multiagent.AgentType]bool) requestRoute {
+	route := requestRoute{Intents: a.intents.ClassifyAll(ctx, content)}
+	if len(route.Intents) == 0 {
+		return route
+	}
+	if _, specialistWork := specialistRoutes[route.Intents[0].Label]; !specialistWork {
+		return route
+	}
+
+	routed := make(map[multiagent.AgentType]bool)
+	var candidates []IntentResult
+	for _, intent := range route.Intents {
+		specialist, ok := specialistRoutes[intent.Label]
+		if !ok || !available[specialist] || routed[specialist] {
+			continue
+		}
+		switch {
+		case intent.Confidence >= routeConfidence:
+			routed[specialist] = true
+			route.Specialists = append(route.Specialists, specialist)
+		case intent.Confidence >= clarifyConfidence && len(candidates) < maxClarifyOptions:
+			candidates = append(candidates, intent)
+		}
+	}
+	if len(route.Specialists) == 0 {
+		route.Clarify = candidates
+	}
+	a.logger.InfoContext(ctx, "Routed request", "intents", route.Intents, "specialists", route.Specialists, "clarify", len(route.Clarify))
+	return route
+}
+
+// delegationMode asks the coordinator to plan requests that need several
+// specialists, so that their steps can build on each other
+func delegationMode(route requestRoute) string {
+	if len(route.Specialists) > 1 {
+		return planMode
+	}
+	return ""
+}
+
+// askClarification asks the user which of the candidate intents they meant,
+// keeping the request until they answer
+func (a *ConversationAgent) askClarification(ctx context.Context, msg *multiagent.Message, conversation *multiagent.ConversationContext, route requestRoute) *multiagent.Message {
+	labels := make([]string, len(route.Clarify))
+	var question strings.Builder
+	if len(route.Clarify) == 1 {
+		labels[0] = route.Clarify[0].Label
+		fmt.Fprintf(&question, "Just to check — is this about %s? Say yes, or tell me a bit more about what you need.", a.intents.Describe(labels[0]))
+	} else {
+		question.WriteString("I want to make sure I hand this to the right specialist. Do you mean:\n")
+		for i, intent := range route.Clarify {
+			labels[i] = intent.Label
+			fmt.Fprintf(&question, "%d. %s\n", i+1, a.intents.Describe(intent.Label))
+		}
+		every := "both"
+		if len(labels) > 2 {
+			every = "all"
+		}
+		fmt.Fprintf(&question, "\nReply with a number (or %q), or tell me a bit more.", every)
+	}
+
+	conversation.Context[clarifyRequestKey] = msg.Content
+	conversation.Context[clarifyIntentsKey] = strings.Join(labels, ",")
+	conversation.Messages = append(conversation.Messages, multiagent.ConversationMessage{
+		Role:      "assistant",
+		Content:   question.String(),
+		Timestamp: a.now(),
+		AgentID:   a.id,
+	})
+	conversation.LastActivity = a.now()
+	a.updateConversation(ctx, conversation)
+
+	return &multiagent.Message{
+		ID:        a.messageID(),
+		From:      a.id,
+		To:        []multiagent.AgentID{msg.From},
+		Type:      multiagent.MessageTypeResponse,
+		Content:   question.String(),
+		ReplyTo:   msg.ID,
+		Timestamp: a.now(),
+		Context: map[string]interface{}{
+			"conversation_id": conversation.ID,
+			"intents":         route.Intents,
+			"clarify":         labels,
+		},
+	}
+}
+
+// resolveClarification reads reply as the answer to a pending
+// clarification, returning the original request and its route when the
+// user picked one of the options. The question is only asked once: any
+// reply clears it, and one that picks nothing is handled as a new message.
+func (a *ConversationAgent) resolveClarification(ctx context.Context, conversation *multiagent.ConversationContext, reply string, available map[multiagent.AgentType]bool) (string, requestRoute, bool) {
+	request, _ := conversation.Context[clarifyRequestKey].(string)
+	options, _ := conversation.Context[clarifyIntentsKey].(string)
+	if request == "" || options == "" {
+		return "", requestRoute{}, false
+	}
+	delete(conversation.Context, clarifyRequestKey)
+	delete(conversation.Context, clarifyIntentsKey)
+	labels := strings.Split(options, ",")
+
+	picked := make(map[string]bool)
+	switch {
+	case clarifyAll.MatchString(reply), len(labels) == 1 && clarifyYes.MatchString(reply):
+		for _, label := range labels {
+			picked[label] = true
+		}
+	default:
+		for _, choice := range clarifyChoice.FindAllString(reply, -1) {
+			if n, err := strconv.Atoi(choice); err == nil && n <= len(labels) {
+				picked[labels[n-1]] = true
+			}
+		}
+		lower := strings.ToLower(reply)
+		for _, match := range a.intents.matchAllKeywords(reply) {
+			picked[match.Label] = picked[match.Label] || strings.Contains(options, match.Label)
+		}
+		for _, label := range labels {
+			if strings.Contains(lower, label) {
+				picked[label] = true
+			}
+		}
+	}
+
+	route := requestRoute{}
+	for _, label := range labels {
+		if specialist := specialistRoutes[label]; picked[label] && available[specialist] {
+			route.Intents = append(route.Intents, IntentResult{Label: label, Confidence: 1, Source: "user"})
+			route.Specialists = append(route.Specialists, specialist)
+		}
+	}
+	if len(route.Specialists) == 0 {
+		a.logger.InfoContext(ctx, "Clarification answered with a new request")
+		return "", requestRoute{}, false
+	}
+	// The original request is what the specialists work on; the answer
+	// only chose them
+	return request, route, true
+}
@@ -0,0 +1,122 @@
+package agents
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/llmprovider"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+const (
+	// summarizeAfter is how many messages past its summary a conversation
+	// collects before they are folded into it
+	summarizeAfter = 20
+	// keepVerbatim is how many of the latest messages stay out of the
+	// summary, so replies still see the exchange word for word
+	keepVerbatim = 6
+	// historyInPrompt is the most messages past the summary a reply sees
+	historyInPrompt = 10
+	// recallLimit is the most summarized messages brought back for a reply
+	recallLimit = 3
+)
+
+// recallStopwords are left out when matching the latest message against
+// summarized ones, so "what exactly did I say about the budget" matches on
+// "budget"
+var recallStopwords = map[string]bool{
+	"about": true, "again": true, "before": true, "could": true, "earlier": true,
+	"exactly": true, "from": true, "have": true, "just": true, "know": true,
+	"remember": true, "remind": true, "said": true, "should": true, "tell": true,
+	"that": true, "there": true, "they": true, "this": true, "what": true,
+	"when": true, "where": true, "which": true, "with": true, "would": true,
+	"your": true,
+}
+
+// summarizeIfLong folds all but the latest messages of a long conversation
+// into its rolling summary, which replies see in place of them. The
+// messages stay in the conversation so their details can be recalled; if
+// the LLM fails, replies go on with the recent history alone.
+func (a *ConversationAgent) summarizeIfLong(ctx context.Context, conversation *multiagent.ConversationContext) {
+	through := len(conversation.Messages) - keepVerbatim
+	if len(conversation.Messages)-conversation.SummarizedThrough <= summarizeAfter || through <= conversation.SummarizedThrough {
+		return
+	}
+
+	prompt, err := a.renderPrompt(ctx, "conversation.summarize", prompts.Vars{
+		"Name":     a.name,
+		"Summary":  conversation.Summary,
+		"Messages": conversation.Messages[conversation.SummarizedThrough:through],
+	})
+	if err != nil {
+		a.logger.WarnContext(ctx, "Failed to build conversation summary prompt", "error", err)
+		return
+	}
+	summary, err := a.llmProvider.Query(llmprovider.WithPromptClass(ctx, llmprovider.PromptClassSummary), prompt)
+	if err != nil {
+		a.logger.WarnContext(ctx, "Failed to summarize conversation", "error", err)
+		return
+	}
+	if summary = strings.TrimSpace(summary); summary == "" {
+		return
+	}
+
+	a.logger.InfoContext(ctx, "Summarized conversation", "messages", through-conversation.SummarizedThrough, "summarized_through", through)
+	conversation.Summary = summary
+	conversation.SummarizedThrough = through
+	a.updateConversation(ctx, conversation)
+}
+
+// recallSummarized returns the summarized messages that share the most words
+// with query, oldest first, so a reply can expand on details the summary
+// left out
+func recallSummarized(conversation *multiagent.ConversationContext, query string) []multiagent.ConversationMessage {
+	terms := recallTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	type match struct {
+		index, score int
+	}
+	var matches []match
+	for i, message := range conversation.Messages[:conversation.SummarizedThrough] {
+		score := 0
+		for term := range recallTerms(message.Content) {
+			if terms[term] {
+				score++
+			}
+		}
+		if score > 0 {
+			matches = append(matches, match{i, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	if len(matches) > recallLimit {
+		matches = matches[:recallLimit]
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].index < matches[j].index })
+
+	recalled := make([]multiagent.ConversationMessage, len(matches))
+	for i, m := range matches {
+		recalled[i] = conversation.Messages[m.index]
+	}
+	return recalled
+}
+
+// recallTerms returns text's lowercased words of four letters or more,
+// without recallStopwords
+func recallTerms(text string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) >= 4 && !recallStopwords[word] {
+			terms[word] = true
+		}
+	}
+	return terms
+}
@@ -4,14 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/prompts"
 )
 
+// coordinationTimeout bounds how long a coordination waits for specialists
+const coordinationTimeout = 30 * time.Second
+
 // CoordinatorAgent orchestrates the work of specialist agents
 type CoordinatorAgent struct {
 	*BaseAgent
@@ -33,6 +37,14 @@ type coordination struct {
 	CompletionTime *time.Time
 	RequesterID    multiagent.AgentID
 	FinalResponse  string
+	// Plan is set when the request was run as a plan of dependent steps
+	Plan *Plan
+	// Questions counts the specialists that asked the user a follow-up;
+	// their requests resume with the user's answer outside the coordination
+	Questions int
+	// Conflicts are the contradictions between specialists' responses the
+	// synthesis had to settle
+	Conflicts []Conflict
 }
 
 // NewCoordinatorAgent creates a new coordinator agent
@@ -56,19 +68,25 @@ func NewCoordinatorAgent(config BaseAgentConfig) *CoordinatorAgent {
 
 // HandleMessage processes an incoming message
 func (a *CoordinatorAgent) HandleMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	// Update state to busy
-	a.mu.Lock()
+	// Update state to busy; the agent state is guarded by the base agent's
+	// lock, not the coordinations'
+	a.BaseAgent.mu.Lock()
 	a.state.Status = multiagent.AgentStatusBusy
 	a.state.CurrentTask = "Coordinating agents"
-	a.mu.Unlock()
+	a.BaseAgent.mu.Unlock()
 
 	defer func() {
-		a.mu.Lock()
+		a.BaseAgent.mu.Lock()
 		a.state.Status = multiagent.AgentStatusIdle
 		a.state.CurrentTask = ""
-		a.mu.Unlock()
+		a.BaseAgent.mu.Unlock()
 	}()
 
+	// Replies to delegated requests complete their futures
+	if a.resolveReply(msg) {
+		return nil, nil
+	}
+
 	// Store message in memory
 	if a.memoryStore != nil {
 		msgKey := fmt.Sprintf("coordinator:%s:%s", a.id, msg.ID)
@@ -89,7 +107,7 @@ func (a *CoordinatorAgent) HandleMessage(ctx context.Context, msg *multiagent.Me
 	case multiagent.MessageTypeResponse:
 		// Check if this is a specialist response to coordination
 		if _, hasCoordID := msg.Context["coordination_id"]; hasCoordID {
-			log.Printf("CoordinatorAgent: Treating response as report due to coordination context")
+			a.logger.DebugContext(ctx, "Treating response as report due to coordination context")
 			return a.handleReport(ctx, msg)
 		}
 		// Fall through to default handling
@@ -110,31 +128,31 @@ func (a *CoordinatorAgent) handleRequest(ctx context.Context, msg *multiagent.Me
 	}
 
 	// Get task details
-	log.Printf("CoordinatorAgent: Retrieving task with ID: %s", taskID)
-	taskInterface, err := a.memoryStore.Get(ctx, taskID)
+	a.logger.DebugContext(ctx, "Retrieving task", logging.KeyTaskID, taskID)
+	task, err := a.loadTask(ctx, taskID)
 	if err != nil {
-		log.Printf("CoordinatorAgent: Failed to retrieve task %s: %v", taskID, err)
+		a.logger.ErrorContext(ctx, "Failed to retrieve task", logging.KeyTaskID, taskID, "error", err)
 		return nil, fmt.Errorf("failed to retrieve task: %w", err)
 	}
-	log.Printf("CoordinatorAgent: Successfully retrieved task %s", taskID)
-
-	// Convert to Task
-	var task multiagent.Task
-	taskData, err := json.Marshal(taskInterface)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal task data: %w", err)
-	}
-
-	if err := json.Unmarshal(taskData, &task); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal task data: %w", err)
-	}
+	a.logger.DebugContext(ctx, "Retrieved task", logging.KeyTaskID, taskID)
 
 	// Extract coordination details from task input
 	userMessage, _ := task.Input["user_message"].(string)
 	conversationID, _ := task.Input["conversation_id"].(string)
 	responseKey, _ := task.Input["response_key"].(string)
+	mode, _ := task.Input["mode"].(string)
+	if conversationID != "" {
+		// Specialist and synthesis calls are accounted to the conversation
+		ctx = logging.WithFields(ctx, logging.KeyConversationID, conversationID)
+	}
+	if mode == planMode && a.overBudget(ctx, conversationID) {
+		// Planning costs an LLM call per step; an over-budget conversation
+		// gets the plain fan-out instead
+		a.logger.InfoContext(ctx, "Conversation over its token budget, not planning", logging.KeyConversationID, conversationID)
+		mode = ""
+	}
 
-	log.Printf("CoordinatorAgent: Extracted response key: %s", responseKey)
+	a.logger.DebugContext(ctx, "Extracted response key", "response_key", responseKey)
 
 	// Extract specialists
 	var specialists []multiagent.AgentType
@@ -163,7 +181,7 @@ func (a *CoordinatorAgent) handleRequest(ctx context.Context, msg *multiagent.Me
 		SpecialistIDs:  []multiagent.AgentID{},
 		Responses:      make(map[multiagent.AgentID]string),
 		Status:         "in_progress",
-		StartTime:      time.Now(),
+		StartTime:      a.now(),
 		RequesterID:    multiagent.AgentID(responseKey), // Use response key instead of task requester
 	}
 
@@ -172,20 +190,43 @@ func (a *CoordinatorAgent) handleRequest(ctx context.Context, msg *multiagent.Me
 	a.activeCoordinations[coordID] = coord
 	a.mu.Unlock()
 
-	// Delegate to specialists
-	if err := a.delegateToSpecialists(ctx, coord); err != nil {
-		return nil, fmt.Errorf("failed to delegate to specialists: %w", err)
+	defer func() {
+		a.mu.Lock()
+		delete(a.activeCoordinations, coordID)
+		a.mu.Unlock()
+	}()
+
+	// Run the request as a plan when asked, else delegate to every
+	// specialist at once and wait for their replies
+	if mode != planMode || !a.runPlan(ctx, coord) {
+		futures, err := a.delegateToSpecialists(ctx, coord)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delegate to specialists: %w", err)
+		}
+		a.collectResponses(ctx, coord, futures)
+	}
+
+	// The user was asked a follow-up and answers the specialist directly,
+	// so a synthesis now would be incomplete
+	if coord.Questions > 0 {
+		a.logger.InfoContext(ctx, "Coordination paused for the user's answer", "coordination_id", coordID, "questions", coord.Questions)
+		return nil, nil
+	}
+	if len(coord.Responses) == 0 {
+		return nil, fmt.Errorf("no specialist responded for coordination %s", coordID)
+	}
+	if err := a.finalizeCoordination(ctx, coord); err != nil {
+		return nil, fmt.Errorf("failed to finalize coordination: %w", err)
 	}
 
-	// Return acknowledgment
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
-		Content:   fmt.Sprintf("Coordination %s started with %d specialists", coordID, len(specialists)),
+		Content:   fmt.Sprintf("Coordination %s completed with %d of %d specialists", coordID, len(coord.Responses), len(coord.SpecialistIDs)),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"coordination_id": coordID,
 			"task_id":         taskID,
@@ -193,94 +234,64 @@ func (a *CoordinatorAgent) handleRequest(ctx context.Context, msg *multiagent.Me
 	}, nil
 }
 
-// handleReport processes a report from a specialist agent
-func (a *CoordinatorAgent) handleReport(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	// Check if this is a response to a coordination
-	coordID, isCoord := msg.Context["coordination_id"].(string)
-	if !isCoord {
-		// Not a coordination response, use default handling
-		return a.BaseAgent.HandleMessage(ctx, msg)
-	}
+// taskGetter is implemented by orchestrators that hand out their tasks
+type taskGetter interface {
+	GetTask(ctx context.Context, taskID string) (*multiagent.Task, error)
+}
 
-	// Get coordination
-	a.mu.Lock()
-	coord, exists := a.activeCoordinations[coordID]
-	if !exists {
-		a.mu.Unlock()
-		return nil, fmt.Errorf("coordination not found: %s", coordID)
-	}
-
-	// Store specialist response
-	coord.Responses[msg.From] = msg.Content
-
-	// Check if we have received any responses
-	log.Printf("CoordinatorAgent: Checking responses for coordination %s", coordID)
-	log.Printf("CoordinatorAgent: Expected specialists: %v", coord.SpecialistIDs)
-	log.Printf("CoordinatorAgent: Received responses from: %v", getMapKeys(coord.Responses))
-
-	// If we have at least one response and no more specialists are expected to respond,
-	// or if we've been waiting for a while, consider the coordination complete
-	responseCount := len(coord.Responses)
-	expectedCount := len(coord.SpecialistIDs)
-	waitTime := time.Since(coord.StartTime)
-
-	// Consider coordination complete if:
-	// 1. We have at least one response AND
-	// 2. Either:
-	//    a. We have all expected responses, or
-	//    b. We've been waiting for more than 30 seconds, or
-	//    c. We've received a response from the orchestrator (which indicates routing issues)
-	orchestratorResponded := false
-	for responder := range coord.Responses {
-		if responder == "orchestrator" {
-			orchestratorResponded = true
-			log.Printf("CoordinatorAgent: Received response from orchestrator, will finalize coordination")
-			break
+// loadTask returns a task from the orchestrator, or else from memory. The
+// orchestrator keeps tasks outside any user's memory, so memory only finds
+// tasks assigned without a user.
+func (a *CoordinatorAgent) loadTask(ctx context.Context, taskID string) (multiagent.Task, error) {
+	if getter, ok := a.orchestrator.(taskGetter); ok {
+		if task, err := getter.GetTask(ctx, taskID); err == nil {
+			return *task, nil
 		}
 	}
 
-	allResponded := responseCount > 0 && (responseCount >= expectedCount || waitTime > 30*time.Second || orchestratorResponded)
-
-	if responseCount < expectedCount && waitTime > 30*time.Second {
-		log.Printf("CoordinatorAgent: Proceeding with %d/%d responses after waiting %v", 
-			responseCount, expectedCount, waitTime)
+	var task multiagent.Task
+	taskInterface, err := a.memoryStore.Get(ctx, taskID)
+	if err != nil {
+		return task, err
 	}
+	taskData, err := json.Marshal(taskInterface)
+	if err != nil {
+		return task, fmt.Errorf("failed to marshal task data: %w", err)
+	}
+	if err := json.Unmarshal(taskData, &task); err != nil {
+		return task, fmt.Errorf("failed to unmarshal task data: %w", err)
+	}
+	return task, nil
+}
 
-	a.mu.Unlock()
-
- // If all specialists have responded, finalize coordination
- if allResponded {
-         log.Printf("CoordinatorAgent: All specialists responded for coordination %s", coordID)
-
-         // Finalize coordination synchronously to ensure handler is still registered
-         if err := a.finalizeCoordination(ctx, coord); err != nil {
-                 log.Printf("CoordinatorAgent: Failed to finalize coordination %s: %v", coordID, err)
-                 return nil, fmt.Errorf("failed to finalize coordination: %w", err)
-         }
-
-         // Return nil to indicate completion without further agent-to-agent responses
-         return nil, nil
- }
-
-	log.Printf("CoordinatorAgent: Coordination %s still in progress, awaiting more responses", coordID)
+// handleReport processes a report from a specialist agent. Specialist
+// replies are consumed by their request futures, so a coordination report
+// reaching here arrived after its coordination gave up waiting.
+func (a *CoordinatorAgent) handleReport(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	coordID, isCoord := msg.Context["coordination_id"].(string)
+	if !isCoord {
+		// Not a coordination response, use default handling
+		return a.BaseAgent.HandleMessage(ctx, msg)
+	}
 
-	// Return nil for in-progress coordination to avoid unnecessary agent-to-agent chatter
+	a.logger.InfoContext(ctx, "Ignoring late report", "from", msg.From, "coordination_id", coordID)
 	return nil, nil
 }
 
-// delegateToSpecialists sends requests to specialist agents
-func (a *CoordinatorAgent) delegateToSpecialists(ctx context.Context, coord *coordination) error {
+// delegateToSpecialists sends a request to one agent of each specialist type
+// and returns the futures for their replies
+func (a *CoordinatorAgent) delegateToSpecialists(ctx context.Context, coord *coordination) ([]*Future, error) {
 	if a.orchestrator == nil {
-		return fmt.Errorf("no orchestrator configured")
+		return nil, fmt.Errorf("no orchestrator configured")
 	}
 
-	// Get available agents for each specialist type
+	var futures []*Future
 	for _, specialistType := range coord.Specialists {
-		log.Printf("CoordinatorAgent: Looking for agents of type: %s", specialistType)
+		a.logger.DebugContext(ctx, "Looking for specialists", "specialist_type", specialistType)
 		agents := a.getAgentsByType(ctx, specialistType)
-		log.Printf("CoordinatorAgent: Found %d agents of type %s: %v", len(agents), specialistType, agents)
+		a.logger.DebugContext(ctx, "Found specialists", "specialist_type", specialistType, "agents", agents)
 		if len(agents) == 0 {
-			log.Printf("CoordinatorAgent: No agents found for type %s, skipping", specialistType)
+			a.logger.WarnContext(ctx, "No specialists found, skipping", "specialist_type", specialistType)
 			continue
 		}
 
@@ -288,65 +299,93 @@ func (a *CoordinatorAgent) delegateToSpecialists(ctx context.Context, coord *coo
 		specialistID := agents[0]
 		coord.SpecialistIDs = append(coord.SpecialistIDs, specialistID)
 
-		// Create message for specialist
-		message := &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-			From:      a.id,
-			To:        []multiagent.AgentID{specialistID},
-			Type:      multiagent.MessageTypeRequest,
-			Content:   coord.UserMessage,
-			Priority:  multiagent.PriorityHigh,
-			Timestamp: time.Now(),
+		a.logger.InfoContext(ctx, "Sending request to specialist", "specialist", specialistID, "specialist_type", specialistType)
+		future, err := a.RequestMessage(ctx, &multiagent.Message{
+			To:       []multiagent.AgentID{specialistID},
+			Type:     multiagent.MessageTypeRequest,
+			Content:  coord.UserMessage,
+			Priority: multiagent.PriorityHigh,
 			Context: map[string]interface{}{
 				"coordination_id": coord.ID,
 				"conversation_id": coord.ConversationID,
 				"role":            string(specialistType),
 			},
+		})
+		if err != nil {
+			for _, pending := range futures {
+				pending.Cancel()
+			}
+			return nil, fmt.Errorf("failed to send message to specialist %s: %w", specialistID, err)
 		}
+		futures = append(futures, future)
+	}
 
-		// Send message
-		log.Printf("CoordinatorAgent: Sending message to specialist %s (%s)", specialistID, specialistType)
-		if err := a.orchestrator.RouteMessage(ctx, message); err != nil {
-			return fmt.Errorf("failed to send message to specialist %s: %w", specialistID, err)
+	return futures, nil
+}
+
+// collectResponses waits up to coordinationTimeout for specialist replies;
+// specialists that fail or do not answer in time are left out of the synthesis
+func (a *CoordinatorAgent) collectResponses(ctx context.Context, coord *coordination, futures []*Future) {
+	waitCtx, cancel := context.WithTimeout(ctx, coordinationTimeout)
+	defer cancel()
+
+	for _, future := range futures {
+		reply, err := future.Wait(waitCtx)
+		if err != nil {
+			a.logger.WarnContext(ctx, "No reply from specialist", "specialist", future.To(), "coordination_id", coord.ID, "error", err)
+			future.Cancel()
+			continue
+		}
+
+		if reply.Type == multiagent.MessageTypeQuestion {
+			a.logger.InfoContext(ctx, "Specialist asked the user a question", "specialist", future.To(), "coordination_id", coord.ID)
+			coord.Questions++
+			continue
 		}
-		log.Printf("CoordinatorAgent: Successfully sent message to specialist %s", specialistID)
+
+		a.mu.Lock()
+		coord.Responses[future.To()] = reply.Content
+		a.mu.Unlock()
 	}
 
-	return nil
+	a.logger.InfoContext(ctx, "Collected specialist responses", "coordination_id", coord.ID, "responses", len(coord.Responses), "requested", len(futures))
 }
 
 // finalizeCoordination synthesizes specialist responses and sends final response
 func (a *CoordinatorAgent) finalizeCoordination(ctx context.Context, coord *coordination) error {
-	log.Printf("CoordinatorAgent: Starting finalization for coordination %s", coord.ID)
+	a.logger.DebugContext(ctx, "Starting finalization", "coordination_id", coord.ID)
 
 	// Mark coordination as completed
 	a.mu.Lock()
 	coord.Status = "completed"
-	now := time.Now()
+	now := a.now()
 	coord.CompletionTime = &now
 	a.mu.Unlock()
 
-	log.Printf("CoordinatorAgent: Building synthesis prompt for %d specialist responses", len(coord.Responses))
+	// Responses that contradict each other are settled in the synthesis
+	coord.Conflicts = a.detectConflicts(ctx, coord)
 
-	// Build context for LLM
-	var promptBuilder strings.Builder
-	promptBuilder.WriteString(fmt.Sprintf("You are %s, a coordinator agent. You need to synthesize responses from specialist agents into a coherent, helpful response for the user.\n\n", a.name))
-	promptBuilder.WriteString(fmt.Sprintf("User message: %s\n\n", coord.UserMessage))
-	promptBuilder.WriteString("Specialist responses:\n")
+	a.logger.DebugContext(ctx, "Building synthesis prompt", "responses", len(coord.Responses), "conflicts", len(coord.Conflicts))
 
-	for specialistID, response := range coord.Responses {
-		promptBuilder.WriteString(fmt.Sprintf("--- %s ---\n%s\n\n", specialistID, response))
+	// Build context for LLM
+	prompt, err := a.renderPrompt(ctx, "coordinator.synthesize", prompts.Vars{
+		"Name":      a.name,
+		"Request":   coord.UserMessage,
+		"Plan":      coord.Plan,
+		"Responses": coord.Responses,
+		"Conflicts": coord.Conflicts,
+	})
+	if err != nil {
+		return err
 	}
 
-	promptBuilder.WriteString("Please synthesize these responses into a single, coherent response that addresses the user's request comprehensively. Be concise but thorough, and ensure all relevant information is included.")
-
 	// Query LLM for synthesized response
-	log.Printf("CoordinatorAgent: Querying LLM for synthesis")
-	synthesizedResponse, err := a.llmProvider.Query(ctx, promptBuilder.String())
+	a.logger.DebugContext(ctx, "Querying LLM for synthesis")
+	synthesizedResponse, err := a.llmProvider.Query(ctx, prompt)
 	if err != nil {
 		return fmt.Errorf("failed to synthesize response: %w", err)
 	}
-	log.Printf("CoordinatorAgent: LLM synthesis completed, response length: %d", len(synthesizedResponse))
+	a.logger.DebugContext(ctx, "LLM synthesis completed", "response_length", len(synthesizedResponse))
 
 	// Store final response
 	coord.FinalResponse = synthesizedResponse
@@ -357,15 +396,15 @@ func (a *CoordinatorAgent) finalizeCoordination(ctx context.Context, coord *coor
 	}
 
 	// Send final response to requester
-	log.Printf("CoordinatorAgent: Sending final response to requester: %s", coord.RequesterID)
+	a.logger.DebugContext(ctx, "Sending final response", "requester", coord.RequesterID)
 	finalMessage := &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{coord.RequesterID},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   synthesizedResponse,
 		Priority:  multiagent.PriorityHigh,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"coordination_id": coord.ID,
 			"conversation_id": coord.ConversationID,
@@ -378,41 +417,36 @@ func (a *CoordinatorAgent) finalizeCoordination(ctx context.Context, coord *coor
 		return fmt.Errorf("failed to send final response: %w", err)
 	}
 
-	log.Printf("CoordinatorAgent: Successfully sent final response to %s", coord.RequesterID)
+	a.logger.InfoContext(ctx, "Sent final response", "coordination_id", coord.ID, "requester", coord.RequesterID)
 	return nil
 }
 
 // updateTask updates the task with the final response
 func (a *CoordinatorAgent) updateTask(ctx context.Context, coord *coordination) error {
 	// Get task
-	taskInterface, err := a.memoryStore.Get(ctx, coord.TaskID)
+	task, err := a.loadTask(ctx, coord.TaskID)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve task: %w", err)
 	}
 
-	// Convert to Task
-	var task multiagent.Task
-	taskData, err := json.Marshal(taskInterface)
-	if err != nil {
-		return fmt.Errorf("failed to marshal task data: %w", err)
-	}
-
-	if err := json.Unmarshal(taskData, &task); err != nil {
-		return fmt.Errorf("failed to unmarshal task data: %w", err)
-	}
-
 	// Ensure Output map is initialized
 	if task.Output == nil {
 		task.Output = make(map[string]interface{})
-		log.Printf("CoordinatorAgent: Initialized nil Output map for task %s", coord.TaskID)
+		a.logger.DebugContext(ctx, "Initialized nil Output map", logging.KeyTaskID, coord.TaskID)
 	}
 
 	// Update task
 	task.Status = multiagent.TaskStatusCompleted
-	now := time.Now()
+	now := a.now()
 	task.CompletedAt = &now
 	task.Output["final_response"] = coord.FinalResponse
 	task.Output["specialist_responses"] = coord.Responses
+	if coord.Plan != nil {
+		task.Output["plan"] = coord.Plan
+	}
+	if len(coord.Conflicts) > 0 {
+		task.Output["conflicts"] = coord.Conflicts
+	}
 
 	// Store updated task
 	if err := a.memoryStore.Store(ctx, coord.TaskID, task); err != nil {
@@ -450,15 +484,6 @@ func (a *CoordinatorAgent) shouldIgnoreMessage(msg *multiagent.Message) bool {
 	return false
 }
 
-// Helper function to get map keys as a slice
-func getMapKeys(m map[multiagent.AgentID]string) []multiagent.AgentID {
-	keys := make([]multiagent.AgentID, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
-	}
-	return keys
-}
-
 // getAgentsByType returns available agents of a specific type
 func (a *CoordinatorAgent) getAgentsByType(ctx context.Context, agentType multiagent.AgentType) []multiagent.AgentID {
 	if a.orchestrator == nil {
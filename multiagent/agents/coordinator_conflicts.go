@@ -0,0 +1,66 @@
+package agents
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// Conflict is a contradiction between the responses of specialists to the
+// same request, such as a task planned for time the calendar has booked
+type Conflict struct {
+	Agents      []multiagent.AgentID `json:"agents"`
+	Description string               `json:"description"`
+	// Resolution is how the reply settles the conflict, or what the user
+	// has to decide
+	Resolution string `json:"resolution,omitempty"`
+}
+
+// detectConflicts asks which of coord's specialist responses contradict each
+// other, so the synthesis settles them instead of passing both sides on. It
+// takes an LLM call, so it is skipped unless several specialists answered
+// and the conversation is within its token budget; a failed check finds no
+// conflicts, since the responses are still merged.
+func (a *CoordinatorAgent) detectConflicts(ctx context.Context, coord *coordination) []Conflict {
+	if len(coord.Responses) < 2 || a.overBudget(ctx, coord.ConversationID) {
+		return nil
+	}
+
+	prompt, err := a.renderPrompt(ctx, "coordinator.conflicts", prompts.Vars{
+		"Name":      a.name,
+		"Request":   coord.UserMessage,
+		"Responses": coord.Responses,
+	})
+	if err != nil {
+		a.logger.WarnContext(ctx, "Failed to check specialist responses for conflicts", "coordination_id", coord.ID, "error", err)
+		return nil
+	}
+	var result struct {
+		Conflicts []Conflict `json:"conflicts"`
+	}
+	if err := a.queryJSON(ctx, prompt, objectSchema(map[string]string{"conflicts": "array"}, "conflicts"), &result); err != nil {
+		a.logger.WarnContext(ctx, "Failed to check specialist responses for conflicts", "coordination_id", coord.ID, "error", err)
+		return nil
+	}
+
+	// Keep the conflicts between specialists that answered
+	var conflicts []Conflict
+	for _, conflict := range result.Conflicts {
+		conflict.Description = strings.TrimSpace(conflict.Description)
+		conflict.Resolution = strings.TrimSpace(conflict.Resolution)
+		conflict.Agents = slices.DeleteFunc(conflict.Agents, func(agent multiagent.AgentID) bool {
+			_, answered := coord.Responses[agent]
+			return !answered
+		})
+		if conflict.Description != "" {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	if len(conflicts) > 0 {
+		a.logger.InfoContext(ctx, "Specialist responses conflict", "coordination_id", coord.ID, "conflicts", len(conflicts))
+	}
+	return conflicts
+}
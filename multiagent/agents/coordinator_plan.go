@@ -0,0 +1,361 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/progress"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// planMode is the task input "mode" asking the coordinator to plan a request
+// as dependent steps instead of sending it to every specialist at once
+const planMode = "plan"
+
+// maxPlanSteps bounds the plans the coordinator will run
+const maxPlanSteps = 8
+
+// stepResultLength caps the step result included in a StepFinished event
+const stepResultLength = 280
+
+// Plan step statuses
+const (
+	stepPending   = "pending"
+	stepCompleted = "completed"
+	stepFailed    = "failed"
+	stepSkipped   = "skipped"
+	// stepAsked steps are waiting on the user's answer to a follow-up
+	stepAsked = "asked_user"
+)
+
+// PlanStep is one step of a coordination plan
+type PlanStep struct {
+	ID string `json:"id"`
+	// Agent is the type of specialist responsible for the step
+	Agent       multiagent.AgentType `json:"agent"`
+	Instruction string               `json:"instruction"`
+	// DependsOn lists the steps whose outputs are the step's inputs
+	DependsOn []string `json:"depends_on,omitempty"`
+	// Output describes what the step produces for later steps
+	Output  string             `json:"output,omitempty"`
+	Status  string             `json:"status"`
+	AgentID multiagent.AgentID `json:"agent_id,omitempty"`
+	Result  string             `json:"result,omitempty"`
+}
+
+// Plan is the coordinator's structured plan for a request
+type Plan struct {
+	Goal  string     `json:"goal"`
+	Steps []PlanStep `json:"steps"`
+}
+
+// runPlan plans coord's request and runs it step by step, reporting whether
+// it did; requests that cannot be planned are left to the plain fan-out
+func (a *CoordinatorAgent) runPlan(ctx context.Context, coord *coordination) bool {
+	plan, waves, err := a.createPlan(ctx, coord)
+	if err != nil {
+		a.logger.WarnContext(ctx, "Falling back to delegating without a plan", "coordination_id", coord.ID, "error", err)
+		return false
+	}
+	coord.Plan = plan
+
+	steps := make([]string, len(plan.Steps))
+	for i, step := range plan.Steps {
+		steps[i] = fmt.Sprintf("%s: %s (%s)", step.ID, step.Instruction, step.Agent)
+	}
+	progress.Emit(ctx, progress.Event{
+		Type:           progress.PlanCreated,
+		ConversationID: coord.ConversationID,
+		Detail:         plan.Goal,
+		Data:           map[string]interface{}{"steps": steps},
+	})
+	a.logger.InfoContext(ctx, "Running plan", "coordination_id", coord.ID, "steps", len(plan.Steps), "waves", len(waves))
+
+	for _, wave := range waves {
+		a.runWave(ctx, coord, wave)
+		// The plan waits for the user's answer instead of running on
+		if coord.Questions > 0 {
+			break
+		}
+	}
+	return true
+}
+
+// createPlan asks the LLM for a plan of coord's request over the available
+// specialists and orders its steps into waves that can run together
+func (a *CoordinatorAgent) createPlan(ctx context.Context, coord *coordination) (*Plan, [][]int, error) {
+	available := make(map[multiagent.AgentType]string)
+	if a.orchestrator != nil {
+		for _, agent := range a.orchestrator.ListAgents() {
+			agentType := agent.Type()
+			if _, seen := available[agentType]; !seen && agentType != multiagent.AgentTypeConversation && agentType != multiagent.AgentTypeCoordinator {
+				available[agentType] = agent.Description()
+			}
+		}
+	}
+	if len(available) == 0 {
+		return nil, nil, fmt.Errorf("no specialists available")
+	}
+
+	var plan Plan
+	prompt, err := a.buildPlanPrompt(ctx, coord, available)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := a.queryJSON(ctx, prompt, planSchema(available), &plan); err != nil {
+		return nil, nil, fmt.Errorf("failed to create plan: %w", err)
+	}
+	if len(plan.Steps) == 0 {
+		return nil, nil, fmt.Errorf("plan has no steps")
+	}
+	if len(plan.Steps) > maxPlanSteps {
+		return nil, nil, fmt.Errorf("plan has %d steps, more than %d", len(plan.Steps), maxPlanSteps)
+	}
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		if step.ID == "" {
+			step.ID = fmt.Sprintf("step_%d", i+1)
+		}
+		if _, ok := available[step.Agent]; !ok {
+			return nil, nil, fmt.Errorf("step %s needs unavailable agent %q", step.ID, step.Agent)
+		}
+		if strings.TrimSpace(step.Instruction) == "" {
+			step.Instruction = coord.UserMessage
+		}
+		step.Status = stepPending
+	}
+	if plan.Goal == "" {
+		plan.Goal = coord.UserMessage
+	}
+
+	waves, err := planWaves(plan.Steps)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &plan, waves, nil
+}
+
+// planSchema is the JSON schema of a plan over the available agent types
+func planSchema(available map[multiagent.AgentType]string) map[string]interface{} {
+	agents := make([]string, 0, len(available))
+	for agentType := range available {
+		agents = append(agents, string(agentType))
+	}
+	sort.Strings(agents)
+
+	step := objectSchema(map[string]string{
+		"id":          "string",
+		"instruction": "string",
+		"depends_on":  "array",
+		"output":      "string",
+	}, "id", "agent", "instruction")
+	step["properties"].(map[string]interface{})["agent"] = map[string]interface{}{"type": "string", "enum": agents}
+
+	schema := objectSchema(map[string]string{"goal": "string"}, "steps")
+	schema["properties"].(map[string]interface{})["steps"] = map[string]interface{}{"type": "array", "items": step}
+	return schema
+}
+
+func (a *CoordinatorAgent) buildPlanPrompt(ctx context.Context, coord *coordination, available map[multiagent.AgentType]string) (string, error) {
+	suggested := make([]string, len(coord.Specialists))
+	for i, specialist := range coord.Specialists {
+		suggested[i] = string(specialist)
+	}
+
+	return a.renderPrompt(ctx, "coordinator.plan", prompts.Vars{
+		"Name":      a.name,
+		"Request":   coord.UserMessage,
+		"Agents":    available,
+		"Suggested": suggested,
+		"MaxSteps":  maxPlanSteps,
+	})
+}
+
+// planWaves orders steps by their dependencies into waves of step indexes,
+// each wave depending only on earlier ones
+func planWaves(steps []PlanStep) ([][]int, error) {
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		if _, dup := index[step.ID]; dup {
+			return nil, fmt.Errorf("duplicate plan step %s", step.ID)
+		}
+		index[step.ID] = i
+	}
+
+	remaining := make([]int, len(steps))
+	dependents := make([][]int, len(steps))
+	for i, step := range steps {
+		for _, dep := range step.DependsOn {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("plan step %s depends on unknown step %s", step.ID, dep)
+			}
+			remaining[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	var waves [][]int
+	var ready []int
+	for i := range steps {
+		if remaining[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	planned := 0
+	for len(ready) > 0 {
+		waves = append(waves, ready)
+		planned += len(ready)
+		var next []int
+		for _, i := range ready {
+			for _, dependent := range dependents[i] {
+				if remaining[dependent]--; remaining[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		sort.Ints(next)
+		ready = next
+	}
+	if planned < len(steps) {
+		return nil, fmt.Errorf("plan steps depend on each other in a cycle")
+	}
+	return waves, nil
+}
+
+// runWave sends each step of a wave to its specialist and waits up to
+// coordinationTimeout for the results. Steps whose dependencies did not
+// complete are skipped.
+func (a *CoordinatorAgent) runWave(ctx context.Context, coord *coordination, wave []int) {
+	plan := coord.Plan
+	futures := make(map[int]*Future, len(wave))
+	for _, i := range wave {
+		step := &plan.Steps[i]
+		if blocked := a.blockedBy(plan, step); blocked != "" {
+			a.finishStep(ctx, coord, i, stepSkipped, fmt.Sprintf("%s did not complete", blocked))
+			continue
+		}
+		agents := a.getAgentsByType(ctx, step.Agent)
+		if len(agents) == 0 {
+			a.finishStep(ctx, coord, i, stepFailed, fmt.Sprintf("no %s agent is available", step.Agent))
+			continue
+		}
+		step.AgentID = agents[0]
+
+		progress.Emit(ctx, progress.Event{
+			Type:           progress.StepStarted,
+			ConversationID: coord.ConversationID,
+			AgentID:        step.AgentID,
+			Detail:         step.Instruction,
+			Data:           map[string]interface{}{"step": step.ID, "index": i + 1, "total": len(plan.Steps)},
+		})
+		future, err := a.RequestMessage(ctx, &multiagent.Message{
+			To:       []multiagent.AgentID{step.AgentID},
+			Type:     multiagent.MessageTypeRequest,
+			Content:  a.buildStepRequest(coord, step),
+			Priority: multiagent.PriorityHigh,
+			Context: map[string]interface{}{
+				"coordination_id": coord.ID,
+				"conversation_id": coord.ConversationID,
+				"role":            string(step.Agent),
+				"plan_step":       step.ID,
+			},
+		})
+		if err != nil {
+			a.finishStep(ctx, coord, i, stepFailed, err.Error())
+			continue
+		}
+		coord.SpecialistIDs = append(coord.SpecialistIDs, step.AgentID)
+		futures[i] = future
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, coordinationTimeout)
+	defer cancel()
+	for _, i := range wave {
+		future, ok := futures[i]
+		if !ok {
+			continue
+		}
+		reply, err := future.Wait(waitCtx)
+		if err != nil {
+			future.Cancel()
+			a.finishStep(ctx, coord, i, stepFailed, err.Error())
+			continue
+		}
+		if reply.Type == multiagent.MessageTypeQuestion {
+			coord.Questions++
+			a.finishStep(ctx, coord, i, stepAsked, reply.Content)
+			continue
+		}
+		a.finishStep(ctx, coord, i, stepCompleted, reply.Content)
+	}
+}
+
+// blockedBy returns the first dependency of step that did not complete
+func (a *CoordinatorAgent) blockedBy(plan *Plan, step *PlanStep) string {
+	for _, dep := range step.DependsOn {
+		for _, prereq := range plan.Steps {
+			if prereq.ID == dep && prereq.Status != stepCompleted {
+				return dep
+			}
+		}
+	}
+	return ""
+}
+
+// finishStep records the outcome of step i and reports it
+func (a *CoordinatorAgent) finishStep(ctx context.Context, coord *coordination, i int, status, result string) {
+	step := &coord.Plan.Steps[i]
+	a.mu.Lock()
+	step.Status = status
+	step.Result = result
+	if status == stepCompleted {
+		if previous := coord.Responses[step.AgentID]; previous != "" {
+			result = previous + "\n\n" + result
+		}
+		coord.Responses[step.AgentID] = result
+	}
+	a.mu.Unlock()
+
+	if status != stepCompleted {
+		a.logger.WarnContext(ctx, "Plan step did not complete", "coordination_id", coord.ID, "step", step.ID, "status", status, "reason", step.Result)
+	}
+	progress.Emit(ctx, progress.Event{
+		Type:           progress.StepFinished,
+		ConversationID: coord.ConversationID,
+		AgentID:        step.AgentID,
+		Detail:         truncateText(step.Result, stepResultLength),
+		Data:           map[string]interface{}{"step": step.ID, "status": status, "index": i + 1, "total": len(coord.Plan.Steps)},
+	})
+}
+
+// buildStepRequest is the message asking a specialist to carry out step,
+// carrying the results of the steps it depends on
+func (a *CoordinatorAgent) buildStepRequest(coord *coordination, step *PlanStep) string {
+	var request strings.Builder
+	request.WriteString(step.Instruction)
+	fmt.Fprintf(&request, "\n\nThis is part of handling the user's request: %q", coord.UserMessage)
+	if step.Output != "" {
+		fmt.Fprintf(&request, "\nExpected output: %s", step.Output)
+	}
+	for _, dep := range step.DependsOn {
+		for _, prereq := range coord.Plan.Steps {
+			if prereq.ID == dep {
+				fmt.Fprintf(&request, "\n\nResult of %s (%s):\n%s", prereq.ID, prereq.Instruction, prereq.Result)
+			}
+		}
+	}
+	return request.String()
+}
+
+// truncateText shortens s to at most n runes, marking the cut with an ellipsis
+func truncateText(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
@@ -0,0 +1,31 @@
+// Package agents implements the conversation agent, the coordinator and the
+// specialists they hand work to.
+//
+// # Grounded fact-checks
+//
+// When the wikipedia tool is configured, "fact check ..." retrieves
+// Wikipedia passages for each claim, and the research.verify_claims prompt
+// judges each claim from its passages alone, quoting the ones that support
+// or contradict it. Quotes a passage does not contain are dropped, and a
+// claim left without evidence is UNVERIFIED. Each verdict's confidence is
+// the rank-weighted share of its evidence that agrees with it, scaled down
+// when little was found. The quoted passages become the fact-check
+// session's sources, and each verdict a finding citing them. Without the
+// tool, claims are checked from the model's own knowledge
+// (research.fact_check).
+//
+// # Research monitoring
+//
+// Asking the research assistant to "monitor this topic weekly" (or daily,
+// hourly, every N days) re-runs the latest or named research on that
+// schedule, as does POST /research/{id}/monitor with an interval such as
+// 168h. The default is daily and the shortest hourly; the server's
+// -research-monitor-interval sets how often due monitors are checked. Each
+// run's findings are compared with everything the topic's earlier runs
+// found: findings that only restate them are dropped, and the
+// research.monitor prompt judges whether the rest are materially new. Only
+// then is the run kept as a research session and the user notified
+// (research_update). GET /research/monitors lists monitors with their
+// updates, and DELETE /research/monitors/{id} or "stop monitoring ..." stops
+// one.
+package agents
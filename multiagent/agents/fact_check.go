@@ -0,0 +1,268 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent/prompts"
+	"github.com/kbutz/wikillm/multiagent/search"
+)
+
+// Verdicts a fact-check reaches on a claim
+const (
+	VerdictTrue       = "TRUE"
+	VerdictFalse      = "FALSE"
+	VerdictPartly     = "PARTIALLY TRUE"
+	VerdictUnverified = "UNVERIFIED"
+)
+
+// Stances of the evidence quoted for a verdict
+const (
+	StanceSupports    = "supports"
+	StanceContradicts = "contradicts"
+)
+
+// passagesPerClaim is how many Wikipedia passages are retrieved for each
+// claim
+const passagesPerClaim = 4
+
+// ClaimVerdict is the verdict of a fact-check on one claim
+type ClaimVerdict struct {
+	Claim       string `json:"claim"`
+	Verdict     string `json:"verdict"`
+	Explanation string `json:"explanation"`
+	// Confidence is how strongly the retrieved passages agree with the
+	// verdict, 0-1; it is 0 for claims they do not bear on
+	Confidence float64         `json:"confidence"`
+	Evidence   []ClaimEvidence `json:"evidence"`
+}
+
+// ClaimEvidence is a passage quoted for or against a claim
+type ClaimEvidence struct {
+	Title  string `json:"title"`
+	URL    string `json:"url,omitempty"`
+	Quote  string `json:"quote"`
+	Stance string `json:"stance"`
+	// Rank is how highly the passage's search ranked it for the claim, 0-1
+	Rank float64 `json:"rank"`
+
+	passage *candidateSource
+}
+
+// claimPassages numbers the passages retrieved for a claim for the
+// verification prompt
+type claimPassages struct {
+	Number   int
+	Claim    string
+	Passages []citedSource
+}
+
+// groundClaims checks claims against the Wikipedia passages the wikipedia
+// tool retrieves for each, returning a verdict per claim with the passages
+// quoted as evidence, and the passages quoted as candidate sources. It
+// returns nil when there is no wikipedia tool or it found nothing, leaving
+// the fact-check to the LLM's own knowledge.
+func (a *ResearchAssistantAgent) groundClaims(ctx context.Context, request string, claims []string) ([]ClaimVerdict, []*candidateSource, error) {
+	tool, ok := a.tool(search.WikipediaToolName)
+	if !ok || len(claims) == 0 {
+		return nil, nil, nil
+	}
+
+	// Passages are numbered across claims so the prompt's citations are
+	// unambiguous; each remembers how highly it ranked for its claim
+	var passages []*candidateSource
+	grouped := make([]claimPassages, len(claims))
+	found := false
+	for i, claim := range claims {
+		grouped[i] = claimPassages{Number: i + 1, Claim: claim}
+		args, _ := json.Marshal(map[string]interface{}{"query": claim, "limit": passagesPerClaim})
+		output, err := tool.Execute(ctx, string(args))
+		var results []search.Result
+		if err == nil {
+			results, err = search.ParseResults(output)
+		}
+		if err != nil {
+			a.logger.WarnContext(ctx, "Fact-check search failed", "claim", claim, "error", err)
+			continue
+		}
+		best := 0.0
+		for _, result := range results {
+			best = math.Max(best, result.Score)
+		}
+		for j, result := range results {
+			if strings.TrimSpace(result.Snippet) == "" {
+				continue
+			}
+			rank := 1 / float64(j+1)
+			if best > 0 {
+				rank = result.Score / best
+			}
+			if result.Source == "" {
+				result.Source = search.SourceWikipedia
+			}
+			passages = append(passages, &candidateSource{Result: result, Tool: tool.Name(), Queries: []string{claim}, rank: rank, relevance: rank})
+			grouped[i].Passages = append(grouped[i].Passages, citedSource{Number: len(passages), Title: result.Title, URL: result.URL, Snippet: result.Snippet})
+			found = true
+		}
+	}
+	if !found {
+		a.logger.InfoContext(ctx, "Wikipedia had nothing on the claims, fact-checking without it")
+		return nil, nil, nil
+	}
+
+	verifyPrompt, err := a.renderPrompt(ctx, "research.verify_claims", prompts.Vars{
+		"Request": request,
+		"Claims":  grouped,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	var verification struct {
+		Verdicts []struct {
+			Claim       int    `json:"claim"`
+			Verdict     string `json:"verdict"`
+			Explanation string `json:"explanation"`
+			Evidence    []struct {
+				Passage int    `json:"passage"`
+				Quote   string `json:"quote"`
+				Stance  string `json:"stance"`
+			} `json:"evidence"`
+		} `json:"verdicts"`
+	}
+	verifySchema := objectSchema(map[string]string{"verdicts": "array"}, "verdicts")
+	if err := a.queryJSON(ctx, verifyPrompt, verifySchema, &verification); err != nil {
+		return nil, nil, fmt.Errorf("failed to verify claims: %w", err)
+	}
+
+	verdicts := make([]ClaimVerdict, len(claims))
+	for i, claim := range claims {
+		verdicts[i] = ClaimVerdict{Claim: claim, Verdict: VerdictUnverified, Evidence: []ClaimEvidence{}}
+	}
+	var quoted []*candidateSource
+	for _, item := range verification.Verdicts {
+		if item.Claim < 1 || item.Claim > len(claims) {
+			continue
+		}
+		verdict := &verdicts[item.Claim-1]
+		verdict.Verdict = normalizeVerdict(item.Verdict)
+		verdict.Explanation = strings.TrimSpace(item.Explanation)
+		for _, evidence := range item.Evidence {
+			// Only passages retrieved for this claim count, and only quotes
+			// they really contain: a quote the model made up is no evidence
+			passage := findPassage(grouped[item.Claim-1].Passages, evidence.Passage)
+			stance := strings.ToLower(strings.TrimSpace(evidence.Stance))
+			if passage == nil || (stance != StanceSupports && stance != StanceContradicts) {
+				continue
+			}
+			quote := strings.Trim(strings.TrimSpace(evidence.Quote), `"“”`)
+			if quote == "" || !strings.Contains(normalizeQuote(passage.Snippet), normalizeQuote(quote)) {
+				a.logger.DebugContext(ctx, "Dropped evidence not found in its passage", "passage", evidence.Passage, "quote", quote)
+				continue
+			}
+			candidate := passages[evidence.Passage-1]
+			verdict.Evidence = append(verdict.Evidence, ClaimEvidence{
+				Title:   passage.Title,
+				URL:     passage.URL,
+				Quote:   quote,
+				Stance:  stance,
+				Rank:    math.Round(candidate.rank*100) / 100,
+				passage: candidate,
+			})
+			if !slices.Contains(quoted, candidate) {
+				quoted = append(quoted, candidate)
+			}
+		}
+	}
+	for i := range verdicts {
+		calibrateVerdict(&verdicts[i])
+	}
+	return verdicts, quoted, nil
+}
+
+// calibrateVerdict sets verdict's confidence from how much the passages
+// quoted for it agree: the rank-weighted share of evidence on the verdict's
+// side, scaled down when little evidence was found. A verdict no quoted
+// passage bears on is UNVERIFIED.
+func calibrateVerdict(verdict *ClaimVerdict) {
+	var supports, contradicts float64
+	for _, evidence := range verdict.Evidence {
+		if evidence.Stance == StanceSupports {
+			supports += evidence.Rank
+		} else {
+			contradicts += evidence.Rank
+		}
+	}
+	total := supports + contradicts
+	if total == 0 {
+		verdict.Verdict = VerdictUnverified
+		verdict.Confidence = 0
+		return
+	}
+
+	var agreeing float64
+	switch verdict.Verdict {
+	case VerdictTrue:
+		agreeing = supports
+	case VerdictFalse:
+		agreeing = contradicts
+	case VerdictPartly:
+		// Partly true is borne out by passages on both sides
+		agreeing = 2 * math.Min(supports, contradicts)
+	default:
+		// The passages bear on the claim, so calling it unverified is only
+		// as sound as they are split
+		agreeing = total - math.Abs(supports-contradicts)
+	}
+	strength := 1 - math.Exp(-total)
+	verdict.Confidence = math.Round(agreeing/total*strength*100) / 100
+}
+
+// normalizeVerdict maps the model's verdict onto the known ones
+func normalizeVerdict(verdict string) string {
+	verdict = strings.ToUpper(strings.Join(strings.Fields(strings.ReplaceAll(verdict, "_", " ")), " "))
+	switch verdict {
+	case VerdictTrue, VerdictFalse, VerdictPartly:
+		return verdict
+	case "PARTLY TRUE", "PARTIAL", "MIXED":
+		return VerdictPartly
+	default:
+		return VerdictUnverified
+	}
+}
+
+// normalizeQuote lowercases text and collapses its whitespace, so quotes are
+// matched however the model wrapped them
+func normalizeQuote(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// findPassage returns the passage numbered number, if it is among passages
+func findPassage(passages []citedSource, number int) *citedSource {
+	for i := range passages {
+		if passages[i].Number == number {
+			return &passages[i]
+		}
+	}
+	return nil
+}
+
+// formatVerdicts renders verdicts as the fact-check report
+func formatVerdicts(verdicts []ClaimVerdict) string {
+	var report strings.Builder
+	for i, verdict := range verdicts {
+		fmt.Fprintf(&report, "**%d. %s**\n%s (confidence %.0f%%)", i+1, verdict.Claim, verdict.Verdict, verdict.Confidence*100)
+		if verdict.Explanation != "" {
+			report.WriteString(": " + verdict.Explanation)
+		}
+		report.WriteString("\n")
+		for _, evidence := range verdict.Evidence {
+			fmt.Fprintf(&report, "> \"%s\" (%s, %s)\n", evidence.Quote, evidence.Title, evidence.Stance)
+		}
+		report.WriteString("\n")
+	}
+	return strings.TrimSuffix(report.String(), "\n\n")
+}
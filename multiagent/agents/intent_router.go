@@ -0,0 +1,292 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/llmprovider"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// Intent is one label an IntentRouter can assign to a message
+type Intent struct {
+	Label       string
+	Description string // Shown to the LLM
+	// Keywords are fallback phrases, checked in intent order when the LLM is
+	// unavailable or unsure. Join terms with "&" to require all of them
+	// ("cancel&meeting").
+	Keywords []string
+}
+
+// IntentResult is the outcome of classifying a message
+type IntentResult struct {
+	Label      string  `json:"intent"`
+	Confidence float64 `json:"confidence"`
+	Source     string  `json:"source"` // "llm", "keyword", or "default"
+}
+
+// IntentRouterConfig holds configuration for creating an IntentRouter
+type IntentRouterConfig struct {
+	Name        string // Used in logs, e.g. "SchedulerAgent"
+	LLMProvider multiagent.LLMProvider
+	Intents     []Intent
+	// Default is returned when nothing matches, typically a general-query label
+	Default string
+	// MinConfidence below which the LLM label is checked against keywords (default 0.5)
+	MinConfidence float64
+	// Prompts renders the classification prompts (defaults to prompts.Default)
+	Prompts *prompts.Registry
+}
+
+var intentLogger = logging.For("intent_router")
+
+// IntentRouter classifies free-form requests into a fixed label set using the
+// LLM, with a keyword layer as fallback
+type IntentRouter struct {
+	name          string
+	llmProvider   multiagent.LLMProvider
+	intents       []Intent
+	labels        map[string]bool
+	defaultLabel  string
+	minConfidence float64
+	prompts       *prompts.Registry
+}
+
+// NewIntentRouter creates a new intent router
+func NewIntentRouter(config IntentRouterConfig) *IntentRouter {
+	if config.MinConfidence == 0 {
+		config.MinConfidence = 0.5
+	}
+	if config.Prompts == nil {
+		config.Prompts = prompts.Default()
+	}
+
+	labels := make(map[string]bool, len(config.Intents)+1)
+	for _, intent := range config.Intents {
+		labels[intent.Label] = true
+	}
+	labels[config.Default] = true
+
+	return &IntentRouter{
+		name:          config.Name,
+		llmProvider:   config.LLMProvider,
+		intents:       config.Intents,
+		labels:        labels,
+		defaultLabel:  config.Default,
+		minConfidence: config.MinConfidence,
+		prompts:       config.Prompts,
+	}
+}
+
+// Classify returns the intent of text. A confident LLM label wins; otherwise
+// the first keyword match, then a low-confidence LLM label, then the default.
+func (r *IntentRouter) Classify(ctx context.Context, text string) IntentResult {
+	llmResult, llmErr := r.classifyWithLLM(ctx, text)
+	if llmErr == nil && llmResult.Confidence >= r.minConfidence {
+		return llmResult
+	}
+	if llmErr != nil && r.llmProvider != nil {
+		intentLogger.WarnContext(ctx, "Intent classification fell back to keywords", "caller", r.name, "error", llmErr)
+	}
+
+	if result, ok := r.classifyWithKeywords(text); ok {
+		return result
+	}
+	if llmErr == nil {
+		return llmResult
+	}
+	return IntentResult{Label: r.defaultLabel, Source: "default"}
+}
+
+// ClassifyAll returns every intent in text, most confident first, for
+// requests that ask for several things at once. Confident LLM labels win;
+// otherwise every keyword match, then the low-confidence LLM labels, then
+// the default.
+func (r *IntentRouter) ClassifyAll(ctx context.Context, text string) []IntentResult {
+	llmResults, llmErr := r.classifyAllWithLLM(ctx, text)
+	var confident []IntentResult
+	for _, result := range llmResults {
+		if result.Confidence >= r.minConfidence {
+			confident = append(confident, result)
+		}
+	}
+	if len(confident) > 0 {
+		return confident
+	}
+	if llmErr != nil && r.llmProvider != nil {
+		intentLogger.WarnContext(ctx, "Intent classification fell back to keywords", "caller", r.name, "error", llmErr)
+	}
+
+	if results := r.matchAllKeywords(text); len(results) > 0 {
+		return results
+	}
+	if len(llmResults) > 0 {
+		return llmResults
+	}
+	return []IntentResult{{Label: r.defaultLabel, Source: "default"}}
+}
+
+// Describe returns the description of label, for asking the user which
+// intent they meant
+func (r *IntentRouter) Describe(label string) string {
+	for _, intent := range r.intents {
+		if intent.Label == label {
+			return intent.Description
+		}
+	}
+	return label
+}
+
+func (r *IntentRouter) classifyWithLLM(ctx context.Context, text string) (IntentResult, error) {
+	if r.llmProvider == nil {
+		return IntentResult{}, fmt.Errorf("no LLM provider")
+	}
+
+	labels := r.sortedLabels()
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"intent"},
+		"properties": map[string]interface{}{
+			"intent":     map[string]interface{}{"type": "string", "enum": labels},
+			"confidence": map[string]interface{}{"type": "number"},
+		},
+	}
+
+	// One repair at most: classification runs on every message
+	structured := llmprovider.NewStructuredOutput(llmprovider.StructuredOutputConfig{
+		Provider:   r.llmProvider,
+		Name:       r.name,
+		MaxRepairs: 1,
+		Metrics:    parseMetrics,
+	})
+	prompt, err := r.prompts.Render(ctx, "intent.classify", r.promptVars(text))
+	if err != nil {
+		return IntentResult{}, err
+	}
+	var result IntentResult
+	ctx = llmprovider.WithPromptClass(ctx, llmprovider.PromptClassIntent)
+	if err := structured.Query(ctx, prompt, schema, &result); err != nil {
+		return IntentResult{}, fmt.Errorf("failed to classify intent: %w", err)
+	}
+	if result.Confidence <= 0 || result.Confidence > 1 {
+		result.Confidence = 1
+	}
+	result.Source = "llm"
+	return result, nil
+}
+
+func (r *IntentRouter) classifyAllWithLLM(ctx context.Context, text string) ([]IntentResult, error) {
+	if r.llmProvider == nil {
+		return nil, fmt.Errorf("no LLM provider")
+	}
+
+	labels := r.sortedLabels()
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"intents"},
+		"properties": map[string]interface{}{
+			"intents": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"intent"},
+					"properties": map[string]interface{}{
+						"intent":     map[string]interface{}{"type": "string", "enum": labels},
+						"confidence": map[string]interface{}{"type": "number"},
+					},
+				},
+			},
+		},
+	}
+
+	structured := llmprovider.NewStructuredOutput(llmprovider.StructuredOutputConfig{
+		Provider:   r.llmProvider,
+		Name:       r.name,
+		MaxRepairs: 1,
+		Metrics:    parseMetrics,
+	})
+	prompt, err := r.prompts.Render(ctx, "intent.classify_all", r.promptVars(text))
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Intents []IntentResult `json:"intents"`
+	}
+	ctx = llmprovider.WithPromptClass(ctx, llmprovider.PromptClassIntent)
+	if err := structured.Query(ctx, prompt, schema, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to classify intents: %w", err)
+	}
+
+	seen := make(map[string]bool, len(parsed.Intents))
+	results := make([]IntentResult, 0, len(parsed.Intents))
+	for _, result := range parsed.Intents {
+		if !r.labels[result.Label] || seen[result.Label] {
+			continue
+		}
+		seen[result.Label] = true
+		if result.Confidence <= 0 || result.Confidence > 1 {
+			result.Confidence = 1
+		}
+		result.Source = "llm"
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("failed to classify intents: no known intent in the response")
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Confidence > results[j].Confidence })
+	return results, nil
+}
+
+// sortedLabels lists every label the router can return
+func (r *IntentRouter) sortedLabels() []string {
+	labels := make([]string, 0, len(r.labels))
+	for label := range r.labels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// promptVars are the classification prompts' values: the intents, with
+// their descriptions for the LLM, the default label and the request
+func (r *IntentRouter) promptVars(text string) prompts.Vars {
+	return prompts.Vars{"Intents": r.intents, "Default": r.defaultLabel, "Request": text}
+}
+
+// matchAllKeywords returns every intent with a keyword in text, in intent
+// order
+func (r *IntentRouter) matchAllKeywords(text string) []IntentResult {
+	content := strings.ToLower(text)
+	var results []IntentResult
+	for _, intent := range r.intents {
+		for _, keyword := range intent.Keywords {
+			if containsAllTerms(content, keyword) {
+				results = append(results, IntentResult{Label: intent.Label, Confidence: 0.5, Source: "keyword"})
+				break
+			}
+		}
+	}
+	return results
+}
+
+// classifyWithKeywords returns the first intent with a keyword in text
+func (r *IntentRouter) classifyWithKeywords(text string) (IntentResult, bool) {
+	if results := r.matchAllKeywords(text); len(results) > 0 {
+		return results[0], true
+	}
+	return IntentResult{}, false
+}
+
+// containsAllTerms reports whether content contains every "&"-separated term of keyword
+func containsAllTerms(content, keyword string) bool {
+	for _, term := range strings.Split(keyword, "&") {
+		if !strings.Contains(content, strings.TrimSpace(term)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// scriptedProvider answers prompts with its replies in turn, repeating the
+// last, or fails every prompt with err
+type scriptedProvider struct {
+	replies []string
+	err     error
+	calls   int
+}
+
+func (p *scriptedProvider) Name() string { return "scripted" }
+
+func (p *scriptedProvider) Query(ctx context.Context, prompt string) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	reply := p.replies[min(p.calls, len(p.replies)-1)]
+	p.calls++
+	return reply, nil
+}
+
+func (p *scriptedProvider) QueryWithTools(ctx context.Context, prompt string, tools []multiagent.Tool) (string, error) {
+	return p.Query(ctx, prompt)
+}
+
+// newTestIntentRouter routes calendar requests like the scheduler does
+func newTestIntentRouter(provider multiagent.LLMProvider) *IntentRouter {
+	config := IntentRouterConfig{
+		Name:    "test",
+		Default: "general",
+		Intents: []Intent{
+			{Label: "cancel_event", Description: "cancel a meeting", Keywords: []string{"cancel&meeting"}},
+			{Label: "reschedule", Description: "move a calendar event", Keywords: []string{"reschedule", "move&meeting", "move&event"}},
+			{Label: "view_calendar", Description: "show the calendar", Keywords: []string{"calendar"}},
+		},
+	}
+	if provider != nil {
+		config.LLMProvider = provider
+	}
+	return NewIntentRouter(config)
+}
+
+func TestIntentRouter_Classify(t *testing.T) {
+	unavailable := errors.New("connection refused")
+	tests := []struct {
+		name     string
+		provider *scriptedProvider
+		text     string
+		want     IntentResult
+	}{
+		{
+			name:     "confident LLM label wins over keywords",
+			provider: &scriptedProvider{replies: []string{`{"intent": "cancel_event", "confidence": 0.9}`}},
+			text:     "move the meeting, actually no, drop it",
+			want:     IntentResult{Label: "cancel_event", Confidence: 0.9, Source: "llm"},
+		},
+		{
+			name:     "unsure LLM label falls back to keywords",
+			provider: &scriptedProvider{replies: []string{`{"intent": "general", "confidence": 0.3}`}},
+			text:     "move the meeting to 3pm",
+			want:     IntentResult{Label: "reschedule", Confidence: 0.5, Source: "keyword"},
+		},
+		{
+			name:     "unsure LLM label is kept without a keyword",
+			provider: &scriptedProvider{replies: []string{`{"intent": "view_calendar", "confidence": 0.3}`}},
+			text:     "what's on tomorrow?",
+			want:     IntentResult{Label: "view_calendar", Confidence: 0.3, Source: "llm"},
+		},
+		{
+			// Every & term has to appear, so moving furniture is no reschedule
+			name:     "keyword terms must all match",
+			provider: &scriptedProvider{err: unavailable},
+			text:     "move my desk to the window",
+			want:     IntentResult{Label: "general", Source: "default"},
+		},
+		{
+			name:     "keywords without the LLM",
+			provider: &scriptedProvider{err: unavailable},
+			text:     "please reschedule lunch",
+			want:     IntentResult{Label: "reschedule", Confidence: 0.5, Source: "keyword"},
+		},
+		{
+			name: "default without an LLM or keyword",
+			text: "hello there",
+			want: IntentResult{Label: "general", Source: "default"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var provider multiagent.LLMProvider
+			if test.provider != nil {
+				provider = test.provider
+			}
+			if got := newTestIntentRouter(provider).Classify(context.Background(), test.text); got != test.want {
+				t.Errorf("Classify(%q) = %+v, want %+v", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIntentRouter_ClassifyAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		replies []string
+		text    string
+		want    []IntentResult
+	}{
+		{
+			name:    "duplicate labels are dropped, most confident first",
+			replies: []string{`{"intents": [{"intent": "cancel_event", "confidence": 0.6}, {"intent": "cancel_event", "confidence": 0.9}, {"intent": "reschedule", "confidence": 0.8}]}`},
+			text:    "cancel standup and move the review",
+			want: []IntentResult{
+				{Label: "reschedule", Confidence: 0.8, Source: "llm"},
+				{Label: "cancel_event", Confidence: 0.6, Source: "llm"},
+			},
+		},
+		{
+			name:    "unknown labels are never returned",
+			replies: []string{`{"intents": [{"intent": "book_flight", "confidence": 0.9}]}`},
+			text:    "show my calendar",
+			want:    []IntentResult{{Label: "view_calendar", Confidence: 0.5, Source: "keyword"}},
+		},
+		{
+			name:    "unsure labels fall back to every keyword match",
+			replies: []string{`{"intents": [{"intent": "general", "confidence": 0.2}]}`},
+			text:    "cancel the meeting and move the event",
+			want: []IntentResult{
+				{Label: "cancel_event", Confidence: 0.5, Source: "keyword"},
+				{Label: "reschedule", Confidence: 0.5, Source: "keyword"},
+			},
+		},
+		{
+			name:    "unsure labels are kept without a keyword",
+			replies: []string{`{"intents": [{"intent": "general", "confidence": 0.2}]}`},
+			text:    "move my desk",
+			want:    []IntentResult{{Label: "general", Confidence: 0.2, Source: "llm"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := newTestIntentRouter(&scriptedProvider{replies: test.replies})
+			if got := router.ClassifyAll(context.Background(), test.text); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ClassifyAll(%q) = %+v, want %+v", test.text, got, test.want)
+			}
+		})
+	}
+
+	if got := newTestIntentRouter(nil).ClassifyAll(context.Background(), "hello"); !reflect.DeepEqual(got, []IntentResult{{Label: "general", Source: "default"}}) {
+		t.Errorf("ClassifyAll without an LLM or keyword = %+v, want the default", got)
+	}
+}
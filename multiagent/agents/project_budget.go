@@ -0,0 +1,415 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+const (
+	// defaultCurrency is used when a budget is set without one
+	defaultCurrency = "USD"
+	// budgetWarnShare is the share of a budget spent that draws a warning
+	budgetWarnShare = 0.8
+)
+
+// Budget commands a user can give
+const (
+	budgetSet      = "set"
+	budgetAllocate = "allocate"
+	budgetExpense  = "expense"
+	budgetReport   = "report"
+)
+
+// Expense is money spent on a project
+type Expense struct {
+	ID          string    `json:"id"`
+	Amount      float64   `json:"amount"`
+	Category    string    `json:"category,omitempty"`
+	Description string    `json:"description"`
+	Date        time.Time `json:"date"`
+}
+
+var (
+	// moneyAmount matches an amount such as "$1,200", "350.50" or "2k"
+	moneyAmount = regexp.MustCompile(`(?i)([$€£])?\s*(\d[\d,]*(?:\.\d+)?)\s*(k\b)?`)
+	// budgetPhrase matches "set a budget of $10,000 for website"
+	budgetPhrase = regexp.MustCompile(`(?i)\b(?:set|create|make)\s+(?:a\s+|the\s+)?budget\b`)
+	// expensePhrase matches "spent $200 on hosting" and "log an expense of 50
+	// for stock photos"
+	expensePhrase = regexp.MustCompile(`(?i)\b(?:spent|spend|paid|pay|log(?:ged)?|record(?:ed)?|add(?:ed)?)\b.*?\d.*?\b(?:on|for)\s+(?:the\s+)?(.+?)(?:\s+(?:under|in|to)\s+(?:the\s+)?(\w+)(?:\s+category)?)?[.!?]*$`)
+	// allocatePhrase matches "allocate $3,000 to design"
+	allocatePhrase = regexp.MustCompile(`(?i)\ballocate\b.*?\d.*?\b(?:to|for)\s+(?:the\s+)?(\w+)`)
+	// currencySymbols are the currencies of the amount symbols moneyAmount
+	// reads
+	currencySymbols = map[string]string{"$": "USD", "€": "EUR", "£": "GBP"}
+)
+
+// recalculate updates the budget's spent and remaining amounts from its
+// expenses
+func (b *Budget) recalculate() {
+	b.SpentAmount = 0
+	for _, expense := range b.Expenses {
+		b.SpentAmount += expense.Amount
+	}
+	b.RemainingBudget = b.TotalBudget - b.SpentAmount
+}
+
+// spentByCategory totals the budget's expenses by category
+func (b *Budget) spentByCategory() map[string]float64 {
+	spent := make(map[string]float64)
+	for _, expense := range b.Expenses {
+		spent[expense.Category] += expense.Amount
+	}
+	return spent
+}
+
+// burnRate is the budget's average spending per day since it was set,
+// counting at least one day
+func (b *Budget) burnRate(now time.Time) float64 {
+	if b.SpentAmount <= 0 {
+		return 0
+	}
+	days := math.Max(now.Sub(b.StartedAt).Hours()/24, 1)
+	return b.SpentAmount / days
+}
+
+// formatMoney writes an amount in a currency
+func formatMoney(amount float64, currency string) string {
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
+
+// budgetAlerts warns about a project's budget: overspent or nearly spent,
+// heading over by the project's due date at the current burn rate, or a
+// category over its allocation
+func budgetAlerts(project *Project, now time.Time) []string {
+	budget := project.Budget
+	if budget == nil || budget.TotalBudget <= 0 {
+		return nil
+	}
+	var alerts []string
+	switch {
+	case budget.SpentAmount > budget.TotalBudget:
+		alerts = append(alerts, fmt.Sprintf("🚨 Over budget by %s", formatMoney(budget.SpentAmount-budget.TotalBudget, budget.Currency)))
+	case budget.SpentAmount >= budgetWarnShare*budget.TotalBudget:
+		alerts = append(alerts, fmt.Sprintf("⚠️ %.0f%% of the budget is spent", 100*budget.SpentAmount/budget.TotalBudget))
+	}
+	if projected, ok := projectedSpend(project, now); ok && projected > budget.TotalBudget && budget.SpentAmount <= budget.TotalBudget {
+		alerts = append(alerts, fmt.Sprintf("📈 At the current burn rate spending reaches %s by the due date, %s over budget", formatMoney(projected, budget.Currency), formatMoney(projected-budget.TotalBudget, budget.Currency)))
+	}
+
+	spent := budget.spentByCategory()
+	categories := make([]string, 0, len(budget.Categories))
+	for category := range budget.Categories {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		if allocated := budget.Categories[category]; spent[category] > allocated {
+			alerts = append(alerts, fmt.Sprintf("⚠️ %s is over its %s allocation by %s", category, formatMoney(allocated, budget.Currency), formatMoney(spent[category]-allocated, budget.Currency)))
+		}
+	}
+	return alerts
+}
+
+// projectedSpend is what the project will have spent by its due date at
+// its current burn rate; it is not known for projects without a due date
+// or spending
+func projectedSpend(project *Project, now time.Time) (float64, bool) {
+	budget := project.Budget
+	if budget == nil || project.DueDate == nil || !project.DueDate.After(now) {
+		return 0, false
+	}
+	rate := budget.burnRate(now)
+	if rate <= 0 {
+		return 0, false
+	}
+	return budget.SpentAmount + rate*project.DueDate.Sub(now).Hours()/24, true
+}
+
+// writeBudget reports a project's budget: spending against the total and
+// each category, the burn rate and where it leads, and any alerts
+func writeBudget(b *strings.Builder, project *Project, now time.Time) {
+	budget := project.Budget
+	currency := budget.Currency
+	b.WriteString(fmt.Sprintf("• Total: %s\n", formatMoney(budget.TotalBudget, currency)))
+	if budget.TotalBudget > 0 {
+		b.WriteString(fmt.Sprintf("• Spent: %s (%.0f%%)\n", formatMoney(budget.SpentAmount, currency), 100*budget.SpentAmount/budget.TotalBudget))
+	} else {
+		b.WriteString(fmt.Sprintf("• Spent: %s\n", formatMoney(budget.SpentAmount, currency)))
+	}
+	b.WriteString(fmt.Sprintf("• Remaining: %s\n", formatMoney(budget.RemainingBudget, currency)))
+
+	if rate := budget.burnRate(now); rate > 0 {
+		b.WriteString(fmt.Sprintf("• Burn rate: %s/day\n", formatMoney(rate, currency)))
+		if projected, ok := projectedSpend(project, now); ok {
+			b.WriteString(fmt.Sprintf("• Projected by %s: %s\n", project.DueDate.Format("2006-01-02"), formatMoney(projected, currency)))
+		} else if budget.RemainingBudget > 0 {
+			runsOut := now.Add(time.Duration(budget.RemainingBudget / rate * float64(24*time.Hour)))
+			b.WriteString(fmt.Sprintf("• Runs out around %s at this rate\n", runsOut.Format("2006-01-02")))
+		}
+	}
+
+	spent := budget.spentByCategory()
+	categories := make(map[string]bool)
+	for category := range budget.Categories {
+		categories[category] = true
+	}
+	for category := range spent {
+		if category != "" {
+			categories[category] = true
+		}
+	}
+	if len(categories) > 0 {
+		names := make([]string, 0, len(categories))
+		for category := range categories {
+			names = append(names, category)
+		}
+		sort.Strings(names)
+		b.WriteString("\n**By category**\n")
+		for _, category := range names {
+			if allocated, ok := budget.Categories[category]; ok {
+				b.WriteString(fmt.Sprintf("• %s: %s of %s\n", category, formatMoney(spent[category], currency), formatMoney(allocated, currency)))
+			} else {
+				b.WriteString(fmt.Sprintf("• %s: %s (unallocated)\n", category, formatMoney(spent[category], currency)))
+			}
+		}
+	}
+
+	if alerts := budgetAlerts(project, now); len(alerts) > 0 {
+		b.WriteString("\n" + strings.Join(alerts, "\n") + "\n")
+	}
+}
+
+// budgetCategory returns the allocated category an expense's description
+// names, as "hosting" in "hosting for March", or ""
+func budgetCategory(budget *Budget, description string) string {
+	words := strings.Fields(strings.ToLower(description))
+	for _, word := range words {
+		if _, ok := budget.Categories[word]; ok {
+			return word
+		}
+	}
+	return ""
+}
+
+// parseMoney reads the first amount in text and the currency its symbol
+// names, if any
+func parseMoney(text string) (float64, string, bool) {
+	match := moneyAmount.FindStringSubmatch(text)
+	if match == nil {
+		return 0, "", false
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(match[2], ",", ""), 64)
+	if err != nil {
+		return 0, "", false
+	}
+	if match[3] != "" {
+		amount *= 1000
+	}
+	return amount, currencySymbols[match[1]], true
+}
+
+// handleProjectBudget sets a project's budget and category allocations,
+// logs expenses against it, or reports where it stands
+func (a *ProjectManagerAgent) handleProjectBudget(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadProjectsFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now().In(loc)
+
+	prompt, err := a.renderPrompt(ctx, "project.budget", prompts.Vars{
+		"Request": msg.Content,
+		"Now":     now.Format("2006-01-02 (Monday)"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Project     string             `json:"project"`
+		Action      string             `json:"action"`
+		Amount      float64            `json:"amount"`
+		Currency    string             `json:"currency"`
+		Category    string             `json:"category"`
+		Categories  map[string]float64 `json:"categories"`
+		Description string             `json:"description"`
+		Date        string             `json:"date"`
+	}
+	budgetSchema := objectSchema(map[string]string{
+		"project":     "string",
+		"action":      "string",
+		"amount":      "number",
+		"currency":    "string",
+		"category":    "string",
+		"categories":  "object",
+		"description": "string",
+		"date":        "string",
+	}, "action")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, budgetSchema, &data); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse budget request", "error", err)
+		content := strings.TrimSpace(msg.Content)
+		data.Action = budgetReport
+		amount, currency, hasAmount := parseMoney(content)
+		switch {
+		case hasAmount && budgetPhrase.MatchString(content):
+			data.Action, data.Amount, data.Currency = budgetSet, amount, currency
+		case hasAmount && allocatePhrase.MatchString(content):
+			data.Action, data.Amount, data.Category = budgetAllocate, amount, allocatePhrase.FindStringSubmatch(content)[1]
+		case hasAmount:
+			if match := expensePhrase.FindStringSubmatch(content); match != nil {
+				data.Action, data.Amount, data.Description, data.Category = budgetExpense, amount, match[1], match[2]
+			}
+		}
+	}
+
+	project := a.resolveProject(ctx, data.Project, msg.Content)
+	if project == nil {
+		return a.respond(msg, "❌ Project not found. Use 'list projects' to see available projects.", nil), nil
+	}
+
+	action := strings.ToLower(strings.TrimSpace(data.Action))
+	category := strings.ToLower(strings.TrimSpace(data.Category))
+	currency := strings.ToUpper(strings.TrimSpace(data.Currency))
+
+	a.projectMutex.Lock()
+	if action != budgetSet && project.Budget == nil {
+		name := project.Name
+		a.projectMutex.Unlock()
+		return a.respond(msg, fmt.Sprintf("💰 '%s' has no budget yet. Say something like \"set a budget of $10,000 for %s\".", name, name), nil), nil
+	}
+
+	var content string
+	payload := map[string]interface{}{"name": project.Name, "action": "budget_" + action}
+	switch action {
+	case budgetSet:
+		if data.Amount <= 0 {
+			a.projectMutex.Unlock()
+			return a.respond(msg, fmt.Sprintf("💰 How much is the budget for '%s'?", project.Name), nil), nil
+		}
+		if project.Budget == nil {
+			project.Budget = &Budget{Categories: make(map[string]float64), StartedAt: now}
+		}
+		project.Budget.TotalBudget = data.Amount
+		if currency != "" {
+			project.Budget.Currency = currency
+		} else if project.Budget.Currency == "" {
+			project.Budget.Currency = defaultCurrency
+		}
+		for name, amount := range data.Categories {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" && amount >= 0 {
+				project.Budget.Categories[name] = amount
+			}
+		}
+		content = fmt.Sprintf("💰 Set the budget of '%s' to %s.", project.Name, formatMoney(data.Amount, project.Budget.Currency))
+		payload["total_budget"] = data.Amount
+
+	case budgetAllocate:
+		if category == "" || data.Amount < 0 {
+			a.projectMutex.Unlock()
+			return a.respond(msg, "💰 Which category, and how much should it get? Say something like \"allocate $3,000 to design\".", nil), nil
+		}
+		project.Budget.Categories[category] = data.Amount
+		content = fmt.Sprintf("💰 Allocated %s of '%s' to %s.", formatMoney(data.Amount, project.Budget.Currency), project.Name, category)
+		allocated := 0.0
+		for _, amount := range project.Budget.Categories {
+			allocated += amount
+		}
+		if allocated > project.Budget.TotalBudget {
+			content += fmt.Sprintf("\n\n⚠️ Categories now add up to %s, more than the %s budget.", formatMoney(allocated, project.Budget.Currency), formatMoney(project.Budget.TotalBudget, project.Budget.Currency))
+		}
+		payload["category"], payload["amount"] = category, data.Amount
+
+	case budgetExpense:
+		if data.Amount <= 0 {
+			a.projectMutex.Unlock()
+			return a.respond(msg, "💰 How much was spent? Say something like \"spent $200 on hosting for website\".", nil), nil
+		}
+		date := now
+		if data.Date != "" {
+			if day, err := resolveDate(data.Date, now); err == nil {
+				date = day
+			}
+		}
+		expense := Expense{
+			ID:          a.newID("expense"),
+			Amount:      data.Amount,
+			Category:    category,
+			Description: strings.TrimSpace(data.Description),
+			Date:        date,
+		}
+		// The fallback reads "spent $200 on hosting for website" whole
+		if suffix := " for " + strings.ToLower(project.Name); strings.HasSuffix(strings.ToLower(expense.Description), suffix) {
+			expense.Description = expense.Description[:len(expense.Description)-len(suffix)]
+		}
+		if expense.Category == "" {
+			expense.Category = budgetCategory(project.Budget, expense.Description)
+		}
+		project.Budget.Expenses = append(project.Budget.Expenses, expense)
+		content = fmt.Sprintf("🧾 Logged %s", formatMoney(expense.Amount, project.Budget.Currency))
+		if expense.Description != "" {
+			content += " for " + expense.Description
+		}
+		if expense.Category != "" && !strings.EqualFold(expense.Description, expense.Category) {
+			content += " under " + expense.Category
+		}
+		content += fmt.Sprintf(" on '%s'.", project.Name)
+		payload["amount"], payload["category"] = expense.Amount, expense.Category
+
+	case budgetReport:
+
+	default:
+		a.projectMutex.Unlock()
+		return a.respond(msg, "💰 I can set a project's budget, allocate it to categories, log expenses, or report where it stands.", nil), nil
+	}
+
+	project.Budget.recalculate()
+	var b strings.Builder
+	if content != "" {
+		b.WriteString(content + "\n\n")
+	}
+	b.WriteString(fmt.Sprintf("💰 **Budget: %s**\n", project.Name))
+	writeBudget(&b, project, now)
+	snapshot := *project
+	budget := *project.Budget
+	budget.Categories = make(map[string]float64, len(project.Budget.Categories))
+	for name, amount := range project.Budget.Categories {
+		budget.Categories[name] = amount
+	}
+	budget.Expenses = append([]Expense(nil), project.Budget.Expenses...)
+	snapshot.Budget = &budget
+	a.projectMutex.Unlock()
+
+	if action == budgetReport {
+		return a.respond(msg, b.String(), map[string]interface{}{
+			"project_id": snapshot.ID,
+			"action":     "budget_report",
+		}), nil
+	}
+
+	if err := a.saveProject(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	if action == budgetSet {
+		a.recordShared(ctx, msg, memory.BlackboardEntry{
+			Section: memory.BlackboardFacts,
+			Key:     "project:" + snapshot.ID + ":budget",
+			Value:   fmt.Sprintf("Project '%s' has a budget of %s", snapshot.Name, formatMoney(budget.TotalBudget, budget.Currency)),
+		})
+	}
+	a.recordAudit(ctx, msg, audit.ProjectUpdated, snapshot.ID, payload)
+
+	return a.respond(msg, b.String(), map[string]interface{}{
+		"project_id": snapshot.ID,
+		"action":     "budget_" + action,
+	}), nil
+}
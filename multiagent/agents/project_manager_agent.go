@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
 )
 
 // ProjectManagerAgent specializes in project planning, tracking, and management
@@ -17,6 +20,7 @@ type ProjectManagerAgent struct {
 	*BaseAgent
 	activeProjects map[string]*Project
 	projectMutex   sync.RWMutex
+	intents        *IntentRouter
 }
 
 // Project represents a managed project with tasks, milestones, and tracking
@@ -36,11 +40,13 @@ type Project struct {
 	Resources      []Resource             `json:"resources"`
 	Dependencies   []string               `json:"dependencies"`
 	Progress       float64                `json:"progress"`
+	ProgressMode   string                 `json:"progress_mode,omitempty"` // ProgressByTasks (default) or ProgressByMilestones
 	EstimatedHours float64                `json:"estimated_hours"`
 	ActualHours    float64                `json:"actual_hours"`
 	Budget         *Budget                `json:"budget,omitempty"`
 	Tags           []string               `json:"tags"`
 	Metadata       map[string]interface{} `json:"metadata"`
+	UserID         string                 `json:"user_id,omitempty"`
 }
 
 // ProjectTask represents a task within a project
@@ -61,6 +67,9 @@ type ProjectTask struct {
 	ActualHours    float64             `json:"actual_hours"`
 	Tags           []string            `json:"tags"`
 	Comments       []TaskComment       `json:"comments"`
+	// PersonalTaskID is the task on the user's personal list this one is
+	// worked on through, if assigned to them
+	PersonalTaskID string `json:"personal_task_id,omitempty"`
 }
 
 // TaskStatus represents the status of a project task
@@ -109,13 +118,16 @@ type Resource struct {
 	Availability string  `json:"availability"`
 }
 
-// Budget represents project budget information
+// Budget represents project budget information. Categories allocates the
+// budget; SpentAmount and RemainingBudget follow from Expenses.
 type Budget struct {
 	TotalBudget     float64            `json:"total_budget"`
 	SpentAmount     float64            `json:"spent_amount"`
 	RemainingBudget float64            `json:"remaining_budget"`
 	Categories      map[string]float64 `json:"categories"`
 	Currency        string             `json:"currency"`
+	Expenses        []Expense          `json:"expenses,omitempty"`
+	StartedAt       time.Time          `json:"started_at"` // When the budget was set, which the burn rate counts from
 }
 
 // TaskComment represents a comment on a task
@@ -148,6 +160,24 @@ func NewProjectManagerAgent(config BaseAgentConfig) *ProjectManagerAgent {
 	return &ProjectManagerAgent{
 		BaseAgent:      NewBaseAgent(config),
 		activeProjects: make(map[string]*Project),
+		intents: NewIntentRouter(IntentRouterConfig{
+			Name:        "ProjectManagerAgent",
+			LLMProvider: routingProvider(config),
+			Prompts:     config.Prompts,
+			Default:     "general",
+			Intents: []Intent{
+				{Label: "template", Description: "create, save, list, show or delete project templates, or start a project from one", Keywords: []string{"template"}},
+				{Label: "create_project", Description: "start a new project", Keywords: []string{"create project", "new project"}},
+				{Label: "list_projects", Description: "show existing projects", Keywords: []string{"list projects", "show projects"}},
+				{Label: "milestone", Description: "create, complete, link tasks to or review project milestones, or track progress by them", Keywords: []string{"milestone", "progress by"}},
+				{Label: "assign_to_me", Description: "assign a project task to the user, adding it to their personal task list", Keywords: []string{"assign&to me", "assigned to me", "delegate&to me", "my task list"}},
+				{Label: "project_status", Description: "status or progress of a project", Keywords: []string{"project status", "project progress"}},
+				{Label: "add_task", Description: "add a task to a project", Keywords: []string{"add task", "create task"}},
+				{Label: "update_task", Description: "update or complete a project task", Keywords: []string{"update task", "complete task"}},
+				{Label: "timeline", Description: "project timeline or schedule", Keywords: []string{"project timeline", "project schedule", "gantt", "mermaid", "export&timeline"}},
+				{Label: "budget", Description: "set a project budget, log expenses, or report spending", Keywords: []string{"budget", "expense", "spent&on", "allocate", "burn rate"}},
+			},
+		}),
 	}
 }
 
@@ -166,32 +196,46 @@ func (a *ProjectManagerAgent) HandleMessage(ctx context.Context, msg *multiagent
 		a.mu.Unlock()
 	}()
 
+	// Replies to our own requests, such as task links, complete their
+	// futures
+	if a.resolveReply(msg) {
+		return nil, nil
+	}
+
 	// Store message in memory
 	if a.memoryStore != nil {
 		msgKey := fmt.Sprintf("project_manager:%s:%s", a.id, msg.ID)
 		a.memoryStore.Store(ctx, msgKey, msg)
 	}
 
-	// Process based on message content
-	content := strings.ToLower(msg.Content)
+	// The task manager reports changes to linked personal tasks
+	if link, ok := decodeTaskLink(msg); ok {
+		return a.handleTaskLinkChange(ctx, msg, link)
+	}
 
-	if strings.Contains(content, "create project") || strings.Contains(content, "new project") {
+	// Process based on message intent
+	switch a.intents.Classify(ctx, msg.Content).Label {
+	case "template":
+		return a.handleProjectTemplate(ctx, msg)
+	case "create_project":
 		return a.handleCreateProject(ctx, msg)
-	} else if strings.Contains(content, "list projects") || strings.Contains(content, "show projects") {
+	case "list_projects":
 		return a.handleListProjects(ctx, msg)
-	} else if strings.Contains(content, "project status") || strings.Contains(content, "project progress") {
+	case "assign_to_me":
+		return a.handleAssignToMe(ctx, msg)
+	case "project_status":
 		return a.handleProjectStatus(ctx, msg)
-	} else if strings.Contains(content, "add task") || strings.Contains(content, "create task") {
+	case "add_task":
 		return a.handleAddTask(ctx, msg)
-	} else if strings.Contains(content, "update task") || strings.Contains(content, "complete task") {
+	case "update_task":
 		return a.handleUpdateTask(ctx, msg)
-	} else if strings.Contains(content, "project timeline") || strings.Contains(content, "project schedule") {
+	case "timeline":
 		return a.handleProjectTimeline(ctx, msg)
-	} else if strings.Contains(content, "project budget") || strings.Contains(content, "budget") {
+	case "budget":
 		return a.handleProjectBudget(ctx, msg)
-	} else if strings.Contains(content, "milestone") {
+	case "milestone":
 		return a.handleMilestone(ctx, msg)
-	} else {
+	default:
 		// Use LLM for general project management queries
 		return a.handleGeneralQuery(ctx, msg)
 	}
@@ -200,25 +244,9 @@ func (a *ProjectManagerAgent) HandleMessage(ctx context.Context, msg *multiagent
 // handleCreateProject creates a new project
 func (a *ProjectManagerAgent) handleCreateProject(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Build context for LLM to extract project details
-	contextPrompt := fmt.Sprintf(`
-You are a project manager extracting project details from user input.
-Extract the following information from this request: "%s"
-
-Please provide the response in JSON format with these fields:
-{
-  "name": "project name",
-  "description": "project description", 
-  "priority": "low|medium|high|critical",
-  "due_date": "YYYY-MM-DD format if mentioned, otherwise null",
-  "estimated_hours": number if mentioned, otherwise 0,
-  "tags": ["tag1", "tag2"] if any categories mentioned
-}
-
-If information is missing, make reasonable assumptions based on context.`, msg.Content)
-
-	response, err := a.llmProvider.Query(ctx, contextPrompt)
+	contextPrompt, err := a.renderPrompt(ctx, "project.create", prompts.Vars{"Request": msg.Content})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse project details: %w", err)
+		return nil, err
 	}
 
 	// Parse the JSON response
@@ -231,7 +259,14 @@ If information is missing, make reasonable assumptions based on context.`, msg.C
 		Tags           []string `json:"tags"`
 	}
 
-	if err := json.Unmarshal([]byte(response), &projectData); err != nil {
+	projectSchema := objectSchema(map[string]string{
+		"name":            "string",
+		"due_date":        "string",
+		"estimated_hours": "number",
+		"tags":            "array",
+	}, "name")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+contextPrompt, projectSchema, &projectData); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse project details", "error", err)
 		// If JSON parsing fails, create project with basic info
 		projectData.Name = "New Project"
 		projectData.Description = msg.Content
@@ -240,13 +275,13 @@ If information is missing, make reasonable assumptions based on context.`, msg.C
 
 	// Create project
 	project := &Project{
-		ID:             fmt.Sprintf("proj_%d", time.Now().UnixNano()),
+		ID:             a.newID("proj"),
 		Name:           projectData.Name,
 		Description:    projectData.Description,
 		Status:         ProjectStatusPlanning,
 		Priority:       a.parsePriority(projectData.Priority),
 		Owner:          string(msg.From),
-		CreatedAt:      time.Now(),
+		CreatedAt:      a.now(),
 		Tasks:          []ProjectTask{},
 		Milestones:     []Milestone{},
 		Resources:      []Resource{},
@@ -256,6 +291,7 @@ If information is missing, make reasonable assumptions based on context.`, msg.C
 		ActualHours:    0.0,
 		Tags:           projectData.Tags,
 		Metadata:       make(map[string]interface{}),
+		UserID:         multiagent.UserIDFromContext(ctx),
 	}
 
 	// Set due date if provided
@@ -276,14 +312,32 @@ If information is missing, make reasonable assumptions based on context.`, msg.C
 		a.memoryStore.Store(ctx, projectKey, project)
 	}
 
+	// Share the project so other specialists can plan around it
+	shared := []memory.BlackboardEntry{
+		{Section: memory.BlackboardEntities, Key: "project:" + project.ID, Value: "Project: " + project.Name},
+		{Section: memory.BlackboardGoals, Key: "project:" + project.ID, Value: fmt.Sprintf("Deliver project '%s'", project.Name)},
+	}
+	if project.DueDate != nil {
+		shared = append(shared, memory.BlackboardEntry{
+			Section: memory.BlackboardFacts,
+			Key:     "project:" + project.ID + ":deadline",
+			Value:   fmt.Sprintf("Project '%s' is due %s", project.Name, project.DueDate.Format("2006-01-02")),
+		})
+	}
+	a.recordShared(ctx, msg, shared...)
+	a.recordAudit(ctx, msg, audit.ProjectCreated, project.ID, map[string]interface{}{
+		"name":     project.Name,
+		"due_date": project.DueDate,
+	})
+
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   fmt.Sprintf("✅ Project '%s' created successfully!\n\nProject ID: %s\nStatus: %s\nPriority: %s\n\nYou can now add tasks, set milestones, and track progress.", project.Name, project.ID, project.Status, project.Priority),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"project_id": project.ID,
 			"action":     "project_created",
@@ -299,15 +353,22 @@ func (a *ProjectManagerAgent) handleListProjects(ctx context.Context, msg *multi
 	a.projectMutex.RLock()
 	defer a.projectMutex.RUnlock()
 
-	if len(a.activeProjects) == 0 {
+	projects := make([]*Project, 0, len(a.activeProjects))
+	for _, project := range a.activeProjects {
+		if ownedBy(ctx, project.UserID) {
+			projects = append(projects, project)
+		}
+	}
+
+	if len(projects) == 0 {
 		return &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+			ID:        a.messageID(),
 			From:      a.id,
 			To:        []multiagent.AgentID{msg.From},
 			Type:      multiagent.MessageTypeResponse,
 			Content:   "📋 No projects found. Use 'create project' to start your first project!",
 			ReplyTo:   msg.ID,
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 		}, nil
 	}
 
@@ -315,11 +376,6 @@ func (a *ProjectManagerAgent) handleListProjects(ctx context.Context, msg *multi
 	responseBuilder.WriteString("📋 **Active Projects**\n\n")
 
 	// Sort projects by priority and due date
-	projects := make([]*Project, 0, len(a.activeProjects))
-	for _, project := range a.activeProjects {
-		projects = append(projects, project)
-	}
-
 	sort.Slice(projects, func(i, j int) bool {
 		if projects[i].Priority != projects[j].Priority {
 			return projects[i].Priority > projects[j].Priority
@@ -350,13 +406,13 @@ func (a *ProjectManagerAgent) handleListProjects(ctx context.Context, msg *multi
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   responseBuilder.String(),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
@@ -369,18 +425,18 @@ func (a *ProjectManagerAgent) handleProjectStatus(ctx context.Context, msg *mult
 	project, exists := a.activeProjects[projectID]
 	a.projectMutex.RUnlock()
 
-	if !exists {
+	if !exists || !ownedBy(ctx, project.UserID) {
 		// Try to find project by name
-		project = a.findProjectByName(msg.Content)
+		project = a.findProjectByName(ctx, msg.Content)
 		if project == nil {
 			return &multiagent.Message{
-				ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+				ID:        a.messageID(),
 				From:      a.id,
 				To:        []multiagent.AgentID{msg.From},
 				Type:      multiagent.MessageTypeResponse,
 				Content:   "❌ Project not found. Use 'list projects' to see available projects.",
 				ReplyTo:   msg.ID,
-				Timestamp: time.Now(),
+				Timestamp: a.now(),
 			}, nil
 		}
 	}
@@ -389,7 +445,7 @@ func (a *ProjectManagerAgent) handleProjectStatus(ctx context.Context, msg *mult
 	totalTasks := len(project.Tasks)
 	completedTasks := 0
 	overdueTasks := 0
-	now := time.Now()
+	now := a.now()
 
 	for _, task := range project.Tasks {
 		if task.Status == TaskStatusCompleted {
@@ -406,11 +462,15 @@ func (a *ProjectManagerAgent) handleProjectStatus(ctx context.Context, msg *mult
 	statusBuilder.WriteString(fmt.Sprintf("🔍 **Overview**\n"))
 	statusBuilder.WriteString(fmt.Sprintf("• Status: %s\n", project.Status))
 	statusBuilder.WriteString(fmt.Sprintf("• Priority: %s\n", project.Priority))
-	statusBuilder.WriteString(fmt.Sprintf("• Progress: %.1f%%\n", project.Progress))
+	if project.ProgressMode == ProgressByMilestones {
+		statusBuilder.WriteString(fmt.Sprintf("• Progress: %.1f%% (by milestones)\n", project.Progress))
+	} else {
+		statusBuilder.WriteString(fmt.Sprintf("• Progress: %.1f%%\n", project.Progress))
+	}
 	statusBuilder.WriteString(fmt.Sprintf("• Owner: %s\n", project.Owner))
 
 	if project.DueDate != nil {
-		daysUntilDue := int(time.Until(*project.DueDate).Hours() / 24)
+		daysUntilDue := int(project.DueDate.Sub(a.now()).Hours() / 24)
 		statusBuilder.WriteString(fmt.Sprintf("• Due Date: %s (%d days)\n", project.DueDate.Format("2006-01-02"), daysUntilDue))
 	}
 
@@ -433,25 +493,33 @@ func (a *ProjectManagerAgent) handleProjectStatus(ctx context.Context, msg *mult
 		}
 	}
 
+	if project.Budget != nil {
+		statusBuilder.WriteString("\n💰 **Budget**\n")
+		writeBudget(&statusBuilder, project, now)
+	}
+
 	if len(project.Milestones) > 0 {
-		statusBuilder.WriteString(fmt.Sprintf("\n🎯 **Milestones**\n"))
+		var overdue []string
 		for _, milestone := range project.Milestones {
-			status := "Pending"
-			if milestone.CompletedAt != nil {
-				status = "✅ Completed"
+			if milestone.daysOverdue(now) > 0 {
+				overdue = append(overdue, milestone.Title)
 			}
-			statusBuilder.WriteString(fmt.Sprintf("• %s - %s (%s)\n", milestone.Title, milestone.DueDate.Format("2006-01-02"), status))
 		}
+		if len(overdue) > 0 {
+			statusBuilder.WriteString(fmt.Sprintf("\n⚠️ **Overdue milestones**: %s\n", strings.Join(overdue, ", ")))
+		}
+		statusBuilder.WriteString(fmt.Sprintf("\n🎯 **Milestones**\n"))
+		writeMilestones(&statusBuilder, project, now)
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   statusBuilder.String(),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"project_id": project.ID,
 			"action":     "status_report",
@@ -462,23 +530,9 @@ func (a *ProjectManagerAgent) handleProjectStatus(ctx context.Context, msg *mult
 // handleAddTask adds a new task to a project
 func (a *ProjectManagerAgent) handleAddTask(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Extract project and task information using LLM
-	contextPrompt := fmt.Sprintf(`
-Extract task information from this request: "%s"
-
-Provide response in JSON format:
-{
-  "project_name": "name of project if mentioned",
-  "task_title": "task title",
-  "task_description": "detailed description",
-  "priority": "low|medium|high|critical",
-  "due_date": "YYYY-MM-DD if mentioned, otherwise null",
-  "estimated_hours": number if mentioned, otherwise 0,
-  "assignee": "person if mentioned, otherwise null"
-}`, msg.Content)
-
-	response, err := a.llmProvider.Query(ctx, contextPrompt)
+	contextPrompt, err := a.renderPrompt(ctx, "project.add_task", prompts.Vars{"Request": msg.Content})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse task details: %w", err)
+		return nil, err
 	}
 
 	var taskData struct {
@@ -491,20 +545,28 @@ Provide response in JSON format:
 		Assignee        string  `json:"assignee"`
 	}
 
-	if err := json.Unmarshal([]byte(response), &taskData); err != nil {
-		return nil, fmt.Errorf("failed to parse task JSON: %w", err)
+	taskSchema := objectSchema(map[string]string{
+		"task_title":      "string",
+		"due_date":        "string",
+		"estimated_hours": "number",
+	}, "task_title")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+contextPrompt, taskSchema, &taskData); err != nil {
+		return nil, fmt.Errorf("failed to parse task details: %w", err)
 	}
 
 	// Find the project
 	var project *Project
 	if taskData.ProjectName != "" {
-		project = a.findProjectByName(taskData.ProjectName)
+		project = a.findProjectByName(ctx, taskData.ProjectName)
 	}
 
 	if project == nil {
 		// Use the most recent project or create a default one
 		a.projectMutex.RLock()
 		for _, p := range a.activeProjects {
+			if !ownedBy(ctx, p.UserID) {
+				continue
+			}
 			if project == nil || p.CreatedAt.After(project.CreatedAt) {
 				project = p
 			}
@@ -514,25 +576,25 @@ Provide response in JSON format:
 
 	if project == nil {
 		return &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+			ID:        a.messageID(),
 			From:      a.id,
 			To:        []multiagent.AgentID{msg.From},
 			Type:      multiagent.MessageTypeResponse,
 			Content:   "❌ No project found. Please create a project first or specify which project to add the task to.",
 			ReplyTo:   msg.ID,
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 		}, nil
 	}
 
 	// Create new task
 	task := ProjectTask{
-		ID:             fmt.Sprintf("task_%d", time.Now().UnixNano()),
+		ID:             a.newID("task"),
 		Title:          taskData.TaskTitle,
 		Description:    taskData.TaskDescription,
 		Status:         TaskStatusNotStarted,
 		Priority:       a.parsePriority(taskData.Priority),
 		Assignee:       taskData.Assignee,
-		CreatedAt:      time.Now(),
+		CreatedAt:      a.now(),
 		Dependencies:   []string{},
 		Progress:       0.0,
 		EstimatedHours: taskData.EstimatedHours,
@@ -559,14 +621,22 @@ Provide response in JSON format:
 		a.memoryStore.Store(ctx, projectKey, project)
 	}
 
+	if task.DueDate != nil {
+		a.recordShared(ctx, msg, memory.BlackboardEntry{
+			Section: memory.BlackboardFacts,
+			Key:     "project_task:" + task.ID + ":deadline",
+			Value:   fmt.Sprintf("Task '%s' in project '%s' is due %s", task.Title, project.Name, task.DueDate.Format("2006-01-02")),
+		})
+	}
+
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   fmt.Sprintf("✅ Task '%s' added to project '%s'!\n\nTask ID: %s\nPriority: %s\nStatus: %s", task.Title, project.Name, task.ID, task.Priority, task.Status),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"project_id": project.ID,
 			"task_id":    task.ID,
@@ -578,21 +648,9 @@ Provide response in JSON format:
 // handleUpdateTask updates an existing task
 func (a *ProjectManagerAgent) handleUpdateTask(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Use LLM to extract update information
-	contextPrompt := fmt.Sprintf(`
-Extract task update information from: "%s"
-
-Provide response in JSON format:
-{
-  "task_identifier": "task name or ID mentioned",
-  "status": "not_started|in_progress|on_hold|completed|cancelled if mentioned",
-  "progress": number between 0-100 if mentioned, otherwise null,
-  "actual_hours": number if mentioned, otherwise null,
-  "comment": "any comment or note to add"
-}`, msg.Content)
-
-	response, err := a.llmProvider.Query(ctx, contextPrompt)
+	contextPrompt, err := a.renderPrompt(ctx, "project.update_task", prompts.Vars{"Request": msg.Content})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse update details: %w", err)
+		return nil, err
 	}
 
 	var updateData struct {
@@ -603,8 +661,14 @@ Provide response in JSON format:
 		Comment        string   `json:"comment"`
 	}
 
-	if err := json.Unmarshal([]byte(response), &updateData); err != nil {
-		return nil, fmt.Errorf("failed to parse update JSON: %w", err)
+	updateSchema := objectSchema(map[string]string{
+		"task_identifier": "string",
+		"status":          "string",
+		"progress":        "number",
+		"actual_hours":    "number",
+	}, "task_identifier")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+contextPrompt, updateSchema, &updateData); err != nil {
+		return nil, fmt.Errorf("failed to parse update details: %w", err)
 	}
 
 	// Find the task
@@ -614,6 +678,9 @@ Provide response in JSON format:
 	defer a.projectMutex.Unlock()
 
 	for _, p := range a.activeProjects {
+		if !ownedBy(ctx, p.UserID) {
+			continue
+		}
 		for i := range p.Tasks {
 			if strings.Contains(strings.ToLower(p.Tasks[i].Title), strings.ToLower(updateData.TaskIdentifier)) ||
 				p.Tasks[i].ID == updateData.TaskIdentifier {
@@ -629,18 +696,19 @@ Provide response in JSON format:
 
 	if task == nil {
 		return &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+			ID:        a.messageID(),
 			From:      a.id,
 			To:        []multiagent.AgentID{msg.From},
 			Type:      multiagent.MessageTypeResponse,
 			Content:   fmt.Sprintf("❌ Task '%s' not found.", updateData.TaskIdentifier),
 			ReplyTo:   msg.ID,
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 		}, nil
 	}
 
 	// Apply updates
 	var changes []string
+	wasCompleted := task.Status == TaskStatusCompleted
 
 	if updateData.Status != "" {
 		oldStatus := task.Status
@@ -648,7 +716,7 @@ Provide response in JSON format:
 		changes = append(changes, fmt.Sprintf("Status: %s → %s", oldStatus, task.Status))
 
 		if task.Status == TaskStatusCompleted {
-			now := time.Now()
+			now := a.now()
 			task.CompletedAt = &now
 			task.Progress = 100.0
 		}
@@ -671,10 +739,10 @@ Provide response in JSON format:
 
 	if updateData.Comment != "" {
 		comment := TaskComment{
-			ID:        fmt.Sprintf("comment_%d", time.Now().UnixNano()),
+			ID:        a.newID("comment"),
 			Author:    string(msg.From),
 			Content:   updateData.Comment,
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 		}
 		task.Comments = append(task.Comments, comment)
 		changes = append(changes, "Added comment")
@@ -688,6 +756,7 @@ Provide response in JSON format:
 		projectKey := fmt.Sprintf("project:%s", project.ID)
 		a.memoryStore.Store(ctx, projectKey, project)
 	}
+	a.publishLinkedStatus(ctx, wasCompleted, project, task)
 
 	changesText := "No changes made"
 	if len(changes) > 0 {
@@ -695,13 +764,13 @@ Provide response in JSON format:
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   fmt.Sprintf("✅ Task '%s' updated successfully!\n\n**Changes:**\n• %s\n\n**Current Status:** %s (%.1f%%)", task.Title, changesText, task.Status, task.Progress),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"project_id": project.ID,
 			"task_id":    task.ID,
@@ -717,21 +786,25 @@ func (a *ProjectManagerAgent) handleProjectTimeline(ctx context.Context, msg *mu
 	project := a.getProject(ctx, projectID)
 
 	if project == nil {
-		project = a.findProjectByName(msg.Content)
+		project = a.findProjectByName(ctx, msg.Content)
 	}
 
 	if project == nil {
 		return &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+			ID:        a.messageID(),
 			From:      a.id,
 			To:        []multiagent.AgentID{msg.From},
 			Type:      multiagent.MessageTypeResponse,
 			Content:   "❌ Project not found. Please specify a valid project name or ID.",
 			ReplyTo:   msg.ID,
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 		}, nil
 	}
 
+	if content := strings.ToLower(msg.Content); strings.Contains(content, "gantt") || strings.Contains(content, "mermaid") || strings.Contains(content, "export") {
+		return a.handleExportTimeline(ctx, msg, project)
+	}
+
 	// Build timeline
 	var timelineBuilder strings.Builder
 	timelineBuilder.WriteString(fmt.Sprintf("📅 **Project Timeline: %s**\n\n", project.Name))
@@ -775,13 +848,13 @@ func (a *ProjectManagerAgent) handleProjectTimeline(ctx context.Context, msg *mu
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   timelineBuilder.String(),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"project_id": project.ID,
 			"action":     "timeline_report",
@@ -789,36 +862,13 @@ func (a *ProjectManagerAgent) handleProjectTimeline(ctx context.Context, msg *mu
 	}, nil
 }
 
-// handleProjectBudget manages project budget information
-func (a *ProjectManagerAgent) handleProjectBudget(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "💰 Budget tracking is available but requires additional configuration. Would you like help setting up budget tracking for your project?",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
-// handleMilestone manages project milestones
-func (a *ProjectManagerAgent) handleMilestone(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "🎯 Milestone management is available. You can create milestones, track progress, and set deadlines. What would you like to do with milestones?",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
 // handleGeneralQuery handles general project management questions
 func (a *ProjectManagerAgent) handleGeneralQuery(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Build context with project information
-	contextPrompt := a.buildProjectContext(ctx, msg)
+	contextPrompt, err := a.buildProjectContext(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
 
 	response, err := a.llmProvider.Query(ctx, contextPrompt)
 	if err != nil {
@@ -826,13 +876,13 @@ func (a *ProjectManagerAgent) handleGeneralQuery(ctx context.Context, msg *multi
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   response,
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
@@ -862,14 +912,14 @@ func (a *ProjectManagerAgent) extractProjectID(content string) string {
 	return ""
 }
 
-func (a *ProjectManagerAgent) findProjectByName(content string) *Project {
+func (a *ProjectManagerAgent) findProjectByName(ctx context.Context, content string) *Project {
 	contentLower := strings.ToLower(content)
 
 	a.projectMutex.RLock()
 	defer a.projectMutex.RUnlock()
 
 	for _, project := range a.activeProjects {
-		if strings.Contains(contentLower, strings.ToLower(project.Name)) {
+		if ownedBy(ctx, project.UserID) && strings.Contains(contentLower, strings.ToLower(project.Name)) {
 			return project
 		}
 	}
@@ -885,7 +935,7 @@ func (a *ProjectManagerAgent) getProject(ctx context.Context, projectID string)
 	project, exists := a.activeProjects[projectID]
 	a.projectMutex.RUnlock()
 
-	if exists {
+	if exists && ownedBy(ctx, project.UserID) {
 		return project
 	}
 
@@ -896,6 +946,7 @@ func (a *ProjectManagerAgent) getProject(ctx context.Context, projectID string)
 			var project Project
 			if projectData, err := json.Marshal(projectInterface); err == nil {
 				if err := json.Unmarshal(projectData, &project); err == nil {
+					project.UserID = multiagent.UserIDFromContext(ctx)
 					a.projectMutex.Lock()
 					a.activeProjects[projectID] = &project
 					a.projectMutex.Unlock()
@@ -932,13 +983,39 @@ func (a *ProjectManagerAgent) loadProjectsFromMemory(ctx context.Context) {
 		var project Project
 		if projectData, err := json.Marshal(projectInterface); err == nil {
 			if err := json.Unmarshal(projectData, &project); err == nil {
+				project.UserID = multiagent.UserIDFromContext(ctx)
 				a.activeProjects[project.ID] = &project
 			}
 		}
 	}
 }
 
+// PurgeUser forgets userID's projects
+func (a *ProjectManagerAgent) PurgeUser(userID string) int {
+	a.projectMutex.Lock()
+	defer a.projectMutex.Unlock()
+
+	purged := 0
+	for id, project := range a.activeProjects {
+		if project.UserID == userID {
+			delete(a.activeProjects, id)
+			purged++
+		}
+	}
+	return purged
+}
+
+// recalculateProjectProgress averages the progress of the project's
+// milestones when it is measured by them, and of its tasks otherwise
 func (a *ProjectManagerAgent) recalculateProjectProgress(project *Project) {
+	if project.ProgressMode == ProgressByMilestones && len(project.Milestones) > 0 {
+		total := 0.0
+		for i := range project.Milestones {
+			total += milestoneProgress(project, &project.Milestones[i])
+		}
+		project.Progress = total / float64(len(project.Milestones))
+		return
+	}
 	if len(project.Tasks) == 0 {
 		project.Progress = 0.0
 		return
@@ -954,7 +1031,7 @@ func (a *ProjectManagerAgent) recalculateProjectProgress(project *Project) {
 
 func (a *ProjectManagerAgent) getUpcomingTasks(project *Project, days int) []ProjectTask {
 	var upcoming []ProjectTask
-	cutoff := time.Now().AddDate(0, 0, days)
+	cutoff := a.now().AddDate(0, 0, days)
 
 	for _, task := range project.Tasks {
 		if task.DueDate != nil && task.DueDate.Before(cutoff) && task.Status != TaskStatusCompleted {
@@ -970,26 +1047,21 @@ func (a *ProjectManagerAgent) getUpcomingTasks(project *Project, days int) []Pro
 	return upcoming
 }
 
-func (a *ProjectManagerAgent) buildProjectContext(ctx context.Context, msg *multiagent.Message) string {
-	var contextBuilder strings.Builder
-
-	contextBuilder.WriteString(fmt.Sprintf("You are %s, a project management specialist.\n\n", a.name))
-	contextBuilder.WriteString("You help users manage projects, tasks, timelines, and resources effectively.\n\n")
-
-	// Add current projects summary
+func (a *ProjectManagerAgent) buildProjectContext(ctx context.Context, msg *multiagent.Message) (string, error) {
+	// Add current projects summary; rendered under the lock, since the
+	// template reads the projects
 	a.projectMutex.RLock()
-	if len(a.activeProjects) > 0 {
-		contextBuilder.WriteString("Current Projects:\n")
-		for _, project := range a.activeProjects {
-			contextBuilder.WriteString(fmt.Sprintf("- %s (%s) - %.1f%% complete, %d tasks\n",
-				project.Name, project.Status, project.Progress, len(project.Tasks)))
+	defer a.projectMutex.RUnlock()
+	var projects []*Project
+	for _, project := range a.activeProjects {
+		if ownedBy(ctx, project.UserID) {
+			projects = append(projects, project)
 		}
-		contextBuilder.WriteString("\n")
 	}
-	a.projectMutex.RUnlock()
 
-	contextBuilder.WriteString(fmt.Sprintf("User request: %s\n\n", msg.Content))
-	contextBuilder.WriteString("Please provide helpful project management advice, suggestions, or execute the requested action.")
-
-	return contextBuilder.String()
+	return a.renderPrompt(ctx, "project.general", prompts.Vars{
+		"Name":     a.name,
+		"Projects": projects,
+		"Request":  msg.Content,
+	})
 }
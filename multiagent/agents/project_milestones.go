@@ -0,0 +1,463 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// Milestone statuses; a pending milestone past its due date is overdue
+const (
+	MilestoneStatusPending   = "pending"
+	MilestoneStatusCompleted = "completed"
+)
+
+// Ways a project's progress is measured
+const (
+	ProgressByTasks      = "tasks"      // Average progress of its tasks
+	ProgressByMilestones = "milestones" // Average progress of its milestones
+)
+
+// Milestone commands a user can give
+const (
+	milestoneAdd      = "add"
+	milestoneComplete = "complete"
+	milestoneReopen   = "reopen"
+	milestoneRemove   = "remove"
+	milestoneLink     = "link"
+	milestoneList     = "list"
+	milestoneTrack    = "track"
+)
+
+var (
+	// milestonePhrase matches "add milestone beta to website due friday",
+	// "complete milestone beta of website" and the like when the LLM can't
+	// read the request
+	milestonePhrase = regexp.MustCompile(`(?i)^(add|create|complete|finish|reopen|remove|delete|list|show)\s+(?:a\s+|the\s+|new\s+)?milestones?\s*(?:(.+?)\s+)?(?:to|for|of|in|on)\s+(?:the\s+)?(?:project\s+)?(.+?)(?:\s+(?:due|by)\s+(.+?))?[.!?]*$`)
+	// milestoneLinkPhrase matches "link task design to milestone beta"
+	milestoneLinkPhrase = regexp.MustCompile(`(?i)^link\s+(?:the\s+)?(?:tasks?\s+)?(.+?)\s+to\s+(?:the\s+)?milestone\s+(.+?)[.!?]*$`)
+	// progressModePhrase matches "track progress by milestones"
+	progressModePhrase = regexp.MustCompile(`(?i)\bprogress\s+by\s+(milestones?|tasks?)\b`)
+)
+
+// daysOverdue is how many days past its due date the milestone is still
+// pending at now, or 0
+func (m *Milestone) daysOverdue(now time.Time) int {
+	if m.CompletedAt != nil || m.DueDate.IsZero() {
+		return 0
+	}
+	return max(int(math.Round(startOfDay(now).Sub(startOfDay(m.DueDate.In(now.Location()))).Hours()/24)), 0)
+}
+
+// milestoneProgress is how far along a milestone is: done when completed,
+// otherwise the average progress of its linked tasks
+func milestoneProgress(project *Project, milestone *Milestone) float64 {
+	if milestone.CompletedAt != nil {
+		return 100
+	}
+	total, linked := 0.0, 0
+	for _, taskID := range milestone.Tasks {
+		for _, task := range project.Tasks {
+			if task.ID == taskID {
+				total += task.Progress
+				linked++
+				break
+			}
+		}
+	}
+	if linked == 0 {
+		return 0
+	}
+	return total / float64(linked)
+}
+
+// findMilestone returns the index of the project's milestone named by ref,
+// its ID, 1-based number or part of its title, or -1
+func findMilestone(project *Project, ref string) int {
+	ref = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(ref)), "#")
+	if ref == "" {
+		return -1
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n >= 1 && n <= len(project.Milestones) {
+			return n - 1
+		}
+		return -1
+	}
+	for i, milestone := range project.Milestones {
+		if milestone.ID == ref || strings.EqualFold(milestone.Title, ref) {
+			return i
+		}
+	}
+	for i, milestone := range project.Milestones {
+		if strings.Contains(strings.ToLower(milestone.Title), ref) {
+			return i
+		}
+	}
+	return -1
+}
+
+// findProjectTask returns the ID of the project's task named by ref, its ID
+// or part of its title, or ""
+func findProjectTask(project *Project, ref string) string {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if ref == "" {
+		return ""
+	}
+	for _, task := range project.Tasks {
+		if task.ID == ref || strings.EqualFold(task.Title, ref) {
+			return task.ID
+		}
+	}
+	for _, task := range project.Tasks {
+		if strings.Contains(strings.ToLower(task.Title), ref) {
+			return task.ID
+		}
+	}
+	return ""
+}
+
+// writeMilestones lists the project's milestones in due order with their
+// progress, flagging overdue ones
+func writeMilestones(b *strings.Builder, project *Project, now time.Time) {
+	milestones := append([]Milestone(nil), project.Milestones...)
+	sort.SliceStable(milestones, func(i, j int) bool {
+		return milestones[i].DueDate.Before(milestones[j].DueDate)
+	})
+	for _, milestone := range milestones {
+		icon := "📅"
+		switch {
+		case milestone.CompletedAt != nil:
+			icon = "✅"
+		case milestone.daysOverdue(now) > 0:
+			icon = "⚠️"
+		}
+		b.WriteString(fmt.Sprintf("• %s %s", icon, milestone.Title))
+		if !milestone.DueDate.IsZero() {
+			b.WriteString(" - " + milestone.DueDate.Format("2006-01-02"))
+		}
+		switch {
+		case milestone.CompletedAt != nil:
+			b.WriteString(fmt.Sprintf(" (completed %s)", milestone.CompletedAt.Format("2006-01-02")))
+		case milestone.daysOverdue(now) > 0:
+			b.WriteString(fmt.Sprintf(" (overdue by %d days, %.0f%%)", milestone.daysOverdue(now), milestoneProgress(project, &milestone)))
+		default:
+			b.WriteString(fmt.Sprintf(" (%.0f%%)", milestoneProgress(project, &milestone)))
+		}
+		if len(milestone.Tasks) > 0 {
+			done := 0
+			for _, task := range project.Tasks {
+				for _, id := range milestone.Tasks {
+					if task.ID == id && task.Status == TaskStatusCompleted {
+						done++
+					}
+				}
+			}
+			b.WriteString(fmt.Sprintf(" — %d/%d tasks done", done, len(milestone.Tasks)))
+		}
+		b.WriteString("\n")
+	}
+}
+
+// handleMilestone creates, completes, reopens, removes, links tasks to or
+// lists a project's milestones, or switches the project to measuring its
+// progress by them
+func (a *ProjectManagerAgent) handleMilestone(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadProjectsFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now().In(loc)
+
+	prompt, err := a.renderPrompt(ctx, "project.milestone", prompts.Vars{
+		"Request": msg.Content,
+		"Now":     now.Format("2006-01-02 (Monday)"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Project      string   `json:"project"`
+		Action       string   `json:"action"`
+		Milestone    string   `json:"milestone"`
+		Description  string   `json:"description"`
+		DueDate      string   `json:"due_date"`
+		Tasks        []string `json:"tasks"`
+		ProgressMode string   `json:"progress_mode"`
+	}
+	milestoneSchema := objectSchema(map[string]string{
+		"project":       "string",
+		"action":        "string",
+		"milestone":     "string",
+		"description":   "string",
+		"due_date":      "string",
+		"tasks":         "array",
+		"progress_mode": "string",
+	}, "action")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, milestoneSchema, &data); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse milestone request", "error", err)
+		content := strings.TrimSpace(msg.Content)
+		if match := progressModePhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.ProgressMode = milestoneTrack, match[1]
+		} else if match := milestoneLinkPhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.Tasks, data.Milestone = milestoneLink, strings.Split(match[1], ","), match[2]
+		} else if match := milestonePhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.Milestone, data.Project, data.DueDate = strings.ToLower(match[1]), match[2], match[3], match[4]
+		} else {
+			return a.respond(msg, "🎯 Say something like \"add milestone beta to website due Friday\", \"complete milestone beta of website\" or \"list milestones for website\".", nil), nil
+		}
+	}
+
+	action := strings.ToLower(strings.TrimSpace(data.Action))
+	switch action {
+	case "create":
+		action = milestoneAdd
+	case "finish", "done":
+		action = milestoneComplete
+	case "delete":
+		action = milestoneRemove
+	case "show":
+		action = milestoneList
+	}
+
+	project := a.resolveProject(ctx, data.Project, msg.Content)
+	if project == nil {
+		return a.respond(msg, "❌ Project not found. Use 'list projects' to see available projects.", nil), nil
+	}
+	if action == milestoneRemove {
+		summary := fmt.Sprintf("remove the milestone '%s'", strings.TrimSpace(data.Milestone))
+		if allowed, reply := a.authorize(ctx, msg, ActionDeleteMilestone, project.ID, summary); !allowed {
+			return reply, nil
+		}
+	}
+
+	a.projectMutex.Lock()
+	if action == milestoneList {
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("🎯 **Milestones: %s**\n\n", project.Name))
+		if len(project.Milestones) == 0 {
+			b.WriteString("No milestones yet. Say \"add milestone <title> to " + project.Name + " due <date>\" to set one.\n")
+		}
+		writeMilestones(&b, project, now)
+		if project.ProgressMode == ProgressByMilestones {
+			b.WriteString(fmt.Sprintf("\n📊 Project progress (by milestones): %.1f%%\n", project.Progress))
+		}
+		projectID := project.ID
+		a.projectMutex.Unlock()
+		return a.respond(msg, b.String(), map[string]interface{}{
+			"project_id": projectID,
+			"action":     "milestones_listed",
+		}), nil
+	}
+
+	var content string
+	var shared []memory.BlackboardEntry
+	payload := map[string]interface{}{"name": project.Name}
+	switch action {
+	case milestoneAdd:
+		title := strings.TrimSpace(data.Milestone)
+		if title == "" {
+			a.projectMutex.Unlock()
+			return a.respond(msg, fmt.Sprintf("🎯 What milestone should I add to '%s'?", project.Name), nil), nil
+		}
+		due, err := resolveDate(data.DueDate, now)
+		if err != nil {
+			a.projectMutex.Unlock()
+			return a.respond(msg, fmt.Sprintf("📅 When is '%s' due? Please give a date.", title), nil), nil
+		}
+		milestone := Milestone{
+			ID:          a.newID("milestone"),
+			Title:       title,
+			Description: data.Description,
+			DueDate:     due,
+			Status:      MilestoneStatusPending,
+			Tasks:       []string{},
+		}
+		linked, missing := linkMilestoneTasks(project, &milestone, data.Tasks)
+		project.Milestones = append(project.Milestones, milestone)
+		content = fmt.Sprintf("🎯 Added milestone '%s' to '%s', due %s.", title, project.Name, due.Format("Mon 2006-01-02"))
+		content += linkedNote(linked, missing)
+		payload["milestone"], payload["due_date"] = title, due
+		shared = append(shared, memory.BlackboardEntry{
+			Section: memory.BlackboardFacts,
+			Key:     "project:" + project.ID + ":milestone:" + milestone.ID,
+			Value:   fmt.Sprintf("Milestone '%s' of project '%s' is due %s", title, project.Name, due.Format("2006-01-02")),
+		})
+
+	case milestoneComplete, milestoneReopen, milestoneRemove, milestoneLink:
+		i := findMilestone(project, data.Milestone)
+		if i < 0 {
+			name := project.Name
+			a.projectMutex.Unlock()
+			return a.respond(msg, fmt.Sprintf("❌ '%s' has no milestone '%s'.", name, data.Milestone), nil), nil
+		}
+		milestone := &project.Milestones[i]
+		payload["milestone"] = milestone.Title
+		switch action {
+		case milestoneComplete:
+			if milestone.CompletedAt != nil {
+				a.projectMutex.Unlock()
+				return a.respond(msg, fmt.Sprintf("🎯 '%s' is already complete.", milestone.Title), nil), nil
+			}
+			late := milestone.daysOverdue(now)
+			completed := a.now()
+			milestone.CompletedAt = &completed
+			milestone.Status = MilestoneStatusCompleted
+			content = fmt.Sprintf("✅ Milestone '%s' of '%s' reached! 🎉", milestone.Title, project.Name)
+			if late > 0 {
+				content += fmt.Sprintf("\n\nIt was due %s, %d days ago.", milestone.DueDate.Format("2006-01-02"), late)
+			}
+		case milestoneReopen:
+			milestone.CompletedAt = nil
+			milestone.Status = MilestoneStatusPending
+			content = fmt.Sprintf("↩️ Reopened milestone '%s' of '%s'.", milestone.Title, project.Name)
+		case milestoneRemove:
+			title := milestone.Title
+			project.Milestones = append(project.Milestones[:i:i], project.Milestones[i+1:]...)
+			content = fmt.Sprintf("🗑️ Removed milestone '%s' from '%s'.", title, project.Name)
+		case milestoneLink:
+			linked, missing := linkMilestoneTasks(project, milestone, data.Tasks)
+			if len(linked) == 0 {
+				a.projectMutex.Unlock()
+				return a.respond(msg, fmt.Sprintf("❌ I couldn't find those tasks in '%s'.", project.Name), nil), nil
+			}
+			content = fmt.Sprintf("🔗 Milestone '%s' now tracks %d tasks.", milestone.Title, len(milestone.Tasks))
+			content += linkedNote(linked, missing)
+		}
+
+	case milestoneTrack:
+		mode := ProgressByTasks
+		if strings.HasPrefix(strings.ToLower(data.ProgressMode), "milestone") {
+			mode = ProgressByMilestones
+		}
+		project.ProgressMode = mode
+		content = fmt.Sprintf("📊 '%s' now measures its progress by %s.", project.Name, mode)
+		payload["progress_mode"] = mode
+
+	default:
+		a.projectMutex.Unlock()
+		return a.respond(msg, "🎯 I can add, complete, reopen, remove, link tasks to or list milestones, or track a project's progress by them.", nil), nil
+	}
+
+	a.recalculateProjectProgress(project)
+	content += fmt.Sprintf("\n\n📊 Project progress: %.1f%%", project.Progress)
+	snapshot := *project
+	snapshot.Tasks = append([]ProjectTask(nil), project.Tasks...)
+	snapshot.Milestones = append([]Milestone(nil), project.Milestones...)
+	a.projectMutex.Unlock()
+
+	if err := a.saveProject(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	a.recordShared(ctx, msg, shared...)
+	payload["action"] = "milestone_" + action
+	a.recordAudit(ctx, msg, audit.ProjectUpdated, snapshot.ID, payload)
+
+	return a.respond(msg, content, map[string]interface{}{
+		"project_id": snapshot.ID,
+		"action":     "milestone_" + action,
+	}), nil
+}
+
+// linkMilestoneTasks links the project tasks refs name to milestone,
+// returning the titles linked and the refs not found
+func linkMilestoneTasks(project *Project, milestone *Milestone, refs []string) ([]string, []string) {
+	var linked, missing []string
+	for _, ref := range refs {
+		if strings.TrimSpace(ref) == "" {
+			continue
+		}
+		taskID := findProjectTask(project, ref)
+		if taskID == "" {
+			missing = append(missing, strings.TrimSpace(ref))
+			continue
+		}
+		already := false
+		for _, id := range milestone.Tasks {
+			already = already || id == taskID
+		}
+		if !already {
+			milestone.Tasks = append(milestone.Tasks, taskID)
+		}
+		for _, task := range project.Tasks {
+			if task.ID == taskID {
+				linked = append(linked, task.Title)
+			}
+		}
+	}
+	return linked, missing
+}
+
+// linkedNote tells the user which tasks a milestone was linked to
+func linkedNote(linked, missing []string) string {
+	var note string
+	if len(linked) > 0 {
+		note += fmt.Sprintf("\n🔗 Linked tasks: %s", strings.Join(linked, ", "))
+	}
+	if len(missing) > 0 {
+		note += fmt.Sprintf("\n❓ Not found in the project: %s", strings.Join(missing, ", "))
+	}
+	return note
+}
+
+// resolveProject returns the user's project named by ref or mentioned in
+// content, by ID or name, or their only project when neither names one
+func (a *ProjectManagerAgent) resolveProject(ctx context.Context, ref, content string) *Project {
+	for _, text := range []string{ref, content} {
+		if project := a.getProject(ctx, a.extractProjectID(text)); project != nil {
+			return project
+		}
+	}
+	if ref = strings.TrimSpace(ref); ref != "" {
+		if project := a.findProjectByName(ctx, ref); project != nil {
+			return project
+		}
+		// The request may name only part of the project
+		a.projectMutex.RLock()
+		for _, project := range a.activeProjects {
+			if ownedBy(ctx, project.UserID) && strings.Contains(strings.ToLower(project.Name), strings.ToLower(ref)) {
+				a.projectMutex.RUnlock()
+				return project
+			}
+		}
+		a.projectMutex.RUnlock()
+	}
+	if project := a.findProjectByName(ctx, content); project != nil {
+		return project
+	}
+
+	a.projectMutex.RLock()
+	defer a.projectMutex.RUnlock()
+	var only *Project
+	for _, project := range a.activeProjects {
+		if !ownedBy(ctx, project.UserID) {
+			continue
+		}
+		if only != nil {
+			return nil
+		}
+		only = project
+	}
+	return only
+}
+
+// saveProject persists a project in its owner's memory
+func (a *ProjectManagerAgent) saveProject(ctx context.Context, project *Project) error {
+	if a.memoryStore == nil {
+		return nil
+	}
+	projectKey := fmt.Sprintf("project:%s", project.ID)
+	if err := a.memoryStore.Store(ownerContext(ctx, project.UserID), projectKey, project); err != nil {
+		return fmt.Errorf("failed to save project %s: %w", project.ID, err)
+	}
+	return nil
+}
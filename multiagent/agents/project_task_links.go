@@ -0,0 +1,269 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// taskLinkTimeout bounds how long assigning a task waits for the task
+// manager
+const taskLinkTimeout = 30 * time.Second
+
+// assignPhrase matches "assign the homepage task in website to me" when
+// the LLM can't read the request
+var assignPhrase = regexp.MustCompile(`(?i)\b(?:assign|delegate|give)\s+(?:the\s+)?(?:task\s+)?(.+?)(?:\s+task)?\s+(?:(?:in|of|from|on)\s+(?:the\s+)?(?:project\s+)?(.+?)\s+)?to\s+me\b`)
+
+// Start starts the agent and subscribes it to changes of the personal
+// tasks linked to its project tasks
+func (a *ProjectManagerAgent) Start(ctx context.Context) error {
+	if err := a.BaseAgent.Start(ctx); err != nil {
+		return err
+	}
+	if a.orchestrator == nil {
+		return nil
+	}
+	if err := a.Subscribe(PersonalTaskTopic); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", PersonalTaskTopic, err)
+	}
+	return nil
+}
+
+// publishLinkedStatus tells the task manager the user completed or
+// reopened a project task linked to a personal task
+func (a *ProjectManagerAgent) publishLinkedStatus(ctx context.Context, wasCompleted bool, project *Project, task *ProjectTask) {
+	completed := task.Status == TaskStatusCompleted
+	if task.PersonalTaskID == "" || completed == wasCompleted {
+		return
+	}
+	a.publishTaskLink(ctx, ProjectTaskTopic, TaskLink{
+		Action:         TaskLinkStatus,
+		ProjectID:      project.ID,
+		ProjectTaskID:  task.ID,
+		PersonalTaskID: task.PersonalTaskID,
+		Project:        project.Name,
+		Title:          task.Title,
+		Completed:      completed,
+	})
+}
+
+// handleAssignToMe puts a project task on the user's personal task list,
+// linked so that completing either completes the other
+func (a *ProjectManagerAgent) handleAssignToMe(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadProjectsFromMemory(ctx)
+
+	prompt, err := a.renderPrompt(ctx, "project.assign_to_me", prompts.Vars{"Request": msg.Content})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Project string `json:"project"`
+		Task    string `json:"task"`
+	}
+	assignSchema := objectSchema(map[string]string{
+		"project": "string",
+		"task":    "string",
+	}, "task")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, assignSchema, &data); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse task assignment", "error", err)
+		if match := assignPhrase.FindStringSubmatch(strings.TrimSpace(msg.Content)); match != nil {
+			data.Task, data.Project = match[1], match[2]
+		}
+	}
+	if strings.TrimSpace(data.Task) == "" {
+		return a.respond(msg, "📌 Which task should I put on your list? Say something like \"assign the homepage task in website to me\".", nil), nil
+	}
+
+	project := a.resolveProject(ctx, data.Project, msg.Content)
+	if project == nil {
+		return a.respond(msg, "❌ Project not found. Use 'list projects' to see available projects.", nil), nil
+	}
+
+	a.projectMutex.RLock()
+	taskID := findProjectTask(project, data.Task)
+	var link TaskLink
+	var linkedTo string
+	for _, task := range project.Tasks {
+		if task.ID == taskID {
+			link = TaskLink{
+				Action:         TaskLinkCreate,
+				ProjectID:      project.ID,
+				ProjectTaskID:  task.ID,
+				Project:        project.Name,
+				Title:          task.Title,
+				Description:    task.Description,
+				Priority:       task.Priority,
+				DueDate:        task.DueDate,
+				EstimatedHours: task.EstimatedHours,
+				Completed:      task.Status == TaskStatusCompleted,
+			}
+			linkedTo = task.PersonalTaskID
+		}
+	}
+	a.projectMutex.RUnlock()
+	if taskID == "" {
+		return a.respond(msg, fmt.Sprintf("❌ No task matching '%s' in %s.", data.Task, project.Name), nil), nil
+	}
+	if linkedTo != "" {
+		return a.respond(msg, fmt.Sprintf("📌 '%s' is already on your task list (%s).", link.Title, linkedTo), map[string]interface{}{
+			"project_id":       link.ProjectID,
+			"task_id":          taskID,
+			"personal_task_id": linkedTo,
+		}), nil
+	}
+
+	taskManager, ok := a.agentOfType(multiagent.AgentTypeTask)
+	if !ok {
+		return a.respond(msg, fmt.Sprintf("📌 I can't reach your task list right now, so I couldn't add '%s' to it.", link.Title), nil), nil
+	}
+	request := taskLinkMessage(ctx, link)
+	request.To = []multiagent.AgentID{taskManager}
+	request.Type = multiagent.MessageTypeRequest
+	future, err := a.RequestMessage(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request personal task: %w", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, taskLinkTimeout)
+	defer cancel()
+	reply, err := future.Wait(waitCtx)
+	if err != nil {
+		future.Cancel()
+		a.logger.WarnContext(ctx, "Task manager did not add the task", "task_id", taskID, "error", err)
+		return a.respond(msg, fmt.Sprintf("📌 Your task list didn't answer, so I couldn't add '%s' to it. Please try again.", link.Title), nil), nil
+	}
+	personalTaskID, _ := reply.Context["task_id"].(string)
+	if personalTaskID == "" {
+		return a.respond(msg, reply.Content, map[string]interface{}{
+			"project_id": link.ProjectID,
+			"task_id":    taskID,
+			"action":     "task_assign_refused",
+		}), nil
+	}
+
+	assignee := multiagent.UserIDFromContext(ctx)
+	if assignee == "" {
+		assignee = "me"
+	}
+	a.projectMutex.Lock()
+	task := projectTask(project, taskID)
+	if task == nil {
+		a.projectMutex.Unlock()
+		return a.respond(msg, fmt.Sprintf("📌 '%s' was removed from %s while I added it to your task list as %s.", link.Title, link.Project, personalTaskID), nil), nil
+	}
+	task.PersonalTaskID = personalTaskID
+	task.Assignee = assignee
+	snapshot := *project
+	snapshot.Tasks = append([]ProjectTask(nil), project.Tasks...)
+	snapshot.Milestones = append([]Milestone(nil), project.Milestones...)
+	a.projectMutex.Unlock()
+
+	if err := a.saveProject(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	a.recordShared(ctx, msg, memory.BlackboardEntry{
+		Section: memory.BlackboardFacts,
+		Key:     "project:" + snapshot.ID,
+		Value:   fmt.Sprintf("Project task '%s' of %s is on the user's personal task list as %s", link.Title, snapshot.Name, personalTaskID),
+	})
+	a.recordAudit(ctx, msg, audit.ProjectUpdated, snapshot.ID, map[string]interface{}{
+		"action":           "task_assigned",
+		"task_id":          taskID,
+		"personal_task_id": personalTaskID,
+		"assignee":         assignee,
+	})
+
+	content := fmt.Sprintf("📌 Assigned '%s' (%s) to you — it's on your task list as %s.\nCompleting it there completes it here, and the other way round.", link.Title, snapshot.Name, personalTaskID)
+	return a.respond(msg, content, map[string]interface{}{
+		"project_id":       snapshot.ID,
+		"task_id":          taskID,
+		"personal_task_id": personalTaskID,
+		"action":           "task_assigned",
+	}), nil
+}
+
+// handleTaskLinkChange applies the completion, reopening or deletion of a
+// linked personal task to its project task. It answers nobody: the task
+// manager has already told the user.
+func (a *ProjectManagerAgent) handleTaskLinkChange(ctx context.Context, msg *multiagent.Message, link *TaskLink) (*multiagent.Message, error) {
+	a.loadProjectsFromMemory(ctx)
+
+	a.projectMutex.Lock()
+	project, exists := a.activeProjects[link.ProjectID]
+	if !exists || !ownedBy(ctx, project.UserID) {
+		a.projectMutex.Unlock()
+		return nil, nil
+	}
+	task := projectTask(project, link.ProjectTaskID)
+	if task == nil || task.PersonalTaskID != link.PersonalTaskID {
+		a.projectMutex.Unlock()
+		return nil, nil
+	}
+	now := a.now()
+	action := "task_unlinked"
+	switch link.Action {
+	case TaskLinkStatus:
+		switch {
+		case link.Completed && task.Status != TaskStatusCompleted:
+			task.Status = TaskStatusCompleted
+			task.CompletedAt = &now
+			task.Progress = 100.0
+			action = "task_completed"
+		case !link.Completed && task.Status == TaskStatusCompleted:
+			// A reopened task no longer counts as done
+			task.Status = TaskStatusInProgress
+			task.CompletedAt = nil
+			task.Progress = 0
+			action = "task_reopened"
+		default:
+			a.projectMutex.Unlock()
+			return nil, nil
+		}
+	case TaskLinkUnlink:
+		task.PersonalTaskID = ""
+	default:
+		a.projectMutex.Unlock()
+		return nil, fmt.Errorf("unknown task link action %q", link.Action)
+	}
+	a.recalculateProjectProgress(project)
+	title, taskID := task.Title, task.ID
+	snapshot := *project
+	snapshot.Tasks = append([]ProjectTask(nil), project.Tasks...)
+	snapshot.Milestones = append([]Milestone(nil), project.Milestones...)
+	a.projectMutex.Unlock()
+
+	if err := a.saveProject(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	if action != "task_unlinked" {
+		a.recordShared(ctx, msg, memory.BlackboardEntry{
+			Section: memory.BlackboardFacts,
+			Key:     "project:" + snapshot.ID,
+			Value:   fmt.Sprintf("Project task '%s' of %s was %s from the personal task list; the project is %.0f%% done", title, snapshot.Name, strings.TrimPrefix(action, "task_"), snapshot.Progress),
+		})
+	}
+	a.recordAudit(ctx, msg, audit.ProjectUpdated, snapshot.ID, map[string]interface{}{
+		"action":           action,
+		"task_id":          taskID,
+		"personal_task_id": link.PersonalTaskID,
+		"progress":         snapshot.Progress,
+	})
+	return nil, nil
+}
+
+// projectTask returns the project's task with ID id, or nil
+func projectTask(project *Project, id string) *ProjectTask {
+	for i := range project.Tasks {
+		if project.Tasks[i].ID == id {
+			return &project.Tasks[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,629 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// projectTemplatePrefix is the memory key prefix of project templates; it
+// stays clear of the "project:" prefix projects are listed by
+const projectTemplatePrefix = "project_template:"
+
+// Template commands a user can give
+const (
+	templateCreate      = "create"
+	templateSave        = "save"
+	templateInstantiate = "instantiate"
+	templateList        = "list"
+	templateShow        = "show"
+	templateDelete      = "delete"
+)
+
+// ProjectTemplate is a reusable project plan. Its dates are days after the
+// day a project made from it starts.
+type ProjectTemplate struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Priority    multiagent.Priority `json:"priority"`
+	Tags        []string            `json:"tags"`
+	// DurationDays is when projects made from the template are due; 0 means
+	// on their last task or milestone
+	DurationDays int                 `json:"duration_days,omitempty"`
+	Tasks        []TemplateTask      `json:"tasks"`
+	Milestones   []TemplateMilestone `json:"milestones"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+	UserID       string              `json:"user_id,omitempty"`
+}
+
+// TemplateTask is a task in a project template
+type TemplateTask struct {
+	Title          string              `json:"title"`
+	Description    string              `json:"description,omitempty"`
+	Priority       multiagent.Priority `json:"priority"`
+	StartOffset    int                 `json:"start_offset_days"`
+	DueOffset      *int                `json:"due_offset_days,omitempty"`
+	EstimatedHours float64             `json:"estimated_hours,omitempty"`
+	Tags           []string            `json:"tags,omitempty"`
+	DependsOn      []string            `json:"depends_on,omitempty"` // Titles of the template's tasks it waits on
+	Milestone      string              `json:"milestone,omitempty"`  // Title of the milestone it counts toward
+}
+
+// TemplateMilestone is a milestone in a project template
+type TemplateMilestone struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	DueOffset   int    `json:"due_offset_days"`
+}
+
+var (
+	// templateFromPhrase matches "create project launch q3 from template
+	// launch starting monday" when the LLM can't read the request
+	templateFromPhrase = regexp.MustCompile(`(?i)^(?:create|start|new|make|set up)\b.*?\bproject\s*(?:called\s+|named\s+)?(.*?)\s*\bfrom\s+(?:the\s+|my\s+)?(?:template\s+)?(.+?)(?:\s+template)?(?:\s+(?:starting|beginning|on)\s+(.+?))?[.!?]*$`)
+	// templateSavePhrase matches "save project website as template launch"
+	templateSavePhrase = regexp.MustCompile(`(?i)^save\s+(?:the\s+)?(?:project\s+)?(.+?)\s+as\s+(?:a\s+)?(?:new\s+)?template\s*(?:called\s+|named\s+)?(.*?)[.!?]*$`)
+	// templateCreatePhrase matches "create template launch with tasks plan
+	// (day 0), build (day 5) and ship (day 10)"
+	templateCreatePhrase = regexp.MustCompile(`(?i)^(?:create|add|make|new)\s+(?:a\s+)?(?:project\s+)?template\s+(?:called\s+|named\s+)?(.+?)\s+with\s+(?:the\s+)?tasks?:?\s+(.+?)[.!?]*$`)
+	// templateNamePhrase matches "show template launch" and "delete the
+	// launch template"
+	templateNamePhrase = regexp.MustCompile(`(?i)^(show|view|describe|delete|remove)\s+(?:the\s+|my\s+)?(?:project\s+)?(?:template\s+(.+?)|(.+?)\s+template)[.!?]*$`)
+	// templateTaskDay matches the "(day 5)" or "on day 5" after a task
+	templateTaskDay = regexp.MustCompile(`(?i)\s*(?:\(\s*day\s+(\d+)\s*\)|\b(?:on|by)\s+day\s+(\d+))\s*$`)
+	// templateListSeparator splits "plan, build and ship"
+	templateListSeparator = regexp.MustCompile(`(?i)\s*(?:,|;|\band\b)\s*`)
+)
+
+// daysAfter is how many whole days day falls after base, both in base's
+// location
+func daysAfter(base, day time.Time) int {
+	return int(math.Round(startOfDay(day.In(base.Location())).Sub(startOfDay(base)).Hours() / 24))
+}
+
+// instantiate makes a project from the template starting on start, naming
+// it and its tasks with idgen
+func (t *ProjectTemplate) instantiate(name string, start, now time.Time, owner, userID string, idgen multiagent.IDGenerator) *Project {
+	start = startOfDay(start)
+	project := &Project{
+		ID:           idgen.NewID("proj"),
+		Name:         name,
+		Description:  t.Description,
+		Status:       ProjectStatusPlanning,
+		Priority:     t.Priority,
+		Owner:        owner,
+		CreatedAt:    now,
+		StartDate:    &start,
+		Tasks:        []ProjectTask{},
+		Milestones:   []Milestone{},
+		Resources:    []Resource{},
+		Dependencies: []string{},
+		Tags:         append([]string(nil), t.Tags...),
+		Metadata:     map[string]interface{}{"template_id": t.ID},
+		UserID:       userID,
+	}
+
+	last := 0
+	ids := make(map[string]string, len(t.Tasks))
+	for _, spec := range t.Tasks {
+		taskStart := start.AddDate(0, 0, spec.StartOffset)
+		task := ProjectTask{
+			ID:             idgen.NewID("task"),
+			Title:          spec.Title,
+			Description:    spec.Description,
+			Status:         TaskStatusNotStarted,
+			Priority:       spec.Priority,
+			CreatedAt:      now,
+			StartDate:      &taskStart,
+			Dependencies:   []string{},
+			EstimatedHours: spec.EstimatedHours,
+			Tags:           append([]string{}, spec.Tags...),
+			Comments:       []TaskComment{},
+		}
+		last = max(last, spec.StartOffset)
+		if spec.DueOffset != nil {
+			due := start.AddDate(0, 0, *spec.DueOffset)
+			task.DueDate = &due
+			last = max(last, *spec.DueOffset)
+		}
+		project.EstimatedHours += spec.EstimatedHours
+		ids[strings.ToLower(spec.Title)] = task.ID
+		project.Tasks = append(project.Tasks, task)
+	}
+	for i, spec := range t.Tasks {
+		for _, title := range spec.DependsOn {
+			if id, ok := ids[strings.ToLower(title)]; ok {
+				project.Tasks[i].Dependencies = append(project.Tasks[i].Dependencies, id)
+			}
+		}
+	}
+
+	for _, spec := range t.Milestones {
+		milestone := Milestone{
+			ID:          idgen.NewID("milestone"),
+			Title:       spec.Title,
+			Description: spec.Description,
+			DueDate:     start.AddDate(0, 0, spec.DueOffset),
+			Status:      MilestoneStatusPending,
+			Tasks:       []string{},
+		}
+		for j, task := range t.Tasks {
+			if strings.EqualFold(task.Milestone, spec.Title) {
+				milestone.Tasks = append(milestone.Tasks, project.Tasks[j].ID)
+			}
+		}
+		last = max(last, spec.DueOffset)
+		project.Milestones = append(project.Milestones, milestone)
+	}
+
+	if t.DurationDays > 0 {
+		last = t.DurationDays
+	}
+	if last > 0 || len(t.Tasks)+len(t.Milestones) > 0 {
+		due := start.AddDate(0, 0, last)
+		project.DueDate = &due
+	}
+	return project
+}
+
+// templateFromProject captures a project's plan as a template, with its
+// dates as days after the project started
+func templateFromProject(project *Project, id, name string, now time.Time) *ProjectTemplate {
+	base := project.CreatedAt
+	if project.StartDate != nil {
+		base = *project.StartDate
+	}
+	template := &ProjectTemplate{
+		ID:          id,
+		Name:        name,
+		Description: project.Description,
+		Priority:    project.Priority,
+		Tags:        append([]string(nil), project.Tags...),
+		Tasks:       []TemplateTask{},
+		Milestones:  []TemplateMilestone{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		UserID:      project.UserID,
+	}
+	if project.DueDate != nil {
+		template.DurationDays = max(daysAfter(base, *project.DueDate), 0)
+	}
+
+	titles := make(map[string]string, len(project.Tasks))
+	for _, task := range project.Tasks {
+		titles[task.ID] = task.Title
+	}
+	milestones := make(map[string]string)
+	for _, milestone := range project.Milestones {
+		template.Milestones = append(template.Milestones, TemplateMilestone{
+			Title:       milestone.Title,
+			Description: milestone.Description,
+			DueOffset:   max(daysAfter(base, milestone.DueDate), 0),
+		})
+		for _, taskID := range milestone.Tasks {
+			milestones[taskID] = milestone.Title
+		}
+	}
+	for _, task := range project.Tasks {
+		if task.Status == TaskStatusCancelled {
+			continue
+		}
+		spec := TemplateTask{
+			Title:          task.Title,
+			Description:    task.Description,
+			Priority:       task.Priority,
+			EstimatedHours: task.EstimatedHours,
+			Tags:           append([]string(nil), task.Tags...),
+			Milestone:      milestones[task.ID],
+		}
+		if task.StartDate != nil {
+			spec.StartOffset = max(daysAfter(base, *task.StartDate), 0)
+		}
+		if task.DueDate != nil {
+			due := max(daysAfter(base, *task.DueDate), spec.StartOffset)
+			spec.DueOffset = &due
+		}
+		for _, id := range task.Dependencies {
+			if title, ok := titles[id]; ok {
+				spec.DependsOn = append(spec.DependsOn, title)
+			}
+		}
+		template.Tasks = append(template.Tasks, spec)
+	}
+	return template
+}
+
+// loadTemplates reads the project templates of the user ctx acts for
+func (a *ProjectManagerAgent) loadTemplates(ctx context.Context) []*ProjectTemplate {
+	if a.memoryStore == nil {
+		return nil
+	}
+	keys, err := a.memoryStore.List(ctx, projectTemplatePrefix, maxProjects)
+	if err != nil {
+		return nil
+	}
+	values, err := a.memoryStore.GetMultiple(ctx, keys)
+	if err != nil {
+		return nil
+	}
+
+	var templates []*ProjectTemplate
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		var template ProjectTemplate
+		if err := json.Unmarshal(data, &template); err == nil && template.ID != "" {
+			templates = append(templates, &template)
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool { return strings.ToLower(templates[i].Name) < strings.ToLower(templates[j].Name) })
+	return templates
+}
+
+// findTemplate returns the template ref names, by ID, name or part of its
+// name
+func findTemplate(templates []*ProjectTemplate, ref string) *ProjectTemplate {
+	ref = strings.ToLower(strings.TrimSpace(strings.Trim(ref, `"'`)))
+	if ref == "" {
+		return nil
+	}
+	for _, template := range templates {
+		if template.ID == ref || strings.ToLower(template.Name) == ref {
+			return template
+		}
+	}
+	for _, template := range templates {
+		if strings.Contains(strings.ToLower(template.Name), ref) || strings.Contains(ref, strings.ToLower(template.Name)) {
+			return template
+		}
+	}
+	return nil
+}
+
+// saveTemplate persists a project template in its owner's memory
+func (a *ProjectManagerAgent) saveTemplate(ctx context.Context, template *ProjectTemplate) error {
+	if a.memoryStore == nil {
+		return nil
+	}
+	if err := a.memoryStore.Store(ctx, projectTemplatePrefix+template.ID, template); err != nil {
+		return fmt.Errorf("failed to save project template %s: %w", template.ID, err)
+	}
+	return nil
+}
+
+// writeTemplate describes a template's tasks and milestones by day
+func writeTemplate(b *strings.Builder, template *ProjectTemplate) {
+	if len(template.Tags) > 0 {
+		b.WriteString(fmt.Sprintf("Tags: %s\n", strings.Join(template.Tags, ", ")))
+	}
+	if template.DurationDays > 0 {
+		b.WriteString(fmt.Sprintf("Duration: %d days\n", template.DurationDays))
+	}
+	if len(template.Tasks) > 0 {
+		b.WriteString("\n**Tasks**\n")
+		for _, task := range template.Tasks {
+			b.WriteString("• " + task.Title)
+			switch {
+			case task.DueOffset != nil && task.StartOffset > 0 && *task.DueOffset != task.StartOffset:
+				b.WriteString(fmt.Sprintf(" — days %d–%d", task.StartOffset, *task.DueOffset))
+			case task.DueOffset != nil:
+				b.WriteString(fmt.Sprintf(" — due day %d", *task.DueOffset))
+			case task.StartOffset > 0:
+				b.WriteString(fmt.Sprintf(" — from day %d", task.StartOffset))
+			}
+			if len(task.DependsOn) > 0 {
+				b.WriteString(fmt.Sprintf(" (after %s)", strings.Join(task.DependsOn, ", ")))
+			}
+			b.WriteString("\n")
+		}
+	}
+	if len(template.Milestones) > 0 {
+		b.WriteString("\n**Milestones**\n")
+		for _, milestone := range template.Milestones {
+			b.WriteString(fmt.Sprintf("• %s — day %d\n", milestone.Title, milestone.DueOffset))
+		}
+	}
+}
+
+// parseTemplateTasks reads "plan (day 0), build on day 5 and ship" into
+// template tasks
+func parseTemplateTasks(list string) []TemplateTask {
+	var tasks []TemplateTask
+	for _, item := range templateListSeparator.Split(list, -1) {
+		task := TemplateTask{Priority: multiagent.PriorityMedium}
+		if match := templateTaskDay.FindStringSubmatch(item); match != nil {
+			day, _ := strconv.Atoi(match[1] + match[2])
+			task.DueOffset = &day
+			item = item[:len(item)-len(match[0])]
+		}
+		if task.Title = strings.TrimSpace(item); task.Title != "" {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// handleProjectTemplate creates, saves, lists, shows and deletes project
+// templates, and starts projects from them
+func (a *ProjectManagerAgent) handleProjectTemplate(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadProjectsFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now().In(loc)
+
+	prompt, err := a.renderPrompt(ctx, "project.template", prompts.Vars{
+		"Request": msg.Content,
+		"Now":     now.Format("2006-01-02 (Monday)"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Action      string   `json:"action"`
+		Template    string   `json:"template"`
+		Project     string   `json:"project"`
+		StartDate   string   `json:"start_date"`
+		Description string   `json:"description"`
+		Tags        []string `json:"tags"`
+		Duration    int      `json:"duration_days"`
+		Tasks       []struct {
+			Title          string   `json:"title"`
+			StartOffset    int      `json:"start_offset_days"`
+			DueOffset      *int     `json:"due_offset_days"`
+			EstimatedHours float64  `json:"estimated_hours"`
+			DependsOn      []string `json:"depends_on"`
+			Milestone      string   `json:"milestone"`
+		} `json:"tasks"`
+		Milestones []TemplateMilestone `json:"milestones"`
+	}
+	templateSchema := objectSchema(map[string]string{
+		"action":        "string",
+		"template":      "string",
+		"project":       "string",
+		"start_date":    "string",
+		"description":   "string",
+		"tags":          "array",
+		"duration_days": "integer",
+		"tasks":         "array",
+		"milestones":    "array",
+	}, "action")
+	var fallbackTasks []TemplateTask
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, templateSchema, &data); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse template request", "error", err)
+		content := strings.TrimSpace(msg.Content)
+		data.Action = templateList
+		if match := templateSavePhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.Project, data.Template = templateSave, match[1], match[2]
+		} else if match := templateCreatePhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.Template = templateCreate, match[1]
+			fallbackTasks = parseTemplateTasks(match[2])
+		} else if match := templateFromPhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.Project, data.Template, data.StartDate = templateInstantiate, match[1], match[2], match[3]
+		} else if match := templateNamePhrase.FindStringSubmatch(content); match != nil {
+			data.Action, data.Template = templateShow, match[2]+match[3]
+			if verb := strings.ToLower(match[1]); verb == "delete" || verb == "remove" {
+				data.Action = templateDelete
+			}
+		}
+	}
+
+	templates := a.loadTemplates(ctx)
+	template := findTemplate(templates, data.Template)
+	name := strings.TrimSpace(strings.Trim(data.Template, `"'`))
+
+	switch strings.ToLower(strings.TrimSpace(data.Action)) {
+	case templateList:
+		if len(templates) == 0 {
+			return a.respond(msg, "📐 You have no project templates yet. Say \"save project website as template launch\" or \"create template launch with tasks plan (day 0), build (day 5) and ship (day 10)\".", nil), nil
+		}
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("📐 **Project Templates** (%d)\n\n", len(templates)))
+		for _, t := range templates {
+			b.WriteString(fmt.Sprintf("• **%s** — %d task(s), %d milestone(s)\n", t.Name, len(t.Tasks), len(t.Milestones)))
+		}
+		b.WriteString("\nStart one with \"create project from template <name> starting Monday\".")
+		return a.respond(msg, b.String(), map[string]interface{}{"action": "templates_listed"}), nil
+
+	case templateShow:
+		if template == nil {
+			return a.respond(msg, "❌ Template not found. Use 'list templates' to see your templates.", nil), nil
+		}
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("📐 **Template: %s**\n", template.Name))
+		if template.Description != "" {
+			b.WriteString(template.Description + "\n")
+		}
+		writeTemplate(&b, template)
+		return a.respond(msg, b.String(), map[string]interface{}{"template_id": template.ID, "action": "template_shown"}), nil
+
+	case templateDelete:
+		if template == nil {
+			return a.respond(msg, "❌ Template not found. Use 'list templates' to see your templates.", nil), nil
+		}
+		if allowed, reply := a.authorize(ctx, msg, ActionDeleteProjectTemplate, template.ID, fmt.Sprintf("delete the template '%s'", template.Name)); !allowed {
+			return reply, nil
+		}
+		if a.memoryStore != nil {
+			if err := a.memoryStore.Delete(ctx, projectTemplatePrefix+template.ID); err != nil {
+				return nil, fmt.Errorf("failed to delete project template %s: %w", template.ID, err)
+			}
+		}
+		a.recordAudit(ctx, msg, audit.TemplateDeleted, template.ID, map[string]interface{}{"name": template.Name})
+		return a.respond(msg, fmt.Sprintf("🗑️ Deleted template '%s'.", template.Name), map[string]interface{}{"template_id": template.ID, "action": "template_deleted"}), nil
+
+	case templateSave:
+		project := a.resolveProject(ctx, data.Project, msg.Content)
+		if project == nil {
+			return a.respond(msg, "❌ Project not found. Use 'list projects' to see available projects.", nil), nil
+		}
+		a.projectMutex.RLock()
+		if name == "" {
+			name = project.Name
+		}
+		saved := templateFromProject(project, a.newID("template"), name, a.now())
+		a.projectMutex.RUnlock()
+		saved.UserID = multiagent.UserIDFromContext(ctx)
+		return a.storeTemplate(ctx, msg, saved, template, fmt.Sprintf("from project '%s'", project.Name))
+
+	case templateCreate:
+		if name == "" {
+			return a.respond(msg, "📐 What should the template be called?", nil), nil
+		}
+		now := a.now()
+		created := &ProjectTemplate{
+			ID:           a.newID("template"),
+			Name:         name,
+			Description:  data.Description,
+			Priority:     multiagent.PriorityMedium,
+			Tags:         data.Tags,
+			DurationDays: max(data.Duration, 0),
+			Tasks:        fallbackTasks,
+			Milestones:   []TemplateMilestone{},
+			CreatedAt:    now,
+			UpdatedAt:    now,
+			UserID:       multiagent.UserIDFromContext(ctx),
+		}
+		for _, task := range data.Tasks {
+			if strings.TrimSpace(task.Title) == "" {
+				continue
+			}
+			created.Tasks = append(created.Tasks, TemplateTask{
+				Title:          strings.TrimSpace(task.Title),
+				Priority:       multiagent.PriorityMedium,
+				StartOffset:    max(task.StartOffset, 0),
+				DueOffset:      task.DueOffset,
+				EstimatedHours: task.EstimatedHours,
+				DependsOn:      task.DependsOn,
+				Milestone:      task.Milestone,
+			})
+		}
+		for _, milestone := range data.Milestones {
+			if milestone.Title = strings.TrimSpace(milestone.Title); milestone.Title != "" {
+				created.Milestones = append(created.Milestones, milestone)
+			}
+		}
+		if len(created.Tasks) == 0 && len(created.Milestones) == 0 {
+			return a.respond(msg, fmt.Sprintf("📐 Which tasks belong in '%s'? Say something like \"create template %s with tasks plan (day 0), build (day 5) and ship (day 10)\".", name, name), nil), nil
+		}
+		return a.storeTemplate(ctx, msg, created, template, "")
+
+	case templateInstantiate:
+		if template == nil {
+			return a.respond(msg, "❌ Template not found. Use 'list templates' to see your templates.", nil), nil
+		}
+		start := now
+		if strings.TrimSpace(data.StartDate) != "" {
+			day, err := resolveDate(data.StartDate, now)
+			if err != nil {
+				return a.respond(msg, fmt.Sprintf("📐 When should the project start? I couldn't read %q.", data.StartDate), nil), nil
+			}
+			start = day
+		}
+		projectName := strings.TrimSpace(strings.Trim(data.Project, `"'`))
+		if projectName == "" {
+			projectName = template.Name
+		}
+		project := template.instantiate(projectName, start, a.now(), string(msg.From), multiagent.UserIDFromContext(ctx), a.ids)
+		return a.startFromTemplate(ctx, msg, project, template)
+	}
+
+	return a.respond(msg, "📐 I can create, save, list, show or delete project templates, and start projects from them.", nil), nil
+}
+
+// storeTemplate saves a new template, replacing existing, the template of
+// the same name, if any
+func (a *ProjectManagerAgent) storeTemplate(ctx context.Context, msg *multiagent.Message, template, existing *ProjectTemplate, source string) (*multiagent.Message, error) {
+	verb := "Created"
+	if existing != nil && strings.EqualFold(existing.Name, template.Name) {
+		template.ID, template.CreatedAt = existing.ID, existing.CreatedAt
+		verb = "Updated"
+	}
+	if err := a.saveTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+	a.recordAudit(ctx, msg, audit.TemplateSaved, template.ID, map[string]interface{}{
+		"name":       template.Name,
+		"tasks":      len(template.Tasks),
+		"milestones": len(template.Milestones),
+	})
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("📐 %s template '%s'", verb, template.Name))
+	if source != "" {
+		b.WriteString(" " + source)
+	}
+	b.WriteString(".\n")
+	writeTemplate(&b, template)
+	b.WriteString(fmt.Sprintf("\nStart a project from it with \"create project from template %s starting Monday\".", template.Name))
+	return a.respond(msg, b.String(), map[string]interface{}{
+		"template_id": template.ID,
+		"action":      "template_saved",
+	}), nil
+}
+
+// startFromTemplate saves and announces a project made from a template
+func (a *ProjectManagerAgent) startFromTemplate(ctx context.Context, msg *multiagent.Message, project *Project, template *ProjectTemplate) (*multiagent.Message, error) {
+	a.projectMutex.Lock()
+	a.activeProjects[project.ID] = project
+	a.projectMutex.Unlock()
+	if err := a.saveProject(ctx, project); err != nil {
+		return nil, err
+	}
+
+	shared := []memory.BlackboardEntry{
+		{Section: memory.BlackboardEntities, Key: "project:" + project.ID, Value: "Project: " + project.Name},
+		{Section: memory.BlackboardGoals, Key: "project:" + project.ID, Value: fmt.Sprintf("Deliver project '%s'", project.Name)},
+	}
+	if project.DueDate != nil {
+		shared = append(shared, memory.BlackboardEntry{
+			Section: memory.BlackboardFacts,
+			Key:     "project:" + project.ID + ":deadline",
+			Value:   fmt.Sprintf("Project '%s' is due %s", project.Name, project.DueDate.Format("2006-01-02")),
+		})
+	}
+	a.recordShared(ctx, msg, shared...)
+	a.recordAudit(ctx, msg, audit.ProjectCreated, project.ID, map[string]interface{}{
+		"name":        project.Name,
+		"due_date":    project.DueDate,
+		"template_id": template.ID,
+	})
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("✅ Project '%s' created from template '%s', starting %s.\n\nProject ID: %s\n", project.Name, template.Name, project.StartDate.Format("Mon 2006-01-02"), project.ID))
+	if project.DueDate != nil {
+		b.WriteString(fmt.Sprintf("Due: %s\n", project.DueDate.Format("Mon 2006-01-02")))
+	}
+	if len(project.Tasks) > 0 {
+		b.WriteString("\n**Tasks**\n")
+		for _, task := range project.Tasks {
+			if task.DueDate != nil {
+				b.WriteString(fmt.Sprintf("• %s — due %s\n", task.Title, task.DueDate.Format("Mon 2006-01-02")))
+			} else {
+				b.WriteString(fmt.Sprintf("• %s — from %s\n", task.Title, task.StartDate.Format("Mon 2006-01-02")))
+			}
+		}
+	}
+	if len(project.Milestones) > 0 {
+		b.WriteString("\n**Milestones**\n")
+		writeMilestones(&b, project, a.now().In(project.StartDate.Location()))
+	}
+	return a.respond(msg, b.String(), map[string]interface{}{
+		"project_id":  project.ID,
+		"template_id": template.ID,
+		"action":      "project_created",
+	}), nil
+}
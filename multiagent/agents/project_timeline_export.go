@@ -0,0 +1,228 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/gantt"
+)
+
+// Formats project timelines can be exported in
+const (
+	TimelineFormatMermaid = gantt.FormatMermaid
+	TimelineFormatCSV     = gantt.FormatCSV
+)
+
+// hoursPerDay converts a task's estimated hours into days on the chart
+// when it has no due date
+const hoursPerDay = 8
+
+// maxProjects bounds how many projects LoadProjects reads
+const maxProjects = 1000
+
+// ErrProjectNotFound is returned when no project of the user matches
+var ErrProjectNotFound = errors.New("project not found")
+
+// ProjectExporter is implemented by agents whose project timelines can be
+// exported
+type ProjectExporter interface {
+	// ExportProjectTimeline returns the timeline of the project ref names,
+	// by ID or name, of the user ctx acts for in format. An empty ref
+	// names the user's only project.
+	ExportProjectTimeline(ctx context.Context, ref, format string) ([]byte, error)
+}
+
+// ExportProjectTimeline renders a project's tasks and milestones as a gantt
+// chart in format, one of TimelineFormatMermaid and TimelineFormatCSV
+func (a *ProjectManagerAgent) ExportProjectTimeline(ctx context.Context, ref, format string) ([]byte, error) {
+	a.loadProjectsFromMemory(ctx)
+	project := a.resolveProject(ctx, ref, ref)
+	if project == nil || !projectMatches(project, ref) {
+		return nil, fmt.Errorf("%w: %q", ErrProjectNotFound, ref)
+	}
+
+	a.projectMutex.RLock()
+	snapshot := *project
+	snapshot.Tasks = append([]ProjectTask(nil), project.Tasks...)
+	snapshot.Milestones = append([]Milestone(nil), project.Milestones...)
+	a.projectMutex.RUnlock()
+
+	now := a.now().In(userLocation(ctx, a.memoryStore))
+	return RenderProjectTimeline(&snapshot, format, now)
+}
+
+// handleExportTimeline replies with a project's gantt chart as Mermaid, or
+// as CSV when asked for a spreadsheet
+func (a *ProjectManagerAgent) handleExportTimeline(ctx context.Context, msg *multiagent.Message, project *Project) (*multiagent.Message, error) {
+	content := strings.ToLower(msg.Content)
+	format, name := TimelineFormatMermaid, "Mermaid"
+	if strings.Contains(content, "csv") || strings.Contains(content, "spreadsheet") || strings.Contains(content, "excel") {
+		format, name = TimelineFormatCSV, "CSV"
+	}
+
+	data, err := a.ExportProjectTimeline(ctx, project.ID, format)
+	if err != nil {
+		return nil, err
+	}
+	fence := "```"
+	if format == TimelineFormatMermaid {
+		fence += "mermaid"
+	}
+	return a.respond(msg, fmt.Sprintf("📊 **Gantt Chart: %s — %s**\n\n%s\n%s```", project.Name, name, fence, data), map[string]interface{}{
+		"project_id": project.ID,
+		"action":     "timeline_exported",
+		"format":     format,
+		"data":       string(data),
+	}), nil
+}
+
+// projectMatches reports whether ref, if given, names project rather than
+// resolveProject falling back to the user's only project
+func projectMatches(project *Project, ref string) bool {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	name := strings.ToLower(project.Name)
+	return ref == "" || strings.Contains(ref, strings.ToLower(project.ID)) || strings.Contains(name, ref) || strings.Contains(ref, name)
+}
+
+// LoadProjects reads the projects of the user ctx acts for from store, a
+// per-user view of the memory store, for tools that run without agents
+func LoadProjects(ctx context.Context, store multiagent.MemoryStore) ([]*Project, error) {
+	keys, err := store.List(ctx, "project:", maxProjects)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	values, err := store.GetMultiple(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load projects: %w", err)
+	}
+
+	var projects []*Project
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		var project Project
+		if err := json.Unmarshal(data, &project); err != nil || project.ID == "" {
+			continue
+		}
+		projects = append(projects, &project)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].CreatedAt.Before(projects[j].CreatedAt) })
+	return projects, nil
+}
+
+// RenderProjectTimeline renders project as a gantt chart in format, with
+// dates in now's location
+func RenderProjectTimeline(project *Project, format string, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gantt.Write(&buf, format, projectChart(project, now)); err != nil {
+		return nil, fmt.Errorf("failed to export timeline of project %s: %w", project.ID, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// projectChart lays a project out as a gantt chart: a section per
+// milestone holding its linked tasks and ending on the milestone, then the
+// tasks no milestone covers
+func projectChart(project *Project, now time.Time) gantt.Chart {
+	loc := now.Location()
+	today := startOfDay(now)
+
+	milestones := append([]Milestone(nil), project.Milestones...)
+	sort.SliceStable(milestones, func(i, j int) bool { return milestones[i].DueDate.Before(milestones[j].DueDate) })
+
+	tasks := make(map[string]*ProjectTask, len(project.Tasks))
+	for i := range project.Tasks {
+		tasks[project.Tasks[i].ID] = &project.Tasks[i]
+	}
+	covered := make(map[string]bool)
+	chart := gantt.Chart{Title: project.Name}
+	for _, milestone := range milestones {
+		section := gantt.Section{Name: milestone.Title}
+		for _, taskID := range milestone.Tasks {
+			if task, ok := tasks[taskID]; ok && !covered[task.ID] && task.Status != TaskStatusCancelled {
+				covered[task.ID] = true
+				section.Items = append(section.Items, taskBar(task, project, loc, today))
+			}
+		}
+		due := startOfDay(milestone.DueDate.In(loc))
+		item := gantt.Item{ID: milestone.ID, Title: milestone.Title, Start: due, Milestone: true}
+		switch {
+		case milestone.CompletedAt != nil:
+			item.Status, item.Progress = gantt.StatusDone, 100
+		case due.Before(today):
+			item.Status = gantt.StatusCritical
+		}
+		section.Items = append(section.Items, item)
+		chart.Sections = append(chart.Sections, section)
+	}
+
+	rest := gantt.Section{Name: "Tasks"}
+	if len(milestones) == 0 {
+		rest.Name = project.Name
+	}
+	for i := range project.Tasks {
+		task := &project.Tasks[i]
+		if !covered[task.ID] && task.Status != TaskStatusCancelled {
+			rest.Items = append(rest.Items, taskBar(task, project, loc, today))
+		}
+	}
+	sort.SliceStable(rest.Items, func(i, j int) bool { return rest.Items[i].Start.Before(rest.Items[j].Start) })
+	chart.Sections = append(chart.Sections, rest)
+	return chart
+}
+
+// taskBar places a task on the chart: from its start date (or when it was
+// added) to its due date, its estimate, or the day it was completed
+func taskBar(task *ProjectTask, project *Project, loc *time.Location, today time.Time) gantt.Item {
+	start := task.CreatedAt
+	if task.StartDate != nil {
+		start = *task.StartDate
+	} else if project.StartDate != nil && project.StartDate.After(start) {
+		start = *project.StartDate
+	}
+	start = startOfDay(start.In(loc))
+
+	var end time.Time
+	switch {
+	case task.DueDate != nil:
+		end = startOfDay(task.DueDate.In(loc))
+	case task.CompletedAt != nil:
+		end = startOfDay(task.CompletedAt.In(loc))
+	default:
+		days := int(math.Ceil(task.EstimatedHours / hoursPerDay))
+		end = start.AddDate(0, 0, max(days, 1)-1)
+	}
+	if end.Before(start) {
+		// Due before it was added, e.g. a task logged after the fact
+		start = end
+	}
+
+	item := gantt.Item{
+		ID:       task.ID,
+		Title:    task.Title,
+		Start:    start,
+		End:      end,
+		Progress: task.Progress,
+		Assignee: task.Assignee,
+		After:    task.Dependencies,
+	}
+	switch {
+	case task.Status == TaskStatusCompleted:
+		item.Status, item.Progress = gantt.StatusDone, 100
+	case task.DueDate != nil && end.Before(today):
+		item.Status = gantt.StatusCritical
+	case task.Status == TaskStatusInProgress:
+		item.Status = gantt.StatusActive
+	}
+	return item
+}
@@ -0,0 +1,28 @@
+package agents
+
+import (
+	"github.com/kbutz/wikillm/multiagent/reminders"
+)
+
+// Sources agents register their reminder handlers under
+const (
+	taskReminderSource      = "task_manager"
+	eventReminderSource     = "scheduler"
+	weeklyReviewSource      = "weekly_review"
+	followUpReminderSource  = "follow_up"
+	reconnectReminderSource = "reconnect"
+	scheduledSendSource     = "scheduled_send"
+)
+
+// ensureReminderEngine gives an agent created without a shared engine, e.g.
+// outside the service, one of its own
+func ensureReminderEngine(config *BaseAgentConfig) {
+	if config.Reminders != nil {
+		return
+	}
+	config.Reminders = reminders.NewEngine(reminders.EngineConfig{
+		Store:    config.MemoryStore,
+		Notifier: config.Notifier,
+	})
+	config.Reminders.Start()
+}
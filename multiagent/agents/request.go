@@ -0,0 +1,209 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// DefaultRequestTimeout is used when BaseAgentConfig.RequestTimeout is zero
+const DefaultRequestTimeout = 60 * time.Second
+
+var (
+	// ErrRequestTimeout is returned by Future.Wait when no reply arrives in time
+	ErrRequestTimeout = errors.New("request timed out")
+	// ErrRequestCancelled is returned by Future.Wait after Cancel
+	ErrRequestCancelled = errors.New("request cancelled")
+)
+
+// Future is the pending reply to a message sent with Request
+type Future struct {
+	requestID string
+	to        multiagent.AgentID
+	done      chan struct{}
+	once      sync.Once
+	reply     *multiagent.Message
+	err       error
+	cleanup   func()
+}
+
+// RequestID returns the ID of the request message replies are correlated with
+func (f *Future) RequestID() string {
+	return f.requestID
+}
+
+// To returns the agent the request was sent to
+func (f *Future) To() multiagent.AgentID {
+	return f.to
+}
+
+// Done is closed once the future resolves
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the reply arrives, the request times out or is
+// cancelled, or ctx is done. An error reply is returned along with an error.
+func (f *Future) Wait(ctx context.Context) (*multiagent.Message, error) {
+	select {
+	case <-f.done:
+		return f.reply, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Cancel abandons the request; a reply arriving later is ignored
+func (f *Future) Cancel() {
+	f.resolve(nil, ErrRequestCancelled)
+}
+
+func (f *Future) resolve(reply *multiagent.Message, err error) {
+	f.once.Do(func() {
+		f.reply = reply
+		f.err = err
+		f.cleanup()
+		close(f.done)
+	})
+}
+
+// Request sends content to another agent and returns a future for its reply
+func (a *BaseAgent) Request(ctx context.Context, to multiagent.AgentID, content string) (*Future, error) {
+	return a.RequestMessage(ctx, &multiagent.Message{
+		To:      []multiagent.AgentID{to},
+		Type:    multiagent.MessageTypeRequest,
+		Content: content,
+	})
+}
+
+// agentOfType returns the first registered agent of agentType
+func (a *BaseAgent) agentOfType(agentType multiagent.AgentType) (multiagent.AgentID, bool) {
+	if a.orchestrator == nil {
+		return "", false
+	}
+	for _, agent := range a.orchestrator.ListAgents() {
+		if agent.Type() == agentType {
+			return agent.ID(), true
+		}
+	}
+	return "", false
+}
+
+// RequestMessage sends msg to its single recipient and returns a future
+// resolved by the first message whose ReplyTo is msg.ID. The future times
+// out after the agent's request timeout and is cancelled with ctx.
+func (a *BaseAgent) RequestMessage(ctx context.Context, msg *multiagent.Message) (*Future, error) {
+	if len(msg.To) != 1 {
+		return nil, fmt.Errorf("request must have exactly one recipient, got %d", len(msg.To))
+	}
+	if msg.ID == "" {
+		msg.ID = a.newID("req_" + string(a.id))
+	}
+	if msg.Type == "" {
+		msg.Type = multiagent.MessageTypeRequest
+	}
+	if msg.Context == nil {
+		msg.Context = make(map[string]interface{})
+	}
+	msg.Context[multiagent.ContextExpectsReply] = true
+
+	future := &Future{
+		requestID: msg.ID,
+		to:        msg.To[0],
+		done:      make(chan struct{}),
+	}
+	future.cleanup = func() {
+		a.pendingMu.Lock()
+		delete(a.pending, future.requestID)
+		a.pendingMu.Unlock()
+	}
+
+	a.pendingMu.Lock()
+	a.pending[msg.ID] = future
+	a.pendingMu.Unlock()
+
+	go func(timeout time.Duration) {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-future.done:
+		case <-timer.C:
+			future.resolve(nil, fmt.Errorf("%w after %v waiting for %s", ErrRequestTimeout, timeout, future.to))
+		case <-ctx.Done():
+			future.resolve(nil, ctx.Err())
+		}
+	}(a.requestTimeout)
+
+	if err := a.SendMessage(ctx, msg); err != nil {
+		future.resolve(nil, err)
+		return nil, fmt.Errorf("failed to send request to %s: %w", future.to, err)
+	}
+	return future, nil
+}
+
+// resolveReply completes the pending request msg replies to, if any, and
+// reports whether msg was consumed
+func (a *BaseAgent) resolveReply(msg *multiagent.Message) bool {
+	if msg.ReplyTo == "" {
+		return false
+	}
+
+	a.pendingMu.Lock()
+	future, exists := a.pending[msg.ReplyTo]
+	a.pendingMu.Unlock()
+	if !exists {
+		return false
+	}
+
+	if msg.Type == multiagent.MessageTypeError {
+		future.resolve(msg, fmt.Errorf("agent %s failed request %s: %s", msg.From, msg.ReplyTo, msg.Content))
+	} else {
+		future.resolve(msg, nil)
+	}
+	return true
+}
+
+// failPending resolves every outstanding request with err
+func (a *BaseAgent) failPending(err error) {
+	a.pendingMu.Lock()
+	futures := make([]*Future, 0, len(a.pending))
+	for _, future := range a.pending {
+		futures = append(futures, future)
+	}
+	a.pendingMu.Unlock()
+
+	for _, future := range futures {
+		future.resolve(nil, err)
+	}
+}
+
+// askUser answers msg with a question for the user. The orchestrator pauses
+// the request and, once the user answers, sends it back to this agent with
+// the answer appended to its content and under its "answer" context key.
+func (a *BaseAgent) askUser(msg *multiagent.Message, question string) *multiagent.Message {
+	questionContext := map[string]interface{}{}
+	if conversationID, ok := msg.Context["conversation_id"]; ok {
+		questionContext["conversation_id"] = conversationID
+	}
+	return &multiagent.Message{
+		ID:        a.messageID(),
+		From:      a.id,
+		To:        []multiagent.AgentID{msg.From},
+		Type:      multiagent.MessageTypeQuestion,
+		Content:   question,
+		ReplyTo:   msg.ID,
+		Timestamp: a.now(),
+		Context:   questionContext,
+	}
+}
+
+// userAnswer returns the user's answer to a question this agent asked about
+// msg, if msg is the resumed request
+func userAnswer(msg *multiagent.Message) (string, bool) {
+	answer, ok := msg.Context["answer"].(string)
+	return answer, ok
+}
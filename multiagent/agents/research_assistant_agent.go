@@ -2,13 +2,15 @@ package agents
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/llmprovider"
+	"github.com/kbutz/wikillm/multiagent/prompts"
 )
 
 // ResearchAssistantAgent specializes in information gathering, research, and knowledge synthesis
@@ -36,6 +38,7 @@ type ResearchSession struct {
 	Methodology  ResearchMethodology        `json:"methodology"`
 	Scope        ResearchScope              `json:"scope"`
 	Metadata     map[string]interface{}     `json:"metadata"`
+	UserID       string                     `json:"user_id,omitempty"`
 }
 
 // ResearchStatus represents the status of a research session
@@ -71,15 +74,16 @@ type ResearchSource struct {
 type SourceType string
 
 const (
-	SourceTypeWeb         SourceType = "web"
-	SourceTypeAcademic    SourceType = "academic"
-	SourceTypeBook        SourceType = "book"
-	SourceTypeArticle     SourceType = "article"
-	SourceTypeReport      SourceType = "report"
-	SourceTypeInterview   SourceType = "interview"
-	SourceTypeExpert      SourceType = "expert"
-	SourceTypeDatabase    SourceType = "database"
-	SourceTypeInternal    SourceType = "internal"
+	SourceTypeWeb          SourceType = "web"
+	SourceTypeAcademic     SourceType = "academic"
+	SourceTypeBook         SourceType = "book"
+	SourceTypeArticle      SourceType = "article"
+	SourceTypeReport       SourceType = "report"
+	SourceTypeInterview    SourceType = "interview"
+	SourceTypeExpert       SourceType = "expert"
+	SourceTypeDatabase     SourceType = "database"
+	SourceTypeInternal     SourceType = "internal"
+	SourceTypeEncyclopedia SourceType = "encyclopedia"
 )
 
 // ResearchFinding represents a key finding from research
@@ -169,6 +173,13 @@ func NewResearchAssistantAgent(config BaseAgentConfig) *ResearchAssistantAgent {
 
 // HandleMessage processes incoming research requests
 func (a *ResearchAssistantAgent) HandleMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	// Acknowledgments of the completion notifications sent when research
+	// finishes are not requests; answering them would start the research
+	// over, and again for every acknowledgment after that
+	if msg.Type == multiagent.MessageTypeResponse {
+		return nil, nil
+	}
+
 	// Update state to busy
 	a.mu.Lock()
 	a.state.Status = multiagent.AgentStatusBusy
@@ -191,7 +202,11 @@ func (a *ResearchAssistantAgent) HandleMessage(ctx context.Context, msg *multiag
 	content := strings.ToLower(msg.Content)
 
 	// Route to appropriate handler based on content
-	if strings.Contains(content, "research") || strings.Contains(content, "find information") || strings.Contains(content, "look up") {
+	if strings.Contains(content, "bibliography") || strings.Contains(content, "bibtex") || strings.Contains(content, "citations") {
+		return a.handleBibliography(ctx, msg)
+	} else if strings.Contains(content, "monitor") || strings.Contains(content, "keep an eye on") || strings.Contains(content, "keep watching") {
+		return a.handleMonitor(ctx, msg)
+	} else if strings.Contains(content, "research") || strings.Contains(content, "find information") || strings.Contains(content, "look up") {
 		return a.handleResearchRequest(ctx, msg)
 	} else if strings.Contains(content, "fact check") || strings.Contains(content, "verify") {
 		return a.handleFactCheck(ctx, msg)
@@ -212,27 +227,9 @@ func (a *ResearchAssistantAgent) HandleMessage(ctx context.Context, msg *multiag
 // handleResearchRequest processes a research request
 func (a *ResearchAssistantAgent) handleResearchRequest(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Use LLM to extract research parameters
-	contextPrompt := fmt.Sprintf(`
-Extract research parameters from this request: "%s"
-
-Provide response in JSON format:
-{
-  "topic": "main research topic",
-  "query": "specific research question",
-  "methodology": "comprehensive|quick|deep|comparative|factual",
-  "depth": "surface|medium|deep|expert",
-  "time_limit": "duration in hours if mentioned, otherwise 2",
-  "priority": "low|medium|high|critical",
-  "focus_areas": ["area1", "area2"] if specific areas mentioned,
-  "source_types": ["web", "academic", "article"] preferred source types,
-  "deadline": "YYYY-MM-DD if mentioned, otherwise null"
-}
-
-Make reasonable assumptions for missing information.`, msg.Content)
-
-	response, err := a.llmProvider.Query(ctx, contextPrompt)
+	contextPrompt, err := a.renderPrompt(ctx, "research.request", prompts.Vars{"Request": msg.Content})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse research parameters: %w", err)
+		return nil, err
 	}
 
 	var researchData struct {
@@ -247,7 +244,15 @@ Make reasonable assumptions for missing information.`, msg.Content)
 		Deadline    string   `json:"deadline"`
 	}
 
-	if err := json.Unmarshal([]byte(response), &researchData); err != nil {
+	researchSchema := objectSchema(map[string]string{
+		"topic":        "string",
+		"query":        "string",
+		"time_limit":   "integer",
+		"focus_areas":  "array",
+		"source_types": "array",
+	}, "topic")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+contextPrompt, researchSchema, &researchData); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse research parameters", "error", err)
 		// Fallback to basic research
 		researchData.Topic = msg.Content
 		researchData.Query = msg.Content
@@ -259,17 +264,18 @@ Make reasonable assumptions for missing information.`, msg.Content)
 
 	// Create research session
 	session := &ResearchSession{
-		ID:          fmt.Sprintf("research_%d", time.Now().UnixNano()),
+		ID:          a.newID("research"),
 		Topic:       researchData.Topic,
 		Query:       researchData.Query,
 		Status:      ResearchStatusInitiated,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   a.now(),
+		UpdatedAt:   a.now(),
 		Sources:     []ResearchSource{},
 		Findings:    []ResearchFinding{},
 		Tags:        []string{},
 		Priority:    a.parsePriority(researchData.Priority),
 		RequestedBy: msg.From,
+		UserID:      multiagent.UserIDFromContext(ctx),
 		Methodology: ResearchMethodology{
 			Type:        MethodologyType(researchData.Methodology),
 			Depth:       ResearchDepth(researchData.Depth),
@@ -306,13 +312,13 @@ Make reasonable assumptions for missing information.`, msg.Content)
 	go a.conductResearch(ctx, session)
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   fmt.Sprintf("🔍 Research session '%s' started!\n\n📋 **Research Details:**\n• Topic: %s\n• Methodology: %s\n• Depth: %s\n• Time Limit: %v\n• Priority: %s\n\nI'll begin gathering information and will provide updates as I find relevant sources and insights.", session.Topic, session.ID, session.Methodology.Type, session.Methodology.Depth, session.Methodology.TimeLimit, session.Priority),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"research_session_id": session.ID,
 			"action":              "research_started",
@@ -323,24 +329,9 @@ Make reasonable assumptions for missing information.`, msg.Content)
 // handleFactCheck processes fact-checking requests
 func (a *ResearchAssistantAgent) handleFactCheck(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Extract claims to verify
-	contextPrompt := fmt.Sprintf(`
-Identify factual claims to verify from: "%s"
-
-Provide response in JSON format:
-{
-  "claims": [
-    {
-      "claim": "specific factual claim",
-      "category": "statistic|date|name|event|definition|etc",
-      "importance": "high|medium|low"
-    }
-  ],
-  "context": "additional context for verification"
-}`, msg.Content)
-
-	response, err := a.llmProvider.Query(ctx, contextPrompt)
+	contextPrompt, err := a.renderPrompt(ctx, "research.extract_claims", prompts.Vars{"Request": msg.Content})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse fact-check request: %w", err)
+		return nil, err
 	}
 
 	var factCheckData struct {
@@ -352,23 +343,34 @@ Provide response in JSON format:
 		Context string `json:"context"`
 	}
 
-	if err := json.Unmarshal([]byte(response), &factCheckData); err != nil {
-		return nil, fmt.Errorf("failed to parse fact-check JSON: %w", err)
+	factCheckSchema := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"claims"},
+		"properties": map[string]interface{}{
+			"claims": map[string]interface{}{
+				"type":  "array",
+				"items": objectSchema(map[string]string{"claim": "string"}, "claim"),
+			},
+		},
+	}
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+contextPrompt, factCheckSchema, &factCheckData); err != nil {
+		return nil, fmt.Errorf("failed to parse fact-check request: %w", err)
 	}
 
 	// Create fact-check session
 	session := &ResearchSession{
-		ID:          fmt.Sprintf("factcheck_%d", time.Now().UnixNano()),
+		ID:          a.newID("factcheck"),
 		Topic:       "Fact Verification",
 		Query:       msg.Content,
 		Status:      ResearchStatusInProgress,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   a.now(),
+		UpdatedAt:   a.now(),
 		Sources:     []ResearchSource{},
 		Findings:    []ResearchFinding{},
 		Tags:        []string{"fact-check"},
 		Priority:    multiagent.PriorityHigh,
 		RequestedBy: msg.From,
+		UserID:      multiagent.UserIDFromContext(ctx),
 		Methodology: ResearchMethodology{
 			Type:        MethodologyFactual,
 			Depth:       ResearchDepthMedium,
@@ -387,32 +389,70 @@ Provide response in JSON format:
 	a.activeResearch[session.ID] = session
 	a.researchMutex.Unlock()
 
-	// Perform fact-checking using LLM
-	factCheckPrompt := fmt.Sprintf(`
-You are a fact-checking specialist. Verify the following claims based on your knowledge:
-
-Original text: "%s"
-
-Claims to verify:
-%s
-
-For each claim, provide:
-1. Verification status (TRUE/FALSE/PARTIALLY TRUE/UNVERIFIED)
-2. Explanation with reasoning
-3. Confidence level (0-100%)
-4. Suggested sources for verification
-
-Format your response clearly for each claim.`, msg.Content, a.formatClaimsForPrompt(factCheckData.Claims))
-
-	factCheckResult, err := a.llmProvider.Query(ctx, factCheckPrompt)
+	// Check the claims against Wikipedia when it can be searched, and
+	// against the model's own knowledge otherwise
+	claims := make([]string, 0, len(factCheckData.Claims))
+	for _, claim := range factCheckData.Claims {
+		if text := strings.TrimSpace(claim.Claim); text != "" {
+			claims = append(claims, text)
+		}
+	}
+	verdicts, passages, err := a.groundClaims(ctx, msg.Content, claims)
 	if err != nil {
-		return nil, fmt.Errorf("fact-check analysis failed: %w", err)
+		a.logger.WarnContext(ctx, "Failed to check claims against Wikipedia, checking them from memory", "error", err)
+		verdicts = nil
+	}
+
+	var factCheckResult, note string
+	if verdicts != nil {
+		factCheckResult = formatVerdicts(verdicts)
+		note = "Verdicts rest on the Wikipedia passages quoted; confidence reflects how strongly the passages retrieved agree with each."
+		sourceIDs := make(map[*candidateSource]string)
+		for _, passage := range passages {
+			source := a.researchSource(passage)
+			sourceIDs[passage] = source.ID
+			session.Sources = append(session.Sources, source)
+		}
+		for _, verdict := range verdicts {
+			evidence, cited := []string{}, []string{}
+			for _, item := range verdict.Evidence {
+				evidence = append(evidence, fmt.Sprintf("%q (%s, %s)", item.Quote, item.Title, item.Stance))
+				if id := sourceIDs[item.passage]; !slices.Contains(cited, id) {
+					cited = append(cited, id)
+				}
+			}
+			session.Findings = append(session.Findings, ResearchFinding{
+				ID:         a.newID("finding"),
+				Topic:      session.Topic,
+				Finding:    verdict.Verdict + ": " + verdict.Claim,
+				Evidence:   evidence,
+				Confidence: verdict.Confidence,
+				Sources:    cited,
+				Tags:       []string{},
+				CreatedAt:  a.now(),
+				Metadata:   map[string]interface{}{"verdict": verdict.Verdict},
+			})
+		}
+		session.Metadata["verdicts"] = verdicts
+	} else {
+		factCheckPrompt, err := a.renderPrompt(ctx, "research.fact_check", prompts.Vars{
+			"Request": msg.Content,
+			"Claims":  a.formatClaimsForPrompt(factCheckData.Claims),
+		})
+		if err != nil {
+			return nil, err
+		}
+		factCheckResult, err = a.llmProvider.Query(ctx, factCheckPrompt)
+		if err != nil {
+			return nil, fmt.Errorf("fact-check analysis failed: %w", err)
+		}
+		note = "This analysis is based on my training data. For critical decisions, please verify with authoritative sources."
 	}
 
 	// Update session with results
 	session.Status = ResearchStatusCompleted
 	session.Summary = factCheckResult
-	session.UpdatedAt = time.Now()
+	session.UpdatedAt = a.now()
 
 	// Save updated session
 	if a.memoryStore != nil {
@@ -421,13 +461,13 @@ Format your response clearly for each claim.`, msg.Content, a.formatClaimsForPro
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
-		Content:   fmt.Sprintf("✅ **Fact-Check Results**\n\n%s\n\n---\n\n*Note: This analysis is based on my training data. For critical decisions, please verify with authoritative sources.*", factCheckResult),
+		Content:   fmt.Sprintf("✅ **Fact-Check Results**\n\n%s\n\n---\n\n*Note: %s*", factCheckResult, note),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"research_session_id": session.ID,
 			"action":              "fact_check_completed",
@@ -438,34 +478,29 @@ Format your response clearly for each claim.`, msg.Content, a.formatClaimsForPro
 // handleSummarize creates summaries of research or content
 func (a *ResearchAssistantAgent) handleSummarize(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Use LLM to create summary
-	summaryPrompt := fmt.Sprintf(`
-Create a comprehensive summary of the following content: "%s"
-
-Provide:
-1. Executive Summary (2-3 sentences)
-2. Key Points (bullet points)
-3. Important Details
-4. Conclusions/Takeaways
-
-Structure your response clearly with headers.`, msg.Content)
+	summaryPrompt, err := a.renderPrompt(ctx, "research.summarize", prompts.Vars{"Request": msg.Content})
+	if err != nil {
+		return nil, err
+	}
 
-	summary, err := a.llmProvider.Query(ctx, summaryPrompt)
+	summary, err := a.llmProvider.Query(llmprovider.WithPromptClass(ctx, llmprovider.PromptClassSummary), summaryPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("summarization failed: %w", err)
 	}
 
 	// Store summary session
 	session := &ResearchSession{
-		ID:          fmt.Sprintf("summary_%d", time.Now().UnixNano()),
+		ID:          a.newID("summary"),
 		Topic:       "Content Summary",
 		Query:       msg.Content,
 		Status:      ResearchStatusCompleted,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   a.now(),
+		UpdatedAt:   a.now(),
 		Summary:     summary,
 		Tags:        []string{"summary"},
 		Priority:    multiagent.PriorityMedium,
 		RequestedBy: msg.From,
+		UserID:      multiagent.UserIDFromContext(ctx),
 		Methodology: ResearchMethodology{
 			Type:      MethodologyQuick,
 			Depth:     ResearchDepthMedium,
@@ -483,13 +518,13 @@ Structure your response clearly with headers.`, msg.Content)
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   fmt.Sprintf("📝 **Content Summary**\n\n%s", summary),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"research_session_id": session.ID,
 			"action":              "summary_completed",
@@ -500,17 +535,10 @@ Structure your response clearly with headers.`, msg.Content)
 // handleComparison performs comparative analysis
 func (a *ResearchAssistantAgent) handleComparison(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Use LLM to extract comparison elements
-	comparisonPrompt := fmt.Sprintf(`
-Perform a comparative analysis based on: "%s"
-
-Extract what is being compared and provide a structured comparison including:
-1. Items being compared
-2. Comparison criteria
-3. Detailed comparison
-4. Pros and cons for each
-5. Recommendations or conclusions
-
-Present the analysis in a clear, structured format.`, msg.Content)
+	comparisonPrompt, err := a.renderPrompt(ctx, "research.compare", prompts.Vars{"Request": msg.Content})
+	if err != nil {
+		return nil, err
+	}
 
 	comparison, err := a.llmProvider.Query(ctx, comparisonPrompt)
 	if err != nil {
@@ -518,30 +546,22 @@ Present the analysis in a clear, structured format.`, msg.Content)
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   fmt.Sprintf("⚖️ **Comparative Analysis**\n\n%s", comparison),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
 // handleTrendAnalysis analyzes trends and patterns
 func (a *ResearchAssistantAgent) handleTrendAnalysis(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	trendPrompt := fmt.Sprintf(`
-Analyze trends and patterns from: "%s"
-
-Provide:
-1. Current trends identified
-2. Historical context
-3. Future projections
-4. Key drivers
-5. Potential impacts
-6. Recommendations
-
-Structure your analysis with clear sections.`, msg.Content)
+	trendPrompt, err := a.renderPrompt(ctx, "research.trends", prompts.Vars{"Request": msg.Content})
+	if err != nil {
+		return nil, err
+	}
 
 	analysis, err := a.llmProvider.Query(ctx, trendPrompt)
 	if err != nil {
@@ -549,33 +569,36 @@ Structure your analysis with clear sections.`, msg.Content)
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   fmt.Sprintf("📈 **Trend Analysis**\n\n%s", analysis),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
 // handleSourceManagement manages research sources
 func (a *ResearchAssistantAgent) handleSourceManagement(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   "📚 Source management functionality is available. I can help you organize, evaluate, and cite research sources.",
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
 // handleGeneralQuery handles general research questions
 func (a *ResearchAssistantAgent) handleGeneralQuery(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Build context with research capabilities
-	contextPrompt := a.buildResearchContext(ctx, msg)
+	contextPrompt, err := a.buildResearchContext(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
 
 	response, err := a.llmProvider.Query(ctx, contextPrompt)
 	if err != nil {
@@ -583,13 +606,13 @@ func (a *ResearchAssistantAgent) handleGeneralQuery(ctx context.Context, msg *mu
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   response,
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
@@ -598,60 +621,50 @@ func (a *ResearchAssistantAgent) conductResearch(ctx context.Context, session *R
 	// Update status
 	a.researchMutex.Lock()
 	session.Status = ResearchStatusInProgress
-	session.UpdatedAt = time.Now()
+	session.UpdatedAt = a.now()
 	a.researchMutex.Unlock()
 
-	// Use LLM to conduct research based on methodology
-	researchPrompt := fmt.Sprintf(`
-Conduct research on: "%s"
-
-Research parameters:
-- Methodology: %s
-- Depth: %s
-- Focus areas: %v
-- Time limit: %v
-
-Provide a comprehensive research report including:
-1. Executive Summary
-2. Key Findings (with confidence levels)
-3. Supporting Evidence
-4. Potential Sources to Verify
-5. Areas for Further Research
-6. Conclusions and Insights
-
-Structure your response professionally.`, session.Query, session.Methodology.Type, session.Methodology.Depth, session.Scope.Areas, session.Methodology.TimeLimit)
-
-	researchResult, err := a.llmProvider.Query(ctx, researchPrompt)
+	result, err := a.runResearch(ctx, session)
 	if err != nil {
-		// Mark as failed
+		// Mark as failed, keeping the session in the library
 		a.researchMutex.Lock()
 		session.Status = ResearchStatusCancelled
-		session.UpdatedAt = time.Now()
+		session.UpdatedAt = a.now()
 		session.Metadata["error"] = err.Error()
+		snapshot := copySession(session)
 		a.researchMutex.Unlock()
+		if a.memoryStore != nil {
+			a.memoryStore.Store(ctx, researchSessionPrefix+session.ID, snapshot)
+		}
 		return
 	}
+	researchResult := result.Summary
 
-	// Update session with results
+	// Update session with results, its sources and findings for the library
 	a.researchMutex.Lock()
 	session.Status = ResearchStatusCompleted
 	session.Summary = researchResult
-	session.UpdatedAt = time.Now()
+	session.Sources = append(session.Sources, result.Sources...)
+	session.Findings = append(session.Findings, result.Findings...)
+	session.UpdatedAt = a.now()
 	a.researchMutex.Unlock()
 
 	// Save to memory
 	if a.memoryStore != nil {
 		sessionKey := fmt.Sprintf("research_session:%s", session.ID)
 		a.memoryStore.Store(ctx, sessionKey, session)
+		a.indexForRecall(ctx, sessionKey, fmt.Sprintf("Research on %s: %s", session.Topic, researchResult), map[string]interface{}{
+			"research_session_id": session.ID,
+		})
 
 		// Send completion notification
 		completionMsg := &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+			ID:        a.messageID(),
 			From:      a.id,
 			To:        []multiagent.AgentID{session.RequestedBy},
 			Type:      multiagent.MessageTypeNotification,
 			Content:   fmt.Sprintf("🔍 **Research Completed: %s**\n\n%s", session.Topic, researchResult),
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 			Context: map[string]interface{}{
 				"research_session_id": session.ID,
 				"action":              "research_completed",
@@ -665,6 +678,36 @@ Structure your response professionally.`, session.Query, session.Methodology.Typ
 	}
 }
 
+// runResearch gathers and cites sources for session when search tools are
+// available, and otherwise asks the LLM alone
+func (a *ResearchAssistantAgent) runResearch(ctx context.Context, session *ResearchSession) (*researchResult, error) {
+	result, err := a.researchFromSources(ctx, session)
+	if err == nil && result == nil {
+		result, err = a.researchFromLLM(ctx, session)
+	}
+	return result, err
+}
+
+// researchFromLLM researches session with a single prompt, for when no
+// search tools are available to gather sources with
+func (a *ResearchAssistantAgent) researchFromLLM(ctx context.Context, session *ResearchSession) (*researchResult, error) {
+	researchPrompt, err := a.renderPrompt(ctx, "research.conduct", prompts.Vars{
+		"Query":       session.Query,
+		"Methodology": session.Methodology.Type,
+		"Depth":       session.Methodology.Depth,
+		"Areas":       session.Scope.Areas,
+		"TimeLimit":   session.Methodology.TimeLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	report, err := a.llmProvider.Query(ctx, researchPrompt)
+	if err != nil {
+		return nil, err
+	}
+	return &researchResult{Summary: report, Findings: a.extractFindings(session, report)}, nil
+}
+
 // Helper methods
 
 func (a *ResearchAssistantAgent) parsePriority(priority string) multiagent.Priority {
@@ -718,25 +761,58 @@ func (a *ResearchAssistantAgent) formatClaimsForPrompt(claims []struct {
 	return formatted.String()
 }
 
-func (a *ResearchAssistantAgent) buildResearchContext(ctx context.Context, msg *multiagent.Message) string {
-	var contextBuilder strings.Builder
+// PurgeUser forgets userID's research sessions
+func (a *ResearchAssistantAgent) PurgeUser(userID string) int {
+	a.researchMutex.Lock()
+	defer a.researchMutex.Unlock()
+
+	purged := 0
+	for id, session := range a.activeResearch {
+		if session.UserID == userID {
+			delete(a.activeResearch, id)
+			purged++
+		}
+	}
+	return purged
+}
 
-	contextBuilder.WriteString(fmt.Sprintf("You are %s, a research assistant specialist.\n\n", a.name))
-	contextBuilder.WriteString("You help users gather information, verify facts, analyze trends, and synthesize knowledge from various sources.\n\n")
+func (a *ResearchAssistantAgent) buildResearchContext(ctx context.Context, msg *multiagent.Message) (string, error) {
+	notes := a.searchNotes(ctx, msg.Content)
 
-	// Add active research sessions summary
+	// Add active research sessions summary; rendered under the lock, since
+	// the template reads the sessions
 	a.researchMutex.RLock()
-	if len(a.activeResearch) > 0 {
-		contextBuilder.WriteString("Active Research Sessions:\n")
-		for _, session := range a.activeResearch {
-			contextBuilder.WriteString(fmt.Sprintf("- %s (%s) - %s\n", session.Topic, session.Status, session.Methodology.Type))
+	defer a.researchMutex.RUnlock()
+	var sessions []*ResearchSession
+	for _, session := range a.activeResearch {
+		if ownedBy(ctx, session.UserID) {
+			sessions = append(sessions, session)
 		}
-		contextBuilder.WriteString("\n")
 	}
-	a.researchMutex.RUnlock()
 
-	contextBuilder.WriteString(fmt.Sprintf("User request: %s\n\n", msg.Content))
-	contextBuilder.WriteString("Please provide helpful research assistance, information, or analysis as requested.")
+	return a.renderPrompt(ctx, "research.general", prompts.Vars{
+		"Name":     a.name,
+		"Sessions": sessions,
+		"Notes":    notes,
+		"Request":  msg.Content,
+	})
+}
 
-	return contextBuilder.String()
+// searchNotes returns the passages of the user's own notes that bear on
+// query, so answers can draw on them alongside general knowledge, or ""
+// if the user's notes are not indexed or none match
+func (a *ResearchAssistantAgent) searchNotes(ctx context.Context, query string) string {
+	tool, ok := a.tool("notes")
+	if !ok {
+		return ""
+	}
+	result, err := tool.Execute(ctx, query)
+	if err != nil {
+		a.logger.WarnContext(ctx, "Failed to search notes", "error", err)
+		return ""
+	}
+	if strings.HasPrefix(result, "No notes found") {
+		return ""
+	}
+	return result
 }
@@ -0,0 +1,544 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/bibliography"
+)
+
+// Formats a research session's bibliography can be exported in
+const (
+	BibliographyFormatBibTeX   = bibliography.FormatBibTeX
+	BibliographyFormatMarkdown = bibliography.FormatMarkdown
+)
+
+// researchSessionPrefix holds each user's research sessions
+const researchSessionPrefix = "research_session:"
+
+// maxResearchSessions bounds how many sessions the library reads
+const maxResearchSessions = 1000
+
+// ErrResearchNotFound is returned when no research session of the user
+// matches
+var ErrResearchNotFound = errors.New("research session not found")
+
+// ResearchFilter narrows the sessions and findings of the research library
+type ResearchFilter struct {
+	// Query matches words of the topic, query, summary, findings and
+	// sources, case-insensitively
+	Query string
+	// Tag matches sessions, or findings, carrying the tag
+	Tag    string
+	Status ResearchStatus
+}
+
+// FindingMatch is a finding of the research library with the session it
+// came from
+type FindingMatch struct {
+	SessionID string          `json:"session_id"`
+	Topic     string          `json:"topic"`
+	Finding   ResearchFinding `json:"finding"`
+}
+
+// ResearchLibrary is implemented by agents that keep users' past research,
+// so it can be browsed, extended and cited after the sessions complete
+type ResearchLibrary interface {
+	// ListResearch returns the research sessions of the user ctx acts for
+	// that match filter, newest first
+	ListResearch(ctx context.Context, filter ResearchFilter) ([]*ResearchSession, error)
+	// GetResearch returns one of the user's research sessions
+	GetResearch(ctx context.Context, id string) (*ResearchSession, error)
+	// SearchFindings returns the findings of the user's research that match
+	// filter
+	SearchFindings(ctx context.Context, filter ResearchFilter) ([]FindingMatch, error)
+	// AddResearchSource attaches a source, such as a page found with the web
+	// or Wikipedia tools, to a session
+	AddResearchSource(ctx context.Context, id string, source ResearchSource) (*ResearchSession, error)
+	// TagResearch adds tags to a session, or to one of its findings if
+	// findingID is set
+	TagResearch(ctx context.Context, id, findingID string, tags []string) (*ResearchSession, error)
+	// ExportBibliography renders a session's sources in format, one of
+	// BibliographyFormatBibTeX and BibliographyFormatMarkdown
+	ExportBibliography(ctx context.Context, id, format string) ([]byte, error)
+}
+
+// ListResearch returns the user's research sessions matching filter, newest
+// first, from memory so sessions outlive restarts
+func (a *ResearchAssistantAgent) ListResearch(ctx context.Context, filter ResearchFilter) ([]*ResearchSession, error) {
+	sessions, err := a.loadResearch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	matched := make([]*ResearchSession, 0, len(sessions))
+	for _, session := range sessions {
+		if filter.Status != "" && session.Status != filter.Status {
+			continue
+		}
+		if filter.Tag != "" && !hasTag(session.Tags, filter.Tag) {
+			continue
+		}
+		if filter.Query != "" && !matchesQuery(filter.Query, sessionText(session)) {
+			continue
+		}
+		matched = append(matched, session)
+	}
+	return matched, nil
+}
+
+// GetResearch returns one of the user's research sessions
+func (a *ResearchAssistantAgent) GetResearch(ctx context.Context, id string) (*ResearchSession, error) {
+	a.researchMutex.RLock()
+	defer a.researchMutex.RUnlock()
+	session, err := a.findResearch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return copySession(session), nil
+}
+
+// SearchFindings returns the findings of the user's research matching
+// filter, a session's findings in order and sessions newest first
+func (a *ResearchAssistantAgent) SearchFindings(ctx context.Context, filter ResearchFilter) ([]FindingMatch, error) {
+	sessions, err := a.loadResearch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	matches := []FindingMatch{}
+	for _, session := range sessions {
+		if filter.Status != "" && session.Status != filter.Status {
+			continue
+		}
+		for _, finding := range session.Findings {
+			// A session's tags apply to its findings
+			if filter.Tag != "" && !hasTag(finding.Tags, filter.Tag) && !hasTag(session.Tags, filter.Tag) {
+				continue
+			}
+			if filter.Query != "" && !matchesQuery(filter.Query, finding.Finding+" "+strings.Join(finding.Evidence, " ")+" "+strings.Join(finding.Tags, " ")) {
+				continue
+			}
+			matches = append(matches, FindingMatch{SessionID: session.ID, Topic: session.Topic, Finding: finding})
+		}
+	}
+	return matches, nil
+}
+
+// AddResearchSource attaches source to a session; a source of a Wikipedia
+// page is marked as an encyclopedia, any other with a URL as the web
+func (a *ResearchAssistantAgent) AddResearchSource(ctx context.Context, id string, source ResearchSource) (*ResearchSession, error) {
+	source.Title = strings.TrimSpace(source.Title)
+	source.URL = strings.TrimSpace(source.URL)
+	if source.Title == "" && source.URL == "" {
+		return nil, fmt.Errorf("a source needs a title or a URL")
+	}
+	if source.URL != "" {
+		u, err := url.Parse(source.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid source URL %q", source.URL)
+		}
+		if source.Title == "" {
+			source.Title = titleFromURL(u)
+		}
+		if source.Type == "" {
+			source.Type = SourceTypeWeb
+			if strings.HasSuffix(u.Hostname(), "wikipedia.org") {
+				source.Type = SourceTypeEncyclopedia
+			}
+		}
+	}
+	if source.Type == "" {
+		source.Type = SourceTypeInternal
+	}
+
+	return a.updateResearch(ctx, id, func(session *ResearchSession) error {
+		for _, existing := range session.Sources {
+			if source.URL != "" && existing.URL == source.URL {
+				return fmt.Errorf("session %s already cites %s", session.ID, source.URL)
+			}
+		}
+		source.ID = a.newID("source")
+		if source.AccessedAt.IsZero() {
+			source.AccessedAt = a.now()
+		}
+		session.Sources = append(session.Sources, source)
+		return nil
+	})
+}
+
+// TagResearch adds tags to a session or one of its findings, ignoring tags
+// it already carries
+func (a *ResearchAssistantAgent) TagResearch(ctx context.Context, id, findingID string, tags []string) (*ResearchSession, error) {
+	var cleaned []string
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			cleaned = append(cleaned, tag)
+		}
+	}
+	if len(cleaned) == 0 {
+		return nil, fmt.Errorf("at least one tag is required")
+	}
+
+	return a.updateResearch(ctx, id, func(session *ResearchSession) error {
+		if findingID == "" {
+			session.Tags = addTags(session.Tags, cleaned)
+			return nil
+		}
+		for i := range session.Findings {
+			if session.Findings[i].ID == findingID {
+				session.Findings[i].Tags = addTags(session.Findings[i].Tags, cleaned)
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: finding %s of session %s", ErrResearchNotFound, findingID, session.ID)
+	})
+}
+
+// ExportBibliography renders the sources of a session in format
+func (a *ResearchAssistantAgent) ExportBibliography(ctx context.Context, id, format string) ([]byte, error) {
+	session, err := a.GetResearch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return RenderBibliography(session, format)
+}
+
+// RenderBibliography renders session's sources in format
+func RenderBibliography(session *ResearchSession, format string) ([]byte, error) {
+	bib := bibliography.Bibliography{Title: session.Topic}
+	for _, source := range session.Sources {
+		bib.Entries = append(bib.Entries, bibliography.Entry{
+			Kind:      bibliographyKind(source.Type),
+			Title:     source.Title,
+			Author:    source.Author,
+			Publisher: metadataString(source.Metadata, "publisher"),
+			URL:       source.URL,
+			Published: source.PublishedAt,
+			Accessed:  source.AccessedAt,
+		})
+	}
+	var buf bytes.Buffer
+	if err := bibliography.Write(&buf, format, bib); err != nil {
+		return nil, fmt.Errorf("failed to export bibliography of research %s: %w", session.ID, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// handleBibliography replies with the bibliography of the session the
+// message names by topic, or of the latest one with sources, as Markdown or,
+// when asked for, BibTeX
+func (a *ResearchAssistantAgent) handleBibliography(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	sessions, err := a.loadResearch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session := pickSession(sessions, msg.Content)
+	if session == nil {
+		return a.respond(msg, "📚 You have no research with sources to cite yet.", nil), nil
+	}
+
+	format, fence := BibliographyFormatMarkdown, ""
+	if strings.Contains(strings.ToLower(msg.Content), "bibtex") {
+		format, fence = BibliographyFormatBibTeX, "```bibtex\n"
+	}
+	data, err := RenderBibliography(session, format)
+	if err != nil {
+		return nil, err
+	}
+	content := string(data)
+	if fence != "" {
+		content = fence + content + "```"
+	}
+	return a.respond(msg, content, map[string]interface{}{
+		"research_session_id": session.ID,
+		"action":              "bibliography_exported",
+		"format":              format,
+	}), nil
+}
+
+// pickSession returns the session whose topic shares the most words with
+// content, or else the newest one with sources
+func pickSession(sessions []*ResearchSession, content string) *ResearchSession {
+	var best *ResearchSession
+	bestScore := 0
+	terms := recallTerms(content)
+	for _, session := range sessions {
+		score := 0
+		for term := range recallTerms(session.Topic) {
+			if terms[term] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = session, score
+		}
+	}
+	if best != nil {
+		return best
+	}
+	for _, session := range sessions {
+		if len(session.Sources) > 0 {
+			return session
+		}
+	}
+	return nil
+}
+
+// loadResearch reads the user's sessions from memory, preferring the live
+// copies of sessions still under way, newest first
+func (a *ResearchAssistantAgent) loadResearch(ctx context.Context) ([]*ResearchSession, error) {
+	if a.memoryStore == nil {
+		return nil, fmt.Errorf("no memory store for the research library")
+	}
+	keys, err := a.memoryStore.List(ctx, researchSessionPrefix, maxResearchSessions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list research sessions: %w", err)
+	}
+	values := map[string]interface{}{}
+	if len(keys) > 0 {
+		if values, err = a.memoryStore.GetMultiple(ctx, keys); err != nil {
+			return nil, fmt.Errorf("failed to load research sessions: %w", err)
+		}
+	}
+
+	a.researchMutex.RLock()
+	defer a.researchMutex.RUnlock()
+	byID := make(map[string]*ResearchSession)
+	for _, value := range values {
+		if session := decodeSession(value); session != nil && ownedBy(ctx, session.UserID) {
+			byID[session.ID] = session
+		}
+	}
+	for id, session := range a.activeResearch {
+		if ownedBy(ctx, session.UserID) {
+			byID[id] = copySession(session)
+		}
+	}
+
+	sessions := make([]*ResearchSession, 0, len(byID))
+	for _, session := range byID {
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+// findResearch returns the user's session id, live or from memory; the
+// caller holds researchMutex
+func (a *ResearchAssistantAgent) findResearch(ctx context.Context, id string) (*ResearchSession, error) {
+	if session, ok := a.activeResearch[id]; ok && ownedBy(ctx, session.UserID) {
+		return session, nil
+	}
+	if a.memoryStore != nil {
+		if value, err := a.memoryStore.Get(ctx, researchSessionPrefix+id); err == nil {
+			if session := decodeSession(value); session != nil && ownedBy(ctx, session.UserID) {
+				return session, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrResearchNotFound, id)
+}
+
+// updateResearch applies update to the user's session id and saves it
+func (a *ResearchAssistantAgent) updateResearch(ctx context.Context, id string, update func(*ResearchSession) error) (*ResearchSession, error) {
+	a.researchMutex.Lock()
+	session, err := a.findResearch(ctx, id)
+	if err == nil {
+		err = update(session)
+	}
+	if err != nil {
+		a.researchMutex.Unlock()
+		return nil, err
+	}
+	session.UpdatedAt = a.now()
+	snapshot := copySession(session)
+	a.researchMutex.Unlock()
+
+	if a.memoryStore == nil {
+		return snapshot, nil
+	}
+	if err := a.memoryStore.Store(ctx, researchSessionPrefix+snapshot.ID, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to save research session: %w", err)
+	}
+	return snapshot, nil
+}
+
+// keyFindingsHeading starts the findings section of a research report
+var keyFindingsHeading = regexp.MustCompile(`(?i)^[#*\d.\s]*key findings`)
+
+// listItem is a bulleted or numbered line
+var listItem = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s+(.+)$`)
+
+// extractFindings turns the items of a report's "Key Findings" section into
+// findings, reading a stated confidence level where there is one
+func (a *ResearchAssistantAgent) extractFindings(session *ResearchSession, report string) []ResearchFinding {
+	var findings []ResearchFinding
+	inSection := false
+	for _, line := range strings.Split(report, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if keyFindingsHeading.MatchString(trimmed) {
+			inSection = true
+			continue
+		}
+		if !inSection || trimmed == "" {
+			continue
+		}
+		item := listItem.FindStringSubmatch(line)
+		if item == nil {
+			// The next heading ends the section
+			if strings.HasPrefix(trimmed, "#") || (strings.HasPrefix(trimmed, "**") && strings.HasSuffix(trimmed, "**")) {
+				if len(findings) > 0 {
+					break
+				}
+			}
+			continue
+		}
+		text := strings.TrimSpace(strings.Trim(item[1], "*"))
+		findings = append(findings, ResearchFinding{
+			ID:         a.newID("finding"),
+			Topic:      session.Topic,
+			Finding:    text,
+			Confidence: statedConfidence(text),
+			Evidence:   []string{},
+			Sources:    []string{},
+			Tags:       []string{},
+			CreatedAt:  a.now(),
+			Metadata:   map[string]interface{}{},
+		})
+	}
+	return findings
+}
+
+// statedConfidence reads "high", "medium" or "low" confidence from text
+func statedConfidence(text string) float64 {
+	lower := strings.ToLower(text)
+	if !strings.Contains(lower, "confidence") {
+		return 0
+	}
+	switch {
+	case strings.Contains(lower, "high"):
+		return 0.9
+	case strings.Contains(lower, "medium"), strings.Contains(lower, "moderate"):
+		return 0.6
+	case strings.Contains(lower, "low"):
+		return 0.3
+	}
+	return 0
+}
+
+// decodeSession converts a session read back from memory
+func decodeSession(value interface{}) *ResearchSession {
+	if session, ok := value.(*ResearchSession); ok {
+		return copySession(session)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var session ResearchSession
+	if err := json.Unmarshal(data, &session); err != nil || session.ID == "" {
+		return nil
+	}
+	return &session
+}
+
+// copySession returns a copy of session that shares nothing mutable with it
+func copySession(session *ResearchSession) *ResearchSession {
+	data, err := json.Marshal(session)
+	if err != nil {
+		snapshot := *session
+		return &snapshot
+	}
+	var snapshot ResearchSession
+	json.Unmarshal(data, &snapshot)
+	return &snapshot
+}
+
+// sessionText is what a library query is matched against
+func sessionText(session *ResearchSession) string {
+	parts := []string{session.Topic, session.Query, session.Summary, strings.Join(session.Tags, " ")}
+	for _, finding := range session.Findings {
+		parts = append(parts, finding.Finding, strings.Join(finding.Tags, " "))
+	}
+	for _, source := range session.Sources {
+		parts = append(parts, source.Title, source.Author, source.Summary)
+	}
+	return strings.Join(parts, " ")
+}
+
+// matchesQuery reports whether text contains every word of query
+func matchesQuery(query, text string) bool {
+	text = strings.ToLower(text)
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func addTags(tags, add []string) []string {
+	for _, tag := range add {
+		if !hasTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// titleFromURL names a source by its URL's last path segment, so
+// ".../wiki/Heat_pump" is "Heat pump"
+func titleFromURL(u *url.URL) string {
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if last := segments[len(segments)-1]; last != "" {
+		if unescaped, err := url.PathUnescape(last); err == nil {
+			last = unescaped
+		}
+		return strings.ReplaceAll(last, "_", " ")
+	}
+	return u.Hostname()
+}
+
+// bibliographyKind maps a source type onto a bibliography entry kind
+func bibliographyKind(sourceType SourceType) bibliography.Kind {
+	switch sourceType {
+	case SourceTypeAcademic, SourceTypeArticle:
+		return bibliography.KindArticle
+	case SourceTypeBook:
+		return bibliography.KindBook
+	case SourceTypeReport:
+		return bibliography.KindReport
+	case SourceTypeEncyclopedia:
+		return bibliography.KindEncyclopedia
+	default:
+		return bibliography.KindWeb
+	}
+}
+
+func metadataString(metadata map[string]interface{}, key string) string {
+	if value, ok := metadata[key].(string); ok {
+		return value
+	}
+	return ""
+}
@@ -0,0 +1,499 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+const (
+	// researchMonitorPrefix holds each user's monitored research topics
+	researchMonitorPrefix = "research_monitor:"
+	// DefaultMonitorInterval is how often a topic is re-researched when the
+	// user does not say
+	DefaultMonitorInterval = 24 * time.Hour
+	// MinMonitorInterval bounds how often a topic may be re-researched
+	MinMonitorInterval = time.Hour
+	// maxKnownFindings bounds the findings a monitor remembers, newest kept
+	maxKnownFindings = 100
+	// maxMonitorUpdates bounds the updates a monitor keeps
+	maxMonitorUpdates = 20
+)
+
+// ErrMonitorNotFound is returned when no research monitor of the user
+// matches
+var ErrMonitorNotFound = errors.New("research monitor not found")
+
+// ResearchMonitor re-runs a saved research query on an interval and
+// reports what it finds that is materially new
+type ResearchMonitor struct {
+	ID string `json:"id"`
+	// SessionID is the research the monitor was started from
+	SessionID   string              `json:"session_id"`
+	Topic       string              `json:"topic"`
+	Query       string              `json:"query"`
+	Methodology ResearchMethodology `json:"methodology"`
+	Scope       ResearchScope       `json:"scope"`
+	Interval    time.Duration       `json:"interval"`
+	CreatedAt   time.Time           `json:"created_at"`
+	LastRun     *time.Time          `json:"last_run,omitempty"`
+	NextRun     time.Time           `json:"next_run"`
+	// Known are the findings seen so far, oldest first, which each run's
+	// findings are compared with
+	Known   []string        `json:"known"`
+	Updates []MonitorUpdate `json:"updates"`
+	UserID  string          `json:"user_id,omitempty"`
+}
+
+// MonitorUpdate is materially new information a run of a monitor found
+type MonitorUpdate struct {
+	At time.Time `json:"at"`
+	// SessionID is the research session the run was kept as
+	SessionID string   `json:"session_id"`
+	Findings  []string `json:"findings"`
+	Summary   string   `json:"summary"`
+}
+
+// MonitorAlert is an update the user should be told about
+type MonitorAlert struct {
+	MonitorID string        `json:"monitor_id"`
+	Topic     string        `json:"topic"`
+	Update    MonitorUpdate `json:"update"`
+}
+
+// ResearchMonitoring is implemented by agents that re-run research on a
+// schedule
+type ResearchMonitoring interface {
+	// MonitorResearch re-runs the research session id every interval,
+	// DefaultMonitorInterval if zero
+	MonitorResearch(ctx context.Context, id string, interval time.Duration) (*ResearchMonitor, error)
+	ListMonitors(ctx context.Context) ([]*ResearchMonitor, error)
+	StopMonitor(ctx context.Context, id string) error
+	// RunDueMonitors re-runs the monitors of the user ctx acts for that are
+	// due, returning the materially new information they found
+	RunDueMonitors(ctx context.Context) ([]MonitorAlert, error)
+}
+
+// MonitorResearch starts monitoring the research session id; what the
+// session found is the baseline later runs are compared with
+func (a *ResearchAssistantAgent) MonitorResearch(ctx context.Context, id string, interval time.Duration) (*ResearchMonitor, error) {
+	if interval == 0 {
+		interval = DefaultMonitorInterval
+	}
+	if interval < MinMonitorInterval {
+		return nil, fmt.Errorf("monitors run at most every %v", MinMonitorInterval)
+	}
+	session, err := a.GetResearch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	monitor := &ResearchMonitor{
+		ID:          a.newID("monitor"),
+		SessionID:   session.ID,
+		Topic:       session.Topic,
+		Query:       session.Query,
+		Methodology: session.Methodology,
+		Scope:       session.Scope,
+		Interval:    interval,
+		CreatedAt:   a.now(),
+		NextRun:     a.now().Add(interval),
+		Known:       []string{},
+		Updates:     []MonitorUpdate{},
+		UserID:      multiagent.UserIDFromContext(ctx),
+	}
+	for _, finding := range session.Findings {
+		monitor.Known = append(monitor.Known, finding.Finding)
+	}
+	if err := a.storeMonitor(ctx, monitor); err != nil {
+		return nil, err
+	}
+	return monitor, nil
+}
+
+// ListMonitors returns the user's research monitors, next due first
+func (a *ResearchAssistantAgent) ListMonitors(ctx context.Context) ([]*ResearchMonitor, error) {
+	if a.memoryStore == nil {
+		return nil, fmt.Errorf("no memory store for research monitors")
+	}
+	keys, err := a.memoryStore.List(ctx, researchMonitorPrefix, maxResearchSessions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list research monitors: %w", err)
+	}
+	values := map[string]interface{}{}
+	if len(keys) > 0 {
+		if values, err = a.memoryStore.GetMultiple(ctx, keys); err != nil {
+			return nil, fmt.Errorf("failed to load research monitors: %w", err)
+		}
+	}
+
+	monitors := []*ResearchMonitor{}
+	for _, value := range values {
+		if monitor := decodeMonitor(value); monitor != nil && ownedBy(ctx, monitor.UserID) {
+			monitors = append(monitors, monitor)
+		}
+	}
+	sort.Slice(monitors, func(i, j int) bool {
+		if !monitors[i].NextRun.Equal(monitors[j].NextRun) {
+			return monitors[i].NextRun.Before(monitors[j].NextRun)
+		}
+		return monitors[i].ID < monitors[j].ID
+	})
+	return monitors, nil
+}
+
+// StopMonitor stops and forgets the monitor id
+func (a *ResearchAssistantAgent) StopMonitor(ctx context.Context, id string) error {
+	if _, err := a.findMonitor(ctx, id); err != nil {
+		return err
+	}
+	if err := a.memoryStore.Delete(ctx, researchMonitorPrefix+id); err != nil {
+		return fmt.Errorf("failed to delete research monitor: %w", err)
+	}
+	return nil
+}
+
+// RunDueMonitors re-runs each of the user's monitors whose time has come.
+// A monitor that fails is retried at its next run.
+func (a *ResearchAssistantAgent) RunDueMonitors(ctx context.Context) ([]MonitorAlert, error) {
+	monitors, err := a.ListMonitors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var alerts []MonitorAlert
+	for _, monitor := range monitors {
+		if ctx.Err() != nil {
+			break
+		}
+		if monitor.NextRun.After(a.now()) {
+			continue
+		}
+		update, err := a.runMonitor(ctx, monitor)
+		if err != nil {
+			a.logger.WarnContext(ctx, "Failed to re-run monitored research", "monitor_id", monitor.ID, "error", err)
+		}
+
+		// The monitor may have been stopped while it ran
+		if _, err := a.findMonitor(ctx, monitor.ID); err != nil {
+			continue
+		}
+		if err := a.storeMonitor(ctx, monitor); err != nil {
+			return alerts, err
+		}
+		if update != nil {
+			alerts = append(alerts, MonitorAlert{MonitorID: monitor.ID, Topic: monitor.Topic, Update: *update})
+		}
+	}
+	return alerts, nil
+}
+
+// runMonitor re-runs monitor's research and compares its findings with the
+// ones already known. Findings that only restate known ones are dropped,
+// and the LLM judges whether the rest are materially new; only then is
+// the run kept in the library and an update returned.
+func (a *ResearchAssistantAgent) runMonitor(ctx context.Context, monitor *ResearchMonitor) (*MonitorUpdate, error) {
+	now := a.now()
+	monitor.LastRun = &now
+	monitor.NextRun = now.Add(monitor.Interval)
+
+	session := &ResearchSession{
+		ID:          a.newID("research"),
+		Topic:       monitor.Topic,
+		Query:       monitor.Query,
+		Status:      ResearchStatusInProgress,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Sources:     []ResearchSource{},
+		Findings:    []ResearchFinding{},
+		Tags:        []string{"monitor"},
+		Priority:    multiagent.PriorityMedium,
+		RequestedBy: a.id,
+		Methodology: monitor.Methodology,
+		Scope:       monitor.Scope,
+		Metadata:    map[string]interface{}{"monitor_id": monitor.ID},
+		UserID:      monitor.UserID,
+	}
+	result, err := a.runResearch(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []string
+	for _, finding := range result.Findings {
+		if !restatesAny(monitor.Known, finding.Finding) {
+			candidates = append(candidates, finding.Finding)
+		}
+	}
+	var materiallyNew []string
+	var summary string
+	if len(candidates) > 0 {
+		materiallyNew, summary, err = a.judgeNewFindings(ctx, monitor, candidates)
+		if err != nil {
+			// Judged again next run, rather than alerting on restatements
+			return nil, err
+		}
+	}
+	for _, finding := range result.Findings {
+		monitor.Known = append(monitor.Known, finding.Finding)
+	}
+	if len(monitor.Known) > maxKnownFindings {
+		monitor.Known = monitor.Known[len(monitor.Known)-maxKnownFindings:]
+	}
+	if len(materiallyNew) == 0 {
+		return nil, nil
+	}
+
+	session.Status = ResearchStatusCompleted
+	session.Summary = result.Summary
+	session.Sources = append(session.Sources, result.Sources...)
+	session.Findings = append(session.Findings, result.Findings...)
+	session.UpdatedAt = a.now()
+	if err := a.memoryStore.Store(ctx, researchSessionPrefix+session.ID, session); err != nil {
+		return nil, fmt.Errorf("failed to store research session: %w", err)
+	}
+
+	update := MonitorUpdate{At: now, SessionID: session.ID, Findings: materiallyNew, Summary: summary}
+	monitor.Updates = append(monitor.Updates, update)
+	if len(monitor.Updates) > maxMonitorUpdates {
+		monitor.Updates = monitor.Updates[len(monitor.Updates)-maxMonitorUpdates:]
+	}
+	return &update, nil
+}
+
+// judgeNewFindings asks the LLM which of candidates are materially new
+// compared with what monitor already knows
+func (a *ResearchAssistantAgent) judgeNewFindings(ctx context.Context, monitor *ResearchMonitor, candidates []string) ([]string, string, error) {
+	// Newest first, so the oldest are left out of a prompt over budget
+	known := make([]string, len(monitor.Known))
+	for i, finding := range monitor.Known {
+		known[len(known)-1-i] = finding
+	}
+	type numbered struct {
+		Number int
+		Text   string
+	}
+	findings := make([]numbered, len(candidates))
+	for i, candidate := range candidates {
+		findings[i] = numbered{Number: i + 1, Text: candidate}
+	}
+
+	judgePrompt, err := a.renderPrompt(ctx, "research.monitor", prompts.Vars{
+		"Topic":    monitor.Topic,
+		"Query":    monitor.Query,
+		"Known":    known,
+		"Findings": findings,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	var judgement struct {
+		New     []int  `json:"new"`
+		Summary string `json:"summary"`
+	}
+	if err := a.queryJSON(ctx, judgePrompt, objectSchema(map[string]string{"new": "array", "summary": "string"}, "new"), &judgement); err != nil {
+		return nil, "", fmt.Errorf("failed to compare findings: %w", err)
+	}
+
+	var materiallyNew []string
+	for _, number := range judgement.New {
+		if number >= 1 && number <= len(candidates) && !slices.Contains(materiallyNew, candidates[number-1]) {
+			materiallyNew = append(materiallyNew, candidates[number-1])
+		}
+	}
+	return materiallyNew, strings.TrimSpace(judgement.Summary), nil
+}
+
+// handleMonitor starts or stops monitoring the research a message names,
+// or the latest research
+func (a *ResearchAssistantAgent) handleMonitor(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	content := strings.ToLower(msg.Content)
+	if strings.Contains(content, "stop") || strings.Contains(content, "cancel") {
+		monitors, err := a.ListMonitors(ctx)
+		if err != nil {
+			return nil, err
+		}
+		monitor := pickMonitor(monitors, msg.Content)
+		if monitor == nil {
+			return a.respond(msg, "🔭 You are not monitoring any research topics.", nil), nil
+		}
+		if allowed, reply := a.authorize(ctx, msg, ActionStopResearchMonitor, monitor.ID, fmt.Sprintf("stop monitoring %s", monitor.Topic)); !allowed {
+			return reply, nil
+		}
+		if err := a.StopMonitor(ctx, monitor.ID); err != nil {
+			return nil, err
+		}
+		return a.respond(msg, fmt.Sprintf("🔭 Stopped monitoring %s.", monitor.Topic), map[string]interface{}{
+			"monitor_id": monitor.ID,
+			"action":     "monitor_stopped",
+		}), nil
+	}
+
+	sessions, err := a.loadResearch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session := pickSession(sessions, msg.Content)
+	if session == nil && len(sessions) > 0 {
+		session = sessions[0]
+	}
+	if session == nil {
+		return a.respond(msg, "🔭 Research the topic first, then ask me to monitor it.", nil), nil
+	}
+	monitor, err := a.MonitorResearch(ctx, session.ID, monitorInterval(content))
+	if err != nil {
+		return a.respond(msg, fmt.Sprintf("🔭 I couldn't monitor %s: %v", session.Topic, err), nil), nil
+	}
+	return a.respond(msg, fmt.Sprintf("🔭 Monitoring %s: I'll re-run this research every %s and tell you only when there is something materially new.", monitor.Topic, formatInterval(monitor.Interval)), map[string]interface{}{
+		"monitor_id":          monitor.ID,
+		"research_session_id": session.ID,
+		"action":              "monitor_started",
+	}), nil
+}
+
+var monitorEvery = regexp.MustCompile(`\bevery\s+(\d+\s+)?(hour|day|week)s?\b|\b(hourly|daily|weekly)\b`)
+
+// monitorInterval reads how often a topic should be re-researched from a
+// request such as "monitor this every 2 days", or returns 0
+func monitorInterval(content string) time.Duration {
+	match := monitorEvery.FindStringSubmatch(content)
+	if match == nil {
+		return 0
+	}
+	count := 1
+	if n, err := strconv.Atoi(strings.TrimSpace(match[1])); err == nil && n > 0 {
+		count = n
+	}
+	unit := match[2]
+	if unit == "" {
+		unit = map[string]string{"hourly": "hour", "daily": "day", "weekly": "week"}[match[3]]
+	}
+	switch unit {
+	case "hour":
+		return time.Duration(count) * time.Hour
+	case "week":
+		return time.Duration(count) * 7 * 24 * time.Hour
+	default:
+		return time.Duration(count) * 24 * time.Hour
+	}
+}
+
+// formatInterval describes an interval as "day", "3 days" or "6 hours"
+func formatInterval(interval time.Duration) string {
+	unit, size := "hour", time.Hour
+	if interval%(24*time.Hour) == 0 {
+		unit, size = "day", 24*time.Hour
+	}
+	if interval%(7*24*time.Hour) == 0 {
+		unit, size = "week", 7*24*time.Hour
+	}
+	if n := int(interval / size); n != 1 {
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	return unit
+}
+
+// pickMonitor returns the monitor whose topic the content names most, or
+// the only one
+func pickMonitor(monitors []*ResearchMonitor, content string) *ResearchMonitor {
+	var best *ResearchMonitor
+	bestScore := 0
+	terms := recallTerms(content)
+	for _, monitor := range monitors {
+		score := 0
+		for term := range recallTerms(monitor.Topic) {
+			if terms[term] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = monitor, score
+		}
+	}
+	if best == nil && len(monitors) == 1 {
+		return monitors[0]
+	}
+	return best
+}
+
+var number = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+
+// restatesAny reports whether finding only restates one of known: it
+// mentions no number they do not, and nearly all its words are in it
+func restatesAny(known []string, finding string) bool {
+	terms := recallTerms(finding)
+	numbers := number.FindAllString(finding, -1)
+	for _, k := range known {
+		knownNumbers := number.FindAllString(k, -1)
+		sameNumbers := true
+		for _, n := range numbers {
+			if !slices.Contains(knownNumbers, n) {
+				sameNumbers = false
+				break
+			}
+		}
+		if !sameNumbers {
+			continue
+		}
+		knownTerms := recallTerms(k)
+		shared := 0
+		for term := range terms {
+			if knownTerms[term] {
+				shared++
+			}
+		}
+		if len(terms) == 0 || float64(shared) >= 0.8*float64(len(terms)) {
+			return true
+		}
+	}
+	return false
+}
+
+// findMonitor returns the user's monitor id
+func (a *ResearchAssistantAgent) findMonitor(ctx context.Context, id string) (*ResearchMonitor, error) {
+	if a.memoryStore == nil {
+		return nil, fmt.Errorf("no memory store for research monitors")
+	}
+	value, err := a.memoryStore.Get(ctx, researchMonitorPrefix+id)
+	if err != nil {
+		return nil, ErrMonitorNotFound
+	}
+	monitor := decodeMonitor(value)
+	if monitor == nil || !ownedBy(ctx, monitor.UserID) {
+		return nil, ErrMonitorNotFound
+	}
+	return monitor, nil
+}
+
+// storeMonitor saves monitor in the user's memory
+func (a *ResearchAssistantAgent) storeMonitor(ctx context.Context, monitor *ResearchMonitor) error {
+	if a.memoryStore == nil {
+		return fmt.Errorf("no memory store for research monitors")
+	}
+	if err := a.memoryStore.Store(ctx, researchMonitorPrefix+monitor.ID, monitor); err != nil {
+		return fmt.Errorf("failed to store research monitor: %w", err)
+	}
+	return nil
+}
+
+// decodeMonitor reads a monitor stored in memory, or returns nil
+func decodeMonitor(value interface{}) *ResearchMonitor {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var monitor ResearchMonitor
+	if err := json.Unmarshal(data, &monitor); err != nil || monitor.ID == "" {
+		return nil
+	}
+	return &monitor
+}
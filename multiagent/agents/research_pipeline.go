@@ -0,0 +1,335 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+	"github.com/kbutz/wikillm/multiagent/search"
+)
+
+// researchSearchTools are the tools research gathers sources with, in the
+// order each sub-query is searched
+var researchSearchTools = []string{search.WebToolName, search.WikipediaToolName}
+
+const (
+	// maxSubQueries bounds the searches a question is broken into
+	maxSubQueries = 4
+	// resultsPerSearch is how many results each tool is asked for
+	resultsPerSearch = 5
+	// maxCitedSources bounds the sources a report is synthesized from
+	maxCitedSources = 12
+)
+
+// researchResult is what a session's research produced
+type researchResult struct {
+	Summary  string
+	Sources  []ResearchSource
+	Findings []ResearchFinding
+}
+
+// candidateSource is a search result, with every sub-query that found it
+type candidateSource struct {
+	search.Result
+	Tool    string
+	Queries []string
+	// rank is the best of the result's scores relative to the others of its
+	// search, 0-1
+	rank      float64
+	relevance float64
+}
+
+// citedSource numbers a source for the synthesis prompt
+type citedSource struct {
+	Number  int
+	Title   string
+	URL     string
+	Snippet string
+}
+
+// researchFromSources researches session with the search tools: the
+// question is broken into sub-queries, each is searched with every tool,
+// the results are deduplicated and scored, and the best are synthesized
+// into a report citing them. It returns nil when there are no search
+// tools or they found nothing, leaving the research to the LLM alone.
+func (a *ResearchAssistantAgent) researchFromSources(ctx context.Context, session *ResearchSession) (*researchResult, error) {
+	var tools []multiagent.Tool
+	for _, name := range researchSearchTools {
+		if tool, ok := a.tool(name); ok {
+			tools = append(tools, tool)
+		}
+	}
+	if len(tools) == 0 {
+		return nil, nil
+	}
+
+	queries := a.decomposeQuestion(ctx, session)
+	candidates := a.gatherSources(ctx, tools, queries)
+	if len(candidates) == 0 {
+		a.logger.InfoContext(ctx, "Search found no sources, researching without them", "research_session_id", session.ID)
+		return nil, nil
+	}
+	scoreSources(candidates, session.Query+" "+session.Topic, len(queries))
+
+	limit := session.Methodology.SourceLimit
+	if limit <= 0 || limit > maxCitedSources {
+		limit = maxCitedSources
+	}
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	a.researchMutex.Lock()
+	session.Status = ResearchStatusAnalyzing
+	session.UpdatedAt = a.now()
+	a.researchMutex.Unlock()
+
+	result := &researchResult{}
+	cited := make([]citedSource, len(candidates))
+	for i, candidate := range candidates {
+		result.Sources = append(result.Sources, a.researchSource(candidate))
+		cited[i] = citedSource{Number: i + 1, Title: candidate.Title, URL: candidate.URL, Snippet: candidate.Snippet}
+	}
+
+	synthesisPrompt, err := a.renderPrompt(ctx, "research.synthesize", prompts.Vars{
+		"Query":       session.Query,
+		"Methodology": session.Methodology.Type,
+		"Depth":       session.Methodology.Depth,
+		"Areas":       session.Scope.Areas,
+		"Sources":     cited,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var synthesis struct {
+		Summary  string `json:"summary"`
+		Findings []struct {
+			Finding    string   `json:"finding"`
+			Confidence float64  `json:"confidence"`
+			Sources    []int    `json:"sources"`
+			Evidence   []string `json:"evidence"`
+		} `json:"findings"`
+	}
+	synthesisSchema := objectSchema(map[string]string{
+		"summary":  "string",
+		"findings": "array",
+	}, "summary")
+	if err := a.queryJSON(ctx, synthesisPrompt, synthesisSchema, &synthesis); err != nil {
+		return nil, fmt.Errorf("failed to synthesize research: %w", err)
+	}
+
+	result.Summary = strings.TrimSpace(synthesis.Summary) + "\n\n" + sourceList(result.Sources)
+	for _, item := range synthesis.Findings {
+		text := strings.TrimSpace(item.Finding)
+		if text == "" {
+			continue
+		}
+		sourceIDs := []string{}
+		for _, number := range item.Sources {
+			if number >= 1 && number <= len(result.Sources) {
+				sourceIDs = append(sourceIDs, result.Sources[number-1].ID)
+			}
+		}
+		evidence := item.Evidence
+		if evidence == nil {
+			evidence = []string{}
+		}
+		result.Findings = append(result.Findings, ResearchFinding{
+			ID:         a.newID("finding"),
+			Topic:      session.Topic,
+			Finding:    text,
+			Evidence:   evidence,
+			Confidence: math.Max(0, math.Min(1, item.Confidence)),
+			Sources:    sourceIDs,
+			Tags:       []string{},
+			CreatedAt:  a.now(),
+			Metadata:   map[string]interface{}{},
+		})
+	}
+	if len(result.Findings) == 0 {
+		result.Findings = a.extractFindings(session, synthesis.Summary)
+	}
+	return result, nil
+}
+
+// decomposeQuestion breaks session's question into the searches that would
+// answer it, falling back to searching for the question itself
+func (a *ResearchAssistantAgent) decomposeQuestion(ctx context.Context, session *ResearchSession) []string {
+	question := session.Query
+	if strings.TrimSpace(question) == "" {
+		question = session.Topic
+	}
+	limit := maxSubQueries
+	if session.Methodology.Type == MethodologyQuick {
+		limit = 2
+	}
+
+	var decomposition struct {
+		SubQueries []string `json:"sub_queries"`
+	}
+	decomposePrompt, err := a.renderPrompt(ctx, "research.decompose", prompts.Vars{
+		"Query": question,
+		"Areas": session.Scope.Areas,
+		"Max":   limit,
+	})
+	if err == nil {
+		err = a.queryJSON(ctx, decomposePrompt, objectSchema(map[string]string{"sub_queries": "array"}, "sub_queries"), &decomposition)
+	}
+	if err != nil {
+		a.logger.WarnContext(ctx, "Failed to break research question into searches", "error", err)
+	}
+
+	var queries []string
+	seen := make(map[string]bool)
+	for _, query := range append(decomposition.SubQueries, question) {
+		query = strings.TrimSpace(query)
+		if query == "" || seen[strings.ToLower(query)] || len(queries) == limit {
+			continue
+		}
+		seen[strings.ToLower(query)] = true
+		queries = append(queries, query)
+	}
+	return queries
+}
+
+// gatherSources searches every query with every tool, merging results that
+// point at the same page. A search that fails is skipped.
+func (a *ResearchAssistantAgent) gatherSources(ctx context.Context, tools []multiagent.Tool, queries []string) []*candidateSource {
+	var candidates []*candidateSource
+	byKey := make(map[string]*candidateSource)
+	for _, query := range queries {
+		for _, tool := range tools {
+			args, _ := json.Marshal(map[string]interface{}{"query": query, "limit": resultsPerSearch})
+			output, err := tool.Execute(ctx, string(args))
+			var results []search.Result
+			if err == nil {
+				results, err = search.ParseResults(output)
+			}
+			if err != nil {
+				a.logger.WarnContext(ctx, "Research search failed", "tool", tool.Name(), "query", query, "error", err)
+				continue
+			}
+
+			best := 0.0
+			for _, result := range results {
+				best = math.Max(best, result.Score)
+			}
+			for i, result := range results {
+				if strings.TrimSpace(result.Title) == "" && result.URL == "" {
+					continue
+				}
+				rank := 1 / float64(i+1)
+				if best > 0 {
+					rank = result.Score / best
+				}
+				key := sourceKey(result)
+				if candidate, ok := byKey[key]; ok {
+					candidate.rank = math.Max(candidate.rank, rank)
+					if !slices.Contains(candidate.Queries, query) {
+						candidate.Queries = append(candidate.Queries, query)
+					}
+					if len(result.Snippet) > len(candidate.Snippet) {
+						candidate.Snippet = result.Snippet
+					}
+					continue
+				}
+				if result.Source == "" {
+					result.Source = tool.Name()
+				}
+				candidate := &candidateSource{Result: result, Tool: tool.Name(), Queries: []string{query}, rank: rank}
+				byKey[key] = candidate
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	return candidates
+}
+
+// scoreSources rates how relevant each candidate is to question, from how
+// highly its searches ranked it, how many of the queries found it and how
+// many of the question's words it mentions, and sorts them best first
+func scoreSources(candidates []*candidateSource, question string, queries int) {
+	var terms []string
+	for _, word := range strings.Fields(strings.ToLower(question)) {
+		word = strings.Trim(word, `.,;:!?"'()`)
+		if len(word) > 3 && !slices.Contains(terms, word) {
+			terms = append(terms, word)
+		}
+	}
+	for _, candidate := range candidates {
+		coverage := float64(len(candidate.Queries)) / float64(max(queries, 1))
+		overlap := 0.0
+		if len(terms) > 0 {
+			text := strings.ToLower(candidate.Title + " " + candidate.Snippet)
+			for _, term := range terms {
+				if strings.Contains(text, term) {
+					overlap++
+				}
+			}
+			overlap /= float64(len(terms))
+		}
+		candidate.relevance = math.Min(1, 0.5*candidate.rank+0.25*coverage+0.25*overlap)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].relevance > candidates[j].relevance
+	})
+}
+
+// researchSource records a candidate as a session's source
+func (a *ResearchAssistantAgent) researchSource(candidate *candidateSource) ResearchSource {
+	sourceType, reliability := SourceTypeWeb, 0.5
+	if candidate.Source == search.SourceWikipedia {
+		sourceType, reliability = SourceTypeEncyclopedia, 0.7
+	}
+	return ResearchSource{
+		ID:          a.newID("source"),
+		Type:        sourceType,
+		Title:       candidate.Title,
+		URL:         candidate.URL,
+		AccessedAt:  a.now(),
+		Reliability: reliability,
+		Relevance:   math.Round(candidate.relevance*100) / 100,
+		Summary:     candidate.Snippet,
+		KeyPoints:   []string{},
+		Citations:   []string{},
+		Metadata: map[string]interface{}{
+			"tool":    candidate.Tool,
+			"queries": candidate.Queries,
+		},
+	}
+}
+
+// sourceList renders sources as the numbered list a report cites
+func sourceList(sources []ResearchSource) string {
+	var list strings.Builder
+	list.WriteString("**Sources**\n")
+	for i, source := range sources {
+		fmt.Fprintf(&list, "[%d] %s", i+1, source.Title)
+		if source.URL != "" {
+			fmt.Fprintf(&list, " - %s", source.URL)
+		}
+		list.WriteString("\n")
+	}
+	return strings.TrimSuffix(list.String(), "\n")
+}
+
+// sourceKey identifies the page a result points at, ignoring the scheme,
+// "www." and trailing slashes and fragments of its URL
+func sourceKey(result search.Result) string {
+	u, err := url.Parse(strings.TrimSpace(result.URL))
+	if err != nil || u.Host == "" {
+		return "title:" + strings.ToLower(strings.TrimSpace(result.Title))
+	}
+	key := strings.TrimPrefix(strings.ToLower(u.Host), "www.") + strings.TrimSuffix(u.Path, "/")
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
 )
 
 // SchedulerAgent specializes in calendar management, appointment scheduling, and time planning
@@ -18,6 +21,8 @@ type SchedulerAgent struct {
 	calendar      map[string]*CalendarEvent
 	schedules     map[string]*Schedule
 	scheduleMutex sync.RWMutex
+	defaultHours  WorkingHours
+	intents       *IntentRouter
 }
 
 // CalendarEvent represents a scheduled event
@@ -44,6 +49,7 @@ type CalendarEvent struct {
 	URL           string                 `json:"url,omitempty"`
 	ConferenceURL string                 `json:"conference_url,omitempty"`
 	Metadata      map[string]interface{} `json:"metadata"`
+	UserID        string                 `json:"user_id,omitempty"`
 }
 
 // EventCategory defines different types of events
@@ -111,6 +117,9 @@ type EventReminder struct {
 	Method   ReminderMethod `json:"method"`
 	Message  string         `json:"message"`
 	Sent     bool           `json:"sent"`
+	// SentFor is the start of the occurrence a recurring event's reminder
+	// last fired for
+	SentFor *time.Time `json:"sent_for,omitempty"`
 }
 
 // ReminderMethod defines how reminders are delivered
@@ -238,6 +247,7 @@ type SchedulePreferences struct {
 	FocusTimeBlocks          []TimeSlot           `json:"focus_time_blocks"`
 	LunchBreak               *TimeSlot            `json:"lunch_break,omitempty"`
 	Notifications            NotificationSettings `json:"notifications"`
+	Travel                   TravelRules          `json:"travel"`
 }
 
 // TimeSlot represents a preferred time slot
@@ -266,6 +276,7 @@ type ScheduleTimeRange struct {
 func NewSchedulerAgent(config BaseAgentConfig) *SchedulerAgent {
 	// Ensure the agent type is correct
 	config.Type = multiagent.AgentTypeScheduler
+	ensureReminderEngine(&config)
 
 	// Add scheduling capabilities
 	config.Capabilities = append(config.Capabilities,
@@ -281,11 +292,38 @@ func NewSchedulerAgent(config BaseAgentConfig) *SchedulerAgent {
 		"recurring_events",
 	)
 
-	return &SchedulerAgent{
-		BaseAgent: NewBaseAgent(config),
-		calendar:  make(map[string]*CalendarEvent),
-		schedules: make(map[string]*Schedule),
+	agent := &SchedulerAgent{
+		BaseAgent:    NewBaseAgent(config),
+		calendar:     make(map[string]*CalendarEvent),
+		schedules:    make(map[string]*Schedule),
+		defaultHours: StandardWorkingHours(),
+		intents: NewIntentRouter(IntentRouterConfig{
+			Name:        "SchedulerAgent",
+			LLMProvider: routingProvider(config),
+			Prompts:     config.Prompts,
+			Default:     "general",
+			Intents: []Intent{
+				{Label: "schedule_event", Description: "create a new meeting, appointment, or event", Keywords: []string{"schedule&meeting", "schedule&appointment"}},
+				{Label: "set_preferences", Description: "set working hours or scheduling preferences such as buffers, lunch, focus blocks or a meeting limit", Keywords: []string{"working hours", "work hours", "scheduling preferences", "meetings per day", "buffer between", "travel&takes", "travel&minutes", "avoid&back"}},
+				{Label: "check_travel", Description: "check for travel time and breaks between events, adding travel blocks", Keywords: []string{"travel time", "back-to-back", "back to back"}},
+				{Label: "participant_busy", Description: "record when another person is busy", Keywords: []string{"is busy", "are busy", "busy times"}},
+				{Label: "check_availability", Description: "ask when the user or someone is free, or for the best time to meet", Keywords: []string{"availability", "free time", "available", "best time", "suggest&time"}},
+				{Label: "cancel_event", Description: "cancel an existing meeting or appointment", Keywords: []string{"cancel&meeting", "cancel&appointment"}},
+				{Label: "reschedule", Description: "move an existing calendar event to another time", Keywords: []string{"reschedule", "move&meeting", "move&appointment", "move&event"}},
+				{Label: "set_timezone", Description: "tell the assistant which timezone the user is in", Keywords: []string{"timezone", "time zone"}},
+				{Label: "export_calendar", Description: "export the calendar as an .ics / iCalendar file", Keywords: []string{"export&calendar", ".ics", "icalendar"}},
+				{Label: "view_calendar", Description: "show the calendar or upcoming schedule", Keywords: []string{"calendar", "schedule"}},
+				{Label: "set_reminder", Description: "set a reminder for a time or event", Keywords: []string{"remind"}},
+				{Label: "block_time", Description: "reserve focus or blocked time", Keywords: []string{"block time", "focus time"}},
+				{Label: "recurring_event", Description: "create or change a repeating event", Keywords: []string{"recurring", "repeat"}},
+			},
+		}),
 	}
+
+	// Event reminders fire through the shared reminder engine
+	agent.reminderEngine.Register(eventReminderSource, agent.fireEventReminder)
+
+	return agent
 }
 
 // HandleMessage processes incoming scheduling requests
@@ -303,32 +341,60 @@ func (a *SchedulerAgent) HandleMessage(ctx context.Context, msg *multiagent.Mess
 		a.mu.Unlock()
 	}()
 
+	// Replies to our own requests, such as invitation drafts, complete
+	// their futures
+	if a.resolveReply(msg) {
+		return nil, nil
+	}
+
 	// Store message in memory
 	if a.memoryStore != nil {
 		msgKey := fmt.Sprintf("scheduler:%s:%s", a.id, msg.ID)
 		a.memoryStore.Store(ctx, msgKey, msg)
 	}
 
-	content := strings.ToLower(msg.Content)
+	// Every intent reads the calendar, so bring it up to date with storage
+	a.loadEventsFromMemory(ctx)
 
-	// Route to appropriate handler based on content
-	if strings.Contains(content, "schedule") && (strings.Contains(content, "meeting") || strings.Contains(content, "appointment")) {
+	// The task manager blocks time for tasks, and moves and releases it
+	if block, ok := decodeTimeBlock(msg); ok {
+		return a.handleTimeBlock(ctx, msg, block)
+	}
+
+	// A pasted calendar file is imported whatever the surrounding words say
+	if strings.Contains(msg.Content, "BEGIN:VCALENDAR") {
+		return a.handleImportCalendar(ctx, msg)
+	}
+
+	// Route to appropriate handler based on intent
+	switch a.intents.Classify(ctx, msg.Content).Label {
+	case "schedule_event":
 		return a.handleScheduleEvent(ctx, msg)
-	} else if strings.Contains(content, "availability") || strings.Contains(content, "free time") || strings.Contains(content, "available") {
+	case "set_preferences":
+		return a.handleSetPreferences(ctx, msg)
+	case "check_travel":
+		return a.handleCheckTravel(ctx, msg)
+	case "participant_busy":
+		return a.handleParticipantBusy(ctx, msg)
+	case "check_availability":
 		return a.handleCheckAvailability(ctx, msg)
-	} else if strings.Contains(content, "cancel") && (strings.Contains(content, "meeting") || strings.Contains(content, "appointment")) {
+	case "cancel_event":
 		return a.handleCancelEvent(ctx, msg)
-	} else if strings.Contains(content, "reschedule") || strings.Contains(content, "move") {
+	case "reschedule":
 		return a.handleReschedule(ctx, msg)
-	} else if strings.Contains(content, "calendar") || strings.Contains(content, "schedule") {
+	case "set_timezone":
+		return a.handleSetTimezone(ctx, msg)
+	case "export_calendar":
+		return a.handleExportCalendar(ctx, msg)
+	case "view_calendar":
 		return a.handleViewCalendar(ctx, msg)
-	} else if strings.Contains(content, "remind") || strings.Contains(content, "reminder") {
+	case "set_reminder":
 		return a.handleSetReminder(ctx, msg)
-	} else if strings.Contains(content, "block time") || strings.Contains(content, "focus time") {
+	case "block_time":
 		return a.handleBlockTime(ctx, msg)
-	} else if strings.Contains(content, "recurring") || strings.Contains(content, "repeat") {
+	case "recurring_event":
 		return a.handleRecurringEvent(ctx, msg)
-	} else {
+	default:
 		// Use LLM for general scheduling queries
 		return a.handleGeneralQuery(ctx, msg)
 	}
@@ -336,31 +402,17 @@ func (a *SchedulerAgent) HandleMessage(ctx context.Context, msg *multiagent.Mess
 
 // handleScheduleEvent schedules a new event
 func (a *SchedulerAgent) handleScheduleEvent(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	// Use LLM to extract event details
-	contextPrompt := fmt.Sprintf(`
-Extract event details from this scheduling request: "%s"
-
-Provide response in JSON format:
-{
-  "title": "event title",
-  "description": "event description",
-  "start_time": "YYYY-MM-DD HH:MM",
-  "end_time": "YYYY-MM-DD HH:MM if mentioned",
-  "duration": "duration in minutes if end time not specified",
-  "location": "location if mentioned",
-  "category": "meeting|appointment|task|personal|work|etc",
-  "priority": "low|medium|high|critical",
-  "attendees": ["person1", "person2"] if mentioned,
-  "recurring": "daily|weekly|monthly|yearly if recurring",
-  "reminders": ["15", "60"] reminder times in minutes
-}
-
-Parse dates and times carefully. If no year is specified, assume current year.
-If no specific time is given, suggest appropriate time slots.`, msg.Content)
+	// Times are given and shown in the user's timezone
+	loc := userLocation(ctx, a.memoryStore)
 
-	response, err := a.llmProvider.Query(ctx, contextPrompt)
+	// Use LLM to extract event details
+	contextPrompt, err := a.renderPrompt(ctx, "scheduler.schedule_event", prompts.Vars{
+		"Request":  msg.Content,
+		"Timezone": loc,
+		"Now":      a.now().In(loc).Format("2006-01-02 15:04 (Monday)"),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse event details: %w", err)
+		return nil, err
 	}
 
 	var eventData struct {
@@ -377,21 +429,38 @@ If no specific time is given, suggest appropriate time slots.`, msg.Content)
 		Reminders   []string `json:"reminders"`
 	}
 
-	if err := json.Unmarshal([]byte(response), &eventData); err != nil {
-		return nil, fmt.Errorf("failed to parse event JSON: %w", err)
+	eventSchema := objectSchema(map[string]string{
+		"title":      "string",
+		"start_time": "string",
+		"end_time":   "string",
+		"duration":   "integer",
+		"attendees":  "array",
+		"reminders":  "array",
+	}, "title", "start_time")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+contextPrompt, eventSchema, &eventData); err != nil {
+		return nil, fmt.Errorf("failed to parse event details: %w", err)
 	}
 
-	// Parse start time
-	startTime, err := time.Parse("2006-01-02 15:04", eventData.StartTime)
+	// Parse start time, checked against what the user wrote
+	now := a.now().In(loc)
+	startTime, err := resolveTime(eventData.StartTime, msg.Content, now)
 	if err != nil {
+		// Ask once; an answer that still has no time is an error
+		if _, answered := userAnswer(msg); !answered {
+			what := "this event"
+			if eventData.Title != "" {
+				what = fmt.Sprintf("%q", eventData.Title)
+			}
+			return a.askUser(msg, fmt.Sprintf("When should I schedule %s? Tell me the day and time.", what)), nil
+		}
 		return nil, fmt.Errorf("invalid start time format: %w", err)
 	}
 
 	// Calculate end time
 	var endTime time.Time
 	if eventData.EndTime != "" {
-		endTime, err = time.Parse("2006-01-02 15:04", eventData.EndTime)
-		if err != nil {
+		endTime, err = resolveTime(eventData.EndTime, "", now)
+		if err != nil || !endTime.After(startTime) {
 			endTime = startTime.Add(time.Duration(eventData.Duration) * time.Minute)
 		}
 	} else {
@@ -402,49 +471,70 @@ If no specific time is given, suggest appropriate time slots.`, msg.Content)
 		endTime = startTime.Add(time.Duration(duration) * time.Minute)
 	}
 
-	// Check for conflicts
-	conflicts := a.checkConflicts(startTime, endTime)
-	if len(conflicts) > 0 {
+	// Check for conflicts, in the user's calendar and the busy times of
+	// attendees known to the scheduler
+	attendees, participants := a.resolveAttendees(ctx, a.parseAttendees(eventData.Attendees))
+	conflicts := a.checkConflicts(ctx, startTime, endTime, "")
+	busyAttendees := busyParticipants(participants, startTime, endTime)
+	if len(conflicts) > 0 || len(busyAttendees) > 0 {
 		conflictsList := make([]string, len(conflicts))
 		for i, conflict := range conflicts {
-			conflictsList[i] = fmt.Sprintf("• %s (%s - %s)", conflict.Title, conflict.StartTime.Format("15:04"), conflict.EndTime.Format("15:04"))
+			conflictsList[i] = fmt.Sprintf("• %s (%s - %s)", conflict.Title, conflict.StartTime.In(loc).Format("15:04"), conflict.EndTime.In(loc).Format("15:04"))
+		}
+		for _, name := range busyAttendees {
+			conflictsList = append(conflictsList, fmt.Sprintf("• %s is busy", name))
+		}
+
+		// Offer the best free times over the next few days instead
+		conflictDay := startOfDay(startTime)
+		alternatives := a.suggestSlots(ctx, a.userSchedule(ctx), participants, conflictDay, conflictDay.AddDate(0, 0, 3), endTime.Sub(startTime), []string{partOfDay(startTime)}, now)
+		if len(alternatives) > 3 {
+			alternatives = alternatives[:3]
+		}
+		options := "Would you like me to:\n1. Suggest alternative times\n2. Schedule anyway\n3. Cancel the conflicting event"
+		if len(alternatives) > 0 {
+			options = "Some free alternatives:\n\n" + formatSuggestions(alternatives, loc) + "\nWould you like one of these, or should I schedule it anyway?"
 		}
 
 		return &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+			ID:        a.messageID(),
 			From:      a.id,
 			To:        []multiagent.AgentID{msg.From},
 			Type:      multiagent.MessageTypeResponse,
-			Content:   fmt.Sprintf("⚠️ **Scheduling Conflict Detected**\n\nThe requested time slot (%s - %s) conflicts with:\n\n%s\n\nWould you like me to:\n1. Suggest alternative times\n2. Schedule anyway\n3. Cancel the conflicting event", startTime.Format("2006-01-02 15:04"), endTime.Format("15:04"), strings.Join(conflictsList, "\n")),
+			Content:   fmt.Sprintf("⚠️ **Scheduling Conflict Detected**\n\nThe requested time slot (%s - %s) conflicts with:\n\n%s\n\n%s", startTime.Format("2006-01-02 15:04"), endTime.Format("15:04"), strings.Join(conflictsList, "\n"), options),
 			ReplyTo:   msg.ID,
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 			Context: map[string]interface{}{
-				"action":     "conflict_detected",
-				"conflicts":  conflicts,
-				"event_data": eventData,
+				"action":       "conflict_detected",
+				"conflicts":    conflicts,
+				"event_data":   eventData,
+				"alternatives": alternatives,
+				"busy":         busyAttendees,
 			},
 		}, nil
 	}
 
-	// Create event
+	// Create event; times are kept in UTC alongside the zone they were
+	// scheduled in, which recurrence expands in
 	event := &CalendarEvent{
-		ID:          fmt.Sprintf("event_%d", time.Now().UnixNano()),
+		ID:          a.newID("event"),
 		Title:       eventData.Title,
 		Description: eventData.Description,
-		StartTime:   startTime,
-		EndTime:     endTime,
+		StartTime:   startTime.UTC(),
+		EndTime:     endTime.UTC(),
 		Location:    eventData.Location,
 		Category:    EventCategory(eventData.Category),
 		Priority:    a.parsePriority(eventData.Priority),
 		Status:      EventStatusConfirmed,
-		Attendees:   a.parseAttendees(eventData.Attendees),
+		Attendees:   attendees,
 		Reminders:   a.parseReminders(eventData.Reminders),
 		Tags:        []string{},
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   a.now(),
+		UpdatedAt:   a.now(),
 		CreatedBy:   msg.From,
-		Timezone:    "UTC",
+		Timezone:    loc.String(),
 		Metadata:    make(map[string]interface{}),
+		UserID:      multiagent.UserIDFromContext(ctx),
 	}
 
 	// Set recurring pattern if specified
@@ -461,45 +551,66 @@ If no specific time is given, suggest appropriate time slots.`, msg.Content)
 	a.scheduleMutex.Unlock()
 
 	// Save to memory
-	if a.memoryStore != nil {
-		eventKey := fmt.Sprintf("calendar_event:%s", event.ID)
-		a.memoryStore.Store(ctx, eventKey, event)
+	if err := a.saveEvent(ctx, event); err != nil {
+		return nil, err
+	}
+
+	a.recordShared(ctx, msg, memory.BlackboardEntry{
+		Section: memory.BlackboardFacts,
+		Key:     "event:" + event.ID,
+		Value:   fmt.Sprintf("'%s' is scheduled for %s", event.Title, startTime.Format("2006-01-02 15:04 MST")),
+	})
+	a.recordAudit(ctx, msg, audit.EventScheduled, event.ID, map[string]interface{}{
+		"title":      event.Title,
+		"start_time": event.StartTime,
+		"end_time":   event.EndTime,
+	})
+
+	content := fmt.Sprintf("✅ **Event Scheduled Successfully!**\n\n📅 **%s**\n🕐 %s - %s\n📍 %s\n🏷️ %s\n⚡ Priority: %s\n\nEvent ID: %s", event.Title, startTime.Format("2006-01-02 15:04"), endTime.Format("15:04 MST"), event.Location, event.Category, event.Priority, event.ID)
+	replyContext := map[string]interface{}{
+		"event_id": event.ID,
+		"action":   "event_scheduled",
+	}
+
+	// Keep travel time around it and flag breaks that are too short
+	if note := a.travelNotes(ctx, loc, startTime); note != "" {
+		content += "\n\n" + note
+	}
+
+	// Invite the attendees
+	if len(event.Attendees) > 0 {
+		note, invite := a.sendInvites(ctx, msg, event)
+		if note != "" {
+			content += "\n\n" + note
+		}
+		if invite != nil {
+			replyContext["invite_ics"] = string(invite)
+		}
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
-		Content:   fmt.Sprintf("✅ **Event Scheduled Successfully!**\n\n📅 **%s**\n🕐 %s - %s\n📍 %s\n🏷️ %s\n⚡ Priority: %s\n\nEvent ID: %s", event.Title, event.StartTime.Format("2006-01-02 15:04"), event.EndTime.Format("15:04"), event.Location, event.Category, event.Priority, event.ID),
+		Content:   content,
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-		Context: map[string]interface{}{
-			"event_id": event.ID,
-			"action":   "event_scheduled",
-		},
+		Timestamp: a.now(),
+		Context:   replyContext,
 	}, nil
 }
 
 // handleCheckAvailability checks availability for a given time period
 func (a *SchedulerAgent) handleCheckAvailability(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	// Use LLM to extract time period
-	availabilityPrompt := fmt.Sprintf(`
-Extract availability check details from: "%s"
+	loc := userLocation(ctx, a.memoryStore)
 
-Provide response in JSON format:
-{
-  "start_date": "YYYY-MM-DD",
-  "end_date": "YYYY-MM-DD if range specified",
-  "duration": "duration in minutes if specific meeting duration mentioned",
-  "preferred_times": ["morning", "afternoon", "evening"] if mentioned
-}
-
-If no specific dates are given, assume they want to check today or this week.`, msg.Content)
-
-	response, err := a.llmProvider.Query(ctx, availabilityPrompt)
+	// Use LLM to extract time period
+	availabilityPrompt, err := a.renderPrompt(ctx, "scheduler.availability", prompts.Vars{
+		"Request": msg.Content,
+		"Now":     a.now().In(loc).Format("2006-01-02 (Monday)"),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse availability request: %w", err)
+		return nil, err
 	}
 
 	var availData struct {
@@ -507,43 +618,66 @@ If no specific dates are given, assume they want to check today or this week.`,
 		EndDate        string   `json:"end_date"`
 		Duration       int      `json:"duration"`
 		PreferredTimes []string `json:"preferred_times"`
+		Participants   []string `json:"participants"`
 	}
 
-	if err := json.Unmarshal([]byte(response), &availData); err != nil {
-		return nil, fmt.Errorf("failed to parse availability JSON: %w", err)
+	availSchema := objectSchema(map[string]string{
+		"start_date":      "string",
+		"end_date":        "string",
+		"duration":        "integer",
+		"preferred_times": "array",
+		"participants":    "array",
+	}, "start_date")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+availabilityPrompt, availSchema, &availData); err != nil {
+		return nil, fmt.Errorf("failed to parse availability request: %w", err)
 	}
 
 	// Parse dates
-	startDate, err := time.Parse("2006-01-02", availData.StartDate)
+	now := a.now().In(loc)
+	startDate, err := resolveDate(availData.StartDate, now)
 	if err != nil {
-		startDate = time.Now().Truncate(24 * time.Hour)
+		startDate = startOfDay(now)
 	}
 
-	endDate := startDate.Add(24 * time.Hour)
+	endDate := startDate.AddDate(0, 0, 1)
 	if availData.EndDate != "" {
-		if ed, err := time.Parse("2006-01-02", availData.EndDate); err == nil {
-			endDate = ed.Add(24 * time.Hour)
+		if ed, err := resolveDate(availData.EndDate, now); err == nil {
+			endDate = ed.AddDate(0, 0, 1)
 		}
 	}
 
-	// Find available slots
-	availableSlots := a.findAvailableSlots(startDate, endDate, time.Duration(availData.Duration)*time.Minute)
+	// Find available slots within the user's working hours, when the
+	// participants are free as well
+	schedule := a.userSchedule(ctx)
+	duration := time.Duration(availData.Duration) * time.Minute
+	participants, unknown := a.resolveParticipants(ctx, availData.Participants)
+	availableSlots := a.findAvailableSlots(ctx, schedule, participants, startDate, endDate, duration)
 
 	if len(availableSlots) == 0 {
 		return &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+			ID:        a.messageID(),
 			From:      a.id,
 			To:        []multiagent.AgentID{msg.From},
 			Type:      multiagent.MessageTypeResponse,
-			Content:   fmt.Sprintf("📅 **Availability Check**\n\nNo available slots found for %s to %s.\n\nYour calendar appears to be fully booked during this period.", startDate.Format("2006-01-02"), endDate.Add(-24*time.Hour).Format("2006-01-02")),
+			Content:   fmt.Sprintf("📅 **Availability Check**\n\nNo available slots found for %s to %s.\n\nYour calendar appears to be fully booked during this period.", startDate.Format("2006-01-02"), endDate.AddDate(0, 0, -1).Format("2006-01-02")),
 			ReplyTo:   msg.ID,
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 		}, nil
 	}
 
 	// Format available slots
 	var slotsBuilder strings.Builder
-	slotsBuilder.WriteString(fmt.Sprintf("📅 **Available Time Slots** (%s to %s)\n\n", startDate.Format("2006-01-02"), endDate.Add(-24*time.Hour).Format("2006-01-02")))
+	slotsBuilder.WriteString(fmt.Sprintf("📅 **Available Time Slots** (%s to %s, %s)\n\n", startDate.Format("2006-01-02"), endDate.AddDate(0, 0, -1).Format("2006-01-02"), loc))
+	if len(participants) > 0 {
+		names := make([]string, len(participants))
+		for i, p := range participants {
+			names[i] = p.label()
+		}
+		slotsBuilder.WriteString(fmt.Sprintf("👥 Free for you and %s\n\n", strings.Join(names, ", ")))
+	}
+	if len(unknown) > 0 {
+		slotsBuilder.WriteString(fmt.Sprintf("⚠️ I don't know the busy times of %s; share their calendar (.ics) or tell me when they're busy.\n\n", strings.Join(unknown, ", ")))
+	}
 
 	for i, slot := range availableSlots {
 		if i >= 10 { // Limit to 10 slots
@@ -551,53 +685,60 @@ If no specific dates are given, assume they want to check today or this week.`,
 			break
 		}
 		slotsBuilder.WriteString(fmt.Sprintf("• %s - %s (%s)\n",
-			slot.Start.Format("Mon 2006-01-02 15:04"),
-			slot.End.Format("15:04"),
+			slot.Start.In(loc).Format("Mon 2006-01-02 15:04"),
+			slot.End.In(loc).Format("15:04"),
 			(*slot.End).Sub(*slot.Start).String()))
 	}
 
+	// Rank meeting times against the user's preferences
+	suggestions := a.suggestSlots(ctx, schedule, participants, startDate, endDate, duration, availData.PreferredTimes, now)
+	if len(suggestions) > 0 {
+		slotsBuilder.WriteString("\n⭐ **Suggested Times**\n\n")
+		slotsBuilder.WriteString(formatSuggestions(suggestions, loc))
+	}
+
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   slotsBuilder.String(),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
+		Context: map[string]interface{}{
+			"suggestions": suggestions,
+		},
 	}, nil
 }
 
 // handleViewCalendar shows calendar events
 func (a *SchedulerAgent) handleViewCalendar(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	// Load events from memory if needed
-	a.loadEventsFromMemory(ctx)
-
-	// Determine date range
-	now := time.Now()
-	startDate := now.Truncate(24 * time.Hour)
-	endDate := startDate.Add(7 * 24 * time.Hour) // Default to 1 week
+	// Determine date range, in days of the user's timezone
+	loc := userLocation(ctx, a.memoryStore)
+	startDate := startOfDay(a.now().In(loc))
+	endDate := startDate.AddDate(0, 0, 7) // Default to 1 week
 
 	content := strings.ToLower(msg.Content)
 	if strings.Contains(content, "today") {
-		endDate = startDate.Add(24 * time.Hour)
+		endDate = startDate.AddDate(0, 0, 1)
 	} else if strings.Contains(content, "week") {
-		endDate = startDate.Add(7 * 24 * time.Hour)
+		endDate = startDate.AddDate(0, 0, 7)
 	} else if strings.Contains(content, "month") {
-		endDate = startDate.Add(30 * 24 * time.Hour)
+		endDate = startDate.AddDate(0, 0, 30)
 	}
 
 	// Get events in range
-	events := a.getEventsInRange(startDate, endDate)
+	events := a.getEventsInRange(ctx, startDate, endDate)
 
 	if len(events) == 0 {
 		return &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+			ID:        a.messageID(),
 			From:      a.id,
 			To:        []multiagent.AgentID{msg.From},
 			Type:      multiagent.MessageTypeResponse,
-			Content:   fmt.Sprintf("📅 **Calendar View** (%s to %s)\n\nNo events scheduled for this period.", startDate.Format("2006-01-02"), endDate.Add(-24*time.Hour).Format("2006-01-02")),
+			Content:   fmt.Sprintf("📅 **Calendar View** (%s to %s)\n\nNo events scheduled for this period.", startDate.Format("2006-01-02"), endDate.AddDate(0, 0, -1).Format("2006-01-02")),
 			ReplyTo:   msg.ID,
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 		}, nil
 	}
 
@@ -608,23 +749,24 @@ func (a *SchedulerAgent) handleViewCalendar(ctx context.Context, msg *multiagent
 
 	// Build calendar view
 	var calendarBuilder strings.Builder
-	calendarBuilder.WriteString(fmt.Sprintf("📅 **Calendar View** (%s to %s)\n\n", startDate.Format("2006-01-02"), endDate.Add(-24*time.Hour).Format("2006-01-02")))
+	calendarBuilder.WriteString(fmt.Sprintf("📅 **Calendar View** (%s to %s, %s)\n\n", startDate.Format("2006-01-02"), endDate.AddDate(0, 0, -1).Format("2006-01-02"), loc))
 
 	currentDate := ""
 	for _, event := range events {
-		eventDate := event.StartTime.Format("2006-01-02")
+		start, end := event.StartTime.In(loc), event.EndTime.In(loc)
+		eventDate := start.Format("2006-01-02")
 		if eventDate != currentDate {
 			if currentDate != "" {
 				calendarBuilder.WriteString("\n")
 			}
-			calendarBuilder.WriteString(fmt.Sprintf("**%s (%s)**\n", eventDate, event.StartTime.Format("Monday")))
+			calendarBuilder.WriteString(fmt.Sprintf("**%s (%s)**\n", eventDate, start.Format("Monday")))
 			currentDate = eventDate
 		}
 
 		status := a.getEventStatusEmoji(event.Status)
 		priority := a.getEventPriorityEmoji(event.Priority)
 
-		calendarBuilder.WriteString(fmt.Sprintf("  %s %s %s - %s: **%s**\n", status, priority, event.StartTime.Format("15:04"), event.EndTime.Format("15:04"), event.Title))
+		calendarBuilder.WriteString(fmt.Sprintf("  %s %s %s - %s: **%s**\n", status, priority, start.Format("15:04"), end.Format("15:04"), event.Title))
 
 		if event.Location != "" {
 			calendarBuilder.WriteString(fmt.Sprintf("    📍 %s\n", event.Location))
@@ -632,81 +774,60 @@ func (a *SchedulerAgent) handleViewCalendar(ctx context.Context, msg *multiagent
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   calendarBuilder.String(),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
 // Additional handler methods
 
-func (a *SchedulerAgent) handleCancelEvent(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "❌ Event cancellation functionality is available. Please specify which event you'd like to cancel.",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
-func (a *SchedulerAgent) handleReschedule(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "🔄 Event rescheduling is available. Please specify which event and the new time.",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
 func (a *SchedulerAgent) handleSetReminder(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   "⏰ Reminder functionality is available. I can set reminders for events and tasks.",
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
 func (a *SchedulerAgent) handleBlockTime(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   "🔒 Time blocking functionality is available. I can block time for focused work or personal activities.",
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
 func (a *SchedulerAgent) handleRecurringEvent(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   "🔄 Recurring event functionality is available. I can set up daily, weekly, monthly, or yearly recurring events.",
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
 func (a *SchedulerAgent) handleGeneralQuery(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Build context with calendar information
-	contextPrompt := a.buildSchedulerContext(ctx, msg)
+	contextPrompt, err := a.buildSchedulerContext(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
 
 	response, err := a.llmProvider.Query(ctx, contextPrompt)
 	if err != nil {
@@ -714,13 +835,13 @@ func (a *SchedulerAgent) handleGeneralQuery(ctx context.Context, msg *multiagent
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   response,
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
@@ -767,118 +888,50 @@ func (a *SchedulerAgent) parseReminders(reminders []string) []EventReminder {
 	return result
 }
 
-func (a *SchedulerAgent) checkConflicts(startTime, endTime time.Time) []*CalendarEvent {
+// checkConflicts returns the live events overlapping startTime-endTime,
+// other than excludeID
+func (a *SchedulerAgent) checkConflicts(ctx context.Context, startTime, endTime time.Time, excludeID string) []*CalendarEvent {
 	var conflicts []*CalendarEvent
 
 	a.scheduleMutex.RLock()
 	defer a.scheduleMutex.RUnlock()
 
 	for _, event := range a.calendar {
-		if event.Status == EventStatusCancelled {
+		// Travel blocks move with the events they lead to
+		if event.Status == EventStatusCancelled || event.ID == excludeID || !ownedBy(ctx, event.UserID) || isTravelBlock(event) {
 			continue
 		}
 
-		// Check for overlap
-		if startTime.Before(event.EndTime) && endTime.After(event.StartTime) {
-			conflicts = append(conflicts, event)
-		}
+		// Recurring events conflict through whichever occurrences overlap
+		conflicts = append(conflicts, expandEvent(event, startTime, endTime)...)
 	}
 
 	return conflicts
 }
 
-func (a *SchedulerAgent) findAvailableSlots(startDate, endDate time.Time, duration time.Duration) []ScheduleTimeRange {
-	var slots []ScheduleTimeRange
-
-	// Simple implementation - find gaps between events
-	events := a.getEventsInRange(startDate, endDate)
-
-	// Sort events by start time
-	sort.Slice(events, func(i, j int) bool {
-		return events[i].StartTime.Before(events[j].StartTime)
-	})
-
-	// Working hours: 9 AM to 6 PM
-	workStart := 9
-	workEnd := 18
-
-	currentDate := startDate
-	for currentDate.Before(endDate) {
-		// Skip weekends (simple implementation)
-		if currentDate.Weekday() == time.Saturday || currentDate.Weekday() == time.Sunday {
-			currentDate = currentDate.Add(24 * time.Hour)
-			continue
-		}
-
-		dayStart := time.Date(currentDate.Year(), currentDate.Month(), currentDate.Day(), workStart, 0, 0, 0, currentDate.Location())
-		dayEnd := time.Date(currentDate.Year(), currentDate.Month(), currentDate.Day(), workEnd, 0, 0, 0, currentDate.Location())
-
-		// Find gaps in this day
-		dayEvents := a.getEventsForDate(currentDate)
-
-		if len(dayEvents) == 0 {
-			// Entire day is free
-			if duration == 0 || dayEnd.Sub(dayStart) >= duration {
-				dayStartPtr, dayEndPtr := dayStart, dayEnd
-				slots = append(slots, ScheduleTimeRange{Start: &dayStartPtr, End: &dayEndPtr})
-			}
-		} else {
-			// Find gaps between events
-			currentTime := dayStart
-			for _, event := range dayEvents {
-				if event.StartTime.After(currentTime) {
-					gapDuration := event.StartTime.Sub(currentTime)
-					if duration == 0 || gapDuration >= duration {
-						currentTimePtr, eventStartTimePtr := currentTime, event.StartTime
-						slots = append(slots, ScheduleTimeRange{Start: &currentTimePtr, End: &eventStartTimePtr})
-					}
-				}
-				if event.EndTime.After(currentTime) {
-					currentTime = event.EndTime
-				}
-			}
-
-			// Check gap after last event
-			if currentTime.Before(dayEnd) {
-				gapDuration := dayEnd.Sub(currentTime)
-				if duration == 0 || gapDuration >= duration {
-					currentTimePtr, dayEndPtr := currentTime, dayEnd
-					slots = append(slots, ScheduleTimeRange{Start: &currentTimePtr, End: &dayEndPtr})
-				}
-			}
-		}
-
-		currentDate = currentDate.Add(24 * time.Hour)
-	}
-
-	return slots
-}
-
-func (a *SchedulerAgent) getEventsInRange(startDate, endDate time.Time) []*CalendarEvent {
+func (a *SchedulerAgent) getEventsInRange(ctx context.Context, startDate, endDate time.Time) []*CalendarEvent {
 	var events []*CalendarEvent
 
 	a.scheduleMutex.RLock()
 	defer a.scheduleMutex.RUnlock()
 
 	for _, event := range a.calendar {
-		if event.Status == EventStatusCancelled {
+		if event.Status == EventStatusCancelled || !ownedBy(ctx, event.UserID) {
 			continue
 		}
 
-		// Check if event overlaps with date range
-		if event.StartTime.Before(endDate) && event.EndTime.After(startDate) {
-			events = append(events, event)
-		}
+		// Recurring events contribute each occurrence overlapping the range
+		events = append(events, expandEvent(event, startDate, endDate)...)
 	}
 
 	return events
 }
 
-func (a *SchedulerAgent) getEventsForDate(date time.Time) []*CalendarEvent {
+func (a *SchedulerAgent) getEventsForDate(ctx context.Context, date time.Time) []*CalendarEvent {
 	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	endOfDay := startOfDay.AddDate(0, 0, 1)
 
-	return a.getEventsInRange(startOfDay, endOfDay)
+	return a.getEventsInRange(ctx, startOfDay, endOfDay)
 }
 
 func (a *SchedulerAgent) getEventStatusEmoji(status EventStatus) string {
@@ -937,35 +990,46 @@ func (a *SchedulerAgent) loadEventsFromMemory(ctx context.Context) {
 		var event CalendarEvent
 		if eventData, err := json.Marshal(eventInterface); err == nil {
 			if err := json.Unmarshal(eventData, &event); err == nil {
+				event.UserID = multiagent.UserIDFromContext(ctx)
 				a.calendar[event.ID] = &event
 			}
 		}
 	}
 }
 
-func (a *SchedulerAgent) buildSchedulerContext(ctx context.Context, msg *multiagent.Message) string {
-	var contextBuilder strings.Builder
-
-	contextBuilder.WriteString(fmt.Sprintf("You are %s, a scheduling and calendar management specialist.\n\n", a.name))
-	contextBuilder.WriteString("You help users manage their calendar, schedule events, check availability, and optimize their time.\n\n")
+// PurgeUser forgets userID's calendar events and scheduling preferences
+func (a *SchedulerAgent) PurgeUser(userID string) int {
+	a.scheduleMutex.Lock()
+	defer a.scheduleMutex.Unlock()
 
-	// Add upcoming events summary
-	now := time.Now()
-	upcomingEvents := a.getEventsInRange(now, now.Add(7*24*time.Hour))
-	if len(upcomingEvents) > 0 {
-		contextBuilder.WriteString("Upcoming Events (Next 7 Days):\n")
-		for i, event := range upcomingEvents {
-			if i >= 5 { // Limit to 5 events
-				contextBuilder.WriteString(fmt.Sprintf("... and %d more events\n", len(upcomingEvents)-i))
-				break
-			}
-			contextBuilder.WriteString(fmt.Sprintf("- %s: %s (%s)\n", event.StartTime.Format("Mon 15:04"), event.Title, event.Category))
+	delete(a.schedules, userID)
+	purged := 0
+	for id, event := range a.calendar {
+		if event.UserID == userID {
+			delete(a.calendar, id)
+			purged++
 		}
-		contextBuilder.WriteString("\n")
 	}
+	return purged
+}
 
-	contextBuilder.WriteString(fmt.Sprintf("User request: %s\n\n", msg.Content))
-	contextBuilder.WriteString("Please provide helpful scheduling assistance, calendar management, or time planning advice.")
+func (a *SchedulerAgent) buildSchedulerContext(ctx context.Context, msg *multiagent.Message) (string, error) {
+	// Add upcoming events summary
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now().In(loc)
+	upcomingEvents := a.getEventsInRange(ctx, now, now.Add(7*24*time.Hour))
+	moreEvents := 0
+	if len(upcomingEvents) > 5 { // Limit to 5 events
+		moreEvents = len(upcomingEvents) - 5
+		upcomingEvents = upcomingEvents[:5]
+	}
 
-	return contextBuilder.String()
+	return a.renderPrompt(ctx, "scheduler.general", prompts.Vars{
+		"Name":       a.name,
+		"Events":     upcomingEvents,
+		"MoreEvents": moreEvents,
+		"Timezone":   loc,
+		"Now":        now.Format("Mon 2006-01-02 15:04"),
+		"Request":    msg.Content,
+	})
 }
@@ -0,0 +1,295 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// eventReference is how a user points at an existing event: by ID, by
+// (part of) its title, and/or by when it happens
+type eventReference struct {
+	EventID string `json:"event_id"`
+	Title   string `json:"title"`
+	Date    string `json:"date"`
+	Time    string `json:"time"`
+}
+
+// handleCancelEvent cancels the event the user refers to
+func (a *SchedulerAgent) handleCancelEvent(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	loc := userLocation(ctx, a.memoryStore)
+	prompt, err := a.renderPrompt(ctx, "scheduler.cancel_event", prompts.Vars{
+		"Request":  msg.Content,
+		"Now":      a.now().In(loc).Format("2006-01-02 (Monday)"),
+		"Timezone": loc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ref eventReference
+	refSchema := objectSchema(map[string]string{
+		"event_id": "string",
+		"title":    "string",
+		"date":     "string",
+		"time":     "string",
+	})
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, refSchema, &ref); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse event reference", "error", err)
+	}
+	if ref.EventID == "" {
+		ref.EventID = extractEventID(msg.Content)
+	}
+
+	event, reply := a.resolveEvent(ctx, msg, ref, loc, "cancel")
+	if reply != nil {
+		return reply, nil
+	}
+	if approved, reply := a.confirmAction(ctx, msg, ProposedAction{
+		Action:  ActionCancelEvent,
+		Subject: event.ID,
+		Summary: fmt.Sprintf("cancel '%s' on %s", event.Title, event.StartTime.In(loc).Format("Mon Jan 2 15:04")),
+		Details: map[string]string{"Event": event.ID},
+	}); !approved {
+		return reply, nil
+	}
+
+	a.scheduleMutex.Lock()
+	event.Status = EventStatusCancelled
+	event.UpdatedAt = a.now()
+	a.scheduleMutex.Unlock()
+	if err := a.saveEvent(ctx, event); err != nil {
+		return nil, err
+	}
+	a.notifyTimeBlock(ctx, event)
+
+	a.recordShared(ctx, msg, memory.BlackboardEntry{
+		Section: memory.BlackboardFacts,
+		Key:     "event:" + event.ID,
+		Value:   fmt.Sprintf("'%s' on %s was cancelled", event.Title, event.StartTime.In(loc).Format("2006-01-02 15:04 MST")),
+	})
+	a.recordAudit(ctx, msg, audit.EventCancelled, event.ID, map[string]interface{}{
+		"title":      event.Title,
+		"start_time": event.StartTime,
+	})
+
+	content := fmt.Sprintf("❌ **Event Cancelled**\n\n📅 **%s**\n🕐 %s - %s\n\nEvent ID: %s", event.Title, event.StartTime.In(loc).Format("2006-01-02 15:04"), event.EndTime.In(loc).Format("15:04 MST"), event.ID)
+	if note := a.travelNotes(ctx, loc, event.StartTime); note != "" {
+		content += "\n\n" + note
+	}
+	return a.respond(msg, content, map[string]interface{}{
+		"event_id": event.ID,
+		"action":   "event_cancelled",
+	}), nil
+}
+
+// handleReschedule moves the event the user refers to, refusing if the new
+// time conflicts with something else
+func (a *SchedulerAgent) handleReschedule(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	loc := userLocation(ctx, a.memoryStore)
+	prompt, err := a.renderPrompt(ctx, "scheduler.reschedule", prompts.Vars{
+		"Request":  msg.Content,
+		"Now":      a.now().In(loc).Format("2006-01-02 (Monday)"),
+		"Timezone": loc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		eventReference
+		NewStartTime string `json:"new_start_time"`
+		NewDuration  int    `json:"new_duration"`
+	}
+	rescheduleSchema := objectSchema(map[string]string{
+		"event_id":       "string",
+		"title":          "string",
+		"date":           "string",
+		"time":           "string",
+		"new_start_time": "string",
+		"new_duration":   "integer",
+	}, "new_start_time")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, rescheduleSchema, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse reschedule request: %w", err)
+	}
+	if data.EventID == "" {
+		data.EventID = extractEventID(msg.Content)
+	}
+
+	// The request names the old time too, so only repair the new one
+	newStart, err := resolveTime(data.NewStartTime, "", a.now().In(loc))
+	if err != nil {
+		return a.respond(msg, "🔄 When should the event move to? Please give a new date and time.", nil), nil
+	}
+
+	event, reply := a.resolveEvent(ctx, msg, data.eventReference, loc, "reschedule")
+	if reply != nil {
+		return reply, nil
+	}
+
+	a.scheduleMutex.RLock()
+	duration := event.EndTime.Sub(event.StartTime)
+	oldStart := event.StartTime.In(loc)
+	a.scheduleMutex.RUnlock()
+	if data.NewDuration > 0 {
+		duration = time.Duration(data.NewDuration) * time.Minute
+	}
+	newEnd := newStart.Add(duration)
+
+	if conflicts := a.checkConflicts(ctx, newStart, newEnd, event.ID); len(conflicts) > 0 {
+		conflictsList := make([]string, len(conflicts))
+		for i, conflict := range conflicts {
+			conflictsList[i] = fmt.Sprintf("• %s (%s - %s)", conflict.Title, conflict.StartTime.In(loc).Format("15:04"), conflict.EndTime.In(loc).Format("15:04"))
+		}
+		return a.respond(msg, fmt.Sprintf("⚠️ **Scheduling Conflict Detected**\n\nMoving '%s' to %s - %s would conflict with:\n\n%s\n\nThe event was left at %s.", event.Title, newStart.Format("2006-01-02 15:04"), newEnd.Format("15:04"), strings.Join(conflictsList, "\n"), oldStart.Format("2006-01-02 15:04")), map[string]interface{}{
+			"event_id":  event.ID,
+			"action":    "conflict_detected",
+			"conflicts": conflicts,
+		}), nil
+	}
+
+	a.scheduleMutex.Lock()
+	event.StartTime = newStart.UTC()
+	event.EndTime = newEnd.UTC()
+	event.Timezone = loc.String()
+	if event.Status == EventStatusPostponed || event.Status == EventStatusTentative {
+		event.Status = EventStatusConfirmed
+	}
+	event.UpdatedAt = a.now()
+	a.scheduleMutex.Unlock()
+	if err := a.saveEvent(ctx, event); err != nil {
+		return nil, err
+	}
+	a.notifyTimeBlock(ctx, event)
+
+	a.recordShared(ctx, msg, memory.BlackboardEntry{
+		Section: memory.BlackboardFacts,
+		Key:     "event:" + event.ID,
+		Value:   fmt.Sprintf("'%s' was moved from %s to %s", event.Title, oldStart.Format("2006-01-02 15:04"), newStart.Format("2006-01-02 15:04")),
+	})
+	a.recordAudit(ctx, msg, audit.EventRescheduled, event.ID, map[string]interface{}{
+		"title":          event.Title,
+		"old_start_time": oldStart,
+		"start_time":     newStart,
+		"end_time":       newEnd,
+	})
+
+	content := fmt.Sprintf("🔄 **Event Rescheduled**\n\n📅 **%s**\n🕐 %s - %s (was %s)\n\nEvent ID: %s", event.Title, newStart.Format("2006-01-02 15:04"), newEnd.Format("15:04"), oldStart.Format("2006-01-02 15:04"), event.ID)
+	if note := a.travelNotes(ctx, loc, oldStart, newStart); note != "" {
+		content += "\n\n" + note
+	}
+	return a.respond(msg, content, map[string]interface{}{
+		"event_id": event.ID,
+		"action":   "event_rescheduled",
+	}), nil
+}
+
+// resolveEvent finds the single event ref points at; otherwise it returns a
+// reply asking the user to be more specific
+func (a *SchedulerAgent) resolveEvent(ctx context.Context, msg *multiagent.Message, ref eventReference, loc *time.Location, verb string) (*CalendarEvent, *multiagent.Message) {
+	matches := a.findEvents(ctx, ref, loc)
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		return nil, a.respond(msg, fmt.Sprintf("❓ I couldn't find an event to %s. Please give its ID, title, or when it is.", verb), nil)
+	}
+
+	var options strings.Builder
+	for i, event := range matches {
+		if i >= 10 {
+			options.WriteString(fmt.Sprintf("... and %d more\n", len(matches)-i))
+			break
+		}
+		options.WriteString(fmt.Sprintf("• %s — %s (%s)\n", event.Title, event.StartTime.In(loc).Format("Mon 2006-01-02 15:04"), event.ID))
+	}
+	return nil, a.respond(msg, fmt.Sprintf("❓ Several events match. Which one should I %s?\n\n%s", verb, options.String()), map[string]interface{}{
+		"action": "event_ambiguous",
+	})
+}
+
+// findEvents returns the user's live events matching ref, whose date and
+// time are in loc, soonest first; an ID match wins outright
+func (a *SchedulerAgent) findEvents(ctx context.Context, ref eventReference, loc *time.Location) []*CalendarEvent {
+	title := strings.ToLower(strings.TrimSpace(ref.Title))
+	if ref.EventID == "" && title == "" && ref.Date == "" && ref.Time == "" {
+		return nil
+	}
+
+	a.scheduleMutex.RLock()
+	defer a.scheduleMutex.RUnlock()
+
+	if event, ok := a.calendar[ref.EventID]; ok && ownedBy(ctx, event.UserID) && event.Status != EventStatusCancelled {
+		return []*CalendarEvent{event}
+	}
+
+	var matches []*CalendarEvent
+	for _, event := range a.calendar {
+		if !ownedBy(ctx, event.UserID) || event.Status == EventStatusCancelled {
+			continue
+		}
+		eventTitle := strings.ToLower(event.Title)
+		if title != "" && !strings.Contains(eventTitle, title) && !strings.Contains(title, eventTitle) {
+			continue
+		}
+		start := event.StartTime.In(loc)
+		if ref.Date != "" && start.Format("2006-01-02") != ref.Date {
+			continue
+		}
+		if ref.Time != "" && start.Format("15:04") != ref.Time {
+			continue
+		}
+		matches = append(matches, event)
+	}
+
+	// Prefer upcoming events, then the most recent past ones
+	now := a.now()
+	sort.Slice(matches, func(i, j int) bool {
+		iUpcoming, jUpcoming := !matches[i].EndTime.Before(now), !matches[j].EndTime.Before(now)
+		if iUpcoming != jUpcoming {
+			return iUpcoming
+		}
+		if iUpcoming {
+			return matches[i].StartTime.Before(matches[j].StartTime)
+		}
+		return matches[i].StartTime.After(matches[j].StartTime)
+	})
+	return matches
+}
+
+// saveEvent persists an event through the memory store and reschedules
+// its reminders
+func (a *SchedulerAgent) saveEvent(ctx context.Context, event *CalendarEvent) error {
+	a.scheduleEventReminders(ctx, event, a.now())
+	return a.persistEvent(ctx, event)
+}
+
+// persistEvent stores an event without touching its reminders
+func (a *SchedulerAgent) persistEvent(ctx context.Context, event *CalendarEvent) error {
+	if a.memoryStore == nil {
+		return nil
+	}
+	a.scheduleMutex.RLock()
+	defer a.scheduleMutex.RUnlock()
+	if err := a.memoryStore.Store(ctx, fmt.Sprintf("calendar_event:%s", event.ID), event); err != nil {
+		return fmt.Errorf("failed to save event %s: %w", event.ID, err)
+	}
+	return nil
+}
+
+func extractEventID(content string) string {
+	for _, word := range strings.Fields(content) {
+		word = strings.Trim(word, ".,;:!?()[]\"'")
+		if strings.HasPrefix(word, "event_") {
+			return word
+		}
+	}
+	return ""
+}
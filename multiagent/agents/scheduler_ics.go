@@ -0,0 +1,434 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/ical"
+)
+
+// icalUIDKey is the event metadata key holding the UID an imported event
+// had in its source calendar, so importing it again updates it
+const icalUIDKey = "ical_uid"
+
+// CalendarExchanger is implemented by agents whose calendar can be exported
+// to and imported from iCalendar (.ics) data
+type CalendarExchanger interface {
+	// ExportICS returns the calendar of the user ctx acts for
+	ExportICS(ctx context.Context) ([]byte, error)
+	// ImportICS adds or updates the events in data in the calendar of the
+	// user ctx acts for
+	ImportICS(ctx context.Context, data []byte) (*ICSImportResult, error)
+}
+
+// ICSImportResult reports what ImportICS did
+type ICSImportResult struct {
+	Imported int `json:"imported"`
+	Updated  int `json:"updated"`
+	// Skipped counts events that could not be represented, such as
+	// overrides of single occurrences of a recurring event
+	Skipped int `json:"skipped"`
+}
+
+// ExportICS returns the user's live events as an iCalendar file, including
+// their recurrence rules, exceptions, attendees and reminders
+func (a *SchedulerAgent) ExportICS(ctx context.Context) ([]byte, error) {
+	a.loadEventsFromMemory(ctx)
+
+	a.scheduleMutex.RLock()
+	var events []ical.Event
+	for _, event := range a.calendar {
+		if !ownedBy(ctx, event.UserID) || event.Status == EventStatusCancelled {
+			continue
+		}
+		events = append(events, eventToICS(event))
+	}
+	a.scheduleMutex.RUnlock()
+	sort.Slice(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+
+	var buf bytes.Buffer
+	if err := ical.Encode(&buf, &ical.Calendar{Name: "wikillm", Events: events}); err != nil {
+		return nil, fmt.Errorf("failed to export calendar: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ImportICS adds the events in an iCalendar file to the user's calendar;
+// events imported before, or exported from here, are updated in place
+func (a *SchedulerAgent) ImportICS(ctx context.Context, data []byte) (*ICSImportResult, error) {
+	cal, err := ical.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to import calendar: %w", err)
+	}
+	result, err := a.upsertICSEvents(ctx, cal.Events)
+	if err != nil {
+		return result, err
+	}
+
+	a.recordAudit(ctx, nil, audit.CalendarImported, "", map[string]interface{}{
+		"imported": result.Imported,
+		"updated":  result.Updated,
+		"skipped":  result.Skipped,
+	})
+	return result, nil
+}
+
+// ICSEvents returns all of the user's events, cancelled ones included, for
+// calendar sync
+func (a *SchedulerAgent) ICSEvents(ctx context.Context) ([]ical.Event, error) {
+	a.loadEventsFromMemory(ctx)
+
+	a.scheduleMutex.RLock()
+	defer a.scheduleMutex.RUnlock()
+	var events []ical.Event
+	for _, event := range a.calendar {
+		if ownedBy(ctx, event.UserID) {
+			events = append(events, eventToICS(event))
+		}
+	}
+	return events, nil
+}
+
+// ApplyICSEvents adds or updates events by UID, for calendar sync
+func (a *SchedulerAgent) ApplyICSEvents(ctx context.Context, events []ical.Event) error {
+	_, err := a.upsertICSEvents(ctx, events)
+	return err
+}
+
+// upsertICSEvents adds events to the user's calendar, updating those whose
+// UID it already holds
+func (a *SchedulerAgent) upsertICSEvents(ctx context.Context, events []ical.Event) (*ICSImportResult, error) {
+	a.loadEventsFromMemory(ctx)
+
+	a.scheduleMutex.RLock()
+	existing := make(map[string]*CalendarEvent)
+	for _, event := range a.calendar {
+		if ownedBy(ctx, event.UserID) {
+			existing[icalUID(event)] = event
+		}
+	}
+	a.scheduleMutex.RUnlock()
+
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now()
+	result := &ICSImportResult{}
+	for i := range events {
+		source := &events[i]
+		// Overrides of single occurrences have no equivalent here
+		if source.RecurrenceID != nil {
+			result.Skipped++
+			continue
+		}
+
+		event := eventFromICS(source, loc)
+		event.UserID = multiagent.UserIDFromContext(ctx)
+		event.UpdatedAt = now
+		if current, ok := existing[source.UID]; ok {
+			event.ID = current.ID
+			event.Priority = current.Priority
+			event.CreatedAt = current.CreatedAt
+			event.CreatedBy = current.CreatedBy
+			event.Notes = current.Notes
+			// Reminders that already fired stay fired
+			for i := range event.Reminders {
+				for _, reminder := range current.Reminders {
+					if reminder.Duration == event.Reminders[i].Duration {
+						event.Reminders[i].Sent = reminder.Sent
+						event.Reminders[i].SentFor = reminder.SentFor
+					}
+				}
+			}
+			result.Updated++
+		} else {
+			event.ID = a.newID("event")
+			event.Priority = multiagent.PriorityMedium
+			event.CreatedBy = a.id
+			if event.CreatedAt.IsZero() {
+				event.CreatedAt = now
+			}
+			result.Imported++
+		}
+		existing[source.UID] = event
+
+		a.scheduleMutex.Lock()
+		a.calendar[event.ID] = event
+		a.scheduleMutex.Unlock()
+		if err := a.saveEvent(ctx, event); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// handleExportCalendar replies with the user's calendar as an .ics file
+func (a *SchedulerAgent) handleExportCalendar(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	data, err := a.ExportICS(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return a.respond(msg, fmt.Sprintf("📤 **Calendar Export**\n\nSave the following as a `.ics` file to import it into Google Calendar, Apple Calendar or Outlook:\n\n```\n%s```", data), map[string]interface{}{
+		"action": "calendar_exported",
+		"ics":    string(data),
+	}), nil
+}
+
+// handleImportCalendar imports the iCalendar data pasted into msg
+func (a *SchedulerAgent) handleImportCalendar(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	content := msg.Content
+	begin := strings.Index(content, "BEGIN:VCALENDAR")
+	data := content[begin:]
+	intro := content[:begin]
+	if end := strings.LastIndex(data, "END:VCALENDAR"); end >= 0 {
+		intro += " " + data[end+len("END:VCALENDAR"):]
+		data = data[:end+len("END:VCALENDAR")]
+	}
+
+	// Someone else's calendar is kept as their busy times
+	if name, email := a.importOwner(ctx, intro); name != "" || email != "" {
+		return a.handleImportParticipantCalendar(ctx, msg, name, email, []byte(data))
+	}
+
+	result, err := a.ImportICS(ctx, []byte(data))
+	if err != nil {
+		return a.respond(msg, fmt.Sprintf("❌ I couldn't read that calendar: %v", err), nil), nil
+	}
+
+	reply := fmt.Sprintf("📥 **Calendar Imported**\n\n• %d new events\n• %d updated events", result.Imported, result.Updated)
+	if result.Skipped > 0 {
+		reply += fmt.Sprintf("\n• %d changes to single occurrences of recurring events were skipped", result.Skipped)
+	}
+	return a.respond(msg, reply, map[string]interface{}{
+		"action":   "calendar_imported",
+		"imported": result.Imported,
+		"updated":  result.Updated,
+		"skipped":  result.Skipped,
+	}), nil
+}
+
+// icalUID returns the UID event is exported with
+func icalUID(event *CalendarEvent) string {
+	if uid, ok := event.Metadata[icalUIDKey].(string); ok && uid != "" {
+		return uid
+	}
+	return event.ID + "@wikillm"
+}
+
+// eventToICS converts an event, with times in its own timezone
+func eventToICS(event *CalendarEvent) ical.Event {
+	loc := event.location()
+	out := ical.Event{
+		UID:          icalUID(event),
+		Summary:      event.Title,
+		Description:  event.Description,
+		Location:     event.Location,
+		URL:          event.URL,
+		Start:        event.StartTime.In(loc),
+		End:          event.EndTime.In(loc),
+		AllDay:       event.AllDay,
+		Created:      event.CreatedAt,
+		LastModified: event.UpdatedAt,
+	}
+	if out.URL == "" {
+		out.URL = event.ConferenceURL
+	}
+
+	switch event.Status {
+	case EventStatusTentative, EventStatusPostponed:
+		out.Status = ical.StatusTentative
+	case EventStatusCancelled:
+		out.Status = ical.StatusCancelled
+	default:
+		out.Status = ical.StatusConfirmed
+	}
+	if event.Category != "" {
+		out.Categories = append(out.Categories, string(event.Category))
+	}
+	out.Categories = append(out.Categories, event.Tags...)
+
+	for _, attendee := range event.Attendees {
+		out.Attendees = append(out.Attendees, ical.Attendee{
+			Name:     attendee.Name,
+			Email:    attendee.Email,
+			Status:   attendeeStatusToICS(attendee.Status),
+			Optional: !attendee.Required || attendee.Role == AttendeeRoleOptional,
+		})
+	}
+	for _, reminder := range event.Reminders {
+		action := "DISPLAY"
+		if reminder.Method == ReminderMethodEmail {
+			action = "EMAIL"
+		}
+		out.Alarms = append(out.Alarms, ical.Alarm{Before: reminder.Duration, Action: action, Description: reminder.Message})
+	}
+
+	if rule := event.Recurring; rule != nil {
+		out.RRule = &ical.RRule{
+			Freq:     strings.ToUpper(string(rule.Frequency)),
+			Interval: rule.Interval,
+			Count:    rule.Count,
+			Until:    rule.EndDate,
+		}
+		for _, weekday := range rule.DaysOfWeek {
+			day := ical.WeekdayNum{Day: weekday}
+			if rule.Frequency == RecurrenceFreqMonthly || rule.Frequency == RecurrenceFreqYearly {
+				day.N = rule.WeekOfMonth
+			}
+			out.RRule.ByDay = append(out.RRule.ByDay, day)
+		}
+		if rule.DayOfMonth != 0 && len(rule.DaysOfWeek) == 0 {
+			out.RRule.ByMonthDay = []int{rule.DayOfMonth}
+		}
+		if rule.MonthOfYear != 0 {
+			out.RRule.ByMonth = []int{rule.MonthOfYear}
+		}
+		out.ExDates = rule.Exceptions
+	}
+	return out
+}
+
+// eventFromICS converts an imported event; floating and all-day times are
+// read in loc, the user's timezone. Rule parts with several values that
+// RecurrenceRule cannot hold keep their first value.
+func eventFromICS(source *ical.Event, loc *time.Location) *CalendarEvent {
+	start, end := source.Start, source.End
+	if source.AllDay {
+		start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+		end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc)
+	} else if start.Location() == time.UTC {
+		start, end = start.In(loc), end.In(loc)
+	}
+
+	event := &CalendarEvent{
+		Title:       source.Summary,
+		Description: source.Description,
+		StartTime:   start.UTC(),
+		EndTime:     end.UTC(),
+		AllDay:      source.AllDay,
+		Location:    source.Location,
+		URL:         source.URL,
+		Category:    EventCategoryAppointment,
+		Tags:        []string{},
+		CreatedAt:   source.Created,
+		Timezone:    start.Location().String(),
+		Metadata:    map[string]interface{}{icalUIDKey: source.UID},
+	}
+
+	switch source.Status {
+	case ical.StatusCancelled:
+		event.Status = EventStatusCancelled
+	case ical.StatusTentative:
+		event.Status = EventStatusTentative
+	default:
+		event.Status = EventStatusConfirmed
+	}
+	for i, category := range source.Categories {
+		if i == 0 && isEventCategory(category) {
+			event.Category = EventCategory(strings.ToLower(category))
+			continue
+		}
+		event.Tags = append(event.Tags, category)
+	}
+
+	for _, attendee := range source.Attendees {
+		role := AttendeeRoleParticipant
+		if attendee.Optional {
+			role = AttendeeRoleOptional
+		}
+		name := attendee.Name
+		if name == "" {
+			name = attendee.Email
+		}
+		event.Attendees = append(event.Attendees, Attendee{
+			Name:     name,
+			Email:    attendee.Email,
+			Role:     role,
+			Status:   attendeeStatusFromICS(attendee.Status),
+			Required: !attendee.Optional,
+		})
+	}
+	for i, alarm := range source.Alarms {
+		method := ReminderMethodNotification
+		if alarm.Action == "EMAIL" {
+			method = ReminderMethodEmail
+		}
+		message := alarm.Description
+		if message == "" || message == source.Summary {
+			message = "Event reminder"
+		}
+		event.Reminders = append(event.Reminders, EventReminder{
+			ID:       fmt.Sprintf("reminder_%d", i),
+			Duration: alarm.Before,
+			Method:   method,
+			Message:  message,
+		})
+	}
+
+	if source.RRule != nil {
+		rule := &RecurrenceRule{
+			Frequency: RecurrenceFreq(strings.ToLower(source.RRule.Freq)),
+			Interval:  source.RRule.Interval,
+			Count:     source.RRule.Count,
+			EndDate:   source.RRule.Until,
+		}
+		if rule.Interval < 1 {
+			rule.Interval = 1
+		}
+		for _, day := range source.RRule.ByDay {
+			rule.DaysOfWeek = append(rule.DaysOfWeek, day.Day)
+			if day.N != 0 && rule.WeekOfMonth == 0 {
+				rule.WeekOfMonth = day.N
+			}
+		}
+		if len(source.RRule.ByMonthDay) > 0 {
+			rule.DayOfMonth = source.RRule.ByMonthDay[0]
+		}
+		if len(source.RRule.ByMonth) > 0 {
+			rule.MonthOfYear = source.RRule.ByMonth[0]
+		}
+		rule.Exceptions = source.ExDates
+		event.Recurring = rule
+	}
+	return event
+}
+
+func isEventCategory(name string) bool {
+	switch EventCategory(strings.ToLower(name)) {
+	case EventCategoryMeeting, EventCategoryAppointment, EventCategoryTask, EventCategoryDeadline,
+		EventCategoryPersonal, EventCategoryWork, EventCategoryTravel, EventCategoryBreak,
+		EventCategoryFocusTime, EventCategoryReminder:
+		return true
+	}
+	return false
+}
+
+func attendeeStatusToICS(status AttendeeStatus) string {
+	switch status {
+	case AttendeeStatusAccepted:
+		return "ACCEPTED"
+	case AttendeeStatusDeclined:
+		return "DECLINED"
+	case AttendeeStatusTentative:
+		return "TENTATIVE"
+	default:
+		return "NEEDS-ACTION"
+	}
+}
+
+func attendeeStatusFromICS(status string) AttendeeStatus {
+	switch status {
+	case "ACCEPTED":
+		return AttendeeStatusAccepted
+	case "DECLINED":
+		return AttendeeStatusDeclined
+	case "TENTATIVE":
+		return AttendeeStatusTentative
+	default:
+		return AttendeeStatusPending
+	}
+}
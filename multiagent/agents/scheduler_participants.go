@@ -0,0 +1,497 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/ical"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// participantKeyPrefix is where other people's busy times are stored
+const participantKeyPrefix = "participant:"
+
+const (
+	// busyHorizon bounds how far ahead imported recurring events are
+	// expanded into busy times
+	busyHorizon = 90 * 24 * time.Hour
+	// inviteTimeout bounds how long scheduling waits for invitation drafts
+	inviteTimeout = 30 * time.Second
+)
+
+// Busy time sources
+const (
+	BusySourceICS    = "ics"
+	BusySourceManual = "manual"
+)
+
+// Participant is someone other than the user whose busy times are known,
+// so meetings with them can be placed when everyone is free
+type Participant struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Email     string       `json:"email,omitempty"`
+	Busy      []BusyPeriod `json:"busy"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	UserID    string       `json:"user_id,omitempty"`
+}
+
+// BusyPeriod is a time a participant is not available
+type BusyPeriod struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Source string    `json:"source"`
+}
+
+// ParticipantCalendar is implemented by agents that track other people's
+// busy times
+type ParticipantCalendar interface {
+	// ImportParticipantICS replaces the busy times imported for a
+	// participant with the events in data
+	ImportParticipantICS(ctx context.Context, name, email string, data []byte) (*Participant, error)
+}
+
+// participantID identifies a participant by email, or by name without one
+func participantID(name, email string) string {
+	if email != "" {
+		return strings.ToLower(strings.TrimSpace(email))
+	}
+	return strings.Join(strings.Fields(strings.ToLower(name)), "_")
+}
+
+// label names a participant for replies
+func (p *Participant) label() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.Email
+}
+
+// setBusy replaces the participant's busy times from source, dropping
+// those that ended more than a day before now
+func (p *Participant) setBusy(source string, periods []BusyPeriod, now time.Time) {
+	cutoff := now.Add(-24 * time.Hour)
+	var kept []BusyPeriod
+	for _, period := range p.Busy {
+		if period.Source != source && period.End.After(cutoff) {
+			kept = append(kept, period)
+		}
+	}
+	for _, period := range periods {
+		if period.End.After(cutoff) && period.End.After(period.Start) {
+			period.Source = source
+			kept = append(kept, period)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Start.Before(kept[j].Start) })
+	p.Busy = kept
+}
+
+// addBusy adds a busy time entered by hand
+func (p *Participant) addBusy(period BusyPeriod, now time.Time) {
+	var manual []BusyPeriod
+	for _, existing := range p.Busy {
+		if existing.Source == BusySourceManual {
+			manual = append(manual, existing)
+		}
+	}
+	p.setBusy(BusySourceManual, append(manual, period), now)
+}
+
+// busyBetween returns the participant's busy times overlapping from-to
+func (p *Participant) busyBetween(from, to time.Time) []timeWindow {
+	var windows []timeWindow
+	for _, period := range p.Busy {
+		if period.Start.Before(to) && period.End.After(from) {
+			windows = append(windows, timeWindow{period.Start, period.End})
+		}
+	}
+	return windows
+}
+
+// participantsBusy returns every participant's busy times overlapping
+// from-to
+func participantsBusy(participants []*Participant, from, to time.Time) []timeWindow {
+	var windows []timeWindow
+	for _, p := range participants {
+		windows = append(windows, p.busyBetween(from, to)...)
+	}
+	return windows
+}
+
+// loadParticipants returns the participants of the user ctx acts for
+func (a *SchedulerAgent) loadParticipants(ctx context.Context) []*Participant {
+	if a.memoryStore == nil {
+		return nil
+	}
+	keys, err := a.memoryStore.List(ctx, participantKeyPrefix, 1000)
+	if err != nil || len(keys) == 0 {
+		return nil
+	}
+	values, err := a.memoryStore.GetMultiple(ctx, keys)
+	if err != nil {
+		return nil
+	}
+
+	var participants []*Participant
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		var participant Participant
+		if err := json.Unmarshal(data, &participant); err == nil && ownedBy(ctx, participant.UserID) {
+			participants = append(participants, &participant)
+		}
+	}
+	sort.Slice(participants, func(i, j int) bool { return participants[i].ID < participants[j].ID })
+	return participants
+}
+
+// findParticipant returns the participant with the given email, or whose
+// name contains who
+func (a *SchedulerAgent) findParticipant(ctx context.Context, who string) *Participant {
+	who = strings.ToLower(strings.TrimSpace(who))
+	if who == "" {
+		return nil
+	}
+	participants := a.loadParticipants(ctx)
+	for _, p := range participants {
+		if p.ID == who || strings.EqualFold(p.Email, who) || strings.EqualFold(p.Name, who) {
+			return p
+		}
+	}
+	for _, p := range participants {
+		if p.Name != "" && strings.Contains(strings.ToLower(p.Name), who) {
+			return p
+		}
+	}
+	return nil
+}
+
+// participantFor returns the known participant for name or email, or a new
+// one
+func (a *SchedulerAgent) participantFor(ctx context.Context, name, email string) *Participant {
+	for _, who := range []string{email, name} {
+		if p := a.findParticipant(ctx, who); p != nil {
+			if p.Email == "" && email != "" {
+				p.Email = email
+			}
+			if p.Name == "" {
+				p.Name = name
+			}
+			return p
+		}
+	}
+	return &Participant{
+		ID:     participantID(name, email),
+		Name:   name,
+		Email:  email,
+		UserID: multiagent.UserIDFromContext(ctx),
+	}
+}
+
+// saveParticipant stores a participant in the memory of the user ctx acts
+// for
+func (a *SchedulerAgent) saveParticipant(ctx context.Context, participant *Participant) error {
+	if a.memoryStore == nil {
+		return fmt.Errorf("no memory store to save busy times in")
+	}
+	participant.UpdatedAt = a.now()
+	if err := a.memoryStore.Store(ctx, participantKeyPrefix+participant.ID, participant); err != nil {
+		return fmt.Errorf("failed to save participant %s: %w", participant.ID, err)
+	}
+	return nil
+}
+
+// resolveParticipants looks up the named people, returning those with
+// known busy times and the names of the rest
+func (a *SchedulerAgent) resolveParticipants(ctx context.Context, names []string) ([]*Participant, []string) {
+	var known []*Participant
+	var unknown []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		p := a.findParticipant(ctx, name)
+		switch {
+		case p == nil:
+			if strings.TrimSpace(name) != "" {
+				unknown = append(unknown, name)
+			}
+		case !seen[p.ID]:
+			seen[p.ID] = true
+			known = append(known, p)
+		}
+	}
+	return known, unknown
+}
+
+// ImportParticipantICS records the events in an iCalendar file as a
+// participant's busy times, replacing those imported before. Cancelled and
+// transparent (free) events are ignored and recurring ones are expanded
+// over the next 90 days.
+func (a *SchedulerAgent) ImportParticipantICS(ctx context.Context, name, email string, data []byte) (*Participant, error) {
+	if name == "" && email == "" {
+		return nil, fmt.Errorf("participant needs a name or an email")
+	}
+	cal, err := ical.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to import busy times: %w", err)
+	}
+
+	loc := userLocation(ctx, a.memoryStore)
+	from := a.now().Add(-24 * time.Hour)
+	to := a.now().Add(busyHorizon)
+	var periods []BusyPeriod
+	for i := range cal.Events {
+		source := &cal.Events[i]
+		if source.Transparent || source.Status == ical.StatusCancelled {
+			continue
+		}
+		for _, occurrence := range expandEvent(eventFromICS(source, loc), from, to) {
+			periods = append(periods, BusyPeriod{Start: occurrence.StartTime, End: occurrence.EndTime})
+		}
+	}
+
+	participant := a.participantFor(ctx, name, email)
+	participant.setBusy(BusySourceICS, periods, a.now())
+	if err := a.saveParticipant(ctx, participant); err != nil {
+		return nil, err
+	}
+	return participant, nil
+}
+
+// importOwner asks whose calendar a pasted iCalendar file is, from the
+// words around it; both are empty for the user's own
+func (a *SchedulerAgent) importOwner(ctx context.Context, intro string) (name, email string) {
+	intro = strings.TrimSpace(intro)
+	if intro == "" {
+		return "", ""
+	}
+	prompt, err := a.renderPrompt(ctx, "scheduler.import_owner", prompts.Vars{"Intro": intro})
+	if err != nil {
+		a.logger.WarnContext(ctx, "Failed to tell whose calendar was pasted", "error", err)
+		return "", ""
+	}
+
+	var data struct {
+		Participant string `json:"participant"`
+		Email       string `json:"email"`
+	}
+	schema := objectSchema(map[string]string{"participant": "string", "email": "string"})
+	if err := a.queryJSON(ctx, prompt, schema, &data); err != nil {
+		a.logger.WarnContext(ctx, "Failed to tell whose calendar was pasted", "error", err)
+		return "", ""
+	}
+	return strings.TrimSpace(data.Participant), strings.TrimSpace(data.Email)
+}
+
+// handleImportParticipantCalendar imports a pasted calendar as name's busy
+// times
+func (a *SchedulerAgent) handleImportParticipantCalendar(ctx context.Context, msg *multiagent.Message, name, email string, data []byte) (*multiagent.Message, error) {
+	participant, err := a.ImportParticipantICS(ctx, name, email, data)
+	if err != nil {
+		return a.respond(msg, fmt.Sprintf("❌ I couldn't read that calendar: %v", err), nil), nil
+	}
+	return a.respond(msg, fmt.Sprintf("👥 **Busy Times Imported**\n\nRecorded %d busy times for %s over the next 90 days. I'll avoid them when suggesting meetings with them.", len(participant.Busy), participant.label()), map[string]interface{}{
+		"action":         "participant_imported",
+		"participant_id": participant.ID,
+		"busy":           len(participant.Busy),
+	}), nil
+}
+
+// handleParticipantBusy records a busy time someone else mentioned
+func (a *SchedulerAgent) handleParticipantBusy(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	loc := userLocation(ctx, a.memoryStore)
+	prompt, err := a.renderPrompt(ctx, "scheduler.participant_busy", prompts.Vars{
+		"Request":  msg.Content,
+		"Timezone": loc,
+		"Now":      a.now().In(loc).Format("2006-01-02 15:04 (Monday)"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Participant string `json:"participant"`
+		Email       string `json:"email"`
+		StartTime   string `json:"start_time"`
+		EndTime     string `json:"end_time"`
+	}
+	schema := objectSchema(map[string]string{
+		"participant": "string",
+		"email":       "string",
+		"start_time":  "string",
+		"end_time":    "string",
+	}, "participant", "start_time")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, schema, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse busy time: %w", err)
+	}
+
+	now := a.now().In(loc)
+	start, err := resolveTime(data.StartTime, msg.Content, now)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time format: %w", err)
+	}
+	end, err := resolveTime(data.EndTime, "", now)
+	if err != nil || !end.After(start) {
+		end = start.Add(time.Hour)
+	}
+
+	participant := a.participantFor(ctx, data.Participant, data.Email)
+	participant.addBusy(BusyPeriod{Start: start.UTC(), End: end.UTC()}, a.now())
+	if err := a.saveParticipant(ctx, participant); err != nil {
+		return nil, err
+	}
+	return a.respond(msg, fmt.Sprintf("👥 Noted: **%s** is busy %s - %s.", participant.label(), start.Format("Mon 2006-01-02 15:04"), end.Format("15:04 MST")), map[string]interface{}{
+		"action":         "participant_busy",
+		"participant_id": participant.ID,
+	}), nil
+}
+
+// resolveAttendees fills in the emails of attendees who are known
+// participants, and of those given as an email address
+func (a *SchedulerAgent) resolveAttendees(ctx context.Context, attendees []Attendee) ([]Attendee, []*Participant) {
+	var participants []*Participant
+	for i := range attendees {
+		attendee := &attendees[i]
+		if attendee.Email == "" && strings.Contains(attendee.Name, "@") {
+			attendee.Email = attendee.Name
+		}
+		p := a.findParticipant(ctx, attendee.Email)
+		if p == nil {
+			p = a.findParticipant(ctx, attendee.Name)
+		}
+		if p == nil {
+			continue
+		}
+		participants = append(participants, p)
+		if attendee.Email == "" {
+			attendee.Email = p.Email
+		}
+		if attendee.Name == attendee.Email && p.Name != "" {
+			attendee.Name = p.Name
+		}
+	}
+	return attendees, participants
+}
+
+// busyParticipants lists the participants busy during start-end
+func busyParticipants(participants []*Participant, start, end time.Time) []string {
+	var busy []string
+	for _, p := range participants {
+		if len(p.busyBetween(start, end)) > 0 {
+			busy = append(busy, p.label())
+		}
+	}
+	return busy
+}
+
+// EventInvite asks the communication manager to draft invitations to an
+// event for its attendees
+type EventInvite struct {
+	EventID   string     `json:"event_id"`
+	Title     string     `json:"title"`
+	When      string     `json:"when"`
+	Location  string     `json:"location,omitempty"`
+	Details   string     `json:"details,omitempty"`
+	Organizer string     `json:"organizer,omitempty"`
+	Attendees []Attendee `json:"attendees"`
+	// ICS is the iCalendar invitation (METHOD:REQUEST) to attach
+	ICS string `json:"ics"`
+}
+
+// inviteContextKey is the message context key an EventInvite travels in
+const inviteContextKey = "event_invite"
+
+// inviteICS returns the iCalendar invitation to event
+func inviteICS(event *CalendarEvent, organizer string) ([]byte, error) {
+	invite := eventToICS(event)
+	invite.Organizer = &ical.Attendee{Name: organizer}
+	for i := range invite.Attendees {
+		invite.Attendees[i].Status = "NEEDS-ACTION"
+	}
+
+	var buf bytes.Buffer
+	if err := ical.Encode(&buf, &ical.Calendar{Method: ical.MethodRequest, Events: []ical.Event{invite}}); err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// sendInvites creates the invitation to event and asks the communication
+// manager to draft emails carrying it, returning a note for the reply
+func (a *SchedulerAgent) sendInvites(ctx context.Context, msg *multiagent.Message, event *CalendarEvent) (string, []byte) {
+	organizer := multiagent.UserIDFromContext(ctx)
+	data, err := inviteICS(event, organizer)
+	if err != nil {
+		a.logger.WarnContext(ctx, "Failed to create invitation", "event_id", event.ID, "error", err)
+		return "", nil
+	}
+
+	commManager, ok := a.agentOfType(multiagent.AgentTypeCommunicationManager)
+	if !ok {
+		return "📎 The invitation (.ics) is attached for you to send.", data
+	}
+
+	loc := event.location()
+	invite := EventInvite{
+		EventID:   event.ID,
+		Title:     event.Title,
+		When:      event.StartTime.In(loc).Format("Mon Jan 2, 2006 15:04") + " - " + event.EndTime.In(loc).Format("15:04 MST"),
+		Location:  event.Location,
+		Details:   event.Description,
+		Organizer: organizer,
+		Attendees: event.Attendees,
+		ICS:       string(data),
+	}
+	requestContext := map[string]interface{}{
+		inviteContextKey:         invite,
+		multiagent.ContextUserID: organizer,
+	}
+	if conversationID, ok := msg.Context["conversation_id"].(string); ok {
+		requestContext["conversation_id"] = conversationID
+	}
+
+	future, err := a.RequestMessage(ctx, &multiagent.Message{
+		To:      []multiagent.AgentID{commManager},
+		Content: fmt.Sprintf("Draft invitations to %q for its attendees", event.Title),
+		Context: requestContext,
+	})
+	if err == nil {
+		waitCtx, cancel := context.WithTimeout(ctx, inviteTimeout)
+		defer cancel()
+		var reply *multiagent.Message
+		if reply, err = future.Wait(waitCtx); err == nil {
+			return reply.Content, data
+		}
+		future.Cancel()
+	}
+	a.logger.WarnContext(ctx, "Failed to draft invitations", "event_id", event.ID, "error", err)
+	return "📎 I couldn't draft the invitation emails; the invitation (.ics) is attached for you to send.", data
+}
+
+// decodeInvite reads the EventInvite a message carries, if any
+func decodeInvite(msg *multiagent.Message) (*EventInvite, bool) {
+	value, ok := msg.Context[inviteContextKey]
+	if !ok {
+		return nil, false
+	}
+	if invite, ok := value.(EventInvite); ok {
+		return &invite, true
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var invite EventInvite
+	if err := json.Unmarshal(data, &invite); err != nil {
+		return nil, false
+	}
+	return &invite, true
+}
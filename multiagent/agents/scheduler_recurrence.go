@@ -0,0 +1,185 @@
+package agents
+
+import (
+	"sort"
+	"time"
+)
+
+// maxRecurrencePeriods bounds how many periods (days, weeks, months or
+// years) an expansion walks, so a malformed rule cannot spin forever
+const maxRecurrencePeriods = 50000
+
+// Occurrences returns the start times of every occurrence of a series that
+// begins at dtstart and starts within [from, to). Like an RRULE, the first
+// occurrence is dtstart itself when it matches the rule, Count counts
+// occurrences before Exceptions are removed, and EndDate is inclusive
+func (r *RecurrenceRule) Occurrences(dtstart, from, to time.Time) []time.Time {
+	if r == nil {
+		if !dtstart.Before(from) && dtstart.Before(to) {
+			return []time.Time{dtstart}
+		}
+		return nil
+	}
+
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	var occurrences []time.Time
+	count := 0
+	for period := 0; period < maxRecurrencePeriods; period += interval {
+		candidates := r.candidates(dtstart, period)
+		if candidates == nil {
+			break
+		}
+		for _, candidate := range candidates {
+			if candidate.Before(dtstart) {
+				continue
+			}
+			if !candidate.Before(to) || (r.EndDate != nil && candidate.After(*r.EndDate)) {
+				return occurrences
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return occurrences
+			}
+			if !candidate.Before(from) && !r.isException(candidate) {
+				occurrences = append(occurrences, candidate)
+			}
+		}
+	}
+	return occurrences
+}
+
+// candidates returns the sorted occurrence times in the period-th day, week,
+// month or year after dtstart's, or nil for an unknown frequency
+func (r *RecurrenceRule) candidates(dtstart time.Time, period int) []time.Time {
+	var days []time.Time
+	switch r.Frequency {
+	case RecurrenceFreqDaily:
+		day := atTimeOf(dtstart, dtstart.Year(), dtstart.Month(), dtstart.Day()+period)
+		if len(r.DaysOfWeek) == 0 || hasWeekday(r.DaysOfWeek, day.Weekday()) {
+			days = append(days, day)
+		}
+	case RecurrenceFreqWeekly:
+		// Weeks start on Monday
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		weekdays := r.DaysOfWeek
+		if len(weekdays) == 0 {
+			weekdays = []time.Weekday{dtstart.Weekday()}
+		}
+		for _, weekday := range weekdays {
+			dayOffset := (int(weekday) + 6) % 7
+			days = append(days, atTimeOf(dtstart, dtstart.Year(), dtstart.Month(), dtstart.Day()-offset+period*7+dayOffset))
+		}
+	case RecurrenceFreqMonthly:
+		month := atTimeOf(dtstart, dtstart.Year(), dtstart.Month()+time.Month(period), 1)
+		days = r.daysInMonth(dtstart, month.Year(), month.Month())
+	case RecurrenceFreqYearly:
+		month := dtstart.Month()
+		if r.MonthOfYear >= 1 && r.MonthOfYear <= 12 {
+			month = time.Month(r.MonthOfYear)
+		}
+		days = r.daysInMonth(dtstart, dtstart.Year()+period, month)
+	default:
+		return nil
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	if days == nil {
+		days = []time.Time{}
+	}
+	return days
+}
+
+// daysInMonth returns the occurrences the rule selects within one month:
+// the WeekOfMonth-th DaysOfWeek (negative counts from the end), every
+// DaysOfWeek, or DayOfMonth (negative counts from the end), defaulting to
+// dtstart's day of the month
+func (r *RecurrenceRule) daysInMonth(dtstart time.Time, year int, month time.Month) []time.Time {
+	lastDay := atTimeOf(dtstart, year, month+1, 0).Day()
+
+	var days []time.Time
+	if len(r.DaysOfWeek) > 0 {
+		for day := 1; day <= lastDay; day++ {
+			date := atTimeOf(dtstart, year, month, day)
+			if !hasWeekday(r.DaysOfWeek, date.Weekday()) {
+				continue
+			}
+			switch {
+			case r.WeekOfMonth > 0 && (day-1)/7+1 != r.WeekOfMonth:
+				continue
+			case r.WeekOfMonth < 0 && (lastDay-day)/7+1 != -r.WeekOfMonth:
+				continue
+			}
+			days = append(days, date)
+		}
+		return days
+	}
+
+	day := dtstart.Day()
+	if r.DayOfMonth > 0 {
+		day = r.DayOfMonth
+	} else if r.DayOfMonth < 0 {
+		day = lastDay + r.DayOfMonth + 1
+	}
+	// Months without the day are skipped, as an RRULE does
+	if day < 1 || day > lastDay {
+		return nil
+	}
+	return append(days, atTimeOf(dtstart, year, month, day))
+}
+
+// isException reports whether t falls on one of the rule's excluded days
+func (r *RecurrenceRule) isException(t time.Time) bool {
+	for _, exception := range r.Exceptions {
+		exception = exception.In(t.Location())
+		if exception.Year() == t.Year() && exception.YearDay() == t.YearDay() {
+			return true
+		}
+	}
+	return false
+}
+
+// expandEvent returns the occurrences of event that overlap [from, to); each
+// is a copy of the event moved to its own time, keeping the series' ID.
+// Occurrences keep their wall-clock time in the event's timezone, so a 9:00
+// meeting stays at 9:00 across daylight saving changes
+func expandEvent(event *CalendarEvent, from, to time.Time) []*CalendarEvent {
+	if event.Recurring == nil {
+		if event.StartTime.Before(to) && event.EndTime.After(from) {
+			return []*CalendarEvent{event}
+		}
+		return nil
+	}
+
+	duration := event.EndTime.Sub(event.StartTime)
+	starts := event.Recurring.Occurrences(event.StartTime.In(event.location()), from.Add(-duration), to)
+	instances := make([]*CalendarEvent, 0, len(starts))
+	for _, start := range starts {
+		if !start.Add(duration).After(from) {
+			continue
+		}
+		instance := *event
+		instance.StartTime = start
+		instance.EndTime = start.Add(duration)
+		instances = append(instances, &instance)
+	}
+	return instances
+}
+
+// atTimeOf returns the given day at dtstart's time of day and location;
+// out-of-range days and months normalise as with time.Date
+func atTimeOf(dtstart time.Time, year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, dtstart.Hour(), dtstart.Minute(), dtstart.Second(), dtstart.Nanosecond(), dtstart.Location())
+}
+
+func hasWeekday(weekdays []time.Weekday, weekday time.Weekday) bool {
+	for _, w := range weekdays {
+		if w == weekday {
+			return true
+		}
+	}
+	return false
+}
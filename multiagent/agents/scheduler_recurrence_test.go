@@ -0,0 +1,186 @@
+package agents
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceRule_Occurrences(t *testing.T) {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 9, 0, 0, 0, time.UTC)
+	}
+	until := func(month time.Month, d int) *time.Time {
+		end := day(month, d)
+		return &end
+	}
+
+	tests := []struct {
+		name    string
+		rule    RecurrenceRule
+		dtstart time.Time
+		from    time.Time
+		to      time.Time
+		want    []time.Time
+	}{
+		{
+			// Months without a 31st are skipped rather than clamped
+			name:    "monthly on the 31st",
+			rule:    RecurrenceRule{Frequency: RecurrenceFreqMonthly},
+			dtstart: day(time.January, 31),
+			from:    day(time.January, 1),
+			to:      day(time.August, 1),
+			want:    []time.Time{day(time.January, 31), day(time.March, 31), day(time.May, 31), day(time.July, 31)},
+		},
+		{
+			name:    "monthly on the last day",
+			rule:    RecurrenceRule{Frequency: RecurrenceFreqMonthly, DayOfMonth: -1},
+			dtstart: day(time.January, 31),
+			from:    day(time.January, 1),
+			to:      day(time.May, 1),
+			want:    []time.Time{day(time.January, 31), day(time.February, 28), day(time.March, 31), day(time.April, 30)},
+		},
+		{
+			name:    "last Friday of the month",
+			rule:    RecurrenceRule{Frequency: RecurrenceFreqMonthly, DaysOfWeek: []time.Weekday{time.Friday}, WeekOfMonth: -1},
+			dtstart: day(time.January, 30),
+			from:    day(time.January, 1),
+			to:      day(time.May, 1),
+			want:    []time.Time{day(time.January, 30), day(time.February, 27), day(time.March, 27), day(time.April, 24)},
+		},
+		{
+			name:    "second Tuesday of the month",
+			rule:    RecurrenceRule{Frequency: RecurrenceFreqMonthly, DaysOfWeek: []time.Weekday{time.Tuesday}, WeekOfMonth: 2},
+			dtstart: day(time.January, 13),
+			from:    day(time.January, 1),
+			to:      day(time.April, 1),
+			want:    []time.Time{day(time.January, 13), day(time.February, 10), day(time.March, 10)},
+		},
+		{
+			name:    "count ends the series",
+			rule:    RecurrenceRule{Frequency: RecurrenceFreqWeekly, Count: 3},
+			dtstart: day(time.January, 5),
+			from:    day(time.January, 1),
+			to:      day(time.March, 1),
+			want:    []time.Time{day(time.January, 5), day(time.January, 12), day(time.January, 19)},
+		},
+		{
+			// Occurrences before the window still use up the count
+			name:    "count includes occurrences before the window",
+			rule:    RecurrenceRule{Frequency: RecurrenceFreqWeekly, Count: 3},
+			dtstart: day(time.January, 5),
+			from:    day(time.January, 13),
+			to:      day(time.March, 1),
+			want:    []time.Time{day(time.January, 19)},
+		},
+		{
+			name:    "until is inclusive",
+			rule:    RecurrenceRule{Frequency: RecurrenceFreqWeekly, EndDate: until(time.January, 19)},
+			dtstart: day(time.January, 5),
+			from:    day(time.January, 1),
+			to:      day(time.March, 1),
+			want:    []time.Time{day(time.January, 5), day(time.January, 12), day(time.January, 19)},
+		},
+		{
+			name:    "until before an occurrence ends the series",
+			rule:    RecurrenceRule{Frequency: RecurrenceFreqWeekly, EndDate: until(time.January, 18)},
+			dtstart: day(time.January, 5),
+			from:    day(time.January, 1),
+			to:      day(time.March, 1),
+			want:    []time.Time{day(time.January, 5), day(time.January, 12)},
+		},
+		{
+			// The excluded day still counts towards Count
+			name: "exceptions are removed after counting",
+			rule: RecurrenceRule{
+				Frequency:  RecurrenceFreqDaily,
+				Count:      5,
+				Exceptions: []time.Time{time.Date(2026, time.January, 7, 0, 0, 0, 0, time.UTC)},
+			},
+			dtstart: day(time.January, 5),
+			from:    day(time.January, 1),
+			to:      day(time.February, 1),
+			want:    []time.Time{day(time.January, 5), day(time.January, 6), day(time.January, 8), day(time.January, 9)},
+		},
+		{
+			name:    "weekly on several days, every other week",
+			rule:    RecurrenceRule{Frequency: RecurrenceFreqWeekly, Interval: 2, DaysOfWeek: []time.Weekday{time.Friday, time.Monday}},
+			dtstart: day(time.January, 5),
+			from:    day(time.January, 1),
+			to:      day(time.February, 1),
+			want:    []time.Time{day(time.January, 5), day(time.January, 9), day(time.January, 19), day(time.January, 23)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rule.Occurrences(tt.dtstart, tt.from, tt.to)
+			if !equalTimes(got, tt.want) {
+				t.Errorf("Occurrences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandEvent_KeepsWallClockAcrossDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone database unavailable: %v", err)
+	}
+	// Daylight saving time starts on Sunday 8 March 2026
+	start := time.Date(2026, time.March, 2, 9, 0, 0, 0, ny)
+	event := &CalendarEvent{
+		ID:        "standup",
+		StartTime: start,
+		EndTime:   start.Add(30 * time.Minute),
+		Timezone:  "America/New_York",
+		Recurring: &RecurrenceRule{Frequency: RecurrenceFreqWeekly},
+	}
+
+	instances := expandEvent(event, time.Date(2026, time.March, 1, 0, 0, 0, 0, ny), time.Date(2026, time.March, 17, 0, 0, 0, 0, ny))
+	if len(instances) != 3 {
+		t.Fatalf("expected 3 occurrences, got %d", len(instances))
+	}
+	for i, instance := range instances {
+		local := instance.StartTime.In(ny)
+		if local.Day() != 2+7*i || local.Hour() != 9 || local.Minute() != 0 {
+			t.Errorf("occurrence %d starts at %s, want 9:00 on March %d", i, local, 2+7*i)
+		}
+		if instance.EndTime.Sub(instance.StartTime) != 30*time.Minute {
+			t.Errorf("occurrence %d lasts %s, want 30m", i, instance.EndTime.Sub(instance.StartTime))
+		}
+		if instance.ID != "standup" {
+			t.Errorf("occurrence %d has ID %q, want the series ID", i, instance.ID)
+		}
+	}
+	// The same wall-clock time is an hour earlier in UTC once DST starts
+	if before, after := instances[0].StartTime.UTC().Hour(), instances[1].StartTime.UTC().Hour(); before != 14 || after != 13 {
+		t.Errorf("expected UTC hours 14 then 13, got %d then %d", before, after)
+	}
+}
+
+func TestExpandEvent_IncludesOccurrenceInProgress(t *testing.T) {
+	start := time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC)
+	event := &CalendarEvent{
+		StartTime: start,
+		EndTime:   start.Add(2 * time.Hour),
+		Timezone:  "UTC",
+		Recurring: &RecurrenceRule{Frequency: RecurrenceFreqDaily},
+	}
+
+	// A window opening at 10:00 still overlaps that day's 9:00-11:00 occurrence
+	instances := expandEvent(event, start.Add(25*time.Hour), start.Add(26*time.Hour))
+	if len(instances) != 1 || !instances[0].StartTime.Equal(start.Add(24*time.Hour)) {
+		t.Fatalf("expected the occurrence in progress, got %v", instances)
+	}
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
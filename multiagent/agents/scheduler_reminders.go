@@ -0,0 +1,182 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/reminders"
+)
+
+// reminderHorizons are how far ahead the next occurrence of a recurring
+// event is looked for, widening so sparse series stay cheap to search
+var reminderHorizons = []time.Duration{7 * 24 * time.Hour, 400 * 24 * time.Hour, 5 * 365 * 24 * time.Hour}
+
+// eventReminderID identifies one of an event's reminders in the engine
+func eventReminderID(event *CalendarEvent, i int) string {
+	reminderID := event.Reminders[i].ID
+	if reminderID == "" {
+		reminderID = fmt.Sprintf("%d", i)
+	}
+	return fmt.Sprintf("event:%s:%s", event.ID, reminderID)
+}
+
+// nextReminderOccurrence returns the start of the occurrence reminder fires
+// for next: the first that has not ended at now and it has not fired for
+func nextReminderOccurrence(event *CalendarEvent, reminder EventReminder, now time.Time) (time.Time, bool) {
+	if event.Recurring == nil {
+		// SentFor tells a rescheduled event's reminder to fire again
+		sent := reminder.Sent && (reminder.SentFor == nil || reminder.SentFor.Equal(event.StartTime))
+		if sent || !event.EndTime.After(now) {
+			return time.Time{}, false
+		}
+		return event.StartTime, true
+	}
+
+	for _, horizon := range reminderHorizons {
+		for _, instance := range expandEvent(event, now, now.Add(horizon)) {
+			if reminder.SentFor != nil && !instance.StartTime.After(*reminder.SentFor) {
+				continue
+			}
+			return instance.StartTime, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// scheduleEventReminders brings the engine in line with event's reminders,
+// cancelling those the event no longer has
+func (a *SchedulerAgent) scheduleEventReminders(ctx context.Context, event *CalendarEvent, now time.Time) {
+	wanted := make(map[string]reminders.Reminder)
+	a.scheduleMutex.RLock()
+	if event.Status != EventStatusCancelled && event.Status != EventStatusCompleted {
+		for i, reminder := range event.Reminders {
+			start, ok := nextReminderOccurrence(event, reminder, now)
+			if !ok {
+				continue
+			}
+			id := eventReminderID(event, i)
+			wanted[id] = reminders.Reminder{
+				ID:        id,
+				UserID:    event.UserID,
+				Source:    eventReminderSource,
+				Subject:   event.ID,
+				Title:     "📅 " + event.Title,
+				Body:      reminder.Message,
+				Kind:      notify.KindEventReminder,
+				Priority:  event.Priority,
+				TriggerAt: start.Add(-reminder.Duration),
+				Data:      map[string]string{"event_id": event.ID},
+			}
+		}
+	}
+	a.scheduleMutex.RUnlock()
+
+	for _, scheduled := range a.reminderEngine.List(event.UserID) {
+		if scheduled.Source == eventReminderSource && scheduled.Subject == event.ID {
+			if _, ok := wanted[scheduled.ID]; !ok {
+				a.reminderEngine.Cancel(ctx, scheduled.ID)
+			}
+		}
+	}
+	for id, reminder := range wanted {
+		if existing, ok := a.reminderEngine.Get(id); ok && existing.TriggerAt.Equal(reminder.TriggerAt) {
+			continue
+		}
+		if err := a.reminderEngine.Schedule(ctx, reminder); err != nil {
+			a.logger.WarnContext(ctx, "Failed to schedule event reminder", "event_id", event.ID, "error", err)
+		}
+	}
+}
+
+// fireEventReminder is the engine's handler for event reminders: it marks
+// the reminder sent for its occurrence, describes it, and returns when it
+// fires for the next occurrence
+func (a *SchedulerAgent) fireEventReminder(ctx context.Context, scheduled reminders.Reminder, now time.Time) (*notify.Notification, time.Time) {
+	eventID := scheduled.Data["event_id"]
+	a.scheduleMutex.RLock()
+	_, loaded := a.calendar[eventID]
+	a.scheduleMutex.RUnlock()
+	if !loaded {
+		// The owner has not been active since a restart
+		a.loadEventsFromMemory(ctx)
+	}
+
+	a.scheduleMutex.Lock()
+	event, ok := a.calendar[eventID]
+	if !ok || event.Status == EventStatusCancelled || event.Status == EventStatusCompleted {
+		a.scheduleMutex.Unlock()
+		return nil, time.Time{}
+	}
+	index := -1
+	for i := range event.Reminders {
+		if eventReminderID(event, i) == scheduled.ID {
+			index = i
+		}
+	}
+	if index < 0 {
+		a.scheduleMutex.Unlock()
+		return nil, time.Time{}
+	}
+	reminder := &event.Reminders[index]
+	start, ok := nextReminderOccurrence(event, *reminder, now)
+	if !ok {
+		a.scheduleMutex.Unlock()
+		return nil, time.Time{}
+	}
+	if trigger := start.Add(-reminder.Duration); trigger.After(now) {
+		// The event moved later since this was scheduled
+		a.scheduleMutex.Unlock()
+		return nil, trigger
+	}
+
+	reminder.Sent = true
+	reminder.SentFor = &start
+	fired := *reminder
+	var next time.Time
+	if nextStart, ok := nextReminderOccurrence(event, fired, now); ok {
+		next = nextStart.Add(-fired.Duration)
+	}
+	snapshot := *event
+	a.scheduleMutex.Unlock()
+
+	if err := a.persistEvent(ctx, event); err != nil {
+		a.logger.WarnContext(ctx, "Failed to save fired reminder", "event_id", event.ID, "error", err)
+	}
+	notification := a.eventReminderNotification(ctx, &snapshot, fired, start, now)
+	return &notification, next
+}
+
+// eventReminderNotification describes a fired event reminder in the event
+// owner's timezone
+func (a *SchedulerAgent) eventReminderNotification(ctx context.Context, event *CalendarEvent, reminder EventReminder, start, now time.Time) notify.Notification {
+	start = start.In(userLocation(ctx, a.memoryStore))
+
+	title := fmt.Sprintf("📅 %s at %s", event.Title, start.Format("15:04"))
+	if !start.After(now) {
+		title = fmt.Sprintf("📅 %s started at %s", event.Title, start.Format("15:04"))
+	}
+	var body []string
+	if reminder.Message != "" {
+		body = append(body, reminder.Message)
+	}
+	body = append(body, start.Format("Monday, January 2 15:04 MST"))
+	if event.Location != "" {
+		body = append(body, "📍 "+event.Location)
+	}
+	if event.ConferenceURL != "" {
+		body = append(body, "🔗 "+event.ConferenceURL)
+	}
+
+	return notify.Notification{
+		UserID:   event.UserID,
+		Kind:     notify.KindEventReminder,
+		Title:    title,
+		Body:     strings.Join(body, "\n"),
+		Priority: event.Priority,
+		At:       now,
+		Subject:  event.ID,
+	}
+}
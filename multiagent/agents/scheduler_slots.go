@@ -0,0 +1,612 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// scheduleKey is where a user's working hours and preferences are stored
+const scheduleKey = "schedule_preferences"
+
+const (
+	// slotStep is the granularity suggested meeting times start on
+	slotStep = 30 * time.Minute
+	// maxSuggestions and maxSuggestionsPerDay bound the suggested times,
+	// spreading them over several days
+	maxSuggestions       = 5
+	maxSuggestionsPerDay = 2
+)
+
+// SlotSuggestion is a proposed meeting time and why it was picked
+type SlotSuggestion struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Score   int       `json:"score"`
+	Reasons []string  `json:"reasons"`
+}
+
+// timeWindow is a span of time within a day
+type timeWindow struct {
+	start, end time.Time
+}
+
+// clockTime returns a time of day as kept in schedules, which use only the
+// hour and minute
+func clockTime(hour, minute int) time.Time {
+	return time.Date(0, 1, 1, hour, minute, 0, 0, time.UTC)
+}
+
+// defaultSchedule is used until a user sets their own: the default working
+// hours with lunch at noon and half-hour meetings
+func defaultSchedule(userID string, loc *time.Location, hours WorkingHours) *Schedule {
+	return &Schedule{
+		ID:           "schedule_" + userID,
+		Name:         "Working hours",
+		Owner:        userID,
+		Timezone:     loc.String(),
+		WorkingHours: hours,
+		Preferences: SchedulePreferences{
+			PreferredMeetingDuration: 30 * time.Minute,
+			LunchBreak:               &TimeSlot{StartTime: clockTime(12, 0), EndTime: clockTime(13, 0)},
+		},
+		Metadata: make(map[string]interface{}),
+	}
+}
+
+// day returns the schedule for a weekday
+func (w *WorkingHours) day(weekday time.Weekday) *DaySchedule {
+	return [...]*DaySchedule{&w.Sunday, &w.Monday, &w.Tuesday, &w.Wednesday, &w.Thursday, &w.Friday, &w.Saturday}[weekday]
+}
+
+// appliesOn reports whether a slot limited to weekdays covers weekday
+func (s *TimeSlot) appliesOn(weekday time.Weekday) bool {
+	return len(s.Weekdays) == 0 || hasWeekday(s.Weekdays, weekday)
+}
+
+// on returns the slot's window on day
+func (s *TimeSlot) on(day time.Time) timeWindow {
+	return timeWindow{atClock(day, s.StartTime), atClock(day, s.EndTime)}
+}
+
+// userSchedule returns the working hours and preferences of the user ctx
+// acts for, or the defaults
+func (a *SchedulerAgent) userSchedule(ctx context.Context) *Schedule {
+	userID := multiagent.UserIDFromContext(ctx)
+	a.scheduleMutex.RLock()
+	schedule, ok := a.schedules[userID]
+	hours := a.defaultHours
+	a.scheduleMutex.RUnlock()
+	if ok {
+		return schedule
+	}
+
+	schedule = defaultSchedule(userID, userLocation(ctx, a.memoryStore), hours)
+	if a.memoryStore != nil {
+		if value, err := a.memoryStore.Get(ctx, scheduleKey); err == nil {
+			if data, err := json.Marshal(value); err == nil {
+				var stored Schedule
+				if err := json.Unmarshal(data, &stored); err == nil {
+					schedule = &stored
+				}
+			}
+		}
+	}
+
+	a.scheduleMutex.Lock()
+	a.schedules[userID] = schedule
+	a.scheduleMutex.Unlock()
+	return schedule
+}
+
+// saveSchedule stores the working hours and preferences of the user ctx
+// acts for
+func (a *SchedulerAgent) saveSchedule(ctx context.Context, schedule *Schedule) error {
+	schedule.UpdatedAt = a.now()
+	a.scheduleMutex.Lock()
+	a.schedules[multiagent.UserIDFromContext(ctx)] = schedule
+	a.scheduleMutex.Unlock()
+
+	if a.memoryStore == nil {
+		return nil
+	}
+	if err := a.memoryStore.Store(ctx, scheduleKey, schedule); err != nil {
+		return fmt.Errorf("failed to save schedule: %w", err)
+	}
+	return nil
+}
+
+// isMeeting reports whether an event counts toward the meetings-per-day
+// limit
+func isMeeting(event *CalendarEvent) bool {
+	switch event.Category {
+	case EventCategoryFocusTime, EventCategoryBreak, EventCategoryReminder, EventCategoryTask, EventCategoryDeadline, EventCategoryTravel:
+		return false
+	}
+	return !event.AllDay
+}
+
+// blocksTime reports whether an event keeps its time from being booked;
+// all-day deadlines and reminders do not
+func blocksTime(event *CalendarEvent) bool {
+	if !event.AllDay {
+		return true
+	}
+	return event.Category != EventCategoryDeadline && event.Category != EventCategoryReminder && event.Category != EventCategoryTask
+}
+
+// freeWindows returns the gaps in day's working hours left by its events
+// (padded by the buffer time), breaks, lunch, focus blocks, blocked times
+// and others' busy times
+func freeWindows(schedule *Schedule, day time.Time, events []*CalendarEvent, others []timeWindow) []timeWindow {
+	hours := schedule.WorkingHours.day(day.Weekday())
+	if !hours.IsWorkingDay {
+		return nil
+	}
+	work := timeWindow{atClock(day, hours.StartTime), atClock(day, hours.EndTime)}
+	prefs := schedule.Preferences
+
+	busy := append([]timeWindow(nil), others...)
+	for _, event := range events {
+		if blocksTime(event) {
+			busy = append(busy, timeWindow{event.StartTime.Add(-prefs.BufferTime), event.EndTime.Add(prefs.BufferTime)})
+		}
+	}
+	for _, block := range hours.BreakTimes {
+		busy = append(busy, timeWindow{atClock(day, block.StartTime), atClock(day, block.EndTime)})
+	}
+	if prefs.LunchBreak != nil && prefs.LunchBreak.appliesOn(day.Weekday()) {
+		busy = append(busy, prefs.LunchBreak.on(day))
+	}
+	for i := range prefs.FocusTimeBlocks {
+		if focus := &prefs.FocusTimeBlocks[i]; focus.appliesOn(day.Weekday()) {
+			busy = append(busy, focus.on(day))
+		}
+	}
+	for _, block := range schedule.BlockedTimes {
+		if block.Recurring {
+			busy = append(busy, timeWindow{atClock(day, block.StartTime), atClock(day, block.EndTime)})
+		} else {
+			busy = append(busy, timeWindow{block.StartTime, block.EndTime})
+		}
+	}
+	sort.Slice(busy, func(i, j int) bool { return busy[i].start.Before(busy[j].start) })
+
+	var free []timeWindow
+	cursor := work.start
+	for _, b := range busy {
+		if b.start.After(cursor) {
+			free = append(free, timeWindow{cursor, minTime(b.start, work.end)})
+		}
+		if b.end.After(cursor) {
+			cursor = b.end
+		}
+		if !cursor.Before(work.end) {
+			break
+		}
+	}
+	if cursor.Before(work.end) {
+		free = append(free, timeWindow{cursor, work.end})
+	}
+
+	windows := free[:0]
+	for _, w := range free {
+		if w.end.After(w.start) {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// findAvailableSlots returns the free time at least duration long within
+// the user's working hours between startDate and endDate, from now on,
+// when the participants are free as well
+func (a *SchedulerAgent) findAvailableSlots(ctx context.Context, schedule *Schedule, participants []*Participant, startDate, endDate time.Time, duration time.Duration) []ScheduleTimeRange {
+	var slots []ScheduleTimeRange
+	if duration <= 0 {
+		duration = time.Minute
+	}
+	now := a.now()
+	for day := startDate; day.Before(endDate); day = day.AddDate(0, 0, 1) {
+		others := participantsBusy(participants, day, day.AddDate(0, 0, 1))
+		for _, w := range freeWindows(schedule, day, a.getEventsForDate(ctx, day), others) {
+			if !w.end.After(now) {
+				continue
+			}
+			if w.start.Before(now) {
+				w.start = now
+			}
+			if w.end.Sub(w.start) >= duration {
+				start, end := w.start, w.end
+				slots = append(slots, ScheduleTimeRange{Start: &start, End: &end})
+			}
+		}
+	}
+	return slots
+}
+
+// suggestSlots ranks meeting times of the given duration between startDate
+// and endDate against the user's preferences and returns the best few.
+// preferredTimes are parts of the day ("morning", "afternoon", "evening")
+// the request asked for; the participants must be free too.
+func (a *SchedulerAgent) suggestSlots(ctx context.Context, schedule *Schedule, participants []*Participant, startDate, endDate time.Time, duration time.Duration, preferredTimes []string, now time.Time) []SlotSuggestion {
+	prefs := schedule.Preferences
+	if duration <= 0 {
+		duration = prefs.PreferredMeetingDuration
+	}
+	if duration <= 0 {
+		duration = slotStep
+	}
+
+	var candidates []SlotSuggestion
+	for day, dayIndex := startDate, 0; day.Before(endDate); day, dayIndex = day.AddDate(0, 0, 1), dayIndex+1 {
+		events := a.getEventsForDate(ctx, day)
+		meetings := 0
+		for _, event := range events {
+			if isMeeting(event) {
+				meetings++
+			}
+		}
+		if prefs.MaxMeetingsPerDay > 0 && meetings >= prefs.MaxMeetingsPerDay {
+			continue
+		}
+
+		others := participantsBusy(participants, day, day.AddDate(0, 0, 1))
+		for _, w := range freeWindows(schedule, day, events, others) {
+			start := alignToStep(maxTime(w.start, now))
+			for ; !start.Add(duration).After(w.end); start = start.Add(slotStep) {
+				candidate := scoreSlot(schedule, events, start, start.Add(duration), dayIndex, meetings, preferredTimes)
+				if len(participants) > 0 {
+					candidate.Reasons = append(candidate.Reasons, "everyone is free")
+				}
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Start.Before(candidates[j].Start)
+	})
+
+	var picked []SlotSuggestion
+	perDay := make(map[string]int)
+	for _, candidate := range candidates {
+		day := candidate.Start.Format("2006-01-02")
+		if perDay[day] >= maxSuggestionsPerDay || overlapsAny(picked, candidate) {
+			continue
+		}
+		picked = append(picked, candidate)
+		perDay[day]++
+		if len(picked) == maxSuggestions {
+			break
+		}
+	}
+	return picked
+}
+
+// scoreSlot rates one candidate meeting time, noting the reasons
+func scoreSlot(schedule *Schedule, events []*CalendarEvent, start, end time.Time, dayIndex, meetings int, preferredTimes []string) SlotSuggestion {
+	prefs := schedule.Preferences
+	suggestion := SlotSuggestion{Start: start, End: end, Score: 100 - 2*dayIndex}
+	reason := func(points int, format string, args ...interface{}) {
+		suggestion.Score += points
+		suggestion.Reasons = append(suggestion.Reasons, fmt.Sprintf(format, args...))
+	}
+
+	for i := range prefs.PreferredTimeSlots {
+		preferred := &prefs.PreferredTimeSlots[i]
+		if w := preferred.on(start); preferred.appliesOn(start.Weekday()) && !start.Before(w.start) && !end.After(w.end) {
+			reason(20, "within your preferred meeting hours")
+			break
+		}
+	}
+	if part := partOfDay(start); containsFold(preferredTimes, part) {
+		reason(15, "in the %s as asked", part)
+	}
+
+	switch meetings {
+	case 0:
+		reason(10, "nothing else scheduled that day")
+	case 1:
+		reason(-5, "1 other meeting that day")
+	default:
+		reason(-5*meetings, "%d other meetings that day", meetings)
+	}
+
+	if prefs.AvoidBackToBack {
+		for _, event := range events {
+			if isMeeting(event) && (event.EndTime.Equal(start) || event.StartTime.Equal(end)) {
+				reason(-15, "back-to-back with %s", event.Title)
+				break
+			}
+		}
+	}
+	if prefs.BufferTime > 0 && meetings > 0 {
+		suggestion.Reasons = append(suggestion.Reasons, fmt.Sprintf("keeps a %s buffer around other events", formatDuration(prefs.BufferTime)))
+	}
+	if len(suggestion.Reasons) == 0 {
+		suggestion.Reasons = append(suggestion.Reasons, "free within your working hours")
+	}
+	return suggestion
+}
+
+// partOfDay names the part of the day t falls in
+func partOfDay(t time.Time) string {
+	switch {
+	case t.Hour() < 12:
+		return "morning"
+	case t.Hour() < 17:
+		return "afternoon"
+	default:
+		return "evening"
+	}
+}
+
+// alignToStep rounds t up to the next slotStep boundary of its day
+func alignToStep(t time.Time) time.Time {
+	midnight := startOfDay(t)
+	offset := t.Sub(midnight)
+	return midnight.Add((offset + slotStep - 1) / slotStep * slotStep)
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func overlapsAny(picked []SlotSuggestion, candidate SlotSuggestion) bool {
+	for _, p := range picked {
+		if candidate.Start.Before(p.End) && p.Start.Before(candidate.End) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimSpace(value), target) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatDuration renders a duration as "1h30m" without zero units
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	hours, minutes := int(d/time.Hour), int(d%time.Hour/time.Minute)
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+}
+
+// formatSuggestions lists suggested times in loc, one per line
+func formatSuggestions(suggestions []SlotSuggestion, loc *time.Location) string {
+	var b strings.Builder
+	for i, s := range suggestions {
+		fmt.Fprintf(&b, "%d. %s - %s — %s\n", i+1, s.Start.In(loc).Format("Mon 2006-01-02 15:04"), s.End.In(loc).Format("15:04"), strings.Join(s.Reasons, "; "))
+	}
+	return b.String()
+}
+
+// handleSetPreferences updates the user's working hours and scheduling
+// preferences, which availability and suggested times follow
+func (a *SchedulerAgent) handleSetPreferences(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	prompt, err := a.renderPrompt(ctx, "scheduler.preferences", prompts.Vars{"Request": msg.Content})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		WorkingDays            []string `json:"working_days"`
+		WorkStart              string   `json:"work_start"`
+		WorkEnd                string   `json:"work_end"`
+		LunchStart             string   `json:"lunch_start"`
+		LunchEnd               string   `json:"lunch_end"`
+		BufferMinutes          *int     `json:"buffer_minutes"`
+		MaxMeetingsPerDay      *int     `json:"max_meetings_per_day"`
+		MeetingDurationMinutes int      `json:"meeting_duration_minutes"`
+		AvoidBackToBack        *bool    `json:"avoid_back_to_back"`
+		FocusBlocks            []string `json:"focus_blocks"`
+		PreferredTimes         []string `json:"preferred_times"`
+		TravelMinutes          int      `json:"travel_minutes"`
+		TravelRoutes           []string `json:"travel_routes"`
+		AutoTravelBlocks       *bool    `json:"auto_travel_blocks"`
+	}
+	preferencesSchema := objectSchema(map[string]string{
+		"working_days":             "array",
+		"work_start":               "string",
+		"work_end":                 "string",
+		"lunch_start":              "string",
+		"lunch_end":                "string",
+		"buffer_minutes":           "integer",
+		"max_meetings_per_day":     "integer",
+		"meeting_duration_minutes": "integer",
+		"avoid_back_to_back":       "boolean",
+		"focus_blocks":             "array",
+		"preferred_times":          "array",
+		"travel_minutes":           "integer",
+		"travel_routes":            "array",
+		"auto_travel_blocks":       "boolean",
+	})
+	if err := a.queryJSON(ctx, prompt, preferencesSchema, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse scheduling preferences: %w", err)
+	}
+
+	// Work on a copy so a failed save leaves the cached schedule alone
+	schedule := *a.userSchedule(ctx)
+	prefs := &schedule.Preferences
+
+	if len(data.WorkingDays) > 0 || data.WorkStart != "" || data.WorkEnd != "" {
+		var days []time.Weekday
+		for _, name := range data.WorkingDays {
+			if weekday, ok := parseWeekday(name); ok {
+				days = append(days, weekday)
+			}
+		}
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			hours := schedule.WorkingHours.day(weekday)
+			if len(days) > 0 {
+				hours.IsWorkingDay = hasWeekday(days, weekday)
+			}
+			if start, ok := parseClock(data.WorkStart); ok {
+				hours.StartTime = start
+			}
+			if end, ok := parseClock(data.WorkEnd); ok {
+				hours.EndTime = end
+			}
+			if hours.IsWorkingDay && !hours.EndTime.After(hours.StartTime) {
+				hours.StartTime, hours.EndTime = clockTime(9, 0), clockTime(18, 0)
+			}
+		}
+	}
+	if start, ok := parseClock(data.LunchStart); ok {
+		end, ok := parseClock(data.LunchEnd)
+		if !ok || !end.After(start) {
+			end = start.Add(time.Hour)
+		}
+		prefs.LunchBreak = &TimeSlot{StartTime: start, EndTime: end}
+	}
+	if data.BufferMinutes != nil {
+		prefs.BufferTime = time.Duration(max(*data.BufferMinutes, 0)) * time.Minute
+	}
+	if data.MaxMeetingsPerDay != nil {
+		prefs.MaxMeetingsPerDay = max(*data.MaxMeetingsPerDay, 0)
+	}
+	if data.MeetingDurationMinutes > 0 {
+		prefs.PreferredMeetingDuration = time.Duration(data.MeetingDurationMinutes) * time.Minute
+	}
+	if data.AvoidBackToBack != nil {
+		prefs.AvoidBackToBack = *data.AvoidBackToBack
+	}
+	if blocks := parseTimeSlots(data.FocusBlocks); len(blocks) > 0 {
+		prefs.FocusTimeBlocks = blocks
+	}
+	if slots := parseTimeSlots(data.PreferredTimes); len(slots) > 0 {
+		prefs.PreferredTimeSlots = slots
+	}
+	if data.TravelMinutes > 0 {
+		prefs.Travel.DefaultTravelTime = time.Duration(data.TravelMinutes) * time.Minute
+	}
+	if routes := parseTravelRoutes(data.TravelRoutes); len(routes) > 0 {
+		prefs.Travel.Routes = mergeTravelRoutes(prefs.Travel.Routes, routes)
+	}
+	if data.AutoTravelBlocks != nil {
+		prefs.Travel.ManualBlocks = !*data.AutoTravelBlocks
+	}
+
+	if err := a.saveSchedule(ctx, &schedule); err != nil {
+		return nil, err
+	}
+	return a.respond(msg, "⚙️ **Scheduling Preferences Updated**\n\n"+describeSchedule(&schedule), map[string]interface{}{
+		"action":   "preferences_set",
+		"schedule": &schedule,
+	}), nil
+}
+
+// describeSchedule summarises working hours and preferences for the user
+func describeSchedule(schedule *Schedule) string {
+	var b strings.Builder
+	for weekday := time.Monday; weekday <= time.Saturday+1; weekday++ {
+		day := weekday % 7
+		hours := schedule.WorkingHours.day(day)
+		if hours.IsWorkingDay {
+			fmt.Fprintf(&b, "• %s: %s - %s\n", day, hours.StartTime.Format("15:04"), hours.EndTime.Format("15:04"))
+		}
+	}
+	prefs := schedule.Preferences
+	if prefs.LunchBreak != nil {
+		fmt.Fprintf(&b, "• Lunch: %s\n", formatSlot(*prefs.LunchBreak))
+	}
+	for _, focus := range prefs.FocusTimeBlocks {
+		fmt.Fprintf(&b, "• Focus time: %s\n", formatSlot(focus))
+	}
+	for _, preferred := range prefs.PreferredTimeSlots {
+		fmt.Fprintf(&b, "• Preferred for meetings: %s\n", formatSlot(preferred))
+	}
+	if prefs.BufferTime > 0 {
+		fmt.Fprintf(&b, "• Buffer between events: %s\n", formatDuration(prefs.BufferTime))
+	}
+	if prefs.MaxMeetingsPerDay > 0 {
+		fmt.Fprintf(&b, "• At most %d meeting(s) a day\n", prefs.MaxMeetingsPerDay)
+	}
+	if prefs.PreferredMeetingDuration > 0 {
+		fmt.Fprintf(&b, "• Default meeting length: %s\n", formatDuration(prefs.PreferredMeetingDuration))
+	}
+	if prefs.AvoidBackToBack {
+		b.WriteString("• Avoiding back-to-back meetings\n")
+	}
+	fmt.Fprintf(&b, "• Travel between places: %s\n", formatDuration(prefs.Travel.travelTime("", "")))
+	for _, route := range prefs.Travel.Routes {
+		fmt.Fprintf(&b, "• Travel %s ↔ %s: %s\n", route.From, route.To, formatDuration(route.Duration))
+	}
+	if prefs.Travel.ManualBlocks {
+		b.WriteString("• Travel time is not blocked out automatically\n")
+	}
+	return b.String()
+}
+
+func formatSlot(slot TimeSlot) string {
+	return slot.StartTime.Format("15:04") + " - " + slot.EndTime.Format("15:04")
+}
+
+// parseClock reads an "HH:MM" time of day
+func parseClock(value string) (time.Time, bool) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return clockTime(t.Hour(), t.Minute()), true
+}
+
+// parseTimeSlots reads "HH:MM-HH:MM" ranges, skipping malformed ones
+func parseTimeSlots(values []string) []TimeSlot {
+	var slots []TimeSlot
+	for _, value := range values {
+		from, to, ok := strings.Cut(value, "-")
+		start, startOK := parseClock(from)
+		end, endOK := parseClock(to)
+		if ok && startOK && endOK && end.After(start) {
+			slots = append(slots, TimeSlot{StartTime: start, EndTime: end})
+		}
+	}
+	return slots
+}
+
+// parseWeekday reads a weekday name or its abbreviation
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) < 3 {
+		return 0, false
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.HasPrefix(strings.ToLower(weekday.String()), name[:3]) {
+			return weekday, true
+		}
+	}
+	return 0, false
+}
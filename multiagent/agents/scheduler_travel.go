@@ -0,0 +1,412 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+const (
+	// defaultTravelTime is kept between places no route is known for
+	defaultTravelTime = 30 * time.Minute
+	// backToBackGap is the break wanted between meetings by those avoiding
+	// back-to-back meetings
+	backToBackGap = 15 * time.Minute
+	// travelBlockKey marks the travel blocks the scheduler inserted itself
+	travelBlockKey = "auto_travel"
+	// travelCheckDays is how far ahead a travel check looks
+	travelCheckDays = 7
+)
+
+// TravelRules configures the travel time kept between events held at
+// different places
+type TravelRules struct {
+	// DefaultTravelTime is used between places without a route; zero means
+	// 30 minutes
+	DefaultTravelTime time.Duration `json:"default_travel_time"`
+	Routes            []TravelRoute `json:"routes"`
+	// ManualBlocks stops travel blocks being added to the calendar; too
+	// little time between places is still reported
+	ManualBlocks bool `json:"manual_blocks"`
+}
+
+// TravelRoute is the travel time between two places, either way; places
+// match locations containing them
+type TravelRoute struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Duration time.Duration `json:"duration"`
+}
+
+// gapRule is one reason two consecutive events need time between them; it
+// returns the time needed, or zero when it does not apply
+type gapRule func(prefs *SchedulePreferences, prev, next *CalendarEvent) (time.Duration, string)
+
+// gapRules are checked between every pair of consecutive events
+var gapRules = []gapRule{travelGap, bufferGap, backToBackRule}
+
+// travelGap needs the travel time between events at different places
+func travelGap(prefs *SchedulePreferences, prev, next *CalendarEvent) (time.Duration, string) {
+	if !isPhysicalLocation(prev) || !isPhysicalLocation(next) || samePlace(prev.Location, next.Location) {
+		return 0, ""
+	}
+	travel := prefs.Travel.travelTime(prev.Location, next.Location)
+	return travel, fmt.Sprintf("%s to get from %s to %s", formatDuration(travel), prev.Location, next.Location)
+}
+
+// bufferGap needs the user's buffer time between meetings
+func bufferGap(prefs *SchedulePreferences, prev, next *CalendarEvent) (time.Duration, string) {
+	if prefs.BufferTime <= 0 || !isMeeting(prev) || !isMeeting(next) {
+		return 0, ""
+	}
+	return prefs.BufferTime, fmt.Sprintf("your %s buffer between meetings", formatDuration(prefs.BufferTime))
+}
+
+// backToBackRule needs a short break between meetings for users avoiding
+// back-to-back meetings
+func backToBackRule(prefs *SchedulePreferences, prev, next *CalendarEvent) (time.Duration, string) {
+	if !prefs.AvoidBackToBack || !isMeeting(prev) || !isMeeting(next) {
+		return 0, ""
+	}
+	return backToBackGap, "you avoid back-to-back meetings"
+}
+
+// travelTime returns the time to travel between two places
+func (r *TravelRules) travelTime(from, to string) time.Duration {
+	from, to = strings.ToLower(from), strings.ToLower(to)
+	for _, route := range r.Routes {
+		a, b := strings.ToLower(route.From), strings.ToLower(route.To)
+		if a == "" || b == "" || route.Duration <= 0 {
+			continue
+		}
+		if (strings.Contains(from, a) && strings.Contains(to, b)) || (strings.Contains(from, b) && strings.Contains(to, a)) {
+			return route.Duration
+		}
+	}
+	if r.DefaultTravelTime > 0 {
+		return r.DefaultTravelTime
+	}
+	return defaultTravelTime
+}
+
+// onlineMarkers identify locations that are not somewhere to travel to
+var onlineMarkers = []string{"http://", "https://", "zoom", "teams", "google meet", "meet.google", "webex", "skype", "online", "virtual", "remote", "phone", "call"}
+
+// isPhysicalLocation reports whether an event happens somewhere one has to
+// be in person
+func isPhysicalLocation(event *CalendarEvent) bool {
+	location := strings.ToLower(strings.TrimSpace(event.Location))
+	if location == "" {
+		return false
+	}
+	for _, marker := range onlineMarkers {
+		if strings.Contains(location, marker) {
+			return false
+		}
+	}
+	return true
+}
+
+// samePlace reports whether two locations name the same place
+func samePlace(a, b string) bool {
+	a, b = strings.ToLower(strings.TrimSpace(a)), strings.ToLower(strings.TrimSpace(b))
+	return a == b || strings.Contains(a, b) || strings.Contains(b, a)
+}
+
+// isTravelBlock reports whether event is a travel block the scheduler
+// inserted
+func isTravelBlock(event *CalendarEvent) bool {
+	auto, _ := event.Metadata[travelBlockKey].(bool)
+	return auto
+}
+
+// gapCheck is the time between two consecutive events against the time
+// the rules need there
+type gapCheck struct {
+	prev, next *CalendarEvent
+	actual     time.Duration
+	required   time.Duration
+	travel     time.Duration
+	reasons    []string
+}
+
+// checkGap applies the rules to two consecutive events
+func checkGap(prefs *SchedulePreferences, prev, next *CalendarEvent) gapCheck {
+	check := gapCheck{prev: prev, next: next, actual: next.StartTime.Sub(prev.EndTime)}
+	for i, rule := range gapRules {
+		needed, reason := rule(prefs, prev, next)
+		if needed <= 0 {
+			continue
+		}
+		if i == 0 {
+			check.travel = needed
+		}
+		if needed > check.required {
+			check.required = needed
+		}
+		check.reasons = append(check.reasons, reason)
+	}
+	return check
+}
+
+// travelWarning is a pair of events with too little time between them and
+// a proposed fix
+type travelWarning struct {
+	check    gapCheck
+	proposal string
+}
+
+// travelPlan is what applying the rules to a day did and found
+type travelPlan struct {
+	added    []*CalendarEvent
+	removed  []*CalendarEvent
+	warnings []travelWarning
+}
+
+// applyTravelRules checks the time between the user's events on day,
+// keeping a travel block before each event that needs travel to when there
+// is room for it, and reports the gaps that are too short
+func (a *SchedulerAgent) applyTravelRules(ctx context.Context, day time.Time) travelPlan {
+	var plan travelPlan
+	schedule := a.userSchedule(ctx)
+	prefs := &schedule.Preferences
+
+	var events, existing []*CalendarEvent
+	for _, event := range a.getEventsForDate(ctx, day) {
+		switch {
+		case isTravelBlock(event):
+			existing = append(existing, event)
+		case !event.AllDay:
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].StartTime.Before(events[j].StartTime) })
+
+	wanted := make(map[string]*CalendarEvent)
+	for i := 1; i < len(events); i++ {
+		prev, next := events[i-1], events[i]
+		if next.StartTime.Before(prev.EndTime) {
+			// Overlaps are reported as conflicts, not here
+			continue
+		}
+		check := checkGap(prefs, prev, next)
+		if check.travel > 0 && check.actual >= check.travel && !prefs.Travel.ManualBlocks {
+			block := a.travelBlock(ctx, prev, next, check.travel)
+			wanted[block.ID] = block
+		}
+		if check.actual < check.required {
+			plan.warnings = append(plan.warnings, travelWarning{check: check, proposal: a.proposeGapFix(ctx, prefs, events, i, check)})
+		}
+	}
+
+	// Bring the calendar's travel blocks in line with those wanted
+	for _, block := range existing {
+		if want, ok := wanted[block.ID]; ok && want.StartTime.Equal(block.StartTime) && want.EndTime.Equal(block.EndTime) {
+			delete(wanted, block.ID)
+			continue
+		}
+		a.scheduleMutex.Lock()
+		stored, ok := a.calendar[block.ID]
+		if ok {
+			stored.Status = EventStatusCancelled
+			stored.UpdatedAt = a.now()
+		}
+		a.scheduleMutex.Unlock()
+		if ok {
+			if err := a.persistEvent(ctx, stored); err != nil {
+				a.logger.WarnContext(ctx, "Failed to remove travel block", "event_id", block.ID, "error", err)
+			}
+			if _, replaced := wanted[block.ID]; !replaced {
+				plan.removed = append(plan.removed, stored)
+			}
+		}
+	}
+	ids := make([]string, 0, len(wanted))
+	for id := range wanted {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		block := wanted[id]
+		a.scheduleMutex.Lock()
+		a.calendar[block.ID] = block
+		a.scheduleMutex.Unlock()
+		if err := a.persistEvent(ctx, block); err != nil {
+			a.logger.WarnContext(ctx, "Failed to save travel block", "event_id", block.ID, "error", err)
+			continue
+		}
+		plan.added = append(plan.added, block)
+	}
+	return plan
+}
+
+// travelBlock returns the travel block ending as next starts
+func (a *SchedulerAgent) travelBlock(ctx context.Context, prev, next *CalendarEvent, travel time.Duration) *CalendarEvent {
+	return &CalendarEvent{
+		// Occurrences of a recurring event share its ID, so the day is
+		// part of the block's
+		ID:          fmt.Sprintf("travel_%s_%s", next.ID, next.StartTime.UTC().Format("20060102")),
+		Title:       "🚗 Travel to " + next.Location,
+		Description: fmt.Sprintf("From %s (%s) to %s (%s)", prev.Location, prev.Title, next.Location, next.Title),
+		StartTime:   next.StartTime.Add(-travel).UTC(),
+		EndTime:     next.StartTime.UTC(),
+		Category:    EventCategoryTravel,
+		Priority:    next.Priority,
+		Status:      EventStatusConfirmed,
+		Tags:        []string{"travel"},
+		CreatedAt:   a.now(),
+		UpdatedAt:   a.now(),
+		CreatedBy:   a.id,
+		Timezone:    next.Timezone,
+		Metadata: map[string]interface{}{
+			travelBlockKey: true,
+			"travel_for":   next.ID,
+		},
+		UserID: multiagent.UserIDFromContext(ctx),
+	}
+}
+
+// proposeGapFix suggests moving the later of events[i-1] and events[i]
+// back, or else the earlier one forward, far enough to leave the time the
+// rules need without cutting into the time needed on its other side;
+// recurring events are left alone
+func (a *SchedulerAgent) proposeGapFix(ctx context.Context, prefs *SchedulePreferences, events []*CalendarEvent, i int, check gapCheck) string {
+	loc := check.next.location()
+	if check.next.Recurring == nil {
+		moved := *check.next
+		moved.StartTime = check.prev.EndTime.Add(check.required)
+		moved.EndTime = moved.StartTime.Add(check.next.EndTime.Sub(check.next.StartTime))
+		fits := i+1 >= len(events) || !moved.EndTime.Add(checkGap(prefs, &moved, events[i+1]).required).After(events[i+1].StartTime)
+		if fits && len(a.checkConflicts(ctx, moved.StartTime, moved.EndTime, moved.ID)) == 0 {
+			return fmt.Sprintf("move '%s' to %s - %s", moved.Title, moved.StartTime.In(loc).Format("15:04"), moved.EndTime.In(loc).Format("15:04"))
+		}
+	}
+	if check.prev.Recurring == nil {
+		moved := *check.prev
+		moved.EndTime = check.next.StartTime.Add(-check.required)
+		moved.StartTime = moved.EndTime.Add(-check.prev.EndTime.Sub(check.prev.StartTime))
+		fits := i < 2 || !events[i-2].EndTime.Add(checkGap(prefs, events[i-2], &moved).required).After(moved.StartTime)
+		if fits && len(a.checkConflicts(ctx, moved.StartTime, moved.EndTime, moved.ID)) == 0 {
+			return fmt.Sprintf("move '%s' to %s - %s", moved.Title, moved.StartTime.In(loc).Format("15:04"), moved.EndTime.In(loc).Format("15:04"))
+		}
+	}
+	return ""
+}
+
+// describe renders what a plan did for a reply, or "" if nothing
+func (p travelPlan) describe(loc *time.Location) string {
+	var b strings.Builder
+	for _, block := range p.added {
+		fmt.Fprintf(&b, "🚗 Blocked %s - %s to travel to %s\n", block.StartTime.In(loc).Format("Mon 15:04"), block.EndTime.In(loc).Format("15:04"), strings.TrimPrefix(block.Title, "🚗 Travel to "))
+	}
+	for _, block := range p.removed {
+		fmt.Fprintf(&b, "🗑️ Removed the travel block %s - %s\n", block.StartTime.In(loc).Format("Mon 15:04"), block.EndTime.In(loc).Format("15:04"))
+	}
+	for _, warning := range p.warnings {
+		check := warning.check
+		gap := "No time"
+		if check.actual > 0 {
+			gap = "Only " + formatDuration(check.actual)
+		}
+		fmt.Fprintf(&b, "⚠️ %s between '%s' (ends %s) and '%s' (starts %s), but you need %s: %s.",
+			gap, check.prev.Title, check.prev.EndTime.In(loc).Format("Mon 15:04"),
+			check.next.Title, check.next.StartTime.In(loc).Format("15:04"), formatDuration(check.required), strings.Join(check.reasons, ", "))
+		if warning.proposal != "" {
+			fmt.Fprintf(&b, " Suggestion: %s.", warning.proposal)
+		} else {
+			b.WriteString(" Neither can move without crowding another event; consider rescheduling one of them.")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// travelNotes applies the rules to the given days and describes the
+// result for a reply
+func (a *SchedulerAgent) travelNotes(ctx context.Context, loc *time.Location, days ...time.Time) string {
+	var notes []string
+	seen := make(map[string]bool)
+	for _, day := range days {
+		day = startOfDay(day.In(loc))
+		if key := day.Format("2006-01-02"); !seen[key] {
+			seen[key] = true
+			if note := a.applyTravelRules(ctx, day).describe(loc); note != "" {
+				notes = append(notes, note)
+			}
+		}
+	}
+	return strings.TrimSpace(strings.Join(notes, ""))
+}
+
+// handleCheckTravel reviews the coming week for missing travel and buffer
+// time, adding travel blocks where they fit
+func (a *SchedulerAgent) handleCheckTravel(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	loc := userLocation(ctx, a.memoryStore)
+	today := startOfDay(a.now().In(loc))
+
+	var added, warnings int
+	var b strings.Builder
+	for i := 0; i < travelCheckDays; i++ {
+		plan := a.applyTravelRules(ctx, today.AddDate(0, 0, i))
+		added += len(plan.added)
+		warnings += len(plan.warnings)
+		b.WriteString(plan.describe(loc))
+	}
+
+	content := "🚗 **Travel & Buffer Check** (next 7 days)\n\n"
+	if b.Len() == 0 {
+		content += "✅ Every event leaves enough time for travel and breaks."
+	} else {
+		content += b.String()
+	}
+	return a.respond(msg, content, map[string]interface{}{
+		"action":   "travel_checked",
+		"added":    added,
+		"warnings": warnings,
+	}), nil
+}
+
+// parseTravelRoutes reads routes given as "Office -> Client HQ: 45"
+// (minutes), skipping malformed ones
+func parseTravelRoutes(values []string) []TravelRoute {
+	var routes []TravelRoute
+	for _, value := range values {
+		places, minutes, ok := strings.Cut(value, ":")
+		if !ok {
+			continue
+		}
+		from, to, ok := strings.Cut(places, "->")
+		n, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(minutes), "m")))
+		if !ok || err != nil || n <= 0 || strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+			continue
+		}
+		routes = append(routes, TravelRoute{From: strings.TrimSpace(from), To: strings.TrimSpace(to), Duration: time.Duration(n) * time.Minute})
+	}
+	return routes
+}
+
+// mergeTravelRoutes adds routes to existing ones, replacing those between
+// the same places
+func mergeTravelRoutes(existing, routes []TravelRoute) []TravelRoute {
+	merged := append([]TravelRoute(nil), existing...)
+	for _, route := range routes {
+		replaced := false
+		for i, old := range merged {
+			if (strings.EqualFold(old.From, route.From) && strings.EqualFold(old.To, route.To)) ||
+				(strings.EqualFold(old.From, route.To) && strings.EqualFold(old.To, route.From)) {
+				merged[i] = route
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, route)
+		}
+	}
+	return merged
+}
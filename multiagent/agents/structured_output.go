@@ -0,0 +1,40 @@
+package agents
+
+import (
+	"context"
+
+	"github.com/kbutz/wikillm/multiagent/llmprovider"
+)
+
+// parseMetrics is shared by every agent so failures can be compared across agents
+var parseMetrics = llmprovider.NewParseMetrics()
+
+// StructuredOutputMetrics returns JSON parse and repair counters by agent name
+func StructuredOutputMetrics() map[string]llmprovider.ParseStats {
+	return parseMetrics.Snapshot()
+}
+
+// queryJSON asks the LLM for JSON matching schema and decodes it into out,
+// requesting repairs when the reply is malformed
+func (a *BaseAgent) queryJSON(ctx context.Context, prompt string, schema map[string]interface{}, out interface{}) error {
+	structured := llmprovider.NewStructuredOutput(llmprovider.StructuredOutputConfig{
+		Provider: a.llmProvider,
+		Name:     a.name,
+		Metrics:  parseMetrics,
+	})
+	return structured.Query(ctx, prompt, schema, out)
+}
+
+// objectSchema builds a JSON schema for an object whose properties have the
+// given JSON types ("string", "integer", "array", ...)
+func objectSchema(properties map[string]string, required ...string) map[string]interface{} {
+	props := make(map[string]interface{}, len(properties))
+	for name, typeName := range properties {
+		props[name] = map[string]interface{}{"type": typeName}
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+		"required":   required,
+	}
+}
@@ -0,0 +1,851 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+	"github.com/kbutz/wikillm/multiagent/reminders"
+)
+
+const (
+	// urgentWindow is how soon a task must be due to count as urgent
+	urgentWindow = 48 * time.Hour
+	// staleTaskAge is how long a low-value task can sit untouched before
+	// it is suggested for someday/maybe
+	staleTaskAge = 30 * 24 * time.Hour
+	// maxPrioritySuggestions bounds the suggestions in a prioritization reply
+	maxPrioritySuggestions = 8
+	// maxListedTasks bounds the tasks listed per section of a reply
+	maxListedTasks = 10
+)
+
+// taskReference is how a user points at an existing task: by ID and/or by
+// (part of) its title
+type taskReference struct {
+	TaskID string `json:"task_id"`
+	Title  string `json:"title"`
+}
+
+// quadrant is a cell of the Eisenhower matrix
+type quadrant int
+
+const (
+	quadrantDo        quadrant = iota // Urgent and important
+	quadrantSchedule                  // Important, not urgent
+	quadrantDelegate                  // Urgent, not important
+	quadrantEliminate                 // Neither
+)
+
+var quadrantTitles = [...]string{
+	quadrantDo:        "🔥 **Do first** (urgent & important)",
+	quadrantSchedule:  "📅 **Schedule** (important, not urgent)",
+	quadrantDelegate:  "🤝 **Delegate or batch** (urgent, not important)",
+	quadrantEliminate: "💭 **Drop or defer** (neither)",
+}
+
+// isActive reports whether the task still needs doing
+func (t *PersonalTask) isActive() bool {
+	return t.DeletedAt == nil && t.Status != PersonalTaskStatusCompleted && t.Status != PersonalTaskStatusCancelled
+}
+
+// quadrant places the task in the Eisenhower matrix: it is urgent when due
+// within urgentWindow of now and important when high priority or above
+func (t *PersonalTask) quadrant(now time.Time) quadrant {
+	urgent := t.DueDate != nil && t.DueDate.Sub(now) < urgentWindow
+	important := t.Priority >= multiagent.PriorityHigh
+	switch {
+	case urgent && important:
+		return quadrantDo
+	case important:
+		return quadrantSchedule
+	case urgent:
+		return quadrantDelegate
+	default:
+		return quadrantEliminate
+	}
+}
+
+// handleUpdateTask applies the field changes the user asks for to one of
+// their tasks
+func (a *TaskManagerAgent) handleUpdateTask(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+
+	prompt, err := a.renderPrompt(ctx, "task.update", prompts.Vars{
+		"Request":  msg.Content,
+		"Now":      a.now().In(loc).Format("2006-01-02 15:04 (Monday)"),
+		"Timezone": loc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		taskReference
+		NewTitle      string   `json:"new_title"`
+		Description   string   `json:"description"`
+		Priority      string   `json:"priority"`
+		Status        string   `json:"status"`
+		Category      string   `json:"category"`
+		Project       string   `json:"project"`
+		DueDate       string   `json:"due_date"`
+		EstimatedTime *int     `json:"estimated_time"`
+		EnergyLevel   string   `json:"energy_level"`
+		Context       string   `json:"context"`
+		Tags          []string `json:"tags"`
+		Progress      *float64 `json:"progress"`
+		Note          string   `json:"note"`
+	}
+	updateSchema := objectSchema(map[string]string{
+		"task_id":        "string",
+		"title":          "string",
+		"new_title":      "string",
+		"priority":       "string",
+		"status":         "string",
+		"due_date":       "string",
+		"estimated_time": "integer",
+		"tags":           "array",
+		"progress":       "number",
+	})
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, updateSchema, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse task update: %w", err)
+	}
+
+	// Read the new due date before taking the lock
+	var newDue *time.Time
+	clearDue := strings.EqualFold(strings.TrimSpace(data.DueDate), "none")
+	if data.DueDate != "" && !clearDue {
+		due, err := resolveTime(data.DueDate, "", a.now().In(loc))
+		if err != nil {
+			return a.respond(msg, "📅 I couldn't read the new due date. Please give it as a date and time.", nil), nil
+		}
+		newDue = &due
+	}
+
+	a.taskMutex.Lock()
+	task := a.resolveTask(ctx, data.taskReference, msg.Content, false)
+	if task == nil {
+		a.taskMutex.Unlock()
+		return a.respond(msg, "❌ Task not found. Please specify a valid task ID or title.", nil), nil
+	}
+
+	var changes []string
+	change := func(field, from, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", field, orDefault(from, "none"), orDefault(to, "none")))
+		}
+	}
+	if data.NewTitle != "" {
+		change("title", task.Title, data.NewTitle)
+		task.Title = data.NewTitle
+	}
+	if data.Description != "" {
+		change("description", task.Description, data.Description)
+		task.Description = data.Description
+	}
+	if data.Priority != "" {
+		priority := a.parsePriority(data.Priority)
+		change("priority", priorityName(task.Priority), priorityName(priority))
+		task.Priority = priority
+	}
+	wasCompleted := task.Status == PersonalTaskStatusCompleted
+	if status, ok := parseTaskStatus(data.Status); ok {
+		change("status", string(task.Status), string(status))
+		finished := status == PersonalTaskStatusCompleted && task.Status != status
+		task.setStatus(status, a.now(), "")
+		if finished {
+			a.scheduleNextOccurrence(ctx, task)
+		}
+	}
+	if data.Category != "" {
+		change("category", task.Category, data.Category)
+		task.Category = data.Category
+	}
+	if data.Project != "" {
+		change("project", task.Project, data.Project)
+		task.Project = data.Project
+	}
+	dueChanged := false
+	oldDue := task.DueDate
+	if newDue != nil || (clearDue && task.DueDate != nil) {
+		change("due", formatTaskDue(task.DueDate, loc), formatTaskDue(newDue, loc))
+		task.DueDate = newDue
+		dueChanged = true
+	}
+	if data.EstimatedTime != nil && *data.EstimatedTime >= 0 {
+		estimate := time.Duration(*data.EstimatedTime) * time.Minute
+		change("estimate", formatDuration(task.EstimatedTime), formatDuration(estimate))
+		task.EstimatedTime = estimate
+	}
+	if data.EnergyLevel != "" {
+		energy := a.parseEnergyLevel(data.EnergyLevel)
+		change("energy", string(task.Energy), string(energy))
+		task.Energy = energy
+	}
+	if data.Context != "" {
+		change("context", task.Context, data.Context)
+		task.Context = data.Context
+	}
+	if data.Tags != nil {
+		change("tags", strings.Join(task.Tags, ", "), strings.Join(data.Tags, ", "))
+		task.Tags = data.Tags
+	}
+	// Tasks with subtasks take their progress from them
+	if data.Progress != nil && *data.Progress >= 0 && *data.Progress <= 100 && len(task.Subtasks) == 0 {
+		change("progress", fmt.Sprintf("%.0f%%", task.Progress), fmt.Sprintf("%.0f%%", *data.Progress))
+		task.Progress = *data.Progress
+	}
+	if data.Note != "" {
+		task.Notes = append(task.Notes, TaskNote{
+			ID:        a.newID("note"),
+			Content:   data.Note,
+			Timestamp: a.now(),
+			Type:      "update",
+		})
+		changes = append(changes, "note added: "+data.Note)
+	}
+
+	if len(changes) == 0 {
+		title := task.Title
+		a.taskMutex.Unlock()
+		return a.respond(msg, fmt.Sprintf("🔄 What would you like to change about '%s'? You can update its title, priority, status, project, due date, estimate, energy, context, tags or progress.", title), nil), nil
+	}
+	task.UpdatedAt = a.now()
+	// Calendar blocks are freed with the task and follow its due date
+	var blockChanges []TimeBlockRequest
+	switch {
+	case !task.isActive():
+		blockChanges = task.releaseCalendarBlocks(a.now())
+	case dueChanged && oldDue != nil && task.DueDate != nil:
+		blockChanges = task.shiftCalendarBlocks(task.DueDate.Sub(*oldDue), a.now())
+	}
+	snapshot := *task
+	unblocked := ""
+	if snapshot.Status == PersonalTaskStatusCompleted {
+		unblocked = a.unblockedNote(ctx, snapshot.ID)
+	}
+	a.taskMutex.Unlock()
+
+	if err := a.saveTask(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	if dueChanged {
+		a.cancelDueReminder(ctx, &snapshot)
+		if snapshot.DueDate != nil && snapshot.isActive() {
+			a.createAutomaticReminder(ctx, &snapshot)
+		}
+	}
+	a.syncTimeBlocks(ctx, blockChanges)
+	a.publishLinkedStatus(ctx, wasCompleted, &snapshot)
+	a.recordCompleted(ctx, msg, wasCompleted, &snapshot)
+
+	a.recordShared(ctx, msg, memory.BlackboardEntry{
+		Section: memory.BlackboardFacts,
+		Key:     "task:" + snapshot.ID,
+		Value:   fmt.Sprintf("Personal task '%s' was updated (%s)", snapshot.Title, strings.Join(changes, "; ")),
+	})
+	a.recordAudit(ctx, msg, audit.TaskUpdated, snapshot.ID, map[string]interface{}{
+		"title":   snapshot.Title,
+		"changes": changes,
+	})
+
+	content := fmt.Sprintf("🔄 Updated '%s':\n• %s", snapshot.Title, strings.Join(changes, "\n• "))
+	if note := timeBlockNote(blockChanges); note != "" {
+		content += "\n" + note
+	}
+	if unblocked != "" {
+		content += "\n\n" + unblocked
+	}
+	return a.respond(msg, content, map[string]interface{}{
+		"task_id": snapshot.ID,
+		"action":  "task_updated",
+	}), nil
+}
+
+// handleDeleteTask moves one of the user's tasks to the trash, from where
+// handleRestoreTask can bring it back
+func (a *TaskManagerAgent) handleDeleteTask(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+
+	a.taskMutex.Lock()
+	task := a.resolveTask(ctx, taskReference{}, msg.Content, false)
+	if task == nil {
+		a.taskMutex.Unlock()
+		return a.respond(msg, "❌ Task not found. Please specify a valid task ID or title.", nil), nil
+	}
+	taskID, title := task.ID, task.Title
+	a.taskMutex.Unlock()
+
+	if approved, reply := a.confirmAction(ctx, msg, ProposedAction{
+		Action:  ActionDeleteTask,
+		Subject: taskID,
+		Summary: fmt.Sprintf("delete the task '%s'", title),
+		Details: map[string]string{"Task": taskID},
+	}); !approved {
+		return reply, nil
+	}
+
+	a.taskMutex.Lock()
+	task, ok := a.tasks[taskID]
+	if !ok || task.DeletedAt != nil {
+		a.taskMutex.Unlock()
+		return a.respond(msg, fmt.Sprintf("❌ '%s' is already gone.", title), nil), nil
+	}
+	now := a.now()
+	task.stopTimer(now)
+	task.DeletedAt = &now
+	task.UpdatedAt = now
+	blockChanges := task.releaseCalendarBlocks(now)
+	// The project task goes back to being tracked in the project only
+	projectLink := task.ProjectLink
+	task.ProjectLink = nil
+	snapshot := *task
+	a.taskMutex.Unlock()
+
+	if err := a.saveTask(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	a.cancelDueReminder(ctx, &snapshot)
+	a.syncTimeBlocks(ctx, blockChanges)
+	if projectLink != nil {
+		a.publishTaskLink(ctx, PersonalTaskTopic, TaskLink{
+			Action:         TaskLinkUnlink,
+			ProjectID:      projectLink.ProjectID,
+			ProjectTaskID:  projectLink.TaskID,
+			PersonalTaskID: snapshot.ID,
+			Title:          snapshot.Title,
+		})
+	}
+
+	a.recordShared(ctx, msg, memory.BlackboardEntry{
+		Section: memory.BlackboardFacts,
+		Key:     "task:" + snapshot.ID,
+		Value:   fmt.Sprintf("Personal task '%s' was deleted", snapshot.Title),
+	})
+	a.recordAudit(ctx, msg, audit.TaskDeleted, snapshot.ID, map[string]interface{}{
+		"title": snapshot.Title,
+	})
+
+	return a.respond(msg, fmt.Sprintf("🗑️ Deleted '%s'. Say \"undo\" to restore it.", snapshot.Title), map[string]interface{}{
+		"task_id": snapshot.ID,
+		"action":  "task_deleted",
+	}), nil
+}
+
+// handleRestoreTask brings back the deleted task the user names, or the one
+// they deleted last
+func (a *TaskManagerAgent) handleRestoreTask(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+
+	a.taskMutex.Lock()
+	task := a.resolveTask(ctx, taskReference{}, msg.Content, true)
+	if task == nil {
+		for _, candidate := range a.tasks {
+			if ownedBy(ctx, candidate.UserID) && candidate.DeletedAt != nil && (task == nil || candidate.DeletedAt.After(*task.DeletedAt)) {
+				task = candidate
+			}
+		}
+	}
+	if task == nil {
+		a.taskMutex.Unlock()
+		return a.respond(msg, "There's no deleted task to restore.", nil), nil
+	}
+	task.DeletedAt = nil
+	task.UpdatedAt = a.now()
+	snapshot := *task
+	a.taskMutex.Unlock()
+
+	if err := a.saveTask(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.DueDate != nil && snapshot.isActive() {
+		a.createAutomaticReminder(ctx, &snapshot)
+	}
+
+	a.recordShared(ctx, msg, memory.BlackboardEntry{
+		Section: memory.BlackboardFacts,
+		Key:     "task:" + snapshot.ID,
+		Value:   fmt.Sprintf("Personal task '%s' was restored", snapshot.Title),
+	})
+	a.recordAudit(ctx, msg, audit.TaskRestored, snapshot.ID, map[string]interface{}{
+		"title": snapshot.Title,
+	})
+
+	return a.respond(msg, fmt.Sprintf("↩️ Restored '%s' (%s).", snapshot.Title, snapshot.Status), map[string]interface{}{
+		"task_id": snapshot.ID,
+		"action":  "task_restored",
+	}), nil
+}
+
+// handlePrioritize sorts the user's open tasks into the Eisenhower matrix
+// and suggests GTD moves and priority changes
+func (a *TaskManagerAgent) handlePrioritize(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now()
+
+	tasks := a.userTasks(ctx, (*PersonalTask).isActive)
+	if len(tasks) == 0 {
+		return a.respond(msg, "🎯 You have no open tasks to prioritize.", nil), nil
+	}
+
+	var quadrants [len(quadrantTitles)][]*PersonalTask
+	for _, task := range tasks {
+		q := task.quadrant(now)
+		quadrants[q] = append(quadrants[q], task)
+	}
+
+	var b strings.Builder
+	b.WriteString("🎯 **Your Priorities**\n")
+	for q, inQuadrant := range quadrants {
+		if len(inQuadrant) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s\n", quadrantTitles[q])
+		a.writeTaskList(&b, inQuadrant, loc)
+	}
+
+	suggestions := prioritySuggestions(tasks, quadrants[quadrantDo], now, loc)
+	if len(suggestions) > 0 {
+		b.WriteString("\n💡 **Suggestions**\n")
+		for _, suggestion := range suggestions {
+			fmt.Fprintf(&b, "• %s\n", suggestion)
+		}
+	}
+
+	return a.respond(msg, b.String(), map[string]interface{}{
+		"action":      "tasks_prioritized",
+		"do_first":    len(quadrants[quadrantDo]),
+		"suggestions": len(suggestions),
+	}), nil
+}
+
+// prioritySuggestions proposes GTD moves and priority changes for the open
+// tasks, most pressing first
+func prioritySuggestions(tasks, doFirst []*PersonalTask, now time.Time, loc *time.Location) []string {
+	var suggestions []string
+	if len(doFirst) > 3 {
+		suggestions = append(suggestions, fmt.Sprintf("%d tasks are urgent and important; pick the three that matter most for today and reschedule the rest", len(doFirst)))
+	}
+
+	inbox := 0
+	for _, task := range tasks {
+		due := formatTaskDue(task.DueDate, loc)
+		switch q := task.quadrant(now); {
+		case q == quadrantDo && task.Status == PersonalTaskStatusWaiting:
+			suggestions = append(suggestions, fmt.Sprintf("Follow up on '%s': it's waiting on someone and due %s", task.Title, due))
+		case q == quadrantDo && (task.Status == PersonalTaskStatusInbox || task.Status == PersonalTaskStatusSomeday || task.Status == PersonalTaskStatusDeferred):
+			suggestions = append(suggestions, fmt.Sprintf("Move '%s' to your next actions: it's due %s", task.Title, due))
+		case q == quadrantDelegate && task.DueDate.Sub(now) < 24*time.Hour:
+			suggestions = append(suggestions, fmt.Sprintf("Raise '%s' to high priority or hand it off: it's due %s", task.Title, due))
+		case q == quadrantSchedule && task.DueDate == nil:
+			suggestions = append(suggestions, fmt.Sprintf("Give '%s' a due date or block time for it so it doesn't slip", task.Title))
+		case q == quadrantEliminate && task.Priority == multiagent.PriorityLow && now.Sub(task.UpdatedAt) > staleTaskAge && task.Status != PersonalTaskStatusSomeday:
+			suggestions = append(suggestions, fmt.Sprintf("Move '%s' to someday/maybe: it hasn't been touched in %d days", task.Title, int(now.Sub(task.UpdatedAt).Hours()/24)))
+		}
+		if task.Status == PersonalTaskStatusInbox {
+			inbox++
+		}
+	}
+	if inbox > 0 {
+		suggestions = append(suggestions, fmt.Sprintf("Process your %d inbox task(s): decide each one's next action, or move it to someday", inbox))
+	}
+
+	if len(suggestions) > maxPrioritySuggestions {
+		suggestions = suggestions[:maxPrioritySuggestions]
+	}
+	return suggestions
+}
+
+// handleTodayTasks lists what is due today, overdue and in progress, with
+// what has already been finished today
+func (a *TaskManagerAgent) handleTodayTasks(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now().In(loc)
+	dayStart := startOfDay(now)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	var dueToday, overdue, inProgress []*PersonalTask
+	completedToday := 0
+	for _, task := range a.userTasks(ctx, func(task *PersonalTask) bool { return task.DeletedAt == nil }) {
+		switch {
+		case task.Status == PersonalTaskStatusCompleted:
+			if task.CompletedAt != nil && !task.CompletedAt.Before(dayStart) {
+				completedToday++
+			}
+		case !task.isActive():
+		case task.DueDate != nil && task.DueDate.Before(now):
+			overdue = append(overdue, task)
+		case task.DueDate != nil && task.DueDate.Before(dayEnd):
+			dueToday = append(dueToday, task)
+		case task.Status == PersonalTaskStatusInProgress:
+			inProgress = append(inProgress, task)
+		}
+	}
+
+	if len(dueToday)+len(overdue)+len(inProgress) == 0 {
+		content := "📅 Nothing is due today. Ask for your next actions to pick something to work on."
+		if completedToday > 0 {
+			content = fmt.Sprintf("📅 Nothing else is due today, and you've finished %d task(s) already. 🎉", completedToday)
+		}
+		return a.respond(msg, content, map[string]interface{}{"action": "today_tasks", "due_today": 0}), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📅 **Today, %s**\n", now.Format("Monday 2 January"))
+	if len(dueToday) > 0 {
+		fmt.Fprintf(&b, "\n**Due today**%s\n", estimateNote(dueToday))
+		a.writeTaskList(&b, dueToday, loc)
+	}
+	if len(overdue) > 0 {
+		b.WriteString("\n⚠️ **Overdue**\n")
+		a.writeTaskList(&b, overdue, loc)
+	}
+	if len(inProgress) > 0 {
+		b.WriteString("\n⏳ **In progress**\n")
+		a.writeTaskList(&b, inProgress, loc)
+	}
+	if completedToday > 0 {
+		fmt.Fprintf(&b, "\n✅ Finished today: %d\n", completedToday)
+	}
+
+	return a.respond(msg, b.String(), map[string]interface{}{
+		"action":    "today_tasks",
+		"due_today": len(dueToday),
+		"overdue":   len(overdue),
+	}), nil
+}
+
+// handleOverdueTasks lists the user's open tasks past their due date,
+// longest overdue first
+func (a *TaskManagerAgent) handleOverdueTasks(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now()
+
+	overdue := a.userTasks(ctx, func(task *PersonalTask) bool {
+		return task.isActive() && task.DueDate != nil && task.DueDate.Before(now)
+	})
+	if len(overdue) == 0 {
+		return a.respond(msg, "✅ Nothing is overdue. Nice work!", map[string]interface{}{"action": "overdue_tasks", "overdue": 0}), nil
+	}
+	sort.SliceStable(overdue, func(i, j int) bool {
+		return overdue[i].DueDate.Before(*overdue[j].DueDate)
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "⚠️ **%d Overdue Task(s)**\n\n", len(overdue))
+	for i, task := range overdue {
+		if i >= maxListedTasks {
+			fmt.Fprintf(&b, "... and %d more\n", len(overdue)-i)
+			break
+		}
+		fmt.Fprintf(&b, "%d. %s **%s** — due %s (%s ago)\n", i+1, a.getPriorityEmoji(task.Priority), task.Title, formatTaskDue(task.DueDate, loc), formatOverdue(now.Sub(*task.DueDate)))
+	}
+	b.WriteString("\nTo catch up, give a task a new due date (\"update task <title> due Friday\"), delete it, or mark it done.")
+
+	return a.respond(msg, b.String(), map[string]interface{}{
+		"action":  "overdue_tasks",
+		"overdue": len(overdue),
+	}), nil
+}
+
+// handleNextActions lists the user's GTD next actions that are not blocked
+// by unfinished dependencies, narrowed to the energy level or context the
+// message mentions
+func (a *TaskManagerAgent) handleNextActions(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	content := strings.ToLower(msg.Content)
+
+	a.taskMutex.RLock()
+	inbox := 0
+	var actions []*PersonalTask
+	var blocked []string
+	for _, task := range a.tasks {
+		if !ownedBy(ctx, task.UserID) || !task.isActive() {
+			continue
+		}
+		if task.Status == PersonalTaskStatusInbox {
+			inbox++
+		}
+		if task.Status != PersonalTaskStatusNext && task.Status != PersonalTaskStatusInProgress {
+			continue
+		}
+		if blockers := a.blockers(task); len(blockers) > 0 {
+			blocked = append(blocked, fmt.Sprintf("**%s** — waiting on %s", task.Title, taskTitles(blockers)))
+			continue
+		}
+		snapshot := *task
+		actions = append(actions, &snapshot)
+	}
+	cycles := a.dependencyCycles(ctx)
+	a.taskMutex.RUnlock()
+	sort.Strings(blocked)
+
+	var filters []string
+	if strings.Contains(content, "tired") || strings.Contains(content, "low energy") {
+		actions = filterTasks(actions, func(task *PersonalTask) bool { return task.Energy == EnergyLevelLow })
+		filters = append(filters, "low energy")
+	}
+	var contextual []*PersonalTask
+	for _, task := range actions {
+		if task.Context != "" && strings.Contains(content, strings.ToLower(task.Context)) {
+			contextual = append(contextual, task)
+		}
+	}
+	if len(contextual) > 0 {
+		actions = contextual
+		filters = append(filters, contextual[0].Context)
+	}
+
+	var b strings.Builder
+	if len(actions) == 0 {
+		b.WriteString("➡️ You have no next actions right now.")
+		if inbox > 0 {
+			fmt.Fprintf(&b, " Process your %d inbox task(s) to decide what to do next.", inbox)
+		}
+		b.WriteString("\n")
+	} else {
+		// Finish what's started before picking up something new
+		sortByUrgency(actions)
+		sort.SliceStable(actions, func(i, j int) bool {
+			return actions[i].Status == PersonalTaskStatusInProgress && actions[j].Status != PersonalTaskStatusInProgress
+		})
+
+		b.WriteString("➡️ **Next Actions**")
+		if len(filters) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(filters, ", "))
+		}
+		b.WriteString("\n\n")
+		a.writeTaskList(&b, actions, loc)
+		if inbox > 0 {
+			fmt.Fprintf(&b, "\n📥 %d task(s) in your inbox still need a next action.\n", inbox)
+		}
+	}
+	if len(blocked) > 0 {
+		b.WriteString("\n🔒 **Blocked**\n")
+		for i, line := range blocked {
+			if i >= maxListedTasks {
+				fmt.Fprintf(&b, "   ... and %d more\n", len(blocked)-i)
+				break
+			}
+			fmt.Fprintf(&b, "• %s\n", line)
+		}
+	}
+	for _, cycle := range cycles {
+		fmt.Fprintf(&b, "\n♻️ These tasks wait on each other, so none can start: %s. Remove one of the dependencies.\n", cycleTitles(cycle))
+	}
+
+	return a.respond(msg, b.String(), map[string]interface{}{
+		"action":       "next_actions",
+		"next_actions": len(actions),
+		"blocked":      len(blocked),
+	}), nil
+}
+
+// resolveTask finds the user's task by ID, then by the referenced title,
+// then by a title the content mentions; deleted only finds tasks in the
+// trash. Callers hold taskMutex.
+func (a *TaskManagerAgent) resolveTask(ctx context.Context, ref taskReference, content string, deleted bool) *PersonalTask {
+	candidate := func(task *PersonalTask) bool {
+		return ownedBy(ctx, task.UserID) && (task.DeletedAt != nil) == deleted
+	}
+	if ref.TaskID == "" {
+		ref.TaskID = a.extractTaskID(content)
+	}
+	if task, ok := a.tasks[ref.TaskID]; ok && candidate(task) {
+		return task
+	}
+
+	title := strings.ToLower(strings.TrimSpace(ref.Title))
+	content = strings.ToLower(content)
+	var partial, mentioned *PersonalTask
+	for _, task := range a.tasks {
+		taskTitle := strings.ToLower(task.Title)
+		if !candidate(task) || taskTitle == "" {
+			continue
+		}
+		switch {
+		case title != "" && taskTitle == title:
+			return task
+		case title != "" && strings.Contains(taskTitle, title):
+			partial = task
+		case strings.Contains(content, taskTitle) && (mentioned == nil || len(task.Title) > len(mentioned.Title)):
+			mentioned = task
+		}
+	}
+	if partial != nil {
+		return partial
+	}
+	return mentioned
+}
+
+// userTasks returns copies of the user's tasks that keep accepts, most
+// urgent first
+func (a *TaskManagerAgent) userTasks(ctx context.Context, keep func(*PersonalTask) bool) []*PersonalTask {
+	a.taskMutex.RLock()
+	var tasks []*PersonalTask
+	for _, task := range a.tasks {
+		if ownedBy(ctx, task.UserID) && keep(task) {
+			snapshot := *task
+			snapshot.TimeSpent = append([]TimeEntry(nil), task.TimeSpent...)
+			snapshot.Subtasks = append([]Subtask(nil), task.Subtasks...)
+			snapshot.CalendarBlocks = append([]CalendarBlock(nil), task.CalendarBlocks...)
+			tasks = append(tasks, &snapshot)
+		}
+	}
+	a.taskMutex.RUnlock()
+
+	sortByUrgency(tasks)
+	return tasks
+}
+
+// saveTask persists a task in its owner's memory
+func (a *TaskManagerAgent) saveTask(ctx context.Context, task *PersonalTask) error {
+	if a.memoryStore == nil {
+		return nil
+	}
+	taskKey := fmt.Sprintf("personal_task:%s", task.ID)
+	if err := a.memoryStore.Store(ownerContext(ctx, task.UserID), taskKey, task); err != nil {
+		return fmt.Errorf("failed to save task %s: %w", task.ID, err)
+	}
+	return nil
+}
+
+// cancelDueReminder drops the automatic reminder before the task's due date
+func (a *TaskManagerAgent) cancelDueReminder(ctx context.Context, task *PersonalTask) {
+	reminderID := fmt.Sprintf("reminder_%s_due", task.ID)
+	a.taskMutex.Lock()
+	if reminder, ok := a.reminders[reminderID]; ok {
+		reminder.Status = ReminderStatusCancelled
+	}
+	a.taskMutex.Unlock()
+
+	if err := a.reminderEngine.Cancel(ctx, reminderID); err != nil && !errors.Is(err, reminders.ErrNotFound) {
+		a.logger.WarnContext(ctx, "Failed to cancel due date reminder", "task_id", task.ID, "error", err)
+	}
+}
+
+// writeTaskList writes one line per task, up to maxListedTasks
+func (a *TaskManagerAgent) writeTaskList(b *strings.Builder, tasks []*PersonalTask, loc *time.Location) {
+	for i, task := range tasks {
+		if i >= maxListedTasks {
+			fmt.Fprintf(b, "   ... and %d more\n", len(tasks)-i)
+			return
+		}
+		fmt.Fprintf(b, "%d. %s %s **%s**", i+1, a.getStatusEmoji(task.Status), a.getPriorityEmoji(task.Priority), task.Title)
+		if count := subtaskCount(task); count != "" {
+			fmt.Fprintf(b, " [%s]", count)
+		}
+		if task.DueDate != nil {
+			fmt.Fprintf(b, " — due %s", formatTaskDue(task.DueDate, loc))
+		}
+		if task.EstimatedTime > 0 {
+			fmt.Fprintf(b, " (~%s)", formatDuration(task.EstimatedTime))
+		}
+		b.WriteString("\n")
+	}
+}
+
+// sortByUrgency orders tasks by priority, then earliest due date, with
+// undated tasks last
+func sortByUrgency(tasks []*PersonalTask) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Priority != tasks[j].Priority {
+			return tasks[i].Priority > tasks[j].Priority
+		}
+		switch {
+		case tasks[i].DueDate != nil && tasks[j].DueDate != nil:
+			return tasks[i].DueDate.Before(*tasks[j].DueDate)
+		case tasks[i].DueDate != nil || tasks[j].DueDate != nil:
+			return tasks[i].DueDate != nil
+		}
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+}
+
+// filterTasks returns the tasks keep accepts
+func filterTasks(tasks []*PersonalTask, keep func(*PersonalTask) bool) []*PersonalTask {
+	var kept []*PersonalTask
+	for _, task := range tasks {
+		if keep(task) {
+			kept = append(kept, task)
+		}
+	}
+	return kept
+}
+
+// parseTaskStatus reads a status name, accepting spaces, "done" and
+// "doing"
+func parseTaskStatus(status string) (PersonalTaskStatus, bool) {
+	status = strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(status)))
+	switch status {
+	case "done":
+		return PersonalTaskStatusCompleted, true
+	case "doing", "inprogress":
+		return PersonalTaskStatusInProgress, true
+	}
+	switch s := PersonalTaskStatus(status); s {
+	case PersonalTaskStatusInbox, PersonalTaskStatusNext, PersonalTaskStatusSomeday, PersonalTaskStatusWaiting,
+		PersonalTaskStatusInProgress, PersonalTaskStatusCompleted, PersonalTaskStatusCancelled, PersonalTaskStatusDeferred:
+		return s, true
+	}
+	return "", false
+}
+
+// formatTaskDue shows a due date in loc, or "none"
+func formatTaskDue(due *time.Time, loc *time.Location) string {
+	if due == nil {
+		return "none"
+	}
+	return due.In(loc).Format("Mon Jan 2 15:04")
+}
+
+// formatOverdue shows how long ago something was due, in days once it is
+// more than a day
+func formatOverdue(d time.Duration) string {
+	if d < 24*time.Hour {
+		return formatDuration(d)
+	}
+	days := int(d / (24 * time.Hour))
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// estimateNote sums the estimates of tasks, or returns "" if none have one
+func estimateNote(tasks []*PersonalTask) string {
+	var total time.Duration
+	for _, task := range tasks {
+		total += task.EstimatedTime
+	}
+	if total == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (~%s of work)", formatDuration(total))
+}
+
+// priorityName is the word parsePriority reads back as priority
+func priorityName(priority multiagent.Priority) string {
+	switch priority {
+	case multiagent.PriorityCritical:
+		return "critical"
+	case multiagent.PriorityHigh:
+		return "high"
+	case multiagent.PriorityLow:
+		return "low"
+	default:
+		return "medium"
+	}
+}
@@ -0,0 +1,477 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/reminders"
+	"github.com/kbutz/wikillm/multiagent/timeparse"
+)
+
+const (
+	// statsWeeks is how many weeks productivity analytics look back over
+	statsWeeks = 4
+	// stalledAfter is how long a next action can sit untouched before the
+	// weekly review calls it stalled
+	stalledAfter = 14 * 24 * time.Hour
+	// defaultReviewHour is when the weekly review is sent when no time is named
+	defaultReviewHour = 16
+)
+
+// dayPeriods names the parts of the day analytics group work into, by the
+// hour each starts
+var dayPeriods = []struct {
+	Name  string
+	Start int
+}{
+	{"night", 0},
+	{"morning", 5},
+	{"afternoon", 12},
+	{"evening", 17},
+	{"night", 22},
+}
+
+// weekStats is one week of productivity analytics
+type weekStats struct {
+	Start     time.Time
+	Completed int
+	Due       int
+	OnTime    int
+}
+
+// productivityStats summarizes a user's tasks over the last statsWeeks weeks
+type productivityStats struct {
+	Created         int
+	Completed       int
+	CreatedDone     int           // Of the tasks created in the window, how many are done
+	LeadTime        time.Duration // Average time from adding a task to finishing it
+	CycleTime       time.Duration // Average time from starting a task to finishing it
+	Started         int           // How many finished tasks have a recorded start
+	Overdue         int
+	Weeks           []weekStats
+	CompletedAt     map[string]int
+	TrackedAt       map[string]time.Duration
+	BestWeekday     time.Weekday
+	BestWeekdayDone int
+}
+
+// handleProductivityStats reports completion rate, cycle time, overdue
+// trends and when in the day the user gets things done
+func (a *TaskManagerAgent) handleProductivityStats(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now().In(loc)
+
+	stats := computeProductivityStats(a.userTasks(ctx, func(task *PersonalTask) bool { return task.DeletedAt == nil }), now)
+	if stats.Created == 0 && stats.Completed == 0 {
+		return a.respond(msg, fmt.Sprintf("📊 No tasks were added or finished in the last %d weeks, so there's nothing to analyze yet.", statsWeeks), map[string]interface{}{
+			"action": "productivity_stats",
+		}), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📊 **Productivity, last %d weeks**\n\n", statsWeeks)
+	fmt.Fprintf(&b, "• Added %d tasks, finished %d\n", stats.Created, stats.Completed)
+	if stats.Created > 0 {
+		fmt.Fprintf(&b, "• Completion rate: %.0f%% of the tasks added are done\n", 100*float64(stats.CreatedDone)/float64(stats.Created))
+	}
+	if stats.LeadTime > 0 {
+		fmt.Fprintf(&b, "• Average lead time: %s from adding a task to finishing it\n", formatCycleTime(stats.LeadTime))
+	}
+	if stats.CycleTime > 0 {
+		fmt.Fprintf(&b, "• Average cycle time: %s from starting a task to finishing it (%d tasks)\n", formatCycleTime(stats.CycleTime), stats.Started)
+	}
+	fmt.Fprintf(&b, "• Overdue right now: %d\n", stats.Overdue)
+
+	b.WriteString("\n**Week by week**\n")
+	for _, week := range stats.Weeks {
+		fmt.Fprintf(&b, "• %s: %d done", week.Start.Format("Jan 2"), week.Completed)
+		if week.Due > 0 {
+			fmt.Fprintf(&b, ", %d of %d due on time", week.OnTime, week.Due)
+		}
+		b.WriteString("\n")
+	}
+	if trend := overdueTrend(stats.Weeks); trend != "" {
+		fmt.Fprintf(&b, "%s\n", trend)
+	}
+
+	if period, count := busiestPeriod(stats.CompletedAt); count > 0 {
+		fmt.Fprintf(&b, "\n**When you get things done**\n• Most tasks are finished in the %s (%d)\n", period, count)
+		if stats.BestWeekdayDone > 0 {
+			fmt.Fprintf(&b, "• Your most productive day is %s (%d done)\n", stats.BestWeekday, stats.BestWeekdayDone)
+		}
+		if period, tracked := longestTracked(stats.TrackedAt); tracked > 0 {
+			fmt.Fprintf(&b, "• Most tracked time falls in the %s (%s)\n", period, formatDuration(tracked))
+		}
+	}
+
+	return a.respond(msg, b.String(), map[string]interface{}{
+		"action":    "productivity_stats",
+		"created":   stats.Created,
+		"completed": stats.Completed,
+		"overdue":   stats.Overdue,
+	}), nil
+}
+
+// computeProductivityStats analyzes tasks over the statsWeeks weeks up to
+// now, grouping by week and time of day in now's location
+func computeProductivityStats(tasks []*PersonalTask, now time.Time) productivityStats {
+	loc := now.Location()
+	thisWeek := startOfWeek(now)
+	from := thisWeek.AddDate(0, 0, -7*(statsWeeks-1))
+
+	stats := productivityStats{
+		CompletedAt: make(map[string]int),
+		TrackedAt:   make(map[string]time.Duration),
+	}
+	for i := 0; i < statsWeeks; i++ {
+		stats.Weeks = append(stats.Weeks, weekStats{Start: from.AddDate(0, 0, 7*i)})
+	}
+	weekOf := func(t time.Time) int {
+		if t.Before(from) || t.After(now) {
+			return -1
+		}
+		for i := len(stats.Weeks) - 1; i >= 0; i-- {
+			if !t.Before(stats.Weeks[i].Start) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	var leadTotal, cycleTotal time.Duration
+	byWeekday := make(map[time.Weekday]int)
+	for _, task := range tasks {
+		if task.Status == PersonalTaskStatusCancelled {
+			continue
+		}
+		done := task.Status == PersonalTaskStatusCompleted && task.CompletedAt != nil
+		if !task.CreatedAt.Before(from) {
+			stats.Created++
+			if done {
+				stats.CreatedDone++
+			}
+		}
+		if task.isActive() && task.DueDate != nil && task.DueDate.Before(now) {
+			stats.Overdue++
+		}
+
+		if done {
+			if week := weekOf(*task.CompletedAt); week >= 0 {
+				completed := task.CompletedAt.In(loc)
+				stats.Completed++
+				stats.Weeks[week].Completed++
+				leadTotal += completed.Sub(task.CreatedAt)
+				if started := task.startedAt(); started != nil && started.Before(completed) {
+					stats.Started++
+					cycleTotal += completed.Sub(*started)
+				}
+				stats.CompletedAt[dayPeriod(completed.Hour())]++
+				byWeekday[completed.Weekday()]++
+			}
+		}
+		if task.DueDate != nil {
+			if week := weekOf(*task.DueDate); week >= 0 {
+				stats.Weeks[week].Due++
+				if done && !task.CompletedAt.After(*task.DueDate) {
+					stats.Weeks[week].OnTime++
+				}
+			}
+		}
+
+		for _, entry := range task.TimeSpent {
+			if weekOf(entry.StartTime) >= 0 {
+				duration := entry.Duration
+				if entry.EndTime == nil {
+					duration = now.Sub(entry.StartTime)
+				}
+				stats.TrackedAt[dayPeriod(entry.StartTime.In(loc).Hour())] += duration
+			}
+		}
+	}
+
+	if stats.Completed > 0 {
+		stats.LeadTime = leadTotal / time.Duration(stats.Completed)
+	}
+	if stats.Started > 0 {
+		stats.CycleTime = cycleTotal / time.Duration(stats.Started)
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if byWeekday[weekday] > stats.BestWeekdayDone {
+			stats.BestWeekday, stats.BestWeekdayDone = weekday, byWeekday[weekday]
+		}
+	}
+	return stats
+}
+
+// handleWeeklyReview sends the user their GTD weekly review now
+func (a *TaskManagerAgent) handleWeeklyReview(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	return a.respond(msg, a.weeklyReview(ctx, a.now().In(loc)), map[string]interface{}{
+		"action": "weekly_review",
+	}), nil
+}
+
+// weeklyReview walks the GTD weekly review over the user's tasks: what got
+// done, what needs processing or chasing, and what's coming up
+func (a *TaskManagerAgent) weeklyReview(ctx context.Context, now time.Time) string {
+	loc := now.Location()
+	weekStart := startOfWeek(now)
+	nextWeek := now.AddDate(0, 0, 7)
+
+	var done, overdue, waiting, stalled, upcoming []*PersonalTask
+	inbox, someday := 0, 0
+	var tracked time.Duration
+	for _, task := range a.userTasks(ctx, func(task *PersonalTask) bool { return task.DeletedAt == nil }) {
+		tracked += timeSpentBetween(task, weekStart, now, now)
+		if task.Status == PersonalTaskStatusCompleted {
+			if task.CompletedAt != nil && !task.CompletedAt.Before(weekStart) {
+				done = append(done, task)
+			}
+			continue
+		}
+		if !task.isActive() {
+			continue
+		}
+		switch task.Status {
+		case PersonalTaskStatusInbox:
+			inbox++
+		case PersonalTaskStatusSomeday:
+			someday++
+		case PersonalTaskStatusWaiting:
+			waiting = append(waiting, task)
+		case PersonalTaskStatusNext:
+			// Overdue tasks are listed as overdue instead
+			if now.Sub(task.UpdatedAt) > stalledAfter && (task.DueDate == nil || !task.DueDate.Before(now)) {
+				stalled = append(stalled, task)
+			}
+		}
+		switch {
+		case task.DueDate == nil:
+		case task.DueDate.Before(now):
+			overdue = append(overdue, task)
+		case task.DueDate.Before(nextWeek):
+			upcoming = append(upcoming, task)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🗓️ **Weekly Review — week of %s**\n", weekStart.Format("Mon 2 Jan"))
+
+	b.WriteString("\n**Get clear**\n")
+	fmt.Fprintf(&b, "✅ Finished this week: %d", len(done))
+	if tracked > 0 {
+		fmt.Fprintf(&b, " (%s tracked)", formatDuration(tracked))
+	}
+	b.WriteString("\n")
+	a.writeTaskList(&b, done, loc)
+	if inbox > 0 {
+		fmt.Fprintf(&b, "📥 Process your inbox: %d task(s) need a next action, a date, or a place in someday\n", inbox)
+	} else {
+		b.WriteString("📥 Your inbox is empty\n")
+	}
+
+	b.WriteString("\n**Get current**\n")
+	if len(overdue) > 0 {
+		b.WriteString("⚠️ Overdue — reschedule, finish or drop:\n")
+		a.writeTaskList(&b, overdue, loc)
+	}
+	if len(waiting) > 0 {
+		b.WriteString("⏸️ Waiting for — chase anything that's been quiet:\n")
+		for i, task := range waiting {
+			if i >= maxListedTasks {
+				fmt.Fprintf(&b, "   ... and %d more\n", len(waiting)-i)
+				break
+			}
+			on := ""
+			if task.WaitingOn != "" {
+				on = " on " + task.WaitingOn
+			}
+			fmt.Fprintf(&b, "%d. **%s**%s (%d days)\n", i+1, task.Title, on, int(now.Sub(task.UpdatedAt).Hours()/24))
+		}
+	}
+	if len(stalled) > 0 {
+		b.WriteString("🐢 Stalled next actions — still the right next step?\n")
+		a.writeTaskList(&b, stalled, loc)
+	}
+	if len(upcoming) > 0 {
+		b.WriteString("📅 Due in the coming week:\n")
+		a.writeTaskList(&b, upcoming, loc)
+	}
+	if len(overdue)+len(waiting)+len(stalled)+len(upcoming) == 0 {
+		b.WriteString("Nothing overdue, stalled or due in the coming week.\n")
+	}
+
+	b.WriteString("\n**Get creative**\n")
+	if someday > 0 {
+		fmt.Fprintf(&b, "💭 Look over your %d someday/maybe task(s): is anything ready to start?\n", someday)
+	}
+	b.WriteString("✍️ Anything new on your mind? Capture it with \"add task\".")
+	return b.String()
+}
+
+// handleScheduleReview sends the weekly review every week at the day and
+// time the user names (Friday 16:00 by default), or stops sending it
+func (a *TaskManagerAgent) handleScheduleReview(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	userID := multiagent.UserIDFromContext(ctx)
+	reminderID := "weekly_review_" + userID
+	content := strings.ToLower(msg.Content)
+
+	if strings.Contains(content, "cancel") || strings.Contains(content, "stop") || strings.Contains(content, "turn off") {
+		if err := a.reminderEngine.Cancel(ctx, reminderID); errors.Is(err, reminders.ErrNotFound) {
+			return a.respond(msg, "🗓️ Your weekly review isn't scheduled.", nil), nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to cancel weekly review: %w", err)
+		}
+		return a.respond(msg, "🗓️ I'll stop sending your weekly review.", map[string]interface{}{
+			"action": "weekly_review_cancelled",
+		}), nil
+	}
+
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now().In(loc)
+	next := nextWeekdayAt(now, time.Friday, defaultReviewHour, 0)
+	if found, ok := timeparse.Extract(msg.Content, now); ok {
+		weekday, hour, minute := time.Friday, defaultReviewHour, 0
+		if found.HasDate {
+			weekday = found.Time.Weekday()
+		}
+		if found.HasTime {
+			hour, minute = found.Time.Hour(), found.Time.Minute()
+		}
+		next = nextWeekdayAt(now, weekday, hour, minute)
+	}
+
+	err := a.reminderEngine.Schedule(ctx, reminders.Reminder{
+		ID:        reminderID,
+		UserID:    userID,
+		Source:    weeklyReviewSource,
+		Title:     "🗓️ Your weekly review",
+		Kind:      notify.KindWeeklyReview,
+		Priority:  multiagent.PriorityMedium,
+		TriggerAt: next,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule weekly review: %w", err)
+	}
+
+	return a.respond(msg, fmt.Sprintf("🗓️ I'll send your weekly review every %s at %s, starting %s.", next.Weekday(), next.Format("15:04"), next.Format("Mon 2 Jan")), map[string]interface{}{
+		"action":   "weekly_review_scheduled",
+		"next_run": next,
+	}), nil
+}
+
+// fireWeeklyReview is the engine's handler for the scheduled weekly review:
+// it sends the review and fires again a week later
+func (a *TaskManagerAgent) fireWeeklyReview(ctx context.Context, scheduled reminders.Reminder, now time.Time) (*notify.Notification, time.Time) {
+	a.loadTasksFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+
+	// Step by calendar weeks in the user's timezone so it keeps its time
+	// of day across daylight saving changes
+	next := scheduled.TriggerAt.In(loc)
+	for !next.After(now) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return &notify.Notification{
+		UserID:   scheduled.UserID,
+		Kind:     notify.KindWeeklyReview,
+		Title:    scheduled.Title,
+		Body:     a.weeklyReview(ctx, now.In(loc)),
+		Priority: multiagent.PriorityMedium,
+		At:       now,
+		Subject:  scheduled.ID,
+	}, next
+}
+
+// nextWeekdayAt returns the next time after now that falls on weekday at
+// hour:minute in now's location
+func nextWeekdayAt(now time.Time, weekday time.Weekday, hour, minute int) time.Time {
+	days := (int(weekday) - int(now.Weekday()) + 7) % 7
+	next := time.Date(now.Year(), now.Month(), now.Day()+days, hour, minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// dayPeriod names the part of the day an hour falls in
+func dayPeriod(hour int) string {
+	name := dayPeriods[0].Name
+	for _, period := range dayPeriods {
+		if hour >= period.Start {
+			name = period.Name
+		}
+	}
+	return name
+}
+
+// busiestPeriod returns the part of the day with the most completions
+func busiestPeriod(counts map[string]int) (string, int) {
+	best, most := "", 0
+	for _, period := range dayPeriods {
+		if counts[period.Name] > most {
+			best, most = period.Name, counts[period.Name]
+		}
+	}
+	return best, most
+}
+
+// longestTracked returns the part of the day with the most tracked time
+func longestTracked(tracked map[string]time.Duration) (string, time.Duration) {
+	best, most := "", time.Duration(0)
+	for _, period := range dayPeriods {
+		if tracked[period.Name] > most {
+			best, most = period.Name, tracked[period.Name]
+		}
+	}
+	return best, most
+}
+
+// overdueTrend compares the on-time rate of the last two finished weeks
+// with the weeks before them, or returns "" when there's too little to go on
+func overdueTrend(weeks []weekStats) string {
+	if len(weeks) < 3 {
+		return ""
+	}
+	// The current week isn't over, so leave it out
+	past := weeks[:len(weeks)-1]
+	split := len(past) - 2
+	if split < 1 {
+		split = 1
+	}
+	rate := func(weeks []weekStats) (float64, bool) {
+		due, onTime := 0, 0
+		for _, week := range weeks {
+			due += week.Due
+			onTime += week.OnTime
+		}
+		return float64(onTime) / float64(due), due > 0
+	}
+	before, okBefore := rate(past[:split])
+	recent, okRecent := rate(past[split:])
+	switch {
+	case !okBefore || !okRecent:
+		return ""
+	case recent > before+0.1:
+		return fmt.Sprintf("📈 Meeting more deadlines lately: %.0f%% on time, up from %.0f%%", 100*recent, 100*before)
+	case recent < before-0.1:
+		return fmt.Sprintf("📉 More deadlines slipping lately: %.0f%% on time, down from %.0f%%", 100*recent, 100*before)
+	default:
+		return fmt.Sprintf("➖ Deadlines steady at about %.0f%% on time", 100*recent)
+	}
+}
+
+// formatCycleTime shows a cycle time in days once it is more than a day
+func formatCycleTime(d time.Duration) string {
+	if d < 24*time.Hour {
+		return formatDuration(d)
+	}
+	return fmt.Sprintf("%.1f days", d.Hours()/24)
+}
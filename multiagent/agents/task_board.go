@@ -0,0 +1,265 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// boardDoneWindow is how far back the board's done column reaches
+const boardDoneWindow = 7 * 24 * time.Hour
+
+// boardColumns are the board's columns, left to right
+var boardColumns = []struct {
+	Title  string
+	Status PersonalTaskStatus
+}{
+	{"📥 Inbox", PersonalTaskStatusInbox},
+	{"➡️ Next", PersonalTaskStatusNext},
+	{"⏳ In progress", PersonalTaskStatusInProgress},
+	{"⏸️ Waiting", PersonalTaskStatusWaiting},
+	{"✅ Done", PersonalTaskStatusCompleted},
+}
+
+// movePhrase matches "move X to waiting on Bob" when the LLM can't read
+// the request
+var movePhrase = regexp.MustCompile(`(?i)^move\s+(.+?)\s+(?:to|into)\s+(inbox|next|in[ _-]?progress|doing|waiting|done|someday|deferred|cancelled)(?:\s+(?:on|for)\s+(.+?))?[.!?]*$`)
+
+// setStatus moves the task to status at now, recording the transition.
+// Leaving in-progress stops the task's timer, and completing it stamps
+// CompletedAt.
+func (t *PersonalTask) setStatus(status PersonalTaskStatus, now time.Time, note string) {
+	if t.Status == status {
+		return
+	}
+	if status != PersonalTaskStatusInProgress {
+		t.stopTimer(now)
+	}
+	t.Transitions = append(t.Transitions, StatusTransition{From: t.Status, To: status, At: now, Note: note})
+	t.Status = status
+	if status == PersonalTaskStatusCompleted {
+		t.CompletedAt = &now
+		t.Progress = 100.0
+	} else {
+		t.CompletedAt = nil
+		t.rollUpProgress()
+	}
+	if status != PersonalTaskStatusWaiting {
+		t.WaitingOn = ""
+	}
+	t.UpdatedAt = now
+}
+
+// recordCompleted audits a task that was just completed, so completions
+// reach the audit log whichever way the user finished the task
+func (a *TaskManagerAgent) recordCompleted(ctx context.Context, msg *multiagent.Message, wasCompleted bool, task *PersonalTask) {
+	if wasCompleted || task.Status != PersonalTaskStatusCompleted {
+		return
+	}
+	a.recordAudit(ctx, msg, audit.TaskCompleted, task.ID, map[string]interface{}{
+		"title":   task.Title,
+		"project": task.Project,
+	})
+}
+
+// startedAt returns when work on the task first started, or nil if it
+// never moved to in-progress
+func (t *PersonalTask) startedAt() *time.Time {
+	for _, transition := range t.Transitions {
+		if transition.To == PersonalTaskStatusInProgress {
+			at := transition.At
+			return &at
+		}
+	}
+	return nil
+}
+
+// handleBoard shows the user's tasks as a board with a column per status
+func (a *TaskManagerAgent) handleBoard(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now()
+
+	columns := make(map[PersonalTaskStatus][]*PersonalTask)
+	parked := 0
+	for _, task := range a.userTasks(ctx, func(task *PersonalTask) bool { return task.DeletedAt == nil }) {
+		switch task.Status {
+		case PersonalTaskStatusCompleted:
+			if task.CompletedAt != nil && now.Sub(*task.CompletedAt) <= boardDoneWindow {
+				columns[task.Status] = append(columns[task.Status], task)
+			}
+		case PersonalTaskStatusSomeday, PersonalTaskStatusDeferred:
+			parked++
+		case PersonalTaskStatusCancelled:
+		default:
+			columns[task.Status] = append(columns[task.Status], task)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("🗂️ **Task Board**\n")
+	var header []string
+	for _, column := range boardColumns {
+		header = append(header, fmt.Sprintf("%s %d", column.Title, len(columns[column.Status])))
+	}
+	fmt.Fprintf(&b, "%s\n", strings.Join(header, " │ "))
+
+	for _, column := range boardColumns {
+		tasks := columns[column.Status]
+		fmt.Fprintf(&b, "\n**%s**\n", column.Title)
+		if len(tasks) == 0 {
+			b.WriteString("   —\n")
+			continue
+		}
+		for i, task := range tasks {
+			if i >= maxListedTasks {
+				fmt.Fprintf(&b, "   ... and %d more\n", len(tasks)-i)
+				break
+			}
+			fmt.Fprintf(&b, "• %s %s", a.getPriorityEmoji(task.Priority), task.Title)
+			if count := subtaskCount(task); count != "" && task.Status != PersonalTaskStatusCompleted {
+				fmt.Fprintf(&b, " [%s]", count)
+			}
+			switch {
+			case task.Status == PersonalTaskStatusWaiting && task.WaitingOn != "":
+				fmt.Fprintf(&b, " — on %s", task.WaitingOn)
+			case task.Status == PersonalTaskStatusCompleted:
+				fmt.Fprintf(&b, " — %s", task.CompletedAt.In(loc).Format("Mon"))
+			case task.DueDate != nil:
+				fmt.Fprintf(&b, " — due %s", formatTaskDue(task.DueDate, loc))
+			}
+			b.WriteString("\n")
+		}
+	}
+	if parked > 0 {
+		fmt.Fprintf(&b, "\n💭 %d someday/deferred task(s) not shown.\n", parked)
+	}
+	b.WriteString("\nMove a card with \"move <task> to next / in progress / waiting on <someone> / done\".")
+
+	return a.respond(msg, b.String(), map[string]interface{}{
+		"action": "task_board",
+	}), nil
+}
+
+// handleMoveTask moves one of the user's tasks to another column,
+// recording the transition
+func (a *TaskManagerAgent) handleMoveTask(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+
+	prompt, err := a.renderPrompt(ctx, "task.move", prompts.Vars{"Request": msg.Content})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		taskReference
+		Status    string `json:"status"`
+		WaitingOn string `json:"waiting_on"`
+		Note      string `json:"note"`
+	}
+	moveSchema := objectSchema(map[string]string{
+		"task_id":    "string",
+		"title":      "string",
+		"status":     "string",
+		"waiting_on": "string",
+		"note":       "string",
+	}, "status")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, moveSchema, &data); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse task move", "error", err)
+		if match := movePhrase.FindStringSubmatch(strings.TrimSpace(msg.Content)); match != nil {
+			data.Title, data.Status, data.WaitingOn = match[1], match[2], match[3]
+		}
+	}
+	status, ok := parseTaskStatus(data.Status)
+	if !ok {
+		return a.respond(msg, "🗂️ Where should it go? Say something like \"move report to in progress\" or \"move invoice to waiting on Bob\".", nil), nil
+	}
+
+	a.taskMutex.Lock()
+	task := a.resolveTask(ctx, data.taskReference, msg.Content, false)
+	if task == nil {
+		a.taskMutex.Unlock()
+		return a.respond(msg, "❌ Task not found. Please specify a valid task ID or title.", nil), nil
+	}
+	from := task.Status
+	if from == status && (status != PersonalTaskStatusWaiting || data.WaitingOn == "") {
+		title := task.Title
+		a.taskMutex.Unlock()
+		return a.respond(msg, fmt.Sprintf("🗂️ '%s' is already in %s.", title, statusName(status)), nil), nil
+	}
+
+	now := a.now()
+	task.setStatus(status, now, data.Note)
+	if status == PersonalTaskStatusWaiting && data.WaitingOn != "" {
+		task.WaitingOn = data.WaitingOn
+		task.UpdatedAt = now
+	}
+	var unblocked string
+	if status == PersonalTaskStatusCompleted {
+		a.scheduleNextOccurrence(ctx, task)
+		unblocked = a.unblockedNote(ctx, task.ID)
+	}
+	var blockChanges []TimeBlockRequest
+	if !task.isActive() {
+		blockChanges = task.releaseCalendarBlocks(now)
+	}
+	snapshot := *task
+	snapshot.TimeSpent = append([]TimeEntry(nil), task.TimeSpent...)
+	snapshot.Transitions = append([]StatusTransition(nil), task.Transitions...)
+	a.taskMutex.Unlock()
+
+	if err := a.saveTask(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	a.syncTimeBlocks(ctx, blockChanges)
+	a.publishLinkedStatus(ctx, from == PersonalTaskStatusCompleted, &snapshot)
+	a.recordCompleted(ctx, msg, from == PersonalTaskStatusCompleted, &snapshot)
+
+	destination := statusName(status)
+	if snapshot.WaitingOn != "" {
+		destination += " on " + snapshot.WaitingOn
+	}
+	a.recordShared(ctx, msg, memory.BlackboardEntry{
+		Section: memory.BlackboardFacts,
+		Key:     "task:" + snapshot.ID,
+		Value:   fmt.Sprintf("Personal task '%s' moved from %s to %s", snapshot.Title, statusName(from), destination),
+	})
+	a.recordAudit(ctx, msg, audit.TaskUpdated, snapshot.ID, map[string]interface{}{
+		"title":      snapshot.Title,
+		"from":       from,
+		"to":         status,
+		"waiting_on": snapshot.WaitingOn,
+	})
+
+	content := fmt.Sprintf("🗂️ Moved '%s' from %s to %s.", snapshot.Title, statusName(from), destination)
+	if note := timeBlockNote(blockChanges); note != "" {
+		content += "\n" + note
+	}
+	if unblocked != "" {
+		content += "\n\n" + unblocked
+	}
+	return a.respond(msg, content, map[string]interface{}{
+		"task_id": snapshot.ID,
+		"action":  "task_moved",
+		"from":    string(from),
+		"to":      string(status),
+	}), nil
+}
+
+// statusName is how a status reads in a sentence
+func statusName(status PersonalTaskStatus) string {
+	switch status {
+	case PersonalTaskStatusInProgress:
+		return "in progress"
+	case PersonalTaskStatusCompleted:
+		return "done"
+	default:
+		return string(status)
+	}
+}
@@ -0,0 +1,257 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// dependencyPhrase matches "X depends on Y", "X is blocked by Y" and "X no
+// longer depends on Y" when the LLM can't read the request
+var dependencyPhrase = regexp.MustCompile(`(?i)^(?:make\s+)?(.+?)\s+(no longer depends on|doesn't depend on|does not depend on|depends on|depend on|is blocked by|blocked by|waits? (?:for|on))\s+(.+?)[.!?]*$`)
+
+// handleSetDependency records that one of the user's tasks can't start
+// until another is done, or removes that dependency, refusing changes that
+// would make tasks wait on each other
+func (a *TaskManagerAgent) handleSetDependency(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+
+	prompt, err := a.renderPrompt(ctx, "task.dependency", prompts.Vars{"Request": msg.Content})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		TaskID      string `json:"task_id"`
+		Task        string `json:"task"`
+		DependsOnID string `json:"depends_on_id"`
+		DependsOn   string `json:"depends_on"`
+		Remove      bool   `json:"remove"`
+	}
+	dependencySchema := objectSchema(map[string]string{
+		"task_id":       "string",
+		"task":          "string",
+		"depends_on_id": "string",
+		"depends_on":    "string",
+		"remove":        "boolean",
+	}, "task", "depends_on")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, dependencySchema, &data); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse task dependency", "error", err)
+		match := dependencyPhrase.FindStringSubmatch(strings.TrimSpace(msg.Content))
+		if match == nil {
+			return a.respond(msg, "🔗 Which task waits for which? Say something like \"publish post depends on write draft\".", nil), nil
+		}
+		verb := strings.ToLower(match[2])
+		data.Task, data.DependsOn = match[1], match[3]
+		data.Remove = strings.Contains(verb, "longer") || strings.Contains(verb, "n't") || strings.Contains(verb, "not")
+	}
+
+	a.taskMutex.Lock()
+	task := a.resolveTask(ctx, taskReference{TaskID: data.TaskID, Title: data.Task}, "", false)
+	blocker := a.resolveTask(ctx, taskReference{TaskID: data.DependsOnID, Title: data.DependsOn}, "", false)
+	switch {
+	case task == nil || blocker == nil:
+		a.taskMutex.Unlock()
+		missing := data.Task
+		if task != nil {
+			missing = data.DependsOn
+		}
+		return a.respond(msg, fmt.Sprintf("❌ I couldn't find the task '%s'.", missing), nil), nil
+	case task.ID == blocker.ID:
+		a.taskMutex.Unlock()
+		return a.respond(msg, "🔗 A task can't depend on itself.", nil), nil
+	}
+
+	var content string
+	if data.Remove {
+		kept := make([]string, 0, len(task.Dependencies))
+		for _, id := range task.Dependencies {
+			if id != blocker.ID {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == len(task.Dependencies) {
+			a.taskMutex.Unlock()
+			return a.respond(msg, fmt.Sprintf("🔗 '%s' doesn't depend on '%s'.", task.Title, blocker.Title), nil), nil
+		}
+		task.Dependencies = kept
+		content = fmt.Sprintf("🔓 '%s' no longer waits for '%s'.", task.Title, blocker.Title)
+		if blockers := a.blockers(task); len(blockers) > 0 {
+			content += fmt.Sprintf(" It's still waiting on %s.", taskTitles(blockers))
+		}
+	} else {
+		for _, id := range task.Dependencies {
+			if id == blocker.ID {
+				a.taskMutex.Unlock()
+				return a.respond(msg, fmt.Sprintf("🔗 '%s' already waits for '%s'.", task.Title, blocker.Title), nil), nil
+			}
+		}
+		// The blocker waiting on the task, however indirectly, would leave
+		// both waiting forever
+		if path := a.dependencyPath(blocker.ID, task.ID, false); path != nil {
+			a.taskMutex.Unlock()
+			return a.respond(msg, fmt.Sprintf("♻️ I can't do that: %s already waits for '%s' (%s), so neither could ever start.", quoteTitle(blocker), task.Title, cycleTitles(path)), map[string]interface{}{
+				"action": "dependency_cycle",
+			}), nil
+		}
+		task.Dependencies = append(task.Dependencies, blocker.ID)
+		content = fmt.Sprintf("🔗 '%s' now waits for '%s'.", task.Title, blocker.Title)
+		if !blocker.isActive() {
+			content += fmt.Sprintf(" '%s' is already done, so it isn't blocked.", blocker.Title)
+		}
+	}
+	task.UpdatedAt = a.now()
+	snapshot := *task
+	snapshot.Dependencies = append([]string(nil), task.Dependencies...)
+	blockerID := blocker.ID
+	a.taskMutex.Unlock()
+
+	if err := a.saveTask(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	a.recordAudit(ctx, msg, audit.TaskUpdated, snapshot.ID, map[string]interface{}{
+		"title":        snapshot.Title,
+		"dependencies": snapshot.Dependencies,
+	})
+
+	action := "dependency_added"
+	if data.Remove {
+		action = "dependency_removed"
+	}
+	return a.respond(msg, content, map[string]interface{}{
+		"task_id":    snapshot.ID,
+		"depends_on": blockerID,
+		"action":     action,
+	}), nil
+}
+
+// blockers returns the unfinished tasks task waits for; callers hold
+// taskMutex
+func (a *TaskManagerAgent) blockers(task *PersonalTask) []*PersonalTask {
+	var blockers []*PersonalTask
+	for _, id := range task.Dependencies {
+		if dep, ok := a.tasks[id]; ok && dep.isActive() {
+			blockers = append(blockers, dep)
+		}
+	}
+	return blockers
+}
+
+// unblockedBy returns the user's open tasks that waited for the task with
+// the given ID and now wait for nothing; callers hold taskMutex
+func (a *TaskManagerAgent) unblockedBy(ctx context.Context, taskID string) []*PersonalTask {
+	var unblocked []*PersonalTask
+	for _, task := range a.tasks {
+		if !ownedBy(ctx, task.UserID) || !task.isActive() || len(a.blockers(task)) > 0 {
+			continue
+		}
+		for _, id := range task.Dependencies {
+			if id == taskID {
+				unblocked = append(unblocked, task)
+				break
+			}
+		}
+	}
+	sort.Slice(unblocked, func(i, j int) bool { return unblocked[i].Title < unblocked[j].Title })
+	return unblocked
+}
+
+// unblockedNote tells the user which tasks completing taskID freed up, or
+// returns ""; callers hold taskMutex
+func (a *TaskManagerAgent) unblockedNote(ctx context.Context, taskID string) string {
+	unblocked := a.unblockedBy(ctx, taskID)
+	if len(unblocked) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("🔓 Now unblocked: %s", taskTitles(unblocked))
+}
+
+// dependencyPath returns the chain of tasks from the task with ID from to
+// the one with ID to by following dependencies, or nil if there is none.
+// With activeOnly, finished tasks break the chain. Callers hold taskMutex.
+func (a *TaskManagerAgent) dependencyPath(from, to string, activeOnly bool) []*PersonalTask {
+	visited := make(map[string]bool)
+	var walk func(id string) []*PersonalTask
+	walk = func(id string) []*PersonalTask {
+		task, ok := a.tasks[id]
+		if !ok || visited[id] || (activeOnly && !task.isActive()) {
+			return nil
+		}
+		visited[id] = true
+		if id == to {
+			return []*PersonalTask{task}
+		}
+		for _, dep := range task.Dependencies {
+			if path := walk(dep); path != nil {
+				return append([]*PersonalTask{task}, path...)
+			}
+		}
+		return nil
+	}
+	return walk(from)
+}
+
+// dependencyCycles finds the groups of the user's open tasks that wait on
+// each other, each as a chain starting and ending with the same task;
+// callers hold taskMutex
+func (a *TaskManagerAgent) dependencyCycles(ctx context.Context) [][]*PersonalTask {
+	var ids []string
+	for id, task := range a.tasks {
+		if ownedBy(ctx, task.UserID) && task.isActive() && len(task.Dependencies) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	seen := make(map[string]bool)
+	var cycles [][]*PersonalTask
+	for _, id := range ids {
+		task := a.tasks[id]
+		for _, dep := range task.Dependencies {
+			path := a.dependencyPath(dep, id, true)
+			if path == nil {
+				continue
+			}
+			members := make([]string, 0, len(path))
+			for _, member := range path {
+				members = append(members, member.ID)
+			}
+			sort.Strings(members)
+			if key := strings.Join(members, ","); !seen[key] {
+				seen[key] = true
+				cycles = append(cycles, append([]*PersonalTask{task}, path...))
+			}
+			break
+		}
+	}
+	return cycles
+}
+
+// taskTitles lists task titles in quotes
+func taskTitles(tasks []*PersonalTask) string {
+	titles := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		titles = append(titles, quoteTitle(task))
+	}
+	return strings.Join(titles, ", ")
+}
+
+// cycleTitles shows a chain of tasks as "'A' → 'B' → 'A'"
+func cycleTitles(chain []*PersonalTask) string {
+	titles := make([]string, 0, len(chain))
+	for _, task := range chain {
+		titles = append(titles, quoteTitle(task))
+	}
+	return strings.Join(titles, " → ")
+}
+
+// quoteTitle shows a task's title in quotes
+func quoteTitle(task *PersonalTask) string {
+	return "'" + task.Title + "'"
+}
@@ -0,0 +1,371 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/taskio"
+)
+
+// Formats tasks can be exported in and imported from
+const (
+	TaskFormatTodoist = "todoist"
+	TaskFormatCSV     = "csv"
+)
+
+// taskImportIDKey is the task metadata key holding the ID an imported task
+// had in the app it came from, so importing it again updates it
+const taskImportIDKey = "import_id"
+
+// allDayDue is the time of day all-day due dates are set to, so a task
+// due on a day isn't overdue until that day is over
+const allDayDue = 23*time.Hour + 59*time.Minute
+
+// TaskExchanger is implemented by agents whose tasks can be exported to and
+// imported from other to-do apps
+type TaskExchanger interface {
+	// ExportTasks returns the tasks of the user ctx acts for in format
+	ExportTasks(ctx context.Context, format string) ([]byte, error)
+	// ImportTasks adds or updates the tasks in data, which is in format, in
+	// the task list of the user ctx acts for
+	ImportTasks(ctx context.Context, format string, data []byte) (*TaskImportResult, error)
+}
+
+// TaskImportResult reports what ImportTasks did
+type TaskImportResult struct {
+	Imported int `json:"imported"`
+	Updated  int `json:"updated"`
+	// Subtasks counts tasks that became subtasks of another task
+	Subtasks int `json:"subtasks"`
+}
+
+// ExportTasks returns the user's tasks, subtasks included, as a Todoist
+// export or CSV
+func (a *TaskManagerAgent) ExportTasks(ctx context.Context, format string) ([]byte, error) {
+	a.loadTasksFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+
+	tasks := a.userTasks(ctx, func(task *PersonalTask) bool {
+		return task.DeletedAt == nil && task.Status != PersonalTaskStatusCancelled
+	})
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].CreatedAt.Before(tasks[j].CreatedAt) })
+
+	var exported []taskio.Task
+	for _, task := range tasks {
+		exported = append(exported, taskToExport(task, loc))
+		for _, subtask := range task.Subtasks {
+			child := taskio.Task{
+				ID:          subtask.ID,
+				ParentID:    task.ID,
+				Title:       subtask.Title,
+				Priority:    taskio.PriorityNormal,
+				Project:     task.Project,
+				Completed:   subtask.Completed,
+				CompletedAt: subtask.CompletedAt,
+				CreatedAt:   subtask.CreatedAt,
+			}
+			exported = append(exported, child)
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case TaskFormatTodoist:
+		err = taskio.EncodeTodoist(&buf, exported)
+	case TaskFormatCSV:
+		err = taskio.EncodeCSV(&buf, exported)
+	default:
+		return nil, fmt.Errorf("unknown task format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to export tasks: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ImportTasks adds the tasks in a Todoist export or CSV file to the user's
+// task list; tasks imported before, or exported from here, are updated in
+// place, and tasks with a parent become its subtasks
+func (a *TaskManagerAgent) ImportTasks(ctx context.Context, format string, data []byte) (*TaskImportResult, error) {
+	loc := userLocation(ctx, a.memoryStore)
+	var sources []taskio.Task
+	var err error
+	switch format {
+	case TaskFormatTodoist:
+		sources, err = taskio.DecodeTodoist(bytes.NewReader(data), loc)
+	case TaskFormatCSV:
+		sources, err = taskio.DecodeCSV(bytes.NewReader(data), loc)
+	default:
+		return nil, fmt.Errorf("unknown task format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to import tasks: %w", err)
+	}
+
+	a.loadTasksFromMemory(ctx)
+	now := a.now()
+	result := &TaskImportResult{}
+
+	a.taskMutex.Lock()
+	existing := make(map[string]*PersonalTask)
+	for _, task := range a.tasks {
+		if ownedBy(ctx, task.UserID) && task.DeletedAt == nil {
+			existing[task.ID] = task
+			if id, ok := task.Metadata[taskImportIDKey].(string); ok && id != "" {
+				existing[id] = task
+			}
+		}
+	}
+
+	// A subtask whose parent is neither in the file nor here becomes a
+	// task of its own
+	inFile := make(map[string]bool)
+	for _, source := range sources {
+		inFile[source.ID] = true
+	}
+	var topLevel, children []taskio.Task
+	for _, source := range sources {
+		if source.ParentID != "" && (inFile[source.ParentID] || existing[source.ParentID] != nil) {
+			children = append(children, source)
+		} else {
+			topLevel = append(topLevel, source)
+		}
+	}
+
+	var changed []*PersonalTask
+	created := make(map[string]bool)
+	for _, source := range topLevel {
+		task, ok := existing[source.ID]
+		if ok {
+			result.Updated++
+		} else {
+			task = &PersonalTask{
+				ID:           a.newID("task"),
+				Status:       PersonalTaskStatusNext,
+				CreatedAt:    now,
+				Energy:       EnergyLevelMedium,
+				Subtasks:     []Subtask{},
+				Dependencies: []string{},
+				Reminders:    []string{},
+				Notes:        []TaskNote{},
+				Attachments:  []string{},
+				TimeSpent:    []TimeEntry{},
+				Metadata:     map[string]interface{}{taskImportIDKey: source.ID},
+				UserID:       multiagent.UserIDFromContext(ctx),
+			}
+			if !source.CreatedAt.IsZero() {
+				task.CreatedAt = source.CreatedAt
+			}
+			a.tasks[task.ID] = task
+			existing[source.ID] = task
+			created[task.ID] = true
+			result.Imported++
+		}
+		applyImport(task, source, now)
+		changed = append(changed, task)
+	}
+
+	for _, source := range children {
+		parent, ok := existing[source.ParentID]
+		if !ok {
+			// The parent was itself a subtask in the file
+			continue
+		}
+		subtask := Subtask{ID: source.ID, Title: source.Title, CreatedAt: now}
+		if !source.CreatedAt.IsZero() {
+			subtask.CreatedAt = source.CreatedAt
+		}
+		if source.Completed {
+			completedAt := now
+			if source.CompletedAt != nil {
+				completedAt = *source.CompletedAt
+			}
+			subtask.Completed, subtask.CompletedAt = true, &completedAt
+		}
+		replaced := false
+		for i := range parent.Subtasks {
+			if parent.Subtasks[i].ID == subtask.ID {
+				parent.Subtasks[i], replaced = subtask, true
+			}
+		}
+		if !replaced {
+			parent.Subtasks = append(parent.Subtasks, subtask)
+		}
+		parent.rollUpProgress()
+		parent.UpdatedAt = now
+		changed = append(changed, parent)
+		result.Subtasks++
+	}
+
+	saved := make(map[string]bool)
+	var snapshots []PersonalTask
+	for _, task := range changed {
+		if saved[task.ID] {
+			continue
+		}
+		saved[task.ID] = true
+		snapshot := *task
+		snapshot.Subtasks = append([]Subtask(nil), task.Subtasks...)
+		snapshot.TimeSpent = append([]TimeEntry(nil), task.TimeSpent...)
+		snapshots = append(snapshots, snapshot)
+	}
+	a.taskMutex.Unlock()
+
+	for i := range snapshots {
+		task := &snapshots[i]
+		if err := a.saveTask(ctx, task); err != nil {
+			return result, err
+		}
+		if !created[task.ID] {
+			continue
+		}
+		a.indexForRecall(ctx, "personal_task:"+task.ID, fmt.Sprintf("Task: %s. %s", task.Title, task.Description), map[string]interface{}{
+			"task_id": task.ID,
+		})
+		if task.isActive() && task.DueDate != nil {
+			a.createAutomaticReminder(ctx, task)
+		}
+	}
+
+	a.recordAudit(ctx, nil, audit.TasksImported, "", map[string]interface{}{
+		"format":   format,
+		"imported": result.Imported,
+		"updated":  result.Updated,
+		"subtasks": result.Subtasks,
+	})
+	return result, nil
+}
+
+// handleExportTasks replies with the user's tasks as a Todoist export, or
+// as CSV when asked for a spreadsheet or TickTick
+func (a *TaskManagerAgent) handleExportTasks(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	content := strings.ToLower(msg.Content)
+	format, app := TaskFormatTodoist, "Todoist (REST JSON)"
+	if strings.Contains(content, "csv") || strings.Contains(content, "ticktick") || strings.Contains(content, "spreadsheet") {
+		format, app = TaskFormatCSV, "CSV"
+	}
+
+	data, err := a.ExportTasks(ctx, format)
+	if err != nil {
+		return nil, err
+	}
+	return a.respond(msg, fmt.Sprintf("📤 **Task Export — %s**\n\nSave the following to import it into another to-do app:\n\n```\n%s```", app, data), map[string]interface{}{
+		"action": "tasks_exported",
+		"format": format,
+		"data":   string(data),
+	}), nil
+}
+
+// taskToExport converts a task, with due times in loc
+func taskToExport(task *PersonalTask, loc *time.Location) taskio.Task {
+	out := taskio.Task{
+		ID:          task.ID,
+		Title:       task.Title,
+		Description: task.Description,
+		Priority:    exportPriority(task.Priority),
+		Labels:      task.Tags,
+		Project:     task.Project,
+		Duration:    task.EstimatedTime,
+		Completed:   task.Status == PersonalTaskStatusCompleted,
+		CompletedAt: task.CompletedAt,
+		CreatedAt:   task.CreatedAt,
+	}
+	if task.DueDate != nil {
+		due := task.DueDate.In(loc)
+		if due.Sub(startOfDay(due)) == allDayDue {
+			due, out.AllDay = startOfDay(due), true
+		}
+		out.Due = &due
+	}
+	if pattern := task.Recurring; pattern != nil {
+		unit := map[RecurrenceType]string{
+			RecurrenceTypeDaily:   taskio.UnitDay,
+			RecurrenceTypeWeekly:  taskio.UnitWeek,
+			RecurrenceTypeMonthly: taskio.UnitMonth,
+			RecurrenceTypeYearly:  taskio.UnitYear,
+		}[pattern.Type]
+		if unit != "" {
+			out.Recurrence = &taskio.Recurrence{Interval: max(pattern.Interval, 1), Unit: unit}
+		}
+	}
+	return out
+}
+
+// applyImport copies an imported task's fields onto task
+func applyImport(task *PersonalTask, source taskio.Task, now time.Time) {
+	task.Title = source.Title
+	task.Description = source.Description
+	task.Priority = importPriority(source.Priority)
+	task.Tags = source.Labels
+	if source.Project != "" {
+		task.Project = source.Project
+	}
+	if source.Duration > 0 {
+		task.EstimatedTime = source.Duration
+	}
+
+	task.DueDate = nil
+	if source.Due != nil {
+		due := *source.Due
+		if source.AllDay {
+			due = startOfDay(due).Add(allDayDue)
+		}
+		task.DueDate = &due
+	}
+	task.Recurring = nil
+	if rule := source.Recurrence; rule != nil {
+		kind := map[string]RecurrenceType{
+			taskio.UnitDay:   RecurrenceTypeDaily,
+			taskio.UnitWeek:  RecurrenceTypeWeekly,
+			taskio.UnitMonth: RecurrenceTypeMonthly,
+			taskio.UnitYear:  RecurrenceTypeYearly,
+		}[rule.Unit]
+		task.Recurring = &RecurrencePattern{Type: kind, Interval: rule.Interval}
+	}
+
+	switch {
+	case source.Completed && task.Status != PersonalTaskStatusCompleted:
+		task.setStatus(PersonalTaskStatusCompleted, now, "imported")
+		if source.CompletedAt != nil {
+			task.CompletedAt = source.CompletedAt
+		}
+	case !source.Completed && task.Status == PersonalTaskStatusCompleted:
+		task.setStatus(PersonalTaskStatusNext, now, "imported")
+	}
+	task.UpdatedAt = now
+}
+
+// exportPriority maps a priority onto Todoist's scale
+func exportPriority(priority multiagent.Priority) taskio.Priority {
+	switch priority {
+	case multiagent.PriorityCritical:
+		return taskio.PriorityUrgent
+	case multiagent.PriorityHigh:
+		return taskio.PriorityHigh
+	case multiagent.PriorityMedium:
+		return taskio.PriorityMedium
+	default:
+		return taskio.PriorityNormal
+	}
+}
+
+// importPriority maps a priority from Todoist's scale
+func importPriority(priority taskio.Priority) multiagent.Priority {
+	switch priority {
+	case taskio.PriorityUrgent:
+		return multiagent.PriorityCritical
+	case taskio.PriorityHigh:
+		return multiagent.PriorityHigh
+	case taskio.PriorityMedium:
+		return multiagent.PriorityMedium
+	default:
+		return multiagent.PriorityLow
+	}
+}
@@ -0,0 +1,81 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+// IssueAssignedTopic carries issues and pull requests assigned to the user
+// in trackers such as GitHub, submitted to POST /events
+const IssueAssignedTopic = "external.issue_assigned"
+
+// taskExternalIDKey is the task metadata key holding what an outside event
+// was about, e.g. "github:octo/repo#12", so a repeat adds no second task
+const taskExternalIDKey = "external_id"
+
+// handleIssueAssigned adds a follow-up task for an issue assigned to the
+// user, unless one is already open
+func (a *TaskManagerAgent) handleIssueAssigned(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	title, _ := msg.Context["title"].(string)
+	if title == "" {
+		return nil, fmt.Errorf("assigned issue has no title")
+	}
+	externalID, _ := msg.Context["external_id"].(string)
+	url, _ := msg.Context["url"].(string)
+	if reference, ok := msg.Context["reference"].(string); ok && reference != "" {
+		title = reference + ": " + title
+	}
+
+	a.loadTasksFromMemory(ctx)
+	now := a.now()
+	a.taskMutex.Lock()
+	if externalID != "" {
+		for _, task := range a.tasks {
+			if ownedBy(ctx, task.UserID) && task.DeletedAt == nil && task.isActive() && task.Metadata[taskExternalIDKey] == externalID {
+				a.taskMutex.Unlock()
+				return nil, nil
+			}
+		}
+	}
+	task := &PersonalTask{
+		ID:           a.newID("task"),
+		Title:        "Follow up on " + title,
+		Description:  url,
+		Status:       PersonalTaskStatusNext,
+		Priority:     multiagent.PriorityMedium,
+		Category:     "work",
+		Tags:         []string{string(msg.From)},
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Subtasks:     []Subtask{},
+		Dependencies: []string{},
+		Reminders:    []string{},
+		Notes:        []TaskNote{},
+		Attachments:  []string{},
+		TimeSpent:    []TimeEntry{},
+		Metadata:     map[string]interface{}{taskExternalIDKey: externalID},
+		UserID:       multiagent.UserIDFromContext(ctx),
+	}
+	a.tasks[task.ID] = task
+	snapshot := *task
+	a.taskMutex.Unlock()
+
+	if err := a.saveTask(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	a.recordShared(ctx, msg, memory.BlackboardEntry{
+		Section: memory.BlackboardFacts,
+		Key:     "task:" + snapshot.ID,
+		Value:   fmt.Sprintf("Personal task '%s' was added when it was assigned in %s", snapshot.Title, msg.From),
+	})
+	a.recordAudit(ctx, msg, audit.TaskCreated, snapshot.ID, map[string]interface{}{
+		"title":       snapshot.Title,
+		"external_id": externalID,
+	})
+	a.logger.InfoContext(ctx, "Added follow-up for assigned issue", "task_id", snapshot.ID, "external_id", externalID)
+	return nil, nil
+}
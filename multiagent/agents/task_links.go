@@ -0,0 +1,261 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+// Topics linked tasks are synced on: each side publishes when the user
+// completes, reopens or deletes its half of a link
+const (
+	PersonalTaskTopic = "personal_task.updated"
+	ProjectTaskTopic  = "project_task.updated"
+)
+
+// taskLinkContextKey is the message context key a TaskLink travels in
+// between the project manager and the task manager
+const taskLinkContextKey = "task_link"
+
+// Task link actions. The project manager asks the task manager to create a
+// personal task for a project task the user takes on; afterwards either
+// side reports status changes and unlinks.
+const (
+	TaskLinkCreate = "create"
+	TaskLinkStatus = "status"
+	TaskLinkUnlink = "unlink"
+)
+
+// TaskLink ties a project task to the personal task the user works on it
+// through
+type TaskLink struct {
+	Action         string              `json:"action"`
+	ProjectID      string              `json:"project_id"`
+	ProjectTaskID  string              `json:"project_task_id"`
+	PersonalTaskID string              `json:"personal_task_id,omitempty"`
+	Project        string              `json:"project,omitempty"` // The project's name
+	Title          string              `json:"title,omitempty"`
+	Description    string              `json:"description,omitempty"`
+	Priority       multiagent.Priority `json:"priority,omitempty"`
+	DueDate        *time.Time          `json:"due_date,omitempty"`
+	EstimatedHours float64             `json:"estimated_hours,omitempty"`
+	Completed      bool                `json:"completed"`
+}
+
+// ProjectTaskLink names the project task a personal task was created for
+type ProjectTaskLink struct {
+	ProjectID string `json:"project_id"`
+	TaskID    string `json:"task_id"`
+}
+
+// decodeTaskLink reads the TaskLink a message carries, if any
+func decodeTaskLink(msg *multiagent.Message) (*TaskLink, bool) {
+	value, ok := msg.Context[taskLinkContextKey]
+	if !ok {
+		return nil, false
+	}
+	if link, ok := value.(TaskLink); ok {
+		return &link, true
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var link TaskLink
+	if err := json.Unmarshal(data, &link); err != nil || link.Action == "" {
+		return nil, false
+	}
+	return &link, true
+}
+
+// taskLinkMessage builds a message carrying link for the user ctx acts for
+func taskLinkMessage(ctx context.Context, link TaskLink) *multiagent.Message {
+	return &multiagent.Message{
+		Type:    multiagent.MessageTypeNotification,
+		Content: fmt.Sprintf("%s the link of project task %s", link.Action, link.ProjectTaskID),
+		Context: map[string]interface{}{
+			taskLinkContextKey:       link,
+			multiagent.ContextUserID: multiagent.UserIDFromContext(ctx),
+		},
+	}
+}
+
+// publishTaskLink tells the other side of a link about a change to this one
+func (a *BaseAgent) publishTaskLink(ctx context.Context, topic string, link TaskLink) {
+	if a.orchestrator == nil {
+		return
+	}
+	msg := taskLinkMessage(ctx, link)
+	msg.ID = a.messageID()
+	if _, err := a.Publish(ctx, topic, msg); err != nil {
+		a.logger.WarnContext(ctx, "Failed to publish task link", "topic", topic, "project_task_id", link.ProjectTaskID, "error", err)
+	}
+}
+
+// Start starts the agent and subscribes it to changes of the project tasks
+// its tasks are linked to, and to issues assigned to the user
+func (a *TaskManagerAgent) Start(ctx context.Context) error {
+	if err := a.BaseAgent.Start(ctx); err != nil {
+		return err
+	}
+	if a.orchestrator == nil {
+		return nil
+	}
+	for _, topic := range []string{ProjectTaskTopic, IssueAssignedTopic} {
+		if err := a.Subscribe(topic); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+// publishLinkedStatus tells the project manager the user completed or
+// reopened a personal task linked to a project task
+func (a *TaskManagerAgent) publishLinkedStatus(ctx context.Context, wasCompleted bool, task *PersonalTask) {
+	completed := task.Status == PersonalTaskStatusCompleted
+	if task.ProjectLink == nil || completed == wasCompleted {
+		return
+	}
+	a.publishTaskLink(ctx, PersonalTaskTopic, TaskLink{
+		Action:         TaskLinkStatus,
+		ProjectID:      task.ProjectLink.ProjectID,
+		ProjectTaskID:  task.ProjectLink.TaskID,
+		PersonalTaskID: task.ID,
+		Title:          task.Title,
+		Completed:      completed,
+	})
+}
+
+// handleTaskLink creates the personal task for a project task the user
+// takes on, or applies a change the project manager made to one. Only
+// creation, which the project manager waits on, is answered.
+func (a *TaskManagerAgent) handleTaskLink(ctx context.Context, msg *multiagent.Message, link *TaskLink) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+	if link.Action == TaskLinkCreate {
+		return a.createLinkedTask(ctx, msg, link)
+	}
+
+	a.taskMutex.Lock()
+	task, exists := a.tasks[link.PersonalTaskID]
+	if !exists || !ownedBy(ctx, task.UserID) || task.ProjectLink == nil || task.ProjectLink.TaskID != link.ProjectTaskID {
+		a.taskMutex.Unlock()
+		return nil, nil
+	}
+	now := a.now()
+	wasCompleted := task.Status == PersonalTaskStatusCompleted
+	var blockChanges []TimeBlockRequest
+	switch link.Action {
+	case TaskLinkStatus:
+		switch {
+		case link.Completed && task.Status != PersonalTaskStatusCompleted:
+			task.setStatus(PersonalTaskStatusCompleted, now, "completed in project "+link.Project)
+			blockChanges = task.releaseCalendarBlocks(now)
+		case !link.Completed && task.Status == PersonalTaskStatusCompleted:
+			task.setStatus(PersonalTaskStatusNext, now, "reopened in project "+link.Project)
+		default:
+			a.taskMutex.Unlock()
+			return nil, nil
+		}
+	case TaskLinkUnlink:
+		task.ProjectLink = nil
+	default:
+		a.taskMutex.Unlock()
+		return nil, fmt.Errorf("unknown task link action %q", link.Action)
+	}
+	task.UpdatedAt = now
+	snapshot := *task
+	snapshot.TimeSpent = append([]TimeEntry(nil), task.TimeSpent...)
+	snapshot.Transitions = append([]StatusTransition(nil), task.Transitions...)
+	a.taskMutex.Unlock()
+
+	if err := a.saveTask(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	if !snapshot.isActive() {
+		a.cancelDueReminder(ctx, &snapshot)
+	}
+	a.syncTimeBlocks(ctx, blockChanges)
+	a.recordAudit(ctx, msg, audit.TaskUpdated, snapshot.ID, map[string]interface{}{
+		"title":           snapshot.Title,
+		"status":          snapshot.Status,
+		"project_task_id": link.ProjectTaskID,
+		"action":          "project_" + link.Action,
+	})
+	a.recordCompleted(ctx, msg, wasCompleted, &snapshot)
+	return nil, nil
+}
+
+// createLinkedTask adds a personal task for a project task, or returns the
+// one already linked to it
+func (a *TaskManagerAgent) createLinkedTask(ctx context.Context, msg *multiagent.Message, link *TaskLink) (*multiagent.Message, error) {
+	now := a.now()
+	a.taskMutex.Lock()
+	for _, task := range a.tasks {
+		if ownedBy(ctx, task.UserID) && task.DeletedAt == nil && task.ProjectLink != nil && task.ProjectLink.TaskID == link.ProjectTaskID {
+			taskID, title := task.ID, task.Title
+			a.taskMutex.Unlock()
+			return a.respond(msg, fmt.Sprintf("📌 '%s' is already on your task list (%s).", title, taskID), map[string]interface{}{
+				"task_id": taskID,
+				"action":  "task_linked",
+			}), nil
+		}
+	}
+	task := &PersonalTask{
+		ID:            a.newID("task"),
+		Title:         link.Title,
+		Description:   link.Description,
+		Status:        PersonalTaskStatusNext,
+		Priority:      link.Priority,
+		Category:      "work",
+		Project:       link.Project,
+		Tags:          []string{},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		DueDate:       link.DueDate,
+		EstimatedTime: time.Duration(link.EstimatedHours * float64(time.Hour)),
+		Subtasks:      []Subtask{},
+		Dependencies:  []string{},
+		Reminders:     []string{},
+		Notes:         []TaskNote{},
+		Attachments:   []string{},
+		TimeSpent:     []TimeEntry{},
+		Metadata:      make(map[string]interface{}),
+		UserID:        multiagent.UserIDFromContext(ctx),
+		ProjectLink:   &ProjectTaskLink{ProjectID: link.ProjectID, TaskID: link.ProjectTaskID},
+	}
+	if link.Completed {
+		task.setStatus(PersonalTaskStatusCompleted, now, "completed in project "+link.Project)
+	}
+	a.tasks[task.ID] = task
+	snapshot := *task
+	a.taskMutex.Unlock()
+
+	if err := a.saveTask(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.DueDate != nil && snapshot.isActive() {
+		a.createAutomaticReminder(ctx, &snapshot)
+	}
+
+	a.recordShared(ctx, msg, memory.BlackboardEntry{
+		Section: memory.BlackboardFacts,
+		Key:     "task:" + snapshot.ID,
+		Value:   fmt.Sprintf("Personal task '%s' was added for project %s", snapshot.Title, link.Project),
+	})
+	a.recordAudit(ctx, msg, audit.TaskCreated, snapshot.ID, map[string]interface{}{
+		"title":           snapshot.Title,
+		"due_date":        snapshot.DueDate,
+		"project_id":      link.ProjectID,
+		"project_task_id": link.ProjectTaskID,
+	})
+
+	return a.respond(msg, fmt.Sprintf("✅ Added '%s' from project %s to your task list (%s).", snapshot.Title, link.Project, snapshot.ID), map[string]interface{}{
+		"task_id": snapshot.ID,
+		"action":  "task_linked",
+	}), nil
+}
@@ -4,13 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+	"github.com/kbutz/wikillm/multiagent/timeparse"
 )
 
 // TaskManagerAgent specializes in personal task management, reminders, and productivity
@@ -19,6 +22,7 @@ type TaskManagerAgent struct {
 	tasks      map[string]*PersonalTask
 	reminders  map[string]*Reminder
 	taskMutex  sync.RWMutex
+	intents    *IntentRouter
 }
 
 // PersonalTask represents a personal task with detailed tracking
@@ -29,6 +33,7 @@ type PersonalTask struct {
 	Status          PersonalTaskStatus          `json:"status"`
 	Priority        multiagent.Priority         `json:"priority"`
 	Category        string                      `json:"category"`
+	Project         string                      `json:"project,omitempty"`
 	Tags            []string                    `json:"tags"`
 	CreatedAt       time.Time                   `json:"created_at"`
 	UpdatedAt       time.Time                   `json:"updated_at"`
@@ -49,6 +54,12 @@ type PersonalTask struct {
 	LastWorkedOn    *time.Time                  `json:"last_worked_on,omitempty"`
 	TimeSpent       []TimeEntry                 `json:"time_spent"`
 	Metadata        map[string]interface{}      `json:"metadata"`
+	UserID          string                      `json:"user_id,omitempty"`
+	WaitingOn       string                      `json:"waiting_on,omitempty"`  // Who or what a waiting task waits for
+	Transitions     []StatusTransition          `json:"transitions,omitempty"` // Status changes, oldest first
+	DeletedAt       *time.Time                  `json:"deleted_at,omitempty"` // Set while the task is in the trash
+	CalendarBlocks  []CalendarBlock             `json:"calendar_blocks,omitempty"` // Calendar time reserved to work on the task
+	ProjectLink     *ProjectTaskLink            `json:"project_link,omitempty"` // The project task this task was taken on for
 }
 
 // PersonalTaskStatus represents the status of a personal task
@@ -120,6 +131,14 @@ type TimeEntry struct {
 	Note      string        `json:"note"`
 }
 
+// StatusTransition records a task moving between statuses
+type StatusTransition struct {
+	From PersonalTaskStatus `json:"from"`
+	To   PersonalTaskStatus `json:"to"`
+	At   time.Time          `json:"at"`
+	Note string             `json:"note,omitempty"`
+}
+
 // Reminder represents a reminder for tasks or events
 type Reminder struct {
 	ID         string          `json:"id"`
@@ -133,7 +152,9 @@ type Reminder struct {
 	Recurring  bool            `json:"recurring"`
 	Snoozed    bool            `json:"snoozed"`
 	SnoozedUntil *time.Time    `json:"snoozed_until,omitempty"`
+	LastTriggered *time.Time   `json:"last_triggered,omitempty"`
 	Context    map[string]interface{} `json:"context"`
+	UserID     string          `json:"user_id,omitempty"`
 }
 
 // ReminderStatus represents the status of a reminder
@@ -162,6 +183,7 @@ const (
 func NewTaskManagerAgent(config BaseAgentConfig) *TaskManagerAgent {
 	// Ensure the agent type is correct
 	config.Type = multiagent.AgentTypeTask
+	ensureReminderEngine(&config)
 
 	// Add task management capabilities
 	config.Capabilities = append(config.Capabilities,
@@ -181,10 +203,42 @@ func NewTaskManagerAgent(config BaseAgentConfig) *TaskManagerAgent {
 		BaseAgent: NewBaseAgent(config),
 		tasks:     make(map[string]*PersonalTask),
 		reminders: make(map[string]*Reminder),
+		intents: NewIntentRouter(IntentRouterConfig{
+			Name:        "TaskManagerAgent",
+			LLMProvider: routingProvider(config),
+			Prompts:     config.Prompts,
+			Default:     "general",
+			Intents: []Intent{
+				{Label: "start_timer", Description: "start tracking time on a task", Keywords: []string{"start working", "start timer", "start tracking", "begin working"}},
+				{Label: "stop_timer", Description: "stop the running time tracking timer", Keywords: []string{"stop working", "stop timer", "stop tracking"}},
+				{Label: "time_report", Description: "report where the user's time went this or last week", Keywords: []string{"time report", "time spent", "timesheet", "how much time"}},
+				{Label: "export_tasks", Description: "export the user's tasks for Todoist, TickTick or a spreadsheet", Keywords: []string{"export&task", "todoist", "ticktick"}},
+				{Label: "schedule_review", Description: "schedule or cancel the recurring weekly review", Keywords: []string{"weekly review&every", "schedule&weekly review", "cancel&weekly review", "stop&weekly review"}},
+				{Label: "weekly_review", Description: "a GTD weekly review of the user's tasks", Keywords: []string{"weekly review", "review my week"}},
+				{Label: "subtask", Description: "add, check off, reopen, rename, remove or list a task's subtasks", Keywords: []string{"subtask", "sub-task"}},
+				{Label: "set_dependency", Description: "make a task wait for another task, or stop it waiting", Keywords: []string{"depends on", "depend on", "blocked by", "waits for"}},
+				{Label: "move_task", Description: "move a task to another board column or status", Keywords: []string{"move&to"}},
+				{Label: "block_time", Description: "reserve time on the calendar to work on a task", Keywords: []string{"block&hour", "block&minute", "time block", "timebox", "block out time", "block time for"}},
+				{Label: "add_task", Description: "create a new personal task or to-do", Keywords: []string{"add task", "create task", "new task"}},
+				{Label: "list_tasks", Description: "show the user's tasks", Keywords: []string{"list tasks", "show tasks", "my tasks", "board", "kanban"}},
+				{Label: "complete_task", Description: "mark a task as done", Keywords: []string{"complete task", "finish task", "done"}},
+				{Label: "snooze_reminder", Description: "postpone a reminder that just went off", Keywords: []string{"snooze"}},
+				{Label: "create_reminder", Description: "set a reminder", Keywords: []string{"remind me", "reminder"}},
+				{Label: "update_task", Description: "change an existing task", Keywords: []string{"update task", "modify task"}},
+				{Label: "restore_task", Description: "undo deleting a task", Keywords: []string{"undo", "restore task", "undelete"}},
+				{Label: "delete_task", Description: "remove a task", Keywords: []string{"delete task", "remove task"}},
+				{Label: "prioritize", Description: "prioritize or re-rank tasks", Keywords: []string{"prioritize", "priority"}},
+				{Label: "today", Description: "tasks due today", Keywords: []string{"today"}},
+				{Label: "overdue", Description: "overdue tasks", Keywords: []string{"overdue"}},
+				{Label: "next_actions", Description: "what to work on next", Keywords: []string{"next actions", "next tasks"}},
+				{Label: "productivity_stats", Description: "productivity statistics", Keywords: []string{"productivity", "statistics", "stats", "analytics"}},
+			},
+		}),
 	}
 
-	// Start reminder checking routine
-	go agent.reminderChecker(context.Background())
+	// Task reminders fire through the shared reminder engine
+	agent.reminderEngine.Register(taskReminderSource, agent.fireTaskReminder)
+	agent.reminderEngine.Register(weeklyReviewSource, agent.fireWeeklyReview)
 
 	return agent
 }
@@ -204,38 +258,83 @@ func (a *TaskManagerAgent) HandleMessage(ctx context.Context, msg *multiagent.Me
 		a.mu.Unlock()
 	}()
 
+	// Replies to our own requests, such as time blocks, complete their
+	// futures
+	if a.resolveReply(msg) {
+		return nil, nil
+	}
+
 	// Store message in memory
 	if a.memoryStore != nil {
 		msgKey := fmt.Sprintf("task_manager:%s:%s", a.id, msg.ID)
 		a.memoryStore.Store(ctx, msgKey, msg)
 	}
 
-	content := strings.ToLower(msg.Content)
+	// The scheduler reports time blocks the user changed on the calendar
+	if change, ok := decodeTimeBlock(msg); ok {
+		return a.handleTimeBlockChange(ctx, msg, change)
+	}
+
+	// The project manager links project tasks the user takes on
+	if link, ok := decodeTaskLink(msg); ok {
+		return a.handleTaskLink(ctx, msg, link)
+	}
+
+	// Issues assigned to the user in trackers such as GitHub become
+	// follow-up tasks
+	if msg.Context["topic"] == IssueAssignedTopic {
+		return a.handleIssueAssigned(ctx, msg)
+	}
 
-	// Route to appropriate handler based on content
-	if strings.Contains(content, "add task") || strings.Contains(content, "create task") || strings.Contains(content, "new task") {
+	// Route to appropriate handler based on intent
+	switch a.intents.Classify(ctx, msg.Content).Label {
+	case "start_timer":
+		return a.handleStartTimer(ctx, msg)
+	case "stop_timer":
+		return a.handleStopTimer(ctx, msg)
+	case "time_report":
+		return a.handleTimeReport(ctx, msg)
+	case "export_tasks":
+		return a.handleExportTasks(ctx, msg)
+	case "schedule_review":
+		return a.handleScheduleReview(ctx, msg)
+	case "weekly_review":
+		return a.handleWeeklyReview(ctx, msg)
+	case "subtask":
+		return a.handleSubtask(ctx, msg)
+	case "set_dependency":
+		return a.handleSetDependency(ctx, msg)
+	case "move_task":
+		return a.handleMoveTask(ctx, msg)
+	case "block_time":
+		return a.handleBlockTime(ctx, msg)
+	case "add_task":
 		return a.handleAddTask(ctx, msg)
-	} else if strings.Contains(content, "list tasks") || strings.Contains(content, "show tasks") || strings.Contains(content, "my tasks") {
+	case "list_tasks":
 		return a.handleListTasks(ctx, msg)
-	} else if strings.Contains(content, "complete task") || strings.Contains(content, "finish task") || strings.Contains(content, "done") {
+	case "complete_task":
 		return a.handleCompleteTask(ctx, msg)
-	} else if strings.Contains(content, "remind me") || strings.Contains(content, "reminder") || strings.Contains(content, "set reminder") {
+	case "create_reminder":
 		return a.handleCreateReminder(ctx, msg)
-	} else if strings.Contains(content, "update task") || strings.Contains(content, "modify task") {
+	case "snooze_reminder":
+		return a.handleSnoozeReminder(ctx, msg)
+	case "update_task":
 		return a.handleUpdateTask(ctx, msg)
-	} else if strings.Contains(content, "delete task") || strings.Contains(content, "remove task") {
+	case "restore_task":
+		return a.handleRestoreTask(ctx, msg)
+	case "delete_task":
 		return a.handleDeleteTask(ctx, msg)
-	} else if strings.Contains(content, "prioritize") || strings.Contains(content, "priority") {
+	case "prioritize":
 		return a.handlePrioritize(ctx, msg)
-	} else if strings.Contains(content, "today") || strings.Contains(content, "due today") {
+	case "today":
 		return a.handleTodayTasks(ctx, msg)
-	} else if strings.Contains(content, "overdue") {
+	case "overdue":
 		return a.handleOverdueTasks(ctx, msg)
-	} else if strings.Contains(content, "next actions") || strings.Contains(content, "next tasks") {
+	case "next_actions":
 		return a.handleNextActions(ctx, msg)
-	} else if strings.Contains(content, "productivity") || strings.Contains(content, "statistics") || strings.Contains(content, "stats") {
+	case "productivity_stats":
 		return a.handleProductivityStats(ctx, msg)
-	} else {
+	default:
 		// Use LLM for general task management queries
 		return a.handleGeneralQuery(ctx, msg)
 	}
@@ -244,28 +343,9 @@ func (a *TaskManagerAgent) HandleMessage(ctx context.Context, msg *multiagent.Me
 // handleAddTask creates a new personal task
 func (a *TaskManagerAgent) handleAddTask(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Use LLM to extract task details
-	contextPrompt := fmt.Sprintf(`
-Extract task information from this request: "%s"
-
-Provide response in JSON format:
-{
-  "title": "task title",
-  "description": "detailed description",
-  "priority": "low|medium|high|critical",
-  "category": "work|personal|health|learning|etc",
-  "due_date": "YYYY-MM-DD HH:MM if mentioned, otherwise null",
-  "estimated_time": "duration in minutes if mentioned, otherwise 0",
-  "energy_level": "low|medium|high",
-  "context": "location or context if mentioned",
-  "tags": ["tag1", "tag2"] if any mentioned,
-  "recurring": "daily|weekly|monthly|yearly if recurring, otherwise null"
-}
-
-Make reasonable assumptions for missing information.`, msg.Content)
-
-	response, err := a.llmProvider.Query(ctx, contextPrompt)
+	contextPrompt, err := a.renderPrompt(ctx, "task.create", prompts.Vars{"Request": msg.Content})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse task details: %w", err)
+		return nil, err
 	}
 
 	var taskData struct {
@@ -273,6 +353,7 @@ Make reasonable assumptions for missing information.`, msg.Content)
 		Description   string   `json:"description"`
 		Priority      string   `json:"priority"`
 		Category      string   `json:"category"`
+		Project       string   `json:"project"`
 		DueDate       string   `json:"due_date"`
 		EstimatedTime int      `json:"estimated_time"`
 		EnergyLevel   string   `json:"energy_level"`
@@ -281,7 +362,14 @@ Make reasonable assumptions for missing information.`, msg.Content)
 		Recurring     string   `json:"recurring"`
 	}
 
-	if err := json.Unmarshal([]byte(response), &taskData); err != nil {
+	taskSchema := objectSchema(map[string]string{
+		"title":          "string",
+		"due_date":       "string",
+		"estimated_time": "integer",
+		"tags":           "array",
+	}, "title")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+contextPrompt, taskSchema, &taskData); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse task details", "error", err)
 		// Fallback to basic task creation
 		taskData.Title = msg.Content
 		taskData.Priority = "medium"
@@ -291,15 +379,16 @@ Make reasonable assumptions for missing information.`, msg.Content)
 
 	// Create task
 	task := &PersonalTask{
-		ID:             fmt.Sprintf("task_%d", time.Now().UnixNano()),
+		ID:             a.newID("task"),
 		Title:          taskData.Title,
 		Description:    taskData.Description,
 		Status:         PersonalTaskStatusInbox,
 		Priority:       a.parsePriority(taskData.Priority),
 		Category:       taskData.Category,
+		Project:        taskData.Project,
 		Tags:           taskData.Tags,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+		CreatedAt:      a.now(),
+		UpdatedAt:      a.now(),
 		EstimatedTime:  time.Duration(taskData.EstimatedTime) * time.Minute,
 		Energy:         a.parseEnergyLevel(taskData.EnergyLevel),
 		Context:        taskData.Context,
@@ -311,6 +400,7 @@ Make reasonable assumptions for missing information.`, msg.Content)
 		Attachments:    []string{},
 		TimeSpent:      []TimeEntry{},
 		Metadata:       make(map[string]interface{}),
+		UserID:         multiagent.UserIDFromContext(ctx),
 	}
 
 	// Set due date if provided
@@ -339,21 +429,34 @@ Make reasonable assumptions for missing information.`, msg.Content)
 	if a.memoryStore != nil {
 		taskKey := fmt.Sprintf("personal_task:%s", task.ID)
 		a.memoryStore.Store(ctx, taskKey, task)
+		a.indexForRecall(ctx, taskKey, fmt.Sprintf("Task: %s. %s", task.Title, task.Description), map[string]interface{}{
+			"task_id": task.ID,
+		})
 	}
 
+	taskFact := fmt.Sprintf("Personal task '%s' was added", task.Title)
+	if task.DueDate != nil {
+		taskFact += fmt.Sprintf(", due %s", task.DueDate.Format("2006-01-02 15:04"))
+	}
+	a.recordShared(ctx, msg, memory.BlackboardEntry{Section: memory.BlackboardFacts, Key: "task:" + task.ID, Value: taskFact})
+	a.recordAudit(ctx, msg, audit.TaskCreated, task.ID, map[string]interface{}{
+		"title":    task.Title,
+		"due_date": task.DueDate,
+	})
+
 	// Create automatic reminder if due date is set
 	if task.DueDate != nil {
 		a.createAutomaticReminder(ctx, task)
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   fmt.Sprintf("✅ Task '%s' added successfully!\n\n📋 **Details:**\n• ID: %s\n• Priority: %s\n• Category: %s\n• Status: %s\n• Energy Level: %s", task.Title, task.ID, task.Priority, task.Category, task.Status, task.Energy),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"task_id": task.ID,
 			"action":  "task_created",
@@ -367,6 +470,9 @@ func (a *TaskManagerAgent) handleListTasks(ctx context.Context, msg *multiagent.
 	a.loadTasksFromMemory(ctx)
 
 	content := strings.ToLower(msg.Content)
+	if strings.Contains(content, "board") || strings.Contains(content, "kanban") {
+		return a.handleBoard(ctx, msg)
+	}
 	var filteredTasks []*PersonalTask
 
 	a.taskMutex.RLock()
@@ -374,6 +480,9 @@ func (a *TaskManagerAgent) handleListTasks(ctx context.Context, msg *multiagent.
 
 	// Apply filters based on request
 	for _, task := range a.tasks {
+		if !ownedBy(ctx, task.UserID) || task.DeletedAt != nil {
+			continue
+		}
 		include := true
 
 		// Filter by status
@@ -408,13 +517,13 @@ func (a *TaskManagerAgent) handleListTasks(ctx context.Context, msg *multiagent.
 
 	if len(filteredTasks) == 0 {
 		return &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+			ID:        a.messageID(),
 			From:      a.id,
 			To:        []multiagent.AgentID{msg.From},
 			Type:      multiagent.MessageTypeResponse,
 			Content:   "📋 No tasks found matching your criteria. Use 'add task' to create your first task!",
 			ReplyTo:   msg.ID,
-			Timestamp: time.Now(),
+			Timestamp: a.now(),
 		}, nil
 	}
 
@@ -452,7 +561,9 @@ func (a *TaskManagerAgent) handleListTasks(ctx context.Context, msg *multiagent.
 			responseBuilder.WriteString(fmt.Sprintf("   📅 Due: %s\n", dueText))
 		}
 
-		if task.Progress > 0 {
+		if count := subtaskCount(task); count != "" {
+			responseBuilder.WriteString(fmt.Sprintf("   📊 Progress: %.0f%% (%s subtasks)\n", task.Progress, count))
+		} else if task.Progress > 0 {
 			responseBuilder.WriteString(fmt.Sprintf("   📊 Progress: %.0f%%\n", task.Progress))
 		}
 
@@ -464,13 +575,13 @@ func (a *TaskManagerAgent) handleListTasks(ctx context.Context, msg *multiagent.
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   responseBuilder.String(),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
@@ -482,55 +593,59 @@ func (a *TaskManagerAgent) handleCompleteTask(ctx context.Context, msg *multiage
 	defer a.taskMutex.Unlock()
 
 	task, exists := a.tasks[taskID]
-	if !exists {
+	if !exists || !ownedBy(ctx, task.UserID) || task.DeletedAt != nil {
 		// Try to find by title
-		task = a.findTaskByTitle(msg.Content)
+		task = a.findTaskByTitle(ctx, msg.Content)
 		if task == nil {
 			return &multiagent.Message{
-				ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+				ID:        a.messageID(),
 				From:      a.id,
 				To:        []multiagent.AgentID{msg.From},
 				Type:      multiagent.MessageTypeResponse,
 				Content:   "❌ Task not found. Please specify a valid task ID or title.",
 				ReplyTo:   msg.ID,
-				Timestamp: time.Now(),
+				Timestamp: a.now(),
 			}, nil
 		}
 	}
 
-	// Mark as completed
-	task.Status = PersonalTaskStatusCompleted
-	task.Progress = 100.0
-	now := time.Now()
-	task.CompletedAt = &now
-	task.UpdatedAt = now
+	// Mark as completed, stopping its timer
+	now := a.now()
+	wasCompleted := task.Status == PersonalTaskStatusCompleted
+	task.setStatus(PersonalTaskStatusCompleted, now, "")
+	blockChanges := task.releaseCalendarBlocks(now)
 
 	// Save to memory
 	if a.memoryStore != nil {
 		taskKey := fmt.Sprintf("personal_task:%s", task.ID)
 		a.memoryStore.Store(ctx, taskKey, task)
 	}
+	a.publishLinkedStatus(ctx, wasCompleted, task)
+	a.recordCompleted(ctx, msg, wasCompleted, task)
 
 	// Handle recurring tasks
-	if task.Recurring != nil {
-		newTask := a.createRecurringTask(task)
-		if newTask != nil {
-			a.tasks[newTask.ID] = newTask
-			if a.memoryStore != nil {
-				newTaskKey := fmt.Sprintf("personal_task:%s", newTask.ID)
-				a.memoryStore.Store(ctx, newTaskKey, newTask)
-			}
-		}
+	a.scheduleNextOccurrence(ctx, task)
+
+	content := fmt.Sprintf("✅ Task '%s' marked as completed! 🎉\n\nCompleted at: %s", task.Title, now.Format("2006-01-02 15:04"))
+	if task.ActualTime > 0 {
+		content += fmt.Sprintf("\nTime logged: %s", formatDuration(task.ActualTime))
+	}
+	if note := a.unblockedNote(ctx, task.ID); note != "" {
+		content += "\n\n" + note
+	}
+	if note := timeBlockNote(blockChanges); note != "" {
+		a.syncTimeBlocks(ctx, blockChanges)
+		content += "\n" + note
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
-		Content:   fmt.Sprintf("✅ Task '%s' marked as completed! 🎉\n\nCompleted at: %s", task.Title, now.Format("2006-01-02 15:04")),
+		Content:   content,
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"task_id": task.ID,
 			"action":  "task_completed",
@@ -540,22 +655,17 @@ func (a *TaskManagerAgent) handleCompleteTask(ctx context.Context, msg *multiage
 
 // handleCreateReminder creates a new reminder
 func (a *TaskManagerAgent) handleCreateReminder(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	// Use LLM to extract reminder details
-	contextPrompt := fmt.Sprintf(`
-Extract reminder information from: "%s"
-
-Provide response in JSON format:
-{
-  "title": "reminder title",
-  "message": "reminder message",
-  "trigger_time": "YYYY-MM-DD HH:MM when to trigger",
-  "type": "task|deadline|appointment|follow_up|general",
-  "recurring": true/false
-}`, msg.Content)
+	// Trigger times are given and shown in the user's timezone
+	loc := userLocation(ctx, a.memoryStore)
 
-	response, err := a.llmProvider.Query(ctx, contextPrompt)
+	// Use LLM to extract reminder details
+	contextPrompt, err := a.renderPrompt(ctx, "task.reminder", prompts.Vars{
+		"Request":  msg.Content,
+		"Now":      a.now().In(loc).Format("2006-01-02 15:04 (Monday)"),
+		"Timezone": loc,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse reminder details: %w", err)
+		return nil, err
 	}
 
 	var reminderData struct {
@@ -566,79 +676,54 @@ Provide response in JSON format:
 		Recurring   bool   `json:"recurring"`
 	}
 
-	// Try to parse the JSON response
-	if err := json.Unmarshal([]byte(response), &reminderData); err != nil {
-		log.Printf("TaskManagerAgent: Warning: Failed to parse reminder JSON: %v", err)
-		log.Printf("TaskManagerAgent: LLM response: %s", response)
-
-		// Check if response contains both JSON-like content and non-JSON content
-		if strings.Contains(response, "{") && strings.Contains(response, "}") {
-			// Try to extract JSON from the response
-			startIdx := strings.Index(response, "{")
-			endIdx := strings.LastIndex(response, "}") + 1
-
-			if startIdx >= 0 && endIdx > startIdx {
-				jsonStr := response[startIdx:endIdx]
-				// Try to parse the extracted JSON
-				if err := json.Unmarshal([]byte(jsonStr), &reminderData); err == nil {
-					// Successfully extracted and parsed JSON
-					log.Printf("TaskManagerAgent: Successfully extracted JSON from LLM response")
-				} else {
-					log.Printf("TaskManagerAgent: Failed to parse extracted JSON: %v", err)
-					// Fall back to the lenient approach
-					return a.handleCreateReminderFallback(ctx, msg, response)
-				}
-			} else {
-				// Fall back to the lenient approach
-				return a.handleCreateReminderFallback(ctx, msg, response)
-			}
-		} else if strings.Contains(response, "<") {
-			// Response contains HTML/XML tags but no valid JSON
-			// Try to extract information using a more lenient approach
-			return a.handleCreateReminderFallback(ctx, msg, response)
-		} else {
-			return nil, fmt.Errorf("failed to parse reminder JSON: %w", err)
-		}
+	reminderSchema := objectSchema(map[string]string{
+		"title":        "string",
+		"message":      "string",
+		"trigger_time": "string",
+		"type":         "string",
+		"recurring":    "boolean",
+	}, "title", "trigger_time")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+contextPrompt, reminderSchema, &reminderData); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse reminder details", "error", err)
+		return a.handleCreateReminderFallback(ctx, msg)
 	}
 
 	// Parse trigger time
-	triggerAt, err := time.Parse("2006-01-02 15:04", reminderData.TriggerTime)
+	// Parse trigger time, checked against what the user wrote
+	triggerAt, err := resolveTime(reminderData.TriggerTime, msg.Content, a.now().In(loc))
 	if err != nil {
 		return nil, fmt.Errorf("invalid trigger time format: %w", err)
 	}
 
 	// Create reminder
 	reminder := &Reminder{
-		ID:        fmt.Sprintf("reminder_%d", time.Now().UnixNano()),
+		ID:        a.newID("reminder"),
 		Title:     reminderData.Title,
 		Message:   reminderData.Message,
 		TriggerAt: triggerAt,
-		CreatedAt: time.Now(),
+		CreatedAt: a.now(),
 		Status:    ReminderStatusPending,
 		Type:      ReminderType(reminderData.Type),
 		Recurring: reminderData.Recurring,
 		Context:   make(map[string]interface{}),
+		UserID:    multiagent.UserIDFromContext(ctx),
 	}
 
-	// Store reminder
-	a.taskMutex.Lock()
-	a.reminders[reminder.ID] = reminder
-	a.taskMutex.Unlock()
-
-	// Save to memory
-	if a.memoryStore != nil {
-		reminderKey := fmt.Sprintf("reminder:%s", reminder.ID)
-		a.memoryStore.Store(ctx, reminderKey, reminder)
-	}
+	// Store and schedule reminder
+	a.addReminder(ctx, reminder)
+	a.recordAudit(ctx, msg, audit.ReminderCreated, reminder.ID, map[string]interface{}{
+		"title":      reminder.Title,
+		"trigger_at": reminder.TriggerAt,
+	})
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
-		Content:   fmt.Sprintf("⏰ Reminder '%s' set for %s\n\nI'll remind you: %s", reminder.Title, triggerAt.Format("2006-01-02 15:04"), reminder.Message),
+		Content:   fmt.Sprintf("⏰ Reminder '%s' set for %s\n\nI'll remind you: %s", reminder.Title, triggerAt.Format("2006-01-02 15:04 MST"), reminder.Message),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"reminder_id": reminder.ID,
 			"action":      "reminder_created",
@@ -707,7 +792,7 @@ func (a *TaskManagerAgent) getPriorityEmoji(priority multiagent.Priority) string
 }
 
 func (a *TaskManagerAgent) formatDueDate(dueDate time.Time) string {
-	now := time.Now()
+	now := a.now()
 	diff := dueDate.Sub(now)
 
 	if diff < 0 {
@@ -731,11 +816,11 @@ func (a *TaskManagerAgent) extractTaskID(content string) string {
 	return ""
 }
 
-func (a *TaskManagerAgent) findTaskByTitle(content string) *PersonalTask {
+func (a *TaskManagerAgent) findTaskByTitle(ctx context.Context, content string) *PersonalTask {
 	contentLower := strings.ToLower(content)
 
 	for _, task := range a.tasks {
-		if strings.Contains(contentLower, strings.ToLower(task.Title)) {
+		if ownedBy(ctx, task.UserID) && task.DeletedAt == nil && strings.Contains(contentLower, strings.ToLower(task.Title)) {
 			return task
 		}
 	}
@@ -766,12 +851,34 @@ func (a *TaskManagerAgent) loadTasksFromMemory(ctx context.Context) {
 		var task PersonalTask
 		if taskData, err := json.Marshal(taskInterface); err == nil {
 			if err := json.Unmarshal(taskData, &task); err == nil {
+				task.UserID = multiagent.UserIDFromContext(ctx)
 				a.tasks[task.ID] = &task
 			}
 		}
 	}
 }
 
+// PurgeUser forgets userID's tasks and reminders
+func (a *TaskManagerAgent) PurgeUser(userID string) int {
+	a.taskMutex.Lock()
+	defer a.taskMutex.Unlock()
+
+	purged := 0
+	for id, task := range a.tasks {
+		if task.UserID == userID {
+			delete(a.tasks, id)
+			purged++
+		}
+	}
+	for id, reminder := range a.reminders {
+		if reminder.UserID == userID {
+			delete(a.reminders, id)
+			purged++
+		}
+	}
+	return purged
+}
+
 func (a *TaskManagerAgent) createAutomaticReminder(ctx context.Context, task *PersonalTask) {
 	if task.DueDate == nil {
 		return
@@ -779,7 +886,7 @@ func (a *TaskManagerAgent) createAutomaticReminder(ctx context.Context, task *Pe
 
 	// Create reminder 1 day before due date
 	reminderTime := task.DueDate.Add(-24 * time.Hour)
-	if reminderTime.Before(time.Now()) {
+	if reminderTime.Before(a.now()) {
 		return // Don't create past reminders
 	}
 
@@ -788,20 +895,30 @@ func (a *TaskManagerAgent) createAutomaticReminder(ctx context.Context, task *Pe
 		Title:     fmt.Sprintf("Task Due Tomorrow: %s", task.Title),
 		Message:   fmt.Sprintf("Task '%s' is due tomorrow at %s", task.Title, task.DueDate.Format("15:04")),
 		TriggerAt: reminderTime,
-		CreatedAt: time.Now(),
+		CreatedAt: a.now(),
 		Status:    ReminderStatusPending,
 		Type:      ReminderTypeDeadline,
 		TaskID:    task.ID,
 		Context:   make(map[string]interface{}),
+		UserID:    task.UserID,
 	}
 
-	a.taskMutex.Lock()
-	a.reminders[reminder.ID] = reminder
-	a.taskMutex.Unlock()
+	a.addReminder(ctx, reminder)
+}
 
-	if a.memoryStore != nil {
-		reminderKey := fmt.Sprintf("reminder:%s", reminder.ID)
-		a.memoryStore.Store(ctx, reminderKey, reminder)
+// scheduleNextOccurrence adds the next occurrence of a recurring task that
+// was just completed; callers hold taskMutex
+func (a *TaskManagerAgent) scheduleNextOccurrence(ctx context.Context, task *PersonalTask) {
+	if task.Recurring == nil {
+		return
+	}
+	newTask := a.createRecurringTask(task)
+	if newTask != nil {
+		a.tasks[newTask.ID] = newTask
+		if a.memoryStore != nil {
+			newTaskKey := fmt.Sprintf("personal_task:%s", newTask.ID)
+			a.memoryStore.Store(ctx, newTaskKey, newTask)
+		}
 	}
 }
 
@@ -811,15 +928,16 @@ func (a *TaskManagerAgent) createRecurringTask(originalTask *PersonalTask) *Pers
 	}
 
 	newTask := &PersonalTask{
-		ID:             fmt.Sprintf("task_%d", time.Now().UnixNano()),
+		ID:             a.newID("task"),
 		Title:          originalTask.Title,
 		Description:    originalTask.Description,
 		Status:         PersonalTaskStatusNext,
 		Priority:       originalTask.Priority,
 		Category:       originalTask.Category,
+		Project:        originalTask.Project,
 		Tags:           originalTask.Tags,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+		CreatedAt:      a.now(),
+		UpdatedAt:      a.now(),
 		EstimatedTime:  originalTask.EstimatedTime,
 		Energy:         originalTask.Energy,
 		Context:        originalTask.Context,
@@ -833,6 +951,7 @@ func (a *TaskManagerAgent) createRecurringTask(originalTask *PersonalTask) *Pers
 		Attachments:    []string{},
 		TimeSpent:      []TimeEntry{},
 		Metadata:       make(map[string]interface{}),
+		UserID:         originalTask.UserID,
 	}
 
 	// Calculate next due date
@@ -859,137 +978,12 @@ func (a *TaskManagerAgent) calculateNextDueDate(lastDue time.Time, pattern *Recu
 	}
 }
 
-func (a *TaskManagerAgent) reminderChecker(ctx context.Context) {
-	ticker := time.NewTicker(1 * time.Minute) // Check every minute
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			a.checkReminders(ctx)
-		case <-ctx.Done():
-			return
-		}
-	}
-}
-
-func (a *TaskManagerAgent) checkReminders(ctx context.Context) {
-	now := time.Now()
-
-	a.taskMutex.Lock()
-	defer a.taskMutex.Unlock()
-
-	for _, reminder := range a.reminders {
-		if reminder.Status == ReminderStatusPending && reminder.TriggerAt.Before(now) {
-			reminder.Status = ReminderStatusTriggered
-
-			// Send reminder message (in a real system, this would notify the user)
-			// For now, we'll just log it or store it as a system message
-			if a.memoryStore != nil {
-				reminderKey := fmt.Sprintf("reminder:%s", reminder.ID)
-				a.memoryStore.Store(ctx, reminderKey, reminder)
-
-				// Store triggered reminder as a system message
-				systemMsgKey := fmt.Sprintf("system_reminder:%d", time.Now().UnixNano())
-				a.memoryStore.Store(ctx, systemMsgKey, map[string]interface{}{
-					"type":       "reminder_triggered",
-					"reminder":   reminder,
-					"timestamp":  now,
-				})
-			}
-		}
-	}
-}
-
-// Additional handler methods (simplified for space)
-
-func (a *TaskManagerAgent) handleUpdateTask(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "🔄 Task update functionality is available. Please specify which task and what changes you'd like to make.",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
-func (a *TaskManagerAgent) handleDeleteTask(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "🗑️ Task deletion is available. Please specify which task you'd like to delete.",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
-func (a *TaskManagerAgent) handlePrioritize(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "🎯 Task prioritization is available. I can help you organize tasks by priority, deadline, or energy level.",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
-func (a *TaskManagerAgent) handleTodayTasks(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "📅 Today's tasks functionality is available. I can show you tasks due today and help you plan your day.",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
-func (a *TaskManagerAgent) handleOverdueTasks(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "⚠️ Overdue task tracking is available. I can help you identify and reschedule overdue tasks.",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
-func (a *TaskManagerAgent) handleNextActions(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "➡️ Next actions functionality is available. I can help you identify your next actionable tasks based on GTD methodology.",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
-func (a *TaskManagerAgent) handleProductivityStats(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
-	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
-		From:      a.id,
-		To:        []multiagent.AgentID{msg.From},
-		Type:      multiagent.MessageTypeResponse,
-		Content:   "📊 Productivity statistics are available. I can analyze your task completion rates, time tracking, and productivity patterns.",
-		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
-	}, nil
-}
-
 func (a *TaskManagerAgent) handleGeneralQuery(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
 	// Build context with task information
-	contextPrompt := a.buildTaskContext(ctx, msg)
+	contextPrompt, err := a.buildTaskContext(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
 
 	response, err := a.llmProvider.Query(ctx, contextPrompt)
 	if err != nil {
@@ -997,46 +991,37 @@ func (a *TaskManagerAgent) handleGeneralQuery(ctx context.Context, msg *multiage
 	}
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
 		Content:   response,
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 	}, nil
 }
 
-func (a *TaskManagerAgent) buildTaskContext(ctx context.Context, msg *multiagent.Message) string {
-	var contextBuilder strings.Builder
-
-	contextBuilder.WriteString(fmt.Sprintf("You are %s, a personal task management specialist.\n\n", a.name))
-	contextBuilder.WriteString("You help users manage their personal tasks, reminders, and productivity using GTD (Getting Things Done) methodology.\n\n")
-
+func (a *TaskManagerAgent) buildTaskContext(ctx context.Context, msg *multiagent.Message) (string, error) {
 	// Add current task summary
 	a.taskMutex.RLock()
-	if len(a.tasks) > 0 {
-		contextBuilder.WriteString("Current Task Summary:\n")
-		statusCounts := make(map[PersonalTaskStatus]int)
-		for _, task := range a.tasks {
+	statusCounts := make(map[PersonalTaskStatus]int)
+	for _, task := range a.tasks {
+		if ownedBy(ctx, task.UserID) && task.DeletedAt == nil {
 			statusCounts[task.Status]++
 		}
-		for status, count := range statusCounts {
-			contextBuilder.WriteString(fmt.Sprintf("- %s: %d tasks\n", status, count))
-		}
-		contextBuilder.WriteString("\n")
 	}
 	a.taskMutex.RUnlock()
 
-	contextBuilder.WriteString(fmt.Sprintf("User request: %s\n\n", msg.Content))
-	contextBuilder.WriteString("Please provide helpful task management advice, suggestions, or execute the requested action.")
-
-	return contextBuilder.String()
+	return a.renderPrompt(ctx, "task.general", prompts.Vars{
+		"Name":         a.name,
+		"StatusCounts": statusCounts,
+		"Request":      msg.Content,
+	})
 }
 
 // handleCreateReminderFallback is a fallback method when JSON parsing fails
-func (a *TaskManagerAgent) handleCreateReminderFallback(ctx context.Context, msg *multiagent.Message, llmResponse string) (*multiagent.Message, error) {
-	log.Printf("TaskManagerAgent: Using fallback reminder creation method")
+func (a *TaskManagerAgent) handleCreateReminderFallback(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.logger.DebugContext(ctx, "Using fallback reminder creation method")
 
 	// Extract information directly from the original message
 	content := msg.Content
@@ -1051,71 +1036,42 @@ func (a *TaskManagerAgent) handleCreateReminderFallback(ctx context.Context, msg
 		}
 	}
 
-	// Try to extract time
-	triggerAt := time.Now().Add(24 * time.Hour) // Default to tomorrow
-	timeKeywords := []string{"at", "on", "tomorrow", "today"}
-
-	for _, keyword := range timeKeywords {
-		if strings.Contains(content, keyword) {
-			parts := strings.Split(content, keyword)
-			if len(parts) > 1 {
-				timeStr := strings.Split(parts[1], "?")[0]
-				timeStr = strings.Split(timeStr, ".")[0]
-				timeStr = strings.TrimSpace(timeStr)
-
-				// Try to parse common time formats
-				if strings.Contains(timeStr, "AM") || strings.Contains(timeStr, "PM") {
-					if t, err := time.Parse("3:04 PM", timeStr); err == nil {
-						now := time.Now()
-						triggerAt = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
-					}
-				} else if strings.Contains(timeStr, ":") {
-					if t, err := time.Parse("15:04", timeStr); err == nil {
-						now := time.Now()
-						triggerAt = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
-					}
-				}
-
-				// Check for tomorrow
-				if strings.Contains(content, "tomorrow") {
-					triggerAt = triggerAt.Add(24 * time.Hour)
-				}
-			}
-		}
+	// Try to extract time, in the user's timezone
+	loc := userLocation(ctx, a.memoryStore)
+	triggerAt := a.now().Add(24 * time.Hour) // Default to tomorrow
+	if found, ok := timeparse.Extract(content, a.now().In(loc)); ok {
+		triggerAt = withDefaultHour(found)
 	}
 
 	// Create reminder
 	reminder := &Reminder{
-		ID:        fmt.Sprintf("reminder_%d", time.Now().UnixNano()),
+		ID:        a.newID("reminder"),
 		Title:     title,
 		Message:   content,
 		TriggerAt: triggerAt,
-		CreatedAt: time.Now(),
+		CreatedAt: a.now(),
 		Status:    ReminderStatusPending,
 		Type:      ReminderTypeGeneral,
 		Recurring: false,
 		Context:   make(map[string]interface{}),
+		UserID:    multiagent.UserIDFromContext(ctx),
 	}
 
-	// Store reminder
-	a.taskMutex.Lock()
-	a.reminders[reminder.ID] = reminder
-	a.taskMutex.Unlock()
-
-	// Save to memory
-	if a.memoryStore != nil {
-		reminderKey := fmt.Sprintf("reminder:%s", reminder.ID)
-		a.memoryStore.Store(ctx, reminderKey, reminder)
-	}
+	// Store and schedule reminder
+	a.addReminder(ctx, reminder)
+	a.recordAudit(ctx, msg, audit.ReminderCreated, reminder.ID, map[string]interface{}{
+		"title":      reminder.Title,
+		"trigger_at": reminder.TriggerAt,
+	})
 
 	return &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%s_%d", a.id, time.Now().UnixNano()),
+		ID:        a.messageID(),
 		From:      a.id,
 		To:        []multiagent.AgentID{msg.From},
 		Type:      multiagent.MessageTypeResponse,
-		Content:   fmt.Sprintf("⏰ Reminder '%s' set for %s\n\nI'll remind you about this. Note: I had to use a simplified approach to create this reminder.", reminder.Title, triggerAt.Format("2006-01-02 15:04")),
+		Content:   fmt.Sprintf("⏰ Reminder '%s' set for %s\n\nI'll remind you about this. Note: I had to use a simplified approach to create this reminder.", reminder.Title, triggerAt.In(loc).Format("2006-01-02 15:04 MST")),
 		ReplyTo:   msg.ID,
-		Timestamp: time.Now(),
+		Timestamp: a.now(),
 		Context: map[string]interface{}{
 			"reminder_id": reminder.ID,
 			"action":      "reminder_created",
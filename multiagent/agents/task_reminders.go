@@ -0,0 +1,209 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/reminders"
+)
+
+// defaultSnooze is how long a reminder is snoozed when no time is named
+const defaultSnooze = 10 * time.Minute
+
+// snoozeDuration matches "for 20 minutes", "1h", "2 hours" and the like
+var snoozeDuration = regexp.MustCompile(`(?i)(\d+)\s*(m|min|mins|minutes?|h|hrs?|hours?)\b`)
+
+// addReminder records a reminder and schedules it with the reminder engine;
+// recurring reminders repeat daily
+func (a *TaskManagerAgent) addReminder(ctx context.Context, reminder *Reminder) {
+	a.taskMutex.Lock()
+	a.reminders[reminder.ID] = reminder
+	a.taskMutex.Unlock()
+
+	a.saveReminder(ctx, reminder)
+	a.scheduleReminder(ctx, reminder, reminder.TriggerAt)
+}
+
+// saveReminder persists a reminder in its owner's memory
+func (a *TaskManagerAgent) saveReminder(ctx context.Context, reminder *Reminder) {
+	if a.memoryStore == nil {
+		return
+	}
+	reminderKey := fmt.Sprintf("reminder:%s", reminder.ID)
+	if err := a.memoryStore.Store(ownerContext(ctx, reminder.UserID), reminderKey, reminder); err != nil {
+		a.logger.WarnContext(ctx, "Failed to save reminder", "reminder_id", reminder.ID, "error", err)
+	}
+}
+
+// scheduleReminder has the engine fire reminder at triggerAt
+func (a *TaskManagerAgent) scheduleReminder(ctx context.Context, reminder *Reminder, triggerAt time.Time) {
+	scheduled := reminders.Reminder{
+		ID:        reminder.ID,
+		UserID:    reminder.UserID,
+		Source:    taskReminderSource,
+		Subject:   reminder.TaskID,
+		Title:     "⏰ " + reminder.Title,
+		Body:      reminder.Message,
+		Kind:      notify.KindTaskReminder,
+		Priority:  multiagent.PriorityMedium,
+		TriggerAt: triggerAt,
+	}
+	if reminder.Recurring {
+		scheduled.Interval = 24 * time.Hour
+	}
+	if err := a.reminderEngine.Schedule(ctx, scheduled); err != nil {
+		a.logger.WarnContext(ctx, "Failed to schedule reminder", "reminder_id", reminder.ID, "error", err)
+	}
+}
+
+// fireTaskReminder is the engine's handler for task reminders: it marks the
+// reminder triggered and describes it, staying silent for tasks already
+// finished
+func (a *TaskManagerAgent) fireTaskReminder(ctx context.Context, scheduled reminders.Reminder, now time.Time) (*notify.Notification, time.Time) {
+	a.taskMutex.Lock()
+	reminder, ok := a.reminders[scheduled.ID]
+	if !ok {
+		a.taskMutex.Unlock()
+		// Not loaded since a restart; the engine's copy says enough
+		return &notify.Notification{Kind: scheduled.Kind, Title: scheduled.Title, Body: scheduled.Body, Priority: scheduled.Priority, At: now}, time.Time{}
+	}
+	if task, ok := a.tasks[reminder.TaskID]; ok && !task.isActive() {
+		reminder.Status = ReminderStatusCompleted
+		a.taskMutex.Unlock()
+		a.saveReminder(ctx, reminder)
+		return nil, time.Time{}
+	}
+
+	if !reminder.Recurring {
+		reminder.Status = ReminderStatusTriggered
+	}
+	reminder.Snoozed = false
+	reminder.SnoozedUntil = nil
+	reminder.LastTriggered = &now
+	notification := a.reminderNotification(ctx, reminder, now)
+	snapshot := *reminder
+	a.taskMutex.Unlock()
+
+	// Keep a record of the reminder for the user's history
+	a.saveReminder(ctx, &snapshot)
+	if a.memoryStore != nil {
+		systemMsgKey := "system_reminder:" + a.newID("")
+		a.memoryStore.Store(ctx, systemMsgKey, map[string]interface{}{
+			"type":      "reminder_triggered",
+			"reminder":  snapshot,
+			"timestamp": now,
+		})
+	}
+	return &notification, time.Time{}
+}
+
+// reminderNotification describes a triggered reminder for the notifier;
+// callers hold taskMutex
+func (a *TaskManagerAgent) reminderNotification(ctx context.Context, reminder *Reminder, now time.Time) notify.Notification {
+	notification := notify.Notification{
+		UserID:   reminder.UserID,
+		Kind:     notify.KindTaskReminder,
+		Title:    "⏰ " + reminder.Title,
+		Body:     reminder.Message,
+		Priority: multiagent.PriorityMedium,
+		At:       now,
+		Subject:  reminder.ID,
+	}
+	if task, ok := a.tasks[reminder.TaskID]; ok {
+		notification.Priority = task.Priority
+		if notification.Body == "" {
+			notification.Body = task.Title
+		}
+		if task.DueDate != nil {
+			loc := userLocation(ownerContext(ctx, reminder.UserID), a.memoryStore)
+			notification.Body += fmt.Sprintf(" (due %s)", task.DueDate.In(loc).Format("Mon Jan 2 15:04"))
+		}
+	}
+	return notification
+}
+
+// handleSnoozeReminder postpones the user's reminder that went off last, or
+// the one whose title the message names
+func (a *TaskManagerAgent) handleSnoozeReminder(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	content := strings.ToLower(msg.Content)
+
+	a.taskMutex.Lock()
+	var target *Reminder
+	for _, reminder := range a.reminders {
+		if !ownedBy(ctx, reminder.UserID) || reminder.LastTriggered == nil {
+			continue
+		}
+		named := reminder.Title != "" && strings.Contains(content, strings.ToLower(reminder.Title))
+		if named || target == nil || reminder.LastTriggered.After(*target.LastTriggered) {
+			target = reminder
+		}
+		if named {
+			break
+		}
+	}
+	if target == nil {
+		a.taskMutex.Unlock()
+		return &multiagent.Message{
+			ID:        a.messageID(),
+			From:      a.id,
+			To:        []multiagent.AgentID{msg.From},
+			Type:      multiagent.MessageTypeResponse,
+			Content:   "There's no reminder that has gone off to snooze.",
+			ReplyTo:   msg.ID,
+			Timestamp: a.now(),
+		}, nil
+	}
+
+	until := a.now().Add(parseSnooze(content))
+	target.Status = ReminderStatusPending
+	target.Snoozed = true
+	target.SnoozedUntil = &until
+	snapshot := *target
+	a.taskMutex.Unlock()
+
+	// A repeating reminder is still scheduled; a one-off fires once more
+	if err := a.reminderEngine.Snooze(ctx, snapshot.ID, until); errors.Is(err, reminders.ErrNotFound) {
+		a.scheduleReminder(ctx, &snapshot, until)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to snooze reminder: %w", err)
+	}
+	a.saveReminder(ctx, &snapshot)
+
+	loc := userLocation(ctx, a.memoryStore)
+	return &multiagent.Message{
+		ID:        a.messageID(),
+		From:      a.id,
+		To:        []multiagent.AgentID{msg.From},
+		Type:      multiagent.MessageTypeResponse,
+		Content:   fmt.Sprintf("😴 Snoozed '%s' until %s", snapshot.Title, until.In(loc).Format("15:04 MST")),
+		ReplyTo:   msg.ID,
+		Timestamp: a.now(),
+		Context: map[string]interface{}{
+			"reminder_id": snapshot.ID,
+			"action":      "reminder_snoozed",
+		},
+	}, nil
+}
+
+// parseSnooze reads how long to snooze for from the message
+func parseSnooze(content string) time.Duration {
+	match := snoozeDuration.FindStringSubmatch(content)
+	if match == nil {
+		return defaultSnooze
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil || n <= 0 {
+		return defaultSnooze
+	}
+	if strings.HasPrefix(strings.ToLower(match[2]), "h") {
+		return time.Duration(n) * time.Hour
+	}
+	return time.Duration(n) * time.Minute
+}
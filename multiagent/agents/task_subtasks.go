@@ -0,0 +1,262 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// Subtask commands a user can give
+const (
+	subtaskAdd      = "add"
+	subtaskComplete = "complete"
+	subtaskReopen   = "reopen"
+	subtaskRemove   = "remove"
+	subtaskRename   = "rename"
+	subtaskList     = "list"
+)
+
+// subtaskPhrase matches "add subtask X to Y", "complete subtask X of Y" and
+// the like when the LLM can't read the request
+var subtaskPhrase = regexp.MustCompile(`(?i)^(add|complete|finish|check off|tick off|uncheck|reopen|remove|delete|list|show)\s+(?:the\s+)?sub-?tasks?\s+(?:(.+?)\s+)?(?:to|on|of|in|from|for)\s+(?:the |my )?(.+?)[.!?]*$`)
+
+// rollUpProgress sets the task's progress from its finished subtasks; tasks
+// without subtasks keep the progress they were given
+func (t *PersonalTask) rollUpProgress() {
+	if len(t.Subtasks) == 0 || t.Status == PersonalTaskStatusCompleted {
+		return
+	}
+	done := 0
+	for _, subtask := range t.Subtasks {
+		if subtask.Completed {
+			done++
+		}
+	}
+	t.Progress = 100 * float64(done) / float64(len(t.Subtasks))
+}
+
+// subtaskCount shows how many of the task's subtasks are done, as "2/5",
+// or "" when it has none
+func subtaskCount(task *PersonalTask) string {
+	if len(task.Subtasks) == 0 {
+		return ""
+	}
+	done := 0
+	for _, subtask := range task.Subtasks {
+		if subtask.Completed {
+			done++
+		}
+	}
+	return fmt.Sprintf("%d/%d", done, len(task.Subtasks))
+}
+
+// findSubtask returns the index of the subtask named by ref, either its
+// 1-based number or part of its title, or -1
+func findSubtask(task *PersonalTask, ref string) int {
+	ref = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(ref)), "#")
+	if ref == "" {
+		return -1
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n >= 1 && n <= len(task.Subtasks) {
+			return n - 1
+		}
+		return -1
+	}
+	for i, subtask := range task.Subtasks {
+		if strings.EqualFold(subtask.Title, ref) {
+			return i
+		}
+	}
+	for i, subtask := range task.Subtasks {
+		if strings.Contains(strings.ToLower(subtask.Title), ref) {
+			return i
+		}
+	}
+	return -1
+}
+
+// handleSubtask adds, checks off, reopens, renames, removes or lists the
+// subtasks of one of the user's tasks, rolling their completion up into
+// the task's progress
+func (a *TaskManagerAgent) handleSubtask(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+
+	prompt, err := a.renderPrompt(ctx, "task.subtask", prompts.Vars{"Request": msg.Content})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		taskReference
+		Action   string   `json:"action"`
+		Subtasks []string `json:"subtasks"`
+		Subtask  string   `json:"subtask"`
+		NewTitle string   `json:"new_title"`
+	}
+	subtaskSchema := objectSchema(map[string]string{
+		"task_id":   "string",
+		"title":     "string",
+		"action":    "string",
+		"subtasks":  "array",
+		"subtask":   "string",
+		"new_title": "string",
+	}, "action")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, subtaskSchema, &data); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse subtask request", "error", err)
+		match := subtaskPhrase.FindStringSubmatch(strings.TrimSpace(msg.Content))
+		if match == nil {
+			return a.respond(msg, "☑️ Say something like \"add subtask book venue to plan party\" or \"complete subtask 2 of plan party\".", nil), nil
+		}
+		data.Action, data.Subtask, data.Title = strings.ToLower(match[1]), match[2], match[3]
+		if data.Subtask != "" {
+			data.Subtasks = strings.Split(data.Subtask, ",")
+		}
+	}
+
+	action := strings.ToLower(strings.TrimSpace(data.Action))
+	switch action {
+	case "finish", "check off", "tick off", "done":
+		action = subtaskComplete
+	case "uncheck":
+		action = subtaskReopen
+	case "delete":
+		action = subtaskRemove
+	case "show":
+		action = subtaskList
+	}
+
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now()
+
+	a.taskMutex.Lock()
+	task := a.resolveTask(ctx, data.taskReference, "", false)
+	if task == nil {
+		a.taskMutex.Unlock()
+		return a.respond(msg, "❌ Task not found. Please specify a valid task ID or title.", nil), nil
+	}
+	if action == subtaskList {
+		snapshot := *task
+		snapshot.Subtasks = append([]Subtask(nil), task.Subtasks...)
+		a.taskMutex.Unlock()
+		return a.respond(msg, subtaskChecklist(&snapshot, loc), map[string]interface{}{
+			"task_id": snapshot.ID,
+			"action":  "subtasks_listed",
+		}), nil
+	}
+
+	var content string
+	switch action {
+	case subtaskAdd:
+		var added []string
+		for _, title := range data.Subtasks {
+			title = strings.TrimSpace(title)
+			if title == "" {
+				continue
+			}
+			task.Subtasks = append(task.Subtasks, Subtask{
+				ID:        a.newID("subtask"),
+				Title:     title,
+				CreatedAt: now,
+			})
+			added = append(added, "'"+title+"'")
+		}
+		if len(added) == 0 {
+			a.taskMutex.Unlock()
+			return a.respond(msg, fmt.Sprintf("☑️ What should I add to '%s'?", task.Title), nil), nil
+		}
+		content = fmt.Sprintf("➕ Added %s to '%s'.", strings.Join(added, ", "), task.Title)
+
+	case subtaskComplete, subtaskReopen, subtaskRemove, subtaskRename:
+		i := findSubtask(task, data.Subtask)
+		if i < 0 {
+			title := task.Title
+			a.taskMutex.Unlock()
+			return a.respond(msg, fmt.Sprintf("❌ '%s' has no subtask '%s'.", title, data.Subtask), nil), nil
+		}
+		subtask := &task.Subtasks[i]
+		switch action {
+		case subtaskComplete:
+			if subtask.Completed {
+				a.taskMutex.Unlock()
+				return a.respond(msg, fmt.Sprintf("☑️ '%s' is already done.", subtask.Title), nil), nil
+			}
+			subtask.Completed = true
+			subtask.CompletedAt = &now
+			content = fmt.Sprintf("✅ Checked off '%s' on '%s'.", subtask.Title, task.Title)
+		case subtaskReopen:
+			subtask.Completed = false
+			subtask.CompletedAt = nil
+			content = fmt.Sprintf("↩️ Reopened '%s' on '%s'.", subtask.Title, task.Title)
+		case subtaskRemove:
+			content = fmt.Sprintf("🗑️ Removed '%s' from '%s'.", subtask.Title, task.Title)
+			task.Subtasks = append(task.Subtasks[:i], task.Subtasks[i+1:]...)
+		case subtaskRename:
+			if strings.TrimSpace(data.NewTitle) == "" {
+				a.taskMutex.Unlock()
+				return a.respond(msg, "☑️ What should the subtask be called?", nil), nil
+			}
+			content = fmt.Sprintf("✏️ Renamed '%s' to '%s'.", subtask.Title, data.NewTitle)
+			subtask.Title = strings.TrimSpace(data.NewTitle)
+		}
+
+	default:
+		a.taskMutex.Unlock()
+		return a.respond(msg, "☑️ I can add, complete, reopen, rename, remove or list subtasks.", nil), nil
+	}
+
+	task.rollUpProgress()
+	task.UpdatedAt = now
+	snapshot := *task
+	snapshot.Subtasks = append([]Subtask(nil), task.Subtasks...)
+	a.taskMutex.Unlock()
+
+	if err := a.saveTask(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	a.recordAudit(ctx, msg, audit.TaskUpdated, snapshot.ID, map[string]interface{}{
+		"title":    snapshot.Title,
+		"subtasks": action,
+		"progress": snapshot.Progress,
+	})
+
+	if count := subtaskCount(&snapshot); count != "" {
+		content += fmt.Sprintf("\n📊 Progress: %.0f%% (%s subtasks)", snapshot.Progress, count)
+		if snapshot.Progress == 100 && snapshot.isActive() {
+			content += fmt.Sprintf("\n🎯 Every step is done — say \"mark %s done\" to close the task.", snapshot.Title)
+		}
+	}
+	return a.respond(msg, content, map[string]interface{}{
+		"task_id":  snapshot.ID,
+		"action":   "subtask_" + action,
+		"progress": snapshot.Progress,
+	}), nil
+}
+
+// subtaskChecklist shows a task's subtasks as a numbered checklist
+func subtaskChecklist(task *PersonalTask, loc *time.Location) string {
+	if len(task.Subtasks) == 0 {
+		return fmt.Sprintf("☑️ '%s' has no subtasks yet. Add one with \"add subtask <step> to %s\".", task.Title, task.Title)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "☑️ **%s** — %.0f%% (%s)\n\n", task.Title, task.Progress, subtaskCount(task))
+	for i, subtask := range task.Subtasks {
+		check := "⬜"
+		if subtask.Completed {
+			check = "✅"
+		}
+		fmt.Fprintf(&b, "%d. %s %s", i+1, check, subtask.Title)
+		if subtask.CompletedAt != nil {
+			fmt.Fprintf(&b, " — %s", subtask.CompletedAt.In(loc).Format("Mon Jan 2"))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,285 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// timerSubject matches the task named in "start working on X", "stop the
+// timer for X" and the like
+var timerSubject = regexp.MustCompile(`(?i)\b(?:working on|work on|timer (?:for|on)|tracking)\s+(?:the |my )?(.+?)[.!?]*$`)
+
+// timeShare is the time spent under one heading of a time report
+type timeShare struct {
+	Name  string
+	Spent time.Duration
+}
+
+// openEntry returns the task's running time entry, or nil
+func (t *PersonalTask) openEntry() *TimeEntry {
+	for i := range t.TimeSpent {
+		if t.TimeSpent[i].EndTime == nil {
+			return &t.TimeSpent[i]
+		}
+	}
+	return nil
+}
+
+// stopTimer closes the task's running time entry at now, adding it to
+// ActualTime, and returns how long it ran
+func (t *PersonalTask) stopTimer(now time.Time) time.Duration {
+	entry := t.openEntry()
+	if entry == nil {
+		return 0
+	}
+	end := now
+	entry.EndTime = &end
+	entry.Duration = now.Sub(entry.StartTime)
+	t.ActualTime += entry.Duration
+	t.LastWorkedOn = &end
+	t.UpdatedAt = now
+	return entry.Duration
+}
+
+// handleStartTimer starts tracking time on the task the user names,
+// stopping the timer of whatever they were working on before
+func (a *TaskManagerAgent) handleStartTimer(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now()
+
+	a.taskMutex.Lock()
+	task := a.resolveTask(ctx, timerReference(msg.Content), msg.Content, false)
+	if task == nil || !task.isActive() {
+		a.taskMutex.Unlock()
+		return a.respond(msg, "⏱️ Which task are you starting? Name one of your open tasks, or add it first with \"add task\".", nil), nil
+	}
+	if entry := task.openEntry(); entry != nil {
+		title, started := task.Title, entry.StartTime
+		a.taskMutex.Unlock()
+		return a.respond(msg, fmt.Sprintf("⏱️ You've been working on '%s' since %s.", title, started.In(loc).Format("15:04")), nil), nil
+	}
+
+	var switched *PersonalTask
+	var switchedAfter time.Duration
+	if running := a.runningTask(ctx); running != nil {
+		switchedAfter = running.stopTimer(now)
+		snapshot := *running
+		snapshot.TimeSpent = append([]TimeEntry(nil), running.TimeSpent...)
+		switched = &snapshot
+	}
+
+	task.TimeSpent = append(task.TimeSpent, TimeEntry{
+		ID:        a.newID("time"),
+		StartTime: now,
+	})
+	task.setStatus(PersonalTaskStatusInProgress, now, "")
+	task.LastWorkedOn = &now
+	task.UpdatedAt = now
+	snapshot := *task
+	snapshot.TimeSpent = append([]TimeEntry(nil), task.TimeSpent...)
+	a.taskMutex.Unlock()
+
+	if switched != nil {
+		if err := a.saveTask(ctx, switched); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.saveTask(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+
+	content := fmt.Sprintf("⏱️ Started tracking '%s' at %s.", snapshot.Title, now.In(loc).Format("15:04"))
+	if switched != nil {
+		content += fmt.Sprintf("\n⏹️ Stopped '%s' after %s.", switched.Title, formatDuration(switchedAfter))
+	}
+	if snapshot.ActualTime > 0 || snapshot.EstimatedTime > 0 {
+		content += "\n\n" + timeLogged(&snapshot)
+	}
+	return a.respond(msg, content, map[string]interface{}{
+		"task_id": snapshot.ID,
+		"action":  "timer_started",
+	}), nil
+}
+
+// handleStopTimer stops the user's running timer and adds the session to
+// the task's actual time
+func (a *TaskManagerAgent) handleStopTimer(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+	now := a.now()
+
+	a.taskMutex.Lock()
+	task := a.runningTask(ctx)
+	if task == nil {
+		a.taskMutex.Unlock()
+		return a.respond(msg, "⏹️ No timer is running. Say \"start working on\" a task to track time.", nil), nil
+	}
+	session := task.stopTimer(now)
+	snapshot := *task
+	snapshot.TimeSpent = append([]TimeEntry(nil), task.TimeSpent...)
+	a.taskMutex.Unlock()
+
+	if err := a.saveTask(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+
+	content := fmt.Sprintf("⏹️ Stopped '%s' after %s.\n\n%s", snapshot.Title, formatDuration(session), timeLogged(&snapshot))
+	return a.respond(msg, content, map[string]interface{}{
+		"task_id":         snapshot.ID,
+		"action":          "timer_stopped",
+		"session_minutes": int(session.Minutes()),
+	}), nil
+}
+
+// handleTimeReport breaks down the time tracked this week, or last week,
+// by category, project and task
+func (a *TaskManagerAgent) handleTimeReport(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now().In(loc)
+
+	weekStart := startOfWeek(now)
+	if strings.Contains(strings.ToLower(msg.Content), "last week") {
+		weekStart = weekStart.AddDate(0, 0, -7)
+	}
+	weekEnd := weekStart.AddDate(0, 0, 7)
+
+	var total time.Duration
+	byCategory := make(map[string]time.Duration)
+	byProject := make(map[string]time.Duration)
+	byTask := make(map[string]time.Duration)
+	running := ""
+	for _, task := range a.userTasks(ctx, func(task *PersonalTask) bool { return task.DeletedAt == nil }) {
+		spent := timeSpentBetween(task, weekStart, weekEnd, now)
+		if spent <= 0 {
+			continue
+		}
+		total += spent
+		byCategory[orDefault(task.Category, "uncategorized")] += spent
+		byProject[orDefault(task.Project, "no project")] += spent
+		byTask[task.Title] += spent
+		if task.openEntry() != nil {
+			running = task.Title
+		}
+	}
+
+	period := fmt.Sprintf("%s – %s", weekStart.Format("Mon 2 Jan"), weekEnd.AddDate(0, 0, -1).Format("Mon 2 Jan"))
+	if total == 0 {
+		return a.respond(msg, fmt.Sprintf("⏱️ No time tracked for %s. Say \"start working on\" a task to start a timer.", period), map[string]interface{}{
+			"action":        "time_report",
+			"total_minutes": 0,
+		}), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "⏱️ **Time Report: %s**\n\nTotal: %s\n", period, formatDuration(total))
+	writeTimeShares(&b, "By category", byCategory, total)
+	writeTimeShares(&b, "By project", byProject, total)
+	writeTimeShares(&b, "Top tasks", byTask, total)
+	if running != "" {
+		fmt.Fprintf(&b, "\n⏱️ Still tracking '%s'.\n", running)
+	}
+
+	return a.respond(msg, b.String(), map[string]interface{}{
+		"action":        "time_report",
+		"total_minutes": int(total.Minutes()),
+		"week_start":    weekStart.Format("2006-01-02"),
+	}), nil
+}
+
+// runningTask returns the user's task whose timer is running, or nil;
+// callers hold taskMutex
+func (a *TaskManagerAgent) runningTask(ctx context.Context) *PersonalTask {
+	for _, task := range a.tasks {
+		if ownedBy(ctx, task.UserID) && task.DeletedAt == nil && task.openEntry() != nil {
+			return task
+		}
+	}
+	return nil
+}
+
+// timerReference reads the task a timer command names
+func timerReference(content string) taskReference {
+	var ref taskReference
+	if match := timerSubject.FindStringSubmatch(strings.TrimSpace(content)); match != nil {
+		ref.Title = match[1]
+	}
+	return ref
+}
+
+// timeSpentBetween sums the task's tracked time that falls in [from, to),
+// counting a running timer up to now
+func timeSpentBetween(task *PersonalTask, from, to, now time.Time) time.Duration {
+	var spent time.Duration
+	for _, entry := range task.TimeSpent {
+		start, end := entry.StartTime, now
+		if entry.EndTime != nil {
+			end = *entry.EndTime
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			spent += end.Sub(start)
+		}
+	}
+	return spent
+}
+
+// timeLogged describes the task's tracked time against its estimate
+func timeLogged(task *PersonalTask) string {
+	logged := fmt.Sprintf("📊 Logged on this task: %s", formatDuration(task.ActualTime))
+	if task.EstimatedTime > 0 {
+		logged += fmt.Sprintf(" of %s estimated", formatDuration(task.EstimatedTime))
+		if task.ActualTime > task.EstimatedTime {
+			logged += fmt.Sprintf(" (⚠️ %s over)", formatDuration(task.ActualTime-task.EstimatedTime))
+		}
+	}
+	return logged
+}
+
+// writeTimeShares writes a section of a time report, largest share first
+func writeTimeShares(b *strings.Builder, title string, spent map[string]time.Duration, total time.Duration) {
+	shares := make([]timeShare, 0, len(spent))
+	for name, d := range spent {
+		shares = append(shares, timeShare{Name: name, Spent: d})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Spent != shares[j].Spent {
+			return shares[i].Spent > shares[j].Spent
+		}
+		return shares[i].Name < shares[j].Name
+	})
+
+	fmt.Fprintf(b, "\n**%s**\n", title)
+	for i, share := range shares {
+		if i >= maxListedTasks {
+			fmt.Fprintf(b, "• ... and %d more\n", len(shares)-i)
+			return
+		}
+		fmt.Fprintf(b, "• %s: %s (%.0f%%)\n", share.Name, formatDuration(share.Spent), 100*share.Spent.Seconds()/total.Seconds())
+	}
+}
+
+// startOfWeek returns midnight of the Monday starting t's week
+func startOfWeek(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -daysSinceMonday)
+}
+
+// orDefault returns value, or fallback when it is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
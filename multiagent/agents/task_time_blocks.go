@@ -0,0 +1,297 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// timeBlockTimeout bounds how long blocking time waits for the scheduler
+const timeBlockTimeout = 30 * time.Second
+
+var (
+	// blockLength matches how long to block, as in "block 2 hours" or
+	// "block 90 minutes"
+	blockLength = regexp.MustCompile(`(?i)\b(\d+(?:\.\d+)?|an?)\s*(hours?|hrs?|h|minutes?|mins?)\b`)
+	// blockSubject matches the task named in "block 2 hours for the report
+	// tomorrow morning"
+	blockSubject = regexp.MustCompile(`(?i)\b(?:for|on)\s+(?:the |my )?(.+?)(?:\s+(?:today|tonight|tomorrow|this|next|on|at|from|in)\b.*)?[.!?]*$`)
+)
+
+// releaseCalendarBlocks frees the calendar time the task no longer needs:
+// blocks yet to start are cancelled and one under way ends at now. It
+// returns the changes for the scheduler.
+func (t *PersonalTask) releaseCalendarBlocks(now time.Time) []TimeBlockRequest {
+	var changes []TimeBlockRequest
+	var kept []CalendarBlock
+	for _, block := range t.CalendarBlocks {
+		switch {
+		case block.Start.After(now):
+			changes = append(changes, TimeBlockRequest{Action: TimeBlockCancel, TaskID: t.ID, EventID: block.EventID})
+			continue
+		case block.End.After(now):
+			block.End = now
+			changes = append(changes, TimeBlockRequest{Action: TimeBlockUpdate, TaskID: t.ID, EventID: block.EventID, Start: block.Start, End: now})
+		}
+		kept = append(kept, block)
+	}
+	t.CalendarBlocks = kept
+	return changes
+}
+
+// shiftCalendarBlocks moves the task's blocks yet to start by delta, as
+// when its due date moves, and returns the changes for the scheduler
+func (t *PersonalTask) shiftCalendarBlocks(delta time.Duration, now time.Time) []TimeBlockRequest {
+	if delta == 0 {
+		return nil
+	}
+	var changes []TimeBlockRequest
+	blocks := make([]CalendarBlock, len(t.CalendarBlocks))
+	for i, block := range t.CalendarBlocks {
+		if block.Start.After(now) {
+			block.Start, block.End = block.Start.Add(delta), block.End.Add(delta)
+			changes = append(changes, TimeBlockRequest{Action: TimeBlockUpdate, TaskID: t.ID, EventID: block.EventID, Start: block.Start, End: block.End})
+		}
+		blocks[i] = block
+	}
+	t.CalendarBlocks = blocks
+	return changes
+}
+
+// syncTimeBlocks passes changes to a task's calendar blocks on to the
+// scheduler
+func (a *TaskManagerAgent) syncTimeBlocks(ctx context.Context, changes []TimeBlockRequest) {
+	if len(changes) == 0 {
+		return
+	}
+	scheduler, ok := a.agentOfType(multiagent.AgentTypeScheduler)
+	if !ok {
+		a.logger.WarnContext(ctx, "No scheduler to update time blocks", "task_id", changes[0].TaskID)
+		return
+	}
+	for _, change := range changes {
+		if err := a.SendMessage(ctx, timeBlockMessage(ctx, scheduler, change)); err != nil {
+			a.logger.WarnContext(ctx, "Failed to update time block", "task_id", change.TaskID, "event_id", change.EventID, "error", err)
+		}
+	}
+}
+
+// timeBlockNote tells the user what happened to a task's calendar blocks
+func timeBlockNote(changes []TimeBlockRequest) string {
+	cancelled, updated := 0, 0
+	for _, change := range changes {
+		if change.Action == TimeBlockCancel {
+			cancelled++
+		} else {
+			updated++
+		}
+	}
+	var parts []string
+	if cancelled > 0 {
+		parts = append(parts, fmt.Sprintf("cancelled %d upcoming", cancelled))
+	}
+	if updated > 0 {
+		parts = append(parts, fmt.Sprintf("updated %d", updated))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("🗓️ Calendar: %s time block(s).", strings.Join(parts, " and "))
+}
+
+// handleBlockTime reserves time on the user's calendar to work on a task,
+// asking the scheduler for an event linked to the task
+func (a *TaskManagerAgent) handleBlockTime(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+	loc := userLocation(ctx, a.memoryStore)
+	now := a.now().In(loc)
+
+	prompt, err := a.renderPrompt(ctx, "task.block_time", prompts.Vars{
+		"Request":  msg.Content,
+		"Now":      now.Format("2006-01-02 15:04 (Monday)"),
+		"Timezone": loc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		taskReference
+		StartTime string `json:"start_time"`
+		Duration  int    `json:"duration"`
+	}
+	blockSchema := objectSchema(map[string]string{
+		"task_id":    "string",
+		"title":      "string",
+		"start_time": "string",
+		"duration":   "integer",
+	}, "start_time")
+	if err := a.queryJSON(ctx, a.sharedContext(ctx, msg)+prompt, blockSchema, &data); err != nil {
+		a.logger.WarnContext(ctx, "Failed to parse time block request", "error", err)
+		if match := blockSubject.FindStringSubmatch(strings.TrimSpace(msg.Content)); match != nil {
+			data.Title = match[1]
+		}
+	}
+	if data.Duration <= 0 {
+		data.Duration = blockMinutes(msg.Content)
+	}
+	start, err := resolveTime(data.StartTime, msg.Content, now)
+	if err != nil {
+		return a.respond(msg, "🗓️ When should I block the time? Say something like \"block 2 hours for the report tomorrow morning\".", nil), nil
+	}
+
+	a.taskMutex.Lock()
+	task := a.resolveTask(ctx, data.taskReference, msg.Content, false)
+	if task == nil || !task.isActive() {
+		a.taskMutex.Unlock()
+		return a.respond(msg, "❌ Task not found. Please specify a valid task ID or title.", nil), nil
+	}
+	taskID, title := task.ID, task.Title
+	length := time.Duration(data.Duration) * time.Minute
+	if length <= 0 {
+		length = task.EstimatedTime
+	}
+	a.taskMutex.Unlock()
+	if length <= 0 {
+		length = time.Hour
+	}
+	end := start.Add(length)
+	if !end.After(now) {
+		return a.respond(msg, fmt.Sprintf("🗓️ %s has already passed. When should I block time for '%s'?", start.Format("2006-01-02 15:04"), title), nil), nil
+	}
+
+	scheduler, ok := a.agentOfType(multiagent.AgentTypeScheduler)
+	if !ok {
+		return a.respond(msg, fmt.Sprintf("🗓️ I can't reach your calendar right now, so I couldn't block time for '%s'.", title), nil), nil
+	}
+	future, err := a.RequestMessage(ctx, timeBlockMessage(ctx, scheduler, TimeBlockRequest{
+		Action: TimeBlockSchedule,
+		TaskID: taskID,
+		Title:  title,
+		Start:  start,
+		End:    end,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to request time block: %w", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeBlockTimeout)
+	defer cancel()
+	reply, err := future.Wait(waitCtx)
+	if err != nil {
+		future.Cancel()
+		a.logger.WarnContext(ctx, "Scheduler did not block time", "task_id", taskID, "error", err)
+		return a.respond(msg, fmt.Sprintf("🗓️ Your calendar didn't answer, so I couldn't block time for '%s'. Please try again.", title), nil), nil
+	}
+	eventID, _ := reply.Context["event_id"].(string)
+	if eventID == "" {
+		// The scheduler explains why, e.g. a conflict
+		return a.respond(msg, reply.Content, map[string]interface{}{
+			"task_id": taskID,
+			"action":  "time_block_refused",
+		}), nil
+	}
+
+	block := CalendarBlock{EventID: eventID, Start: start, End: end}
+	a.taskMutex.Lock()
+	task, exists := a.tasks[taskID]
+	if !exists {
+		a.taskMutex.Unlock()
+		return a.respond(msg, fmt.Sprintf("🗓️ '%s' was removed while I blocked time for it; the calendar event %s is still there.", title, eventID), nil), nil
+	}
+	task.CalendarBlocks = append(task.CalendarBlocks, block)
+	task.UpdatedAt = a.now()
+	snapshot := *task
+	a.taskMutex.Unlock()
+
+	if err := a.saveTask(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+
+	when := fmt.Sprintf("%s - %s", start.Format("Mon 2006-01-02 15:04"), end.Format("15:04 MST"))
+	a.recordShared(ctx, msg, memory.BlackboardEntry{
+		Section: memory.BlackboardFacts,
+		Key:     "task:" + taskID,
+		Value:   fmt.Sprintf("%s is blocked on the calendar to work on '%s'", when, title),
+	})
+	a.recordAudit(ctx, msg, audit.TaskUpdated, taskID, map[string]interface{}{
+		"title":      title,
+		"time_block": eventID,
+		"start_time": start,
+		"end_time":   end,
+	})
+
+	content := fmt.Sprintf("🔒 Blocked %s (%s) for '%s'.\n\nEvent ID: %s\nCompleting the task frees the rest of the block, and moving its due date moves the block with it.", when, formatDuration(length), title, eventID)
+	return a.respond(msg, content, map[string]interface{}{
+		"task_id":  taskID,
+		"event_id": eventID,
+		"action":   "time_blocked",
+	}), nil
+}
+
+// handleTimeBlockChange records that the user rescheduled or cancelled one
+// of a task's blocks on the calendar. It answers nobody: the scheduler has
+// already told the user.
+func (a *TaskManagerAgent) handleTimeBlockChange(ctx context.Context, msg *multiagent.Message, change *TimeBlockRequest) (*multiagent.Message, error) {
+	a.loadTasksFromMemory(ctx)
+
+	a.taskMutex.Lock()
+	task, exists := a.tasks[change.TaskID]
+	if !exists || !ownedBy(ctx, task.UserID) {
+		a.taskMutex.Unlock()
+		return nil, nil
+	}
+	var blocks []CalendarBlock
+	found := false
+	for _, block := range task.CalendarBlocks {
+		if block.EventID == change.EventID {
+			found = true
+			if change.Action == TimeBlockCancel {
+				continue
+			}
+			block.Start, block.End = change.Start, change.End
+		}
+		blocks = append(blocks, block)
+	}
+	if !found {
+		a.taskMutex.Unlock()
+		return nil, nil
+	}
+	task.CalendarBlocks = blocks
+	task.UpdatedAt = a.now()
+	snapshot := *task
+	a.taskMutex.Unlock()
+
+	if err := a.saveTask(ctx, &snapshot); err != nil {
+		return nil, err
+	}
+	a.recordAudit(ctx, msg, audit.TaskUpdated, snapshot.ID, map[string]interface{}{
+		"title":      snapshot.Title,
+		"time_block": change.EventID,
+		"action":     change.Action,
+	})
+	return nil, nil
+}
+
+// blockMinutes reads how long to block from the request, or 0
+func blockMinutes(content string) int {
+	match := blockLength.FindStringSubmatch(content)
+	if match == nil {
+		return 0
+	}
+	amount := 1.0
+	if n, err := strconv.ParseFloat(match[1], 64); err == nil {
+		amount = n
+	}
+	if strings.HasPrefix(strings.ToLower(match[2]), "h") {
+		amount *= 60
+	}
+	return int(amount)
+}
@@ -0,0 +1,226 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+// timeBlockContextKey is the message context key a TimeBlockRequest
+// travels in between the task manager and the scheduler
+const timeBlockContextKey = "time_block"
+
+// timeBlockTaskKey is the CalendarEvent metadata key naming the task an
+// event blocks time for
+const timeBlockTaskKey = "task_id"
+
+// Time block actions. The task manager asks the scheduler to schedule,
+// update or cancel the event blocking time for a task; the scheduler tells
+// the task manager when the user updates or cancels one on the calendar.
+const (
+	TimeBlockSchedule = "schedule"
+	TimeBlockUpdate   = "update"
+	TimeBlockCancel   = "cancel"
+)
+
+// TimeBlockRequest is a change to a calendar event reserving time to work
+// on a task
+type TimeBlockRequest struct {
+	Action  string    `json:"action"`
+	TaskID  string    `json:"task_id"`
+	EventID string    `json:"event_id,omitempty"`
+	Title   string    `json:"title,omitempty"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+}
+
+// CalendarBlock is a calendar event reserving time to work on a task
+type CalendarBlock struct {
+	EventID string    `json:"event_id"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+}
+
+// decodeTimeBlock reads the TimeBlockRequest a message carries, if any
+func decodeTimeBlock(msg *multiagent.Message) (*TimeBlockRequest, bool) {
+	value, ok := msg.Context[timeBlockContextKey]
+	if !ok {
+		return nil, false
+	}
+	if block, ok := value.(TimeBlockRequest); ok {
+		return &block, true
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var block TimeBlockRequest
+	if err := json.Unmarshal(data, &block); err != nil || block.Action == "" {
+		return nil, false
+	}
+	return &block, true
+}
+
+// timeBlockMessage builds a message to an agent carrying block for the
+// user ctx acts for
+func timeBlockMessage(ctx context.Context, to multiagent.AgentID, block TimeBlockRequest) *multiagent.Message {
+	return &multiagent.Message{
+		To:      []multiagent.AgentID{to},
+		Type:    multiagent.MessageTypeRequest,
+		Content: fmt.Sprintf("%s the time block of task %s", block.Action, block.TaskID),
+		Context: map[string]interface{}{
+			timeBlockContextKey:      block,
+			multiagent.ContextUserID: multiagent.UserIDFromContext(ctx),
+		},
+	}
+}
+
+// blockedTask returns the ID of the task event blocks time for, or ""
+func blockedTask(event *CalendarEvent) string {
+	taskID, _ := event.Metadata[timeBlockTaskKey].(string)
+	return taskID
+}
+
+// handleTimeBlock applies the task manager's change to the event blocking
+// time for a task. Only requests to schedule a block, which the task
+// manager waits on, are answered.
+func (a *SchedulerAgent) handleTimeBlock(ctx context.Context, msg *multiagent.Message, block *TimeBlockRequest) (*multiagent.Message, error) {
+	if block.Action == TimeBlockSchedule {
+		return a.scheduleTimeBlock(ctx, msg, block)
+	}
+
+	a.scheduleMutex.Lock()
+	event, ok := a.calendar[block.EventID]
+	if !ok || !ownedBy(ctx, event.UserID) || event.Status == EventStatusCancelled {
+		a.scheduleMutex.Unlock()
+		a.logger.WarnContext(ctx, "Time block not found", "event_id", block.EventID, "task_id", block.TaskID)
+		return nil, nil
+	}
+	eventType := audit.EventRescheduled
+	switch block.Action {
+	case TimeBlockUpdate:
+		event.StartTime = block.Start.UTC()
+		event.EndTime = block.End.UTC()
+	case TimeBlockCancel:
+		event.Status = EventStatusCancelled
+		eventType = audit.EventCancelled
+	default:
+		a.scheduleMutex.Unlock()
+		return nil, fmt.Errorf("unknown time block action %q", block.Action)
+	}
+	event.UpdatedAt = a.now()
+	a.scheduleMutex.Unlock()
+	if err := a.saveEvent(ctx, event); err != nil {
+		return nil, err
+	}
+
+	a.recordAudit(ctx, msg, eventType, event.ID, map[string]interface{}{
+		"title":      event.Title,
+		"start_time": event.StartTime,
+		"end_time":   event.EndTime,
+		"task_id":    block.TaskID,
+	})
+	return nil, nil
+}
+
+// scheduleTimeBlock creates a focus-time event for a task, refusing if it
+// would overlap another event
+func (a *SchedulerAgent) scheduleTimeBlock(ctx context.Context, msg *multiagent.Message, block *TimeBlockRequest) (*multiagent.Message, error) {
+	loc := userLocation(ctx, a.memoryStore)
+	start, end := block.Start.In(loc), block.End.In(loc)
+	if !end.After(start) {
+		return nil, fmt.Errorf("time block for task %s ends before it starts", block.TaskID)
+	}
+
+	if conflicts := a.checkConflicts(ctx, start, end, ""); len(conflicts) > 0 {
+		conflictsList := make([]string, len(conflicts))
+		for i, conflict := range conflicts {
+			conflictsList[i] = fmt.Sprintf("• %s (%s - %s)", conflict.Title, conflict.StartTime.In(loc).Format("15:04"), conflict.EndTime.In(loc).Format("15:04"))
+		}
+		return a.respond(msg, fmt.Sprintf("⚠️ **Scheduling Conflict Detected**\n\nBlocking %s - %s for '%s' would conflict with:\n\n%s", start.Format("2006-01-02 15:04"), end.Format("15:04"), block.Title, strings.Join(conflictsList, "\n")), map[string]interface{}{
+			"action":    "conflict_detected",
+			"conflicts": conflicts,
+		}), nil
+	}
+
+	now := a.now()
+	event := &CalendarEvent{
+		ID:          a.newID("event"),
+		Title:       block.Title,
+		Description: fmt.Sprintf("Time blocked to work on task %s", block.TaskID),
+		StartTime:   start.UTC(),
+		EndTime:     end.UTC(),
+		Category:    EventCategoryFocusTime,
+		Priority:    multiagent.PriorityMedium,
+		Status:      EventStatusConfirmed,
+		Attendees:   []Attendee{},
+		Reminders:   []EventReminder{},
+		Tags:        []string{"time_block"},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatedBy:   msg.From,
+		Timezone:    loc.String(),
+		Metadata:    map[string]interface{}{timeBlockTaskKey: block.TaskID},
+		UserID:      multiagent.UserIDFromContext(ctx),
+	}
+	a.scheduleMutex.Lock()
+	a.calendar[event.ID] = event
+	a.scheduleMutex.Unlock()
+	if err := a.saveEvent(ctx, event); err != nil {
+		return nil, err
+	}
+
+	a.recordShared(ctx, msg, memory.BlackboardEntry{
+		Section: memory.BlackboardFacts,
+		Key:     "event:" + event.ID,
+		Value:   fmt.Sprintf("%s - %s is blocked to work on '%s'", start.Format("2006-01-02 15:04"), end.Format("15:04 MST"), event.Title),
+	})
+	a.recordAudit(ctx, msg, audit.EventScheduled, event.ID, map[string]interface{}{
+		"title":      event.Title,
+		"start_time": event.StartTime,
+		"end_time":   event.EndTime,
+		"task_id":    block.TaskID,
+	})
+
+	return a.respond(msg, fmt.Sprintf("🔒 Blocked %s - %s for '%s'.\n\nEvent ID: %s", start.Format("2006-01-02 15:04"), end.Format("15:04 MST"), event.Title, event.ID), map[string]interface{}{
+		"event_id":   event.ID,
+		"start_time": event.StartTime,
+		"end_time":   event.EndTime,
+		"action":     "time_block_scheduled",
+	}), nil
+}
+
+// notifyTimeBlock tells the task manager that the user rescheduled or
+// cancelled event, when it blocks time for a task
+func (a *SchedulerAgent) notifyTimeBlock(ctx context.Context, event *CalendarEvent) {
+	a.scheduleMutex.RLock()
+	block := TimeBlockRequest{
+		Action:  TimeBlockUpdate,
+		TaskID:  blockedTask(event),
+		EventID: event.ID,
+		Title:   event.Title,
+		Start:   event.StartTime,
+		End:     event.EndTime,
+	}
+	if event.Status == EventStatusCancelled {
+		block.Action = TimeBlockCancel
+	}
+	a.scheduleMutex.RUnlock()
+	if block.TaskID == "" {
+		return
+	}
+
+	taskManager, ok := a.agentOfType(multiagent.AgentTypeTask)
+	if !ok {
+		return
+	}
+	if err := a.SendMessage(ctx, timeBlockMessage(ctx, taskManager, block)); err != nil {
+		a.logger.WarnContext(ctx, "Failed to tell the task manager about a time block", "event_id", event.ID, "task_id", block.TaskID, "error", err)
+	}
+}
@@ -0,0 +1,156 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+	"github.com/kbutz/wikillm/multiagent/timeparse"
+)
+
+// defaultHour is the time of day assumed when only a day is named
+const defaultHour = 9
+
+// locations caches loaded timezones, since time.LoadLocation reads the
+// zone database on every call
+var locations sync.Map
+
+// loadLocation returns the named IANA timezone, or UTC when the name is
+// empty or unknown
+func loadLocation(name string) *time.Location {
+	if name == "" || name == "UTC" {
+		return time.UTC
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	locations.Store(name, loc)
+	return loc
+}
+
+// userLocation returns the acting user's timezone from their profile, or UTC
+func userLocation(ctx context.Context, store multiagent.MemoryStore) *time.Location {
+	if store == nil {
+		return time.UTC
+	}
+	profile, err := memory.LoadProfile(ctx, store)
+	if err != nil {
+		return time.UTC
+	}
+	return loadLocation(profile.Timezone)
+}
+
+// resolveTime reads an LLM-extracted "YYYY-MM-DD HH:MM" time in now's
+// location, repairing it with a deterministic parse: a value in another
+// format is read as natural language, and when utterance is given, a day or
+// time found in what the user actually wrote overrides the LLM's
+func resolveTime(extracted, utterance string, now time.Time) (time.Time, error) {
+	extracted = strings.TrimSpace(extracted)
+	llmTime, err := time.ParseInLocation("2006-01-02 15:04", extracted, now.Location())
+	if err != nil {
+		if parsed, parseErr := timeparse.Parse(extracted, now); parseErr == nil {
+			llmTime, err = withDefaultHour(parsed), nil
+		}
+	}
+
+	if utterance != "" {
+		if found, ok := timeparse.Extract(utterance, now); ok {
+			switch {
+			case err != nil, found.HasDate && found.HasTime:
+				return withDefaultHour(found), nil
+			case found.HasDate:
+				return atClock(found.Time, llmTime), nil
+			case found.HasTime:
+				return atClock(llmTime, found.Time), nil
+			}
+		}
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read time %q: %w", extracted, err)
+	}
+	return llmTime, nil
+}
+
+// resolveDate reads an LLM-extracted "YYYY-MM-DD" date in now's location,
+// falling back to natural language
+func resolveDate(extracted string, now time.Time) (time.Time, error) {
+	extracted = strings.TrimSpace(extracted)
+	if date, err := time.ParseInLocation("2006-01-02", extracted, now.Location()); err == nil {
+		return date, nil
+	}
+	parsed, err := timeparse.Parse(extracted, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read date %q: %w", extracted, err)
+	}
+	return startOfDay(parsed.Time), nil
+}
+
+// withDefaultHour returns the parsed time, at defaultHour if only a day was
+// named
+func withDefaultHour(parsed timeparse.Result) time.Time {
+	if parsed.HasTime {
+		return parsed.Time
+	}
+	t := parsed.Time
+	return time.Date(t.Year(), t.Month(), t.Day(), defaultHour, 0, 0, 0, t.Location())
+}
+
+// atClock returns day's date at clock's time of day
+func atClock(day, clock time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location())
+}
+
+// startOfDay returns midnight of t's day in t's location
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// location returns the timezone the event was scheduled in
+func (e *CalendarEvent) location() *time.Location {
+	return loadLocation(e.Timezone)
+}
+
+// handleSetTimezone records the user's timezone in their profile, which
+// scheduling and reminders then parse and display times in
+func (a *SchedulerAgent) handleSetTimezone(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	prompt, err := a.renderPrompt(ctx, "scheduler.set_timezone", prompts.Vars{"Request": msg.Content})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Timezone string `json:"timezone"`
+	}
+	timezoneSchema := objectSchema(map[string]string{"timezone": "string"}, "timezone")
+	if err := a.queryJSON(ctx, prompt, timezoneSchema, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse timezone: %w", err)
+	}
+
+	if a.memoryStore == nil {
+		return nil, fmt.Errorf("no memory store to save the timezone in")
+	}
+	profile, err := memory.LoadProfile(ctx, a.memoryStore)
+	if err != nil {
+		return nil, err
+	}
+	profile.Timezone = data.Timezone
+	if err := memory.SaveProfile(ctx, a.memoryStore, profile); err != nil {
+		a.logger.WarnContext(ctx, "Failed to save timezone", "timezone", data.Timezone, "error", err)
+		return a.respond(msg, fmt.Sprintf("🌍 I don't recognise the timezone %q. Please name a city or region, e.g. \"America/New_York\".", data.Timezone), nil), nil
+	}
+
+	loc := loadLocation(profile.Timezone)
+	return a.respond(msg, fmt.Sprintf("🌍 Your timezone is now **%s**. It is %s there; I'll schedule and show times in it.", loc, a.now().In(loc).Format("15:04 MST on Monday")), map[string]interface{}{
+		"timezone": loc.String(),
+		"action":   "timezone_set",
+	}), nil
+}
@@ -0,0 +1,27 @@
+package agents
+
+import (
+	"context"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// UserDataPurger is implemented by agents that keep per-user state in
+// memory, so a user's data can be dropped without restarting the agent
+type UserDataPurger interface {
+	// PurgeUser forgets every in-memory entity owned by userID and returns
+	// how many were dropped
+	PurgeUser(userID string) int
+}
+
+// ownedBy reports whether an entity owned by owner belongs to the user ctx
+// acts for; entities created without a user stay visible only without one
+func ownedBy(ctx context.Context, owner string) bool {
+	return owner == multiagent.UserIDFromContext(ctx)
+}
+
+// ownerContext returns ctx acting for owner, for background work such as
+// reminder checks that touches an entity outside any request
+func ownerContext(ctx context.Context, owner string) context.Context {
+	return multiagent.WithUserID(ctx, owner)
+}
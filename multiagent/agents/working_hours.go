@@ -0,0 +1,84 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// StandardWorkingHours are the working hours of users who have not set
+// their own, unless configured otherwise: weekdays 9:00-18:00
+func StandardWorkingHours() WorkingHours {
+	workday := DaySchedule{IsWorkingDay: true, StartTime: clockTime(9, 0), EndTime: clockTime(18, 0)}
+	return WorkingHours{Monday: workday, Tuesday: workday, Wednesday: workday, Thursday: workday, Friday: workday}
+}
+
+// LoadWorkingHours reads default working hours from a JSON file mapping
+// weekdays to "HH:MM-HH:MM"; days left out are not working days:
+//
+//	{"monday": "09:00-17:00", "tuesday": "09:00-17:00", "saturday": "10:00-13:00"}
+func LoadWorkingHours(path string) (WorkingHours, error) {
+	var hours WorkingHours
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return hours, fmt.Errorf("failed to read working hours: %w", err)
+	}
+	var days map[string]string
+	if err := json.Unmarshal(data, &days); err != nil {
+		return hours, fmt.Errorf("failed to parse working hours: %w", err)
+	}
+
+	for name, span := range days {
+		weekday, ok := weekdayNames[strings.ToLower(name)]
+		if !ok {
+			return hours, fmt.Errorf("unknown weekday %q", name)
+		}
+		start, end, ok := strings.Cut(span, "-")
+		if !ok {
+			return hours, fmt.Errorf("working hours for %s must look like 09:00-17:00, got %q", name, span)
+		}
+		startTime, err := time.Parse("15:04", strings.TrimSpace(start))
+		if err != nil {
+			return hours, fmt.Errorf("invalid start of working hours for %s: %w", name, err)
+		}
+		endTime, err := time.Parse("15:04", strings.TrimSpace(end))
+		if err != nil {
+			return hours, fmt.Errorf("invalid end of working hours for %s: %w", name, err)
+		}
+		if !endTime.After(startTime) {
+			return hours, fmt.Errorf("working hours for %s end before they start", name)
+		}
+		*hours.day(weekday) = DaySchedule{
+			IsWorkingDay: true,
+			StartTime:    clockTime(startTime.Hour(), startTime.Minute()),
+			EndTime:      clockTime(endTime.Hour(), endTime.Minute()),
+		}
+	}
+	return hours, nil
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// SetDefaultWorkingHours changes the working hours of users who have not
+// set their own, taking effect from their next scheduling request
+func (a *SchedulerAgent) SetDefaultWorkingHours(hours WorkingHours) {
+	a.scheduleMutex.Lock()
+	defer a.scheduleMutex.Unlock()
+	a.defaultHours = hours
+	// Cached schedules are reloaded, so defaults are rebuilt from hours
+	// and users' own schedules come back from memory
+	a.schedules = make(map[string]*Schedule)
+}
+
+// DefaultWorkingHours returns the working hours of users who have not set
+// their own
+func (a *SchedulerAgent) DefaultWorkingHours() WorkingHours {
+	a.scheduleMutex.RLock()
+	defer a.scheduleMutex.RUnlock()
+	return a.defaultHours
+}
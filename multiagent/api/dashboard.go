@@ -0,0 +1,105 @@
+package api
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// dashboardFiles is the web dashboard served at /dashboard/: agent status,
+// message flow, task board, calendar, memory browser and a chat to send
+// messages as a user. It only calls the routes of this API.
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+func dashboardHandler() http.Handler {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(fmt.Sprintf("api: dashboard files missing: %v", err))
+	}
+	return http.StripPrefix("/dashboard/", http.FileServerFS(files))
+}
+
+func (s *Server) handleListEvents(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	from, err := parseTimeParam(r, "from", now.AddDate(0, 0, -1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	to, err := parseTimeParam(r, "to", from.AddDate(0, 0, 14))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	events, err := s.service.ListEvents(r.Context(), from, to)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// parseTimeParam reads a query parameter given as RFC 3339 or a date,
+// YYYY-MM-DD, or returns def if it is absent
+func parseTimeParam(r *http.Request, name string, def time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be RFC 3339 or YYYY-MM-DD, got %q", name, value)
+	}
+	return t, nil
+}
+
+func (s *Server) handleListMemory(w http.ResponseWriter, r *http.Request) {
+	limit, err := intParam(r, "limit", 200)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	keys, err := s.service.GetMemoryStore().List(r.Context(), r.URL.Query().Get("prefix"), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (s *Server) handleRecentMessages(w http.ResponseWriter, r *http.Request) {
+	after, err := intParam(r, "after", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit, err := intParam(r, "limit", 200)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.service.RecentMessages(int64(after), limit))
+}
+
+// intParam reads a non-negative integer query parameter, or returns def if
+// it is absent
+func intParam(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", name, value)
+	}
+	return n, nil
+}
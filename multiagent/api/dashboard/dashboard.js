@@ -0,0 +1,303 @@
+// Dashboard for the multiagent REST API. Every panel reads the same routes
+// a frontend would; nothing here talks to the service directly.
+"use strict";
+
+const REFRESH_MS = 3000;
+const TASK_COLUMNS = ["inbox", "next", "in_progress", "waiting", "deferred", "someday", "completed", "cancelled"];
+
+const $ = (selector, root = document) => root.querySelector(selector);
+
+let activeTab = "agents";
+let lastMessageSeq = 0;
+
+function user() {
+  return $("#user").value.trim() || "default";
+}
+
+function withUser(path) {
+  const sep = path.includes("?") ? "&" : "?";
+  return `${path}${sep}user=${encodeURIComponent(user())}`;
+}
+
+// authorization returns the headers authenticating requests with the token
+function authorization() {
+  const token = $("#token").value;
+  return token ? { Authorization: `Bearer ${token}` } : {};
+}
+
+async function getJSON(path) {
+  const response = await fetch(path, { headers: authorization() });
+  const body = await response.json();
+  if (!response.ok && body.error) {
+    throw new Error(body.error);
+  }
+  return body;
+}
+
+function el(tag, attrs = {}, ...children) {
+  const node = document.createElement(tag);
+  for (const [name, value] of Object.entries(attrs)) {
+    node.setAttribute(name, value);
+  }
+  for (const child of children) {
+    node.append(child ?? "");
+  }
+  return node;
+}
+
+function formatTime(value) {
+  if (!value || value.startsWith("0001-")) {
+    return "";
+  }
+  return new Date(value).toLocaleString();
+}
+
+// Agents
+
+async function refreshAgents() {
+  const [agents, health] = await Promise.all([getJSON("/agents"), getJSON("/health")]);
+  const heartbeats = health.last_heartbeats || {};
+
+  const rows = agents
+    .sort((a, b) => a.id.localeCompare(b.id))
+    .map((agent) => el("tr", {},
+      el("td", { title: agent.description }, agent.name, el("div", { class: "meta" }, agent.id)),
+      el("td", {}, el("span", { class: `badge status-${agent.status}` }, agent.status)),
+      el("td", {}, formatTime(heartbeats[agent.id])),
+      el("td", { class: "meta" }, (agent.capabilities || []).join(", "))));
+  $("#agents tbody").replaceChildren(...rows);
+
+  const badge = $("#health");
+  badge.textContent = health.status;
+  badge.className = `badge status-${health.status}`;
+
+  const stats = [
+    ["Active agents", `${health.active_agents} / ${health.total_agents}`],
+    ["Messages processed", health.messages_processed],
+    ["Message queue", health.message_queue_size],
+    ["Events processed", health.events_processed],
+    ["Event queue", health.event_queue_size],
+    ["Uptime", `${Math.round(health.uptime / 1e9)}s`],
+  ];
+  $("#stats").replaceChildren(...stats.flatMap(([name, value]) => [el("dt", {}, name), el("dd", {}, String(value))]));
+}
+
+// Messages
+
+async function refreshMessages() {
+  const error = $("#messages-error");
+  try {
+    const records = await getJSON(`/admin/messages?after=${lastMessageSeq}`);
+    error.textContent = "";
+    const tbody = $("#messages tbody");
+    for (const record of records) {
+      lastMessageSeq = Math.max(lastMessageSeq, record.seq);
+      tbody.prepend(el("tr", { title: record.conversation_id || "" },
+        el("td", {}, formatTime(record.timestamp)),
+        el("td", {}, record.from),
+        el("td", {}, (record.to || []).join(", ")),
+        el("td", {}, record.type),
+        el("td", {}, record.hops ?? ""),
+        el("td", { class: "meta" }, record.content)));
+    }
+    while (tbody.children.length > 500) {
+      tbody.lastChild.remove();
+    }
+  } catch (err) {
+    error.textContent = err.message;
+  }
+}
+
+// Tasks
+
+async function refreshTasks() {
+  const tasks = await getJSON(withUser("/tasks"));
+  const columns = TASK_COLUMNS.map((status) => {
+    const cards = tasks
+      .filter((task) => task.status === status)
+      .map((task) => el("div", { class: "card", title: task.description || "" },
+        task.title,
+        el("div", { class: "meta" },
+          [task.project, task.priority, task.due_date ? `due ${formatTime(task.due_date)}` : ""].filter(Boolean).join(" · "))));
+    return el("div", { class: "column" }, el("h2", {}, `${status.replace("_", " ")} (${cards.length})`), ...cards);
+  });
+  $("#tasks .board").replaceChildren(...columns);
+}
+
+// Calendar
+
+async function refreshCalendar() {
+  const form = $("#calendar-range");
+  const params = new URLSearchParams();
+  for (const name of ["from", "to"]) {
+    if (form.elements[name].value) {
+      params.set(name, form.elements[name].value);
+    }
+  }
+  const events = await getJSON(withUser(`/calendar/events?${params}`));
+  const items = events.map((event) => el("li", {},
+    el("strong", {}, event.title),
+    el("div", { class: "meta" },
+      [event.all_day ? new Date(event.start_time).toLocaleDateString() : `${formatTime(event.start_time)} – ${formatTime(event.end_time)}`,
+        event.location, event.status].filter(Boolean).join(" · "))));
+  $("#calendar .events").replaceChildren(...(items.length ? items : [el("li", { class: "meta" }, "No events")]));
+}
+
+// Memory
+
+async function searchMemory() {
+  const prefix = $("#memory-search").elements.prefix.value;
+  const keys = await getJSON(withUser(`/memory?prefix=${encodeURIComponent(prefix)}`));
+  const items = (keys || []).sort().map((key) => {
+    const item = el("li", {}, key);
+    item.addEventListener("click", () => showMemory(key));
+    return item;
+  });
+  $("#memory .keys").replaceChildren(...items);
+}
+
+async function showMemory(key) {
+  const pane = $("#memory .value");
+  try {
+    const entry = await getJSON(withUser(`/memory/${key.split("/").map(encodeURIComponent).join("/")}`));
+    pane.textContent = JSON.stringify(entry.value, null, 2);
+  } catch (err) {
+    pane.textContent = err.message;
+  }
+}
+
+// Chat
+
+async function loadHistory() {
+  const history = await getJSON(`/conversations/${encodeURIComponent(user())}/history`);
+  $("#chat .transcript").replaceChildren(...history.messages.map((turn) => el("div", { class: `turn ${turn.role}` }, turn.content)));
+}
+
+function addTurn(role, content) {
+  const transcript = $("#chat .transcript");
+  transcript.append(el("div", { class: `turn ${role}` }, content));
+  transcript.lastChild.scrollIntoView();
+}
+
+// sendMessage posts content as the user and shows the progress events
+// streamed back until the reply arrives
+async function sendMessage(content) {
+  addTurn("user", content);
+  const progress = $("#chat .progress");
+  progress.replaceChildren();
+
+  const response = await fetch(`/conversations/${encodeURIComponent(user())}/messages`, {
+    method: "POST",
+    headers: { ...authorization(), "Content-Type": "application/json", Accept: "text/event-stream" },
+    body: JSON.stringify({ content }),
+  });
+  if (!response.ok) {
+    const body = await response.json();
+    addTurn("error", body.error);
+    return;
+  }
+
+  const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+  let buffer = "";
+  for (;;) {
+    const { value, done } = await reader.read();
+    if (done) {
+      break;
+    }
+    buffer += value;
+    let end;
+    while ((end = buffer.indexOf("\n\n")) >= 0) {
+      handleStreamEvent(buffer.slice(0, end), progress);
+      buffer = buffer.slice(end + 2);
+    }
+  }
+}
+
+function handleStreamEvent(raw, progress) {
+  let name = "message";
+  let data = "";
+  for (const line of raw.split("\n")) {
+    if (line.startsWith("event: ")) {
+      name = line.slice(7);
+    } else if (line.startsWith("data: ")) {
+      data += line.slice(6);
+    }
+  }
+  if (!data) {
+    return;
+  }
+  const body = JSON.parse(data);
+  switch (name) {
+    case "response":
+      addTurn("assistant", body.response);
+      break;
+    case "error":
+      addTurn("error", body.error);
+      break;
+    default:
+      progress.append(el("li", {}, [body.agent_id, body.type, body.detail].filter(Boolean).join(" · ")));
+  }
+}
+
+// Wiring
+
+const refreshers = {
+  agents: refreshAgents,
+  messages: refreshMessages,
+  tasks: refreshTasks,
+  calendar: refreshCalendar,
+};
+
+function refresh() {
+  const refresher = refreshers[activeTab];
+  if (refresher) {
+    refresher().catch((err) => console.warn(`refreshing ${activeTab}:`, err));
+  }
+}
+
+function showTab(name) {
+  activeTab = name;
+  for (const button of document.querySelectorAll("nav button")) {
+    button.classList.toggle("active", button.dataset.tab === name);
+  }
+  for (const section of document.querySelectorAll(".tab")) {
+    section.classList.toggle("active", section.id === name);
+  }
+  if (name === "memory") {
+    searchMemory().catch((err) => ($("#memory .value").textContent = err.message));
+  } else if (name === "chat") {
+    loadHistory().catch((err) => console.warn("loading history:", err));
+  }
+  refresh();
+}
+
+for (const button of document.querySelectorAll("nav button")) {
+  button.addEventListener("click", () => showTab(button.dataset.tab));
+}
+
+$("#user").addEventListener("change", () => showTab(activeTab));
+$("#token").addEventListener("change", () => showTab(activeTab));
+
+$("#calendar-range").addEventListener("submit", (event) => {
+  event.preventDefault();
+  refresh();
+});
+
+$("#memory-search").addEventListener("submit", (event) => {
+  event.preventDefault();
+  searchMemory().catch((err) => ($("#memory .value").textContent = err.message));
+});
+
+$("#chat-form").addEventListener("submit", (event) => {
+  event.preventDefault();
+  const input = event.target.elements.content;
+  const content = input.value.trim();
+  if (!content) {
+    return;
+  }
+  input.value = "";
+  sendMessage(content).catch((err) => addTurn("error", err.message));
+});
+
+showTab(activeTab);
+setInterval(refresh, REFRESH_MS);
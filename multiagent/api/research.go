@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/agents"
+)
+
+// TagRequest is the body of POST /research/{id}/tags
+type TagRequest struct {
+	Tags []string `json:"tags"`
+	// Finding, if set, tags one of the session's findings instead
+	Finding string `json:"finding,omitempty"`
+}
+
+// MonitorRequest is the body of POST /research/{id}/monitor
+type MonitorRequest struct {
+	// Interval is how often the research is re-run, as a Go duration such
+	// as "12h" (default a day)
+	Interval string `json:"interval,omitempty"`
+}
+
+// researchFilter reads a library filter from the q, tag and status
+// parameters
+func researchFilter(r *http.Request) agents.ResearchFilter {
+	query := r.URL.Query()
+	return agents.ResearchFilter{
+		Query:  query.Get("q"),
+		Tag:    query.Get("tag"),
+		Status: agents.ResearchStatus(query.Get("status")),
+	}
+}
+
+func (s *Server) handleListResearch(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.service.ListResearch(r.Context(), researchFilter(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+func (s *Server) handleSearchFindings(w http.ResponseWriter, r *http.Request) {
+	findings, err := s.service.SearchResearchFindings(r.Context(), researchFilter(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, findings)
+}
+
+func (s *Server) handleGetResearch(w http.ResponseWriter, r *http.Request) {
+	session, err := s.service.GetResearch(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeResearchError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, session)
+}
+
+func (s *Server) handleAddResearchSource(w http.ResponseWriter, r *http.Request) {
+	var source agents.ResearchSource
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&source); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	session, err := s.service.AddResearchSource(r.Context(), r.PathValue("id"), source)
+	if err != nil {
+		writeResearchError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, session)
+}
+
+func (s *Server) handleTagResearch(w http.ResponseWriter, r *http.Request) {
+	var req TagRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	session, err := s.service.TagResearch(r.Context(), r.PathValue("id"), req.Finding, req.Tags)
+	if err != nil {
+		writeResearchError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, session)
+}
+
+func (s *Server) handleExportBibliography(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = agents.BibliographyFormatBibTeX
+	}
+	contentType, extension := "application/x-bibtex; charset=utf-8", "bib"
+	switch format {
+	case agents.BibliographyFormatBibTeX:
+	case agents.BibliographyFormatMarkdown:
+		contentType, extension = "text/markdown; charset=utf-8", "md"
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("format must be %s or %s", agents.BibliographyFormatBibTeX, agents.BibliographyFormatMarkdown))
+		return
+	}
+
+	id := r.PathValue("id")
+	data, err := s.service.ExportBibliography(r.Context(), id, format)
+	if err != nil {
+		writeResearchError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+"."+extension))
+	w.Write(data)
+}
+
+func (s *Server) handleMonitorResearch(w http.ResponseWriter, r *http.Request) {
+	var req MonitorRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+	var interval time.Duration
+	if req.Interval != "" {
+		var err error
+		if interval, err = time.ParseDuration(req.Interval); err != nil || interval <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("interval must be a positive duration such as 24h"))
+			return
+		}
+	}
+	monitor, err := s.service.MonitorResearch(r.Context(), r.PathValue("id"), interval)
+	if err != nil {
+		writeResearchError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, monitor)
+}
+
+func (s *Server) handleListResearchMonitors(w http.ResponseWriter, r *http.Request) {
+	monitors, err := s.service.ListResearchMonitors(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, monitors)
+}
+
+func (s *Server) handleStopResearchMonitor(w http.ResponseWriter, r *http.Request) {
+	if err := s.service.StopResearchMonitor(r.Context(), r.PathValue("id")); err != nil {
+		writeResearchError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeResearchError answers 404 for sessions, findings and monitors the
+// user does not have, and 400 for edits the library refuses
+func writeResearchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, agents.ErrResearchNotFound), errors.Is(err, agents.ErrMonitorNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case strings.HasPrefix(err.Error(), "no agent"), strings.HasPrefix(err.Error(), "failed to"):
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		writeError(w, http.StatusBadRequest, err)
+	}
+}
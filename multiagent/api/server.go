@@ -0,0 +1,648 @@
+// Package api exposes MultiAgentService over HTTP with JSON responses, so
+// the assistant can back a web or mobile frontend. The routes are described
+// by the OpenAPI spec served at /openapi.yaml.
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+	"github.com/kbutz/wikillm/multiagent/progress"
+	"github.com/kbutz/wikillm/multiagent/service"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+var logger = logging.For("api")
+
+// Service is the part of MultiAgentService the API serves
+type Service interface {
+	ProcessUserMessage(ctx context.Context, userID string, message string) (string, error)
+	ListAgents() []service.AgentInfo
+	GetSystemHealth() service.SystemHealthInfo
+	ListTasks(ctx context.Context) ([]*agents.PersonalTask, error)
+	GetMemoryStore() multiagent.MemoryStore
+	SubscribeProgress(userID string) (<-chan progress.Event, func())
+	ConversationHistory(ctx context.Context, userID string) (*service.ConversationHistory, error)
+	ListUsers(ctx context.Context) ([]service.UserInfo, error)
+	PurgeUser(ctx context.Context, userID string) (*service.PurgeResult, error)
+	ExportCalendar(ctx context.Context) ([]byte, error)
+	ImportCalendar(ctx context.Context, data []byte) (*agents.ICSImportResult, error)
+	ImportParticipantCalendar(ctx context.Context, name, email string, data []byte) (*agents.Participant, error)
+	ExportTasks(ctx context.Context, format string) ([]byte, error)
+	ImportTasks(ctx context.Context, format string, data []byte) (*agents.TaskImportResult, error)
+	ExportContacts(ctx context.Context, format string) ([]byte, error)
+	ImportContacts(ctx context.Context, format string, data []byte) (*agents.ContactImportResult, error)
+	ExportProjectTimeline(ctx context.Context, ref, format string) ([]byte, error)
+	ListEvents(ctx context.Context, from, to time.Time) ([]*agents.CalendarEvent, error)
+	RecentMessages(after int64, limit int) []service.MessageRecord
+	ListFacts(ctx context.Context, userID string, status memory.FactStatus) ([]memory.Fact, error)
+	ReviewFact(ctx context.Context, userID, factID string, approve bool) (*memory.Fact, error)
+	ListResearch(ctx context.Context, filter agents.ResearchFilter) ([]*agents.ResearchSession, error)
+	GetResearch(ctx context.Context, id string) (*agents.ResearchSession, error)
+	SearchResearchFindings(ctx context.Context, filter agents.ResearchFilter) ([]agents.FindingMatch, error)
+	AddResearchSource(ctx context.Context, id string, source agents.ResearchSource) (*agents.ResearchSession, error)
+	TagResearch(ctx context.Context, id, findingID string, tags []string) (*agents.ResearchSession, error)
+	ExportBibliography(ctx context.Context, id, format string) ([]byte, error)
+	MonitorResearch(ctx context.Context, id string, interval time.Duration) (*agents.ResearchMonitor, error)
+	ListResearchMonitors(ctx context.Context) ([]*agents.ResearchMonitor, error)
+	StopResearchMonitor(ctx context.Context, id string) error
+}
+
+// Server serves the REST API
+type Server struct {
+	service        Service
+	messageTimeout time.Duration
+	adminToken     string
+	userTokens     map[string]string
+	reload         func(ctx context.Context) (*service.ReloadResult, error)
+	mux            *http.ServeMux
+}
+
+// ServerConfig holds configuration for creating a Server
+type ServerConfig struct {
+	Service Service
+	// MessageTimeout bounds how long a message request waits for the
+	// assistant's reply (default 90 seconds)
+	MessageTimeout time.Duration
+	// AdminToken is the bearer token the /admin routes require; they are
+	// refused without one. It also lets a request act for the user in
+	// ?user=.
+	AdminToken string
+	// UserTokens maps the bearer tokens users authenticate with to their
+	// user IDs. Per-user routes act for the user the request's token
+	// belongs to, and are refused without one.
+	UserTokens map[string]string
+	// Reload, if set, reloads the server's configuration files for
+	// POST /admin/reload
+	Reload func(ctx context.Context) (*service.ReloadResult, error)
+}
+
+// LoadUserTokens reads ServerConfig.UserTokens from a JSON file mapping
+// each token to the user ID it authenticates
+func LoadUserTokens(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user tokens: %w", err)
+	}
+	var tokens map[string]string
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse user tokens %s: %w", path, err)
+	}
+	for token, userID := range tokens {
+		if token == "" || userID == "" {
+			return nil, fmt.Errorf("user tokens %s: tokens and user IDs must not be empty", path)
+		}
+	}
+	return tokens, nil
+}
+
+// MessageRequest is the body of POST /conversations/{id}/messages
+type MessageRequest struct {
+	Content string `json:"content"`
+}
+
+// MessageResponse is the assistant's reply to a MessageRequest
+type MessageResponse struct {
+	ConversationID string    `json:"conversation_id"`
+	Response       string    `json:"response"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// MemoryEntry is the body of GET /memory/{key}
+type MemoryEntry struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// Error is the body of every non-2xx response
+type Error struct {
+	Error string `json:"error"`
+}
+
+// NewServer creates a REST API server for config.Service
+func NewServer(config ServerConfig) *Server {
+	if config.MessageTimeout <= 0 {
+		config.MessageTimeout = 90 * time.Second
+	}
+
+	s := &Server{
+		service:        config.Service,
+		messageTimeout: config.MessageTimeout,
+		adminToken:     config.AdminToken,
+		userTokens:     config.UserTokens,
+		reload:         config.Reload,
+		mux:            http.NewServeMux(),
+	}
+	s.mux.HandleFunc("POST /conversations/{id}/messages", s.requireConversation(s.handlePostMessage))
+	s.mux.HandleFunc("GET /conversations/{id}/events", s.requireConversation(s.handleEvents))
+	s.mux.HandleFunc("GET /conversations/{id}/history", s.requireConversation(s.handleHistory))
+	s.mux.HandleFunc("GET /agents", s.handleListAgents)
+	s.mux.HandleFunc("GET /health", s.handleHealth)
+	s.mux.HandleFunc("GET /tasks", s.requireUser(s.handleListTasks))
+	s.mux.HandleFunc("GET /tasks/export", s.requireUser(s.handleExportTasks))
+	s.mux.HandleFunc("POST /tasks/import", s.requireUser(s.handleImportTasks))
+	s.mux.HandleFunc("GET /contacts/export", s.requireUser(s.handleExportContacts))
+	s.mux.HandleFunc("POST /contacts/import", s.requireUser(s.handleImportContacts))
+	s.mux.HandleFunc("GET /projects/{project}/timeline", s.requireUser(s.handleExportProjectTimeline))
+	s.mux.HandleFunc("GET /facts", s.requireUser(s.handleListFacts))
+	s.mux.HandleFunc("POST /facts/{id}/approve", s.requireUser(s.handleReviewFact(true)))
+	s.mux.HandleFunc("POST /facts/{id}/reject", s.requireUser(s.handleReviewFact(false)))
+	s.mux.HandleFunc("GET /research", s.requireUser(s.handleListResearch))
+	s.mux.HandleFunc("GET /research/findings", s.requireUser(s.handleSearchFindings))
+	s.mux.HandleFunc("GET /research/{id}", s.requireUser(s.handleGetResearch))
+	s.mux.HandleFunc("POST /research/{id}/sources", s.requireUser(s.handleAddResearchSource))
+	s.mux.HandleFunc("POST /research/{id}/tags", s.requireUser(s.handleTagResearch))
+	s.mux.HandleFunc("GET /research/{id}/bibliography", s.requireUser(s.handleExportBibliography))
+	s.mux.HandleFunc("POST /research/{id}/monitor", s.requireUser(s.handleMonitorResearch))
+	s.mux.HandleFunc("GET /research/monitors", s.requireUser(s.handleListResearchMonitors))
+	s.mux.HandleFunc("DELETE /research/monitors/{id}", s.requireUser(s.handleStopResearchMonitor))
+	s.mux.HandleFunc("GET /memory", s.requireUser(s.handleListMemory))
+	s.mux.HandleFunc("GET /memory/{key...}", s.requireUser(s.handleGetMemory))
+	s.mux.HandleFunc("GET /calendar.ics", s.requireUser(s.handleExportCalendar))
+	s.mux.HandleFunc("GET /calendar/events", s.requireUser(s.handleListEvents))
+	s.mux.HandleFunc("POST /calendar/import", s.requireUser(s.handleImportCalendar))
+	s.mux.HandleFunc("POST /calendar/participants/import", s.requireUser(s.handleImportParticipantCalendar))
+	s.mux.HandleFunc("GET /admin/users", s.requireAdmin(s.handleListUsers))
+	s.mux.HandleFunc("DELETE /admin/users/{id}", s.requireAdmin(s.handlePurgeUser))
+	s.mux.HandleFunc("POST /admin/reload", s.requireAdmin(s.handleReload))
+	s.mux.HandleFunc("GET /admin/messages", s.requireAdmin(s.handleRecentMessages))
+	s.mux.HandleFunc("GET /openapi.yaml", s.handleOpenAPI)
+	s.mux.Handle("GET /dashboard/", dashboardHandler())
+	s.mux.Handle("GET /dashboard", http.RedirectHandler("/dashboard/", http.StatusMovedPermanently))
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handlePostMessage(w http.ResponseWriter, r *http.Request) {
+	conversationID := r.PathValue("id")
+
+	var req MessageRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("content is required"))
+		return
+	}
+	if wantsEventStream(r) {
+		s.streamMessage(w, r, conversationID, req.Content)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.messageTimeout)
+	defer cancel()
+	ctx = logging.WithFields(ctx, logging.KeyConversationID, conversationID)
+
+	// ProcessUserMessage keys conversations by user, so each API
+	// conversation gets its own history
+	response, err := s.service.ProcessUserMessage(ctx, conversationID, req.Content)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to process message", "error", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, orchestrator.ErrMessageBlocked):
+			status = http.StatusForbidden
+		case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+			status = http.StatusGatewayTimeout
+		}
+		writeError(w, status, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, MessageResponse{
+		ConversationID: conversationID,
+		Response:       response,
+		CreatedAt:      time.Now(),
+	})
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := s.service.ConversationHistory(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+func (s *Server) handleListAgents(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.service.ListAgents())
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	health := s.service.GetSystemHealth()
+	status := http.StatusOK
+	if health.Status == string(multiagent.SystemStatusCritical) || health.Status == string(multiagent.SystemStatusOffline) {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, health)
+}
+
+func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
+	tasks, err := s.service.ListTasks(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		filtered := tasks[:0]
+		for _, task := range tasks {
+			if string(task.Status) == status {
+				filtered = append(filtered, task)
+			}
+		}
+		tasks = filtered
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+func (s *Server) handleGetMemory(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if key == "" {
+		writeError(w, http.StatusBadRequest, errors.New("key is required"))
+		return
+	}
+
+	value, err := s.service.GetMemoryStore().Get(r.Context(), key)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, MemoryEntry{Key: key, Value: value})
+}
+
+func (s *Server) handleListFacts(w http.ResponseWriter, r *http.Request) {
+	status := memory.FactStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", memory.FactPending, memory.FactApproved, memory.FactRejected:
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("status must be %s, %s or %s", memory.FactPending, memory.FactApproved, memory.FactRejected))
+		return
+	}
+	facts, err := s.service.ListFacts(r.Context(), multiagent.UserIDFromContext(r.Context()), status)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, facts)
+}
+
+// handleReviewFact approves or rejects a fact learned about the user
+func (s *Server) handleReviewFact(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fact, err := s.service.ReviewFact(r.Context(), multiagent.UserIDFromContext(r.Context()), r.PathValue("id"), approve)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				writeError(w, http.StatusNotFound, err)
+				return
+			}
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, fact)
+	}
+}
+
+func (s *Server) handleExportTasks(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = agents.TaskFormatTodoist
+	}
+	contentType, filename := "application/json", "tasks.json"
+	switch format {
+	case agents.TaskFormatTodoist:
+	case agents.TaskFormatCSV:
+		contentType, filename = "text/csv; charset=utf-8", "tasks.csv"
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("format must be %s or %s", agents.TaskFormatTodoist, agents.TaskFormatCSV))
+		return
+	}
+
+	data, err := s.service.ExportTasks(r.Context(), format)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(data)
+}
+
+func (s *Server) handleImportTasks(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		// Without a format, the body's type decides
+		format = agents.TaskFormatTodoist
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+			format = agents.TaskFormatCSV
+		}
+	}
+	if format != agents.TaskFormatTodoist && format != agents.TaskFormatCSV {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("format must be %s or %s", agents.TaskFormatTodoist, agents.TaskFormatCSV))
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 10<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("body must be a Todoist export or a CSV file"))
+		return
+	}
+
+	result, err := s.service.ImportTasks(r.Context(), format, data)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleExportContacts(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = agents.ContactFormatVCard
+	}
+	contentType, filename := "text/vcard; charset=utf-8", "contacts.vcf"
+	switch format {
+	case agents.ContactFormatVCard:
+	case agents.ContactFormatCSV:
+		contentType, filename = "text/csv; charset=utf-8", "contacts.csv"
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("format must be %s or %s", agents.ContactFormatVCard, agents.ContactFormatCSV))
+		return
+	}
+
+	data, err := s.service.ExportContacts(r.Context(), format)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(data)
+}
+
+func (s *Server) handleImportContacts(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		// Without a format, the body's type decides
+		format = agents.ContactFormatVCard
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+			format = agents.ContactFormatCSV
+		}
+	}
+	if format != agents.ContactFormatVCard && format != agents.ContactFormatCSV {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("format must be %s or %s", agents.ContactFormatVCard, agents.ContactFormatCSV))
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 10<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("body must be a vCard or a CSV file"))
+		return
+	}
+
+	result, err := s.service.ImportContacts(r.Context(), format, data)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleExportProjectTimeline(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = agents.TimelineFormatMermaid
+	}
+	contentType, extension := "text/plain; charset=utf-8", "mmd"
+	switch format {
+	case agents.TimelineFormatMermaid:
+	case agents.TimelineFormatCSV:
+		contentType, extension = "text/csv; charset=utf-8", "csv"
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("format must be %s or %s", agents.TimelineFormatMermaid, agents.TimelineFormatCSV))
+		return
+	}
+
+	ref := r.PathValue("project")
+	data, err := s.service.ExportProjectTimeline(r.Context(), ref, format)
+	if errors.Is(err, agents.ErrProjectNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "timeline."+extension))
+	w.Write(data)
+}
+
+func (s *Server) handleExportCalendar(w http.ResponseWriter, r *http.Request) {
+	data, err := s.service.ExportCalendar(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="calendar.ics"`)
+	w.Write(data)
+}
+
+func (s *Server) handleImportCalendar(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 10<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if !bytes.Contains(data, []byte("BEGIN:VCALENDAR")) {
+		writeError(w, http.StatusBadRequest, errors.New("body must be an iCalendar (.ics) file"))
+		return
+	}
+
+	result, err := s.service.ImportCalendar(r.Context(), data)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleImportParticipantCalendar(w http.ResponseWriter, r *http.Request) {
+	name, email := r.URL.Query().Get("name"), r.URL.Query().Get("email")
+	if name == "" && email == "" {
+		writeError(w, http.StatusBadRequest, errors.New("name or email is required"))
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 10<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if !bytes.Contains(data, []byte("BEGIN:VCALENDAR")) {
+		writeError(w, http.StatusBadRequest, errors.New("body must be an iCalendar (.ics) file"))
+		return
+	}
+
+	participant, err := s.service.ImportParticipantCalendar(r.Context(), name, email, data)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, participant)
+}
+
+func (s *Server) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := s.service.ListUsers(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+func (s *Server) handlePurgeUser(w http.ResponseWriter, r *http.Request) {
+	result, err := s.service.PurgeUser(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if s.reload == nil {
+		writeError(w, http.StatusNotImplemented, errors.New("configuration reload is not enabled"))
+		return
+	}
+	// Parts that failed are listed in the result alongside those reloaded
+	result, err := s.reload(r.Context())
+	switch {
+	case err != nil && result == nil:
+		writeError(w, http.StatusInternalServerError, err)
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, result)
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// requireAdmin rejects requests without the admin token, and every request
+// when none is set
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.adminToken == "" {
+			writeError(w, http.StatusForbidden, errors.New("admin routes are disabled: no admin token is set"))
+			return
+		}
+		if !s.isAdmin(r) {
+			writeError(w, http.StatusUnauthorized, errors.New("admin token required"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireUser runs next acting for the user r authenticates as: the user its
+// bearer token belongs to, or with the admin token the user in ?user=
+func (s *Server) requireUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, status, err := s.authenticate(r, r.URL.Query().Get("user"))
+		if err != nil {
+			writeError(w, status, err)
+			return
+		}
+		next(w, r.WithContext(multiagent.WithUserID(r.Context(), userID)))
+	}
+}
+
+// requireConversation runs next if r authenticates as the user whose
+// conversation is in the path; conversations are keyed by user ID
+func (s *Server) requireConversation(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, status, err := s.authenticate(r, r.PathValue("id"))
+		if err != nil {
+			writeError(w, status, err)
+			return
+		}
+		next(w, r.WithContext(multiagent.WithUserID(r.Context(), userID)))
+	}
+}
+
+// authenticate returns the user r acts for, which has to be requested
+// unless requested is empty: the owner of r's user token, or for the admin
+// token requested. It returns the status to refuse r with otherwise.
+func (s *Server) authenticate(r *http.Request, requested string) (string, int, error) {
+	if s.isAdmin(r) {
+		if requested == "" {
+			return "", http.StatusBadRequest, errors.New("user is required")
+		}
+		return requested, 0, nil
+	}
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	owner, ok := s.userTokens[token]
+	if token == "" || !ok {
+		return "", http.StatusUnauthorized, errors.New("a user or admin token is required")
+	}
+	if requested != "" && requested != owner {
+		return "", http.StatusForbidden, fmt.Errorf("the token does not belong to %s", requested)
+	}
+	return owner, 0, nil
+}
+
+// isAdmin reports whether r carries the admin token
+func (s *Server) isAdmin(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && s.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Warn("Failed to write response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, Error{Error: err.Error()})
+}
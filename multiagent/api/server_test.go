@@ -0,0 +1,1099 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+	"github.com/kbutz/wikillm/multiagent/progress"
+	"github.com/kbutz/wikillm/multiagent/service"
+)
+
+type fakeService struct {
+	hub      *progress.Hub
+	store    multiagent.MemoryStore
+	health   string
+	received map[string]string
+	purged   []string
+	reply    func(ctx context.Context) (string, error)
+	research *agents.ResearchAssistantAgent
+}
+
+func (f *fakeService) ProcessUserMessage(ctx context.Context, userID string, message string) (string, error) {
+	f.received[userID] = message
+	return f.reply(ctx)
+}
+
+func (f *fakeService) ListAgents() []service.AgentInfo {
+	return []service.AgentInfo{{ID: "task_manager_agent", Name: "Task Manager", Status: "idle"}}
+}
+
+func (f *fakeService) GetSystemHealth() service.SystemHealthInfo {
+	return service.SystemHealthInfo{Status: f.health, TotalAgents: 1}
+}
+
+func (f *fakeService) ListTasks(ctx context.Context) ([]*agents.PersonalTask, error) {
+	return []*agents.PersonalTask{
+		{ID: "task_1", Title: "File taxes", Status: agents.PersonalTaskStatusNext},
+		{ID: "task_2", Title: "Buy milk", Status: agents.PersonalTaskStatusCompleted},
+	}, nil
+}
+
+func (f *fakeService) GetMemoryStore() multiagent.MemoryStore {
+	return memory.PartitionByUser(f.store)
+}
+
+func (f *fakeService) SubscribeProgress(userID string) (<-chan progress.Event, func()) {
+	return f.hub.Subscribe(userID)
+}
+
+func (f *fakeService) ConversationHistory(ctx context.Context, userID string) (*service.ConversationHistory, error) {
+	history := &service.ConversationHistory{ConversationID: "conv_" + userID, UserID: userID}
+	if message, ok := f.received[userID]; ok {
+		history.Messages = []service.TranscriptEntry{{Role: "user", Content: message}}
+	}
+	return history, nil
+}
+
+func (f *fakeService) ListUsers(ctx context.Context) ([]service.UserInfo, error) {
+	return []service.UserInfo{{ID: "alice", Messages: 3}}, nil
+}
+
+func (f *fakeService) PurgeUser(ctx context.Context, userID string) (*service.PurgeResult, error) {
+	f.purged = append(f.purged, userID)
+	return &service.PurgeResult{UserID: userID, MemoryEntries: 2}, nil
+}
+
+func (f *fakeService) ExportCalendar(ctx context.Context) ([]byte, error) {
+	return []byte("BEGIN:VCALENDAR\r\nX-WR-CALNAME:" + multiagent.UserIDFromContext(ctx) + "\r\nEND:VCALENDAR\r\n"), nil
+}
+
+func (f *fakeService) ImportCalendar(ctx context.Context, data []byte) (*agents.ICSImportResult, error) {
+	f.received[multiagent.UserIDFromContext(ctx)] = string(data)
+	return &agents.ICSImportResult{Imported: 1}, nil
+}
+
+func (f *fakeService) ImportParticipantCalendar(ctx context.Context, name, email string, data []byte) (*agents.Participant, error) {
+	f.received[multiagent.UserIDFromContext(ctx)] = string(data)
+	return &agents.Participant{ID: email, Name: name, Email: email}, nil
+}
+
+func (f *fakeService) ExportTasks(ctx context.Context, format string) ([]byte, error) {
+	return []byte(format + ":" + multiagent.UserIDFromContext(ctx)), nil
+}
+
+func (f *fakeService) ImportTasks(ctx context.Context, format string, data []byte) (*agents.TaskImportResult, error) {
+	f.received[multiagent.UserIDFromContext(ctx)] = format + ":" + string(data)
+	return &agents.TaskImportResult{Imported: 2}, nil
+}
+
+func (f *fakeService) ExportContacts(ctx context.Context, format string) ([]byte, error) {
+	return []byte(format + ":" + multiagent.UserIDFromContext(ctx)), nil
+}
+
+func (f *fakeService) ImportContacts(ctx context.Context, format string, data []byte) (*agents.ContactImportResult, error) {
+	f.received[multiagent.UserIDFromContext(ctx)] = format + ":" + string(data)
+	return &agents.ContactImportResult{Imported: 1, Duplicates: []agents.ContactMerge{{ID: "merge_1_0", Reason: "same email jane@example.com"}}}, nil
+}
+
+func (f *fakeService) ExportProjectTimeline(ctx context.Context, ref, format string) ([]byte, error) {
+	if ref != "website" {
+		return nil, fmt.Errorf("%w: %q", agents.ErrProjectNotFound, ref)
+	}
+	return []byte(format + ":" + ref + ":" + multiagent.UserIDFromContext(ctx)), nil
+}
+
+func (f *fakeService) ListEvents(ctx context.Context, from, to time.Time) ([]*agents.CalendarEvent, error) {
+	start := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	if !start.Before(to) || start.Add(time.Hour).Before(from) {
+		return []*agents.CalendarEvent{}, nil
+	}
+	return []*agents.CalendarEvent{{ID: "event_1", Title: "Dentist", StartTime: start, EndTime: start.Add(time.Hour)}}, nil
+}
+
+func (f *fakeService) RecentMessages(after int64, limit int) []service.MessageRecord {
+	records := []service.MessageRecord{}
+	for seq := after + 1; seq <= 3 && len(records) < limit; seq++ {
+		records = append(records, service.MessageRecord{Seq: seq, From: "conversation_agent", To: []multiagent.AgentID{"task_manager_agent"}})
+	}
+	return records
+}
+
+func (f *fakeService) ListFacts(ctx context.Context, userID string, status memory.FactStatus) ([]memory.Fact, error) {
+	return memory.LoadFacts(multiagent.WithUserID(ctx, userID), memory.PartitionByUser(f.store), status)
+}
+
+func (f *fakeService) ReviewFact(ctx context.Context, userID, factID string, approve bool) (*memory.Fact, error) {
+	status := memory.FactRejected
+	if approve {
+		status = memory.FactApproved
+	}
+	fact, err := memory.ReviewFact(multiagent.WithUserID(ctx, userID), memory.PartitionByUser(f.store), factID, status, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &fact, nil
+}
+
+func (f *fakeService) ListResearch(ctx context.Context, filter agents.ResearchFilter) ([]*agents.ResearchSession, error) {
+	return f.research.ListResearch(ctx, filter)
+}
+
+func (f *fakeService) GetResearch(ctx context.Context, id string) (*agents.ResearchSession, error) {
+	return f.research.GetResearch(ctx, id)
+}
+
+func (f *fakeService) SearchResearchFindings(ctx context.Context, filter agents.ResearchFilter) ([]agents.FindingMatch, error) {
+	return f.research.SearchFindings(ctx, filter)
+}
+
+func (f *fakeService) AddResearchSource(ctx context.Context, id string, source agents.ResearchSource) (*agents.ResearchSession, error) {
+	return f.research.AddResearchSource(ctx, id, source)
+}
+
+func (f *fakeService) TagResearch(ctx context.Context, id, findingID string, tags []string) (*agents.ResearchSession, error) {
+	return f.research.TagResearch(ctx, id, findingID, tags)
+}
+
+func (f *fakeService) ExportBibliography(ctx context.Context, id, format string) ([]byte, error) {
+	return f.research.ExportBibliography(ctx, id, format)
+}
+
+func (f *fakeService) MonitorResearch(ctx context.Context, id string, interval time.Duration) (*agents.ResearchMonitor, error) {
+	return f.research.MonitorResearch(ctx, id, interval)
+}
+
+func (f *fakeService) ListResearchMonitors(ctx context.Context) ([]*agents.ResearchMonitor, error) {
+	return f.research.ListMonitors(ctx)
+}
+
+func (f *fakeService) StopResearchMonitor(ctx context.Context, id string) error {
+	return f.research.StopMonitor(ctx, id)
+}
+
+func newTestServer(t *testing.T) (*fakeService, *httptest.Server) {
+	t.Helper()
+	store, err := memory.NewSQLiteMemoryStore(filepath.Join(t.TempDir(), "memory.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	fake := &fakeService{
+		hub:      progress.NewHub(),
+		store:    store,
+		health:   string(multiagent.SystemStatusHealthy),
+		received: make(map[string]string),
+		reply:    func(ctx context.Context) (string, error) { return "Added it to your list.", nil },
+	}
+	fake.research = agents.NewResearchAssistantAgent(agents.BaseAgentConfig{
+		ID:          "research_assistant_agent",
+		Type:        multiagent.AgentTypeResearch,
+		MemoryStore: memory.PartitionByUser(store),
+	})
+	handler := NewServer(ServerConfig{
+		Service:        fake,
+		MessageTimeout: 200 * time.Millisecond,
+		AdminToken:     "s3cret",
+		UserTokens:     map[string]string{"alice-token": "alice"},
+	})
+	// Requests act as the admin unless they authenticate otherwise
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer s3cret")
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func decode(t *testing.T, resp *http.Response, into interface{}) {
+	t.Helper()
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+}
+
+func TestPostMessage(t *testing.T) {
+	fake, server := newTestServer(t)
+
+	resp, err := http.Post(server.URL+"/conversations/alice/messages", "application/json",
+		strings.NewReader(`{"content":"remind me to file taxes"}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	var body MessageResponse
+	decode(t, resp, &body)
+	if body.ConversationID != "alice" || body.Response != "Added it to your list." {
+		t.Errorf("unexpected response %+v", body)
+	}
+	if fake.received["alice"] != "remind me to file taxes" {
+		t.Errorf("service received %v", fake.received)
+	}
+
+	var history service.ConversationHistory
+	resp, err = http.Get(server.URL + "/conversations/alice/history")
+	if err != nil {
+		t.Fatalf("GET history: %v", err)
+	}
+	decode(t, resp, &history)
+	if history.UserID != "alice" || len(history.Messages) != 1 || history.Messages[0].Content != "remind me to file taxes" {
+		t.Errorf("unexpected history %+v", history)
+	}
+
+	resp, err = http.Post(server.URL+"/conversations/alice/messages", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	var apiErr Error
+	decode(t, resp, &apiErr)
+	if resp.StatusCode != http.StatusBadRequest || apiErr.Error == "" {
+		t.Errorf("empty content: status %d, error %q", resp.StatusCode, apiErr.Error)
+	}
+}
+
+func TestPostMessageTimesOut(t *testing.T) {
+	fake, server := newTestServer(t)
+	fake.reply = func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	resp, err := http.Post(server.URL+"/conversations/bob/messages", "application/json",
+		strings.NewReader(`{"content":"plan my week"}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want 504", resp.StatusCode)
+	}
+}
+
+func TestPostMessageBlockedByPolicy(t *testing.T) {
+	fake, server := newTestServer(t)
+	fake.reply = func(ctx context.Context) (string, error) {
+		return "", fmt.Errorf("failed to route message: %w: no passwords", orchestrator.ErrMessageBlocked)
+	}
+
+	resp, err := http.Post(server.URL+"/conversations/bob/messages", "application/json",
+		strings.NewReader(`{"content":"my password is hunter2"}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestPostMessageStreamsProgress(t *testing.T) {
+	fake, server := newTestServer(t)
+	fake.reply = func(ctx context.Context) (string, error) {
+		fake.hub.Publish(progress.Event{Type: progress.AgentStarted, ConversationID: "carol", AgentID: "scheduler_agent"})
+		fake.hub.Publish(progress.Event{Type: progress.AgentStarted, ConversationID: "someone_else"})
+		fake.hub.Publish(progress.Event{Type: progress.PartialResult, ConversationID: "carol", Detail: "Tuesday is free"})
+		return "Booked Tuesday at 10.", nil
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/conversations/carol/messages",
+		strings.NewReader(`{"content":"book a dentist appointment"}`))
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	var names []string
+	var last string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			names = append(names, name)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			last = data
+		}
+	}
+
+	want := []string{"agent_started", "partial_result", "response"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v, want %v", names, want)
+	}
+	var reply MessageResponse
+	if err := json.Unmarshal([]byte(last), &reply); err != nil || reply.Response != "Booked Tuesday at 10." {
+		t.Errorf("unexpected final event %q (%v)", last, err)
+	}
+}
+
+func TestReadEndpoints(t *testing.T) {
+	fake, server := newTestServer(t)
+	aliceCtx := multiagent.WithUserID(context.Background(), "alice")
+	if err := fake.GetMemoryStore().Store(aliceCtx, "contact:ada", map[string]interface{}{"name": "Ada"}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	var tasks []agents.PersonalTask
+	resp, err := http.Get(server.URL + "/tasks?user=alice&status=next")
+	if err != nil {
+		t.Fatalf("GET /tasks: %v", err)
+	}
+	decode(t, resp, &tasks)
+	if len(tasks) != 1 || tasks[0].ID != "task_1" {
+		t.Errorf("expected only the next task, got %+v", tasks)
+	}
+
+	var agentList []service.AgentInfo
+	resp, err = http.Get(server.URL + "/agents")
+	if err != nil {
+		t.Fatalf("GET /agents: %v", err)
+	}
+	decode(t, resp, &agentList)
+	if len(agentList) != 1 || agentList[0].ID != "task_manager_agent" {
+		t.Errorf("unexpected agents %+v", agentList)
+	}
+
+	var entry MemoryEntry
+	resp, err = http.Get(server.URL + "/memory/contact:ada?user=alice")
+	if err != nil {
+		t.Fatalf("GET /memory: %v", err)
+	}
+	decode(t, resp, &entry)
+	if value, _ := entry.Value.(map[string]interface{}); value["name"] != "Ada" {
+		t.Errorf("unexpected memory entry %+v", entry)
+	}
+
+	resp, err = http.Get(server.URL + "/memory/contact:nobody?user=alice")
+	if err != nil {
+		t.Fatalf("GET /memory: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("missing key: status = %d, want 404", resp.StatusCode)
+	}
+
+	if err := fake.GetMemoryStore().Store(aliceCtx, "contact:bea", map[string]interface{}{"name": "Bea"}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	resp, err = http.Get(server.URL + "/memory/contact:bea?user=alice")
+	if err != nil {
+		t.Fatalf("GET /memory: %v", err)
+	}
+	decode(t, resp, &entry)
+	if value, _ := entry.Value.(map[string]interface{}); value["name"] != "Bea" {
+		t.Errorf("unexpected memory entry for alice %+v", entry)
+	}
+	for _, path := range []string{"/memory/contact:bea?user=bob"} {
+		resp, err = http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404 for another user's entry", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestTaskExchange(t *testing.T) {
+	fake, server := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/tasks/export?user=alice&format=csv")
+	if err != nil {
+		t.Fatalf("GET /tasks/export: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type = %q, want text/csv", ct)
+	}
+	if string(body) != "csv:alice" {
+		t.Errorf("expected alice's tasks as CSV, got %q", body)
+	}
+
+	resp, err = http.Get(server.URL + "/tasks/export?format=xml")
+	if err != nil {
+		t.Fatalf("GET /tasks/export: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 for an unknown format", resp.StatusCode)
+	}
+
+	csv := "title,due\nFile taxes,2026-04-15\n"
+	resp, err = http.Post(server.URL+"/tasks/import?user=alice", "text/csv", strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("POST /tasks/import: %v", err)
+	}
+	var result agents.TaskImportResult
+	decode(t, resp, &result)
+	if result.Imported != 2 || fake.received["alice"] != "csv:"+csv {
+		t.Errorf("unexpected import %+v of %q", result, fake.received["alice"])
+	}
+
+	resp, err = http.Post(server.URL+"/tasks/import?user=alice", "application/json", strings.NewReader(" "))
+	if err != nil {
+		t.Fatalf("POST /tasks/import: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 for an empty body", resp.StatusCode)
+	}
+}
+
+func TestContactExchange(t *testing.T) {
+	fake, server := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/contacts/export?user=alice")
+	if err != nil {
+		t.Fatalf("GET /contacts/export: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/vcard") {
+		t.Errorf("Content-Type = %q, want text/vcard", ct)
+	}
+	if string(body) != "vcard:alice" {
+		t.Errorf("expected alice's contacts as vCard, got %q", body)
+	}
+
+	csv := "First Name,E-mail 1 - Value\nJane,jane@example.com\n"
+	resp, err = http.Post(server.URL+"/contacts/import?user=alice", "text/csv", strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("POST /contacts/import: %v", err)
+	}
+	var result agents.ContactImportResult
+	decode(t, resp, &result)
+	if result.Imported != 1 || len(result.Duplicates) != 1 || fake.received["alice"] != "csv:"+csv {
+		t.Errorf("unexpected import %+v of %q", result, fake.received["alice"])
+	}
+
+	resp, err = http.Post(server.URL+"/contacts/import?format=ldif", "text/plain", strings.NewReader("dn: x"))
+	if err != nil {
+		t.Fatalf("POST /contacts/import: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 for an unknown format", resp.StatusCode)
+	}
+}
+
+func TestExportProjectTimeline(t *testing.T) {
+	_, server := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/projects/website/timeline?user=alice")
+	if err != nil {
+		t.Fatalf("GET /projects/website/timeline: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "mermaid:website:alice" {
+		t.Errorf("expected alice's website timeline as Mermaid, got %q", body)
+	}
+
+	resp, err = http.Get(server.URL + "/projects/website/timeline?user=alice&format=csv")
+	if err != nil {
+		t.Fatalf("GET /projects/website/timeline: %v", err)
+	}
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type = %q, want text/csv", ct)
+	}
+
+	for path, want := range map[string]int{
+		"/projects/website/timeline?user=alice&format=png": http.StatusBadRequest,
+		"/projects/garden/timeline?user=alice":             http.StatusNotFound,
+	} {
+		resp, err = http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s status = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestCalendarExchange(t *testing.T) {
+	fake, server := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/calendar.ics?user=alice")
+	if err != nil {
+		t.Fatalf("GET /calendar.ics: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("Content-Type = %q, want text/calendar", ct)
+	}
+	if !strings.Contains(string(body), "X-WR-CALNAME:alice") {
+		t.Errorf("expected alice's calendar, got %q", body)
+	}
+
+	ics := "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"
+	resp, err = http.Post(server.URL+"/calendar/import?user=alice", "text/calendar", strings.NewReader(ics))
+	if err != nil {
+		t.Fatalf("POST /calendar/import: %v", err)
+	}
+	var result agents.ICSImportResult
+	decode(t, resp, &result)
+	if result.Imported != 1 || fake.received["alice"] != ics {
+		t.Errorf("unexpected import %+v of %q", result, fake.received["alice"])
+	}
+
+	resp, err = http.Post(server.URL+"/calendar/import", "text/calendar", strings.NewReader("not a calendar"))
+	if err != nil {
+		t.Fatalf("POST /calendar/import: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 for a body that is not a calendar", resp.StatusCode)
+	}
+
+	resp, err = http.Post(server.URL+"/calendar/participants/import?user=alice&name=Bob&email=bob@example.com", "text/calendar", strings.NewReader(ics))
+	if err != nil {
+		t.Fatalf("POST /calendar/participants/import: %v", err)
+	}
+	var participant agents.Participant
+	decode(t, resp, &participant)
+	if participant.Name != "Bob" || participant.Email != "bob@example.com" {
+		t.Errorf("unexpected participant %+v", participant)
+	}
+
+	resp, err = http.Post(server.URL+"/calendar/participants/import", "text/calendar", strings.NewReader(ics))
+	if err != nil {
+		t.Fatalf("POST /calendar/participants/import: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 without a participant", resp.StatusCode)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	_, server := newTestServer(t)
+	get := func(path, token string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	tests := []struct {
+		path  string
+		token string
+		want  int
+	}{
+		{"/tasks", "alice-token", http.StatusOK},
+		{"/tasks?user=alice", "alice-token", http.StatusOK},
+		{"/tasks?user=bob", "alice-token", http.StatusForbidden},
+		{"/tasks?user=bob", "guess", http.StatusUnauthorized},
+		{"/tasks?user=bob", "s3cret", http.StatusOK},
+		{"/tasks", "s3cret", http.StatusBadRequest},
+		{"/conversations/alice/history", "alice-token", http.StatusOK},
+		{"/conversations/bob/history", "alice-token", http.StatusForbidden},
+		{"/conversations/bob/history", "s3cret", http.StatusOK},
+		{"/admin/users", "alice-token", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		if status := get(test.path, test.token); status != test.want {
+			t.Errorf("GET %s with %s: status = %d, want %d", test.path, test.token, status, test.want)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/agents")
+	if err != nil {
+		t.Fatalf("GET /agents: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /agents: status = %d, want 200 without a token", resp.StatusCode)
+	}
+}
+
+func TestAdminRoutesNeedAToken(t *testing.T) {
+	fake, _ := newTestServer(t)
+	server := httptest.NewServer(NewServer(ServerConfig{Service: fake}))
+	defer server.Close()
+
+	for _, path := range []string{"/admin/users", "/tasks?user=alice"} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer ")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("GET %s without tokens configured: status = %d, want it refused", path, resp.StatusCode)
+		}
+	}
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/admin/users/alice", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE /admin/users: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || len(fake.purged) != 0 {
+		t.Errorf("DELETE /admin/users without an admin token: status = %d, purged %v", resp.StatusCode, fake.purged)
+	}
+}
+
+func TestAdminUsers(t *testing.T) {
+	fake, _ := newTestServer(t)
+	server := httptest.NewServer(NewServer(ServerConfig{Service: fake, AdminToken: "s3cret"}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/admin/users")
+	if err != nil {
+		t.Fatalf("GET /admin/users: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("without the token: status = %d, want 401", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/admin/users", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /admin/users: %v", err)
+	}
+	var users []service.UserInfo
+	decode(t, resp, &users)
+	if len(users) != 1 || users[0].ID != "alice" {
+		t.Errorf("unexpected users %+v", users)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, server.URL+"/admin/users/alice", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE /admin/users: %v", err)
+	}
+	var result service.PurgeResult
+	decode(t, resp, &result)
+	if result.UserID != "alice" || len(fake.purged) != 1 || fake.purged[0] != "alice" {
+		t.Errorf("unexpected purge %+v, purged %v", result, fake.purged)
+	}
+}
+
+func TestAdminReload(t *testing.T) {
+	fake, _ := newTestServer(t)
+	post := func(server *httptest.Server) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/admin/reload", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST /admin/reload: %v", err)
+		}
+		return resp
+	}
+
+	disabled := httptest.NewServer(NewServer(ServerConfig{Service: fake, AdminToken: "s3cret"}))
+	defer disabled.Close()
+	if resp := post(disabled); resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("without Reload: status = %d, want 501", resp.StatusCode)
+	}
+
+	failPrompts := false
+	server := httptest.NewServer(NewServer(ServerConfig{Service: fake, AdminToken: "s3cret", Reload: func(ctx context.Context) (*service.ReloadResult, error) {
+		if failPrompts {
+			return &service.ReloadResult{Reloaded: []string{"llm"}, Errors: []string{"bad template"}}, errors.New("bad template")
+		}
+		return &service.ReloadResult{Reloaded: []string{"llm", "prompts"}}, nil
+	}}))
+	defer server.Close()
+
+	var result service.ReloadResult
+	decode(t, post(server), &result)
+	if len(result.Reloaded) != 2 || len(result.Errors) != 0 {
+		t.Errorf("unexpected reload result %+v", result)
+	}
+
+	failPrompts = true
+	resp := post(server)
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("with a failed part: status = %d, want 500", resp.StatusCode)
+	}
+	result = service.ReloadResult{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	resp.Body.Close()
+	if len(result.Reloaded) != 1 || len(result.Errors) != 1 {
+		t.Errorf("unexpected partial reload result %+v", result)
+	}
+}
+
+func TestHealthStatusCodes(t *testing.T) {
+	fake, server := newTestServer(t)
+
+	for health, want := range map[multiagent.SystemStatus]int{
+		multiagent.SystemStatusHealthy:  http.StatusOK,
+		multiagent.SystemStatusDegraded: http.StatusOK,
+		multiagent.SystemStatusCritical: http.StatusServiceUnavailable,
+	} {
+		fake.health = string(health)
+		resp, err := http.Get(server.URL + "/health")
+		if err != nil {
+			t.Fatalf("GET /health: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: status = %d, want %d", health, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	_, server := newTestServer(t)
+
+	for _, path := range []string{"/dashboard/", "/dashboard/dashboard.js", "/dashboard/dashboard.css"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: status = %d, want 200", path, resp.StatusCode)
+		}
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(server.URL + "/dashboard")
+	if err != nil {
+		t.Fatalf("GET /dashboard: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != "/dashboard/" {
+		t.Errorf("GET /dashboard: status = %d, Location = %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+}
+
+func TestListEvents(t *testing.T) {
+	_, server := newTestServer(t)
+
+	var events []agents.CalendarEvent
+	resp, err := http.Get(server.URL + "/calendar/events?user=alice&from=2025-03-10&to=2025-03-11")
+	if err != nil {
+		t.Fatalf("GET /calendar/events: %v", err)
+	}
+	decode(t, resp, &events)
+	if len(events) != 1 || events[0].ID != "event_1" {
+		t.Errorf("unexpected events %+v", events)
+	}
+
+	resp, err = http.Get(server.URL + "/calendar/events?user=alice&from=next+week")
+	if err != nil {
+		t.Fatalf("GET /calendar/events: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("with a bad from: status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestRecentMessages(t *testing.T) {
+	fake, _ := newTestServer(t)
+	server := httptest.NewServer(NewServer(ServerConfig{Service: fake, AdminToken: "s3cret"}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/admin/messages")
+	if err != nil {
+		t.Fatalf("GET /admin/messages: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("without the token: status = %d, want 401", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/admin/messages?after=1", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /admin/messages: %v", err)
+	}
+	var records []service.MessageRecord
+	decode(t, resp, &records)
+	if len(records) != 2 || records[0].Seq != 2 {
+		t.Errorf("unexpected messages %+v", records)
+	}
+}
+
+func TestFactReview(t *testing.T) {
+	fake, server := newTestServer(t)
+	alice := multiagent.WithUserID(context.Background(), "alice")
+	for _, fact := range []memory.Fact{
+		{ID: "fact_1", Kind: memory.FactPreference, Statement: "Prefers morning meetings", Status: memory.FactPending},
+		{ID: "fact_2", Kind: memory.FactName, Statement: "Sister is named Maya", Status: memory.FactPending},
+	} {
+		if err := memory.SaveFact(alice, memory.PartitionByUser(fake.store), fact); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := http.Post(server.URL+"/facts/fact_1/approve?user=alice", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST /facts/fact_1/approve: %v", err)
+	}
+	var approved memory.Fact
+	decode(t, resp, &approved)
+	if approved.Status != memory.FactApproved || approved.ReviewedAt == nil {
+		t.Errorf("approved fact %+v", approved)
+	}
+
+	resp, err = http.Get(server.URL + "/facts?user=alice&status=pending")
+	if err != nil {
+		t.Fatalf("GET /facts: %v", err)
+	}
+	var pending []memory.Fact
+	decode(t, resp, &pending)
+	if len(pending) != 1 || pending[0].ID != "fact_2" {
+		t.Errorf("pending facts %+v, want fact_2", pending)
+	}
+
+	resp, err = http.Get(server.URL + "/facts?user=bob")
+	if err != nil {
+		t.Fatalf("GET /facts: %v", err)
+	}
+	var others []memory.Fact
+	decode(t, resp, &others)
+	if len(others) != 0 {
+		t.Errorf("bob sees alice's facts: %+v", others)
+	}
+
+	for path, want := range map[string]int{
+		"/facts/fact_9/reject?user=alice": http.StatusNotFound,
+		"/facts/fact_2/reject?user=bob":   http.StatusNotFound,
+	} {
+		resp, err := http.Post(server.URL+path, "application/json", nil)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("POST %s: status = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+	resp, err = http.Get(server.URL + "/facts?status=maybe")
+	if err != nil {
+		t.Fatalf("GET /facts: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown status: status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestResearchLibrary(t *testing.T) {
+	fake, server := newTestServer(t)
+	alice := multiagent.WithUserID(context.Background(), "alice")
+	created := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	session := &agents.ResearchSession{
+		ID: "research_1", Topic: "Heat pumps for cold climates", Status: agents.ResearchStatusCompleted,
+		CreatedAt: created, UpdatedAt: created, UserID: "alice",
+		Findings: []agents.ResearchFinding{{ID: "finding_1", Finding: "Cold-climate models keep working at -25C"}},
+	}
+	if err := memory.PartitionByUser(fake.store).Store(alice, "research_session:research_1", session); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	post := func(path, body string) *http.Response {
+		t.Helper()
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		return resp
+	}
+	list := func(path string) []agents.ResearchSession {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		var sessions []agents.ResearchSession
+		decode(t, resp, &sessions)
+		return sessions
+	}
+
+	if sessions := list("/research?user=alice&q=heat+pumps"); len(sessions) != 1 || sessions[0].ID != "research_1" {
+		t.Fatalf("alice's research %+v, want the heat pump session", sessions)
+	}
+	if sessions := list("/research?user=bob"); len(sessions) != 0 {
+		t.Errorf("bob sees alice's research: %+v", sessions)
+	}
+
+	resp := post("/research/research_1/sources?user=alice", `{"url": "https://en.wikipedia.org/wiki/Heat_pump"}`)
+	var updated agents.ResearchSession
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("add source status = %d", resp.StatusCode)
+	}
+	decode(t, resp, &updated)
+	if len(updated.Sources) != 1 || updated.Sources[0].Title != "Heat pump" || updated.Sources[0].Type != agents.SourceTypeEncyclopedia {
+		t.Errorf("sources %+v, want the Wikipedia page as an encyclopedia", updated.Sources)
+	}
+	if resp := post("/research/research_1/sources?user=alice", `{"url": "https://en.wikipedia.org/wiki/Heat_pump"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("duplicate source status = %d, want 400", resp.StatusCode)
+	}
+
+	post("/research/research_1/tags?user=alice", `{"tags": ["Energy"]}`).Body.Close()
+	post("/research/research_1/tags?user=alice", `{"tags": ["key"], "finding": "finding_1"}`).Body.Close()
+	if sessions := list("/research?user=alice&tag=energy"); len(sessions) != 1 {
+		t.Errorf("research tagged energy %+v, want the session", sessions)
+	}
+	resp, err := http.Get(server.URL + "/research/findings?user=alice&tag=key&q=-25C")
+	if err != nil {
+		t.Fatalf("GET /research/findings: %v", err)
+	}
+	var findings []agents.FindingMatch
+	decode(t, resp, &findings)
+	if len(findings) != 1 || findings[0].SessionID != "research_1" || findings[0].Finding.ID != "finding_1" {
+		t.Errorf("findings %+v, want the tagged finding", findings)
+	}
+
+	resp, err = http.Get(server.URL + "/research/research_1/bibliography?user=alice")
+	if err != nil {
+		t.Fatalf("GET bibliography: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/x-bibtex") || !strings.Contains(string(body), "@misc{wikipediaheat,") {
+		t.Errorf("BibTeX (%s):\n%s", ct, body)
+	}
+	resp, err = http.Get(server.URL + "/research/research_1/bibliography?user=alice&format=markdown")
+	if err != nil {
+		t.Fatalf("GET bibliography: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "1. [Heat pump](https://en.wikipedia.org/wiki/Heat_pump). Wikipedia.") {
+		t.Errorf("Markdown bibliography:\n%s", body)
+	}
+
+	for path, want := range map[string]int{
+		"/research/research_1/bibliography?user=alice&format=ris": http.StatusBadRequest,
+		"/research/research_1/bibliography?user=bob":              http.StatusNotFound,
+		"/research/research_2?user=alice":                         http.StatusNotFound,
+	} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s status = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestResearchMonitors(t *testing.T) {
+	fake, server := newTestServer(t)
+	alice := multiagent.WithUserID(context.Background(), "alice")
+	session := &agents.ResearchSession{
+		ID: "research_1", Topic: "Heat pumps for cold climates", Query: "heat pumps cold climates",
+		Status: agents.ResearchStatusCompleted, UserID: "alice",
+		Findings: []agents.ResearchFinding{{ID: "finding_1", Finding: "Cold-climate models keep working at -25C"}},
+	}
+	if err := memory.PartitionByUser(fake.store).Store(alice, "research_session:research_1", session); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	for body, want := range map[string]int{
+		`{"interval": "10m"}`:   http.StatusBadRequest,
+		`{"interval": "often"}`: http.StatusBadRequest,
+	} {
+		resp, err := http.Post(server.URL+"/research/research_1/monitor?user=alice", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST monitor: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("monitor with %s status = %d, want %d", body, resp.StatusCode, want)
+		}
+	}
+
+	resp, err := http.Post(server.URL+"/research/research_1/monitor?user=alice", "application/json", strings.NewReader(`{"interval": "12h"}`))
+	if err != nil {
+		t.Fatalf("POST monitor: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("monitor status = %d", resp.StatusCode)
+	}
+	var monitor agents.ResearchMonitor
+	decode(t, resp, &monitor)
+	if monitor.Interval != 12*time.Hour || monitor.Query != "heat pumps cold climates" || len(monitor.Known) != 1 {
+		t.Errorf("monitor %+v, want the session's query every 12h with its finding known", monitor)
+	}
+
+	list := func(user string) []agents.ResearchMonitor {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/research/monitors?user=" + user)
+		if err != nil {
+			t.Fatalf("GET /research/monitors: %v", err)
+		}
+		var monitors []agents.ResearchMonitor
+		decode(t, resp, &monitors)
+		return monitors
+	}
+	if monitors := list("alice"); len(monitors) != 1 || monitors[0].ID != monitor.ID {
+		t.Errorf("alice's monitors %+v", monitors)
+	}
+	if monitors := list("bob"); len(monitors) != 0 {
+		t.Errorf("bob sees alice's monitors: %+v", monitors)
+	}
+
+	stop := func(user string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodDelete, server.URL+"/research/monitors/"+monitor.ID+"?user="+user, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("DELETE monitor: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := stop("bob"); status != http.StatusNotFound {
+		t.Errorf("bob stopping alice's monitor status = %d, want 404", status)
+	}
+	if status := stop("alice"); status != http.StatusNoContent {
+		t.Errorf("stop status = %d, want 204", status)
+	}
+	if monitors := list("alice"); len(monitors) != 0 {
+		t.Errorf("monitors after stopping %+v", monitors)
+	}
+}
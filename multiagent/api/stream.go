@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/progress"
+)
+
+// heartbeatInterval keeps idle event streams open through proxies
+const heartbeatInterval = 15 * time.Second
+
+// sseWriter writes Server-Sent Events
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &sseWriter{w: w, flusher: flusher}, true
+}
+
+func (s *sseWriter) event(name string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *sseWriter) heartbeat() error {
+	if _, err := fmt.Fprint(s.w, ": keepalive\n\n"); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func wantsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// handleEvents streams progress for a conversation until the client
+// disconnects
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	events, unsubscribe := s.service.SubscribeProgress(r.PathValue("id"))
+	defer unsubscribe()
+
+	stream, ok := newSSEWriter(w)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+	s.pumpEvents(r.Context(), stream, events)
+}
+
+// streamMessage processes a message while streaming its progress, then
+// sends the reply as a "response" event (or an "error" event)
+func (s *Server) streamMessage(w http.ResponseWriter, r *http.Request, conversationID string, content string) {
+	// Subscribe before sending so no early events are missed
+	events, unsubscribe := s.service.SubscribeProgress(conversationID)
+	defer unsubscribe()
+
+	stream, ok := newSSEWriter(w)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.messageTimeout)
+	defer cancel()
+	ctx = logging.WithFields(ctx, logging.KeyConversationID, conversationID)
+
+	type result struct {
+		response string
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := s.service.ProcessUserMessage(ctx, conversationID, content)
+		done <- result{response, err}
+	}()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	var final result
+	for waiting := true; waiting; {
+		select {
+		case event := <-events:
+			stream.event(string(event.Type), event)
+		case <-heartbeat.C:
+			stream.heartbeat()
+		case final = <-done:
+			waiting = false
+		}
+	}
+
+	// Flush progress published just before the reply
+	for drained := false; !drained; {
+		select {
+		case event := <-events:
+			stream.event(string(event.Type), event)
+		default:
+			drained = true
+		}
+	}
+
+	if final.err != nil {
+		logger.WarnContext(ctx, "Failed to process streamed message", "error", final.err)
+		stream.event("error", Error{Error: final.err.Error()})
+		return
+	}
+	stream.event("response", MessageResponse{
+		ConversationID: conversationID,
+		Response:       final.response,
+		CreatedAt:      time.Now(),
+	})
+}
+
+// pumpEvents forwards events to stream until ctx ends or a write fails
+func (s *Server) pumpEvents(ctx context.Context, stream *sseWriter, events <-chan progress.Event) {
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := stream.event(string(event.Type), event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := stream.heartbeat(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package assistant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/api"
+	"github.com/kbutz/wikillm/multiagent/llmprovider"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/service"
+)
+
+// AskOptions configures Ask; Register binds it to command-line flags
+type AskOptions struct {
+	// ServerURL, if set, is a running server's API that answers the message;
+	// otherwise the assistant is started for the one message
+	ServerURL   string
+	BaseDir     string
+	LMStudioURL string
+	UserID      string
+	// Token is the bearer token the server authenticates UserID by
+	Token   string
+	Timeout time.Duration
+}
+
+// Register adds the ask flags, such as -server and -user, to fs
+func (o *AskOptions) Register(fs *flag.FlagSet) {
+	fs.StringVar(&o.ServerURL, "server", "", "API of a running server to send the message to, e.g. http://localhost:8080 (the assistant is started for the message if empty)")
+	fs.StringVar(&o.BaseDir, "memory", "./wikillm_memory", "directory for the assistant's memory, without -server")
+	fs.StringVar(&o.LMStudioURL, "lmstudio", "http://localhost:1234/v1", "LMStudio API base URL, without -server")
+	fs.StringVar(&o.UserID, "user", defaultUser(), "user, and conversation, the message is from (default $USER)")
+	fs.StringVar(&o.Token, "token", os.Getenv("WIKILLM_TOKEN"), "the user's bearer token for -server (default $WIKILLM_TOKEN)")
+	fs.DurationVar(&o.Timeout, "timeout", 90*time.Second, "how long to wait for the reply")
+}
+
+// defaultUser is the user the command-line clients talk as: $USER, so
+// ask and chat share one user's tasks, events and conversation
+func defaultUser() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "cli"
+}
+
+// Ask sends the assistant one message and returns its reply
+func Ask(ctx context.Context, o AskOptions, message string) (*api.MessageResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.Timeout)
+	defer cancel()
+	if o.ServerURL != "" {
+		return askServer(ctx, o, message)
+	}
+
+	if err := os.MkdirAll(o.BaseDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create memory directory: %w", err)
+	}
+	// Reminders that fire meanwhile must not mix with the reply on stdout
+	svc, err := service.NewMultiAgentService(service.ServiceConfig{
+		BaseDir:       o.BaseDir,
+		LLMProvider:   llmprovider.NewLMStudioProvider(o.LMStudioURL),
+		Notifications: notify.DispatcherConfig{Default: []notify.Channel{notify.NewConsoleChannel(os.Stderr)}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create multi-agent service: %w", err)
+	}
+	if err := svc.Start(ctx); err != nil {
+		return nil, fmt.Errorf("failed to start service: %w", err)
+	}
+	defer svc.Stop(context.Background())
+
+	response, err := svc.ProcessUserMessage(ctx, o.UserID, message)
+	if err != nil {
+		return nil, err
+	}
+	return &api.MessageResponse{ConversationID: o.UserID, Response: response, CreatedAt: time.Now()}, nil
+}
+
+// askServer posts message to the server's POST /conversations/{id}/messages
+func askServer(ctx context.Context, o AskOptions, message string) (*api.MessageResponse, error) {
+	body, err := json.Marshal(api.MessageRequest{Content: message})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	endpoint := strings.TrimSuffix(o.ServerURL, "/") + "/conversations/" + url.PathEscape(o.UserID) + "/messages"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+o.Token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr api.Error
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("%s: %s", resp.Status, apiErr.Error)
+		}
+		return nil, errors.New(resp.Status)
+	}
+	var reply api.MessageResponse
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &reply, nil
+}
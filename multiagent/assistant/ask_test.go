@@ -0,0 +1,53 @@
+package assistant
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/api"
+)
+
+func TestAskServer(t *testing.T) {
+	var path, authorization string
+	var request api.MessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, authorization = r.URL.Path, r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&request)
+		if request.Content == "slow" {
+			w.WriteHeader(http.StatusGatewayTimeout)
+			json.NewEncoder(w).Encode(api.Error{Error: "context deadline exceeded"})
+			return
+		}
+		json.NewEncoder(w).Encode(api.MessageResponse{ConversationID: "alice", Response: "You have 2 tasks."})
+	}))
+	defer server.Close()
+	options := AskOptions{ServerURL: server.URL + "/", UserID: "alice", Token: "alice-token", Timeout: time.Minute}
+
+	reply, err := Ask(context.Background(), options, "what is on my list?")
+	if err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if reply.Response != "You have 2 tasks." || path != "/conversations/alice/messages" || request.Content != "what is on my list?" || authorization != "Bearer alice-token" {
+		t.Errorf("reply %+v to %q at %s (%s)", reply, request.Content, path, authorization)
+	}
+
+	if _, err := Ask(context.Background(), options, "slow"); err == nil || err.Error() != "504 Gateway Timeout: context deadline exceeded" {
+		t.Errorf("Ask = %v, want the server's error", err)
+	}
+}
+
+func TestChatAndAskShareTheDefaultUser(t *testing.T) {
+	t.Setenv("USER", "alice")
+	var chat ChatOptions
+	chat.Register(flag.NewFlagSet("chat", flag.ContinueOnError))
+	var ask AskOptions
+	ask.Register(flag.NewFlagSet("ask", flag.ContinueOnError))
+	if chat.UserID != "alice" || ask.UserID != "alice" {
+		t.Errorf("chat user %q, ask user %q, want $USER for both", chat.UserID, ask.UserID)
+	}
+}
@@ -0,0 +1,181 @@
+package assistant
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/llmprovider"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+	"github.com/kbutz/wikillm/multiagent/service"
+	"github.com/kbutz/wikillm/multiagent/tui"
+	"github.com/kbutz/wikillm/multiagent/usage"
+	"github.com/kbutz/wikillm/multiagent/voice"
+)
+
+// ChatOptions configures Chat; Register binds it to command-line flags
+type ChatOptions struct {
+	BaseDir     string
+	LMStudioURL string
+	VoiceConfig string
+	// UserID is the user, and conversation, the chat is with, so a later
+	// chat or ask continues where this one left off
+	UserID string
+	// Logging is the logging configuration; while the terminal UI runs,
+	// logs go to interactive.log in BaseDir instead
+	Logging logging.Config
+}
+
+// Register adds the chat's flags, such as -memory and -voice-config, to fs
+func (o *ChatOptions) Register(fs *flag.FlagSet) {
+	fs.StringVar(&o.BaseDir, "memory", "./wikillm_memory", "directory for the assistant's memory")
+	fs.StringVar(&o.LMStudioURL, "lmstudio", "http://localhost:1234/v1", "LMStudio API base URL")
+	fs.StringVar(&o.UserID, "user", defaultUser(), "user, and conversation, to chat as (default $USER)")
+	fs.StringVar(&o.VoiceConfig, "voice-config", "", "JSON file with the speech-to-text and text-to-speech services and audio commands for talking to the assistant (disabled if empty)")
+}
+
+// Chat runs the assistant with the terminal UI until the user quits or
+// SIGINT is received
+func Chat(ctx context.Context, o ChatOptions) error {
+	if o.UserID == "" {
+		o.UserID = defaultUser()
+	}
+	var speech *voice.Voice
+	if o.VoiceConfig != "" {
+		settings, err := voice.LoadConfig(o.VoiceConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load voice config: %w", err)
+		}
+		speech = voice.New(settings, nil)
+	}
+
+	if err := os.MkdirAll(o.BaseDir, 0755); err != nil {
+		return fmt.Errorf("failed to create memory directory: %w", err)
+	}
+	log.Printf("Using memory directory: %s", o.BaseDir)
+
+	// Test LMStudio connectivity first
+	log.Println("🔌 Testing LMStudio connection...")
+	llmProvider := llmprovider.NewLMStudioProvider(o.LMStudioURL,
+		llmprovider.WithTemperature(0.7),
+		llmprovider.WithMaxTokens(2048),
+		llmprovider.WithDebug(false), // Reduced debug output for cleaner interaction
+	)
+
+	// Test a simple query to ensure LMStudio is working
+	log.Println("⏳ First request may take longer if model is loading...")
+	testCtx, cancel := context.WithTimeout(ctx, 600*time.Second)
+	testResponse, err := llmProvider.Query(testCtx, "Say hello in one word.")
+	cancel()
+	if err != nil {
+		log.Println("Please ensure:")
+		log.Println("1. LMStudio is running")
+		log.Println("2. A model is loaded")
+		log.Printf("3. The server is accessible at %s", o.LMStudioURL)
+		return fmt.Errorf("LMStudio connection test failed: %w", err)
+	}
+	log.Printf("✅ LMStudio connection successful! Test response: %s", testResponse)
+
+	// Service logs would draw over the terminal UI, so they go to a file
+	logFile, err := os.OpenFile(filepath.Join(o.BaseDir, "interactive.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer logFile.Close()
+	logConfig := o.Logging
+	logConfig.Output = logFile
+	logging.Configure(logConfig)
+	defer logging.Configure(o.Logging)
+
+	// Create the multi-agent service with all specialist agents
+	log.Println("🏗️  Creating personal assistant service with all specialist agents...")
+	notifications := tui.NewNotificationChannel()
+	svc, err := service.NewMultiAgentService(service.ServiceConfig{
+		BaseDir:       o.BaseDir,
+		LLMProvider:   llmProvider,
+		Notifications: notify.DispatcherConfig{Default: []notify.Channel{notifications}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create multi-agent service: %w", err)
+	}
+
+	if err := svc.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start service: %w", err)
+	}
+	defer func() {
+		log.Println("🔄 Shutting down personal assistant...")
+		if err := svc.Stop(context.Background()); err != nil {
+			log.Printf("Warning: Failed to stop service cleanly: %v", err)
+		}
+	}()
+
+	runCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	config := tui.Config{
+		Service:       svc,
+		UserID:        o.UserID,
+		Notifications: notifications.C,
+		Voice:         speech,
+		Commands: map[string]func(ctx context.Context) (string, error){
+			"usage": func(ctx context.Context) (string, error) {
+				return describeUsage(ctx, svc)
+			},
+			"debug-requests": func(ctx context.Context) (string, error) {
+				return describePendingRequests(svc), nil
+			},
+		},
+	}
+	if err := tui.Run(runCtx, config); err != nil && runCtx.Err() == nil {
+		return fmt.Errorf("terminal UI failed: %w", err)
+	}
+	return nil
+}
+
+// describeUsage lists today's LLM token usage by agent, conversation and model
+func describeUsage(ctx context.Context, svc *service.MultiAgentService) (string, error) {
+	days, err := svc.TokenUsage(ctx, time.Now())
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("🔢 Today's Token Usage:")
+	for _, group := range []struct {
+		name string
+		by   func(*usage.Day) map[string]usage.Totals
+	}{
+		{"Agents", func(d *usage.Day) map[string]usage.Totals { return d.Agents }},
+		{"Conversations", func(d *usage.Day) map[string]usage.Totals { return d.Conversations }},
+		{"Models", func(d *usage.Day) map[string]usage.Totals { return d.Models }},
+	} {
+		fmt.Fprintf(&b, "\n   %s:", group.name)
+		for key, totals := range usage.Sum(days, group.by) {
+			fmt.Fprintf(&b, "\n      %s: %d tokens (%d prompt, %d completion) in %d calls",
+				key, totals.Tokens(), totals.PromptTokens, totals.CompletionTokens, totals.Calls)
+		}
+	}
+	return b.String(), nil
+}
+
+// describePendingRequests lists the requests waiting on a reply
+func describePendingRequests(svc *service.MultiAgentService) string {
+	debugOrch, ok := svc.GetOrchestrator().(*orchestrator.DefaultOrchestrator)
+	if !ok {
+		return "Orchestrator doesn't support request debugging"
+	}
+	requests := debugOrch.PendingRequests()
+	var b strings.Builder
+	fmt.Fprintf(&b, "🔍 Pending Requests: %d", len(requests))
+	for i, request := range requests {
+		fmt.Fprintf(&b, "\n   %d. %s (%s, deadline %s)", i+1, request.ID, request.ConversationID, request.Deadline.Format(time.Kitchen))
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package assistant
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/llmprovider"
+	"github.com/kbutz/wikillm/multiagent/service"
+)
+
+// MCPOptions configures ServeMCP; Register binds it to command-line flags
+type MCPOptions struct {
+	BaseDir     string
+	LMStudioURL string
+	// HTTPAddr serves the streamable HTTP transport instead of stdio, if set
+	HTTPAddr string
+	Token    string
+}
+
+// Register adds the MCP server's flags, such as -memory and -http, to fs
+func (o *MCPOptions) Register(fs *flag.FlagSet) {
+	fs.StringVar(&o.BaseDir, "memory", "./wikillm_memory", "directory for the assistant's memory")
+	fs.StringVar(&o.LMStudioURL, "lmstudio", "http://localhost:1234/v1", "LMStudio API base URL")
+	fs.StringVar(&o.HTTPAddr, "http", "", "serve MCP over HTTP on this address instead of stdio")
+	fs.StringVar(&o.Token, "token", os.Getenv("WIKILLM_MCP_TOKEN"), "bearer token HTTP clients must present (default $WIKILLM_MCP_TOKEN)")
+}
+
+// ServeMCP exposes the assistant's to-dos, memory, calendar, tasks and
+// research as a Model Context Protocol server, over stdio so a client can
+// launch it directly or over HTTP, until ctx ends or SIGINT or SIGTERM is
+// received
+func ServeMCP(ctx context.Context, o MCPOptions) error {
+	// Stdout carries the protocol; send anything else printed there to stderr
+	protocolOut := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = protocolOut }()
+
+	if err := os.MkdirAll(o.BaseDir, 0755); err != nil {
+		return fmt.Errorf("failed to create memory directory: %w", err)
+	}
+
+	svc, err := service.NewMultiAgentService(service.ServiceConfig{
+		BaseDir:     o.BaseDir,
+		LLMProvider: llmprovider.NewLMStudioProvider(o.LMStudioURL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create multi-agent service: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := svc.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start service: %w", err)
+	}
+	mcpServer := svc.MCPServer(o.Token)
+
+	var failure error
+	if o.HTTPAddr == "" {
+		if err := mcpServer.ServeStdio(ctx, os.Stdin, protocolOut); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("Warning: MCP stdio session failed: %v", err)
+		}
+	} else {
+		server := &http.Server{Addr: o.HTTPAddr, Handler: mcpServer}
+		serveErr := make(chan error, 1)
+		go func() {
+			log.Printf("Serving MCP on %s", o.HTTPAddr)
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- fmt.Errorf("MCP server failed: %w", err)
+			}
+		}()
+		select {
+		case <-ctx.Done():
+		case failure = <-serveErr:
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Warning: Failed to stop MCP server cleanly: %v", err)
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := svc.Stop(shutdownCtx); err != nil {
+		log.Printf("Warning: Failed to stop service cleanly: %v", err)
+	}
+	return failure
+}
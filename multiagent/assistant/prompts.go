@@ -0,0 +1,80 @@
+package assistant
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+// PromptsOptions configures Prompts; Register binds it to command-line flags
+type PromptsOptions struct {
+	Dir      string
+	Versions string
+	// Show is the prompt whose active template text is printed, if set
+	Show string
+	// Export is the directory the built-in prompts are written to, if set
+	Export string
+}
+
+// Register adds the prompt listing's flags, such as -dir and -show, to fs
+func (o *PromptsOptions) Register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Dir, "dir", "", "directory of prompt templates that add to or replace the built-in ones")
+	fs.StringVar(&o.Versions, "versions", "", "prompt versions to render, as given to the server's -prompt-versions")
+	fs.StringVar(&o.Show, "show", "", "print the active template text of this prompt")
+	fs.StringVar(&o.Export, "export", "", "write the built-in prompts to this directory and exit")
+}
+
+// Prompts writes the prompts agents render to w, or one prompt's active
+// template text, or exports the built-in prompts to edit for -prompt-dir
+func Prompts(o PromptsOptions, w io.Writer) error {
+	if o.Export != "" {
+		if err := prompts.ExportBuiltin(o.Export); err != nil {
+			return fmt.Errorf("failed to export prompts: %w", err)
+		}
+		fmt.Fprintf(w, "Exported built-in prompts to %s\n", o.Export)
+		return nil
+	}
+
+	overrides, err := prompts.ParseOverrides(o.Versions)
+	if err != nil {
+		return fmt.Errorf("invalid -versions: %w", err)
+	}
+	registry, err := prompts.NewRegistry(prompts.RegistryConfig{Dir: o.Dir, Overrides: overrides})
+	if err != nil {
+		return fmt.Errorf("failed to load prompts: %w", err)
+	}
+
+	if o.Show != "" {
+		for _, prompt := range registry.List() {
+			if prompt.Name != o.Show {
+				continue
+			}
+			for _, version := range prompt.Active {
+				t, _ := registry.Template(prompt.Name, version)
+				fmt.Fprintf(w, "# %s v%d (%s)\n%s\n\n", t.Name, t.Version, t.Source, t.Text)
+			}
+			return nil
+		}
+		return fmt.Errorf("unknown prompt %q", o.Show)
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "PROMPT\tVERSIONS\tACTIVE\tOVERRIDDEN")
+	for _, prompt := range registry.List() {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", prompt.Name, versionList(prompt.Versions), versionList(prompt.Active), versionList(prompt.Overridden))
+	}
+	return table.Flush()
+}
+
+// versionList formats versions as "v1,v2"
+func versionList(versions []int) string {
+	parts := make([]string, len(versions))
+	for i, version := range versions {
+		parts[i] = fmt.Sprintf("v%d", version)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,333 @@
+package assistant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/usage"
+)
+
+// StoreOptions names the memory store the assistant's records are read
+// from: a FileMemoryStore directory or a SQLite database
+type StoreOptions struct {
+	From   string
+	SQLite string
+}
+
+// Register adds -from and -sqlite to fs
+func (o *StoreOptions) Register(fs *flag.FlagSet) {
+	fs.StringVar(&o.From, "from", "", "FileMemoryStore directory to read from")
+	fs.StringVar(&o.SQLite, "sqlite", "", "SQLite memory database to read from")
+}
+
+// open opens the store; the caller closes it
+func (o StoreOptions) open() (multiagent.MemoryStore, io.Closer, error) {
+	switch {
+	case o.From != "" && o.SQLite == "":
+		store, err := memory.NewFileMemoryStore(o.From)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open file store: %w", err)
+		}
+		return store, store, nil
+	case o.SQLite != "" && o.From == "":
+		store, err := memory.NewSQLiteMemoryStore(o.SQLite)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open sqlite store: %w", err)
+		}
+		return store, store, nil
+	}
+	return nil, nil, errors.New("exactly one of -from or -sqlite is required")
+}
+
+// AuditOptions configures Audit; Register binds it to command-line flags
+type AuditOptions struct {
+	Store        StoreOptions
+	Actor        string
+	Types        string
+	Subject      string
+	Conversation string
+	Since        time.Duration
+	Limit        int
+	JSON         bool
+}
+
+// Register adds the audit query's flags, such as -actor and -since, to fs
+func (o *AuditOptions) Register(fs *flag.FlagSet) {
+	o.Store.Register(fs)
+	fs.StringVar(&o.Actor, "actor", "", "only show actions by this agent")
+	fs.StringVar(&o.Types, "type", "", "comma-separated event types (e.g. task.created,memory.written)")
+	fs.StringVar(&o.Subject, "subject", "", "only show actions on subjects with this prefix")
+	fs.StringVar(&o.Conversation, "conversation", "", "only show actions in this conversation")
+	fs.DurationVar(&o.Since, "since", 0, "only show actions newer than this (e.g. 24h)")
+	fs.IntVar(&o.Limit, "limit", 50, "maximum number of actions to show (0 for all)")
+	fs.BoolVar(&o.JSON, "json", false, "print events as JSON lines")
+}
+
+// Audit writes what the agents did, read from the audit log in a memory
+// store, to w as a table or JSON lines
+func Audit(ctx context.Context, o AuditOptions, w io.Writer) error {
+	store, closer, err := o.Store.open()
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	filter := audit.Filter{
+		Actor:          multiagent.AgentID(o.Actor),
+		Subject:        o.Subject,
+		ConversationID: o.Conversation,
+		Limit:          o.Limit,
+	}
+	for _, t := range strings.Split(o.Types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			filter.Types = append(filter.Types, audit.EventType(t))
+		}
+	}
+	if o.Since > 0 {
+		filter.Since = time.Now().Add(-o.Since)
+	}
+
+	events, err := audit.NewLog(audit.LogConfig{Store: store}).Query(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to query audit log: %w", err)
+	}
+
+	if o.JSON {
+		encoder := json.NewEncoder(w)
+		for _, event := range events {
+			if err := encoder.Encode(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TIME\tACTOR\tTYPE\tSUBJECT\tDETAILS")
+	for _, event := range events {
+		details, _ := json.Marshal(event.Payload)
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n",
+			event.Timestamp.Format("2006-01-02 15:04:05"), event.Actor, event.Type, event.Subject, details)
+	}
+	return table.Flush()
+}
+
+// UsageOptions configures Usage; Register binds it to command-line flags
+type UsageOptions struct {
+	Store StoreOptions
+	Days  int
+	By    string
+	JSON  bool
+}
+
+// Register adds the usage report's flags, such as -days and -by, to fs
+func (o *UsageOptions) Register(fs *flag.FlagSet) {
+	o.Store.Register(fs)
+	fs.IntVar(&o.Days, "days", 1, "number of days to show, including today")
+	fs.StringVar(&o.By, "by", "agent", "group usage by agent, conversation, model or day")
+	fs.BoolVar(&o.JSON, "json", false, "print the totals as JSON")
+}
+
+// Usage writes the LLM tokens the agents spent, read from the per-day usage
+// records in a memory store, to w as a table or JSON
+func Usage(ctx context.Context, o UsageOptions, w io.Writer) error {
+	store, closer, err := o.Store.open()
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	since := time.Now().AddDate(0, 0, 1-o.Days)
+	usageDays, err := usage.NewTracker(usage.TrackerConfig{Store: store}).Query(ctx, since)
+	if err != nil {
+		return fmt.Errorf("failed to query usage: %w", err)
+	}
+
+	var totals map[string]usage.Totals
+	switch o.By {
+	case "agent":
+		totals = usage.Sum(usageDays, func(d *usage.Day) map[string]usage.Totals { return d.Agents })
+	case "conversation":
+		totals = usage.Sum(usageDays, func(d *usage.Day) map[string]usage.Totals { return d.Conversations })
+	case "model":
+		totals = usage.Sum(usageDays, func(d *usage.Day) map[string]usage.Totals { return d.Models })
+	case "day":
+		totals = make(map[string]usage.Totals, len(usageDays))
+		for _, day := range usageDays {
+			totals[day.Date] = day.Total
+		}
+	default:
+		return fmt.Errorf("invalid -by %q: want agent, conversation, model or day", o.By)
+	}
+
+	if o.JSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(totals)
+	}
+
+	keys := make([]string, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "%s\tCALLS\tPROMPT\tCOMPLETION\tTOTAL\tESTIMATED\n", strings.ToUpper(o.By))
+	for _, key := range keys {
+		t := totals[key]
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%d\t%d\n", key, t.Calls, t.PromptTokens, t.CompletionTokens, t.Tokens(), t.EstimatedCalls)
+	}
+	return table.Flush()
+}
+
+// GanttOptions configures Gantt; Register binds it to command-line flags
+type GanttOptions struct {
+	Store   StoreOptions
+	UserID  string
+	Project string
+	Format  string
+	// Timezone lays dates out; the local one if empty
+	Timezone string
+	// Output is the file written instead of w, if set
+	Output string
+}
+
+// Register adds the gantt chart's flags, such as -user and -project, to fs
+func (o *GanttOptions) Register(fs *flag.FlagSet) {
+	o.Store.Register(fs)
+	fs.StringVar(&o.UserID, "user", "", "user whose project to print")
+	fs.StringVar(&o.Project, "project", "", "project ID or name (may be omitted when the user has one project)")
+	fs.StringVar(&o.Format, "format", agents.TimelineFormatMermaid, "mermaid or csv")
+	fs.StringVar(&o.Timezone, "tz", "", "timezone to lay dates out in (defaults to the local one)")
+	fs.StringVar(&o.Output, "o", "", "file to write to instead of stdout")
+}
+
+// Gantt writes a user's project, read from a memory store, as a Mermaid
+// gantt diagram or CSV to w or the output file. When no single project
+// matches, the user's projects are listed in the error.
+func Gantt(ctx context.Context, o GanttOptions, w io.Writer) error {
+	if o.UserID == "" {
+		return errors.New("-user is required")
+	}
+	loc := time.Local
+	if o.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(o.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone: %w", err)
+		}
+	}
+	store, closer, err := o.Store.open()
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	ctx = multiagent.WithUserID(ctx, o.UserID)
+	projects, err := agents.LoadProjects(ctx, memory.PartitionByUser(store))
+	if err != nil {
+		return fmt.Errorf("failed to load projects: %w", err)
+	}
+	project := findProject(projects, o.Project)
+	if project == nil {
+		var list strings.Builder
+		table := tabwriter.NewWriter(&list, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "ID\tNAME\tSTATUS")
+		for _, p := range projects {
+			fmt.Fprintf(table, "%s\t%s\t%s\n", p.ID, p.Name, p.Status)
+		}
+		table.Flush()
+		return fmt.Errorf("no single project matches %q; pick one of these with -project:\n%s", o.Project, list.String())
+	}
+
+	data, err := agents.RenderProjectTimeline(project, o.Format, time.Now().In(loc))
+	if err != nil {
+		return fmt.Errorf("failed to render timeline: %w", err)
+	}
+	if o.Output == "" {
+		_, err := w.Write(data)
+		return err
+	}
+	if err := os.WriteFile(o.Output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", o.Output, err)
+	}
+	return nil
+}
+
+// findProject returns the project ref names by ID or name, or the only
+// project when ref is empty
+func findProject(projects []*agents.Project, ref string) *agents.Project {
+	if ref == "" {
+		if len(projects) == 1 {
+			return projects[0]
+		}
+		return nil
+	}
+	var matches []*agents.Project
+	for _, project := range projects {
+		if project.ID == ref || strings.EqualFold(project.Name, ref) {
+			return project
+		}
+		if strings.Contains(strings.ToLower(project.Name), strings.ToLower(ref)) {
+			matches = append(matches, project)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0]
+	}
+	return nil
+}
+
+// MigrateOptions configures MigrateMemory; Register binds it to
+// command-line flags
+type MigrateOptions struct {
+	From string
+	To   string
+}
+
+// Register adds -from and -to to fs
+func (o *MigrateOptions) Register(fs *flag.FlagSet) {
+	fs.StringVar(&o.From, "from", "", "FileMemoryStore directory to read from")
+	fs.StringVar(&o.To, "to", "", "SQLite database file to write to")
+}
+
+// MigrateMemory copies a FileMemoryStore directory into a SQLite memory
+// store, writing what was copied to w
+func MigrateMemory(ctx context.Context, o MigrateOptions, w io.Writer) error {
+	if o.From == "" || o.To == "" {
+		return errors.New("both -from and -to are required")
+	}
+	src, err := memory.NewFileMemoryStore(o.From)
+	if err != nil {
+		return fmt.Errorf("failed to open file store: %w", err)
+	}
+	defer src.Close()
+	dst, err := memory.NewSQLiteMemoryStore(o.To)
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite store: %w", err)
+	}
+	defer dst.Close()
+
+	report, err := memory.MigrateFileStore(ctx, src, dst)
+	if err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	fmt.Fprintf(w, "Migrated %d entries (%d expired skipped, %d unreadable)\n", report.Migrated, report.Expired, len(report.Failed))
+	for _, key := range report.Failed {
+		fmt.Fprintf(w, "  unreadable: %s\n", key)
+	}
+	return nil
+}
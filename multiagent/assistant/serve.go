@@ -0,0 +1,544 @@
+// Package assistant runs the multi-agent personal assistant as a program:
+// Serve puts it behind the REST API and chat frontends, ServeMCP behind the
+// Model Context Protocol, and Chat talks to it in the terminal. Audit,
+// Usage and Gantt read back what it recorded, Prompts lists the prompts it
+// renders, and MigrateMemory moves its memory to SQLite. It is shared by the
+// interactive example and the wikillm command.
+package assistant
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // User timezones must resolve without a system zone database
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/access"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/api"
+	"github.com/kbutz/wikillm/multiagent/caldav"
+	"github.com/kbutz/wikillm/multiagent/discord"
+	"github.com/kbutz/wikillm/multiagent/email"
+	"github.com/kbutz/wikillm/multiagent/ingest"
+	"github.com/kbutz/wikillm/multiagent/llmprovider"
+	"github.com/kbutz/wikillm/multiagent/mcp"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/notes"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+	"github.com/kbutz/wikillm/multiagent/policy"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+	"github.com/kbutz/wikillm/multiagent/search"
+	"github.com/kbutz/wikillm/multiagent/service"
+	"github.com/kbutz/wikillm/multiagent/slack"
+	"github.com/kbutz/wikillm/multiagent/telegram"
+	"github.com/kbutz/wikillm/multiagent/webhooks"
+)
+
+// ServerOptions configures Serve; Register binds it to command-line flags
+type ServerOptions struct {
+	Addr               string
+	BaseDir            string
+	LMStudioURL        string
+	MetricsAddr        string
+	GRPCAddr           string
+	GRPCToken          string
+	MCPConfig          string
+	CalDAVConfig       string
+	EmailConfig        string
+	LLMConfig          string
+	WebhookConfig      string
+	EventSourceConfig  string
+	NotifyConfig       string
+	WorkingHoursConfig string
+	BriefingSchedule   string
+	WeatherLocations   string
+	FactInterval       time.Duration
+	NotesConfig        string
+	NotesInterval      time.Duration
+	MonitorInterval    time.Duration
+	WebSearchURL       string
+	WikipediaURL       string
+	QdrantAddr         string
+	EmbeddingModel     string
+	AdminToken         string
+	UserTokens         string
+	ConfirmActions     string
+	AccessPolicy       string
+	MessageTimeout     time.Duration
+	LLMCache           string
+	PromptDir          string
+	PromptVersions     string
+	MessagePolicy      string
+	SlackConfig        string
+	TelegramConfig     string
+	DiscordConfig      string
+	TokenBudget        int
+	LLMConcurrency     int
+	LLMContextWindow   int
+
+	// Tools are more tools given to every agent, which have no flags, such
+	// as a search over a local Wikipedia index named
+	// search.WikipediaToolName in place of -wikipedia-url
+	Tools []multiagent.Tool
+}
+
+// Register adds the server's flags, such as -addr and -memory, to fs
+func (o *ServerOptions) Register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Addr, "addr", ":8080", "address to serve the API on")
+	fs.StringVar(&o.BaseDir, "memory", "./wikillm_memory", "directory for the assistant's memory")
+	fs.StringVar(&o.LMStudioURL, "lmstudio", "http://localhost:1234/v1", "LMStudio API base URL")
+	fs.StringVar(&o.MetricsAddr, "metrics-addr", "", "address to serve Prometheus metrics on (disabled if empty)")
+	fs.StringVar(&o.GRPCAddr, "grpc-addr", "", "address to serve the orchestrator over gRPC on (disabled if empty)")
+	fs.StringVar(&o.GRPCToken, "grpc-token", os.Getenv("WIKILLM_GRPC_TOKEN"), "bearer token gRPC clients must present (default $WIKILLM_GRPC_TOKEN)")
+	fs.StringVar(&o.MCPConfig, "mcp-config", "", "JSON file listing MCP servers whose tools agents can use")
+	fs.StringVar(&o.CalDAVConfig, "caldav-config", "", "JSON file listing users' CalDAV calendars to keep in sync")
+	fs.StringVar(&o.EmailConfig, "email-config", "", "JSON file listing users' mail accounts, for sending composed email (SMTP) and reading replies (IMAP)")
+	fs.StringVar(&o.LLMConfig, "llm-config", "", "JSON file assigning LLM backends and models to agent roles; reloaded on SIGHUP (-lmstudio for every agent if empty)")
+	fs.StringVar(&o.WebhookConfig, "webhook-config", "", "JSON file listing URLs that reminders, completed tasks and new events are POSTed to, signed and retried; reloaded on SIGHUP (format in webhooks.LoadConfig)")
+	fs.StringVar(&o.EventSourceConfig, "event-source-config", "", "JSON file listing outside systems, such as GitHub or a CI server, allowed to submit events to /events for agents to act on (disabled if empty; format in ingest.LoadConfig)")
+	fs.StringVar(&o.NotifyConfig, "notify-config", "", "JSON file configuring the channels reminders are delivered on; reloaded on SIGHUP (console if empty)")
+	fs.StringVar(&o.WorkingHoursConfig, "working-hours", "", "JSON file of the working hours assumed for users who have not set their own, e.g. {\"monday\": \"09:00-17:00\"}; reloaded on SIGHUP (weekdays 9:00-18:00 if empty)")
+	fs.StringVar(&o.BriefingSchedule, "briefing-schedule", "0 7 * * *", "cron expression, in each user's timezone, for the daily briefing (\"off\" disables it)")
+	fs.StringVar(&o.WeatherLocations, "weather-locations", "", "comma-separated user=place pairs whose briefings include the weather, e.g. alice=Berlin")
+	fs.DurationVar(&o.FactInterval, "fact-interval", 30*time.Minute, "how often conversations are mined for facts about their users, which agents use once approved at /facts (0 disables it)")
+	fs.StringVar(&o.NotesConfig, "notes-config", "", "JSON file listing users' directories of Markdown, text and PDF notes to index for agents to search (disabled if empty; format in notes.LoadSources)")
+	fs.DurationVar(&o.NotesInterval, "notes-interval", time.Minute, "how often -notes-config directories are rescanned for added, changed and deleted notes")
+	fs.DurationVar(&o.MonitorInterval, "research-monitor-interval", 5*time.Minute, "how often research topics users monitor are checked for a due re-run; users are told only what is materially new (0 disables it)")
+	fs.StringVar(&o.WebSearchURL, "web-search-url", "", "SearxNG instance, with its JSON format enabled, that the research assistant searches the web through (disabled if empty)")
+	fs.StringVar(&o.WikipediaURL, "wikipedia-url", "", "MediaWiki site, such as https://en.wikipedia.org, whose articles the research assistant searches and checks facts against (disabled if empty)")
+	fs.StringVar(&o.QdrantAddr, "qdrant-addr", "", "host:port of a Qdrant gRPC API to index memory in, so agents recall past conversations, tasks and research by meaning (disabled if empty)")
+	fs.StringVar(&o.EmbeddingModel, "embedding-model", "all-minilm", "LMStudio embedding model memory is indexed with for -qdrant-addr")
+	fs.StringVar(&o.AdminToken, "admin-token", os.Getenv("WIKILLM_ADMIN_TOKEN"), "bearer token the /admin routes require (default $WIKILLM_ADMIN_TOKEN; the /admin routes are refused if empty)")
+	fs.StringVar(&o.UserTokens, "user-tokens", "", "JSON file mapping bearer tokens to the users they authenticate, for the per-user API routes (refused if empty, except with the admin token)")
+	fs.StringVar(&o.ConfirmActions, "confirm-actions", "all", "comma-separated actions agents ask the user to approve first: send_email, delete_task, cancel_event (\"all\" or \"none\")")
+	fs.StringVar(&o.AccessPolicy, "access-policy", "", "JSON file of the tools and actions each agent and user role may use, and users' roles (default: only the responsible agent sends email, deletes tasks or cancels events, and guests may only search)")
+	fs.DurationVar(&o.MessageTimeout, "message-timeout", 90*time.Second, "how long a message request waits for a reply")
+	fs.StringVar(&o.LLMCache, "llm-cache", "", "prompt classes whose LLM responses are cached, with their TTLs, e.g. intent=10m,summary=1h (disabled if empty)")
+	fs.StringVar(&o.PromptDir, "prompt-dir", "", "directory of prompt templates that add to or replace the built-in ones; watched for edits and reloaded on SIGHUP")
+	fs.StringVar(&o.PromptVersions, "prompt-versions", "", "prompt versions to render, pinned or split per conversation, e.g. task.create=v2,intent.classify=v1|v2 (latest if empty)")
+	fs.StringVar(&o.MessagePolicy, "message-policy", "", "JSON file listing the steps messages pass through as they are routed: log, redact and block rules (format in policy.LoadConfig)")
+	fs.StringVar(&o.SlackConfig, "slack-config", "", "JSON file with a Slack app's bot token and signing secret, for talking to the assistant in Slack through /slack/events (disabled if empty)")
+	fs.StringVar(&o.TelegramConfig, "telegram-config", "", "JSON file with a Telegram bot's token and users, for talking to the assistant in Telegram by long polling, or through /telegram/webhook if it sets a webhook_url (disabled if empty)")
+	fs.StringVar(&o.DiscordConfig, "discord-config", "", "JSON file with a Discord application's ID, public key and bot token, for the /task, /schedule and /research slash commands through /discord/interactions (disabled if empty)")
+	fs.IntVar(&o.TokenBudget, "token-budget", 0, "LLM tokens a conversation may spend a day before it is degraded to cheaper behavior (0 for unlimited)")
+	fs.IntVar(&o.LLMConcurrency, "llm-concurrency", 0, "LLM calls to have in flight on -lmstudio at once, across all agents; more wait their turn (0 for unlimited; see max_concurrent in -llm-config)")
+	fs.IntVar(&o.LLMContextWindow, "llm-context-window", 0, "tokens of context -lmstudio loads its model with; prompts are shrunk to fit (the model's known window, or 4096, if 0; see context_window in -llm-config)")
+}
+
+// Serve runs the assistant and its API until ctx ends or SIGINT or SIGTERM
+// is received, then shuts it down
+func Serve(ctx context.Context, o ServerOptions) error {
+	if err := os.MkdirAll(o.BaseDir, 0755); err != nil {
+		return fmt.Errorf("failed to create memory directory: %w", err)
+	}
+
+	var err error
+	var mcpServers []mcp.ClientConfig
+	if o.MCPConfig != "" {
+		mcpServers, err = mcp.LoadConfig(o.MCPConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load MCP servers: %w", err)
+		}
+	}
+
+	var caldavAccounts []caldav.Account
+	if o.CalDAVConfig != "" {
+		caldavAccounts, err = caldav.LoadAccounts(o.CalDAVConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load CalDAV accounts: %w", err)
+		}
+	}
+
+	var emailAccounts []email.Account
+	if o.EmailConfig != "" {
+		emailAccounts, err = email.LoadAccounts(o.EmailConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load email accounts: %w", err)
+		}
+	}
+
+	var noteSources []notes.Source
+	if o.NotesConfig != "" {
+		noteSources, err = notes.LoadSources(o.NotesConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load notes config: %w", err)
+		}
+	}
+
+	// Research sources; a Wikipedia tool passed in replaces -wikipedia-url
+	extraTools := append([]multiagent.Tool(nil), o.Tools...)
+	if o.WebSearchURL != "" {
+		extraTools = append(extraTools, search.NewTool(search.WebToolName, "Search the web", search.NewSearxNGSearcher(o.WebSearchURL, nil)))
+	}
+	if o.WikipediaURL != "" && !hasTool(extraTools, search.WikipediaToolName) {
+		extraTools = append(extraTools, search.NewTool(search.WikipediaToolName, "Search Wikipedia articles", search.NewWikipediaSearcher(o.WikipediaURL, nil)))
+	}
+
+	var vectorMemory *memory.QdrantMemoryStoreConfig
+	if o.QdrantAddr != "" {
+		host, port, err := net.SplitHostPort(o.QdrantAddr)
+		if err != nil {
+			return fmt.Errorf("invalid -qdrant-addr: %w", err)
+		}
+		portNumber, err := strconv.Atoi(port)
+		if err != nil {
+			return fmt.Errorf("invalid -qdrant-addr port: %w", err)
+		}
+		vectorMemory = &memory.QdrantMemoryStoreConfig{
+			Host:     host,
+			Port:     portNumber,
+			Embedder: llmprovider.NewLMStudioProvider(o.LMStudioURL, llmprovider.WithEmbeddingModel(o.EmbeddingModel)),
+		}
+	}
+
+	var llm multiagent.LLMProvider = llmprovider.NewLMStudioProvider(o.LMStudioURL, llmprovider.WithContextWindow(o.LLMContextWindow))
+	llmGovernor := llmprovider.NewGovernor(llmprovider.GovernorConfig{
+		Name:          "lmstudio",
+		Kind:          llmprovider.KindLMStudio,
+		URL:           o.LMStudioURL,
+		MaxConcurrent: o.LLMConcurrency,
+	})
+	var llmPool *llmprovider.Pool
+	if o.LLMConfig != "" {
+		poolConfig, err := llmprovider.LoadPoolConfig(o.LLMConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load LLM config: %w", err)
+		}
+		if llmPool, err = llmprovider.NewPool(poolConfig); err != nil {
+			return fmt.Errorf("invalid LLM config: %w", err)
+		}
+		llm, llmGovernor = nil, nil
+	}
+
+	var notifications notify.DispatcherConfig
+	if o.NotifyConfig != "" {
+		notifications, err = notify.LoadConfig(o.NotifyConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load notification channels: %w", err)
+		}
+	}
+
+	var webhookEndpoints []webhooks.Endpoint
+	if o.WebhookConfig != "" {
+		webhookEndpoints, err = webhooks.LoadConfig(o.WebhookConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load webhooks: %w", err)
+		}
+	}
+
+	var eventSources []ingest.Source
+	if o.EventSourceConfig != "" {
+		eventSources, err = ingest.LoadConfig(o.EventSourceConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load event sources: %w", err)
+		}
+	}
+
+	var slackSettings *slack.Config
+	if o.SlackConfig != "" {
+		settings, err := slack.LoadConfig(o.SlackConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load Slack config: %w", err)
+		}
+		slackSettings = &settings
+	}
+
+	var telegramSettings *telegram.Config
+	if o.TelegramConfig != "" {
+		settings, err := telegram.LoadConfig(o.TelegramConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load Telegram config: %w", err)
+		}
+		telegramSettings = &settings
+	}
+
+	var discordSettings *discord.Config
+	if o.DiscordConfig != "" {
+		settings, err := discord.LoadConfig(o.DiscordConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load Discord config: %w", err)
+		}
+		discordSettings = &settings
+	}
+
+	var userTokens map[string]string
+	if o.UserTokens != "" {
+		if userTokens, err = api.LoadUserTokens(o.UserTokens); err != nil {
+			return err
+		}
+	}
+	if o.AdminToken == "" && len(userTokens) == 0 {
+		log.Printf("Warning: no -admin-token or -user-tokens; the API serves only /agents, /health and the dashboard")
+	}
+
+	var routeMiddleware []orchestrator.RouteMiddleware
+	if o.MessagePolicy != "" {
+		routeMiddleware, err = policy.LoadConfig(o.MessagePolicy)
+		if err != nil {
+			return fmt.Errorf("failed to load message policy: %w", err)
+		}
+	}
+
+	var workingHours *agents.WorkingHours
+	if o.WorkingHoursConfig != "" {
+		hours, err := agents.LoadWorkingHours(o.WorkingHoursConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load working hours: %w", err)
+		}
+		workingHours = &hours
+	}
+
+	briefings := service.BriefingConfig{
+		Disabled:  o.BriefingSchedule == "off",
+		Schedule:  o.BriefingSchedule,
+		Locations: make(map[string]string),
+	}
+	for _, pair := range strings.Split(o.WeatherLocations, ",") {
+		if user, place, ok := strings.Cut(pair, "="); ok && strings.TrimSpace(user) != "" {
+			briefings.Locations[strings.TrimSpace(user)] = strings.TrimSpace(place)
+		}
+	}
+
+	confirmations, err := agents.ParseConfirmationPolicy(o.ConfirmActions)
+	if err != nil {
+		return fmt.Errorf("invalid -confirm-actions: %w", err)
+	}
+	var accessPolicy *access.Policy
+	if o.AccessPolicy != "" {
+		if accessPolicy, err = access.LoadPolicy(o.AccessPolicy); err != nil {
+			return err
+		}
+	}
+	cacheClasses, err := llmprovider.ParseCacheClasses(o.LLMCache)
+	if err != nil {
+		return fmt.Errorf("invalid -llm-cache: %w", err)
+	}
+	promptOverrides, err := prompts.ParseOverrides(o.PromptVersions)
+	if err != nil {
+		return fmt.Errorf("invalid -prompt-versions: %w", err)
+	}
+
+	svc, err := service.NewMultiAgentService(service.ServiceConfig{
+		BaseDir:            o.BaseDir,
+		VectorMemory:       vectorMemory,
+		LLMProvider:        llm,
+		LLMPool:            llmPool,
+		LLMGovernor:        llmGovernor,
+		MetricsAddr:        o.MetricsAddr,
+		GRPCAddr:           o.GRPCAddr,
+		GRPCToken:          o.GRPCToken,
+		MCPServers:         mcpServers,
+		CalDAVAccounts:     caldavAccounts,
+		EmailAccounts:      emailAccounts,
+		Notifications:      notifications,
+		Webhooks:           webhookEndpoints,
+		Briefings:          briefings,
+		FactExtraction:     service.FactExtractionConfig{Disabled: o.FactInterval <= 0, Interval: o.FactInterval},
+		ResearchMonitoring: service.ResearchMonitorConfig{Disabled: o.MonitorInterval <= 0, CheckInterval: o.MonitorInterval},
+		Notes:              noteSources,
+		NotesInterval:      o.NotesInterval,
+		Tools:              extraTools,
+		Confirmations:      confirmations,
+		Access:             accessPolicy,
+		TokenBudget:        o.TokenBudget,
+		LLMCache:           llmprovider.CacheConfig{Classes: cacheClasses},
+		PromptDir:          o.PromptDir,
+		PromptOverrides:    promptOverrides,
+		WorkingHours:       workingHours,
+		RouteMiddleware:    routeMiddleware,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create multi-agent service: %w", err)
+	}
+
+	// Slack and Telegram users get their reminders where they chat
+	var slackAdapter *slack.Adapter
+	if slackSettings != nil {
+		slackAdapter = slack.NewAdapter(slack.AdapterConfig{Service: svc, Slack: *slackSettings, MessageTimeout: o.MessageTimeout})
+	}
+	var telegramAdapter *telegram.Adapter
+	if telegramSettings != nil {
+		telegramAdapter = telegram.NewAdapter(telegram.AdapterConfig{Service: svc, Telegram: *telegramSettings, MessageTimeout: o.MessageTimeout})
+	}
+	routeNotifications := func(config notify.DispatcherConfig) notify.DispatcherConfig {
+		if slackAdapter != nil {
+			config = slackAdapter.Route(config)
+		}
+		if telegramAdapter != nil {
+			config = telegramAdapter.Route(config)
+		}
+		return config
+	}
+	if slackAdapter != nil || telegramAdapter != nil {
+		if err := svc.ReloadNotifications(routeNotifications(notifications)); err != nil {
+			return fmt.Errorf("failed to route notifications to chat frontends: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := svc.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start service: %w", err)
+	}
+
+	// Reloading re-reads every configuration file given; a file that fails
+	// to load keeps its part's current settings
+	reloadConfig := func(ctx context.Context) (*service.ReloadResult, error) {
+		var config service.ReloadConfig
+		var loadErrs []error
+		if o.LLMConfig != "" {
+			if poolConfig, err := llmprovider.LoadPoolConfig(o.LLMConfig); err != nil {
+				loadErrs = append(loadErrs, fmt.Errorf("failed to load LLM config: %w", err))
+			} else {
+				config.LLMPool = &poolConfig
+			}
+		}
+		config.Prompts = o.PromptDir != ""
+		if o.NotifyConfig != "" {
+			if notifications, err := notify.LoadConfig(o.NotifyConfig); err != nil {
+				loadErrs = append(loadErrs, fmt.Errorf("failed to load notification channels: %w", err))
+			} else {
+				notifications = routeNotifications(notifications)
+				config.Notifications = &notifications
+			}
+		}
+		if o.WebhookConfig != "" {
+			if endpoints, err := webhooks.LoadConfig(o.WebhookConfig); err != nil {
+				loadErrs = append(loadErrs, fmt.Errorf("failed to load webhooks: %w", err))
+			} else {
+				config.Webhooks = &endpoints
+			}
+		}
+		if o.WorkingHoursConfig != "" {
+			if hours, err := agents.LoadWorkingHours(o.WorkingHoursConfig); err != nil {
+				loadErrs = append(loadErrs, fmt.Errorf("failed to load working hours: %w", err))
+			} else {
+				config.WorkingHours = &hours
+			}
+		}
+
+		result, err := svc.Reload(config)
+		for _, loadErr := range loadErrs {
+			result.Errors = append(result.Errors, loadErr.Error())
+		}
+		return result, errors.Join(append(loadErrs, err)...)
+	}
+
+	var discordAdapter *discord.Adapter
+	if discordSettings != nil {
+		discordAdapter = discord.NewAdapter(discord.AdapterConfig{Service: svc, Discord: *discordSettings, MessageTimeout: o.MessageTimeout})
+		if err := discordAdapter.RegisterCommands(ctx); err != nil {
+			log.Printf("Warning: Failed to register Discord commands: %v", err)
+		}
+	}
+
+	handler := http.NewServeMux()
+	handler.Handle("/", api.NewServer(api.ServerConfig{
+		Service:        svc,
+		MessageTimeout: o.MessageTimeout,
+		AdminToken:     o.AdminToken,
+		UserTokens:     userTokens,
+		Reload:         reloadConfig,
+	}))
+	if len(eventSources) > 0 {
+		handler.Handle("POST /events", ingest.NewHandler(ingest.HandlerConfig{Submitter: svc, Sources: eventSources}))
+	}
+	if slackAdapter != nil {
+		handler.Handle("POST /slack/events", slackAdapter)
+	}
+	if discordAdapter != nil {
+		handler.Handle("POST /discord/interactions", discordAdapter)
+	}
+	if telegramAdapter != nil {
+		if telegramSettings.WebhookURL != "" {
+			handler.Handle("POST /telegram/webhook", telegramAdapter)
+			if err := telegramAdapter.SetWebhook(ctx); err != nil {
+				svc.Stop(context.Background())
+				return fmt.Errorf("failed to set Telegram webhook: %w", err)
+			}
+		} else {
+			go func() {
+				if err := telegramAdapter.Poll(ctx); err != nil && !errors.Is(err, context.Canceled) {
+					log.Printf("Warning: Telegram polling stopped: %v", err)
+				}
+			}()
+		}
+	}
+	server := &http.Server{Addr: o.Addr, Handler: handler}
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Serving API on %s", o.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("API server failed: %w", err)
+		}
+	}()
+
+	// SIGHUP, like POST /admin/reload, switches agents to the models,
+	// prompts, notification channels, webhooks and working hours now configured
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+	go func() {
+		for range reload {
+			result, err := reloadConfig(ctx)
+			if err != nil {
+				log.Printf("Warning: Reload incomplete: %v", err)
+			}
+			log.Printf("Reloaded configuration: %s", strings.Join(result.Reloaded, ", "))
+		}
+	}()
+
+	var failure error
+	select {
+	case <-ctx.Done():
+	case failure = <-serveErr:
+	}
+	log.Println("Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Warning: Failed to stop API server cleanly: %v", err)
+	}
+	if slackAdapter != nil {
+		if err := slackAdapter.Wait(shutdownCtx); err != nil {
+			log.Printf("Warning: Slack messages left unanswered: %v", err)
+		}
+	}
+	if telegramAdapter != nil {
+		if err := telegramAdapter.Wait(shutdownCtx); err != nil {
+			log.Printf("Warning: Telegram messages left unanswered: %v", err)
+		}
+	}
+	if discordAdapter != nil {
+		if err := discordAdapter.Wait(shutdownCtx); err != nil {
+			log.Printf("Warning: Discord commands left unanswered: %v", err)
+		}
+	}
+	if err := svc.Stop(shutdownCtx); err != nil {
+		log.Printf("Warning: Failed to stop service cleanly: %v", err)
+	}
+	return failure
+}
+
+// hasTool reports whether tools has one named name
+func hasTool(tools []multiagent.Tool, name string) bool {
+	for _, tool := range tools {
+		if tool.Name() == name {
+			return true
+		}
+	}
+	return false
+}
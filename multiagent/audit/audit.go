@@ -0,0 +1,221 @@
+// Package audit records an append-only log of what agents did on the user's
+// behalf, so every task, event, contact, message, and memory write can be
+// traced back to the agent that made it.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+const (
+	keyPrefix = "audit:"
+	// maxScannedEvents bounds a single Query scan
+	maxScannedEvents = 100000
+)
+
+// EventType names a kind of audited state change
+type EventType string
+
+const (
+	TaskCreated              EventType = "task.created"
+	TaskUpdated              EventType = "task.updated"
+	TaskCompleted            EventType = "task.completed"
+	TaskDeleted              EventType = "task.deleted"
+	TaskRestored             EventType = "task.restored"
+	ReminderCreated          EventType = "reminder.created"
+	ProjectCreated           EventType = "project.created"
+	ProjectUpdated           EventType = "project.updated"
+	TemplateSaved            EventType = "project.template_saved"
+	TemplateDeleted          EventType = "project.template_deleted"
+	EventScheduled           EventType = "calendar.event_scheduled"
+	EventCancelled           EventType = "calendar.event_cancelled"
+	EventRescheduled         EventType = "calendar.event_rescheduled"
+	CalendarImported         EventType = "calendar.imported"
+	TasksImported            EventType = "tasks.imported"
+	ContactAdded             EventType = "contact.added"
+	ContactsImported         EventType = "contacts.imported"
+	ContactsMerged           EventType = "contact.merged"
+	MessageDrafted           EventType = "communication.message_drafted"
+	MessageSent              EventType = "message.sent"
+	MessageFailed            EventType = "communication.message_failed"
+	MessageScheduled         EventType = "communication.message_scheduled"
+	MessageScheduleCancelled EventType = "communication.message_schedule_cancelled"
+	MessageTemplateSaved     EventType = "communication.template_saved"
+	MessageTemplateDeleted   EventType = "communication.template_deleted"
+	MessageReceived          EventType = "communication.message_received"
+	EmailSent                EventType = "communication.email_sent"
+	EmailFailed              EventType = "communication.email_failed"
+	FollowUpOpened           EventType = "communication.follow_up_opened"
+	FollowUpClosed           EventType = "communication.follow_up_closed"
+	ContactCadenceSet        EventType = "contact.cadence_set"
+	ActionProposed           EventType = "action.proposed"
+	ActionApproved           EventType = "action.approved"
+	ActionDeclined           EventType = "action.declined"
+	AccessDenied             EventType = "access.denied"
+	MemoryWritten            EventType = "memory.written"
+	MemoryDeleted            EventType = "memory.deleted"
+)
+
+// Event is a single audited action
+type Event struct {
+	ID             string                 `json:"id"`
+	Type           EventType              `json:"type"`
+	Actor          multiagent.AgentID     `json:"actor"`
+	Subject        string                 `json:"subject,omitempty"` // What the action touched, e.g. a task ID or memory key
+	ConversationID string                 `json:"conversation_id,omitempty"`
+	Timestamp      time.Time              `json:"timestamp"`
+	Payload        map[string]interface{} `json:"payload,omitempty"`
+}
+
+// Recorder accepts audit events
+type Recorder interface {
+	Record(ctx context.Context, event Event) error
+}
+
+// Filter selects events in Query; zero fields match everything
+type Filter struct {
+	Actor          multiagent.AgentID
+	Types          []EventType
+	Subject        string // Matches subjects with this prefix
+	ConversationID string
+	Since          time.Time
+	Until          time.Time
+	// Limit caps the number of events returned, newest first (default all)
+	Limit int
+}
+
+func (f Filter) matches(event *Event) bool {
+	if f.Actor != "" && event.Actor != f.Actor {
+		return false
+	}
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if event.Type == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Subject != "" && !strings.HasPrefix(event.Subject, f.Subject) {
+		return false
+	}
+	if f.ConversationID != "" && event.ConversationID != f.ConversationID {
+		return false
+	}
+	if !f.Since.IsZero() && event.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !event.Timestamp.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Log is an append-only audit log kept in a MemoryStore under "audit:" keys.
+// Events are never updated or deleted through it.
+type Log struct {
+	store    multiagent.MemoryStore
+	onRecord func(ctx context.Context, event Event)
+	mu       sync.Mutex
+	seq      uint64
+}
+
+// LogConfig holds configuration for creating an audit log
+type LogConfig struct {
+	Store multiagent.MemoryStore
+	// OnRecord, if set, is called after every event is recorded, e.g. to
+	// pass events on to webhooks
+	OnRecord func(ctx context.Context, event Event)
+}
+
+// NewLog creates an audit log on config.Store
+func NewLog(config LogConfig) *Log {
+	return &Log{store: config.Store, onRecord: config.OnRecord}
+}
+
+// Record appends event, filling in its ID and timestamp when unset
+func (l *Log) Record(ctx context.Context, event Event) error {
+	if event.Type == "" {
+		return fmt.Errorf("audit event has no type")
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	// Keys sort by time, so List returns events in the order they happened
+	l.mu.Lock()
+	l.seq++
+	key := fmt.Sprintf("%s%020d:%06d", keyPrefix, event.Timestamp.UnixNano(), l.seq%1000000)
+	l.mu.Unlock()
+	if event.ID == "" {
+		event.ID = strings.TrimPrefix(key, keyPrefix)
+	}
+
+	if err := l.store.Store(ctx, key, event); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	if l.onRecord != nil {
+		l.onRecord(ctx, event)
+	}
+	return nil
+}
+
+// Query returns events matching filter, newest first
+func (l *Log) Query(ctx context.Context, filter Filter) ([]Event, error) {
+	keys, err := l.store.List(ctx, keyPrefix, maxScannedEvents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+	var events []Event
+	for _, key := range keys {
+		value, err := l.store.Get(ctx, key)
+		if err != nil {
+			continue
+		}
+		event, err := decodeEvent(value)
+		if err != nil {
+			continue
+		}
+		if !filter.matches(event) {
+			continue
+		}
+		events = append(events, *event)
+		if filter.Limit > 0 && len(events) >= filter.Limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+func decodeEvent(value interface{}) (*Event, error) {
+	if event, ok := value.(Event); ok {
+		return &event, nil
+	}
+	if event, ok := value.(*Event); ok {
+		return event, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit event: %w", err)
+	}
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode audit event: %w", err)
+	}
+	return &event, nil
+}
@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+func newTestLog(t *testing.T) *Log {
+	t.Helper()
+	store, err := memory.NewSQLiteMemoryStore(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return NewLog(LogConfig{Store: store})
+}
+
+func TestLogQueriesNewestFirstWithFilters(t *testing.T) {
+	ctx := context.Background()
+	log := newTestLog(t)
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	events := []Event{
+		{Type: TaskCreated, Actor: "task_manager_agent", Subject: "task_1", ConversationID: "conv_alice", Timestamp: start},
+		{Type: EventScheduled, Actor: "scheduler_agent", Subject: "event_1", ConversationID: "conv_alice", Timestamp: start.Add(time.Minute)},
+		{Type: MemoryWritten, Actor: "scheduler_agent", Subject: "calendar_event:event_1", Timestamp: start.Add(2 * time.Minute)},
+		{Type: ContactAdded, Actor: "communication_manager_agent", Subject: "contact_1", ConversationID: "conv_bob", Timestamp: start.Add(3 * time.Minute)},
+	}
+	for _, event := range events {
+		if err := log.Record(ctx, event); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	all, err := log.Query(ctx, Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(all) != 4 || all[0].Type != ContactAdded || all[3].Type != TaskCreated {
+		t.Fatalf("expected all events newest first, got %+v", all)
+	}
+	if all[0].ID == "" {
+		t.Error("expected Record to assign an ID")
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []EventType
+	}{
+		{"actor", Filter{Actor: "scheduler_agent"}, []EventType{MemoryWritten, EventScheduled}},
+		{"types", Filter{Types: []EventType{TaskCreated, ContactAdded}}, []EventType{ContactAdded, TaskCreated}},
+		{"subject prefix", Filter{Subject: "calendar_event:"}, []EventType{MemoryWritten}},
+		{"conversation", Filter{ConversationID: "conv_alice"}, []EventType{EventScheduled, TaskCreated}},
+		{"window", Filter{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)}, []EventType{MemoryWritten, EventScheduled}},
+		{"limit", Filter{Limit: 1}, []EventType{ContactAdded}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := log.Query(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, event := range got {
+				if event.Type != tt.want[i] {
+					t.Errorf("event %d = %s, want %s", i, event.Type, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLogRejectsUntypedEvents(t *testing.T) {
+	if err := newTestLog(t).Record(context.Background(), Event{Actor: "scheduler_agent"}); err == nil {
+		t.Fatal("expected an error for an event without a type")
+	}
+}
+
+func TestLogCallsOnRecord(t *testing.T) {
+	store, err := memory.NewSQLiteMemoryStore(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	var recorded []Event
+	log := NewLog(LogConfig{Store: store, OnRecord: func(ctx context.Context, event Event) {
+		recorded = append(recorded, event)
+	}})
+
+	log.Record(context.Background(), Event{Actor: "task_manager_agent"})
+	if err := log.Record(context.Background(), Event{Type: TaskCompleted, Actor: "task_manager_agent", Subject: "task_1"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if len(recorded) != 1 || recorded[0].Type != TaskCompleted || recorded[0].ID == "" || recorded[0].Timestamp.IsZero() {
+		t.Fatalf("OnRecord got %+v, want the recorded event with its ID and timestamp", recorded)
+	}
+}
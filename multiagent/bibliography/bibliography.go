@@ -0,0 +1,257 @@
+// Package bibliography renders the sources of a piece of research as BibTeX,
+// for LaTeX and reference managers such as Zotero, or as a Markdown list to
+// paste into notes.
+package bibliography
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Export formats
+const (
+	FormatBibTeX   = "bibtex"
+	FormatMarkdown = "markdown"
+)
+
+const date = "2006-01-02"
+
+// Kind is what a source is, which picks its BibTeX entry type
+type Kind string
+
+const (
+	KindArticle      Kind = "article"
+	KindBook         Kind = "book"
+	KindReport       Kind = "report"
+	KindEncyclopedia Kind = "encyclopedia"
+	KindWeb          Kind = "web"
+)
+
+// Bibliography is the sources cited by a piece of research
+type Bibliography struct {
+	Title   string
+	Entries []Entry
+}
+
+// Entry is a cited source
+type Entry struct {
+	Kind   Kind
+	Title  string
+	Author string
+	// Publisher is the journal, publisher or site the source appeared in
+	Publisher string
+	URL       string
+	Published *time.Time
+	Accessed  time.Time
+	Note      string
+}
+
+// Write renders bib to w in format, one of FormatBibTeX and FormatMarkdown
+func Write(w io.Writer, format string, bib Bibliography) error {
+	switch format {
+	case FormatBibTeX:
+		return writeBibTeX(w, bib)
+	case FormatMarkdown:
+		return writeMarkdown(w, bib)
+	default:
+		return fmt.Errorf("unsupported bibliography format %q: use %s or %s", format, FormatBibTeX, FormatMarkdown)
+	}
+}
+
+// writeBibTeX writes one entry per source, keyed like "smith2024heat"
+func writeBibTeX(w io.Writer, bib Bibliography) error {
+	if bib.Title != "" {
+		if _, err := fmt.Fprintf(w, "%% %s\n\n", strings.ReplaceAll(bib.Title, "\n", " ")); err != nil {
+			return err
+		}
+	}
+	used := make(map[string]int)
+	for _, entry := range bib.Entries {
+		key := citeKey(entry)
+		used[key]++
+		if n := used[key]; n > 1 {
+			key += string(rune('a' + n - 2))
+		}
+
+		entryType := "misc"
+		switch entry.Kind {
+		case KindArticle:
+			entryType = "article"
+		case KindBook:
+			entryType = "book"
+		case KindReport:
+			entryType = "techreport"
+		}
+		fields := [][2]string{{"title", entry.Title}, {"author", entry.Author}}
+		switch entryType {
+		case "article":
+			fields = append(fields, [2]string{"journal", entry.Publisher})
+		case "book":
+			fields = append(fields, [2]string{"publisher", entry.Publisher})
+		case "techreport":
+			fields = append(fields, [2]string{"institution", entry.Publisher})
+		default:
+			fields = append(fields, [2]string{"howpublished", publisherOrSite(entry)})
+		}
+		if entry.Published != nil {
+			fields = append(fields,
+				[2]string{"year", entry.Published.Format("2006")},
+				[2]string{"month", strings.ToLower(entry.Published.Format("Jan"))})
+		}
+		fields = append(fields, [2]string{"url", entry.URL})
+		if entry.URL != "" && !entry.Accessed.IsZero() {
+			fields = append(fields, [2]string{"urldate", entry.Accessed.Format(date)})
+		}
+		fields = append(fields, [2]string{"note", entry.Note})
+
+		if _, err := fmt.Fprintf(w, "@%s{%s,\n", entryType, key); err != nil {
+			return err
+		}
+		for _, field := range fields {
+			if field[1] == "" {
+				continue
+			}
+			value := escapeBibTeX(field[1])
+			switch field[0] {
+			case "url":
+				// URLs are read verbatim by the url package
+				value = field[1]
+			case "month":
+				if _, err := fmt.Fprintf(w, "  %s = %s,\n", field[0], value); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "  %s = {%s},\n", field[0], value); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "}\n\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMarkdown writes a numbered list in an author-date style
+func writeMarkdown(w io.Writer, bib Bibliography) error {
+	title := "Bibliography"
+	if bib.Title != "" {
+		title += ": " + bib.Title
+	}
+	if _, err := fmt.Fprintf(w, "# %s\n\n", title); err != nil {
+		return err
+	}
+	if len(bib.Entries) == 0 {
+		_, err := io.WriteString(w, "No sources yet.\n")
+		return err
+	}
+	for i, entry := range bib.Entries {
+		var line strings.Builder
+		fmt.Fprintf(&line, "%d. ", i+1)
+		if entry.Author != "" {
+			line.WriteString(strings.TrimSuffix(entry.Author, ".") + ". ")
+		}
+		if entry.Published != nil {
+			fmt.Fprintf(&line, "(%s). ", entry.Published.Format("2006"))
+		}
+		title := strings.TrimSuffix(entry.Title, ".")
+		if title == "" {
+			title = "Untitled"
+		}
+		if entry.URL != "" {
+			fmt.Fprintf(&line, "[%s](%s). ", title, entry.URL)
+		} else {
+			fmt.Fprintf(&line, "*%s*. ", title)
+		}
+		if publisher := publisherOrSite(entry); publisher != "" {
+			line.WriteString(publisher + ". ")
+		}
+		if entry.URL != "" && !entry.Accessed.IsZero() {
+			fmt.Fprintf(&line, "Accessed %s. ", entry.Accessed.Format(date))
+		}
+		if entry.Note != "" {
+			line.WriteString(strings.TrimSuffix(entry.Note, ".") + ".")
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimSpace(line.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// citeKey builds a key from the first author's surname, the year and the
+// first significant word of the title
+func citeKey(entry Entry) string {
+	var key strings.Builder
+	author := strings.Split(entry.Author, " and ")[0]
+	if last, _, ok := strings.Cut(author, ","); ok {
+		author = last
+	} else if fields := strings.Fields(author); len(fields) > 0 {
+		author = fields[len(fields)-1]
+	}
+	if author == "" {
+		author = publisherOrSite(entry)
+	}
+	key.WriteString(keyWord(author))
+	if entry.Published != nil {
+		key.WriteString(entry.Published.Format("2006"))
+	}
+	for _, word := range strings.Fields(entry.Title) {
+		word = keyWord(word)
+		if len(word) > 3 && !titleStopwords[word] {
+			key.WriteString(word)
+			break
+		}
+	}
+	if key.Len() == 0 {
+		return "source"
+	}
+	return key.String()
+}
+
+var titleStopwords = map[string]bool{"about": true, "from": true, "into": true, "that": true, "their": true, "this": true, "what": true, "when": true, "with": true}
+
+// keyWord lowercases word and keeps only its ASCII letters and digits
+func keyWord(word string) string {
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, word)
+}
+
+// publisherOrSite returns the entry's publisher, or the site its URL is on
+func publisherOrSite(entry Entry) string {
+	if entry.Publisher != "" {
+		return entry.Publisher
+	}
+	if entry.Kind == KindEncyclopedia && strings.Contains(entry.URL, "wikipedia.org") {
+		return "Wikipedia"
+	}
+	if u, err := url.Parse(entry.URL); err == nil && u.Host != "" {
+		return strings.TrimPrefix(u.Host, "www.")
+	}
+	return ""
+}
+
+// escapeBibTeX escapes the characters LaTeX treats specially
+func escapeBibTeX(s string) string {
+	return strings.NewReplacer(
+		`\`, `\textbackslash{}`,
+		"{", `\{`,
+		"}", `\}`,
+		"&", `\&`,
+		"%", `\%`,
+		"$", `\$`,
+		"#", `\#`,
+		"_", `\_`,
+		"\n", " ",
+	).Replace(s)
+}
@@ -0,0 +1,79 @@
+package bibliography
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func sampleBibliography() Bibliography {
+	published := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	accessed := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+	return Bibliography{
+		Title: "Heat pumps for cold climates",
+		Entries: []Entry{
+			{Kind: KindArticle, Title: "Heat pumps & the 50% question", Author: "Smith, Jane and Lee, Ann", Publisher: "Energy Journal",
+				URL: "https://example.org/hp_study", Published: &published, Accessed: accessed},
+			{Kind: KindEncyclopedia, Title: "Heat pump", URL: "https://en.wikipedia.org/wiki/Heat_pump", Accessed: accessed},
+			{Kind: KindWeb, Title: "Heat pumps explained", Author: "Jane Smith", Published: &published, Note: "Vendor overview"},
+		},
+	}
+}
+
+func TestWriteBibTeX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatBibTeX, sampleBibliography()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := `% Heat pumps for cold climates
+
+@article{smith2024heat,
+  title = {Heat pumps \& the 50\% question},
+  author = {Smith, Jane and Lee, Ann},
+  journal = {Energy Journal},
+  year = {2024},
+  month = mar,
+  url = {https://example.org/hp_study},
+  urldate = {2026-05-04},
+}
+
+@misc{wikipediaheat,
+  title = {Heat pump},
+  howpublished = {Wikipedia},
+  url = {https://en.wikipedia.org/wiki/Heat_pump},
+  urldate = {2026-05-04},
+}
+
+@misc{smith2024heata,
+  title = {Heat pumps explained},
+  author = {Jane Smith},
+  year = {2024},
+  month = mar,
+  note = {Vendor overview},
+}
+
+`
+	if got := buf.String(); got != want {
+		t.Errorf("BibTeX:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatMarkdown, sampleBibliography()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := `# Bibliography: Heat pumps for cold climates
+
+1. Smith, Jane and Lee, Ann. (2024). [Heat pumps & the 50% question](https://example.org/hp_study). Energy Journal. Accessed 2026-05-04.
+2. [Heat pump](https://en.wikipedia.org/wiki/Heat_pump). Wikipedia. Accessed 2026-05-04.
+3. Jane Smith. (2024). *Heat pumps explained*. Vendor overview.
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Markdown:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := Write(&buf, "ris", sampleBibliography()); err == nil {
+		t.Error("Write accepted an unknown format")
+	}
+}
@@ -0,0 +1,304 @@
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/ical"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+// fakeServer is a minimal CalDAV collection at /cal/
+type fakeServer struct {
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	version int
+}
+
+type fakeObject struct {
+	etag string
+	data []byte
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user, password, _ := r.BasicAuth(); user != "alice" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	object := f.objects[r.URL.Path]
+	switch r.Method {
+	case "REPORT":
+		var body strings.Builder
+		body.WriteString(`<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+		for href, object := range f.objects {
+			fmt.Fprintf(&body, `<d:response><d:href>%s</d:href><d:propstat><d:prop><d:getetag>%s</d:getetag><c:calendar-data>%s</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+				href, object.etag, xmlEscape(string(object.data)))
+		}
+		body.WriteString(`</d:multistatus>`)
+		w.WriteHeader(http.StatusMultiStatus)
+		io.WriteString(w, body.String())
+	case http.MethodPut:
+		if match := r.Header.Get("If-Match"); match != "" && (object == nil || object.etag != match) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && object != nil {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		etag := f.store(r.URL.Path, data)
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if object == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != "" && object.etag != match {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeServer) store(href string, data []byte) string {
+	f.version++
+	etag := fmt.Sprintf(`"v%d"`, f.version)
+	f.objects[href] = &fakeObject{etag: etag, data: data}
+	return etag
+}
+
+// put changes an event on the server, as another client would
+func (f *fakeServer) put(t *testing.T, href string, event ical.Event) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := ical.Encode(&buf, &ical.Calendar{Events: []ical.Event{event}}); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.store(href, buf.Bytes())
+}
+
+func (f *fakeServer) event(t *testing.T, href string) *ical.Event {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, ok := f.objects[href]
+	if !ok {
+		return nil
+	}
+	cal, err := ical.Decode(bytes.NewReader(object.data))
+	if err != nil || len(cal.Events) != 1 {
+		t.Fatalf("server holds an unreadable object at %s: %v", href, err)
+	}
+	return &cal.Events[0]
+}
+
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// fakeCalendar is the local side, stamping changes like the scheduler does
+type fakeCalendar struct {
+	events map[string]ical.Event
+}
+
+func (c *fakeCalendar) ICSEvents(ctx context.Context) ([]ical.Event, error) {
+	var events []ical.Event
+	for _, event := range c.events {
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (c *fakeCalendar) ApplyICSEvents(ctx context.Context, events []ical.Event) error {
+	for _, event := range events {
+		event.LastModified = time.Now()
+		c.events[event.UID] = event
+	}
+	return nil
+}
+
+func (c *fakeCalendar) change(uid, summary string) {
+	event := c.events[uid]
+	event.Summary = summary
+	event.LastModified = time.Now()
+	c.events[uid] = event
+}
+
+func newTestSyncer(t *testing.T, policy ConflictPolicy) (*Syncer, *fakeServer, *fakeCalendar) {
+	t.Helper()
+	server := &fakeServer{objects: map[string]*fakeObject{}}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	client, err := NewClient(ClientConfig{CalendarURL: httpServer.URL + "/cal", Username: "alice", Password: "secret"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	store, err := memory.NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	calendar := &fakeCalendar{events: map[string]ical.Event{}}
+	syncer := NewSyncer(SyncerConfig{
+		Name:     "test",
+		UserID:   "alice",
+		Client:   client,
+		Calendar: calendar,
+		Store:    memory.PartitionByUser(store),
+		Policy:   policy,
+	})
+	return syncer, server, calendar
+}
+
+func testEvent(uid, summary string, modified time.Time) ical.Event {
+	start := time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC)
+	return ical.Event{
+		UID:          uid,
+		Summary:      summary,
+		Start:        start,
+		End:          start.Add(time.Hour),
+		Status:       ical.StatusConfirmed,
+		RRule:        &ical.RRule{Freq: ical.FreqWeekly, ByDay: []ical.WeekdayNum{{Day: time.Tuesday}}},
+		LastModified: modified,
+	}
+}
+
+func expectSync(t *testing.T, syncer *Syncer, want SyncResult) {
+	t.Helper()
+	result, err := syncer.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if result != want {
+		t.Errorf("Sync = %+v, want %+v", result, want)
+	}
+}
+
+func TestSync_TwoWay(t *testing.T) {
+	syncer, server, calendar := newTestSyncer(t, ConflictNewest)
+	past := time.Now().Add(-time.Hour)
+	calendar.events["local@wikillm"] = testEvent("local@wikillm", "Standup", past)
+	server.put(t, "/cal/remote.ics", testEvent("remote@example.com", "Dentist", past))
+
+	expectSync(t, syncer, SyncResult{Pulled: 1, Pushed: 1})
+	if got := server.event(t, "/cal/local@wikillm.ics"); got == nil || got.Summary != "Standup" || got.RRule == nil || got.RRule.String() != "FREQ=WEEKLY;BYDAY=TU" {
+		t.Errorf("expected the local event with its rule on the server, got %+v", got)
+	}
+	if got := calendar.events["remote@example.com"]; got.Summary != "Dentist" {
+		t.Errorf("expected the server event locally, got %+v", got)
+	}
+
+	// Nothing changed since
+	expectSync(t, syncer, SyncResult{})
+
+	// One change on each side
+	server.put(t, "/cal/remote.ics", testEvent("remote@example.com", "Dentist (moved)", time.Now()))
+	calendar.change("local@wikillm", "Standup (short)")
+	expectSync(t, syncer, SyncResult{Pulled: 1, Pushed: 1})
+	if got := calendar.events["remote@example.com"]; got.Summary != "Dentist (moved)" {
+		t.Errorf("expected the server change locally, got %q", got.Summary)
+	}
+	if got := server.event(t, "/cal/local@wikillm.ics"); got.Summary != "Standup (short)" {
+		t.Errorf("expected the local change on the server, got %q", got.Summary)
+	}
+
+	// A deletion on each side
+	server.mu.Lock()
+	delete(server.objects, "/cal/remote.ics")
+	server.mu.Unlock()
+	cancelled := calendar.events["local@wikillm"]
+	cancelled.Status = ical.StatusCancelled
+	cancelled.LastModified = time.Now()
+	calendar.events["local@wikillm"] = cancelled
+	expectSync(t, syncer, SyncResult{Deleted: 2})
+	if got := calendar.events["remote@example.com"]; got.Status != ical.StatusCancelled {
+		t.Errorf("expected the event deleted on the server to be cancelled locally, got %q", got.Status)
+	}
+	if got := server.event(t, "/cal/local@wikillm.ics"); got != nil {
+		t.Errorf("expected the locally cancelled event to be deleted on the server, got %+v", got)
+	}
+	expectSync(t, syncer, SyncResult{})
+}
+
+func TestSync_Conflicts(t *testing.T) {
+	for _, tt := range []struct {
+		policy      ConflictPolicy
+		remoteLater bool
+		want        string
+	}{
+		{ConflictNewest, true, "theirs"},
+		{ConflictNewest, false, "ours"},
+		{ConflictLocal, true, "ours"},
+		{ConflictRemote, false, "theirs"},
+	} {
+		t.Run(fmt.Sprintf("%s/remote_later=%v", tt.policy, tt.remoteLater), func(t *testing.T) {
+			syncer, server, calendar := newTestSyncer(t, tt.policy)
+			calendar.events["e@wikillm"] = testEvent("e@wikillm", "original", time.Now().Add(-time.Hour))
+			expectSync(t, syncer, SyncResult{Pushed: 1})
+
+			ours, theirs := time.Now().Add(-time.Minute), time.Now()
+			if !tt.remoteLater {
+				ours, theirs = theirs, ours
+			}
+			calendar.change("e@wikillm", "ours")
+			local := calendar.events["e@wikillm"]
+			local.LastModified = ours
+			calendar.events["e@wikillm"] = local
+			server.put(t, "/cal/e@wikillm.ics", testEvent("e@wikillm", "theirs", theirs))
+
+			result, err := syncer.Sync(context.Background())
+			if err != nil {
+				t.Fatalf("Sync: %v", err)
+			}
+			if result.Conflicts != 1 {
+				t.Errorf("expected a conflict, got %+v", result)
+			}
+			if local, remote := calendar.events["e@wikillm"].Summary, server.event(t, "/cal/e@wikillm.ics").Summary; local != tt.want || remote != tt.want {
+				t.Errorf("expected %q on both sides, got %q locally and %q on the server", tt.want, local, remote)
+			}
+			expectSync(t, syncer, SyncResult{})
+		})
+	}
+}
+
+func TestClient_StaleETag(t *testing.T) {
+	syncer, server, _ := newTestSyncer(t, ConflictNewest)
+	client := syncer.config.Client
+	ctx := context.Background()
+
+	etag, err := client.Put(ctx, "/cal/x.ics", []byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"), "")
+	if err != nil || etag == "" {
+		t.Fatalf("Put: %q, %v", etag, err)
+	}
+	if _, err := client.Put(ctx, "/cal/x.ics", []byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"), ""); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("creating an existing object: expected ErrPreconditionFailed, got %v", err)
+	}
+	server.put(t, "/cal/x.ics", testEvent("x", "changed elsewhere", time.Now()))
+	if _, err := client.Put(ctx, "/cal/x.ics", []byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"), etag); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("replacing with a stale ETag: expected ErrPreconditionFailed, got %v", err)
+	}
+	if err := client.Delete(ctx, "/cal/x.ics", etag); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("deleting with a stale ETag: expected ErrPreconditionFailed, got %v", err)
+	}
+}
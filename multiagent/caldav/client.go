@@ -0,0 +1,204 @@
+// Package caldav keeps the assistant's calendar in two-way sync with a
+// CalDAV calendar collection (Fastmail, Nextcloud, iCloud and the like).
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+var logger = logging.For("caldav")
+
+// ErrPreconditionFailed is returned when an object changed on the server
+// since its ETag was read, or already exists when creating it
+var ErrPreconditionFailed = errors.New("caldav: precondition failed")
+
+// calendarQuery asks a collection for every event with its ETag and data
+const calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop>
+    <d:getetag/>
+    <c:calendar-data/>
+  </d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT"/>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`
+
+// ClientConfig holds configuration for creating a Client
+type ClientConfig struct {
+	// CalendarURL is the calendar collection, e.g.
+	// https://caldav.fastmail.com/dav/calendars/user/me@fastmail.com/Default/
+	CalendarURL string
+	Username    string
+	// Password is usually an app-specific password
+	Password string
+	// Timeout bounds each request (default 30s)
+	Timeout time.Duration
+}
+
+// Object is one calendar object resource in the collection
+type Object struct {
+	// Href is the object's URL path on the server
+	Href string
+	ETag string
+	Data []byte
+}
+
+// Client talks to one CalDAV calendar collection
+type Client struct {
+	config ClientConfig
+	base   *url.URL
+	http   *http.Client
+}
+
+// NewClient creates a client for the collection at config.CalendarURL
+func NewClient(config ClientConfig) (*Client, error) {
+	base, err := url.Parse(config.CalendarURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid calendar URL %q", config.CalendarURL)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &Client{config: config, base: base, http: &http.Client{Timeout: config.Timeout}}, nil
+}
+
+// EventHref returns where a new event with uid is stored
+func (c *Client) EventHref(uid string) string {
+	return c.base.Path + url.PathEscape(uid) + ".ics"
+}
+
+// List returns every event object in the collection
+func (c *Client) List(ctx context.Context) ([]Object, error) {
+	resp, err := c.do(ctx, "REPORT", c.base.Path, strings.NewReader(calendarQuery), map[string]string{
+		"Content-Type": "application/xml; charset=utf-8",
+		"Depth":        "1",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, statusError("REPORT", c.base.Path, resp)
+	}
+
+	var status struct {
+		Responses []struct {
+			Href      string `xml:"href"`
+			Propstats []struct {
+				Status string `xml:"status"`
+				Prop   struct {
+					ETag         string `xml:"getetag"`
+					CalendarData string `xml:"calendar-data"`
+				} `xml:"prop"`
+			} `xml:"propstat"`
+		} `xml:"response"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to parse calendar listing: %w", err)
+	}
+
+	var objects []Object
+	for _, response := range status.Responses {
+		for _, propstat := range response.Propstats {
+			if propstat.Prop.CalendarData == "" || (propstat.Status != "" && !strings.Contains(propstat.Status, " 200")) {
+				continue
+			}
+			objects = append(objects, Object{
+				Href: response.Href,
+				ETag: propstat.Prop.ETag,
+				Data: []byte(propstat.Prop.CalendarData),
+			})
+		}
+	}
+	return objects, nil
+}
+
+// Put stores data at href. With an etag the object is only replaced if it
+// is unchanged on the server; without one it is only created if it does not
+// exist yet. It returns the object's new ETag, or "" if the server does not
+// report it.
+func (c *Client) Put(ctx context.Context, href string, data []byte, etag string) (string, error) {
+	headers := map[string]string{"Content-Type": "text/calendar; charset=utf-8"}
+	if etag != "" {
+		headers["If-Match"] = etag
+	} else {
+		headers["If-None-Match"] = "*"
+	}
+	resp, err := c.do(ctx, http.MethodPut, href, bytes.NewReader(data), headers)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", statusError(http.MethodPut, href, resp)
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// Delete removes the object at href if it is unchanged since etag
+func (c *Client) Delete(ctx context.Context, href, etag string) error {
+	headers := map[string]string{}
+	if etag != "" {
+		headers["If-Match"] = etag
+	}
+	resp, err := c.do(ctx, http.MethodDelete, href, nil, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError(http.MethodDelete, href, resp)
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, href string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	target, err := c.base.Parse(href)
+	if err != nil {
+		return nil, fmt.Errorf("invalid href %q: %w", href, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if c.config.Username != "" || c.config.Password != "" {
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("caldav %s %s failed: %w", method, href, err)
+	}
+	return resp, nil
+}
+
+func statusError(method, href string, resp *http.Response) error {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return fmt.Errorf("caldav %s %s: %w", method, href, ErrPreconditionFailed)
+	}
+	return fmt.Errorf("caldav %s %s: %s", method, href, resp.Status)
+}
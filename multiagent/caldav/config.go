@@ -0,0 +1,77 @@
+package caldav
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Account is one user's CalDAV calendar to keep in sync
+type Account struct {
+	// Name identifies the account; it must be unique per user
+	Name        string `json:"name"`
+	UserID      string `json:"user"`
+	CalendarURL string `json:"url"`
+	Username    string `json:"username"`
+	// Password may reference environment variables, e.g. "$FASTMAIL_APP_PASSWORD"
+	Password string         `json:"password"`
+	Interval Duration       `json:"interval"`
+	Policy   ConflictPolicy `json:"conflict_policy"`
+}
+
+// Duration is a time.Duration read from JSON as a string such as "15m"
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string such as \"15m\": %w", err)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// LoadAccounts reads accounts from a JSON file:
+//
+//	{"accounts": [{"name": "fastmail", "user": "alice",
+//	  "url": "https://caldav.fastmail.com/dav/calendars/user/alice@fastmail.com/Default/",
+//	  "username": "alice@fastmail.com", "password": "$FASTMAIL_APP_PASSWORD",
+//	  "interval": "10m", "conflict_policy": "newest"}]}
+func LoadAccounts(path string) ([]Account, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read caldav config: %w", err)
+	}
+	var file struct {
+		Accounts []Account `json:"accounts"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse caldav config: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range file.Accounts {
+		account := &file.Accounts[i]
+		if account.Name == "" || account.CalendarURL == "" {
+			return nil, fmt.Errorf("caldav account %d needs a name and a url", i+1)
+		}
+		key := account.UserID + "/" + account.Name
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate caldav account %q for user %q", account.Name, account.UserID)
+		}
+		seen[key] = true
+		switch account.Policy {
+		case "", ConflictNewest, ConflictRemote, ConflictLocal:
+		default:
+			return nil, fmt.Errorf("caldav account %q: unknown conflict policy %q", account.Name, account.Policy)
+		}
+		account.Password = os.ExpandEnv(account.Password)
+	}
+	return file.Accounts, nil
+}
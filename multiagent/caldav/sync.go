@@ -0,0 +1,382 @@
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/ical"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// stateKeyPrefix holds, per account, what each synced event looked like on
+// both sides after the last sync
+const stateKeyPrefix = "caldav_sync:"
+
+// LocalCalendar is the assistant's side of a sync
+type LocalCalendar interface {
+	// ICSEvents returns every event of the user ctx acts for, cancelled ones
+	// with STATUS:CANCELLED; LastModified tells when each last changed
+	ICSEvents(ctx context.Context) ([]ical.Event, error)
+	// ApplyICSEvents adds events to, or updates them by UID in, the calendar
+	// of the user ctx acts for
+	ApplyICSEvents(ctx context.Context, events []ical.Event) error
+}
+
+// ConflictPolicy decides which side wins when an event changed both locally
+// and on the server since the last sync
+type ConflictPolicy string
+
+const (
+	// ConflictNewest keeps whichever side was modified last (the default)
+	ConflictNewest ConflictPolicy = "newest"
+	// ConflictRemote always keeps the server's version
+	ConflictRemote ConflictPolicy = "remote"
+	// ConflictLocal always keeps the assistant's version
+	ConflictLocal ConflictPolicy = "local"
+)
+
+// SyncerConfig holds configuration for creating a Syncer
+type SyncerConfig struct {
+	// Name identifies the account in logs and in the stored sync state
+	Name   string
+	UserID string
+	Client *Client
+	// Calendar is the local calendar, usually the scheduler agent
+	Calendar LocalCalendar
+	// Store keeps the sync state; it is read and written acting for UserID
+	Store multiagent.MemoryStore
+	// Interval is how often Start syncs (default 15 minutes)
+	Interval time.Duration
+	Policy   ConflictPolicy
+}
+
+// SyncResult reports what one sync changed
+type SyncResult struct {
+	Pulled    int `json:"pulled"`
+	Pushed    int `json:"pushed"`
+	Deleted   int `json:"deleted"`
+	Conflicts int `json:"conflicts"`
+}
+
+// syncedEvent is an event's state after the last sync
+type syncedEvent struct {
+	Href string `json:"href"`
+	// ETag is the server's version; empty when the server did not report it
+	ETag string `json:"etag"`
+	// LocalModified is the local event's LastModified
+	LocalModified time.Time `json:"local_modified"`
+}
+
+// syncState is stored per account
+type syncState struct {
+	Events   map[string]syncedEvent `json:"events"`
+	LastSync time.Time              `json:"last_sync"`
+}
+
+// remoteEvent is a decoded server object
+type remoteEvent struct {
+	Object
+	Event ical.Event
+}
+
+// Syncer keeps one user's calendar in sync with one CalDAV collection
+type Syncer struct {
+	config SyncerConfig
+	syncMu sync.Mutex // serialises syncs
+
+	mu       sync.Mutex
+	running  bool
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewSyncer creates a syncer for config's account
+func NewSyncer(config SyncerConfig) *Syncer {
+	if config.Interval <= 0 {
+		config.Interval = 15 * time.Minute
+	}
+	if config.Policy == "" {
+		config.Policy = ConflictNewest
+	}
+	return &Syncer{config: config}
+}
+
+// Start syncs immediately and then on every interval until Stop
+func (s *Syncer) Start(ctx context.Context) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.stopChan = make(chan struct{})
+	stop := s.stopChan
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if result, err := s.Sync(ctx); err != nil {
+				logger.WarnContext(ctx, "CalDAV sync failed", "account", s.config.Name, logging.KeyUserID, s.config.UserID, "error", err)
+			} else if result.Pulled+result.Pushed+result.Deleted+result.Conflicts > 0 {
+				logger.InfoContext(ctx, "CalDAV sync finished", "account", s.config.Name, logging.KeyUserID, s.config.UserID,
+					"pulled", result.Pulled, "pushed", result.Pushed, "deleted", result.Deleted, "conflicts", result.Conflicts)
+			}
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops syncing after any sync in progress
+func (s *Syncer) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// Sync reconciles the local calendar with the server once. Changes on one
+// side are copied to the other; an event changed on both is settled by the
+// conflict policy, and a write that loses a race with another client (its
+// ETag no longer matches) is retried on the next sync. Server-side
+// overrides of single occurrences are not synced, and pushing a recurring
+// event replaces any the server had.
+func (s *Syncer) Sync(ctx context.Context) (SyncResult, error) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	ctx = multiagent.WithUserID(ctx, s.config.UserID)
+	var result SyncResult
+
+	state, err := s.loadState(ctx)
+	if err != nil {
+		return result, err
+	}
+	remote, err := s.fetchRemote(ctx)
+	if err != nil {
+		return result, err
+	}
+	localEvents, err := s.config.Calendar.ICSEvents(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to read local calendar: %w", err)
+	}
+	local := make(map[string]ical.Event, len(localEvents))
+	for _, event := range localEvents {
+		local[event.UID] = event
+	}
+
+	next := make(map[string]syncedEvent)
+	// Local changes that failed to push keep their old LocalModified, so the
+	// next sync still sees them as changed
+	unpushed := make(map[string]time.Time)
+	var pull []ical.Event
+	for uid, theirs := range remote {
+		previous, known := state.Events[uid]
+		next[uid] = syncedEvent{Href: theirs.Href, ETag: theirs.ETag}
+		ours, hasLocal := local[uid]
+		remoteChanged := !known || (previous.ETag != "" && previous.ETag != theirs.ETag)
+		localChanged := hasLocal && (!known || ours.LastModified.After(previous.LocalModified))
+
+		switch {
+		case !hasLocal || (remoteChanged && !localChanged):
+			pull = append(pull, theirs.Event)
+			result.Pulled++
+		case remoteChanged && localChanged:
+			result.Conflicts++
+			if s.remoteWins(ours, theirs.Event) {
+				pull = append(pull, theirs.Event)
+				result.Pulled++
+				continue
+			}
+			if !s.push(ctx, uid, ours, theirs.Href, theirs.ETag, next, &result) {
+				unpushed[uid] = previous.LocalModified
+			}
+		case localChanged:
+			if !s.push(ctx, uid, ours, theirs.Href, theirs.ETag, next, &result) {
+				unpushed[uid] = previous.LocalModified
+			}
+		}
+	}
+
+	for uid, ours := range local {
+		if _, onServer := remote[uid]; onServer {
+			continue
+		}
+		previous, known := state.Events[uid]
+		switch {
+		case known && !ours.LastModified.After(previous.LocalModified):
+			// Deleted on the server since the last sync
+			if ours.Status != ical.StatusCancelled {
+				ours.Status = ical.StatusCancelled
+				pull = append(pull, ours)
+				result.Deleted++
+			}
+		case ours.Status == ical.StatusCancelled:
+			// Never synced, or gone on both sides
+		default:
+			href := previous.Href
+			if href == "" {
+				href = s.config.Client.EventHref(uid)
+			}
+			if !s.push(ctx, uid, ours, href, "", next, &result) {
+				delete(next, uid)
+			}
+		}
+	}
+
+	if len(pull) > 0 {
+		if err := s.config.Calendar.ApplyICSEvents(ctx, pull); err != nil {
+			return result, fmt.Errorf("failed to apply server changes: %w", err)
+		}
+	}
+
+	// Record what the local events look like now, including what was pulled
+	localEvents, err = s.config.Calendar.ICSEvents(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to read local calendar: %w", err)
+	}
+	for _, event := range localEvents {
+		if synced, ok := next[event.UID]; ok {
+			synced.LocalModified = event.LastModified
+			if modified, failed := unpushed[event.UID]; failed {
+				synced.LocalModified = modified
+			}
+			next[event.UID] = synced
+		}
+	}
+	return result, s.saveState(ctx, syncState{Events: next, LastSync: time.Now()})
+}
+
+// push writes a local event to the server, deleting it there if it was
+// cancelled locally; etag is the server version it replaces, if any. It
+// reports whether the server accepted the change.
+func (s *Syncer) push(ctx context.Context, uid string, event ical.Event, href, etag string, next map[string]syncedEvent, result *SyncResult) bool {
+	if event.Status == ical.StatusCancelled {
+		if err := s.config.Client.Delete(ctx, href, etag); err != nil {
+			s.pushFailed(ctx, uid, err, result)
+			return false
+		}
+		delete(next, uid)
+		result.Deleted++
+		return true
+	}
+
+	var buf bytes.Buffer
+	if err := ical.Encode(&buf, &ical.Calendar{Events: []ical.Event{event}}); err != nil {
+		s.pushFailed(ctx, uid, err, result)
+		return false
+	}
+	newETag, err := s.config.Client.Put(ctx, href, buf.Bytes(), etag)
+	if err != nil {
+		s.pushFailed(ctx, uid, err, result)
+		return false
+	}
+	next[uid] = syncedEvent{Href: href, ETag: newETag}
+	result.Pushed++
+	return true
+}
+
+// pushFailed notes a write the next sync will retry; losing a race for the
+// ETag is a conflict, which the next sync settles by policy
+func (s *Syncer) pushFailed(ctx context.Context, uid string, err error, result *SyncResult) {
+	if errors.Is(err, ErrPreconditionFailed) {
+		result.Conflicts++
+		return
+	}
+	logger.WarnContext(ctx, "Failed to push event to CalDAV server", "account", s.config.Name, "uid", uid, "error", err)
+}
+
+// remoteWins settles a conflict
+func (s *Syncer) remoteWins(ours, theirs ical.Event) bool {
+	switch s.config.Policy {
+	case ConflictLocal:
+		return false
+	case ConflictRemote:
+		return true
+	default:
+		// A server version without LAST-MODIFIED is taken as authoritative
+		return theirs.LastModified.IsZero() || !ours.LastModified.After(theirs.LastModified)
+	}
+}
+
+// fetchRemote lists the server's events by UID
+func (s *Syncer) fetchRemote(ctx context.Context) (map[string]remoteEvent, error) {
+	objects, err := s.config.Client.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list server events: %w", err)
+	}
+
+	remote := make(map[string]remoteEvent, len(objects))
+	for _, object := range objects {
+		cal, err := ical.Decode(bytes.NewReader(object.Data))
+		if err != nil {
+			logger.WarnContext(ctx, "Skipping unreadable CalDAV object", "account", s.config.Name, "href", object.Href, "error", err)
+			continue
+		}
+		for _, event := range cal.Events {
+			// The master event; overrides of single occurrences are skipped
+			if event.RecurrenceID == nil {
+				remote[event.UID] = remoteEvent{Object: object, Event: event}
+				break
+			}
+		}
+	}
+	return remote, nil
+}
+
+func (s *Syncer) stateKey() string {
+	return stateKeyPrefix + s.config.Name
+}
+
+func (s *Syncer) loadState(ctx context.Context) (syncState, error) {
+	state := syncState{Events: map[string]syncedEvent{}}
+	value, err := s.config.Store.Get(ctx, s.stateKey())
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return state, nil
+		}
+		return state, fmt.Errorf("failed to load sync state: %w", err)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return state, fmt.Errorf("failed to marshal sync state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to unmarshal sync state: %w", err)
+	}
+	if state.Events == nil {
+		state.Events = map[string]syncedEvent{}
+	}
+	return state, nil
+}
+
+func (s *Syncer) saveState(ctx context.Context, state syncState) error {
+	if err := s.config.Store.Store(ctx, s.stateKey(), state); err != nil {
+		return fmt.Errorf("failed to save sync state: %w", err)
+	}
+	return nil
+}
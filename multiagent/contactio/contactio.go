@@ -0,0 +1,100 @@
+// Package contactio reads and writes address books as vCard (.vcf) files
+// and as CSV in the layout of Google Contacts' export — which Outlook's
+// and most other apps' exports resemble closely enough to read — so users
+// can bring their contacts into the assistant and take them out again.
+package contactio
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Contact is a person in a form every supported format can carry
+type Contact struct {
+	// ID is the vCard UID, if the file had one
+	ID         string
+	Name       string
+	GivenName  string
+	FamilyName string
+	// Emails and Phones hold the primary address or number first
+	Emails       []string
+	Phones       []string
+	Organization string
+	Title        string
+	Notes        string
+	Labels       []string
+}
+
+// DisplayName returns the contact's name, built from its parts or taken
+// from its first email address when it has none
+func (c Contact) DisplayName() string {
+	if name := strings.TrimSpace(c.Name); name != "" {
+		return name
+	}
+	if name := strings.TrimSpace(c.GivenName + " " + c.FamilyName); name != "" {
+		return name
+	}
+	if len(c.Emails) > 0 {
+		return c.Emails[0]
+	}
+	return ""
+}
+
+// splitName returns the given and family names of c, splitting its name
+// at the last space when the file didn't
+func (c Contact) splitName() (string, string) {
+	if c.GivenName != "" || c.FamilyName != "" {
+		return c.GivenName, c.FamilyName
+	}
+	name := strings.TrimSpace(c.Name)
+	if i := strings.LastIndex(name, " "); i > 0 {
+		return strings.TrimSpace(name[:i]), name[i+1:]
+	}
+	return name, ""
+}
+
+// NormalizeEmail returns an email address in the form duplicates are found
+// by
+func NormalizeEmail(address string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(address), "mailto:")))
+}
+
+// NormalizePhone returns a phone number in the form duplicates are found
+// by: its last ten digits, so "+1 (555) 123-4567" and "555.123.4567"
+// match, or "" when it has too few digits to tell numbers apart
+func NormalizePhone(number string) string {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, strings.TrimPrefix(strings.TrimSpace(number), "tel:"))
+	if len(digits) < 7 {
+		return ""
+	}
+	if len(digits) > 10 {
+		digits = digits[len(digits)-10:]
+	}
+	return digits
+}
+
+// appendUnique appends the values not already in list, compared by key
+func appendUnique(list []string, key func(string) string, values ...string) []string {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		seen := false
+		for _, existing := range list {
+			if key(existing) == key(value) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			list = append(list, value)
+		}
+	}
+	return list
+}
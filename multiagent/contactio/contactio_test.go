@@ -0,0 +1,153 @@
+package contactio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func sampleContacts() []Contact {
+	return []Contact{
+		{
+			ID:           "contact_1",
+			Name:         "Jane Doe",
+			Emails:       []string{"jane@example.com", "jane.doe@work.example"},
+			Phones:       []string{"+1 555 123 4567"},
+			Organization: "Acme; Inc",
+			Title:        "CTO",
+			Notes:        "Met at the conference,\nlikes \"tea\"",
+			Labels:       []string{"work", "vip"},
+		},
+		{ID: "contact_2", Name: "Bob", Phones: []string{"555-987-6543", "020 7946 0018"}},
+	}
+}
+
+func checkContacts(t *testing.T, got, want []Contact, withIDs bool) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d contacts, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Name != w.Name || g.Organization != w.Organization || g.Title != w.Title || g.Notes != w.Notes ||
+			strings.Join(g.Emails, "|") != strings.Join(w.Emails, "|") || strings.Join(g.Phones, "|") != strings.Join(w.Phones, "|") ||
+			strings.Join(g.Labels, "|") != strings.Join(w.Labels, "|") || (withIDs && g.ID != w.ID) {
+			t.Errorf("contact %d: got %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestVCard_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeVCard(&buf, sampleContacts()); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line not folded: %q", line)
+		}
+	}
+	got, err := DecodeVCard(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkContacts(t, got, sampleContacts(), true)
+	if got[0].GivenName != "Jane" || got[0].FamilyName != "Doe" {
+		t.Errorf("name parts = %q %q", got[0].GivenName, got[0].FamilyName)
+	}
+}
+
+func TestDecodeVCard_OtherApps(t *testing.T) {
+	// A phone's vCard 2.1 export, with quoted-printable text, and an Apple
+	// Contacts card with grouped properties and a preferred address
+	data := strings.Join([]string{
+		"BEGIN:VCARD",
+		"VERSION:2.1",
+		"N;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:M=C3=BCller;J=C3=BCrgen;;;",
+		"TEL;CELL;PREF:+49 30 1234567",
+		"NOTE;ENCODING=QUOTED-PRINTABLE:First line=0D=0A=",
+		"second line",
+		"PHOTO;ENCODING=BASE64;TYPE=JPEG:/9j/4AAQ",
+		"END:VCARD",
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		"FN:Ann Lee",
+		"item1.EMAIL;type=INTERNET:ann@home.example",
+		"item2.EMAIL;type=INTERNET;type=pref:ann@work.exa",
+		" mple",
+		"ORG:Initech;Accounting",
+		"CATEGORIES:Friends,Book club",
+		"END:VCARD",
+		"BEGIN:VCARD",
+		"VERSION:4.0",
+		"ORG:Nobody",
+		"END:VCARD",
+	}, "\r\n")
+	got, err := DecodeVCard(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkContacts(t, got, []Contact{
+		{Name: "Jürgen Müller", Phones: []string{"+49 30 1234567"}, Notes: "First line\r\nsecond line"},
+		{Name: "Ann Lee", Emails: []string{"ann@work.example", "ann@home.example"}, Organization: "Initech", Labels: []string{"Friends", "Book club"}},
+	}, false)
+
+	if _, err := DecodeVCard(strings.NewReader("Name,Email\nJane,jane@example.com\n")); err == nil {
+		t.Error("CSV decoded as vCard")
+	}
+}
+
+func TestCSV_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeCSV(&buf, sampleContacts()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkContacts(t, got, sampleContacts(), false)
+}
+
+func TestDecodeCSV_GoogleExports(t *testing.T) {
+	// Google's older export, then its current one
+	older := "\ufeffName,Given Name,Additional Name,Family Name,Notes,Group Membership,E-mail 1 - Type,E-mail 1 - Value,Phone 1 - Type,Phone 1 - Value,Organization 1 - Name,Organization 1 - Title\n" +
+		"Jane Doe,Jane,,Doe,,* myContacts ::: Work,* Home,jane@example.com ::: jane@work.example,Mobile,555 123 4567,Acme,CTO\n" +
+		",,,,,,,,,,,\n"
+	current := "First Name,Middle Name,Last Name,Labels,E-mail 1 - Label,E-mail 1 - Value,Phone 1 - Label,Phone 1 - Value,Phone 2 - Label,Phone 2 - Value\n" +
+		"Bob,J.,Smith,* starred,,,Mobile,555-987-6543,Work,+1 555 987 6543\n" +
+		",,,,,,,020 7946 0018,,\n"
+	got, err := DecodeCSV(strings.NewReader(older))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkContacts(t, got, []Contact{
+		{Name: "Jane Doe", Emails: []string{"jane@example.com", "jane@work.example"}, Phones: []string{"555 123 4567"}, Organization: "Acme", Title: "CTO", Labels: []string{"Work"}},
+	}, false)
+
+	got, err = DecodeCSV(strings.NewReader(current))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkContacts(t, got, []Contact{
+		{Name: "Bob J. Smith", Phones: []string{"555-987-6543"}},
+		{Name: "020 7946 0018", Phones: []string{"020 7946 0018"}},
+	}, false)
+
+	if _, err := DecodeCSV(strings.NewReader("title,due\nx,y\n")); err == nil {
+		t.Error("task CSV decoded as contacts")
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	for _, pair := range [][2]string{
+		{"+1 (555) 123-4567", "5551234567"},
+		{"555.123.4567", "5551234567"},
+		{"tel:+44 20 7946 0018", "2079460018"},
+		{"ext 12", ""},
+	} {
+		if got := NormalizePhone(pair[0]); got != pair[1] {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", pair[0], got, pair[1])
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package contactio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// googleSeparator joins several values in one Google Contacts cell
+const googleSeparator = " ::: "
+
+var (
+	// emailColumn matches the email columns of Google's exports, old and
+	// new ("E-mail 1 - Value"), and Outlook's ("E-mail 2 Address")
+	emailColumn = regexp.MustCompile(`^e-?mail( address)?( \d+)?( - value| address)?$`)
+	// phoneColumn matches "Phone 1 - Value", "Mobile Phone", "Business
+	// Phone 2" and the like
+	phoneColumn = regexp.MustCompile(`^([a-z]+ )?phone( \d+)?( - value)?$`)
+)
+
+// csvAliases are the column names other apps use for each field, after
+// normalizing
+var csvAliases = map[string][]string{
+	"name":         {"name", "full name", "display name"},
+	"given":        {"first name", "given name"},
+	"middle":       {"middle name", "additional name"},
+	"family":       {"last name", "family name", "surname"},
+	"organization": {"organization name", "organization 1 - name", "company"},
+	"title":        {"organization title", "organization 1 - title", "job title"},
+	"notes":        {"notes", "note"},
+	"labels":       {"labels", "group membership", "categories"},
+}
+
+// EncodeCSV writes contacts in the layout of Google Contacts' export, which
+// Google Contacts imports back, with as many email and phone columns as
+// the contact with the most needs
+func EncodeCSV(w io.Writer, contacts []Contact) error {
+	emails, phones := 1, 1
+	for _, contact := range contacts {
+		emails, phones = max(emails, len(contact.Emails)), max(phones, len(contact.Phones))
+	}
+	header := []string{"First Name", "Last Name", "Organization Name", "Organization Title", "Notes", "Labels"}
+	for i := 1; i <= emails; i++ {
+		header = append(header, fmt.Sprintf("E-mail %d - Label", i), fmt.Sprintf("E-mail %d - Value", i))
+	}
+	for i := 1; i <= phones; i++ {
+		header = append(header, fmt.Sprintf("Phone %d - Label", i), fmt.Sprintf("Phone %d - Value", i))
+	}
+
+	out := csv.NewWriter(w)
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+	for _, contact := range contacts {
+		given, family := contact.splitName()
+		record := []string{given, family, contact.Organization, contact.Title, contact.Notes, strings.Join(contact.Labels, googleSeparator)}
+		for i := 0; i < emails; i++ {
+			record = append(record, labelled(contact.Emails, i)...)
+		}
+		for i := 0; i < phones; i++ {
+			record = append(record, labelled(contact.Phones, i)...)
+		}
+		if err := out.Write(record); err != nil {
+			return fmt.Errorf("failed to write contact %q: %w", contact.DisplayName(), err)
+		}
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
+
+// labelled returns the label and value cells of values[i], marking the
+// first of several as preferred the way Google does
+func labelled(values []string, i int) []string {
+	switch {
+	case i >= len(values):
+		return []string{"", ""}
+	case i == 0 && len(values) > 1:
+		return []string{"* Other", values[i]}
+	default:
+		return []string{"Other", values[i]}
+	}
+}
+
+// DecodeCSV reads contacts from CSV with a header row, recognizing the
+// columns of Google Contacts' exports, old and new, Outlook's and
+// EncodeCSV's. Rows without a name, email address or phone number are
+// skipped.
+func DecodeCSV(r io.Reader) ([]Contact, error) {
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = -1
+	in.LazyQuotes = true
+
+	header, err := in.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	columns, emailColumns, phoneColumns := csvColumns(header)
+	if len(columns) == 0 && len(emailColumns) == 0 && len(phoneColumns) == 0 {
+		return nil, fmt.Errorf("csv has no name, email or phone columns")
+	}
+
+	var contacts []Contact
+	for line := 2; ; line++ {
+		record, err := in.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv line %d: %w", line, err)
+		}
+		cell := func(i int) string {
+			if i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return cell(i)
+			}
+			return ""
+		}
+
+		contact := Contact{
+			Name:         field("name"),
+			GivenName:    strings.TrimSpace(field("given") + " " + field("middle")),
+			FamilyName:   field("family"),
+			Organization: field("organization"),
+			Title:        field("title"),
+			Notes:        field("notes"),
+		}
+		for _, i := range emailColumns {
+			contact.Emails = appendUnique(contact.Emails, NormalizeEmail, strings.Split(cell(i), googleSeparator)...)
+		}
+		for _, i := range phoneColumns {
+			contact.Phones = appendUnique(contact.Phones, phoneKey, strings.Split(cell(i), googleSeparator)...)
+		}
+		for _, label := range strings.Split(field("labels"), googleSeparator) {
+			// Google's own groups, "* myContacts" and "* starred", aren't
+			// the user's labels
+			if label = strings.TrimSpace(label); label != "" && !strings.HasPrefix(label, "* ") {
+				contact.Labels = appendUnique(contact.Labels, strings.ToLower, label)
+			}
+		}
+		if contact.Name = contact.DisplayName(); contact.Name == "" && len(contact.Phones) == 0 {
+			continue
+		}
+		if contact.Name == "" {
+			contact.Name = contact.Phones[0]
+		}
+		contacts = append(contacts, contact)
+	}
+	return contacts, nil
+}
+
+// csvColumns maps each field to its column in header, by the first alias
+// found, and lists the email and phone columns in order
+func csvColumns(header []string) (map[string]int, []int, []int) {
+	index := make(map[string]int)
+	var emails, phones []int
+	for i, name := range header {
+		name = strings.ToLower(strings.Join(strings.Fields(strings.TrimPrefix(name, "\ufeff")), " "))
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+		switch {
+		case emailColumn.MatchString(name):
+			emails = append(emails, i)
+		case phoneColumn.MatchString(name):
+			phones = append(phones, i)
+		}
+	}
+
+	columns := make(map[string]int)
+	for field, aliases := range csvAliases {
+		for _, alias := range aliases {
+			if i, ok := index[alias]; ok {
+				columns[field] = i
+				break
+			}
+		}
+	}
+	return columns, emails, phones
+}
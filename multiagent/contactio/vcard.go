@@ -0,0 +1,314 @@
+package contactio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the length vCard lines are folded at
+const maxLineOctets = 75
+
+// vcardEncoder writes folded content lines, keeping the first error
+type vcardEncoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+// EncodeVCard writes contacts as vCard 3.0, the version most address
+// books import
+func EncodeVCard(w io.Writer, contacts []Contact) error {
+	e := &vcardEncoder{w: bufio.NewWriter(w)}
+	for _, contact := range contacts {
+		given, family := contact.splitName()
+		e.line("BEGIN:VCARD")
+		e.line("VERSION:3.0")
+		if contact.ID != "" {
+			e.line("UID:" + escapeText(contact.ID))
+		}
+		e.line("FN:" + escapeText(contact.DisplayName()))
+		e.line("N:" + escapeText(family) + ";" + escapeText(given) + ";;;")
+		for i, email := range contact.Emails {
+			types := "INTERNET"
+			if i == 0 && len(contact.Emails) > 1 {
+				types += ",PREF"
+			}
+			e.line("EMAIL;TYPE=" + types + ":" + escapeText(email))
+		}
+		for i, phone := range contact.Phones {
+			prefix := "TEL"
+			if i == 0 && len(contact.Phones) > 1 {
+				prefix += ";TYPE=PREF"
+			}
+			e.line(prefix + ":" + escapeText(phone))
+		}
+		if contact.Organization != "" {
+			e.line("ORG:" + escapeText(contact.Organization))
+		}
+		if contact.Title != "" {
+			e.line("TITLE:" + escapeText(contact.Title))
+		}
+		if contact.Notes != "" {
+			e.line("NOTE:" + escapeText(contact.Notes))
+		}
+		if len(contact.Labels) > 0 {
+			labels := make([]string, len(contact.Labels))
+			for i, label := range contact.Labels {
+				labels[i] = escapeText(label)
+			}
+			e.line("CATEGORIES:" + strings.Join(labels, ","))
+		}
+		e.line("END:VCARD")
+	}
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	if e.err != nil {
+		return fmt.Errorf("failed to write vcard: %w", e.err)
+	}
+	return nil
+}
+
+// line writes one content line, folded to 75 octets
+func (e *vcardEncoder) line(content string) {
+	if e.err != nil {
+		return
+	}
+	for len(content) > maxLineOctets {
+		cut := maxLineOctets
+		if strings.HasPrefix(content, " ") {
+			cut-- // The continuation's leading space counts
+		}
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		if _, e.err = e.w.WriteString(content[:cut] + "\r\n"); e.err != nil {
+			return
+		}
+		content = " " + content[cut:]
+	}
+	_, e.err = e.w.WriteString(content + "\r\n")
+}
+
+// vcardProperty is one unfolded content line
+type vcardProperty struct {
+	name   string
+	params map[string][]string
+	value  string
+}
+
+// DecodeVCard reads the contacts in a vCard file of version 2.1, 3.0 or
+// 4.0, as Apple Contacts, Google Contacts, Outlook and phones export them.
+// Cards without a name, email address or phone number are skipped.
+func DecodeVCard(r io.Reader) ([]Contact, error) {
+	lines, err := unfoldVCard(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vcard: %w", err)
+	}
+
+	var contacts []Contact
+	var card *Contact
+	found := false
+	for _, line := range lines {
+		prop, ok := parseVCardLine(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VCARD"):
+			card, found = &Contact{}, true
+		case prop.name == "END" && strings.EqualFold(prop.value, "VCARD"):
+			if card != nil && (card.DisplayName() != "" || len(card.Phones) > 0) {
+				if card.Name = card.DisplayName(); card.Name == "" {
+					card.Name = card.Phones[0]
+				}
+				contacts = append(contacts, *card)
+			}
+			card = nil
+		case card != nil:
+			card.apply(prop)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no vcards found")
+	}
+	return contacts, nil
+}
+
+// apply sets the field prop carries
+func (c *Contact) apply(prop vcardProperty) {
+	value := prop.value
+	if encoding := prop.param("ENCODING"); strings.EqualFold(encoding, "QUOTED-PRINTABLE") {
+		if decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(value))); err == nil {
+			value = string(decoded)
+		}
+	} else if encoding != "" {
+		// Base64 values are photos, logos and keys
+		return
+	}
+	preferred := prop.hasType("PREF") || prop.param("PREF") != ""
+
+	switch prop.name {
+	case "UID":
+		c.ID = unescapeText(value)
+	case "FN":
+		c.Name = strings.TrimSpace(unescapeText(value))
+	case "N":
+		parts := splitEscaped(value, ';')
+		for len(parts) < 3 {
+			parts = append(parts, "")
+		}
+		c.FamilyName = strings.TrimSpace(parts[0])
+		c.GivenName = strings.TrimSpace(parts[1] + " " + parts[2])
+	case "EMAIL":
+		c.Emails = addValue(c.Emails, NormalizeEmail, unescapeText(value), preferred)
+	case "TEL":
+		c.Phones = addValue(c.Phones, phoneKey, unescapeText(value), preferred)
+	case "ORG":
+		c.Organization = strings.TrimSpace(splitEscaped(value, ';')[0])
+	case "TITLE":
+		c.Title = strings.TrimSpace(unescapeText(value))
+	case "NOTE":
+		c.Notes = strings.TrimSpace(unescapeText(value))
+	case "CATEGORIES":
+		c.Labels = appendUnique(c.Labels, strings.ToLower, splitEscaped(value, ',')...)
+	}
+}
+
+// param returns the first value of the parameter name
+func (p vcardProperty) param(name string) string {
+	if values := p.params[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// hasType reports whether the property is of kind, e.g. PREF
+func (p vcardProperty) hasType(kind string) bool {
+	for _, value := range p.params["TYPE"] {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), kind) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addValue adds value to list, first when it is preferred
+func addValue(list []string, key func(string) string, value string, preferred bool) []string {
+	before := len(list)
+	list = appendUnique(list, key, value)
+	if preferred && len(list) > before {
+		list = append(list[len(list)-1:], list[:len(list)-1]...)
+	}
+	return list
+}
+
+// phoneKey compares phone numbers by their digits, or as written when they
+// have too few
+func phoneKey(number string) string {
+	if normalized := NormalizePhone(number); normalized != "" {
+		return normalized
+	}
+	return strings.TrimSpace(number)
+}
+
+// unfoldVCard joins folded lines, and version 2.1's quoted-printable soft
+// line breaks, into whole content lines
+func unfoldVCard(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var lines []string
+	softBreak := false
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		switch {
+		case softBreak:
+			lines[len(lines)-1] += line
+		case (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0:
+			lines[len(lines)-1] += line[1:]
+		default:
+			lines = append(lines, line)
+		}
+		last := lines[len(lines)-1]
+		head, _, _ := strings.Cut(last, ":")
+		softBreak = strings.Contains(strings.ToUpper(head), "QUOTED-PRINTABLE") && strings.HasSuffix(last, "=")
+		if softBreak {
+			lines[len(lines)-1] = strings.TrimSuffix(last, "=")
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseVCardLine splits "group.NAME;PARAM=value:VALUE", honouring quoted
+// parameters; version 2.1's bare parameters, e.g. ";WORK", are types
+func parseVCardLine(line string) (vcardProperty, bool) {
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return vcardProperty{}, false
+	}
+
+	prop := vcardProperty{params: map[string][]string{}, value: line[colon+1:]}
+	head := strings.Split(line[:colon], ";")
+	name := head[0]
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	prop.name = strings.ToUpper(strings.TrimSpace(name))
+	for _, param := range head[1:] {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			key, value = "TYPE", param
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		prop.params[key] = append(prop.params[key], strings.Trim(value, "\""))
+	}
+	return prop, true
+}
+
+// splitEscaped splits a structured value at the separators that aren't
+// escaped, unescaping each part
+func splitEscaped(value string, sep rune) []string {
+	var parts []string
+	var part strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			part.WriteString(unescapeText("\\" + string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == sep:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+	}
+	return append(parts, part.String())
+}
+
+func escapeText(text string) string {
+	return strings.NewReplacer("\\", "\\\\", ";", "\\;", ",", "\\,", "\r\n", "\\n", "\n", "\\n").Replace(text)
+}
+
+func unescapeText(text string) string {
+	return strings.NewReplacer("\\\\", "\\", "\\;", ";", "\\,", ",", "\\n", "\n", "\\N", "\n").Replace(text)
+}
@@ -0,0 +1,204 @@
+// Package cron reads standard five-field cron expressions ("30 7 * * 1-5")
+// and finds the times they fire, so jobs such as the morning briefing can be
+// scheduled in each user's own timezone.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchDays bounds how far ahead Next looks; every valid expression fires
+// within a few years (February 29th within eight)
+const searchDays = 8 * 366
+
+// field is the range one position of an expression takes
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// aliases are the shorthands Parse accepts in place of an expression
+var aliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	spec                             string
+	minutes, hours, days, months, wd uint64
+	// anyDay and anyWeekday record unrestricted day fields: when both day
+	// fields are restricted a day matching either fires, as in cron(8)
+	anyDay, anyWeekday bool
+}
+
+// Parse reads a cron expression: minute, hour, day of month, month and day
+// of week, each "*", a number, a range "a-b", or a list of these, with an
+// optional step ("*/15", "9-17/2"). Sunday is 0 or 7. The shorthands
+// @hourly, @daily, @weekly, @monthly and @yearly are accepted too.
+func Parse(spec string) (*Schedule, error) {
+	expr := strings.TrimSpace(spec)
+	if alias, ok := aliases[strings.ToLower(expr)]; ok {
+		expr = alias
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron expression %q needs 5 fields, has %d", spec, len(parts))
+	}
+
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+		}
+		sets[i] = set
+	}
+	// Sunday may be written as 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] = sets[4]&^(1<<7) | 1
+	}
+
+	return &Schedule{
+		spec:       spec,
+		minutes:    sets[0],
+		hours:      sets[1],
+		days:       sets[2],
+		months:     sets[3],
+		wd:         sets[4],
+		anyDay:     parts[2] == "*",
+		anyWeekday: parts[4] == "*",
+	}, nil
+}
+
+// parseField reads one comma-separated field into a bit set
+func parseField(value string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step %q in %s field", stepPart, f.name)
+			}
+			step = n
+		}
+
+		low, high := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = parseNumber(from, f); err != nil {
+				return 0, err
+			}
+			if high, err = parseNumber(to, f); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("range %q in %s field runs backwards", rangePart, f.name)
+			}
+		default:
+			n, err := parseNumber(rangePart, f)
+			if err != nil {
+				return 0, err
+			}
+			low = n
+			// "5/10" means from 5 to the end in steps of 10
+			if !hasStep {
+				high = n
+			}
+		}
+
+		for n := low; n <= high; n += step {
+			set |= 1 << n
+		}
+	}
+	return set, nil
+}
+
+func parseNumber(value string, f field) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%q is not a valid %s (%d-%d)", value, f.name, f.min, f.max)
+	}
+	return n, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Next returns the first time after t the schedule fires, in t's location,
+// or the zero time if it never does. Wall-clock times skipped by a
+// daylight saving change do not fire.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	start := t.Truncate(time.Minute).Add(time.Minute)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+
+	for i := 0; i < searchDays; i++ {
+		if s.matchesDay(day) {
+			for hour := 0; hour < 24; hour++ {
+				if s.hours&(1<<hour) == 0 {
+					continue
+				}
+				for minute := 0; minute < 60; minute++ {
+					if s.minutes&(1<<minute) == 0 {
+						continue
+					}
+					candidate := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+					// time.Date moves wall-clock times that do not exist
+					if candidate.Hour() != hour || candidate.Minute() != minute || candidate.Before(start) {
+						continue
+					}
+					return candidate
+				}
+			}
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+	}
+	return time.Time{}
+}
+
+// Matches reports whether the schedule fires in t's minute
+func (s *Schedule) Matches(t time.Time) bool {
+	return s.matchesDay(t) && s.hours&(1<<t.Hour()) != 0 && s.minutes&(1<<t.Minute()) != 0
+}
+
+func (s *Schedule) matchesDay(t time.Time) bool {
+	if s.months&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	dayOK := s.days&(1<<t.Day()) != 0
+	weekdayOK := s.wd&(1<<int(t.Weekday())) != 0
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekdayOK
+	case s.anyWeekday:
+		return dayOK
+	default:
+		return dayOK || weekdayOK
+	}
+}
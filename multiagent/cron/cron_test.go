@@ -0,0 +1,64 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no timezone database")
+	}
+	// 2025-03-28 is a Friday; Berlin moves to summer time at 02:00 on the 30th
+	from := time.Date(2025, 3, 28, 8, 15, 0, 0, berlin)
+
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"0 7 * * *", from, time.Date(2025, 3, 29, 7, 0, 0, 0, berlin)},
+		{"30 8 * * *", from, time.Date(2025, 3, 28, 8, 30, 0, 0, berlin)},
+		{"15 8 * * *", from, time.Date(2025, 3, 29, 8, 15, 0, 0, berlin)},
+		{"0 7 * * 1-5", from, time.Date(2025, 3, 31, 7, 0, 0, 0, berlin)},
+		{"*/20 9-10 * * *", from, time.Date(2025, 3, 28, 9, 0, 0, 0, berlin)},
+		{"0 0 1 * *", from, time.Date(2025, 4, 1, 0, 0, 0, 0, berlin)},
+		{"0 12 13 * 5", from, time.Date(2025, 3, 28, 12, 0, 0, 0, berlin)},
+		{"0 9 * * 7", from, time.Date(2025, 3, 30, 9, 0, 0, 0, berlin)},
+		{"@daily", from, time.Date(2025, 3, 29, 0, 0, 0, 0, berlin)},
+		{"0 0 29 2 *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, berlin)},
+		// 02:30 does not exist on the 30th
+		{"30 2 * * *", time.Date(2025, 3, 29, 3, 0, 0, 0, berlin), time.Date(2025, 3, 31, 2, 30, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		schedule, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", tt.spec, err)
+		}
+		if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next(%v) = %v, want %v", tt.spec, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	schedule, err := Parse("0 7 * * 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !schedule.Matches(time.Date(2025, 3, 28, 7, 0, 30, 0, time.UTC)) {
+		t.Error("expected Friday 07:00 to match")
+	}
+	if schedule.Matches(time.Date(2025, 3, 29, 7, 0, 0, 0, time.UTC)) {
+		t.Error("expected Saturday 07:00 not to match")
+	}
+}
@@ -0,0 +1,52 @@
+package discord
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config is how the assistant reaches its Discord application
+type Config struct {
+	// ApplicationID identifies the application the slash commands belong to
+	ApplicationID string `json:"application_id"`
+	// PublicKey is the application's hex-encoded key, which verifies that
+	// interactions come from Discord
+	PublicKey string `json:"public_key"`
+	// BotToken registers the slash commands; it may reference environment
+	// variables, e.g. "$DISCORD_BOT_TOKEN"
+	BotToken string `json:"bot_token"`
+	// Guilds maps Discord server IDs to assistant users, so everyone using
+	// the commands in the server shares one user's data and conversation
+	Guilds map[string]string `json:"guilds,omitempty"`
+	// Users maps Discord user IDs to assistant users; anyone else talks to
+	// the assistant as "discord-" and their Discord user ID
+	Users map[string]string `json:"users,omitempty"`
+	// APIURL defaults to https://discord.com/api/v10
+	APIURL string `json:"api_url,omitempty"`
+}
+
+// LoadConfig reads the Discord application's settings from a JSON file:
+//
+//	{"application_id": "1089...", "public_key": "3c8a...", "bot_token": "$DISCORD_BOT_TOKEN",
+//	 "guilds": {"8120...": "family"}, "users": {"2271...": "alice"}}
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read discord config: %w", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse discord config: %w", err)
+	}
+	config.BotToken = os.ExpandEnv(config.BotToken)
+	if config.ApplicationID == "" || config.BotToken == "" {
+		return config, fmt.Errorf("discord config needs an application_id and a bot_token")
+	}
+	if key, err := hex.DecodeString(config.PublicKey); err != nil || len(key) != ed25519.PublicKeySize {
+		return config, fmt.Errorf("discord config needs the application's hex public_key")
+	}
+	return config, nil
+}
@@ -0,0 +1,476 @@
+// Package discord makes a Discord application a frontend for the
+// assistant: people use its /task, /schedule and /research slash commands,
+// and task and calendar listings come back as embeds.
+package discord
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+var logger = logging.For("discord")
+
+const (
+	// UserPrefix starts the assistant user IDs of Discord users without a
+	// mapping in Config.Users
+	UserPrefix = "discord-"
+
+	// maxRequestAge rejects replayed interactions
+	maxRequestAge = 5 * time.Minute
+	// agendaDays is how far ahead /schedule lists events
+	agendaDays = 7
+	// maxFields and maxContent are Discord's limits on an embed's fields
+	// and a message's text
+	maxFields  = 25
+	maxContent = 2000
+
+	colorTasks    = 0x5865F2
+	colorCalendar = 0x57F287
+)
+
+// Values of the interactions API
+const (
+	interactionPing    = 1
+	interactionCommand = 2
+
+	responsePong            = 1
+	responseDeferredMessage = 5
+
+	commandTypeChatInput = 1
+	optionString         = 3
+
+	// interactionTokenValidity is how long a command's reply can be edited
+	interactionTokenValidity = 15 * time.Minute
+)
+
+// The slash commands and their options
+const (
+	commandTask     = "task"
+	commandSchedule = "schedule"
+	commandResearch = "research"
+
+	requestOption = "request"
+	topicOption   = "topic"
+)
+
+// Service is the part of MultiAgentService the adapter uses
+type Service interface {
+	ProcessUserMessage(ctx context.Context, userID string, message string) (string, error)
+	ListTasks(ctx context.Context) ([]*agents.PersonalTask, error)
+	ListEvents(ctx context.Context, from, to time.Time) ([]*agents.CalendarEvent, error)
+}
+
+// AdapterConfig holds configuration for creating an Adapter
+type AdapterConfig struct {
+	Service Service
+	Discord Config
+	// MessageTimeout bounds how long a command waits for the assistant's
+	// reply (default 90 seconds); Discord drops replies after 15 minutes
+	MessageTimeout time.Duration
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+	// Clock defaults to the system clock
+	Clock multiagent.Clock
+}
+
+// Adapter answers the application's slash commands. It serves Discord's
+// interactions endpoint as an http.Handler.
+type Adapter struct {
+	service   Service
+	config    Config
+	publicKey ed25519.PublicKey
+	apiURL    string
+	timeout   time.Duration
+	client    *http.Client
+	clock     multiagent.Clock
+
+	wg sync.WaitGroup
+}
+
+// NewAdapter creates an adapter for config.Discord's application. An
+// invalid public key makes it reject every interaction.
+func NewAdapter(config AdapterConfig) *Adapter {
+	if config.MessageTimeout <= 0 {
+		config.MessageTimeout = 90 * time.Second
+	}
+	if config.MessageTimeout > interactionTokenValidity {
+		config.MessageTimeout = interactionTokenValidity
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	apiURL := config.Discord.APIURL
+	if apiURL == "" {
+		apiURL = "https://discord.com/api/v10"
+	}
+	publicKey, _ := hex.DecodeString(config.Discord.PublicKey)
+
+	return &Adapter{
+		service:   config.Service,
+		config:    config.Discord,
+		publicKey: ed25519.PublicKey(publicKey),
+		apiURL:    strings.TrimRight(apiURL, "/"),
+		timeout:   config.MessageTimeout,
+		client:    config.Client,
+		clock:     ids.ClockOrSystem(config.Clock),
+	}
+}
+
+// Wait blocks until commands being handled have been answered, or ctx ends
+func (a *Adapter) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// command is the definition of a slash command
+type command struct {
+	Name        string          `json:"name"`
+	Type        int             `json:"type"`
+	Description string          `json:"description"`
+	Options     []commandOption `json:"options,omitempty"`
+}
+
+type commandOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// commands are the slash commands the adapter answers
+var commands = []command{
+	{Name: commandTask, Type: commandTypeChatInput, Description: "Add or update a task, or list your open tasks", Options: []commandOption{
+		{Type: optionString, Name: requestOption, Description: "e.g. 'call the dentist by Friday'; leave empty to list open tasks"},
+	}},
+	{Name: commandSchedule, Type: commandTypeChatInput, Description: "Schedule or move an event, or list the coming week", Options: []commandOption{
+		{Type: optionString, Name: requestOption, Description: "e.g. 'lunch with Sam Tuesday at noon'; leave empty to list the week"},
+	}},
+	{Name: commandResearch, Type: commandTypeChatInput, Description: "Have the assistant research a topic", Options: []commandOption{
+		{Type: optionString, Name: topicOption, Description: "What to research", Required: true},
+	}},
+}
+
+// RegisterCommands creates or replaces the application's global slash
+// commands
+func (a *Adapter) RegisterCommands(ctx context.Context) error {
+	return a.call(ctx, http.MethodPut, "/applications/"+a.config.ApplicationID+"/commands", commands)
+}
+
+// interaction is a request to the interactions endpoint
+type interaction struct {
+	Type    int    `json:"type"`
+	Token   string `json:"token"`
+	GuildID string `json:"guild_id"`
+	// Member is set in servers, User in direct messages
+	Member *struct {
+		User user `json:"user"`
+	} `json:"member"`
+	User *user `json:"user"`
+	Data struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string          `json:"name"`
+			Value json.RawMessage `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+type user struct {
+	ID string `json:"id"`
+}
+
+// option returns the string value of the named option, or ""
+func (i *interaction) option(name string) string {
+	for _, option := range i.Data.Options {
+		var value string
+		if option.Name == name && json.Unmarshal(option.Value, &value) == nil {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// ServeHTTP answers Discord's interactions endpoint: it defers the reply to
+// each command and edits it in once the command is handled, as Discord
+// wants a response within three seconds
+func (a *Adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	if err := a.verify(r.Header, body); err != nil {
+		logger.Warn("Rejected Discord request", "error", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req interaction
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "invalid interaction", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch req.Type {
+	case interactionPing:
+		json.NewEncoder(w).Encode(map[string]int{"type": responsePong})
+	case interactionCommand:
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.handle(&req)
+		}()
+		json.NewEncoder(w).Encode(map[string]int{"type": responseDeferredMessage})
+	default:
+		http.Error(w, "unsupported interaction", http.StatusBadRequest)
+	}
+}
+
+// verify checks the request's Ed25519 signature against the application's
+// public key
+func (a *Adapter) verify(header http.Header, body []byte) error {
+	timestamp := header.Get("X-Signature-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("missing request timestamp")
+	}
+	if age := a.clock.Now().Sub(time.Unix(seconds, 0)); age > maxRequestAge || age < -maxRequestAge {
+		return errors.New("stale request timestamp")
+	}
+	signature, err := hex.DecodeString(header.Get("X-Signature-Ed25519"))
+	if err != nil || len(a.publicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid request signature")
+	}
+	if !ed25519.Verify(a.publicKey, append([]byte(timestamp), body...), signature) {
+		return errors.New("invalid request signature")
+	}
+	return nil
+}
+
+// UserID returns the assistant user a Discord user talks as in guild ("" in
+// direct messages): the guild's user if it is mapped, so the server shares
+// one conversation, or else the Discord user's own
+func (a *Adapter) UserID(guild, discordUser string) string {
+	if userID, ok := a.config.Guilds[guild]; ok && guild != "" {
+		return userID
+	}
+	if userID, ok := a.config.Users[discordUser]; ok {
+		return userID
+	}
+	return UserPrefix + discordUser
+}
+
+// handle runs a command and edits its reply in
+func (a *Adapter) handle(req *interaction) {
+	var discordUser string
+	switch {
+	case req.Member != nil:
+		discordUser = req.Member.User.ID
+	case req.User != nil:
+		discordUser = req.User.ID
+	default:
+		return
+	}
+	userID := a.UserID(req.GuildID, discordUser)
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+	ctx = logging.WithFields(multiagent.WithUserID(ctx, userID), logging.KeyUserID, userID, "discord_command", req.Data.Name)
+
+	reply, err := a.run(ctx, userID, req)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to handle Discord command", "error", err)
+		reply = &message{Content: "⚠️ Sorry, something went wrong: " + err.Error()}
+	}
+	if len(reply.Content) > maxContent {
+		reply.Content = reply.Content[:maxContent-1] + "…"
+	}
+
+	// Editing the reply needs no bot token: the interaction's token
+	// authorizes it
+	path := "/webhooks/" + a.config.ApplicationID + "/" + req.Token + "/messages/@original"
+	if err := a.call(ctx, http.MethodPatch, path, reply); err != nil {
+		logger.WarnContext(ctx, "Failed to send Discord reply", "error", err)
+	}
+}
+
+// run answers the command: listings come from the service directly, and
+// requests go to the assistant with the command as a hint
+func (a *Adapter) run(ctx context.Context, userID string, req *interaction) (*message, error) {
+	var prompt string
+	switch req.Data.Name {
+	case commandTask:
+		request := req.option(requestOption)
+		if request == "" {
+			return a.listTasks(ctx)
+		}
+		prompt = "Task: " + request
+	case commandSchedule:
+		request := req.option(requestOption)
+		if request == "" {
+			return a.listEvents(ctx)
+		}
+		prompt = "Calendar: " + request
+	case commandResearch:
+		prompt = "Research: " + req.option(topicOption)
+	default:
+		return nil, fmt.Errorf("unknown command /%s", req.Data.Name)
+	}
+
+	response, err := a.service.ProcessUserMessage(ctx, userID, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return &message{Content: response}, nil
+}
+
+// message is the content of a reply
+type message struct {
+	Content string  `json:"content,omitempty"`
+	Embeds  []embed `json:"embeds,omitempty"`
+}
+
+type embed struct {
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	Color       int          `json:"color"`
+	Fields      []embedField `json:"fields,omitempty"`
+}
+
+type embedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// listTasks renders the user's open tasks, dated ones first, as an embed
+func (a *Adapter) listTasks(ctx context.Context) (*message, error) {
+	tasks, err := a.service.ListTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	open := make([]*agents.PersonalTask, 0, len(tasks))
+	for _, task := range tasks {
+		if task.Status != agents.PersonalTaskStatusCompleted && task.Status != agents.PersonalTaskStatusCancelled {
+			open = append(open, task)
+		}
+	}
+	sort.SliceStable(open, func(i, j int) bool {
+		a, b := open[i].DueDate, open[j].DueDate
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.Before(*b)
+	})
+
+	listing := embed{Title: "Open tasks", Color: colorTasks}
+	for _, task := range open {
+		details := []string{strings.ReplaceAll(string(task.Status), "_", " ")}
+		if task.DueDate != nil {
+			details = append(details, "due "+task.DueDate.Format("Mon Jan 2 15:04"))
+		}
+		if task.Project != "" {
+			details = append(details, task.Project)
+		}
+		listing.Fields = append(listing.Fields, embedField{Name: task.Title, Value: strings.Join(details, " · ")})
+	}
+	return &message{Embeds: []embed{finish(listing, len(open), "Nothing to do.")}}, nil
+}
+
+// listEvents renders the coming week's events as an embed
+func (a *Adapter) listEvents(ctx context.Context) (*message, error) {
+	now := a.clock.Now()
+	events, err := a.service.ListEvents(ctx, now, now.AddDate(0, 0, agendaDays))
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].StartTime.Before(events[j].StartTime) })
+
+	listing := embed{Title: "The next " + strconv.Itoa(agendaDays) + " days", Color: colorCalendar}
+	for _, event := range events {
+		when := event.StartTime.Format("Mon Jan 2 15:04") + "–" + event.EndTime.Format("15:04")
+		if event.AllDay {
+			when = event.StartTime.Format("Mon Jan 2") + ", all day"
+		}
+		if event.Location != "" {
+			when += " · " + event.Location
+		}
+		listing.Fields = append(listing.Fields, embedField{Name: event.Title, Value: when})
+	}
+	return &message{Embeds: []embed{finish(listing, len(events), "Nothing scheduled.")}}, nil
+}
+
+// finish caps listing at Discord's field limit, noting what was left out,
+// or describes it as empty
+func finish(listing embed, count int, empty string) embed {
+	switch {
+	case count == 0:
+		listing.Description = empty
+	case count > maxFields:
+		listing.Fields = listing.Fields[:maxFields]
+		listing.Description = fmt.Sprintf("%d more not shown.", count-maxFields)
+	}
+	return listing
+}
+
+// call sends body to an API route as JSON
+func (a *Adapter) call(ctx context.Context, method, path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", path, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.apiURL+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", path, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bot "+a.config.BotToken)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		// The error's URL would include the interaction token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to reach discord: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("discord %s %s returned %s: %s", method, strings.SplitN(path, "/", 3)[1], resp.Status, bytes.TrimSpace(detail))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
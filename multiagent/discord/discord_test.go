@@ -0,0 +1,243 @@
+package discord
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+)
+
+type fakeService struct {
+	mu       sync.Mutex
+	received []string
+	listed   []string
+	from, to time.Time
+}
+
+func (f *fakeService) ProcessUserMessage(ctx context.Context, userID string, message string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.received = append(f.received, userID+": "+message)
+	if strings.Contains(message, "fail") {
+		return "", fmt.Errorf("no LLM")
+	}
+	return "Here is what I found.", nil
+}
+
+func (f *fakeService) ListTasks(ctx context.Context) ([]*agents.PersonalTask, error) {
+	f.mu.Lock()
+	f.listed = append(f.listed, multiagent.UserIDFromContext(ctx))
+	f.mu.Unlock()
+	due := testNow.Add(24 * time.Hour)
+	return []*agents.PersonalTask{
+		{ID: "task_1", Title: "Someday", Status: agents.PersonalTaskStatusSomeday},
+		{ID: "task_2", Title: "Done", Status: agents.PersonalTaskStatusCompleted},
+		{ID: "task_3", Title: "File taxes", Status: agents.PersonalTaskStatusNext, DueDate: &due},
+	}, nil
+}
+
+func (f *fakeService) ListEvents(ctx context.Context, from, to time.Time) ([]*agents.CalendarEvent, error) {
+	f.mu.Lock()
+	f.listed = append(f.listed, multiagent.UserIDFromContext(ctx))
+	f.from, f.to = from, to
+	f.mu.Unlock()
+	return []*agents.CalendarEvent{
+		{ID: "event_2", Title: "Dentist", StartTime: from.Add(26 * time.Hour), EndTime: from.Add(27 * time.Hour), Location: "Main St"},
+		{ID: "event_1", Title: "Standup", StartTime: from.Add(time.Hour), EndTime: from.Add(90 * time.Minute)},
+	}, nil
+}
+
+// request is an API request the fake Discord received
+type request struct {
+	method, path string
+	body         json.RawMessage
+}
+
+type fakeDiscord struct {
+	mu       sync.Mutex
+	requests []request
+}
+
+func (f *fakeDiscord) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+	json.NewDecoder(r.Body).Decode(&body)
+	f.mu.Lock()
+	f.requests = append(f.requests, request{method: r.Method, path: r.URL.Path, body: body})
+	f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bot bot-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+}
+
+func (f *fakeDiscord) last(t *testing.T) request {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.requests) == 0 {
+		t.Fatal("no request reached Discord")
+	}
+	return f.requests[len(f.requests)-1]
+}
+
+type fixedClock struct{ now time.Time }
+
+func (c fixedClock) Now() time.Time { return c.now }
+
+var testNow = time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+func newTestAdapter(t *testing.T) (*fakeService, *fakeDiscord, *Adapter, ed25519.PrivateKey) {
+	t.Helper()
+	api := &fakeDiscord{}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeService{}
+	adapter := NewAdapter(AdapterConfig{
+		Service: fake,
+		Discord: Config{
+			ApplicationID: "app1",
+			PublicKey:     hex.EncodeToString(public),
+			BotToken:      "bot-token",
+			Guilds:        map[string]string{"GFAMILY": "family"},
+			Users:         map[string]string{"UALICE": "alice"},
+			APIURL:        server.URL,
+		},
+		Clock: fixedClock{testNow},
+	})
+	return fake, api, adapter, private
+}
+
+// deliver sends body to the adapter signed with key, and waits for the
+// command to be handled
+func deliver(t *testing.T, adapter *Adapter, body string, key ed25519.PrivateKey) *httptest.ResponseRecorder {
+	t.Helper()
+	timestamp := strconv.FormatInt(testNow.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/discord/interactions", strings.NewReader(body))
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(key, []byte(timestamp+body))))
+	rec := httptest.NewRecorder()
+	adapter.ServeHTTP(rec, req)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := adapter.Wait(ctx); err != nil {
+		t.Fatalf("command not handled: %v", err)
+	}
+	return rec
+}
+
+func commandBody(name, guild, userID, option, value string) string {
+	options := "[]"
+	if option != "" {
+		options = fmt.Sprintf(`[{"name": %q, "type": 3, "value": %q}]`, option, value)
+	}
+	if guild == "" {
+		return fmt.Sprintf(`{"type": 2, "token": "tok", "user": {"id": %q}, "data": {"name": %q, "options": %s}}`, userID, name, options)
+	}
+	return fmt.Sprintf(`{"type": 2, "token": "tok", "guild_id": %q, "member": {"user": {"id": %q}}, "data": {"name": %q, "options": %s}}`,
+		guild, userID, name, options)
+}
+
+func TestVerification(t *testing.T) {
+	_, _, adapter, key := newTestAdapter(t)
+
+	rec := deliver(t, adapter, `{"type": 1}`, key)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"type":1}` {
+		t.Errorf("ping: status %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	_, other, _ := ed25519.GenerateKey(nil)
+	if rec := deliver(t, adapter, `{"type": 1}`, other); rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestResearch(t *testing.T) {
+	fake, api, adapter, key := newTestAdapter(t)
+
+	rec := deliver(t, adapter, commandBody("research", "", "UBOB", "topic", "heat pumps"), key)
+	if strings.TrimSpace(rec.Body.String()) != `{"type":5}` {
+		t.Errorf("reply not deferred: %q", rec.Body.String())
+	}
+	if len(fake.received) != 1 || fake.received[0] != UserPrefix+"UBOB: Research: heat pumps" {
+		t.Fatalf("received %v", fake.received)
+	}
+	edit := api.last(t)
+	if edit.method != http.MethodPatch || edit.path != "/webhooks/app1/tok/messages/@original" || !strings.Contains(string(edit.body), "Here is what I found.") {
+		t.Errorf("unexpected reply %+v", edit)
+	}
+
+	// Everyone in a mapped server shares its user
+	deliver(t, adapter, commandBody("task", "GFAMILY", "UALICE", "request", "fail"), key)
+	if got := fake.received[1]; got != "family: Task: fail" {
+		t.Errorf("received %q", got)
+	}
+	if body := string(api.last(t).body); !strings.Contains(body, "no LLM") {
+		t.Errorf("error not reported: %s", body)
+	}
+}
+
+func TestListings(t *testing.T) {
+	fake, api, adapter, key := newTestAdapter(t)
+
+	deliver(t, adapter, commandBody("task", "GOTHER", "UALICE", "", ""), key)
+	var reply message
+	if err := json.Unmarshal(api.last(t).body, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Embeds) != 1 || len(reply.Embeds[0].Fields) != 2 {
+		t.Fatalf("want an embed of the open tasks, got %+v", reply)
+	}
+	if fields := reply.Embeds[0].Fields; fields[0].Name != "File taxes" || !strings.Contains(fields[0].Value, "due Tue Mar 11") {
+		t.Errorf("want dated tasks first, got %+v", fields)
+	}
+
+	deliver(t, adapter, commandBody("schedule", "", "UALICE", "", ""), key)
+	reply = message{}
+	json.Unmarshal(api.last(t).body, &reply)
+	if len(reply.Embeds) != 1 || len(reply.Embeds[0].Fields) != 2 {
+		t.Fatalf("want an embed of the week's events, got %+v", reply)
+	}
+	if fields := reply.Embeds[0].Fields; fields[0].Name != "Standup" || fields[1].Value != "Tue Mar 11 11:00–12:00 · Main St" {
+		t.Errorf("unexpected events %+v", fields)
+	}
+	if !fake.from.Equal(testNow) || !fake.to.Equal(testNow.AddDate(0, 0, 7)) {
+		t.Errorf("listed events from %v to %v", fake.from, fake.to)
+	}
+	if len(fake.listed) != 2 || fake.listed[0] != "alice" || fake.listed[1] != "alice" || len(fake.received) != 0 {
+		t.Errorf("listed for %v, received %v", fake.listed, fake.received)
+	}
+}
+
+func TestRegisterCommands(t *testing.T) {
+	_, api, adapter, _ := newTestAdapter(t)
+	if err := adapter.RegisterCommands(context.Background()); err != nil {
+		t.Fatalf("RegisterCommands: %v", err)
+	}
+	req := api.last(t)
+	var registered []command
+	json.Unmarshal(req.body, &registered)
+	if req.method != http.MethodPut || req.path != "/applications/app1/commands" || len(registered) != 3 {
+		t.Errorf("unexpected registration %+v", req)
+	}
+
+	adapter.config.BotToken = "wrong"
+	if err := adapter.RegisterCommands(context.Background()); err == nil {
+		t.Error("RegisterCommands succeeded without authorization")
+	}
+}
@@ -0,0 +1,147 @@
+package email
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"os"
+	"strings"
+	"time"
+)
+
+// TLSMode is how the connection to a mail server is secured
+type TLSMode string
+
+const (
+	// TLSStartTLS upgrades a plain connection, usually on port 587
+	TLSStartTLS TLSMode = "starttls"
+	// TLSImplicit connects over TLS from the start, usually on port 465
+	TLSImplicit TLSMode = "tls"
+	// TLSNone sends in the clear; only for relays on a trusted network
+	TLSNone TLSMode = "none"
+)
+
+// Account is the mail account one user's email is sent through, over
+// SMTP, and read from, over IMAP; either may be left out
+type Account struct {
+	UserID   string `json:"user"`
+	SMTPAddr string `json:"smtp_addr"`
+	IMAPAddr string `json:"imap_addr"`
+	Username string `json:"username"`
+	// Password may reference environment variables, e.g. "$GMAIL_APP_PASSWORD";
+	// providers with two-factor sign-in need an app password here
+	Password string `json:"password"`
+	// From is the sender, e.g. "Alice Smith <alice@example.com>"
+	From string `json:"from"`
+	// TLS defaults to tls on port 465 and starttls otherwise
+	TLS TLSMode `json:"tls"`
+	// IMAPTLS defaults to tls on port 993 and starttls otherwise
+	IMAPTLS TLSMode `json:"imap_tls"`
+	// Mailbox is the IMAP mailbox polled for replies (default INBOX)
+	Mailbox string `json:"mailbox"`
+	// Interval is how often the mailbox is polled (default 5 minutes)
+	Interval Duration `json:"interval"`
+}
+
+// Duration is a time.Duration read from JSON as a string such as "5m"
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5m\": %w", err)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// LoadAccounts reads accounts from a JSON file, one per user:
+//
+//	{"accounts": [{"user": "alice", "smtp_addr": "smtp.gmail.com:587",
+//	  "imap_addr": "imap.gmail.com:993", "interval": "5m",
+//	  "username": "alice@gmail.com", "password": "$GMAIL_APP_PASSWORD",
+//	  "from": "Alice Smith <alice@gmail.com>", "tls": "starttls"}]}
+func LoadAccounts(path string) ([]Account, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email config: %w", err)
+	}
+	var file struct {
+		Accounts []Account `json:"accounts"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse email config: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range file.Accounts {
+		account := &file.Accounts[i]
+		account.Username = os.ExpandEnv(account.Username)
+		account.Password = os.ExpandEnv(account.Password)
+		if err := account.validate(); err != nil {
+			return nil, fmt.Errorf("email account %d: %w", i+1, err)
+		}
+		if seen[account.UserID] {
+			return nil, fmt.Errorf("duplicate email account for user %q", account.UserID)
+		}
+		seen[account.UserID] = true
+	}
+	return file.Accounts, nil
+}
+
+// validate checks the account is usable and fills in its defaults
+func (a *Account) validate() error {
+	if a.UserID == "" || a.From == "" || (a.SMTPAddr == "" && a.IMAPAddr == "") {
+		return fmt.Errorf("needs a user, from, and an smtp_addr or imap_addr")
+	}
+	if _, err := netmail.ParseAddress(a.From); err != nil {
+		return fmt.Errorf("invalid from address %q: %w", a.From, err)
+	}
+	var err error
+	if a.SMTPAddr != "" {
+		if a.TLS, err = tlsMode(a.SMTPAddr, a.TLS, "465"); err != nil {
+			return fmt.Errorf("smtp_addr: %w", err)
+		}
+	}
+	if a.IMAPAddr != "" {
+		if a.IMAPTLS, err = tlsMode(a.IMAPAddr, a.IMAPTLS, "993"); err != nil {
+			return fmt.Errorf("imap_addr: %w", err)
+		}
+		if a.Username == "" {
+			return fmt.Errorf("user %q needs a username to read mail", a.UserID)
+		}
+		if a.Mailbox == "" {
+			a.Mailbox = "INBOX"
+		}
+	}
+	if a.Username != "" && a.Password == "" {
+		return fmt.Errorf("user %q has a username but no password", a.UserID)
+	}
+	return nil
+}
+
+// tlsMode validates addr and returns mode, defaulting to implicit TLS on
+// tlsPort and STARTTLS elsewhere
+func tlsMode(addr string, mode TLSMode, tlsPort string) (TLSMode, error) {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("must be host:port: %w", err)
+	}
+	switch mode = TLSMode(strings.ToLower(string(mode))); mode {
+	case "":
+		if port == tlsPort {
+			return TLSImplicit, nil
+		}
+		return TLSStartTLS, nil
+	case TLSStartTLS, TLSImplicit, TLSNone:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown tls mode %q", mode)
+	}
+}
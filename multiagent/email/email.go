@@ -0,0 +1,230 @@
+// Package email sends the messages users compose through their own SMTP
+// accounts and reads the replies from their IMAP mailboxes
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// ErrNoAccount is returned when sending for a user without an account
+var ErrNoAccount = errors.New("email: no account configured")
+
+// DefaultTimeout bounds a whole send when the context has no deadline
+const DefaultTimeout = time.Minute
+
+// Message is one plain-text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+	Date    time.Time
+}
+
+// Receipt records a message the SMTP server accepted
+type Receipt struct {
+	MessageID string    `json:"message_id"`
+	Server    string    `json:"server"`
+	From      string    `json:"from"`
+	To        []string  `json:"to"`
+	At        time.Time `json:"at"`
+}
+
+// Sender sends email on behalf of users
+type Sender interface {
+	// From returns the address userID sends from, if they have an account
+	From(userID string) (string, bool)
+	// Send hands msg to userID's SMTP server
+	Send(ctx context.Context, userID string, msg Message) (Receipt, error)
+}
+
+// MailerConfig configures a Mailer
+type MailerConfig struct {
+	Accounts []Account
+	// Timeout bounds a send when ctx has no deadline (default DefaultTimeout)
+	Timeout time.Duration
+	// TLSConfig is the base TLS configuration, e.g. with extra root CAs;
+	// ServerName is always set to the account's host
+	TLSConfig *tls.Config
+}
+
+// Mailer sends email through each user's SMTP account
+type Mailer struct {
+	accounts  map[string]Account
+	timeout   time.Duration
+	tlsConfig *tls.Config
+}
+
+// NewMailer creates a mailer for the accounts in config
+func NewMailer(config MailerConfig) (*Mailer, error) {
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+	m := &Mailer{
+		accounts:  make(map[string]Account, len(config.Accounts)),
+		timeout:   config.Timeout,
+		tlsConfig: config.TLSConfig,
+	}
+	for _, account := range config.Accounts {
+		if err := account.validate(); err != nil {
+			return nil, fmt.Errorf("invalid email account: %w", err)
+		}
+		if account.SMTPAddr != "" {
+			m.accounts[account.UserID] = account
+		}
+	}
+	return m, nil
+}
+
+// From implements Sender
+func (m *Mailer) From(userID string) (string, bool) {
+	account, ok := m.accounts[userID]
+	return account.From, ok
+}
+
+// Send implements Sender. Once Send returns a receipt the server has taken
+// responsibility for the message; delivery to the recipient may still fail
+// later and is reported by the provider, not here.
+func (m *Mailer) Send(ctx context.Context, userID string, msg Message) (Receipt, error) {
+	account, ok := m.accounts[userID]
+	if !ok {
+		return Receipt{}, ErrNoAccount
+	}
+	if len(msg.To) == 0 {
+		return Receipt{}, errors.New("email: message has no recipients")
+	}
+	from, err := netmail.ParseAddress(account.From)
+	if err != nil {
+		return Receipt{}, fmt.Errorf("invalid from address: %w", err)
+	}
+	to := make([]string, 0, len(msg.To))
+	for _, recipient := range msg.To {
+		address, err := netmail.ParseAddress(recipient)
+		if err != nil {
+			return Receipt{}, fmt.Errorf("invalid recipient %q: %w", recipient, err)
+		}
+		to = append(to, address.Address)
+	}
+	if msg.Date.IsZero() {
+		msg.Date = time.Now()
+	}
+	host, _, _ := net.SplitHostPort(account.SMTPAddr)
+	messageID := newMessageID(from.Address)
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", account.SMTPAddr)
+	if err != nil {
+		return Receipt{}, fmt.Errorf("failed to connect to %s: %w", account.SMTPAddr, err)
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	// net/smtp knows nothing of contexts; cancelling unblocks it by
+	// expiring the connection
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	if account.TLS == TLSImplicit {
+		conn = tls.Client(conn, m.tlsFor(host))
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return Receipt{}, fmt.Errorf("failed to greet %s: %w", account.SMTPAddr, err)
+	}
+	defer client.Close()
+
+	if account.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return Receipt{}, fmt.Errorf("%s does not offer STARTTLS", account.SMTPAddr)
+		}
+		if err := client.StartTLS(m.tlsFor(host)); err != nil {
+			return Receipt{}, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if account.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", account.Username, account.Password, host)); err != nil {
+			return Receipt{}, fmt.Errorf("failed to authenticate as %s: %w", account.Username, err)
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return Receipt{}, fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return Receipt{}, fmt.Errorf("failed to add recipient %s: %w", recipient, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return Receipt{}, fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(format(from.String(), msg, messageID)); err != nil {
+		return Receipt{}, fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return Receipt{}, fmt.Errorf("server refused message: %w", err)
+	}
+	// The message is accepted; a failed QUIT doesn't change that
+	client.Quit()
+
+	return Receipt{
+		MessageID: messageID,
+		Server:    account.SMTPAddr,
+		From:      from.Address,
+		To:        to,
+		At:        time.Now(),
+	}, nil
+}
+
+// tlsFor returns the TLS configuration for host
+func (m *Mailer) tlsFor(host string) *tls.Config {
+	config := &tls.Config{}
+	if m.tlsConfig != nil {
+		config = m.tlsConfig.Clone()
+	}
+	config.ServerName = host
+	return config
+}
+
+// newMessageID returns a unique Message-ID in the sender's domain
+func newMessageID(from string) string {
+	_, domain, ok := strings.Cut(from, "@")
+	if !ok {
+		domain = "localhost"
+	}
+	random := make([]byte, 12)
+	rand.Read(random)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain)
+}
+
+// format renders msg as a plain-text email
+func format(from string, msg Message, messageID string) []byte {
+	oneLine := strings.NewReplacer("\r", " ", "\n", " ")
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", oneLine.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", oneLine.Replace(strings.Join(msg.To, ", ")))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", oneLine.Replace(msg.Subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", msg.Date.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: %s\r\n", messageID)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
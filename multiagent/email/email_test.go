@@ -0,0 +1,288 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTP is a minimal SMTP server that records what it is sent
+type fakeSMTP struct {
+	listener net.Listener
+	startTLS *tls.Config // Offered as STARTTLS when set
+
+	mu   sync.Mutex
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+func newFakeSMTP(t *testing.T, listener net.Listener, startTLS *tls.Config) *fakeSMTP {
+	s := &fakeSMTP{listener: listener, startTLS: startTLS}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r, w := bufio.NewReader(conn), conn
+	reply := func(line string) { w.Write([]byte(line + "\r\n")) }
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			extensions := []string{"250-fake", "250 AUTH PLAIN"}
+			if s.startTLS != nil {
+				extensions = []string{"250-fake", "250-STARTTLS", "250 AUTH PLAIN"}
+			}
+			for _, extension := range extensions {
+				reply(extension)
+			}
+		case "STARTTLS":
+			reply("220 go ahead")
+			tlsConn := tls.Server(conn, s.startTLS)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, r, w = tlsConn, bufio.NewReader(tlsConn), tlsConn
+			s.startTLS = nil
+		case "AUTH":
+			_, encoded, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(encoded)
+			s.mu.Lock()
+			s.auth = string(decoded)
+			s.mu.Unlock()
+			reply("235 ok")
+		case "MAIL":
+			s.mu.Lock()
+			s.from = arg
+			s.mu.Unlock()
+			reply("250 ok")
+		case "RCPT":
+			if strings.Contains(arg, "reject") {
+				reply("550 no such user")
+				continue
+			}
+			s.mu.Lock()
+			s.to = append(s.to, arg)
+			s.mu.Unlock()
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var b strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				b.WriteString(line)
+			}
+			s.mu.Lock()
+			s.data = b.String()
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown")
+		}
+	}
+}
+
+// testTLS returns a server certificate for 127.0.0.1 and a client config
+// trusting it
+func testTLS(t *testing.T) (*tls.Config, *tls.Config) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	client := server.Client().Transport.(*http.Transport).TLSClientConfig
+	return &tls.Config{Certificates: server.TLS.Certificates}, client
+}
+
+func TestMailer_Send(t *testing.T) {
+	serverTLS, clientTLS := testTLS(t)
+	tests := []struct {
+		name string
+		mode TLSMode
+	}{
+		{"starttls", TLSStartTLS},
+		{"implicit tls", TLSImplicit},
+		{"plain", TLSNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var listener net.Listener
+			var err error
+			var startTLS *tls.Config
+			switch tt.mode {
+			case TLSImplicit:
+				listener, err = tls.Listen("tcp", "127.0.0.1:0", serverTLS)
+			case TLSStartTLS:
+				startTLS = serverTLS
+				fallthrough
+			default:
+				listener, err = net.Listen("tcp", "127.0.0.1:0")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := newFakeSMTP(t, listener, startTLS)
+
+			mailer, err := NewMailer(MailerConfig{
+				Accounts: []Account{{
+					UserID:   "alice",
+					SMTPAddr: listener.Addr().String(),
+					Username: "alice@example.com",
+					Password: "app-password",
+					From:     "Alice Smith <alice@example.com>",
+					TLS:      tt.mode,
+				}},
+				TLSConfig: clientTLS,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			receipt, err := mailer.Send(context.Background(), "alice", Message{
+				To:      []string{"Bob <bob@example.com>"},
+				Subject: "Proposal\r\nBcc: eve@example.com",
+				Body:    "Hi Bob,\nsee attached.",
+			})
+			if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			if !strings.HasSuffix(receipt.MessageID, "@example.com>") || receipt.Server != listener.Addr().String() {
+				t.Errorf("unexpected receipt %+v", receipt)
+			}
+
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			if server.auth != "\x00alice@example.com\x00app-password" {
+				t.Errorf("auth = %q", server.auth)
+			}
+			if server.from != "FROM:<alice@example.com>" || len(server.to) != 1 || server.to[0] != "TO:<bob@example.com>" {
+				t.Errorf("envelope = %q %q", server.from, server.to)
+			}
+			for _, want := range []string{"From: \"Alice Smith\" <alice@example.com>\r\n", "Subject: Proposal  Bcc: eve@example.com\r\n", "Message-ID: " + receipt.MessageID, "Hi Bob,\r\nsee attached."} {
+				if !strings.Contains(server.data, want) {
+					t.Errorf("message lacks %q:\n%s", want, server.data)
+				}
+			}
+		})
+	}
+}
+
+func TestMailer_Failures(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newFakeSMTP(t, listener, nil)
+	account := Account{UserID: "alice", SMTPAddr: listener.Addr().String(), From: "alice@example.com"}
+
+	mailer, err := NewMailer(MailerConfig{Accounts: []Account{account}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mailer.Send(context.Background(), "bob", Message{To: []string{"x@example.com"}}); !errors.Is(err, ErrNoAccount) {
+		t.Errorf("send without account: %v", err)
+	}
+	// The server doesn't offer STARTTLS, so nothing may be sent in the clear
+	if _, err := mailer.Send(context.Background(), "alice", Message{To: []string{"bob@example.com"}}); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("send without STARTTLS: %v", err)
+	}
+
+	account.TLS = TLSNone
+	mailer, _ = NewMailer(MailerConfig{Accounts: []Account{account}})
+	if _, err := mailer.Send(context.Background(), "alice", Message{To: []string{"reject@example.com"}}); err == nil {
+		t.Error("rejected recipient was sent to")
+	}
+	if _, err := mailer.Send(context.Background(), "alice", Message{To: []string{"not an address"}}); err == nil {
+		t.Error("invalid recipient was sent to")
+	}
+
+	// A server that never greets is given up on when ctx ends
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	go func() {
+		conn, err := silent.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(2 * time.Second)
+		}
+	}()
+	account.SMTPAddr = silent.Addr().String()
+	mailer, _ = NewMailer(MailerConfig{Accounts: []Account{account}})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := mailer.Send(ctx, "alice", Message{To: []string{"bob@example.com"}}); err == nil || time.Since(start) > time.Second {
+		t.Errorf("send to silent server: %v after %s", err, time.Since(start))
+	}
+}
+
+func TestLoadAccounts(t *testing.T) {
+	t.Setenv("TEST_APP_PASSWORD", "secret")
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "email.json")
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	accounts, err := LoadAccounts(write(`{"accounts": [
+		{"user": "alice", "smtp_addr": "smtp.gmail.com:587", "username": "alice@gmail.com", "password": "$TEST_APP_PASSWORD", "from": "alice@gmail.com"},
+		{"user": "bob", "smtp_addr": "smtp.example.com:465", "from": "bob@example.com"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accounts[0].Password != "secret" || accounts[0].TLS != TLSStartTLS || accounts[1].TLS != TLSImplicit {
+		t.Errorf("unexpected accounts %+v", accounts)
+	}
+
+	for _, bad := range []string{
+		`{"accounts": [{"user": "alice", "smtp_addr": "smtp.example.com", "from": "alice@example.com"}]}`,
+		`{"accounts": [{"user": "alice", "smtp_addr": "smtp.example.com:587", "from": "nobody"}]}`,
+		`{"accounts": [{"user": "alice", "smtp_addr": "smtp.example.com:587", "from": "a@example.com", "tls": "ssl3"}]}`,
+		`{"accounts": [{"user": "alice", "smtp_addr": "smtp.example.com:587", "from": "a@example.com", "username": "a", "password": "$UNSET_APP_PASSWORD"}]}`,
+		`{"accounts": [{"user": "alice", "smtp_addr": "a:587", "from": "a@example.com"}, {"user": "alice", "smtp_addr": "b:587", "from": "a@example.com"}]}`,
+	} {
+		if _, err := LoadAccounts(write(bad)); err == nil {
+			t.Errorf("accepted %s", bad)
+		}
+	}
+}
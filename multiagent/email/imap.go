@@ -0,0 +1,202 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapLiteral matches the {n} that announces n bytes of literal data at the
+// end of a response line
+var imapLiteral = regexp.MustCompile(`\{(\d+)\}$`)
+
+// imapUIDValidity finds the mailbox's UIDVALIDITY in a SELECT response
+var imapUIDValidity = regexp.MustCompile(`(?i)\[UIDVALIDITY (\d+)\]`)
+
+// imapLine is one response line with the literals it carried
+type imapLine struct {
+	text     string
+	literals [][]byte
+}
+
+// imapConn is the little of IMAP4rev1 (RFC 3501) polling an inbox needs:
+// log in, select a mailbox, search it by UID or date and fetch messages
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// dialIMAP connects to addr and reads the greeting, upgrading the
+// connection first or after as mode says
+func dialIMAP(ctx context.Context, addr string, mode TLSMode, tlsConfig *tls.Config) (*imapConn, error) {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if mode == TLSImplicit {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	c := &imapConn{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readLine()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read greeting: %w", err)
+	}
+	if !strings.HasPrefix(strings.ToUpper(greeting.text), "* OK") && !strings.HasPrefix(strings.ToUpper(greeting.text), "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected greeting %q", greeting.text)
+	}
+	if mode == TLSStartTLS {
+		if _, err := c.command("STARTTLS"); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s does not offer STARTTLS: %w", addr, err)
+		}
+		c.conn = tls.Client(conn, tlsConfig)
+		c.r = bufio.NewReader(c.conn)
+	}
+	return c, nil
+}
+
+// Close closes the connection
+func (c *imapConn) Close() error {
+	return c.conn.Close()
+}
+
+// login authenticates with a username and (app) password
+func (c *imapConn) login(username, password string) error {
+	if _, err := c.command("LOGIN " + imapQuote(username) + " " + imapQuote(password)); err != nil {
+		return fmt.Errorf("failed to log in as %s: %w", username, err)
+	}
+	return nil
+}
+
+// selectMailbox opens mailbox read-only and returns its UIDVALIDITY
+func (c *imapConn) selectMailbox(mailbox string) (uint32, error) {
+	lines, err := c.command("EXAMINE " + imapQuote(mailbox))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", mailbox, err)
+	}
+	for _, line := range lines {
+		if match := imapUIDValidity.FindStringSubmatch(line.text); match != nil {
+			validity, _ := strconv.ParseUint(match[1], 10, 32)
+			return uint32(validity), nil
+		}
+	}
+	return 0, nil
+}
+
+// searchUIDs returns the UIDs matching criteria, e.g. "UID 42:*" or
+// "SINCE 8-Oct-2026"
+func (c *imapConn) searchUIDs(criteria string) ([]uint32, error) {
+	lines, err := c.command("UID SEARCH " + criteria)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search mailbox: %w", err)
+	}
+	var uids []uint32
+	for _, line := range lines {
+		fields := strings.Fields(line.text)
+		if len(fields) < 2 || fields[0] != "*" || !strings.EqualFold(fields[1], "SEARCH") {
+			continue
+		}
+		for _, field := range fields[2:] {
+			if uid, err := strconv.ParseUint(field, 10, 32); err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// fetch returns up to limit bytes of the message with UID uid, without
+// marking it read
+func (c *imapConn) fetch(uid uint32, limit int) ([]byte, error) {
+	lines, err := c.command(fmt.Sprintf("UID FETCH %d (UID BODY.PEEK[]<0.%d>)", uid, limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch message %d: %w", uid, err)
+	}
+	for _, line := range lines {
+		if len(line.literals) > 0 {
+			return line.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("message %d not found", uid)
+}
+
+// logout ends the session politely
+func (c *imapConn) logout() {
+	c.command("LOGOUT")
+}
+
+// command sends one tagged command and returns the untagged lines before
+// its completion, failing unless the completion is OK
+func (c *imapConn) command(command string) ([]imapLine, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%d", c.tag)
+	if _, err := io.WriteString(c.conn, tag+" "+command+"\r\n"); err != nil {
+		return nil, err
+	}
+	var lines []imapLine
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if rest, ok := strings.CutPrefix(line.text, tag+" "); ok {
+			if status, _, _ := strings.Cut(rest, " "); !strings.EqualFold(status, "OK") {
+				return nil, fmt.Errorf("imap: %s", rest)
+			}
+			return lines, nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+// readLine reads one response line, following the literals it announces
+func (c *imapConn) readLine() (imapLine, error) {
+	var line imapLine
+	var b strings.Builder
+	for {
+		text, err := c.r.ReadString('\n')
+		if err != nil {
+			return line, err
+		}
+		text = strings.TrimRight(text, "\r\n")
+		b.WriteString(text)
+		match := imapLiteral.FindStringSubmatch(text)
+		if match == nil {
+			line.text = b.String()
+			return line, nil
+		}
+		size, err := strconv.Atoi(match[1])
+		if err != nil || size > 64<<20 {
+			return line, fmt.Errorf("imap: bad literal size %q", match[1])
+		}
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return line, err
+		}
+		line.literals = append(line.literals, literal)
+	}
+}
+
+// imapQuote renders s as an IMAP quoted string
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// imapDate renders t as an IMAP search date
+func imapDate(t time.Time) string {
+	return t.Format("2-Jan-2006")
+}
@@ -0,0 +1,364 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+var logger = logging.For("email")
+
+// pollStateKeyPrefix holds, per user, how far their mailbox has been read
+const pollStateKeyPrefix = "email_poll:"
+
+const (
+	// maxMessageBytes is how much of each message is fetched
+	maxMessageBytes = 256 << 10
+	// maxBodyRunes is how much of a message's text is kept
+	maxBodyRunes = 4000
+	// maxPerPoll bounds how many messages one poll reads, newest first
+	maxPerPoll = 50
+)
+
+// InboundEmail is a message read from a user's mailbox
+type InboundEmail struct {
+	MessageID string    `json:"message_id"`
+	InReplyTo []string  `json:"in_reply_to,omitempty"` // In-Reply-To and References
+	FromName  string    `json:"from_name"`
+	FromAddr  string    `json:"from_addr"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	Date      time.Time `json:"date"`
+}
+
+// Inbox is the assistant's side of polling: where the messages read end up
+type Inbox interface {
+	// LogInboundEmail records a message the user ctx acts for received,
+	// reporting whether it was new and kept
+	LogInboundEmail(ctx context.Context, message InboundEmail) (bool, error)
+}
+
+// PollerConfig holds configuration for creating a Poller
+type PollerConfig struct {
+	Account Account
+	// Inbox receives the messages read, usually the communication manager
+	Inbox Inbox
+	// Store keeps the poll state; it is read and written acting for the
+	// account's user
+	Store multiagent.MemoryStore
+	// Lookback is how far back the first poll reads (default 7 days)
+	Lookback time.Duration
+	// Timeout bounds one poll (default DefaultTimeout)
+	Timeout time.Duration
+	// TLSConfig is the base TLS configuration, as for MailerConfig
+	TLSConfig *tls.Config
+}
+
+// PollResult reports what one poll read
+type PollResult struct {
+	Fetched int `json:"fetched"`
+	Logged  int `json:"logged"`
+}
+
+// pollState is stored per user
+type pollState struct {
+	UIDValidity uint32    `json:"uid_validity"`
+	LastUID     uint32    `json:"last_uid"`
+	LastPoll    time.Time `json:"last_poll"`
+}
+
+// Poller reads new messages from one user's IMAP mailbox into the inbox
+type Poller struct {
+	config PollerConfig
+	pollMu sync.Mutex // serialises polls
+
+	mu       sync.Mutex
+	running  bool
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewPoller creates a poller for config's account
+func NewPoller(config PollerConfig) (*Poller, error) {
+	if err := config.Account.validate(); err != nil {
+		return nil, fmt.Errorf("invalid email account: %w", err)
+	}
+	if config.Account.IMAPAddr == "" {
+		return nil, fmt.Errorf("email account of %q has no imap_addr", config.Account.UserID)
+	}
+	if config.Account.Interval <= 0 {
+		config.Account.Interval = Duration(5 * time.Minute)
+	}
+	if config.Lookback <= 0 {
+		config.Lookback = 7 * 24 * time.Hour
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	return &Poller{config: config}, nil
+}
+
+// Start polls immediately and then on every interval until Stop
+func (p *Poller) Start(ctx context.Context) {
+	p.mu.Lock()
+	if p.running {
+		p.mu.Unlock()
+		return
+	}
+	p.running = true
+	p.stopChan = make(chan struct{})
+	stop := p.stopChan
+	p.mu.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(time.Duration(p.config.Account.Interval))
+		defer ticker.Stop()
+
+		for {
+			if result, err := p.Poll(ctx); err != nil {
+				logger.WarnContext(ctx, "Mailbox poll failed", logging.KeyUserID, p.config.Account.UserID, "error", err)
+			} else if result.Logged > 0 {
+				logger.InfoContext(ctx, "Mailbox poll finished", logging.KeyUserID, p.config.Account.UserID, "fetched", result.Fetched, "logged", result.Logged)
+			}
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops polling after any poll in progress
+func (p *Poller) Stop() {
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return
+	}
+	p.running = false
+	close(p.stopChan)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+// Poll reads the messages that arrived since the last poll, or within the
+// lookback on the first, and hands them to the inbox oldest first. A
+// message the inbox fails on is read again on the next poll.
+func (p *Poller) Poll(ctx context.Context) (PollResult, error) {
+	p.pollMu.Lock()
+	defer p.pollMu.Unlock()
+
+	account := p.config.Account
+	ctx = multiagent.WithUserID(ctx, account.UserID)
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+	var result PollResult
+
+	state, err := p.loadState(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	host, _, _ := net.SplitHostPort(account.IMAPAddr)
+	tlsConfig := &tls.Config{}
+	if p.config.TLSConfig != nil {
+		tlsConfig = p.config.TLSConfig.Clone()
+	}
+	tlsConfig.ServerName = host
+	conn, err := dialIMAP(ctx, account.IMAPAddr, account.IMAPTLS, tlsConfig)
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+	// The IMAP exchange knows nothing of contexts; cancelling unblocks it
+	// by expiring the connection
+	stop := context.AfterFunc(ctx, func() { conn.conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	if err := conn.login(account.Username, account.Password); err != nil {
+		return result, err
+	}
+	defer conn.logout()
+	validity, err := conn.selectMailbox(account.Mailbox)
+	if err != nil {
+		return result, err
+	}
+	if validity != state.UIDValidity {
+		// UIDs from another incarnation of the mailbox mean nothing here
+		state = pollState{UIDValidity: validity}
+	}
+
+	criteria := "SINCE " + imapDate(time.Now().Add(-p.config.Lookback))
+	if state.LastUID > 0 {
+		criteria = fmt.Sprintf("UID %d:*", state.LastUID+1)
+	}
+	uids, err := conn.searchUIDs(criteria)
+	if err != nil {
+		return result, err
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	fresh := uids[:0]
+	for _, uid := range uids {
+		// "n:*" always matches the newest message, even when it is older
+		if uid > state.LastUID {
+			fresh = append(fresh, uid)
+		}
+	}
+	if len(fresh) > maxPerPoll {
+		fresh = fresh[len(fresh)-maxPerPoll:]
+	}
+
+	self := ""
+	if from, err := netmail.ParseAddress(account.From); err == nil {
+		self = strings.ToLower(from.Address)
+	}
+	for _, uid := range fresh {
+		raw, err := conn.fetch(uid, maxMessageBytes)
+		if err != nil {
+			return result, p.finish(ctx, state, err)
+		}
+		result.Fetched++
+		message, err := ParseMessage(raw)
+		if err != nil {
+			logger.WarnContext(ctx, "Skipping unreadable message", logging.KeyUserID, account.UserID, "uid", uid, "error", err)
+			state.LastUID = uid
+			continue
+		}
+		if strings.ToLower(message.FromAddr) != self {
+			logged, err := p.config.Inbox.LogInboundEmail(ctx, message)
+			if err != nil {
+				return result, p.finish(ctx, state, fmt.Errorf("failed to log message %d: %w", uid, err))
+			}
+			if logged {
+				result.Logged++
+			}
+		}
+		state.LastUID = uid
+	}
+	return result, p.finish(ctx, state, nil)
+}
+
+// finish saves how far the mailbox was read and returns pollErr, or the
+// error saving
+func (p *Poller) finish(ctx context.Context, state pollState, pollErr error) error {
+	state.LastPoll = time.Now()
+	if err := p.config.Store.Store(ctx, pollStateKeyPrefix+p.config.Account.UserID, state); err != nil && pollErr == nil {
+		return fmt.Errorf("failed to save poll state: %w", err)
+	}
+	return pollErr
+}
+
+// loadState reads how far the mailbox was read, if it ever was
+func (p *Poller) loadState(ctx context.Context) (pollState, error) {
+	var state pollState
+	value, err := p.config.Store.Get(ctx, pollStateKeyPrefix+p.config.Account.UserID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return state, nil
+		}
+		return state, fmt.Errorf("failed to load poll state: %w", err)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return state, fmt.Errorf("failed to marshal poll state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to unmarshal poll state: %w", err)
+	}
+	return state, nil
+}
+
+// ParseMessage reads the sender, subject, threading headers and plain text
+// of a raw RFC 5322 message
+func ParseMessage(raw []byte) (InboundEmail, error) {
+	var message InboundEmail
+	parsed, err := netmail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return message, fmt.Errorf("failed to parse message: %w", err)
+	}
+	header := parsed.Header
+	from, err := header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return message, fmt.Errorf("message has no sender: %w", err)
+	}
+	decoder := new(mime.WordDecoder)
+	message.FromName, message.FromAddr = from[0].Name, from[0].Address
+	message.Subject = header.Get("Subject")
+	if decoded, err := decoder.DecodeHeader(message.Subject); err == nil {
+		message.Subject = decoded
+	}
+	message.MessageID = strings.TrimSpace(header.Get("Message-Id"))
+	message.InReplyTo = strings.Fields(header.Get("In-Reply-To") + " " + header.Get("References"))
+	if message.Date, err = header.Date(); err != nil {
+		message.Date = time.Now()
+	}
+
+	body, err := plainText(header.Get("Content-Type"), header.Get("Content-Transfer-Encoding"), parsed.Body)
+	if err != nil {
+		// A truncated or odd body still leaves the headers worth logging
+		logger.Debug("Failed to read message body", "message_id", message.MessageID, "error", err)
+	}
+	body = strings.TrimSpace(strings.ReplaceAll(body, "\r\n", "\n"))
+	if runes := []rune(body); len(runes) > maxBodyRunes {
+		body = string(runes[:maxBodyRunes]) + "…"
+	}
+	message.Body = body
+	return message, nil
+}
+
+// plainText returns the first text/plain part of a body
+func plainText(contentType, encoding string, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if contentType == "" || err != nil {
+		mediaType = "text/plain"
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err != nil {
+				return "", err
+			}
+			text, err := plainText(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if text != "" || err != nil {
+				return text, err
+			}
+		}
+	case mediaType == "text/plain":
+		switch strings.ToLower(strings.TrimSpace(encoding)) {
+		case "quoted-printable":
+			body = quotedprintable.NewReader(body)
+		case "base64":
+			// The decoder skips the line breaks base64 bodies are wrapped with
+			body = base64.NewDecoder(base64.StdEncoding, body)
+		}
+		data, err := io.ReadAll(body)
+		return string(data), err
+	default:
+		return "", nil
+	}
+}
@@ -0,0 +1,218 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+// fakeIMAP is a minimal IMAP server holding one mailbox
+type fakeIMAP struct {
+	mu       sync.Mutex
+	messages map[uint32]string
+	logins   []string
+}
+
+func newFakeIMAP(t *testing.T) (*fakeIMAP, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	s := &fakeIMAP{messages: make(map[uint32]string)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s, listener.Addr().String()
+}
+
+func (s *fakeIMAP) add(uid uint32, raw string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[uid] = strings.ReplaceAll(raw, "\n", "\r\n")
+}
+
+func (s *fakeIMAP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprintf(conn, "* OK fake IMAP ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		tag, command := fields[0], strings.ToUpper(strings.Join(fields[1:min(3, len(fields))], " "))
+		s.mu.Lock()
+		switch {
+		case strings.HasPrefix(command, "LOGIN"):
+			s.logins = append(s.logins, fields[2]+" "+fields[3])
+			fmt.Fprintf(conn, "%s OK logged in\r\n", tag)
+		case strings.HasPrefix(command, "EXAMINE"):
+			fmt.Fprintf(conn, "* %d EXISTS\r\n* OK [UIDVALIDITY 7] ok\r\n%s OK [READ-ONLY] done\r\n", len(s.messages), tag)
+		case command == "UID SEARCH":
+			var matches []string
+			if strings.EqualFold(fields[3], "UID") {
+				from, _ := strconv.ParseUint(strings.TrimSuffix(fields[4], ":*"), 10, 32)
+				newest := uint32(0)
+				for uid := range s.messages {
+					if uint32(from) <= uid {
+						matches = append(matches, strconv.Itoa(int(uid)))
+					}
+					newest = max(newest, uid)
+				}
+				if len(matches) == 0 && newest > 0 {
+					matches = append(matches, strconv.Itoa(int(newest)))
+				}
+			} else {
+				for uid := range s.messages {
+					matches = append(matches, strconv.Itoa(int(uid)))
+				}
+			}
+			fmt.Fprintf(conn, "* SEARCH %s\r\n%s OK done\r\n", strings.Join(matches, " "), tag)
+		case command == "UID FETCH":
+			uid, _ := strconv.ParseUint(fields[3], 10, 32)
+			if raw, ok := s.messages[uint32(uid)]; ok {
+				fmt.Fprintf(conn, "* 1 FETCH (UID %d BODY[]<0> {%d}\r\n%s)\r\n", uid, len(raw), raw)
+			}
+			fmt.Fprintf(conn, "%s OK done\r\n", tag)
+		case command == "LOGOUT":
+			fmt.Fprintf(conn, "* BYE\r\n%s OK bye\r\n", tag)
+			s.mu.Unlock()
+			return
+		default:
+			fmt.Fprintf(conn, "%s BAD unknown\r\n", tag)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// recordingInbox keeps what it is given, refusing subjects containing
+// "refuse"
+type recordingInbox struct {
+	mu       sync.Mutex
+	messages []InboundEmail
+	users    []string
+}
+
+func (i *recordingInbox) LogInboundEmail(ctx context.Context, message InboundEmail) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if strings.Contains(message.Subject, "refuse") {
+		return false, fmt.Errorf("refused")
+	}
+	i.messages = append(i.messages, message)
+	i.users = append(i.users, multiagent.UserIDFromContext(ctx))
+	return true, nil
+}
+
+func TestPoller_Poll(t *testing.T) {
+	server, addr := newFakeIMAP(t)
+	server.add(3, "From: Jane Doe <jane@example.com>\nSubject: Re: Proposal\nMessage-ID: <r1@example.com>\nIn-Reply-To: <s1@example.com>\nDate: Mon, 12 Oct 2026 10:00:00 +0000\n\nLooks good!\n")
+	server.add(5, "From: Alice <alice@example.com>\nSubject: Sent by me\n\nNot a reply.\n")
+
+	fs, err := memory.NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fs.Close() })
+	inbox := &recordingInbox{}
+	poller, err := NewPoller(PollerConfig{
+		Account: Account{UserID: "alice", IMAPAddr: addr, IMAPTLS: TLSNone, Username: "alice@example.com", Password: "app-pw", From: "alice@example.com"},
+		Inbox:   inbox,
+		Store:   memory.PartitionByUser(fs),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	result, err := poller.Poll(ctx)
+	if err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	// The user's own message is read but not logged
+	if result.Fetched != 2 || result.Logged != 1 {
+		t.Fatalf("first poll = %+v", result)
+	}
+	got := inbox.messages[0]
+	if got.FromName != "Jane Doe" || got.FromAddr != "jane@example.com" || got.Subject != "Re: Proposal" || got.Body != "Looks good!" ||
+		got.MessageID != "<r1@example.com>" || len(got.InReplyTo) != 1 || got.InReplyTo[0] != "<s1@example.com>" || inbox.users[0] != "alice" {
+		t.Errorf("unexpected message %+v for %q", got, inbox.users[0])
+	}
+	if server.logins[0] != `"alice@example.com" "app-pw"` {
+		t.Errorf("login = %q", server.logins[0])
+	}
+
+	// Nothing new: the newest message that "UID 6:*" returns is not read again
+	if result, err := poller.Poll(ctx); err != nil || result.Fetched != 0 {
+		t.Fatalf("second poll = %+v, %v", result, err)
+	}
+
+	// A message the inbox refuses is retried, and holds back those after it
+	server.add(8, "From: bob@example.com\nSubject: please refuse\n\nx\n")
+	server.add(9, "From: bob@example.com\nSubject: later\n\ny\n")
+	if _, err := poller.Poll(ctx); err == nil {
+		t.Fatal("refused message did not fail the poll")
+	}
+	server.add(8, "From: bob@example.com\nSubject: accepted now\n\nx\n")
+	if result, err := poller.Poll(ctx); err != nil || result.Logged != 2 {
+		t.Fatalf("retry poll = %+v, %v", result, err)
+	}
+	if len(inbox.messages) != 3 || inbox.messages[1].Subject != "accepted now" || inbox.messages[2].Subject != "later" {
+		t.Errorf("unexpected messages %+v", inbox.messages)
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	raw := strings.ReplaceAll(`From: =?utf-8?q?Jos=C3=A9?= <jose@example.com>
+Subject: =?utf-8?q?Caf=C3=A9_plans?=
+Message-ID: <m1@example.com>
+References: <a@example.com> <b@example.com>
+Content-Type: multipart/alternative; boundary="b1"
+
+--b1
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+See you at the caf=C3=A9 =
+tomorrow.
+--b1
+Content-Type: text/html
+
+<p>See you</p>
+--b1--
+`, "\n", "\r\n")
+	message, err := ParseMessage([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.FromName != "José" || message.Subject != "Café plans" || message.Body != "See you at the café tomorrow." || len(message.InReplyTo) != 2 {
+		t.Errorf("unexpected message %+v", message)
+	}
+
+	encoded := "From: a@example.com\r\nContent-Transfer-Encoding: base64\r\n\r\nSGVsbG8g\r\nd29ybGQ=\r\n"
+	if message, err := ParseMessage([]byte(encoded)); err != nil || message.Body != "Hello world" {
+		t.Errorf("base64 body = %q, %v", message.Body, err)
+	}
+	if _, err := ParseMessage([]byte("Subject: no sender\r\n\r\nx")); err == nil {
+		t.Error("message without sender parsed")
+	}
+}
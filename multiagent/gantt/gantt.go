@@ -0,0 +1,188 @@
+// Package gantt renders project schedules as Mermaid gantt diagrams, which
+// Markdown viewers such as GitHub's draw, and as CSV for spreadsheet tools.
+package gantt
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats
+const (
+	FormatMermaid = "mermaid"
+	FormatCSV     = "csv"
+)
+
+const date = "2006-01-02"
+
+// Status is how a bar is drawn
+type Status string
+
+const (
+	StatusPending  Status = ""
+	StatusActive   Status = "active"
+	StatusDone     Status = "done"
+	StatusCritical Status = "crit"
+)
+
+// Chart is a project schedule
+type Chart struct {
+	Title    string
+	Sections []Section
+}
+
+// Section groups bars under a heading, as a milestone groups its tasks
+type Section struct {
+	Name  string
+	Items []Item
+}
+
+// Item is a bar, or a milestone when Milestone is set. Start and End are
+// the first and last days of the bar; a milestone falls on Start.
+type Item struct {
+	ID        string
+	Title     string
+	Start     time.Time
+	End       time.Time
+	Milestone bool
+	Status    Status
+	// Progress is the percentage of the work done
+	Progress float64
+	Assignee string
+	// After lists the IDs of items this one depends on
+	After []string
+}
+
+var (
+	// mermaidUnsafeID matches what Mermaid does not accept in a task ID
+	mermaidUnsafeID = regexp.MustCompile(`[^A-Za-z0-9_]`)
+	// mermaidUnsafeText matches what ends a title or heading in Mermaid's
+	// gantt syntax
+	mermaidUnsafeText = regexp.MustCompile(`[:;#\r\n]+`)
+)
+
+// csvHeader is the layout WriteCSV writes
+var csvHeader = []string{
+	"section", "id", "title", "type", "start", "end", "duration_days", "status", "progress", "assignee", "depends_on",
+}
+
+// Write renders chart in format
+func Write(w io.Writer, format string, chart Chart) error {
+	switch format {
+	case FormatMermaid:
+		return WriteMermaid(w, chart)
+	case FormatCSV:
+		return WriteCSV(w, chart)
+	}
+	return fmt.Errorf("unknown gantt format %q", format)
+}
+
+// WriteMermaid renders chart as a Mermaid gantt diagram
+func WriteMermaid(w io.Writer, chart Chart) error {
+	var b strings.Builder
+	b.WriteString("gantt\n")
+	if title := mermaidText(chart.Title); title != "" {
+		b.WriteString("    title " + title + "\n")
+	}
+	b.WriteString("    dateFormat YYYY-MM-DD\n")
+	b.WriteString("    axisFormat %b %d\n")
+	for _, section := range chart.Sections {
+		if len(section.Items) == 0 {
+			continue
+		}
+		b.WriteString("    section " + mermaidText(section.Name) + "\n")
+		for _, item := range section.Items {
+			var fields []string
+			if item.Milestone {
+				fields = append(fields, "milestone")
+			}
+			if item.Status != StatusPending {
+				fields = append(fields, string(item.Status))
+			}
+			fields = append(fields, mermaidID(item.ID), item.Start.Format(date))
+			if item.Milestone {
+				fields = append(fields, "0d")
+			} else {
+				// Mermaid's end dates are exclusive
+				fields = append(fields, lastDay(item).AddDate(0, 0, 1).Format(date))
+			}
+			b.WriteString(fmt.Sprintf("    %s :%s\n", mermaidText(item.Title), strings.Join(fields, ", ")))
+		}
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write mermaid gantt: %w", err)
+	}
+	return nil
+}
+
+// WriteCSV writes chart as CSV with a header row, one row per bar or
+// milestone
+func WriteCSV(w io.Writer, chart Chart) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+	for _, section := range chart.Sections {
+		for _, item := range section.Items {
+			kind, days := "task", strconv.Itoa(Days(item))
+			if item.Milestone {
+				kind = "milestone"
+			}
+			status := string(item.Status)
+			if item.Status == StatusPending {
+				status = "pending"
+			}
+			record := []string{
+				section.Name, item.ID, item.Title, kind, item.Start.Format(date), lastDay(item).Format(date), days,
+				status, strconv.FormatFloat(item.Progress, 'f', -1, 64), item.Assignee, strings.Join(item.After, " "),
+			}
+			if err := out.Write(record); err != nil {
+				return fmt.Errorf("failed to write %q: %w", item.Title, err)
+			}
+		}
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
+
+// Days is how many days item spans, counting both its first and last;
+// milestones span none
+func Days(item Item) int {
+	if item.Milestone {
+		return 0
+	}
+	start := time.Date(item.Start.Year(), item.Start.Month(), item.Start.Day(), 0, 0, 0, 0, time.UTC)
+	end := lastDay(item)
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+// lastDay is the item's last day, never before its first
+func lastDay(item Item) time.Time {
+	if item.Milestone || item.End.Before(item.Start) {
+		return item.Start
+	}
+	return item.End
+}
+
+// mermaidID makes id usable as a Mermaid task ID
+func mermaidID(id string) string {
+	id = mermaidUnsafeID.ReplaceAllString(id, "_")
+	if id == "" || (id[0] >= '0' && id[0] <= '9') {
+		id = "t" + id
+	}
+	return id
+}
+
+// mermaidText keeps text from breaking a Mermaid line
+func mermaidText(text string) string {
+	return strings.Join(strings.Fields(mermaidUnsafeText.ReplaceAllString(text, " ")), " ")
+}
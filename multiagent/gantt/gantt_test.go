@@ -0,0 +1,82 @@
+package gantt
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleChart() Chart {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	return Chart{
+		Title: "Website: relaunch",
+		Sections: []Section{
+			{Name: "Beta", Items: []Item{
+				{ID: "task_1", Title: "Design", Start: day(2), End: day(4), Status: StatusDone, Progress: 100, Assignee: "me"},
+				{ID: "task-2", Title: "Build; part 1", Start: day(5), End: day(5), Status: StatusActive, Progress: 40, After: []string{"task_1"}},
+				{ID: "2", Title: "Beta", Start: day(6), Milestone: true, Status: StatusCritical},
+			}},
+			{Name: "Empty"},
+			{Name: "Tasks", Items: []Item{
+				{ID: "task_3", Title: "Launch", Start: day(9), End: day(8)},
+			}},
+		},
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMermaid(&buf, sampleChart()); err != nil {
+		t.Fatalf("WriteMermaid: %v", err)
+	}
+	want := `gantt
+    title Website relaunch
+    dateFormat YYYY-MM-DD
+    axisFormat %b %d
+    section Beta
+    Design :done, task_1, 2026-03-02, 2026-03-05
+    Build part 1 :active, task_2, 2026-03-05, 2026-03-06
+    Beta :milestone, crit, t2, 2026-03-06, 0d
+    section Tasks
+    Launch :task_3, 2026-03-09, 2026-03-10
+`
+	if buf.String() != want {
+		t.Errorf("unexpected diagram:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatCSV, sampleChart()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	if len(records) != 5 || strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		t.Fatalf("unexpected records %q", records)
+	}
+	checks := []struct {
+		row  int
+		want string
+	}{
+		{1, "Beta,task_1,Design,task,2026-03-02,2026-03-04,3,done,100,me,"},
+		{2, "Beta,task-2,Build; part 1,task,2026-03-05,2026-03-05,1,active,40,,task_1"},
+		{3, "Beta,2,Beta,milestone,2026-03-06,2026-03-06,0,crit,0,,"},
+		{4, "Tasks,task_3,Launch,task,2026-03-09,2026-03-09,1,pending,0,,"},
+	}
+	for _, check := range checks {
+		if got := strings.Join(records[check.row], ","); got != check.want {
+			t.Errorf("row %d = %q, want %q", check.row, got, check.want)
+		}
+	}
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "xml", sampleChart()); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
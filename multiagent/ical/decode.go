@@ -0,0 +1,454 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// windowsZones maps the Windows timezone names Outlook writes as TZIDs to
+// IANA names
+var windowsZones = map[string]string{
+	"UTC":                            "UTC",
+	"GMT Standard Time":              "Europe/London",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Romance Standard Time":          "Europe/Paris",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"FLE Standard Time":              "Europe/Kiev",
+	"Russian Standard Time":          "Europe/Moscow",
+	"Eastern Standard Time":          "America/New_York",
+	"Central Standard Time":          "America/Chicago",
+	"Mountain Standard Time":         "America/Denver",
+	"US Mountain Standard Time":      "America/Phoenix",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"Alaskan Standard Time":          "America/Anchorage",
+	"Hawaiian Standard Time":         "Pacific/Honolulu",
+	"Atlantic Standard Time":         "America/Halifax",
+	"E. South America Standard Time": "America/Sao_Paulo",
+	"India Standard Time":            "Asia/Kolkata",
+	"China Standard Time":            "Asia/Shanghai",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"Singapore Standard Time":        "Asia/Singapore",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+	"New Zealand Standard Time":      "Pacific/Auckland",
+}
+
+// Decode reads an iCalendar stream. Components other than events and their
+// alarms, such as VTIMEZONE and VTODO, are skipped; TZIDs are resolved as
+// IANA (or Windows) zone names, and floating times are read as UTC.
+func Decode(r io.Reader) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+
+	cal := &Calendar{}
+	var (
+		stack  []string
+		event  *Event
+		alarm  *alarmDraft
+		alarms []*alarmDraft
+		found  bool
+	)
+	for number, raw := range lines {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		prop, err := parseLine(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number+1, err)
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			component := strings.ToUpper(prop.value)
+			stack = append(stack, component)
+			switch {
+			case component == "VCALENDAR":
+				found = true
+			case component == "VEVENT" && len(stack) == 2:
+				event = &Event{}
+			case component == "VALARM" && event != nil:
+				alarm = &alarmDraft{}
+			}
+			continue
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != strings.ToUpper(prop.value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", number+1, prop.value)
+			}
+			stack = stack[:len(stack)-1]
+			switch {
+			case strings.EqualFold(prop.value, "VALARM") && alarm != nil && event != nil:
+				// Triggers may be relative to DTEND, so they are resolved
+				// with the event
+				alarms = append(alarms, alarm)
+				alarm = nil
+			case strings.EqualFold(prop.value, "VEVENT") && event != nil:
+				if err := finishEvent(event); err != nil {
+					return nil, fmt.Errorf("line %d: %w", number+1, err)
+				}
+				for _, draft := range alarms {
+					event.Alarms = append(event.Alarms, draft.resolve(event))
+				}
+				cal.Events = append(cal.Events, *event)
+				event, alarms = nil, nil
+			}
+			continue
+		}
+
+		if len(stack) == 0 {
+			continue
+		}
+		switch current := stack[len(stack)-1]; {
+		case current == "VCALENDAR":
+			switch prop.name {
+			case "PRODID":
+				cal.ProdID = prop.value
+			case "X-WR-CALNAME":
+				cal.Name = unescapeText(prop.value)
+			case "METHOD":
+				cal.Method = strings.ToUpper(prop.value)
+			}
+		case current == "VALARM" && alarm != nil:
+			if err := alarm.set(prop); err != nil {
+				return nil, fmt.Errorf("line %d: %w", number+1, err)
+			}
+		case current == "VEVENT" && event != nil:
+			if err := setEventProperty(event, prop); err != nil {
+				return nil, fmt.Errorf("line %d: %w", number+1, err)
+			}
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no VCALENDAR found")
+	}
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("unterminated %s", stack[len(stack)-1])
+	}
+	return cal, nil
+}
+
+// property is one content line
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// unfold joins folded content lines
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine splits "NAME;PARAM=value:VALUE", honouring quoted parameters
+func parseLine(line string) (property, error) {
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, fmt.Errorf("invalid content line %q", line)
+	}
+
+	prop := property{params: map[string]string{}, value: line[colon+1:]}
+	head := splitOutsideQuotes(line[:colon], ';')
+	prop.name = strings.ToUpper(head[0])
+	for _, param := range head[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(name)] = strings.Trim(value, "\"")
+	}
+	return prop, nil
+}
+
+func splitOutsideQuotes(s string, sep rune) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func setEventProperty(event *Event, prop property) error {
+	var err error
+	switch prop.name {
+	case "UID":
+		event.UID = unescapeText(prop.value)
+	case "SUMMARY":
+		event.Summary = unescapeText(prop.value)
+	case "DESCRIPTION":
+		event.Description = unescapeText(prop.value)
+	case "LOCATION":
+		event.Location = unescapeText(prop.value)
+	case "URL":
+		event.URL = prop.value
+	case "STATUS":
+		event.Status = strings.ToUpper(prop.value)
+	case "CATEGORIES":
+		for _, category := range splitText(prop.value) {
+			if category != "" {
+				event.Categories = append(event.Categories, category)
+			}
+		}
+	case "DTSTART":
+		event.Start, event.AllDay, err = parseDateTime(prop.value, prop.params, time.UTC)
+	case "DTEND":
+		event.End, _, err = parseDateTime(prop.value, prop.params, time.UTC)
+	case "DURATION":
+		var d time.Duration
+		if d, err = parseDuration(prop.value); err == nil {
+			// Resolved against DTSTART once the event is complete
+			event.End = time.Time{}.Add(d)
+		}
+	case "RECURRENCE-ID":
+		var t time.Time
+		t, _, err = parseDateTime(prop.value, prop.params, time.UTC)
+		event.RecurrenceID = &t
+	case "RRULE":
+		event.RRule, err = ParseRRule(prop.value, time.UTC)
+	case "EXDATE":
+		for _, value := range strings.Split(prop.value, ",") {
+			var t time.Time
+			if t, _, err = parseDateTime(value, prop.params, time.UTC); err != nil {
+				break
+			}
+			event.ExDates = append(event.ExDates, t)
+		}
+	case "TRANSP":
+		event.Transparent = strings.EqualFold(prop.value, "TRANSPARENT")
+	case "ORGANIZER":
+		organizer := parseAttendee(prop)
+		organizer.Status, organizer.Optional = "", false
+		event.Organizer = &organizer
+	case "ATTENDEE":
+		event.Attendees = append(event.Attendees, parseAttendee(prop))
+	case "CREATED":
+		event.Created, _, err = parseDateTime(prop.value, prop.params, time.UTC)
+	case "LAST-MODIFIED":
+		event.LastModified, _, err = parseDateTime(prop.value, prop.params, time.UTC)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", prop.name, err)
+	}
+	return nil
+}
+
+// finishEvent resolves what depends on several properties
+func finishEvent(event *Event) error {
+	if event.Start.IsZero() {
+		return fmt.Errorf("event %q has no DTSTART", event.UID)
+	}
+	if event.UID == "" {
+		event.UID = fmt.Sprintf("%s-%s", event.Start.UTC().Format(utcLayout), event.Summary)
+	}
+
+	// A DURATION was parsed as an offset from the zero time
+	if !event.End.IsZero() && event.End.Year() == 1 {
+		event.End = event.Start.Add(event.End.Sub(time.Time{}))
+	}
+	if event.End.IsZero() {
+		if event.AllDay {
+			event.End = event.Start.AddDate(0, 0, 1)
+		} else {
+			event.End = event.Start
+		}
+	}
+
+	// A date UNTIL includes that whole day
+	if rule := event.RRule; rule != nil && rule.Until != nil && event.AllDay {
+		until := *rule.Until
+		local := time.Date(until.Year(), until.Month(), until.Day(), 23, 59, 59, 0, event.Start.Location())
+		rule.Until = &local
+	}
+	return nil
+}
+
+// parseDateTime reads a DATE or DATE-TIME value, returning whether it was a
+// date; TZID selects the zone, a trailing Z means UTC, and anything else is
+// read in floating
+func parseDateTime(value string, params map[string]string, floating *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(params["VALUE"], "DATE") || len(value) == len(dateLayout) {
+		loc := floating
+		if tzid := params["TZID"]; tzid != "" {
+			loc = resolveTZID(tzid)
+		}
+		t, err := time.ParseInLocation(dateLayout, value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(utcLayout, value)
+		return t, false, err
+	}
+
+	loc := floating
+	if tzid := params["TZID"]; tzid != "" {
+		loc = resolveTZID(tzid)
+	}
+	t, err := time.ParseInLocation(localLayout, value, loc)
+	return t, false, err
+}
+
+// resolveTZID returns the zone a TZID names, or UTC if it is unknown
+func resolveTZID(tzid string) *time.Location {
+	tzid = strings.Trim(tzid, "\"")
+	// Some producers prefix a path, e.g. "/mozilla.org/20050126_1/Europe/Berlin"
+	candidates := []string{tzid}
+	if windows, ok := windowsZones[tzid]; ok {
+		candidates = append([]string{windows}, candidates...)
+	}
+	if parts := strings.Split(tzid, "/"); len(parts) > 2 {
+		candidates = append(candidates, strings.Join(parts[len(parts)-2:], "/"))
+	}
+	for _, name := range candidates {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration reads an iCalendar duration such as "-PT15M" or "P1DT2H"
+func parseDuration(value string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(value)))
+	if m == nil || value == "P" || value == "PT" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", value, err)
+		}
+		d += time.Duration(n) * unit
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+func parseAttendee(prop property) Attendee {
+	attendee := Attendee{
+		Name:     prop.params["CN"],
+		Status:   strings.ToUpper(prop.params["PARTSTAT"]),
+		Optional: strings.EqualFold(prop.params["ROLE"], "OPT-PARTICIPANT") || strings.EqualFold(prop.params["ROLE"], "NON-PARTICIPANT"),
+	}
+	address := prop.value
+	switch {
+	case strings.HasPrefix(strings.ToLower(address), "mailto:"):
+		attendee.Email = address[len("mailto:"):]
+	case strings.HasPrefix(address, "urn:invalid:") && attendee.Name == "":
+		attendee.Name = strings.ReplaceAll(address[len("urn:invalid:"):], "%20", " ")
+	}
+	return attendee
+}
+
+// alarmDraft collects a VALARM's properties until its event is complete
+type alarmDraft struct {
+	action      string
+	description string
+	trigger     time.Duration
+	relatedEnd  bool
+	absolute    *time.Time
+}
+
+func (d *alarmDraft) set(prop property) error {
+	switch prop.name {
+	case "ACTION":
+		d.action = strings.ToUpper(prop.value)
+	case "DESCRIPTION":
+		d.description = unescapeText(prop.value)
+	case "TRIGGER":
+		if strings.EqualFold(prop.params["VALUE"], "DATE-TIME") {
+			t, _, err := parseDateTime(prop.value, prop.params, time.UTC)
+			if err != nil {
+				return fmt.Errorf("invalid TRIGGER: %w", err)
+			}
+			d.absolute = &t
+			return nil
+		}
+		trigger, err := parseDuration(prop.value)
+		if err != nil {
+			return fmt.Errorf("invalid TRIGGER: %w", err)
+		}
+		d.trigger = trigger
+		d.relatedEnd = strings.EqualFold(prop.params["RELATED"], "END")
+	}
+	return nil
+}
+
+// resolve converts the trigger into a lead time before the event's start
+func (d *alarmDraft) resolve(event *Event) Alarm {
+	alarm := Alarm{Action: d.action, Description: d.description, Before: -d.trigger}
+	switch {
+	case d.absolute != nil && !event.Start.IsZero():
+		alarm.Before = event.Start.Sub(*d.absolute)
+	case d.relatedEnd:
+		alarm.Before = -(event.End.Sub(event.Start) + d.trigger)
+	}
+	return alarm
+}
+
+// splitText splits a comma-separated TEXT list, honouring escaped commas
+func splitText(value string) []string {
+	var parts []string
+	var current strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			current.WriteRune('\\')
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ',':
+			parts = append(parts, unescapeText(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	return append(parts, unescapeText(current.String()))
+}
+
+func unescapeText(text string) string {
+	return strings.NewReplacer("\\\\", "\\", "\\;", ";", "\\,", ",", "\\n", "\n", "\\N", "\n").Replace(text)
+}
@@ -0,0 +1,264 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	dateLayout  = "20060102"
+
+	// maxLineOctets is the longest content line before it must be folded
+	maxLineOctets = 75
+)
+
+// Encode writes cal as an iCalendar stream
+func Encode(w io.Writer, cal *Calendar) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+	prodID := cal.ProdID
+	if prodID == "" {
+		prodID = DefaultProdID
+	}
+
+	e.line("BEGIN:VCALENDAR")
+	e.line("VERSION:2.0")
+	e.line("PRODID:" + prodID)
+	e.line("CALSCALE:GREGORIAN")
+	if cal.Method != "" {
+		e.line("METHOD:" + cal.Method)
+	}
+	if cal.Name != "" {
+		e.line("X-WR-CALNAME:" + escapeText(cal.Name))
+	}
+	for i := range cal.Events {
+		e.event(&cal.Events[i])
+	}
+	e.line("END:VCALENDAR")
+
+	if e.err != nil {
+		return fmt.Errorf("failed to write calendar: %w", e.err)
+	}
+	if err := e.w.Flush(); err != nil {
+		return fmt.Errorf("failed to write calendar: %w", err)
+	}
+	return nil
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (e *encoder) event(event *Event) {
+	e.line("BEGIN:VEVENT")
+	e.line("UID:" + escapeText(event.UID))
+	stamp := event.LastModified
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	e.line("DTSTAMP:" + stamp.UTC().Format(utcLayout))
+	if !event.Created.IsZero() {
+		e.line("CREATED:" + event.Created.UTC().Format(utcLayout))
+	}
+	if !event.LastModified.IsZero() {
+		e.line("LAST-MODIFIED:" + event.LastModified.UTC().Format(utcLayout))
+	}
+
+	e.line("DTSTART" + formatDateTime(event.Start, event.AllDay))
+	end := event.End
+	if event.AllDay && !end.After(event.Start) {
+		end = event.Start.AddDate(0, 0, 1)
+	}
+	if !end.IsZero() {
+		e.line("DTEND" + formatDateTime(end, event.AllDay))
+	}
+	if event.RecurrenceID != nil {
+		e.line("RECURRENCE-ID" + formatDateTime(*event.RecurrenceID, event.AllDay))
+	}
+
+	e.line("SUMMARY:" + escapeText(event.Summary))
+	if event.Description != "" {
+		e.line("DESCRIPTION:" + escapeText(event.Description))
+	}
+	if event.Location != "" {
+		e.line("LOCATION:" + escapeText(event.Location))
+	}
+	if event.URL != "" {
+		e.line("URL:" + event.URL)
+	}
+	if event.Status != "" {
+		e.line("STATUS:" + event.Status)
+	}
+	if len(event.Categories) > 0 {
+		categories := make([]string, len(event.Categories))
+		for i, category := range event.Categories {
+			categories[i] = escapeText(category)
+		}
+		e.line("CATEGORIES:" + strings.Join(categories, ","))
+	}
+
+	if event.Transparent {
+		e.line("TRANSP:TRANSPARENT")
+	}
+
+	if event.RRule != nil {
+		e.line("RRULE:" + event.RRule.String())
+	}
+	for _, exdate := range event.ExDates {
+		if !event.AllDay {
+			// An exception names the occurrence's start, at its time of day
+			exdate = time.Date(exdate.Year(), exdate.Month(), exdate.Day(), event.Start.Hour(), event.Start.Minute(), event.Start.Second(), 0, event.Start.Location())
+		}
+		e.line("EXDATE" + formatDateTime(exdate, event.AllDay))
+	}
+
+	if event.Organizer != nil {
+		e.line("ORGANIZER" + formatOrganizer(*event.Organizer))
+	}
+	for _, attendee := range event.Attendees {
+		e.line("ATTENDEE" + formatAttendee(attendee))
+	}
+
+	for _, alarm := range event.Alarms {
+		action := alarm.Action
+		if action == "" {
+			action = "DISPLAY"
+		}
+		description := alarm.Description
+		if description == "" {
+			description = event.Summary
+		}
+		e.line("BEGIN:VALARM")
+		e.line("ACTION:" + action)
+		e.line("TRIGGER:" + formatDuration(-alarm.Before))
+		e.line("DESCRIPTION:" + escapeText(description))
+		e.line("END:VALARM")
+	}
+	e.line("END:VEVENT")
+}
+
+// line writes one content line, folded to 75 octets
+func (e *encoder) line(content string) {
+	if e.err != nil {
+		return
+	}
+	for len(content) > maxLineOctets {
+		cut := maxLineOctets
+		if strings.HasPrefix(content, " ") {
+			cut-- // The continuation's leading space counts
+		}
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		if _, e.err = e.w.WriteString(content[:cut] + "\r\n"); e.err != nil {
+			return
+		}
+		content = " " + content[cut:]
+	}
+	_, e.err = e.w.WriteString(content + "\r\n")
+}
+
+// formatDateTime formats a DTSTART-like value with its parameters, starting
+// with ";" or ":"
+func formatDateTime(t time.Time, allDay bool) string {
+	switch {
+	case allDay:
+		return ";VALUE=DATE:" + t.Format(dateLayout)
+	case t.Location() == time.UTC:
+		return ":" + t.Format(utcLayout)
+	default:
+		return ";TZID=" + t.Location().String() + ":" + t.Format(localLayout)
+	}
+}
+
+func formatAttendee(attendee Attendee) string {
+	var b strings.Builder
+	if attendee.Name != "" {
+		fmt.Fprintf(&b, ";CN=%s", quoteParam(attendee.Name))
+	}
+	if attendee.Optional {
+		b.WriteString(";ROLE=OPT-PARTICIPANT")
+	} else {
+		b.WriteString(";ROLE=REQ-PARTICIPANT")
+	}
+	if attendee.Status != "" {
+		b.WriteString(";PARTSTAT=" + attendee.Status)
+	}
+	if attendee.Email != "" {
+		b.WriteString(":mailto:" + attendee.Email)
+	} else {
+		// An attendee needs an address; name-only attendees get a
+		// placeholder that decodes back to just the name
+		b.WriteString(":urn:invalid:" + strings.ReplaceAll(attendee.Name, " ", "%20"))
+	}
+	return b.String()
+}
+
+func formatOrganizer(organizer Attendee) string {
+	var b strings.Builder
+	if organizer.Name != "" {
+		fmt.Fprintf(&b, ";CN=%s", quoteParam(organizer.Name))
+	}
+	if organizer.Email != "" {
+		b.WriteString(":mailto:" + organizer.Email)
+	} else {
+		b.WriteString(":urn:invalid:" + strings.ReplaceAll(organizer.Name, " ", "%20"))
+	}
+	return b.String()
+}
+
+// quoteParam quotes a parameter value when it holds separators
+func quoteParam(value string) string {
+	value = strings.ReplaceAll(value, "\"", "'")
+	if strings.ContainsAny(value, ";:,") {
+		return "\"" + value + "\""
+	}
+	return value
+}
+
+// formatDuration formats d as an iCalendar duration such as "-PT15M"
+func formatDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	if d == 0 {
+		return "PT0S"
+	}
+
+	var b strings.Builder
+	b.WriteString(sign + "P")
+	if days := d / (24 * time.Hour); days > 0 {
+		if d%(7*24*time.Hour) == 0 {
+			fmt.Fprintf(&b, "%dW", days/7)
+			return b.String()
+		}
+		fmt.Fprintf(&b, "%dD", days)
+		d -= days * 24 * time.Hour
+	}
+	if d > 0 {
+		b.WriteString("T")
+		if hours := d / time.Hour; hours > 0 {
+			fmt.Fprintf(&b, "%dH", hours)
+			d -= hours * time.Hour
+		}
+		if minutes := d / time.Minute; minutes > 0 {
+			fmt.Fprintf(&b, "%dM", minutes)
+			d -= minutes * time.Minute
+		}
+		if seconds := d / time.Second; seconds > 0 {
+			fmt.Fprintf(&b, "%dS", seconds)
+		}
+	}
+	return b.String()
+}
+
+func escapeText(text string) string {
+	return strings.NewReplacer("\\", "\\\\", ";", "\\;", ",", "\\,", "\r\n", "\\n", "\n", "\\n").Replace(text)
+}
@@ -0,0 +1,84 @@
+// Package ical reads and writes iCalendar (RFC 5545) data — events with
+// their recurrence rules, exceptions, attendees and alarms — so calendars
+// can be exchanged with Google, Apple and Outlook.
+package ical
+
+import (
+	"time"
+)
+
+// DefaultProdID identifies calendars written by this package
+const DefaultProdID = "-//wikillm//multiagent//EN"
+
+// Methods of scheduling messages
+const (
+	MethodPublish = "PUBLISH"
+	MethodRequest = "REQUEST"
+	MethodCancel  = "CANCEL"
+)
+
+// Event statuses
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusTentative = "TENTATIVE"
+	StatusCancelled = "CANCELLED"
+)
+
+// Calendar is a VCALENDAR
+type Calendar struct {
+	ProdID string
+	// Name is the calendar's display name (X-WR-CALNAME)
+	Name string
+	// Method is the iTIP method of a scheduling message, e.g. REQUEST for
+	// an invitation; plain calendars have none
+	Method string
+	Events []Event
+}
+
+// Event is a VEVENT
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	// Start and End are in the event's timezone, which is written as its
+	// TZID; UTC times are written as UTC. All-day events span whole days,
+	// End being exclusive.
+	Start      time.Time
+	End        time.Time
+	AllDay     bool
+	Status     string
+	Categories []string
+	// Transparent events (TRANSP:TRANSPARENT) do not make anyone busy
+	Transparent bool
+	// Organizer is who sends the event's invitations
+	Organizer *Attendee
+	Attendees []Attendee
+	RRule     *RRule
+	// ExDates are occurrences removed from the recurrence
+	ExDates []time.Time
+	Alarms  []Alarm
+	// RecurrenceID is set on events that override one occurrence of a
+	// recurring event with the same UID
+	RecurrenceID *time.Time
+	Created      time.Time
+	LastModified time.Time
+}
+
+// Attendee is an ATTENDEE of an event
+type Attendee struct {
+	Name  string
+	Email string
+	// Status is the PARTSTAT, e.g. ACCEPTED or NEEDS-ACTION
+	Status   string
+	Optional bool
+}
+
+// Alarm is a VALARM
+type Alarm struct {
+	// Before is how long before the event starts the alarm fires
+	Before      time.Duration
+	Action      string // DISPLAY, AUDIO or EMAIL
+	Description string
+}
@@ -0,0 +1,162 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone database unavailable: %v", err)
+	}
+	until := time.Date(2026, 6, 30, 23, 59, 59, 0, time.UTC)
+	event := Event{
+		UID:         "standup-1@example.com",
+		Summary:     "Standup; daily, short",
+		Description: strings.Repeat("A long agenda line that has to be folded. ", 5) + "\nSecond line — with ünïcode",
+		Location:    "Room 4",
+		Start:       time.Date(2026, 3, 2, 9, 30, 0, 0, berlin),
+		End:         time.Date(2026, 3, 2, 9, 45, 0, 0, berlin),
+		Status:      StatusConfirmed,
+		Categories:  []string{"work", "team, core"},
+		Transparent: true,
+		Organizer:   &Attendee{Name: "Grace Hopper", Email: "grace@example.com"},
+		Attendees: []Attendee{
+			{Name: "Ada Lovelace", Email: "ada@example.com", Status: "ACCEPTED"},
+			{Name: "Bob", Optional: true},
+		},
+		RRule: &RRule{
+			Freq:  FreqWeekly,
+			Until: &until,
+			ByDay: []WeekdayNum{{Day: time.Monday}, {Day: time.Wednesday}},
+		},
+		ExDates: []time.Time{time.Date(2026, 3, 4, 0, 0, 0, 0, berlin)},
+		Alarms:  []Alarm{{Before: 15 * time.Minute, Action: "DISPLAY"}},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, &Calendar{Name: "Work", Method: MethodRequest, Events: []Event{event}}); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line longer than %d octets: %q", maxLineOctets, line)
+		}
+	}
+	if !strings.Contains(buf.String(), "DTSTART;TZID=Europe/Berlin:20260302T093000") {
+		t.Errorf("expected a TZID start, got:\n%s", buf.String())
+	}
+
+	cal, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if cal.Name != "Work" || cal.Method != MethodRequest || len(cal.Events) != 1 {
+		t.Fatalf("unexpected calendar: %+v", cal)
+	}
+	got := cal.Events[0]
+	if got.UID != event.UID || got.Summary != event.Summary || got.Description != event.Description {
+		t.Errorf("text did not round-trip: %q / %q / %q", got.UID, got.Summary, got.Description)
+	}
+	if !got.Start.Equal(event.Start) || got.Start.Location().String() != "Europe/Berlin" || !got.End.Equal(event.End) {
+		t.Errorf("times did not round-trip: %s - %s", got.Start, got.End)
+	}
+	if len(got.Categories) != 2 || got.Categories[1] != "team, core" {
+		t.Errorf("categories did not round-trip: %q", got.Categories)
+	}
+	if got.Organizer == nil || *got.Organizer != *event.Organizer || !got.Transparent {
+		t.Errorf("organizer or transparency did not round-trip: %+v %v", got.Organizer, got.Transparent)
+	}
+	if len(got.Attendees) != 2 || got.Attendees[0].Email != "ada@example.com" || got.Attendees[1].Name != "Bob" || !got.Attendees[1].Optional {
+		t.Errorf("attendees did not round-trip: %+v", got.Attendees)
+	}
+	if got.RRule == nil || got.RRule.String() != event.RRule.String() {
+		t.Errorf("rule did not round-trip: %+v", got.RRule)
+	}
+	if len(got.ExDates) != 1 || !got.ExDates[0].Equal(time.Date(2026, 3, 4, 9, 30, 0, 0, berlin)) {
+		t.Errorf("exceptions did not round-trip: %v", got.ExDates)
+	}
+	if len(got.Alarms) != 1 || got.Alarms[0].Before != 15*time.Minute {
+		t.Errorf("alarms did not round-trip: %+v", got.Alarms)
+	}
+}
+
+func TestDecode_ThirdPartyCalendar(t *testing.T) {
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"PRODID:-//Google Inc//Google Calendar 70.9054//EN",
+		"VERSION:2.0",
+		"BEGIN:VTIMEZONE",
+		"TZID:Eastern Standard Time",
+		"BEGIN:STANDARD",
+		"DTSTART:16010101T020000",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"DTSTART;TZID=Eastern Standard Time:20260310T140000",
+		"DURATION:PT1H30M",
+		"RRULE:FREQ=MONTHLY;BYDAY=2TU;COUNT=6",
+		"UID:abc123@google.com",
+		`ATTENDEE;CN="Smith, Jane";PARTSTAT=NEEDS-ACTION;ROLE=OPT-PARTICIPANT:mailto:jane@exa`,
+		" mple.com",
+		"SUMMARY:Budget review",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER;RELATED=END:-PT2H",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:holiday@example.com",
+		"DTSTART;VALUE=DATE:20260704",
+		"SUMMARY:Independence Day",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	cal, err := Decode(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(cal.Events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(cal.Events))
+	}
+
+	review := cal.Events[0]
+	if review.Start.Location().String() != "America/New_York" || review.Start.Hour() != 14 {
+		t.Errorf("expected 14:00 New York, got %s", review.Start)
+	}
+	if review.End.Sub(review.Start) != 90*time.Minute {
+		t.Errorf("expected a 90 minute event, got %s", review.End.Sub(review.Start))
+	}
+	if rule := review.RRule; rule == nil || rule.Count != 6 || len(rule.ByDay) != 1 || rule.ByDay[0] != (WeekdayNum{N: 2, Day: time.Tuesday}) {
+		t.Errorf("unexpected rule: %+v", review.RRule)
+	}
+	if len(review.Attendees) != 1 || review.Attendees[0].Name != "Smith, Jane" || review.Attendees[0].Email != "jane@example.com" {
+		t.Errorf("unexpected attendees: %+v", review.Attendees)
+	}
+	// Two hours before a 90 minute event ends is 30 minutes before it starts
+	if len(review.Alarms) != 1 || review.Alarms[0].Before != 30*time.Minute {
+		t.Errorf("unexpected alarms: %+v", review.Alarms)
+	}
+
+	holiday := cal.Events[1]
+	if !holiday.AllDay || holiday.End.Sub(holiday.Start) != 24*time.Hour {
+		t.Errorf("expected a one-day all-day event, got %s - %s", holiday.Start, holiday.End)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:x\r\nEND:VEVENT\r\nEND:VCALENDAR",
+		"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:20260101T090000Z\r\nEND:VCALENDAR",
+		"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:tomorrow\r\nEND:VEVENT\r\nEND:VCALENDAR",
+	} {
+		if _, err := Decode(strings.NewReader(data)); err == nil {
+			t.Errorf("expected an error decoding %q", data)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package ical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// RRule is a recurrence rule (RFC 5545 section 3.3.10); parts this package
+// does not model, such as BYSETPOS, are dropped
+type RRule struct {
+	Freq     string
+	Interval int
+	Count    int
+	Until    *time.Time
+	// ByDay lists weekdays, each optionally numbered within the month or
+	// year (2 for "2TU", -1 for "-1FR")
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+}
+
+// WeekdayNum is one BYDAY entry
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// ParseRRule parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE"; a
+// floating UNTIL is read in loc
+func ParseRRule(value string, loc *time.Location) (*RRule, error) {
+	rule := &RRule{}
+	for _, part := range strings.Split(value, ";") {
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(val)
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(val)
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(val)
+		case "UNTIL":
+			var until time.Time
+			until, _, err = parseDateTime(val, nil, loc)
+			rule.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				code = strings.ToUpper(strings.TrimSpace(code))
+				if len(code) < 2 {
+					return nil, fmt.Errorf("invalid BYDAY %q", val)
+				}
+				day, known := weekdayCodes[code[len(code)-2:]]
+				if !known {
+					return nil, fmt.Errorf("invalid BYDAY %q", val)
+				}
+				n := 0
+				if prefix := code[:len(code)-2]; prefix != "" {
+					if n, err = strconv.Atoi(strings.TrimPrefix(prefix, "+")); err != nil {
+						return nil, fmt.Errorf("invalid BYDAY %q: %w", val, err)
+					}
+				}
+				rule.ByDay = append(rule.ByDay, WeekdayNum{N: n, Day: day})
+			}
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseInts(val)
+		case "BYMONTH":
+			rule.ByMonth, err = parseInts(val)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid RRULE part %q: %w", part, err)
+		}
+	}
+	switch rule.Freq {
+	case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+	default:
+		return nil, fmt.Errorf("unsupported RRULE frequency %q", rule.Freq)
+	}
+	return rule, nil
+}
+
+// String formats the rule as an RRULE value
+func (r RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(utcLayout))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = weekdayNames[day.Day]
+			if day.N != 0 {
+				days[i] = strconv.Itoa(day.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+formatInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+formatInts(r.ByMonth))
+	}
+	return strings.Join(parts, ";")
+}
+
+func parseInts(value string) ([]int, error) {
+	var ints []int
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		ints = append(ints, n)
+	}
+	return ints, nil
+}
+
+func formatInts(ints []int) string {
+	fields := make([]string, len(ints))
+	for i, n := range ints {
+		fields[i] = strconv.Itoa(n)
+	}
+	return strings.Join(fields, ",")
+}
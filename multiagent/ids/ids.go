@@ -0,0 +1,185 @@
+// Package ids makes the IDs of messages, tasks and the other records agents
+// keep, and provides the clocks those records are stamped with. IDs are
+// ULIDs by default: unique under load and sorted by creation time. Tests
+// inject a Sequence and a ManualClock to make both deterministic.
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// alphabet is Crockford's base32, lowercased to match the rest of an ID
+const alphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+
+var defaultGenerator = NewULIDGenerator(nil)
+
+// Default returns the shared ULID generator, for code nothing injects a
+// generator into
+func Default() multiagent.IDGenerator {
+	return defaultGenerator
+}
+
+// New returns a new ID starting with prefix from the shared ULID generator
+func New(prefix string) string {
+	return defaultGenerator.NewID(prefix)
+}
+
+// OrDefault returns generator, or the shared ULID generator if it is nil
+func OrDefault(generator multiagent.IDGenerator) multiagent.IDGenerator {
+	if generator == nil {
+		return defaultGenerator
+	}
+	return generator
+}
+
+// ULIDGenerator makes IDs ending in a lowercase ULID: a millisecond
+// timestamp and 80 random bits. IDs from one generator are strictly
+// increasing, including within a millisecond.
+type ULIDGenerator struct {
+	mu      sync.Mutex
+	clock   multiagent.Clock
+	entropy io.Reader
+	lastMs  uint64
+	last    [10]byte
+}
+
+// NewULIDGenerator creates a ULID generator timestamping IDs with clock
+// (default the system clock)
+func NewULIDGenerator(clock multiagent.Clock) *ULIDGenerator {
+	return &ULIDGenerator{clock: ClockOrSystem(clock), entropy: rand.Reader}
+}
+
+// NewID returns prefix, an underscore and a new ULID
+func (g *ULIDGenerator) NewID(prefix string) string {
+	return join(prefix, g.NewULID())
+}
+
+// NewULID returns a new ULID
+func (g *ULIDGenerator) NewULID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(g.clock.Now().UnixMilli())
+	if ms > g.lastMs {
+		if _, err := io.ReadFull(g.entropy, g.last[:]); err != nil {
+			panic(fmt.Sprintf("ids: failed to read entropy: %v", err))
+		}
+		g.lastMs = ms
+	} else if !increment(g.last[:]) {
+		// The random part overflowed; borrow the next millisecond
+		g.lastMs++
+	}
+
+	var id [16]byte
+	binary.BigEndian.PutUint16(id[0:2], uint16(g.lastMs>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(g.lastMs))
+	copy(id[6:], g.last[:])
+	return encode(id)
+}
+
+// Sequence makes IDs numbered per prefix, "task_000001" and so on, so tests
+// can predict them
+type Sequence struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewSequence creates a sequence starting at 1 for every prefix
+func NewSequence() *Sequence {
+	return &Sequence{counts: make(map[string]int)}
+}
+
+// NewID returns prefix and the next number for it
+func (s *Sequence) NewID(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[prefix]++
+	return join(prefix, fmt.Sprintf("%06d", s.counts[prefix]))
+}
+
+// System is the system clock
+var System multiagent.Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// ClockOrSystem returns clock, or the system clock if it is nil
+func ClockOrSystem(clock multiagent.Clock) multiagent.Clock {
+	if clock == nil {
+		return System
+	}
+	return clock
+}
+
+// ManualClock is a clock that only moves when told to
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock creates a clock stopped at start
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the clock's time
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to t
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Advance moves the clock forward by d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func join(prefix, id string) string {
+	if prefix == "" {
+		return id
+	}
+	return prefix + "_" + id
+}
+
+// increment adds one to b as a big-endian number, reporting false if it
+// wrapped around to zero
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encode writes the 128 bits of id as 26 base32 characters, the first
+// holding only the top 3 bits
+func encode(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = alphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
@@ -0,0 +1,114 @@
+package ids
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestULIDsAreUniqueAndOrderedWithinAMillisecond(t *testing.T) {
+	clock := NewManualClock(time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC))
+	generator := NewULIDGenerator(clock)
+
+	var previous string
+	for i := 0; i < 1000; i++ {
+		id := generator.NewID("task")
+		if !strings.HasPrefix(id, "task_") || len(id) != len("task_")+26 {
+			t.Fatalf("unexpected ID %q", id)
+		}
+		if id <= previous {
+			t.Fatalf("ID %q does not sort after %q", id, previous)
+		}
+		previous = id
+	}
+
+	clock.Advance(time.Millisecond)
+	if id := generator.NewID("task"); id <= previous {
+		t.Fatalf("ID %q from a later millisecond sorts before %q", id, previous)
+	}
+}
+
+func TestULIDEncodesTimestamp(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	generator := NewULIDGenerator(NewManualClock(at))
+	generator.entropy = bytes.NewReader(make([]byte, 10))
+
+	// The first 10 characters hold the millisecond timestamp
+	want := ""
+	for ms, i := uint64(at.UnixMilli()), 0; i < 10; i++ {
+		want = string(alphabet[ms&31]) + want
+		ms >>= 5
+	}
+	if got := generator.NewULID(); got != want+strings.Repeat("0", 16) {
+		t.Errorf("got %q, want %q", got, want+strings.Repeat("0", 16))
+	}
+}
+
+func TestULIDBorrowsMillisecondOnOverflow(t *testing.T) {
+	clock := NewManualClock(time.UnixMilli(1000))
+	generator := NewULIDGenerator(clock)
+	generator.entropy = bytes.NewReader(bytes.Repeat([]byte{0xff}, 10))
+
+	first := generator.NewULID()
+	second := generator.NewULID()
+	if second <= first {
+		t.Fatalf("ID %q after overflow does not sort after %q", second, first)
+	}
+	if generator.lastMs != 1001 {
+		t.Errorf("expected timestamp 1001 after overflow, got %d", generator.lastMs)
+	}
+}
+
+func TestULIDsAreUniqueUnderConcurrency(t *testing.T) {
+	generator := NewULIDGenerator(nil)
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+		wg   sync.WaitGroup
+	)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				id := generator.NewID("msg")
+				mu.Lock()
+				if seen[id] {
+					t.Errorf("duplicate ID %q", id)
+				}
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSequenceNumbersPerPrefix(t *testing.T) {
+	sequence := NewSequence()
+	got := []string{sequence.NewID("task"), sequence.NewID("task"), sequence.NewID("proj"), sequence.NewID("")}
+	want := []string{"task_000001", "task_000002", "proj_000001", "000001"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ID %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	clock.Advance(90 * time.Minute)
+	if got := clock.Now(); !got.Equal(start.Add(90 * time.Minute)) {
+		t.Errorf("got %v after Advance", got)
+	}
+	clock.Set(start)
+	if got := clock.Now(); !got.Equal(start) {
+		t.Errorf("got %v after Set", got)
+	}
+	if ClockOrSystem(nil) != System || OrDefault(nil) != Default() {
+		t.Error("expected defaults for nil clock and generator")
+	}
+}
@@ -0,0 +1,94 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Payload formats a source can send
+const (
+	// FormatGeneric is wikillm's own event format, see Incoming
+	FormatGeneric = "generic"
+	// FormatGitHub is GitHub's webhook payloads
+	FormatGitHub = "github"
+)
+
+// Source is an outside system allowed to submit events, such as GitHub, a
+// CI server or a parcel tracker
+type Source struct {
+	// Name identifies the source in the ?source= query parameter, and
+	// becomes the Source of its events
+	Name string `json:"name"`
+	// Format is FormatGeneric (the default) or FormatGitHub
+	Format string `json:"format,omitempty"`
+	// Secret authenticates requests: generic sources send it as a bearer
+	// token, GitHub signs payloads with it. It may reference environment
+	// variables, e.g. "$GITHUB_WEBHOOK_SECRET".
+	Secret string `json:"secret"`
+	// Users maps the source's account names, such as GitHub logins, to
+	// assistant users; events for other accounts are ignored
+	Users map[string]string `json:"users,omitempty"`
+	// UserID receives every event when Users is empty
+	UserID string `json:"user_id,omitempty"`
+}
+
+// user returns the assistant user the source's account maps to, or ""
+func (s *Source) user(account string) string {
+	if len(s.Users) == 0 {
+		return s.UserID
+	}
+	return s.Users[account]
+}
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// LoadConfig reads the sources allowed to submit events from a JSON file:
+//
+//	{"sources": [{"name": "github", "format": "github",
+//	  "secret": "$GITHUB_WEBHOOK_SECRET", "users": {"octocat": "alice"}}]}
+func LoadConfig(path string) ([]Source, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event source config: %w", err)
+	}
+	var file struct {
+		Sources []Source `json:"sources"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse event source config: %w", err)
+	}
+	names := make(map[string]bool, len(file.Sources))
+	for i := range file.Sources {
+		source := &file.Sources[i]
+		source.Secret = os.ExpandEnv(source.Secret)
+		if source.Format == "" {
+			source.Format = FormatGeneric
+		}
+		if err := source.validate(); err != nil {
+			return nil, fmt.Errorf("event source %d: %w", i+1, err)
+		}
+		if names[source.Name] {
+			return nil, fmt.Errorf("event source %d: duplicate name %q", i+1, source.Name)
+		}
+		names[source.Name] = true
+	}
+	return file.Sources, nil
+}
+
+func (s *Source) validate() error {
+	if !namePattern.MatchString(s.Name) {
+		return fmt.Errorf("needs a lowercase name, got %q", s.Name)
+	}
+	if s.Format != FormatGeneric && s.Format != FormatGitHub {
+		return fmt.Errorf("unknown format %q", s.Format)
+	}
+	if s.Secret == "" {
+		return fmt.Errorf("needs a secret to authenticate requests with")
+	}
+	if len(s.Users) == 0 && s.UserID == "" {
+		return fmt.Errorf("needs users or a user_id to deliver events to")
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package ingest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// githubAccount is a GitHub user in a webhook payload
+type githubAccount struct {
+	Login string `json:"login"`
+}
+
+// githubItem is an issue or pull request
+type githubItem struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+}
+
+// githubPayload holds the fields read from the webhook events handled
+type githubPayload struct {
+	Action      string         `json:"action"`
+	Issue       *githubItem    `json:"issue"`
+	PullRequest *githubItem    `json:"pull_request"`
+	Assignee    *githubAccount `json:"assignee"`
+	Repository  struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	WorkflowRun *struct {
+		ID         int64         `json:"id"`
+		Name       string        `json:"name"`
+		Conclusion string        `json:"conclusion"`
+		HTMLURL    string        `json:"html_url"`
+		HeadBranch string        `json:"head_branch"`
+		Actor      githubAccount `json:"actor"`
+	} `json:"workflow_run"`
+}
+
+// validGitHubSignature checks X-Hub-Signature-256, the hex HMAC-SHA256 of
+// the body keyed with the webhook's secret
+func validGitHubSignature(secret, signature string, body []byte) bool {
+	sum, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// normalizeGitHub turns issues and pull requests being assigned into
+// issue_assigned events, and finished workflow runs into ci_finished events
+func normalizeGitHub(source *Source, eventName string, body []byte) (*multiagent.Event, error) {
+	var payload githubPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid GitHub payload: %w", err)
+	}
+	repository := payload.Repository.FullName
+
+	switch {
+	case (eventName == "issues" || eventName == "pull_request") && payload.Action == "assigned":
+		item, kind := payload.Issue, "issue"
+		if eventName == "pull_request" {
+			item, kind = payload.PullRequest, "pull_request"
+		}
+		if item == nil || payload.Assignee == nil {
+			return nil, fmt.Errorf("GitHub %s payload has no %s or assignee", eventName, kind)
+		}
+		userID := source.user(payload.Assignee.Login)
+		if userID == "" {
+			return nil, fmt.Errorf("%w: assigned to %s", errIgnored, payload.Assignee.Login)
+		}
+		reference := fmt.Sprintf("%s#%d", repository, item.Number)
+		return newEvent(source, "issue_assigned", userID, map[string]interface{}{
+			"title":       item.Title,
+			"url":         item.HTMLURL,
+			"external_id": source.Name + ":" + reference,
+			"reference":   reference,
+			"kind":        kind,
+			"repository":  repository,
+		}), nil
+
+	case eventName == "workflow_run" && payload.Action == "completed":
+		run := payload.WorkflowRun
+		if run == nil {
+			return nil, fmt.Errorf("GitHub workflow_run payload has no workflow_run")
+		}
+		userID := source.user(run.Actor.Login)
+		if userID == "" {
+			return nil, fmt.Errorf("%w: run by %s", errIgnored, run.Actor.Login)
+		}
+		return newEvent(source, "ci_finished", userID, map[string]interface{}{
+			"title":       fmt.Sprintf("%s %s on %s (%s)", run.Name, run.Conclusion, repository, run.HeadBranch),
+			"url":         run.HTMLURL,
+			"external_id": fmt.Sprintf("%s:%s/runs/%d", source.Name, repository, run.ID),
+			"conclusion":  run.Conclusion,
+			"repository":  repository,
+			"branch":      run.HeadBranch,
+		}), nil
+	}
+	return nil, fmt.Errorf("%w: GitHub %s %s", errIgnored, eventName, payload.Action)
+}
@@ -0,0 +1,186 @@
+// Package ingest accepts events from outside systems on POST /events, such
+// as a CI run finishing, an issue being assigned or a package being
+// delivered, normalizes them into multiagent.Events and submits them to the
+// orchestrator. Each is published on its topic, e.g. "external.ci_finished",
+// for agents to subscribe to.
+//
+// Normalized events carry the assistant user in Data["user_id"], and where
+// the source provides them a "title", a "url" and an "external_id" naming
+// what the event is about, e.g. "github:octo/repo#12".
+package ingest
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+var logger = logging.For("ingest")
+
+// maxBodySize bounds the payloads accepted
+const maxBodySize = 1 << 20
+
+// Submitter queues events for the orchestrator
+type Submitter interface {
+	SubmitEvent(event *multiagent.Event) error
+}
+
+// Incoming is the body generic sources POST to /events
+type Incoming struct {
+	// Type is the kind of event in lower snake case, e.g. "ci_finished" or
+	// "package_delivered"
+	Type string `json:"type"`
+	// ID, if set, names what the event is about in the source, such as a
+	// tracking number, so repeats can be recognized
+	ID string `json:"id,omitempty"`
+	// User is the source's account the event is for; sources delivering to
+	// a single user may leave it out
+	User  string                 `json:"user,omitempty"`
+	Title string                 `json:"title,omitempty"`
+	URL   string                 `json:"url,omitempty"`
+	Data  map[string]interface{} `json:"data,omitempty"`
+}
+
+// Accepted is the body of a 202 response
+type Accepted struct {
+	EventID string               `json:"event_id"`
+	Type    multiagent.EventType `json:"type"`
+}
+
+// errIgnored marks payloads that are valid but not for the assistant, such
+// as GitHub's ping or an issue assigned to someone else
+var errIgnored = errors.New("event ignored")
+
+var typePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// HandlerConfig holds configuration for creating a Handler
+type HandlerConfig struct {
+	Submitter Submitter
+	Sources   []Source
+}
+
+// Handler serves POST /events?source=<name>
+type Handler struct {
+	submitter Submitter
+	sources   map[string]Source
+}
+
+// NewHandler creates a handler accepting events from config.Sources
+func NewHandler(config HandlerConfig) *Handler {
+	sources := make(map[string]Source, len(config.Sources))
+	for _, source := range config.Sources {
+		if source.Format == "" {
+			source.Format = FormatGeneric
+		}
+		sources[source.Name] = source
+	}
+	return &Handler{submitter: config.Submitter, sources: sources}
+}
+
+// ServeHTTP implements http.Handler. Accepted events get 202 and their ID;
+// payloads that are valid but not for the assistant get 204.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	source, known := h.sources[r.URL.Query().Get("source")]
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	// Unknown sources are refused like bad secrets, so names can't be probed
+	if !known || !authenticated(&source, r, body) {
+		logger.Warn("Rejected event with invalid credentials", "source", r.URL.Query().Get("source"))
+		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	var event *multiagent.Event
+	switch source.Format {
+	case FormatGitHub:
+		event, err = normalizeGitHub(&source, r.Header.Get("X-GitHub-Event"), body)
+	default:
+		event, err = normalizeGeneric(&source, body)
+	}
+	if errors.Is(err, errIgnored) {
+		logger.Debug("Ignored event", "source", source.Name, "reason", err)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := h.submitter.SubmitEvent(event); err != nil {
+		logger.Warn("Failed to submit event", "source", source.Name, "event_type", event.Type, "error", err)
+		http.Error(w, "event could not be queued", http.StatusServiceUnavailable)
+		return
+	}
+	logger.Info("Accepted event", "source", source.Name, "event_id", event.ID, "event_type", event.Type, logging.KeyUserID, event.Data[multiagent.ContextUserID])
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(Accepted{EventID: event.ID, Type: event.Type})
+}
+
+// authenticated checks the request's bearer token, or GitHub's signature
+func authenticated(source *Source, r *http.Request, body []byte) bool {
+	if source.Format == FormatGitHub {
+		return validGitHubSignature(source.Secret, r.Header.Get("X-Hub-Signature-256"), body)
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(source.Secret)) == 1
+}
+
+// normalizeGeneric turns an Incoming body into an event
+func normalizeGeneric(source *Source, body []byte) (*multiagent.Event, error) {
+	var incoming Incoming
+	if err := json.Unmarshal(body, &incoming); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+	if !typePattern.MatchString(incoming.Type) {
+		return nil, fmt.Errorf("event type must be lower snake case, got %q", incoming.Type)
+	}
+	userID := source.user(incoming.User)
+	if userID == "" {
+		return nil, fmt.Errorf("no user is configured for %q", incoming.User)
+	}
+
+	data := make(map[string]interface{}, len(incoming.Data)+4)
+	for key, value := range incoming.Data {
+		data[key] = value
+	}
+	if incoming.Title != "" {
+		data["title"] = incoming.Title
+	}
+	if incoming.URL != "" {
+		data["url"] = incoming.URL
+	}
+	if incoming.ID != "" {
+		data["external_id"] = source.Name + ":" + incoming.ID
+	}
+	return newEvent(source, incoming.Type, userID, data), nil
+}
+
+// newEvent builds the event of kind for userID; the orchestrator fills in
+// its ID and timestamp
+func newEvent(source *Source, kind, userID string, data map[string]interface{}) *multiagent.Event {
+	data[multiagent.ContextUserID] = userID
+	return &multiagent.Event{
+		Type:   multiagent.EventType(multiagent.ExternalEventPrefix + kind),
+		Source: source.Name,
+		Data:   data,
+	}
+}
@@ -0,0 +1,179 @@
+package ingest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+type fakeSubmitter struct {
+	events []*multiagent.Event
+	err    error
+}
+
+func (f *fakeSubmitter) SubmitEvent(event *multiagent.Event) error {
+	if f.err != nil {
+		return f.err
+	}
+	event.ID = fmt.Sprintf("event_%d", len(f.events)+1)
+	f.events = append(f.events, event)
+	return nil
+}
+
+func newTestHandler() (*fakeSubmitter, *Handler) {
+	submitter := &fakeSubmitter{}
+	return submitter, NewHandler(HandlerConfig{
+		Submitter: submitter,
+		Sources: []Source{
+			{Name: "tracker", Secret: "tracker-secret", UserID: "alice"},
+			{Name: "github", Format: FormatGitHub, Secret: "gh-secret", Users: map[string]string{"octocat": "alice"}},
+		},
+	})
+}
+
+func post(handler http.Handler, source, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/events?source="+source, strings.NewReader(body))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func bearer(token string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
+
+func github(event, body, secret string) http.Header {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return http.Header{
+		"X-Github-Event":      {event},
+		"X-Hub-Signature-256": {"sha256=" + hex.EncodeToString(mac.Sum(nil))},
+	}
+}
+
+func TestGenericEvent(t *testing.T) {
+	submitter, handler := newTestHandler()
+	body := `{"type": "package_delivered", "id": "1Z999", "title": "Boots delivered", "data": {"carrier": "UPS", "user_id": "mallory"}}`
+
+	rec := post(handler, "tracker", body, bearer("tracker-secret"))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var accepted Accepted
+	json.Unmarshal(rec.Body.Bytes(), &accepted)
+	if accepted.EventID != "event_1" || accepted.Type != multiagent.EventExternalPackageDelivered {
+		t.Errorf("unexpected response %+v", accepted)
+	}
+
+	event := submitter.events[0]
+	if event.Source != "tracker" || event.Data[multiagent.ContextUserID] != "alice" {
+		t.Errorf("event for %v from %s, want alice's from tracker", event.Data[multiagent.ContextUserID], event.Source)
+	}
+	if event.Data["title"] != "Boots delivered" || event.Data["external_id"] != "tracker:1Z999" || event.Data["carrier"] != "UPS" {
+		t.Errorf("unexpected data %v", event.Data)
+	}
+
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"wrong secret":   post(handler, "tracker", body, bearer("gh-secret")),
+		"unknown source": post(handler, "other", body, bearer("tracker-secret")),
+		"invalid type":   post(handler, "tracker", `{"type": "Package Delivered"}`, bearer("tracker-secret")),
+	} {
+		if rec.Code != http.StatusUnauthorized && rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status %d", name, rec.Code)
+		}
+	}
+	if len(submitter.events) != 1 {
+		t.Errorf("rejected requests submitted %d events", len(submitter.events)-1)
+	}
+
+	submitter.err = fmt.Errorf("event queue is full")
+	if rec := post(handler, "tracker", body, bearer("tracker-secret")); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("full queue: status %d", rec.Code)
+	}
+}
+
+func TestGitHubEvents(t *testing.T) {
+	submitter, handler := newTestHandler()
+	assigned := `{"action": "assigned", "issue": {"number": 12, "title": "Login fails", "html_url": "https://github.com/octo/app/issues/12"},
+		"assignee": {"login": "octocat"}, "repository": {"full_name": "octo/app"}}`
+
+	if rec := post(handler, "github", assigned, github("issues", assigned, "wrong")); rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature: status %d", rec.Code)
+	}
+	if rec := post(handler, "github", assigned, github("issues", assigned, "gh-secret")); rec.Code != http.StatusAccepted {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	event := submitter.events[0]
+	if event.Type != multiagent.EventExternalIssueAssigned || event.Data[multiagent.ContextUserID] != "alice" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if event.Data["external_id"] != "github:octo/app#12" || event.Data["title"] != "Login fails" || event.Data["reference"] != "octo/app#12" {
+		t.Errorf("unexpected data %v", event.Data)
+	}
+
+	finished := `{"action": "completed", "repository": {"full_name": "octo/app"}, "workflow_run": {"id": 7, "name": "CI",
+		"conclusion": "failure", "head_branch": "main", "html_url": "https://github.com/octo/app/actions/runs/7", "actor": {"login": "octocat"}}}`
+	if rec := post(handler, "github", finished, github("workflow_run", finished, "gh-secret")); rec.Code != http.StatusAccepted {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if event := submitter.events[1]; event.Type != multiagent.EventExternalCIFinished || event.Data["title"] != "CI failure on octo/app (main)" {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	// Pings and other people's issues are acknowledged but not submitted
+	other := strings.Replace(assigned, "octocat", "hubot", 1)
+	for event, body := range map[string]string{"ping": `{"zen": "Keep it simple."}`, "issues": other} {
+		if rec := post(handler, "github", body, github(event, body, "gh-secret")); rec.Code != http.StatusNoContent {
+			t.Errorf("%s: status %d", event, rec.Code)
+		}
+	}
+	if len(submitter.events) != 2 {
+		t.Errorf("ignored payloads submitted %d events", len(submitter.events)-2)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GITHUB_WEBHOOK_SECRET", "s3cret")
+	load := func(content string) ([]Source, error) {
+		path := filepath.Join(dir, "sources.json")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return LoadConfig(path)
+	}
+
+	sources, err := load(`{"sources": [{"name": "github", "format": "github", "secret": "$GITHUB_WEBHOOK_SECRET", "users": {"octocat": "alice"}},
+		{"name": "ci", "secret": "token", "user_id": "alice"}]}`)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(sources) != 2 || sources[0].Secret != "s3cret" || sources[1].Format != FormatGeneric {
+		t.Errorf("unexpected sources %+v", sources)
+	}
+
+	for _, invalid := range []string{
+		`{"sources": [{"name": "ci", "user_id": "alice"}]}`,
+		`{"sources": [{"name": "ci", "secret": "token"}]}`,
+		`{"sources": [{"name": "CI Server", "secret": "token", "user_id": "alice"}]}`,
+		`{"sources": [{"name": "ci", "format": "gitlab", "secret": "token", "user_id": "alice"}]}`,
+		`{"sources": [{"name": "ci", "secret": "a", "user_id": "alice"}, {"name": "ci", "secret": "b", "user_id": "bob"}]}`,
+	} {
+		if _, err := load(invalid); err == nil {
+			t.Errorf("LoadConfig accepted %s", invalid)
+		}
+	}
+}
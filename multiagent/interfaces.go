@@ -53,6 +53,23 @@ type Message struct {
 	RequiresACK bool                   `json:"requires_ack"`          // Whether acknowledgment is required
 }
 
+// ContextExpectsReply is the message context flag set by a sender waiting on
+// a reply correlated by ReplyTo; the orchestrator always routes such replies
+const ContextExpectsReply = "expects_reply"
+
+// ContextInterim is the message context flag set on a reply that reports
+// progress, such as an acknowledgment, rather than answering the request
+const ContextInterim = "interim"
+
+// ContextUserID is the message context key naming the user a message acts
+// for; the orchestrator fills it in for messages on a known conversation
+const ContextUserID = "user_id"
+
+// ContextHops is the message context key counting the messages a chain
+// passed through before this one; the orchestrator stamps it and refuses
+// messages past its hop limit
+const ContextHops = "hops"
+
 // MessageType defines different types of messages between agents
 type MessageType string
 
@@ -64,6 +81,7 @@ const (
 	MessageTypeCommand      MessageType = "command"       // Direct command
 	MessageTypeReport       MessageType = "report"        // Status or result report
 	MessageTypeError        MessageType = "error"         // Error notification
+	MessageTypeQuestion     MessageType = "question"      // Follow-up question for the user
 )
 
 // AgentState represents the current state of an agent
@@ -111,6 +129,29 @@ type Agent interface {
 	CanHandle(messageType MessageType) bool
 }
 
+// CapabilityDescriptor describes one thing an agent can do in structured form
+type CapabilityDescriptor struct {
+	Name     string   `json:"name"`
+	Domain   string   `json:"domain"`             // e.g. "calendar"
+	Action   string   `json:"action"`             // e.g. "schedule"
+	Keywords []string `json:"keywords,omitempty"` // Extra terms that signal this capability
+	// InputSchema is a JSON schema for task input; its "required" fields must be present
+	InputSchema map[string]interface{} `json:"input_schema,omitempty"`
+}
+
+// Pinger is implemented by agents that answer liveness checks; Ping returns
+// an error if the agent can't take work, and one that doesn't return before
+// ctx ends counts as missed
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// CapabilityDescriber is implemented by agents that describe their
+// capabilities beyond the plain GetCapabilities strings
+type CapabilityDescriber interface {
+	DescribeCapabilities() []CapabilityDescriptor
+}
+
 // Tool defines the interface for tools that agents can use
 type Tool interface {
 	Name() string
@@ -142,6 +183,28 @@ type MemoryStore interface {
 	Cleanup(ctx context.Context) error
 }
 
+// VectorMemoryStore extends MemoryStore with embedding-based semantic recall
+type VectorMemoryStore interface {
+	MemoryStore
+
+	// StoreEmbedding embeds text and indexes it under key for similarity search
+	StoreEmbedding(ctx context.Context, key string, text string, metadata map[string]interface{}) error
+	// SearchSimilar returns the entries whose embeddings are closest to query
+	SearchSimilar(ctx context.Context, query string, limit int) ([]SimilarMemory, error)
+}
+
+// SimilarMemory is a single semantic search hit
+type SimilarMemory struct {
+	Entry MemoryEntry `json:"entry"`
+	Text  string      `json:"text"`
+	Score float64     `json:"score"`
+}
+
+// Embedder turns text into embedding vectors
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
 // MemoryEntry represents a single memory item
 type MemoryEntry struct {
 	Key          string                 `json:"key"`
@@ -172,6 +235,13 @@ type Orchestrator interface {
 	// Task coordination
 	AssignTask(ctx context.Context, task Task) (AgentID, error)
 	GetTaskStatus(ctx context.Context, taskID string) (TaskStatus, error)
+	CancelTask(ctx context.Context, taskID string) error
+
+	// Topic publish/subscribe; patterns use "*" for one dot-separated
+	// segment and "#" for any number of trailing segments
+	Publish(ctx context.Context, topic string, msg *Message) (int, error)
+	Subscribe(agentID AgentID, pattern string) error
+	Unsubscribe(agentID AgentID, pattern string) error
 	
 	// System management
 	Start(ctx context.Context) error
@@ -195,6 +265,36 @@ type Task struct {
 	StartedAt   *time.Time             `json:"started_at,omitempty"`
 	CompletedAt *time.Time             `json:"completed_at,omitempty"`
 	Deadline    *time.Time             `json:"deadline,omitempty"`
+	// DependsOn lists task IDs that must complete before this task is dispatched
+	DependsOn  []string `json:"depends_on,omitempty"`
+	WorkflowID string   `json:"workflow_id,omitempty"`
+	// Timeout bounds each attempt; Deadline bounds the task as a whole
+	Timeout       time.Duration    `json:"timeout,omitempty"`
+	Retry         *RetryPolicy     `json:"retry,omitempty"`
+	Attempts      int              `json:"attempts,omitempty"`
+	NextAttemptAt *time.Time       `json:"next_attempt_at,omitempty"`
+	History       []TaskTransition `json:"history,omitempty"`
+}
+
+// RetryPolicy controls how failed or timed-out task attempts are retried
+type RetryPolicy struct {
+	// MaxAttempts on the original assignee, including the first
+	MaxAttempts int           `json:"max_attempts"`
+	Backoff     time.Duration `json:"backoff"`
+	// BackoffMultiplier grows the delay between attempts (default 2)
+	BackoffMultiplier float64       `json:"backoff_multiplier,omitempty"`
+	MaxBackoff        time.Duration `json:"max_backoff,omitempty"`
+	// FallbackAgent gets one final attempt once MaxAttempts are exhausted
+	FallbackAgent AgentID `json:"fallback_agent,omitempty"`
+}
+
+// TaskTransition records a status change in a task's history
+type TaskTransition struct {
+	Status  TaskStatus `json:"status"`
+	Agent   AgentID    `json:"agent,omitempty"`
+	Attempt int        `json:"attempt,omitempty"`
+	Error   string     `json:"error,omitempty"`
+	At      time.Time  `json:"at"`
 }
 
 // TaskStatus represents the status of a task
@@ -202,6 +302,7 @@ type TaskStatus string
 
 const (
 	TaskStatusPending    TaskStatus = "pending"
+	TaskStatusWaiting    TaskStatus = "waiting" // Held until dependencies complete
 	TaskStatusAssigned   TaskStatus = "assigned"
 	TaskStatusInProgress TaskStatus = "in_progress"
 	TaskStatusCompleted  TaskStatus = "completed"
@@ -221,6 +322,49 @@ type SystemHealth struct {
 	Uptime        time.Duration          `json:"uptime"`
 	LastCheck     time.Time              `json:"last_check"`
 	AgentHealth   map[AgentID]AgentState `json:"agent_health"`
+	Memory        *MemoryStats           `json:"memory,omitempty"`
+	Queue         *QueueStats            `json:"queue,omitempty"`
+	// AgentRestarts counts supervisor restarts per agent
+	AgentRestarts map[AgentID]int `json:"agent_restarts,omitempty"`
+	// LastHeartbeats is when each agent last answered a ping
+	LastHeartbeats map[AgentID]time.Time `json:"last_heartbeats,omitempty"`
+}
+
+// TopicStats counts deliveries for a pub/sub topic
+type TopicStats struct {
+	Published     int64     `json:"published"`
+	Delivered     int64     `json:"delivered"`
+	Unrouted      int64     `json:"unrouted"`
+	Failed        int64     `json:"failed"`
+	LastPublished time.Time `json:"last_published"`
+}
+
+// QueueStats describes the orchestrator's message queue
+type QueueStats struct {
+	Depth         int              `json:"depth"`
+	Deferred      int              `json:"deferred"`
+	Shed          int64            `json:"shed"`
+	HighWatermark int              `json:"high_watermark"`
+	ByPriority    map[Priority]int `json:"by_priority,omitempty"`
+}
+
+// MemoryStats summarizes memory store size and background maintenance
+type MemoryStats struct {
+	TotalEntries    int            `json:"total_entries"`
+	EntriesByPrefix map[string]int `json:"entries_by_prefix,omitempty"`
+	Expired         int64          `json:"expired"`
+	Compacted       int64          `json:"compacted"`
+	Evicted         int64          `json:"evicted"`
+	LastSweep       time.Time      `json:"last_sweep"`
+	LastSweepTook   time.Duration  `json:"last_sweep_took"`
+	LastError       string         `json:"last_error,omitempty"`
+	// QuotaUsage is the fill level of the fullest prefix quota (0-100)
+	QuotaUsage float64 `json:"quota_usage_percent"`
+}
+
+// MemoryStatsProvider reports MemoryStats for health checks
+type MemoryStatsProvider interface {
+	MemoryStats() MemoryStats
 }
 
 // SystemStatus represents the overall system status
@@ -240,15 +384,32 @@ const (
 	EventAgentRegistered   EventType = "agent_registered"
 	EventAgentUnregistered EventType = "agent_unregistered"
 	EventAgentStateChange  EventType = "agent_state_change"
+	EventAgentCrashed      EventType = "agent_crashed"
+	EventAgentRestarted    EventType = "agent_restarted"
+	EventAgentCapabilities EventType = "agent_capabilities"
 	EventTaskCreated       EventType = "task_created"
 	EventTaskAssigned      EventType = "task_assigned"
 	EventTaskCompleted     EventType = "task_completed"
 	EventTaskFailed        EventType = "task_failed"
+	EventTaskCancelled     EventType = "task_cancelled"
+	EventTaskTimedOut      EventType = "task_timed_out"
+	EventTaskRetrying      EventType = "task_retrying"
 	EventMessageSent       EventType = "message_sent"
 	EventMessageReceived   EventType = "message_received"
 	EventSystemError       EventType = "system_error"
+	EventLoopDetected      EventType = "loop_detected"
+
+	// Events from outside systems, submitted to POST /events; each is
+	// published on its topic, e.g. external_issue_assigned on
+	// "external.issue_assigned"
+	EventExternalIssueAssigned    EventType = "external_issue_assigned"
+	EventExternalCIFinished       EventType = "external_ci_finished"
+	EventExternalPackageDelivered EventType = "external_package_delivered"
 )
 
+// ExternalEventPrefix starts the type of every event from outside systems
+const ExternalEventPrefix = "external_"
+
 // Event represents a system event
 type Event struct {
 	ID        string                 `json:"id"`
@@ -270,6 +431,17 @@ type LLMProvider interface {
 	QueryWithTools(ctx context.Context, prompt string, tools []Tool) (string, error)
 }
 
+// Clock tells agents and the orchestrator the time, so tests can fix it
+type Clock interface {
+	Now() time.Time
+}
+
+// IDGenerator makes the IDs of messages, tasks and other records
+type IDGenerator interface {
+	// NewID returns a new unique ID starting with prefix and an underscore
+	NewID(prefix string) string
+}
+
 // ConversationContext maintains context for ongoing conversations
 type ConversationContext struct {
 	ID           string                 `json:"id"`
@@ -279,6 +451,10 @@ type ConversationContext struct {
 	Messages     []ConversationMessage  `json:"messages"`
 	Context      map[string]interface{} `json:"context"`
 	ActiveAgents []AgentID              `json:"active_agents"`
+	// Summary condenses the first SummarizedThrough messages, which prompts
+	// include in place of those messages; the messages themselves are kept
+	Summary           string `json:"summary,omitempty"`
+	SummarizedThrough int    `json:"summarized_through,omitempty"`
 }
 
 // ConversationMessage represents a single message in a conversation
@@ -0,0 +1,210 @@
+package llmprovider
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/metrics"
+)
+
+// Prompt classes agents tag their deterministic prompts with, so a
+// ResponseCache can opt them in
+const (
+	// PromptClassIntent is intent classification of a user's request
+	PromptClassIntent = "intent"
+	// PromptClassSummary is a summary of content the user supplied
+	PromptClassSummary = "summary"
+)
+
+// defaultCacheEntries bounds a ResponseCache without MaxEntries
+const defaultCacheEntries = 1000
+
+type promptClassKey struct{}
+
+// WithPromptClass tags the LLM calls made with ctx as prompts of class
+func WithPromptClass(ctx context.Context, class string) context.Context {
+	return context.WithValue(ctx, promptClassKey{}, class)
+}
+
+// PromptClassFrom returns the prompt class ctx was tagged with, or ""
+func PromptClassFrom(ctx context.Context) string {
+	class, _ := ctx.Value(promptClassKey{}).(string)
+	return class
+}
+
+// CacheConfig holds configuration for creating a ResponseCache
+type CacheConfig struct {
+	// Classes maps each cached prompt class to how long its responses are
+	// kept; prompts of other classes, or untagged ones, are never cached
+	Classes map[string]time.Duration
+	// MaxEntries bounds the cache, evicting the least recently used
+	// response (default 1000)
+	MaxEntries int
+	// Metrics, if set, counts hits and misses per class
+	Metrics *metrics.Registry
+}
+
+// ResponseCache keeps LLM responses keyed by a hash of the model and prompt,
+// for the prompt classes opted in to it. Wrap each provider with Provider;
+// the wrappers share the cache.
+type ResponseCache struct {
+	mu         sync.Mutex
+	classes    map[string]time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	recent     *list.List
+	requests   *metrics.Counter
+}
+
+type cacheEntry struct {
+	key      string
+	response string
+	expires  time.Time
+}
+
+// NewResponseCache creates a response cache
+func NewResponseCache(config CacheConfig) *ResponseCache {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultCacheEntries
+	}
+	cache := &ResponseCache{
+		classes:    config.Classes,
+		maxEntries: config.MaxEntries,
+		entries:    make(map[string]*list.Element),
+		recent:     list.New(),
+	}
+	if config.Metrics != nil {
+		cache.requests = config.Metrics.NewCounter("multiagent_llm_cache_requests_total",
+			"LLM queries of cached prompt classes, by whether the response was cached", "class", "result")
+	}
+	return cache
+}
+
+// Provider wraps provider so its queries of opted-in prompt classes are
+// answered from the cache when possible
+func (c *ResponseCache) Provider(provider multiagent.LLMProvider) multiagent.LLMProvider {
+	return &cachingProvider{cache: c, provider: provider}
+}
+
+// Len returns the number of cached responses, expired ones included
+func (c *ResponseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.recent.Len()
+}
+
+func (c *ResponseCache) get(key string, now time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	entry := element.Value.(*cacheEntry)
+	if now.After(entry.expires) {
+		c.recent.Remove(element)
+		delete(c.entries, key)
+		return "", false
+	}
+	c.recent.MoveToFront(element)
+	return entry.response, true
+}
+
+func (c *ResponseCache) put(key, response string, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		entry.response, entry.expires = response, expires
+		c.recent.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.recent.PushFront(&cacheEntry{key: key, response: response, expires: expires})
+	for c.recent.Len() > c.maxEntries {
+		oldest := c.recent.Back()
+		c.recent.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *ResponseCache) count(class, result string) {
+	if c.requests != nil {
+		c.requests.Inc(class, result)
+	}
+}
+
+// cachingProvider answers a provider's opted-in queries from a ResponseCache
+type cachingProvider struct {
+	cache    *ResponseCache
+	provider multiagent.LLMProvider
+}
+
+func (p *cachingProvider) Name() string {
+	return p.provider.Name()
+}
+
+func (p *cachingProvider) Capabilities() Capabilities {
+	capabilities, _ := CapabilitiesOf(p.provider)
+	return capabilities
+}
+
+// Query answers from the cache when ctx's prompt class is opted in and the
+// same model answered the same prompt within the class's TTL
+func (p *cachingProvider) Query(ctx context.Context, prompt string) (string, error) {
+	class := PromptClassFrom(ctx)
+	ttl, ok := p.cache.classes[class]
+	if !ok || ttl <= 0 {
+		return p.provider.Query(ctx, prompt)
+	}
+
+	// The model is part of the key, so switching models misses
+	sum := sha256.Sum256([]byte(p.provider.Name() + "\x00" + prompt))
+	key := hex.EncodeToString(sum[:])
+	if response, ok := p.cache.get(key, time.Now()); ok {
+		p.cache.count(class, "hit")
+		logger.DebugContext(ctx, "Answered LLM query from cache", "class", class)
+		return response, nil
+	}
+	p.cache.count(class, "miss")
+
+	response, err := p.provider.Query(ctx, prompt)
+	if err != nil {
+		return response, err
+	}
+	p.cache.put(key, response, time.Now().Add(ttl))
+	return response, nil
+}
+
+// QueryWithTools is never cached, since tools act on the world
+func (p *cachingProvider) QueryWithTools(ctx context.Context, prompt string, tools []multiagent.Tool) (string, error) {
+	return p.provider.QueryWithTools(ctx, prompt, tools)
+}
+
+// ParseCacheClasses parses prompt classes and their TTLs written as
+// "intent=10m,summary=1h"
+func ParseCacheClasses(spec string) (map[string]time.Duration, error) {
+	classes := make(map[string]time.Duration)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		class, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid cache class %q: want class=ttl", pair)
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid TTL for cache class %q: %q", class, value)
+		}
+		classes[strings.TrimSpace(class)] = ttl
+	}
+	return classes, nil
+}
@@ -0,0 +1,91 @@
+package llmprovider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// countingProvider answers each query with how many it has answered
+type countingProvider struct {
+	name  string
+	calls int
+}
+
+func (p *countingProvider) Name() string { return p.name }
+
+func (p *countingProvider) Query(ctx context.Context, prompt string) (string, error) {
+	p.calls++
+	return fmt.Sprintf("%s answer %d", p.name, p.calls), nil
+}
+
+func (p *countingProvider) QueryWithTools(ctx context.Context, prompt string, tools []multiagent.Tool) (string, error) {
+	return p.Query(ctx, prompt)
+}
+
+func TestResponseCacheAnswersOptedInClasses(t *testing.T) {
+	cache := NewResponseCache(CacheConfig{Classes: map[string]time.Duration{PromptClassIntent: time.Minute}})
+	big, small := &countingProvider{name: "big"}, &countingProvider{name: "small"}
+	cachedBig, cachedSmall := cache.Provider(big), cache.Provider(small)
+	intent := WithPromptClass(context.Background(), PromptClassIntent)
+
+	first, _ := cachedBig.Query(intent, "classify: hi")
+	if again, _ := cachedBig.Query(intent, "classify: hi"); again != first || big.calls != 1 {
+		t.Fatalf("repeated intent prompt answered %q after %d calls, want the cached %q", again, big.calls, first)
+	}
+
+	// Other prompts, models and classes miss
+	cachedBig.Query(intent, "classify: bye")
+	cachedSmall.Query(intent, "classify: hi")
+	cachedBig.Query(WithPromptClass(context.Background(), PromptClassSummary), "classify: hi")
+	cachedBig.Query(context.Background(), "classify: hi")
+	if big.calls != 4 || small.calls != 1 {
+		t.Fatalf("calls = big %d, small %d; want 4 and 1", big.calls, small.calls)
+	}
+	if cache.Len() != 3 {
+		t.Fatalf("cached %d responses, want 3", cache.Len())
+	}
+}
+
+func TestResponseCacheExpiresAndEvicts(t *testing.T) {
+	cache := NewResponseCache(CacheConfig{
+		Classes:    map[string]time.Duration{PromptClassIntent: 20 * time.Millisecond},
+		MaxEntries: 2,
+	})
+	provider := &countingProvider{name: "big"}
+	cached := cache.Provider(provider)
+	ctx := WithPromptClass(context.Background(), PromptClassIntent)
+
+	cached.Query(ctx, "a")
+	time.Sleep(30 * time.Millisecond)
+	if cached.Query(ctx, "a"); provider.calls != 2 {
+		t.Fatalf("expired response was served; calls = %d", provider.calls)
+	}
+
+	// "a" was used last, so "b" is evicted for "c"
+	cached.Query(ctx, "b")
+	cached.Query(ctx, "a")
+	cached.Query(ctx, "c")
+	cached.Query(ctx, "a")
+	if provider.calls != 4 || cache.Len() != 2 {
+		t.Fatalf("calls = %d, entries = %d; want 4 and 2", provider.calls, cache.Len())
+	}
+	if cached.Query(ctx, "b"); provider.calls != 5 {
+		t.Fatalf("evicted response was served; calls = %d", provider.calls)
+	}
+}
+
+func TestParseCacheClasses(t *testing.T) {
+	classes, err := ParseCacheClasses("intent=10m, summary=1h")
+	if err != nil || classes[PromptClassIntent] != 10*time.Minute || classes[PromptClassSummary] != time.Hour {
+		t.Fatalf("ParseCacheClasses = %v, %v", classes, err)
+	}
+	for _, spec := range []string{"intent", "intent=soon", "intent=-1m"} {
+		if _, err := ParseCacheClasses(spec); err == nil {
+			t.Errorf("ParseCacheClasses(%q) accepted an invalid spec", spec)
+		}
+	}
+}
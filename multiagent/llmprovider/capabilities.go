@@ -0,0 +1,94 @@
+package llmprovider
+
+import (
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// DefaultContextWindow is assumed for models that are not known: the
+// context LMStudio and Ollama load a model with unless told otherwise
+const DefaultContextWindow = 4096
+
+// Capabilities describe what a model accepts
+type Capabilities struct {
+	// ContextWindow is the tokens of prompt and completion together
+	ContextWindow int `json:"context_window"`
+	// Tools is set for models trained to call tools
+	Tools bool `json:"tools"`
+	// JSONMode is set for models that can be held to a JSON response
+	JSONMode bool `json:"json_mode"`
+	// CompletionTokens is the part of the window a call keeps for its
+	// response, the provider's max tokens
+	CompletionTokens int `json:"completion_tokens,omitempty"`
+}
+
+// knownModels are matched in order against a lowercased model name, so
+// more specific names come first
+var knownModels = []struct {
+	match        string
+	capabilities Capabilities
+}{
+	{"gpt-4o", Capabilities{ContextWindow: 128000, Tools: true, JSONMode: true}},
+	{"gpt-4.1", Capabilities{ContextWindow: 1047576, Tools: true, JSONMode: true}},
+	{"gpt-4-turbo", Capabilities{ContextWindow: 128000, Tools: true, JSONMode: true}},
+	{"gpt-3.5-turbo", Capabilities{ContextWindow: 16385, Tools: true, JSONMode: true}},
+	{"llama-3.1", Capabilities{ContextWindow: 131072, Tools: true}},
+	{"llama3.1", Capabilities{ContextWindow: 131072, Tools: true}},
+	{"llama-3.2", Capabilities{ContextWindow: 131072, Tools: true}},
+	{"llama3.2", Capabilities{ContextWindow: 131072, Tools: true}},
+	{"llama-3.3", Capabilities{ContextWindow: 131072, Tools: true}},
+	{"llama3.3", Capabilities{ContextWindow: 131072, Tools: true}},
+	{"llama-3", Capabilities{ContextWindow: 8192}},
+	{"llama3", Capabilities{ContextWindow: 8192}},
+	{"qwen3", Capabilities{ContextWindow: 32768, Tools: true, JSONMode: true}},
+	{"qwen2.5", Capabilities{ContextWindow: 32768, Tools: true, JSONMode: true}},
+	{"mistral-nemo", Capabilities{ContextWindow: 131072, Tools: true}},
+	{"mistral", Capabilities{ContextWindow: 32768, Tools: true}},
+	{"gemma-3", Capabilities{ContextWindow: 131072}},
+	{"gemma3", Capabilities{ContextWindow: 131072}},
+	{"gemma", Capabilities{ContextWindow: 8192}},
+	{"phi-4", Capabilities{ContextWindow: 16384}},
+	{"phi4", Capabilities{ContextWindow: 16384}},
+}
+
+// LookupCapabilities returns the capabilities of a model by its name, e.g.
+// "qwen2.5-7b-instruct" or "lmstudio-community/Meta-Llama-3.1-8B"; unknown
+// models get DefaultContextWindow and nothing else
+func LookupCapabilities(model string) Capabilities {
+	model = strings.ToLower(model)
+	for _, known := range knownModels {
+		if strings.Contains(model, known.match) {
+			return known.capabilities
+		}
+	}
+	return Capabilities{ContextWindow: DefaultContextWindow}
+}
+
+// CapabilitiesOf returns the capabilities of provider's model, if it knows
+// them
+func CapabilitiesOf(provider multiagent.LLMProvider) (Capabilities, bool) {
+	reporter, ok := provider.(interface{ Capabilities() Capabilities })
+	if !ok {
+		return Capabilities{}, false
+	}
+	return reporter.Capabilities(), true
+}
+
+// PromptBudget returns how many tokens a prompt to provider may use: its
+// model's context window less the room kept for the response, or 0 if the
+// window is unknown
+func PromptBudget(provider multiagent.LLMProvider) int {
+	capabilities, ok := CapabilitiesOf(provider)
+	if !ok || capabilities.ContextWindow <= 0 {
+		return 0
+	}
+	// A response allowed the whole window still leaves the prompt a quarter
+	return max(capabilities.ContextWindow-capabilities.CompletionTokens, capabilities.ContextWindow/4)
+}
+
+// EstimateTokens approximates how many tokens text is, at four characters
+// per token
+func EstimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
@@ -0,0 +1,55 @@
+package llmprovider
+
+import (
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent/metrics"
+)
+
+func TestLookupCapabilities(t *testing.T) {
+	for model, want := range map[string]int{
+		"qwen2.5-7b-instruct":                  32768,
+		"lmstudio-community/Meta-Llama-3.1-8B": 131072,
+		"Meta-Llama-3-8B-Instruct":             8192,
+		"gpt-4o-mini":                          128000,
+		"default":                              DefaultContextWindow,
+		"some-finetune-nobody-has-heard-of-v2": DefaultContextWindow,
+	} {
+		if got := LookupCapabilities(model).ContextWindow; got != want {
+			t.Errorf("context window of %s = %d, want %d", model, got, want)
+		}
+	}
+	if capabilities := LookupCapabilities("qwen2.5-7b-instruct"); !capabilities.Tools || !capabilities.JSONMode {
+		t.Errorf("qwen2.5 capabilities = %+v", capabilities)
+	}
+}
+
+func TestPromptBudgetFollowsWrappedModels(t *testing.T) {
+	local := echoServer(t, "local")
+	pool, err := NewPool(PoolConfig{
+		Backends: []BackendConfig{{Name: "local", URL: local.URL, Model: "qwen2.5-7b-instruct", MaxTokens: 1024, ContextWindow: 8192}},
+		Roles:    map[string]ModelConfig{"research": {Backend: "local", ContextWindow: 16384}},
+	})
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+	registry := metrics.NewRegistry()
+	cache := NewResponseCache(CacheConfig{})
+
+	for role, want := range map[string]int{"task": 8192 - 1024, "research": 16384 - 1024} {
+		provider := cache.Provider(NewInstrumentedProvider(pool.Provider(role), registry))
+		if got := PromptBudget(provider); got != want {
+			t.Errorf("prompt budget of %s = %d, want %d", role, got, want)
+		}
+	}
+
+	if got := PromptBudget(NewLMStudioProvider("http://localhost:1234/v1")); got != DefaultContextWindow-2048 {
+		t.Errorf("prompt budget of an unknown model = %d", got)
+	}
+	if got := PromptBudget(NewLMStudioProvider("", WithContextWindow(2048))); got != 512 {
+		t.Errorf("prompt budget when max tokens fill the window = %d, want a quarter of it", got)
+	}
+	if got := PromptBudget(&countingProvider{name: "mock"}); got != 0 {
+		t.Errorf("prompt budget of a provider without capabilities = %d", got)
+	}
+}
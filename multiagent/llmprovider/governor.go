@@ -0,0 +1,212 @@
+package llmprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// Backend kinds, which decide how a backend's loaded models are probed;
+// other OpenAI-compatible servers are left unprobed
+const (
+	KindLMStudio = "lmstudio"
+	KindOllama   = "ollama"
+)
+
+// probeTimeout bounds asking a backend which models it has loaded
+const probeTimeout = 2 * time.Second
+
+// GovernorConfig describes the backend a Governor guards
+type GovernorConfig struct {
+	Name string
+	// Kind is KindLMStudio, KindOllama, or empty for another server
+	Kind string
+	// URL is the backend's OpenAI-compatible API, e.g. http://localhost:1234/v1
+	URL string
+	// MaxConcurrent bounds the calls in flight at once; 0 is unlimited
+	MaxConcurrent int
+}
+
+// Governor bounds the LLM calls in flight against one backend, across every
+// agent and model querying it, so concurrent generations do not thrash a
+// single local GPU; calls over the limit wait their turn. Local servers do
+// not report their own queues, so the calls waiting here are the queue
+// depth. Wrap each provider on the backend with Provider.
+type Governor struct {
+	config   GovernorConfig
+	slots    chan struct{}
+	inFlight atomic.Int64
+	queued   atomic.Int64
+}
+
+// BackendStatus is a backend's load: the calls in flight and waiting at its
+// governor, and the models the server has loaded
+type BackendStatus struct {
+	Name          string   `json:"name"`
+	Kind          string   `json:"kind,omitempty"`
+	MaxConcurrent int      `json:"max_concurrent,omitempty"`
+	InFlight      int      `json:"in_flight"`
+	Queued        int      `json:"queued"`
+	LoadedModels  []string `json:"loaded_models,omitempty"`
+	// ProbeError is why the loaded models are unknown
+	ProbeError string `json:"probe_error,omitempty"`
+}
+
+// NewGovernor creates a governor for the backend config describes
+func NewGovernor(config GovernorConfig) *Governor {
+	g := &Governor{config: config}
+	if config.MaxConcurrent > 0 {
+		g.slots = make(chan struct{}, config.MaxConcurrent)
+	}
+	return g
+}
+
+// Provider wraps provider so its calls take one of the governor's slots
+func (g *Governor) Provider(provider multiagent.LLMProvider) multiagent.LLMProvider {
+	return &governedProvider{governor: g, provider: provider}
+}
+
+// Load returns the calls in flight and waiting, without probing the backend
+func (g *Governor) Load() BackendStatus {
+	return BackendStatus{
+		Name:          g.config.Name,
+		Kind:          g.config.Kind,
+		MaxConcurrent: g.config.MaxConcurrent,
+		InFlight:      int(g.inFlight.Load()),
+		Queued:        int(g.queued.Load()),
+	}
+}
+
+// Status returns the governor's load and the models the backend has loaded
+func (g *Governor) Status(ctx context.Context) BackendStatus {
+	status := g.Load()
+	if g.config.Kind != KindLMStudio && g.config.Kind != KindOllama {
+		return status
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	models, err := probeLoadedModels(ctx, g.config.Kind, g.config.URL)
+	if err != nil {
+		status.ProbeError = err.Error()
+		return status
+	}
+	status.LoadedModels = models
+	return status
+}
+
+// acquire waits for a free slot; release gives it back
+func (g *Governor) acquire(ctx context.Context) (release func(), err error) {
+	if g.slots != nil {
+		g.queued.Add(1)
+		started := time.Now()
+		select {
+		case g.slots <- struct{}{}:
+			g.queued.Add(-1)
+		case <-ctx.Done():
+			g.queued.Add(-1)
+			return nil, fmt.Errorf("waiting for LLM backend %s: %w", g.config.Name, ctx.Err())
+		}
+		if waited := time.Since(started); waited > time.Second {
+			logger.DebugContext(ctx, "Waited for a free LLM slot", "backend", g.config.Name, "waited", waited)
+		}
+	}
+	g.inFlight.Add(1)
+	return func() {
+		g.inFlight.Add(-1)
+		if g.slots != nil {
+			<-g.slots
+		}
+	}, nil
+}
+
+// governedProvider queries a provider within its backend's governor
+type governedProvider struct {
+	governor *Governor
+	provider multiagent.LLMProvider
+}
+
+func (p *governedProvider) Name() string {
+	return p.provider.Name()
+}
+
+func (p *governedProvider) Capabilities() Capabilities {
+	capabilities, _ := CapabilitiesOf(p.provider)
+	return capabilities
+}
+
+func (p *governedProvider) Query(ctx context.Context, prompt string) (string, error) {
+	release, err := p.governor.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return p.provider.Query(ctx, prompt)
+}
+
+func (p *governedProvider) QueryWithTools(ctx context.Context, prompt string, tools []multiagent.Tool) (string, error) {
+	release, err := p.governor.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return p.provider.QueryWithTools(ctx, prompt, tools)
+}
+
+// probeLoadedModels asks a server's native API which models are loaded:
+// GET /api/v0/models on LMStudio and GET /api/ps on Ollama, both beside
+// the OpenAI-compatible /v1
+func probeLoadedModels(ctx context.Context, kind, apiURL string) ([]string, error) {
+	base := strings.TrimSuffix(strings.TrimSuffix(apiURL, "/"), "/v1")
+	path := "/api/v0/models"
+	if kind == KindOllama {
+		path = "/api/ps"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", path, resp.StatusCode)
+	}
+
+	var result struct {
+		// LMStudio lists every model with its state
+		Data []struct {
+			ID    string `json:"id"`
+			State string `json:"state"`
+		} `json:"data"`
+		// Ollama lists only the loaded ones
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	models := []string{}
+	for _, model := range result.Data {
+		if model.State == "loaded" {
+			models = append(models, model.ID)
+		}
+	}
+	for _, model := range result.Models {
+		models = append(models, model.Name)
+	}
+	return models, nil
+}
@@ -0,0 +1,117 @@
+package llmprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// blockingProvider answers each query once it is released
+type blockingProvider struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *blockingProvider) Name() string { return "blocking" }
+
+func (p *blockingProvider) Query(ctx context.Context, prompt string) (string, error) {
+	p.started <- struct{}{}
+	<-p.release
+	return prompt, nil
+}
+
+func (p *blockingProvider) QueryWithTools(ctx context.Context, prompt string, tools []multiagent.Tool) (string, error) {
+	return p.Query(ctx, prompt)
+}
+
+func TestGovernorBoundsCallsAcrossProviders(t *testing.T) {
+	governor := NewGovernor(GovernorConfig{Name: "gpu", MaxConcurrent: 1})
+	blocking := &blockingProvider{started: make(chan struct{}, 2), release: make(chan struct{})}
+	research, task := governor.Provider(blocking), governor.Provider(blocking)
+
+	done := make(chan string, 2)
+	go func() { answer, _ := research.Query(context.Background(), "research"); done <- answer }()
+	<-blocking.started
+	go func() { answer, _ := task.Query(context.Background(), "task"); done <- answer }()
+
+	waitFor(t, func() bool { return governor.Load().Queued == 1 })
+	if load := governor.Load(); load.InFlight != 1 || load.MaxConcurrent != 1 {
+		t.Fatalf("load with one call waiting = %+v", load)
+	}
+	select {
+	case <-blocking.started:
+		t.Fatal("second call started while the first was in flight")
+	default:
+	}
+
+	// A call that gives up while waiting leaves the queue
+	ctx, cancel := context.WithCancel(context.Background())
+	failed := make(chan error, 1)
+	go func() { _, err := task.Query(ctx, "abandoned"); failed <- err }()
+	waitFor(t, func() bool { return governor.Load().Queued == 2 })
+	cancel()
+	if err := <-failed; !errors.Is(err, context.Canceled) {
+		t.Errorf("abandoned call = %v, want context.Canceled", err)
+	}
+
+	blocking.release <- struct{}{}
+	<-blocking.started
+	blocking.release <- struct{}{}
+	if first, second := <-done, <-done; first != "research" || second != "task" {
+		t.Errorf("answers = %q, %q", first, second)
+	}
+	if load := governor.Load(); load.InFlight != 0 || load.Queued != 0 {
+		t.Errorf("load after the calls = %+v", load)
+	}
+}
+
+func TestGovernorProbesLoadedModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/models":
+			w.Write([]byte(`{"data": [{"id": "qwen2.5-7b-instruct", "state": "loaded"}, {"id": "llama-3-8b", "state": "not-loaded"}]}`))
+		case "/api/ps":
+			w.Write([]byte(`{"models": [{"name": "llama3.2:latest"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	for kind, want := range map[string][]string{
+		KindLMStudio: {"qwen2.5-7b-instruct"},
+		KindOllama:   {"llama3.2:latest"},
+	} {
+		status := NewGovernor(GovernorConfig{Name: kind, Kind: kind, URL: server.URL + "/v1"}).Status(ctx)
+		if !slices.Equal(status.LoadedModels, want) || status.ProbeError != "" {
+			t.Errorf("%s status = %+v, want loaded %q", kind, status, want)
+		}
+	}
+
+	// Other servers are not probed, and failed probes are reported
+	if status := NewGovernor(GovernorConfig{Name: "openai", URL: server.URL}).Status(ctx); status.LoadedModels != nil || status.ProbeError != "" {
+		t.Errorf("unprobed status = %+v", status)
+	}
+	server.Close()
+	if status := NewGovernor(GovernorConfig{Name: "down", Kind: KindOllama, URL: server.URL}).Status(ctx); status.ProbeError == "" {
+		t.Errorf("status of a stopped server = %+v", status)
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
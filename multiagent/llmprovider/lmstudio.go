@@ -8,13 +8,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
 )
 
+var logger = logging.For("llm")
+
 // LMStudioProvider implements the LLMProvider interface for LMStudio
 type LMStudioProvider struct {
 	ServerURL   string
@@ -23,6 +26,10 @@ type LMStudioProvider struct {
 	MaxTokens   int
 	Temperature float64
 	Debug       bool
+	// EmbeddingModel is the model used by Embed; LMStudio requires an embedding model to be loaded
+	EmbeddingModel string
+	// ContextWindow overrides the context window known for Model
+	ContextWindow int
 }
 
 // NewLMStudioProvider creates a new LMStudio provider
@@ -71,6 +78,21 @@ func WithTemperature(temperature float64) func(*LMStudioProvider) {
 	}
 }
 
+// WithEmbeddingModel sets the embedding model for the provider
+func WithEmbeddingModel(model string) func(*LMStudioProvider) {
+	return func(p *LMStudioProvider) {
+		p.EmbeddingModel = model
+	}
+}
+
+// WithContextWindow sets the tokens the loaded model's context holds, when
+// it differs from the model's known window
+func WithContextWindow(tokens int) func(*LMStudioProvider) {
+	return func(p *LMStudioProvider) {
+		p.ContextWindow = tokens
+	}
+}
+
 // WithDebug enables or disables debug mode
 func WithDebug(debug bool) func(*LMStudioProvider) {
 	return func(p *LMStudioProvider) {
@@ -83,6 +105,25 @@ func (p *LMStudioProvider) Name() string {
 	return "lmstudio"
 }
 
+// Capabilities returns those known for the model, with the configured
+// context window and max tokens
+func (p *LMStudioProvider) Capabilities() Capabilities {
+	capabilities := LookupCapabilities(p.Model)
+	if p.ContextWindow > 0 {
+		capabilities.ContextWindow = p.ContextWindow
+	}
+	capabilities.CompletionTokens = p.MaxTokens
+	return capabilities
+}
+
+// logLevel is Info in debug mode so payloads show up without -debug llm
+func (p *LMStudioProvider) logLevel() slog.Level {
+	if p.Debug {
+		return slog.LevelInfo
+	}
+	return slog.LevelDebug
+}
+
 // Query sends a prompt to the LMStudio server and returns the response
 func (p *LMStudioProvider) Query(ctx context.Context, prompt string) (string, error) {
 	// Create request payload
@@ -102,10 +143,8 @@ func (p *LMStudioProvider) Query(ctx context.Context, prompt string) (string, er
 		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Print request payload in debug mode
-	if p.Debug {
-		log.Printf("Request payload: %s", string(jsonData))
-	}
+	// Payloads are logged at debug level unless the provider is in debug mode
+	logger.Log(ctx, p.logLevel(), "LMStudio request payload", "payload", string(jsonData))
 
 	// Create HTTP request
 	req, err := http.NewRequestWithContext(ctx, "POST", p.ServerURL+"/chat/completions", bytes.NewBuffer(jsonData))
@@ -120,9 +159,7 @@ func (p *LMStudioProvider) Query(ctx context.Context, prompt string) (string, er
 	}
 
 	// Send request
-	if p.Debug {
-		log.Printf("Sending request to LMStudio at %s", p.ServerURL+"/chat/completions")
-	}
+	logger.Log(ctx, p.logLevel(), "Sending request to LMStudio", "url", p.ServerURL+"/chat/completions")
 	client := &http.Client{
 		Timeout: 600 * time.Second, // Increased timeout to 10 minutes for longer generations
 	}
@@ -138,10 +175,7 @@ func (p *LMStudioProvider) Query(ctx context.Context, prompt string) (string, er
 		return "", fmt.Errorf("failed to read response: %w", err)
 	}
 
-	// Print response in debug mode
-	if p.Debug {
-		log.Printf("Response: %s", string(body))
-	}
+	logger.Log(ctx, p.logLevel(), "LMStudio response", "body", string(body))
 
 	// Check for error status code
 	if resp.StatusCode != http.StatusOK {
@@ -175,6 +209,12 @@ func (p *LMStudioProvider) Query(ctx context.Context, prompt string) (string, er
 		return "", fmt.Errorf("invalid response format: missing content")
 	}
 
+	if usage, ok := result["usage"].(map[string]interface{}); ok {
+		prompt, _ := usage["prompt_tokens"].(float64)
+		completion, _ := usage["completion_tokens"].(float64)
+		reportUsage(ctx, int(prompt), int(completion))
+	}
+
 	return content, nil
 }
 
@@ -210,3 +250,68 @@ func (p *LMStudioProvider) QueryWithTools(ctx context.Context, prompt string, to
 
 	return response, nil
 }
+
+// Embed returns embedding vectors for texts using the OpenAI-compatible /embeddings endpoint
+func (p *LMStudioProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	payload := map[string]interface{}{
+		"input": texts,
+		"model": p.EmbeddingModel,
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.ServerURL+"/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	client := &http.Client{
+		Timeout: 120 * time.Second,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("LMStudio API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("invalid response format: expected %d embeddings, got %d", len(texts), len(result.Data))
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("invalid response format: embedding index %d out of range", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+
+	return embeddings, nil
+}
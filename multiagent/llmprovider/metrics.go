@@ -0,0 +1,142 @@
+package llmprovider
+
+import (
+	"context"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/metrics"
+	"github.com/kbutz/wikillm/multiagent/progress"
+	"github.com/kbutz/wikillm/multiagent/usage"
+)
+
+// charsPerToken approximates token counts for providers that do not report usage
+const charsPerToken = 4
+
+// InstrumentedProvider wraps an LLMProvider and records call latency, errors,
+// and token usage, and reports each call as a progress event. Token counts
+// are the ones the server reports, or estimated when it reports none.
+type InstrumentedProvider struct {
+	provider multiagent.LLMProvider
+	seconds  *metrics.Histogram
+	errors   *metrics.Counter
+	tokens   *metrics.Counter
+	usage    *usage.Tracker
+	// fallback answers conversations over their token budget
+	fallback multiagent.LLMProvider
+}
+
+// WithUsageTracker accounts each call's tokens to its agent and conversation
+func WithUsageTracker(tracker *usage.Tracker) func(*InstrumentedProvider) {
+	return func(p *InstrumentedProvider) {
+		p.usage = tracker
+	}
+}
+
+// WithBudgetFallback sends the calls of conversations over their token
+// budget to a cheaper provider; without it they are only counted
+func WithBudgetFallback(provider multiagent.LLMProvider) func(*InstrumentedProvider) {
+	return func(p *InstrumentedProvider) {
+		p.fallback = provider
+	}
+}
+
+// NewInstrumentedProvider wraps provider, registering its metrics on registry
+func NewInstrumentedProvider(provider multiagent.LLMProvider, registry *metrics.Registry, options ...func(*InstrumentedProvider)) *InstrumentedProvider {
+	p := &InstrumentedProvider{
+		provider: provider,
+		seconds: registry.NewHistogram("multiagent_llm_request_seconds",
+			"LLM request latency", nil, "provider", "method"),
+		errors: registry.NewCounter("multiagent_llm_request_errors_total",
+			"LLM requests that returned an error", "provider", "method"),
+		tokens: registry.NewCounter("multiagent_llm_tokens_total",
+			"LLM tokens, as reported by the server or estimated at four characters per token", "provider", "direction"),
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// Name returns the wrapped provider's name
+func (p *InstrumentedProvider) Name() string {
+	return p.provider.Name()
+}
+
+// Query forwards to the wrapped provider and records the call
+func (p *InstrumentedProvider) Query(ctx context.Context, prompt string) (string, error) {
+	provider := p.choose(ctx)
+	progress.Emit(ctx, progress.Event{Type: progress.LLMQuery, Detail: provider.Name()})
+	ctx, reported := withUsageReport(ctx)
+	started := time.Now()
+	response, err := provider.Query(ctx, prompt)
+	p.record(ctx, provider, "query", prompt, response, reported, time.Since(started), err)
+	return response, err
+}
+
+// QueryWithTools forwards to the wrapped provider and records the call
+func (p *InstrumentedProvider) QueryWithTools(ctx context.Context, prompt string, tools []multiagent.Tool) (string, error) {
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name())
+	}
+	provider := p.choose(ctx)
+	progress.Emit(ctx, progress.Event{
+		Type:   progress.LLMQuery,
+		Detail: provider.Name(),
+		Data:   map[string]interface{}{"tools": names},
+	})
+	ctx, reported := withUsageReport(ctx)
+	started := time.Now()
+	response, err := provider.QueryWithTools(ctx, prompt, tools)
+	p.record(ctx, provider, "query_with_tools", prompt, response, reported, time.Since(started), err)
+	return response, err
+}
+
+// Capabilities returns those of the wrapped provider's model
+func (p *InstrumentedProvider) Capabilities() Capabilities {
+	capabilities, _ := CapabilitiesOf(p.provider)
+	return capabilities
+}
+
+// choose returns the provider for a call: the budget fallback once the
+// call's conversation is over budget, otherwise the wrapped provider
+func (p *InstrumentedProvider) choose(ctx context.Context) multiagent.LLMProvider {
+	if p.usage == nil || p.fallback == nil {
+		return p.provider
+	}
+	if conversationID := logging.Field(ctx, logging.KeyConversationID); p.usage.Exceeded(ctx, conversationID) {
+		logger.DebugContext(ctx, "Conversation over its token budget, using the fallback model", "model", p.fallback.Name())
+		return p.fallback
+	}
+	return p.provider
+}
+
+func (p *InstrumentedProvider) record(ctx context.Context, provider multiagent.LLMProvider, method, prompt, response string, reported *reportedUsage, elapsed time.Duration, err error) {
+	name := provider.Name()
+	p.seconds.ObserveDuration(elapsed, name, method)
+	if err != nil {
+		p.errors.Inc(name, method)
+	}
+
+	call := usage.Call{
+		Agent:            logging.Field(ctx, logging.KeyAgentID),
+		ConversationID:   logging.Field(ctx, logging.KeyConversationID),
+		Model:            name,
+		PromptTokens:     reported.promptTokens,
+		CompletionTokens: reported.completionTokens,
+	}
+	if !reported.reported {
+		call.PromptTokens = EstimateTokens(prompt)
+		call.CompletionTokens = EstimateTokens(response)
+		call.Estimated = true
+	}
+	p.tokens.Add(float64(call.PromptTokens), name, "prompt")
+	p.tokens.Add(float64(call.CompletionTokens), name, "completion")
+	if p.usage != nil && (err == nil || reported.reported) {
+		if err := p.usage.Record(ctx, call); err != nil {
+			logger.WarnContext(ctx, "Failed to record LLM usage", "error", err)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package llmprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// RoleRouting is the role of the model agents classify requests with; it
+// falls back to the default model like any other role
+const RoleRouting = "routing"
+
+// BackendConfig describes an OpenAI-compatible server, such as LMStudio, in
+// the pool. URL and APIKey may reference environment variables, e.g.
+// "$OPENAI_API_KEY".
+type BackendConfig struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	APIKey string `json:"api_key,omitempty"`
+	// Model, MaxTokens and Temperature are defaults for roles on the backend
+	Model       string   `json:"model,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	// ContextWindow is the tokens the backend loads models with, when it
+	// differs from the models' known windows
+	ContextWindow int `json:"context_window,omitempty"`
+	// Kind, KindLMStudio or KindOllama, lets the pool probe which models
+	// the server has loaded
+	Kind string `json:"kind,omitempty"`
+	// MaxConcurrent bounds the calls in flight on the backend, across all
+	// roles using it; 0 is unlimited
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+}
+
+// ModelConfig picks the backend and model a role queries, overriding the
+// backend's defaults where set
+type ModelConfig struct {
+	Backend       string   `json:"backend"`
+	Model         string   `json:"model,omitempty"`
+	MaxTokens     int      `json:"max_tokens,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	ContextWindow int      `json:"context_window,omitempty"`
+}
+
+// PoolConfig configures a Pool: its backends, the model roles without one
+// of their own use, and per-role models. Roles are agent types, e.g.
+// "research", or RoleRouting.
+type PoolConfig struct {
+	Backends []BackendConfig        `json:"backends"`
+	Default  ModelConfig            `json:"default"`
+	Roles    map[string]ModelConfig `json:"roles,omitempty"`
+}
+
+// Pool hands out a provider per role over a set of backends. Providers
+// resolve their model on every call, so Reload switches models at runtime.
+// Calls to a backend go through its Governor.
+type Pool struct {
+	mu        sync.RWMutex
+	fallback  multiagent.LLMProvider
+	roles     map[string]multiagent.LLMProvider
+	governors []*Governor
+}
+
+// NewPool creates a pool from config
+func NewPool(config PoolConfig) (*Pool, error) {
+	pool := &Pool{}
+	if err := pool.Reload(config); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// Reload replaces the pool's models with those in config; on error the
+// current models are kept
+func (p *Pool) Reload(config PoolConfig) error {
+	backends := make(map[string]BackendConfig, len(config.Backends))
+	for _, backend := range config.Backends {
+		if backend.Name == "" || backend.URL == "" {
+			return fmt.Errorf("backend %q needs a name and a url", backend.Name)
+		}
+		if _, exists := backends[backend.Name]; exists {
+			return fmt.Errorf("backend %q is listed twice", backend.Name)
+		}
+		backends[backend.Name] = backend
+	}
+	governors := p.governorsFor(config.Backends)
+	if config.Default.Backend == "" && len(config.Backends) == 1 {
+		config.Default.Backend = config.Backends[0].Name
+	}
+
+	fallback, err := newModel(backends, governors, config.Default)
+	if err != nil {
+		return fmt.Errorf("invalid default model: %w", err)
+	}
+	roles := make(map[string]multiagent.LLMProvider, len(config.Roles))
+	for role, model := range config.Roles {
+		if roles[role], err = newModel(backends, governors, model); err != nil {
+			return fmt.Errorf("invalid model for role %q: %w", role, err)
+		}
+	}
+
+	p.mu.Lock()
+	p.fallback = fallback
+	p.roles = roles
+	p.governors = make([]*Governor, 0, len(config.Backends))
+	for _, backend := range config.Backends {
+		p.governors = append(p.governors, governors[backend.Name])
+	}
+	p.mu.Unlock()
+	logger.Info("Configured LLM models", "default", fallback.Name(), "roles", p.Describe())
+	return nil
+}
+
+// Provider returns the provider for role, which queries the role's current
+// model
+func (p *Pool) Provider(role string) multiagent.LLMProvider {
+	return &roleProvider{pool: p, role: role}
+}
+
+// Describe maps each configured role to its model, e.g. "fast/qwen2.5-3b"
+func (p *Pool) Describe() map[string]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	models := make(map[string]string, len(p.roles))
+	for role, provider := range p.roles {
+		models[role] = provider.Name()
+	}
+	return models
+}
+
+// Governors returns the governor of each backend
+func (p *Pool) Governors() []*Governor {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]*Governor(nil), p.governors...)
+}
+
+// governorsFor returns a governor per backend, keeping the current one of
+// a backend whose settings are unchanged so its in-flight calls still count
+func (p *Pool) governorsFor(backends []BackendConfig) map[string]*Governor {
+	p.mu.RLock()
+	current := make(map[GovernorConfig]*Governor, len(p.governors))
+	for _, governor := range p.governors {
+		current[governor.config] = governor
+	}
+	p.mu.RUnlock()
+
+	governors := make(map[string]*Governor, len(backends))
+	for _, backend := range backends {
+		config := GovernorConfig{
+			Name:          backend.Name,
+			Kind:          backend.Kind,
+			URL:           strings.TrimSuffix(os.ExpandEnv(backend.URL), "/"),
+			MaxConcurrent: backend.MaxConcurrent,
+		}
+		if governor, ok := current[config]; ok {
+			governors[backend.Name] = governor
+		} else {
+			governors[backend.Name] = NewGovernor(config)
+		}
+	}
+	return governors
+}
+
+func (p *Pool) resolve(role string) multiagent.LLMProvider {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if provider, ok := p.roles[role]; ok {
+		return provider
+	}
+	return p.fallback
+}
+
+// newModel creates a provider for model on its backend, within the
+// backend's governor
+func newModel(backends map[string]BackendConfig, governors map[string]*Governor, model ModelConfig) (multiagent.LLMProvider, error) {
+	backend, ok := backends[model.Backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q", model.Backend)
+	}
+
+	options := []func(*LMStudioProvider){WithAPIKey(os.ExpandEnv(backend.APIKey))}
+	name := model.Model
+	if name == "" {
+		name = backend.Model
+	}
+	if name != "" {
+		options = append(options, WithModel(name))
+	}
+	if maxTokens := firstPositive(model.MaxTokens, backend.MaxTokens); maxTokens > 0 {
+		options = append(options, WithMaxTokens(maxTokens))
+	}
+	if contextWindow := firstPositive(model.ContextWindow, backend.ContextWindow); contextWindow > 0 {
+		options = append(options, WithContextWindow(contextWindow))
+	}
+	if temperature := model.Temperature; temperature != nil {
+		options = append(options, WithTemperature(*temperature))
+	} else if backend.Temperature != nil {
+		options = append(options, WithTemperature(*backend.Temperature))
+	}
+
+	provider := NewLMStudioProvider(strings.TrimSuffix(os.ExpandEnv(backend.URL), "/"), options...)
+	named := &namedProvider{LMStudioProvider: provider, name: backend.Name + "/" + provider.Model}
+	return governors[backend.Name].Provider(named), nil
+}
+
+func firstPositive(values ...int) int {
+	for _, value := range values {
+		if value > 0 {
+			return value
+		}
+	}
+	return 0
+}
+
+// namedProvider names a model by its backend and model, so metrics and logs
+// tell the pool's models apart
+type namedProvider struct {
+	*LMStudioProvider
+	name string
+}
+
+func (p *namedProvider) Name() string {
+	return p.name
+}
+
+// roleProvider queries the model currently configured for a role
+type roleProvider struct {
+	pool *Pool
+	role string
+}
+
+func (p *roleProvider) Name() string {
+	return p.pool.resolve(p.role).Name()
+}
+
+func (p *roleProvider) Capabilities() Capabilities {
+	capabilities, _ := CapabilitiesOf(p.pool.resolve(p.role))
+	return capabilities
+}
+
+func (p *roleProvider) Query(ctx context.Context, prompt string) (string, error) {
+	return p.pool.resolve(p.role).Query(ctx, prompt)
+}
+
+func (p *roleProvider) QueryWithTools(ctx context.Context, prompt string, tools []multiagent.Tool) (string, error) {
+	return p.pool.resolve(p.role).QueryWithTools(ctx, prompt, tools)
+}
+
+// LoadPoolConfig reads a pool's backends and per-role models from a JSON
+// file:
+//
+//	{"backends": [{"name": "local", "url": "http://localhost:1234/v1", "model": "qwen2.5-7b-instruct",
+//	               "kind": "lmstudio", "max_concurrent": 1},
+//	              {"name": "fast", "url": "http://localhost:1235/v1", "model": "qwen2.5-1.5b-instruct", "max_tokens": 256}],
+//	 "default": {"backend": "local"},
+//	 "roles": {"routing": {"backend": "fast", "temperature": 0},
+//	           "research": {"backend": "local", "model": "qwen2.5-32b-instruct", "max_tokens": 4096, "context_window": 32768}}}
+func LoadPoolConfig(path string) (PoolConfig, error) {
+	var config PoolConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read LLM config: %w", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse LLM config: %w", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,117 @@
+package llmprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// echoServer answers chat completions with the request's model, temperature
+// and max tokens
+func echoServer(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Model       string  `json:"model"`
+			Temperature float64 `json:"temperature"`
+			MaxTokens   int     `json:"max_tokens"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		content := fmt.Sprintf("%s %s %.1f %d", name, request.Model, request.Temperature, request.MaxTokens)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": content}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPoolRoutesRolesToTheirModels(t *testing.T) {
+	ctx := context.Background()
+	local, fast := echoServer(t, "local"), echoServer(t, "fast")
+	zero := 0.0
+	config := PoolConfig{
+		Backends: []BackendConfig{
+			{Name: "local", URL: local.URL + "/", Model: "big", MaxTokens: 4096},
+			{Name: "fast", URL: fast.URL, Model: "small"},
+		},
+		Default: ModelConfig{Backend: "local"},
+		Roles: map[string]ModelConfig{
+			RoleRouting: {Backend: "fast", Temperature: &zero, MaxTokens: 64},
+			"research":  {Backend: "local", Model: "huge"},
+		},
+	}
+	pool, err := NewPool(config)
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+
+	for role, want := range map[string]string{
+		RoleRouting: "fast small 0.0 64",
+		"research":  "local huge 0.7 4096",
+		"task":      "local big 0.7 4096",
+	} {
+		got, err := pool.Provider(role).Query(ctx, "hi")
+		if err != nil {
+			t.Fatalf("Query(%s): %v", role, err)
+		}
+		if got != want {
+			t.Errorf("role %s answered %q, want %q", role, got, want)
+		}
+	}
+	if name := pool.Provider("research").Name(); name != "local/huge" {
+		t.Errorf("research provider is named %q", name)
+	}
+
+	// Providers already handed out follow a reload
+	routing := pool.Provider(RoleRouting)
+	config.Roles = map[string]ModelConfig{RoleRouting: {Backend: "local", Model: "medium"}}
+	if err := pool.Reload(config); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got, _ := routing.Query(ctx, "hi"); got != "local medium 0.7 4096" {
+		t.Errorf("routing after reload answered %q", got)
+	}
+
+	// An invalid config leaves the current models in place
+	config.Roles = map[string]ModelConfig{RoleRouting: {Backend: "missing"}}
+	if err := pool.Reload(config); err == nil {
+		t.Fatal("expected an error reloading a role on an unknown backend")
+	}
+	if got, _ := routing.Query(ctx, "hi"); got != "local medium 0.7 4096" {
+		t.Errorf("routing after a failed reload answered %q", got)
+	}
+}
+
+func TestPoolKeepsGovernorsOfUnchangedBackends(t *testing.T) {
+	local, fast := echoServer(t, "local"), echoServer(t, "fast")
+	config := PoolConfig{
+		Backends: []BackendConfig{
+			{Name: "local", URL: local.URL, Kind: KindLMStudio, MaxConcurrent: 1},
+			{Name: "fast", URL: fast.URL},
+		},
+		Default: ModelConfig{Backend: "local"},
+	}
+	pool, err := NewPool(config)
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+	before := pool.Governors()
+	if len(before) != 2 || before[0].Load().MaxConcurrent != 1 {
+		t.Fatalf("governors = %+v", before)
+	}
+
+	config.Backends[1].MaxConcurrent = 2
+	if err := pool.Reload(config); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	after := pool.Governors()
+	if after[0] != before[0] || after[1] == before[1] || after[1].Load().MaxConcurrent != 2 {
+		t.Errorf("governors after changing the fast backend's limit: %p %p, were %p %p", after[0], after[1], before[0], before[1])
+	}
+}
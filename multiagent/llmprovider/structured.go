@@ -0,0 +1,377 @@
+package llmprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// ParseStats counts structured-output outcomes for one caller
+type ParseStats struct {
+	Requests      int64 `json:"requests"`
+	FirstTry      int64 `json:"first_try"`
+	Repaired      int64 `json:"repaired"`
+	Failed        int64 `json:"failed"`
+	ParseErrors   int64 `json:"parse_errors"`   // Responses with no decodable JSON
+	SchemaErrors  int64 `json:"schema_errors"`  // Decodable JSON that failed validation
+	RepairQueries int64 `json:"repair_queries"` // "Fix your JSON" prompts sent
+}
+
+// ParseMetrics aggregates ParseStats by caller; one instance is typically
+// shared by every agent in a process
+type ParseMetrics struct {
+	mu    sync.Mutex
+	stats map[string]*ParseStats
+}
+
+// NewParseMetrics creates an empty metrics collector
+func NewParseMetrics() *ParseMetrics {
+	return &ParseMetrics{stats: make(map[string]*ParseStats)}
+}
+
+// Snapshot returns a copy of the counters for every caller
+func (m *ParseMetrics) Snapshot() map[string]ParseStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]ParseStats, len(m.stats))
+	for caller, stats := range m.stats {
+		snapshot[caller] = *stats
+	}
+	return snapshot
+}
+
+func (m *ParseMetrics) record(caller string, update func(*ParseStats)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.stats[caller]
+	if stats == nil {
+		stats = &ParseStats{}
+		m.stats[caller] = stats
+	}
+	update(stats)
+}
+
+// StructuredOutputConfig holds configuration for creating a StructuredOutput
+type StructuredOutputConfig struct {
+	Provider multiagent.LLMProvider
+	// Name identifies the caller in metrics and logs, e.g. "SchedulerAgent"
+	Name string
+	// MaxRepairs is how many times a bad response is sent back for fixing (default 2)
+	MaxRepairs int
+	Metrics    *ParseMetrics
+}
+
+// StructuredOutput queries an LLM for JSON, validates the reply against a
+// schema, and asks the model to repair invalid output
+type StructuredOutput struct {
+	provider   multiagent.LLMProvider
+	name       string
+	maxRepairs int
+	metrics    *ParseMetrics
+}
+
+// NewStructuredOutput creates a new structured output helper
+func NewStructuredOutput(config StructuredOutputConfig) *StructuredOutput {
+	if config.MaxRepairs == 0 {
+		config.MaxRepairs = 2
+	}
+	if config.MaxRepairs < 0 {
+		config.MaxRepairs = 0
+	}
+	if config.Metrics == nil {
+		config.Metrics = NewParseMetrics()
+	}
+
+	return &StructuredOutput{
+		provider:   config.Provider,
+		name:       config.Name,
+		maxRepairs: config.MaxRepairs,
+		metrics:    config.Metrics,
+	}
+}
+
+// Metrics returns the collector this helper records into
+func (s *StructuredOutput) Metrics() *ParseMetrics {
+	return s.metrics
+}
+
+// Query sends prompt and decodes the JSON reply into out. A nil schema skips
+// validation. LLM errors are returned immediately; parse and schema errors
+// trigger up to MaxRepairs follow-up prompts before giving up.
+func (s *StructuredOutput) Query(ctx context.Context, prompt string, schema map[string]interface{}, out interface{}) error {
+	if s.provider == nil {
+		return fmt.Errorf("no LLM provider")
+	}
+	s.metrics.record(s.name, func(stats *ParseStats) { stats.Requests++ })
+
+	response, err := s.provider.Query(ctx, prompt)
+	if err != nil {
+		return fmt.Errorf("LLM query failed: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		parseErr := s.decode(response, schema, out)
+		if parseErr == nil {
+			s.metrics.record(s.name, func(stats *ParseStats) {
+				if attempt == 0 {
+					stats.FirstTry++
+				} else {
+					stats.Repaired++
+				}
+			})
+			return nil
+		}
+
+		if attempt >= s.maxRepairs {
+			s.metrics.record(s.name, func(stats *ParseStats) { stats.Failed++ })
+			return fmt.Errorf("failed to get valid JSON after %d attempts: %w", attempt+1, parseErr)
+		}
+
+		logger.WarnContext(ctx, "Invalid structured output, requesting repair", "caller", s.name, "error", parseErr)
+		s.metrics.record(s.name, func(stats *ParseStats) { stats.RepairQueries++ })
+		response, err = s.provider.Query(ctx, repairPrompt(prompt, response, schema, parseErr))
+		if err != nil {
+			s.metrics.record(s.name, func(stats *ParseStats) { stats.Failed++ })
+			return fmt.Errorf("LLM repair query failed: %w", err)
+		}
+	}
+}
+
+// decode extracts, validates, and unmarshals one response
+func (s *StructuredOutput) decode(response string, schema map[string]interface{}, out interface{}) error {
+	raw, err := ExtractJSON(response)
+	if err != nil {
+		s.metrics.record(s.name, func(stats *ParseStats) { stats.ParseErrors++ })
+		return err
+	}
+
+	if schema != nil {
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			s.metrics.record(s.name, func(stats *ParseStats) { stats.ParseErrors++ })
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+		if err := ValidateJSON(value, schema); err != nil {
+			s.metrics.record(s.name, func(stats *ParseStats) { stats.SchemaErrors++ })
+			return err
+		}
+	}
+
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		s.metrics.record(s.name, func(stats *ParseStats) { stats.ParseErrors++ })
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return nil
+}
+
+func repairPrompt(prompt, response string, schema map[string]interface{}, parseErr error) string {
+	var b strings.Builder
+	b.WriteString(prompt)
+	fmt.Fprintf(&b, "\n\nYour previous response could not be used: %v\n\nPrevious response:\n%s\n\n", parseErr, response)
+	if schema != nil {
+		if schemaJSON, err := json.Marshal(schema); err == nil {
+			fmt.Fprintf(&b, "The JSON must match this schema:\n%s\n\n", schemaJSON)
+		}
+	}
+	b.WriteString("Fix your JSON. Respond with only the corrected JSON, no code fences or other text.")
+	return b.String()
+}
+
+// ExtractJSON returns the JSON value embedded in an LLM response, stripping
+// markdown code fences and any text before or after it
+func ExtractJSON(response string) (string, error) {
+	text := strings.TrimSpace(response)
+
+	if start := strings.Index(text, "```"); start != -1 {
+		body := text[start+3:]
+		if newline := strings.Index(body, "\n"); newline != -1 {
+			body = body[newline+1:] // Drop the language tag, e.g. ```json
+		}
+		if end := strings.Index(body, "```"); end != -1 {
+			body = body[:end]
+		}
+		text = strings.TrimSpace(body)
+	}
+
+	start := strings.IndexAny(text, "{[")
+	if start == -1 {
+		return "", fmt.Errorf("no JSON found in response")
+	}
+	closing := "}"
+	if text[start] == '[' {
+		closing = "]"
+	}
+	end := strings.LastIndex(text, closing)
+	if end < start {
+		return "", fmt.Errorf("unterminated JSON in response")
+	}
+
+	raw := text[start : end+1]
+	if !json.Valid([]byte(raw)) {
+		return "", fmt.Errorf("invalid JSON in response")
+	}
+	return raw, nil
+}
+
+// ValidateJSON checks a decoded JSON value against a subset of JSON Schema:
+// type, properties, required, enum, items, minimum, and maximum
+func ValidateJSON(value interface{}, schema map[string]interface{}) error {
+	return validateAt("$", value, schema)
+}
+
+func validateAt(path string, value interface{}, schema map[string]interface{}) error {
+	if typeName, ok := schema["type"].(string); ok && !matchesType(value, typeName) {
+		return fmt.Errorf("%s: expected %s, got %s", path, typeName, jsonTypeName(value))
+	}
+
+	if enum, ok := schema["enum"]; ok && !inEnum(value, enum) {
+		return fmt.Errorf("%s: value %v is not one of %v", path, value, enum)
+	}
+
+	if number, ok := value.(float64); ok {
+		if minimum, ok := toFloat(schema["minimum"]); ok && number < minimum {
+			return fmt.Errorf("%s: %v is less than minimum %v", path, number, minimum)
+		}
+		if maximum, ok := toFloat(schema["maximum"]); ok && number > maximum {
+			return fmt.Errorf("%s: %v is greater than maximum %v", path, number, maximum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, field := range stringList(schema["required"]) {
+			if _, ok := v[field]; !ok {
+				return fmt.Errorf("%s: missing required field %q", path, field)
+			}
+		}
+		if properties, ok := schema["properties"].(map[string]interface{}); ok {
+			names := make([]string, 0, len(properties))
+			for name := range properties {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fieldValue, present := v[name]
+				fieldSchema, ok := properties[name].(map[string]interface{})
+				if !present || !ok || fieldValue == nil {
+					continue
+				}
+				if err := validateAt(path+"."+name, fieldValue, fieldSchema); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateAt(fmt.Sprintf("%s[%d]", path, i), item, items); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func matchesType(value interface{}, typeName string) bool {
+	switch typeName {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == float64(int64(number))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func inEnum(value interface{}, enum interface{}) bool {
+	switch options := enum.(type) {
+	case []string:
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		for _, option := range options {
+			if s == option {
+				return true
+			}
+		}
+		return false
+	case []interface{}:
+		for _, option := range options {
+			if option == value {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func stringList(value interface{}) []string {
+	switch list := value.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		names := make([]string, 0, len(list))
+		for _, item := range list {
+			if name, ok := item.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
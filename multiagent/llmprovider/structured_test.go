@@ -0,0 +1,128 @@
+package llmprovider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// scriptedProvider returns canned responses in order and records prompts
+type scriptedProvider struct {
+	responses []string
+	prompts   []string
+}
+
+func (p *scriptedProvider) Name() string { return "scripted" }
+
+func (p *scriptedProvider) Query(ctx context.Context, prompt string) (string, error) {
+	p.prompts = append(p.prompts, prompt)
+	response := p.responses[0]
+	if len(p.responses) > 1 {
+		p.responses = p.responses[1:]
+	}
+	return response, nil
+}
+
+func (p *scriptedProvider) QueryWithTools(ctx context.Context, prompt string, tools []multiagent.Tool) (string, error) {
+	return p.Query(ctx, prompt)
+}
+
+var eventSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []string{"title"},
+	"properties": map[string]interface{}{
+		"title":    map[string]interface{}{"type": "string"},
+		"duration": map[string]interface{}{"type": "integer", "minimum": 0},
+		"priority": map[string]interface{}{"type": "string", "enum": []string{"low", "high"}},
+	},
+}
+
+type event struct {
+	Title    string `json:"title"`
+	Duration int    `json:"duration"`
+	Priority string `json:"priority"`
+}
+
+func TestExtractJSONStripsFencesAndProse(t *testing.T) {
+	response := "Sure! Here it is:\n```json\n{\"title\": \"Standup\"}\n```\nLet me know."
+	raw, err := ExtractJSON(response)
+	if err != nil {
+		t.Fatalf("ExtractJSON failed: %v", err)
+	}
+	if raw != `{"title": "Standup"}` {
+		t.Fatalf("unexpected JSON %q", raw)
+	}
+
+	if _, err := ExtractJSON("no json here"); err == nil {
+		t.Fatal("expected error for response without JSON")
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	cases := []struct {
+		name  string
+		value map[string]interface{}
+		want  string
+	}{
+		{"valid", map[string]interface{}{"title": "a", "duration": 30.0, "priority": "low"}, ""},
+		{"missing required", map[string]interface{}{"duration": 30.0}, "missing required field"},
+		{"wrong type", map[string]interface{}{"title": "a", "duration": "30 minutes"}, "expected integer"},
+		{"enum", map[string]interface{}{"title": "a", "priority": "urgent"}, "is not one of"},
+		{"minimum", map[string]interface{}{"title": "a", "duration": -5.0}, "less than minimum"},
+	}
+	for _, tc := range cases {
+		err := ValidateJSON(tc.value, eventSchema)
+		if tc.want == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+		if tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestStructuredOutputRepairsInvalidJSON(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{
+		`{"title": "Standup", "duration": "15 minutes"}`,
+		"```json\n{\"title\": \"Standup\", \"duration\": 15}\n```",
+	}}
+	metrics := NewParseMetrics()
+	structured := NewStructuredOutput(StructuredOutputConfig{Provider: provider, Name: "test", Metrics: metrics})
+
+	var out event
+	if err := structured.Query(context.Background(), "Parse: standup 15 min", eventSchema, &out); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if out.Title != "Standup" || out.Duration != 15 {
+		t.Fatalf("unexpected result %+v", out)
+	}
+	if len(provider.prompts) != 2 || !strings.Contains(provider.prompts[1], "Fix your JSON") {
+		t.Fatalf("expected one repair prompt, got %d prompts", len(provider.prompts))
+	}
+
+	stats := metrics.Snapshot()["test"]
+	if stats.Requests != 1 || stats.Repaired != 1 || stats.SchemaErrors != 1 || stats.RepairQueries != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestStructuredOutputGivesUpAfterMaxRepairs(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{"I cannot do that"}}
+	metrics := NewParseMetrics()
+	structured := NewStructuredOutput(StructuredOutputConfig{Provider: provider, Name: "test", MaxRepairs: 2, Metrics: metrics})
+
+	var out event
+	if err := structured.Query(context.Background(), "Parse", eventSchema, &out); err == nil {
+		t.Fatal("expected error after exhausting repairs")
+	}
+	if len(provider.prompts) != 3 {
+		t.Fatalf("expected 3 prompts, got %d", len(provider.prompts))
+	}
+
+	stats := metrics.Snapshot()["test"]
+	if stats.Failed != 1 || stats.ParseErrors != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,29 @@
+package llmprovider
+
+import "context"
+
+// reportedUsage holds the token counts a provider reported for a call
+type reportedUsage struct {
+	reported         bool
+	promptTokens     int
+	completionTokens int
+}
+
+type usageKey struct{}
+
+// withUsageReport returns a context a provider reports its call's token
+// usage into, and the slot it lands in
+func withUsageReport(ctx context.Context) (context.Context, *reportedUsage) {
+	slot := &reportedUsage{}
+	return context.WithValue(ctx, usageKey{}, slot), slot
+}
+
+// reportUsage records the token usage the server reported for a call made
+// with ctx; it does nothing if nobody is accounting for the call
+func reportUsage(ctx context.Context, promptTokens, completionTokens int) {
+	if slot, ok := ctx.Value(usageKey{}).(*reportedUsage); ok {
+		slot.reported = true
+		slot.promptTokens = promptTokens
+		slot.completionTokens = completionTokens
+	}
+}
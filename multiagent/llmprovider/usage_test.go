@@ -0,0 +1,48 @@
+package llmprovider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/metrics"
+	"github.com/kbutz/wikillm/multiagent/usage"
+)
+
+func TestInstrumentedProviderAccountsUsageAndEnforcesBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": "reported"}}},
+			"usage":   map[string]int{"prompt_tokens": 70, "completion_tokens": 40},
+		})
+	}))
+	defer server.Close()
+	cheap := echoServer(t, "cheap")
+
+	tracker := usage.NewTracker(usage.TrackerConfig{Budget: usage.Budget{ConversationTokensPerDay: 100}})
+	provider := NewInstrumentedProvider(NewLMStudioProvider(server.URL, WithModel("big")), metrics.NewRegistry(),
+		WithUsageTracker(tracker), WithBudgetFallback(NewLMStudioProvider(cheap.URL, WithModel("small"))))
+	ctx := logging.WithFields(context.Background(), logging.KeyAgentID, "task_manager_agent", logging.KeyConversationID, "conv_alice")
+
+	if got, err := provider.Query(ctx, "hi"); err != nil || got != "reported" {
+		t.Fatalf("Query = %q, %v", got, err)
+	}
+	today := tracker.Day(ctx, time.Now())
+	if agent := today.Agents["task_manager_agent"]; agent.PromptTokens != 70 || agent.CompletionTokens != 40 || agent.EstimatedCalls != 0 {
+		t.Fatalf("task_manager_agent usage = %+v, want the reported 70 and 40 tokens", agent)
+	}
+
+	// conv_alice has spent its budget, so the cheaper model answers and its
+	// unreported usage is estimated
+	if got, _ := provider.Query(ctx, "hi"); got != "cheap small 0.7 2048" {
+		t.Fatalf("over-budget Query answered %q, want the fallback", got)
+	}
+	today = tracker.Day(ctx, time.Now())
+	if model := today.Models["lmstudio"]; model.Calls != 2 || model.EstimatedCalls != 1 {
+		t.Errorf("model usage = %+v, want 2 calls with 1 estimated", today.Models)
+	}
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Flags binds logging configuration to command-line flags
+type Flags struct {
+	Level string
+	JSON  bool
+	Debug string
+}
+
+// Register adds -log-level, -log-json, and -debug to fs
+func (f *Flags) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.Level, "log-level", "info", "minimum log level (debug, info, warn, error)")
+	fs.BoolVar(&f.JSON, "log-json", false, "write logs as JSON lines")
+	fs.StringVar(&f.Debug, "debug", "", `comma-separated components to log at debug level (e.g. "orchestrator,coordinator"), or "all"`)
+}
+
+// Config converts the parsed flags into a Config
+func (f *Flags) Config() (Config, error) {
+	config := Config{JSON: f.JSON}
+	if f.Level != "" {
+		if err := config.Level.UnmarshalText([]byte(f.Level)); err != nil {
+			return Config{}, fmt.Errorf("invalid log level %q: %w", f.Level, err)
+		}
+	}
+
+	for _, component := range strings.Split(f.Debug, ",") {
+		component = strings.TrimSpace(component)
+		switch component {
+		case "":
+		case "all", "*":
+			config.Level = slog.LevelDebug
+		default:
+			if config.Components == nil {
+				config.Components = make(map[string]slog.Level)
+			}
+			config.Components[component] = slog.LevelDebug
+		}
+	}
+	return config, nil
+}
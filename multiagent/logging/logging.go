@@ -0,0 +1,255 @@
+// Package logging provides leveled, structured loggers for multiagent
+// components, with per-component levels and correlation IDs carried in the
+// context.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// Attribute keys shared by every component so logs can be joined across them
+const (
+	KeyComponent      = "component"
+	KeyConversationID = "conversation_id"
+	KeyMessageID      = "message_id"
+	KeyAgentID        = "agent_id"
+	KeyTaskID         = "task_id"
+	KeyUserID         = "user_id"
+)
+
+// Config controls log output for the whole process
+type Config struct {
+	// Level is the minimum level for components without an override (default Info)
+	Level slog.Level
+	// Components overrides Level per component, e.g. {"orchestrator": slog.LevelDebug}
+	Components map[string]slog.Level
+	// JSON switches from text to JSON lines
+	JSON bool
+	// Output defaults to stderr
+	Output io.Writer
+}
+
+// state is the active configuration; loggers consult it on every record so
+// Configure takes effect for loggers created earlier
+type state struct {
+	config  Config
+	handler slog.Handler
+}
+
+var current atomic.Pointer[state]
+
+func init() {
+	current.Store(newState(Config{}))
+}
+
+// Configure replaces the process-wide logging configuration; output from the
+// standard log package is routed through it as well
+func Configure(config Config) {
+	current.Store(newState(config))
+	slog.SetDefault(slog.New(&componentHandler{}))
+}
+
+func newState(config Config) *state {
+	if config.Output == nil {
+		config.Output = os.Stderr
+	}
+
+	// Components filter themselves, so the base handler lets everything through
+	options := &slog.HandlerOptions{Level: slog.Level(-32)}
+	var handler slog.Handler
+	if config.JSON {
+		handler = slog.NewJSONHandler(config.Output, options)
+	} else {
+		handler = slog.NewTextHandler(config.Output, options)
+	}
+	return &state{config: config, handler: handler}
+}
+
+// For returns the logger for a component such as "orchestrator" or an agent ID
+func For(component string) *slog.Logger {
+	return slog.New(&componentHandler{component: component})
+}
+
+// componentHandler applies the component's level and adds correlation IDs
+// from the context before delegating to the configured handler
+type componentHandler struct {
+	component string
+	attrs     []slog.Attr
+	groups    []string
+}
+
+func (h *componentHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	config := current.Load().config
+	min := config.Level
+	if override, ok := config.Components[h.component]; ok {
+		min = override
+	}
+	return level >= min
+}
+
+func (h *componentHandler) Handle(ctx context.Context, record slog.Record) error {
+	handler := current.Load().handler
+	if h.component != "" {
+		handler = handler.WithAttrs([]slog.Attr{slog.String(KeyComponent, h.component)})
+	}
+	if fields := h.contextFields(ctx, record); len(fields) > 0 {
+		handler = handler.WithAttrs(fields)
+	}
+	if len(h.attrs) > 0 {
+		handler = handler.WithAttrs(h.attrs)
+	}
+	for _, group := range h.groups {
+		handler = handler.WithGroup(group)
+	}
+	return handler.Handle(ctx, record)
+}
+
+// contextFields returns ctx's correlation fields except those the logger or
+// the record sets itself, so a key appears once and the explicit value wins
+func (h *componentHandler) contextFields(ctx context.Context, record slog.Record) []slog.Attr {
+	fields := contextFields(ctx)
+	if len(fields) == 0 {
+		return nil
+	}
+	explicit := make(map[string]bool)
+	for _, attr := range h.attrs {
+		explicit[attr.Key] = true
+	}
+	if len(h.groups) == 0 {
+		record.Attrs(func(attr slog.Attr) bool {
+			explicit[attr.Key] = true
+			return true
+		})
+	}
+	return slices.DeleteFunc(fields, func(attr slog.Attr) bool { return explicit[attr.Key] })
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	if len(h.groups) > 0 {
+		// Attributes inside a group are rare here; keep them grouped
+		attrs = []slog.Attr{{Key: strings.Join(h.groups, "."), Value: slog.GroupValue(attrs...)}}
+	}
+	clone.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &clone
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = append(append([]string(nil), h.groups...), name)
+	return &clone
+}
+
+// contextKey stores correlation fields on a context
+type contextKey struct{}
+
+type fields struct {
+	parent *fields
+	attrs  []slog.Attr
+}
+
+// WithFields returns a context whose log records carry the given key/value
+// pairs; later values for the same key win
+func WithFields(ctx context.Context, args ...interface{}) context.Context {
+	var attrs []slog.Attr
+	for i := 0; i+1 < len(args); i += 2 {
+		key := fmt.Sprint(args[i])
+		value := args[i+1]
+		if s, ok := value.(fmt.Stringer); ok {
+			value = s.String()
+		}
+		if s := fmt.Sprint(value); s == "" {
+			continue
+		}
+		attrs = append(attrs, slog.Any(key, value))
+	}
+	if len(attrs) == 0 {
+		return ctx
+	}
+	parent, _ := ctx.Value(contextKey{}).(*fields)
+	return context.WithValue(ctx, contextKey{}, &fields{parent: parent, attrs: attrs})
+}
+
+// WithMessage tags ctx with the message's ID and, when present, its
+// conversation, task, and user IDs
+func WithMessage(ctx context.Context, msg *multiagent.Message) context.Context {
+	if msg == nil {
+		return ctx
+	}
+	args := []interface{}{KeyMessageID, msg.ID}
+	if conversationID, ok := msg.Context["conversation_id"].(string); ok {
+		args = append(args, KeyConversationID, conversationID)
+	}
+	if taskID, ok := msg.Context["task_id"].(string); ok {
+		args = append(args, KeyTaskID, taskID)
+	}
+	if userID := multiagent.UserIDFromMessage(msg); userID != "" {
+		args = append(args, KeyUserID, userID)
+	}
+	return WithFields(ctx, args...)
+}
+
+// WithAgent tags ctx with the agent handling the work
+func WithAgent(ctx context.Context, agentID multiagent.AgentID) context.Context {
+	return WithFields(ctx, KeyAgentID, string(agentID))
+}
+
+// WithTask tags ctx with a task ID
+func WithTask(ctx context.Context, taskID string) context.Context {
+	return WithFields(ctx, KeyTaskID, taskID)
+}
+
+// Field returns the innermost value of a correlation field on ctx, or ""
+func Field(ctx context.Context, key string) string {
+	if ctx == nil {
+		return ""
+	}
+	f, _ := ctx.Value(contextKey{}).(*fields)
+	for ; f != nil; f = f.parent {
+		for i := len(f.attrs) - 1; i >= 0; i-- {
+			if f.attrs[i].Key == key {
+				return f.attrs[i].Value.String()
+			}
+		}
+	}
+	return ""
+}
+
+// contextFields flattens the fields on ctx, innermost value first per key
+func contextFields(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	f, _ := ctx.Value(contextKey{}).(*fields)
+	if f == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var attrs []slog.Attr
+	for ; f != nil; f = f.parent {
+		for i := len(f.attrs) - 1; i >= 0; i-- {
+			if !seen[f.attrs[i].Key] {
+				seen[f.attrs[i].Key] = true
+				attrs = append(attrs, f.attrs[i])
+			}
+		}
+	}
+	// Restore outermost-first order
+	for i, j := 0, len(attrs)-1; i < j; i, j = i+1, j-1 {
+		attrs[i], attrs[j] = attrs[j], attrs[i]
+	}
+	return attrs
+}
@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+func configureForTest(t *testing.T, config Config) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	config.Output = &buf
+	previous := current.Load()
+	Configure(config)
+	t.Cleanup(func() { current.Store(previous) })
+	return &buf
+}
+
+func TestComponentLevelOverride(t *testing.T) {
+	buf := configureForTest(t, Config{
+		Level:      slog.LevelInfo,
+		Components: map[string]slog.Level{"orchestrator": slog.LevelDebug},
+	})
+
+	For("orchestrator").Debug("routing detail")
+	For("coordinator").Debug("hidden detail")
+	For("coordinator").Info("visible")
+
+	out := buf.String()
+	if !strings.Contains(out, "routing detail") {
+		t.Errorf("expected orchestrator debug output, got:\n%s", out)
+	}
+	if strings.Contains(out, "hidden detail") {
+		t.Errorf("expected coordinator debug output to be filtered, got:\n%s", out)
+	}
+	if !strings.Contains(out, "component=coordinator") {
+		t.Errorf("expected component attribute, got:\n%s", out)
+	}
+}
+
+func TestContextCorrelationFields(t *testing.T) {
+	buf := configureForTest(t, Config{JSON: true})
+
+	ctx := WithMessage(context.Background(), &multiagent.Message{
+		ID:      "msg_1",
+		Context: map[string]interface{}{"conversation_id": "conv_alice", "task_id": "task_9"},
+	})
+	ctx = WithAgent(ctx, "scheduler_agent")
+	For("scheduler").InfoContext(ctx, "booked meeting", "slot", "10:00")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected one JSON record, got %q: %v", buf.String(), err)
+	}
+	want := map[string]string{
+		KeyComponent:      "scheduler",
+		KeyMessageID:      "msg_1",
+		KeyConversationID: "conv_alice",
+		KeyTaskID:         "task_9",
+		KeyAgentID:        "scheduler_agent",
+		"slot":            "10:00",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %s", key, record[key], value)
+		}
+	}
+}
+
+func TestInnerFieldsOverrideOuter(t *testing.T) {
+	buf := configureForTest(t, Config{JSON: true})
+
+	ctx := WithTask(context.Background(), "task_outer")
+	ctx = WithTask(ctx, "task_inner")
+	For("orchestrator").InfoContext(ctx, "retrying")
+
+	if strings.Count(buf.String(), KeyTaskID) != 1 || !strings.Contains(buf.String(), "task_inner") {
+		t.Fatalf("expected only the inner task ID, got %s", buf.String())
+	}
+}
+
+func TestRecordFieldsOverrideContext(t *testing.T) {
+	buf := configureForTest(t, Config{})
+
+	ctx := WithTask(context.Background(), "research")
+	For("orchestrator").InfoContext(ctx, "step started", KeyTaskID, "draft")
+	For("orchestrator").With(KeyTaskID, "review").InfoContext(ctx, "step started")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two records, got %q", buf.String())
+	}
+	for i, want := range []string{"task_id=draft", "task_id=review"} {
+		if strings.Count(lines[i], KeyTaskID) != 1 || !strings.Contains(lines[i], want) {
+			t.Errorf("record %d = %q, want only %s", i, lines[i], want)
+		}
+	}
+}
+
+func TestFlagsConfig(t *testing.T) {
+	flags := Flags{Level: "warn", Debug: "orchestrator, coordinator"}
+	config, err := flags.Config()
+	if err != nil {
+		t.Fatalf("Config: %v", err)
+	}
+	if config.Level != slog.LevelWarn {
+		t.Errorf("Level = %v, want warn", config.Level)
+	}
+	if config.Components["orchestrator"] != slog.LevelDebug || config.Components["coordinator"] != slog.LevelDebug {
+		t.Errorf("unexpected component levels %v", config.Components)
+	}
+
+	all, err := (&Flags{Level: "info", Debug: "all"}).Config()
+	if err != nil || all.Level != slog.LevelDebug {
+		t.Errorf("expected -debug all to lower the global level, got %v (%v)", all.Level, err)
+	}
+
+	if _, err := (&Flags{Level: "loud"}).Config(); err == nil {
+		t.Error("expected an invalid level to fail")
+	}
+}
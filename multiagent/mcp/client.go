@@ -0,0 +1,224 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+var logger = logging.For("mcp")
+
+// clientInfo identifies wikillm to MCP servers
+var clientInfo = Implementation{Name: "wikillm", Version: "1.0.0"}
+
+// ClientConfig describes how to reach an MCP server: either a command to
+// launch and talk to over stdio, or the URL of a streamable HTTP endpoint
+type ClientConfig struct {
+	// Name identifies the server and prefixes its tool names ("<name>_<tool>")
+	// so tools from different servers don't collide
+	Name string
+
+	Command string
+	Args    []string
+	// Env adds variables to the server process's environment
+	Env map[string]string
+	Dir string
+
+	URL string
+	// Headers are sent with every HTTP request, e.g. Authorization
+	Headers map[string]string
+
+	// Timeout bounds each request (default 60s)
+	Timeout time.Duration
+}
+
+// Client is a connection to one MCP server
+type Client struct {
+	config     ClientConfig
+	transport  transport
+	nextID     atomic.Int64
+	serverInfo Implementation
+}
+
+// NewClient creates a client for the server described by config; call
+// Connect before using it
+func NewClient(config ClientConfig) (*Client, error) {
+	if (config.Command == "") == (config.URL == "") {
+		return nil, fmt.Errorf("mcp server %q needs exactly one of a command or a URL", config.Name)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 60 * time.Second
+	}
+	return &Client{config: config}, nil
+}
+
+// Connect starts or reaches the server and performs the initialize handshake
+func (c *Client) Connect(ctx context.Context) error {
+	var err error
+	if c.config.Command != "" {
+		c.transport, err = c.startProcess()
+	} else {
+		c.transport = &httpTransport{client: &http.Client{}, url: c.config.URL, headers: c.config.Headers}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := c.initialize(ctx); err != nil {
+		c.transport.close()
+		return fmt.Errorf("failed to initialize mcp server %q: %w", c.config.Name, err)
+	}
+	logger.InfoContext(ctx, "Connected to MCP server", "server", c.config.Name, "implementation", c.serverInfo.Name, "version", c.serverInfo.Version)
+	return nil
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	var result initializeResult
+	err := c.call(ctx, "initialize", initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      clientInfo,
+	}, &result)
+	if err != nil {
+		return err
+	}
+	c.serverInfo = result.ServerInfo
+	return c.transport.notify(ctx, &message{JSONRPC: jsonRPCVersion, Method: "notifications/initialized"})
+}
+
+// startProcess launches the server command with its stdio as the transport
+func (c *Client) startProcess() (transport, error) {
+	cmd := exec.Command(c.config.Command, c.config.Args...)
+	cmd.Dir = c.config.Dir
+	cmd.Env = os.Environ()
+	for name, value := range c.config.Env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start mcp server %q: %w", c.config.Name, err)
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			logger.Debug("MCP server output", "server", c.config.Name, "line", scanner.Text())
+		}
+	}()
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	return newStdioTransport(stdout, stdin, func() error {
+		// Closing stdin asks the server to exit; give it a moment first
+		select {
+		case <-exited:
+		case <-time.After(5 * time.Second):
+			cmd.Process.Kill()
+			<-exited
+		}
+		return nil
+	}), nil
+}
+
+// ServerInfo returns the name and version the server reported
+func (c *Client) ServerInfo() Implementation {
+	return c.serverInfo
+}
+
+// ListTools returns every tool the server offers
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	var tools []ToolInfo
+	cursor := ""
+	for {
+		var result listToolsResult
+		if err := c.call(ctx, "tools/list", listToolsParams{Cursor: cursor}, &result); err != nil {
+			return nil, fmt.Errorf("failed to list tools: %w", err)
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool invokes a tool by its server-side name
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: args}, &result); err != nil {
+		return nil, fmt.Errorf("failed to call tool %s: %w", name, err)
+	}
+	return &result, nil
+}
+
+// Tools discovers the server's tools and adapts them for agents
+func (c *Client) Tools(ctx context.Context) ([]multiagent.Tool, error) {
+	infos, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tools := make([]multiagent.Tool, len(infos))
+	for i, info := range infos {
+		tools[i] = newTool(c, info)
+	}
+	return tools, nil
+}
+
+// Close disconnects from the server, stopping it if it was launched
+func (c *Client) Close() error {
+	if c.transport == nil {
+		return nil
+	}
+	return c.transport.close()
+}
+
+func (c *Client) call(ctx context.Context, method string, params, result interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal params: %w", err)
+	}
+	req := &message{
+		JSONRPC: jsonRPCVersion,
+		ID:      json.RawMessage(strconv.FormatInt(c.nextID.Add(1), 10)),
+		Method:  method,
+		Params:  data,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	resp, err := c.transport.roundTrip(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// When set, the test binary acts as a stdio MCP server
+const fakeServerEnv = "WIKILLM_FAKE_MCP_SERVER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) != "" {
+		serveFake(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeResponse answers the requests of a small weather server whose tool
+// list spans two pages
+func fakeResponse(req *message) *message {
+	reply := &message{JSONRPC: jsonRPCVersion, ID: req.ID}
+	var result interface{}
+	switch req.Method {
+	case "initialize":
+		result = initializeResult{ProtocolVersion: ProtocolVersion, ServerInfo: Implementation{Name: "weather", Version: "0.1"}}
+	case "tools/list":
+		var params listToolsParams
+		json.Unmarshal(req.Params, &params)
+		if params.Cursor == "" {
+			result = listToolsResult{
+				Tools:      []ToolInfo{{Name: "forecast", Description: "Get the forecast for a city", InputSchema: map[string]interface{}{"type": "object"}}},
+				NextCursor: "page2",
+			}
+		} else {
+			result = listToolsResult{Tools: []ToolInfo{{Name: "alerts", Title: "Weather alerts"}}}
+		}
+	case "tools/call":
+		var params callToolParams
+		json.Unmarshal(req.Params, &params)
+		if params.Name == "alerts" {
+			result = CallToolResult{Content: []Content{TextContent("alerts service is down")}, IsError: true}
+		} else {
+			result = CallToolResult{Content: []Content{
+				TextContent(fmt.Sprintf("Sunny in %v", params.Arguments["city"])),
+				{Type: "image", MimeType: "image/png", Data: "iVBORw0KGgo="},
+			}}
+		}
+	default:
+		reply.Error = &RPCError{Code: codeMethodNotFound, Message: "unknown method " + req.Method}
+		return reply
+	}
+	reply.Result, _ = json.Marshal(result)
+	return reply
+}
+
+func serveFake(r io.Reader, w io.Writer) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var req message
+		if json.Unmarshal(scanner.Bytes(), &req) != nil || len(req.ID) == 0 {
+			continue
+		}
+		data, _ := json.Marshal(fakeResponse(&req))
+		fmt.Fprintf(w, "%s\n", data)
+	}
+}
+
+func assertWeatherTools(t *testing.T, client *Client) {
+	t.Helper()
+	ctx := context.Background()
+	if got := client.ServerInfo().Name; got != "weather" {
+		t.Errorf("expected server info from initialize, got %q", got)
+	}
+
+	tools, err := client.Tools(ctx)
+	if err != nil {
+		t.Fatalf("Tools: %v", err)
+	}
+	if len(tools) != 2 || tools[0].Name() != "weather_forecast" || tools[1].Description() != "Weather alerts" {
+		t.Fatalf("expected both pages of prefixed tools, got %d", len(tools))
+	}
+
+	output, err := tools[0].Execute(ctx, `{"city": "Oslo"}`)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if output != "Sunny in Oslo\n[image image/png content omitted]" {
+		t.Errorf("unexpected output %q", output)
+	}
+	if _, err := tools[1].Execute(ctx, ""); err == nil || !strings.Contains(err.Error(), "alerts service is down") {
+		t.Errorf("expected the tool's error result to surface, got %v", err)
+	}
+}
+
+func TestClientStdio(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Executable: %v", err)
+	}
+	client, err := NewClient(ClientConfig{
+		Name:    "weather",
+		Command: executable,
+		Env:     map[string]string{fakeServerEnv: "1"},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer client.Close()
+
+	assertWeatherTools(t, client)
+}
+
+func TestClientHTTPEventStream(t *testing.T) {
+	var sessions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			sessions = append(sessions, "closed:"+r.Header.Get(sessionHeader))
+			return
+		}
+		var req message
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Method == "initialize" {
+			w.Header().Set(sessionHeader, "session-1")
+		} else {
+			sessions = append(sessions, r.Header.Get(sessionHeader))
+		}
+		if len(req.ID) == 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		data, _ := json.Marshal(fakeResponse(&req))
+		if req.Method == "tools/call" {
+			// Answer calls as a stream with a progress notification first
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ClientConfig{Name: "weather", URL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	assertWeatherTools(t, client)
+	client.Close()
+
+	for _, session := range sessions[:len(sessions)-1] {
+		if session != "session-1" {
+			t.Errorf("expected every request to carry the session, got %v", sessions)
+			break
+		}
+	}
+	if sessions[len(sessions)-1] != "closed:session-1" {
+		t.Errorf("expected Close to end the session, got %v", sessions)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.json")
+	os.WriteFile(path, []byte(`{"mcpServers": {
+		"notes": {"url": "http://localhost:8931/mcp", "headers": {"Authorization": "Bearer t"}},
+		"github": {"command": "github-mcp-server", "args": ["stdio"], "env": {"GITHUB_TOKEN": "x"}}
+	}}`), 0644)
+
+	configs, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(configs) != 2 || configs[0].Name != "github" || configs[0].Args[0] != "stdio" || configs[1].URL == "" {
+		t.Errorf("unexpected configs %+v", configs)
+	}
+	if _, err := NewClient(ClientConfig{Name: "both", Command: "x", URL: "http://y"}); err == nil {
+		t.Error("expected a config with both a command and a URL to be rejected")
+	}
+}
@@ -0,0 +1,56 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// serverEntry is one server in an "mcpServers" config file
+type serverEntry struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Env     map[string]string `json:"env"`
+	Cwd     string            `json:"cwd"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+}
+
+// LoadConfig reads MCP servers from a JSON file in the format used by most
+// MCP hosts:
+//
+//	{"mcpServers": {"github": {"command": "github-mcp", "args": ["stdio"]}}}
+func LoadConfig(path string) ([]ClientConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mcp config: %w", err)
+	}
+	var file struct {
+		MCPServers map[string]serverEntry `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse mcp config: %w", err)
+	}
+
+	names := make([]string, 0, len(file.MCPServers))
+	for name := range file.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	configs := make([]ClientConfig, 0, len(names))
+	for _, name := range names {
+		entry := file.MCPServers[name]
+		configs = append(configs, ClientConfig{
+			Name:    name,
+			Command: entry.Command,
+			Args:    entry.Args,
+			Env:     entry.Env,
+			Dir:     entry.Cwd,
+			URL:     entry.URL,
+			Headers: entry.Headers,
+		})
+	}
+	return configs, nil
+}
@@ -0,0 +1,235 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// ServerConfig configures an MCP server
+type ServerConfig struct {
+	Name    string
+	Version string
+	// Instructions tell clients how to use the server's tools
+	Instructions string
+	Tools        []multiagent.Tool
+	// Token, if set, is the bearer token HTTP clients must present
+	Token string
+	// ToolContext, if set, derives the context each tool call runs with,
+	// e.g. to act for a particular user
+	ToolContext func(ctx context.Context) context.Context
+}
+
+// Server exposes multiagent tools to MCP clients over stdio or HTTP
+type Server struct {
+	config ServerConfig
+	tools  map[string]multiagent.Tool
+}
+
+// NewServer creates a server offering config.Tools
+func NewServer(config ServerConfig) *Server {
+	if config.Name == "" {
+		config.Name = clientInfo.Name
+	}
+	if config.Version == "" {
+		config.Version = clientInfo.Version
+	}
+	tools := make(map[string]multiagent.Tool, len(config.Tools))
+	for _, tool := range config.Tools {
+		tools[tool.Name()] = tool
+	}
+	return &Server{config: config, tools: tools}
+}
+
+// ServeStdio answers newline-delimited requests from r on w until r ends or
+// ctx is done; requests are handled concurrently
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	var writeMu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	write := func(msg *message) {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			logger.Error("Failed to marshal MCP response", "error", err)
+			return
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		w.Write(append(data, '\n'))
+	}
+
+	lines := make(chan []byte)
+	scanErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+		for scanner.Scan() {
+			select {
+			case lines <- append([]byte(nil), scanner.Bytes()...):
+			case <-ctx.Done():
+				return
+			}
+		}
+		scanErr <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				return <-scanErr
+			}
+			if len(strings.TrimSpace(string(line))) == 0 {
+				continue
+			}
+			var msg message
+			if err := json.Unmarshal(line, &msg); err != nil {
+				write(&message{JSONRPC: jsonRPCVersion, ID: json.RawMessage("null"), Error: &RPCError{Code: codeParseError, Message: err.Error()}})
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if reply := s.handle(ctx, &msg); reply != nil {
+					write(reply)
+				}
+			}()
+		}
+	}
+}
+
+// ServeHTTP serves the streamable HTTP transport; every response is plain
+// JSON since the server never streams
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.config.Token != "" {
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(s.config.Token)) != 1 {
+			http.Error(w, "invalid or missing bearer token", http.StatusUnauthorized)
+			return
+		}
+	}
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		// Sessions aren't tracked, so there is nothing to end
+		return
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var msg message
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		writeMessage(w, http.StatusBadRequest, &message{JSONRPC: jsonRPCVersion, ID: json.RawMessage("null"), Error: &RPCError{Code: codeParseError, Message: err.Error()}})
+		return
+	}
+	reply := s.handle(r.Context(), &msg)
+	if reply == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeMessage(w, http.StatusOK, reply)
+}
+
+func writeMessage(w http.ResponseWriter, status int, msg *message) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(msg)
+}
+
+// handle answers one request; notifications and stray responses get nil
+func (s *Server) handle(ctx context.Context, req *message) *message {
+	if len(req.ID) == 0 || req.Method == "" {
+		return nil
+	}
+
+	reply := &message{JSONRPC: jsonRPCVersion, ID: req.ID}
+	var result interface{}
+	var err *RPCError
+	switch req.Method {
+	case "initialize":
+		result = initializeResult{
+			ProtocolVersion: ProtocolVersion,
+			Capabilities:    map[string]interface{}{"tools": map[string]interface{}{}},
+			ServerInfo:      Implementation{Name: s.config.Name, Version: s.config.Version},
+			Instructions:    s.config.Instructions,
+		}
+	case "ping":
+		result = struct{}{}
+	case "tools/list":
+		result = listToolsResult{Tools: s.toolInfos()}
+	case "tools/call":
+		result, err = s.callTool(ctx, req.Params)
+	default:
+		err = &RPCError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+
+	if err != nil {
+		reply.Error = err
+		return reply
+	}
+	data, marshalErr := json.Marshal(result)
+	if marshalErr != nil {
+		reply.Error = &RPCError{Code: codeInternalError, Message: marshalErr.Error()}
+		return reply
+	}
+	reply.Result = data
+	return reply
+}
+
+func (s *Server) toolInfos() []ToolInfo {
+	infos := make([]ToolInfo, 0, len(s.config.Tools))
+	for _, tool := range s.config.Tools {
+		infos = append(infos, ToolInfo{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			InputSchema: tool.Parameters(),
+		})
+	}
+	return infos
+}
+
+// callTool runs a tool; its failures are reported in the result, as MCP
+// expects, so the calling model can see and react to them
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (*CallToolResult, *RPCError) {
+	var call callToolParams
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, &RPCError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid tools/call params: %v", err)}
+	}
+	tool, ok := s.tools[call.Name]
+	if !ok {
+		return nil, &RPCError{Code: codeInvalidParams, Message: "unknown tool: " + call.Name}
+	}
+
+	args := "{}"
+	if call.Arguments != nil {
+		data, err := json.Marshal(call.Arguments)
+		if err != nil {
+			return nil, &RPCError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid arguments: %v", err)}
+		}
+		args = string(data)
+	}
+
+	if s.config.ToolContext != nil {
+		ctx = s.config.ToolContext(ctx)
+	}
+	output, err := tool.Execute(ctx, args)
+	if err != nil {
+		logger.WarnContext(ctx, "MCP tool call failed", "tool", call.Name, "error", err)
+		return &CallToolResult{Content: []Content{TextContent(err.Error())}, IsError: true}, nil
+	}
+	return &CallToolResult{Content: []Content{TextContent(output)}}, nil
+}
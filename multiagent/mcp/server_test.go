@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+type upperTool struct{}
+
+func (upperTool) Name() string        { return "upper" }
+func (upperTool) Description() string { return "Uppercases text" }
+func (upperTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}}}
+}
+func (upperTool) Execute(ctx context.Context, args string) (string, error) {
+	if !strings.Contains(args, "text") {
+		return "", errors.New("text is required")
+	}
+	return strings.ToUpper(args), nil
+}
+
+func newTestServer() *Server {
+	return NewServer(ServerConfig{Name: "wikillm", Tools: []multiagent.Tool{upperTool{}}, Token: "s3cret"})
+}
+
+func assertUpperTool(t *testing.T, client *Client) {
+	t.Helper()
+	ctx := context.Background()
+	if got := client.ServerInfo().Name; got != "wikillm" {
+		t.Errorf("expected server name wikillm, got %q", got)
+	}
+
+	infos, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(infos) != 1 || infos[0].Name != "upper" || infos[0].InputSchema["type"] != "object" {
+		t.Fatalf("unexpected tools %+v", infos)
+	}
+
+	result, err := client.CallTool(ctx, "upper", map[string]interface{}{"text": "hi"})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if result.IsError || result.Content[0].Text != `{"TEXT":"HI"}` {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	result, err = client.CallTool(ctx, "upper", nil)
+	if err != nil || !result.IsError || result.Content[0].Text != "text is required" {
+		t.Errorf("expected the tool error in the result, got %+v, %v", result, err)
+	}
+
+	var rpcErr *RPCError
+	if _, err := client.CallTool(ctx, "missing", nil); !errors.As(err, &rpcErr) || rpcErr.Code != codeInvalidParams {
+		t.Errorf("expected an invalid params error for an unknown tool, got %v", err)
+	}
+}
+
+func TestServerStdio(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- newTestServer().ServeStdio(ctx, serverIn, serverOut) }()
+
+	client, _ := NewClient(ClientConfig{Name: "wikillm", Command: "unused"})
+	client.transport = newStdioTransport(clientIn, clientOut, nil)
+	if err := client.initialize(ctx); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	assertUpperTool(t, client)
+
+	client.Close()
+	if err := <-done; err != nil {
+		t.Errorf("expected ServeStdio to end cleanly when input closes, got %v", err)
+	}
+}
+
+func TestServerHTTP(t *testing.T) {
+	server := httptest.NewServer(newTestServer())
+	defer server.Close()
+
+	client, _ := NewClient(ClientConfig{Name: "wikillm", URL: server.URL, Headers: map[string]string{"Authorization": "Bearer s3cret"}})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer client.Close()
+	assertUpperTool(t, client)
+
+	unauthorized, _ := NewClient(ClientConfig{Name: "wikillm", URL: server.URL})
+	if err := unauthorized.Connect(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected a client without the token to be rejected, got %v", err)
+	}
+}
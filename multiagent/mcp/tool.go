@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Tool exposes one MCP server tool as a multiagent.Tool
+type Tool struct {
+	client *Client
+	info   ToolInfo
+	name   string
+}
+
+func newTool(client *Client, info ToolInfo) *Tool {
+	name := info.Name
+	if client.config.Name != "" {
+		name = client.config.Name + "_" + info.Name
+	}
+	return &Tool{client: client, info: info, name: name}
+}
+
+// Name returns the tool's name, prefixed with its server's name
+func (t *Tool) Name() string {
+	return t.name
+}
+
+// Description returns the description the server gave the tool
+func (t *Tool) Description() string {
+	if t.info.Description != "" {
+		return t.info.Description
+	}
+	return t.info.Title
+}
+
+// Parameters returns the tool's JSON Schema input
+func (t *Tool) Parameters() map[string]interface{} {
+	if t.info.InputSchema == nil {
+		return map[string]interface{}{"type": "object"}
+	}
+	return t.info.InputSchema
+}
+
+// Execute calls the tool with a JSON object of arguments and returns its
+// text output; a result the server flags as an error becomes an error
+func (t *Tool) Execute(ctx context.Context, args string) (string, error) {
+	var arguments map[string]interface{}
+	if trimmed := strings.TrimSpace(args); trimmed != "" {
+		if err := json.Unmarshal([]byte(trimmed), &arguments); err != nil {
+			return "", fmt.Errorf("failed to parse JSON arguments: %w", err)
+		}
+	}
+
+	result, err := t.client.CallTool(ctx, t.info.Name, arguments)
+	if err != nil {
+		return "", err
+	}
+	output := renderContent(result)
+	if result.IsError {
+		return "", fmt.Errorf("tool %s failed: %s", t.name, output)
+	}
+	return output, nil
+}
+
+// renderContent flattens a result to text, noting non-text items
+func renderContent(result *CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		switch content.Type {
+		case "text":
+			parts = append(parts, content.Text)
+		default:
+			parts = append(parts, fmt.Sprintf("[%s content omitted]", strings.TrimSpace(content.Type+" "+content.MimeType)))
+		}
+	}
+	if len(parts) == 0 && result.StructuredContent != nil {
+		if data, err := json.Marshal(result.StructuredContent); err == nil {
+			return string(data)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,292 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// transport carries JSON-RPC messages to an MCP server
+type transport interface {
+	// roundTrip sends a request and waits for the response with its ID
+	roundTrip(ctx context.Context, req *message) (*message, error)
+	// notify sends a notification, which has no response
+	notify(ctx context.Context, msg *message) error
+	close() error
+}
+
+// maxLineSize bounds one newline-delimited message on stdio
+const maxLineSize = 16 << 20
+
+// stdioTransport exchanges newline-delimited JSON with a subprocess
+type stdioTransport struct {
+	writeMu sync.Mutex
+	w       io.WriteCloser
+	onClose func() error
+
+	mu      sync.Mutex
+	pending map[string]chan *message
+	done    chan struct{}
+	err     error
+}
+
+func newStdioTransport(r io.Reader, w io.WriteCloser, onClose func() error) *stdioTransport {
+	t := &stdioTransport{
+		w:       w,
+		onClose: onClose,
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+	}
+	go t.readLoop(r)
+	return t
+}
+
+func (t *stdioTransport) readLoop(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			logger.Warn("Ignoring malformed MCP message", "error", err)
+			continue
+		}
+
+		switch {
+		case msg.isResponse():
+			t.mu.Lock()
+			ch, ok := t.pending[string(msg.ID)]
+			delete(t.pending, string(msg.ID))
+			t.mu.Unlock()
+			if ok {
+				ch <- &msg
+			}
+		case len(msg.ID) > 0:
+			t.answerServerRequest(&msg)
+		default:
+			logger.Debug("Received MCP notification", "method", msg.Method)
+		}
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	t.mu.Lock()
+	t.err = fmt.Errorf("mcp server connection closed: %w", err)
+	t.mu.Unlock()
+	close(t.done)
+}
+
+// answerServerRequest replies to requests the server sends the client;
+// only ping is supported
+func (t *stdioTransport) answerServerRequest(req *message) {
+	reply := &message{JSONRPC: jsonRPCVersion, ID: req.ID}
+	if req.Method == "ping" {
+		reply.Result = json.RawMessage("{}")
+	} else {
+		reply.Error = &RPCError{Code: codeMethodNotFound, Message: "method not supported by client: " + req.Method}
+	}
+	if err := t.write(reply); err != nil {
+		logger.Warn("Failed to answer MCP server request", "method", req.Method, "error", err)
+	}
+}
+
+func (t *stdioTransport) roundTrip(ctx context.Context, req *message) (*message, error) {
+	ch := make(chan *message, 1)
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return nil, t.err
+	}
+	t.pending[string(req.ID)] = ch
+	t.mu.Unlock()
+
+	if err := t.write(req); err != nil {
+		t.forget(req.ID)
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-ctx.Done():
+		t.forget(req.ID)
+		return nil, ctx.Err()
+	case <-t.done:
+		return nil, t.err
+	}
+}
+
+func (t *stdioTransport) notify(ctx context.Context, msg *message) error {
+	return t.write(msg)
+}
+
+func (t *stdioTransport) write(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to mcp server: %w", err)
+	}
+	return nil
+}
+
+func (t *stdioTransport) forget(id json.RawMessage) {
+	t.mu.Lock()
+	delete(t.pending, string(id))
+	t.mu.Unlock()
+}
+
+func (t *stdioTransport) close() error {
+	err := t.w.Close()
+	if t.onClose != nil {
+		if closeErr := t.onClose(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// httpTransport speaks the streamable HTTP transport: every message is a
+// POST, answered with either JSON or a short server-sent event stream
+type httpTransport struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+const sessionHeader = "Mcp-Session-Id"
+
+func (t *httpTransport) roundTrip(ctx context.Context, req *message) (*message, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readEventStreamResponse(resp.Body, req.ID)
+	}
+	var reply message
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("failed to decode mcp response: %w", err)
+	}
+	return &reply, nil
+}
+
+func (t *httpTransport) notify(ctx context.Context, msg *message) error {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (t *httpTransport) post(ctx context.Context, msg *message) (*http.Response, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	t.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach mcp server: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("mcp server returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if sessionID := resp.Header.Get(sessionHeader); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) setHeaders(req *http.Request) {
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("MCP-Protocol-Version", ProtocolVersion)
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set(sessionHeader, t.sessionID)
+	}
+	t.mu.Unlock()
+}
+
+// close ends the session, if the server assigned one
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	t.setHeaders(req)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// readEventStreamResponse reads server-sent events until the response to id
+func readEventStreamResponse(r io.Reader, id json.RawMessage) (*message, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(value, " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+
+		var msg message
+		err := json.Unmarshal([]byte(data.String()), &msg)
+		data.Reset()
+		if err == nil && msg.isResponse() && bytes.Equal(msg.ID, id) {
+			return &msg, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mcp event stream: %w", err)
+	}
+	return nil, fmt.Errorf("mcp event stream ended without a response")
+}
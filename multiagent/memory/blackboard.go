@@ -0,0 +1,201 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// BlackboardSection groups related blackboard entries
+type BlackboardSection string
+
+const (
+	BlackboardFacts         BlackboardSection = "facts"          // Things learned, e.g. a deadline
+	BlackboardGoals         BlackboardSection = "goals"          // What the user is trying to achieve
+	BlackboardEntities      BlackboardSection = "entities"       // People, projects, places mentioned
+	BlackboardOpenQuestions BlackboardSection = "open_questions" // Details still to ask the user
+)
+
+// DefaultBlackboardLimit caps entries per conversation; the oldest are dropped first
+const DefaultBlackboardLimit = 200
+
+// BlackboardEntry is one item of shared conversation context
+type BlackboardEntry struct {
+	Section BlackboardSection `json:"section"`
+	// Key identifies the entry within its section; appending an entry with
+	// the same section and key replaces the earlier one
+	Key        string             `json:"key,omitempty"`
+	Value      string             `json:"value"`
+	Source     multiagent.AgentID `json:"source"`
+	RecordedAt time.Time          `json:"recorded_at"`
+}
+
+// BlackboardSnapshot is the blackboard's content at one point in time
+type BlackboardSnapshot struct {
+	ConversationID string            `json:"conversation_id"`
+	Entries        []BlackboardEntry `json:"entries"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// Section returns the entries of one section, oldest first
+func (s BlackboardSnapshot) Section(section BlackboardSection) []BlackboardEntry {
+	var entries []BlackboardEntry
+	for _, entry := range s.Entries {
+		if entry.Section == section {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Lookup finds an entry by section and key
+func (s BlackboardSnapshot) Lookup(section BlackboardSection, key string) (BlackboardEntry, bool) {
+	for _, entry := range s.Entries {
+		if entry.Section == section && entry.Key == key {
+			return entry, true
+		}
+	}
+	return BlackboardEntry{}, false
+}
+
+// String renders the snapshot for inclusion in an LLM prompt
+func (s BlackboardSnapshot) String() string {
+	if len(s.Entries) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, section := range []BlackboardSection{BlackboardGoals, BlackboardFacts, BlackboardEntities, BlackboardOpenQuestions} {
+		entries := s.Section(section)
+		if len(entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:\n", strings.ReplaceAll(string(section), "_", " "))
+		for _, entry := range entries {
+			fmt.Fprintf(&b, "- %s (from %s)\n", entry.Value, entry.Source)
+		}
+	}
+	return b.String()
+}
+
+// Blackboard is the shared context for one conversation: facts, goals,
+// entities, and open questions that every specialist reads and appends to
+type Blackboard struct {
+	store          multiagent.MemoryStore
+	conversationID string
+	limit          int
+}
+
+// NewBlackboard creates a blackboard for conversationID backed by store
+func NewBlackboard(store multiagent.MemoryStore, conversationID string) *Blackboard {
+	return &Blackboard{
+		store:          store,
+		conversationID: conversationID,
+		limit:          DefaultBlackboardLimit,
+	}
+}
+
+// Key returns the memory key the blackboard is stored under; it lives in the
+// conversation scope so every agent may write it
+func (b *Blackboard) Key() string {
+	return ConversationKey(b.conversationID, "blackboard")
+}
+
+// Read returns the current content; a blackboard nobody has written is empty
+func (b *Blackboard) Read(ctx context.Context) (BlackboardSnapshot, error) {
+	value, err := b.store.Get(ctx, b.Key())
+	if err != nil {
+		return BlackboardSnapshot{ConversationID: b.conversationID}, nil
+	}
+	return decodeBlackboard(value, b.conversationID)
+}
+
+// Append adds entries, replacing existing entries with the same section and key
+func (b *Blackboard) Append(ctx context.Context, entries ...BlackboardEntry) error {
+	now := time.Now()
+	for i := range entries {
+		if entries[i].RecordedAt.IsZero() {
+			entries[i].RecordedAt = now
+		}
+	}
+
+	return b.update(ctx, func(snapshot *BlackboardSnapshot) {
+		for _, entry := range entries {
+			if entry.Key != "" {
+				snapshot.remove(entry.Section, entry.Key)
+			}
+			snapshot.Entries = append(snapshot.Entries, entry)
+		}
+		if overflow := len(snapshot.Entries) - b.limit; overflow > 0 {
+			snapshot.Entries = snapshot.Entries[overflow:]
+		}
+	})
+}
+
+// Remove deletes an entry, e.g. an open question once it has been answered
+func (b *Blackboard) Remove(ctx context.Context, section BlackboardSection, key string) error {
+	return b.update(ctx, func(snapshot *BlackboardSnapshot) {
+		snapshot.remove(section, key)
+	})
+}
+
+func (b *Blackboard) update(ctx context.Context, apply func(*BlackboardSnapshot)) error {
+	updater := func(current interface{}) (interface{}, error) {
+		snapshot, err := decodeBlackboard(current, b.conversationID)
+		if err != nil {
+			return nil, err
+		}
+		apply(&snapshot)
+		snapshot.UpdatedAt = time.Now()
+		return snapshot, nil
+	}
+
+	// Update is atomic but needs an existing key; create an empty board first
+	if _, err := b.store.Get(ctx, b.Key()); err != nil {
+		empty := BlackboardSnapshot{ConversationID: b.conversationID, UpdatedAt: time.Now()}
+		if err := b.store.Store(ctx, b.Key(), empty); err != nil {
+			return fmt.Errorf("failed to create blackboard: %w", err)
+		}
+	}
+	if err := b.store.Update(ctx, b.Key(), updater); err != nil {
+		return fmt.Errorf("failed to update blackboard: %w", err)
+	}
+	return nil
+}
+
+func (s *BlackboardSnapshot) remove(section BlackboardSection, key string) {
+	kept := make([]BlackboardEntry, 0, len(s.Entries))
+	for _, entry := range s.Entries {
+		if entry.Section != section || entry.Key != key {
+			kept = append(kept, entry)
+		}
+	}
+	s.Entries = kept
+}
+
+// decodeBlackboard converts a stored value, which may have round-tripped
+// through JSON as a map, back into a snapshot
+func decodeBlackboard(value interface{}, conversationID string) (BlackboardSnapshot, error) {
+	if snapshot, ok := value.(BlackboardSnapshot); ok {
+		// Copy so updates never alias the stored value
+		snapshot.Entries = append([]BlackboardEntry(nil), snapshot.Entries...)
+		return snapshot, nil
+	}
+
+	var snapshot BlackboardSnapshot
+	data, err := json.Marshal(value)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to marshal blackboard: %w", err)
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("failed to unmarshal blackboard: %w", err)
+	}
+	if snapshot.ConversationID == "" {
+		snapshot.ConversationID = conversationID
+	}
+	return snapshot, nil
+}
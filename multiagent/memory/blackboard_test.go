@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBlackboard_SharedAcrossScopedAgents(t *testing.T) {
+	ctx := context.Background()
+	base := newTestSQLiteStore(t)
+	policy := DefaultNamespacePolicy()
+
+	projects := NewBlackboard(NewScopedMemoryStore(base, "project_manager_agent", policy), "conv_1")
+	scheduler := NewBlackboard(NewScopedMemoryStore(base, "scheduler_agent", policy), "conv_1")
+
+	empty, err := scheduler.Read(ctx)
+	if err != nil || len(empty.Entries) != 0 {
+		t.Fatalf("expected empty blackboard, got %+v, %v", empty, err)
+	}
+
+	if err := projects.Append(ctx,
+		BlackboardEntry{Section: BlackboardFacts, Key: "project:launch:deadline", Value: "Launch is due 2025-03-01", Source: "project_manager_agent"},
+		BlackboardEntry{Section: BlackboardEntities, Key: "project:launch", Value: "Project: Launch", Source: "project_manager_agent"},
+	); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	// Re-recording a keyed fact replaces it rather than duplicating it
+	if err := projects.Append(ctx, BlackboardEntry{Section: BlackboardFacts, Key: "project:launch:deadline", Value: "Launch is due 2025-03-15", Source: "project_manager_agent"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := scheduler.Append(ctx, BlackboardEntry{Section: BlackboardOpenQuestions, Key: "meeting_length", Value: "How long is the review?", Source: "scheduler_agent"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	snapshot, err := scheduler.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(snapshot.Entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", snapshot.Entries)
+	}
+	deadline, ok := snapshot.Lookup(BlackboardFacts, "project:launch:deadline")
+	if !ok || deadline.Value != "Launch is due 2025-03-15" || deadline.RecordedAt.IsZero() {
+		t.Fatalf("unexpected deadline entry %+v", deadline)
+	}
+	if rendered := snapshot.String(); !strings.Contains(rendered, "open questions:") || !strings.Contains(rendered, "2025-03-15") {
+		t.Fatalf("unexpected rendering:\n%s", rendered)
+	}
+
+	if err := scheduler.Remove(ctx, BlackboardOpenQuestions, "meeting_length"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	snapshot, _ = projects.Read(ctx)
+	if len(snapshot.Section(BlackboardOpenQuestions)) != 0 {
+		t.Fatalf("expected open question removed, got %+v", snapshot.Entries)
+	}
+
+	other, _ := NewBlackboard(base, "conv_2").Read(ctx)
+	if len(other.Entries) != 0 {
+		t.Fatalf("expected conversations to be isolated, got %+v", other.Entries)
+	}
+}
+
+func TestBlackboard_DropsOldestPastLimit(t *testing.T) {
+	ctx := context.Background()
+	board := NewBlackboard(newTestSQLiteStore(t), "conv_1")
+	board.limit = 2
+
+	for _, value := range []string{"a", "b", "c"} {
+		if err := board.Append(ctx, BlackboardEntry{Section: BlackboardFacts, Value: value}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	snapshot, _ := board.Read(ctx)
+	if len(snapshot.Entries) != 2 || snapshot.Entries[0].Value != "b" {
+		t.Fatalf("expected oldest entry dropped, got %+v", snapshot.Entries)
+	}
+}
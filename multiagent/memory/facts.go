@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// FactKeyPrefix holds the facts learned about a user from their
+// conversations, one per key in the user's partition
+const FactKeyPrefix = "fact:"
+
+// FactKind says what a fact is about
+type FactKind string
+
+const (
+	FactPreference FactKind = "preference" // Likes, dislikes and ways of working
+	FactName       FactKind = "name"       // People, pets and places in the user's life
+	FactCommitment FactKind = "commitment" // Something the user promised or plans to do
+	FactDate       FactKind = "date"       // Birthdays, anniversaries and other dates to remember
+)
+
+// FactKinds lists every kind of fact, in the order they are explained to
+// the LLM
+var FactKinds = []FactKind{FactPreference, FactName, FactCommitment, FactDate}
+
+// FactStatus is where a fact is in the user's review; only approved facts
+// are given to agents
+type FactStatus string
+
+const (
+	FactPending  FactStatus = "pending"
+	FactApproved FactStatus = "approved"
+	FactRejected FactStatus = "rejected"
+)
+
+// FactSource is where a fact was learned
+type FactSource struct {
+	ConversationID string `json:"conversation_id"`
+	// Quote is the user's message the fact was taken from
+	Quote  string    `json:"quote"`
+	SaidAt time.Time `json:"said_at"`
+}
+
+// Fact is a durable statement about a user, such as "Prefers meetings in
+// the morning", mined from a conversation
+type Fact struct {
+	ID          string     `json:"id"`
+	Kind        FactKind   `json:"kind"`
+	Statement   string     `json:"statement"`
+	Status      FactStatus `json:"status"`
+	Source      FactSource `json:"source"`
+	ExtractedAt time.Time  `json:"extracted_at"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// ValidFactKind reports whether kind is one of FactKinds
+func ValidFactKind(kind FactKind) bool {
+	for _, known := range FactKinds {
+		if kind == known {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveFact stores fact in the acting user's partition
+func SaveFact(ctx context.Context, store multiagent.MemoryStore, fact Fact) error {
+	if fact.ID == "" {
+		return fmt.Errorf("fact ID is required")
+	}
+	if err := store.Store(ctx, FactKeyPrefix+fact.ID, fact); err != nil {
+		return fmt.Errorf("failed to save fact: %w", err)
+	}
+	return nil
+}
+
+// LoadFacts returns the acting user's facts with status, or all of them if
+// status is empty, oldest first
+func LoadFacts(ctx context.Context, store multiagent.MemoryStore, status FactStatus) ([]Fact, error) {
+	keys, err := store.List(ctx, FactKeyPrefix, 10000)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list facts: %w", err)
+	}
+	if len(keys) == 0 {
+		return []Fact{}, nil
+	}
+	values, err := store.GetMultiple(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load facts: %w", err)
+	}
+
+	facts := make([]Fact, 0, len(values))
+	for _, value := range values {
+		fact, err := decodeFact(value)
+		if err != nil || fact.ID == "" {
+			continue
+		}
+		if status == "" || fact.Status == status {
+			facts = append(facts, fact)
+		}
+	}
+	sort.Slice(facts, func(i, j int) bool {
+		if !facts[i].ExtractedAt.Equal(facts[j].ExtractedAt) {
+			return facts[i].ExtractedAt.Before(facts[j].ExtractedAt)
+		}
+		return facts[i].ID < facts[j].ID
+	})
+	return facts, nil
+}
+
+// ReviewFact records the user's verdict on a fact, approved or rejected.
+// Rejected facts are kept so they are not proposed again.
+func ReviewFact(ctx context.Context, store multiagent.MemoryStore, id string, status FactStatus, at time.Time) (Fact, error) {
+	var fact Fact
+	if status != FactApproved && status != FactRejected {
+		return fact, fmt.Errorf("invalid fact review %q: use %s or %s", status, FactApproved, FactRejected)
+	}
+	value, err := store.Get(ctx, FactKeyPrefix+id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return fact, fmt.Errorf("fact %s not found", id)
+		}
+		return fact, fmt.Errorf("failed to load fact: %w", err)
+	}
+	if fact, err = decodeFact(value); err != nil {
+		return fact, err
+	}
+
+	fact.Status = status
+	fact.ReviewedAt = &at
+	if err := SaveFact(ctx, store, fact); err != nil {
+		return fact, err
+	}
+	return fact, nil
+}
+
+// decodeFact converts a fact read back from memory
+func decodeFact(value interface{}) (Fact, error) {
+	var fact Fact
+	if stored, ok := value.(Fact); ok {
+		return stored, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fact, fmt.Errorf("failed to marshal fact: %w", err)
+	}
+	if err := json.Unmarshal(data, &fact); err != nil {
+		return fact, fmt.Errorf("failed to unmarshal fact: %w", err)
+	}
+	return fact, nil
+}
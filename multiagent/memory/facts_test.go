@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+func TestFacts_ReviewPerUser(t *testing.T) {
+	store := PartitionByUser(newTestSQLiteStore(t))
+	alice := multiagent.WithUserID(context.Background(), "alice")
+	bob := multiagent.WithUserID(context.Background(), "bob")
+	extracted := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	for i, fact := range []Fact{
+		{ID: "fact_2", Kind: FactName, Statement: "Sister is named Maya", Status: FactPending, ExtractedAt: extracted.Add(time.Minute)},
+		{ID: "fact_1", Kind: FactPreference, Statement: "Prefers morning meetings", Status: FactPending, ExtractedAt: extracted,
+			Source: FactSource{ConversationID: "conv_alice", Quote: "I like my meetings before noon", SaidAt: extracted}},
+	} {
+		if err := SaveFact(alice, store, fact); err != nil {
+			t.Fatalf("SaveFact %d: %v", i, err)
+		}
+	}
+
+	pending, err := LoadFacts(alice, store, FactPending)
+	if err != nil {
+		t.Fatalf("LoadFacts: %v", err)
+	}
+	if len(pending) != 2 || pending[0].ID != "fact_1" || pending[0].Source.Quote != "I like my meetings before noon" {
+		t.Fatalf("pending facts %+v, want both oldest first with their source", pending)
+	}
+	if others, err := LoadFacts(bob, store, ""); err != nil || len(others) != 0 {
+		t.Errorf("bob's facts %+v (%v), want none", others, err)
+	}
+
+	reviewed := extracted.Add(time.Hour)
+	fact, err := ReviewFact(alice, store, "fact_1", FactApproved, reviewed)
+	if err != nil {
+		t.Fatalf("ReviewFact: %v", err)
+	}
+	if fact.Status != FactApproved || fact.ReviewedAt == nil || !fact.ReviewedAt.Equal(reviewed) {
+		t.Errorf("reviewed fact %+v", fact)
+	}
+	approved, _ := LoadFacts(alice, store, FactApproved)
+	if len(approved) != 1 || approved[0].Statement != "Prefers morning meetings" {
+		t.Errorf("approved facts %+v", approved)
+	}
+
+	if _, err := ReviewFact(alice, store, "fact_2", FactPending, reviewed); err == nil {
+		t.Error("expected a review back to pending to be rejected")
+	}
+	if _, err := ReviewFact(bob, store, "fact_2", FactRejected, reviewed); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("bob reviewing alice's fact: %v, want not found", err)
+	}
+}
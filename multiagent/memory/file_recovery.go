@@ -0,0 +1,376 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+const (
+	walFileName   = "_wal.log"
+	quarantineDir = "_quarantine"
+	tempPrefix    = ".tmp-"
+
+	walOpPut    = "put"
+	walOpDelete = "delete"
+)
+
+// walRecord is one line of the FileMemoryStore write-ahead log. Records are
+// appended and synced before entry files or the index are touched, and the
+// log is truncated once the index reflecting them has been saved.
+type walRecord struct {
+	Op    string                  `json:"op"`
+	Key   string                  `json:"key"`
+	Entry *multiagent.MemoryEntry `json:"entry,omitempty"`
+}
+
+// RecoveryReport describes what FileMemoryStore repaired on startup
+type RecoveryReport struct {
+	// Replayed is the number of WAL records re-applied
+	Replayed int `json:"replayed"`
+	// TornRecords counts trailing WAL lines that were cut off mid-write
+	TornRecords int `json:"torn_records"`
+	// IndexRebuilt is true when _index.json was unreadable
+	IndexRebuilt bool `json:"index_rebuilt"`
+	// Adopted lists keys found on disk but missing from the index
+	Adopted []string `json:"adopted,omitempty"`
+	// Missing lists indexed keys whose entry file no longer exists
+	Missing []string `json:"missing,omitempty"`
+	// Quarantined lists files moved aside because they could not be parsed
+	Quarantined []string `json:"quarantined,omitempty"`
+	// TempFilesRemoved counts leftovers from interrupted atomic writes
+	TempFilesRemoved int `json:"temp_files_removed"`
+}
+
+// Clean reports whether startup found nothing to repair
+func (r RecoveryReport) Clean() bool {
+	return r.Replayed == 0 && r.TornRecords == 0 && !r.IndexRebuilt &&
+		len(r.Adopted) == 0 && len(r.Missing) == 0 && len(r.Quarantined) == 0 &&
+		r.TempFilesRemoved == 0
+}
+
+// RecoveryReport returns what was repaired when the store was opened
+func (s *FileMemoryStore) RecoveryReport() RecoveryReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.recovery
+}
+
+// Close stops the cleanup routine, flushes and closes the write-ahead log,
+// then waits for access-stat writes already under way so none land after it
+// returns
+func (s *FileMemoryStore) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	s.cleanup.Wait()
+
+	s.mu.Lock()
+	if s.wal == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	err := s.wal.Close()
+	s.wal = nil
+	s.mu.Unlock()
+
+	s.pending.Wait()
+	return err
+}
+
+// recover replays the WAL, then runs fsck over the entry files
+func (s *FileMemoryStore) recover() error {
+	if s.index == nil {
+		s.index = make(map[string]*indexEntry)
+	}
+	if s.tagIndex == nil {
+		s.tagIndex = make(map[string][]string)
+	}
+
+	if err := s.replayWAL(); err != nil {
+		return err
+	}
+	if err := s.fsck(); err != nil {
+		return err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(s.baseDir, walFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	s.wal = wal
+
+	if !s.recovery.Clean() {
+		if err := s.checkpoint(); err != nil {
+			return err
+		}
+		logger.Warn("FileMemoryStore recovered", "dir", s.baseDir,
+			"replayed", s.recovery.Replayed, "torn", s.recovery.TornRecords, "rebuilt", s.recovery.IndexRebuilt,
+			"adopted", len(s.recovery.Adopted), "missing", len(s.recovery.Missing),
+			"quarantined", len(s.recovery.Quarantined), "temp_files", s.recovery.TempFilesRemoved)
+	}
+
+	return nil
+}
+
+// replayWAL re-applies records left behind by a crash before checkpoint
+func (s *FileMemoryStore) replayWAL() error {
+	data, err := os.ReadFile(filepath.Join(s.baseDir, walFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read write-ahead log: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record walRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			// Only the tail can be torn; anything after it was never acknowledged
+			s.recovery.TornRecords++
+			break
+		}
+
+		switch record.Op {
+		case walOpPut:
+			if record.Entry == nil {
+				continue
+			}
+			if err := s.putEntry(*record.Entry); err != nil {
+				return fmt.Errorf("failed to replay put %s: %w", record.Key, err)
+			}
+		case walOpDelete:
+			if err := s.deleteEntry(record.Key); err != nil {
+				return fmt.Errorf("failed to replay delete %s: %w", record.Key, err)
+			}
+		}
+		s.recovery.Replayed++
+	}
+
+	return scanner.Err()
+}
+
+// fsck reconciles the index with the entry files on disk: unreadable files are
+// quarantined, orphaned files are adopted, and dangling index entries dropped
+func (s *FileMemoryStore) fsck() error {
+	files, err := os.ReadDir(s.baseDir)
+	if err != nil {
+		return fmt.Errorf("failed to read memory directory: %w", err)
+	}
+
+	onDisk := make(map[string]bool)
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() {
+			continue
+		}
+
+		if strings.HasPrefix(name, tempPrefix) {
+			os.Remove(filepath.Join(s.baseDir, name))
+			s.recovery.TempFilesRemoved++
+			continue
+		}
+		if strings.HasPrefix(name, "_") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		path := filepath.Join(s.baseDir, name)
+		entry, err := readEntryFile(path)
+		if err != nil || entry.Key == "" {
+			if err := s.quarantine(name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		onDisk[entry.Key] = true
+		if _, exists := s.index[entry.Key]; !exists {
+			s.addToIndex(*entry)
+			s.recovery.Adopted = append(s.recovery.Adopted, entry.Key)
+		}
+	}
+
+	for key, entry := range s.index {
+		if onDisk[key] {
+			continue
+		}
+		delete(s.index, key)
+		for _, tag := range entry.Tags {
+			s.removeFromTagIndex(key, tag)
+		}
+		s.recovery.Missing = append(s.recovery.Missing, key)
+	}
+
+	return nil
+}
+
+// quarantine moves an unreadable entry file aside for manual inspection
+func (s *FileMemoryStore) quarantine(name string) error {
+	dir := filepath.Join(s.baseDir, quarantineDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	target := filepath.Join(dir, fmt.Sprintf("%s.%d", name, time.Now().UnixNano()))
+	if err := os.Rename(filepath.Join(s.baseDir, name), target); err != nil {
+		return fmt.Errorf("failed to quarantine %s: %w", name, err)
+	}
+
+	logger.Warn("FileMemoryStore quarantined unreadable entry", "entry", name, "target", target)
+	s.recovery.Quarantined = append(s.recovery.Quarantined, name)
+	return nil
+}
+
+// appendWAL durably records an operation before it is applied
+func (s *FileMemoryStore) appendWAL(record walRecord) error {
+	if s.wal == nil {
+		return fmt.Errorf("memory store is closed")
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal wal record: %w", err)
+	}
+	data = append(data, '\n')
+
+	if _, err := s.wal.Write(data); err != nil {
+		return fmt.Errorf("failed to append wal record: %w", err)
+	}
+	if err := s.wal.Sync(); err != nil {
+		return fmt.Errorf("failed to sync wal: %w", err)
+	}
+	return nil
+}
+
+// checkpoint saves the index and truncates the WAL it now covers
+func (s *FileMemoryStore) checkpoint() error {
+	if err := s.saveIndex(); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+	if s.wal == nil {
+		return nil
+	}
+	if err := s.wal.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate wal: %w", err)
+	}
+	return nil
+}
+
+// putEntry writes an entry file atomically and indexes it
+func (s *FileMemoryStore) putEntry(entry multiagent.MemoryEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal entry: %w", err)
+	}
+
+	if err := writeFileAtomic(s.getFilename(entry.Key), data); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	s.addToIndex(entry)
+	return nil
+}
+
+// deleteEntry removes an entry file and its index entries
+func (s *FileMemoryStore) deleteEntry(key string) error {
+	if indexEntry, exists := s.index[key]; exists {
+		delete(s.index, key)
+		for _, tag := range indexEntry.Tags {
+			s.removeFromTagIndex(key, tag)
+		}
+	}
+
+	if err := os.Remove(s.getFilename(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileMemoryStore) addToIndex(entry multiagent.MemoryEntry) {
+	if previous, exists := s.index[entry.Key]; exists {
+		for _, tag := range previous.Tags {
+			s.removeFromTagIndex(entry.Key, tag)
+		}
+	}
+
+	s.index[entry.Key] = &indexEntry{
+		Key:        entry.Key,
+		Category:   entry.Category,
+		Tags:       entry.Tags,
+		CreatedAt:  entry.CreatedAt,
+		UpdatedAt:  entry.UpdatedAt,
+		AccessedAt: entry.AccessedAt,
+		ExpiresAt:  entry.ExpiresAt,
+	}
+	s.updateTagIndex(entry.Key, entry.Tags)
+}
+
+func (s *FileMemoryStore) readEntry(key string) (*multiagent.MemoryEntry, error) {
+	return readEntryFile(s.getFilename(key))
+}
+
+func readEntryFile(path string) (*multiagent.MemoryEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var entry multiagent.MemoryEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// writeFileAtomic writes data to a temp file in the same directory, syncs it,
+// and renames it over path so readers never observe a partial write
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, tempPrefix+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	// Persist the rename itself; not all platforms support syncing directories
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
@@ -11,8 +11,11 @@ import (
 	"time"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
 )
 
+var logger = logging.For("memory")
+
 // FileMemoryStore implements MemoryStore using the filesystem
 type FileMemoryStore struct {
 	baseDir    string
@@ -20,6 +23,14 @@ type FileMemoryStore struct {
 	index      map[string]*indexEntry
 	tagIndex   map[string][]string
 	cleanupMu  sync.Mutex
+	wal        *os.File
+	recovery   RecoveryReport
+	// pending tracks access-stat writes started by Get, which Close waits on
+	pending sync.WaitGroup
+	// done is closed by Close to stop the cleanup routine
+	done      chan struct{}
+	closeOnce sync.Once
+	cleanup   sync.WaitGroup
 }
 
 type indexEntry struct {
@@ -43,14 +54,24 @@ func NewFileMemoryStore(baseDir string) (*FileMemoryStore, error) {
 		baseDir:  baseDir,
 		index:    make(map[string]*indexEntry),
 		tagIndex: make(map[string][]string),
+		done:     make(chan struct{}),
 	}
 
-	// Load existing index
+	// Load existing index; a corrupt index is rebuilt from the entry files
 	if err := store.loadIndex(); err != nil {
-		return nil, fmt.Errorf("failed to load index: %w", err)
+		logger.Warn("FileMemoryStore index unreadable, rebuilding from entries", "error", err)
+		store.index = make(map[string]*indexEntry)
+		store.tagIndex = make(map[string][]string)
+		store.recovery.IndexRebuilt = true
+	}
+
+	// Replay interrupted writes and check entries before serving requests
+	if err := store.recover(); err != nil {
+		return nil, fmt.Errorf("failed to recover memory store: %w", err)
 	}
 
 	// Start cleanup routine
+	store.cleanup.Add(1)
 	go store.cleanupRoutine()
 
 	return store, nil
@@ -84,35 +105,17 @@ func (s *FileMemoryStore) StoreWithTTL(ctx context.Context, key string, value in
 	}
 
 	// Extract category and tags from key
-	entry.Category, entry.Tags = s.extractMetadata(key)
+	entry.Category, entry.Tags = extractKeyMetadata(key)
 
-	// Marshal entry to JSON
-	data, err := json.MarshalIndent(entry, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal entry: %w", err)
+	if err := s.appendWAL(walRecord{Op: walOpPut, Key: key, Entry: &entry}); err != nil {
+		return err
 	}
-
-	// Write to file
-	filename := s.getFilename(key)
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	if err := s.putEntry(entry); err != nil {
+		return err
 	}
 
-	// Update index
-	indexEntry := &indexEntry{
-		Key:       key,
-		Category:  entry.Category,
-		Tags:      entry.Tags,
-		CreatedAt: entry.CreatedAt,
-		UpdatedAt: entry.UpdatedAt,
-		ExpiresAt: entry.ExpiresAt,
-	}
-	
-	s.index[key] = indexEntry
-	s.updateTagIndex(key, entry.Tags)
-	
 	// Save index
-	return s.saveIndex()
+	return s.checkpoint()
 }
 
 // Get retrieves a value by key
@@ -148,13 +151,24 @@ func (s *FileMemoryStore) Get(ctx context.Context, key string) (interface{}, err
 	entry.AccessedAt = time.Now()
 	entry.AccessCount++
 	
-	// Save updated entry (in background)
+	// Save updated entry (in background) unless the store is closed
+	if s.wal == nil {
+		return entry.Value, nil
+	}
+	s.pending.Add(1)
 	go func() {
+		defer s.pending.Done()
 		s.mu.Lock()
 		defer s.mu.Unlock()
 		
+		// Skip if the entry was deleted or replaced while unlocked
+		current, ok := s.index[key]
+		if !ok || !current.UpdatedAt.Equal(entry.UpdatedAt) {
+			return
+		}
+		current.AccessedAt = entry.AccessedAt
 		if data, err := json.MarshalIndent(entry, "", "  "); err == nil {
-			os.WriteFile(filename, data, 0644)
+			writeFileAtomic(filename, data)
 		}
 	}()
 
@@ -271,24 +285,15 @@ func (s *FileMemoryStore) Delete(ctx context.Context, key string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Remove from index
-	if indexEntry, exists := s.index[key]; exists {
-		delete(s.index, key)
-		
-		// Remove from tag index
-		for _, tag := range indexEntry.Tags {
-			s.removeFromTagIndex(key, tag)
-		}
+	if err := s.appendWAL(walRecord{Op: walOpDelete, Key: key}); err != nil {
+		return err
 	}
-	
-	// Delete file
-	filename := s.getFilename(key)
-	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to delete file: %w", err)
+	if err := s.deleteEntry(key); err != nil {
+		return err
 	}
-	
+
 	// Save index
-	return s.saveIndex()
+	return s.checkpoint()
 }
 
 // Update updates an existing entry
@@ -297,19 +302,34 @@ func (s *FileMemoryStore) Update(ctx context.Context, key string, updater func(i
 	defer s.mu.Unlock()
 
 	// Load current value
-	value, err := s.Get(ctx, key)
+	indexEntry, exists := s.index[key]
+	if !exists {
+		return fmt.Errorf("key not found: %s", key)
+	}
+	if indexEntry.ExpiresAt != nil && time.Now().After(*indexEntry.ExpiresAt) {
+		return fmt.Errorf("key expired: %s", key)
+	}
+	entry, err := s.readEntry(key)
 	if err != nil {
 		return err
 	}
 	
 	// Apply update
-	newValue, err := updater(value)
+	newValue, err := updater(entry.Value)
 	if err != nil {
 		return err
 	}
 	
-	// Store updated value
-	return s.Store(ctx, key, newValue)
+	// Store updated value, keeping creation time and any TTL
+	entry.Value = newValue
+	entry.UpdatedAt = time.Now()
+	if err := s.appendWAL(walRecord{Op: walOpPut, Key: key, Entry: entry}); err != nil {
+		return err
+	}
+	if err := s.putEntry(*entry); err != nil {
+		return err
+	}
+	return s.checkpoint()
 }
 
 // List returns keys matching a prefix
@@ -331,6 +351,27 @@ func (s *FileMemoryStore) List(ctx context.Context, prefix string, limit int) ([
 	return keys, nil
 }
 
+// ListEntries returns index metadata for keys matching prefix, including expired ones
+func (s *FileMemoryStore) ListEntries(ctx context.Context, prefix string) ([]EntryInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := make([]EntryInfo, 0)
+	for key, indexEntry := range s.index {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		infos = append(infos, EntryInfo{
+			Key:        key,
+			CreatedAt:  indexEntry.CreatedAt,
+			AccessedAt: indexEntry.AccessedAt,
+			ExpiresAt:  indexEntry.ExpiresAt,
+		})
+	}
+
+	return infos, nil
+}
+
 // Cleanup removes expired entries
 func (s *FileMemoryStore) Cleanup(ctx context.Context) error {
 	s.cleanupMu.Lock()
@@ -351,11 +392,9 @@ func (s *FileMemoryStore) Cleanup(ctx context.Context) error {
 	
 	// Delete expired entries
 	for _, key := range toDelete {
-		delete(s.index, key)
-		
-		// Remove file
-		filename := s.getFilename(key)
-		os.Remove(filename)
+		if err := s.deleteEntry(key); err != nil {
+			logger.Error("FileMemoryStore failed to remove expired entry", "key", key, "error", err)
+		}
 	}
 	
 	if len(toDelete) > 0 {
@@ -374,7 +413,8 @@ func (s *FileMemoryStore) getFilename(key string) string {
 	return filepath.Join(s.baseDir, safeKey+".json")
 }
 
-func (s *FileMemoryStore) extractMetadata(key string) (category string, tags []string) {
+// extractKeyMetadata derives the category and tags shared by all store backends
+func extractKeyMetadata(key string) (category string, tags []string) {
 	// Extract category from key pattern (e.g., "agent:id:data" -> "agent")
 	parts := strings.Split(key, ":")
 	if len(parts) > 0 {
@@ -477,17 +517,22 @@ func (s *FileMemoryStore) saveIndex() error {
 		return err
 	}
 	
-	return os.WriteFile(indexFile, data, 0644)
+	return writeFileAtomic(indexFile, data)
 }
 
 func (s *FileMemoryStore) cleanupRoutine() {
+	defer s.cleanup.Done()
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
-	
-	for range ticker.C {
-		ctx := context.Background()
-		if err := s.Cleanup(ctx); err != nil {
-			fmt.Printf("Memory cleanup error: %v\n", err)
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Cleanup(context.Background()); err != nil {
+				logger.Error("FileMemoryStore cleanup failed", "error", err)
+			}
+		case <-s.done:
+			return
 		}
 	}
 }
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileMemoryStore_RecoversAfterCrash(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := NewFileMemoryStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileMemoryStore: %v", err)
+	}
+	if err := store.Store(ctx, "task:1", "keep me"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := store.Store(ctx, "task:2", "corrupt me"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	store.Close()
+
+	// Simulate a crash: an acknowledged WAL record that never reached the
+	// index, a torn trailing record, a half-written entry, and a stray temp file
+	wal := `{"op":"put","key":"task:3","entry":{"key":"task:3","value":"from wal","category":"task","tags":["task"]}}` + "\n" +
+		`{"op":"put","key":"task:4","entr`
+	if err := os.WriteFile(filepath.Join(dir, walFileName), []byte(wal), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.getFilename("task:2"), []byte(`{"key":"task:2","val`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, tempPrefix+"task_5.json-123"), []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileMemoryStore(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	report := reopened.RecoveryReport()
+	if report.Replayed != 1 || report.TornRecords != 1 || len(report.Quarantined) != 1 || report.TempFilesRemoved != 1 {
+		t.Fatalf("unexpected recovery report: %+v", report)
+	}
+
+	if v, err := reopened.Get(ctx, "task:1"); err != nil || v != "keep me" {
+		t.Fatalf("expected task:1 intact, got %v, %v", v, err)
+	}
+	if v, err := reopened.Get(ctx, "task:3"); err != nil || v != "from wal" {
+		t.Fatalf("expected task:3 replayed, got %v, %v", v, err)
+	}
+	if _, err := reopened.Get(ctx, "task:2"); err == nil {
+		t.Fatal("expected corrupt task:2 to be dropped")
+	}
+	if files, _ := os.ReadDir(filepath.Join(dir, quarantineDir)); len(files) != 1 {
+		t.Fatalf("expected one quarantined file, got %d", len(files))
+	}
+
+	// Update must not deadlock and should keep the entry readable
+	err = reopened.Update(ctx, "task:1", func(v interface{}) (interface{}, error) {
+		return v.(string) + "!", nil
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if v, _ := reopened.Get(ctx, "task:1"); v != "keep me!" {
+		t.Fatalf("expected updated value, got %v", v)
+	}
+}
+
+func TestFileMemoryStore_CloseWaitsForAccessStats(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileMemoryStore: %v", err)
+	}
+	if err := store.Store(ctx, "task:1", "read me"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if _, err := store.Get(ctx, "task:1"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	store.Close()
+
+	// The access recorded by Get is on disk once Close returns
+	entry, err := store.readEntry("task:1")
+	if err != nil {
+		t.Fatalf("readEntry: %v", err)
+	}
+	if entry.AccessCount != 1 {
+		t.Fatalf("expected the access to be saved before Close returned, got count %d", entry.AccessCount)
+	}
+	if _, err := store.Get(ctx, "task:1"); err != nil {
+		t.Fatalf("Get after Close: %v", err)
+	}
+}
+
+func TestFileMemoryStore_CloseStopsCleanup(t *testing.T) {
+	store, err := NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileMemoryStore: %v", err)
+	}
+
+	closed := make(chan error, 1)
+	go func() { closed <- store.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not stop the cleanup routine")
+	}
+	// Closing again is harmless
+	if err := store.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
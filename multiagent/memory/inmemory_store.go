@@ -0,0 +1,255 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// InMemoryStore implements MemoryStore in process memory, for tests and
+// simulations. Values are stored as JSON, so reads return the same generic
+// values the file and SQLite stores do and callers never share them.
+type InMemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]*storedEntry
+}
+
+type storedEntry struct {
+	entry multiagent.MemoryEntry
+	value []byte
+}
+
+// NewInMemoryStore creates an empty in-memory store
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{entries: make(map[string]*storedEntry)}
+}
+
+// Store saves a value with the given key
+func (s *InMemoryStore) Store(ctx context.Context, key string, value interface{}) error {
+	return s.StoreWithTTL(ctx, key, value, 0)
+}
+
+// StoreWithTTL saves a value with the given key and TTL
+func (s *InMemoryStore) StoreWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	now := time.Now()
+	entry := multiagent.MemoryEntry{
+		Key:        key,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		AccessedAt: now,
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		entry.TTL = &ttl
+		entry.ExpiresAt = &expiresAt
+	}
+	entry.Category, entry.Tags = extractKeyMetadata(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = &storedEntry{entry: entry, value: data}
+	return nil
+}
+
+// Get retrieves a value by key
+func (s *InMemoryStore) Get(ctx context.Context, key string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.entries[key]
+	if !ok {
+		return nil, fmt.Errorf("key not found: %s", key)
+	}
+	if stored.expired(time.Now()) {
+		return nil, fmt.Errorf("key expired: %s", key)
+	}
+	stored.entry.AccessedAt = time.Now()
+	stored.entry.AccessCount++
+	return stored.decode()
+}
+
+// GetMultiple retrieves multiple values by keys, skipping missing ones
+func (s *InMemoryStore) GetMultiple(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	results := make(map[string]interface{})
+	for _, key := range keys {
+		if value, err := s.Get(ctx, key); err == nil {
+			results[key] = value
+		}
+	}
+	return results, nil
+}
+
+// Search returns entries whose key or category, and value, contain query,
+// most recently updated first
+func (s *InMemoryStore) Search(ctx context.Context, query string, limit int) ([]multiagent.MemoryEntry, error) {
+	queryLower := strings.ToLower(query)
+	return s.find(limit, func(stored *storedEntry) bool {
+		entry := stored.entry
+		return (strings.Contains(strings.ToLower(entry.Key), queryLower) ||
+			strings.Contains(strings.ToLower(entry.Category), queryLower)) &&
+			strings.Contains(strings.ToLower(string(stored.value)), queryLower)
+	})
+}
+
+// SearchByTags returns entries carrying all of tags, most recently updated first
+func (s *InMemoryStore) SearchByTags(ctx context.Context, tags []string, limit int) ([]multiagent.MemoryEntry, error) {
+	if len(tags) == 0 {
+		return []multiagent.MemoryEntry{}, nil
+	}
+	return s.find(limit, func(stored *storedEntry) bool {
+		for _, tag := range tags {
+			found := false
+			for _, entryTag := range stored.entry.Tags {
+				if entryTag == tag {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Delete removes an entry by key
+func (s *InMemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Update replaces an existing entry's value with updater's result, keeping
+// its creation time and TTL
+func (s *InMemoryStore) Update(ctx context.Context, key string, updater func(interface{}) (interface{}, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.entries[key]
+	if !ok {
+		return fmt.Errorf("key not found: %s", key)
+	}
+	if stored.expired(time.Now()) {
+		return fmt.Errorf("key expired: %s", key)
+	}
+	current, err := stored.decode()
+	if err != nil {
+		return err
+	}
+	newValue, err := updater(current)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(newValue)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+	stored.value = data
+	stored.entry.UpdatedAt = time.Now()
+	return nil
+}
+
+// List returns live keys matching a prefix, in key order
+func (s *InMemoryStore) List(ctx context.Context, prefix string, limit int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]string, 0)
+	for key, stored := range s.entries {
+		if strings.HasPrefix(key, prefix) && !stored.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys, nil
+}
+
+// ListEntries returns metadata for keys matching prefix, including expired ones
+func (s *InMemoryStore) ListEntries(ctx context.Context, prefix string) ([]EntryInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := make([]EntryInfo, 0)
+	for key, stored := range s.entries {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		infos = append(infos, EntryInfo{
+			Key:        key,
+			CreatedAt:  stored.entry.CreatedAt,
+			AccessedAt: stored.entry.AccessedAt,
+			ExpiresAt:  stored.entry.ExpiresAt,
+		})
+	}
+	return infos, nil
+}
+
+// Cleanup removes expired entries
+func (s *InMemoryStore) Cleanup(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, stored := range s.entries {
+		if stored.expired(now) {
+			delete(s.entries, key)
+		}
+	}
+	return nil
+}
+
+// find returns up to limit live entries matching match, most recently
+// updated first
+func (s *InMemoryStore) find(limit int, match func(*storedEntry) bool) ([]multiagent.MemoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	results := make([]multiagent.MemoryEntry, 0)
+	for _, stored := range s.entries {
+		if stored.expired(now) || !match(stored) {
+			continue
+		}
+		entry := stored.entry
+		value, err := stored.decode()
+		if err != nil {
+			continue
+		}
+		entry.Value = value
+		results = append(results, entry)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].UpdatedAt.After(results[j].UpdatedAt) })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (e *storedEntry) expired(now time.Time) bool {
+	return e.entry.ExpiresAt != nil && now.After(*e.entry.ExpiresAt)
+}
+
+func (e *storedEntry) decode() (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(e.value, &value); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+	return value, nil
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInMemoryStore_StoreGetList(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+
+	type task struct {
+		Title string `json:"title"`
+	}
+	original := &task{Title: "write docs"}
+	if err := store.Store(ctx, "task:1", original); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	original.Title = "changed after storing"
+	if err := store.Store(ctx, "task:2", "second"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	// Values come back as generic JSON, like the persistent stores return
+	value, err := store.Get(ctx, "task:1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok || m["title"] != "write docs" {
+		t.Fatalf("unexpected value: %#v", value)
+	}
+
+	keys, err := store.List(ctx, "task:", 10)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(keys) != 2 || keys[0] != "task:1" || keys[1] != "task:2" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	entries, err := store.Search(ctx, "docs", 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no match on value alone, got %d", len(entries))
+	}
+	entries, err = store.SearchByTags(ctx, []string{"task"}, 10)
+	if err != nil {
+		t.Fatalf("SearchByTags: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 tagged entries, got %d", len(entries))
+	}
+}
+
+func TestInMemoryStore_TTLAndUpdate(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+
+	if err := store.StoreWithTTL(ctx, "session:1", "short", time.Millisecond); err != nil {
+		t.Fatalf("StoreWithTTL: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := store.Get(ctx, "session:1"); err == nil {
+		t.Fatal("expected expired key to be unreadable")
+	}
+	if err := store.Cleanup(ctx); err != nil {
+		t.Fatalf("Cleanup: %v", err)
+	}
+	if entries, _ := store.ListEntries(ctx, "session:"); len(entries) != 0 {
+		t.Fatalf("expected cleanup to remove expired entries, got %d", len(entries))
+	}
+
+	if err := store.Store(ctx, "counter", 1); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	err := store.Update(ctx, "counter", func(v interface{}) (interface{}, error) {
+		return v.(float64) + 1, nil
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if value, _ := store.Get(ctx, "counter"); value != float64(2) {
+		t.Fatalf("unexpected value after update: %#v", value)
+	}
+	if err := store.Update(ctx, "missing", func(v interface{}) (interface{}, error) { return v, nil }); err == nil {
+		t.Fatal("expected update of a missing key to fail")
+	}
+}
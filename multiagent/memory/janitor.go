@@ -0,0 +1,448 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// ErrListEntriesUnsupported is returned by wrappers whose base store cannot list entry metadata
+var ErrListEntriesUnsupported = errors.New("store does not support listing entries")
+
+// EntryInfo is the metadata the janitor needs to expire, compact and evict entries
+type EntryInfo struct {
+	Key        string
+	CreatedAt  time.Time
+	AccessedAt time.Time
+	ExpiresAt  *time.Time
+}
+
+// EntryLister is implemented by stores that can report entry metadata without
+// loading values
+type EntryLister interface {
+	ListEntries(ctx context.Context, prefix string) ([]EntryInfo, error)
+}
+
+// PrefixQuota caps the number of live entries under a key prefix; the least
+// recently accessed entries are evicted first. A "*" in Prefix matches one
+// key segment and the cap applies to each match on its own, so
+// "user:*:msg:" bounds every user's message log.
+type PrefixQuota struct {
+	Prefix     string `json:"prefix"`
+	MaxEntries int    `json:"max_entries"`
+}
+
+// CompactionRule thins out time-series keys such as events and health snapshots.
+// Entries older than KeepRecent are grouped into Bucket-sized windows. Without a
+// SummaryPrefix only the newest entry per window is kept; with one, each window
+// is rolled up into a CompactionSummary and the originals are deleted.
+type CompactionRule struct {
+	Prefix        string        `json:"prefix"`
+	KeepRecent    time.Duration `json:"keep_recent"`
+	Bucket        time.Duration `json:"bucket"`
+	SummaryPrefix string        `json:"summary_prefix,omitempty"`
+	SummaryTTL    time.Duration `json:"summary_ttl,omitempty"`
+}
+
+// CompactionSummary replaces a window of rolled-up entries
+type CompactionSummary struct {
+	Prefix      string         `json:"prefix"`
+	BucketStart time.Time      `json:"bucket_start"`
+	BucketEnd   time.Time      `json:"bucket_end"`
+	Count       int            `json:"count"`
+	ByType      map[string]int `json:"by_type,omitempty"`
+}
+
+// JanitorConfig holds configuration for creating a Janitor
+type JanitorConfig struct {
+	Store       multiagent.MemoryStore
+	Interval    time.Duration
+	Quotas      []PrefixQuota
+	Compactions []CompactionRule
+}
+
+// DefaultCompactionRules keeps a day of raw orchestrator history, then
+// downsamples health snapshots to hourly and rolls events up into hourly counts
+func DefaultCompactionRules() []CompactionRule {
+	return []CompactionRule{
+		{
+			Prefix:     "orchestrator:health:",
+			KeepRecent: 24 * time.Hour,
+			Bucket:     time.Hour,
+		},
+		{
+			Prefix:        "orchestrator:event:",
+			KeepRecent:    time.Hour,
+			Bucket:        time.Hour,
+			SummaryPrefix: "orchestrator:event_summary:",
+			SummaryTTL:    30 * 24 * time.Hour,
+		},
+	}
+}
+
+// DefaultQuotas bounds the prefixes that grow with every message, in the
+// shared keyspace and in each user's partition
+func DefaultQuotas() []PrefixQuota {
+	return []PrefixQuota{
+		{Prefix: "msg:", MaxEntries: 10000},
+		{Prefix: userPrefix + "*:msg:", MaxEntries: 10000},
+		{Prefix: "orchestrator:event:", MaxEntries: 10000},
+		{Prefix: "orchestrator:dead_letter:", MaxEntries: 1000},
+	}
+}
+
+// Janitor periodically expires TTL'd keys, compacts time-series prefixes, and
+// enforces per-prefix quotas
+type Janitor struct {
+	store       multiagent.MemoryStore
+	interval    time.Duration
+	quotas      []PrefixQuota
+	compactions []CompactionRule
+
+	mu    sync.RWMutex
+	stats multiagent.MemoryStats
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+}
+
+// NewJanitor creates a new memory janitor
+func NewJanitor(config JanitorConfig) *Janitor {
+	if config.Interval == 0 {
+		config.Interval = time.Minute
+	}
+
+	return &Janitor{
+		store:       config.Store,
+		interval:    config.Interval,
+		quotas:      config.Quotas,
+		compactions: config.Compactions,
+		stats: multiagent.MemoryStats{
+			EntriesByPrefix: make(map[string]int),
+		},
+	}
+}
+
+// Start runs a sweep immediately and then on every interval
+func (j *Janitor) Start(ctx context.Context) {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.stopChan = make(chan struct{})
+	j.mu.Unlock()
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		j.Sweep(ctx)
+		for {
+			select {
+			case <-ticker.C:
+				j.Sweep(ctx)
+			case <-j.stopChan:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts the background sweep and waits for it to finish
+func (j *Janitor) Stop() {
+	j.mu.Lock()
+	if !j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = false
+	close(j.stopChan)
+	j.mu.Unlock()
+
+	j.wg.Wait()
+}
+
+// MemoryStats returns the statistics from the most recent sweep
+func (j *Janitor) MemoryStats() multiagent.MemoryStats {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	stats := j.stats
+	stats.EntriesByPrefix = make(map[string]int, len(j.stats.EntriesByPrefix))
+	for prefix, count := range j.stats.EntriesByPrefix {
+		stats.EntriesByPrefix[prefix] = count
+	}
+	return stats
+}
+
+// Sweep runs one expire/compact/evict pass
+func (j *Janitor) Sweep(ctx context.Context) {
+	start := time.Now()
+	var expired, compacted, evicted int
+	var sweepErr error
+
+	lister, ok := j.store.(EntryLister)
+
+	// Count expired entries before the store removes them
+	if ok {
+		if entries, err := lister.ListEntries(ctx, ""); err == nil {
+			for _, entry := range entries {
+				if entry.ExpiresAt != nil && start.After(*entry.ExpiresAt) {
+					expired++
+				}
+			}
+		}
+	}
+	if err := j.store.Cleanup(ctx); err != nil {
+		sweepErr = fmt.Errorf("failed to clean up expired entries: %w", err)
+	}
+
+	byPrefix := make(map[string]int)
+	total := 0
+	quotaUsage := 0.0
+
+	if ok {
+		for _, rule := range j.compactions {
+			n, err := j.compact(ctx, lister, rule, start)
+			compacted += n
+			if err != nil && sweepErr == nil {
+				sweepErr = err
+			}
+		}
+
+		for _, quota := range j.quotas {
+			n, remaining, fullest, err := j.enforceQuota(ctx, lister, quota, start)
+			evicted += n
+			if err != nil && sweepErr == nil {
+				sweepErr = err
+			}
+			byPrefix[quota.Prefix] = remaining
+			if quota.MaxEntries > 0 {
+				if usage := float64(fullest) / float64(quota.MaxEntries) * 100; usage > quotaUsage {
+					quotaUsage = usage
+				}
+			}
+		}
+
+		if entries, err := lister.ListEntries(ctx, ""); err == nil {
+			topLevel := make(map[string]int)
+			for _, entry := range live(entries, start) {
+				total++
+				topLevel[topLevelPrefix(entry.Key)]++
+			}
+			for prefix, count := range topLevel {
+				if _, exists := byPrefix[prefix]; !exists {
+					byPrefix[prefix] = count
+				}
+			}
+		} else if !errors.Is(err, ErrListEntriesUnsupported) && sweepErr == nil {
+			sweepErr = err
+		}
+	}
+
+	if sweepErr != nil {
+		logger.ErrorContext(ctx, "Memory janitor sweep failed", "error", sweepErr)
+	}
+	if compacted > 0 || evicted > 0 {
+		logger.InfoContext(ctx, "Memory janitor sweep", "expired", expired, "compacted", compacted, "evicted", evicted)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.stats.TotalEntries = total
+	j.stats.EntriesByPrefix = byPrefix
+	j.stats.Expired += int64(expired)
+	j.stats.Compacted += int64(compacted)
+	j.stats.Evicted += int64(evicted)
+	j.stats.LastSweep = start
+	j.stats.LastSweepTook = time.Since(start)
+	j.stats.QuotaUsage = quotaUsage
+	j.stats.LastError = ""
+	if sweepErr != nil {
+		j.stats.LastError = sweepErr.Error()
+	}
+}
+
+// Internal helper methods
+
+func (j *Janitor) compact(ctx context.Context, lister EntryLister, rule CompactionRule, now time.Time) (int, error) {
+	if rule.Bucket <= 0 {
+		return 0, nil
+	}
+
+	entries, err := lister.ListEntries(ctx, rule.Prefix)
+	if err != nil {
+		if errors.Is(err, ErrListEntriesUnsupported) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to list %s: %w", rule.Prefix, err)
+	}
+
+	cutoff := now.Add(-rule.KeepRecent)
+	buckets := make(map[time.Time][]EntryInfo)
+	for _, entry := range live(entries, now) {
+		if entry.CreatedAt.Before(cutoff) {
+			bucket := entry.CreatedAt.Truncate(rule.Bucket)
+			buckets[bucket] = append(buckets[bucket], entry)
+		}
+	}
+
+	removed := 0
+	for bucket, group := range buckets {
+		if rule.SummaryPrefix == "" {
+			if len(group) < 2 {
+				continue
+			}
+			// Keep the newest entry in each window
+			sort.Slice(group, func(a, b int) bool { return group[a].CreatedAt.After(group[b].CreatedAt) })
+			group = group[1:]
+		} else if err := j.rollUp(ctx, rule, bucket, group); err != nil {
+			return removed, err
+		}
+
+		for _, entry := range group {
+			if err := j.store.Delete(ctx, entry.Key); err != nil {
+				return removed, fmt.Errorf("failed to delete compacted entry %s: %w", entry.Key, err)
+			}
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// rollUp merges a window of entries into its summary, counting values by their "type" field
+func (j *Janitor) rollUp(ctx context.Context, rule CompactionRule, bucket time.Time, group []EntryInfo) error {
+	summaryKey := fmt.Sprintf("%s%d", rule.SummaryPrefix, bucket.Unix())
+
+	summary := CompactionSummary{
+		Prefix:      rule.Prefix,
+		BucketStart: bucket,
+		BucketEnd:   bucket.Add(rule.Bucket),
+		ByType:      make(map[string]int),
+	}
+	if existing, err := j.store.Get(ctx, summaryKey); err == nil {
+		if data, err := json.Marshal(existing); err == nil {
+			json.Unmarshal(data, &summary)
+		}
+		if summary.ByType == nil {
+			summary.ByType = make(map[string]int)
+		}
+	}
+
+	for _, entry := range group {
+		summary.Count++
+		value, err := j.store.Get(ctx, entry.Key)
+		if err != nil {
+			continue
+		}
+		if m, ok := value.(map[string]interface{}); ok {
+			if t, ok := m["type"].(string); ok {
+				summary.ByType[t]++
+			}
+		}
+	}
+
+	if rule.SummaryTTL > 0 {
+		return j.store.StoreWithTTL(ctx, summaryKey, summary, rule.SummaryTTL)
+	}
+	return j.store.Store(ctx, summaryKey, summary)
+}
+
+// enforceQuota evicts least recently accessed entries until the prefix, or
+// each of its wildcard matches, fits. It returns the number evicted, the
+// number left, and the number left under the fullest match.
+func (j *Janitor) enforceQuota(ctx context.Context, lister EntryLister, quota PrefixQuota, now time.Time) (int, int, int, error) {
+	head, tail, wildcard := strings.Cut(quota.Prefix, "*")
+	entries, err := lister.ListEntries(ctx, head)
+	if err != nil {
+		if errors.Is(err, ErrListEntriesUnsupported) {
+			return 0, 0, 0, nil
+		}
+		return 0, 0, 0, fmt.Errorf("failed to list %s: %w", quota.Prefix, err)
+	}
+
+	groups := map[string][]EntryInfo{"": live(entries, now)}
+	if wildcard {
+		groups = make(map[string][]EntryInfo)
+		for _, entry := range live(entries, now) {
+			rest := entry.Key[len(head):]
+			segment, _, _ := strings.Cut(rest, ":")
+			if strings.HasPrefix(rest[len(segment):], tail) {
+				groups[segment] = append(groups[segment], entry)
+			}
+		}
+	}
+
+	evicted, remaining, fullest := 0, 0, 0
+	for _, group := range groups {
+		n, err := j.evictExcess(ctx, group, quota.MaxEntries)
+		evicted += n
+		remaining += len(group) - n
+		if left := len(group) - n; left > fullest {
+			fullest = left
+		}
+		if err != nil {
+			return evicted, remaining, fullest, err
+		}
+	}
+	return evicted, remaining, fullest, nil
+}
+
+// evictExcess deletes the least recently accessed entries beyond max
+func (j *Janitor) evictExcess(ctx context.Context, entries []EntryInfo, max int) (int, error) {
+	if max <= 0 || len(entries) <= max {
+		return 0, nil
+	}
+
+	sort.Slice(entries, func(a, b int) bool {
+		if !entries[a].AccessedAt.Equal(entries[b].AccessedAt) {
+			return entries[a].AccessedAt.Before(entries[b].AccessedAt)
+		}
+		return entries[a].CreatedAt.Before(entries[b].CreatedAt)
+	})
+
+	evicted := 0
+	for _, entry := range entries[:len(entries)-max] {
+		if err := j.store.Delete(ctx, entry.Key); err != nil {
+			return evicted, fmt.Errorf("failed to evict %s: %w", entry.Key, err)
+		}
+		evicted++
+	}
+	return evicted, nil
+}
+
+// live drops entries that have already expired
+func live(entries []EntryInfo, now time.Time) []EntryInfo {
+	result := make([]EntryInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.ExpiresAt == nil || now.Before(*entry.ExpiresAt) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// topLevelPrefix groups keys by their first segment for stats
+func topLevelPrefix(key string) string {
+	if i := strings.Index(key, ":"); i >= 0 {
+		return key[:i+1]
+	}
+	return key
+}
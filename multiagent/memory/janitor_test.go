@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+func TestJanitor_QuotaEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLiteStore(t)
+
+	for _, key := range []string{"msg:a:1", "msg:a:2", "msg:a:3"} {
+		if err := store.Store(ctx, key, key); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// Touch the oldest entry so it becomes the most recently used
+	if _, err := store.Get(ctx, "msg:a:1"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	janitor := NewJanitor(JanitorConfig{
+		Store:  store,
+		Quotas: []PrefixQuota{{Prefix: "msg:", MaxEntries: 2}},
+	})
+	janitor.Sweep(ctx)
+
+	keys, _ := store.List(ctx, "msg:", 10)
+	if len(keys) != 2 || keys[0] != "msg:a:1" || keys[1] != "msg:a:3" {
+		t.Fatalf("unexpected keys after eviction: %v", keys)
+	}
+
+	stats := janitor.MemoryStats()
+	if stats.Evicted != 1 || stats.EntriesByPrefix["msg:"] != 2 || stats.QuotaUsage != 100 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestJanitor_QuotaAppliesPerUserPartition(t *testing.T) {
+	base := newTestSQLiteStore(t)
+	store := ScopeForAgent(PartitionByUser(base), "conversation_agent", DefaultNamespacePolicy())
+	alice := multiagent.WithUserID(context.Background(), "alice")
+	bob := multiagent.WithUserID(context.Background(), "bob")
+
+	for i := 1; i <= 3; i++ {
+		if err := store.Store(alice, fmt.Sprintf("msg:conversation_agent:%d", i), i); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := store.Store(bob, "msg:conversation_agent:1", 1); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	janitor := NewJanitor(JanitorConfig{
+		Store:  base,
+		Quotas: []PrefixQuota{{Prefix: "user:*:msg:", MaxEntries: 2}},
+	})
+	janitor.Sweep(context.Background())
+
+	keys, _ := store.List(alice, "msg:", 10)
+	if len(keys) != 2 || keys[0] != "msg:conversation_agent:2" || keys[1] != "msg:conversation_agent:3" {
+		t.Errorf("alice's messages after eviction: %v", keys)
+	}
+	if keys, _ := store.List(bob, "msg:", 10); len(keys) != 1 {
+		t.Errorf("bob's messages after eviction: %v", keys)
+	}
+
+	stats := janitor.MemoryStats()
+	if stats.Evicted != 1 || stats.EntriesByPrefix["user:*:msg:"] != 3 || stats.QuotaUsage != 100 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestJanitor_CompactionRollsUpEvents(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLiteStore(t)
+
+	for i, eventType := range []string{"task_created", "task_created", "message_sent"} {
+		key := "orchestrator:event:" + string(rune('a'+i))
+		if err := store.Store(ctx, key, map[string]interface{}{"type": eventType}); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+
+	janitor := NewJanitor(JanitorConfig{Store: store})
+	rule := CompactionRule{
+		Prefix:        "orchestrator:event:",
+		KeepRecent:    time.Hour,
+		Bucket:        24 * time.Hour,
+		SummaryPrefix: "orchestrator:event_summary:",
+	}
+
+	removed, err := janitor.compact(ctx, store, rule, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if removed != 3 {
+		t.Fatalf("expected 3 compacted events, got %d", removed)
+	}
+
+	summaries, _ := store.List(ctx, "orchestrator:event_summary:", 10)
+	if len(summaries) != 1 {
+		t.Fatalf("expected one summary, got %v", summaries)
+	}
+	value, _ := store.Get(ctx, summaries[0])
+	summary := value.(map[string]interface{})
+	byType := summary["by_type"].(map[string]interface{})
+	if summary["count"] != float64(3) || byType["task_created"] != float64(2) {
+		t.Fatalf("unexpected summary: %v", summary)
+	}
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// MigrationReport summarizes a file store to SQLite migration
+type MigrationReport struct {
+	Migrated int      `json:"migrated"`
+	Expired  int      `json:"expired"`
+	Failed   []string `json:"failed,omitempty"`
+}
+
+// MigrateFileStore copies every live entry from a FileMemoryStore into a
+// SQLiteMemoryStore, preserving timestamps, access counts and TTLs. Expired
+// entries are skipped; unreadable entries are reported but do not abort.
+func MigrateFileStore(ctx context.Context, src *FileMemoryStore, dst *SQLiteMemoryStore) (*MigrationReport, error) {
+	src.mu.RLock()
+	keys := make([]string, 0, len(src.index))
+	for key := range src.index {
+		keys = append(keys, key)
+	}
+	src.mu.RUnlock()
+
+	report := &MigrationReport{}
+	now := time.Now()
+
+	tx, err := dst.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		data, err := os.ReadFile(src.getFilename(key))
+		if err != nil {
+			report.Failed = append(report.Failed, key)
+			continue
+		}
+
+		var entry multiagent.MemoryEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			report.Failed = append(report.Failed, key)
+			continue
+		}
+
+		if entry.ExpiresAt != nil && now.After(*entry.ExpiresAt) {
+			report.Expired++
+			continue
+		}
+
+		// Older files may predate the key field
+		if entry.Key == "" {
+			entry.Key = key
+		}
+
+		if err := dst.putEntry(ctx, tx, entry); err != nil {
+			return nil, fmt.Errorf("failed to migrate %s: %w", key, err)
+		}
+		report.Migrated++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit migration: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// ProfileKey holds the user profile; through a user-partitioned store each
+// user has their own, and every agent may read and write it
+const ProfileKey = "user_profile"
+
+// UserProfile holds a user's settings that agents share
+type UserProfile struct {
+	// Timezone is an IANA zone name such as "Europe/Berlin"; empty means UTC
+	Timezone  string    `json:"timezone,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Location returns the profile's timezone, falling back to UTC
+func (p UserProfile) Location() *time.Location {
+	if p.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// LoadProfile returns the acting user's profile; a user without one gets
+// the zero profile
+func LoadProfile(ctx context.Context, store multiagent.MemoryStore) (UserProfile, error) {
+	var profile UserProfile
+	value, err := store.Get(ctx, ProfileKey)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return profile, nil
+		}
+		return profile, fmt.Errorf("failed to load profile: %w", err)
+	}
+	if stored, ok := value.(UserProfile); ok {
+		return stored, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return profile, fmt.Errorf("failed to marshal profile: %w", err)
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return profile, fmt.Errorf("failed to unmarshal profile: %w", err)
+	}
+	return profile, nil
+}
+
+// SaveProfile stores the acting user's profile after checking its timezone
+func SaveProfile(ctx context.Context, store multiagent.MemoryStore, profile UserProfile) error {
+	if profile.Timezone != "" {
+		if _, err := time.LoadLocation(profile.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q: %w", profile.Timezone, err)
+		}
+	}
+	profile.UpdatedAt = time.Now()
+	if err := store.Store(ctx, ProfileKey, profile); err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+func TestProfile_PerUserTimezone(t *testing.T) {
+	base := newTestSQLiteStore(t)
+	store := NewScopedMemoryStore(PartitionByUser(base), "scheduler_agent", DefaultNamespacePolicy())
+	alice := multiagent.WithUserID(context.Background(), "alice")
+	bob := multiagent.WithUserID(context.Background(), "bob")
+
+	empty, err := LoadProfile(alice, store)
+	if err != nil {
+		t.Fatalf("LoadProfile: %v", err)
+	}
+	if empty.Location() != time.UTC {
+		t.Errorf("expected UTC without a profile, got %s", empty.Location())
+	}
+
+	if err := SaveProfile(alice, store, UserProfile{Timezone: "Not/AZone"}); err == nil {
+		t.Error("expected an unknown timezone to be rejected")
+	}
+	if err := SaveProfile(alice, store, UserProfile{Timezone: "America/New_York"}); err != nil {
+		t.Fatalf("SaveProfile: %v", err)
+	}
+
+	profile, err := LoadProfile(alice, store)
+	if err != nil {
+		t.Fatalf("LoadProfile: %v", err)
+	}
+	if profile.Location().String() != "America/New_York" || profile.UpdatedAt.IsZero() {
+		t.Errorf("unexpected profile %+v", profile)
+	}
+
+	other, err := LoadProfile(bob, store)
+	if err != nil {
+		t.Fatalf("LoadProfile: %v", err)
+	}
+	if other.Timezone != "" {
+		t.Errorf("expected bob's profile to be separate, got %+v", other)
+	}
+}
@@ -0,0 +1,294 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// QdrantMemoryStore adds semantic recall to any MemoryStore by indexing
+// embeddings in a Qdrant collection. Values stay in the wrapped store; Qdrant
+// only holds vectors plus enough payload to rebuild a hit if the value is gone.
+type QdrantMemoryStore struct {
+	multiagent.MemoryStore
+
+	client     *qdrant.Client
+	collection string
+	embedder   multiagent.Embedder
+
+	collectionMu    sync.Mutex
+	collectionReady bool
+}
+
+// QdrantMemoryStoreConfig holds configuration for creating a QdrantMemoryStore
+type QdrantMemoryStoreConfig struct {
+	// Base is the store that keeps the actual values
+	Base multiagent.MemoryStore
+	// Host and Port of the Qdrant gRPC API (default localhost:6334)
+	Host string
+	Port int
+	// APIKey, if set, authenticates with Qdrant; UseTLS encrypts the connection
+	APIKey string
+	UseTLS bool
+	// Collection name for agent memories (default "wikillm_memory")
+	Collection string
+	Embedder   multiagent.Embedder
+}
+
+// NewQdrantMemoryStore wraps a MemoryStore with Qdrant-backed similarity search
+func NewQdrantMemoryStore(config QdrantMemoryStoreConfig) (*QdrantMemoryStore, error) {
+	if config.Base == nil {
+		return nil, fmt.Errorf("base memory store is required")
+	}
+	if config.Embedder == nil {
+		return nil, fmt.Errorf("embedder is required")
+	}
+	if config.Collection == "" {
+		config.Collection = "wikillm_memory"
+	}
+
+	client, err := qdrant.NewClient(&qdrant.Config{
+		Host:   config.Host,
+		Port:   config.Port,
+		APIKey: config.APIKey,
+		UseTLS: config.UseTLS,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create qdrant client: %w", err)
+	}
+
+	return &QdrantMemoryStore{
+		MemoryStore: config.Base,
+		client:      client,
+		collection:  config.Collection,
+		embedder:    config.Embedder,
+	}, nil
+}
+
+// Close releases the Qdrant connection; the base store is left open
+func (s *QdrantMemoryStore) Close() error {
+	return s.client.Close()
+}
+
+// StoreEmbedding embeds text and upserts it into the collection under key
+func (s *QdrantMemoryStore) StoreEmbedding(ctx context.Context, key string, text string, metadata map[string]interface{}) error {
+	vectors, err := s.embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return fmt.Errorf("failed to embed text: %w", err)
+	}
+	if len(vectors) != 1 || len(vectors[0]) == 0 {
+		return fmt.Errorf("embedder returned no vector for %s", key)
+	}
+
+	if err := s.ensureCollection(ctx, len(vectors[0])); err != nil {
+		return err
+	}
+
+	category, _ := extractKeyMetadata(key)
+	payload := map[string]interface{}{
+		"key":       key,
+		"text":      text,
+		"category":  category,
+		"stored_at": time.Now().Format(time.RFC3339),
+	}
+	if len(metadata) > 0 {
+		// Round-trip through JSON so the payload only holds types Qdrant
+		// values can represent
+		data, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		var normalized map[string]interface{}
+		if err := json.Unmarshal(data, &normalized); err != nil {
+			return fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+		payload["metadata"] = normalized
+	}
+	values, err := qdrant.TryValueMap(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	_, err = s.client.Upsert(ctx, &qdrant.UpsertPoints{
+		CollectionName: s.collection,
+		Wait:           qdrant.PtrOf(true),
+		Points: []*qdrant.PointStruct{{
+			Id:      qdrant.NewID(pointID(key)),
+			Vectors: qdrant.NewVectorsDense(vectors[0]),
+			Payload: values,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upsert embedding: %w", err)
+	}
+
+	return nil
+}
+
+// SearchSimilar embeds query and returns the closest stored memories
+func (s *QdrantMemoryStore) SearchSimilar(ctx context.Context, query string, limit int) ([]multiagent.SimilarMemory, error) {
+	vectors, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(vectors) != 1 || len(vectors[0]) == 0 {
+		return nil, fmt.Errorf("embedder returned no vector for query")
+	}
+
+	hits, err := s.client.Query(ctx, &qdrant.QueryPoints{
+		CollectionName: s.collection,
+		Query:          qdrant.NewQuery(vectors[0]...),
+		Limit:          qdrant.PtrOf(uint64(limit)),
+		WithPayload:    qdrant.NewWithPayload(true),
+	})
+	if err != nil {
+		if isQdrantNotFound(err) {
+			// Nothing has been embedded yet
+			return []multiagent.SimilarMemory{}, nil
+		}
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	}
+
+	memories := make([]multiagent.SimilarMemory, 0, len(hits))
+	for _, hit := range hits {
+		payload := hit.GetPayload()
+		key := payload["key"].GetStringValue()
+		text := payload["text"].GetStringValue()
+		entry := multiagent.MemoryEntry{
+			Key:      key,
+			Category: payload["category"].GetStringValue(),
+		}
+		if metadata, ok := qdrantValue(payload["metadata"]).(map[string]interface{}); ok {
+			entry.Metadata = metadata
+		}
+
+		// Prefer the live value; fall back to the embedded text if it expired
+		if value, err := s.MemoryStore.Get(ctx, key); err == nil {
+			entry.Value = value
+		} else {
+			entry.Value = text
+		}
+
+		memories = append(memories, multiagent.SimilarMemory{
+			Entry: entry,
+			Text:  text,
+			Score: float64(hit.GetScore()),
+		})
+	}
+
+	return memories, nil
+}
+
+// Delete removes the value from the base store and its embedding from Qdrant
+func (s *QdrantMemoryStore) Delete(ctx context.Context, key string) error {
+	if err := s.MemoryStore.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	_, err := s.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: s.collection,
+		Wait:           qdrant.PtrOf(true),
+		Points:         qdrant.NewPointsSelector(qdrant.NewID(pointID(key))),
+	})
+	if err != nil && !isQdrantNotFound(err) {
+		return fmt.Errorf("failed to delete embedding: %w", err)
+	}
+
+	return nil
+}
+
+// ListEntries delegates to the base store so the janitor can maintain it
+func (s *QdrantMemoryStore) ListEntries(ctx context.Context, prefix string) ([]EntryInfo, error) {
+	lister, ok := s.MemoryStore.(EntryLister)
+	if !ok {
+		return nil, ErrListEntriesUnsupported
+	}
+	return lister.ListEntries(ctx, prefix)
+}
+
+// Internal helper methods
+
+// ensureCollection creates the collection on first use, sized to the embedder
+func (s *QdrantMemoryStore) ensureCollection(ctx context.Context, vectorSize int) error {
+	s.collectionMu.Lock()
+	defer s.collectionMu.Unlock()
+
+	if s.collectionReady {
+		return nil
+	}
+
+	info, err := s.client.GetCollectionInfo(ctx, s.collection)
+	switch {
+	case err == nil:
+		if existing := info.GetConfig().GetParams().GetVectorsConfig().GetParams().GetSize(); existing != uint64(vectorSize) {
+			return fmt.Errorf("dimension mismatch: collection %s has %d dimensions but embedder produces %d", s.collection, existing, vectorSize)
+		}
+	case isQdrantNotFound(err):
+		err := s.client.CreateCollection(ctx, &qdrant.CreateCollection{
+			CollectionName: s.collection,
+			VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
+				Size:     uint64(vectorSize),
+				Distance: qdrant.Distance_Cosine,
+			}),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create collection: %w", err)
+		}
+	default:
+		return fmt.Errorf("failed to check collection: %w", err)
+	}
+
+	s.collectionReady = true
+	return nil
+}
+
+// isQdrantNotFound reports whether err is Qdrant saying the collection or
+// point does not exist
+func isQdrantNotFound(err error) bool {
+	return status.Code(err) == codes.NotFound
+}
+
+// qdrantValue converts a payload value back into the Go value it was made from
+func qdrantValue(value *qdrant.Value) interface{} {
+	switch kind := value.GetKind().(type) {
+	case *qdrant.Value_BoolValue:
+		return kind.BoolValue
+	case *qdrant.Value_IntegerValue:
+		return kind.IntegerValue
+	case *qdrant.Value_DoubleValue:
+		return kind.DoubleValue
+	case *qdrant.Value_StringValue:
+		return kind.StringValue
+	case *qdrant.Value_StructValue:
+		fields := make(map[string]interface{}, len(kind.StructValue.GetFields()))
+		for name, field := range kind.StructValue.GetFields() {
+			fields[name] = qdrantValue(field)
+		}
+		return fields
+	case *qdrant.Value_ListValue:
+		items := make([]interface{}, len(kind.ListValue.GetValues()))
+		for i, item := range kind.ListValue.GetValues() {
+			items[i] = qdrantValue(item)
+		}
+		return items
+	default:
+		return nil
+	}
+}
+
+// pointID maps a memory key onto a stable UUID, since Qdrant point IDs must
+// be unsigned integers or UUIDs
+func pointID(key string) string {
+	sum := sha1.Sum([]byte(key))
+	sum[6] = (sum[6] & 0x0f) | 0x50 // version 5
+	sum[8] = (sum[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
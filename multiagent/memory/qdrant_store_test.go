@@ -0,0 +1,273 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQdrantMemoryStore_RecallsSimilarMemories(t *testing.T) {
+	ctx := context.Background()
+	fake, store := newTestQdrantStore(t)
+
+	// Before anything is embedded there is no collection to search
+	if hits, err := store.SearchSimilar(ctx, "tea", 5); err != nil || len(hits) != 0 {
+		t.Fatalf("expected no hits without a collection, got %v, %v", hits, err)
+	}
+
+	for key, text := range map[string]string{
+		"fact:drink": "prefers green tea in the afternoon",
+		"fact:car":   "drives an electric car to work",
+	} {
+		if err := store.Store(ctx, key, text); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+		if err := store.StoreEmbedding(ctx, key, text, map[string]interface{}{"agent_id": "conversation_agent", "tags": []string{"fact"}}); err != nil {
+			t.Fatalf("StoreEmbedding: %v", err)
+		}
+	}
+	if size := fake.collectionSize("wikillm_memory"); size != 3 {
+		t.Fatalf("expected a 3-dimensional collection, got %d", size)
+	}
+
+	hits, err := store.SearchSimilar(ctx, "tea", 1)
+	if err != nil {
+		t.Fatalf("SearchSimilar: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Entry.Key != "fact:drink" || hits[0].Entry.Value != "prefers green tea in the afternoon" {
+		t.Fatalf("expected the tea fact, got %+v", hits)
+	}
+	if hits[0].Entry.Category != "fact" || hits[0].Entry.Metadata["agent_id"] != "conversation_agent" {
+		t.Errorf("expected the payload to carry category and metadata, got %+v", hits[0].Entry)
+	}
+
+	// Deleting removes the embedding too
+	if err := store.Delete(ctx, "fact:drink"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	hits, err = store.SearchSimilar(ctx, "tea", 5)
+	if err != nil {
+		t.Fatalf("SearchSimilar: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Entry.Key != "fact:car" {
+		t.Fatalf("expected only the car fact left, got %+v", hits)
+	}
+}
+
+func TestQdrantMemoryStore_RejectsMismatchedCollection(t *testing.T) {
+	ctx := context.Background()
+	fake, store := newTestQdrantStore(t)
+	fake.createCollection("wikillm_memory", 768)
+
+	err := store.StoreEmbedding(ctx, "fact:drink", "prefers green tea", nil)
+	if err == nil || !strings.Contains(err.Error(), "dimension mismatch") {
+		t.Fatalf("expected a dimension mismatch, got %v", err)
+	}
+}
+
+func TestQdrantMemoryStore_DeleteWithoutCollection(t *testing.T) {
+	ctx := context.Background()
+	_, store := newTestQdrantStore(t)
+
+	if err := store.Store(ctx, "fact:drink", "tea"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := store.Delete(ctx, "fact:drink"); err != nil {
+		t.Fatalf("expected deleting an unembedded key to succeed, got %v", err)
+	}
+}
+
+func newTestQdrantStore(t *testing.T) (*fakeQdrant, *QdrantMemoryStore) {
+	t.Helper()
+	fake, addr := newFakeQdrant(t)
+	host, port, _ := net.SplitHostPort(addr)
+	portNumber, _ := strconv.Atoi(port)
+	store, err := NewQdrantMemoryStore(QdrantMemoryStoreConfig{
+		Base:     NewInMemoryStore(),
+		Host:     host,
+		Port:     portNumber,
+		Embedder: keywordEmbedder{"tea", "car", "work"},
+	})
+	if err != nil {
+		t.Fatalf("NewQdrantMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return fake, store
+}
+
+// keywordEmbedder embeds text as how often it mentions each keyword
+type keywordEmbedder []string
+
+func (e keywordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(e))
+		for j, keyword := range e {
+			vectors[i][j] = float32(strings.Count(strings.ToLower(text), keyword))
+		}
+	}
+	return vectors, nil
+}
+
+// fakeQdrant is a minimal Qdrant gRPC server: collections of dense points
+// searched by cosine similarity, answering NotFound like Qdrant does
+type fakeQdrant struct {
+	mu          sync.Mutex
+	collections map[string]*fakeCollection
+}
+
+type fakeCollection struct {
+	size   uint64
+	points map[string]*qdrant.PointStruct
+}
+
+type fakeQdrantCollections struct {
+	qdrant.UnimplementedCollectionsServer
+	*fakeQdrant
+}
+
+type fakeQdrantPoints struct {
+	qdrant.UnimplementedPointsServer
+	*fakeQdrant
+}
+
+type fakeQdrantHealth struct {
+	qdrant.UnimplementedQdrantServer
+}
+
+func newFakeQdrant(t *testing.T) (*fakeQdrant, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeQdrant{collections: make(map[string]*fakeCollection)}
+	server := grpc.NewServer()
+	qdrant.RegisterCollectionsServer(server, &fakeQdrantCollections{fakeQdrant: fake})
+	qdrant.RegisterPointsServer(server, &fakeQdrantPoints{fakeQdrant: fake})
+	qdrant.RegisterQdrantServer(server, &fakeQdrantHealth{})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return fake, listener.Addr().String()
+}
+
+func (f *fakeQdrant) createCollection(name string, size uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.collections[name] = &fakeCollection{size: size, points: make(map[string]*qdrant.PointStruct)}
+}
+
+func (f *fakeQdrant) collectionSize(name string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if collection, ok := f.collections[name]; ok {
+		return collection.size
+	}
+	return 0
+}
+
+// collection returns name's collection; the caller holds f.mu
+func (f *fakeQdrant) collection(name string) (*fakeCollection, error) {
+	collection, ok := f.collections[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "Collection `%s` doesn't exist!", name)
+	}
+	return collection, nil
+}
+
+func (h *fakeQdrantHealth) HealthCheck(ctx context.Context, req *qdrant.HealthCheckRequest) (*qdrant.HealthCheckReply, error) {
+	return &qdrant.HealthCheckReply{Title: "qdrant", Version: "1.14.0"}, nil
+}
+
+func (c *fakeQdrantCollections) Get(ctx context.Context, req *qdrant.GetCollectionInfoRequest) (*qdrant.GetCollectionInfoResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	collection, err := c.collection(req.GetCollectionName())
+	if err != nil {
+		return nil, err
+	}
+	return &qdrant.GetCollectionInfoResponse{Result: &qdrant.CollectionInfo{
+		Config: &qdrant.CollectionConfig{Params: &qdrant.CollectionParams{
+			VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{Size: collection.size, Distance: qdrant.Distance_Cosine}),
+		}},
+	}}, nil
+}
+
+func (c *fakeQdrantCollections) Create(ctx context.Context, req *qdrant.CreateCollection) (*qdrant.CollectionOperationResponse, error) {
+	c.createCollection(req.GetCollectionName(), req.GetVectorsConfig().GetParams().GetSize())
+	return &qdrant.CollectionOperationResponse{Result: true}, nil
+}
+
+func (p *fakeQdrantPoints) Upsert(ctx context.Context, req *qdrant.UpsertPoints) (*qdrant.PointsOperationResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	collection, err := p.collection(req.GetCollectionName())
+	if err != nil {
+		return nil, err
+	}
+	for _, point := range req.GetPoints() {
+		if uint64(len(point.GetVectors().GetVector().GetData())) != collection.size {
+			return nil, status.Errorf(codes.InvalidArgument, "wrong vector dimension")
+		}
+		collection.points[point.GetId().GetUuid()] = point
+	}
+	return &qdrant.PointsOperationResponse{Result: &qdrant.UpdateResult{Status: qdrant.UpdateStatus_Completed}}, nil
+}
+
+func (p *fakeQdrantPoints) Delete(ctx context.Context, req *qdrant.DeletePoints) (*qdrant.PointsOperationResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	collection, err := p.collection(req.GetCollectionName())
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range req.GetPoints().GetPoints().GetIds() {
+		delete(collection.points, id.GetUuid())
+	}
+	return &qdrant.PointsOperationResponse{Result: &qdrant.UpdateResult{Status: qdrant.UpdateStatus_Completed}}, nil
+}
+
+func (p *fakeQdrantPoints) Query(ctx context.Context, req *qdrant.QueryPoints) (*qdrant.QueryResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	collection, err := p.collection(req.GetCollectionName())
+	if err != nil {
+		return nil, err
+	}
+	query := req.GetQuery().GetNearest().GetDense().GetData()
+	var hits []*qdrant.ScoredPoint
+	for _, point := range collection.points {
+		hits = append(hits, &qdrant.ScoredPoint{
+			Id:      point.GetId(),
+			Payload: point.GetPayload(),
+			Score:   cosine(query, point.GetVectors().GetVector().GetData()),
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if limit := int(req.GetLimit()); limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return &qdrant.QueryResponse{Result: hits}, nil
+}
+
+func cosine(a, b []float32) float32 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
@@ -0,0 +1,639 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisMemoryStore implements MemoryStore on Redis so that several service
+// instances, or out-of-process agents, can share one memory keyspace.
+//
+// Layout (all keys are namespaced by KeyPrefix):
+//
+//	<prefix>entry:<key>  hash holding the serialized MemoryEntry fields
+//	<prefix>stats:<key>  hash of access_count and accessed_at, kept out of the
+//	                     entry so reads aren't reported as writes
+//	<prefix>keys         sorted set of all keys (score 0) for lexicographic prefix scans
+//	<prefix>tag:<tag>    set of keys carrying a tag
+//
+// Entry TTLs map directly onto Redis expirations; index references to expired
+// entries are pruned lazily on read and by Cleanup.
+type RedisMemoryStore struct {
+	client           *redis.Client
+	prefix           string
+	maxUpdateRetries int
+	changes          chan MemoryChange
+	pubsub           *redis.PubSub
+}
+
+// RedisMemoryStoreConfig holds configuration for creating a RedisMemoryStore
+type RedisMemoryStoreConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix namespaces all keys written by the store (default "wikillm:memory:")
+	KeyPrefix string
+	// MaxUpdateRetries bounds optimistic-lock retries in Update (default 10)
+	MaxUpdateRetries int
+	// KeyspaceNotifications subscribes to Redis keyspace events and publishes
+	// them on Changes(), so other processes' writes can be observed
+	KeyspaceNotifications bool
+	// ConfigureNotifications issues CONFIG SET notify-keyspace-events when
+	// KeyspaceNotifications is enabled; disable for managed Redis that forbids CONFIG
+	ConfigureNotifications bool
+}
+
+// MemoryChangeType describes what happened to a key
+type MemoryChangeType string
+
+const (
+	MemoryChangeSet     MemoryChangeType = "set"
+	MemoryChangeDeleted MemoryChangeType = "deleted"
+	MemoryChangeExpired MemoryChangeType = "expired"
+)
+
+// MemoryChange is emitted when a key changes in a shared memory backend
+type MemoryChange struct {
+	Key       string           `json:"key"`
+	Type      MemoryChangeType `json:"type"`
+	Timestamp time.Time        `json:"timestamp"`
+}
+
+// ErrUpdateConflict is returned when Update keeps losing optimistic-lock races
+var ErrUpdateConflict = errors.New("memory update conflict: too many concurrent writers")
+
+// NewRedisMemoryStore connects to Redis and returns a memory store
+func NewRedisMemoryStore(config RedisMemoryStoreConfig) (*RedisMemoryStore, error) {
+	if config.Addr == "" {
+		config.Addr = "localhost:6379"
+	}
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = "wikillm:memory:"
+	}
+	if config.MaxUpdateRetries == 0 {
+		config.MaxUpdateRetries = 10
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.Addr,
+		Password: config.Password,
+		DB:       config.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", config.Addr, err)
+	}
+
+	store := &RedisMemoryStore{
+		client:           client,
+		prefix:           config.KeyPrefix,
+		maxUpdateRetries: config.MaxUpdateRetries,
+	}
+
+	if config.KeyspaceNotifications {
+		if config.ConfigureNotifications {
+			// K = keyspace channel, g = generic (del), h = hash, x = expired
+			if err := client.ConfigSet(ctx, "notify-keyspace-events", "Khgx").Err(); err != nil {
+				client.Close()
+				return nil, fmt.Errorf("failed to enable keyspace notifications: %w", err)
+			}
+		}
+
+		channel := fmt.Sprintf("__keyspace@%d__:%sentry:*", config.DB, config.KeyPrefix)
+		store.pubsub = client.PSubscribe(context.Background(), channel)
+		store.changes = make(chan MemoryChange, 256)
+		go store.notificationLoop()
+	}
+
+	return store, nil
+}
+
+// Changes returns the change-event stream, or nil if keyspace notifications are disabled
+func (s *RedisMemoryStore) Changes() <-chan MemoryChange {
+	return s.changes
+}
+
+// Close releases the Redis connection and notification subscription
+func (s *RedisMemoryStore) Close() error {
+	if s.pubsub != nil {
+		s.pubsub.Close()
+	}
+	return s.client.Close()
+}
+
+// Store saves a value with the given key
+func (s *RedisMemoryStore) Store(ctx context.Context, key string, value interface{}) error {
+	return s.StoreWithTTL(ctx, key, value, 0)
+}
+
+// StoreWithTTL saves a value with the given key, mapping ttl to a Redis expiration
+func (s *RedisMemoryStore) StoreWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	now := time.Now()
+
+	entry := multiagent.MemoryEntry{
+		Key:        key,
+		Value:      value,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		AccessedAt: now,
+	}
+	if ttl > 0 {
+		entry.TTL = &ttl
+		expiresAt := now.Add(ttl)
+		entry.ExpiresAt = &expiresAt
+	}
+	entry.Category, entry.Tags = extractKeyMetadata(key)
+
+	fields, err := encodeRedisEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	entryKey := s.entryKey(key)
+	txf := func(tx *redis.Tx) error {
+		// An overwrite keeps the entry's creation time
+		createdAt, err := tx.HGet(ctx, entryKey, "created_at").Result()
+		if err == nil {
+			fields["created_at"] = createdAt
+		} else if !errors.Is(err, redis.Nil) {
+			return err
+		}
+
+		// The hash is updated in place rather than deleted and rewritten, so
+		// watchers see one write instead of a deletion followed by a write
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, entryKey, fields)
+			if stale := staleRedisFields(fields); len(stale) > 0 {
+				pipe.HDel(ctx, entryKey, stale...)
+			}
+			if ttl > 0 {
+				pipe.PExpire(ctx, entryKey, ttl)
+			} else {
+				pipe.Persist(ctx, entryKey)
+			}
+			pipe.Del(ctx, s.statsKey(key))
+			pipe.ZAdd(ctx, s.keysKey(), redis.Z{Score: 0, Member: key})
+			for _, tag := range entry.Tags {
+				pipe.SAdd(ctx, s.tagKey(tag), key)
+			}
+			return nil
+		})
+		return err
+	}
+
+	if err := s.watch(ctx, key, txf); err != nil {
+		return fmt.Errorf("failed to store entry: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves a value by key
+func (s *RedisMemoryStore) Get(ctx context.Context, key string) (interface{}, error) {
+	entry, err := s.getEntry(ctx, s.client, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update access time and count beside the entry, expiring them with it
+	// so a read racing the expiration cannot leave them behind
+	statsKey := s.statsKey(key)
+	s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, statsKey, "access_count", 1)
+		pipe.HSet(ctx, statsKey, "accessed_at", time.Now().UnixNano())
+		if entry.ExpiresAt != nil {
+			pipe.PExpireAt(ctx, statsKey, *entry.ExpiresAt)
+		}
+		return nil
+	})
+
+	return entry.Value, nil
+}
+
+// GetMultiple retrieves multiple values by keys
+func (s *RedisMemoryStore) GetMultiple(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	results := make(map[string]interface{})
+
+	for _, key := range keys {
+		value, err := s.Get(ctx, key)
+		if err == nil {
+			results[key] = value
+		}
+	}
+
+	return results, nil
+}
+
+// Search searches for entries whose key or category and value match the query
+func (s *RedisMemoryStore) Search(ctx context.Context, query string, limit int) ([]multiagent.MemoryEntry, error) {
+	queryLower := strings.ToLower(query)
+	results := make([]multiagent.MemoryEntry, 0, limit)
+
+	keys, err := s.client.ZRange(ctx, s.keysKey(), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key index: %w", err)
+	}
+
+	for _, key := range keys {
+		entry, err := s.getEntry(ctx, s.client, key)
+		if err != nil {
+			continue
+		}
+
+		if !strings.Contains(strings.ToLower(key), queryLower) &&
+			!strings.Contains(strings.ToLower(entry.Category), queryLower) {
+			continue
+		}
+
+		valueStr := fmt.Sprintf("%v", entry.Value)
+		if strings.Contains(strings.ToLower(valueStr), queryLower) {
+			results = append(results, *entry)
+			if len(results) >= limit {
+				break
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// SearchByTags searches for entries with all of the given tags
+func (s *RedisMemoryStore) SearchByTags(ctx context.Context, tags []string, limit int) ([]multiagent.MemoryEntry, error) {
+	results := make([]multiagent.MemoryEntry, 0, limit)
+	if len(tags) == 0 {
+		return results, nil
+	}
+
+	tagKeys := make([]string, len(tags))
+	for i, tag := range tags {
+		tagKeys[i] = s.tagKey(tag)
+	}
+
+	keys, err := s.client.SInter(ctx, tagKeys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to intersect tags: %w", err)
+	}
+
+	for _, key := range keys {
+		entry, err := s.getEntry(ctx, s.client, key)
+		if err != nil {
+			continue
+		}
+		results = append(results, *entry)
+		if len(results) >= limit {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+// Delete removes an entry by key
+func (s *RedisMemoryStore) Delete(ctx context.Context, key string) error {
+	_, tags := extractKeyMetadata(key)
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, s.entryKey(key), s.statsKey(key))
+		pipe.ZRem(ctx, s.keysKey(), key)
+		for _, tag := range tags {
+			pipe.SRem(ctx, s.tagKey(tag), key)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete entry: %w", err)
+	}
+
+	return nil
+}
+
+// Update applies updater under optimistic locking (WATCH/MULTI), retrying
+// when another writer modifies the key between read and write
+func (s *RedisMemoryStore) Update(ctx context.Context, key string, updater func(interface{}) (interface{}, error)) error {
+	entryKey := s.entryKey(key)
+
+	txf := func(tx *redis.Tx) error {
+		entry, err := s.getEntry(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+
+		newValue, err := updater(entry.Value)
+		if err != nil {
+			return err
+		}
+
+		valueData, err := json.Marshal(newValue)
+		if err != nil {
+			return fmt.Errorf("failed to marshal value: %w", err)
+		}
+
+		// Reapplying the expiration alongside HSET means an entry that
+		// expires mid-update is removed again rather than recreated without one
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, entryKey,
+				"value", valueData,
+				"updated_at", time.Now().UnixNano(),
+			)
+			if entry.ExpiresAt != nil {
+				pipe.PExpireAt(ctx, entryKey, *entry.ExpiresAt)
+			}
+			return nil
+		})
+		return err
+	}
+
+	return s.watch(ctx, key, txf)
+}
+
+// watch runs txf with key's entry watched, retrying when another writer
+// modifies it between read and write
+func (s *RedisMemoryStore) watch(ctx context.Context, key string, txf func(*redis.Tx) error) error {
+	for attempt := 0; attempt < s.maxUpdateRetries; attempt++ {
+		err := s.client.Watch(ctx, txf, s.entryKey(key))
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrUpdateConflict, key)
+}
+
+// List returns keys matching a prefix using a lexicographic range scan
+func (s *RedisMemoryStore) List(ctx context.Context, prefix string, limit int) ([]string, error) {
+	rangeBy := &redis.ZRangeBy{Min: "[" + prefix, Max: "+", Count: int64(limit)}
+	if upper, ok := prefixUpperBound(prefix); ok {
+		rangeBy.Max = "(" + upper
+	}
+	if prefix == "" {
+		rangeBy.Min = "-"
+	}
+
+	candidates, err := s.client.ZRangeByLex(ctx, s.keysKey(), rangeBy).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	// Filter out keys whose entries have already expired in Redis
+	pipe := s.client.Pipeline()
+	exists := make([]*redis.IntCmd, len(candidates))
+	for i, key := range candidates {
+		exists[i] = pipe.Exists(ctx, s.entryKey(key))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to check keys: %w", err)
+	}
+
+	keys := make([]string, 0, len(candidates))
+	for i, key := range candidates {
+		if exists[i].Val() > 0 {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// ListEntries returns metadata for keys matching prefix; entries Redis has
+// already expired are skipped
+func (s *RedisMemoryStore) ListEntries(ctx context.Context, prefix string) ([]EntryInfo, error) {
+	rangeBy := &redis.ZRangeBy{Min: "[" + prefix, Max: "+"}
+	if upper, ok := prefixUpperBound(prefix); ok {
+		rangeBy.Max = "(" + upper
+	}
+	if prefix == "" {
+		rangeBy.Min = "-"
+	}
+
+	keys, err := s.client.ZRangeByLex(ctx, s.keysKey(), rangeBy).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(keys))
+	accessed := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.HMGet(ctx, s.entryKey(key), "created_at", "accessed_at", "expires_at")
+		accessed[i] = pipe.HGet(ctx, s.statsKey(key), "accessed_at")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read entry metadata: %w", err)
+	}
+
+	parseNanos := func(v interface{}) (time.Time, bool) {
+		str, ok := v.(string)
+		if !ok {
+			return time.Time{}, false
+		}
+		n, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(0, n), true
+	}
+
+	infos := make([]EntryInfo, 0, len(keys))
+	for i, key := range keys {
+		values := cmds[i].Val()
+		if len(values) != 3 || values[0] == nil {
+			continue
+		}
+
+		info := EntryInfo{Key: key}
+		info.CreatedAt, _ = parseNanos(values[0])
+		info.AccessedAt, _ = parseNanos(values[1])
+		if accessedAt, ok := parseNanos(accessed[i].Val()); ok {
+			info.AccessedAt = accessedAt
+		}
+		if expiresAt, ok := parseNanos(values[2]); ok {
+			info.ExpiresAt = &expiresAt
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// Cleanup prunes index and tag references to entries Redis has already expired
+func (s *RedisMemoryStore) Cleanup(ctx context.Context) error {
+	keys, err := s.client.ZRange(ctx, s.keysKey(), 0, -1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read key index: %w", err)
+	}
+
+	for _, key := range keys {
+		n, err := s.client.Exists(ctx, s.entryKey(key)).Result()
+		if err != nil {
+			return fmt.Errorf("failed to check key %s: %w", key, err)
+		}
+		if n == 0 {
+			if err := s.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Internal helper methods
+
+func (s *RedisMemoryStore) entryKey(key string) string { return s.prefix + "entry:" + key }
+func (s *RedisMemoryStore) statsKey(key string) string { return s.prefix + "stats:" + key }
+func (s *RedisMemoryStore) keysKey() string            { return s.prefix + "keys" }
+func (s *RedisMemoryStore) tagKey(tag string) string   { return s.prefix + "tag:" + tag }
+
+func (s *RedisMemoryStore) getEntry(ctx context.Context, c redis.Cmdable, key string) (*multiagent.MemoryEntry, error) {
+	fields, err := c.HGetAll(ctx, s.entryKey(key)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entry: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("key not found: %s", key)
+	}
+
+	stats, err := c.HGetAll(ctx, s.statsKey(key)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access stats: %w", err)
+	}
+	for name, value := range stats {
+		fields[name] = value
+	}
+
+	return decodeRedisEntry(key, fields)
+}
+
+func (s *RedisMemoryStore) notificationLoop() {
+	defer close(s.changes)
+
+	entryPrefix := s.prefix + "entry:"
+	for msg := range s.pubsub.Channel() {
+		// Channel is "__keyspace@<db>__:<prefix>entry:<key>", payload is the command
+		idx := strings.Index(msg.Channel, entryPrefix)
+		if idx < 0 {
+			continue
+		}
+
+		change := MemoryChange{
+			Key:       msg.Channel[idx+len(entryPrefix):],
+			Timestamp: time.Now(),
+		}
+		switch msg.Payload {
+		case "hset":
+			change.Type = MemoryChangeSet
+		case "del":
+			change.Type = MemoryChangeDeleted
+		case "expired":
+			change.Type = MemoryChangeExpired
+		default:
+			continue
+		}
+
+		select {
+		case s.changes <- change:
+		default:
+			logger.Warn("RedisMemoryStore change stream full, dropping event", "key", change.Key)
+		}
+	}
+}
+
+func encodeRedisEntry(entry multiagent.MemoryEntry) (map[string]interface{}, error) {
+	valueData, err := json.Marshal(entry.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	tagsData, err := json.Marshal(entry.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	fields := map[string]interface{}{
+		"value":        valueData,
+		"category":     entry.Category,
+		"tags":         tagsData,
+		"created_at":   entry.CreatedAt.UnixNano(),
+		"updated_at":   entry.UpdatedAt.UnixNano(),
+		"accessed_at":  entry.AccessedAt.UnixNano(),
+		"access_count": entry.AccessCount,
+	}
+	if entry.Metadata != nil {
+		metadataData, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		fields["metadata"] = metadataData
+	}
+	if entry.TTL != nil {
+		fields["ttl"] = int64(*entry.TTL)
+	}
+	if entry.ExpiresAt != nil {
+		fields["expires_at"] = entry.ExpiresAt.UnixNano()
+	}
+
+	return fields, nil
+}
+
+// staleRedisFields returns the optional entry fields missing from fields,
+// which an overwrite must remove from the existing hash
+func staleRedisFields(fields map[string]interface{}) []string {
+	var stale []string
+	for _, name := range []string{"metadata", "ttl", "expires_at"} {
+		if _, ok := fields[name]; !ok {
+			stale = append(stale, name)
+		}
+	}
+	return stale
+}
+
+func decodeRedisEntry(key string, fields map[string]string) (*multiagent.MemoryEntry, error) {
+	entry := &multiagent.MemoryEntry{
+		Key:      key,
+		Category: fields["category"],
+	}
+
+	if err := json.Unmarshal([]byte(fields["value"]), &entry.Value); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal entry: %w", err)
+	}
+	if tags := fields["tags"]; tags != "" {
+		json.Unmarshal([]byte(tags), &entry.Tags)
+	}
+	if metadata := fields["metadata"]; metadata != "" {
+		json.Unmarshal([]byte(metadata), &entry.Metadata)
+	}
+
+	parseNanos := func(name string) time.Time {
+		n, _ := strconv.ParseInt(fields[name], 10, 64)
+		return time.Unix(0, n)
+	}
+	entry.CreatedAt = parseNanos("created_at")
+	entry.UpdatedAt = parseNanos("updated_at")
+	entry.AccessedAt = parseNanos("accessed_at")
+	entry.AccessCount, _ = strconv.Atoi(fields["access_count"])
+
+	if v, ok := fields["ttl"]; ok {
+		n, _ := strconv.ParseInt(v, 10, 64)
+		ttl := time.Duration(n)
+		entry.TTL = &ttl
+	}
+	if _, ok := fields["expires_at"]; ok {
+		expiresAt := parseNanos("expires_at")
+		entry.ExpiresAt = &expiresAt
+	}
+
+	return entry, nil
+}
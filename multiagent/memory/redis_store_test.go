@@ -0,0 +1,634 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRedisMemoryStore_ExpiresEntries(t *testing.T) {
+	ctx := context.Background()
+	fake, store := newTestRedisStore(t, false)
+
+	if err := store.StoreWithTTL(ctx, "session:1", "hello", time.Minute); err != nil {
+		t.Fatalf("StoreWithTTL: %v", err)
+	}
+	if v, err := store.Get(ctx, "session:1"); err != nil || v != "hello" {
+		t.Fatalf("expected session:1 before it expires, got %v, %v", v, err)
+	}
+	if err := store.Update(ctx, "session:1", func(v interface{}) (interface{}, error) {
+		return v.(string) + "!", nil
+	}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if ttl := fake.ttl("wikillm:memory:entry:session:1"); ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("expected the update to keep the expiration, got %v", ttl)
+	}
+
+	fake.fastForward(2 * time.Minute)
+	if _, err := store.Get(ctx, "session:1"); err == nil {
+		t.Fatal("expected session:1 to have expired")
+	}
+	if keys, _ := store.List(ctx, "session:", 10); len(keys) != 0 {
+		t.Fatalf("expected no listed keys, got %v", keys)
+	}
+	if err := store.Update(ctx, "session:1", func(v interface{}) (interface{}, error) {
+		return "resurrected", nil
+	}); err == nil {
+		t.Fatal("expected updating an expired entry to fail")
+	}
+	for _, key := range []string{"wikillm:memory:entry:session:1", "wikillm:memory:stats:session:1"} {
+		if fake.has(key) {
+			t.Errorf("expected %s to be gone after expiring", key)
+		}
+	}
+}
+
+func TestRedisMemoryStore_ReportsWritesNotReads(t *testing.T) {
+	ctx := context.Background()
+	fake, store := newTestRedisStore(t, true)
+
+	next := func() MemoryChange {
+		t.Helper()
+		select {
+		case change := <-store.Changes():
+			return change
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for a change")
+			return MemoryChange{}
+		}
+	}
+
+	if err := store.Store(ctx, "note:1", "first"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if change := next(); change.Key != "note:1" || change.Type != MemoryChangeSet {
+		t.Fatalf("expected note:1 set, got %+v", change)
+	}
+
+	// Reading and overwriting must not be reported as a deletion
+	for i := 0; i < 3; i++ {
+		if _, err := store.Get(ctx, "note:1"); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+	}
+	if err := store.Store(ctx, "note:1", "second"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if change := next(); change.Key != "note:1" || change.Type != MemoryChangeSet {
+		t.Fatalf("expected note:1 set by the overwrite, got %+v", change)
+	}
+
+	if err := store.Delete(ctx, "note:1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if change := next(); change.Key != "note:1" || change.Type != MemoryChangeDeleted {
+		t.Fatalf("expected note:1 deleted, got %+v", change)
+	}
+
+	if err := store.StoreWithTTL(ctx, "note:2", "brief", time.Minute); err != nil {
+		t.Fatalf("StoreWithTTL: %v", err)
+	}
+	if change := next(); change.Key != "note:2" || change.Type != MemoryChangeSet {
+		t.Fatalf("expected note:2 set, got %+v", change)
+	}
+	fake.fastForward(2 * time.Minute)
+	if change := next(); change.Key != "note:2" || change.Type != MemoryChangeExpired {
+		t.Fatalf("expected note:2 expired, got %+v", change)
+	}
+}
+
+func TestRedisMemoryStore_OverwriteKeepsCreatedAt(t *testing.T) {
+	ctx := context.Background()
+	fake, store := newTestRedisStore(t, false)
+
+	if err := store.StoreWithTTL(ctx, "fact:color", "blue", time.Minute); err != nil {
+		t.Fatalf("StoreWithTTL: %v", err)
+	}
+	if _, err := store.Get(ctx, "fact:color"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	before, err := store.ListEntries(ctx, "fact:")
+	if err != nil || len(before) != 1 {
+		t.Fatalf("ListEntries: %v, %v", before, err)
+	}
+
+	time.Sleep(time.Millisecond)
+	if err := store.Store(ctx, "fact:color", "green"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	after, err := store.ListEntries(ctx, "fact:")
+	if err != nil || len(after) != 1 {
+		t.Fatalf("ListEntries: %v, %v", after, err)
+	}
+	if !after[0].CreatedAt.Equal(before[0].CreatedAt) {
+		t.Errorf("expected created_at %v to survive the overwrite, got %v", before[0].CreatedAt, after[0].CreatedAt)
+	}
+	if after[0].ExpiresAt != nil {
+		t.Errorf("expected the overwrite to drop the expiration, got %v", after[0].ExpiresAt)
+	}
+
+	// The overwrite has no TTL, so the entry outlives the old one
+	fake.fastForward(2 * time.Minute)
+	if v, err := store.Get(ctx, "fact:color"); err != nil || v != "green" {
+		t.Fatalf("expected the overwritten entry, got %v, %v", v, err)
+	}
+}
+
+func newTestRedisStore(t *testing.T, notifications bool) (*fakeRedis, *RedisMemoryStore) {
+	t.Helper()
+	fake, addr := newFakeRedis(t)
+	store, err := NewRedisMemoryStore(RedisMemoryStoreConfig{
+		Addr:                   addr,
+		KeyspaceNotifications:  notifications,
+		ConfigureNotifications: notifications,
+	})
+	if err != nil {
+		t.Fatalf("NewRedisMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if notifications {
+		fake.waitForSubscriber(t)
+	}
+	return fake, store
+}
+
+// fakeRedis is a minimal RESP2 server with the commands RedisMemoryStore
+// uses, keyspace notifications for every key, and a clock tests can advance
+type fakeRedis struct {
+	mu          sync.Mutex
+	offset      time.Duration
+	hashes      map[string]map[string]string
+	sets        map[string]map[string]bool
+	expires     map[string]time.Time
+	versions    map[string]int
+	subscribers map[*fakeRedisConn][]string
+}
+
+type fakeRedisConn struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	queue   [][]string
+	queuing bool
+	watched map[string]int
+}
+
+// fakeRedis replies are written as RESP: string is a bulk string, nil a
+// null bulk string, and []interface{} an array
+type (
+	fakeStatus string
+	fakeError  string
+)
+
+var errFakeAborted = errors.New("transaction aborted")
+
+func newFakeRedis(t *testing.T) (*fakeRedis, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	s := &fakeRedis{
+		hashes:      make(map[string]map[string]string),
+		sets:        make(map[string]map[string]bool),
+		expires:     make(map[string]time.Time),
+		versions:    make(map[string]int),
+		subscribers: make(map[*fakeRedisConn][]string),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s, listener.Addr().String()
+}
+
+func (s *fakeRedis) serve(netConn net.Conn) {
+	defer netConn.Close()
+	conn := &fakeRedisConn{w: bufio.NewWriter(netConn)}
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, conn)
+		s.mu.Unlock()
+	}()
+
+	r := bufio.NewReader(netConn)
+	for {
+		args, err := readFakeCommand(r)
+		if err != nil {
+			return
+		}
+		reply := s.handle(conn, args)
+		conn.mu.Lock()
+		writeFakeReply(conn.w, reply)
+		conn.w.Flush()
+		conn.mu.Unlock()
+	}
+}
+
+func (s *fakeRedis) handle(conn *fakeRedisConn, args []string) interface{} {
+	name := strings.ToLower(args[0])
+	switch name {
+	case "multi":
+		conn.queuing = true
+		conn.queue = nil
+		return fakeStatus("OK")
+	case "discard":
+		conn.queuing = false
+		conn.queue = nil
+		return fakeStatus("OK")
+	case "exec":
+		return s.exec(conn)
+	case "psubscribe":
+		s.mu.Lock()
+		s.subscribers[conn] = append(s.subscribers[conn], args[1:]...)
+		s.mu.Unlock()
+		return []interface{}{"psubscribe", args[1], int64(len(args) - 1)}
+	}
+	if conn.queuing {
+		conn.queue = append(conn.queue, args)
+		return fakeStatus("QUEUED")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch name {
+	case "watch":
+		conn.watched = make(map[string]int)
+		for _, key := range args[1:] {
+			conn.watched[key] = s.versions[key]
+		}
+		return fakeStatus("OK")
+	case "unwatch":
+		conn.watched = nil
+		return fakeStatus("OK")
+	}
+	return s.run(args)
+}
+
+func (s *fakeRedis) exec(conn *fakeRedisConn) interface{} {
+	queue := conn.queue
+	watched := conn.watched
+	conn.queuing, conn.queue, conn.watched = false, nil, nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireDue()
+	for key, version := range watched {
+		if s.versions[key] != version {
+			return errFakeAborted
+		}
+	}
+	replies := make([]interface{}, len(queue))
+	for i, args := range queue {
+		replies[i] = s.run(args)
+	}
+	return replies
+}
+
+// run executes one command; the caller holds s.mu
+func (s *fakeRedis) run(args []string) interface{} {
+	s.expireDue()
+	name, args := strings.ToLower(args[0]), args[1:]
+	switch name {
+	case "ping":
+		return fakeStatus("PONG")
+	case "config":
+		return fakeStatus("OK")
+	case "hset":
+		hash := s.hashes[args[0]]
+		if hash == nil {
+			hash = make(map[string]string)
+			s.hashes[args[0]] = hash
+		}
+		var added int64
+		for i := 1; i+1 < len(args); i += 2 {
+			if _, ok := hash[args[i]]; !ok {
+				added++
+			}
+			hash[args[i]] = args[i+1]
+		}
+		s.touch(args[0], "hset")
+		return added
+	case "hincrby":
+		hash := s.hashes[args[0]]
+		if hash == nil {
+			hash = make(map[string]string)
+			s.hashes[args[0]] = hash
+		}
+		n, _ := strconv.ParseInt(hash[args[1]], 10, 64)
+		by, _ := strconv.ParseInt(args[2], 10, 64)
+		hash[args[1]] = strconv.FormatInt(n+by, 10)
+		s.touch(args[0], "hincrby")
+		return n + by
+	case "hdel":
+		var removed int64
+		for _, field := range args[1:] {
+			if _, ok := s.hashes[args[0]][field]; ok {
+				delete(s.hashes[args[0]], field)
+				removed++
+			}
+		}
+		if removed > 0 {
+			s.touch(args[0], "hdel")
+		}
+		return removed
+	case "hget":
+		if value, ok := s.hashes[args[0]][args[1]]; ok {
+			return value
+		}
+		return nil
+	case "hmget":
+		values := make([]interface{}, len(args)-1)
+		for i, field := range args[1:] {
+			if value, ok := s.hashes[args[0]][field]; ok {
+				values[i] = value
+			}
+		}
+		return values
+	case "hgetall":
+		var values []interface{}
+		for field, value := range s.hashes[args[0]] {
+			values = append(values, field, value)
+		}
+		return values
+	case "del":
+		var removed int64
+		for _, key := range args {
+			if s.exists(key) {
+				s.remove(key, "del")
+				removed++
+			}
+		}
+		return removed
+	case "exists":
+		var found int64
+		for _, key := range args {
+			if s.exists(key) {
+				found++
+			}
+		}
+		return found
+	case "pexpire", "pexpireat":
+		if !s.exists(args[0]) {
+			return int64(0)
+		}
+		ms, _ := strconv.ParseInt(args[1], 10, 64)
+		at := s.now().Add(time.Duration(ms) * time.Millisecond)
+		if name == "pexpireat" {
+			at = time.UnixMilli(ms)
+		}
+		if !at.After(s.now()) {
+			s.remove(args[0], "del")
+			return int64(1)
+		}
+		s.expires[args[0]] = at
+		s.touch(args[0], "expire")
+		return int64(1)
+	case "persist":
+		if _, ok := s.expires[args[0]]; !ok {
+			return int64(0)
+		}
+		delete(s.expires, args[0])
+		s.touch(args[0], "persist")
+		return int64(1)
+	case "zadd", "sadd":
+		members := args[1:]
+		if name == "zadd" {
+			members = nil
+			for i := 2; i < len(args); i += 2 {
+				members = append(members, args[i])
+			}
+		}
+		set := s.sets[args[0]]
+		if set == nil {
+			set = make(map[string]bool)
+			s.sets[args[0]] = set
+		}
+		var added int64
+		for _, member := range members {
+			if !set[member] {
+				set[member] = true
+				added++
+			}
+		}
+		s.touch(args[0], name)
+		return added
+	case "zrem", "srem":
+		var removed int64
+		for _, member := range args[1:] {
+			if s.sets[args[0]][member] {
+				delete(s.sets[args[0]], member)
+				removed++
+			}
+		}
+		return removed
+	case "zrange", "smembers":
+		return s.members(args[0], func(string) bool { return true })
+	case "zrangebylex":
+		members := s.members(args[0], func(member string) bool {
+			return fakeLexAbove(member, args[1]) && fakeLexBelow(member, args[2])
+		})
+		if len(args) == 6 && strings.EqualFold(args[3], "limit") {
+			count, _ := strconv.Atoi(args[5])
+			if count >= 0 && count < len(members) {
+				members = members[:count]
+			}
+		}
+		return members
+	case "sinter":
+		return s.members(args[0], func(member string) bool {
+			for _, key := range args[1:] {
+				if !s.sets[key][member] {
+					return false
+				}
+			}
+			return true
+		})
+	default:
+		return fakeError("ERR unknown command '" + name + "'")
+	}
+}
+
+func (s *fakeRedis) now() time.Time {
+	return time.Now().Add(s.offset)
+}
+
+func (s *fakeRedis) exists(key string) bool {
+	return len(s.hashes[key]) > 0 || len(s.sets[key]) > 0
+}
+
+func (s *fakeRedis) members(key string, keep func(string) bool) []interface{} {
+	var members []string
+	for member := range s.sets[key] {
+		if keep(member) {
+			members = append(members, member)
+		}
+	}
+	sort.Strings(members)
+	values := make([]interface{}, len(members))
+	for i, member := range members {
+		values[i] = member
+	}
+	return values
+}
+
+func (s *fakeRedis) remove(key, event string) {
+	delete(s.hashes, key)
+	delete(s.sets, key)
+	delete(s.expires, key)
+	s.touch(key, event)
+}
+
+func (s *fakeRedis) expireDue() {
+	for key, at := range s.expires {
+		if !at.After(s.now()) {
+			s.remove(key, "expired")
+		}
+	}
+}
+
+// touch bumps key's version for WATCH and publishes the keyspace event
+func (s *fakeRedis) touch(key, event string) {
+	s.versions[key]++
+	channel := "__keyspace@0__:" + key
+	for conn, patterns := range s.subscribers {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, channel); ok {
+				conn.mu.Lock()
+				writeFakeReply(conn.w, []interface{}{"pmessage", pattern, channel, event})
+				conn.w.Flush()
+				conn.mu.Unlock()
+			}
+		}
+	}
+}
+
+func (s *fakeRedis) fastForward(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset += d
+	s.expireDue()
+}
+
+func (s *fakeRedis) ttl(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if at, ok := s.expires[key]; ok {
+		return at.Sub(s.now())
+	}
+	return 0
+}
+
+func (s *fakeRedis) has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.exists(key)
+}
+
+func (s *fakeRedis) waitForSubscriber(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		subscribed := len(s.subscribers) > 0
+		s.mu.Unlock()
+		if subscribed {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for the keyspace subscription")
+}
+
+// fakeLexAbove and fakeLexBelow apply ZRANGEBYLEX bounds: - and +, or an
+// inclusive [ or exclusive ( prefix
+func fakeLexAbove(member, min string) bool {
+	switch {
+	case min == "-":
+		return true
+	case strings.HasPrefix(min, "["):
+		return member >= min[1:]
+	default:
+		return member > min[1:]
+	}
+}
+
+func fakeLexBelow(member, max string) bool {
+	switch {
+	case max == "+":
+		return true
+	case strings.HasPrefix(max, "["):
+		return member <= max[1:]
+	default:
+		return member < max[1:]
+	}
+}
+
+func readFakeCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected command %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func writeFakeReply(w *bufio.Writer, reply interface{}) {
+	switch v := reply.(type) {
+	case fakeStatus:
+		fmt.Fprintf(w, "+%s\r\n", v)
+	case fakeError:
+		fmt.Fprintf(w, "-%s\r\n", v)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case nil:
+		fmt.Fprintf(w, "$-1\r\n")
+	case error:
+		// Only an aborted EXEC replies with an error value: a null array
+		fmt.Fprintf(w, "*-1\r\n")
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeFakeReply(w, item)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// Scope identifies which namespace a memory key belongs to
+type Scope string
+
+const (
+	// ScopePrivate keys belong to a single agent; only the owner may write them
+	ScopePrivate Scope = "private"
+	// ScopeConversation keys are shared by every agent working on a conversation
+	ScopeConversation Scope = "conversation"
+	// ScopeGlobal keys are readable and writable by every agent
+	ScopeGlobal Scope = "global"
+)
+
+// Explicit namespace prefixes. Keys built with PrivateKey are hidden from
+// other agents entirely; legacy keys claimed through NamespacePolicy.Owners
+// stay readable by everyone but are write-protected.
+const (
+	privatePrefix      = "private:"
+	conversationPrefix = "shared:"
+	globalPrefix       = "global:"
+)
+
+// ErrScopeViolation is returned when an agent touches a key outside its scope
+var ErrScopeViolation = errors.New("memory scope violation")
+
+// PrivateKey builds a key visible only to agentID
+func PrivateKey(agentID multiagent.AgentID, key string) string {
+	return fmt.Sprintf("%s%s:%s", privatePrefix, agentID, key)
+}
+
+// ConversationKey builds a key shared by all agents on a conversation
+func ConversationKey(conversationID string, key string) string {
+	return fmt.Sprintf("%s%s:%s", conversationPrefix, conversationID, key)
+}
+
+// GlobalKey builds a key in the global namespace
+func GlobalKey(key string) string {
+	return globalPrefix + key
+}
+
+// NamespacePolicy maps the existing flat keyspace onto scopes
+type NamespacePolicy struct {
+	// Owners maps a key prefix to the agent that owns it
+	Owners map[string]multiagent.AgentID
+	// ConversationPrefixes lists prefixes that hold per-conversation shared state
+	ConversationPrefixes []string
+	// AgentPrefixes lists prefixes whose second segment is the owning agent ID
+	// (e.g. "msg:<agent>:<id>")
+	AgentPrefixes []string
+	// OnWrite, if set, is called after every successful write through a
+	// scoped store, e.g. to audit which agent changed what
+	OnWrite WriteHook
+}
+
+// WriteOp is the kind of write reported to a WriteHook
+type WriteOp string
+
+const (
+	WriteStore  WriteOp = "store"
+	WriteUpdate WriteOp = "update"
+	WriteDelete WriteOp = "delete"
+)
+
+// WriteHook observes writes made by agentID
+type WriteHook func(ctx context.Context, agentID multiagent.AgentID, op WriteOp, key string)
+
+// DefaultNamespacePolicy returns the ownership table for the built-in agents
+func DefaultNamespacePolicy() NamespacePolicy {
+	return NamespacePolicy{
+		Owners: map[string]multiagent.AgentID{
+			"calendar_event:":        "scheduler_agent",
+			"personal_task:":         "task_manager_agent",
+			"reminder:":              "task_manager_agent",
+			"project:":               "project_manager_agent",
+			"project_template:":      "project_manager_agent",
+			"contact:":               "communication_manager_agent",
+			"communication_message:": "communication_manager_agent",
+			"message_template:":      "communication_manager_agent",
+			"follow_up:":             "communication_manager_agent",
+			"contact_merge:":         "communication_manager_agent",
+			"research_session:":      "research_assistant_agent",
+			"research_monitor:":      "research_assistant_agent",
+			"audit:":                 "audit",          // Append-only; no agent may rewrite history
+			"fact:":                  "fact_extractor", // Proposed by the extractor, approved by the user
+			"note:":                  "notes_indexer",  // Mirrors the user's files; edited there, not here
+			"note_file:":             "notes_indexer",
+		},
+		ConversationPrefixes: []string{
+			"conversation:",
+			"conversation_turn:",
+		},
+		AgentPrefixes: []string{
+			"agent:",
+			"msg:",
+			"scheduler:",
+			"task_manager:",
+			"project_manager:",
+			"communication_manager:",
+			"research_assistant:",
+			"coordinator:",
+		},
+	}
+}
+
+// Classify returns the scope of key and, for private keys, its owner
+func (p NamespacePolicy) Classify(key string) (Scope, multiagent.AgentID) {
+	switch {
+	case strings.HasPrefix(key, privatePrefix):
+		return ScopePrivate, multiagent.AgentID(secondSegment(key))
+	case strings.HasPrefix(key, conversationPrefix):
+		return ScopeConversation, ""
+	case strings.HasPrefix(key, globalPrefix):
+		return ScopeGlobal, ""
+	}
+
+	for _, prefix := range p.ConversationPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return ScopeConversation, ""
+		}
+	}
+
+	for _, prefix := range p.AgentPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			if owner := secondSegment(key); owner != "" {
+				return ScopePrivate, multiagent.AgentID(owner)
+			}
+		}
+	}
+
+	// Longest matching owner prefix wins
+	var owner multiagent.AgentID
+	longest := 0
+	for prefix, agentID := range p.Owners {
+		if strings.HasPrefix(key, prefix) && len(prefix) > longest {
+			owner, longest = agentID, len(prefix)
+		}
+	}
+	if owner != "" {
+		return ScopePrivate, owner
+	}
+
+	return ScopeGlobal, ""
+}
+
+// ScopedMemoryStore wraps a MemoryStore on behalf of one agent and rejects
+// writes to keys owned by other agents
+type ScopedMemoryStore struct {
+	base    multiagent.MemoryStore
+	agentID multiagent.AgentID
+	policy  NamespacePolicy
+}
+
+// NewScopedMemoryStore creates a scope-enforcing view of base for agentID
+func NewScopedMemoryStore(base multiagent.MemoryStore, agentID multiagent.AgentID, policy NamespacePolicy) *ScopedMemoryStore {
+	return &ScopedMemoryStore{
+		base:    base,
+		agentID: agentID,
+		policy:  policy,
+	}
+}
+
+// ScopeForAgent returns a scoped view of base that keeps vector search
+// available when base supports it
+func ScopeForAgent(base multiagent.MemoryStore, agentID multiagent.AgentID, policy NamespacePolicy) multiagent.MemoryStore {
+	scoped := NewScopedMemoryStore(base, agentID, policy)
+	if vectorStore, ok := base.(multiagent.VectorMemoryStore); ok {
+		return &scopedVectorStore{ScopedMemoryStore: scoped, vector: vectorStore}
+	}
+	return scoped
+}
+
+// AgentID returns the agent this view acts for
+func (s *ScopedMemoryStore) AgentID() multiagent.AgentID {
+	return s.agentID
+}
+
+// Store saves a value if the agent may write key
+func (s *ScopedMemoryStore) Store(ctx context.Context, key string, value interface{}) error {
+	if err := s.checkWrite(key); err != nil {
+		return err
+	}
+	return s.observe(ctx, WriteStore, key, s.base.Store(ctx, key, value))
+}
+
+// StoreWithTTL saves a value with TTL if the agent may write key
+func (s *ScopedMemoryStore) StoreWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := s.checkWrite(key); err != nil {
+		return err
+	}
+	return s.observe(ctx, WriteStore, key, s.base.StoreWithTTL(ctx, key, value, ttl))
+}
+
+// Get retrieves a value if the agent may read key
+func (s *ScopedMemoryStore) Get(ctx context.Context, key string) (interface{}, error) {
+	if err := s.checkRead(key); err != nil {
+		return nil, err
+	}
+	return s.base.Get(ctx, key)
+}
+
+// GetMultiple retrieves the readable subset of keys
+func (s *ScopedMemoryStore) GetMultiple(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	readable := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if s.canRead(key) {
+			readable = append(readable, key)
+		}
+	}
+	return s.base.GetMultiple(ctx, readable)
+}
+
+// Search returns matching entries the agent may read
+func (s *ScopedMemoryStore) Search(ctx context.Context, query string, limit int) ([]multiagent.MemoryEntry, error) {
+	entries, err := s.base.Search(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.filterEntries(entries), nil
+}
+
+// SearchByTags returns tagged entries the agent may read
+func (s *ScopedMemoryStore) SearchByTags(ctx context.Context, tags []string, limit int) ([]multiagent.MemoryEntry, error) {
+	entries, err := s.base.SearchByTags(ctx, tags, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.filterEntries(entries), nil
+}
+
+// Delete removes key if the agent may write it
+func (s *ScopedMemoryStore) Delete(ctx context.Context, key string) error {
+	if err := s.checkWrite(key); err != nil {
+		return err
+	}
+	return s.observe(ctx, WriteDelete, key, s.base.Delete(ctx, key))
+}
+
+// Update modifies key if the agent may write it
+func (s *ScopedMemoryStore) Update(ctx context.Context, key string, updater func(interface{}) (interface{}, error)) error {
+	if err := s.checkWrite(key); err != nil {
+		return err
+	}
+	return s.observe(ctx, WriteUpdate, key, s.base.Update(ctx, key, updater))
+}
+
+// List returns readable keys matching prefix
+func (s *ScopedMemoryStore) List(ctx context.Context, prefix string, limit int) ([]string, error) {
+	keys, err := s.base.List(ctx, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	readable := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if s.canRead(key) {
+			readable = append(readable, key)
+		}
+	}
+	return readable, nil
+}
+
+// Cleanup delegates to the underlying store
+func (s *ScopedMemoryStore) Cleanup(ctx context.Context) error {
+	return s.base.Cleanup(ctx)
+}
+
+// Internal helper methods
+
+// observe reports a successful write to the policy's hook and passes err through
+func (s *ScopedMemoryStore) observe(ctx context.Context, op WriteOp, key string, err error) error {
+	if err == nil && s.policy.OnWrite != nil {
+		s.policy.OnWrite(ctx, s.agentID, op, key)
+	}
+	return err
+}
+
+func (s *ScopedMemoryStore) checkWrite(key string) error {
+	scope, owner := s.policy.Classify(key)
+	if scope == ScopePrivate && owner != s.agentID {
+		return fmt.Errorf("%w: agent %s cannot write %s (owned by %s)", ErrScopeViolation, s.agentID, key, owner)
+	}
+	return nil
+}
+
+func (s *ScopedMemoryStore) checkRead(key string) error {
+	if !s.canRead(key) {
+		return fmt.Errorf("%w: agent %s cannot read %s", ErrScopeViolation, s.agentID, key)
+	}
+	return nil
+}
+
+// canRead allows everything except explicit private: keys of other agents;
+// legacy owned prefixes remain readable so agents can still collaborate
+func (s *ScopedMemoryStore) canRead(key string) bool {
+	if strings.HasPrefix(key, privatePrefix) {
+		return multiagent.AgentID(secondSegment(key)) == s.agentID
+	}
+	return true
+}
+
+func (s *ScopedMemoryStore) filterEntries(entries []multiagent.MemoryEntry) []multiagent.MemoryEntry {
+	filtered := make([]multiagent.MemoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if s.canRead(entry.Key) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// scopedVectorStore keeps StoreEmbedding/SearchSimilar behind the same checks
+type scopedVectorStore struct {
+	*ScopedMemoryStore
+	vector multiagent.VectorMemoryStore
+}
+
+// StoreEmbedding indexes text under key if the agent may write key
+func (s *scopedVectorStore) StoreEmbedding(ctx context.Context, key string, text string, metadata map[string]interface{}) error {
+	if err := s.checkWrite(key); err != nil {
+		return err
+	}
+	return s.vector.StoreEmbedding(ctx, key, text, metadata)
+}
+
+// SearchSimilar returns semantic hits the agent may read
+func (s *scopedVectorStore) SearchSimilar(ctx context.Context, query string, limit int) ([]multiagent.SimilarMemory, error) {
+	hits, err := s.vector.SearchSimilar(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]multiagent.SimilarMemory, 0, len(hits))
+	for _, hit := range hits {
+		if s.canRead(hit.Entry.Key) {
+			filtered = append(filtered, hit)
+		}
+	}
+	return filtered, nil
+}
+
+// secondSegment returns "b" for keys shaped like "a:b:..."
+func secondSegment(key string) string {
+	parts := strings.SplitN(key, ":", 3)
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[1]
+}
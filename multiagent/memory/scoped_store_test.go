@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+func TestScopedMemoryStore_EnforcesOwnership(t *testing.T) {
+	ctx := context.Background()
+	base := newTestSQLiteStore(t)
+	policy := DefaultNamespacePolicy()
+
+	scheduler := NewScopedMemoryStore(base, "scheduler_agent", policy)
+	comms := NewScopedMemoryStore(base, "communication_manager_agent", policy)
+
+	if err := scheduler.Store(ctx, "calendar_event:1", "standup"); err != nil {
+		t.Fatalf("owner Store: %v", err)
+	}
+	if err := comms.Store(ctx, "calendar_event:1", "clobbered"); !errors.Is(err, ErrScopeViolation) {
+		t.Fatalf("expected scope violation, got %v", err)
+	}
+	if err := comms.Delete(ctx, "msg:scheduler_agent:abc"); !errors.Is(err, ErrScopeViolation) {
+		t.Fatalf("expected scope violation for agent-prefixed key, got %v", err)
+	}
+
+	// Legacy owned keys stay readable so agents can collaborate
+	if v, err := comms.Get(ctx, "calendar_event:1"); err != nil || v != "standup" {
+		t.Fatalf("expected readable calendar event, got %v, %v", v, err)
+	}
+
+	// Conversation and global keys are shared
+	if err := comms.Store(ctx, "conversation:c1", "hello"); err != nil {
+		t.Fatalf("conversation Store: %v", err)
+	}
+	if err := comms.Store(ctx, ConversationKey("c1", "notes"), "shared"); err != nil {
+		t.Fatalf("shared Store: %v", err)
+	}
+	if err := comms.Store(ctx, "task_123", "global"); err != nil {
+		t.Fatalf("global Store: %v", err)
+	}
+
+	// Explicit private keys are hidden from other agents
+	secret := PrivateKey("scheduler_agent", "token")
+	if err := scheduler.Store(ctx, secret, "s3cret"); err != nil {
+		t.Fatalf("private Store: %v", err)
+	}
+	if _, err := comms.Get(ctx, secret); !errors.Is(err, ErrScopeViolation) {
+		t.Fatalf("expected private read to fail, got %v", err)
+	}
+	keys, err := comms.List(ctx, "private:", 10)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("expected private keys to be filtered, got %v", keys)
+	}
+}
+
+func TestScopedMemoryStore_ReportsWritesAndProtectsAudit(t *testing.T) {
+	ctx := context.Background()
+	base := newTestSQLiteStore(t)
+
+	type write struct {
+		agent string
+		op    WriteOp
+		key   string
+	}
+	var writes []write
+	policy := DefaultNamespacePolicy()
+	policy.OnWrite = func(ctx context.Context, agentID multiagent.AgentID, op WriteOp, key string) {
+		writes = append(writes, write{string(agentID), op, key})
+	}
+	scheduler := NewScopedMemoryStore(base, "scheduler_agent", policy)
+
+	if err := scheduler.Store(ctx, "calendar_event:1", "standup"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := scheduler.Delete(ctx, "calendar_event:1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := scheduler.Store(ctx, "contact:1", "not mine"); !errors.Is(err, ErrScopeViolation) {
+		t.Fatalf("expected scope violation, got %v", err)
+	}
+	if err := scheduler.Store(ctx, "audit:rewrite", "history"); !errors.Is(err, ErrScopeViolation) {
+		t.Fatalf("expected audit keys to be write-protected, got %v", err)
+	}
+
+	want := []write{
+		{"scheduler_agent", WriteStore, "calendar_event:1"},
+		{"scheduler_agent", WriteDelete, "calendar_event:1"},
+	}
+	if len(writes) != len(want) {
+		t.Fatalf("expected %d reported writes, got %v", len(want), writes)
+	}
+	for i := range want {
+		if writes[i] != want[i] {
+			t.Errorf("write %d = %v, want %v", i, writes[i], want[i])
+		}
+	}
+}
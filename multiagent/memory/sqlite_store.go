@@ -0,0 +1,570 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+)
+
+// sqliteSchema creates the tables and indexes used by SQLiteMemoryStore.
+// Timestamps are stored as unix nanoseconds so range scans can use indexes.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS memory_entries (
+	key          TEXT PRIMARY KEY,
+	value        TEXT NOT NULL,
+	category     TEXT NOT NULL DEFAULT '',
+	metadata     TEXT,
+	created_at   INTEGER NOT NULL,
+	updated_at   INTEGER NOT NULL,
+	accessed_at  INTEGER NOT NULL,
+	access_count INTEGER NOT NULL DEFAULT 0,
+	ttl          INTEGER,
+	expires_at   INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_memory_entries_category ON memory_entries(category);
+CREATE INDEX IF NOT EXISTS idx_memory_entries_created_at ON memory_entries(created_at);
+CREATE INDEX IF NOT EXISTS idx_memory_entries_updated_at ON memory_entries(updated_at);
+CREATE INDEX IF NOT EXISTS idx_memory_entries_expires_at ON memory_entries(expires_at);
+
+CREATE TABLE IF NOT EXISTS memory_tags (
+	key TEXT NOT NULL REFERENCES memory_entries(key) ON DELETE CASCADE,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (key, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_memory_tags_tag ON memory_tags(tag);
+`
+
+// SQLiteMemoryStore implements MemoryStore on top of a single SQLite database
+type SQLiteMemoryStore struct {
+	db   *sql.DB
+	path string
+
+	// done is closed by Close to stop the cleanup routine
+	done      chan struct{}
+	closeOnce sync.Once
+	cleanup   sync.WaitGroup
+}
+
+// NewSQLiteMemoryStore opens (or creates) a SQLite-backed memory store at path
+func NewSQLiteMemoryStore(path string) (*SQLiteMemoryStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create memory directory: %w", err)
+		}
+	}
+
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	// SQLite serializes writers anyway; a single connection avoids SQLITE_BUSY churn
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	store := &SQLiteMemoryStore{
+		db:   db,
+		path: path,
+		done: make(chan struct{}),
+	}
+
+	// Start cleanup routine
+	store.cleanup.Add(1)
+	go store.cleanupRoutine()
+
+	return store, nil
+}
+
+// Close stops the cleanup routine and releases the underlying database
+// handle
+func (s *SQLiteMemoryStore) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	s.cleanup.Wait()
+	return s.db.Close()
+}
+
+// Store saves a value with the given key
+func (s *SQLiteMemoryStore) Store(ctx context.Context, key string, value interface{}) error {
+	return s.StoreWithTTL(ctx, key, value, 0)
+}
+
+// StoreWithTTL saves a value with the given key and TTL in a single transaction
+func (s *SQLiteMemoryStore) StoreWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	now := time.Now()
+
+	entry := multiagent.MemoryEntry{
+		Key:        key,
+		Value:      value,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		AccessedAt: now,
+	}
+	if ttl > 0 {
+		entry.TTL = &ttl
+		expiresAt := now.Add(ttl)
+		entry.ExpiresAt = &expiresAt
+	}
+	entry.Category, entry.Tags = extractKeyMetadata(key)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.putEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit entry: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves a value by key
+func (s *SQLiteMemoryStore) Get(ctx context.Context, key string) (interface{}, error) {
+	entry, err := s.getEntry(ctx, s.db, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update access time and count
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE memory_entries SET accessed_at = ?, access_count = access_count + 1 WHERE key = ?`,
+		time.Now().UnixNano(), key); err != nil {
+		return nil, fmt.Errorf("failed to update access stats: %w", err)
+	}
+
+	return entry.Value, nil
+}
+
+// GetMultiple retrieves multiple values by keys
+func (s *SQLiteMemoryStore) GetMultiple(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	results := make(map[string]interface{})
+
+	for _, key := range keys {
+		value, err := s.Get(ctx, key)
+		if err == nil {
+			results[key] = value
+		}
+	}
+
+	return results, nil
+}
+
+// Search searches for entries whose key or category and value match the query
+func (s *SQLiteMemoryStore) Search(ctx context.Context, query string, limit int) ([]multiagent.MemoryEntry, error) {
+	pattern := "%" + escapeLike(strings.ToLower(query)) + "%"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT key, value, category, metadata, created_at, updated_at, accessed_at, access_count, ttl, expires_at
+		FROM memory_entries
+		WHERE (expires_at IS NULL OR expires_at > ?)
+		  AND (lower(key) LIKE ? ESCAPE '\' OR lower(category) LIKE ? ESCAPE '\')
+		  AND lower(value) LIKE ? ESCAPE '\'
+		ORDER BY updated_at DESC
+		LIMIT ?`,
+		time.Now().UnixNano(), pattern, pattern, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search entries: %w", err)
+	}
+	defer rows.Close()
+
+	return s.scanEntries(ctx, rows)
+}
+
+// SearchByTags searches for entries that carry all of the given tags
+func (s *SQLiteMemoryStore) SearchByTags(ctx context.Context, tags []string, limit int) ([]multiagent.MemoryEntry, error) {
+	if len(tags) == 0 {
+		return []multiagent.MemoryEntry{}, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(tags)), ",")
+	args := make([]interface{}, 0, len(tags)+3)
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+	args = append(args, len(tags), time.Now().UnixNano(), limit)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.key, e.value, e.category, e.metadata, e.created_at, e.updated_at, e.accessed_at, e.access_count, e.ttl, e.expires_at
+		FROM memory_entries e
+		JOIN (
+			SELECT key FROM memory_tags WHERE tag IN (`+placeholders+`)
+			GROUP BY key HAVING COUNT(DISTINCT tag) = ?
+		) t ON t.key = e.key
+		WHERE e.expires_at IS NULL OR e.expires_at > ?
+		ORDER BY e.updated_at DESC
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tags: %w", err)
+	}
+	defer rows.Close()
+
+	return s.scanEntries(ctx, rows)
+}
+
+// Delete removes an entry by key
+func (s *SQLiteMemoryStore) Delete(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM memory_entries WHERE key = ?`, key); err != nil {
+		return fmt.Errorf("failed to delete entry: %w", err)
+	}
+	return nil
+}
+
+// Update applies updater to the current value of key inside a transaction
+func (s *SQLiteMemoryStore) Update(ctx context.Context, key string, updater func(interface{}) (interface{}, error)) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	entry, err := s.getEntry(ctx, tx, key)
+	if err != nil {
+		return err
+	}
+
+	newValue, err := updater(entry.Value)
+	if err != nil {
+		return err
+	}
+
+	// Preserve creation time and TTL, refresh everything else
+	entry.Value = newValue
+	entry.UpdatedAt = time.Now()
+	entry.AccessedAt = entry.UpdatedAt
+	if err := s.putEntry(ctx, tx, *entry); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit update: %w", err)
+	}
+
+	return nil
+}
+
+// List returns keys matching a prefix using an index range scan
+func (s *SQLiteMemoryStore) List(ctx context.Context, prefix string, limit int) ([]string, error) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+
+	now := time.Now().UnixNano()
+	if upper, ok := prefixUpperBound(prefix); ok {
+		rows, err = s.db.QueryContext(ctx, `
+			SELECT key FROM memory_entries
+			WHERE key >= ? AND key < ? AND (expires_at IS NULL OR expires_at > ?)
+			ORDER BY key LIMIT ?`, prefix, upper, now, limit)
+	} else {
+		rows, err = s.db.QueryContext(ctx, `
+			SELECT key FROM memory_entries
+			WHERE key >= ? AND (expires_at IS NULL OR expires_at > ?)
+			ORDER BY key LIMIT ?`, prefix, now, limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0, limit)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// ListEntries returns metadata for keys matching prefix, including expired ones
+func (s *SQLiteMemoryStore) ListEntries(ctx context.Context, prefix string) ([]EntryInfo, error) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+
+	if upper, ok := prefixUpperBound(prefix); ok {
+		rows, err = s.db.QueryContext(ctx, `
+			SELECT key, created_at, accessed_at, expires_at FROM memory_entries
+			WHERE key >= ? AND key < ?`, prefix, upper)
+	} else {
+		rows, err = s.db.QueryContext(ctx, `
+			SELECT key, created_at, accessed_at, expires_at FROM memory_entries
+			WHERE key >= ?`, prefix)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+	defer rows.Close()
+
+	infos := make([]EntryInfo, 0)
+	for rows.Next() {
+		var (
+			info                  EntryInfo
+			createdAt, accessedAt int64
+			expiresAt             sql.NullInt64
+		)
+		if err := rows.Scan(&info.Key, &createdAt, &accessedAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		info.CreatedAt = time.Unix(0, createdAt)
+		info.AccessedAt = time.Unix(0, accessedAt)
+		if expiresAt.Valid {
+			t := time.Unix(0, expiresAt.Int64)
+			info.ExpiresAt = &t
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, rows.Err()
+}
+
+// Cleanup removes expired entries
+func (s *SQLiteMemoryStore) Cleanup(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx,
+		`DELETE FROM memory_entries WHERE expires_at IS NOT NULL AND expires_at <= ?`,
+		time.Now().UnixNano()); err != nil {
+		return fmt.Errorf("failed to remove expired entries: %w", err)
+	}
+	return nil
+}
+
+// Internal helper methods
+
+// sqlQuerier is satisfied by both *sql.DB and *sql.Tx
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// putEntry upserts an entry and replaces its tags
+func (s *SQLiteMemoryStore) putEntry(ctx context.Context, q sqlQuerier, entry multiagent.MemoryEntry) error {
+	valueData, err := json.Marshal(entry.Value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	var metadataData []byte
+	if entry.Metadata != nil {
+		if metadataData, err = json.Marshal(entry.Metadata); err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+	}
+
+	var ttl, expiresAt sql.NullInt64
+	if entry.TTL != nil {
+		ttl = sql.NullInt64{Int64: int64(*entry.TTL), Valid: true}
+	}
+	if entry.ExpiresAt != nil {
+		expiresAt = sql.NullInt64{Int64: entry.ExpiresAt.UnixNano(), Valid: true}
+	}
+
+	if _, err := q.ExecContext(ctx, `
+		INSERT INTO memory_entries (key, value, category, metadata, created_at, updated_at, accessed_at, access_count, ttl, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			value = excluded.value,
+			category = excluded.category,
+			metadata = excluded.metadata,
+			updated_at = excluded.updated_at,
+			accessed_at = excluded.accessed_at,
+			ttl = excluded.ttl,
+			expires_at = excluded.expires_at`,
+		entry.Key, string(valueData), entry.Category, nullableString(metadataData),
+		entry.CreatedAt.UnixNano(), entry.UpdatedAt.UnixNano(), entry.AccessedAt.UnixNano(),
+		entry.AccessCount, ttl, expiresAt); err != nil {
+		return fmt.Errorf("failed to write entry: %w", err)
+	}
+
+	if _, err := q.ExecContext(ctx, `DELETE FROM memory_tags WHERE key = ?`, entry.Key); err != nil {
+		return fmt.Errorf("failed to clear tags: %w", err)
+	}
+	for _, tag := range entry.Tags {
+		if _, err := q.ExecContext(ctx,
+			`INSERT OR IGNORE INTO memory_tags (key, tag) VALUES (?, ?)`, entry.Key, tag); err != nil {
+			return fmt.Errorf("failed to write tag: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// getEntry loads a single non-expired entry including its tags
+func (s *SQLiteMemoryStore) getEntry(ctx context.Context, q sqlQuerier, key string) (*multiagent.MemoryEntry, error) {
+	row := q.QueryRowContext(ctx, `
+		SELECT key, value, category, metadata, created_at, updated_at, accessed_at, access_count, ttl, expires_at
+		FROM memory_entries WHERE key = ?`, key)
+
+	entry, err := scanEntry(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("key not found: %s", key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.ExpiresAt != nil && time.Now().After(*entry.ExpiresAt) {
+		return nil, fmt.Errorf("key expired: %s", key)
+	}
+
+	if entry.Tags, err = s.loadTags(ctx, q, key); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (s *SQLiteMemoryStore) loadTags(ctx context.Context, q sqlQuerier, key string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT tag FROM memory_tags WHERE key = ? ORDER BY tag`, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// scanEntries drains rows into entries and then attaches tags
+func (s *SQLiteMemoryStore) scanEntries(ctx context.Context, rows *sql.Rows) ([]multiagent.MemoryEntry, error) {
+	results := []multiagent.MemoryEntry{}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Tags are loaded after the result set is closed since the pool has a single connection
+	for i := range results {
+		tags, err := s.loadTags(ctx, s.db, results[i].Key)
+		if err != nil {
+			return nil, err
+		}
+		results[i].Tags = tags
+	}
+
+	return results, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEntry(row rowScanner) (*multiagent.MemoryEntry, error) {
+	var (
+		entry                            multiagent.MemoryEntry
+		valueData                        string
+		metadataData                     sql.NullString
+		createdAt, updatedAt, accessedAt int64
+		ttl, expiresAt                   sql.NullInt64
+	)
+
+	if err := row.Scan(&entry.Key, &valueData, &entry.Category, &metadataData,
+		&createdAt, &updatedAt, &accessedAt, &entry.AccessCount, &ttl, &expiresAt); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(valueData), &entry.Value); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal entry: %w", err)
+	}
+	if metadataData.Valid && metadataData.String != "" {
+		if err := json.Unmarshal([]byte(metadataData.String), &entry.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+	}
+
+	entry.CreatedAt = time.Unix(0, createdAt)
+	entry.UpdatedAt = time.Unix(0, updatedAt)
+	entry.AccessedAt = time.Unix(0, accessedAt)
+	if ttl.Valid {
+		d := time.Duration(ttl.Int64)
+		entry.TTL = &d
+	}
+	if expiresAt.Valid {
+		t := time.Unix(0, expiresAt.Int64)
+		entry.ExpiresAt = &t
+	}
+
+	return &entry, nil
+}
+
+func (s *SQLiteMemoryStore) cleanupRoutine() {
+	defer s.cleanup.Done()
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Cleanup(context.Background()); err != nil {
+				logger.Error("SQLiteMemoryStore cleanup failed", "error", err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// prefixUpperBound returns the smallest string greater than every string with
+// the given prefix, so prefix scans can be expressed as an index range
+func prefixUpperBound(prefix string) (string, bool) {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1]), true
+		}
+	}
+	return "", false
+}
+
+func escapeLike(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `%`, `\%`)
+	return strings.ReplaceAll(s, `_`, `\_`)
+}
+
+func nullableString(b []byte) sql.NullString {
+	if b == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(b), Valid: true}
+}
@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestSQLiteStore(t *testing.T) *SQLiteMemoryStore {
+	t.Helper()
+	store, err := NewSQLiteMemoryStore(filepath.Join(t.TempDir(), "memory.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLiteMemoryStore_StoreGetList(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLiteStore(t)
+
+	if err := store.Store(ctx, "task:1", map[string]interface{}{"title": "write docs"}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := store.Store(ctx, "task:2", "second"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := store.Store(ctx, "tasks_archive", "not a prefix match"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	value, err := store.Get(ctx, "task:1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok || m["title"] != "write docs" {
+		t.Fatalf("unexpected value: %#v", value)
+	}
+
+	keys, err := store.List(ctx, "task:", 10)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(keys) != 2 || keys[0] != "task:1" || keys[1] != "task:2" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	entries, err := store.SearchByTags(ctx, []string{"task"}, 10)
+	if err != nil {
+		t.Fatalf("SearchByTags: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 tagged entries, got %d", len(entries))
+	}
+}
+
+func TestSQLiteMemoryStore_TTLAndUpdate(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLiteStore(t)
+
+	if err := store.StoreWithTTL(ctx, "session:a", "short", time.Millisecond); err != nil {
+		t.Fatalf("StoreWithTTL: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := store.Get(ctx, "session:a"); err == nil {
+		t.Fatal("expected expired key to be unreadable")
+	}
+	if err := store.Cleanup(ctx); err != nil {
+		t.Fatalf("Cleanup: %v", err)
+	}
+	if keys, _ := store.List(ctx, "session:", 10); len(keys) != 0 {
+		t.Fatalf("expected expired key to be removed, got %v", keys)
+	}
+
+	if err := store.Store(ctx, "counter", float64(1)); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	err := store.Update(ctx, "counter", func(v interface{}) (interface{}, error) {
+		return v.(float64) + 1, nil
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if v, _ := store.Get(ctx, "counter"); v != float64(2) {
+		t.Fatalf("expected 2, got %v", v)
+	}
+}
+
+func TestSQLiteMemoryStore_CloseStopsCleanup(t *testing.T) {
+	store := newTestSQLiteStore(t)
+
+	closed := make(chan error, 1)
+	go func() { closed <- store.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not stop the cleanup routine")
+	}
+	// Closing again, as the test cleanup does, is harmless
+	if err := store.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// userPrefix namespaces each user's keys as "user:<id>:<key>"
+const userPrefix = "user:"
+
+// searchOverfetch widens searches on the shared store, since hits from
+// other users are dropped afterwards
+const searchOverfetch = 4
+
+// purgeBatchSize is how many keys PurgeUser lists at a time
+const purgeBatchSize = 500
+
+// UserKeyPrefix returns the prefix under which userID's keys are stored
+func UserKeyPrefix(userID string) string {
+	return fmt.Sprintf("%s%s:", userPrefix, userID)
+}
+
+// UserPartitionedStore keeps each user's memory apart: while ctx acts for a
+// user (multiagent.WithUserID), keys are transparently stored under
+// UserKeyPrefix and only that user's keys are visible. Work without a user,
+// and GlobalKey keys, use the shared keyspace as before.
+type UserPartitionedStore struct {
+	base multiagent.MemoryStore
+}
+
+// NewUserPartitionedStore creates a per-user view of base
+func NewUserPartitionedStore(base multiagent.MemoryStore) *UserPartitionedStore {
+	return &UserPartitionedStore{base: base}
+}
+
+// PartitionByUser returns a per-user view of base that keeps vector search
+// available when base supports it
+func PartitionByUser(base multiagent.MemoryStore) multiagent.MemoryStore {
+	partitioned := NewUserPartitionedStore(base)
+	if vectorStore, ok := base.(multiagent.VectorMemoryStore); ok {
+		return &userVectorStore{UserPartitionedStore: partitioned, vector: vectorStore}
+	}
+	return partitioned
+}
+
+// Store saves a value in the acting user's namespace
+func (s *UserPartitionedStore) Store(ctx context.Context, key string, value interface{}) error {
+	return s.base.Store(ctx, s.physical(ctx, key), value)
+}
+
+// StoreWithTTL saves a value with TTL in the acting user's namespace
+func (s *UserPartitionedStore) StoreWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return s.base.StoreWithTTL(ctx, s.physical(ctx, key), value, ttl)
+}
+
+// Get retrieves a value from the acting user's namespace
+func (s *UserPartitionedStore) Get(ctx context.Context, key string) (interface{}, error) {
+	return s.base.Get(ctx, s.physical(ctx, key))
+}
+
+// GetMultiple retrieves values from the acting user's namespace
+func (s *UserPartitionedStore) GetMultiple(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	physical := make([]string, len(keys))
+	for i, key := range keys {
+		physical[i] = s.physical(ctx, key)
+	}
+	values, err := s.base.GetMultiple(ctx, physical)
+	if err != nil {
+		return nil, err
+	}
+
+	logical := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		logical[s.logical(ctx, key)] = value
+	}
+	return logical, nil
+}
+
+// Search returns matching entries visible to the acting user
+func (s *UserPartitionedStore) Search(ctx context.Context, query string, limit int) ([]multiagent.MemoryEntry, error) {
+	entries, err := s.base.Search(ctx, query, s.overfetch(ctx, limit))
+	if err != nil {
+		return nil, err
+	}
+	return s.visibleEntries(ctx, entries, limit), nil
+}
+
+// SearchByTags returns tagged entries visible to the acting user
+func (s *UserPartitionedStore) SearchByTags(ctx context.Context, tags []string, limit int) ([]multiagent.MemoryEntry, error) {
+	entries, err := s.base.SearchByTags(ctx, tags, s.overfetch(ctx, limit))
+	if err != nil {
+		return nil, err
+	}
+	return s.visibleEntries(ctx, entries, limit), nil
+}
+
+// Delete removes key from the acting user's namespace
+func (s *UserPartitionedStore) Delete(ctx context.Context, key string) error {
+	return s.base.Delete(ctx, s.physical(ctx, key))
+}
+
+// Update modifies key in the acting user's namespace
+func (s *UserPartitionedStore) Update(ctx context.Context, key string, updater func(interface{}) (interface{}, error)) error {
+	return s.base.Update(ctx, s.physical(ctx, key), updater)
+}
+
+// List returns the acting user's keys matching prefix
+func (s *UserPartitionedStore) List(ctx context.Context, prefix string, limit int) ([]string, error) {
+	keys, err := s.base.List(ctx, s.physical(ctx, prefix), limit)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = s.logical(ctx, key)
+	}
+	return keys, nil
+}
+
+// Cleanup delegates to the underlying store
+func (s *UserPartitionedStore) Cleanup(ctx context.Context) error {
+	return s.base.Cleanup(ctx)
+}
+
+// physical maps a key as an agent sees it to where it is stored
+func (s *UserPartitionedStore) physical(ctx context.Context, key string) string {
+	userID := multiagent.UserIDFromContext(ctx)
+	if userID == "" || strings.HasPrefix(key, globalPrefix) {
+		return key
+	}
+	return UserKeyPrefix(userID) + key
+}
+
+// logical strips the acting user's namespace from a stored key
+func (s *UserPartitionedStore) logical(ctx context.Context, key string) string {
+	userID := multiagent.UserIDFromContext(ctx)
+	if userID == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, UserKeyPrefix(userID))
+}
+
+// visible reports whether a stored key belongs to the acting user's view
+func (s *UserPartitionedStore) visible(ctx context.Context, key string) bool {
+	userID := multiagent.UserIDFromContext(ctx)
+	if userID == "" {
+		return !strings.HasPrefix(key, userPrefix)
+	}
+	return strings.HasPrefix(key, UserKeyPrefix(userID)) || strings.HasPrefix(key, globalPrefix)
+}
+
+func (s *UserPartitionedStore) overfetch(ctx context.Context, limit int) int {
+	if limit <= 0 || multiagent.UserIDFromContext(ctx) == "" {
+		return limit
+	}
+	return limit * searchOverfetch
+}
+
+func (s *UserPartitionedStore) visibleEntries(ctx context.Context, entries []multiagent.MemoryEntry, limit int) []multiagent.MemoryEntry {
+	visible := make([]multiagent.MemoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !s.visible(ctx, entry.Key) {
+			continue
+		}
+		entry.Key = s.logical(ctx, entry.Key)
+		visible = append(visible, entry)
+		if limit > 0 && len(visible) == limit {
+			break
+		}
+	}
+	return visible
+}
+
+// userVectorStore keeps StoreEmbedding/SearchSimilar partitioned too
+type userVectorStore struct {
+	*UserPartitionedStore
+	vector multiagent.VectorMemoryStore
+}
+
+// StoreEmbedding indexes text under key in the acting user's namespace
+func (s *userVectorStore) StoreEmbedding(ctx context.Context, key string, text string, metadata map[string]interface{}) error {
+	return s.vector.StoreEmbedding(ctx, s.physical(ctx, key), text, metadata)
+}
+
+// SearchSimilar returns semantic hits visible to the acting user
+func (s *userVectorStore) SearchSimilar(ctx context.Context, query string, limit int) ([]multiagent.SimilarMemory, error) {
+	hits, err := s.vector.SearchSimilar(ctx, query, s.overfetch(ctx, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]multiagent.SimilarMemory, 0, len(hits))
+	for _, hit := range hits {
+		if !s.visible(ctx, hit.Entry.Key) {
+			continue
+		}
+		hit.Entry.Key = s.logical(ctx, hit.Entry.Key)
+		visible = append(visible, hit)
+		if limit > 0 && len(visible) == limit {
+			break
+		}
+	}
+	return visible, nil
+}
+
+// PurgeUser deletes every key stored for userID and returns how many were
+// removed
+func PurgeUser(ctx context.Context, store multiagent.MemoryStore, userID string) (int, error) {
+	if userID == "" {
+		return 0, fmt.Errorf("user ID is required")
+	}
+	purged := 0
+	for {
+		keys, err := store.List(ctx, UserKeyPrefix(userID), purgeBatchSize)
+		if err != nil {
+			return purged, fmt.Errorf("failed to list keys for user %s: %w", userID, err)
+		}
+		if len(keys) == 0 {
+			return purged, nil
+		}
+		for _, key := range keys {
+			if err := store.Delete(ctx, key); err != nil {
+				return purged, fmt.Errorf("failed to delete %s: %w", key, err)
+			}
+			purged++
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+func TestUserPartitionedStore_IsolatesUsers(t *testing.T) {
+	base := newTestSQLiteStore(t)
+	store := PartitionByUser(base)
+	alice := multiagent.WithUserID(context.Background(), "alice")
+	bob := multiagent.WithUserID(context.Background(), "bob")
+
+	if err := store.Store(alice, "personal_task:1", "file taxes"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := store.Store(bob, "personal_task:1", "buy milk"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := store.Store(bob, "note:milk", "buy milk"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := store.Store(alice, GlobalKey("timezone"), "UTC"); err != nil {
+		t.Fatalf("Store global: %v", err)
+	}
+
+	if v, err := store.Get(alice, "personal_task:1"); err != nil || v != "file taxes" {
+		t.Errorf("alice's task = %v, %v", v, err)
+	}
+	if v, err := store.Get(bob, "personal_task:1"); err != nil || v != "buy milk" {
+		t.Errorf("bob's task = %v, %v", v, err)
+	}
+	if _, err := store.Get(context.Background(), "personal_task:1"); err == nil {
+		t.Error("expected user data to be hidden from work without a user")
+	}
+	if v, err := store.Get(bob, GlobalKey("timezone")); err != nil || v != "UTC" {
+		t.Errorf("expected global keys to be shared, got %v, %v", v, err)
+	}
+
+	keys, err := store.List(alice, "personal_task:", 10)
+	if err != nil || len(keys) != 1 || keys[0] != "personal_task:1" {
+		t.Errorf("alice's keys = %v, %v", keys, err)
+	}
+	entries, err := store.Search(bob, "milk", 10)
+	if err != nil || len(entries) != 1 || entries[0].Key != "note:milk" {
+		t.Errorf("bob's search = %+v, %v", entries, err)
+	}
+	if entries, _ := store.Search(alice, "milk", 10); len(entries) != 0 {
+		t.Errorf("expected alice not to find bob's task, got %+v", entries)
+	}
+}
+
+func TestPurgeUser(t *testing.T) {
+	base := newTestSQLiteStore(t)
+	store := PartitionByUser(base)
+	alice := multiagent.WithUserID(context.Background(), "alice")
+	bob := multiagent.WithUserID(context.Background(), "bob")
+
+	for _, key := range []string{"personal_task:1", "contact:ada", "conversation:conv_alice"} {
+		if err := store.Store(alice, key, "x"); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+	if err := store.Store(bob, "personal_task:1", "y"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	purged, err := PurgeUser(context.Background(), base, "alice")
+	if err != nil || purged != 3 {
+		t.Fatalf("PurgeUser = %d, %v; want 3", purged, err)
+	}
+	if keys, _ := store.List(alice, "", 10); len(keys) != 0 {
+		t.Errorf("expected nothing left for alice, got %v", keys)
+	}
+	if v, err := store.Get(bob, "personal_task:1"); err != nil || v != "y" {
+		t.Errorf("expected bob's data to survive, got %v, %v", v, err)
+	}
+}
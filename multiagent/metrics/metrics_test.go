@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWritesTextExposition(t *testing.T) {
+	r := NewRegistry()
+	routed := r.NewCounter("messages_total", "Messages routed", "type")
+	depth := r.NewGauge("queue_depth", "Queue depth")
+
+	routed.Inc("request")
+	routed.Add(2, "request")
+	routed.Inc("response")
+	r.OnScrape(func() { depth.Set(7) })
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	want := `# HELP messages_total Messages routed
+# TYPE messages_total counter
+messages_total{type="request"} 3
+messages_total{type="response"} 1
+# HELP queue_depth Queue depth
+# TYPE queue_depth gauge
+queue_depth 7
+`
+	if b.String() != want {
+		t.Fatalf("unexpected exposition:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestHistogramBucketsAreCumulative(t *testing.T) {
+	r := NewRegistry()
+	latency := r.NewHistogram("latency_seconds", "Latency", []float64{1, 0.1}, "agent")
+
+	latency.Observe(0.05, "a")
+	latency.Observe(0.5, "a")
+	latency.Observe(3, "a")
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, line := range []string{
+		`latency_seconds_bucket{agent="a",le="0.1"} 1`,
+		`latency_seconds_bucket{agent="a",le="1"} 2`,
+		`latency_seconds_bucket{agent="a",le="+Inf"} 3`,
+		`latency_seconds_sum{agent="a"} 3.55`,
+		`latency_seconds_count{agent="a"} 3`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+}
+
+func TestLabelValuesAreEscaped(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("errors_total", "Errors", "reason").Inc("bad \"quote\"\nline")
+
+	var b strings.Builder
+	r.WriteTo(&b)
+	if !strings.Contains(b.String(), `errors_total{reason="bad \"quote\"\nline"} 1`) {
+		t.Fatalf("label not escaped:\n%s", b.String())
+	}
+}
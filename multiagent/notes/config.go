@@ -0,0 +1,52 @@
+package notes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Source is a user's directories of notes and documents to index
+type Source struct {
+	UserID string `json:"user"`
+	// Dirs are searched recursively; "~/" and environment variables such
+	// as "$HOME" are expanded
+	Dirs []string `json:"dirs"`
+}
+
+// LoadSources reads sources from a JSON file:
+//
+//	{"sources": [{"user": "alice", "dirs": ["~/Documents/notes", "$HOME/papers"]}]}
+func LoadSources(path string) ([]Source, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notes config: %w", err)
+	}
+	var file struct {
+		Sources []Source `json:"sources"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse notes config: %w", err)
+	}
+
+	home, _ := os.UserHomeDir()
+	for i := range file.Sources {
+		source := &file.Sources[i]
+		if source.UserID == "" || len(source.Dirs) == 0 {
+			return nil, fmt.Errorf("notes source %d needs a user and dirs", i+1)
+		}
+		for j, dir := range source.Dirs {
+			dir = os.ExpandEnv(dir)
+			if rest, ok := strings.CutPrefix(dir, "~/"); ok && home != "" {
+				dir = filepath.Join(home, rest)
+			}
+			if dir, err = filepath.Abs(dir); err != nil {
+				return nil, fmt.Errorf("notes source %d: %w", i+1, err)
+			}
+			source.Dirs[j] = dir
+		}
+	}
+	return file.Sources, nil
+}
@@ -0,0 +1,403 @@
+// Package notes indexes users' own notes and documents, the Markdown, text
+// and PDF files in directories they name, into their memory, embedding each
+// passage when the store supports vector search. Directories are rescanned
+// on an interval, so notes that are added, edited or deleted are picked up
+// while running, and agents search the index through the notes tool.
+package notes
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+var logger = logging.For("notes")
+
+const (
+	// KeyPrefix holds the indexed passages, "note:<file>:<n>", in each
+	// user's partition
+	KeyPrefix = "note:"
+	// filePrefix records what was indexed of each file, so unchanged files
+	// are skipped after a restart
+	filePrefix = "note_file:"
+	// defaultInterval is how often directories are rescanned
+	defaultInterval = time.Minute
+	// defaultChunkSize is the most characters in a passage
+	defaultChunkSize = 1500
+	// maxFileSize skips files too large to be notes
+	maxFileSize = 20 << 20
+)
+
+// extensions are the files indexed, by the format they are read as
+var extensions = map[string]string{
+	".md":       "text",
+	".markdown": "text",
+	".txt":      "text",
+	".text":     "text",
+	".pdf":      "pdf",
+}
+
+// Passage is one indexed piece of a file, stored under KeyPrefix
+type Passage struct {
+	Path  string `json:"path"`
+	Title string `json:"title"`
+	Index int    `json:"index"`
+	Text  string `json:"text"`
+}
+
+// indexedFile is what was indexed of a file
+type indexedFile struct {
+	Path     string    `json:"path"`
+	ModTime  time.Time `json:"mod_time"`
+	Size     int64     `json:"size"`
+	Passages int       `json:"passages"`
+}
+
+// IndexerConfig holds configuration for creating an Indexer
+type IndexerConfig struct {
+	// Store is the user-partitioned memory passages are kept in; with a
+	// multiagent.VectorMemoryStore they are embedded too
+	Store   multiagent.MemoryStore
+	Sources []Source
+	// Interval is how often directories are rescanned (default 1 minute)
+	Interval time.Duration
+	// ChunkSize is the most characters in a passage (default 1500)
+	ChunkSize int
+}
+
+// SyncResult reports what a Sync changed
+type SyncResult struct {
+	Indexed   int `json:"indexed"`
+	Unchanged int `json:"unchanged"`
+	Removed   int `json:"removed"`
+	// Failed lists the files that could not be read or indexed
+	Failed []string `json:"failed,omitempty"`
+}
+
+// Indexer keeps users' notes indexed
+type Indexer struct {
+	store     multiagent.MemoryStore
+	sources   []Source
+	interval  time.Duration
+	chunkSize int
+
+	syncMu  sync.Mutex
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+// NewIndexer creates an indexer for config.Sources
+func NewIndexer(config IndexerConfig) *Indexer {
+	if config.Interval <= 0 {
+		config.Interval = defaultInterval
+	}
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = defaultChunkSize
+	}
+	return &Indexer{
+		store:     config.Store,
+		sources:   config.Sources,
+		interval:  config.Interval,
+		chunkSize: config.ChunkSize,
+	}
+}
+
+// Start indexes the sources now and rescans them every interval until Stop
+func (ix *Indexer) Start(ctx context.Context) {
+	ix.mu.Lock()
+	if ix.running {
+		ix.mu.Unlock()
+		return
+	}
+	ix.running = true
+	ctx, ix.cancel = context.WithCancel(ctx)
+	ix.mu.Unlock()
+
+	ix.wg.Add(1)
+	go func() {
+		defer ix.wg.Done()
+
+		ticker := time.NewTicker(ix.interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := ix.Sync(ctx); err != nil && ctx.Err() == nil {
+				logger.WarnContext(ctx, "Failed to index notes", "error", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	logger.InfoContext(ctx, "Watching notes", "sources", len(ix.sources), "interval", ix.interval)
+}
+
+// Stop stops rescanning, abandoning a scan under way
+func (ix *Indexer) Stop() {
+	ix.mu.Lock()
+	if !ix.running {
+		ix.mu.Unlock()
+		return
+	}
+	ix.running = false
+	ix.cancel()
+	ix.mu.Unlock()
+
+	ix.wg.Wait()
+}
+
+// Sync indexes the files of every source that are new or changed since
+// they were last indexed, and drops the passages of files that are gone
+func (ix *Indexer) Sync(ctx context.Context) (*SyncResult, error) {
+	ix.syncMu.Lock()
+	defer ix.syncMu.Unlock()
+
+	result := &SyncResult{}
+	users := make(map[string][]string)
+	for _, source := range ix.sources {
+		users[source.UserID] = append(users[source.UserID], source.Dirs...)
+	}
+	for userID, dirs := range users {
+		if err := ix.syncUser(multiagent.WithUserID(ctx, userID), dirs, result); err != nil {
+			return result, fmt.Errorf("failed to index notes of %s: %w", userID, err)
+		}
+	}
+	if result.Indexed > 0 || result.Removed > 0 || len(result.Failed) > 0 {
+		logger.InfoContext(ctx, "Indexed notes", "indexed", result.Indexed, "removed", result.Removed, "failed", len(result.Failed))
+	}
+	return result, nil
+}
+
+// syncUser brings the index of the user ctx acts for up to date with dirs
+func (ix *Indexer) syncUser(ctx context.Context, dirs []string, result *SyncResult) error {
+	indexed, err := ix.indexedFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				// An unreadable directory leaves the rest of the tree indexed
+				logger.WarnContext(ctx, "Skipping unreadable notes", "path", path, "error", err)
+				if entry != nil && entry.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if entry.IsDir() {
+				if path != dir && strings.HasPrefix(entry.Name(), ".") {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if _, ok := extensions[strings.ToLower(filepath.Ext(path))]; !ok || strings.HasPrefix(entry.Name(), ".") {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil || info.Size() > maxFileSize {
+				return nil
+			}
+
+			seen[path] = true
+			previous, ok := indexed[path]
+			if ok && previous.Size == info.Size() && previous.ModTime.Equal(info.ModTime()) {
+				result.Unchanged++
+				return nil
+			}
+			if err := ix.indexFile(ctx, path, info, previous); err != nil {
+				logger.WarnContext(ctx, "Failed to index note", "path", path, "error", err)
+				result.Failed = append(result.Failed, path)
+				return nil
+			}
+			result.Indexed++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for path, file := range indexed {
+		if seen[path] {
+			continue
+		}
+		if err := ix.removeFile(ctx, file); err != nil {
+			return err
+		}
+		result.Removed++
+	}
+	return nil
+}
+
+// indexedFiles returns what was indexed for the user ctx acts for, by path
+func (ix *Indexer) indexedFiles(ctx context.Context) (map[string]indexedFile, error) {
+	keys, err := ix.store.List(ctx, filePrefix, 100000)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexed notes: %w", err)
+	}
+	files := make(map[string]indexedFile, len(keys))
+	if len(keys) == 0 {
+		return files, nil
+	}
+	values, err := ix.store.GetMultiple(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load indexed notes: %w", err)
+	}
+	for _, value := range values {
+		var file indexedFile
+		if err := decode(value, &file); err == nil && file.Path != "" {
+			files[file.Path] = file
+		}
+	}
+	return files, nil
+}
+
+// indexFile replaces the passages of path with its current content
+func (ix *Indexer) indexFile(ctx context.Context, path string, info fs.FileInfo, previous indexedFile) error {
+	text, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	title := titleOf(path, text)
+	chunks := chunkText(text, ix.chunkSize)
+
+	id := fileID(path)
+	vectorStore, embed := ix.store.(multiagent.VectorMemoryStore)
+	for i, chunk := range chunks {
+		key := fmt.Sprintf("%s%s:%d", KeyPrefix, id, i)
+		passage := Passage{Path: path, Title: title, Index: i, Text: chunk}
+		if err := ix.store.Store(ctx, key, passage); err != nil {
+			return fmt.Errorf("failed to store passage: %w", err)
+		}
+		if embed {
+			metadata := map[string]interface{}{"path": path, "title": title, "passage": i}
+			if err := vectorStore.StoreEmbedding(ctx, key, title+"\n\n"+chunk, metadata); err != nil {
+				return fmt.Errorf("failed to embed passage: %w", err)
+			}
+		}
+	}
+	// An edit that shortened the file leaves passages past its end
+	for i := len(chunks); i < previous.Passages; i++ {
+		ix.store.Delete(ctx, fmt.Sprintf("%s%s:%d", KeyPrefix, id, i))
+	}
+
+	file := indexedFile{Path: path, ModTime: info.ModTime(), Size: info.Size(), Passages: len(chunks)}
+	if err := ix.store.Store(ctx, filePrefix+id, file); err != nil {
+		return fmt.Errorf("failed to record indexed note: %w", err)
+	}
+	return nil
+}
+
+// removeFile drops the passages of a file that is gone
+func (ix *Indexer) removeFile(ctx context.Context, file indexedFile) error {
+	id := fileID(file.Path)
+	for i := 0; i < file.Passages; i++ {
+		ix.store.Delete(ctx, fmt.Sprintf("%s%s:%d", KeyPrefix, id, i))
+	}
+	if err := ix.store.Delete(ctx, filePrefix+id); err != nil && !strings.Contains(err.Error(), "not found") {
+		return fmt.Errorf("failed to forget removed note: %w", err)
+	}
+	return nil
+}
+
+// readFile returns the text of a note
+func readFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if extensions[strings.ToLower(filepath.Ext(path))] == "pdf" {
+		return pdfText(data)
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("not UTF-8 text")
+	}
+	return string(data), nil
+}
+
+// titleOf returns a note's first Markdown heading, or its file name
+func titleOf(path, text string) string {
+	for _, line := range strings.SplitN(text, "\n", 20) {
+		if heading, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok && strings.TrimSpace(heading) != "" {
+			return strings.TrimSpace(heading)
+		}
+	}
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// chunkText splits text into passages of at most size characters, at
+// paragraph breaks where it can and at spaces where a paragraph is longer
+func chunkText(text string, size int) []string {
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+	}
+
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		if current.Len() > 0 && current.Len()+len(paragraph)+2 > size {
+			flush()
+		}
+		for len(paragraph) > size {
+			cut := strings.LastIndexAny(paragraph[:size], " \n\t")
+			if cut <= 0 {
+				cut = size
+				for cut > 0 && !utf8.RuneStart(paragraph[cut]) {
+					cut--
+				}
+			}
+			current.WriteString(paragraph[:cut])
+			flush()
+			paragraph = strings.TrimSpace(paragraph[cut:])
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(paragraph)
+	}
+	flush()
+	return chunks
+}
+
+// fileID names a file's keys after its path
+func fileID(path string) string {
+	sum := sha1.Sum([]byte(path))
+	return hex.EncodeToString(sum[:8])
+}
+
+// decode converts a value read back from memory into into
+func decode(value interface{}, into interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, into)
+}
@@ -0,0 +1,97 @@
+package notes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+func writeNote(t *testing.T, path, text string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexer_SyncUpdatesAndRemoves(t *testing.T) {
+	dir := t.TempDir()
+	garden := filepath.Join(dir, "garden.md")
+	writeNote(t, garden, "# Garden plans\n\nPlant tomatoes along the south fence in May.")
+	writeNote(t, filepath.Join(dir, "trips", "lisbon.txt"), "Lisbon: stay near Alfama, book the tram tour.")
+	writeNote(t, filepath.Join(dir, "photo.jpg"), "not a note")
+	writeNote(t, filepath.Join(dir, ".obsidian", "workspace.md"), "editor state")
+
+	store := memory.PartitionByUser(memory.NewInMemoryStore())
+	indexer := NewIndexer(IndexerConfig{Store: store, Sources: []Source{{UserID: "alice", Dirs: []string{dir}}}})
+	ctx := context.Background()
+	alice := multiagent.WithUserID(ctx, "alice")
+
+	result, err := indexer.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if result.Indexed != 2 || result.Removed != 0 || len(result.Failed) != 0 {
+		t.Fatalf("first sync %+v, want the two notes indexed", result)
+	}
+
+	hits, err := Search(alice, store, "when do I plant tomatoes?", 5)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Path != garden || hits[0].Title != "Garden plans" || !strings.Contains(hits[0].Text, "south fence") {
+		t.Fatalf("hits %+v, want the garden note", hits)
+	}
+	if hits, err := Search(multiagent.WithUserID(ctx, "bob"), store, "tomatoes", 5); err != nil || len(hits) != 0 {
+		t.Errorf("bob's hits %+v (%v), want none", hits, err)
+	}
+
+	if result, err := indexer.Sync(ctx); err != nil || result.Indexed != 0 || result.Unchanged != 2 {
+		t.Fatalf("second sync %+v (%v), want nothing reindexed", result, err)
+	}
+
+	writeNote(t, garden, "# Garden plans\n\nPlant peppers by the shed instead.")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(garden, later, later); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(dir, "trips", "lisbon.txt"))
+
+	result, err = indexer.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync after edits: %v", err)
+	}
+	if result.Indexed != 1 || result.Removed != 1 {
+		t.Fatalf("sync after edits %+v, want one reindexed and one removed", result)
+	}
+	if hits, _ := Search(alice, store, "tomatoes", 5); len(hits) != 0 {
+		t.Errorf("hits for the old text %+v, want none", hits)
+	}
+	if hits, _ := Search(alice, store, "peppers", 5); len(hits) != 1 {
+		t.Errorf("hits for the new text %+v, want the garden note", hits)
+	}
+	if hits, _ := Search(alice, store, "lisbon tram", 5); len(hits) != 0 {
+		t.Errorf("hits for the removed note %+v, want none", hits)
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	text := strings.Repeat("word ", 50) + "\n\n" + strings.Repeat("more ", 10) + "\n\nend"
+	chunks := chunkText(text, 100)
+	for _, chunk := range chunks {
+		if len(chunk) > 100 {
+			t.Errorf("chunk of %d characters exceeds 100", len(chunk))
+		}
+	}
+	if joined := strings.Join(chunks, " "); strings.Count(joined, "word") != 50 || !strings.HasSuffix(joined, "end") {
+		t.Errorf("chunks %q lost text", chunks)
+	}
+}
@@ -0,0 +1,260 @@
+package notes
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxStreamSize bounds an inflated PDF stream, so a malformed file cannot
+// exhaust memory
+const maxStreamSize = 64 << 20
+
+// pdfText extracts the text of a PDF on a best-effort basis: it reads the
+// text-showing operators of its uncompressed and Flate-compressed content
+// streams. Text in fonts with custom encodings, or in scanned images, is
+// not recovered.
+func pdfText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF")) {
+		return "", fmt.Errorf("not a PDF")
+	}
+
+	var text strings.Builder
+	for rest := data; ; {
+		start := bytes.Index(rest, []byte("stream"))
+		if start < 0 {
+			break
+		}
+		// "endstream" contains "stream"; only a keyword at a line end opens one
+		if start >= 3 && string(rest[start-3:start]) == "end" {
+			rest = rest[start+len("stream"):]
+			continue
+		}
+		dict := rest[:start]
+		if open := bytes.LastIndex(dict, []byte("<<")); open >= 0 {
+			dict = dict[open:]
+		}
+		body := rest[start+len("stream"):]
+		body = bytes.TrimPrefix(body, []byte("\r"))
+		body = bytes.TrimPrefix(body, []byte("\n"))
+		end := bytes.Index(body, []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		content := body[:end]
+		rest = body[end+len("endstream"):]
+
+		if skipStream(dict) {
+			continue
+		}
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			inflated, err := inflate(content)
+			if err != nil {
+				continue
+			}
+			content = inflated
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			// Other filters hold images or need decoders we do not have
+			continue
+		}
+		showText(content, &text)
+	}
+
+	result := strings.TrimSpace(text.String())
+	if result == "" {
+		return "", fmt.Errorf("no text found in PDF")
+	}
+	return result, nil
+}
+
+// skipStream reports whether a stream's dictionary marks it as something
+// other than page content
+func skipStream(dict []byte) bool {
+	for _, kind := range []string{"/Image", "/XRef", "/ObjStm", "/Metadata", "/FontFile", "/Length1"} {
+		if bytes.Contains(dict, []byte(kind)) {
+			return true
+		}
+	}
+	return false
+}
+
+// inflate decompresses a Flate stream, keeping what was read if it is
+// truncated
+func inflate(content []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	inflated, err := io.ReadAll(io.LimitReader(reader, maxStreamSize))
+	if err != nil && len(inflated) == 0 {
+		return nil, err
+	}
+	return inflated, nil
+}
+
+// showText appends the text shown by a content stream's Tj, TJ, ' and "
+// operators to text, breaking lines where the stream moves to a new one
+func showText(content []byte, text *strings.Builder) {
+	var operands []string
+	var strs []string
+	newline := func() {
+		if text.Len() > 0 && !strings.HasSuffix(text.String(), "\n") {
+			text.WriteString("\n")
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, next := literalString(content, i)
+			strs = append(strs, s)
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			s, next := hexString(content, i)
+			strs = append(strs, s)
+			i = next
+		case c == '[':
+			strs = nil
+			i++
+		case c == ']':
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case isPDFSpace(c):
+			i++
+		case isPDFDelimiter(c):
+			i++
+			operands = nil
+		default:
+			start := i
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			token := string(content[start:i])
+			if _, err := strconv.ParseFloat(token, 64); err == nil {
+				operands = append(operands, token)
+				// A large negative TJ adjustment is a gap between words
+				if n, _ := strconv.ParseFloat(token, 64); n < -250 && len(strs) > 0 {
+					strs[len(strs)-1] += " "
+				}
+				continue
+			}
+			switch token {
+			case "Tj", "TJ":
+				text.WriteString(strings.Join(strs, ""))
+			case "'", "\"":
+				newline()
+				text.WriteString(strings.Join(strs, ""))
+			case "T*", "ET":
+				newline()
+			case "Td", "TD":
+				if len(operands) >= 2 && operands[len(operands)-1] != "0" {
+					newline()
+				} else if text.Len() > 0 && !strings.HasSuffix(text.String(), " ") && !strings.HasSuffix(text.String(), "\n") {
+					text.WriteString(" ")
+				}
+			}
+			operands = nil
+			strs = nil
+		}
+	}
+}
+
+// literalString reads the "(...)" string starting at content[i], returning
+// it and the index after it
+func literalString(content []byte, i int) (string, int) {
+	var s strings.Builder
+	depth := 0
+	for i++; i < len(content); i++ {
+		c := content[i]
+		switch c {
+		case '(':
+			depth++
+			s.WriteByte(c)
+		case ')':
+			if depth == 0 {
+				return s.String(), i + 1
+			}
+			depth--
+			s.WriteByte(c)
+		case '\\':
+			i++
+			if i >= len(content) {
+				return s.String(), i
+			}
+			switch e := content[i]; e {
+			case 'n':
+				s.WriteByte('\n')
+			case 'r':
+				s.WriteByte('\r')
+			case 't':
+				s.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// A backslash at a line end continues the string
+				if e == '\r' && i+1 < len(content) && content[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					j := i
+					for ; j < len(content) && j < i+3 && content[j] >= '0' && content[j] <= '7'; j++ {
+						n = n*8 + int(content[j]-'0')
+					}
+					s.WriteRune(rune(n & 0xff))
+					i = j - 1
+				} else {
+					s.WriteByte(e)
+				}
+			}
+		default:
+			s.WriteByte(c)
+		}
+	}
+	return s.String(), i
+}
+
+// hexString reads the "<...>" string starting at content[i], returning it
+// and the index after it
+func hexString(content []byte, i int) (string, int) {
+	end := bytes.IndexByte(content[i:], '>')
+	if end < 0 {
+		return "", len(content)
+	}
+	digits := strings.Map(func(r rune) rune {
+		if isPDFSpace(byte(r)) {
+			return -1
+		}
+		return r
+	}, string(content[i+1:i+end]))
+	if len(digits)%2 == 1 {
+		digits += "0"
+	}
+	var s strings.Builder
+	for j := 0; j+1 < len(digits); j += 2 {
+		n, err := strconv.ParseUint(digits[j:j+2], 16, 8)
+		if err != nil {
+			break
+		}
+		if n >= 0x20 || n == '\n' || n == '\t' {
+			s.WriteRune(rune(n))
+		}
+	}
+	return s.String(), i + end + 1
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return c == '(' || c == ')' || c == '<' || c == '>' || c == '[' || c == ']' || c == '{' || c == '}' || c == '/' || c == '%'
+}
@@ -0,0 +1,55 @@
+package notes
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"testing"
+)
+
+// buildPDF returns a minimal PDF whose page content is content, compressed
+// if flate is set
+func buildPDF(content string, flate bool) []byte {
+	stream := []byte(content)
+	filter := ""
+	if flate {
+		var compressed bytes.Buffer
+		w := zlib.NewWriter(&compressed)
+		w.Write(stream)
+		w.Close()
+		stream = compressed.Bytes()
+		filter = " /Filter /FlateDecode"
+	}
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	pdf.WriteString("2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n")
+	pdf.WriteString("3 0 obj << /Type /Page /Parent 2 0 R /Contents 4 0 R >> endobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj << /Length %d%s >>\nstream\n", len(stream), filter)
+	pdf.Write(stream)
+	pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestPDFText(t *testing.T) {
+	content := "BT /F1 12 Tf 72 720 Td (Quarterly \\(draft\\) review) Tj 0 -14 Td " +
+		"[(Revenue) -300 (grew) -20 (4%)] TJ T* <48656C6C6F> Tj ET"
+	want := "Quarterly (draft) review\nRevenue grew4%\nHello"
+
+	for _, flate := range []bool{false, true} {
+		text, err := pdfText(buildPDF(content, flate))
+		if err != nil {
+			t.Fatalf("flate=%v: %v", flate, err)
+		}
+		if text != want {
+			t.Errorf("flate=%v: text %q, want %q", flate, text, want)
+		}
+	}
+
+	if _, err := pdfText([]byte("plain text")); err == nil {
+		t.Error("pdfText accepted a file that is not a PDF")
+	}
+	if _, err := pdfText(buildPDF("0 0 m 100 100 l S", false)); err == nil {
+		t.Error("pdfText accepted a PDF without text")
+	}
+}
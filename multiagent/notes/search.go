@@ -0,0 +1,139 @@
+package notes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// maxPassages bounds the passages scanned by a keyword search
+const maxPassages = 20000
+
+// Hit is a passage of a user's notes that matches a search
+type Hit struct {
+	Path  string  `json:"path"`
+	Title string  `json:"title"`
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+}
+
+// Search returns up to limit passages of the notes of the user ctx acts for
+// that best match query, best first. Vector stores are searched by meaning;
+// other stores by the words query shares with each passage.
+func Search(ctx context.Context, store multiagent.MemoryStore, query string, limit int) ([]Hit, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+	if vectorStore, ok := store.(multiagent.VectorMemoryStore); ok {
+		return searchSimilar(ctx, vectorStore, query, limit)
+	}
+	return searchTerms(ctx, store, query, limit)
+}
+
+// searchSimilar searches passages by embedding, dropping other memories
+// that are close to query
+func searchSimilar(ctx context.Context, store multiagent.VectorMemoryStore, query string, limit int) ([]Hit, error) {
+	similar, err := store.SearchSimilar(ctx, query, limit*4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search notes: %w", err)
+	}
+	hits := []Hit{}
+	for _, match := range similar {
+		if !strings.HasPrefix(match.Entry.Key, KeyPrefix) {
+			continue
+		}
+		var passage Passage
+		if err := decode(match.Entry.Value, &passage); err != nil || passage.Text == "" {
+			continue
+		}
+		hits = append(hits, Hit{Path: passage.Path, Title: passage.Title, Text: passage.Text, Score: match.Score})
+		if len(hits) == limit {
+			break
+		}
+	}
+	return hits, nil
+}
+
+// searchTerms scores every passage by the share of query's words it
+// contains, counting a word in the title twice
+func searchTerms(ctx context.Context, store multiagent.MemoryStore, query string, limit int) ([]Hit, error) {
+	terms := searchTermsOf(query)
+	if len(terms) == 0 {
+		return []Hit{}, nil
+	}
+	keys, err := store.List(ctx, KeyPrefix, maxPassages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notes: %w", err)
+	}
+	if len(keys) == 0 {
+		return []Hit{}, nil
+	}
+	values, err := store.GetMultiple(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notes: %w", err)
+	}
+
+	hits := []Hit{}
+	for _, value := range values {
+		var passage Passage
+		if err := decode(value, &passage); err != nil || passage.Text == "" {
+			continue
+		}
+		words := searchTermsOf(passage.Text)
+		title := searchTermsOf(passage.Title)
+		score := 0.0
+		for term := range terms {
+			if words[term] {
+				score++
+			}
+			if title[term] {
+				score++
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		hits = append(hits, Hit{
+			Path:  passage.Path,
+			Title: passage.Title,
+			Text:  passage.Text,
+			Score: score / float64(2*len(terms)),
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Path < hits[j].Path
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// searchTermsOf returns text's lowercased words of three letters or more
+func searchTermsOf(text string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) >= 3 && !stopwords[word] {
+			terms[word] = true
+		}
+	}
+	return terms
+}
+
+// stopwords are too common to tell passages apart
+var stopwords = map[string]bool{
+	"and": true, "are": true, "about": true, "did": true, "does": true,
+	"for": true, "from": true, "have": true, "how": true, "its": true,
+	"not": true, "notes": true, "that": true, "the": true, "this": true,
+	"was": true, "what": true, "when": true, "where": true, "which": true,
+	"who": true, "why": true, "with": true, "you": true, "your": true,
+}
@@ -0,0 +1,253 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// ConsoleChannel prints notifications, e.g. to the terminal running the server
+type ConsoleChannel struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewConsoleChannel creates a channel writing to w (default os.Stdout)
+func NewConsoleChannel(w io.Writer) *ConsoleChannel {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &ConsoleChannel{writer: w}
+}
+
+// Name implements Channel
+func (c *ConsoleChannel) Name() string { return "console" }
+
+// Send implements Channel
+func (c *ConsoleChannel) Send(ctx context.Context, n Notification) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	user := ""
+	if n.UserID != "" {
+		user = " @" + n.UserID
+	}
+	_, err := fmt.Fprintf(c.writer, "🔔 [%s]%s %s: %s\n", n.At.Format("2006-01-02 15:04"), user, n.Title, n.Body)
+	return err
+}
+
+// DesktopChannel shows a desktop notification with notify-send (Linux) or
+// osascript (macOS) on the machine running the server
+type DesktopChannel struct{}
+
+// Name implements Channel
+func (DesktopChannel) Name() string { return "desktop" }
+
+// Send implements Channel
+func (DesktopChannel) Send(ctx context.Context, n Notification) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd":
+		urgency := "normal"
+		if n.Priority >= multiagent.PriorityHigh {
+			urgency = "critical"
+		}
+		cmd = exec.CommandContext(ctx, "notify-send", "--urgency", urgency, n.Title, n.Body)
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(n.Body), strconv.Quote(n.Title))
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", cmd.Path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// WebhookChannel POSTs each notification as JSON
+type WebhookChannel struct {
+	URL string
+	// Headers are sent with every request, e.g. Authorization
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// Name implements Channel
+func (c *WebhookChannel) Name() string { return "webhook" }
+
+// Send implements Channel
+func (c *WebhookChannel) Send(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	return post(ctx, c.Client, c.URL, "application/json", bytes.NewReader(body), c.Headers)
+}
+
+// NtfyChannel publishes to an ntfy topic (https://ntfy.sh)
+type NtfyChannel struct {
+	// Server defaults to https://ntfy.sh
+	Server string
+	Topic  string
+	// Token is an access token for protected topics
+	Token  string
+	Client *http.Client
+}
+
+// Name implements Channel
+func (c *NtfyChannel) Name() string { return "ntfy" }
+
+// Send implements Channel
+func (c *NtfyChannel) Send(ctx context.Context, n Notification) error {
+	server := c.Server
+	if server == "" {
+		server = "https://ntfy.sh"
+	}
+	headers := map[string]string{
+		// Header values must be ASCII; ntfy decodes RFC 2047 words
+		"Title":    mime.QEncoding.Encode("utf-8", n.Title),
+		"Priority": strconv.Itoa(ntfyPriority(n.Priority)),
+		"Tags":     "bell",
+	}
+	if c.Token != "" {
+		headers["Authorization"] = "Bearer " + c.Token
+	}
+	return post(ctx, c.Client, strings.TrimRight(server, "/")+"/"+url.PathEscape(c.Topic), "text/plain; charset=utf-8", strings.NewReader(n.Body), headers)
+}
+
+// ntfyPriority maps priorities onto ntfy's 1 (min) to 5 (max)
+func ntfyPriority(priority multiagent.Priority) int {
+	switch priority {
+	case multiagent.PriorityLow:
+		return 2
+	case multiagent.PriorityHigh:
+		return 4
+	case multiagent.PriorityCritical:
+		return 5
+	default:
+		return 3
+	}
+}
+
+// PushoverChannel sends Pushover messages (https://pushover.net)
+type PushoverChannel struct {
+	// Token is the application's API token
+	Token string
+	// UserKey is the recipient's user or group key
+	UserKey string
+	// APIURL defaults to Pushover's messages endpoint
+	APIURL string
+	Client *http.Client
+}
+
+// Name implements Channel
+func (c *PushoverChannel) Name() string { return "pushover" }
+
+// Send implements Channel
+func (c *PushoverChannel) Send(ctx context.Context, n Notification) error {
+	apiURL := c.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.pushover.net/1/messages.json"
+	}
+	priority := 0
+	switch n.Priority {
+	case multiagent.PriorityLow:
+		priority = -1
+	case multiagent.PriorityHigh, multiagent.PriorityCritical:
+		priority = 1
+	}
+	form := url.Values{
+		"token":     {c.Token},
+		"user":      {c.UserKey},
+		"title":     {n.Title},
+		"message":   {n.Body},
+		"priority":  {strconv.Itoa(priority)},
+		"timestamp": {strconv.FormatInt(n.At.Unix(), 10)},
+	}
+	return post(ctx, c.Client, apiURL, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()), nil)
+}
+
+// EmailChannel sends notifications by SMTP
+type EmailChannel struct {
+	// Addr is the SMTP server's host:port
+	Addr     string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Name implements Channel
+func (c *EmailChannel) Name() string { return "email" }
+
+// Send implements Channel; net/smtp cannot be cancelled, so ctx only stops
+// a send that has not started
+func (c *EmailChannel) Send(ctx context.Context, n Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if c.Username != "" {
+		host, _, _ := strings.Cut(c.Addr, ":")
+		auth = smtp.PlainAuth("", c.Username, c.Password, host)
+	}
+	if err := smtp.SendMail(c.Addr, auth, c.From, c.To, c.message(n)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// message formats n as a plain-text email
+func (c *EmailChannel) message(n Notification) []byte {
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(n.Title)
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", c.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(c.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", n.At.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(n.Body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+func post(ctx context.Context, client *http.Client, target, contentType string, body io.Reader, headers map[string]string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
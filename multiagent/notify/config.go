@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ChannelConfig describes one channel in a config file; which fields apply
+// depends on Type. String fields may reference environment variables, e.g.
+// "$PUSHOVER_TOKEN".
+type ChannelConfig struct {
+	// Type is console, desktop, webhook, email, ntfy or pushover
+	Type string `json:"type"`
+
+	// webhook
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// ntfy (Server defaults to https://ntfy.sh) and pushover (Token, User)
+	Server string `json:"server,omitempty"`
+	Topic  string `json:"topic,omitempty"`
+	Token  string `json:"token,omitempty"`
+	User   string `json:"user,omitempty"`
+
+	// email
+	SMTPAddr string   `json:"smtp_addr,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+}
+
+// NewChannel creates the channel config describes
+func NewChannel(config ChannelConfig) (Channel, error) {
+	expand := os.ExpandEnv
+	switch strings.ToLower(config.Type) {
+	case "console":
+		return NewConsoleChannel(nil), nil
+	case "desktop":
+		return DesktopChannel{}, nil
+	case "webhook":
+		if config.URL == "" {
+			return nil, fmt.Errorf("webhook channel needs a url")
+		}
+		headers := make(map[string]string, len(config.Headers))
+		for name, value := range config.Headers {
+			headers[name] = expand(value)
+		}
+		return &WebhookChannel{URL: expand(config.URL), Headers: headers}, nil
+	case "ntfy":
+		if config.Topic == "" {
+			return nil, fmt.Errorf("ntfy channel needs a topic")
+		}
+		return &NtfyChannel{Server: expand(config.Server), Topic: expand(config.Topic), Token: expand(config.Token)}, nil
+	case "pushover":
+		if config.Token == "" || config.User == "" {
+			return nil, fmt.Errorf("pushover channel needs a token and a user")
+		}
+		return &PushoverChannel{Token: expand(config.Token), UserKey: expand(config.User)}, nil
+	case "email":
+		if config.SMTPAddr == "" || config.From == "" || len(config.To) == 0 {
+			return nil, fmt.Errorf("email channel needs smtp_addr, from and to")
+		}
+		return &EmailChannel{
+			Addr:     expand(config.SMTPAddr),
+			Username: expand(config.Username),
+			Password: expand(config.Password),
+			From:     expand(config.From),
+			To:       config.To,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown notification channel type %q", config.Type)
+	}
+}
+
+// LoadConfig reads notification channels from a JSON file; users without
+// channels of their own get the defaults:
+//
+//	{"default": [{"type": "console"}],
+//	 "users": {"alice": [{"type": "ntfy", "topic": "alice-reminders"},
+//	                     {"type": "email", "smtp_addr": "smtp.example.com:587", "username": "bot",
+//	                      "password": "$SMTP_PASSWORD", "from": "bot@example.com", "to": ["alice@example.com"]}]}}
+func LoadConfig(path string) (DispatcherConfig, error) {
+	var config DispatcherConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read notification config: %w", err)
+	}
+	var file struct {
+		Default []ChannelConfig            `json:"default"`
+		Users   map[string][]ChannelConfig `json:"users"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return config, fmt.Errorf("failed to parse notification config: %w", err)
+	}
+
+	if config.Default, err = newChannels(file.Default); err != nil {
+		return config, fmt.Errorf("invalid default channels: %w", err)
+	}
+	config.Users = make(map[string][]Channel, len(file.Users))
+	for userID, configs := range file.Users {
+		if config.Users[userID], err = newChannels(configs); err != nil {
+			return config, fmt.Errorf("invalid channels for user %q: %w", userID, err)
+		}
+	}
+	return config, nil
+}
+
+func newChannels(configs []ChannelConfig) ([]Channel, error) {
+	channels := make([]Channel, 0, len(configs))
+	for _, config := range configs {
+		channel, err := NewChannel(config)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
@@ -0,0 +1,185 @@
+// Package notify delivers notifications such as task and event reminders to
+// users over pluggable channels: the console, desktop notifications,
+// webhooks, email, ntfy and Pushover.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+var logger = logging.For("notify")
+
+// Notification kinds
+const (
+	KindTaskReminder   = "task_reminder"
+	KindEventReminder  = "event_reminder"
+	KindBriefing       = "daily_briefing"
+	KindWeeklyReview   = "weekly_review"
+	KindFollowUp       = "follow_up"
+	KindReconnect      = "reconnect"
+	KindScheduledSend  = "scheduled_send"
+	KindFactReview     = "fact_review"
+	KindResearchUpdate = "research_update"
+)
+
+// Notification is one message for a user
+type Notification struct {
+	UserID   string              `json:"user_id,omitempty"`
+	Kind     string              `json:"kind"`
+	Title    string              `json:"title"`
+	Body     string              `json:"body"`
+	Priority multiagent.Priority `json:"priority"`
+	// At is when the notification was raised
+	At time.Time `json:"at"`
+	// Subject is the ID of the task, reminder or event it is about
+	Subject string `json:"subject,omitempty"`
+}
+
+// Notifier delivers notifications
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// Channel is one way of reaching a user
+type Channel interface {
+	// Name identifies the channel in logs and errors, e.g. "ntfy"
+	Name() string
+	Send(ctx context.Context, notification Notification) error
+}
+
+// DispatcherConfig holds configuration for creating a Dispatcher
+type DispatcherConfig struct {
+	// Default channels reach users without channels of their own
+	Default []Channel
+	// Users maps user IDs to their channels
+	Users map[string][]Channel
+	// Prefixes maps user ID prefixes to the channels of users without
+	// channels of their own, e.g. "slack-" for everyone reached over Slack
+	Prefixes map[string][]Channel
+	// Timeout bounds each channel's delivery (default 30s)
+	Timeout time.Duration
+}
+
+// Dispatcher routes each notification to its user's channels
+type Dispatcher struct {
+	mu       sync.RWMutex
+	defaults []Channel
+	users    map[string][]Channel
+	prefixes map[string][]Channel
+	timeout  time.Duration
+}
+
+// NewDispatcher creates a dispatcher
+func NewDispatcher(config DispatcherConfig) *Dispatcher {
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	users := make(map[string][]Channel, len(config.Users))
+	for userID, channels := range config.Users {
+		users[userID] = channels
+	}
+	prefixes := make(map[string][]Channel, len(config.Prefixes))
+	for prefix, channels := range config.Prefixes {
+		prefixes[prefix] = channels
+	}
+	return &Dispatcher{defaults: config.Default, users: users, prefixes: prefixes, timeout: config.Timeout}
+}
+
+// Reconfigure replaces the default, per-user and per-prefix channels, and
+// the timeout if config sets one; notifications already being sent finish
+// on the old channels
+func (d *Dispatcher) Reconfigure(config DispatcherConfig) {
+	users := make(map[string][]Channel, len(config.Users))
+	for userID, channels := range config.Users {
+		users[userID] = channels
+	}
+	prefixes := make(map[string][]Channel, len(config.Prefixes))
+	for prefix, channels := range config.Prefixes {
+		prefixes[prefix] = channels
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.defaults = config.Default
+	d.users = users
+	d.prefixes = prefixes
+	if config.Timeout > 0 {
+		d.timeout = config.Timeout
+	}
+}
+
+// SetUserChannels replaces userID's channels; nil reverts to the defaults
+func (d *Dispatcher) SetUserChannels(userID string, channels []Channel) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if channels == nil {
+		delete(d.users, userID)
+		return
+	}
+	d.users[userID] = channels
+}
+
+// Channels returns the channels notifications for userID go to: the
+// user's own, those of the longest prefix of the ID, or the defaults
+func (d *Dispatcher) Channels(userID string) []Channel {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if channels, ok := d.users[userID]; ok {
+		return channels
+	}
+	longest := ""
+	for prefix := range d.prefixes {
+		if strings.HasPrefix(userID, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	if longest != "" {
+		return d.prefixes[longest]
+	}
+	return d.defaults
+}
+
+// Notify sends notification on every channel of its user, concurrently. It
+// fails only if no channel delivered it, returning every channel's error.
+func (d *Dispatcher) Notify(ctx context.Context, notification Notification) error {
+	if notification.At.IsZero() {
+		notification.At = time.Now()
+	}
+	channels := d.Channels(notification.UserID)
+	d.mu.RLock()
+	timeout := d.timeout
+	d.mu.RUnlock()
+	if len(channels) == 0 {
+		return fmt.Errorf("no notification channels for user %q", notification.UserID)
+	}
+
+	errs := make([]error, len(channels))
+	var wg sync.WaitGroup
+	for i, channel := range channels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sendCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			if err := channel.Send(sendCtx, notification); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", channel.Name(), err)
+				logger.WarnContext(ctx, "Failed to deliver notification", "channel", channel.Name(), logging.KeyUserID, notification.UserID, "kind", notification.Kind, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to deliver notification: %w", errors.Join(errs...))
+}
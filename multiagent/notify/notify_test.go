@@ -0,0 +1,250 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// recordingChannel remembers what it was sent and fails if err is set
+type recordingChannel struct {
+	name string
+	err  error
+
+	mu   sync.Mutex
+	sent []Notification
+}
+
+func (c *recordingChannel) Name() string { return c.name }
+
+func (c *recordingChannel) Send(ctx context.Context, n Notification) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, n)
+	return c.err
+}
+
+func TestDispatcher_Routing(t *testing.T) {
+	fallback := &recordingChannel{name: "fallback"}
+	alice := &recordingChannel{name: "alice"}
+	dispatcher := NewDispatcher(DispatcherConfig{
+		Default: []Channel{fallback},
+		Users:   map[string][]Channel{"alice": {alice}},
+	})
+	ctx := context.Background()
+
+	for _, userID := range []string{"alice", "bob"} {
+		if err := dispatcher.Notify(ctx, Notification{UserID: userID, Title: "Reminder"}); err != nil {
+			t.Fatalf("Notify(%s): %v", userID, err)
+		}
+	}
+	if len(alice.sent) != 1 || alice.sent[0].UserID != "alice" || alice.sent[0].At.IsZero() {
+		t.Errorf("expected alice's notification on her channel with a time, got %+v", alice.sent)
+	}
+	if len(fallback.sent) != 1 || fallback.sent[0].UserID != "bob" {
+		t.Errorf("expected bob's notification on the default channel, got %+v", fallback.sent)
+	}
+
+	dispatcher.SetUserChannels("alice", nil)
+	if err := dispatcher.Notify(ctx, Notification{UserID: "alice"}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(fallback.sent) != 2 {
+		t.Errorf("expected alice to fall back to the defaults, got %d", len(fallback.sent))
+	}
+}
+
+func TestDispatcher_Prefixes(t *testing.T) {
+	fallback := &recordingChannel{name: "fallback"}
+	slack := &recordingChannel{name: "slack"}
+	team := &recordingChannel{name: "team"}
+	own := &recordingChannel{name: "own"}
+	dispatcher := NewDispatcher(DispatcherConfig{
+		Default:  []Channel{fallback},
+		Users:    map[string][]Channel{"slack-U1": {own}},
+		Prefixes: map[string][]Channel{"slack-": {slack}, "slack-T9-": {team}},
+	})
+
+	for userID, want := range map[string]Channel{
+		"slack-U1":    own,
+		"slack-U2":    slack,
+		"slack-T9-U3": team,
+		"bob":         fallback,
+	} {
+		if got := dispatcher.Channels(userID); len(got) != 1 || got[0] != want {
+			t.Errorf("Channels(%s) = %v, want %s", userID, got, want.Name())
+		}
+	}
+}
+
+func TestDispatcher_Reconfigure(t *testing.T) {
+	console := &recordingChannel{name: "console"}
+	ntfy := &recordingChannel{name: "ntfy"}
+	dispatcher := NewDispatcher(DispatcherConfig{Default: []Channel{console}})
+	dispatcher.Reconfigure(DispatcherConfig{
+		Default: []Channel{console},
+		Users:   map[string][]Channel{"alice": {ntfy}},
+	})
+
+	ctx := context.Background()
+	for _, userID := range []string{"alice", "bob"} {
+		if err := dispatcher.Notify(ctx, Notification{UserID: userID}); err != nil {
+			t.Fatalf("Notify(%s): %v", userID, err)
+		}
+	}
+	if len(ntfy.sent) != 1 || ntfy.sent[0].UserID != "alice" {
+		t.Errorf("expected alice's notification on her new channel, got %+v", ntfy.sent)
+	}
+	if len(console.sent) != 1 || console.sent[0].UserID != "bob" {
+		t.Errorf("expected bob's notification on the default channel, got %+v", console.sent)
+	}
+
+	dispatcher.Reconfigure(DispatcherConfig{})
+	if err := dispatcher.Notify(ctx, Notification{UserID: "alice"}); err == nil {
+		t.Error("expected an error without channels")
+	}
+}
+
+func TestDispatcher_Failures(t *testing.T) {
+	broken := &recordingChannel{name: "broken", err: errors.New("unreachable")}
+	working := &recordingChannel{name: "working"}
+	ctx := context.Background()
+
+	if err := NewDispatcher(DispatcherConfig{Default: []Channel{broken, working}}).Notify(ctx, Notification{}); err != nil {
+		t.Errorf("expected success when one channel delivers, got %v", err)
+	}
+
+	err := NewDispatcher(DispatcherConfig{Default: []Channel{broken, broken}}).Notify(ctx, Notification{})
+	if err == nil || !strings.Contains(err.Error(), "broken: unreachable") {
+		t.Errorf("expected the channel errors, got %v", err)
+	}
+
+	if err := NewDispatcher(DispatcherConfig{}).Notify(ctx, Notification{UserID: "carol"}); err == nil {
+		t.Error("expected an error without channels")
+	}
+}
+
+func TestHTTPChannels(t *testing.T) {
+	var got *http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got, body = r, string(data)
+		if r.URL.Path == "/fail" {
+			http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	n := Notification{UserID: "alice", Kind: KindEventReminder, Title: "Dentist in 15 minutes", Body: "Main St 1", Priority: multiagent.PriorityHigh, At: time.Now()}
+
+	webhook := &WebhookChannel{URL: server.URL + "/hook", Headers: map[string]string{"Authorization": "Bearer x"}}
+	if err := webhook.Send(ctx, n); err != nil {
+		t.Fatalf("webhook: %v", err)
+	}
+	if got.Header.Get("Authorization") != "Bearer x" || !strings.Contains(body, `"kind":"event_reminder"`) {
+		t.Errorf("webhook sent %v %s", got.Header, body)
+	}
+
+	ntfy := &NtfyChannel{Server: server.URL, Topic: "alice-reminders", Token: "tk"}
+	if err := ntfy.Send(ctx, n); err != nil {
+		t.Fatalf("ntfy: %v", err)
+	}
+	if got.URL.Path != "/alice-reminders" || got.Header.Get("Title") != n.Title || got.Header.Get("Priority") != "4" || body != "Main St 1" {
+		t.Errorf("ntfy sent %s %v %q", got.URL.Path, got.Header, body)
+	}
+
+	pushover := &PushoverChannel{Token: "app", UserKey: "user", APIURL: server.URL + "/1/messages.json"}
+	if err := pushover.Send(ctx, n); err != nil {
+		t.Fatalf("pushover: %v", err)
+	}
+	if form, err := url.ParseQuery(body); err != nil || form.Get("user") != "user" || form.Get("priority") != "1" || form.Get("title") != n.Title {
+		t.Errorf("pushover sent %v (%v)", form, err)
+	}
+
+	failing := &WebhookChannel{URL: server.URL + "/fail"}
+	if err := failing.Send(ctx, n); err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("expected the server's error, got %v", err)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	channel := &EmailChannel{From: "bot@example.com", To: []string{"alice@example.com"}}
+	message := string(channel.message(Notification{
+		Title: "Café with Bob\nBcc: evil@example.com",
+		Body:  "Line one\nLine two",
+		At:    time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC),
+	}))
+
+	header, body, ok := strings.Cut(message, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("no header/body separator in %q", message)
+	}
+	if strings.Contains(header, "\r\nBcc:") || !strings.Contains(header, "Subject: =?utf-8?q?") {
+		t.Errorf("expected a single encoded subject line, got %q", header)
+	}
+	if !strings.Contains(header, "To: alice@example.com\r\n") {
+		t.Errorf("missing recipient in %q", header)
+	}
+	if body != "Line one\r\nLine two\r\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestConsoleChannel(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewConsoleChannel(&buf).Send(context.Background(), Notification{UserID: "alice", Title: "Standup", Body: "in 10 minutes"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if !strings.Contains(buf.String(), "@alice Standup: in 10 minutes") {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("NTFY_TOKEN", "secret")
+	path := filepath.Join(t.TempDir(), "notify.json")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"default": [{"type": "console"}],
+		"users": {"alice": [{"type": "ntfy", "topic": "alice", "token": "$NTFY_TOKEN"}, {"type": "webhook", "url": "http://localhost/hook"}]}}`)
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(config.Default) != 1 || config.Default[0].Name() != "console" {
+		t.Errorf("default channels = %v", config.Default)
+	}
+	alice := config.Users["alice"]
+	if len(alice) != 2 || alice[0].(*NtfyChannel).Token != "secret" || alice[1].Name() != "webhook" {
+		t.Errorf("alice's channels = %+v", alice)
+	}
+
+	for _, invalid := range []string{
+		`{"default": [{"type": "carrier-pigeon"}]}`,
+		`{"users": {"bob": [{"type": "email", "smtp_addr": "localhost:25"}]}}`,
+		`{"default": [`,
+	} {
+		write(invalid)
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("expected an error for %s", invalid)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// Routing score weights; a candidate must match at least one capability to be considered
+const (
+	capabilityWeight = 0.6
+	workloadWeight   = 0.25
+	successWeight    = 0.15
+)
+
+// CapabilityCandidate is an agent scored against a task or message
+type CapabilityCandidate struct {
+	AgentID     multiagent.AgentID `json:"agent_id"`
+	Capability  string             `json:"capability"`
+	Match       float64            `json:"match"`
+	SuccessRate float64            `json:"success_rate"`
+	Workload    int                `json:"workload"`
+	Score       float64            `json:"score"`
+}
+
+// CapabilityRegistry indexes structured capability descriptors by agent and
+// tracks each agent's task success rate
+type CapabilityRegistry struct {
+	mu          sync.RWMutex
+	descriptors map[multiagent.AgentID][]multiagent.CapabilityDescriptor
+	published   map[multiagent.AgentID][]multiagent.CapabilityDescriptor
+	outcomes    map[multiagent.AgentID]*agentOutcomes
+}
+
+type agentOutcomes struct {
+	succeeded int
+	failed    int
+}
+
+// NewCapabilityRegistry creates an empty registry
+func NewCapabilityRegistry() *CapabilityRegistry {
+	return &CapabilityRegistry{
+		descriptors: make(map[multiagent.AgentID][]multiagent.CapabilityDescriptor),
+		published:   make(map[multiagent.AgentID][]multiagent.CapabilityDescriptor),
+		outcomes:    make(map[multiagent.AgentID]*agentOutcomes),
+	}
+}
+
+// Register records an agent's capabilities: those published for it, else
+// DescribeCapabilities when the agent implements it, else descriptors derived
+// from GetCapabilities ("calendar_management" -> domain calendar, action
+// management)
+func (r *CapabilityRegistry) Register(agent multiagent.Agent) {
+	var descriptors []multiagent.CapabilityDescriptor
+	if describer, ok := agent.(multiagent.CapabilityDescriber); ok {
+		descriptors = describer.DescribeCapabilities()
+	}
+	if len(descriptors) == 0 {
+		for _, capability := range agent.GetCapabilities() {
+			descriptors = append(descriptors, DescriptorFromName(capability))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if published, ok := r.published[agent.ID()]; ok {
+		descriptors = published
+	}
+	r.descriptors[agent.ID()] = descriptors
+}
+
+// Publish replaces an agent's capabilities with descriptors, which are kept
+// when the agent is registered again after a restart
+func (r *CapabilityRegistry) Publish(agentID multiagent.AgentID, descriptors []multiagent.CapabilityDescriptor) {
+	descriptors = append([]multiagent.CapabilityDescriptor(nil), descriptors...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.published[agentID] = descriptors
+	r.descriptors[agentID] = descriptors
+}
+
+// Unregister forgets an agent's capabilities and history
+func (r *CapabilityRegistry) Unregister(agentID multiagent.AgentID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.descriptors, agentID)
+	delete(r.published, agentID)
+	delete(r.outcomes, agentID)
+}
+
+// Descriptors returns the capabilities registered for an agent
+func (r *CapabilityRegistry) Descriptors(agentID multiagent.AgentID) []multiagent.CapabilityDescriptor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]multiagent.CapabilityDescriptor(nil), r.descriptors[agentID]...)
+}
+
+// RecordOutcome updates an agent's success rate after a task finishes
+func (r *CapabilityRegistry) RecordOutcome(agentID multiagent.AgentID, succeeded bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	outcomes := r.outcomes[agentID]
+	if outcomes == nil {
+		outcomes = &agentOutcomes{}
+		r.outcomes[agentID] = outcomes
+	}
+	if succeeded {
+		outcomes.succeeded++
+	} else {
+		outcomes.failed++
+	}
+}
+
+// SuccessRate returns the smoothed share of an agent's tasks that completed;
+// agents without history start at 0.5
+func (r *CapabilityRegistry) SuccessRate(agentID multiagent.AgentID) float64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	outcomes := r.outcomes[agentID]
+	if outcomes == nil {
+		return 0.5
+	}
+	return float64(outcomes.succeeded+1) / float64(outcomes.succeeded+outcomes.failed+2)
+}
+
+// MatchTask scores how well an agent's capabilities fit a task
+func (r *CapabilityRegistry) MatchTask(agentID multiagent.AgentID, task multiagent.Task) (float64, string) {
+	terms := tokenize(task.Type + " " + task.Description)
+	return r.match(agentID, task.Type, terms, task.Input)
+}
+
+// MatchText scores how well an agent's capabilities fit free-form text
+func (r *CapabilityRegistry) MatchText(agentID multiagent.AgentID, text string) (float64, string) {
+	return r.match(agentID, "", tokenize(text), nil)
+}
+
+func (r *CapabilityRegistry) match(agentID multiagent.AgentID, name string, terms map[string]bool, input map[string]interface{}) (float64, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	best, bestName := 0.0, ""
+	for _, descriptor := range r.descriptors[agentID] {
+		score := scoreDescriptor(descriptor, name, terms)
+		if score > 0 && input != nil && !hasRequiredInput(descriptor.InputSchema, input) {
+			score /= 2
+		}
+		if score > best {
+			best, bestName = score, descriptor.Name
+		}
+	}
+	return best, bestName
+}
+
+// scoreDescriptor returns 1 for an exact name match, otherwise partial
+// credit for domain, action, and keyword overlap
+func scoreDescriptor(descriptor multiagent.CapabilityDescriptor, name string, terms map[string]bool) float64 {
+	if name != "" && (strings.EqualFold(name, descriptor.Name) ||
+		strings.EqualFold(name, descriptor.Domain+"."+descriptor.Action)) {
+		return 1
+	}
+
+	score := 0.0
+	if descriptor.Domain != "" && terms[stem(strings.ToLower(descriptor.Domain))] {
+		score += 0.5
+	}
+	if descriptor.Action != "" && terms[stem(strings.ToLower(descriptor.Action))] {
+		score += 0.3
+	}
+	if len(descriptor.Keywords) > 0 {
+		hits := 0
+		for _, keyword := range descriptor.Keywords {
+			if terms[stem(strings.ToLower(keyword))] {
+				hits++
+			}
+		}
+		score += 0.2 * float64(hits) / float64(len(descriptor.Keywords))
+	}
+	return score
+}
+
+// hasRequiredInput checks the "required" list of a JSON schema against task input
+func hasRequiredInput(schema map[string]interface{}, input map[string]interface{}) bool {
+	if schema == nil {
+		return true
+	}
+
+	var required []string
+	switch fields := schema["required"].(type) {
+	case []string:
+		required = fields
+	case []interface{}:
+		for _, field := range fields {
+			if name, ok := field.(string); ok {
+				required = append(required, name)
+			}
+		}
+	}
+	for _, field := range required {
+		if _, ok := input[field]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// DescriptorFromName derives a descriptor from a capability string such as
+// "appointment_scheduling"; the first word is the domain and the rest the action
+func DescriptorFromName(name string) multiagent.CapabilityDescriptor {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == '_' || r == '.' || r == '-' || unicode.IsSpace(r)
+	})
+
+	descriptor := multiagent.CapabilityDescriptor{Name: name}
+	if len(words) > 0 {
+		descriptor.Domain = words[0]
+	}
+	if len(words) > 1 {
+		descriptor.Action = words[len(words)-1]
+		descriptor.Keywords = words[1 : len(words)-1]
+	}
+	return descriptor
+}
+
+// tokenize lowercases text and returns its stemmed words
+func tokenize(text string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) > 2 {
+			terms[stem(word)] = true
+		}
+	}
+	return terms
+}
+
+// stem strips common English suffixes so "scheduling", "schedules", and
+// "schedule" compare equal
+func stem(word string) string {
+	for stripped := true; stripped; {
+		stripped = false
+		for _, suffix := range []string{"ing", "ment", "ion", "es", "s", "e"} {
+			if len(word) > len(suffix)+3 && strings.HasSuffix(word, suffix) {
+				word = word[:len(word)-len(suffix)]
+				stripped = true
+				break
+			}
+		}
+	}
+	return word
+}
+
+// rankAgents scores every available agent; callers hold o.mu
+func (o *DefaultOrchestrator) rankAgents(match func(multiagent.AgentID) (float64, string)) []CapabilityCandidate {
+	candidates := make([]CapabilityCandidate, 0, len(o.agents))
+	for id, agent := range o.agents {
+		state := agent.GetState()
+		if state.Status != multiagent.AgentStatusIdle && state.Status != multiagent.AgentStatusBusy {
+			continue
+		}
+
+		score, capability := match(id)
+		if score <= 0 {
+			continue
+		}
+
+		workload := state.Workload
+		if workload < 0 {
+			workload = 0
+		}
+		if workload > 100 {
+			workload = 100
+		}
+		successRate := o.capabilities.SuccessRate(id)
+
+		candidates = append(candidates, CapabilityCandidate{
+			AgentID:     id,
+			Capability:  capability,
+			Match:       score,
+			SuccessRate: successRate,
+			Workload:    state.Workload,
+			Score: capabilityWeight*score +
+				workloadWeight*(1-float64(workload)/100) +
+				successWeight*successRate,
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].AgentID < candidates[j].AgentID
+	})
+	return candidates
+}
+
+// RankAgentsForTask returns the agents able to take task, best first
+func (o *DefaultOrchestrator) RankAgentsForTask(task multiagent.Task) []CapabilityCandidate {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.rankAgents(func(id multiagent.AgentID) (float64, string) {
+		return o.capabilities.MatchTask(id, task)
+	})
+}
+
+// RouteByCapability sends a free-form message to the agent whose
+// capabilities best match its content and returns that agent
+func (o *DefaultOrchestrator) RouteByCapability(ctx context.Context, msg *multiagent.Message) (multiagent.AgentID, error) {
+	o.mu.RLock()
+	candidates := o.rankAgents(func(id multiagent.AgentID) (float64, string) {
+		if id == msg.From {
+			return 0, ""
+		}
+		return o.capabilities.MatchText(id, msg.Content)
+	})
+	o.mu.RUnlock()
+
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no agent has a capability matching message %s", msg.ID)
+	}
+
+	best := candidates[0]
+	logger.InfoContext(ctx, "Routing message by capability", logging.KeyMessageID, msg.ID, logging.KeyAgentID, best.AgentID, "capability", best.Capability, "score", best.Score)
+	msg.To = []multiagent.AgentID{best.AgentID}
+	if err := o.RouteMessage(ctx, msg); err != nil {
+		return "", err
+	}
+	return best.AgentID, nil
+}
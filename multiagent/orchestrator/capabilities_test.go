@@ -0,0 +1,71 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// capableAgent is a stubAgent with its own capability list
+type capableAgent struct {
+	stubAgent
+	capabilities []string
+}
+
+func (a *capableAgent) GetCapabilities() []string { return a.capabilities }
+
+func TestCapabilityRouting(t *testing.T) {
+	ctx := context.Background()
+	orch, _ := newTestOrchestrator(t)
+
+	scheduler := &capableAgent{
+		stubAgent:    stubAgent{id: "scheduler"},
+		capabilities: []string{"calendar_management", "appointment_scheduling", "meeting_coordination"},
+	}
+	tasks := &capableAgent{
+		stubAgent:    stubAgent{id: "tasks"},
+		capabilities: []string{"task_management", "reminder_system"},
+	}
+	for _, agent := range []multiagent.Agent{scheduler, tasks} {
+		if err := orch.RegisterAgent(agent); err != nil {
+			t.Fatalf("RegisterAgent: %v", err)
+		}
+	}
+
+	got, err := orch.RouteByCapability(ctx, &multiagent.Message{
+		ID:      "msg_meeting",
+		From:    "user",
+		Content: "Can you schedule a meeting with Sam on Friday?",
+	})
+	if err != nil || got != "scheduler" {
+		t.Fatalf("expected scheduler, got %q, %v", got, err)
+	}
+
+	ranked := orch.RankAgentsForTask(multiagent.Task{Type: "reminder", Description: "Set a reminder to file taxes"})
+	if len(ranked) == 0 || ranked[0].AgentID != "tasks" {
+		t.Fatalf("expected tasks agent first, got %+v", ranked)
+	}
+}
+
+func TestCapabilityScoreUsesSuccessRate(t *testing.T) {
+	orch, _ := newTestOrchestrator(t)
+	for _, id := range []multiagent.AgentID{"reliable", "flaky"} {
+		agent := &capableAgent{stubAgent: stubAgent{id: id}, capabilities: []string{"research_summary"}}
+		if err := orch.RegisterAgent(agent); err != nil {
+			t.Fatalf("RegisterAgent: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		orch.capabilities.RecordOutcome("flaky", false)
+		orch.capabilities.RecordOutcome("reliable", true)
+	}
+
+	ranked := orch.RankAgentsForTask(multiagent.Task{Type: "research_summary"})
+	if len(ranked) != 2 || ranked[0].AgentID != "reliable" {
+		t.Fatalf("expected reliable agent first, got %+v", ranked)
+	}
+	if ranked[0].Match != 1 {
+		t.Fatalf("expected exact capability match, got %v", ranked[0].Match)
+	}
+}
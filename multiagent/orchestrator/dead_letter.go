@@ -0,0 +1,203 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+const (
+	deadLetterKeyPrefix = "orchestrator:dead_letter:"
+	// maxListedDeadLetters bounds a single ListDeadLetters scan
+	maxListedDeadLetters = 10000
+)
+
+// errHandlerPanic wraps a panic recovered from an agent's HandleMessage
+var errHandlerPanic = errors.New("agent handler panicked")
+
+// DeadLetterReason says why a message could not be delivered
+type DeadLetterReason string
+
+const (
+	DeadLetterUnknownAgent DeadLetterReason = "unknown_agent"
+	DeadLetterHandlerPanic DeadLetterReason = "handler_panic"
+	DeadLetterHandlerError DeadLetterReason = "handler_error"
+	// DeadLetterLateReply is a reply to a request that had already finished
+	DeadLetterLateReply DeadLetterReason = "late_reply"
+)
+
+// DeadLetter is a message that could not be delivered to one of its recipients
+type DeadLetter struct {
+	ID         string              `json:"id"`
+	Message    *multiagent.Message `json:"message"`
+	Recipient  multiagent.AgentID  `json:"recipient"`
+	Reason     DeadLetterReason    `json:"reason"`
+	Error      string              `json:"error,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	ReplayedAt *time.Time          `json:"replayed_at,omitempty"`
+	Replays    int                 `json:"replays"`
+}
+
+// ListDeadLetters returns dead letters newest first; limit <= 0 returns all
+func (o *DefaultOrchestrator) ListDeadLetters(ctx context.Context, limit int) ([]*DeadLetter, error) {
+	if o.memoryStore == nil {
+		return nil, nil
+	}
+
+	keys, err := o.memoryStore.List(ctx, deadLetterKeyPrefix, maxListedDeadLetters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	letters := make([]*DeadLetter, 0, len(keys))
+	for _, key := range keys {
+		letter, err := o.getDeadLetter(ctx, strings.TrimPrefix(key, deadLetterKeyPrefix))
+		if err != nil {
+			continue
+		}
+		letters = append(letters, letter)
+	}
+
+	sort.Slice(letters, func(i, j int) bool { return letters[i].CreatedAt.After(letters[j].CreatedAt) })
+	if limit > 0 && len(letters) > limit {
+		letters = letters[:limit]
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetter re-sends a dead letter to its original recipient and
+// removes it once the message is accepted. It fails, keeping the letter,
+// if the recipient still cannot receive it.
+func (o *DefaultOrchestrator) ReplayDeadLetter(ctx context.Context, id string) error {
+	letter, err := o.getDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !o.canDeliver(letter.Recipient) {
+		now := o.now()
+		letter.ReplayedAt = &now
+		letter.Replays++
+		o.storeDeadLetter(ctx, letter)
+		return fmt.Errorf("recipient %s of dead letter %s is still unavailable", letter.Recipient, id)
+	}
+
+	msg := *letter.Message
+	msg.To = []multiagent.AgentID{letter.Recipient}
+	msg.Context = make(map[string]interface{}, len(letter.Message.Context)+1)
+	for k, v := range letter.Message.Context {
+		msg.Context[k] = v
+	}
+	msg.Context["replayed_from"] = letter.ID
+
+	if err := o.RouteMessage(ctx, &msg); err != nil {
+		return fmt.Errorf("failed to replay dead letter %s: %w", id, err)
+	}
+	if err := o.memoryStore.Delete(ctx, deadLetterKeyPrefix+id); err != nil {
+		return fmt.Errorf("failed to remove replayed dead letter %s: %w", id, err)
+	}
+
+	logger.InfoContext(ctx, "Replayed dead letter", "dead_letter_id", id, logging.KeyAgentID, letter.Recipient)
+	return nil
+}
+
+// PurgeDeadLetters deletes dead letters older than olderThan (all of them if
+// olderThan is zero) and returns how many were removed
+func (o *DefaultOrchestrator) PurgeDeadLetters(ctx context.Context, olderThan time.Duration) (int, error) {
+	letters, err := o.ListDeadLetters(ctx, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := o.now().Add(-olderThan)
+	purged := 0
+	for _, letter := range letters {
+		if olderThan > 0 && letter.CreatedAt.After(cutoff) {
+			continue
+		}
+		if err := o.memoryStore.Delete(ctx, deadLetterKeyPrefix+letter.ID); err != nil {
+			return purged, fmt.Errorf("failed to purge dead letter %s: %w", letter.ID, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// handleWithRecovery runs an agent's HandleMessage through the middleware,
+// turning a panic into an error
+func (o *DefaultOrchestrator) handleWithRecovery(ctx context.Context, agent multiagent.Agent, msg *multiagent.Message) (response *multiagent.Message, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			response = nil
+			err = fmt.Errorf("%w: %v", errHandlerPanic, r)
+		}
+	}()
+	return o.handler()(ctx, agent, msg)
+}
+
+// deadLetter records a message that could not be delivered to recipient
+func (o *DefaultOrchestrator) deadLetter(ctx context.Context, msg *multiagent.Message, recipient multiagent.AgentID, reason DeadLetterReason, cause string) {
+	logger.WarnContext(ctx, "Dead-lettering message", logging.KeyMessageID, msg.ID, logging.KeyAgentID, recipient, "reason", reason, "cause", cause)
+	if o.memoryStore == nil {
+		return
+	}
+
+	o.storeDeadLetter(ctx, &DeadLetter{
+		ID:        o.newID("dl"),
+		Message:   msg,
+		Recipient: recipient,
+		Reason:    reason,
+		Error:     cause,
+		CreatedAt: o.now(),
+	})
+}
+
+func (o *DefaultOrchestrator) storeDeadLetter(ctx context.Context, letter *DeadLetter) {
+	if err := o.memoryStore.Store(ctx, deadLetterKeyPrefix+letter.ID, letter); err != nil {
+		logger.ErrorContext(ctx, "Failed to store dead letter", "dead_letter_id", letter.ID, "error", err)
+	}
+}
+
+func (o *DefaultOrchestrator) getDeadLetter(ctx context.Context, id string) (*DeadLetter, error) {
+	if o.memoryStore == nil {
+		return nil, fmt.Errorf("dead letter %s not found", id)
+	}
+
+	value, err := o.memoryStore.Get(ctx, deadLetterKeyPrefix+id)
+	if err != nil {
+		return nil, fmt.Errorf("dead letter %s not found: %w", id, err)
+	}
+	if letter, ok := value.(*DeadLetter); ok {
+		return letter, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+	var letter DeadLetter
+	if err := json.Unmarshal(data, &letter); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+	}
+	return &letter, nil
+}
+
+// canDeliver reports whether recipient currently is an agent or a pending
+// request
+func (o *DefaultOrchestrator) canDeliver(recipient multiagent.AgentID) bool {
+	if request, ok := o.requests.get(recipient); ok {
+		return request.State() == RequestPending
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	_, exists := o.agents[recipient]
+	return exists
+}
@@ -0,0 +1,74 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+func waitForDeadLetters(t *testing.T, orch *DefaultOrchestrator, n int) []*DeadLetter {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		letters, err := orch.ListDeadLetters(context.Background(), 0)
+		if err != nil {
+			t.Fatalf("ListDeadLetters: %v", err)
+		}
+		if len(letters) >= n {
+			return letters
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d dead letters, got %d", n, len(letters))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDeadLetterReplayAndPurge(t *testing.T) {
+	ctx := context.Background()
+	orch, worker := newTestOrchestrator(t)
+	worker.handle = func(context.Context, *multiagent.Message) error {
+		panic("boom")
+	}
+
+	orch.RouteMessage(ctx, &multiagent.Message{From: "user", To: []multiagent.AgentID{"ghost"}, Content: "hello"})
+	letters := waitForDeadLetters(t, orch, 1)
+	if letters[0].Reason != DeadLetterUnknownAgent || letters[0].Recipient != "ghost" {
+		t.Fatalf("unexpected dead letter: %+v", letters[0])
+	}
+
+	if err := orch.ReplayDeadLetter(ctx, letters[0].ID); err == nil {
+		t.Fatal("expected replay to fail while the recipient is missing")
+	}
+	ghost := &stubAgent{id: "ghost"}
+	if err := orch.RegisterAgent(ghost); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	if err := orch.ReplayDeadLetter(ctx, letters[0].ID); err != nil {
+		t.Fatalf("ReplayDeadLetter: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(ghost.order()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("replayed message was not delivered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	orch.RouteMessage(ctx, &multiagent.Message{
+		From:    "user",
+		To:      []multiagent.AgentID{"worker"},
+		Context: map[string]interface{}{"task_id": "not_a_task"},
+	})
+	letters = waitForDeadLetters(t, orch, 1)
+	if len(letters) != 1 || letters[0].Reason != DeadLetterHandlerPanic {
+		t.Fatalf("expected only the panic dead letter, got %+v", letters)
+	}
+
+	purged, err := orch.PurgeDeadLetters(ctx, 0)
+	if err != nil || purged != 1 {
+		t.Fatalf("expected 1 purged, got %d, %v", purged, err)
+	}
+}
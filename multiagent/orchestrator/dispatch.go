@@ -0,0 +1,87 @@
+package orchestrator
+
+import (
+	"sync"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// defaultAgentConcurrency is how many queued messages an agent handles at
+// once unless configured otherwise
+const defaultAgentConcurrency = 4
+
+// workerSlots bounds how many queued messages each agent handles at once.
+// The router only takes a message off the queue once every agent it is for
+// has a free slot, so messages for busy agents wait in the queue, where
+// priority and fairness decide which runs next.
+type workerSlots struct {
+	limit int
+	freed chan struct{}
+
+	mu   sync.Mutex
+	busy map[multiagent.AgentID]int
+}
+
+func newWorkerSlots(limit int) *workerSlots {
+	if limit <= 0 {
+		limit = defaultAgentConcurrency
+	}
+	return &workerSlots{
+		limit: limit,
+		freed: make(chan struct{}, 1),
+		busy:  make(map[multiagent.AgentID]int),
+	}
+}
+
+// tryAcquire takes a slot for each of agentIDs if all of them have one free
+func (w *workerSlots) tryAcquire(agentIDs []multiagent.AgentID) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	wanted := make(map[multiagent.AgentID]int, len(agentIDs))
+	for _, id := range agentIDs {
+		wanted[id]++
+	}
+	for id, n := range wanted {
+		if w.busy[id]+n > w.limit {
+			return false
+		}
+	}
+	for id, n := range wanted {
+		w.busy[id] += n
+	}
+	return true
+}
+
+// release returns one of agentID's slots and wakes the router
+func (w *workerSlots) release(agentID multiagent.AgentID) {
+	w.mu.Lock()
+	if w.busy[agentID] <= 1 {
+		delete(w.busy, agentID)
+	} else {
+		w.busy[agentID]--
+	}
+	w.mu.Unlock()
+
+	select {
+	case w.freed <- struct{}{}:
+	default:
+	}
+}
+
+// Freed is signalled whenever a slot is released
+func (w *workerSlots) Freed() <-chan struct{} {
+	return w.freed
+}
+
+// slotRecipients returns the recipients of msg that take a worker slot:
+// every one but the orchestrator itself and callers' requests
+func (o *DefaultOrchestrator) slotRecipients(msg *multiagent.Message) []multiagent.AgentID {
+	var agentIDs []multiagent.AgentID
+	for _, id := range msg.To {
+		if id != "orchestrator" && !o.isRequest(id) {
+			agentIDs = append(agentIDs, id)
+		}
+	}
+	return agentIDs
+}
@@ -0,0 +1,131 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// heartbeatLoop pings every agent each HeartbeatInterval
+func (o *DefaultOrchestrator) heartbeatLoop(ctx context.Context) {
+	defer o.wg.Done()
+
+	ticker := time.NewTicker(o.supervisor.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			o.pingAgents(ctx)
+
+		case <-o.stopChan:
+			return
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pingAgents pings the agents concurrently and records the outcome. An
+// agent that misses MaxMissedHeartbeats in a row is marked unresponsive,
+// reported in an error state, and restarted by the supervisor.
+func (o *DefaultOrchestrator) pingAgents(ctx context.Context) {
+	o.mu.RLock()
+	agents := make([]multiagent.Agent, 0, len(o.agents))
+	for _, agent := range o.agents {
+		agents = append(agents, agent)
+	}
+	o.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, agent := range agents {
+		if o.restartPending(agent.ID()) {
+			continue
+		}
+		wg.Add(1)
+		go func(agent multiagent.Agent) {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, o.supervisor.config.HeartbeatTimeout)
+			defer cancel()
+			o.recordHeartbeat(agent.ID(), ping(pingCtx, agent))
+		}(agent)
+	}
+	wg.Wait()
+}
+
+// ping asks agent whether it is alive: with Ping if it implements
+// multiagent.Pinger, otherwise by reading its state, either of which must
+// answer before ctx ends
+func ping(ctx context.Context, agent multiagent.Agent) error {
+	if pinger, ok := agent.(multiagent.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	done := make(chan multiagent.AgentState, 1)
+	go func() { done <- agent.GetState() }()
+	select {
+	case state := <-done:
+		if state.Status == multiagent.AgentStatusError {
+			return fmt.Errorf("agent reports an error state")
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("agent did not report its state: %w", ctx.Err())
+	}
+}
+
+// recordHeartbeat updates an agent's heartbeat history with the outcome of
+// a ping
+func (o *DefaultOrchestrator) recordHeartbeat(agentID multiagent.AgentID, err error) {
+	o.supervisor.mu.Lock()
+	agent := o.supervisor.agents[agentID]
+	if agent == nil {
+		agent = &supervisedAgent{}
+		o.supervisor.agents[agentID] = agent
+	}
+	if err == nil {
+		agent.lastHeartbeat = o.now()
+		agent.missedHeartbeats = 0
+		agent.unresponsive = false
+		o.supervisor.mu.Unlock()
+		return
+	}
+
+	agent.missedHeartbeats++
+	missed := agent.missedHeartbeats
+	tripped := missed >= o.supervisor.config.MaxMissedHeartbeats && !agent.unresponsive
+	if tripped {
+		agent.unresponsive = true
+	}
+	o.supervisor.mu.Unlock()
+
+	logger.Warn("Agent missed a heartbeat", logging.KeyAgentID, agentID, "missed", missed, "error", err)
+	if tripped {
+		o.reportCrash(agentID, fmt.Sprintf("unresponsive after %d missed heartbeats: %v", missed, err))
+	}
+}
+
+// restartPending reports whether the supervisor is about to restart agentID
+func (o *DefaultOrchestrator) restartPending(agentID multiagent.AgentID) bool {
+	o.supervisor.mu.Lock()
+	defer o.supervisor.mu.Unlock()
+	agent := o.supervisor.agents[agentID]
+	return agent != nil && agent.pending
+}
+
+// heartbeatState returns when agentID last answered a ping and whether it
+// has stopped answering
+func (o *DefaultOrchestrator) heartbeatState(agentID multiagent.AgentID) (time.Time, bool) {
+	o.supervisor.mu.Lock()
+	defer o.supervisor.mu.Unlock()
+	agent := o.supervisor.agents[agentID]
+	if agent == nil {
+		return time.Time{}, false
+	}
+	return agent.lastHeartbeat, agent.unresponsive
+}
@@ -0,0 +1,98 @@
+package orchestrator
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+// hangingAgent stops answering pings while hung, and recovers on Start
+type hangingAgent struct {
+	stubAgent
+	mu     sync.Mutex
+	hung   bool
+	starts int
+}
+
+func (a *hangingAgent) Ping(ctx context.Context) error {
+	a.mu.Lock()
+	hung := a.hung
+	a.mu.Unlock()
+	if hung {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (a *hangingAgent) Start(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.hung = false
+	a.starts++
+	return nil
+}
+
+func (a *hangingAgent) hang() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.hung = true
+}
+
+func TestHeartbeatRestartsUnresponsiveAgent(t *testing.T) {
+	store, err := memory.NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	orch := NewOrchestrator(OrchestratorConfig{
+		MemoryStore: store,
+		Supervisor: SupervisorConfig{
+			CheckInterval:       5 * time.Millisecond,
+			InitialBackoff:      10 * time.Millisecond,
+			HeartbeatInterval:   -1,
+			HeartbeatTimeout:    10 * time.Millisecond,
+			MaxMissedHeartbeats: 2,
+		},
+	})
+	hanging := &hangingAgent{stubAgent: stubAgent{id: "hanging"}}
+	healthy := &stubAgent{id: "healthy"}
+	for _, agent := range []multiagent.Agent{hanging, healthy} {
+		if err := orch.RegisterAgent(agent); err != nil {
+			t.Fatalf("RegisterAgent: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	orch.pingAgents(ctx)
+	health := orch.GetSystemHealth()
+	if health.LastHeartbeats["hanging"].IsZero() || health.LastHeartbeats["healthy"].IsZero() {
+		t.Fatalf("expected heartbeats for both agents, got %v", health.LastHeartbeats)
+	}
+
+	hanging.hang()
+	orch.pingAgents(ctx)
+	if state := orch.GetSystemHealth().AgentHealth["hanging"]; state.Status == multiagent.AgentStatusError {
+		t.Fatal("agent marked in error after a single missed heartbeat")
+	}
+	orch.pingAgents(ctx)
+	if state := orch.GetSystemHealth().AgentHealth["hanging"]; state.Status != multiagent.AgentStatusError {
+		t.Fatalf("unresponsive agent reported %s, want error", state.Status)
+	}
+
+	// The supervisor restarts it, after which it answers again
+	if err := orch.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { orch.Stop(context.Background()) })
+	waitForRestarts(t, orch, "hanging", 1)
+	orch.pingAgents(ctx)
+	if state := orch.GetSystemHealth().AgentHealth["hanging"]; state.Status == multiagent.AgentStatusError {
+		t.Errorf("restarted agent still reported in error")
+	}
+}
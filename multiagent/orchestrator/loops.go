@@ -0,0 +1,277 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// ErrLoopDetected is returned, wrapped with the limit reached, when a
+// message would continue a runaway exchange between agents
+var ErrLoopDetected = errors.New("message loop detected")
+
+// Reasons a loop guard stops a message, in loop_detected events
+const (
+	LoopHopLimit          = "hop_limit"
+	LoopConversationLimit = "conversation_budget"
+	LoopCircuitOpen       = "circuit_open"
+)
+
+// LoopConfig bounds how far messages spread from one another. Zero values
+// take the defaults, and negative ones turn a limit off.
+type LoopConfig struct {
+	// MaxHops is how many messages a chain may pass through, counted from
+	// the message that started it, e.g. a user's (default 20)
+	MaxHops int
+	// ConversationBudget is how many messages a conversation may route
+	// within BudgetWindow (default 200 in 10 minutes)
+	ConversationBudget int
+	BudgetWindow       time.Duration
+	// BreakerThreshold is how many messages two agents may exchange within
+	// BreakerWindow before the circuit between them opens (default 20 in
+	// 10 seconds)
+	BreakerThreshold int
+	BreakerWindow    time.Duration
+	// BreakerCooldown is how long an open circuit refuses messages between
+	// the two agents (default 1 minute)
+	BreakerCooldown time.Duration
+}
+
+func (c LoopConfig) withDefaults() LoopConfig {
+	if c.MaxHops == 0 {
+		c.MaxHops = 20
+	}
+	if c.ConversationBudget == 0 {
+		c.ConversationBudget = 200
+	}
+	if c.BudgetWindow <= 0 {
+		c.BudgetWindow = 10 * time.Minute
+	}
+	if c.BreakerThreshold == 0 {
+		c.BreakerThreshold = 20
+	}
+	if c.BreakerWindow <= 0 {
+		c.BreakerWindow = 10 * time.Second
+	}
+	if c.BreakerCooldown <= 0 {
+		c.BreakerCooldown = time.Minute
+	}
+	return c
+}
+
+// hopsKey carries the hop count of the message being handled, so messages
+// an agent sends while handling it continue its chain
+type hopsKey struct{}
+
+func withHops(ctx context.Context, hops int) context.Context {
+	return context.WithValue(ctx, hopsKey{}, hops)
+}
+
+// messageHops returns the hop count stamped on msg; it arrives as a float64
+// on messages decoded from JSON
+func messageHops(msg *multiagent.Message) (int, bool) {
+	switch hops := msg.Context[multiagent.ContextHops].(type) {
+	case int:
+		return hops, true
+	case float64:
+		return int(hops), true
+	default:
+		return 0, false
+	}
+}
+
+// agentPair names two agents regardless of which one sent a message
+type agentPair [2]multiagent.AgentID
+
+func pairOf(a, b multiagent.AgentID) agentPair {
+	if b < a {
+		a, b = b, a
+	}
+	return agentPair{a, b}
+}
+
+// circuit tracks the messages two agents exchanged recently
+type circuit struct {
+	sent      []time.Time
+	openUntil time.Time
+}
+
+// loopGuard enforces a LoopConfig on the messages routed
+type loopGuard struct {
+	config LoopConfig
+
+	mu            sync.Mutex
+	conversations map[string][]time.Time
+	// overBudget marks conversations refused since they last sent a
+	// message, so each overrun is reported once
+	overBudget map[string]bool
+	circuits   map[agentPair]*circuit
+}
+
+func newLoopGuard(config LoopConfig) *loopGuard {
+	return &loopGuard{
+		config:        config.withDefaults(),
+		conversations: make(map[string][]time.Time),
+		overBudget:    make(map[string]bool),
+		circuits:      make(map[agentPair]*circuit),
+	}
+}
+
+// recent drops the times in sent before since
+func recent(sent []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(sent) && sent[i].Before(since) {
+		i++
+	}
+	return sent[i:]
+}
+
+// admitMessage stamps msg with its hop count and refuses it if it exceeds
+// the hop limit, its conversation's budget, or is sent across an open
+// circuit; tripping a limit emits a loop_detected event. Only admitted
+// messages count against the budget and circuits.
+func (o *DefaultOrchestrator) admitMessage(ctx context.Context, msg *multiagent.Message) error {
+	hops, ok := messageHops(msg)
+	if !ok {
+		if parent, ok := ctx.Value(hopsKey{}).(int); ok {
+			hops = parent + 1
+		}
+	}
+	if msg.Context == nil {
+		msg.Context = make(map[string]interface{})
+	}
+	msg.Context[multiagent.ContextHops] = hops
+
+	g := o.loops
+	if g.config.MaxHops > 0 && hops > g.config.MaxHops {
+		o.loopDetected(ctx, LoopHopLimit, msg, map[string]interface{}{"hops": hops})
+		return fmt.Errorf("%w: message passed through %d agents, more than %d", ErrLoopDetected, hops, g.config.MaxHops)
+	}
+
+	now := o.now()
+	conversationID, _ := msg.Context["conversation_id"].(string)
+	if conversationID == "" {
+		conversationID = logging.Field(ctx, logging.KeyConversationID)
+	}
+
+	g.mu.Lock()
+	var conversation []time.Time
+	if conversationID != "" && g.config.ConversationBudget > 0 {
+		conversation = recent(g.conversations[conversationID], now.Add(-g.config.BudgetWindow))
+		g.conversations[conversationID] = conversation
+		if len(conversation) >= g.config.ConversationBudget {
+			reported := g.overBudget[conversationID]
+			g.overBudget[conversationID] = true
+			g.mu.Unlock()
+			if !reported {
+				o.loopDetected(ctx, LoopConversationLimit, msg, map[string]interface{}{
+					"conversation_id": conversationID,
+					"messages":        len(conversation) + 1,
+					"window":          g.config.BudgetWindow.String(),
+				})
+			}
+			return fmt.Errorf("%w: conversation %s sent more than %d messages in %v", ErrLoopDetected, conversationID, g.config.ConversationBudget, g.config.BudgetWindow)
+		}
+	}
+
+	// Refuse the message if any of its circuits is open, or it would trip
+	// one, before charging any of them
+	var circuits []*circuit
+	var tripped []map[string]interface{}
+	var refused error
+	if g.config.BreakerThreshold > 0 && !o.isRequest(msg.From) {
+		for _, to := range msg.To {
+			if to == msg.From || o.isRequest(to) {
+				continue
+			}
+			pair := pairOf(msg.From, to)
+			c, ok := g.circuits[pair]
+			if !ok {
+				c = &circuit{}
+				g.circuits[pair] = c
+			}
+			if now.Before(c.openUntil) {
+				refused = fmt.Errorf("%w: circuit between %s and %s is open until %s", ErrLoopDetected, pair[0], pair[1], c.openUntil.Format(time.RFC3339))
+				continue
+			}
+			c.sent = recent(c.sent, now.Add(-g.config.BreakerWindow))
+			if len(c.sent) >= g.config.BreakerThreshold {
+				c.openUntil = now.Add(g.config.BreakerCooldown)
+				tripped = append(tripped, map[string]interface{}{
+					"agents":     []multiagent.AgentID{pair[0], pair[1]},
+					"messages":   len(c.sent) + 1,
+					"window":     g.config.BreakerWindow.String(),
+					"open_until": c.openUntil,
+				})
+				c.sent = nil
+				refused = fmt.Errorf("%w: %s and %s exchanged more than %d messages in %v", ErrLoopDetected, pair[0], pair[1], g.config.BreakerThreshold, g.config.BreakerWindow)
+				continue
+			}
+			circuits = append(circuits, c)
+		}
+	}
+	if refused == nil {
+		if conversationID != "" && g.config.ConversationBudget > 0 {
+			g.conversations[conversationID] = append(conversation, now)
+			delete(g.overBudget, conversationID)
+		}
+		for _, c := range circuits {
+			c.sent = append(c.sent, now)
+		}
+	}
+	g.sweep(now)
+	g.mu.Unlock()
+
+	for _, data := range tripped {
+		o.loopDetected(ctx, LoopCircuitOpen, msg, data)
+	}
+	return refused
+}
+
+// sweep forgets conversations and circuits idle for longer than their
+// windows, once enough have built up; g.mu must be held
+func (g *loopGuard) sweep(now time.Time) {
+	if len(g.conversations)+len(g.circuits) < 1000 {
+		return
+	}
+	for id, sent := range g.conversations {
+		if len(recent(sent, now.Add(-g.config.BudgetWindow))) == 0 {
+			delete(g.conversations, id)
+			delete(g.overBudget, id)
+		}
+	}
+	for pair, c := range g.circuits {
+		if !now.Before(c.openUntil) && len(recent(c.sent, now.Add(-g.config.BreakerWindow))) == 0 {
+			delete(g.circuits, pair)
+		}
+	}
+}
+
+// loopDetected logs a tripped limit and emits a loop_detected event
+// describing it
+func (o *DefaultOrchestrator) loopDetected(ctx context.Context, reason string, msg *multiagent.Message, data map[string]interface{}) {
+	logger.WarnContext(logging.WithMessage(ctx, msg), "Message loop detected", "reason", reason, "from", msg.From, "to", msg.To, "details", data)
+	o.metrics.loopDetected(reason)
+
+	data["reason"] = reason
+	data["message_id"] = msg.ID
+	data["from"] = msg.From
+	data["to"] = msg.To
+	event := &multiagent.Event{
+		ID:        o.newID("event"),
+		Type:      multiagent.EventLoopDetected,
+		Source:    "orchestrator",
+		Timestamp: o.now(),
+		Data:      data,
+	}
+	select {
+	case o.eventQueue <- event:
+	default:
+		logger.Warn("Event queue full, dropping event", "event_type", event.Type)
+	}
+}
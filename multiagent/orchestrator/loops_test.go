@@ -0,0 +1,213 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/ids"
+)
+
+// loopEvent returns the next loop_detected event queued, if any
+func loopEvent(o *DefaultOrchestrator) (*multiagent.Event, bool) {
+	for {
+		select {
+		case event := <-o.eventQueue:
+			if event.Type == multiagent.EventLoopDetected {
+				return event, true
+			}
+		default:
+			return nil, false
+		}
+	}
+}
+
+func TestLoopGuardHopLimit(t *testing.T) {
+	orch := NewOrchestrator(OrchestratorConfig{Loops: LoopConfig{MaxHops: 3, BreakerThreshold: -1}})
+
+	msg := &multiagent.Message{From: "a", To: []multiagent.AgentID{"b"}}
+	if err := orch.admitMessage(withHops(context.Background(), 2), msg); err != nil {
+		t.Fatalf("admitMessage at hop 3: %v", err)
+	}
+	if hops, _ := messageHops(msg); hops != 3 {
+		t.Errorf("message stamped with %d hops, want 3", hops)
+	}
+
+	// Hops decoded from JSON continue the chain
+	next := &multiagent.Message{From: "b", To: []multiagent.AgentID{"a"}, Context: map[string]interface{}{multiagent.ContextHops: float64(4)}}
+	if err := orch.admitMessage(context.Background(), next); !errors.Is(err, ErrLoopDetected) {
+		t.Fatalf("admitMessage past the hop limit returned %v, want ErrLoopDetected", err)
+	}
+	event, ok := loopEvent(orch)
+	if !ok || event.Data["reason"] != LoopHopLimit {
+		t.Errorf("expected a hop_limit event, got %+v", event)
+	}
+}
+
+func TestLoopGuardCircuitBreaker(t *testing.T) {
+	clock := ids.NewManualClock(time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC))
+	orch := NewOrchestrator(OrchestratorConfig{Clock: clock, Loops: LoopConfig{
+		BreakerThreshold: 3,
+		BreakerWindow:    10 * time.Second,
+		BreakerCooldown:  time.Minute,
+	}})
+	send := func(from, to multiagent.AgentID) error {
+		return orch.admitMessage(context.Background(), &multiagent.Message{From: from, To: []multiagent.AgentID{to}})
+	}
+
+	for i, pair := range [][2]multiagent.AgentID{{"a", "b"}, {"b", "a"}, {"a", "b"}} {
+		if err := send(pair[0], pair[1]); err != nil {
+			t.Fatalf("message %d: %v", i+1, err)
+		}
+		clock.Advance(time.Second)
+	}
+	if err := send("b", "a"); !errors.Is(err, ErrLoopDetected) {
+		t.Fatalf("fourth message in the window returned %v, want ErrLoopDetected", err)
+	}
+	if event, ok := loopEvent(orch); !ok || event.Data["reason"] != LoopCircuitOpen {
+		t.Errorf("expected a circuit_open event, got %+v", event)
+	}
+
+	// Other pairs are unaffected, and the circuit closes after the cooldown
+	if err := send("a", "c"); err != nil {
+		t.Errorf("message to another agent: %v", err)
+	}
+	clock.Advance(30 * time.Second)
+	if err := send("a", "b"); !errors.Is(err, ErrLoopDetected) {
+		t.Errorf("message across an open circuit returned %v", err)
+	}
+	clock.Advance(31 * time.Second)
+	if err := send("a", "b"); err != nil {
+		t.Errorf("message after the cooldown: %v", err)
+	}
+}
+
+func TestLoopGuardConversationBudget(t *testing.T) {
+	clock := ids.NewManualClock(time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC))
+	orch := NewOrchestrator(OrchestratorConfig{Clock: clock, Loops: LoopConfig{
+		ConversationBudget: 2,
+		BudgetWindow:       time.Minute,
+		BreakerThreshold:   -1,
+	}})
+	send := func() error {
+		return orch.admitMessage(context.Background(), &multiagent.Message{
+			From:    "a",
+			To:      []multiagent.AgentID{"b"},
+			Context: map[string]interface{}{"conversation_id": "conv_alice"},
+		})
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := send(); err != nil {
+			t.Fatalf("message %d: %v", i+1, err)
+		}
+	}
+	if err := send(); !errors.Is(err, ErrLoopDetected) {
+		t.Fatalf("message over budget returned %v, want ErrLoopDetected", err)
+	}
+	if event, ok := loopEvent(orch); !ok || event.Data["conversation_id"] != "conv_alice" {
+		t.Errorf("expected a conversation_budget event, got %+v", event)
+	}
+	clock.Advance(2 * time.Minute)
+	if err := send(); err != nil {
+		t.Errorf("message once the window passed: %v", err)
+	}
+}
+
+func TestLoopGuardCountsOnlyAdmittedMessages(t *testing.T) {
+	clock := ids.NewManualClock(time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC))
+	orch := NewOrchestrator(OrchestratorConfig{Clock: clock, Loops: LoopConfig{
+		ConversationBudget: 2,
+		BudgetWindow:       time.Minute,
+		BreakerThreshold:   2,
+		BreakerWindow:      10 * time.Second,
+		BreakerCooldown:    time.Minute,
+	}})
+	send := func(conversationID string, from multiagent.AgentID, to ...multiagent.AgentID) error {
+		return orch.admitMessage(context.Background(), &multiagent.Message{
+			From:    from,
+			To:      to,
+			Context: map[string]interface{}{"conversation_id": conversationID},
+		})
+	}
+
+	// Refused messages neither extend a conversation's overrun nor repeat
+	// its event
+	for i := 0; i < 2; i++ {
+		if err := send("conv_alice", "user", "a"); err != nil {
+			t.Fatalf("message %d: %v", i+1, err)
+		}
+	}
+	clock.Advance(30 * time.Second)
+	for i := 0; i < 3; i++ {
+		if err := send("conv_alice", "user", "a"); !errors.Is(err, ErrLoopDetected) {
+			t.Fatalf("message over budget returned %v, want ErrLoopDetected", err)
+		}
+	}
+	if _, ok := loopEvent(orch); !ok {
+		t.Error("expected a conversation_budget event")
+	}
+	if event, ok := loopEvent(orch); ok {
+		t.Errorf("conversation overrun reported again: %+v", event)
+	}
+	clock.Advance(31 * time.Second)
+	if err := send("conv_alice", "user", "a"); err != nil {
+		t.Fatalf("message once the admitted ones left the window: %v", err)
+	}
+
+	// A message one open circuit refuses is not charged to its other pairs
+	for i := 0; i < 3; i++ {
+		send("", "a", "b")
+	}
+	if event, ok := loopEvent(orch); !ok || event.Data["reason"] != LoopCircuitOpen {
+		t.Fatalf("expected a circuit_open event, got %+v", event)
+	}
+	for i := 0; i < 3; i++ {
+		if err := send("", "a", "b", "c"); !errors.Is(err, ErrLoopDetected) {
+			t.Fatalf("message across an open circuit returned %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := send("", "a", "c"); err != nil {
+			t.Fatalf("message %d between a and c: %v", i+1, err)
+		}
+	}
+}
+
+func TestLoopGuardStopsPingPong(t *testing.T) {
+	ctx := context.Background()
+	orch, _ := newTestOrchestrator(t)
+	orch.loops = newLoopGuard(LoopConfig{MaxHops: 6, BreakerThreshold: -1})
+
+	// Each agent answers every message with a new request to the other
+	pingPong := func(to multiagent.AgentID) func(ctx context.Context, msg *multiagent.Message) error {
+		return func(ctx context.Context, msg *multiagent.Message) error {
+			return orch.RouteMessage(ctx, &multiagent.Message{From: msg.To[0], To: []multiagent.AgentID{to}, Type: multiagent.MessageTypeRequest, Content: "over to you"})
+		}
+	}
+	ping := &stubAgent{id: "ping", handle: pingPong("pong")}
+	pong := &stubAgent{id: "pong", handle: pingPong("ping")}
+	for _, agent := range []*stubAgent{ping, pong} {
+		if err := orch.RegisterAgent(agent); err != nil {
+			t.Fatalf("RegisterAgent: %v", err)
+		}
+	}
+
+	if err := orch.RouteMessage(ctx, &multiagent.Message{From: "worker", To: []multiagent.AgentID{"ping"}, Type: multiagent.MessageTypeRequest}); err != nil {
+		t.Fatalf("RouteMessage: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(ping.order())+len(pong.order()) < 7 {
+		if time.Now().After(deadline) {
+			t.Fatalf("chain stopped early: ping %v, pong %v", ping.order(), pong.order())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if handled := len(ping.order()) + len(pong.order()); handled != 7 {
+		t.Errorf("agents handled %d messages, want 7 (hops 0 to 6)", handled)
+	}
+}
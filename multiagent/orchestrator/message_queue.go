@@ -0,0 +1,228 @@
+package orchestrator
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+var (
+	// ErrQueueFull is returned when the message queue is at capacity
+	ErrQueueFull = errors.New("message queue full")
+	// ErrMessageShed is returned when a low-priority message is dropped under load
+	ErrMessageShed = errors.New("message shed under load")
+)
+
+// OverloadPolicy decides what happens to low-priority messages above the high watermark
+type OverloadPolicy string
+
+const (
+	// OverloadShed rejects low-priority messages with ErrMessageShed
+	OverloadShed OverloadPolicy = "shed"
+	// OverloadDefer parks low-priority messages until the queue drains below half the watermark
+	OverloadDefer OverloadPolicy = "defer"
+)
+
+const priorityLevels = int(multiagent.PriorityCritical) + 1
+
+// MessageQueueConfig configures the orchestrator's priority message queue
+type MessageQueueConfig struct {
+	Capacity int
+	// HighWatermark is the depth at which low-priority traffic is shed or
+	// deferred (default 80% of Capacity)
+	HighWatermark int
+	Policy        OverloadPolicy
+	// LowPriorityBelow marks priorities below it as low priority (default PriorityMedium)
+	LowPriorityBelow multiagent.Priority
+}
+
+// priorityQueue serves messages highest priority first and, within a
+// priority, round-robins between senders so one chatty agent cannot starve
+// the others
+type priorityQueue struct {
+	config MessageQueueConfig
+
+	mu       sync.Mutex
+	levels   [priorityLevels]*fairLevel
+	size     int
+	deferred []*multiagent.Message
+	shed     int64
+	ready    chan struct{}
+}
+
+// fairLevel holds the per-sender queues of a single priority
+type fairLevel struct {
+	senders []multiagent.AgentID
+	queues  map[multiagent.AgentID][]*multiagent.Message
+}
+
+func newPriorityQueue(config MessageQueueConfig) *priorityQueue {
+	if config.Capacity <= 0 {
+		config.Capacity = 1000
+	}
+	if config.HighWatermark <= 0 || config.HighWatermark > config.Capacity {
+		config.HighWatermark = config.Capacity * 8 / 10
+	}
+	if config.Policy == "" {
+		config.Policy = OverloadShed
+	}
+	if config.LowPriorityBelow == 0 {
+		config.LowPriorityBelow = multiagent.PriorityMedium
+	}
+
+	q := &priorityQueue{
+		config: config,
+		ready:  make(chan struct{}, 1),
+	}
+	for i := range q.levels {
+		q.levels[i] = &fairLevel{queues: make(map[multiagent.AgentID][]*multiagent.Message)}
+	}
+	return q
+}
+
+// Push enqueues a message, applying the overload policy to low-priority
+// messages once the queue is above its high watermark
+func (q *priorityQueue) Push(msg *multiagent.Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.size >= q.config.Capacity {
+		return ErrQueueFull
+	}
+
+	if q.size >= q.config.HighWatermark && msg.Priority < q.config.LowPriorityBelow {
+		if q.config.Policy == OverloadDefer && len(q.deferred) < q.config.Capacity {
+			q.deferred = append(q.deferred, msg)
+			return nil
+		}
+		q.shed++
+		return ErrMessageShed
+	}
+
+	q.push(msg)
+	return nil
+}
+
+// Pop returns the next message, or nil if the queue is empty
+func (q *priorityQueue) Pop() *multiagent.Message {
+	return q.PopIf(func(*multiagent.Message) bool { return true })
+}
+
+// PopIf returns the next message ready accepts, or nil if it accepts none.
+// Each sender's messages are considered in order, so one that ready turns
+// down holds back the later ones from the same sender.
+func (q *priorityQueue) PopIf(ready func(*multiagent.Message) bool) *multiagent.Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := len(q.levels) - 1; i >= 0; i-- {
+		if msg := q.levels[i].popIf(ready); msg != nil {
+			q.size--
+			q.readmitDeferred()
+			return msg
+		}
+	}
+	return nil
+}
+
+// Ready is signalled whenever a message is enqueued
+func (q *priorityQueue) Ready() <-chan struct{} {
+	return q.ready
+}
+
+// Stats reports queue depth and overload counters
+func (q *priorityQueue) Stats() multiagent.QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := multiagent.QueueStats{
+		Depth:         q.size,
+		Deferred:      len(q.deferred),
+		Shed:          q.shed,
+		HighWatermark: q.config.HighWatermark,
+		ByPriority:    make(map[multiagent.Priority]int),
+	}
+	for i, level := range q.levels {
+		if n := level.len(); n > 0 {
+			stats.ByPriority[multiagent.Priority(i)] = n
+		}
+	}
+	return stats
+}
+
+// Len returns the number of queued messages, excluding deferred ones
+func (q *priorityQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+func (q *priorityQueue) push(msg *multiagent.Message) {
+	level := int(msg.Priority)
+	if level < 0 {
+		level = 0
+	}
+	if level >= priorityLevels {
+		level = priorityLevels - 1
+	}
+	q.levels[level].push(msg)
+	q.size++
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// readmitDeferred moves deferred messages back once the queue has drained
+// well below the high watermark
+func (q *priorityQueue) readmitDeferred() {
+	if len(q.deferred) == 0 || q.size > q.config.HighWatermark/2 {
+		return
+	}
+	for len(q.deferred) > 0 && q.size < q.config.HighWatermark {
+		msg := q.deferred[0]
+		q.deferred[0] = nil
+		q.deferred = q.deferred[1:]
+		q.push(msg)
+	}
+}
+
+func (l *fairLevel) push(msg *multiagent.Message) {
+	if _, exists := l.queues[msg.From]; !exists {
+		l.senders = append(l.senders, msg.From)
+	}
+	l.queues[msg.From] = append(l.queues[msg.From], msg)
+}
+
+// popIf takes the first sender's next message that ready accepts, moving
+// the sender to the back of the round-robin
+func (l *fairLevel) popIf(ready func(*multiagent.Message) bool) *multiagent.Message {
+	for i, sender := range l.senders {
+		pending := l.queues[sender]
+		msg := pending[0]
+		if !ready(msg) {
+			continue
+		}
+		pending[0] = nil
+
+		l.senders = append(l.senders[:i:i], l.senders[i+1:]...)
+		if len(pending) > 1 {
+			l.queues[sender] = pending[1:]
+			l.senders = append(l.senders, sender)
+		} else {
+			delete(l.queues, sender)
+		}
+		return msg
+	}
+	return nil
+}
+
+func (l *fairLevel) len() int {
+	n := 0
+	for _, pending := range l.queues {
+		n += len(pending)
+	}
+	return n
+}
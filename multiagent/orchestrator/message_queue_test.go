@@ -0,0 +1,150 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+func queueMessage(from string, priority multiagent.Priority, n int) *multiagent.Message {
+	return &multiagent.Message{
+		ID:       fmt.Sprintf("%s_%d", from, n),
+		From:     multiagent.AgentID(from),
+		Priority: priority,
+	}
+}
+
+func TestPriorityQueueOrdersByPriorityThenSender(t *testing.T) {
+	q := newPriorityQueue(MessageQueueConfig{Capacity: 100})
+
+	for i := 0; i < 3; i++ {
+		q.Push(queueMessage("chatty", multiagent.PriorityMedium, i))
+	}
+	q.Push(queueMessage("quiet", multiagent.PriorityMedium, 0))
+	q.Push(queueMessage("urgent", multiagent.PriorityCritical, 0))
+
+	var got []string
+	for msg := q.Pop(); msg != nil; msg = q.Pop() {
+		got = append(got, msg.ID)
+	}
+	want := []string{"urgent_0", "chatty_0", "quiet_0", "chatty_1", "chatty_2"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestPriorityQueueOverloadPolicies(t *testing.T) {
+	shed := newPriorityQueue(MessageQueueConfig{Capacity: 4, HighWatermark: 2})
+	shed.Push(queueMessage("a", multiagent.PriorityMedium, 0))
+	shed.Push(queueMessage("a", multiagent.PriorityMedium, 1))
+	if err := shed.Push(queueMessage("a", multiagent.PriorityLow, 2)); !errors.Is(err, ErrMessageShed) {
+		t.Fatalf("expected low-priority message to be shed, got %v", err)
+	}
+	if err := shed.Push(queueMessage("a", multiagent.PriorityHigh, 3)); err != nil {
+		t.Fatalf("expected high-priority message above watermark to be accepted, got %v", err)
+	}
+
+	deferring := newPriorityQueue(MessageQueueConfig{Capacity: 4, HighWatermark: 2, Policy: OverloadDefer})
+	deferring.Push(queueMessage("a", multiagent.PriorityMedium, 0))
+	deferring.Push(queueMessage("a", multiagent.PriorityMedium, 1))
+	if err := deferring.Push(queueMessage("a", multiagent.PriorityLow, 2)); err != nil {
+		t.Fatalf("expected low-priority message to be deferred, got %v", err)
+	}
+	if stats := deferring.Stats(); stats.Deferred != 1 || stats.Depth != 2 {
+		t.Fatalf("expected 1 deferred and depth 2, got %+v", stats)
+	}
+
+	var got []string
+	for msg := deferring.Pop(); msg != nil; msg = deferring.Pop() {
+		got = append(got, msg.ID)
+	}
+	if len(got) != 3 || got[2] != "a_2" {
+		t.Fatalf("expected deferred message to be delivered last, got %v", got)
+	}
+}
+
+func TestCriticalMessageOvertakesBacklog(t *testing.T) {
+	ctx := context.Background()
+	orch := NewOrchestrator(OrchestratorConfig{AgentConcurrency: 1, MessageQueueSize: 20})
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	agent := &stubAgent{id: "worker", handle: func(ctx context.Context, msg *multiagent.Message) error {
+		if msg.ID == "blocker" {
+			started <- struct{}{}
+			<-release
+		}
+		return nil
+	}}
+	if err := orch.RegisterAgent(agent); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	if err := orch.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer orch.Stop(ctx)
+
+	send := func(id string, priority multiagent.Priority) {
+		t.Helper()
+		msg := &multiagent.Message{ID: id, From: "user", To: []multiagent.AgentID{"worker"}, Type: multiagent.MessageTypeRequest, Priority: priority}
+		if err := orch.RouteMessage(ctx, msg); err != nil {
+			t.Fatalf("RouteMessage %s: %v", id, err)
+		}
+	}
+	send("blocker", multiagent.PriorityMedium)
+	<-started
+	for i := 0; i < 5; i++ {
+		send(fmt.Sprintf("low_%d", i), multiagent.PriorityLow)
+	}
+	send("critical", multiagent.PriorityCritical)
+
+	// The busy agent leaves its backlog queued
+	if stats := orch.GetSystemHealth().Queue; stats.Depth != 6 || stats.ByPriority[multiagent.PriorityLow] != 5 {
+		t.Fatalf("queue %+v, want the 6 messages waiting", stats)
+	}
+
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for len(agent.order()) < 7 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	want := []string{"blocker", "critical", "low_0", "low_1", "low_2", "low_3", "low_4"}
+	if got := agent.order(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("handled %v, want %v", got, want)
+	}
+}
+
+func TestBacklogShedsLowPriorityMessages(t *testing.T) {
+	ctx := context.Background()
+	orch := NewOrchestrator(OrchestratorConfig{AgentConcurrency: 1, MessageQueueSize: 4, QueueHighWatermark: 2})
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	agent := &stubAgent{id: "worker", handle: func(ctx context.Context, msg *multiagent.Message) error {
+		if msg.ID == "blocker" {
+			started <- struct{}{}
+			<-release
+		}
+		return nil
+	}}
+	if err := orch.RegisterAgent(agent); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	if err := orch.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	route := func(id string, priority multiagent.Priority) error {
+		return orch.RouteMessage(ctx, &multiagent.Message{ID: id, From: "user", To: []multiagent.AgentID{"worker"}, Priority: priority})
+	}
+	route("blocker", multiagent.PriorityMedium)
+	<-started
+	route("medium_0", multiagent.PriorityMedium)
+	route("medium_1", multiagent.PriorityMedium)
+	if err := route("low", multiagent.PriorityLow); !errors.Is(err, ErrMessageShed) {
+		t.Fatalf("RouteMessage above the watermark = %v, want ErrMessageShed", err)
+	}
+}
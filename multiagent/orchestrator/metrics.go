@@ -0,0 +1,119 @@
+package orchestrator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/metrics"
+)
+
+// orchestratorMetrics instruments routing; a nil value records nothing
+type orchestratorMetrics struct {
+	messagesRouted    *metrics.Counter
+	handlingSeconds   *metrics.Histogram
+	handlingErrors    *metrics.Counter
+	orphanedResponses *metrics.Counter
+	loopsDetected     *metrics.Counter
+	queueDepth        *metrics.Gauge
+	queueDeferred     *metrics.Gauge
+	queueShed         *metrics.Gauge
+	tasks             *metrics.Gauge
+}
+
+func newOrchestratorMetrics(registry *metrics.Registry, o *DefaultOrchestrator) *orchestratorMetrics {
+	if registry == nil {
+		return nil
+	}
+
+	m := &orchestratorMetrics{
+		messagesRouted: registry.NewCounter("multiagent_messages_routed_total",
+			"Messages accepted for routing by the orchestrator", "type"),
+		handlingSeconds: registry.NewHistogram("multiagent_agent_handling_seconds",
+			"Time agents spend handling a message", nil, "agent"),
+		handlingErrors: registry.NewCounter("multiagent_agent_handling_errors_total",
+			"Messages whose handler returned an error or panicked", "agent"),
+		orphanedResponses: registry.NewCounter("multiagent_orphaned_responses_total",
+			"Replies that arrived after their request had finished"),
+		loopsDetected: registry.NewCounter("multiagent_loops_detected_total",
+			"Loop limits tripped, by limit", "reason"),
+		queueDepth: registry.NewGauge("multiagent_queue_depth",
+			"Messages waiting in the orchestrator queue by priority", "priority"),
+		queueDeferred: registry.NewGauge("multiagent_queue_deferred",
+			"Low-priority messages deferred while the queue is overloaded"),
+		queueShed: registry.NewGauge("multiagent_queue_shed",
+			"Low-priority messages dropped since start while the queue was overloaded"),
+		tasks: registry.NewGauge("multiagent_tasks",
+			"Tracked tasks by status", "status"),
+	}
+	registry.OnScrape(func() { m.sample(o) })
+	return m
+}
+
+// sample refreshes the gauges that mirror orchestrator state
+func (m *orchestratorMetrics) sample(o *DefaultOrchestrator) {
+	stats := o.messageQueue.Stats()
+	m.queueDepth.Reset()
+	for priority, depth := range stats.ByPriority {
+		m.queueDepth.Set(float64(depth), priorityLabel(priority))
+	}
+	m.queueDeferred.Set(float64(stats.Deferred))
+	m.queueShed.Set(float64(stats.Shed))
+
+	counts := make(map[multiagent.TaskStatus]int)
+	o.mu.RLock()
+	for _, task := range o.tasks {
+		counts[task.Status]++
+	}
+	o.mu.RUnlock()
+
+	m.tasks.Reset()
+	for status, count := range counts {
+		m.tasks.Set(float64(count), string(status))
+	}
+}
+
+func (m *orchestratorMetrics) loopDetected(reason string) {
+	if m == nil {
+		return
+	}
+	m.loopsDetected.Inc(reason)
+}
+
+func (m *orchestratorMetrics) messageRouted(msg *multiagent.Message) {
+	if m == nil {
+		return
+	}
+	m.messagesRouted.Inc(string(msg.Type))
+}
+
+func (m *orchestratorMetrics) messageHandled(agentID multiagent.AgentID, elapsed time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.handlingSeconds.ObserveDuration(elapsed, string(agentID))
+	if err != nil {
+		m.handlingErrors.Inc(string(agentID))
+	}
+}
+
+func (m *orchestratorMetrics) orphanedResponse() {
+	if m == nil {
+		return
+	}
+	m.orphanedResponses.Inc()
+}
+
+func priorityLabel(priority multiagent.Priority) string {
+	switch priority {
+	case multiagent.PriorityLow:
+		return "low"
+	case multiagent.PriorityMedium:
+		return "medium"
+	case multiagent.PriorityHigh:
+		return "high"
+	case multiagent.PriorityCritical:
+		return "critical"
+	}
+	return fmt.Sprintf("%d", priority)
+}
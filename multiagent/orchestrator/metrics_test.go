@@ -0,0 +1,72 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/metrics"
+)
+
+func scrape(t *testing.T, registry *metrics.Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if _, err := registry.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	return b.String()
+}
+
+func TestOrchestratorExportsRoutingMetrics(t *testing.T) {
+	ctx := context.Background()
+	store, err := memory.NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	registry := metrics.NewRegistry()
+	orch := NewOrchestrator(OrchestratorConfig{MemoryStore: store, Metrics: registry})
+	agent := &stubAgent{id: "worker", handle: func(ctx context.Context, msg *multiagent.Message) error {
+		return errors.New("calendar unavailable")
+	}}
+	if err := orch.RegisterAgent(agent); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	orch.tasks["task_1"] = &multiagent.Task{ID: "task_1", Status: multiagent.TaskStatusPending}
+
+	err = orch.RouteMessage(ctx, &multiagent.Message{
+		From:    "user",
+		To:      []multiagent.AgentID{"worker"},
+		Type:    multiagent.MessageTypeNotification,
+		Content: "sync calendar",
+	})
+	if err != nil {
+		t.Fatalf("RouteMessage: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	var body string
+	for time.Now().Before(deadline) {
+		body = scrape(t, registry)
+		if strings.Contains(body, `multiagent_agent_handling_errors_total{agent="worker"} 1`) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	for _, line := range []string{
+		`multiagent_messages_routed_total{type="notification"} 1`,
+		`multiagent_agent_handling_seconds_count{agent="worker"} 1`,
+		`multiagent_agent_handling_errors_total{agent="worker"} 1`,
+		`multiagent_tasks{status="pending"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// MessageHandler handles a message delivered to agent
+type MessageHandler func(ctx context.Context, agent multiagent.Agent, msg *multiagent.Message) (*multiagent.Message, error)
+
+// MessageMiddleware wraps the delivery of messages to agents, e.g. to log,
+// filter or rewrite them; it calls next to pass a message on
+type MessageMiddleware func(next MessageHandler) MessageHandler
+
+// Use adds middleware around every message delivered to an agent. The
+// first middleware added sees a message first.
+func (o *DefaultOrchestrator) Use(middleware ...MessageMiddleware) {
+	o.middlewareMu.Lock()
+	defer o.middlewareMu.Unlock()
+	o.middleware = append(o.middleware, middleware...)
+}
+
+// handler returns agent.HandleMessage wrapped in the middleware
+func (o *DefaultOrchestrator) handler() MessageHandler {
+	o.middlewareMu.RLock()
+	defer o.middlewareMu.RUnlock()
+
+	handler := func(ctx context.Context, agent multiagent.Agent, msg *multiagent.Message) (*multiagent.Message, error) {
+		return agent.HandleMessage(ctx, msg)
+	}
+	for i := len(o.middleware) - 1; i >= 0; i-- {
+		handler = o.middleware[i](handler)
+	}
+	return handler
+}
+
+// ErrMessageBlocked is returned, wrapped with the reason, when route
+// middleware refuses a message
+var ErrMessageBlocked = errors.New("message blocked by policy")
+
+// RouteHandler passes a message on to be stored and queued for its
+// recipients
+type RouteHandler func(ctx context.Context, msg *multiagent.Message) error
+
+// RouteMiddleware wraps RouteMessage: it runs on every message routed, once
+// it has an ID, timestamp and user, before it is stored and queued. It may log the
+// message, pass a rewritten copy to next, drop it by returning nil, or
+// refuse it with an error wrapping ErrMessageBlocked.
+type RouteMiddleware func(next RouteHandler) RouteHandler
+
+// UseRouting adds middleware to the routing of every message, after that
+// in OrchestratorConfig.RouteMiddleware. The first middleware added sees a
+// message first.
+func (o *DefaultOrchestrator) UseRouting(middleware ...RouteMiddleware) {
+	o.middlewareMu.Lock()
+	defer o.middlewareMu.Unlock()
+	o.routeMiddleware = append(o.routeMiddleware, middleware...)
+}
+
+// router returns routeMessage wrapped in the route middleware
+func (o *DefaultOrchestrator) router() RouteHandler {
+	o.middlewareMu.RLock()
+	defer o.middlewareMu.RUnlock()
+
+	router := RouteHandler(o.routeMessage)
+	for i := len(o.routeMiddleware) - 1; i >= 0; i-- {
+		router = o.routeMiddleware[i](router)
+	}
+	return router
+}
+
+// PublishCapabilities replaces a registered agent's capabilities with
+// descriptors, for routing and for agents watching agent_capabilities events
+func (o *DefaultOrchestrator) PublishCapabilities(agentID multiagent.AgentID, descriptors []multiagent.CapabilityDescriptor) error {
+	o.mu.RLock()
+	_, registered := o.agents[agentID]
+	o.mu.RUnlock()
+	if !registered {
+		return fmt.Errorf("agent %s not found", agentID)
+	}
+
+	o.capabilities.Publish(agentID, descriptors)
+	names := make([]string, len(descriptors))
+	for i, descriptor := range descriptors {
+		names[i] = descriptor.Name
+	}
+	logger.Info("Published agent capabilities", logging.KeyAgentID, agentID, "capabilities", names)
+	o.emitAgentEvent(multiagent.EventAgentCapabilities, agentID, map[string]interface{}{
+		"capabilities": descriptors,
+	})
+	return nil
+}
@@ -0,0 +1,168 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+func TestMiddlewareWrapsDelivery(t *testing.T) {
+	ctx := context.Background()
+	orch, _ := newTestOrchestrator(t)
+	listener := &stubAgent{id: "listener"}
+	if err := orch.RegisterAgent(listener); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	record := func(name string) MessageMiddleware {
+		return func(next MessageHandler) MessageHandler {
+			return func(ctx context.Context, agent multiagent.Agent, msg *multiagent.Message) (*multiagent.Message, error) {
+				mu.Lock()
+				calls = append(calls, name+":"+msg.ID)
+				mu.Unlock()
+				return next(ctx, agent, msg)
+			}
+		}
+	}
+	orch.Use(record("outer"), record("inner"))
+	// Messages to the listener marked private never reach it
+	orch.Use(func(next MessageHandler) MessageHandler {
+		return func(ctx context.Context, agent multiagent.Agent, msg *multiagent.Message) (*multiagent.Message, error) {
+			if agent.ID() == "listener" && msg.Content == "private" {
+				return nil, nil
+			}
+			return next(ctx, agent, msg)
+		}
+	})
+
+	for _, msg := range []*multiagent.Message{
+		{ID: "msg_private", From: "worker", To: []multiagent.AgentID{"listener"}, Type: multiagent.MessageTypeRequest, Content: "private"},
+		{ID: "msg_public", From: "worker", To: []multiagent.AgentID{"listener"}, Type: multiagent.MessageTypeRequest, Content: "public"},
+	} {
+		if err := orch.RouteMessage(ctx, msg); err != nil {
+			t.Fatalf("RouteMessage: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(listener.order()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("listener received nothing")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if handled := listener.order(); len(handled) != 1 || handled[0] != "msg_public" {
+		t.Errorf("listener handled %v, want only msg_public", handled)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]bool{"outer:msg_private": true, "inner:msg_private": true, "outer:msg_public": true, "inner:msg_public": true}
+	if len(calls) != 4 {
+		t.Fatalf("middleware calls %v", calls)
+	}
+	index := make(map[string]int)
+	for i, call := range calls {
+		if !want[call] {
+			t.Errorf("unexpected middleware call %q", call)
+		}
+		index[call] = i
+	}
+	// Middleware added first sees each message first
+	for _, id := range []string{"msg_private", "msg_public"} {
+		if index["outer:"+id] > index["inner:"+id] {
+			t.Errorf("middleware ran out of order: %v", calls)
+		}
+	}
+}
+
+func TestPublishCapabilitiesSurvivesReregistration(t *testing.T) {
+	orch, _ := newTestOrchestrator(t)
+	agent := &capableAgent{stubAgent: stubAgent{id: "expenses"}, capabilities: []string{"general_help"}}
+	if err := orch.RegisterAgent(agent); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+
+	published := []multiagent.CapabilityDescriptor{{Name: "expense_tracking", Domain: "finance", Keywords: []string{"receipt", "expense"}}}
+	if err := orch.PublishCapabilities("expenses", published); err != nil {
+		t.Fatalf("PublishCapabilities: %v", err)
+	}
+	// A restarted agent is registered again from its own capabilities
+	orch.capabilities.Register(agent)
+	if got := orch.capabilities.Descriptors("expenses"); len(got) != 1 || got[0].Name != "expense_tracking" {
+		t.Errorf("descriptors after re-registration %+v", got)
+	}
+
+	ranked := orch.RankAgentsForTask(multiagent.Task{Type: "expense_tracking", Description: "file this receipt"})
+	if len(ranked) == 0 || ranked[0].AgentID != "expenses" {
+		t.Errorf("expected expenses agent ranked first, got %+v", ranked)
+	}
+	if err := orch.PublishCapabilities("unknown", published); err == nil {
+		t.Error("expected an error publishing capabilities of an unregistered agent")
+	}
+}
+
+func TestRouteMiddlewareRewritesAndBlocks(t *testing.T) {
+	ctx := context.Background()
+	orch, _ := newTestOrchestrator(t)
+	received := make(chan string, 4)
+	listener := &stubAgent{id: "listener", handle: func(ctx context.Context, msg *multiagent.Message) error {
+		received <- msg.Content
+		return nil
+	}}
+	if err := orch.RegisterAgent(listener); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+
+	orch.UseRouting(func(next RouteHandler) RouteHandler {
+		return func(ctx context.Context, msg *multiagent.Message) error {
+			if strings.Contains(msg.Content, "secret") {
+				return fmt.Errorf("%w: no secrets", ErrMessageBlocked)
+			}
+			return next(ctx, msg)
+		}
+	}, func(next RouteHandler) RouteHandler {
+		return func(ctx context.Context, msg *multiagent.Message) error {
+			rewritten := *msg
+			rewritten.Content = strings.ToUpper(msg.Content)
+			return next(ctx, &rewritten)
+		}
+	})
+
+	blocked := &multiagent.Message{From: "worker", To: []multiagent.AgentID{"listener"}, Type: multiagent.MessageTypeRequest, Content: "the secret"}
+	if err := orch.RouteMessage(ctx, blocked); !errors.Is(err, ErrMessageBlocked) {
+		t.Fatalf("RouteMessage of a blocked message returned %v, want ErrMessageBlocked", err)
+	}
+	allowed := &multiagent.Message{From: "worker", To: []multiagent.AgentID{"listener"}, Type: multiagent.MessageTypeRequest, Content: "hello"}
+	if err := orch.RouteMessage(ctx, allowed); err != nil {
+		t.Fatalf("RouteMessage: %v", err)
+	}
+
+	select {
+	case content := <-received:
+		if content != "HELLO" {
+			t.Errorf("listener received %q, want the rewritten HELLO", content)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener received nothing")
+	}
+	if allowed.Content != "hello" {
+		t.Errorf("sender's message was changed to %q", allowed.Content)
+	}
+	select {
+	case content := <-received:
+		t.Errorf("listener also received %q", content)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
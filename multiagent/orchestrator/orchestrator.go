@@ -2,30 +2,60 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/metrics"
+	"github.com/kbutz/wikillm/multiagent/progress"
 )
 
+var logger = logging.For("orchestrator")
+
 // DefaultOrchestrator implements the Orchestrator interface
 type DefaultOrchestrator struct {
-	agents               map[multiagent.AgentID]multiagent.Agent
-	agentsByType         map[multiagent.AgentType][]multiagent.Agent
-	tasks                map[string]*multiagent.Task
-	messageQueue         chan *multiagent.Message
-	eventQueue           chan *multiagent.Event
-	memoryStore          multiagent.MemoryStore
-	mu                   sync.RWMutex
-	startTime            time.Time
-	stopChan             chan struct{}
-	wg                   sync.WaitGroup
-	running              bool
-	userResponseHandlers map[string]func(string) // Map of response key to handler function
-	handlersMutex        sync.RWMutex
+	agents            map[multiagent.AgentID]multiagent.Agent
+	agentsByType      map[multiagent.AgentType][]multiagent.Agent
+	capabilities      *CapabilityRegistry
+	tasks             map[string]*multiagent.Task
+	taskStore         TaskStore
+	outbox            Outbox
+	retryPolicy       *multiagent.RetryPolicy
+	taskCheckInterval time.Duration
+	taskCancels       map[string]context.CancelFunc // In-flight attempt contexts
+	attemptStarted    map[string]time.Time
+	messageQueue      *priorityQueue
+	workers           *workerSlots
+	eventQueue        chan *multiagent.Event
+	memoryStore       multiagent.MemoryStore
+	memoryStats       multiagent.MemoryStatsProvider
+	mu                sync.RWMutex
+	startTime         time.Time
+	stopChan          chan struct{}
+	wg                sync.WaitGroup
+	running           atomic.Bool                            // Read by RouteMessage without o.mu, which its callers may hold
+	subscriptions     map[string]map[multiagent.AgentID]bool // Topic pattern to subscribers
+	topicStats        map[string]*multiagent.TopicStats
+	topicsMu          sync.RWMutex
+	supervisor        *supervisor
+	metrics           *orchestratorMetrics
+	audit             audit.Recorder
+	progress          *progress.Hub
+	users             *userDirectory
+	questions         *questionBoard
+	requests          *requestRegistry
+	clock             multiagent.Clock
+	ids               multiagent.IDGenerator
+	middleware        []MessageMiddleware
+	routeMiddleware   []RouteMiddleware
+	middlewareMu      sync.RWMutex
+	loops             *loopGuard
 }
 
 // OrchestratorConfig holds configuration for creating an orchestrator
@@ -33,6 +63,41 @@ type OrchestratorConfig struct {
 	MemoryStore      multiagent.MemoryStore
 	MessageQueueSize int
 	EventQueueSize   int
+	// QueueHighWatermark is the queue depth above which low-priority messages
+	// are shed or deferred (default 80% of MessageQueueSize)
+	QueueHighWatermark int
+	// QueueOverloadPolicy is OverloadShed (default) or OverloadDefer
+	QueueOverloadPolicy OverloadPolicy
+	// AgentConcurrency is how many queued messages each agent handles at
+	// once (default 4); the rest wait in the queue
+	AgentConcurrency int
+	// MemoryStats optionally reports memory maintenance stats in GetSystemHealth
+	MemoryStats multiagent.MemoryStatsProvider
+	// TaskStore persists tasks across restarts (defaults to a MemoryTaskStore on MemoryStore)
+	TaskStore TaskStore
+	// Outbox persists queued messages until handled (defaults to a MemoryOutbox on MemoryStore)
+	Outbox Outbox
+	// DefaultRetryPolicy applies to tasks without their own Retry policy
+	DefaultRetryPolicy *multiagent.RetryPolicy
+	// TaskCheckInterval is how often deadlines and timeouts are checked (default 1s)
+	TaskCheckInterval time.Duration
+	// Supervisor controls automatic restarts of crashed agents
+	Supervisor SupervisorConfig
+	// Metrics, if set, receives routing, queue, and task metrics
+	Metrics *metrics.Registry
+	// Audit, if set, records every routed message
+	Audit audit.Recorder
+	// Progress receives agent progress events (defaults to a new hub)
+	Progress *progress.Hub
+	// Clock stamps messages, tasks and events (default the system clock)
+	Clock multiagent.Clock
+	// IDs names messages, tasks, events and requests (default ULIDs)
+	IDs multiagent.IDGenerator
+	// RouteMiddleware runs, in order, on every message routed, e.g. to log,
+	// redact or block it (see the policy package)
+	RouteMiddleware []RouteMiddleware
+	// Loops limits runaway exchanges between agents
+	Loops LoopConfig
 }
 
 // NewOrchestrator creates a new orchestrator instance
@@ -43,18 +108,69 @@ func NewOrchestrator(config OrchestratorConfig) *DefaultOrchestrator {
 	if config.EventQueueSize == 0 {
 		config.EventQueueSize = 500
 	}
-
-	return &DefaultOrchestrator{
-		agents:               make(map[multiagent.AgentID]multiagent.Agent),
-		agentsByType:         make(map[multiagent.AgentType][]multiagent.Agent),
-		tasks:                make(map[string]*multiagent.Task),
-		messageQueue:         make(chan *multiagent.Message, config.MessageQueueSize),
-		eventQueue:           make(chan *multiagent.Event, config.EventQueueSize),
-		memoryStore:          config.MemoryStore,
-		stopChan:             make(chan struct{}),
-		running:              false,
-		userResponseHandlers: make(map[string]func(string)),
+	if config.TaskCheckInterval == 0 {
+		config.TaskCheckInterval = time.Second
+	}
+	if config.TaskStore == nil && config.MemoryStore != nil {
+		config.TaskStore = NewMemoryTaskStore(config.MemoryStore)
+	}
+	if config.Outbox == nil && config.MemoryStore != nil {
+		config.Outbox = NewMemoryOutbox(config.MemoryStore)
 	}
+	if config.Progress == nil {
+		config.Progress = progress.NewHub()
+	}
+	config.Clock = ids.ClockOrSystem(config.Clock)
+	config.IDs = ids.OrDefault(config.IDs)
+
+	messageQueue := newPriorityQueue(MessageQueueConfig{
+		Capacity:      config.MessageQueueSize,
+		HighWatermark: config.QueueHighWatermark,
+		Policy:        config.QueueOverloadPolicy,
+	})
+
+	o := &DefaultOrchestrator{
+		agents:            make(map[multiagent.AgentID]multiagent.Agent),
+		agentsByType:      make(map[multiagent.AgentType][]multiagent.Agent),
+		capabilities:      NewCapabilityRegistry(),
+		tasks:             make(map[string]*multiagent.Task),
+		taskStore:         config.TaskStore,
+		outbox:            config.Outbox,
+		retryPolicy:       config.DefaultRetryPolicy,
+		taskCheckInterval: config.TaskCheckInterval,
+		taskCancels:       make(map[string]context.CancelFunc),
+		attemptStarted:    make(map[string]time.Time),
+		messageQueue:      messageQueue,
+		workers:           newWorkerSlots(config.AgentConcurrency),
+		eventQueue:        make(chan *multiagent.Event, config.EventQueueSize),
+		memoryStore:       config.MemoryStore,
+		memoryStats:       config.MemoryStats,
+		stopChan:          make(chan struct{}),
+		subscriptions:     make(map[string]map[multiagent.AgentID]bool),
+		topicStats:        make(map[string]*multiagent.TopicStats),
+		supervisor:        newSupervisor(config.Supervisor),
+		audit:             config.Audit,
+		progress:          config.Progress,
+		users:             newUserDirectory(),
+		questions:         newQuestionBoard(),
+		requests:          newRequestRegistry(config.Clock, config.IDs),
+		clock:             config.Clock,
+		ids:               config.IDs,
+		routeMiddleware:   config.RouteMiddleware,
+		loops:             newLoopGuard(config.Loops),
+	}
+	o.metrics = newOrchestratorMetrics(config.Metrics, o)
+	return o
+}
+
+// now returns the time on the orchestrator's clock
+func (o *DefaultOrchestrator) now() time.Time {
+	return o.clock.Now()
+}
+
+// newID returns a new ID starting with prefix
+func (o *DefaultOrchestrator) newID(prefix string) string {
+	return o.ids.NewID(prefix)
 }
 
 // RegisterAgent registers a new agent with the orchestrator
@@ -71,6 +187,7 @@ func (o *DefaultOrchestrator) RegisterAgent(agent multiagent.Agent) error {
 
 	// Add to agent maps
 	o.agents[agentID] = agent
+	o.capabilities.Register(agent)
 
 	agentType := agent.Type()
 	if o.agentsByType[agentType] == nil {
@@ -78,7 +195,7 @@ func (o *DefaultOrchestrator) RegisterAgent(agent multiagent.Agent) error {
 	}
 	o.agentsByType[agentType] = append(o.agentsByType[agentType], agent)
 
-	log.Printf("Orchestrator: Registered agent %s (%s) of type %s", agent.Name(), agentID, agentType)
+	logger.Info("Registered agent", logging.KeyAgentID, agentID, "name", agent.Name(), "type", agentType)
 
 	// Store registration in memory
 	if o.memoryStore != nil {
@@ -86,7 +203,7 @@ func (o *DefaultOrchestrator) RegisterAgent(agent multiagent.Agent) error {
 		o.memoryStore.Store(context.Background(), regKey, map[string]interface{}{
 			"agent_id":     agentID,
 			"agent_type":   agentType,
-			"registered":   time.Now(),
+			"registered":   o.now(),
 			"capabilities": agent.GetCapabilities(),
 		})
 	}
@@ -114,6 +231,8 @@ func (o *DefaultOrchestrator) UnregisterAgent(agentID multiagent.AgentID) error
 
 	// Remove from maps
 	delete(o.agents, agentID)
+	o.capabilities.Unregister(agentID)
+	o.unsubscribeAll(agentID)
 
 	// Remove from type map
 	agentType := agent.Type()
@@ -165,12 +284,28 @@ func (o *DefaultOrchestrator) ListAgents() []multiagent.Agent {
 func (o *DefaultOrchestrator) RouteMessage(ctx context.Context, msg *multiagent.Message) error {
 	// Validate message
 	if msg.ID == "" {
-		msg.ID = fmt.Sprintf("msg_%d", time.Now().UnixNano())
+		msg.ID = o.newID("msg")
 	}
 	if msg.Timestamp.IsZero() {
-		msg.Timestamp = time.Now()
+		msg.Timestamp = o.now()
+	}
+	o.users.stamp(msg)
+	if err := o.admitMessage(ctx, msg); err != nil {
+		return err
 	}
 
+	err := o.router()(ctx, msg)
+	if errors.Is(err, ErrMessageBlocked) {
+		logger.WarnContext(ctx, "Message blocked", logging.KeyMessageID, msg.ID, "from", msg.From, "to", msg.To, "error", err)
+	}
+	return err
+}
+
+// routeMessage stores and queues a message the route middleware passed on
+func (o *DefaultOrchestrator) routeMessage(ctx context.Context, msg *multiagent.Message) error {
+	o.metrics.messageRouted(msg)
+	o.auditMessage(ctx, msg)
+
 	// Store message in memory
 	if o.memoryStore != nil {
 		msgKey := fmt.Sprintf("orchestrator:message:%s", msg.ID)
@@ -178,19 +313,33 @@ func (o *DefaultOrchestrator) RouteMessage(ctx context.Context, msg *multiagent.
 	}
 
 	// If orchestrator is running, add to message queue
-	if o.running {
-		select {
-		case o.messageQueue <- msg:
-			return nil
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			return fmt.Errorf("message queue full")
-		}
+	if o.running.Load() {
+		return o.enqueue(ctx, msg)
 	}
 
 	// If not running, route directly
-	return o.routeMessageToAgents(ctx, msg)
+	return o.routeMessageToAgents(ctx, msg, nil, nil)
+}
+
+// SubmitEvent queues an event reported from outside the orchestrator, e.g.
+// an out-of-process agent reporting task_completed
+func (o *DefaultOrchestrator) SubmitEvent(event *multiagent.Event) error {
+	if event.Type == "" {
+		return fmt.Errorf("event has no type")
+	}
+	if event.ID == "" {
+		event.ID = o.newID("event")
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = o.now()
+	}
+
+	select {
+	case o.eventQueue <- event:
+		return nil
+	default:
+		return fmt.Errorf("event queue is full")
+	}
 }
 
 // BroadcastMessage sends a message to all agents
@@ -215,19 +364,19 @@ func (o *DefaultOrchestrator) AssignTask(ctx context.Context, task multiagent.Ta
 
 	// Generate task ID if not set
 	if task.ID == "" {
-		task.ID = fmt.Sprintf("task_%d", time.Now().UnixNano())
+		task.ID = o.newID("task")
 	}
 
-	log.Printf("Orchestrator: AssignTask called for task %s, assignee: %s", task.ID, task.Assignee)
+	logger.DebugContext(ctx, "AssignTask called", logging.KeyTaskID, task.ID, "assignee", task.Assignee)
 
 	// Set initial status
 	task.Status = multiagent.TaskStatusPending
-	task.CreatedAt = time.Now()
+	task.CreatedAt = o.now()
 
 	// Ensure Output map is initialized if nil
 	if task.Output == nil {
 		task.Output = make(map[string]interface{})
-		log.Printf("Orchestrator: Initialized nil Output map for task %s", task.ID)
+		logger.DebugContext(ctx, "Initialized nil Output map", logging.KeyTaskID, task.ID)
 	}
 
 	var agent multiagent.Agent
@@ -253,7 +402,23 @@ func (o *DefaultOrchestrator) AssignTask(ctx context.Context, task multiagent.Ta
 
 	task.Status = multiagent.TaskStatusAssigned
 
+	// Hold the task until its prerequisites complete
+	if len(task.DependsOn) > 0 {
+		ready, err := o.checkDependencies(ctx, &task)
+		if err != nil {
+			return "", err
+		}
+		if !ready {
+			o.transition(&task, multiagent.TaskStatusWaiting, "")
+			o.tasks[task.ID] = &task
+			o.persistTask(ctx, &task)
+			logger.InfoContext(ctx, "Task waiting on dependencies", logging.KeyTaskID, task.ID, "depends_on", task.DependsOn)
+			return agent.ID(), nil
+		}
+	}
+
 	// Store task
+	o.transition(&task, multiagent.TaskStatusAssigned, "")
 	o.tasks[task.ID] = &task
 
 	// Store in memory
@@ -263,26 +428,18 @@ func (o *DefaultOrchestrator) AssignTask(ctx context.Context, task multiagent.Ta
 	}
 
 	// Send task to agent
-	taskMsg := &multiagent.Message{
-		ID:        fmt.Sprintf("msg_%d", time.Now().UnixNano()),
-		From:      multiagent.AgentID("orchestrator"),
-		To:        []multiagent.AgentID{agent.ID()},
-		Type:      multiagent.MessageTypeRequest,
-		Content:   fmt.Sprintf("Execute task %s: %s", task.ID, task.Description),
-		Context:   map[string]interface{}{"task_id": task.ID},
-		Priority:  task.Priority,
-		Timestamp: time.Now(),
-	}
-
-	log.Printf("Orchestrator: Sending task message to agent %s", agent.ID())
-	if err := o.RouteMessage(ctx, taskMsg); err != nil {
-		task.Status = multiagent.TaskStatusFailed
-		task.Error = fmt.Sprintf("Failed to send task to agent: %v", err)
-		log.Printf("Orchestrator: Failed to send task to agent %s: %v", agent.ID(), err)
+	logger.DebugContext(ctx, "Sending task message", logging.KeyTaskID, task.ID, logging.KeyAgentID, agent.ID())
+	err = o.dispatchTask(ctx, &task)
+	if err != nil {
+		o.transition(&task, multiagent.TaskStatusFailed, fmt.Sprintf("Failed to send task to agent: %v", err))
+	}
+	o.persistTask(ctx, &task)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to send task", logging.KeyTaskID, task.ID, logging.KeyAgentID, agent.ID(), "error", err)
 		return "", err
 	}
 
-	log.Printf("Orchestrator: Successfully assigned task %s to agent %s", task.ID, agent.ID())
+	logger.InfoContext(ctx, "Assigned task", logging.KeyTaskID, task.ID, logging.KeyAgentID, agent.ID())
 
 	return agent.ID(), nil
 }
@@ -294,13 +451,10 @@ func (o *DefaultOrchestrator) GetTaskStatus(ctx context.Context, taskID string)
 
 	task, exists := o.tasks[taskID]
 	if !exists {
-		// Try to load from memory
-		if o.memoryStore != nil {
-			taskKey := fmt.Sprintf("orchestrator:task:%s", taskID)
-			if value, err := o.memoryStore.Get(ctx, taskKey); err == nil {
-				if task, ok := value.(multiagent.Task); ok {
-					return task.Status, nil
-				}
+		// Try to load from the task store
+		if o.taskStore != nil {
+			if task, err := o.taskStore.Get(ctx, taskID); err == nil {
+				return task.Status, nil
 			}
 		}
 		return "", fmt.Errorf("task %s not found", taskID)
@@ -309,17 +463,34 @@ func (o *DefaultOrchestrator) GetTaskStatus(ctx context.Context, taskID string)
 	return task.Status, nil
 }
 
+// GetTask returns a copy of a task
+func (o *DefaultOrchestrator) GetTask(ctx context.Context, taskID string) (*multiagent.Task, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	if task, exists := o.tasks[taskID]; exists {
+		copied := *task
+		return &copied, nil
+	}
+	if o.taskStore != nil {
+		if task, err := o.taskStore.Get(ctx, taskID); err == nil {
+			return task, nil
+		}
+	}
+	return nil, fmt.Errorf("task %s not found", taskID)
+}
+
 // Start begins the orchestrator's operation
 func (o *DefaultOrchestrator) Start(ctx context.Context) error {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 
-	if o.running {
+	if o.running.Load() {
 		return fmt.Errorf("orchestrator is already running")
 	}
 
-	o.startTime = time.Now()
-	o.running = true
+	o.startTime = o.now()
+	o.running.Store(true)
 
 	// Start message router
 	o.wg.Add(1)
@@ -333,21 +504,43 @@ func (o *DefaultOrchestrator) Start(ctx context.Context) error {
 	o.wg.Add(1)
 	go o.healthMonitor(ctx)
 
+	// Start deadline and timeout enforcement
+	o.wg.Add(1)
+	go o.taskMonitor(ctx)
+
+	// Restart crashed agents
+	if !o.supervisor.config.Disabled {
+		o.wg.Add(1)
+		go o.supervisorLoop(ctx)
+	}
+
+	// Ping agents to find those that stopped responding
+	if o.supervisor.config.HeartbeatInterval > 0 {
+		o.wg.Add(1)
+		go o.heartbeatLoop(ctx)
+	}
+
+	// Re-queue messages that were never handled, then reload unfinished
+	// tasks; replayed task requests from older attempts are skipped as stale
+	o.replayOutbox(ctx)
+	o.restoreTasks(ctx)
+
 	return nil
 }
 
 // Stop halts the orchestrator's operation
 func (o *DefaultOrchestrator) Stop(ctx context.Context) error {
 	o.mu.Lock()
-	if !o.running {
+	if !o.running.Load() {
 		o.mu.Unlock()
 		return nil
 	}
-	o.running = false
+	o.running.Store(false)
 	o.mu.Unlock()
 
-	// Signal stop
+	// Signal stop; nobody will answer the requests still waiting
 	close(o.stopChan)
+	o.requests.cancelAll()
 
 	// Wait for goroutines to finish
 	done := make(chan struct{})
@@ -372,7 +565,7 @@ func (o *DefaultOrchestrator) GetSystemHealth() multiagent.SystemHealth {
 	defer o.mu.RUnlock()
 
 	status := multiagent.SystemStatusOffline
-	if o.running {
+	if o.running.Load() {
 		status = multiagent.SystemStatusHealthy
 	}
 
@@ -382,16 +575,35 @@ func (o *DefaultOrchestrator) GetSystemHealth() multiagent.SystemHealth {
 		ActiveAgents: 0,
 		PendingTasks: 0,
 		ActiveTasks:  0,
-		MessageQueue: len(o.messageQueue),
-		Uptime:       time.Since(o.startTime),
-		LastCheck:    time.Now(),
+		MessageQueue: o.messageQueue.Len(),
+		Uptime:       o.now().Sub(o.startTime),
+		LastCheck:    o.now(),
 		AgentHealth:  make(map[multiagent.AgentID]multiagent.AgentState),
 	}
 
 	// Check agent states
 	errorCount := 0
 	for id, agent := range o.agents {
-		state := agent.GetState()
+		lastHeartbeat, unresponsive := o.heartbeatState(id)
+		if !lastHeartbeat.IsZero() {
+			if health.LastHeartbeats == nil {
+				health.LastHeartbeats = make(map[multiagent.AgentID]time.Time)
+			}
+			health.LastHeartbeats[id] = lastHeartbeat
+		}
+
+		// An agent that stopped answering pings may not answer GetState
+		// either
+		var state multiagent.AgentState
+		if unresponsive {
+			state = multiagent.AgentState{
+				Status:       multiagent.AgentStatusError,
+				LastActivity: lastHeartbeat,
+				Metadata:     map[string]interface{}{"error": "unresponsive to heartbeats"},
+			}
+		} else {
+			state = agent.GetState()
+		}
 		health.AgentHealth[id] = state
 
 		switch state.Status {
@@ -405,253 +617,150 @@ func (o *DefaultOrchestrator) GetSystemHealth() multiagent.SystemHealth {
 	// Count tasks
 	for _, task := range o.tasks {
 		switch task.Status {
-		case multiagent.TaskStatusPending:
+		case multiagent.TaskStatusPending, multiagent.TaskStatusWaiting:
 			health.PendingTasks++
 		case multiagent.TaskStatusAssigned, multiagent.TaskStatusInProgress:
 			health.ActiveTasks++
 		}
 	}
 
-	// Determine overall system status
-	if o.running {
-		if errorCount > len(o.agents)/2 {
-			health.Status = multiagent.SystemStatusCritical
-		} else if errorCount > 0 || health.MessageQueue > 800 {
-			health.Status = multiagent.SystemStatusDegraded
-		}
+	// Include memory maintenance stats
+	if o.memoryStats != nil {
+		stats := o.memoryStats.MemoryStats()
+		health.Memory = &stats
+		health.MemoryUsage = stats.QuotaUsage
 	}
 
-	return health
-}
+	queueStats := o.messageQueue.Stats()
+	health.Queue = &queueStats
 
-// RegisterUserResponseHandler registers a handler for user responses
-func (o *DefaultOrchestrator) RegisterUserResponseHandler(responseKey string, handler func(string)) {
-	o.handlersMutex.Lock()
-	defer o.handlersMutex.Unlock()
-
-	if existing, exists := o.userResponseHandlers[responseKey]; exists {
-		log.Printf("Orchestrator: [REGISTER] ⚠️ Replacing existing handler for key: %s", responseKey)
-		_ = existing
-	}
-
-	o.userResponseHandlers[responseKey] = handler
-	log.Printf("Orchestrator: [REGISTER] ✅ Handler registered for key: %s (total: %d)", 
-		responseKey, len(o.userResponseHandlers))
-}
-
-// UnregisterUserResponseHandler removes a user response handler
-func (o *DefaultOrchestrator) UnregisterUserResponseHandler(responseKey string) {
-	o.handlersMutex.Lock()
-	defer o.handlersMutex.Unlock()
-
-	if _, exists := o.userResponseHandlers[responseKey]; exists {
-		delete(o.userResponseHandlers, responseKey)
-		log.Printf("Orchestrator: [UNREGISTER] ✅ Handler unregistered for key: %s (total: %d)", 
-			responseKey, len(o.userResponseHandlers))
-	} else {
-		log.Printf("Orchestrator: [UNREGISTER] ⚠️ Attempted to unregister non-existent handler: %s", responseKey)
-	}
-}
-
-// GetOrphanedResponse attempts to retrieve an orphaned response for recovery
-func (o *DefaultOrchestrator) GetOrphanedResponse(ctx context.Context, responseKey string) (string, bool) {
-	if o.memoryStore == nil {
-		return "", false
+	if restarts := o.RestartCounts(); len(restarts) > 0 {
+		health.AgentRestarts = restarts
 	}
 
-	orphanKey := fmt.Sprintf("orchestrator:orphaned_response:%s", responseKey)
-	if value, err := o.memoryStore.Get(ctx, orphanKey); err == nil {
-		if orphanData, ok := value.(map[string]interface{}); ok {
-			if content, ok := orphanData["content"].(string); ok {
-				log.Printf("Orchestrator: [RECOVERY] ✅ Retrieved orphaned response for key: %s", responseKey)
-				// Clean up the orphaned response after retrieval
-				o.memoryStore.Delete(ctx, orphanKey)
-				return content, true
-			}
+	// Determine overall system status
+	if o.running.Load() {
+		if errorCount > len(o.agents)/2 {
+			health.Status = multiagent.SystemStatusCritical
+		} else if errorCount > 0 || health.MessageQueue >= queueStats.HighWatermark || health.MemoryUsage > 95 {
+			health.Status = multiagent.SystemStatusDegraded
 		}
 	}
 
-	return "", false
-}
-
-// GetUserResponseHandlerCount returns the current number of registered handlers
-func (o *DefaultOrchestrator) GetUserResponseHandlerCount() int {
-	o.handlersMutex.RLock()
-	defer o.handlersMutex.RUnlock()
-	return len(o.userResponseHandlers)
-}
-
-// GetUserResponseHandlerKeys returns the keys of all registered handlers
-func (o *DefaultOrchestrator) GetUserResponseHandlerKeys() []string {
-	o.handlersMutex.RLock()
-	defer o.handlersMutex.RUnlock()
-
-	keys := make([]string, 0, len(o.userResponseHandlers))
-	for key := range o.userResponseHandlers {
-		keys = append(keys, key)
-	}
-	return keys
-}
-
-// handleUserResponse handles responses meant for users with enhanced diagnostics
-func (o *DefaultOrchestrator) handleUserResponse(ctx context.Context, response *multiagent.Message) {
-	if len(response.To) == 0 {
-		log.Printf("Orchestrator: [USER_RESPONSE] ❌ No recipients in message")
-		return
-	}
-
-	responseKey := string(response.To[0])
-	log.Printf("Orchestrator: [USER_RESPONSE] Processing response for key: %s", responseKey)
-	log.Printf("Orchestrator: [USER_RESPONSE] Response content length: %d", len(response.Content))
-
-	// Get handler with detailed logging
-	o.handlersMutex.RLock()
-	handler, exists := o.userResponseHandlers[responseKey]
-	totalHandlers := len(o.userResponseHandlers)
-
-	// Log all available handlers for debugging
-	availableKeys := make([]string, 0, len(o.userResponseHandlers))
-	for key := range o.userResponseHandlers {
-		availableKeys = append(availableKeys, key)
-	}
-	o.handlersMutex.RUnlock()
-
-	log.Printf("Orchestrator: [USER_RESPONSE] Handler exists: %v, total handlers: %d", exists, totalHandlers)
-	log.Printf("Orchestrator: [USER_RESPONSE] Available handler keys: %v", availableKeys)
-
-	if exists && handler != nil {
-		log.Printf("Orchestrator: [USER_RESPONSE] ✅ Executing handler for key: %s", responseKey)
-
-		// Execute handler with panic recovery
-		go func() {
-			defer func() {
-				if r := recover(); r != nil {
-					log.Printf("Orchestrator: [USER_RESPONSE] ❌ Handler panic for %s: %v", responseKey, r)
-				}
-			}()
-
-			handler(response.Content)
-			log.Printf("Orchestrator: [USER_RESPONSE] ✅ Handler executed successfully for %s", responseKey)
-		}()
-
-		return
-	}
-
-	// Handler not found - this should not happen with our new approach
-	log.Printf("Orchestrator: [USER_RESPONSE] ❌ CRITICAL: No handler found for %s", responseKey)
-	log.Printf("Orchestrator: [USER_RESPONSE] This indicates a cleanup bug - handler was unregistered prematurely")
-
-	// Store as orphaned response for recovery
-	if o.memoryStore != nil {
-		orphanKey := fmt.Sprintf("orchestrator:orphaned_response:%s", responseKey)
-		orphanData := map[string]interface{}{
-			"response_key": responseKey,
-			"content":      response.Content,
-			"timestamp":    time.Now(),
-			"from_agent":   response.From,
-		}
-
-		// Store with a longer TTL to ensure it's available for recovery
-		if err := o.memoryStore.StoreWithTTL(ctx, orphanKey, orphanData, 24*time.Hour); err != nil {
-			log.Printf("Orchestrator: [USER_RESPONSE] ❌ Failed to store orphaned response: %v", err)
-		} else {
-			log.Printf("Orchestrator: [USER_RESPONSE] 💾 Stored orphaned response with 24-hour TTL")
-		}
-
-		// Try to re-register a handler for this response key
-		// This is a fallback mechanism to handle race conditions where the handler was unregistered prematurely
-		log.Printf("Orchestrator: [USER_RESPONSE] 🔄 Attempting to re-register a temporary handler for %s", responseKey)
-		o.handlersMutex.Lock()
-		o.userResponseHandlers[responseKey] = func(content string) {
-			log.Printf("Orchestrator: [USER_RESPONSE] ⚠️ Late-registered handler called for %s", responseKey)
-			// This is a no-op handler since the response is already stored as an orphan
-			// The service will retrieve it via GetOrphanedResponse
-		}
-		o.handlersMutex.Unlock()
-		log.Printf("Orchestrator: [USER_RESPONSE] ✅ Temporary handler registered for %s", responseKey)
-	}
+	return health
 }
 
 // Internal helper methods
 
 func (o *DefaultOrchestrator) findBestAgent(task multiagent.Task) (multiagent.Agent, error) {
-	// Simple algorithm: find least loaded agent that can handle the task type
-	var bestAgent multiagent.Agent
-	lowestWorkload := 101
-
-	for _, agent := range o.agents {
-		state := agent.GetState()
-
-		// Skip unavailable agents
-		if state.Status != multiagent.AgentStatusIdle && state.Status != multiagent.AgentStatusBusy {
-			continue
-		}
-
-		// Check if agent can handle this task type
-		canHandle := false
-		for _, capability := range agent.GetCapabilities() {
-			if capability == task.Type {
-				canHandle = true
-				break
-			}
-		}
-
-		if canHandle && state.Workload < lowestWorkload {
-			bestAgent = agent
-			lowestWorkload = state.Workload
-		}
-	}
-
-	if bestAgent == nil {
+	// Score agents on capability match, workload, and past success
+	candidates := o.rankAgents(func(id multiagent.AgentID) (float64, string) {
+		return o.capabilities.MatchTask(id, task)
+	})
+	if len(candidates) == 0 {
 		return nil, fmt.Errorf("no suitable agent found for task type: %s", task.Type)
 	}
 
-	return bestAgent, nil
+	best := candidates[0]
+	logger.Debug("Selected best agent for task", logging.KeyTaskID, task.ID, logging.KeyAgentID, best.AgentID, "capability", best.Capability, "score", best.Score)
+	return o.agents[best.AgentID], nil
 }
 
+// messageRouter takes messages off the queue as their recipients have free
+// worker slots, highest priority first
 func (o *DefaultOrchestrator) messageRouter(ctx context.Context) {
 	defer o.wg.Done()
 
 	for {
 		select {
-		case msg := <-o.messageQueue:
-			o.routeMessageToAgents(ctx, msg)
-
+		case <-o.messageQueue.Ready():
+		case <-o.workers.Freed():
 		case <-o.stopChan:
 			return
-
 		case <-ctx.Done():
 			return
 		}
+
+		for {
+			var reserved []multiagent.AgentID
+			msg := o.messageQueue.PopIf(func(msg *multiagent.Message) bool {
+				reserved = o.slotRecipients(msg)
+				return o.workers.tryAcquire(reserved)
+			})
+			if msg == nil {
+				break
+			}
+			o.routeQueuedMessage(ctx, msg, reserved)
+		}
 	}
 }
 
-func (o *DefaultOrchestrator) routeMessageToAgents(ctx context.Context, msg *multiagent.Message) error {
+// routeQueuedMessage routes a message taken off the queue with worker slots
+// reserved for its recipients, clearing it from the outbox once every
+// recipient has handled it
+func (o *DefaultOrchestrator) routeQueuedMessage(ctx context.Context, msg *multiagent.Message, reserved []multiagent.AgentID) {
+	o.routeMessageToAgents(ctx, msg, reserved, func() { o.markDelivered(ctx, msg) })
+}
+
+// routeMessageToAgents delivers msg to each recipient, releasing the worker
+// slot reserved for each once it has finished; onHandled, if set, runs after
+// all recipients have finished with it
+func (o *DefaultOrchestrator) routeMessageToAgents(ctx context.Context, msg *multiagent.Message, reserved []multiagent.AgentID, onHandled func()) error {
+	ctx = progress.WithHub(logging.WithMessage(ctx, msg), o.progress)
+	ctx = multiagent.WithUserID(ctx, multiagent.UserIDFromMessage(msg))
+	if hops, ok := messageHops(msg); ok {
+		ctx = withHops(ctx, hops)
+	}
 	o.mu.RLock()
 	defer o.mu.RUnlock()
 
-	log.Printf("Orchestrator: Routing message %s from %s to %v (type: %s)", msg.ID, msg.From, msg.To, msg.Type)
+	// Slots not handed to a recipient's handler are released once routed
+	slots := make(map[multiagent.AgentID]int, len(reserved))
+	for _, id := range reserved {
+		slots[id]++
+	}
+	defer func() {
+		for id, n := range slots {
+			for ; n > 0; n-- {
+				o.workers.release(id)
+			}
+		}
+	}()
+
+	var handling sync.WaitGroup
+	if onHandled != nil {
+		defer func() {
+			go func() {
+				handling.Wait()
+				onHandled()
+			}()
+		}()
+	}
+
+	logger.DebugContext(ctx, "Routing message", "from", msg.From, "to", msg.To, "type", msg.Type)
 
 	// Route to each recipient
 	for _, recipientID := range msg.To {
-		// Special handling for user response keys
-		if strings.HasPrefix(string(recipientID), "user_response_") {
-			log.Printf("Orchestrator: Routing message %s to user response handler for %s", msg.ID, recipientID)
-			o.handleUserResponse(ctx, msg)
+		// Replies to a caller's request answer it
+		if o.isRequest(recipientID) {
+			logger.DebugContext(ctx, "Routing reply to its request", "request_id", recipientID)
+			o.answerRequest(ctx, msg)
 			continue
 		}
 
 		// Special handling for messages directed to the orchestrator itself
 		if recipientID == "orchestrator" {
-			log.Printf("Orchestrator: Processing message %s directed to orchestrator", msg.ID)
+			logger.DebugContext(ctx, "Processing message directed to orchestrator")
 
 			// Handle orchestrator-directed messages
+			handling.Add(1)
 			go func(m *multiagent.Message) {
+				defer handling.Done()
 				response := o.handleOrchestratorMessage(ctx, m)
 				if response != nil {
-					log.Printf("Orchestrator: Routing orchestrator response back")
+					logger.DebugContext(ctx, "Routing orchestrator response back")
 					if err := o.RouteMessage(ctx, response); err != nil {
-						log.Printf("Error routing orchestrator response: %v", err)
+						logger.ErrorContext(ctx, "Failed to route orchestrator response", "error", err)
 					}
 				}
 			}(msg)
@@ -660,42 +769,111 @@ func (o *DefaultOrchestrator) routeMessageToAgents(ctx context.Context, msg *mul
 
 		agent, exists := o.agents[recipientID]
 		if !exists {
-			// Log error but continue with other recipients
-			log.Printf("Warning: Agent %s not found for message %s", recipientID, msg.ID)
+			// Dead-letter it but continue with other recipients
+			logger.WarnContext(ctx, "Recipient agent not found", logging.KeyAgentID, recipientID)
+			o.deadLetter(ctx, msg, recipientID, DeadLetterUnknownAgent, "agent not registered")
+			if expectsReply(msg) {
+				o.replyWithError(ctx, msg, "orchestrator", fmt.Errorf("agent %s not found", recipientID))
+			}
 			continue
 		}
 
-		log.Printf("Orchestrator: Sending message %s to agent %s (%s)", msg.ID, recipientID, agent.Name())
+		logger.DebugContext(ctx, "Sending message to agent", logging.KeyAgentID, recipientID, "name", agent.Name())
 
 		// Handle the message directly with the agent
+		slot := slots[recipientID] > 0
+		if slot {
+			slots[recipientID]--
+		}
+		handling.Add(1)
 		go func(a multiagent.Agent, m *multiagent.Message) {
-			log.Printf("Orchestrator: Processing message %s with agent %s", m.ID, a.ID())
+			defer handling.Done()
+			if slot {
+				defer o.workers.release(a.ID())
+			}
+			handleCtx := logging.WithAgent(ctx, a.ID())
+			logger.DebugContext(handleCtx, "Processing message with agent")
+			taskID, attempt := taskAttemptFromMessage(m)
+			if taskID != "" {
+				var cancel context.CancelFunc
+				handleCtx, cancel = context.WithCancel(handleCtx)
+				defer cancel()
+				if !o.markTaskStarted(ctx, taskID, attempt, cancel) {
+					logger.InfoContext(handleCtx, "Skipping stale or cancelled task attempt", "attempt", attempt)
+					return
+				}
+			}
+
 			// Process the message with the agent
-			response, err := a.HandleMessage(ctx, m)
+			progress.Emit(handleCtx, progress.Event{Type: progress.AgentStarted, Detail: a.Name()})
+			started := time.Now()
+			response, err := o.handleWithRecovery(handleCtx, a, m)
+			o.metrics.messageHandled(a.ID(), time.Since(started), err)
+			o.reportHandled(handleCtx, a, time.Since(started), err)
+			if taskID != "" {
+				o.markTaskFinished(ctx, taskID, attempt, response, err)
+			}
 			if err != nil {
-				log.Printf("Error handling message %s with agent %s: %v", m.ID, a.ID(), err)
+				logger.ErrorContext(handleCtx, "Agent failed to handle message", "error", err)
+				// Failed tasks are tracked (and retried) through the task record
+				if taskID == "" {
+					reason := DeadLetterHandlerError
+					if errors.Is(err, errHandlerPanic) {
+						reason = DeadLetterHandlerPanic
+					}
+					o.deadLetter(ctx, m, a.ID(), reason, err.Error())
+				}
+				if errors.Is(err, errHandlerPanic) {
+					o.reportCrash(a.ID(), err.Error())
+				}
+				if expectsReply(m) {
+					o.replyWithError(ctx, m, a.ID(), err)
+				}
 				return
 			}
 
-			log.Printf("Orchestrator: Agent %s processed message %s, response: %v", a.ID(), m.ID, response != nil)
+			logger.DebugContext(handleCtx, "Agent processed message", "has_response", response != nil)
+
+			// A question pauses the request until the user answers it
+			if response != nil && response.Type == multiagent.MessageTypeQuestion {
+				if expectsReply(m) && response.ReplyTo == "" {
+					response.ReplyTo = m.ID
+				}
+				if len(response.To) == 0 {
+					response.To = []multiagent.AgentID{m.From}
+				}
+				o.askQuestion(handleCtx, a.ID(), m, response)
+				return
+			}
 
 			// If we got a response, handle it appropriately
 			if response != nil {
-				log.Printf("Orchestrator: Handling response from agent %s to %v (type: %s)", a.ID(), response.To, response.Type)
+				if expectsReply(m) {
+					// Correlate the reply with the sender's pending request
+					if response.ReplyTo == "" {
+						response.ReplyTo = m.ID
+					}
+					if len(response.To) == 0 {
+						response.To = []multiagent.AgentID{m.From}
+					}
+				}
+				logger.DebugContext(handleCtx, "Handling agent response", "to", response.To, "type", response.Type)
 
-				// Check if the response is meant for a user (starts with "user_response_")
-				if len(response.To) > 0 && strings.HasPrefix(string(response.To[0]), "user_response_") {
-					// This is a response to a user request - handle it via callback
-					log.Printf("Orchestrator: Routing response to user callback")
-					o.handleUserResponse(ctx, response)
+				// A reply to a caller's request answers it
+				if len(response.To) > 0 && o.isRequest(response.To[0]) {
+					logger.DebugContext(handleCtx, "Answering request with response")
+					o.answerRequest(ctx, response)
 				} else if o.shouldRouteResponse(m, response) {
 					// Route the response back through the orchestrator for agent-to-agent communication
-					log.Printf("Orchestrator: Routing response back through orchestrator")
+					logger.DebugContext(handleCtx, "Routing response back through orchestrator")
+					if response.Type == multiagent.MessageTypeResponse {
+						progress.Emit(handleCtx, progress.Event{Type: progress.PartialResult, Detail: truncate(response.Content, partialResultLength)})
+					}
 					if err := o.RouteMessage(ctx, response); err != nil {
-						log.Printf("Error routing response from agent %s: %v", a.ID(), err)
+						logger.ErrorContext(handleCtx, "Failed to route agent response", "error", err)
 					}
 				} else {
-					log.Printf("Orchestrator: Terminating message chain to prevent loop")
+					logger.DebugContext(handleCtx, "Terminating message chain to prevent loop")
 				}
 			}
 		}(agent, msg)
@@ -728,6 +906,14 @@ func (o *DefaultOrchestrator) processEvent(ctx context.Context, event *multiagen
 		o.memoryStore.StoreWithTTL(ctx, eventKey, event, 24*time.Hour)
 	}
 
+	// Fan out to topic subscribers
+	o.publishEvent(ctx, event)
+
+	// Events the orchestrator emits itself only record transitions it already applied
+	if event.Source == "orchestrator" {
+		return
+	}
+
 	// Process based on event type
 	switch event.Type {
 	case multiagent.EventTaskCompleted:
@@ -737,6 +923,8 @@ func (o *DefaultOrchestrator) processEvent(ctx context.Context, event *multiagen
 			if task, exists := o.tasks[taskID]; exists {
 				task.Status = multiagent.TaskStatusCompleted
 				task.CompletedAt = &event.Timestamp
+				o.persistTask(ctx, task)
+				o.releaseDependents(ctx, taskID)
 			}
 			o.mu.Unlock()
 		}
@@ -750,6 +938,8 @@ func (o *DefaultOrchestrator) processEvent(ctx context.Context, event *multiagen
 				if errorMsg, ok := event.Data["error"].(string); ok {
 					task.Error = errorMsg
 				}
+				o.persistTask(ctx, task)
+				o.releaseDependents(ctx, taskID)
 			}
 			o.mu.Unlock()
 		}
@@ -767,15 +957,18 @@ func (o *DefaultOrchestrator) healthMonitor(ctx context.Context) {
 		case <-ticker.C:
 			health := o.GetSystemHealth()
 
+			// Pick up restored tasks whose agents have come online since
+			o.redispatchStrandedTasks(ctx)
+
 			// Store health snapshot
 			if o.memoryStore != nil {
-				healthKey := fmt.Sprintf("orchestrator:health:%d", time.Now().Unix())
+				healthKey := fmt.Sprintf("orchestrator:health:%d", o.now().Unix())
 				o.memoryStore.StoreWithTTL(ctx, healthKey, health, 7*24*time.Hour)
 			}
 
 			// Log if system is degraded
 			if health.Status != multiagent.SystemStatusHealthy {
-				fmt.Printf("System health warning: %s\n", health.Status)
+				logger.WarnContext(ctx, "System health degraded", "status", health.Status)
 			}
 
 		case <-o.stopChan:
@@ -787,91 +980,97 @@ func (o *DefaultOrchestrator) healthMonitor(ctx context.Context) {
 	}
 }
 
-// shouldRouteResponse determines if a response should be routed to prevent infinite loops
+// expectsReply reports whether msg was sent with BaseAgent.Request-style correlation
+// auditMessage records msg as sent by its sender
+func (o *DefaultOrchestrator) auditMessage(ctx context.Context, msg *multiagent.Message) {
+	if o.audit == nil {
+		return
+	}
+
+	event := audit.Event{
+		Type:      audit.MessageSent,
+		Actor:     msg.From,
+		Subject:   msg.ID,
+		Timestamp: msg.Timestamp,
+		Payload: map[string]interface{}{
+			"to":   msg.To,
+			"type": msg.Type,
+		},
+	}
+	if msg.ReplyTo != "" {
+		event.Payload["reply_to"] = msg.ReplyTo
+	}
+	event.ConversationID, _ = msg.Context["conversation_id"].(string)
+	if err := o.audit.Record(ctx, event); err != nil {
+		logger.WarnContext(ctx, "Failed to audit message", logging.KeyMessageID, msg.ID, "error", err)
+	}
+}
+
+func expectsReply(msg *multiagent.Message) bool {
+	expects, _ := msg.Context[multiagent.ContextExpectsReply].(bool)
+	return expects
+}
+
+// replyWithError tells a waiting requester that its request failed
+func (o *DefaultOrchestrator) replyWithError(ctx context.Context, request *multiagent.Message, from multiagent.AgentID, err error) {
+	reply := &multiagent.Message{
+		ID:        o.newID("msg"),
+		From:      from,
+		To:        []multiagent.AgentID{request.From},
+		Type:      multiagent.MessageTypeError,
+		Content:   err.Error(),
+		ReplyTo:   request.ID,
+		Timestamp: o.now(),
+	}
+	if routeErr := o.RouteMessage(ctx, reply); routeErr != nil {
+		logger.ErrorContext(ctx, "Failed to send error reply", logging.KeyMessageID, request.ID, "error", routeErr)
+	}
+}
+
+// shouldRouteResponse reports whether an agent's response to originalMsg
+// is passed on. Only structure is considered; runaway exchanges are stopped
+// by the loop guard (see LoopConfig).
 func (o *DefaultOrchestrator) shouldRouteResponse(originalMsg *multiagent.Message, response *multiagent.Message) bool {
 	// Don't route if it's the same agent responding to itself
 	if response.From == originalMsg.From {
 		return false
 	}
 
-	// Always route messages intended for users (user_response_ prefix)
-	if len(response.To) > 0 && strings.HasPrefix(string(response.To[0]), "user_response_") {
-		log.Printf("Orchestrator: Allowing user-directed response")
+	// Always route the reply a requester is waiting on
+	if expectsReply(originalMsg) && response.ReplyTo == originalMsg.ID {
 		return true
 	}
 
 	// Always route final responses from coordination
 	if finalResp, ok := response.Context["final_response"].(bool); ok && finalResp {
-		log.Printf("Orchestrator: Allowing final coordination response")
-		return true
-	}
-
-	// Always route responses from coordinator agent to user response handlers
-	if response.From == "coordinator_agent" && len(response.To) > 0 && strings.HasPrefix(string(response.To[0]), "user_response_") {
-		log.Printf("Orchestrator: Allowing coordinator response to user")
 		return true
 	}
 
-	// Don't route simple acknowledgment messages between agents
-	if response.Type == multiagent.MessageTypeResponse {
-		// Check for coordination acknowledgments that are just status updates
-		if _, hasCoordID := response.Context["coordination_id"]; hasCoordID {
-			if ack, hasAck := response.Context["acknowledged"].(bool); hasAck && ack {
-				log.Printf("Orchestrator: Skipping routing of coordination acknowledgment")
-				return false
-			}
-
-			// Allow all coordination responses regardless of content - coordinator will handle them
-			log.Printf("Orchestrator: Allowing coordination response")
-			return true
-		}
-
-		// Check for simple acknowledgment content patterns (only for non-coordination messages)
-		contentLower := strings.ToLower(response.Content)
-		if (strings.Contains(contentLower, "response received") && len(response.Content) < 50) ||
-			(strings.Contains(contentLower, "processed") && len(response.Content) < 50) {
-			log.Printf("Orchestrator: Skipping routing of simple acknowledgment")
-			return false
-		}
-
-		// Don't route generic help messages that create loops (only for non-coordination messages)
-		if strings.Contains(contentLower, "thank you for confirming") ||
-			(strings.Contains(contentLower, "as your") && strings.Contains(contentLower, "manager") && len(response.Content) < 200) ||
-			(strings.Contains(contentLower, "would you like to:") && len(response.Content) < 300) {
-			log.Printf("Orchestrator: Skipping routing of generic help message")
-			return false
-		}
+	// Coordination responses go to the coordinator, except acknowledgments
+	// that are just status updates
+	if _, hasCoordID := response.Context["coordination_id"]; hasCoordID && response.Type == multiagent.MessageTypeResponse {
+		ack, _ := response.Context["acknowledged"].(bool)
+		return !ack
 	}
 
-	// Check for reply chains that are getting too long
-	// Only block if we're seeing the same two agents repeatedly exchanging messages
-	if response.ReplyTo != "" && originalMsg.ReplyTo != "" {
-		// Allow coordinator final responses even in reply chains
-		if response.From == "coordinator_agent" && strings.HasPrefix(string(response.To[0]), "user_response_") {
-			log.Printf("Orchestrator: Allowing coordinator final response despite reply chain")
-			return true
-		}
-
-		// Only block if it's the same agents talking back and forth
-		if response.From == originalMsg.To[0] && response.To[0] == originalMsg.From {
-			log.Printf("Orchestrator: Terminating deep reply chain between same agents")
-			return false
-		}
+	// A response to a response only acknowledges it, and routing it would
+	// start agents answering each other
+	if originalMsg.Type == multiagent.MessageTypeResponse && response.Type == multiagent.MessageTypeResponse {
+		logger.Debug("Skipping response to a response")
+		return false
 	}
-
-	// Route if it's a legitimate new message from a different agent
-	return response.From != originalMsg.From
+	return true
 }
 
 // handleOrchestratorMessage handles messages directed to the orchestrator itself
 func (o *DefaultOrchestrator) handleOrchestratorMessage(ctx context.Context, msg *multiagent.Message) *multiagent.Message {
-	log.Printf("Orchestrator: Handling message %s of type %s", msg.ID, msg.Type)
+	logger.DebugContext(ctx, "Handling orchestrator message", "type", msg.Type)
 
 	switch msg.Type {
 	case multiagent.MessageTypeResponse:
 		// Handle coordination status updates
 		if coordinationID, ok := msg.Context["coordination_id"].(string); ok {
-			log.Printf("Orchestrator: Received coordination status update for %s", coordinationID)
+			logger.InfoContext(ctx, "Received coordination status update", "coordination_id", coordinationID)
 
 			// Store coordination status in memory
 			if o.memoryStore != nil {
@@ -886,7 +1085,7 @@ func (o *DefaultOrchestrator) handleOrchestratorMessage(ctx context.Context, msg
 
 			// Acknowledge the coordination message
 			return &multiagent.Message{
-				ID:      fmt.Sprintf("msg_orchestrator_%d", time.Now().UnixNano()),
+				ID:      o.newID("msg_orchestrator"),
 				From:    multiagent.AgentID("orchestrator"),
 				To:      []multiagent.AgentID{msg.From},
 				Type:    multiagent.MessageTypeResponse,
@@ -897,24 +1096,24 @@ func (o *DefaultOrchestrator) handleOrchestratorMessage(ctx context.Context, msg
 				},
 				Priority:  multiagent.PriorityLow,
 				ReplyTo:   msg.ID,
-				Timestamp: time.Now(),
+				Timestamp: o.now(),
 			}
 		}
 
 	case multiagent.MessageTypeRequest:
 		// Handle direct requests to orchestrator
-		log.Printf("Orchestrator: Processing direct request: %s", msg.Content)
+		logger.DebugContext(ctx, "Processing direct request", "content", msg.Content)
 
 		// Respond with orchestrator status or capabilities
 		return &multiagent.Message{
-			ID:        fmt.Sprintf("msg_orchestrator_%d", time.Now().UnixNano()),
+			ID:        o.newID("msg_orchestrator"),
 			From:      multiagent.AgentID("orchestrator"),
 			To:        []multiagent.AgentID{msg.From},
 			Type:      multiagent.MessageTypeResponse,
 			Content:   fmt.Sprintf("Orchestrator received request: %s", msg.Content),
 			Priority:  multiagent.PriorityMedium,
 			ReplyTo:   msg.ID,
-			Timestamp: time.Now(),
+			Timestamp: o.now(),
 		}
 	}
 
@@ -0,0 +1,165 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// Outbox persists queued messages until they have been handled so a crash
+// does not lose them
+type Outbox interface {
+	Append(ctx context.Context, msg *multiagent.Message) error
+	MarkDelivered(ctx context.Context, messageID string) error
+	// Pending returns undelivered messages, oldest first
+	Pending(ctx context.Context) ([]*multiagent.Message, error)
+}
+
+const (
+	outboxKeyPrefix = "orchestrator:outbox:"
+	// maxReplayedMessages bounds a single Pending scan
+	maxReplayedMessages = 10000
+)
+
+// outboxEntry is what is persisted for each queued message
+type outboxEntry struct {
+	Message    *multiagent.Message `json:"message"`
+	EnqueuedAt time.Time           `json:"enqueued_at"`
+}
+
+// MemoryOutbox implements Outbox on top of a MemoryStore
+type MemoryOutbox struct {
+	memoryStore multiagent.MemoryStore
+}
+
+// NewMemoryOutbox creates an outbox that keeps messages under orchestrator:outbox:<id>
+func NewMemoryOutbox(memoryStore multiagent.MemoryStore) *MemoryOutbox {
+	return &MemoryOutbox{memoryStore: memoryStore}
+}
+
+// Append records a message as enqueued
+func (b *MemoryOutbox) Append(ctx context.Context, msg *multiagent.Message) error {
+	entry := outboxEntry{Message: msg, EnqueuedAt: time.Now()}
+	if err := b.memoryStore.Store(ctx, outboxKeyPrefix+msg.ID, entry); err != nil {
+		return fmt.Errorf("failed to persist message %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// MarkDelivered removes a handled message
+func (b *MemoryOutbox) MarkDelivered(ctx context.Context, messageID string) error {
+	if err := b.memoryStore.Delete(ctx, outboxKeyPrefix+messageID); err != nil {
+		return fmt.Errorf("failed to mark message %s delivered: %w", messageID, err)
+	}
+	return nil
+}
+
+// Pending loads every undelivered message, oldest first
+func (b *MemoryOutbox) Pending(ctx context.Context) ([]*multiagent.Message, error) {
+	keys, err := b.memoryStore.List(ctx, outboxKeyPrefix, maxReplayedMessages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbox: %w", err)
+	}
+
+	entries := make([]outboxEntry, 0, len(keys))
+	for _, key := range keys {
+		value, err := b.memoryStore.Get(ctx, key)
+		if err != nil {
+			continue
+		}
+		entry, err := decodeOutboxEntry(value)
+		if err != nil || entry.Message == nil {
+			logger.WarnContext(ctx, "Skipping unreadable outbox entry", logging.KeyMessageID, strings.TrimPrefix(key, outboxKeyPrefix), "error", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].EnqueuedAt.Before(entries[j].EnqueuedAt) })
+	messages := make([]*multiagent.Message, len(entries))
+	for i, entry := range entries {
+		messages[i] = entry.Message
+	}
+	return messages, nil
+}
+
+func decodeOutboxEntry(value interface{}) (outboxEntry, error) {
+	if entry, ok := value.(outboxEntry); ok {
+		return entry, nil
+	}
+
+	var entry outboxEntry
+	data, err := json.Marshal(value)
+	if err != nil {
+		return entry, fmt.Errorf("failed to marshal outbox entry: %w", err)
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, fmt.Errorf("failed to unmarshal outbox entry: %w", err)
+	}
+	return entry, nil
+}
+
+// enqueue persists a message to the outbox and queues it for routing
+func (o *DefaultOrchestrator) enqueue(ctx context.Context, msg *multiagent.Message) error {
+	if o.outbox != nil {
+		if err := o.outbox.Append(ctx, msg); err != nil {
+			return err
+		}
+	}
+
+	if err := o.messageQueue.Push(msg); err != nil {
+		if o.outbox != nil {
+			o.outbox.MarkDelivered(ctx, msg.ID)
+		}
+		return fmt.Errorf("failed to enqueue message %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// markDelivered clears a message from the outbox once every recipient has handled it
+func (o *DefaultOrchestrator) markDelivered(ctx context.Context, msg *multiagent.Message) {
+	if o.outbox == nil {
+		return
+	}
+	if err := o.outbox.MarkDelivered(ctx, msg.ID); err != nil {
+		logger.ErrorContext(ctx, "Failed to mark message delivered", logging.KeyMessageID, msg.ID, "error", err)
+	}
+}
+
+// replayOutbox re-queues messages that were enqueued but never handled
+// before the last shutdown
+func (o *DefaultOrchestrator) replayOutbox(ctx context.Context) {
+	if o.outbox == nil {
+		return
+	}
+
+	messages, err := o.outbox.Pending(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to replay outbox", "error", err)
+		return
+	}
+
+	replayed := 0
+	for _, msg := range messages {
+		if err := o.messageQueue.Push(msg); err != nil {
+			logger.ErrorContext(ctx, "Failed to replay message", logging.KeyMessageID, msg.ID, "error", err)
+			if errors.Is(err, ErrMessageShed) {
+				o.markDelivered(ctx, msg)
+			}
+			// Otherwise left in the outbox for the next start
+			continue
+		}
+		replayed++
+	}
+
+	if len(messages) > 0 {
+		logger.InfoContext(ctx, "Replayed undelivered messages", "replayed", replayed, "undelivered", len(messages))
+	}
+}
@@ -0,0 +1,54 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+func TestOutboxReplaysUndeliveredMessages(t *testing.T) {
+	ctx := context.Background()
+	store, err := memory.NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileMemoryStore: %v", err)
+	}
+	defer store.Close()
+
+	// Left behind by a process that died before routing it
+	outbox := NewMemoryOutbox(store)
+	if err := outbox.Append(ctx, &multiagent.Message{ID: "msg_lost", From: "user", To: []multiagent.AgentID{"worker"}}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	orch := NewOrchestrator(OrchestratorConfig{MemoryStore: store})
+	agent := &stubAgent{id: "worker"}
+	if err := orch.RegisterAgent(agent); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	if err := orch.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer orch.Stop(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pending, err := outbox.Pending(ctx)
+		if err != nil {
+			t.Fatalf("Pending: %v", err)
+		}
+		if len(pending) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("message was not marked delivered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if handled := agent.order(); len(handled) != 1 || handled[0] != "msg_lost" {
+		t.Fatalf("expected replayed message to be handled once, got %v", handled)
+	}
+}
@@ -0,0 +1,36 @@
+package orchestrator
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/progress"
+)
+
+// partialResultLength caps the specialist output included in a PartialResult event
+const partialResultLength = 280
+
+// Progress returns the hub that receives agent progress events
+func (o *DefaultOrchestrator) Progress() *progress.Hub {
+	return o.progress
+}
+
+// reportHandled emits an AgentFinished event for a handled message
+func (o *DefaultOrchestrator) reportHandled(ctx context.Context, agent multiagent.Agent, elapsed time.Duration, err error) {
+	data := map[string]interface{}{"duration_ms": elapsed.Milliseconds()}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	progress.Emit(ctx, progress.Event{Type: progress.AgentFinished, Detail: agent.Name(), Data: data})
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,58 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/progress"
+)
+
+func TestRoutedMessagesReportProgress(t *testing.T) {
+	orch, agent := newTestOrchestrator(t)
+	agent.handle = func(ctx context.Context, msg *multiagent.Message) error {
+		progress.Emit(ctx, progress.Event{Type: progress.ToolCall, Detail: "calendar_lookup"})
+		return errors.New("calendar unavailable")
+	}
+
+	events, unsubscribe := orch.Progress().Subscribe("conv_alice")
+	defer unsubscribe()
+
+	err := orch.RouteMessage(context.Background(), &multiagent.Message{
+		ID:      "msg_progress",
+		From:    "tester",
+		To:      []multiagent.AgentID{"worker"},
+		Type:    multiagent.MessageTypeNotification,
+		Content: "find a free slot",
+		Context: map[string]interface{}{"conversation_id": "conv_alice"},
+	})
+	if err != nil {
+		t.Fatalf("RouteMessage: %v", err)
+	}
+
+	var got []progress.Event
+	timeout := time.After(2 * time.Second)
+	for len(got) < 3 {
+		select {
+		case event := <-events:
+			got = append(got, event)
+		case <-timeout:
+			t.Fatalf("timed out after %d events: %+v", len(got), got)
+		}
+	}
+
+	wantTypes := []progress.Type{progress.AgentStarted, progress.ToolCall, progress.AgentFinished}
+	for i, event := range got {
+		if event.Type != wantTypes[i] {
+			t.Errorf("event %d type = %s, want %s", i, event.Type, wantTypes[i])
+		}
+		if event.AgentID != "worker" || event.MessageID != "msg_progress" || event.ConversationID != "conv_alice" {
+			t.Errorf("event %d not correlated: %+v", i, event)
+		}
+	}
+	if got[2].Data["error"] != "calendar unavailable" {
+		t.Errorf("expected the handler error on agent_finished, got %v", got[2].Data)
+	}
+}
@@ -0,0 +1,221 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// Subscribe registers agentID for messages published to topics matching
+// pattern. Patterns are dot-separated; "*" matches one segment and "#"
+// matches any number of trailing segments ("calendar.*", "task.#", "#").
+func (o *DefaultOrchestrator) Subscribe(agentID multiagent.AgentID, pattern string) error {
+	if err := validateTopicPattern(pattern); err != nil {
+		return err
+	}
+
+	o.mu.RLock()
+	_, registered := o.agents[agentID]
+	o.mu.RUnlock()
+	if !registered {
+		return fmt.Errorf("agent %s not found", agentID)
+	}
+
+	o.topicsMu.Lock()
+	defer o.topicsMu.Unlock()
+	if o.subscriptions[pattern] == nil {
+		o.subscriptions[pattern] = make(map[multiagent.AgentID]bool)
+	}
+	o.subscriptions[pattern][agentID] = true
+
+	logger.Info("Agent subscribed", logging.KeyAgentID, agentID, "pattern", pattern)
+	return nil
+}
+
+// Unsubscribe removes a subscription made with Subscribe
+func (o *DefaultOrchestrator) Unsubscribe(agentID multiagent.AgentID, pattern string) error {
+	o.topicsMu.Lock()
+	defer o.topicsMu.Unlock()
+
+	subscribers, exists := o.subscriptions[pattern]
+	if !exists || !subscribers[agentID] {
+		return fmt.Errorf("agent %s is not subscribed to %s", agentID, pattern)
+	}
+	delete(subscribers, agentID)
+	if len(subscribers) == 0 {
+		delete(o.subscriptions, pattern)
+	}
+	return nil
+}
+
+// Publish delivers msg to every agent subscribed to topic, other than the
+// sender, and returns how many subscribers it was routed to
+func (o *DefaultOrchestrator) Publish(ctx context.Context, topic string, msg *multiagent.Message) (int, error) {
+	if topic == "" || strings.ContainsAny(topic, "*#") {
+		return 0, fmt.Errorf("invalid topic %q", topic)
+	}
+
+	recipients := o.subscribers(topic, msg.From)
+
+	o.topicsMu.Lock()
+	stats := o.topicStats[topic]
+	if stats == nil {
+		stats = &multiagent.TopicStats{}
+		o.topicStats[topic] = stats
+	}
+	stats.Published++
+	stats.LastPublished = o.now()
+	if len(recipients) == 0 {
+		stats.Unrouted++
+	}
+	o.topicsMu.Unlock()
+
+	if len(recipients) == 0 {
+		return 0, nil
+	}
+
+	published := *msg
+	published.To = recipients
+	if published.Type == "" {
+		published.Type = multiagent.MessageTypeNotification
+	}
+	published.Context = make(map[string]interface{}, len(msg.Context)+1)
+	for k, v := range msg.Context {
+		published.Context[k] = v
+	}
+	published.Context["topic"] = topic
+
+	err := o.RouteMessage(ctx, &published)
+
+	o.topicsMu.Lock()
+	if err != nil {
+		stats.Failed += int64(len(recipients))
+	} else {
+		stats.Delivered += int64(len(recipients))
+	}
+	o.topicsMu.Unlock()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+	return len(recipients), nil
+}
+
+// GetTopicStats returns delivery counters for every topic published so far
+func (o *DefaultOrchestrator) GetTopicStats() map[string]multiagent.TopicStats {
+	o.topicsMu.RLock()
+	defer o.topicsMu.RUnlock()
+
+	stats := make(map[string]multiagent.TopicStats, len(o.topicStats))
+	for topic, s := range o.topicStats {
+		stats[topic] = *s
+	}
+	return stats
+}
+
+// subscribers returns the agents whose patterns match topic, excluding sender
+func (o *DefaultOrchestrator) subscribers(topic string, sender multiagent.AgentID) []multiagent.AgentID {
+	o.topicsMu.RLock()
+	defer o.topicsMu.RUnlock()
+
+	matched := make(map[multiagent.AgentID]bool)
+	for pattern, agents := range o.subscriptions {
+		if !topicMatches(pattern, topic) {
+			continue
+		}
+		for agentID := range agents {
+			if agentID != sender {
+				matched[agentID] = true
+			}
+		}
+	}
+
+	recipients := make([]multiagent.AgentID, 0, len(matched))
+	for agentID := range matched {
+		recipients = append(recipients, agentID)
+	}
+	sort.Slice(recipients, func(i, j int) bool { return recipients[i] < recipients[j] })
+	return recipients
+}
+
+// unsubscribeAll drops every subscription held by agentID
+func (o *DefaultOrchestrator) unsubscribeAll(agentID multiagent.AgentID) {
+	o.topicsMu.Lock()
+	defer o.topicsMu.Unlock()
+
+	for pattern, subscribers := range o.subscriptions {
+		delete(subscribers, agentID)
+		if len(subscribers) == 0 {
+			delete(o.subscriptions, pattern)
+		}
+	}
+}
+
+// publishEvent republishes an orchestrator event on its topic, e.g.
+// task_completed on "task.completed"
+func (o *DefaultOrchestrator) publishEvent(ctx context.Context, event *multiagent.Event) {
+	topic := eventTopic(event.Type)
+
+	msg := &multiagent.Message{
+		ID:        o.newID("msg"),
+		From:      multiagent.AgentID(event.Source),
+		Type:      multiagent.MessageTypeNotification,
+		Content:   fmt.Sprintf("Event %s from %s", event.Type, event.Source),
+		Context:   map[string]interface{}{"event_id": event.ID, "event_type": string(event.Type)},
+		Timestamp: event.Timestamp,
+	}
+	for k, v := range event.Data {
+		msg.Context[k] = v
+	}
+
+	if _, err := o.Publish(ctx, topic, msg); err != nil {
+		logger.ErrorContext(ctx, "Failed to publish event", "event_id", event.ID, "error", err)
+	}
+}
+
+// eventTopic maps an event type to a topic: the first underscore becomes a dot
+func eventTopic(eventType multiagent.EventType) string {
+	return strings.Replace(string(eventType), "_", ".", 1)
+}
+
+func validateTopicPattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("topic pattern is required")
+	}
+	segments := strings.Split(pattern, ".")
+	for i, segment := range segments {
+		if segment == "" {
+			return fmt.Errorf("invalid topic pattern %q: empty segment", pattern)
+		}
+		if strings.Contains(segment, "#") && (segment != "#" || i != len(segments)-1) {
+			return fmt.Errorf("invalid topic pattern %q: # must be the whole last segment", pattern)
+		}
+		if strings.Contains(segment, "*") && segment != "*" {
+			return fmt.Errorf("invalid topic pattern %q: * must be a whole segment", pattern)
+		}
+	}
+	return nil
+}
+
+// topicMatches reports whether a validated pattern matches topic
+func topicMatches(pattern, topic string) bool {
+	patternSegments := strings.Split(pattern, ".")
+	topicSegments := strings.Split(topic, ".")
+
+	for i, segment := range patternSegments {
+		if segment == "#" {
+			return true
+		}
+		if i >= len(topicSegments) {
+			return false
+		}
+		if segment != "*" && segment != topicSegments[i] {
+			return false
+		}
+	}
+	return len(patternSegments) == len(topicSegments)
+}
@@ -0,0 +1,84 @@
+package orchestrator
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+func TestTopicMatches(t *testing.T) {
+	cases := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"calendar.updated", "calendar.updated", true},
+		{"calendar.*", "calendar.updated", true},
+		{"calendar.*", "calendar.event.updated", false},
+		{"task.#", "task", true},
+		{"task.#", "task.completed", true},
+		{"#", "anything.at.all", true},
+		{"*.completed", "task.completed", true},
+		{"task.completed", "task.failed", false},
+	}
+	for _, c := range cases {
+		if got := topicMatches(c.pattern, c.topic); got != c.want {
+			t.Errorf("topicMatches(%q, %q) = %v, want %v", c.pattern, c.topic, got, c.want)
+		}
+	}
+
+	if err := validateTopicPattern("task.#.done"); err == nil {
+		t.Error("expected # in the middle of a pattern to be rejected")
+	}
+}
+
+func TestPublishDeliversToSubscribers(t *testing.T) {
+	ctx := context.Background()
+	orch, _ := newTestOrchestrator(t)
+	listener := &stubAgent{id: "listener"}
+	if err := orch.RegisterAgent(listener); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+
+	if err := orch.Subscribe("listener", "calendar.*"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := orch.Subscribe("worker", "task.#"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	n, err := orch.Publish(ctx, "calendar.updated", &multiagent.Message{ID: "cal_1", From: "worker", Content: "moved"})
+	if err != nil || n != 1 {
+		t.Fatalf("expected delivery to 1 subscriber, got %d, %v", n, err)
+	}
+	if n, _ := orch.Publish(ctx, "reminder.due", &multiagent.Message{From: "worker"}); n != 0 {
+		t.Fatalf("expected no subscribers for reminder.due, got %d", n)
+	}
+
+	// Task events are published on their topic
+	if _, err := orch.AssignTask(ctx, multiagent.Task{ID: "t1", Type: "stub", Assignee: "listener"}); err != nil {
+		t.Fatalf("AssignTask: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if stats := orch.GetTopicStats()["task.completed"]; stats.Delivered == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task.completed was not delivered: %+v", orch.GetTopicStats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stats := orch.GetTopicStats()
+	if stats["calendar.updated"].Delivered != 1 || stats["reminder.due"].Unrouted != 1 {
+		t.Fatalf("unexpected topic stats: %+v", stats)
+	}
+	// Agents handle several messages at once, so only delivery is certain
+	if handled := listener.order(); !slices.Contains(handled, "cal_1") {
+		t.Fatalf("expected listener to receive cal_1, got %v", handled)
+	}
+}
@@ -0,0 +1,213 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/progress"
+)
+
+const (
+	questionKeyPrefix = "orchestrator:question:"
+	// questionTTL is how long a paused request waits for the user's answer
+	questionTTL = 24 * time.Hour
+)
+
+// Question is a follow-up an agent asked the user while handling a request.
+// The request is paused until the user answers, and then resumed with the
+// answer at the agent that asked.
+type Question struct {
+	ID             string             `json:"id"`
+	ConversationID string             `json:"conversation_id"`
+	AgentID        multiagent.AgentID `json:"agent_id"`
+	Content        string             `json:"content"`
+	// Context is the question message's context, e.g. the action it
+	// proposes, given back to the agent with the answer
+	Context map[string]interface{} `json:"context,omitempty"`
+	Request *multiagent.Message    `json:"request"`
+	AskedAt time.Time              `json:"asked_at"`
+}
+
+// questionBoard remembers the user waiting on each conversation and the
+// questions asked on it
+type questionBoard struct {
+	mu sync.RWMutex
+	// responders maps a conversation to the latest request opened on it
+	responders map[string]multiagent.AgentID
+	pending    map[string]*Question
+}
+
+func newQuestionBoard() *questionBoard {
+	return &questionBoard{
+		responders: make(map[string]multiagent.AgentID),
+		pending:    make(map[string]*Question),
+	}
+}
+
+// track records the request a user is waiting on in a conversation
+func (b *questionBoard) track(conversationID string, requestID multiagent.AgentID) {
+	b.mu.Lock()
+	b.responders[conversationID] = requestID
+	b.mu.Unlock()
+}
+
+func (b *questionBoard) responder(conversationID string) (multiagent.AgentID, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	key, ok := b.responders[conversationID]
+	return key, ok
+}
+
+// askQuestion pauses request, which agentID answered with question: the
+// question is kept until the user answers and is shown to the user waiting
+// on the conversation. A requester waiting on a reply gets the question as
+// that reply, so it knows the request is paused.
+func (o *DefaultOrchestrator) askQuestion(ctx context.Context, agentID multiagent.AgentID, request, question *multiagent.Message) {
+	conversationID, _ := question.Context["conversation_id"].(string)
+	if conversationID == "" {
+		conversationID, _ = request.Context["conversation_id"].(string)
+	}
+	if conversationID == "" {
+		logger.WarnContext(ctx, "Dropping question asked outside a conversation", logging.KeyAgentID, agentID)
+		return
+	}
+
+	pending := &Question{
+		ID:             o.newID("question"),
+		ConversationID: conversationID,
+		AgentID:        agentID,
+		Content:        question.Content,
+		Context:        question.Context,
+		Request:        request,
+		AskedAt:        o.now(),
+	}
+	o.questions.mu.Lock()
+	o.questions.pending[conversationID] = pending
+	o.questions.mu.Unlock()
+	if o.memoryStore != nil {
+		if err := o.memoryStore.StoreWithTTL(ctx, questionKeyPrefix+conversationID, pending, questionTTL); err != nil {
+			logger.WarnContext(ctx, "Failed to store question", "conversation_id", conversationID, "error", err)
+		}
+	}
+	logger.InfoContext(ctx, "Paused request for the user's answer", logging.KeyAgentID, agentID, "conversation_id", conversationID, "question_id", pending.ID)
+	// The action tells frontends what kind of answer the question wants,
+	// e.g. confirmation_required for a yes or no
+	data := map[string]interface{}{"question_id": pending.ID}
+	if action, ok := question.Context["action"].(string); ok {
+		data["action"] = action
+	}
+	progress.Emit(ctx, progress.Event{
+		Type:           progress.QuestionAsked,
+		ConversationID: conversationID,
+		AgentID:        agentID,
+		Detail:         question.Content,
+		Data:           data,
+	})
+
+	if question.Context == nil {
+		question.Context = make(map[string]interface{})
+	}
+	question.Context["question_id"] = pending.ID
+	question.Context["conversation_id"] = conversationID
+
+	// The user waiting on the conversation sees the question as their reply
+	user, ok := o.questions.responder(conversationID)
+	if o.isRequest(request.From) {
+		user, ok = request.From, true
+	}
+	if ok {
+		shown := *question
+		shown.To = []multiagent.AgentID{user}
+		o.answerRequest(ctx, &shown)
+	} else {
+		logger.WarnContext(ctx, "No user is waiting for the question", "conversation_id", conversationID)
+	}
+
+	if expectsReply(request) && request.From != user {
+		if err := o.RouteMessage(ctx, question); err != nil {
+			logger.ErrorContext(ctx, "Failed to tell the requester about the question", "requester", request.From, "error", err)
+		}
+	}
+}
+
+// PendingQuestion returns the question waiting on the user's answer in a
+// conversation
+func (o *DefaultOrchestrator) PendingQuestion(ctx context.Context, conversationID string) (*Question, bool) {
+	o.questions.mu.RLock()
+	question, ok := o.questions.pending[conversationID]
+	o.questions.mu.RUnlock()
+	if ok {
+		return question, true
+	}
+	if o.memoryStore == nil {
+		return nil, false
+	}
+
+	// Questions outlive restarts in memory
+	value, err := o.memoryStore.Get(ctx, questionKeyPrefix+conversationID)
+	if err != nil {
+		return nil, false
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	question = &Question{}
+	if err := json.Unmarshal(data, question); err != nil || question.Request == nil {
+		return nil, false
+	}
+	return question, true
+}
+
+// AnswerQuestion resumes the request paused by the question pending in a
+// conversation: the agent that asked gets the request again with the
+// answer appended and in its "answer" context, and replies to requestID,
+// the request the user opened to wait on the outcome
+func (o *DefaultOrchestrator) AnswerQuestion(ctx context.Context, conversationID string, requestID multiagent.AgentID, answer string) error {
+	question, ok := o.PendingQuestion(ctx, conversationID)
+	if !ok {
+		return fmt.Errorf("no question pending in conversation %s", conversationID)
+	}
+	o.questions.mu.Lock()
+	delete(o.questions.pending, conversationID)
+	o.questions.mu.Unlock()
+	if o.memoryStore != nil {
+		o.memoryStore.Delete(ctx, questionKeyPrefix+conversationID)
+	}
+
+	request := question.Request
+	resumed := &multiagent.Message{
+		ID:        o.newID("msg_answer"),
+		From:      requestID,
+		To:        []multiagent.AgentID{question.AgentID},
+		Type:      request.Type,
+		Content:   strings.TrimSpace(request.Content + "\n" + answer),
+		Priority:  request.Priority,
+		Timestamp: o.now(),
+		Context:   make(map[string]interface{}, len(request.Context)+5),
+	}
+	for key, value := range request.Context {
+		resumed.Context[key] = value
+	}
+	resumed.Context["conversation_id"] = conversationID
+	resumed.Context["response_key"] = string(requestID)
+	resumed.Context["question_id"] = question.ID
+	resumed.Context["question"] = question.Content
+	resumed.Context["answer"] = answer
+	if question.Context != nil {
+		resumed.Context["question_context"] = question.Context
+	}
+	resumed.Context[multiagent.ContextExpectsReply] = true
+
+	logger.InfoContext(ctx, "Resuming request with the user's answer", logging.KeyAgentID, question.AgentID, "conversation_id", conversationID, "question_id", question.ID)
+	if err := o.RouteMessage(ctx, resumed); err != nil {
+		return fmt.Errorf("failed to resume request: %w", err)
+	}
+	return nil
+}
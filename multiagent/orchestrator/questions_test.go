@@ -0,0 +1,162 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// schedulingAgent asks for a time unless the request carries an answer
+func schedulingAgent() *replierAgent {
+	return &replierAgent{stubAgent: stubAgent{id: "scheduler"}, reply: func(msg *multiagent.Message) (*multiagent.Message, error) {
+		if answer, ok := msg.Context["answer"].(string); ok {
+			return &multiagent.Message{From: "scheduler", To: []multiagent.AgentID{msg.From}, Type: multiagent.MessageTypeResponse, Content: "Booked: " + msg.Content + " (" + answer + ")"}, nil
+		}
+		return &multiagent.Message{From: "scheduler", To: []multiagent.AgentID{msg.From}, Type: multiagent.MessageTypeQuestion, Content: "What time?"}, nil
+	}}
+}
+
+func waitForReply(t *testing.T, request *PendingRequest) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	reply, err := request.Wait(ctx)
+	if err != nil {
+		t.Fatalf("user never received a reply: %v", err)
+	}
+	return reply
+}
+
+func TestQuestionPausesAndResumesRequest(t *testing.T) {
+	ctx := context.Background()
+	orch, _ := newTestOrchestrator(t)
+	if err := orch.RegisterAgent(schedulingAgent()); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+
+	request := orch.OpenRequest("conv_alice", 0)
+	err := orch.RouteMessage(ctx, &multiagent.Message{
+		ID:      "msg_user_1",
+		From:    request.ID,
+		To:      []multiagent.AgentID{"scheduler"},
+		Type:    multiagent.MessageTypeRequest,
+		Content: "book a call with Bob tomorrow",
+		Context: map[string]interface{}{"conversation_id": "conv_alice", multiagent.ContextUserID: "alice"},
+	})
+	if err != nil {
+		t.Fatalf("RouteMessage: %v", err)
+	}
+	if got := waitForReply(t, request); got != "What time?" {
+		t.Fatalf("user saw %q, want the question", got)
+	}
+
+	question, ok := orch.PendingQuestion(ctx, "conv_alice")
+	if !ok || question.AgentID != "scheduler" || question.Request.Content != "book a call with Bob tomorrow" {
+		t.Fatalf("pending question = %+v, %v", question, ok)
+	}
+
+	// The answer arrives on the user's next request
+	answer := orch.OpenRequest("conv_alice", 0)
+	if err := orch.AnswerQuestion(ctx, "conv_alice", answer.ID, "at 3pm"); err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+	if got, want := waitForReply(t, answer), "Booked: book a call with Bob tomorrow\nat 3pm (at 3pm)"; got != want {
+		t.Fatalf("resumed reply = %q, want %q", got, want)
+	}
+	if _, ok := orch.PendingQuestion(ctx, "conv_alice"); ok {
+		t.Fatal("question still pending after it was answered")
+	}
+	if err := orch.AnswerQuestion(ctx, "conv_alice", answer.ID, "again"); err == nil {
+		t.Fatal("expected an error answering with no question pending")
+	}
+}
+
+func TestQuestionReachesWaitingRequester(t *testing.T) {
+	ctx := context.Background()
+	orch, _ := newTestOrchestrator(t)
+	if err := orch.RegisterAgent(schedulingAgent()); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+
+	// The user's request reached the scheduler through a coordinating agent
+	request := orch.OpenRequest("conv_alice", 0)
+
+	requester := &inboxAgent{stubAgent: stubAgent{id: "requester"}, inbox: make(chan *multiagent.Message, 1)}
+	if err := orch.RegisterAgent(requester); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	err := orch.RouteMessage(ctx, &multiagent.Message{
+		ID:      "req_1",
+		From:    "requester",
+		To:      []multiagent.AgentID{"scheduler"},
+		Type:    multiagent.MessageTypeRequest,
+		Content: "book a call with Bob",
+		Context: map[string]interface{}{multiagent.ContextExpectsReply: true, "conversation_id": "conv_alice"},
+	})
+	if err != nil {
+		t.Fatalf("RouteMessage: %v", err)
+	}
+
+	if got := waitForReply(t, request); got != "What time?" {
+		t.Fatalf("user saw %q, want the question", got)
+	}
+	select {
+	case reply := <-requester.inbox:
+		if reply.Type != multiagent.MessageTypeQuestion || reply.ReplyTo != "req_1" {
+			t.Fatalf("requester got %+v, want the question as its reply", reply)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("requester never heard about the question")
+	}
+}
+
+func TestAnswerCarriesQuestionContext(t *testing.T) {
+	ctx := context.Background()
+	orch, _ := newTestOrchestrator(t)
+	resumed := make(chan *multiagent.Message, 1)
+	agent := &replierAgent{stubAgent: stubAgent{id: "task_manager"}, reply: func(msg *multiagent.Message) (*multiagent.Message, error) {
+		if _, ok := msg.Context["answer"]; ok {
+			resumed <- msg
+			return nil, nil
+		}
+		return &multiagent.Message{
+			From:    "task_manager",
+			To:      []multiagent.AgentID{msg.From},
+			Type:    multiagent.MessageTypeQuestion,
+			Content: "Delete 'buy milk'?",
+			Context: map[string]interface{}{"proposed_action": map[string]interface{}{"action": "delete_task", "subject": "task_1"}},
+		}, nil
+	}}
+	if err := orch.RegisterAgent(agent); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+
+	request := orch.OpenRequest("conv_alice", 0)
+	err := orch.RouteMessage(ctx, &multiagent.Message{
+		ID:      "msg_user_1",
+		From:    request.ID,
+		To:      []multiagent.AgentID{"task_manager"},
+		Type:    multiagent.MessageTypeRequest,
+		Content: "delete buy milk",
+		Context: map[string]interface{}{"conversation_id": "conv_alice"},
+	})
+	if err != nil {
+		t.Fatalf("RouteMessage: %v", err)
+	}
+	waitForReply(t, request)
+
+	if err := orch.AnswerQuestion(ctx, "conv_alice", orch.OpenRequest("conv_alice", 0).ID, "yes"); err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+	select {
+	case msg := <-resumed:
+		asked, ok := msg.Context["question_context"].(map[string]interface{})
+		if !ok || asked["proposed_action"] == nil {
+			t.Fatalf("resumed context = %+v, want the question's context", msg.Context)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("request was never resumed")
+	}
+}
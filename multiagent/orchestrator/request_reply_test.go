@@ -0,0 +1,102 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// inboxAgent passes every message it receives to inbox
+type inboxAgent struct {
+	stubAgent
+	inbox chan *multiagent.Message
+}
+
+func (a *inboxAgent) HandleMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.inbox <- msg
+	return nil, nil
+}
+
+// replierAgent answers each message with reply
+type replierAgent struct {
+	stubAgent
+	reply func(msg *multiagent.Message) (*multiagent.Message, error)
+}
+
+func (a *replierAgent) HandleMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	return a.reply(msg)
+}
+
+func sendRequest(t *testing.T, orch *DefaultOrchestrator, to multiagent.AgentID) (*multiagent.Message, *multiagent.Message) {
+	t.Helper()
+
+	requester := &inboxAgent{stubAgent: stubAgent{id: "requester"}, inbox: make(chan *multiagent.Message, 1)}
+	if err := orch.RegisterAgent(requester); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+
+	request := &multiagent.Message{
+		ID:      "req_1",
+		From:    "requester",
+		To:      []multiagent.AgentID{to},
+		Type:    multiagent.MessageTypeRequest,
+		Content: "what's next?",
+		Context: map[string]interface{}{multiagent.ContextExpectsReply: true},
+	}
+	if err := orch.RouteMessage(context.Background(), request); err != nil {
+		t.Fatalf("RouteMessage: %v", err)
+	}
+
+	select {
+	case reply := <-requester.inbox:
+		return request, reply
+	case <-time.After(2 * time.Second):
+		t.Fatal("requester never received a reply")
+		return nil, nil
+	}
+}
+
+func TestRequestReplyIsCorrelatedAndRouted(t *testing.T) {
+	orch, _ := newTestOrchestrator(t)
+
+	// The reply looks like a bare acknowledgment, which would normally be dropped
+	replier := &replierAgent{stubAgent: stubAgent{id: "replier"}, reply: func(msg *multiagent.Message) (*multiagent.Message, error) {
+		return &multiagent.Message{From: "replier", Type: multiagent.MessageTypeResponse, Content: "Processed"}, nil
+	}}
+	if err := orch.RegisterAgent(replier); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+
+	request, reply := sendRequest(t, orch, "replier")
+	if reply.ReplyTo != request.ID || reply.Type != multiagent.MessageTypeResponse || reply.Content != "Processed" {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+}
+
+func TestRequestHandlerErrorIsReturnedToRequester(t *testing.T) {
+	orch, _ := newTestOrchestrator(t)
+
+	replier := &replierAgent{stubAgent: stubAgent{id: "replier"}, reply: func(msg *multiagent.Message) (*multiagent.Message, error) {
+		return nil, errors.New("calendar unavailable")
+	}}
+	if err := orch.RegisterAgent(replier); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+
+	request, reply := sendRequest(t, orch, "replier")
+	if reply.ReplyTo != request.ID || reply.Type != multiagent.MessageTypeError || reply.Content != "calendar unavailable" {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+}
+
+func TestRequestToUnknownAgentFailsFast(t *testing.T) {
+	orch, _ := newTestOrchestrator(t)
+
+	request, reply := sendRequest(t, orch, "nobody")
+	if reply.ReplyTo != request.ID || reply.Type != multiagent.MessageTypeError {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+}
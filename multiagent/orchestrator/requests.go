@@ -0,0 +1,246 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// requestRetention is how long a finished request is remembered, so replies
+// arriving after it are recognised as late rather than misaddressed
+const requestRetention = 10 * time.Minute
+
+// RequestState is where a PendingRequest is in its lifecycle. A request
+// starts pending and ends in exactly one of the other, terminal states.
+type RequestState string
+
+const (
+	RequestPending   RequestState = "pending"
+	RequestAnswered  RequestState = "answered"
+	RequestTimedOut  RequestState = "timed_out"
+	RequestCancelled RequestState = "cancelled"
+)
+
+var (
+	// ErrRequestTimedOut is returned by Wait when the deadline passed first
+	ErrRequestTimedOut = errors.New("request timed out")
+	// ErrRequestCancelled is returned by Wait when the request was cancelled
+	ErrRequestCancelled = errors.New("request cancelled")
+)
+
+// PendingRequest is a caller outside the agents, such as a user, waiting on
+// the reply to a message it sent from ID. The first reply addressed to ID
+// answers it; later replies are dead-lettered, so each request is answered
+// at most once.
+type PendingRequest struct {
+	ID             multiagent.AgentID
+	ConversationID string
+	// Deadline is when Wait gives up; zero waits until answered or cancelled
+	Deadline time.Time
+
+	clock      multiagent.Clock
+	mu         sync.Mutex
+	state      RequestState
+	reply      string
+	finishedAt time.Time
+	done       chan struct{}
+}
+
+// State returns where the request is in its lifecycle
+func (r *PendingRequest) State() RequestState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// Done is closed once the request reaches a terminal state
+func (r *PendingRequest) Done() <-chan struct{} {
+	return r.done
+}
+
+// Wait blocks until the request is answered and returns the reply. It times
+// the request out at its deadline and cancels it when ctx is done.
+func (r *PendingRequest) Wait(ctx context.Context) (string, error) {
+	var deadline <-chan time.Time
+	if !r.Deadline.IsZero() {
+		timer := time.NewTimer(r.Deadline.Sub(r.clock.Now()))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	select {
+	case <-r.done:
+	case <-deadline:
+		r.finish(RequestTimedOut, "")
+	case <-ctx.Done():
+		r.finish(RequestCancelled, "")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch r.state {
+	case RequestAnswered:
+		return r.reply, nil
+	case RequestTimedOut:
+		return "", ErrRequestTimedOut
+	default:
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("%w: %w", ErrRequestCancelled, err)
+		}
+		return "", ErrRequestCancelled
+	}
+}
+
+// Cancel ends a pending request; it reports false if the request had
+// already finished
+func (r *PendingRequest) Cancel() bool {
+	return r.finish(RequestCancelled, "")
+}
+
+// finish moves a pending request to a terminal state, reporting false if it
+// had already left pending
+func (r *PendingRequest) finish(state RequestState, reply string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state != RequestPending {
+		return false
+	}
+	r.state = state
+	r.reply = reply
+	r.finishedAt = r.clock.Now()
+	close(r.done)
+	return true
+}
+
+// expired reports whether a finished request has been kept long enough
+func (r *PendingRequest) expired(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state != RequestPending && now.Sub(r.finishedAt) > requestRetention
+}
+
+// requestRegistry holds the requests callers are waiting on, and recently
+// finished ones
+type requestRegistry struct {
+	mu       sync.RWMutex
+	clock    multiagent.Clock
+	ids      multiagent.IDGenerator
+	requests map[multiagent.AgentID]*PendingRequest
+}
+
+func newRequestRegistry(clock multiagent.Clock, ids multiagent.IDGenerator) *requestRegistry {
+	return &requestRegistry{clock: clock, ids: ids, requests: make(map[multiagent.AgentID]*PendingRequest)}
+}
+
+func (r *requestRegistry) open(conversationID string, deadline time.Time) *PendingRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	for id, request := range r.requests {
+		if request.expired(now) {
+			delete(r.requests, id)
+		}
+	}
+
+	request := &PendingRequest{
+		ID:             multiagent.AgentID(r.ids.NewID("request")),
+		ConversationID: conversationID,
+		Deadline:       deadline,
+		clock:          r.clock,
+		state:          RequestPending,
+		done:           make(chan struct{}),
+	}
+	r.requests[request.ID] = request
+	return request
+}
+
+func (r *requestRegistry) get(id multiagent.AgentID) (*PendingRequest, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	request, ok := r.requests[id]
+	return request, ok
+}
+
+// pending returns the requests still waiting on a reply
+func (r *requestRegistry) pending() []*PendingRequest {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	requests := make([]*PendingRequest, 0, len(r.requests))
+	for _, request := range r.requests {
+		if request.State() == RequestPending {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+// cancelAll cancels every pending request
+func (r *requestRegistry) cancelAll() {
+	for _, request := range r.pending() {
+		request.Cancel()
+	}
+}
+
+// OpenRequest registers a caller waiting on a reply. Messages the caller
+// sends should come from the request's ID; the first reply addressed to it
+// answers the request, which times out after timeout (zero waits until
+// answered or cancelled).
+func (o *DefaultOrchestrator) OpenRequest(conversationID string, timeout time.Duration) *PendingRequest {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = o.now().Add(timeout)
+	}
+	request := o.requests.open(conversationID, deadline)
+	if conversationID != "" {
+		o.questions.track(conversationID, request.ID)
+	}
+	logger.Debug("Opened request", "request_id", request.ID, "conversation_id", conversationID, "deadline", deadline)
+	return request
+}
+
+// PendingRequests returns the requests still waiting on a reply
+func (o *DefaultOrchestrator) PendingRequests() []*PendingRequest {
+	return o.requests.pending()
+}
+
+// isRequest reports whether id belongs to a caller's request rather than an
+// agent
+func (o *DefaultOrchestrator) isRequest(id multiagent.AgentID) bool {
+	_, ok := o.requests.get(id)
+	return ok
+}
+
+// answerRequest delivers reply to the request it is addressed to. Interim
+// replies, such as an acknowledgment that specialists were consulted, leave
+// the request pending; replies to a finished request are dead-lettered.
+func (o *DefaultOrchestrator) answerRequest(ctx context.Context, reply *multiagent.Message) {
+	if len(reply.To) == 0 {
+		logger.ErrorContext(ctx, "Reply to a request has no recipients", logging.KeyMessageID, reply.ID)
+		return
+	}
+	requestID := reply.To[0]
+	request, ok := o.requests.get(requestID)
+	if !ok {
+		o.deadLetter(ctx, reply, requestID, DeadLetterUnknownAgent, "no such request")
+		return
+	}
+
+	if interim, _ := reply.Context[multiagent.ContextInterim].(bool); interim {
+		logger.DebugContext(ctx, "Request received an interim reply", "request_id", requestID)
+		return
+	}
+	if !request.finish(RequestAnswered, reply.Content) {
+		state := request.State()
+		logger.WarnContext(ctx, "Reply arrived after its request finished", "request_id", requestID, "state", state)
+		o.deadLetter(ctx, reply, requestID, DeadLetterLateReply, fmt.Sprintf("request %s", state))
+		o.metrics.orphanedResponse()
+		return
+	}
+	logger.DebugContext(ctx, "Request answered", "request_id", requestID, "content_length", len(reply.Content))
+}
@@ -0,0 +1,95 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// chattyAgent acknowledges a request, then answers it twice
+func chattyAgent(orch *DefaultOrchestrator) *replierAgent {
+	return &replierAgent{stubAgent: stubAgent{id: "chatty"}, reply: func(msg *multiagent.Message) (*multiagent.Message, error) {
+		for _, content := range []string{"first", "second"} {
+			orch.RouteMessage(context.Background(), &multiagent.Message{From: "chatty", To: []multiagent.AgentID{msg.From}, Type: multiagent.MessageTypeResponse, Content: content})
+		}
+		return &multiagent.Message{
+			From:    "chatty",
+			To:      []multiagent.AgentID{msg.From},
+			Type:    multiagent.MessageTypeResponse,
+			Content: "working on it",
+			Context: map[string]interface{}{multiagent.ContextInterim: true},
+		}, nil
+	}}
+}
+
+func TestRequestIsAnsweredAtMostOnce(t *testing.T) {
+	ctx := context.Background()
+	orch, _ := newTestOrchestrator(t)
+	if err := orch.RegisterAgent(chattyAgent(orch)); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+
+	request := orch.OpenRequest("conv_alice", time.Minute)
+	if err := orch.RouteMessage(ctx, &multiagent.Message{From: request.ID, To: []multiagent.AgentID{"chatty"}, Type: multiagent.MessageTypeRequest, Content: "hello"}); err != nil {
+		t.Fatalf("RouteMessage: %v", err)
+	}
+	if got := waitForReply(t, request); got != "first" {
+		t.Fatalf("reply = %q, want the first non-interim reply", got)
+	}
+	if request.State() != RequestAnswered {
+		t.Fatalf("state = %s, want %s", request.State(), RequestAnswered)
+	}
+
+	// The second reply is dead-lettered as late
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		letters, err := orch.ListDeadLetters(ctx, 0)
+		if err != nil {
+			t.Fatalf("ListDeadLetters: %v", err)
+		}
+		if len(letters) == 1 && letters[0].Reason == DeadLetterLateReply && letters[0].Message.Content == "second" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("dead letters = %+v, want the second reply as late", letters)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRequestTimesOutAndCancels(t *testing.T) {
+	orch, _ := newTestOrchestrator(t)
+
+	request := orch.OpenRequest("conv_alice", 20*time.Millisecond)
+	if _, err := request.Wait(context.Background()); !errors.Is(err, ErrRequestTimedOut) {
+		t.Fatalf("Wait = %v, want %v", err, ErrRequestTimedOut)
+	}
+	if request.Cancel() {
+		t.Fatal("cancelled a request that had already timed out")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	request = orch.OpenRequest("conv_alice", 0)
+	if _, err := request.Wait(ctx); !errors.Is(err, ErrRequestCancelled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait = %v, want a cancellation", err)
+	}
+	if request.State() != RequestCancelled {
+		t.Fatalf("state = %s, want %s", request.State(), RequestCancelled)
+	}
+
+	// Stopping the orchestrator cancels whoever is still waiting
+	request = orch.OpenRequest("conv_alice", 0)
+	if err := orch.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if _, err := request.Wait(context.Background()); !errors.Is(err, ErrRequestCancelled) {
+		t.Fatalf("Wait after Stop = %v, want %v", err, ErrRequestCancelled)
+	}
+	if pending := orch.PendingRequests(); len(pending) != 0 {
+		t.Fatalf("pending requests after Stop = %d", len(pending))
+	}
+}
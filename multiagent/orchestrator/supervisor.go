@@ -0,0 +1,267 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// SupervisorConfig controls how crashed agents are restarted
+type SupervisorConfig struct {
+	// Disabled turns supervision off
+	Disabled bool
+	// CheckInterval is how often agent states are inspected (default 1s)
+	CheckInterval time.Duration
+	// InitialBackoff is the delay before the first restart (default 1s); it
+	// doubles with each consecutive crash up to MaxBackoff (default 1m)
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// StableAfter is how long an agent must run after a restart before its
+	// backoff resets (default 5m)
+	StableAfter time.Duration
+	// HeartbeatInterval is how often agents are pinged (default 15s, negative
+	// to not ping them)
+	HeartbeatInterval time.Duration
+	// HeartbeatTimeout is how long a ping may take (default 5s)
+	HeartbeatTimeout time.Duration
+	// MaxMissedHeartbeats is how many pings in a row an agent may miss
+	// before it is marked unresponsive and restarted (default 3)
+	MaxMissedHeartbeats int
+}
+
+// supervisor tracks crash and restart history per agent
+type supervisor struct {
+	config SupervisorConfig
+	mu     sync.Mutex
+	agents map[multiagent.AgentID]*supervisedAgent
+}
+
+type supervisedAgent struct {
+	restarts    int // Successful restarts in total
+	consecutive int // Crashes since the agent last ran stably
+	pending     bool
+	restartAt   time.Time
+	lastRestart time.Time
+
+	lastHeartbeat    time.Time
+	missedHeartbeats int
+	unresponsive     bool // Missed MaxMissedHeartbeats in a row
+}
+
+func newSupervisor(config SupervisorConfig) *supervisor {
+	if config.CheckInterval == 0 {
+		config.CheckInterval = time.Second
+	}
+	if config.InitialBackoff == 0 {
+		config.InitialBackoff = time.Second
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = time.Minute
+	}
+	if config.StableAfter == 0 {
+		config.StableAfter = 5 * time.Minute
+	}
+	if config.HeartbeatInterval == 0 {
+		config.HeartbeatInterval = 15 * time.Second
+	}
+	if config.HeartbeatTimeout <= 0 {
+		config.HeartbeatTimeout = 5 * time.Second
+	}
+	if config.MaxMissedHeartbeats <= 0 {
+		config.MaxMissedHeartbeats = 3
+	}
+
+	return &supervisor{
+		config: config,
+		agents: make(map[multiagent.AgentID]*supervisedAgent),
+	}
+}
+
+// backoff returns the restart delay after the nth consecutive crash
+func (s *supervisor) backoff(consecutive int) time.Duration {
+	delay := s.config.InitialBackoff
+	for i := 1; i < consecutive && delay < s.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > s.config.MaxBackoff {
+		delay = s.config.MaxBackoff
+	}
+	return delay
+}
+
+// RestartCounts returns how many times the supervisor restarted each agent
+func (o *DefaultOrchestrator) RestartCounts() map[multiagent.AgentID]int {
+	o.supervisor.mu.Lock()
+	defer o.supervisor.mu.Unlock()
+
+	counts := make(map[multiagent.AgentID]int)
+	for id, agent := range o.supervisor.agents {
+		if agent.restarts > 0 {
+			counts[id] = agent.restarts
+		}
+	}
+	return counts
+}
+
+// reportCrash schedules a restart of agentID after its backoff; crashes
+// reported while a restart is already pending are folded into it
+func (o *DefaultOrchestrator) reportCrash(agentID multiagent.AgentID, reason string) {
+	if o.supervisor.config.Disabled {
+		return
+	}
+
+	o.supervisor.mu.Lock()
+	agent := o.supervisor.agents[agentID]
+	if agent == nil {
+		agent = &supervisedAgent{}
+		o.supervisor.agents[agentID] = agent
+	}
+	if agent.pending {
+		o.supervisor.mu.Unlock()
+		return
+	}
+	if !agent.lastRestart.IsZero() && o.now().Sub(agent.lastRestart) > o.supervisor.config.StableAfter {
+		agent.consecutive = 0
+	}
+	agent.consecutive++
+	delay := o.supervisor.backoff(agent.consecutive)
+	agent.pending = true
+	agent.restartAt = o.now().Add(delay)
+	o.supervisor.mu.Unlock()
+
+	logger.Warn("Agent crashed", logging.KeyAgentID, agentID, "reason", reason, "restart_in", delay)
+	o.emitAgentEvent(multiagent.EventAgentCrashed, agentID, map[string]interface{}{
+		"reason":     reason,
+		"restart_in": delay.String(),
+	})
+}
+
+// supervisorLoop restarts agents that panicked or report an error state
+func (o *DefaultOrchestrator) supervisorLoop(ctx context.Context) {
+	defer o.wg.Done()
+
+	ticker := time.NewTicker(o.supervisor.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			o.superviseAgents(ctx)
+
+		case <-o.stopChan:
+			return
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (o *DefaultOrchestrator) superviseAgents(ctx context.Context) {
+	o.mu.RLock()
+	agents := make(map[multiagent.AgentID]multiagent.Agent, len(o.agents))
+	for id, agent := range o.agents {
+		agents[id] = agent
+	}
+	o.mu.RUnlock()
+
+	for id, agent := range agents {
+		// Reading the state of an agent that stopped answering pings could
+		// block; its crash is already reported
+		if _, unresponsive := o.heartbeatState(id); unresponsive {
+			continue
+		}
+		if agent.GetState().Status == multiagent.AgentStatusError {
+			o.reportCrash(id, "agent reported error state")
+		}
+	}
+
+	now := o.now()
+	var due []multiagent.AgentID
+	o.supervisor.mu.Lock()
+	for id, supervised := range o.supervisor.agents {
+		if _, registered := agents[id]; !registered {
+			// Unregistered on purpose; forget its history
+			delete(o.supervisor.agents, id)
+			continue
+		}
+		if supervised.pending && !now.Before(supervised.restartAt) {
+			due = append(due, id)
+		}
+	}
+	o.supervisor.mu.Unlock()
+
+	for _, id := range due {
+		o.restartAgent(ctx, agents[id])
+	}
+}
+
+// restartAgent stops, reinitializes, and restarts an agent, then re-registers
+// its capabilities; a failed restart is retried with a longer backoff
+func (o *DefaultOrchestrator) restartAgent(ctx context.Context, agent multiagent.Agent) {
+	agentID := agent.ID()
+	logger.InfoContext(ctx, "Restarting agent", logging.KeyAgentID, agentID)
+
+	if err := agent.Stop(ctx); err != nil {
+		logger.WarnContext(ctx, "Error stopping crashed agent", logging.KeyAgentID, agentID, "error", err)
+	}
+	err := agent.Initialize(ctx)
+	if err == nil {
+		err = agent.Start(ctx)
+	}
+
+	o.supervisor.mu.Lock()
+	supervised := o.supervisor.agents[agentID]
+	if supervised == nil {
+		o.supervisor.mu.Unlock()
+		return
+	}
+	supervised.pending = false
+	if err == nil {
+		supervised.restarts++
+		supervised.lastRestart = o.now()
+		supervised.lastHeartbeat = supervised.lastRestart
+		supervised.missedHeartbeats = 0
+		supervised.unresponsive = false
+	}
+	restarts := supervised.restarts
+	o.supervisor.mu.Unlock()
+
+	if err != nil {
+		o.reportCrash(agentID, fmt.Sprintf("restart failed: %v", err))
+		return
+	}
+
+	o.mu.Lock()
+	if _, registered := o.agents[agentID]; registered {
+		o.agents[agentID] = agent
+		o.capabilities.Register(agent)
+	}
+	o.mu.Unlock()
+
+	logger.InfoContext(ctx, "Agent restarted", logging.KeyAgentID, agentID, "restarts", restarts)
+	o.emitAgentEvent(multiagent.EventAgentRestarted, agentID, map[string]interface{}{
+		"restarts": restarts,
+	})
+}
+
+func (o *DefaultOrchestrator) emitAgentEvent(eventType multiagent.EventType, agentID multiagent.AgentID, data map[string]interface{}) {
+	data["agent_id"] = agentID
+	event := &multiagent.Event{
+		ID:        o.newID("event"),
+		Type:      eventType,
+		Source:    "orchestrator",
+		Timestamp: o.now(),
+		Data:      data,
+	}
+
+	select {
+	case o.eventQueue <- event:
+	default:
+		logger.Warn("Event queue full, dropping event", "event_type", eventType, logging.KeyAgentID, agentID)
+	}
+}
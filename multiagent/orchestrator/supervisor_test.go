@@ -0,0 +1,118 @@
+package orchestrator
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+// flakyAgent reports whatever status it is given and comes back idle on Start
+type flakyAgent struct {
+	stubAgent
+	stateMu sync.Mutex
+	status  multiagent.AgentStatus
+	starts  int
+}
+
+func (a *flakyAgent) GetState() multiagent.AgentState {
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+	return multiagent.AgentState{Status: a.status}
+}
+
+func (a *flakyAgent) Start(ctx context.Context) error {
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+	a.status = multiagent.AgentStatusIdle
+	a.starts++
+	return nil
+}
+
+func (a *flakyAgent) setStatus(status multiagent.AgentStatus) {
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+	a.status = status
+}
+
+func newSupervisedOrchestrator(t *testing.T, agent multiagent.Agent) *DefaultOrchestrator {
+	t.Helper()
+
+	store, err := memory.NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	orch := NewOrchestrator(OrchestratorConfig{
+		MemoryStore: store,
+		Supervisor:  SupervisorConfig{CheckInterval: 5 * time.Millisecond, InitialBackoff: 10 * time.Millisecond},
+	})
+	if err := orch.RegisterAgent(agent); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	if err := orch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { orch.Stop(context.Background()) })
+	return orch
+}
+
+func waitForRestarts(t *testing.T, orch *DefaultOrchestrator, agentID multiagent.AgentID, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if orch.RestartCounts()[agentID] >= want {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("agent %s restarted %d times, want %d", agentID, orch.RestartCounts()[agentID], want)
+}
+
+func TestSupervisorRestartsAgentInErrorState(t *testing.T) {
+	agent := &flakyAgent{stubAgent: stubAgent{id: "flaky"}, status: multiagent.AgentStatusIdle}
+	orch := newSupervisedOrchestrator(t, agent)
+
+	agent.setStatus(multiagent.AgentStatusError)
+	waitForRestarts(t, orch, "flaky", 1)
+
+	if status := agent.GetState().Status; status != multiagent.AgentStatusIdle {
+		t.Fatalf("expected restarted agent to be idle, got %s", status)
+	}
+	if health := orch.GetSystemHealth(); health.AgentRestarts["flaky"] != 1 {
+		t.Fatalf("expected restart count in health, got %v", health.AgentRestarts)
+	}
+}
+
+func TestSupervisorRestartsAgentAfterHandlerPanic(t *testing.T) {
+	agent := &flakyAgent{stubAgent: stubAgent{id: "flaky", handle: func(ctx context.Context, msg *multiagent.Message) error {
+		panic("nil map write")
+	}}, status: multiagent.AgentStatusIdle}
+	orch := newSupervisedOrchestrator(t, agent)
+
+	err := orch.RouteMessage(context.Background(), &multiagent.Message{
+		From:    "user",
+		To:      []multiagent.AgentID{"flaky"},
+		Type:    multiagent.MessageTypeNotification,
+		Content: "boom",
+	})
+	if err != nil {
+		t.Fatalf("RouteMessage: %v", err)
+	}
+	waitForRestarts(t, orch, "flaky", 1)
+}
+
+func TestSupervisorBackoffDoublesUpToMax(t *testing.T) {
+	s := newSupervisor(SupervisorConfig{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second})
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, expected := range want {
+		if got := s.backoff(i + 1); got != expected {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, expected)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// CancelTask stops a task that has not finished yet. An in-flight attempt has
+// its context cancelled, and tasks depending on it fail.
+func (o *DefaultOrchestrator) CancelTask(ctx context.Context, taskID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	task := o.lookupTask(ctx, taskID)
+	if task == nil {
+		return fmt.Errorf("task %s not found", taskID)
+	}
+	if isTerminal(task.Status) {
+		return fmt.Errorf("task %s is already %s", taskID, task.Status)
+	}
+	o.tasks[task.ID] = task
+
+	o.abortAttempt(task.ID)
+	o.finishTask(ctx, task, multiagent.TaskStatusCancelled, "cancelled", multiagent.EventTaskCancelled)
+	logger.InfoContext(ctx, "Cancelled task", logging.KeyTaskID, taskID)
+	return nil
+}
+
+// Task control helpers; callers hold o.mu unless noted
+
+// transition changes a task's status and records it in the task history
+func (o *DefaultOrchestrator) transition(task *multiagent.Task, status multiagent.TaskStatus, errMsg string) {
+	task.Status = status
+	if errMsg != "" {
+		task.Error = errMsg
+	}
+	task.History = append(task.History, multiagent.TaskTransition{
+		Status:  status,
+		Agent:   task.Assignee,
+		Attempt: task.Attempts,
+		Error:   errMsg,
+		At:      o.now(),
+	})
+}
+
+// finishTask moves a task to a terminal status, persists it, emits eventType,
+// and settles anything waiting on it
+func (o *DefaultOrchestrator) finishTask(ctx context.Context, task *multiagent.Task, status multiagent.TaskStatus, errMsg string, eventType multiagent.EventType) {
+	now := o.now()
+	task.CompletedAt = &now
+	task.NextAttemptAt = nil
+	o.transition(task, status, errMsg)
+	if task.Assignee != "" && status != multiagent.TaskStatusCancelled {
+		o.capabilities.RecordOutcome(task.Assignee, status == multiagent.TaskStatusCompleted)
+	}
+	o.persistTask(ctx, task)
+	o.emitTaskEvent(eventType, task)
+	o.releaseDependents(ctx, task.ID)
+}
+
+// markTaskStarted records that the assignee began an attempt; it returns false
+// when the attempt is stale or the task was cancelled while queued.
+// Called without o.mu held.
+func (o *DefaultOrchestrator) markTaskStarted(ctx context.Context, taskID string, attempt int, cancel context.CancelFunc) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	task, exists := o.tasks[taskID]
+	if !exists {
+		return true
+	}
+	if isTerminal(task.Status) || (attempt != 0 && attempt != task.Attempts) {
+		return false
+	}
+
+	now := o.now()
+	task.StartedAt = &now
+	o.taskCancels[taskID] = cancel
+	o.transition(task, multiagent.TaskStatusInProgress, "")
+	o.persistTask(ctx, task)
+	return true
+}
+
+// markTaskFinished records the outcome of an attempt. Called without o.mu held.
+func (o *DefaultOrchestrator) markTaskFinished(ctx context.Context, taskID string, attempt int, response *multiagent.Message, handleErr error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	task, exists := o.tasks[taskID]
+	if !exists || (attempt != 0 && attempt != task.Attempts) {
+		return
+	}
+	// A timed-out attempt may still report back after a retry was scheduled
+	if task.Status != multiagent.TaskStatusInProgress && task.Status != multiagent.TaskStatusAssigned {
+		return
+	}
+	delete(o.taskCancels, taskID)
+	delete(o.attemptStarted, taskID)
+
+	if handleErr != nil {
+		o.failAttempt(ctx, task, handleErr.Error(), multiagent.EventTaskFailed)
+		return
+	}
+
+	if response != nil {
+		if task.Output == nil {
+			task.Output = make(map[string]interface{})
+		}
+		task.Output["response"] = response.Content
+	}
+	task.Error = ""
+	o.finishTask(ctx, task, multiagent.TaskStatusCompleted, "", multiagent.EventTaskCompleted)
+}
+
+// failAttempt schedules a retry if the task's policy allows one, otherwise
+// fails the task; eventType is emitted on final failure
+func (o *DefaultOrchestrator) failAttempt(ctx context.Context, task *multiagent.Task, reason string, eventType multiagent.EventType) {
+	policy := task.Retry
+	if policy == nil {
+		policy = o.retryPolicy
+	}
+
+	delay, nextAgent, ok := o.nextAttempt(task, policy)
+	if !ok {
+		o.finishTask(ctx, task, multiagent.TaskStatusFailed, reason, eventType)
+		logger.WarnContext(ctx, "Task failed", logging.KeyTaskID, task.ID, "attempts", task.Attempts, "reason", reason)
+		return
+	}
+
+	next := o.now().Add(delay)
+	task.NextAttemptAt = &next
+	o.transition(task, multiagent.TaskStatusPending, reason)
+	if nextAgent != "" {
+		task.Assignee = nextAgent
+	}
+	o.persistTask(ctx, task)
+	o.emitTaskEvent(multiagent.EventTaskRetrying, task)
+	logger.InfoContext(ctx, "Retrying task", logging.KeyTaskID, task.ID, logging.KeyAgentID, task.Assignee, "delay", delay, "attempt", task.Attempts, "reason", reason)
+
+	taskID := task.ID
+	time.AfterFunc(delay, func() { o.retryTask(ctx, taskID) })
+}
+
+// nextAttempt returns the backoff and, when switching to the fallback agent,
+// the agent for the next attempt
+func (o *DefaultOrchestrator) nextAttempt(task *multiagent.Task, policy *multiagent.RetryPolicy) (time.Duration, multiagent.AgentID, bool) {
+	if policy == nil {
+		return 0, "", false
+	}
+	if task.Deadline != nil && o.now().After(*task.Deadline) {
+		return 0, "", false
+	}
+
+	delay := backoffDelay(policy, task.Attempts)
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	if task.Attempts < maxAttempts && task.Assignee != policy.FallbackAgent {
+		return delay, "", true
+	}
+	if policy.FallbackAgent != "" && task.Assignee != policy.FallbackAgent {
+		if _, registered := o.agents[policy.FallbackAgent]; registered {
+			return delay, policy.FallbackAgent, true
+		}
+	}
+	return 0, "", false
+}
+
+// retryTask dispatches a scheduled retry. Called without o.mu held.
+func (o *DefaultOrchestrator) retryTask(ctx context.Context, taskID string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.running.Load() {
+		return
+	}
+	task, exists := o.tasks[taskID]
+	if !exists || task.Status != multiagent.TaskStatusPending || task.NextAttemptAt == nil {
+		return
+	}
+	if !o.agentAlive(task.Assignee) {
+		// Leave it for redispatchStrandedTasks
+		task.NextAttemptAt = nil
+		o.persistTask(ctx, task)
+		return
+	}
+
+	o.transition(task, multiagent.TaskStatusAssigned, "")
+	if err := o.dispatchTask(ctx, task); err != nil {
+		o.failAttempt(ctx, task, fmt.Sprintf("failed to dispatch retry: %v", err), multiagent.EventTaskFailed)
+		return
+	}
+	o.persistTask(ctx, task)
+}
+
+// taskMonitor enforces task deadlines and per-attempt timeouts
+func (o *DefaultOrchestrator) taskMonitor(ctx context.Context) {
+	defer o.wg.Done()
+
+	ticker := time.NewTicker(o.taskCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			o.checkTaskTimeouts(ctx)
+
+		case <-o.stopChan:
+			return
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (o *DefaultOrchestrator) checkTaskTimeouts(ctx context.Context) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.now()
+	for _, task := range o.tasks {
+		if isTerminal(task.Status) {
+			continue
+		}
+
+		if task.Deadline != nil && now.After(*task.Deadline) {
+			o.abortAttempt(task.ID)
+			o.finishTask(ctx, task, multiagent.TaskStatusFailed, "deadline exceeded", multiagent.EventTaskTimedOut)
+			logger.WarnContext(ctx, "Task missed its deadline", logging.KeyTaskID, task.ID)
+			continue
+		}
+
+		if task.Timeout <= 0 {
+			continue
+		}
+		if task.Status != multiagent.TaskStatusAssigned && task.Status != multiagent.TaskStatusInProgress {
+			continue
+		}
+		started, ok := o.attemptStarted[task.ID]
+		if !ok || now.Sub(started) < task.Timeout {
+			continue
+		}
+
+		o.abortAttempt(task.ID)
+		o.failAttempt(ctx, task, fmt.Sprintf("attempt %d timed out after %s", task.Attempts, task.Timeout), multiagent.EventTaskTimedOut)
+	}
+}
+
+// abortAttempt cancels the context of an in-flight attempt
+func (o *DefaultOrchestrator) abortAttempt(taskID string) {
+	if cancel, ok := o.taskCancels[taskID]; ok {
+		cancel()
+		delete(o.taskCancels, taskID)
+	}
+	delete(o.attemptStarted, taskID)
+}
+
+// emitTaskEvent queues a task event without blocking
+func (o *DefaultOrchestrator) emitTaskEvent(eventType multiagent.EventType, task *multiagent.Task) {
+	event := &multiagent.Event{
+		ID:        o.newID("event"),
+		Type:      eventType,
+		Source:    "orchestrator",
+		Timestamp: o.now(),
+		Data: map[string]interface{}{
+			"task_id":  task.ID,
+			"status":   task.Status,
+			"assignee": task.Assignee,
+			"attempt":  task.Attempts,
+			"error":    task.Error,
+		},
+	}
+
+	select {
+	case o.eventQueue <- event:
+	default:
+		logger.Warn("Event queue full, dropping event", "event_type", eventType, logging.KeyTaskID, task.ID)
+	}
+}
+
+// backoffDelay returns the wait before the attempt after `attempts`
+func backoffDelay(policy *multiagent.RetryPolicy, attempts int) time.Duration {
+	multiplier := policy.BackoffMultiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	exponent := attempts - 1
+	if exponent < 0 {
+		exponent = 0
+	}
+	delay := time.Duration(float64(policy.Backoff) * math.Pow(multiplier, float64(exponent)))
+	if policy.MaxBackoff > 0 && delay > policy.MaxBackoff {
+		delay = policy.MaxBackoff
+	}
+	return delay
+}
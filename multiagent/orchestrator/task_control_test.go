@@ -0,0 +1,98 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+func waitForTaskStatus(t *testing.T, orch *DefaultOrchestrator, taskID string, want multiagent.TaskStatus) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err := orch.GetTaskStatus(context.Background(), taskID)
+		if err == nil && status == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task %s: expected status %s, got %q (%v)", taskID, want, status, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRetryFallsBackToAnotherAgent(t *testing.T) {
+	ctx := context.Background()
+	orch, primary := newTestOrchestrator(t)
+	primary.handle = func(context.Context, *multiagent.Message) error {
+		return errors.New("model unavailable")
+	}
+	fallback := &stubAgent{id: "backup"}
+	if err := orch.RegisterAgent(fallback); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+
+	const taskID = "flaky"
+	_, err := orch.AssignTask(ctx, multiagent.Task{
+		ID:       taskID,
+		Type:     "stub",
+		Assignee: "worker",
+		Retry: &multiagent.RetryPolicy{
+			MaxAttempts:   2,
+			Backoff:       time.Millisecond,
+			FallbackAgent: "backup",
+		},
+	})
+	if err != nil {
+		t.Fatalf("AssignTask: %v", err)
+	}
+	waitForTaskStatus(t, orch, taskID, multiagent.TaskStatusCompleted)
+
+	if got := len(primary.order()); got != 2 {
+		t.Fatalf("expected 2 attempts on primary, got %d", got)
+	}
+	if got := len(fallback.order()); got != 1 {
+		t.Fatalf("expected 1 attempt on fallback, got %d", got)
+	}
+
+	orch.mu.RLock()
+	task := *orch.tasks[taskID]
+	orch.mu.RUnlock()
+	if task.Attempts != 3 || task.Assignee != "backup" {
+		t.Fatalf("expected 3 attempts ending on backup, got %d on %s", task.Attempts, task.Assignee)
+	}
+	if last := task.History[len(task.History)-1]; last.Status != multiagent.TaskStatusCompleted {
+		t.Fatalf("expected history to end with completion, got %+v", last)
+	}
+}
+
+func TestTimeoutAndCancel(t *testing.T) {
+	ctx := context.Background()
+	orch, agent := newTestOrchestrator(t)
+	agent.handle = func(ctx context.Context, _ *multiagent.Message) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	if _, err := orch.AssignTask(ctx, multiagent.Task{ID: "slow", Type: "stub", Timeout: 20 * time.Millisecond}); err != nil {
+		t.Fatalf("AssignTask: %v", err)
+	}
+	waitForTaskStatus(t, orch, "slow", multiagent.TaskStatusFailed)
+
+	const stuckID = "stuck"
+	if _, err := orch.AssignTask(ctx, multiagent.Task{ID: stuckID, Type: "stub"}); err != nil {
+		t.Fatalf("AssignTask: %v", err)
+	}
+	waitForTaskStatus(t, orch, stuckID, multiagent.TaskStatusInProgress)
+	if err := orch.CancelTask(ctx, stuckID); err != nil {
+		t.Fatalf("CancelTask: %v", err)
+	}
+	waitForTaskStatus(t, orch, stuckID, multiagent.TaskStatusCancelled)
+
+	if err := orch.CancelTask(ctx, stuckID); err == nil {
+		t.Fatal("expected error cancelling a finished task")
+	}
+}
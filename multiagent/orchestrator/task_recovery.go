@@ -0,0 +1,173 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// Task lifecycle helpers. Callers that touch o.tasks hold o.mu; none of these
+// acquire it themselves except redispatchStrandedTasks, which runs outside
+// any orchestrator lock.
+
+// persistTask writes a task to the task store, logging rather than failing
+func (o *DefaultOrchestrator) persistTask(ctx context.Context, task *multiagent.Task) {
+	if o.taskStore == nil {
+		return
+	}
+	if err := o.taskStore.Save(ctx, task); err != nil {
+		logger.ErrorContext(ctx, "Failed to persist task", logging.KeyTaskID, task.ID, "error", err)
+	}
+}
+
+// dispatchTask sends the execute-task request to the task's assignee,
+// starting a new attempt
+func (o *DefaultOrchestrator) dispatchTask(ctx context.Context, task *multiagent.Task) error {
+	task.Attempts++
+	task.NextAttemptAt = nil
+	o.attemptStarted[task.ID] = o.now()
+
+	taskMsg := &multiagent.Message{
+		ID:        o.newID("msg"),
+		From:      multiagent.AgentID("orchestrator"),
+		To:        []multiagent.AgentID{task.Assignee},
+		Type:      multiagent.MessageTypeRequest,
+		Content:   fmt.Sprintf("Execute task %s: %s", task.ID, task.Description),
+		Context:   map[string]interface{}{"task_id": task.ID, "attempt": task.Attempts},
+		Priority:  task.Priority,
+		Timestamp: o.now(),
+	}
+	if userID := o.users.forTask(task); userID != "" {
+		taskMsg.Context[multiagent.ContextUserID] = userID
+		// Naming the conversation lets the assignee's own messages on it act
+		// for the user too
+		if conversationID, _ := task.Input["conversation_id"].(string); conversationID != "" {
+			taskMsg.Context["conversation_id"] = conversationID
+		}
+	}
+	return o.RouteMessage(ctx, taskMsg)
+}
+
+// restoreTasks reloads unfinished tasks and re-dispatches those whose agents
+// are alive; the rest stay pending until redispatchStrandedTasks finds an agent
+func (o *DefaultOrchestrator) restoreTasks(ctx context.Context) {
+	if o.taskStore == nil {
+		return
+	}
+
+	tasks, err := o.taskStore.List(ctx,
+		multiagent.TaskStatusPending,
+		multiagent.TaskStatusWaiting,
+		multiagent.TaskStatusAssigned,
+		multiagent.TaskStatusInProgress,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to restore tasks", "error", err)
+		return
+	}
+
+	redispatched := 0
+	for _, task := range tasks {
+		if _, exists := o.tasks[task.ID]; exists {
+			continue
+		}
+		o.tasks[task.ID] = task
+
+		if task.Status == multiagent.TaskStatusWaiting {
+			continue
+		}
+		if o.agentAlive(task.Assignee) {
+			o.transition(task, multiagent.TaskStatusAssigned, "")
+			if err := o.dispatchTask(ctx, task); err != nil {
+				logger.ErrorContext(ctx, "Failed to re-dispatch task", logging.KeyTaskID, task.ID, "error", err)
+				o.transition(task, multiagent.TaskStatusPending, err.Error())
+			} else {
+				redispatched++
+			}
+		} else {
+			o.transition(task, multiagent.TaskStatusPending, "")
+		}
+		o.persistTask(ctx, task)
+	}
+
+	// Dependencies may have finished while the waiting tasks were on disk
+	for _, task := range tasks {
+		if task.Status == multiagent.TaskStatusWaiting {
+			o.releaseTask(ctx, task)
+		}
+	}
+
+	if len(tasks) > 0 {
+		logger.InfoContext(ctx, "Restored unfinished tasks",
+			"tasks", len(tasks), "redispatched", redispatched, "waiting", len(tasks)-redispatched)
+	}
+}
+
+// redispatchStrandedTasks retries pending tasks once their assignee (or any
+// capable agent, if the assignee is gone) is alive
+func (o *DefaultOrchestrator) redispatchStrandedTasks(ctx context.Context) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, task := range o.tasks {
+		if task.Status != multiagent.TaskStatusPending {
+			continue
+		}
+		if task.NextAttemptAt != nil && o.now().Before(*task.NextAttemptAt) {
+			continue
+		}
+
+		if _, registered := o.agents[task.Assignee]; !registered {
+			agent, err := o.findBestAgent(*task)
+			if err != nil {
+				continue
+			}
+			task.Assignee = agent.ID()
+		}
+		if !o.agentAlive(task.Assignee) {
+			continue
+		}
+
+		o.transition(task, multiagent.TaskStatusAssigned, "")
+		if err := o.dispatchTask(ctx, task); err != nil {
+			logger.ErrorContext(ctx, "Failed to re-dispatch task", logging.KeyTaskID, task.ID, "error", err)
+			o.transition(task, multiagent.TaskStatusPending, err.Error())
+			continue
+		}
+		logger.InfoContext(ctx, "Re-dispatched stranded task", logging.KeyTaskID, task.ID, logging.KeyAgentID, task.Assignee)
+		o.persistTask(ctx, task)
+	}
+}
+
+// agentAlive reports whether an agent is registered and accepting work
+func (o *DefaultOrchestrator) agentAlive(agentID multiagent.AgentID) bool {
+	agent, exists := o.agents[agentID]
+	if !exists {
+		return false
+	}
+	switch agent.GetState().Status {
+	case multiagent.AgentStatusIdle, multiagent.AgentStatusBusy:
+		return true
+	}
+	return false
+}
+
+// taskAttemptFromMessage returns the task ID and attempt of an orchestrator task request
+func taskAttemptFromMessage(msg *multiagent.Message) (string, int) {
+	if msg.From != "orchestrator" || msg.Type != multiagent.MessageTypeRequest || msg.Context == nil {
+		return "", 0
+	}
+	taskID, _ := msg.Context["task_id"].(string)
+	attempt, _ := msg.Context["attempt"].(int)
+	return taskID, attempt
+}
+
+func isTerminal(status multiagent.TaskStatus) bool {
+	switch status {
+	case multiagent.TaskStatusCompleted, multiagent.TaskStatusFailed, multiagent.TaskStatusCancelled:
+		return true
+	}
+	return false
+}
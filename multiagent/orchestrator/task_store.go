@@ -0,0 +1,111 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// TaskStore persists orchestrator tasks so they survive restarts
+type TaskStore interface {
+	Save(ctx context.Context, task *multiagent.Task) error
+	Get(ctx context.Context, taskID string) (*multiagent.Task, error)
+	// List returns tasks in any of the given statuses, or all tasks if none are given
+	List(ctx context.Context, statuses ...multiagent.TaskStatus) ([]*multiagent.Task, error)
+	Delete(ctx context.Context, taskID string) error
+}
+
+const (
+	taskKeyPrefix = "orchestrator:task:"
+	// maxListedTasks bounds a single List scan
+	maxListedTasks = 10000
+)
+
+// MemoryTaskStore implements TaskStore on top of a MemoryStore
+type MemoryTaskStore struct {
+	memoryStore multiagent.MemoryStore
+}
+
+// NewMemoryTaskStore creates a task store that keeps tasks under orchestrator:task:<id>
+func NewMemoryTaskStore(memoryStore multiagent.MemoryStore) *MemoryTaskStore {
+	return &MemoryTaskStore{memoryStore: memoryStore}
+}
+
+// Save writes the task, replacing any previous version
+func (s *MemoryTaskStore) Save(ctx context.Context, task *multiagent.Task) error {
+	if task.ID == "" {
+		return fmt.Errorf("task ID is required")
+	}
+	if err := s.memoryStore.Store(ctx, taskKeyPrefix+task.ID, task); err != nil {
+		return fmt.Errorf("failed to save task %s: %w", task.ID, err)
+	}
+	return nil
+}
+
+// Get loads a task by ID
+func (s *MemoryTaskStore) Get(ctx context.Context, taskID string) (*multiagent.Task, error) {
+	value, err := s.memoryStore.Get(ctx, taskKeyPrefix+taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task %s not found: %w", taskID, err)
+	}
+	return decodeTask(value)
+}
+
+// List loads stored tasks filtered by status, oldest first
+func (s *MemoryTaskStore) List(ctx context.Context, statuses ...multiagent.TaskStatus) ([]*multiagent.Task, error) {
+	keys, err := s.memoryStore.List(ctx, taskKeyPrefix, maxListedTasks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	wanted := make(map[multiagent.TaskStatus]bool, len(statuses))
+	for _, status := range statuses {
+		wanted[status] = true
+	}
+
+	tasks := make([]*multiagent.Task, 0, len(keys))
+	for _, key := range keys {
+		task, err := s.Get(ctx, strings.TrimPrefix(key, taskKeyPrefix))
+		if err != nil {
+			continue
+		}
+		if len(wanted) > 0 && !wanted[task.Status] {
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].CreatedAt.Before(tasks[j].CreatedAt) })
+	return tasks, nil
+}
+
+// Delete removes a task
+func (s *MemoryTaskStore) Delete(ctx context.Context, taskID string) error {
+	return s.memoryStore.Delete(ctx, taskKeyPrefix+taskID)
+}
+
+// decodeTask converts a stored value back into a Task; JSON-backed stores
+// return generic maps rather than the original struct
+func decodeTask(value interface{}) (*multiagent.Task, error) {
+	switch v := value.(type) {
+	case *multiagent.Task:
+		return v, nil
+	case multiagent.Task:
+		return &v, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	var task multiagent.Task
+	if err := json.Unmarshal(data, &task); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task: %w", err)
+	}
+	return &task, nil
+}
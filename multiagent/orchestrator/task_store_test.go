@@ -0,0 +1,51 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+func TestRestoreTasksAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	store, err := memory.NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileMemoryStore: %v", err)
+	}
+	defer store.Close()
+
+	taskStore := NewMemoryTaskStore(store)
+	now := time.Now()
+	for _, task := range []*multiagent.Task{
+		{ID: "task_done", Status: multiagent.TaskStatusCompleted, Assignee: "gone_agent", CreatedAt: now},
+		{ID: "task_open", Status: multiagent.TaskStatusInProgress, Assignee: "gone_agent", CreatedAt: now},
+	} {
+		if err := taskStore.Save(ctx, task); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	orch := NewOrchestrator(OrchestratorConfig{MemoryStore: store})
+	if err := orch.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer orch.Stop(ctx)
+
+	// The unfinished task waits for an agent; the finished one is served from the store
+	status, err := orch.GetTaskStatus(ctx, "task_open")
+	if err != nil || status != multiagent.TaskStatusPending {
+		t.Fatalf("expected restored task to be pending, got %q, %v", status, err)
+	}
+	status, err = orch.GetTaskStatus(ctx, "task_done")
+	if err != nil || status != multiagent.TaskStatusCompleted {
+		t.Fatalf("expected completed task from store, got %q, %v", status, err)
+	}
+
+	stored, err := taskStore.Get(ctx, "task_open")
+	if err != nil || stored.Status != multiagent.TaskStatusPending {
+		t.Fatalf("expected persisted pending status, got %+v, %v", stored, err)
+	}
+}
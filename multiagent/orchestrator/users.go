@@ -0,0 +1,74 @@
+package orchestrator
+
+import (
+	"sync"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// userDirectory remembers which user each conversation belongs to, so
+// messages agents send on a conversation keep acting for that user
+type userDirectory struct {
+	mu             sync.RWMutex
+	byConversation map[string]string
+}
+
+func newUserDirectory() *userDirectory {
+	return &userDirectory{byConversation: make(map[string]string)}
+}
+
+// stamp records the user of a message's conversation, or fills in the user
+// of a message that names only the conversation
+func (d *userDirectory) stamp(msg *multiagent.Message) {
+	conversationID, _ := msg.Context["conversation_id"].(string)
+	if conversationID == "" {
+		return
+	}
+
+	if userID := multiagent.UserIDFromMessage(msg); userID != "" {
+		d.mu.Lock()
+		d.byConversation[conversationID] = userID
+		d.mu.Unlock()
+		return
+	}
+	if userID := d.forConversation(conversationID); userID != "" {
+		msg.Context[multiagent.ContextUserID] = userID
+	}
+}
+
+func (d *userDirectory) forConversation(conversationID string) string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.byConversation[conversationID]
+}
+
+// forTask returns the user a task acts for, from its input
+func (d *userDirectory) forTask(task *multiagent.Task) string {
+	if userID, _ := task.Input[multiagent.ContextUserID].(string); userID != "" {
+		return userID
+	}
+	conversationID, _ := task.Input["conversation_id"].(string)
+	return d.forConversation(conversationID)
+}
+
+// forget drops every conversation of userID
+func (d *userDirectory) forget(userID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for conversationID, owner := range d.byConversation {
+		if owner == userID {
+			delete(d.byConversation, conversationID)
+		}
+	}
+}
+
+// ForgetUser drops what the orchestrator remembers about userID's
+// conversations, as part of purging the user
+func (o *DefaultOrchestrator) ForgetUser(userID string) {
+	o.users.forget(userID)
+}
+
+// UserForConversation returns the user conversationID belongs to, or ""
+func (o *DefaultOrchestrator) UserForConversation(conversationID string) string {
+	return o.users.forConversation(conversationID)
+}
@@ -0,0 +1,85 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+func TestRouteMessageActsForConversationUser(t *testing.T) {
+	orch, agent := newTestOrchestrator(t)
+	users := make(chan string, 2)
+	agent.handle = func(ctx context.Context, msg *multiagent.Message) error {
+		users <- multiagent.UserIDFromContext(ctx) + "/" + multiagent.UserIDFromMessage(msg)
+		return nil
+	}
+
+	// The user's message names the conversation's user; a specialist's
+	// follow-up on the same conversation only names the conversation
+	for _, msgContext := range []map[string]interface{}{
+		{"conversation_id": "conv_alice", multiagent.ContextUserID: "alice"},
+		{"conversation_id": "conv_alice"},
+	} {
+		msg := &multiagent.Message{
+			ID:      "msg",
+			From:    "coordinator",
+			To:      []multiagent.AgentID{"worker"},
+			Type:    multiagent.MessageTypeRequest,
+			Context: msgContext,
+		}
+		if err := orch.RouteMessage(context.Background(), msg); err != nil {
+			t.Fatalf("RouteMessage: %v", err)
+		}
+		select {
+		case got := <-users:
+			if got != "alice/alice" {
+				t.Errorf("handler acted for %q, want alice/alice", got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("worker never received the message")
+		}
+	}
+
+	orch.ForgetUser("alice")
+	if user := orch.UserForConversation("conv_alice"); user != "" {
+		t.Errorf("expected alice's conversations to be forgotten, got %q", user)
+	}
+}
+
+func TestAssignedTaskActsForItsUser(t *testing.T) {
+	orch, agent := newTestOrchestrator(t)
+	received := make(chan *multiagent.Message, 1)
+	agent.handle = func(ctx context.Context, msg *multiagent.Message) error {
+		received <- msg
+		return nil
+	}
+
+	_, err := orch.AssignTask(context.Background(), multiagent.Task{
+		ID:       "briefing",
+		Type:     "stub",
+		Assignee: "worker",
+		Input:    map[string]interface{}{"conversation_id": "conv_bob", multiagent.ContextUserID: "bob"},
+	})
+	if err != nil {
+		t.Fatalf("AssignTask: %v", err)
+	}
+	select {
+	case msg := <-received:
+		if multiagent.UserIDFromMessage(msg) != "bob" || msg.Context["conversation_id"] != "conv_bob" {
+			t.Errorf("task message context = %v, want bob's conversation", msg.Context)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("worker never received the task")
+	}
+
+	// The worker's own messages on the conversation act for bob
+	if user := orch.UserForConversation("conv_bob"); user != "bob" {
+		t.Errorf("UserForConversation = %q, want bob", user)
+	}
+	task, err := orch.GetTask(context.Background(), "briefing")
+	if err != nil || task.Input["conversation_id"] != "conv_bob" {
+		t.Errorf("GetTask = %+v, %v", task, err)
+	}
+}
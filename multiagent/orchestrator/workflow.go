@@ -0,0 +1,349 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// ErrDependencyCycle is returned when task dependencies form a cycle
+var ErrDependencyCycle = errors.New("task dependency cycle")
+
+const workflowKeyPrefix = "orchestrator:workflow:"
+
+// Workflow is a DAG of tasks submitted together; edges come from each task's DependsOn
+type Workflow struct {
+	ID    string            `json:"id"`
+	Name  string            `json:"name"`
+	Tasks []multiagent.Task `json:"tasks"`
+}
+
+// WorkflowStatus summarizes the progress of a submitted workflow
+type WorkflowStatus struct {
+	ID        string                           `json:"id"`
+	Name      string                           `json:"name"`
+	Status    multiagent.TaskStatus            `json:"status"`
+	Tasks     map[string]multiagent.TaskStatus `json:"tasks"`
+	CreatedAt time.Time                        `json:"created_at"`
+}
+
+// workflowRecord is what is persisted for a workflow; tasks live in the task store
+type workflowRecord struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	TaskIDs   []string  `json:"task_ids"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SubmitWorkflow validates a task DAG and assigns its tasks in dependency
+// order. Tasks whose prerequisites are unfinished are held as waiting and
+// dispatched automatically as the prerequisites complete.
+func (o *DefaultOrchestrator) SubmitWorkflow(ctx context.Context, workflow Workflow) (string, error) {
+	if len(workflow.Tasks) == 0 {
+		return "", fmt.Errorf("workflow has no tasks")
+	}
+	if workflow.ID == "" {
+		workflow.ID = o.newID("workflow")
+	}
+
+	byID := make(map[string]*multiagent.Task, len(workflow.Tasks))
+	for i := range workflow.Tasks {
+		task := &workflow.Tasks[i]
+		if task.ID == "" {
+			task.ID = fmt.Sprintf("%s_task_%d", workflow.ID, i+1)
+		}
+		if _, dup := byID[task.ID]; dup {
+			return "", fmt.Errorf("duplicate task ID %s in workflow", task.ID)
+		}
+		task.WorkflowID = workflow.ID
+		byID[task.ID] = task
+	}
+
+	// Dependencies must be in the workflow or already known to the orchestrator
+	o.mu.RLock()
+	for _, task := range workflow.Tasks {
+		for _, dep := range task.DependsOn {
+			if _, inWorkflow := byID[dep]; inWorkflow {
+				continue
+			}
+			if o.lookupTask(ctx, dep) == nil {
+				o.mu.RUnlock()
+				return "", fmt.Errorf("task %s depends on unknown task %s", task.ID, dep)
+			}
+		}
+	}
+	o.mu.RUnlock()
+
+	order, err := topoSort(workflow.Tasks)
+	if err != nil {
+		return "", err
+	}
+
+	record := workflowRecord{
+		ID:        workflow.ID,
+		Name:      workflow.Name,
+		TaskIDs:   order,
+		CreatedAt: o.now(),
+	}
+	if o.memoryStore != nil {
+		if err := o.memoryStore.Store(ctx, workflowKeyPrefix+workflow.ID, record); err != nil {
+			return "", fmt.Errorf("failed to store workflow: %w", err)
+		}
+	}
+
+	for _, taskID := range order {
+		if _, err := o.AssignTask(ctx, *byID[taskID]); err != nil {
+			return workflow.ID, fmt.Errorf("failed to assign workflow task %s: %w", taskID, err)
+		}
+	}
+
+	logger.InfoContext(ctx, "Submitted workflow", "workflow_id", workflow.ID, "tasks", len(order))
+	return workflow.ID, nil
+}
+
+// GetWorkflowStatus reports the status of each task in a workflow and overall
+func (o *DefaultOrchestrator) GetWorkflowStatus(ctx context.Context, workflowID string) (*WorkflowStatus, error) {
+	if o.memoryStore == nil {
+		return nil, fmt.Errorf("workflow %s not found", workflowID)
+	}
+
+	value, err := o.memoryStore.Get(ctx, workflowKeyPrefix+workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("workflow %s not found: %w", workflowID, err)
+	}
+
+	var record workflowRecord
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal workflow: %w", err)
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal workflow: %w", err)
+	}
+
+	status := &WorkflowStatus{
+		ID:        record.ID,
+		Name:      record.Name,
+		Tasks:     make(map[string]multiagent.TaskStatus, len(record.TaskIDs)),
+		CreatedAt: record.CreatedAt,
+	}
+
+	counts := make(map[multiagent.TaskStatus]int)
+	for _, taskID := range record.TaskIDs {
+		taskStatus, err := o.GetTaskStatus(ctx, taskID)
+		if err != nil {
+			taskStatus = multiagent.TaskStatusPending
+		}
+		status.Tasks[taskID] = taskStatus
+		counts[taskStatus]++
+	}
+
+	switch {
+	case counts[multiagent.TaskStatusFailed] > 0:
+		status.Status = multiagent.TaskStatusFailed
+	case counts[multiagent.TaskStatusCancelled] > 0:
+		status.Status = multiagent.TaskStatusCancelled
+	case counts[multiagent.TaskStatusCompleted] == len(record.TaskIDs):
+		status.Status = multiagent.TaskStatusCompleted
+	case counts[multiagent.TaskStatusCompleted] > 0 || counts[multiagent.TaskStatusInProgress] > 0 ||
+		counts[multiagent.TaskStatusAssigned] > 0:
+		status.Status = multiagent.TaskStatusInProgress
+	default:
+		status.Status = multiagent.TaskStatusPending
+	}
+
+	return status, nil
+}
+
+// Dependency helpers; callers hold o.mu
+
+// checkDependencies reports whether every prerequisite of task has completed.
+// Unknown, failed, or cyclic dependencies are errors.
+func (o *DefaultOrchestrator) checkDependencies(ctx context.Context, task *multiagent.Task) (bool, error) {
+	for _, dep := range task.DependsOn {
+		if dep == task.ID {
+			return false, fmt.Errorf("%w: task %s depends on itself", ErrDependencyCycle, task.ID)
+		}
+		if o.dependsOn(ctx, dep, task.ID, make(map[string]bool)) {
+			return false, fmt.Errorf("%w: %s and %s depend on each other", ErrDependencyCycle, task.ID, dep)
+		}
+	}
+
+	ready := true
+	for _, dep := range task.DependsOn {
+		prereq := o.lookupTask(ctx, dep)
+		if prereq == nil {
+			return false, fmt.Errorf("task %s depends on unknown task %s", task.ID, dep)
+		}
+		switch prereq.Status {
+		case multiagent.TaskStatusFailed, multiagent.TaskStatusCancelled:
+			return false, fmt.Errorf("dependency %s of task %s is %s", dep, task.ID, prereq.Status)
+		case multiagent.TaskStatusCompleted:
+		default:
+			ready = false
+		}
+	}
+
+	if ready {
+		o.attachDependencyOutputs(ctx, task)
+	}
+	return ready, nil
+}
+
+// dependsOn reports whether taskID transitively depends on target
+func (o *DefaultOrchestrator) dependsOn(ctx context.Context, taskID, target string, seen map[string]bool) bool {
+	if seen[taskID] {
+		return false
+	}
+	seen[taskID] = true
+
+	task := o.lookupTask(ctx, taskID)
+	if task == nil {
+		return false
+	}
+	for _, dep := range task.DependsOn {
+		if dep == target || o.dependsOn(ctx, dep, target, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseDependents re-evaluates waiting tasks after finishedID reaches a terminal state
+func (o *DefaultOrchestrator) releaseDependents(ctx context.Context, finishedID string) {
+	for _, task := range o.tasks {
+		if task.Status != multiagent.TaskStatusWaiting {
+			continue
+		}
+		for _, dep := range task.DependsOn {
+			if dep == finishedID {
+				o.releaseTask(ctx, task)
+				break
+			}
+		}
+	}
+}
+
+// releaseTask dispatches a waiting task whose prerequisites have all completed,
+// or fails it (and its own dependents) if any prerequisite failed
+func (o *DefaultOrchestrator) releaseTask(ctx context.Context, task *multiagent.Task) {
+	for _, dep := range task.DependsOn {
+		prereq := o.lookupTask(ctx, dep)
+		if prereq == nil {
+			continue
+		}
+		switch prereq.Status {
+		case multiagent.TaskStatusFailed, multiagent.TaskStatusCancelled:
+			logger.WarnContext(ctx, "Task failed because a dependency did not complete", logging.KeyTaskID, task.ID, "dependency", dep, "dependency_status", prereq.Status)
+			o.finishTask(ctx, task, multiagent.TaskStatusFailed,
+				fmt.Sprintf("dependency %s %s", dep, prereq.Status), multiagent.EventTaskFailed)
+			return
+		case multiagent.TaskStatusCompleted:
+		default:
+			return
+		}
+	}
+
+	o.attachDependencyOutputs(ctx, task)
+
+	if !o.agentAlive(task.Assignee) {
+		// redispatchStrandedTasks will pick it up once an agent is available
+		o.transition(task, multiagent.TaskStatusPending, "")
+		o.persistTask(ctx, task)
+		return
+	}
+
+	o.transition(task, multiagent.TaskStatusAssigned, "")
+	if err := o.dispatchTask(ctx, task); err != nil {
+		logger.ErrorContext(ctx, "Failed to dispatch released task", logging.KeyTaskID, task.ID, "error", err)
+		o.transition(task, multiagent.TaskStatusPending, err.Error())
+	} else {
+		logger.InfoContext(ctx, "Dependencies complete, task dispatched", logging.KeyTaskID, task.ID, logging.KeyAgentID, task.Assignee)
+	}
+	o.persistTask(ctx, task)
+}
+
+// attachDependencyOutputs passes prerequisite outputs to the dependent task
+func (o *DefaultOrchestrator) attachDependencyOutputs(ctx context.Context, task *multiagent.Task) {
+	outputs := make(map[string]interface{}, len(task.DependsOn))
+	for _, dep := range task.DependsOn {
+		if prereq := o.lookupTask(ctx, dep); prereq != nil && prereq.Output != nil {
+			outputs[dep] = prereq.Output
+		}
+	}
+	if len(outputs) == 0 {
+		return
+	}
+	if task.Input == nil {
+		task.Input = make(map[string]interface{})
+	}
+	task.Input["dependency_outputs"] = outputs
+}
+
+// lookupTask finds a task in memory or, failing that, in the task store
+func (o *DefaultOrchestrator) lookupTask(ctx context.Context, taskID string) *multiagent.Task {
+	if task, exists := o.tasks[taskID]; exists {
+		return task
+	}
+	if o.taskStore != nil {
+		if task, err := o.taskStore.Get(ctx, taskID); err == nil {
+			return task
+		}
+	}
+	return nil
+}
+
+// topoSort orders tasks so every task follows its in-workflow dependencies
+func topoSort(tasks []multiagent.Task) ([]string, error) {
+	inWorkflow := make(map[string]*multiagent.Task, len(tasks))
+	for i := range tasks {
+		inWorkflow[tasks[i].ID] = &tasks[i]
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(tasks))
+	order := make([]string, 0, len(tasks))
+	var path []string
+
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s -> %s", ErrDependencyCycle, strings.Join(path, " -> "), id)
+		}
+
+		state[id] = visiting
+		path = append(path, id)
+		for _, dep := range inWorkflow[id].DependsOn {
+			if _, ok := inWorkflow[dep]; !ok {
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = done
+		order = append(order, id)
+		return nil
+	}
+
+	for _, task := range tasks {
+		if err := visit(task.ID); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,144 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+// stubAgent records the task (or message) IDs it is asked to execute; handle, if set,
+// decides the outcome of each one
+type stubAgent struct {
+	id     multiagent.AgentID
+	handle func(ctx context.Context, msg *multiagent.Message) error
+
+	mu      sync.Mutex
+	handled []string
+}
+
+func (a *stubAgent) ID() multiagent.AgentID                                 { return a.id }
+func (a *stubAgent) Type() multiagent.AgentType                             { return multiagent.AgentTypeTask }
+func (a *stubAgent) Name() string                                           { return string(a.id) }
+func (a *stubAgent) Description() string                                    { return "stub" }
+func (a *stubAgent) Initialize(ctx context.Context) error                   { return nil }
+func (a *stubAgent) Start(ctx context.Context) error                        { return nil }
+func (a *stubAgent) Stop(ctx context.Context) error                         { return nil }
+func (a *stubAgent) GetCapabilities() []string                              { return []string{"stub"} }
+func (a *stubAgent) CanHandle(multiagent.MessageType) bool                  { return true }
+func (a *stubAgent) SendMessage(context.Context, *multiagent.Message) error { return nil }
+func (a *stubAgent) ReceiveMessage(context.Context) (*multiagent.Message, error) {
+	return nil, nil
+}
+func (a *stubAgent) GetState() multiagent.AgentState {
+	return multiagent.AgentState{Status: multiagent.AgentStatusIdle}
+}
+
+func (a *stubAgent) HandleMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	a.mu.Lock()
+	handled := msg.ID
+	if taskID, ok := msg.Context["task_id"].(string); ok {
+		handled = taskID
+	}
+	a.handled = append(a.handled, handled)
+	a.mu.Unlock()
+
+	if a.handle != nil {
+		if err := a.handle(ctx, msg); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (a *stubAgent) order() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.handled...)
+}
+
+func newTestOrchestrator(t *testing.T) (*DefaultOrchestrator, *stubAgent) {
+	t.Helper()
+	ctx := context.Background()
+
+	store, err := memory.NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	orch := NewOrchestrator(OrchestratorConfig{MemoryStore: store, TaskCheckInterval: 10 * time.Millisecond})
+	agent := &stubAgent{id: "worker"}
+	if err := orch.RegisterAgent(agent); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	if err := orch.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { orch.Stop(ctx) })
+	return orch, agent
+}
+
+func TestSubmitWorkflowRunsInDependencyOrder(t *testing.T) {
+	ctx := context.Background()
+	orch, agent := newTestOrchestrator(t)
+
+	workflowID, err := orch.SubmitWorkflow(ctx, Workflow{
+		Name: "report",
+		Tasks: []multiagent.Task{
+			{ID: "publish", Type: "stub", DependsOn: []string{"draft", "review"}},
+			{ID: "review", Type: "stub", DependsOn: []string{"research"}},
+			{ID: "draft", Type: "stub", DependsOn: []string{"research"}},
+			{ID: "research", Type: "stub"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SubmitWorkflow: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err := orch.GetWorkflowStatus(ctx, workflowID)
+		if err != nil {
+			t.Fatalf("GetWorkflowStatus: %v", err)
+		}
+		if status.Status == multiagent.TaskStatusCompleted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("workflow did not complete: %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	position := make(map[string]int)
+	for i, id := range agent.order() {
+		position[id] = i
+	}
+	if len(position) != 4 {
+		t.Fatalf("expected 4 tasks executed once each, got %v", agent.order())
+	}
+	if position["research"] > position["draft"] || position["research"] > position["review"] ||
+		position["draft"] > position["publish"] || position["review"] > position["publish"] {
+		t.Fatalf("tasks ran out of order: %v", agent.order())
+	}
+}
+
+func TestSubmitWorkflowRejectsCycles(t *testing.T) {
+	orch, _ := newTestOrchestrator(t)
+
+	_, err := orch.SubmitWorkflow(context.Background(), Workflow{
+		Tasks: []multiagent.Task{
+			{ID: "a", Type: "stub", DependsOn: []string{"c"}},
+			{ID: "b", Type: "stub", DependsOn: []string{"a"}},
+			{ID: "c", Type: "stub", DependsOn: []string{"b"}},
+		},
+	})
+	if !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+// Package plugins lets modules outside this one add agents to the service.
+// A plugin registers itself from an init function, and a server picks it up
+// by importing the plugin's package for its side effects:
+//
+//	func init() {
+//		plugins.Register(plugins.Plugin{
+//			Name: "expenses",
+//			Agents: func(env plugins.Env) ([]multiagent.Agent, error) {
+//				config := env.AgentConfig("expense_agent", "expenses")
+//				config.Name = "Expense Tracker"
+//				return []multiagent.Agent{NewExpenseAgent(config)}, nil
+//			},
+//		})
+//	}
+//
+// Plugins can also hook into their agents' lifecycle and wrap the delivery
+// of every message, without editing the service.
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+)
+
+// Env is what the service offers plugins building their agents
+type Env interface {
+	// AgentConfig returns the configuration the service gives its own
+	// agents, for an agent with id and agentType: the LLM configured for
+	// the type, memory scoped to the agent, the orchestrator, audit log,
+	// reminders, prompts, clock and IDs. Set Name, Description and
+	// Capabilities before creating the agent.
+	AgentConfig(id multiagent.AgentID, agentType multiagent.AgentType) agents.BaseAgentConfig
+	// GetOrchestrator returns the orchestrator agents are registered with
+	GetOrchestrator() multiagent.Orchestrator
+	// GetMemoryStore returns the service's memory store, partitioned by
+	// user but not scoped to an agent
+	GetMemoryStore() multiagent.MemoryStore
+}
+
+// Plugin adds agents and hooks to the service
+type Plugin struct {
+	// Name identifies the plugin in logs and errors
+	Name string
+	// Agents builds the plugin's agents when the service is created; they
+	// are registered after the built-in agents and may not reuse their IDs
+	Agents func(env Env) ([]multiagent.Agent, error)
+	// Capabilities, if set, publishes the listed agents' capabilities in
+	// place of those the agents report themselves
+	Capabilities map[multiagent.AgentID][]multiagent.CapabilityDescriptor
+	// OnRegister runs once each of the plugin's agents is registered with
+	// the orchestrator; an error fails creating the service
+	OnRegister func(ctx context.Context, agent multiagent.Agent) error
+	// OnStart runs once each of the plugin's agents has started; an error
+	// is logged
+	OnStart func(ctx context.Context, agent multiagent.Agent) error
+	// OnMessage, if set, wraps the delivery of every message to any agent
+	OnMessage orchestrator.MessageMiddleware
+}
+
+var (
+	mu         sync.RWMutex
+	registered = make(map[string]Plugin)
+)
+
+// Register makes a plugin available to every service created afterwards.
+// It panics if the name is empty or already registered, as it is meant to
+// be called from init functions.
+func Register(plugin Plugin) {
+	mu.Lock()
+	defer mu.Unlock()
+	if plugin.Name == "" {
+		panic("plugins: Register called without a name")
+	}
+	if _, exists := registered[plugin.Name]; exists {
+		panic(fmt.Sprintf("plugins: Register called twice for plugin %s", plugin.Name))
+	}
+	registered[plugin.Name] = plugin
+}
+
+// Registered returns the registered plugins, sorted by name
+func Registered() []Plugin {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]Plugin, 0, len(registered))
+	for _, plugin := range registered {
+		list = append(list, plugin)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// unregister removes a plugin, for tests
+func unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(registered, name)
+}
@@ -0,0 +1,35 @@
+package plugins
+
+import (
+	"testing"
+)
+
+func TestRegisterKeepsPluginsByName(t *testing.T) {
+	Register(Plugin{Name: "zeta"})
+	Register(Plugin{Name: "alpha"})
+	t.Cleanup(func() {
+		unregister("zeta")
+		unregister("alpha")
+	})
+
+	list := Registered()
+	if len(list) != 2 || list[0].Name != "alpha" || list[1].Name != "zeta" {
+		t.Fatalf("registered plugins %+v, want alpha and zeta", list)
+	}
+}
+
+func TestRegisterPanicsOnDuplicateOrUnnamedPlugin(t *testing.T) {
+	Register(Plugin{Name: "expenses"})
+	t.Cleanup(func() { unregister("expenses") })
+
+	for name, plugin := range map[string]Plugin{"duplicate": {Name: "expenses"}, "unnamed": {}} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected Register to panic")
+				}
+			}()
+			Register(plugin)
+		})
+	}
+}
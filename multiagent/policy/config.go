@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+)
+
+// StepConfig configures one step of the route middleware
+type StepConfig struct {
+	// Type is log, redact or block
+	Type string `json:"type"`
+	Match
+	// Kinds, for redact, limits it to some of email, phone and card
+	Kinds []string `json:"kinds,omitempty"`
+	// Name, Hours, Days, Timezone and Reason configure a block Rule
+	Name     string   `json:"name,omitempty"`
+	Hours    string   `json:"hours,omitempty"`
+	Days     []string `json:"days,omitempty"`
+	Timezone string   `json:"timezone,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+}
+
+// NewMiddleware creates the route middleware a step configures
+func NewMiddleware(config StepConfig) (orchestrator.RouteMiddleware, error) {
+	switch config.Type {
+	case "log":
+		return Log(), nil
+	case "redact":
+		return Redact(config.Match, config.Kinds...)
+	case "block":
+		rule := Rule{
+			Name:   config.Name,
+			Match:  config.Match,
+			Hours:  config.Hours,
+			Days:   config.Days,
+			Reason: config.Reason,
+		}
+		if config.Timezone != "" {
+			location, err := time.LoadLocation(config.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: invalid timezone: %w", config.Name, err)
+			}
+			rule.Location = location
+		}
+		return Block(rule)
+	default:
+		return nil, fmt.Errorf("unknown policy step type %q", config.Type)
+	}
+}
+
+// LoadConfig reads the route middleware of a deployment from a JSON file
+// listing its steps in the order messages pass through them:
+//
+//	{"steps": [{"type": "log"},
+//	           {"type": "block", "name": "email_after_hours", "to": ["communication_agent"],
+//	            "content": "(?i)\\bsend\\b", "hours": "08:00-19:00",
+//	            "days": ["monday", "tuesday", "wednesday", "thursday", "friday"],
+//	            "timezone": "Europe/Berlin", "reason": "email is only sent during office hours"},
+//	           {"type": "redact", "to": ["research_agent"], "kinds": ["email", "phone"]}]}
+func LoadConfig(path string) ([]orchestrator.RouteMiddleware, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message policy: %w", err)
+	}
+	var file struct {
+		Steps []StepConfig `json:"steps"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse message policy: %w", err)
+	}
+
+	middleware := make([]orchestrator.RouteMiddleware, 0, len(file.Steps))
+	for i, step := range file.Steps {
+		m, err := NewMiddleware(step)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy step %d: %w", i+1, err)
+		}
+		middleware = append(middleware, m)
+	}
+	return middleware, nil
+}
@@ -0,0 +1,273 @@
+// Package policy provides route middleware for the orchestrator that logs,
+// redacts and blocks messages by rule. A deployment lists the steps it
+// wants, in order, in a JSON file (see LoadConfig) or builds them in Go and
+// passes them as OrchestratorConfig.RouteMiddleware.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+)
+
+var logger = logging.For("policy")
+
+// Match selects messages by sender, recipient, type and content; empty
+// fields match any message
+type Match struct {
+	From  []multiagent.AgentID     `json:"from,omitempty"`
+	To    []multiagent.AgentID     `json:"to,omitempty"`
+	Types []multiagent.MessageType `json:"types,omitempty"`
+	// Content is a regular expression the message content must contain a
+	// match of
+	Content string `json:"content,omitempty"`
+}
+
+// matcher is a Match with its content pattern compiled
+type matcher struct {
+	Match
+	content *regexp.Regexp
+}
+
+func (m Match) compile() (matcher, error) {
+	compiled := matcher{Match: m}
+	if m.Content != "" {
+		pattern, err := regexp.Compile(m.Content)
+		if err != nil {
+			return compiled, fmt.Errorf("invalid content pattern: %w", err)
+		}
+		compiled.content = pattern
+	}
+	return compiled, nil
+}
+
+func (m matcher) matches(msg *multiagent.Message) bool {
+	if len(m.From) > 0 && !slices.Contains(m.From, msg.From) {
+		return false
+	}
+	if len(m.To) > 0 && !slices.ContainsFunc(msg.To, func(id multiagent.AgentID) bool { return slices.Contains(m.To, id) }) {
+		return false
+	}
+	if len(m.Types) > 0 && !slices.Contains(m.Types, msg.Type) {
+		return false
+	}
+	return m.content == nil || m.content.MatchString(msg.Content)
+}
+
+// Log logs every message routed, without its content
+func Log() orchestrator.RouteMiddleware {
+	return func(next orchestrator.RouteHandler) orchestrator.RouteHandler {
+		return func(ctx context.Context, msg *multiagent.Message) error {
+			logger.InfoContext(logging.WithMessage(ctx, msg), "Routing message",
+				"from", msg.From, "to", msg.To, "type", msg.Type, "priority", msg.Priority, "length", len(msg.Content))
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Kinds of personal data Redact recognises
+const (
+	KindEmail = "email"
+	KindPhone = "phone"
+	KindCard  = "card"
+)
+
+var piiPatterns = map[string]*regexp.Regexp{
+	KindEmail: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	KindPhone: regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)|\b\d{3})[\s.-]?\d{3}[\s.-]?\d{4}\b`),
+	KindCard:  regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+}
+
+// Redact replaces email addresses, phone numbers and card numbers in the
+// content of matching messages with a placeholder such as
+// "[redacted email]"; kinds limits it to some of KindEmail, KindPhone and
+// KindCard. Recipients get a redacted copy, and the sender's message is
+// left as it is.
+func Redact(match Match, kinds ...string) (orchestrator.RouteMiddleware, error) {
+	compiled, err := match.compile()
+	if err != nil {
+		return nil, err
+	}
+	if len(kinds) == 0 {
+		kinds = []string{KindEmail, KindCard, KindPhone}
+	}
+	for _, kind := range kinds {
+		if _, ok := piiPatterns[kind]; !ok {
+			return nil, fmt.Errorf("unknown kind of personal data %q", kind)
+		}
+	}
+
+	return func(next orchestrator.RouteHandler) orchestrator.RouteHandler {
+		return func(ctx context.Context, msg *multiagent.Message) error {
+			if !compiled.matches(msg) {
+				return next(ctx, msg)
+			}
+			content := redact(msg.Content, kinds)
+			if content == msg.Content {
+				return next(ctx, msg)
+			}
+			redacted := *msg
+			redacted.Content = content
+			return next(ctx, &redacted)
+		}
+	}, nil
+}
+
+func redact(content string, kinds []string) string {
+	for _, kind := range kinds {
+		content = piiPatterns[kind].ReplaceAllStringFunc(content, func(found string) string {
+			if kind == KindCard && !luhnValid(found) {
+				return found
+			}
+			return "[redacted " + kind + "]"
+		})
+	}
+	return content
+}
+
+// luhnValid reports whether the digits in number pass the Luhn check card
+// numbers carry, so other long numbers are left alone
+func luhnValid(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// Rule blocks matching messages, always or outside set hours
+type Rule struct {
+	// Name identifies the rule in logs and errors
+	Name string
+	Match
+	// Hours, e.g. "09:00-18:00", limits the rule to messages sent outside
+	// them; the rule applies at all times if empty
+	Hours string
+	// Days are the weekdays Hours apply on, e.g. "monday"; on other days
+	// the rule applies all day (default every day)
+	Days []string
+	// Location is the time zone of Hours (default local time)
+	Location *time.Location
+	// Reason tells the sender why the message was blocked
+	Reason string
+}
+
+// Block refuses messages matching rule with an error wrapping
+// orchestrator.ErrMessageBlocked. The time of a message is its timestamp,
+// so the rule follows the orchestrator's clock.
+func Block(rule Rule) (orchestrator.RouteMiddleware, error) {
+	compiled, err := rule.Match.compile()
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+	}
+	window, err := parseWindow(rule.Hours, rule.Days)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+	}
+	location := rule.Location
+	if location == nil {
+		location = time.Local
+	}
+	reason := rule.Reason
+	if reason == "" {
+		reason = "not allowed"
+	}
+
+	return func(next orchestrator.RouteHandler) orchestrator.RouteHandler {
+		return func(ctx context.Context, msg *multiagent.Message) error {
+			if !compiled.matches(msg) || window.allows(msg.Timestamp.In(location)) {
+				return next(ctx, msg)
+			}
+			return fmt.Errorf("%w: %s (%s)", orchestrator.ErrMessageBlocked, reason, rule.Name)
+		}
+	}, nil
+}
+
+// window is the time of day messages are allowed in, on some weekdays
+type window struct {
+	set        bool
+	start, end time.Duration
+	days       map[time.Weekday]bool
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+func parseWindow(hours string, days []string) (window, error) {
+	var w window
+	if hours == "" {
+		if len(days) > 0 {
+			return w, fmt.Errorf("days are set without hours")
+		}
+		return w, nil
+	}
+
+	start, end, ok := strings.Cut(hours, "-")
+	if !ok {
+		return w, fmt.Errorf("hours must look like 09:00-18:00, got %q", hours)
+	}
+	var err error
+	if w.start, err = parseClock(start); err != nil {
+		return w, err
+	}
+	if w.end, err = parseClock(end); err != nil {
+		return w, err
+	}
+	if w.end <= w.start {
+		return w, fmt.Errorf("hours %q end before they start", hours)
+	}
+
+	w.set = true
+	w.days = make(map[time.Weekday]bool)
+	for _, name := range days {
+		day, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return w, fmt.Errorf("unknown weekday %q", name)
+		}
+		w.days[day] = true
+	}
+	if len(days) == 0 {
+		for _, day := range weekdays {
+			w.days[day] = true
+		}
+	}
+	return w, nil
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %w", value, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// allows reports whether t is within the window; a rule without hours
+// allows nothing
+func (w window) allows(t time.Time) bool {
+	if !w.set || !w.days[t.Weekday()] {
+		return false
+	}
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	return sinceMidnight >= w.start && sinceMidnight < w.end
+}
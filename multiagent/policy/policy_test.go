@@ -0,0 +1,139 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+)
+
+// route runs msg through middleware and returns the message passed on, or
+// nil if it was stopped
+func route(t *testing.T, middleware orchestrator.RouteMiddleware, msg *multiagent.Message) (*multiagent.Message, error) {
+	t.Helper()
+	var passed *multiagent.Message
+	err := middleware(func(ctx context.Context, msg *multiagent.Message) error {
+		passed = msg
+		return nil
+	})(context.Background(), msg)
+	return passed, err
+}
+
+func TestRedact(t *testing.T) {
+	middleware, err := Redact(Match{To: []multiagent.AgentID{"research_agent"}})
+	if err != nil {
+		t.Fatalf("Redact: %v", err)
+	}
+
+	msg := &multiagent.Message{
+		To:      []multiagent.AgentID{"research_agent"},
+		Content: "Mail bob@example.com or call (555) 123-4567, card 4111 1111 1111 1111, order 1234567890123 on 2026-10-15",
+	}
+	passed, err := route(t, middleware, msg)
+	if err != nil {
+		t.Fatalf("route: %v", err)
+	}
+	want := "Mail [redacted email] or call [redacted phone], card [redacted card], order 1234567890123 on 2026-10-15"
+	if passed.Content != want {
+		t.Errorf("redacted content\n got %q\nwant %q", passed.Content, want)
+	}
+	if msg.Content == want {
+		t.Error("the sender's message was redacted in place")
+	}
+
+	other := &multiagent.Message{To: []multiagent.AgentID{"task_agent"}, Content: "bob@example.com"}
+	if passed, _ := route(t, middleware, other); passed.Content != other.Content {
+		t.Errorf("message to another agent was redacted: %q", passed.Content)
+	}
+
+	if _, err := Redact(Match{}, "passport"); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+}
+
+func TestBlockOutsideHours(t *testing.T) {
+	middleware, err := Block(Rule{
+		Name:     "email_after_hours",
+		Match:    Match{To: []multiagent.AgentID{"communication_agent"}, Content: `(?i)\bsend\b`},
+		Hours:    "09:00-18:00",
+		Days:     []string{"monday", "tuesday", "wednesday", "thursday", "friday"},
+		Location: time.UTC,
+		Reason:   "email is only sent during office hours",
+	})
+	if err != nil {
+		t.Fatalf("Block: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		to      multiagent.AgentID
+		content string
+		at      time.Time
+		blocked bool
+	}{
+		{"office hours", "communication_agent", "send draft msg_1", time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC), false},
+		{"evening", "communication_agent", "Send draft msg_1", time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC), true},
+		{"weekend", "communication_agent", "send draft msg_1", time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC), true},
+		{"not sending", "communication_agent", "compose an email to Bob", time.Date(2026, 10, 14, 22, 0, 0, 0, time.UTC), false},
+		{"other agent", "task_agent", "send the report", time.Date(2026, 10, 14, 22, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &multiagent.Message{To: []multiagent.AgentID{tt.to}, Content: tt.content, Timestamp: tt.at}
+			passed, err := route(t, middleware, msg)
+			if blocked := errors.Is(err, orchestrator.ErrMessageBlocked); blocked != tt.blocked {
+				t.Fatalf("blocked = %v (err %v), want %v", blocked, err, tt.blocked)
+			}
+			if tt.blocked && passed != nil {
+				t.Error("blocked message was passed on")
+			}
+		})
+	}
+
+	if _, err := Block(Rule{Name: "bad", Hours: "18:00-09:00"}); err == nil {
+		t.Error("expected an error for hours ending before they start")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	config := `{"steps": [{"type": "log"},
+		{"type": "block", "name": "no_secrets", "content": "(?i)password", "reason": "no passwords"},
+		{"type": "redact", "kinds": ["email"]}]}`
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	steps, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(steps) != 3 {
+		t.Fatalf("loaded %d steps, want 3", len(steps))
+	}
+
+	// Steps are chained in the order listed
+	chain := func(next orchestrator.RouteHandler) orchestrator.RouteHandler {
+		for i := len(steps) - 1; i >= 0; i-- {
+			next = steps[i](next)
+		}
+		return next
+	}
+	if _, err := route(t, chain, &multiagent.Message{Content: "my Password is hunter2"}); !errors.Is(err, orchestrator.ErrMessageBlocked) {
+		t.Errorf("expected the password to be blocked, got %v", err)
+	}
+	if passed, _ := route(t, chain, &multiagent.Message{Content: "ask bob@example.com"}); passed == nil || passed.Content != "ask [redacted email]" {
+		t.Errorf("unexpected message passed on: %+v", passed)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"steps": [{"type": "encrypt"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected an error for an unknown step type")
+	}
+}
@@ -0,0 +1,141 @@
+// Package progress streams what the agents are doing for a user request —
+// which agent picked it up, LLM queries and tool calls, and specialists'
+// partial results — so clients can show live progress instead of waiting
+// silently for the final reply.
+package progress
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// Type names a kind of progress event
+type Type string
+
+const (
+	RequestReceived Type = "request_received"
+	AgentStarted    Type = "agent_started"
+	AgentFinished   Type = "agent_finished"
+	LLMQuery        Type = "llm_query"
+	ToolCall        Type = "tool_call"
+	PartialResult   Type = "partial_result"
+	PlanCreated     Type = "plan_created"
+	StepStarted     Type = "step_started"
+	StepFinished    Type = "step_finished"
+	QuestionAsked   Type = "question_asked"
+	Completed       Type = "completed"
+	Failed          Type = "failed"
+)
+
+// Event is one step of work on a conversation
+type Event struct {
+	Type           Type                   `json:"type"`
+	ConversationID string                 `json:"conversation_id,omitempty"`
+	AgentID        multiagent.AgentID     `json:"agent_id,omitempty"`
+	MessageID      string                 `json:"message_id,omitempty"`
+	Detail         string                 `json:"detail,omitempty"`
+	Data           map[string]interface{} `json:"data,omitempty"`
+	Timestamp      time.Time              `json:"timestamp"`
+}
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before new events are dropped for it
+const subscriberBuffer = 64
+
+type subscriber struct {
+	conversationID string
+	events         chan Event
+}
+
+// Hub fans progress events out to subscribers
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+	dropped     atomic.Int64
+}
+
+// NewHub creates an empty progress hub
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[*subscriber]struct{})}
+}
+
+// Subscribe returns events for conversationID, or for every conversation
+// if it is empty. Call the returned function to unsubscribe; it closes the
+// channel.
+func (h *Hub) Subscribe(conversationID string) (<-chan Event, func()) {
+	sub := &subscriber{conversationID: conversationID, events: make(chan Event, subscriberBuffer)}
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return sub.events, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, sub)
+			h.mu.Unlock()
+			close(sub.events)
+		})
+	}
+}
+
+// Publish delivers event to matching subscribers without blocking; events
+// are dropped for subscribers whose buffer is full
+func (h *Hub) Publish(event Event) {
+	if h == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subscribers {
+		if sub.conversationID != "" && sub.conversationID != event.ConversationID {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			h.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped returns how many events were dropped for slow subscribers
+func (h *Hub) Dropped() int64 {
+	return h.dropped.Load()
+}
+
+type hubKey struct{}
+
+// WithHub returns a context whose Emit calls publish to hub
+func WithHub(ctx context.Context, hub *Hub) context.Context {
+	return context.WithValue(ctx, hubKey{}, hub)
+}
+
+// Emit publishes event to the hub on ctx, filling in the conversation,
+// agent, and message IDs from the logging correlation fields. It does
+// nothing if ctx carries no hub.
+func Emit(ctx context.Context, event Event) {
+	hub, _ := ctx.Value(hubKey{}).(*Hub)
+	if hub == nil {
+		return
+	}
+	if event.ConversationID == "" {
+		event.ConversationID = logging.Field(ctx, logging.KeyConversationID)
+	}
+	if event.AgentID == "" {
+		event.AgentID = multiagent.AgentID(logging.Field(ctx, logging.KeyAgentID))
+	}
+	if event.MessageID == "" {
+		event.MessageID = logging.Field(ctx, logging.KeyMessageID)
+	}
+	hub.Publish(event)
+}
@@ -0,0 +1,65 @@
+package progress
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+func TestSubscribeFiltersByConversation(t *testing.T) {
+	hub := NewHub()
+	alice, stopAlice := hub.Subscribe("conv_alice")
+	defer stopAlice()
+	all, stopAll := hub.Subscribe("")
+	defer stopAll()
+
+	hub.Publish(Event{Type: AgentStarted, ConversationID: "conv_bob"})
+	hub.Publish(Event{Type: AgentStarted, ConversationID: "conv_alice"})
+
+	if event := <-alice; event.ConversationID != "conv_alice" || event.Timestamp.IsZero() {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if len(alice) != 0 {
+		t.Errorf("expected bob's event to be filtered out")
+	}
+	if len(all) != 2 {
+		t.Errorf("expected the unfiltered subscriber to get both events, got %d", len(all))
+	}
+}
+
+func TestSlowSubscribersDropInsteadOfBlocking(t *testing.T) {
+	hub := NewHub()
+	_, unsubscribe := hub.Subscribe("")
+
+	for i := 0; i < subscriberBuffer+5; i++ {
+		hub.Publish(Event{Type: LLMQuery})
+	}
+	if hub.Dropped() != 5 {
+		t.Errorf("Dropped = %d, want 5", hub.Dropped())
+	}
+
+	unsubscribe()
+	unsubscribe() // Safe to call twice
+	hub.Publish(Event{Type: LLMQuery})
+}
+
+func TestEmitUsesContextCorrelation(t *testing.T) {
+	hub := NewHub()
+	events, unsubscribe := hub.Subscribe("conv_alice")
+	defer unsubscribe()
+
+	Emit(context.Background(), Event{Type: ToolCall}) // No hub: ignored
+
+	ctx := logging.WithFields(WithHub(context.Background(), hub),
+		logging.KeyConversationID, "conv_alice", logging.KeyAgentID, "scheduler_agent")
+	Emit(ctx, Event{Type: ToolCall, Detail: "memory"})
+
+	event := <-events
+	if event.AgentID != "scheduler_agent" || event.Detail != "memory" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected only one event")
+	}
+}
@@ -0,0 +1,31 @@
+package progress
+
+import (
+	"context"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// reportingTool emits a ToolCall event whenever the wrapped tool runs
+type reportingTool struct {
+	multiagent.Tool
+}
+
+// WrapTool returns tool with its executions reported as ToolCall events
+func WrapTool(tool multiagent.Tool) multiagent.Tool {
+	return reportingTool{Tool: tool}
+}
+
+// Execute runs the wrapped tool and reports the call
+func (t reportingTool) Execute(ctx context.Context, args string) (string, error) {
+	started := time.Now()
+	result, err := t.Tool.Execute(ctx, args)
+
+	data := map[string]interface{}{"duration_ms": time.Since(started).Milliseconds()}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	Emit(ctx, Event{Type: ToolCall, Detail: t.Name(), Data: data})
+	return result, err
+}
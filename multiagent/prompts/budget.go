@@ -0,0 +1,201 @@
+package prompts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// ErrTooLarge is returned for a prompt that cannot be shrunk to its budget
+var ErrTooLarge = errors.New("prompt does not fit the model's context window")
+
+// truncatedMarker ends text cut short to fit a prompt in its budget
+const truncatedMarker = " …[truncated]"
+
+// minCutLength is the shortest text is cut to; lists are shortened after
+const minCutLength = 200
+
+// Budget bounds the size of a rendered prompt. A prompt over it is shrunk
+// before it is sent, instead of failing at the LLM provider: the oldest
+// conversation messages are left out first, then the longest text is cut,
+// then the last items of other lists are dropped. One that still does not
+// fit is ErrTooLarge.
+type Budget struct {
+	// MaxTokens is how many tokens the prompt may use; 0 is unlimited
+	MaxTokens int
+	// Count measures text in tokens
+	Count func(text string) int
+}
+
+// fit shrinks vars until t renders within budget
+func fit(ctx context.Context, t *Template, vars Vars, text string, budget Budget) (string, error) {
+	if budget.MaxTokens <= 0 || budget.Count == nil {
+		return text, nil
+	}
+	tokens := budget.Count(text)
+	if tokens <= budget.MaxTokens {
+		return text, nil
+	}
+
+	original := tokens
+	s := &shrinker{vars: make(Vars, len(vars)), history: map[string][]multiagent.ConversationMessage{}, leftOut: map[string]int{}}
+	for key, value := range vars {
+		s.vars[key] = value
+	}
+	for tokens > budget.MaxTokens {
+		// Cut about as much of the text as the prompt is over
+		excess := len(text)*(tokens-budget.MaxTokens)/tokens + 1
+		if !s.leaveOutHistory() && !s.cutLongestText(excess) && !s.dropListItem() {
+			return "", fmt.Errorf("%w: about %d tokens, %d allowed", ErrTooLarge, tokens, budget.MaxTokens)
+		}
+		var err error
+		if text, err = execute(t, s.vars); err != nil {
+			return "", err
+		}
+		tokens = budget.Count(text)
+	}
+	logger.InfoContext(ctx, "Shrank prompt to fit the context window", "prompt", t.Name, "tokens", original,
+		"max_tokens", budget.MaxTokens, "messages_left_out", s.messages, "texts_cut", s.cuts, "items_dropped", s.items)
+	return text, nil
+}
+
+// shrinker takes the least needed content out of a prompt's vars, copying
+// rather than changing the caller's maps and slices
+type shrinker struct {
+	vars    Vars
+	history map[string][]multiagent.ConversationMessage
+	leftOut map[string]int
+	// messages, cuts and items count what was taken out
+	messages, cuts, items int
+}
+
+// keys returns the vars' names in order, so shrinking is deterministic
+func (s *shrinker) keys() []string {
+	keys := make([]string, 0, len(s.vars))
+	for key := range s.vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// leaveOutHistory leaves the oldest message of a conversation out, noting
+// how many were, and always keeps the latest one
+func (s *shrinker) leaveOutHistory() bool {
+	for _, key := range s.keys() {
+		original, ok := s.history[key]
+		if !ok {
+			messages, isHistory := s.vars[key].([]multiagent.ConversationMessage)
+			if !isHistory {
+				continue
+			}
+			original = messages
+			s.history[key] = messages
+		}
+		if len(original)-s.leftOut[key] <= 1 {
+			continue
+		}
+		s.leftOut[key]++
+		s.messages++
+		note := multiagent.ConversationMessage{
+			Role:    "system",
+			Content: fmt.Sprintf("(%d earlier messages left out to fit the context window)", s.leftOut[key]),
+		}
+		s.vars[key] = append([]multiagent.ConversationMessage{note}, original[s.leftOut[key]:]...)
+		return true
+	}
+	return false
+}
+
+// cutLongestText cuts excess bytes from the longest string, whether a var
+// or a value of a map var, down to no less than minCutLength
+func (s *shrinker) cutLongestText(excess int) bool {
+	var longestKey string
+	var longestMapKey reflect.Value
+	longest := ""
+	for _, key := range s.keys() {
+		switch value := s.vars[key].(type) {
+		case string:
+			if len(value) > len(longest) {
+				longest, longestKey, longestMapKey = value, key, reflect.Value{}
+			}
+		default:
+			m := reflect.ValueOf(value)
+			if m.Kind() != reflect.Map {
+				continue
+			}
+			for iter := m.MapRange(); iter.Next(); {
+				if text, ok := stringValue(iter.Value()); ok && len(text) > len(longest) {
+					longest, longestKey, longestMapKey = text, key, iter.Key()
+				}
+			}
+		}
+	}
+	text := strings.TrimSuffix(longest, truncatedMarker)
+	if len(text) <= minCutLength {
+		return false
+	}
+
+	n := max(len(text)-excess-len(truncatedMarker), minCutLength)
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	cut := text[:n] + truncatedMarker
+	if !longestMapKey.IsValid() {
+		s.vars[longestKey] = cut
+	} else {
+		s.vars[longestKey] = withMapValue(reflect.ValueOf(s.vars[longestKey]), longestMapKey, cut)
+	}
+	s.cuts++
+	return true
+}
+
+// dropListItem drops the last item of the longest list other than history
+func (s *shrinker) dropListItem() bool {
+	longestKey, longest := "", 0
+	for _, key := range s.keys() {
+		if _, isHistory := s.vars[key].([]multiagent.ConversationMessage); isHistory {
+			continue
+		}
+		if list := reflect.ValueOf(s.vars[key]); list.Kind() == reflect.Slice && list.Len() > longest {
+			longestKey, longest = key, list.Len()
+		}
+	}
+	if longest == 0 {
+		return false
+	}
+	s.vars[longestKey] = reflect.ValueOf(s.vars[longestKey]).Slice(0, longest-1).Interface()
+	s.items++
+	return true
+}
+
+// stringValue returns value's text if it is a string, or holds one
+func stringValue(value reflect.Value) (string, bool) {
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if !value.IsValid() || value.Kind() != reflect.String {
+		return "", false
+	}
+	return value.String(), true
+}
+
+// withMapValue returns a copy of m with key set to text
+func withMapValue(m, key reflect.Value, text string) interface{} {
+	clone := reflect.MakeMapWithSize(m.Type(), m.Len())
+	for iter := m.MapRange(); iter.Next(); {
+		clone.SetMapIndex(iter.Key(), iter.Value())
+	}
+	value := reflect.ValueOf(text)
+	if elem := m.Type().Elem(); elem.Kind() == reflect.String {
+		value = value.Convert(elem)
+	}
+	clone.SetMapIndex(key, value)
+	return clone.Interface()
+}
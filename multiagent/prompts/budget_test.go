@@ -0,0 +1,83 @@
+package prompts
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// countWords measures text in words, to keep budgets readable
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestRenderWithinLeavesOutOldestHistoryFirst(t *testing.T) {
+	messages := []multiagent.ConversationMessage{
+		{Role: "user", Content: "one two " + strings.Repeat("words ", 30)},
+		{Role: "assistant", Content: "six seven " + strings.Repeat("words ", 30)},
+		{Role: "user", Content: "what is due today?"},
+	}
+	vars := Vars{"Name": "Ada", "Facts": nil, "Summary": "", "Recalled": nil, "Messages": messages, "Context": map[string]interface{}{"tasks": "3"}}
+	ctx := context.Background()
+
+	full, err := Default().Render(ctx, "conversation.reply", vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := Default().RenderWithin(ctx, "conversation.reply", vars, Budget{MaxTokens: countWords(full) - 10, Count: countWords})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(text, "one two") || !strings.Contains(text, "six seven") || !strings.Contains(text, "what is due today?") {
+		t.Errorf("prompt within budget:\n%s", text)
+	}
+	if !strings.Contains(text, "(1 earlier messages left out") {
+		t.Errorf("prompt does not say history was left out:\n%s", text)
+	}
+	if len(vars["Messages"].([]multiagent.ConversationMessage)) != 3 {
+		t.Error("the caller's messages were changed")
+	}
+}
+
+func TestRenderWithinCutsLongestTextThenLists(t *testing.T) {
+	ctx := context.Background()
+	results := strings.Repeat("word ", 500)
+	vars := Vars{"Context": results, "Query": "what is due?"}
+
+	text, err := Default().RenderWithin(ctx, "agent.query", vars, Budget{MaxTokens: 100, Count: countWords})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if countWords(text) > 100 || !strings.Contains(text, truncatedMarker) || !strings.Contains(text, "what is due?") {
+		t.Errorf("prompt within budget (%d words):\n%s", countWords(text), text)
+	}
+
+	// Nothing left to take out
+	if _, err := Default().RenderWithin(ctx, "agent.query", vars, Budget{MaxTokens: 3, Count: countWords}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("RenderWithin = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestShrinkerCutsMapValuesAndDropsListItems(t *testing.T) {
+	long := strings.Repeat("x", 1000)
+	responses := map[multiagent.AgentID]string{"research": long, "task": "done"}
+	s := &shrinker{vars: Vars{"Responses": responses, "Memories": []string{"a", "b", "c"}}, history: map[string][]multiagent.ConversationMessage{}, leftOut: map[string]int{}}
+
+	if !s.cutLongestText(500) {
+		t.Fatal("nothing cut")
+	}
+	cut := s.vars["Responses"].(map[multiagent.AgentID]string)
+	if len(cut["research"]) != 500 || !strings.HasSuffix(cut["research"], truncatedMarker) || cut["task"] != "done" {
+		t.Errorf("responses after the cut: research %d bytes, task %q", len(cut["research"]), cut["task"])
+	}
+	if len(responses["research"]) != 1000 {
+		t.Error("the caller's map was changed")
+	}
+
+	if !s.dropListItem() || len(s.vars["Memories"].([]string)) != 2 {
+		t.Errorf("memories after dropping an item: %v", s.vars["Memories"])
+	}
+}
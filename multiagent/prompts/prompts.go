@@ -0,0 +1,483 @@
+// Package prompts keeps the prompts agents send to LLMs as named, versioned
+// text/template files. The built-in versions are compiled in; a directory on
+// disk adds versions or replaces built-in ones, and is reloaded while
+// running, so prompts change without recompiling. Overrides pin a prompt to a
+// version or split conversations between versions for A/B comparisons.
+//
+// Built-in prompts are compiled in from templates/<name>.v<N>.tmpl.
+// ServiceConfig.PromptDir (-prompt-dir on the server) is watched and
+// reloaded on SIGHUP; a file that fails to parse keeps the current prompts.
+// The latest version renders unless -prompt-versions pins one, e.g.
+// "task.create=v2", or splits conversations between several, e.g.
+// "intent.classify=v1|v2". Renders are counted per version in
+// multiagent_prompt_renders_total. "wikillm assistant prompts --export
+// ./prompts" writes out the built-in prompts to start from, and --dir lists
+// what is active.
+package prompts
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/metrics"
+)
+
+//go:embed templates/*.tmpl
+var builtinFS embed.FS
+
+var logger = logging.For("prompts")
+
+// fileSuffix ends every prompt file name: <name>.v<version>.tmpl
+const fileSuffix = ".tmpl"
+
+// Vars are the values a prompt template is rendered with
+type Vars map[string]interface{}
+
+// funcs are available to every template
+var funcs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// Template is one version of a prompt
+type Template struct {
+	Name    string
+	Version int
+	// Source is "builtin" or the file the version was read from
+	Source string
+	Text   string
+	tmpl   *template.Template
+}
+
+// Prompt describes a prompt's versions and which one is rendered
+type Prompt struct {
+	Name     string `json:"name"`
+	Versions []int  `json:"versions"`
+	// Active lists the versions conversations are rendered with; more than
+	// one splits them for an A/B comparison
+	Active []int `json:"active"`
+	// Overridden is set when a version on disk replaces the built-in one
+	Overridden []int `json:"overridden,omitempty"`
+}
+
+// RegistryConfig holds configuration for creating a Registry
+type RegistryConfig struct {
+	// Dir holds prompt files that add versions or replace built-in ones;
+	// without it only the built-in prompts are used
+	Dir string
+	// Overrides pins prompts to versions, e.g. {"task.create": "v2"}, or
+	// splits conversations between them, e.g. {"intent.classify": "v1|v2"};
+	// prompts without one render their latest version
+	Overrides map[string]string
+	// Metrics, if set, counts renders per prompt and version
+	Metrics *metrics.Registry
+}
+
+// Registry renders prompts by name
+type Registry struct {
+	dir       string
+	overrides map[string][]int
+	builtin   map[string]map[int]*Template
+	renders   *metrics.Counter
+
+	mu          sync.RWMutex
+	disk        map[string]map[int]*Template
+	fingerprint string
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultRegistry *Registry
+)
+
+// Default returns a registry of the built-in prompts only
+func Default() *Registry {
+	defaultOnce.Do(func() {
+		registry, err := NewRegistry(RegistryConfig{})
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in prompts: %v", err))
+		}
+		defaultRegistry = registry
+	})
+	return defaultRegistry
+}
+
+// NewRegistry loads the built-in prompts and those in config.Dir
+func NewRegistry(config RegistryConfig) (*Registry, error) {
+	builtin, err := loadFS(builtinFS, "templates", "builtin")
+	if err != nil {
+		return nil, err
+	}
+	registry := &Registry{
+		dir:       config.Dir,
+		overrides: make(map[string][]int, len(config.Overrides)),
+		builtin:   builtin,
+		disk:      map[string]map[int]*Template{},
+	}
+	for name, spec := range config.Overrides {
+		versions, err := parseVersions(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid override for prompt %q: %w", name, err)
+		}
+		registry.overrides[name] = versions
+	}
+	if config.Metrics != nil {
+		registry.renders = config.Metrics.NewCounter("multiagent_prompt_renders_total",
+			"Prompts rendered, by prompt and version", "prompt", "version")
+	}
+	if err := registry.Reload(); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// Render renders the version of prompt name chosen for ctx's conversation.
+// A version from disk that fails to render falls back to the built-in one.
+func (r *Registry) Render(ctx context.Context, name string, vars Vars) (string, error) {
+	return r.RenderWithin(ctx, name, vars, Budget{})
+}
+
+// RenderWithin renders prompt name like Render, shrinking vars until the
+// prompt fits budget (see Budget)
+func (r *Registry) RenderWithin(ctx context.Context, name string, vars Vars, budget Budget) (string, error) {
+	t, ok := r.choose(ctx, name)
+	if !ok {
+		return "", fmt.Errorf("unknown prompt %q", name)
+	}
+	text, err := execute(t, vars)
+	if err != nil && t.Source != "builtin" {
+		logger.WarnContext(ctx, "Prompt from disk failed to render, using the built-in one", "prompt", name, "version", t.Version, "source", t.Source, "error", err)
+		if fallback, ok := r.builtinVersion(name, t.Version); ok {
+			t = fallback
+			text, err = execute(t, vars)
+		}
+	}
+	if err == nil {
+		text, err = fit(ctx, t, vars, text, budget)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to render prompt %s v%d: %w", name, t.Version, err)
+	}
+	if r.renders != nil {
+		r.renders.Inc(name, "v"+strconv.Itoa(t.Version))
+	}
+	logger.DebugContext(ctx, "Rendered prompt", "prompt", name, "version", t.Version, "source", t.Source)
+	return text, nil
+}
+
+// Template returns version of prompt name, preferring the one on disk
+func (r *Registry) Template(name string, version int) (*Template, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if t, ok := r.disk[name][version]; ok {
+		return t, true
+	}
+	t, ok := r.builtin[name][version]
+	return t, ok
+}
+
+// List describes every prompt, sorted by name
+func (r *Registry) List() []Prompt {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := map[string]bool{}
+	for name := range r.builtin {
+		names[name] = true
+	}
+	for name := range r.disk {
+		names[name] = true
+	}
+	prompts := make([]Prompt, 0, len(names))
+	for name := range names {
+		versions := r.versions(name)
+		prompt := Prompt{Name: name, Versions: versions, Active: r.active(name, versions)}
+		for version := range r.disk[name] {
+			if _, ok := r.builtin[name][version]; ok {
+				prompt.Overridden = append(prompt.Overridden, version)
+			}
+		}
+		sort.Ints(prompt.Overridden)
+		prompts = append(prompts, prompt)
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts
+}
+
+// Reload re-reads the prompt directory; if a file in it is invalid the
+// current prompts are kept
+func (r *Registry) Reload() error {
+	if r.dir == "" {
+		return nil
+	}
+	fingerprint, err := r.dirFingerprint()
+	if err != nil {
+		return err
+	}
+	disk, err := loadFS(os.DirFS(r.dir), ".", r.dir)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.disk = disk
+	r.fingerprint = fingerprint
+	r.mu.Unlock()
+	logger.Info("Loaded prompts", "dir", r.dir, "prompts", len(disk))
+	return nil
+}
+
+// Watch reloads the prompt directory whenever its files change, checking
+// every interval until ctx is done
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	if r.dir == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		fingerprint, err := r.dirFingerprint()
+		if err != nil {
+			logger.Warn("Failed to check the prompt directory", "dir", r.dir, "error", err)
+			continue
+		}
+		r.mu.RLock()
+		changed := fingerprint != r.fingerprint
+		r.mu.RUnlock()
+		if !changed {
+			continue
+		}
+		if err := r.Reload(); err != nil {
+			logger.Error("Failed to reload prompts, keeping the current ones", "dir", r.dir, "error", err)
+			r.mu.Lock()
+			r.fingerprint = fingerprint // Wait for the next change before retrying
+			r.mu.Unlock()
+		}
+	}
+}
+
+// ExportBuiltin writes the built-in prompts to dir as a starting point for
+// editing them
+func ExportBuiltin(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create prompt directory: %w", err)
+	}
+	entries, err := fs.ReadDir(builtinFS, "templates")
+	if err != nil {
+		return fmt.Errorf("failed to read built-in prompts: %w", err)
+	}
+	for _, entry := range entries {
+		data, err := fs.ReadFile(builtinFS, "templates/"+entry.Name())
+		if err != nil {
+			return fmt.Errorf("failed to read built-in prompt %s: %w", entry.Name(), err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, entry.Name()), data, 0644); err != nil {
+			return fmt.Errorf("failed to write prompt %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// choose picks the version of name to render for ctx: the latest, the
+// pinned one, or one of an A/B split by conversation
+func (r *Registry) choose(ctx context.Context, name string) (*Template, bool) {
+	r.mu.RLock()
+	versions := r.versions(name)
+	active := r.active(name, versions)
+	r.mu.RUnlock()
+	if len(active) == 0 {
+		return nil, false
+	}
+
+	version := active[0]
+	if len(active) > 1 {
+		// A conversation keeps its variant, so its turns are comparable
+		key := logging.Field(ctx, logging.KeyConversationID)
+		if key == "" {
+			key = logging.Field(ctx, logging.KeyUserID)
+		}
+		hash := fnv.New32a()
+		hash.Write([]byte(name + "\x00" + key))
+		version = active[hash.Sum32()%uint32(len(active))]
+	}
+	return r.Template(name, version)
+}
+
+// versions returns every version of name, ascending; the caller holds r.mu
+func (r *Registry) versions(name string) []int {
+	seen := map[int]bool{}
+	for version := range r.builtin[name] {
+		seen[version] = true
+	}
+	for version := range r.disk[name] {
+		seen[version] = true
+	}
+	versions := make([]int, 0, len(seen))
+	for version := range seen {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// active returns the versions of name that are rendered: its override's
+// available versions, or the latest
+func (r *Registry) active(name string, versions []int) []int {
+	if len(versions) == 0 {
+		return nil
+	}
+	var active []int
+	for _, version := range r.overrides[name] {
+		for _, available := range versions {
+			if version == available {
+				active = append(active, version)
+			}
+		}
+	}
+	if len(active) == 0 {
+		active = []int{versions[len(versions)-1]}
+	}
+	return active
+}
+
+// builtinVersion returns the built-in version of name, or its latest
+// built-in version when that one was added on disk
+func (r *Registry) builtinVersion(name string, version int) (*Template, bool) {
+	versions := r.builtin[name]
+	if t, ok := versions[version]; ok {
+		return t, true
+	}
+	latest := 0
+	for v := range versions {
+		if v > latest {
+			latest = v
+		}
+	}
+	t, ok := versions[latest]
+	return t, ok
+}
+
+func (r *Registry) dirFingerprint() (string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt directory: %w", err)
+	}
+	var b strings.Builder
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), fileSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+// loadFS parses the prompt files in dir of fsys
+func loadFS(fsys fs.FS, dir, source string) (map[string]map[int]*Template, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompts: %w", err)
+	}
+	templates := map[string]map[int]*Template{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), fileSuffix) {
+			continue
+		}
+		name, version, err := parseFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		data, err := fs.ReadFile(fsys, filepath.ToSlash(filepath.Join(dir, entry.Name())))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt %s: %w", entry.Name(), err)
+		}
+		text := strings.TrimSpace(string(data))
+		tmpl, err := template.New(entry.Name()).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse prompt %s: %w", entry.Name(), err)
+		}
+		if templates[name] == nil {
+			templates[name] = map[int]*Template{}
+		}
+		t := &Template{Name: name, Version: version, Source: source, Text: text, tmpl: tmpl}
+		if source != "builtin" {
+			t.Source = filepath.Join(source, entry.Name())
+		}
+		templates[name][version] = t
+	}
+	return templates, nil
+}
+
+// parseFileName splits "task.create.v2.tmpl" into "task.create" and 2
+func parseFileName(file string) (string, int, error) {
+	base := strings.TrimSuffix(file, fileSuffix)
+	dot := strings.LastIndex(base, ".v")
+	if dot <= 0 {
+		return "", 0, fmt.Errorf("prompt file %s is not named <name>.v<version>%s", file, fileSuffix)
+	}
+	version, err := strconv.Atoi(base[dot+2:])
+	if err != nil || version <= 0 {
+		return "", 0, fmt.Errorf("prompt file %s has an invalid version", file)
+	}
+	return base[:dot], version, nil
+}
+
+// parseVersions parses an override such as "v2" or "v1|v2"
+func parseVersions(spec string) ([]int, error) {
+	var versions []int
+	for _, part := range strings.Split(spec, "|") {
+		version, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(part), "v"))
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid version %q", part)
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// ParseOverrides parses overrides written as "task.create=v2,intent.classify=v1|v2"
+func ParseOverrides(spec string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, versions, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid prompt override %q: want name=version", pair)
+		}
+		if _, err := parseVersions(versions); err != nil {
+			return nil, fmt.Errorf("invalid prompt override %q: %w", pair, err)
+		}
+		overrides[strings.TrimSpace(name)] = strings.TrimSpace(versions)
+	}
+	return overrides, nil
+}
+
+func execute(t *Template, vars Vars) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, vars); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,139 @@
+package prompts
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+func TestBuiltinPromptsLoad(t *testing.T) {
+	registry := Default()
+	prompts := registry.List()
+	if len(prompts) == 0 {
+		t.Fatal("no built-in prompts")
+	}
+	for _, prompt := range prompts {
+		if len(prompt.Active) != 1 || prompt.Active[0] != prompt.Versions[len(prompt.Versions)-1] {
+			t.Errorf("prompt %s renders %v, want its latest of %v", prompt.Name, prompt.Active, prompt.Versions)
+		}
+	}
+
+	text, err := registry.Render(context.Background(), "agent.query", Vars{"Context": "3 tasks", "Query": "what is due?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "what is due?") {
+		t.Errorf("rendered prompt %q is missing the query", text)
+	}
+	if _, err := registry.Render(context.Background(), "agent.query", Vars{"Context": "3 tasks"}); err == nil {
+		t.Error("rendered a prompt with a missing variable")
+	}
+	if _, err := registry.Render(context.Background(), "no.such.prompt", nil); err == nil {
+		t.Error("rendered an unknown prompt")
+	}
+}
+
+func writePrompt(t *testing.T, dir, file, text string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiskPromptsOverrideAndReload(t *testing.T) {
+	dir := t.TempDir()
+	writePrompt(t, dir, "agent.query.v1.tmpl", "custom {{.Query}}")
+	registry, err := NewRegistry(RegistryConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if text, _ := registry.Render(ctx, "agent.query", Vars{"Context": "3 tasks", "Query": "hi"}); text != "custom hi" {
+		t.Errorf("rendered %q, want the version on disk", text)
+	}
+
+	writePrompt(t, dir, "agent.query.v2.tmpl", "second {{.Query}}")
+	if err := registry.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := registry.Render(ctx, "agent.query", Vars{"Context": "3 tasks", "Query": "hi"}); text != "second hi" {
+		t.Errorf("rendered %q after reload, want the new latest version", text)
+	}
+
+	// An invalid file keeps the current prompts
+	writePrompt(t, dir, "agent.query.v3.tmpl", "broken {{.Query")
+	if err := registry.Reload(); err == nil {
+		t.Error("reloaded an invalid prompt")
+	}
+	if text, _ := registry.Render(ctx, "agent.query", Vars{"Context": "3 tasks", "Query": "hi"}); text != "second hi" {
+		t.Errorf("rendered %q after a failed reload, want the previous prompts", text)
+	}
+}
+
+func TestDiskPromptFallsBackToBuiltin(t *testing.T) {
+	dir := t.TempDir()
+	writePrompt(t, dir, "agent.query.v1.tmpl", "{{.Missing}}")
+	registry, err := NewRegistry(RegistryConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := registry.Render(context.Background(), "agent.query", Vars{"Context": "3 tasks", "Query": "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "hi") || strings.Contains(text, "no value") {
+		t.Errorf("rendered %q, want the built-in prompt", text)
+	}
+}
+
+func TestOverridesSplitConversations(t *testing.T) {
+	dir := t.TempDir()
+	writePrompt(t, dir, "greet.v1.tmpl", "a")
+	writePrompt(t, dir, "greet.v2.tmpl", "b")
+	writePrompt(t, dir, "greet.v3.tmpl", "c")
+
+	pinned, err := NewRegistry(RegistryConfig{Dir: dir, Overrides: map[string]string{"greet": "v1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := pinned.Render(context.Background(), "greet", nil); text != "a" {
+		t.Errorf("pinned prompt rendered %q, want v1", text)
+	}
+
+	split, err := NewRegistry(RegistryConfig{Dir: dir, Overrides: map[string]string{"greet": "v1|v2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		ctx := logging.WithFields(context.Background(), logging.KeyConversationID, fmt.Sprintf("conv_%d", i))
+		first, _ := split.Render(ctx, "greet", nil)
+		if again, _ := split.Render(ctx, "greet", nil); again != first {
+			t.Fatalf("conversation %d switched from %q to %q", i, first, again)
+		}
+		seen[first] = true
+	}
+	if !seen["a"] || !seen["b"] || seen["c"] {
+		t.Errorf("split rendered %v, want both v1 and v2 only", seen)
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+	overrides, err := ParseOverrides("task.create=v2, intent.classify=v1|v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overrides["task.create"] != "v2" || overrides["intent.classify"] != "v1|v2" {
+		t.Errorf("parsed %v", overrides)
+	}
+	for _, spec := range []string{"task.create", "task.create=latest", "task.create=v0"} {
+		if _, err := ParseOverrides(spec); err == nil {
+			t.Errorf("parsed invalid overrides %q", spec)
+		}
+	}
+}
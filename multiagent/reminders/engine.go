@@ -0,0 +1,467 @@
+// Package reminders schedules reminders for every agent in one place: a
+// priority queue of trigger times served by a single timer, with snoozing,
+// repetition and persistence in each user's memory partition. Agents
+// register a Handler per source that decides what a due reminder says and
+// when it fires next.
+package reminders
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/notify"
+)
+
+var logger = logging.For("reminders")
+
+// keyPrefix is where reminders are stored in their user's partition
+const keyPrefix = "scheduled_reminder:"
+
+// ErrNotFound is returned for reminders that are not scheduled
+var ErrNotFound = errors.New("reminder not found")
+
+// Reminder is one scheduled reminder
+type Reminder struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id,omitempty"`
+	// Source names the Handler that renders the reminder, e.g. "task_manager"
+	Source string `json:"source"`
+	// Subject is the ID of the task, event or other entity it is about
+	Subject string `json:"subject,omitempty"`
+
+	// Title, Body, Kind and Priority make up the notification when the
+	// source has no handler
+	Title    string              `json:"title"`
+	Body     string              `json:"body,omitempty"`
+	Kind     string              `json:"kind,omitempty"`
+	Priority multiagent.Priority `json:"priority"`
+
+	TriggerAt time.Time `json:"trigger_at"`
+	// Interval, if set, repeats the reminder that often after TriggerAt
+	Interval time.Duration `json:"interval,omitempty"`
+	// SnoozedUntil postpones the next firing without moving TriggerAt
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+
+	Fired     int        `json:"fired"`
+	LastFired *time.Time `json:"last_fired,omitempty"`
+	// Data is for the handler's own bookkeeping
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// Due returns when the reminder fires next
+func (r *Reminder) Due() time.Time {
+	if r.SnoozedUntil != nil {
+		return *r.SnoozedUntil
+	}
+	return r.TriggerAt
+}
+
+// Handler renders a due reminder for its source. It returns the
+// notification to deliver (nil to fire silently, e.g. for a task already
+// done) and when the reminder fires next; the zero time ends a reminder
+// unless it repeats by Interval.
+type Handler func(ctx context.Context, reminder Reminder, now time.Time) (*notify.Notification, time.Time)
+
+// EngineConfig holds configuration for creating an Engine
+type EngineConfig struct {
+	// Store persists reminders; a user-partitioned store keeps each user's
+	// reminders in their own partition
+	Store multiagent.MemoryStore
+	// Notifier delivers fired reminders; without one they only run handlers
+	Notifier notify.Notifier
+}
+
+// Engine fires scheduled reminders at their trigger times
+type Engine struct {
+	store    multiagent.MemoryStore
+	notifier notify.Notifier
+
+	mu       sync.Mutex
+	queue    reminderQueue
+	byID     map[string]*item
+	handlers map[string]Handler
+
+	wake     chan struct{}
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+}
+
+// item is a queued reminder; firing items stay in byID but leave the queue
+type item struct {
+	reminder Reminder
+	index    int
+}
+
+// NewEngine creates a reminder engine; Start begins firing reminders
+func NewEngine(config EngineConfig) *Engine {
+	return &Engine{
+		store:    config.Store,
+		notifier: config.Notifier,
+		byID:     make(map[string]*item),
+		handlers: make(map[string]Handler),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler for reminders from source
+func (e *Engine) Register(source string, handler Handler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.handlers[source] = handler
+}
+
+// Schedule adds reminder, replacing any with the same ID, and persists it
+func (e *Engine) Schedule(ctx context.Context, reminder Reminder) error {
+	if reminder.ID == "" {
+		return fmt.Errorf("reminder ID is required")
+	}
+	if reminder.TriggerAt.IsZero() {
+		return fmt.Errorf("reminder %s has no trigger time", reminder.ID)
+	}
+
+	e.mu.Lock()
+	e.remove(reminder.ID)
+	e.push(reminder)
+	e.mu.Unlock()
+
+	e.signal()
+	return e.save(ctx, reminder)
+}
+
+// Cancel removes a reminder
+func (e *Engine) Cancel(ctx context.Context, id string) error {
+	e.mu.Lock()
+	existing, ok := e.byID[id]
+	if ok {
+		e.remove(id)
+	}
+	e.mu.Unlock()
+
+	if !ok {
+		return ErrNotFound
+	}
+	e.signal()
+	return e.delete(ctx, existing.reminder)
+}
+
+// Snooze postpones a scheduled reminder's next firing until the given time
+func (e *Engine) Snooze(ctx context.Context, id string, until time.Time) error {
+	e.mu.Lock()
+	existing, ok := e.byID[id]
+	if !ok {
+		e.mu.Unlock()
+		return ErrNotFound
+	}
+	reminder := existing.reminder
+	reminder.SnoozedUntil = &until
+	e.remove(id)
+	e.push(reminder)
+	e.mu.Unlock()
+
+	e.signal()
+	return e.save(ctx, reminder)
+}
+
+// Get returns a scheduled reminder
+func (e *Engine) Get(id string) (Reminder, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	existing, ok := e.byID[id]
+	if !ok {
+		return Reminder{}, false
+	}
+	return existing.reminder, true
+}
+
+// List returns userID's scheduled reminders, soonest first
+func (e *Engine) List(userID string) []Reminder {
+	e.mu.Lock()
+	var list []Reminder
+	for _, existing := range e.byID {
+		if existing.reminder.UserID == userID {
+			list = append(list, existing.reminder)
+		}
+	}
+	e.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Due().Before(list[j].Due()) })
+	return list
+}
+
+// Load schedules the persisted reminders of the user ctx acts for, e.g. at
+// startup; reminders already scheduled are kept
+func (e *Engine) Load(ctx context.Context) (int, error) {
+	if e.store == nil {
+		return 0, nil
+	}
+	keys, err := e.store.List(ctx, keyPrefix, 10000)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list reminders: %w", err)
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	values, err := e.store.GetMultiple(ctx, keys)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load reminders: %w", err)
+	}
+
+	loaded := 0
+	e.mu.Lock()
+	for _, value := range values {
+		var reminder Reminder
+		data, err := json.Marshal(value)
+		if err != nil || json.Unmarshal(data, &reminder) != nil || reminder.ID == "" {
+			continue
+		}
+		reminder.UserID = multiagent.UserIDFromContext(ctx)
+		if _, ok := e.byID[reminder.ID]; ok {
+			continue
+		}
+		e.push(reminder)
+		loaded++
+	}
+	e.mu.Unlock()
+
+	e.signal()
+	return loaded, nil
+}
+
+// PurgeUser forgets userID's scheduled reminders; their stored copies go
+// with the user's memory partition
+func (e *Engine) PurgeUser(userID string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	purged := 0
+	for id, existing := range e.byID {
+		if existing.reminder.UserID == userID {
+			e.remove(id)
+			purged++
+		}
+	}
+	return purged
+}
+
+// Start begins firing reminders as they come due
+func (e *Engine) Start() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.running {
+		return
+	}
+	e.running = true
+	e.stopChan = make(chan struct{})
+	e.wg.Add(1)
+	go e.run(e.stopChan)
+}
+
+// Stop stops firing reminders and waits for any being delivered
+func (e *Engine) Stop() {
+	e.mu.Lock()
+	if !e.running {
+		e.mu.Unlock()
+		return
+	}
+	e.running = false
+	close(e.stopChan)
+	e.mu.Unlock()
+	e.wg.Wait()
+}
+
+func (e *Engine) run(stop chan struct{}) {
+	defer e.wg.Done()
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		e.FireDue(context.Background(), time.Now())
+
+		// Sleep until the earliest reminder, or a change to the queue
+		wait := time.Hour
+		e.mu.Lock()
+		if len(e.queue) > 0 {
+			wait = time.Until(e.queue[0].reminder.Due())
+		}
+		e.mu.Unlock()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(max(wait, 0))
+
+		select {
+		case <-timer.C:
+		case <-e.wake:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// FireDue fires every reminder due at now and returns how many fired
+func (e *Engine) FireDue(ctx context.Context, now time.Time) int {
+	var due []*item
+	e.mu.Lock()
+	for len(e.queue) > 0 && !e.queue[0].reminder.Due().After(now) {
+		due = append(due, heap.Pop(&e.queue).(*item))
+	}
+	e.mu.Unlock()
+
+	for _, fired := range due {
+		e.fire(ctx, fired, now)
+	}
+	return len(due)
+}
+
+// fire delivers one due reminder and schedules its next firing, unless it
+// was rescheduled or cancelled meanwhile
+func (e *Engine) fire(ctx context.Context, fired *item, now time.Time) {
+	reminder := fired.reminder
+	ownerCtx := multiagent.WithUserID(ctx, reminder.UserID)
+
+	e.mu.Lock()
+	handler := e.handlers[reminder.Source]
+	e.mu.Unlock()
+
+	var notification *notify.Notification
+	var next time.Time
+	if handler != nil {
+		notification, next = handler(ownerCtx, reminder, now)
+	} else {
+		notification = &notify.Notification{Kind: reminder.Kind, Title: reminder.Title, Body: reminder.Body, Priority: reminder.Priority}
+	}
+	if notification != nil && e.notifier != nil {
+		if notification.UserID == "" {
+			notification.UserID = reminder.UserID
+		}
+		if notification.Subject == "" {
+			notification.Subject = reminder.Subject
+		}
+		if err := e.notifier.Notify(ownerCtx, *notification); err != nil {
+			logger.WarnContext(ownerCtx, "Failed to deliver reminder", "reminder_id", reminder.ID, "source", reminder.Source, "error", err)
+		}
+	}
+
+	reminder.Fired++
+	reminder.LastFired = &now
+	reminder.SnoozedUntil = nil
+	if next.IsZero() && reminder.Interval > 0 {
+		next = reminder.TriggerAt
+		for !next.After(now) {
+			next = next.Add(reminder.Interval)
+		}
+	}
+
+	e.mu.Lock()
+	if e.byID[reminder.ID] != fired {
+		// Rescheduled or cancelled while firing
+		e.mu.Unlock()
+		return
+	}
+	delete(e.byID, reminder.ID)
+	if !next.IsZero() {
+		reminder.TriggerAt = next
+		e.push(reminder)
+	}
+	e.mu.Unlock()
+
+	if next.IsZero() {
+		if err := e.delete(ownerCtx, reminder); err != nil {
+			logger.WarnContext(ownerCtx, "Failed to delete fired reminder", "reminder_id", reminder.ID, "error", err)
+		}
+		return
+	}
+	if err := e.save(ownerCtx, reminder); err != nil {
+		logger.WarnContext(ownerCtx, "Failed to save rescheduled reminder", "reminder_id", reminder.ID, "error", err)
+	}
+}
+
+// push queues reminder; callers hold mu
+func (e *Engine) push(reminder Reminder) {
+	queued := &item{reminder: reminder}
+	e.byID[reminder.ID] = queued
+	heap.Push(&e.queue, queued)
+}
+
+// remove drops a reminder, queued or firing; callers hold mu
+func (e *Engine) remove(id string) {
+	existing, ok := e.byID[id]
+	if !ok {
+		return
+	}
+	delete(e.byID, id)
+	if existing.index >= 0 {
+		heap.Remove(&e.queue, existing.index)
+	}
+}
+
+// signal wakes the run loop to recompute its timer
+func (e *Engine) signal() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (e *Engine) save(ctx context.Context, reminder Reminder) error {
+	if e.store == nil {
+		return nil
+	}
+	ownerCtx := multiagent.WithUserID(ctx, reminder.UserID)
+	if err := e.store.Store(ownerCtx, keyPrefix+reminder.ID, reminder); err != nil {
+		return fmt.Errorf("failed to save reminder %s: %w", reminder.ID, err)
+	}
+	return nil
+}
+
+func (e *Engine) delete(ctx context.Context, reminder Reminder) error {
+	if e.store == nil {
+		return nil
+	}
+	ownerCtx := multiagent.WithUserID(ctx, reminder.UserID)
+	if err := e.store.Delete(ownerCtx, keyPrefix+reminder.ID); err != nil {
+		return fmt.Errorf("failed to delete reminder %s: %w", reminder.ID, err)
+	}
+	return nil
+}
+
+// reminderQueue is a min-heap of reminders by when they are due
+type reminderQueue []*item
+
+func (q reminderQueue) Len() int { return len(q) }
+
+func (q reminderQueue) Less(i, j int) bool {
+	return q[i].reminder.Due().Before(q[j].reminder.Due())
+}
+
+func (q reminderQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *reminderQueue) Push(x any) {
+	queued := x.(*item)
+	queued.index = len(*q)
+	*q = append(*q, queued)
+}
+
+func (q *reminderQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	old[len(old)-1] = nil
+	last.index = -1
+	*q = old[:len(old)-1]
+	return last
+}
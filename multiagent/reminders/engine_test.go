@@ -0,0 +1,202 @@
+package reminders
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/notify"
+)
+
+// recordingNotifier remembers what it was asked to deliver
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []notify.Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification notify.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func (n *recordingNotifier) titles() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var titles []string
+	for _, notification := range n.sent {
+		titles = append(titles, notification.UserID+":"+notification.Title)
+	}
+	return titles
+}
+
+func newTestEngine(t *testing.T) (*Engine, *recordingNotifier, multiagent.MemoryStore) {
+	t.Helper()
+	fileStore, err := memory.NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { fileStore.Close() })
+	store := memory.PartitionByUser(fileStore)
+	notifier := &recordingNotifier{}
+	return NewEngine(EngineConfig{Store: store, Notifier: notifier}), notifier, store
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEngine_FiresInOrder(t *testing.T) {
+	engine, notifier, _ := newTestEngine(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	for _, r := range []Reminder{
+		{ID: "late", UserID: "bob", Title: "late", TriggerAt: base.Add(2 * time.Hour)},
+		{ID: "early", UserID: "alice", Title: "early", TriggerAt: base},
+		{ID: "middle", UserID: "alice", Title: "middle", TriggerAt: base.Add(time.Hour)},
+	} {
+		if err := engine.Schedule(ctx, r); err != nil {
+			t.Fatalf("Schedule: %v", err)
+		}
+	}
+
+	if fired := engine.FireDue(ctx, base.Add(90*time.Minute)); fired != 2 {
+		t.Errorf("fired %d, want 2", fired)
+	}
+	if got, want := notifier.titles(), []string{"alice:early", "alice:middle"}; !equal(got, want) {
+		t.Errorf("delivered %v, want %v", got, want)
+	}
+	if _, ok := engine.Get("early"); ok {
+		t.Error("expected a one-off reminder to be removed once fired")
+	}
+	if list := engine.List("bob"); len(list) != 1 || list[0].ID != "late" {
+		t.Errorf("bob's reminders = %+v", list)
+	}
+}
+
+func TestEngine_SnoozeAndRepeat(t *testing.T) {
+	engine, notifier, _ := newTestEngine(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	if err := engine.Schedule(ctx, Reminder{ID: "water", UserID: "alice", Title: "water", TriggerAt: base, Interval: 24 * time.Hour}); err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+	if err := engine.Snooze(ctx, "water", base.Add(10*time.Minute)); err != nil {
+		t.Fatalf("Snooze: %v", err)
+	}
+	if fired := engine.FireDue(ctx, base.Add(5*time.Minute)); fired != 0 {
+		t.Errorf("expected a snoozed reminder to wait, fired %d", fired)
+	}
+	engine.FireDue(ctx, base.Add(10*time.Minute))
+
+	// Repeats keep to the original schedule, not the snoozed time
+	got, ok := engine.Get("water")
+	if !ok || !got.TriggerAt.Equal(base.Add(24*time.Hour)) || got.SnoozedUntil != nil || got.Fired != 1 {
+		t.Errorf("after firing: %+v", got)
+	}
+	if len(notifier.titles()) != 1 {
+		t.Errorf("delivered %v", notifier.titles())
+	}
+	if err := engine.Snooze(ctx, "missing", base); err != ErrNotFound {
+		t.Errorf("Snooze(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestEngine_Handlers(t *testing.T) {
+	engine, notifier, _ := newTestEngine(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	// A source's handler renders its reminders and picks the next firing
+	engine.Register("standup", func(ctx context.Context, r Reminder, now time.Time) (*notify.Notification, time.Time) {
+		if multiagent.UserIDFromContext(ctx) != r.UserID {
+			t.Errorf("handler acts for %q, want %q", multiagent.UserIDFromContext(ctx), r.UserID)
+		}
+		if r.Fired >= 1 {
+			return nil, time.Time{}
+		}
+		return &notify.Notification{Title: "standup #" + r.Data["n"]}, r.TriggerAt.Add(time.Hour)
+	})
+	if err := engine.Schedule(ctx, Reminder{ID: "s", UserID: "alice", Source: "standup", TriggerAt: base, Data: map[string]string{"n": "1"}}); err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+
+	engine.FireDue(ctx, base)
+	if got, ok := engine.Get("s"); !ok || !got.TriggerAt.Equal(base.Add(time.Hour)) {
+		t.Errorf("expected the handler's next time, got %+v", got)
+	}
+	// The second firing is silent and ends the reminder
+	engine.FireDue(ctx, base.Add(time.Hour))
+	if _, ok := engine.Get("s"); ok {
+		t.Error("expected the reminder to end")
+	}
+	if got := notifier.titles(); !equal(got, []string{"alice:standup #1"}) {
+		t.Errorf("delivered %v", got)
+	}
+}
+
+func TestEngine_PersistsPerUser(t *testing.T) {
+	engine, _, store := newTestEngine(t)
+	ctx := context.Background()
+	trigger := time.Now().Add(time.Hour)
+
+	for _, r := range []Reminder{
+		{ID: "a1", UserID: "alice", Title: "one", TriggerAt: trigger},
+		{ID: "a2", UserID: "alice", Title: "two", TriggerAt: trigger},
+		{ID: "b1", UserID: "bob", Title: "three", TriggerAt: trigger},
+	} {
+		if err := engine.Schedule(ctx, r); err != nil {
+			t.Fatalf("Schedule: %v", err)
+		}
+	}
+	if err := engine.Cancel(ctx, "a2"); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	restarted := NewEngine(EngineConfig{Store: store})
+	loaded, err := restarted.Load(multiagent.WithUserID(ctx, "alice"))
+	if err != nil || loaded != 1 {
+		t.Fatalf("Load(alice) = %d, %v; want 1", loaded, err)
+	}
+	if list := restarted.List("alice"); len(list) != 1 || list[0].ID != "a1" || !list[0].TriggerAt.Equal(trigger) {
+		t.Errorf("alice's reminders after restart = %+v", list)
+	}
+	if list := restarted.List("bob"); len(list) != 0 {
+		t.Errorf("expected bob's reminders to stay unloaded, got %+v", list)
+	}
+
+	if purged := restarted.PurgeUser("alice"); purged != 1 {
+		t.Errorf("PurgeUser = %d, want 1", purged)
+	}
+}
+
+func TestEngine_Run(t *testing.T) {
+	engine, notifier, _ := newTestEngine(t)
+	engine.Start()
+	defer engine.Stop()
+
+	if err := engine.Schedule(context.Background(), Reminder{ID: "soon", UserID: "alice", Title: "soon", TriggerAt: time.Now().Add(20 * time.Millisecond)}); err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(notifier.titles()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := notifier.titles(); !equal(got, []string{"alice:soon"}) {
+		t.Errorf("delivered %v", got)
+	}
+}
@@ -0,0 +1,65 @@
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authorizationHeader carries "Bearer <token>" on both transports
+const authorizationHeader = "authorization"
+
+// bearerToken attaches a bearer token to every outgoing gRPC call
+type bearerToken struct {
+	token      string
+	secureOnly bool
+}
+
+func (b bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{authorizationHeader: "Bearer " + b.token}, nil
+}
+
+func (b bearerToken) RequireTransportSecurity() bool {
+	return b.secureOnly
+}
+
+// TokenAuthInterceptor rejects gRPC calls that don't carry "Bearer <token>";
+// install it with grpc.UnaryInterceptor when serving an orchestrator or agent
+func TokenAuthInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var presented string
+		if values := md.Get(authorizationHeader); len(values) > 0 {
+			presented = values[0]
+		}
+		if !validBearer(presented, token) {
+			return nil, status.Error(codes.Unauthenticated, "invalid or missing bearer token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// requireToken wraps an HTTP handler with the same bearer token check
+func requireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validBearer(r.Header.Get(authorizationHeader), token) {
+			http.Error(w, "invalid or missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func validBearer(header, token string) bool {
+	presented, ok := strings.CutPrefix(header, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
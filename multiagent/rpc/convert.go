@@ -0,0 +1,262 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	pb "github.com/kbutz/wikillm/multiagent/rpc/multiagentpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// MessageToProto converts a message for the wire
+func MessageToProto(msg *multiagent.Message) (*pb.Message, error) {
+	if msg == nil {
+		return nil, nil
+	}
+	context, err := toStruct(msg.Context)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert message context: %w", err)
+	}
+
+	to := make([]string, len(msg.To))
+	for i, id := range msg.To {
+		to[i] = string(id)
+	}
+	return &pb.Message{
+		Id:          msg.ID,
+		From:        string(msg.From),
+		To:          to,
+		Type:        string(msg.Type),
+		Content:     msg.Content,
+		Context:     context,
+		Priority:    pb.Priority(msg.Priority),
+		ReplyTo:     msg.ReplyTo,
+		Timestamp:   toTimestamp(msg.Timestamp),
+		RequiresAck: msg.RequiresACK,
+	}, nil
+}
+
+// MessageFromProto converts a wire message
+func MessageFromProto(msg *pb.Message) *multiagent.Message {
+	if msg == nil {
+		return nil
+	}
+	to := make([]multiagent.AgentID, len(msg.GetTo()))
+	for i, id := range msg.GetTo() {
+		to[i] = multiagent.AgentID(id)
+	}
+	return &multiagent.Message{
+		ID:          msg.GetId(),
+		From:        multiagent.AgentID(msg.GetFrom()),
+		To:          to,
+		Type:        multiagent.MessageType(msg.GetType()),
+		Content:     msg.GetContent(),
+		Context:     fromStruct(msg.GetContext()),
+		Priority:    multiagent.Priority(msg.GetPriority()),
+		ReplyTo:     msg.GetReplyTo(),
+		Timestamp:   fromTimestamp(msg.GetTimestamp()),
+		RequiresACK: msg.GetRequiresAck(),
+	}
+}
+
+// TaskToProto converts a task for the wire
+func TaskToProto(task *multiagent.Task) (*pb.Task, error) {
+	input, err := toStruct(task.Input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert task input: %w", err)
+	}
+	output, err := toStruct(task.Output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert task output: %w", err)
+	}
+
+	out := &pb.Task{
+		Id:          task.ID,
+		Type:        task.Type,
+		Description: task.Description,
+		Priority:    pb.Priority(task.Priority),
+		Requester:   string(task.Requester),
+		Assignee:    string(task.Assignee),
+		Status:      string(task.Status),
+		Input:       input,
+		Output:      output,
+		Error:       task.Error,
+		CreatedAt:   toTimestamp(task.CreatedAt),
+		StartedAt:   toTimestampPtr(task.StartedAt),
+		CompletedAt: toTimestampPtr(task.CompletedAt),
+		Deadline:    toTimestampPtr(task.Deadline),
+		DependsOn:   task.DependsOn,
+		WorkflowId:  task.WorkflowID,
+		Attempts:    int32(task.Attempts),
+	}
+	if task.Timeout > 0 {
+		out.Timeout = durationpb.New(task.Timeout)
+	}
+	return out, nil
+}
+
+// TaskFromProto converts a wire task
+func TaskFromProto(task *pb.Task) multiagent.Task {
+	out := multiagent.Task{
+		ID:          task.GetId(),
+		Type:        task.GetType(),
+		Description: task.GetDescription(),
+		Priority:    multiagent.Priority(task.GetPriority()),
+		Requester:   multiagent.AgentID(task.GetRequester()),
+		Assignee:    multiagent.AgentID(task.GetAssignee()),
+		Status:      multiagent.TaskStatus(task.GetStatus()),
+		Input:       fromStruct(task.GetInput()),
+		Output:      fromStruct(task.GetOutput()),
+		Error:       task.GetError(),
+		CreatedAt:   fromTimestamp(task.GetCreatedAt()),
+		StartedAt:   fromTimestampPtr(task.GetStartedAt()),
+		CompletedAt: fromTimestampPtr(task.GetCompletedAt()),
+		Deadline:    fromTimestampPtr(task.GetDeadline()),
+		DependsOn:   task.GetDependsOn(),
+		WorkflowID:  task.GetWorkflowId(),
+		Attempts:    int(task.GetAttempts()),
+	}
+	if task.GetTimeout() != nil {
+		out.Timeout = task.GetTimeout().AsDuration()
+	}
+	return out
+}
+
+// EventToProto converts an event for the wire
+func EventToProto(event *multiagent.Event) (*pb.Event, error) {
+	data, err := toStruct(event.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert event data: %w", err)
+	}
+	return &pb.Event{
+		Id:        event.ID,
+		Type:      string(event.Type),
+		Source:    event.Source,
+		Timestamp: toTimestamp(event.Timestamp),
+		Data:      data,
+	}, nil
+}
+
+// EventFromProto converts a wire event
+func EventFromProto(event *pb.Event) *multiagent.Event {
+	return &multiagent.Event{
+		ID:        event.GetId(),
+		Type:      multiagent.EventType(event.GetType()),
+		Source:    event.GetSource(),
+		Timestamp: fromTimestamp(event.GetTimestamp()),
+		Data:      fromStruct(event.GetData()),
+	}
+}
+
+// AgentStateToProto converts an agent state for the wire
+func AgentStateToProto(state multiagent.AgentState) (*pb.AgentState, error) {
+	metadata, err := toStruct(state.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert agent metadata: %w", err)
+	}
+	return &pb.AgentState{
+		Status:       string(state.Status),
+		CurrentTask:  state.CurrentTask,
+		LastActivity: toTimestamp(state.LastActivity),
+		Capabilities: state.Capabilities,
+		Workload:     int32(state.Workload),
+		Metadata:     metadata,
+	}, nil
+}
+
+// AgentStateFromProto converts a wire agent state
+func AgentStateFromProto(state *pb.AgentState) multiagent.AgentState {
+	return multiagent.AgentState{
+		Status:       multiagent.AgentStatus(state.GetStatus()),
+		CurrentTask:  state.GetCurrentTask(),
+		LastActivity: fromTimestamp(state.GetLastActivity()),
+		Capabilities: state.GetCapabilities(),
+		Workload:     int(state.GetWorkload()),
+		Metadata:     fromStruct(state.GetMetadata()),
+	}
+}
+
+// SystemHealthToProto converts system health for the wire
+func SystemHealthToProto(health multiagent.SystemHealth) (*pb.SystemHealth, error) {
+	out := &pb.SystemHealth{
+		Status:             string(health.Status),
+		ActiveAgents:       int32(health.ActiveAgents),
+		TotalAgents:        int32(health.TotalAgents),
+		PendingTasks:       int32(health.PendingTasks),
+		ActiveTasks:        int32(health.ActiveTasks),
+		MessageQueue:       int32(health.MessageQueue),
+		MemoryUsagePercent: health.MemoryUsage,
+		Uptime:             durationpb.New(health.Uptime),
+		LastCheck:          toTimestamp(health.LastCheck),
+		AgentHealth:        make(map[string]*pb.AgentState, len(health.AgentHealth)),
+		AgentRestarts:      make(map[string]int32, len(health.AgentRestarts)),
+	}
+	for id, state := range health.AgentHealth {
+		converted, err := AgentStateToProto(state)
+		if err != nil {
+			return nil, err
+		}
+		out.AgentHealth[string(id)] = converted
+	}
+	for id, restarts := range health.AgentRestarts {
+		out.AgentRestarts[string(id)] = int32(restarts)
+	}
+	return out, nil
+}
+
+// toStruct converts a free-form map, round-tripping through JSON so values
+// such as times and structs become plain JSON values
+func toStruct(values map[string]interface{}) (*structpb.Struct, error) {
+	if values == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	var plain map[string]interface{}
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(plain)
+}
+
+func fromStruct(values *structpb.Struct) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	return values.AsMap()
+}
+
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func toTimestampPtr(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return toTimestamp(*t)
+}
+
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func fromTimestampPtr(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
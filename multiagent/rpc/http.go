@@ -0,0 +1,45 @@
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// NewAgentHandler serves an agent over plain HTTP with JSON bodies, the
+// counterpart of an AgentProxy with an http(s) endpoint:
+//
+//	POST /messages  multiagent.Message -> 200 with the reply, or 204 for none
+//	GET  /state     -> multiagent.AgentState
+//
+// If token is set, requests must carry "Authorization: Bearer <token>"
+func NewAgentHandler(agent multiagent.Agent, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /messages", func(w http.ResponseWriter, r *http.Request) {
+		var msg multiagent.Message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, "invalid message: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		response, err := agent.HandleMessage(r.Context(), &msg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if response == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, response)
+	})
+	mux.HandleFunc("GET /state", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, agent.GetState())
+	})
+	return requireToken(token, mux)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,6 @@
+// Package multiagentpb holds the protobuf messages and gRPC services that let
+// agents written in other languages, or running in separate processes, talk
+// to the orchestrator.
+package multiagentpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative multiagent.proto
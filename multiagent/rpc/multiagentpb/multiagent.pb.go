@@ -0,0 +1,1392 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.27.1
+// source: multiagent.proto
+
+package multiagentpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Priority mirrors multiagent.Priority
+type Priority int32
+
+const (
+	Priority_PRIORITY_LOW      Priority = 0
+	Priority_PRIORITY_MEDIUM   Priority = 1
+	Priority_PRIORITY_HIGH     Priority = 2
+	Priority_PRIORITY_CRITICAL Priority = 3
+)
+
+// Enum value maps for Priority.
+var (
+	Priority_name = map[int32]string{
+		0: "PRIORITY_LOW",
+		1: "PRIORITY_MEDIUM",
+		2: "PRIORITY_HIGH",
+		3: "PRIORITY_CRITICAL",
+	}
+	Priority_value = map[string]int32{
+		"PRIORITY_LOW":      0,
+		"PRIORITY_MEDIUM":   1,
+		"PRIORITY_HIGH":     2,
+		"PRIORITY_CRITICAL": 3,
+	}
+)
+
+func (x Priority) Enum() *Priority {
+	p := new(Priority)
+	*p = x
+	return p
+}
+
+func (x Priority) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Priority) Descriptor() protoreflect.EnumDescriptor {
+	return file_multiagent_proto_enumTypes[0].Descriptor()
+}
+
+func (Priority) Type() protoreflect.EnumType {
+	return &file_multiagent_proto_enumTypes[0]
+}
+
+func (x Priority) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Priority.Descriptor instead.
+func (Priority) EnumDescriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{0}
+}
+
+// Message is a message between agents
+type Message struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	From  string                 `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To    []string               `protobuf:"bytes,3,rep,name=to,proto3" json:"to,omitempty"`
+	// Type is a multiagent.MessageType, e.g. "request" or "response"
+	Type     string           `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	Content  string           `protobuf:"bytes,5,opt,name=content,proto3" json:"content,omitempty"`
+	Context  *structpb.Struct `protobuf:"bytes,6,opt,name=context,proto3" json:"context,omitempty"`
+	Priority Priority         `protobuf:"varint,7,opt,name=priority,proto3,enum=wikillm.multiagent.v1.Priority" json:"priority,omitempty"`
+	// ReplyTo is the ID of the message this one answers
+	ReplyTo       string                 `protobuf:"bytes,8,opt,name=reply_to,json=replyTo,proto3" json:"reply_to,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	RequiresAck   bool                   `protobuf:"varint,10,opt,name=requires_ack,json=requiresAck,proto3" json:"requires_ack,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_multiagent_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Message) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Message) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *Message) GetTo() []string {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *Message) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Message) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Message) GetContext() *structpb.Struct {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *Message) GetPriority() Priority {
+	if x != nil {
+		return x.Priority
+	}
+	return Priority_PRIORITY_LOW
+}
+
+func (x *Message) GetReplyTo() string {
+	if x != nil {
+		return x.ReplyTo
+	}
+	return ""
+}
+
+func (x *Message) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Message) GetRequiresAck() bool {
+	if x != nil {
+		return x.RequiresAck
+	}
+	return false
+}
+
+// Task is a unit of work assigned to an agent
+type Task struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type        string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Description string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Priority    Priority               `protobuf:"varint,4,opt,name=priority,proto3,enum=wikillm.multiagent.v1.Priority" json:"priority,omitempty"`
+	Requester   string                 `protobuf:"bytes,5,opt,name=requester,proto3" json:"requester,omitempty"`
+	Assignee    string                 `protobuf:"bytes,6,opt,name=assignee,proto3" json:"assignee,omitempty"`
+	// Status is a multiagent.TaskStatus, e.g. "pending" or "completed"
+	Status      string                 `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"`
+	Input       *structpb.Struct       `protobuf:"bytes,8,opt,name=input,proto3" json:"input,omitempty"`
+	Output      *structpb.Struct       `protobuf:"bytes,9,opt,name=output,proto3" json:"output,omitempty"`
+	Error       string                 `protobuf:"bytes,10,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt   *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	StartedAt   *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	CompletedAt *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
+	Deadline    *timestamppb.Timestamp `protobuf:"bytes,14,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	// DependsOn lists task IDs that must complete first
+	DependsOn  []string `protobuf:"bytes,15,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`
+	WorkflowId string   `protobuf:"bytes,16,opt,name=workflow_id,json=workflowId,proto3" json:"workflow_id,omitempty"`
+	// Timeout bounds each attempt
+	Timeout       *durationpb.Duration `protobuf:"bytes,17,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	Attempts      int32                `protobuf:"varint,18,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Task) Reset() {
+	*x = Task{}
+	mi := &file_multiagent_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Task) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Task) ProtoMessage() {}
+
+func (x *Task) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Task.ProtoReflect.Descriptor instead.
+func (*Task) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Task) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Task) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Task) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Task) GetPriority() Priority {
+	if x != nil {
+		return x.Priority
+	}
+	return Priority_PRIORITY_LOW
+}
+
+func (x *Task) GetRequester() string {
+	if x != nil {
+		return x.Requester
+	}
+	return ""
+}
+
+func (x *Task) GetAssignee() string {
+	if x != nil {
+		return x.Assignee
+	}
+	return ""
+}
+
+func (x *Task) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Task) GetInput() *structpb.Struct {
+	if x != nil {
+		return x.Input
+	}
+	return nil
+}
+
+func (x *Task) GetOutput() *structpb.Struct {
+	if x != nil {
+		return x.Output
+	}
+	return nil
+}
+
+func (x *Task) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Task) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Task) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Task) GetCompletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CompletedAt
+	}
+	return nil
+}
+
+func (x *Task) GetDeadline() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Deadline
+	}
+	return nil
+}
+
+func (x *Task) GetDependsOn() []string {
+	if x != nil {
+		return x.DependsOn
+	}
+	return nil
+}
+
+func (x *Task) GetWorkflowId() string {
+	if x != nil {
+		return x.WorkflowId
+	}
+	return ""
+}
+
+func (x *Task) GetTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.Timeout
+	}
+	return nil
+}
+
+func (x *Task) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+// Event is a system event, e.g. task_completed reported by an agent
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Type is a multiagent.EventType, e.g. "task_completed"
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Source        string                 `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Data          *structpb.Struct       `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_multiagent_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Event) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Event) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Event) GetData() *structpb.Struct {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// AgentState is an agent's current state
+type AgentState struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Status is a multiagent.AgentStatus, e.g. "idle" or "busy"
+	Status       string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	CurrentTask  string                 `protobuf:"bytes,2,opt,name=current_task,json=currentTask,proto3" json:"current_task,omitempty"`
+	LastActivity *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=last_activity,json=lastActivity,proto3" json:"last_activity,omitempty"`
+	Capabilities []string               `protobuf:"bytes,4,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	// Workload is on a 0-100 scale
+	Workload      int32            `protobuf:"varint,5,opt,name=workload,proto3" json:"workload,omitempty"`
+	Metadata      *structpb.Struct `protobuf:"bytes,6,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentState) Reset() {
+	*x = AgentState{}
+	mi := &file_multiagent_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentState) ProtoMessage() {}
+
+func (x *AgentState) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentState.ProtoReflect.Descriptor instead.
+func (*AgentState) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *AgentState) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *AgentState) GetCurrentTask() string {
+	if x != nil {
+		return x.CurrentTask
+	}
+	return ""
+}
+
+func (x *AgentState) GetLastActivity() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastActivity
+	}
+	return nil
+}
+
+func (x *AgentState) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *AgentState) GetWorkload() int32 {
+	if x != nil {
+		return x.Workload
+	}
+	return 0
+}
+
+func (x *AgentState) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+// SystemHealth is the overall health of the orchestrator
+type SystemHealth struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Status is a multiagent.SystemStatus, e.g. "healthy"
+	Status             string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	ActiveAgents       int32                  `protobuf:"varint,2,opt,name=active_agents,json=activeAgents,proto3" json:"active_agents,omitempty"`
+	TotalAgents        int32                  `protobuf:"varint,3,opt,name=total_agents,json=totalAgents,proto3" json:"total_agents,omitempty"`
+	PendingTasks       int32                  `protobuf:"varint,4,opt,name=pending_tasks,json=pendingTasks,proto3" json:"pending_tasks,omitempty"`
+	ActiveTasks        int32                  `protobuf:"varint,5,opt,name=active_tasks,json=activeTasks,proto3" json:"active_tasks,omitempty"`
+	MessageQueue       int32                  `protobuf:"varint,6,opt,name=message_queue,json=messageQueue,proto3" json:"message_queue,omitempty"`
+	MemoryUsagePercent float64                `protobuf:"fixed64,7,opt,name=memory_usage_percent,json=memoryUsagePercent,proto3" json:"memory_usage_percent,omitempty"`
+	Uptime             *durationpb.Duration   `protobuf:"bytes,8,opt,name=uptime,proto3" json:"uptime,omitempty"`
+	LastCheck          *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=last_check,json=lastCheck,proto3" json:"last_check,omitempty"`
+	AgentHealth        map[string]*AgentState `protobuf:"bytes,10,rep,name=agent_health,json=agentHealth,proto3" json:"agent_health,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	AgentRestarts      map[string]int32       `protobuf:"bytes,11,rep,name=agent_restarts,json=agentRestarts,proto3" json:"agent_restarts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *SystemHealth) Reset() {
+	*x = SystemHealth{}
+	mi := &file_multiagent_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SystemHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SystemHealth) ProtoMessage() {}
+
+func (x *SystemHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SystemHealth.ProtoReflect.Descriptor instead.
+func (*SystemHealth) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SystemHealth) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SystemHealth) GetActiveAgents() int32 {
+	if x != nil {
+		return x.ActiveAgents
+	}
+	return 0
+}
+
+func (x *SystemHealth) GetTotalAgents() int32 {
+	if x != nil {
+		return x.TotalAgents
+	}
+	return 0
+}
+
+func (x *SystemHealth) GetPendingTasks() int32 {
+	if x != nil {
+		return x.PendingTasks
+	}
+	return 0
+}
+
+func (x *SystemHealth) GetActiveTasks() int32 {
+	if x != nil {
+		return x.ActiveTasks
+	}
+	return 0
+}
+
+func (x *SystemHealth) GetMessageQueue() int32 {
+	if x != nil {
+		return x.MessageQueue
+	}
+	return 0
+}
+
+func (x *SystemHealth) GetMemoryUsagePercent() float64 {
+	if x != nil {
+		return x.MemoryUsagePercent
+	}
+	return 0
+}
+
+func (x *SystemHealth) GetUptime() *durationpb.Duration {
+	if x != nil {
+		return x.Uptime
+	}
+	return nil
+}
+
+func (x *SystemHealth) GetLastCheck() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastCheck
+	}
+	return nil
+}
+
+func (x *SystemHealth) GetAgentHealth() map[string]*AgentState {
+	if x != nil {
+		return x.AgentHealth
+	}
+	return nil
+}
+
+func (x *SystemHealth) GetAgentRestarts() map[string]int32 {
+	if x != nil {
+		return x.AgentRestarts
+	}
+	return nil
+}
+
+type RouteMessageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       *Message               `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RouteMessageRequest) Reset() {
+	*x = RouteMessageRequest{}
+	mi := &file_multiagent_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RouteMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RouteMessageRequest) ProtoMessage() {}
+
+func (x *RouteMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RouteMessageRequest.ProtoReflect.Descriptor instead.
+func (*RouteMessageRequest) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RouteMessageRequest) GetMessage() *Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+type RouteMessageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RouteMessageResponse) Reset() {
+	*x = RouteMessageResponse{}
+	mi := &file_multiagent_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RouteMessageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RouteMessageResponse) ProtoMessage() {}
+
+func (x *RouteMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RouteMessageResponse.ProtoReflect.Descriptor instead.
+func (*RouteMessageResponse) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{6}
+}
+
+type AssignTaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Task          *Task                  `protobuf:"bytes,1,opt,name=task,proto3" json:"task,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AssignTaskRequest) Reset() {
+	*x = AssignTaskRequest{}
+	mi := &file_multiagent_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AssignTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AssignTaskRequest) ProtoMessage() {}
+
+func (x *AssignTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AssignTaskRequest.ProtoReflect.Descriptor instead.
+func (*AssignTaskRequest) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AssignTaskRequest) GetTask() *Task {
+	if x != nil {
+		return x.Task
+	}
+	return nil
+}
+
+type AssignTaskResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// AgentId is the agent the task was assigned to
+	AgentId       string `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AssignTaskResponse) Reset() {
+	*x = AssignTaskResponse{}
+	mi := &file_multiagent_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AssignTaskResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AssignTaskResponse) ProtoMessage() {}
+
+func (x *AssignTaskResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AssignTaskResponse.ProtoReflect.Descriptor instead.
+func (*AssignTaskResponse) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AssignTaskResponse) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+type GetTaskStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTaskStatusRequest) Reset() {
+	*x = GetTaskStatusRequest{}
+	mi := &file_multiagent_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTaskStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTaskStatusRequest) ProtoMessage() {}
+
+func (x *GetTaskStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTaskStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetTaskStatusRequest) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetTaskStatusRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+type GetTaskStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTaskStatusResponse) Reset() {
+	*x = GetTaskStatusResponse{}
+	mi := &file_multiagent_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTaskStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTaskStatusResponse) ProtoMessage() {}
+
+func (x *GetTaskStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTaskStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetTaskStatusResponse) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetTaskStatusResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type SubmitEventRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Event         *Event                 `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitEventRequest) Reset() {
+	*x = SubmitEventRequest{}
+	mi := &file_multiagent_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitEventRequest) ProtoMessage() {}
+
+func (x *SubmitEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitEventRequest.ProtoReflect.Descriptor instead.
+func (*SubmitEventRequest) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SubmitEventRequest) GetEvent() *Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+type SubmitEventResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitEventResponse) Reset() {
+	*x = SubmitEventResponse{}
+	mi := &file_multiagent_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitEventResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitEventResponse) ProtoMessage() {}
+
+func (x *SubmitEventResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitEventResponse.ProtoReflect.Descriptor instead.
+func (*SubmitEventResponse) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{12}
+}
+
+type GetSystemHealthRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSystemHealthRequest) Reset() {
+	*x = GetSystemHealthRequest{}
+	mi := &file_multiagent_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSystemHealthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSystemHealthRequest) ProtoMessage() {}
+
+func (x *GetSystemHealthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSystemHealthRequest.ProtoReflect.Descriptor instead.
+func (*GetSystemHealthRequest) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{13}
+}
+
+type HandleMessageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       *Message               `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HandleMessageRequest) Reset() {
+	*x = HandleMessageRequest{}
+	mi := &file_multiagent_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HandleMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandleMessageRequest) ProtoMessage() {}
+
+func (x *HandleMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandleMessageRequest.ProtoReflect.Descriptor instead.
+func (*HandleMessageRequest) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *HandleMessageRequest) GetMessage() *Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+type HandleMessageResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Response is unset when the agent has nothing to reply
+	Response      *Message `protobuf:"bytes,1,opt,name=response,proto3" json:"response,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HandleMessageResponse) Reset() {
+	*x = HandleMessageResponse{}
+	mi := &file_multiagent_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HandleMessageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandleMessageResponse) ProtoMessage() {}
+
+func (x *HandleMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandleMessageResponse.ProtoReflect.Descriptor instead.
+func (*HandleMessageResponse) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *HandleMessageResponse) GetResponse() *Message {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+type GetStateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStateRequest) Reset() {
+	*x = GetStateRequest{}
+	mi := &file_multiagent_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStateRequest) ProtoMessage() {}
+
+func (x *GetStateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_multiagent_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStateRequest.ProtoReflect.Descriptor instead.
+func (*GetStateRequest) Descriptor() ([]byte, []int) {
+	return file_multiagent_proto_rawDescGZIP(), []int{16}
+}
+
+var File_multiagent_proto protoreflect.FileDescriptor
+
+const file_multiagent_proto_rawDesc = "" +
+	"\n" +
+	"\x10multiagent.proto\x12\x15wikillm.multiagent.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd3\x02\n" +
+	"\aMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x03(\tR\x02to\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x18\n" +
+	"\acontent\x18\x05 \x01(\tR\acontent\x121\n" +
+	"\acontext\x18\x06 \x01(\v2\x17.google.protobuf.StructR\acontext\x12;\n" +
+	"\bpriority\x18\a \x01(\x0e2\x1f.wikillm.multiagent.v1.PriorityR\bpriority\x12\x19\n" +
+	"\breply_to\x18\b \x01(\tR\areplyTo\x128\n" +
+	"\ttimestamp\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12!\n" +
+	"\frequires_ack\x18\n" +
+	" \x01(\bR\vrequiresAck\"\xcf\x05\n" +
+	"\x04Task\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12;\n" +
+	"\bpriority\x18\x04 \x01(\x0e2\x1f.wikillm.multiagent.v1.PriorityR\bpriority\x12\x1c\n" +
+	"\trequester\x18\x05 \x01(\tR\trequester\x12\x1a\n" +
+	"\bassignee\x18\x06 \x01(\tR\bassignee\x12\x16\n" +
+	"\x06status\x18\a \x01(\tR\x06status\x12-\n" +
+	"\x05input\x18\b \x01(\v2\x17.google.protobuf.StructR\x05input\x12/\n" +
+	"\x06output\x18\t \x01(\v2\x17.google.protobuf.StructR\x06output\x12\x14\n" +
+	"\x05error\x18\n" +
+	" \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"created_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"started_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12=\n" +
+	"\fcompleted_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\vcompletedAt\x126\n" +
+	"\bdeadline\x18\x0e \x01(\v2\x1a.google.protobuf.TimestampR\bdeadline\x12\x1d\n" +
+	"\n" +
+	"depends_on\x18\x0f \x03(\tR\tdependsOn\x12\x1f\n" +
+	"\vworkflow_id\x18\x10 \x01(\tR\n" +
+	"workflowId\x123\n" +
+	"\atimeout\x18\x11 \x01(\v2\x19.google.protobuf.DurationR\atimeout\x12\x1a\n" +
+	"\battempts\x18\x12 \x01(\x05R\battempts\"\xaa\x01\n" +
+	"\x05Event\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x16\n" +
+	"\x06source\x18\x03 \x01(\tR\x06source\x128\n" +
+	"\ttimestamp\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12+\n" +
+	"\x04data\x18\x05 \x01(\v2\x17.google.protobuf.StructR\x04data\"\xfd\x01\n" +
+	"\n" +
+	"AgentState\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12!\n" +
+	"\fcurrent_task\x18\x02 \x01(\tR\vcurrentTask\x12?\n" +
+	"\rlast_activity\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\flastActivity\x12\"\n" +
+	"\fcapabilities\x18\x04 \x03(\tR\fcapabilities\x12\x1a\n" +
+	"\bworkload\x18\x05 \x01(\x05R\bworkload\x123\n" +
+	"\bmetadata\x18\x06 \x01(\v2\x17.google.protobuf.StructR\bmetadata\"\xd8\x05\n" +
+	"\fSystemHealth\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12#\n" +
+	"\ractive_agents\x18\x02 \x01(\x05R\factiveAgents\x12!\n" +
+	"\ftotal_agents\x18\x03 \x01(\x05R\vtotalAgents\x12#\n" +
+	"\rpending_tasks\x18\x04 \x01(\x05R\fpendingTasks\x12!\n" +
+	"\factive_tasks\x18\x05 \x01(\x05R\vactiveTasks\x12#\n" +
+	"\rmessage_queue\x18\x06 \x01(\x05R\fmessageQueue\x120\n" +
+	"\x14memory_usage_percent\x18\a \x01(\x01R\x12memoryUsagePercent\x121\n" +
+	"\x06uptime\x18\b \x01(\v2\x19.google.protobuf.DurationR\x06uptime\x129\n" +
+	"\n" +
+	"last_check\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tlastCheck\x12W\n" +
+	"\fagent_health\x18\n" +
+	" \x03(\v24.wikillm.multiagent.v1.SystemHealth.AgentHealthEntryR\vagentHealth\x12]\n" +
+	"\x0eagent_restarts\x18\v \x03(\v26.wikillm.multiagent.v1.SystemHealth.AgentRestartsEntryR\ragentRestarts\x1aa\n" +
+	"\x10AgentHealthEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x127\n" +
+	"\x05value\x18\x02 \x01(\v2!.wikillm.multiagent.v1.AgentStateR\x05value:\x028\x01\x1a@\n" +
+	"\x12AgentRestartsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"O\n" +
+	"\x13RouteMessageRequest\x128\n" +
+	"\amessage\x18\x01 \x01(\v2\x1e.wikillm.multiagent.v1.MessageR\amessage\"\x16\n" +
+	"\x14RouteMessageResponse\"D\n" +
+	"\x11AssignTaskRequest\x12/\n" +
+	"\x04task\x18\x01 \x01(\v2\x1b.wikillm.multiagent.v1.TaskR\x04task\"/\n" +
+	"\x12AssignTaskResponse\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\"/\n" +
+	"\x14GetTaskStatusRequest\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\"/\n" +
+	"\x15GetTaskStatusResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\"H\n" +
+	"\x12SubmitEventRequest\x122\n" +
+	"\x05event\x18\x01 \x01(\v2\x1c.wikillm.multiagent.v1.EventR\x05event\"\x15\n" +
+	"\x13SubmitEventResponse\"\x18\n" +
+	"\x16GetSystemHealthRequest\"P\n" +
+	"\x14HandleMessageRequest\x128\n" +
+	"\amessage\x18\x01 \x01(\v2\x1e.wikillm.multiagent.v1.MessageR\amessage\"S\n" +
+	"\x15HandleMessageResponse\x12:\n" +
+	"\bresponse\x18\x01 \x01(\v2\x1e.wikillm.multiagent.v1.MessageR\bresponse\"\x11\n" +
+	"\x0fGetStateRequest*[\n" +
+	"\bPriority\x12\x10\n" +
+	"\fPRIORITY_LOW\x10\x00\x12\x13\n" +
+	"\x0fPRIORITY_MEDIUM\x10\x01\x12\x11\n" +
+	"\rPRIORITY_HIGH\x10\x02\x12\x15\n" +
+	"\x11PRIORITY_CRITICAL\x10\x032\x93\x04\n" +
+	"\fOrchestrator\x12g\n" +
+	"\fRouteMessage\x12*.wikillm.multiagent.v1.RouteMessageRequest\x1a+.wikillm.multiagent.v1.RouteMessageResponse\x12a\n" +
+	"\n" +
+	"AssignTask\x12(.wikillm.multiagent.v1.AssignTaskRequest\x1a).wikillm.multiagent.v1.AssignTaskResponse\x12j\n" +
+	"\rGetTaskStatus\x12+.wikillm.multiagent.v1.GetTaskStatusRequest\x1a,.wikillm.multiagent.v1.GetTaskStatusResponse\x12d\n" +
+	"\vSubmitEvent\x12).wikillm.multiagent.v1.SubmitEventRequest\x1a*.wikillm.multiagent.v1.SubmitEventResponse\x12e\n" +
+	"\x0fGetSystemHealth\x12-.wikillm.multiagent.v1.GetSystemHealthRequest\x1a#.wikillm.multiagent.v1.SystemHealth2\xca\x01\n" +
+	"\x05Agent\x12j\n" +
+	"\rHandleMessage\x12+.wikillm.multiagent.v1.HandleMessageRequest\x1a,.wikillm.multiagent.v1.HandleMessageResponse\x12U\n" +
+	"\bGetState\x12&.wikillm.multiagent.v1.GetStateRequest\x1a!.wikillm.multiagent.v1.AgentStateB6Z4github.com/kbutz/wikillm/multiagent/rpc/multiagentpbb\x06proto3"
+
+var (
+	file_multiagent_proto_rawDescOnce sync.Once
+	file_multiagent_proto_rawDescData []byte
+)
+
+func file_multiagent_proto_rawDescGZIP() []byte {
+	file_multiagent_proto_rawDescOnce.Do(func() {
+		file_multiagent_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_multiagent_proto_rawDesc), len(file_multiagent_proto_rawDesc)))
+	})
+	return file_multiagent_proto_rawDescData
+}
+
+var file_multiagent_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_multiagent_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_multiagent_proto_goTypes = []any{
+	(Priority)(0),                  // 0: wikillm.multiagent.v1.Priority
+	(*Message)(nil),                // 1: wikillm.multiagent.v1.Message
+	(*Task)(nil),                   // 2: wikillm.multiagent.v1.Task
+	(*Event)(nil),                  // 3: wikillm.multiagent.v1.Event
+	(*AgentState)(nil),             // 4: wikillm.multiagent.v1.AgentState
+	(*SystemHealth)(nil),           // 5: wikillm.multiagent.v1.SystemHealth
+	(*RouteMessageRequest)(nil),    // 6: wikillm.multiagent.v1.RouteMessageRequest
+	(*RouteMessageResponse)(nil),   // 7: wikillm.multiagent.v1.RouteMessageResponse
+	(*AssignTaskRequest)(nil),      // 8: wikillm.multiagent.v1.AssignTaskRequest
+	(*AssignTaskResponse)(nil),     // 9: wikillm.multiagent.v1.AssignTaskResponse
+	(*GetTaskStatusRequest)(nil),   // 10: wikillm.multiagent.v1.GetTaskStatusRequest
+	(*GetTaskStatusResponse)(nil),  // 11: wikillm.multiagent.v1.GetTaskStatusResponse
+	(*SubmitEventRequest)(nil),     // 12: wikillm.multiagent.v1.SubmitEventRequest
+	(*SubmitEventResponse)(nil),    // 13: wikillm.multiagent.v1.SubmitEventResponse
+	(*GetSystemHealthRequest)(nil), // 14: wikillm.multiagent.v1.GetSystemHealthRequest
+	(*HandleMessageRequest)(nil),   // 15: wikillm.multiagent.v1.HandleMessageRequest
+	(*HandleMessageResponse)(nil),  // 16: wikillm.multiagent.v1.HandleMessageResponse
+	(*GetStateRequest)(nil),        // 17: wikillm.multiagent.v1.GetStateRequest
+	nil,                            // 18: wikillm.multiagent.v1.SystemHealth.AgentHealthEntry
+	nil,                            // 19: wikillm.multiagent.v1.SystemHealth.AgentRestartsEntry
+	(*structpb.Struct)(nil),        // 20: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil),  // 21: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),    // 22: google.protobuf.Duration
+}
+var file_multiagent_proto_depIdxs = []int32{
+	20, // 0: wikillm.multiagent.v1.Message.context:type_name -> google.protobuf.Struct
+	0,  // 1: wikillm.multiagent.v1.Message.priority:type_name -> wikillm.multiagent.v1.Priority
+	21, // 2: wikillm.multiagent.v1.Message.timestamp:type_name -> google.protobuf.Timestamp
+	0,  // 3: wikillm.multiagent.v1.Task.priority:type_name -> wikillm.multiagent.v1.Priority
+	20, // 4: wikillm.multiagent.v1.Task.input:type_name -> google.protobuf.Struct
+	20, // 5: wikillm.multiagent.v1.Task.output:type_name -> google.protobuf.Struct
+	21, // 6: wikillm.multiagent.v1.Task.created_at:type_name -> google.protobuf.Timestamp
+	21, // 7: wikillm.multiagent.v1.Task.started_at:type_name -> google.protobuf.Timestamp
+	21, // 8: wikillm.multiagent.v1.Task.completed_at:type_name -> google.protobuf.Timestamp
+	21, // 9: wikillm.multiagent.v1.Task.deadline:type_name -> google.protobuf.Timestamp
+	22, // 10: wikillm.multiagent.v1.Task.timeout:type_name -> google.protobuf.Duration
+	21, // 11: wikillm.multiagent.v1.Event.timestamp:type_name -> google.protobuf.Timestamp
+	20, // 12: wikillm.multiagent.v1.Event.data:type_name -> google.protobuf.Struct
+	21, // 13: wikillm.multiagent.v1.AgentState.last_activity:type_name -> google.protobuf.Timestamp
+	20, // 14: wikillm.multiagent.v1.AgentState.metadata:type_name -> google.protobuf.Struct
+	22, // 15: wikillm.multiagent.v1.SystemHealth.uptime:type_name -> google.protobuf.Duration
+	21, // 16: wikillm.multiagent.v1.SystemHealth.last_check:type_name -> google.protobuf.Timestamp
+	18, // 17: wikillm.multiagent.v1.SystemHealth.agent_health:type_name -> wikillm.multiagent.v1.SystemHealth.AgentHealthEntry
+	19, // 18: wikillm.multiagent.v1.SystemHealth.agent_restarts:type_name -> wikillm.multiagent.v1.SystemHealth.AgentRestartsEntry
+	1,  // 19: wikillm.multiagent.v1.RouteMessageRequest.message:type_name -> wikillm.multiagent.v1.Message
+	2,  // 20: wikillm.multiagent.v1.AssignTaskRequest.task:type_name -> wikillm.multiagent.v1.Task
+	3,  // 21: wikillm.multiagent.v1.SubmitEventRequest.event:type_name -> wikillm.multiagent.v1.Event
+	1,  // 22: wikillm.multiagent.v1.HandleMessageRequest.message:type_name -> wikillm.multiagent.v1.Message
+	1,  // 23: wikillm.multiagent.v1.HandleMessageResponse.response:type_name -> wikillm.multiagent.v1.Message
+	4,  // 24: wikillm.multiagent.v1.SystemHealth.AgentHealthEntry.value:type_name -> wikillm.multiagent.v1.AgentState
+	6,  // 25: wikillm.multiagent.v1.Orchestrator.RouteMessage:input_type -> wikillm.multiagent.v1.RouteMessageRequest
+	8,  // 26: wikillm.multiagent.v1.Orchestrator.AssignTask:input_type -> wikillm.multiagent.v1.AssignTaskRequest
+	10, // 27: wikillm.multiagent.v1.Orchestrator.GetTaskStatus:input_type -> wikillm.multiagent.v1.GetTaskStatusRequest
+	12, // 28: wikillm.multiagent.v1.Orchestrator.SubmitEvent:input_type -> wikillm.multiagent.v1.SubmitEventRequest
+	14, // 29: wikillm.multiagent.v1.Orchestrator.GetSystemHealth:input_type -> wikillm.multiagent.v1.GetSystemHealthRequest
+	15, // 30: wikillm.multiagent.v1.Agent.HandleMessage:input_type -> wikillm.multiagent.v1.HandleMessageRequest
+	17, // 31: wikillm.multiagent.v1.Agent.GetState:input_type -> wikillm.multiagent.v1.GetStateRequest
+	7,  // 32: wikillm.multiagent.v1.Orchestrator.RouteMessage:output_type -> wikillm.multiagent.v1.RouteMessageResponse
+	9,  // 33: wikillm.multiagent.v1.Orchestrator.AssignTask:output_type -> wikillm.multiagent.v1.AssignTaskResponse
+	11, // 34: wikillm.multiagent.v1.Orchestrator.GetTaskStatus:output_type -> wikillm.multiagent.v1.GetTaskStatusResponse
+	13, // 35: wikillm.multiagent.v1.Orchestrator.SubmitEvent:output_type -> wikillm.multiagent.v1.SubmitEventResponse
+	5,  // 36: wikillm.multiagent.v1.Orchestrator.GetSystemHealth:output_type -> wikillm.multiagent.v1.SystemHealth
+	16, // 37: wikillm.multiagent.v1.Agent.HandleMessage:output_type -> wikillm.multiagent.v1.HandleMessageResponse
+	4,  // 38: wikillm.multiagent.v1.Agent.GetState:output_type -> wikillm.multiagent.v1.AgentState
+	32, // [32:39] is the sub-list for method output_type
+	25, // [25:32] is the sub-list for method input_type
+	25, // [25:25] is the sub-list for extension type_name
+	25, // [25:25] is the sub-list for extension extendee
+	0,  // [0:25] is the sub-list for field type_name
+}
+
+func init() { file_multiagent_proto_init() }
+func file_multiagent_proto_init() {
+	if File_multiagent_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_multiagent_proto_rawDesc), len(file_multiagent_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   19,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_multiagent_proto_goTypes,
+		DependencyIndexes: file_multiagent_proto_depIdxs,
+		EnumInfos:         file_multiagent_proto_enumTypes,
+		MessageInfos:      file_multiagent_proto_msgTypes,
+	}.Build()
+	File_multiagent_proto = out.File
+	file_multiagent_proto_goTypes = nil
+	file_multiagent_proto_depIdxs = nil
+}
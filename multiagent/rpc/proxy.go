@@ -0,0 +1,443 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	pb "github.com/kbutz/wikillm/multiagent/rpc/multiagentpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var logger = logging.For("rpc")
+
+// AgentProxyConfig describes an agent running in another process
+type AgentProxyConfig struct {
+	ID           multiagent.AgentID
+	Type         multiagent.AgentType
+	Name         string
+	Description  string
+	Capabilities []string
+	// Endpoint is an http:// or https:// base URL served like NewAgentHandler,
+	// or otherwise a gRPC target (e.g. "localhost:9100") serving pb.Agent
+	Endpoint string
+	// Token, if set, is sent as "Authorization: Bearer <token>"
+	Token string
+	// TLSConfig enables TLS for gRPC endpoints and configures it for https
+	TLSConfig *tls.Config
+	// Timeout bounds each remote call (default 60s)
+	Timeout time.Duration
+	// HealthCheckInterval is how often the remote state is polled (default 30s)
+	HealthCheckInterval time.Duration
+	// Orchestrator routes messages the proxy sends on the agent's behalf
+	Orchestrator multiagent.Orchestrator
+}
+
+// remoteAgent is the transport an AgentProxy forwards over
+type remoteAgent interface {
+	handleMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error)
+	getState(ctx context.Context) (multiagent.AgentState, error)
+	close() error
+}
+
+// AgentProxy is a multiagent.Agent that forwards messages to an agent in
+// another process, so heavyweight specialists (e.g. a Python research agent)
+// can join the orchestrator. It polls the remote agent's state and reports
+// itself offline while the endpoint is unreachable, so no work is assigned
+// to it until it recovers.
+type AgentProxy struct {
+	config AgentProxyConfig
+
+	mu          sync.RWMutex
+	remote      remoteAgent
+	state       multiagent.AgentState
+	lastCheck   time.Time
+	healthError string
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewAgentProxy creates a proxy for the remote agent described by config
+func NewAgentProxy(config AgentProxyConfig) (*AgentProxy, error) {
+	if config.ID == "" {
+		return nil, fmt.Errorf("remote agent ID is required")
+	}
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("remote agent %s has no endpoint", config.ID)
+	}
+	if config.Name == "" {
+		config.Name = string(config.ID)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 60 * time.Second
+	}
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = 30 * time.Second
+	}
+
+	return &AgentProxy{
+		config: config,
+		state: multiagent.AgentState{
+			Status:       multiagent.AgentStatusOffline,
+			Capabilities: config.Capabilities,
+		},
+	}, nil
+}
+
+// ID returns the agent's unique identifier
+func (p *AgentProxy) ID() multiagent.AgentID {
+	return p.config.ID
+}
+
+// Type returns the agent's type
+func (p *AgentProxy) Type() multiagent.AgentType {
+	return p.config.Type
+}
+
+// Name returns the agent's name
+func (p *AgentProxy) Name() string {
+	return p.config.Name
+}
+
+// Description returns the agent's description
+func (p *AgentProxy) Description() string {
+	return p.config.Description
+}
+
+// Initialize connects to the remote agent and checks its health; an
+// unreachable endpoint is not an error, the proxy stays offline until a
+// later health check succeeds
+func (p *AgentProxy) Initialize(ctx context.Context) error {
+	remote, err := p.dial()
+	if err != nil {
+		return fmt.Errorf("failed to connect to remote agent %s: %w", p.config.ID, err)
+	}
+
+	p.mu.Lock()
+	if p.remote != nil {
+		p.remote.close()
+	}
+	p.remote = remote
+	p.mu.Unlock()
+
+	p.checkHealth(ctx)
+	return nil
+}
+
+// Start begins polling the remote agent's health
+func (p *AgentProxy) Start(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.remote == nil {
+		return fmt.Errorf("remote agent %s is not initialized", p.config.ID)
+	}
+	if p.stopChan != nil {
+		return nil
+	}
+	p.stopChan = make(chan struct{})
+
+	p.wg.Add(1)
+	go p.healthLoop(p.stopChan)
+	return nil
+}
+
+// Stop stops health checks and closes the connection
+func (p *AgentProxy) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if p.stopChan != nil {
+		close(p.stopChan)
+		p.stopChan = nil
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state.Status = multiagent.AgentStatusOffline
+	if p.remote == nil {
+		return nil
+	}
+	err := p.remote.close()
+	p.remote = nil
+	return err
+}
+
+// GetState returns the state last reported by the remote agent
+func (p *AgentProxy) GetState() multiagent.AgentState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stateCopy := p.state
+	stateCopy.Metadata = make(map[string]interface{}, len(p.state.Metadata)+3)
+	for k, v := range p.state.Metadata {
+		stateCopy.Metadata[k] = v
+	}
+	stateCopy.Metadata["remote_endpoint"] = p.config.Endpoint
+	if !p.lastCheck.IsZero() {
+		stateCopy.Metadata["last_health_check"] = p.lastCheck
+	}
+	if p.healthError != "" {
+		stateCopy.Metadata["health_error"] = p.healthError
+	}
+	return stateCopy
+}
+
+// SendMessage sends a message through the orchestrator on the remote
+// agent's behalf
+func (p *AgentProxy) SendMessage(ctx context.Context, msg *multiagent.Message) error {
+	if p.config.Orchestrator == nil {
+		return fmt.Errorf("no orchestrator configured")
+	}
+	if msg.From == "" {
+		msg.From = p.config.ID
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	return p.config.Orchestrator.RouteMessage(ctx, msg)
+}
+
+// ReceiveMessage blocks until ctx is done; remote agents receive messages
+// through HandleMessage
+func (p *AgentProxy) ReceiveMessage(ctx context.Context) (*multiagent.Message, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// HandleMessage forwards a message to the remote agent and returns its reply
+func (p *AgentProxy) HandleMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	p.mu.RLock()
+	remote := p.remote
+	p.mu.RUnlock()
+	if remote == nil {
+		return nil, fmt.Errorf("remote agent %s is not connected", p.config.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	response, err := remote.handleMessage(ctx, msg)
+	if err != nil {
+		p.markUnhealthy(err)
+		return nil, fmt.Errorf("failed to forward message to remote agent %s: %w", p.config.ID, err)
+	}
+
+	p.mu.Lock()
+	p.state.LastActivity = time.Now()
+	p.mu.Unlock()
+	return response, nil
+}
+
+// GetCapabilities returns the capabilities the remote agent reported, or
+// the configured ones before it has been reached
+func (p *AgentProxy) GetCapabilities() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	capabilities := make([]string, len(p.state.Capabilities))
+	copy(capabilities, p.state.Capabilities)
+	return capabilities
+}
+
+// CanHandle checks if the agent can handle a specific message type
+func (p *AgentProxy) CanHandle(messageType multiagent.MessageType) bool {
+	switch messageType {
+	case multiagent.MessageTypeRequest,
+		multiagent.MessageTypeQuery,
+		multiagent.MessageTypeCommand:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *AgentProxy) healthLoop(stop chan struct{}) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.checkHealth(context.Background())
+		case <-stop:
+			return
+		}
+	}
+}
+
+// checkHealth fetches the remote state, marking the proxy offline on failure
+func (p *AgentProxy) checkHealth(ctx context.Context) {
+	p.mu.RLock()
+	remote := p.remote
+	p.mu.RUnlock()
+	if remote == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	state, err := remote.getState(ctx)
+	if err != nil {
+		p.markUnhealthy(err)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.healthError != "" || p.state.Status == multiagent.AgentStatusOffline {
+		logger.Info("Remote agent is reachable", logging.KeyAgentID, p.config.ID, "endpoint", p.config.Endpoint)
+	}
+	if len(state.Capabilities) == 0 {
+		state.Capabilities = p.config.Capabilities
+	}
+	if state.Status == "" {
+		state.Status = multiagent.AgentStatusIdle
+	}
+	if state.LastActivity.Before(p.state.LastActivity) {
+		state.LastActivity = p.state.LastActivity
+	}
+	p.state = state
+	p.lastCheck = time.Now()
+	p.healthError = ""
+}
+
+func (p *AgentProxy) markUnhealthy(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.healthError == "" {
+		logger.Warn("Remote agent is unreachable", logging.KeyAgentID, p.config.ID, "endpoint", p.config.Endpoint, "error", err)
+	}
+	p.state.Status = multiagent.AgentStatusOffline
+	p.lastCheck = time.Now()
+	p.healthError = err.Error()
+}
+
+func (p *AgentProxy) dial() (remoteAgent, error) {
+	if strings.HasPrefix(p.config.Endpoint, "http://") || strings.HasPrefix(p.config.Endpoint, "https://") {
+		client := &http.Client{}
+		if p.config.TLSConfig != nil {
+			client.Transport = &http.Transport{TLSClientConfig: p.config.TLSConfig}
+		}
+		return &httpRemote{client: client, baseURL: strings.TrimSuffix(p.config.Endpoint, "/"), token: p.config.Token}, nil
+	}
+
+	transportCredentials := insecure.NewCredentials()
+	if p.config.TLSConfig != nil {
+		transportCredentials = credentials.NewTLS(p.config.TLSConfig)
+	}
+	options := []grpc.DialOption{grpc.WithTransportCredentials(transportCredentials)}
+	if p.config.Token != "" {
+		options = append(options, grpc.WithPerRPCCredentials(bearerToken{token: p.config.Token, secureOnly: p.config.TLSConfig != nil}))
+	}
+	conn, err := grpc.NewClient(p.config.Endpoint, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcRemote{conn: conn, client: pb.NewAgentClient(conn)}, nil
+}
+
+// grpcRemote reaches an agent served with NewAgentServer or another
+// implementation of pb.Agent
+type grpcRemote struct {
+	conn   *grpc.ClientConn
+	client pb.AgentClient
+}
+
+func (r *grpcRemote) handleMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	request, err := MessageToProto(msg)
+	if err != nil {
+		return nil, err
+	}
+	response, err := r.client.HandleMessage(ctx, &pb.HandleMessageRequest{Message: request})
+	if err != nil {
+		return nil, err
+	}
+	return MessageFromProto(response.GetResponse()), nil
+}
+
+func (r *grpcRemote) getState(ctx context.Context) (multiagent.AgentState, error) {
+	state, err := r.client.GetState(ctx, &pb.GetStateRequest{})
+	if err != nil {
+		return multiagent.AgentState{}, err
+	}
+	return AgentStateFromProto(state), nil
+}
+
+func (r *grpcRemote) close() error {
+	return r.conn.Close()
+}
+
+// httpRemote reaches an agent served with NewAgentHandler
+type httpRemote struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+func (r *httpRemote) handleMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+	var response *multiagent.Message
+	if err := r.do(ctx, http.MethodPost, "/messages", body, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (r *httpRemote) getState(ctx context.Context) (multiagent.AgentState, error) {
+	var state multiagent.AgentState
+	err := r.do(ctx, http.MethodGet, "/state", nil, &state)
+	return state, err
+}
+
+func (r *httpRemote) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, r.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.token != "" {
+		req.Header.Set(authorizationHeader, "Bearer "+r.token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote agent returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func (r *httpRemote) close() error {
+	r.client.CloseIdleConnections()
+	return nil
+}
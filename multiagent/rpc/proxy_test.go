@@ -0,0 +1,103 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+	pb "github.com/kbutz/wikillm/multiagent/rpc/multiagentpb"
+	"google.golang.org/grpc"
+)
+
+func serveGRPCAgent(t *testing.T, agent multiagent.Agent, token string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	server := grpc.NewServer(grpc.UnaryInterceptor(TokenAuthInterceptor(token)))
+	pb.RegisterAgentServer(server, NewAgentServer(agent))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func newStartedProxy(t *testing.T, config AgentProxyConfig) *AgentProxy {
+	t.Helper()
+	proxy, err := NewAgentProxy(config)
+	if err != nil {
+		t.Fatalf("NewAgentProxy: %v", err)
+	}
+	if err := proxy.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if err := proxy.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { proxy.Stop(context.Background()) })
+	return proxy
+}
+
+func TestAgentProxyGRPC(t *testing.T) {
+	endpoint := serveGRPCAgent(t, &echoAgent{id: "python_research"}, "s3cret")
+	proxy := newStartedProxy(t, AgentProxyConfig{ID: "python_research", Endpoint: endpoint, Token: "s3cret"})
+
+	if state := proxy.GetState(); state.Status != multiagent.AgentStatusIdle || state.Workload != 40 {
+		t.Fatalf("expected the remote state after initialization, got %+v", state)
+	}
+	if got := proxy.GetCapabilities(); len(got) != 1 || got[0] != "echo" {
+		t.Errorf("expected remote capabilities, got %v", got)
+	}
+
+	reply, err := proxy.HandleMessage(context.Background(), &multiagent.Message{
+		ID:      "msg_1",
+		From:    "coordinator_agent",
+		Type:    multiagent.MessageTypeRequest,
+		Content: "find sources",
+	})
+	if err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+	if reply.Content != "echo: find sources" || reply.ReplyTo != "msg_1" {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+}
+
+func TestAgentProxyRejectedToken(t *testing.T) {
+	endpoint := serveGRPCAgent(t, &echoAgent{id: "python_research"}, "s3cret")
+	proxy := newStartedProxy(t, AgentProxyConfig{ID: "python_research", Endpoint: endpoint, Token: "wrong"})
+
+	state := proxy.GetState()
+	if state.Status != multiagent.AgentStatusOffline || state.Metadata["health_error"] == nil {
+		t.Fatalf("expected an unauthenticated proxy to be offline, got %+v", state)
+	}
+	if _, err := proxy.HandleMessage(context.Background(), &multiagent.Message{ID: "msg_1"}); err == nil {
+		t.Error("expected forwarding to fail without a valid token")
+	}
+}
+
+func TestAgentProxyHTTPHealth(t *testing.T) {
+	server := httptest.NewServer(NewAgentHandler(&echoAgent{id: "remote_writer"}, "s3cret"))
+	proxy := newStartedProxy(t, AgentProxyConfig{ID: "remote_writer", Endpoint: server.URL, Token: "s3cret"})
+
+	reply, err := proxy.HandleMessage(context.Background(), &multiagent.Message{
+		ID:      "msg_2",
+		From:    "coordinator_agent",
+		Content: "draft",
+		Context: map[string]interface{}{"conversation_id": "conv_bob"},
+	})
+	if err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+	if reply.Content != "echo: draft" || reply.Context["conversation_id"] != "conv_bob" {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+
+	server.Close()
+	proxy.checkHealth(context.Background())
+	if state := proxy.GetState(); state.Status != multiagent.AgentStatusOffline {
+		t.Errorf("expected the proxy offline once the endpoint is gone, got %s", state.Status)
+	}
+}
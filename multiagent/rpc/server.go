@@ -0,0 +1,125 @@
+// Package rpc serves the orchestrator and agents over gRPC using the
+// protobuf definitions in multiagentpb, so agents written in other languages
+// or running in separate processes can join the system.
+package rpc
+
+import (
+	"context"
+
+	"github.com/kbutz/wikillm/multiagent"
+	pb "github.com/kbutz/wikillm/multiagent/rpc/multiagentpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// EventSubmitter accepts events reported from outside the orchestrator;
+// orchestrator.DefaultOrchestrator implements it
+type EventSubmitter interface {
+	SubmitEvent(event *multiagent.Event) error
+}
+
+// OrchestratorServer serves a multiagent.Orchestrator over gRPC
+type OrchestratorServer struct {
+	pb.UnimplementedOrchestratorServer
+	orchestrator multiagent.Orchestrator
+}
+
+// NewOrchestratorServer creates a gRPC server for orch; register it with
+// pb.RegisterOrchestratorServer
+func NewOrchestratorServer(orch multiagent.Orchestrator) *OrchestratorServer {
+	return &OrchestratorServer{orchestrator: orch}
+}
+
+// RouteMessage delivers a message to its recipients
+func (s *OrchestratorServer) RouteMessage(ctx context.Context, req *pb.RouteMessageRequest) (*pb.RouteMessageResponse, error) {
+	if req.GetMessage() == nil {
+		return nil, status.Error(codes.InvalidArgument, "message is required")
+	}
+	if err := s.orchestrator.RouteMessage(ctx, MessageFromProto(req.GetMessage())); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to route message: %v", err)
+	}
+	return &pb.RouteMessageResponse{}, nil
+}
+
+// AssignTask assigns a task to the best available agent
+func (s *OrchestratorServer) AssignTask(ctx context.Context, req *pb.AssignTaskRequest) (*pb.AssignTaskResponse, error) {
+	if req.GetTask() == nil {
+		return nil, status.Error(codes.InvalidArgument, "task is required")
+	}
+	agentID, err := s.orchestrator.AssignTask(ctx, TaskFromProto(req.GetTask()))
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to assign task: %v", err)
+	}
+	return &pb.AssignTaskResponse{AgentId: string(agentID)}, nil
+}
+
+// GetTaskStatus returns the status of a task
+func (s *OrchestratorServer) GetTaskStatus(ctx context.Context, req *pb.GetTaskStatusRequest) (*pb.GetTaskStatusResponse, error) {
+	taskStatus, err := s.orchestrator.GetTaskStatus(ctx, req.GetTaskId())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "failed to get task status: %v", err)
+	}
+	return &pb.GetTaskStatusResponse{Status: string(taskStatus)}, nil
+}
+
+// SubmitEvent queues an event reported by a remote agent
+func (s *OrchestratorServer) SubmitEvent(ctx context.Context, req *pb.SubmitEventRequest) (*pb.SubmitEventResponse, error) {
+	submitter, ok := s.orchestrator.(EventSubmitter)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "orchestrator does not accept external events")
+	}
+	if req.GetEvent() == nil {
+		return nil, status.Error(codes.InvalidArgument, "event is required")
+	}
+	if err := submitter.SubmitEvent(EventFromProto(req.GetEvent())); err != nil {
+		return nil, status.Errorf(codes.ResourceExhausted, "failed to submit event: %v", err)
+	}
+	return &pb.SubmitEventResponse{}, nil
+}
+
+// GetSystemHealth returns the health of the system
+func (s *OrchestratorServer) GetSystemHealth(ctx context.Context, req *pb.GetSystemHealthRequest) (*pb.SystemHealth, error) {
+	health, err := SystemHealthToProto(s.orchestrator.GetSystemHealth())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to convert health: %v", err)
+	}
+	return health, nil
+}
+
+// AgentServer serves a multiagent.Agent over gRPC, so a Go agent can run in
+// its own process
+type AgentServer struct {
+	pb.UnimplementedAgentServer
+	agent multiagent.Agent
+}
+
+// NewAgentServer creates a gRPC server for agent; register it with
+// pb.RegisterAgentServer
+func NewAgentServer(agent multiagent.Agent) *AgentServer {
+	return &AgentServer{agent: agent}
+}
+
+// HandleMessage passes a message to the agent and returns its reply
+func (s *AgentServer) HandleMessage(ctx context.Context, req *pb.HandleMessageRequest) (*pb.HandleMessageResponse, error) {
+	if req.GetMessage() == nil {
+		return nil, status.Error(codes.InvalidArgument, "message is required")
+	}
+	response, err := s.agent.HandleMessage(ctx, MessageFromProto(req.GetMessage()))
+	if err != nil {
+		return nil, status.Errorf(codes.Unknown, "agent failed to handle message: %v", err)
+	}
+	converted, err := MessageToProto(response)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to convert response: %v", err)
+	}
+	return &pb.HandleMessageResponse{Response: converted}, nil
+}
+
+// GetState returns the agent's current state
+func (s *AgentServer) GetState(ctx context.Context, req *pb.GetStateRequest) (*pb.AgentState, error) {
+	state, err := AgentStateToProto(s.agent.GetState())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to convert state: %v", err)
+	}
+	return state, nil
+}
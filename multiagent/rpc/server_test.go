@@ -0,0 +1,195 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+	pb "github.com/kbutz/wikillm/multiagent/rpc/multiagentpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type echoAgent struct {
+	id       multiagent.AgentID
+	received chan *multiagent.Message
+}
+
+func (a *echoAgent) ID() multiagent.AgentID                                 { return a.id }
+func (a *echoAgent) Type() multiagent.AgentType                             { return multiagent.AgentTypeResearch }
+func (a *echoAgent) Name() string                                           { return "Echo" }
+func (a *echoAgent) Description() string                                    { return "echoes messages" }
+func (a *echoAgent) Initialize(ctx context.Context) error                   { return nil }
+func (a *echoAgent) Start(ctx context.Context) error                        { return nil }
+func (a *echoAgent) Stop(ctx context.Context) error                         { return nil }
+func (a *echoAgent) GetCapabilities() []string                              { return []string{"echo"} }
+func (a *echoAgent) CanHandle(multiagent.MessageType) bool                  { return true }
+func (a *echoAgent) SendMessage(context.Context, *multiagent.Message) error { return nil }
+func (a *echoAgent) ReceiveMessage(context.Context) (*multiagent.Message, error) {
+	return nil, nil
+}
+func (a *echoAgent) GetState() multiagent.AgentState {
+	return multiagent.AgentState{Status: multiagent.AgentStatusIdle, Capabilities: []string{"echo"}, Workload: 40}
+}
+
+func (a *echoAgent) HandleMessage(ctx context.Context, msg *multiagent.Message) (*multiagent.Message, error) {
+	if a.received != nil {
+		a.received <- msg
+	}
+	return &multiagent.Message{
+		ID:      "reply_" + msg.ID,
+		From:    a.id,
+		To:      []multiagent.AgentID{msg.From},
+		Type:    multiagent.MessageTypeResponse,
+		Content: "echo: " + msg.Content,
+		Context: msg.Context,
+		ReplyTo: msg.ID,
+	}, nil
+}
+
+// dial serves register on an in-memory listener and returns a client connection
+func dial(t *testing.T, register func(*grpc.Server)) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestOrchestratorServer(t *testing.T) {
+	ctx := context.Background()
+	store, err := memory.NewSQLiteMemoryStore(filepath.Join(t.TempDir(), "memory.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	orch := orchestrator.NewOrchestrator(orchestrator.OrchestratorConfig{MemoryStore: store})
+	agent := &echoAgent{id: "echo_agent", received: make(chan *multiagent.Message, 1)}
+	if err := orch.RegisterAgent(agent); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	if err := orch.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { orch.Stop(ctx) })
+
+	client := pb.NewOrchestratorClient(dial(t, func(s *grpc.Server) {
+		pb.RegisterOrchestratorServer(s, NewOrchestratorServer(orch))
+	}))
+
+	_, err = client.RouteMessage(ctx, &pb.RouteMessageRequest{Message: &pb.Message{
+		Id:       "msg_remote",
+		From:     "python_agent",
+		To:       []string{"echo_agent"},
+		Type:     "notification",
+		Content:  "hello from another process",
+		Priority: pb.Priority_PRIORITY_HIGH,
+	}})
+	if err != nil {
+		t.Fatalf("RouteMessage: %v", err)
+	}
+	select {
+	case msg := <-agent.received:
+		if msg.Content != "hello from another process" || msg.Priority != multiagent.PriorityHigh {
+			t.Errorf("unexpected routed message %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message was not routed to the agent")
+	}
+
+	if _, err := client.SubmitEvent(ctx, &pb.SubmitEventRequest{Event: &pb.Event{Type: "task_completed", Source: "python_agent"}}); err != nil {
+		t.Errorf("SubmitEvent: %v", err)
+	}
+	if _, err := client.SubmitEvent(ctx, &pb.SubmitEventRequest{Event: &pb.Event{}}); err == nil {
+		t.Error("expected an untyped event to be rejected")
+	}
+
+	health, err := client.GetSystemHealth(ctx, &pb.GetSystemHealthRequest{})
+	if err != nil {
+		t.Fatalf("GetSystemHealth: %v", err)
+	}
+	if health.GetTotalAgents() != 1 || health.GetAgentHealth()["echo_agent"].GetWorkload() != 40 {
+		t.Errorf("unexpected health %+v", health)
+	}
+}
+
+func TestAgentServerRoundTrip(t *testing.T) {
+	client := pb.NewAgentClient(dial(t, func(s *grpc.Server) {
+		pb.RegisterAgentServer(s, NewAgentServer(&echoAgent{id: "echo_agent"}))
+	}))
+
+	sent := &multiagent.Message{
+		ID:        "msg_1",
+		From:      "coordinator_agent",
+		To:        []multiagent.AgentID{"echo_agent"},
+		Type:      multiagent.MessageTypeRequest,
+		Content:   "ping",
+		Context:   map[string]interface{}{"conversation_id": "conv_alice", "attempt": 2},
+		Priority:  multiagent.PriorityCritical,
+		Timestamp: time.Now(),
+	}
+	wire, err := MessageToProto(sent)
+	if err != nil {
+		t.Fatalf("MessageToProto: %v", err)
+	}
+	resp, err := client.HandleMessage(context.Background(), &pb.HandleMessageRequest{Message: wire})
+	if err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+
+	reply := MessageFromProto(resp.GetResponse())
+	if reply.Content != "echo: ping" || reply.ReplyTo != "msg_1" || reply.To[0] != "coordinator_agent" {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+	if reply.Context["conversation_id"] != "conv_alice" || reply.Context["attempt"] != float64(2) {
+		t.Errorf("context not preserved: %v", reply.Context)
+	}
+
+	state, err := client.GetState(context.Background(), &pb.GetStateRequest{})
+	if err != nil {
+		t.Fatalf("GetState: %v", err)
+	}
+	if got := AgentStateFromProto(state); got.Status != multiagent.AgentStatusIdle || got.Capabilities[0] != "echo" {
+		t.Errorf("unexpected state %+v", got)
+	}
+}
+
+func TestTaskConversionRoundTrip(t *testing.T) {
+	deadline := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	task := multiagent.Task{
+		ID:        "task_1",
+		Type:      "research",
+		Priority:  multiagent.PriorityHigh,
+		Status:    multiagent.TaskStatusPending,
+		Input:     map[string]interface{}{"topic": "solar panels"},
+		Deadline:  &deadline,
+		DependsOn: []string{"task_0"},
+		Timeout:   90 * time.Second,
+	}
+	wire, err := TaskToProto(&task)
+	if err != nil {
+		t.Fatalf("TaskToProto: %v", err)
+	}
+	got := TaskFromProto(wire)
+	if got.ID != task.ID || got.Priority != task.Priority || got.Timeout != task.Timeout ||
+		!got.Deadline.Equal(deadline) || got.DependsOn[0] != "task_0" || got.Input["topic"] != "solar panels" {
+		t.Errorf("round trip changed the task: %+v", got)
+	}
+}
@@ -0,0 +1,164 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultWikipediaURL is the English Wikipedia
+	DefaultWikipediaURL = "https://en.wikipedia.org"
+	// requestTimeout bounds each search
+	requestTimeout = 15 * time.Second
+	// userAgent identifies the assistant, as Wikimedia's API policy asks
+	userAgent = "wikillm-research-assistant/1.0 (https://github.com/kbutz/wikillm)"
+)
+
+// WikipediaSearcher searches a MediaWiki site, such as Wikipedia, with its
+// full-text search API
+type WikipediaSearcher struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewWikipediaSearcher searches the wiki at baseURL, DefaultWikipediaURL if
+// empty; client defaults to http.DefaultClient
+func NewWikipediaSearcher(baseURL string, client *http.Client) *WikipediaSearcher {
+	if baseURL == "" {
+		baseURL = DefaultWikipediaURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WikipediaSearcher{baseURL: strings.TrimRight(baseURL, "/"), client: client}
+}
+
+// Search returns the articles matching query
+func (s *WikipediaSearcher) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	params := url.Values{
+		"action":   {"query"},
+		"list":     {"search"},
+		"srsearch": {query},
+		"srlimit":  {strconv.Itoa(limit)},
+		"format":   {"json"},
+		"utf8":     {"1"},
+	}
+	var response struct {
+		Query struct {
+			Search []struct {
+				Title   string `json:"title"`
+				Snippet string `json:"snippet"`
+			} `json:"search"`
+		} `json:"query"`
+		Error *struct {
+			Info string `json:"info"`
+		} `json:"error"`
+	}
+	if err := getJSON(ctx, s.client, s.baseURL+"/w/api.php?"+params.Encode(), &response); err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("wikipedia: %s", response.Error.Info)
+	}
+
+	results := make([]Result, 0, len(response.Query.Search))
+	for i, page := range response.Query.Search {
+		results = append(results, Result{
+			Title:   page.Title,
+			URL:     WikipediaURL(s.baseURL, page.Title),
+			Snippet: plainText(page.Snippet),
+			Score:   rankScore(i),
+			Source:  SourceWikipedia,
+		})
+	}
+	return results, nil
+}
+
+// SearxNGSearcher searches the web through a SearxNG instance's JSON API,
+// which the instance has to enable in its settings.yml (search.formats)
+type SearxNGSearcher struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewSearxNGSearcher searches through the SearxNG instance at baseURL;
+// client defaults to http.DefaultClient
+func NewSearxNGSearcher(baseURL string, client *http.Client) *SearxNGSearcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &SearxNGSearcher{baseURL: strings.TrimRight(baseURL, "/"), client: client}
+}
+
+// Search returns the web pages matching query
+func (s *SearxNGSearcher) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	params := url.Values{"q": {query}, "format": {"json"}}
+	var response struct {
+		Results []struct {
+			Title   string  `json:"title"`
+			URL     string  `json:"url"`
+			Content string  `json:"content"`
+			Score   float64 `json:"score"`
+		} `json:"results"`
+	}
+	if err := getJSON(ctx, s.client, s.baseURL+"/search?"+params.Encode(), &response); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for i, page := range response.Results {
+		if len(results) == limit {
+			break
+		}
+		score := page.Score
+		if score <= 0 {
+			score = rankScore(i)
+		}
+		results = append(results, Result{
+			Title:   plainText(page.Title),
+			URL:     page.URL,
+			Snippet: plainText(page.Content),
+			Score:   score,
+			Source:  SourceWeb,
+		})
+	}
+	return results, nil
+}
+
+// rankScore scores the result at index i of a backend that only ranks
+func rankScore(i int) float64 {
+	return 1 / float64(i+1)
+}
+
+// getJSON GETs address and decodes the JSON response into v
+func getJSON(ctx context.Context, client *http.Client, address string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("search request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode search response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+// Package search looks things up on the web and in Wikipedia for the
+// research assistant. Each backend is a Searcher, and NewTool wraps one as a
+// tool whose results agents can read back with ParseResults.
+//
+// The server's -web-search-url searches the web through a SearxNG instance
+// and -wikipedia-url searches a MediaWiki site; "wikillm assistant serve
+// --wikipedia-rag" searches the local Qdrant Wikipedia index instead.
+//
+// The research assistant breaks a question into a few sub-queries (the
+// research.decompose prompt) and searches each with every tool. Results for
+// the same page are merged and scored by search rank, how many sub-queries
+// found them and how much of the question they mention. The best are
+// synthesized into a report that cites them by number (research.synthesize),
+// and the research session keeps them as its sources, with findings linked
+// to the sources they cite. Without search tools, research falls back to the
+// single research.conduct prompt.
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Names of the search tools the research assistant looks for
+const (
+	WebToolName       = "web_search"
+	WikipediaToolName = "wikipedia"
+)
+
+// Sources a Result can come from
+const (
+	SourceWeb       = "web"
+	SourceWikipedia = "wikipedia"
+)
+
+// defaultLimit is how many results a tool returns when not asked for a
+// number
+const defaultLimit = 5
+
+// Result is a page found by a search
+type Result struct {
+	Title   string `json:"title"`
+	URL     string `json:"url,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+	// Score is the backend's relevance score, if it gives one; it is only
+	// comparable between results of the same search
+	Score float64 `json:"score,omitempty"`
+	// Source is where the result came from, SourceWeb or SourceWikipedia
+	Source string `json:"source"`
+}
+
+// Searcher finds pages matching a query, best first
+type Searcher interface {
+	Search(ctx context.Context, query string, limit int) ([]Result, error)
+}
+
+// Tool exposes a Searcher to agents
+type Tool struct {
+	name        string
+	description string
+	searcher    Searcher
+}
+
+// NewTool creates a tool named name that searches with searcher
+func NewTool(name, description string, searcher Searcher) *Tool {
+	return &Tool{name: name, description: description, searcher: searcher}
+}
+
+// Name returns the name of the tool
+func (t *Tool) Name() string {
+	return t.name
+}
+
+// Description returns a description of what the tool does
+func (t *Tool) Description() string {
+	return t.description + `.
+Returns a JSON object whose results list the title, URL and a snippet of each page found.
+
+Examples:
+- heat pumps in cold climates
+- {"query": "heat pump efficiency", "limit": 3}`
+}
+
+// Parameters returns the parameter schema for the tool
+func (t *Tool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "What to search for",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of results to return",
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+// Execute searches with the given arguments, a query or a JSON object
+// with query and limit, and returns the results as JSON
+func (t *Tool) Execute(ctx context.Context, args string) (string, error) {
+	var params struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	if strings.HasPrefix(strings.TrimSpace(args), "{") {
+		if err := json.Unmarshal([]byte(args), &params); err != nil {
+			return "", fmt.Errorf("failed to parse JSON arguments: %w", err)
+		}
+	} else {
+		params.Query = args
+	}
+	if params.Query = strings.TrimSpace(params.Query); params.Query == "" {
+		return "", fmt.Errorf("query parameter is required")
+	}
+	if params.Limit <= 0 {
+		params.Limit = defaultLimit
+	}
+
+	results, err := t.searcher.Search(ctx, params.Query, params.Limit)
+	if err != nil {
+		return "", fmt.Errorf("%s search failed: %w", t.name, err)
+	}
+	if results == nil {
+		results = []Result{}
+	}
+	data, err := json.Marshal(struct {
+		Query   string   `json:"query"`
+		Results []Result `json:"results"`
+	}{params.Query, results})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ParseResults reads the results out of a search tool's output
+func ParseResults(output string) ([]Result, error) {
+	var parsed struct {
+		Results []Result `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse search results: %w", err)
+	}
+	return parsed.Results, nil
+}
+
+// WikipediaURL returns the address of the article titled title on the
+// Wikipedia at baseURL, such as https://en.wikipedia.org
+func WikipediaURL(baseURL, title string) string {
+	return strings.TrimRight(baseURL, "/") + "/wiki/" + url.PathEscape(strings.ReplaceAll(title, " ", "_"))
+}
+
+var tag = regexp.MustCompile(`<[^>]*>`)
+
+// plainText strips the markup search backends highlight matches with
+func plainText(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(tag.ReplaceAllString(s, ""))), " ")
+}
@@ -0,0 +1,110 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWikipediaSearcher(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/w/api.php" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.Query().Get("srsearch")
+		w.Write([]byte(`{"query": {"search": [
+			{"title": "Heat pump", "snippet": "A <span class=\"searchmatch\">heat</span> pump moves heat &amp; cools"},
+			{"title": "Coefficient of performance", "snippet": "ratio of heating"}
+		]}}`))
+	}))
+	defer server.Close()
+
+	results, err := NewWikipediaSearcher(server.URL, nil).Search(context.Background(), "heat pump", 2)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if query != "heat pump" {
+		t.Errorf("searched for %q", query)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	want := Result{
+		Title:   "Heat pump",
+		URL:     server.URL + "/wiki/Heat_pump",
+		Snippet: "A heat pump moves heat & cools",
+		Score:   1,
+		Source:  SourceWikipedia,
+	}
+	if results[0] != want {
+		t.Errorf("first result = %+v, want %+v", results[0], want)
+	}
+	if results[1].Score >= results[0].Score {
+		t.Errorf("later results should score lower: %v >= %v", results[1].Score, results[0].Score)
+	}
+}
+
+func TestSearxNGSearcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" || r.URL.Query().Get("format") != "json" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"results": [
+			{"title": "Heat pumps explained", "url": "https://example.org/hp", "content": "How they work", "score": 2.5},
+			{"title": "Cold climate heat pumps", "url": "https://example.org/cold", "content": "Below freezing"},
+			{"title": "Third", "url": "https://example.org/3"}
+		]}`))
+	}))
+	defer server.Close()
+
+	results, err := NewSearxNGSearcher(server.URL, nil).Search(context.Background(), "heat pumps", 2)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want the 2 asked for", len(results))
+	}
+	if results[0].URL != "https://example.org/hp" || results[0].Score != 2.5 || results[0].Source != SourceWeb {
+		t.Errorf("first result = %+v", results[0])
+	}
+
+	if _, err := NewSearxNGSearcher(server.URL+"/nope", nil).Search(context.Background(), "x", 1); err == nil {
+		t.Error("Search succeeded against an instance without the JSON format")
+	}
+}
+
+type fakeSearcher []Result
+
+func (f fakeSearcher) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	if len(f) > limit {
+		return f[:limit], nil
+	}
+	return f, nil
+}
+
+func TestToolRoundTrip(t *testing.T) {
+	tool := NewTool(WebToolName, "Search the web", fakeSearcher{
+		{Title: "A", URL: "https://a.example", Source: SourceWeb},
+		{Title: "B", URL: "https://b.example", Source: SourceWeb},
+	})
+
+	output, err := tool.Execute(context.Background(), `{"query": "letters", "limit": 1}`)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	results, err := ParseResults(output)
+	if err != nil {
+		t.Fatalf("ParseResults: %v", err)
+	}
+	if len(results) != 1 || results[0].Title != "A" {
+		t.Errorf("results = %+v, want just A", results)
+	}
+
+	if _, err := tool.Execute(context.Background(), "  "); err == nil {
+		t.Error("Execute accepted an empty query")
+	}
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/cron"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+)
+
+const (
+	// briefingKeyPrefix holds each user's briefings by date
+	briefingKeyPrefix = "briefing:"
+	// defaultBriefingSchedule sends briefings at 7am every day
+	defaultBriefingSchedule = "0 7 * * *"
+	// defaultWeatherURL is a one-line forecast from wttr.in
+	defaultWeatherURL = "https://wttr.in/{location}?format=3"
+	// briefingCheckInterval is how often the scheduler looks for due briefings
+	briefingCheckInterval = time.Minute
+)
+
+// BriefingConfig configures the daily agenda briefing
+type BriefingConfig struct {
+	// Disabled turns briefings off
+	Disabled bool
+	// Schedule is a cron expression read in each user's timezone (default
+	// "0 7 * * *")
+	Schedule string
+	// WeatherURL is fetched with the http tool for the weather, with
+	// "{location}" replaced by the user's location (default wttr.in)
+	WeatherURL string
+	// Locations maps user IDs to the place their weather is for; users
+	// without one get no weather
+	Locations map[string]string
+	// Timeout bounds assembling one briefing (default 2 minutes)
+	Timeout time.Duration
+}
+
+// Briefing is the agenda a user was sent for a day
+type Briefing struct {
+	UserID    string    `json:"user_id"`
+	Date      string    `json:"date"`
+	Content   string    `json:"content"`
+	Weather   string    `json:"weather,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// briefingScheduler sends each user their briefing when the schedule
+// fires in their timezone
+type briefingScheduler struct {
+	service  *MultiAgentService
+	schedule *cron.Schedule
+
+	mu sync.Mutex
+	// checked is when each user's schedule was last checked
+	checked map[string]time.Time
+	// cancel interrupts briefings being assembled when the scheduler stops
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+// withDefaults fills in the defaults of unset fields
+func (c BriefingConfig) withDefaults() BriefingConfig {
+	if c.Schedule == "" {
+		c.Schedule = defaultBriefingSchedule
+	}
+	if c.WeatherURL == "" {
+		c.WeatherURL = defaultWeatherURL
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 2 * time.Minute
+	}
+	return c
+}
+
+// newBriefingScheduler creates a scheduler firing on the cron expression spec
+func newBriefingScheduler(service *MultiAgentService, spec string) (*briefingScheduler, error) {
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse briefing schedule: %w", err)
+	}
+	return &briefingScheduler{
+		service:  service,
+		schedule: schedule,
+		checked:  make(map[string]time.Time),
+	}, nil
+}
+
+// Start checks for due briefings every minute until Stop
+func (b *briefingScheduler) Start(ctx context.Context) {
+	b.mu.Lock()
+	if b.running {
+		b.mu.Unlock()
+		return
+	}
+	b.running = true
+	ctx, b.cancel = context.WithCancel(ctx)
+	b.mu.Unlock()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		ticker := time.NewTicker(briefingCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				b.sendDue(ctx, now)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	logger.InfoContext(ctx, "Scheduled daily briefings", "schedule", b.schedule.String())
+}
+
+// Stop stops the scheduler, abandoning briefings being assembled
+func (b *briefingScheduler) Stop() {
+	b.mu.Lock()
+	if !b.running {
+		b.mu.Unlock()
+		return
+	}
+	b.running = false
+	b.cancel()
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+// sendDue sends the briefing of every user whose schedule fired since it
+// was last checked. Users are checked from when they are first seen, so
+// briefings missed while the service was down are not sent late.
+func (b *briefingScheduler) sendDue(ctx context.Context, now time.Time) {
+	users, err := b.service.ListUsers(ctx)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to list users for briefings", "error", err)
+		return
+	}
+
+	for _, user := range users {
+		userCtx := multiagent.WithUserID(ctx, user.ID)
+		profile, err := memory.LoadProfile(userCtx, b.service.userMemory)
+		if err != nil {
+			logger.WarnContext(ctx, "Failed to load profile for briefing", logging.KeyUserID, user.ID, "error", err)
+		}
+
+		b.mu.Lock()
+		last, seen := b.checked[user.ID]
+		b.checked[user.ID] = now
+		b.mu.Unlock()
+		if !seen {
+			continue
+		}
+		if next := b.schedule.Next(last.In(profile.Location())); next.IsZero() || next.After(now) {
+			continue
+		}
+
+		b.wg.Add(1)
+		go func(userID string) {
+			defer b.wg.Done()
+			if _, err := b.service.sendBriefing(userCtx); err != nil {
+				logger.WarnContext(ctx, "Failed to send daily briefing", logging.KeyUserID, userID, "error", err)
+			}
+		}(user.ID)
+	}
+}
+
+// SendBriefing assembles the user's briefing for today now, stores it and
+// delivers it through their notification channels
+func (s *MultiAgentService) SendBriefing(ctx context.Context, userID string) (*Briefing, error) {
+	return s.sendBriefing(multiagent.WithUserID(ctx, userID))
+}
+
+// sendBriefing has the coordinator gather today's events, due and overdue
+// tasks and pending follow-ups from the specialists into a briefing for
+// the user ctx acts for
+func (s *MultiAgentService) sendBriefing(ctx context.Context) (*Briefing, error) {
+	userID := multiagent.UserIDFromContext(ctx)
+	ctx, cancel := context.WithTimeout(ctx, s.briefingConfig.Timeout)
+	defer cancel()
+
+	profile, err := memory.LoadProfile(ctx, s.userMemory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load profile: %w", err)
+	}
+	today := s.now().In(profile.Location())
+	weather := s.briefingWeather(ctx, userID)
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Prepare my daily briefing for %s. Cover:\n", today.Format("Monday, 2 January 2006"))
+	prompt.WriteString("- my calendar events today\n")
+	prompt.WriteString("- tasks due today and overdue tasks\n")
+	prompt.WriteString("- pending follow-ups: messages awaiting a reply and people I said I'd get back to\n")
+	if weather != "" {
+		fmt.Fprintf(&prompt, "\nToday's weather: %s\n", weather)
+	}
+	prompt.WriteString("\nKeep it short, with a heading per section, and start with the weather if there is any.")
+
+	content, err := s.askCoordinator(ctx, userID, "briefing_"+userID, prompt.String(), []multiagent.AgentType{
+		multiagent.AgentTypeScheduler,
+		multiagent.AgentTypeTask,
+		multiagent.AgentTypeCommunicationManager,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	briefing := &Briefing{
+		UserID:    userID,
+		Date:      today.Format("2006-01-02"),
+		Content:   content,
+		Weather:   weather,
+		CreatedAt: s.now(),
+	}
+	key := briefingKeyPrefix + briefing.Date
+	if err := s.userMemory.Store(ctx, key, briefing); err != nil {
+		return nil, fmt.Errorf("failed to store briefing: %w", err)
+	}
+
+	err = s.notifier.Notify(ctx, notify.Notification{
+		UserID:   userID,
+		Kind:     notify.KindBriefing,
+		Title:    "Your briefing for " + today.Format("Monday, 2 January"),
+		Body:     content,
+		Priority: multiagent.PriorityMedium,
+		Subject:  key,
+	})
+	if err != nil {
+		return briefing, fmt.Errorf("failed to deliver briefing: %w", err)
+	}
+	logger.InfoContext(ctx, "Sent daily briefing", logging.KeyUserID, userID, "date", briefing.Date)
+	return briefing, nil
+}
+
+// briefingWeather fetches the user's weather with the http tool, or
+// returns "" if they have no location or it cannot be fetched
+func (s *MultiAgentService) briefingWeather(ctx context.Context, userID string) string {
+	location := s.briefingConfig.Locations[userID]
+	tool, ok := s.tools["http"]
+	if location == "" || !ok {
+		return ""
+	}
+	weather, err := tool.Execute(ctx, strings.ReplaceAll(s.briefingConfig.WeatherURL, "{location}", url.PathEscape(location)))
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to fetch weather for briefing", logging.KeyUserID, userID, "error", err)
+		return ""
+	}
+	return strings.TrimSpace(weather)
+}
+
+// askCoordinator assigns the coordinator a task to answer message with the
+// given specialists and waits for its synthesized reply
+func (s *MultiAgentService) askCoordinator(ctx context.Context, userID, conversationID, message string, specialists []multiagent.AgentType) (string, error) {
+	orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator)
+	if !ok {
+		return "", fmt.Errorf("orchestrator does not support user requests")
+	}
+
+	// The coordinator's reply answers the request, which is kept out of the
+	// conversation so questions meant for the user don't land in a briefing
+	request := orch.OpenRequest("", 0)
+
+	task := multiagent.Task{
+		ID:          s.newID("task_" + conversationID),
+		Type:        "user_request",
+		Description: fmt.Sprintf("Handle user request: %s", message),
+		Priority:    multiagent.PriorityMedium,
+		Requester:   request.ID,
+		Assignee:    multiagent.AgentID("coordinator_agent"),
+		Input: map[string]interface{}{
+			"user_message":           message,
+			"conversation_id":        conversationID,
+			"specialists":            specialists,
+			"response_key":           string(request.ID),
+			multiagent.ContextUserID: userID,
+		},
+	}
+	if _, err := s.orchestrator.AssignTask(ctx, task); err != nil {
+		request.Cancel()
+		return "", fmt.Errorf("failed to assign task to coordinator: %w", err)
+	}
+
+	reply, err := request.Wait(ctx)
+	if err != nil {
+		return "", fmt.Errorf("coordinator did not reply: %w", err)
+	}
+	return reply, nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/caldav"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// ExportCalendar returns the calendar of the user ctx acts for as an
+// iCalendar (.ics) file
+func (s *MultiAgentService) ExportCalendar(ctx context.Context) ([]byte, error) {
+	exchanger, err := s.calendarExchanger()
+	if err != nil {
+		return nil, err
+	}
+	return exchanger.ExportICS(ctx)
+}
+
+// ListEvents returns the events of the user ctx acts for that overlap from
+// to to, in start order; recurring events are listed once, at their first
+// occurrence
+func (s *MultiAgentService) ListEvents(ctx context.Context, from, to time.Time) ([]*agents.CalendarEvent, error) {
+	keys, err := s.userMemory.List(ctx, "calendar_event:", 10000)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	values, err := s.userMemory.GetMultiple(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events: %w", err)
+	}
+
+	events := make([]*agents.CalendarEvent, 0, len(values))
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		var event agents.CalendarEvent
+		if err := json.Unmarshal(data, &event); err != nil || event.ID == "" {
+			continue
+		}
+		if event.EndTime.Before(from) || !event.StartTime.Before(to) {
+			continue
+		}
+		events = append(events, &event)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].StartTime.Before(events[j].StartTime) })
+	return events, nil
+}
+
+// ImportCalendar adds the events in an iCalendar (.ics) file to the
+// calendar of the user ctx acts for, updating events imported before
+func (s *MultiAgentService) ImportCalendar(ctx context.Context, data []byte) (*agents.ICSImportResult, error) {
+	exchanger, err := s.calendarExchanger()
+	if err != nil {
+		return nil, err
+	}
+	return exchanger.ImportICS(ctx, data)
+}
+
+// ImportParticipantCalendar records the events in an iCalendar (.ics) file
+// as another person's busy times, for finding times when everyone is free
+func (s *MultiAgentService) ImportParticipantCalendar(ctx context.Context, name, email string, data []byte) (*agents.Participant, error) {
+	for _, agent := range s.agents {
+		if calendar, ok := agent.(agents.ParticipantCalendar); ok {
+			return calendar.ImportParticipantICS(ctx, name, email, data)
+		}
+	}
+	return nil, fmt.Errorf("no agent tracks participants' calendars")
+}
+
+// startCalendarSync starts a syncer for every configured CalDAV account
+func (s *MultiAgentService) startCalendarSync(ctx context.Context) error {
+	if len(s.caldavAccounts) == 0 {
+		return nil
+	}
+	exchanger, err := s.calendarExchanger()
+	if err != nil {
+		return err
+	}
+	calendar, ok := exchanger.(caldav.LocalCalendar)
+	if !ok {
+		return fmt.Errorf("the calendar agent does not support CalDAV sync")
+	}
+
+	for _, account := range s.caldavAccounts {
+		client, err := caldav.NewClient(caldav.ClientConfig{
+			CalendarURL: account.CalendarURL,
+			Username:    account.Username,
+			Password:    account.Password,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to configure caldav account %q: %w", account.Name, err)
+		}
+		syncer := caldav.NewSyncer(caldav.SyncerConfig{
+			Name:     account.Name,
+			UserID:   account.UserID,
+			Client:   client,
+			Calendar: calendar,
+			Store:    s.userMemory,
+			Interval: time.Duration(account.Interval),
+			Policy:   account.Policy,
+		})
+		syncer.Start(ctx)
+		s.caldavSyncers = append(s.caldavSyncers, syncer)
+		logger.InfoContext(ctx, "Syncing CalDAV calendar", "account", account.Name, logging.KeyUserID, account.UserID)
+	}
+	return nil
+}
+
+// calendarExchanger returns the agent that owns the calendar
+func (s *MultiAgentService) calendarExchanger() (agents.CalendarExchanger, error) {
+	for _, agent := range s.agents {
+		if exchanger, ok := agent.(agents.CalendarExchanger); ok {
+			return exchanger, nil
+		}
+	}
+	return nil, fmt.Errorf("no agent manages a calendar")
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kbutz/wikillm/multiagent/agents"
+)
+
+// ExportContacts returns the contacts of the user ctx acts for in format,
+// one of agents.ContactFormatVCard and agents.ContactFormatCSV
+func (s *MultiAgentService) ExportContacts(ctx context.Context, format string) ([]byte, error) {
+	exchanger, err := s.contactExchanger()
+	if err != nil {
+		return nil, err
+	}
+	return exchanger.ExportContacts(ctx, format)
+}
+
+// ImportContacts adds the contacts in a vCard or CSV file to the address
+// book of the user ctx acts for; duplicates of existing contacts wait for
+// the user to merge them in conversation
+func (s *MultiAgentService) ImportContacts(ctx context.Context, format string, data []byte) (*agents.ContactImportResult, error) {
+	exchanger, err := s.contactExchanger()
+	if err != nil {
+		return nil, err
+	}
+	return exchanger.ImportContacts(ctx, format, data)
+}
+
+// contactExchanger returns the agent that owns the address book
+func (s *MultiAgentService) contactExchanger() (agents.ContactExchanger, error) {
+	for _, agent := range s.agents {
+		if exchanger, ok := agent.(agents.ContactExchanger); ok {
+			return exchanger, nil
+		}
+	}
+	return nil, fmt.Errorf("no agent manages contacts")
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kbutz/wikillm/multiagent/email"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// startInboxPolling starts a poller for every email account with an IMAP
+// mailbox
+func (s *MultiAgentService) startInboxPolling(ctx context.Context) error {
+	var inbox email.Inbox
+	for _, agent := range s.agents {
+		if candidate, ok := agent.(email.Inbox); ok {
+			inbox = candidate
+			break
+		}
+	}
+
+	for _, account := range s.emailAccounts {
+		if account.IMAPAddr == "" {
+			continue
+		}
+		if inbox == nil {
+			return fmt.Errorf("no agent logs inbound email")
+		}
+		poller, err := email.NewPoller(email.PollerConfig{
+			Account: account,
+			Inbox:   inbox,
+			Store:   s.userMemory,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to configure mailbox of %q: %w", account.UserID, err)
+		}
+		poller.Start(ctx)
+		s.emailPollers = append(s.emailPollers, poller)
+		logger.InfoContext(ctx, "Polling mailbox", "server", account.IMAPAddr, logging.KeyUserID, account.UserID)
+	}
+	return nil
+}
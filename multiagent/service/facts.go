@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/llmprovider"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+)
+
+const (
+	// factCursorPrefix records how far each conversation's transcript has
+	// been mined for facts, in its user's partition
+	factCursorPrefix = "fact_extraction:"
+	// defaultFactInterval is how often transcripts are mined for facts
+	defaultFactInterval = 30 * time.Minute
+	// factBatchSize bounds the transcript entries sent in one prompt
+	factBatchSize = 30
+)
+
+// FactExtractionConfig configures mining conversations for facts about
+// their users
+type FactExtractionConfig struct {
+	// Disabled turns the background extractor off; ExtractFacts still works
+	Disabled bool
+	// Interval is how often every user's new messages are mined (default 30
+	// minutes)
+	Interval time.Duration
+}
+
+// withDefaults fills in the defaults of unset fields
+func (c FactExtractionConfig) withDefaults() FactExtractionConfig {
+	if c.Interval <= 0 {
+		c.Interval = defaultFactInterval
+	}
+	return c
+}
+
+// factCursor is how far a conversation has been mined
+type factCursor struct {
+	MinedThrough time.Time `json:"mined_through"`
+}
+
+// extractedFacts is the LLM's reply to the facts.extract prompt
+type extractedFacts struct {
+	Facts []struct {
+		Kind      string `json:"kind"`
+		Statement string `json:"statement"`
+		Message   int    `json:"message"`
+	} `json:"facts"`
+}
+
+var extractedFactsSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []interface{}{"facts"},
+	"properties": map[string]interface{}{
+		"facts": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"kind", "statement", "message"},
+				"properties": map[string]interface{}{
+					"kind":      map[string]interface{}{"type": "string"},
+					"statement": map[string]interface{}{"type": "string"},
+					"message":   map[string]interface{}{"type": "integer"},
+				},
+			},
+		},
+	},
+}
+
+// factExtractor mines every user's conversations for facts in the
+// background
+type factExtractor struct {
+	service  *MultiAgentService
+	interval time.Duration
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+// Start mines new messages every interval until Stop
+func (e *factExtractor) Start(ctx context.Context) {
+	e.mu.Lock()
+	if e.running {
+		e.mu.Unlock()
+		return
+	}
+	e.running = true
+	ctx, e.cancel = context.WithCancel(ctx)
+	e.mu.Unlock()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				e.extractAll(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	logger.InfoContext(ctx, "Scheduled fact extraction", "interval", e.interval)
+}
+
+// Stop stops the extractor, abandoning a pass under way
+func (e *factExtractor) Stop() {
+	e.mu.Lock()
+	if !e.running {
+		e.mu.Unlock()
+		return
+	}
+	e.running = false
+	e.cancel()
+	e.mu.Unlock()
+
+	e.wg.Wait()
+}
+
+// extractAll mines the new messages of every user, one at a time so the
+// LLM is left to the users talking to it
+func (e *factExtractor) extractAll(ctx context.Context) {
+	users, err := e.service.ListUsers(ctx)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to list users for fact extraction", "error", err)
+		return
+	}
+	for _, user := range users {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := e.service.ExtractFacts(ctx, user.ID); err != nil {
+			logger.WarnContext(ctx, "Failed to extract facts", logging.KeyUserID, user.ID, "error", err)
+		}
+	}
+}
+
+// ExtractFacts mines userID's conversations since they were last mined for
+// durable facts: preferences, names, commitments and dates. New facts are
+// stored pending the user's review, with the message they came from, and
+// the user is notified; agents only see the facts the user approves.
+func (s *MultiAgentService) ExtractFacts(ctx context.Context, userID string) ([]memory.Fact, error) {
+	if s.llmProvider == nil {
+		return nil, fmt.Errorf("no LLM provider to extract facts with")
+	}
+	ctx = multiagent.WithUserID(ctx, userID)
+
+	keys, err := s.userMemory.List(ctx, transcriptPrefix, 1000)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transcripts: %w", err)
+	}
+	known, err := memory.LoadFacts(ctx, s.userMemory, "")
+	if err != nil {
+		return nil, err
+	}
+
+	added := []memory.Fact{}
+	for _, key := range keys {
+		facts, err := s.extractConversationFacts(ctx, strings.TrimPrefix(key, transcriptPrefix), known)
+		added = append(added, facts...)
+		known = append(known, facts...)
+		if err != nil {
+			return added, err
+		}
+	}
+	if len(added) == 0 {
+		return added, nil
+	}
+
+	logger.InfoContext(ctx, "Extracted facts for review", logging.KeyUserID, userID, "facts", len(added))
+	var body strings.Builder
+	for _, fact := range added {
+		fmt.Fprintf(&body, "- %s (%s)\n", fact.Statement, fact.Kind)
+	}
+	body.WriteString("\nApprove the ones I should remember; until then I won't use them.")
+	err = s.notifier.Notify(ctx, notify.Notification{
+		UserID:   userID,
+		Kind:     notify.KindFactReview,
+		Title:    fmt.Sprintf("%d new things to remember about you", len(added)),
+		Body:     body.String(),
+		Priority: multiagent.PriorityLow,
+	})
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to notify user of facts to review", logging.KeyUserID, userID, "error", err)
+	}
+	return added, nil
+}
+
+// extractConversationFacts mines the transcript entries of a conversation
+// after its cursor, a batch at a time, advancing the cursor past each batch
+func (s *MultiAgentService) extractConversationFacts(ctx context.Context, conversationID string, known []memory.Fact) ([]memory.Fact, error) {
+	value, err := s.userMemory.Get(ctx, transcriptPrefix+conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transcript: %w", err)
+	}
+	var entries []TranscriptEntry
+	if err := decodeJSON(value, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode transcript: %w", err)
+	}
+
+	var cursor factCursor
+	if value, err := s.userMemory.Get(ctx, factCursorPrefix+conversationID); err == nil {
+		decodeJSON(value, &cursor)
+	}
+	var unmined []TranscriptEntry
+	for _, entry := range entries {
+		if entry.Timestamp.After(cursor.MinedThrough) {
+			unmined = append(unmined, entry)
+		}
+	}
+
+	var added []memory.Fact
+	for start := 0; start < len(unmined); start += factBatchSize {
+		batch := unmined[start:min(start+factBatchSize, len(unmined))]
+		facts, err := s.extractBatchFacts(ctx, conversationID, batch, append(known, added...))
+		if err != nil {
+			return added, err
+		}
+		for _, fact := range facts {
+			if err := memory.SaveFact(ctx, s.userMemory, fact); err != nil {
+				return added, err
+			}
+			added = append(added, fact)
+		}
+		cursor.MinedThrough = batch[len(batch)-1].Timestamp
+		if err := s.userMemory.Store(ctx, factCursorPrefix+conversationID, cursor); err != nil {
+			return added, fmt.Errorf("failed to save fact extraction cursor: %w", err)
+		}
+	}
+	return added, nil
+}
+
+// extractBatchFacts asks the LLM for the facts in batch that are not
+// already known, pending or rejected alike
+func (s *MultiAgentService) extractBatchFacts(ctx context.Context, conversationID string, batch []TranscriptEntry, known []memory.Fact) ([]memory.Fact, error) {
+	type indexedEntry struct {
+		Index         int
+		Role, Content string
+	}
+	messages := make([]indexedEntry, len(batch))
+	for i, entry := range batch {
+		messages[i] = indexedEntry{Index: i, Role: entry.Role, Content: entry.Content}
+	}
+	seen := make(map[string]bool, len(known))
+	statements := make([]string, 0, len(known))
+	for _, fact := range known {
+		seen[normalizeFact(fact.Statement)] = true
+		statements = append(statements, fact.Statement)
+	}
+
+	prompt, err := s.prompts.Render(ctx, "facts.extract", prompts.Vars{"Messages": messages, "Known": statements})
+	if err != nil {
+		return nil, err
+	}
+	structured := llmprovider.NewStructuredOutput(llmprovider.StructuredOutputConfig{Provider: s.llmProvider, Name: "FactExtractor"})
+	var result extractedFacts
+	if err := structured.Query(ctx, prompt, extractedFactsSchema, &result); err != nil {
+		return nil, fmt.Errorf("failed to extract facts: %w", err)
+	}
+
+	var facts []memory.Fact
+	for _, extracted := range result.Facts {
+		kind := memory.FactKind(strings.ToLower(strings.TrimSpace(extracted.Kind)))
+		statement := strings.TrimSpace(extracted.Statement)
+		// Facts must come from something the user said, so they can be traced
+		if !memory.ValidFactKind(kind) || statement == "" || seen[normalizeFact(statement)] ||
+			extracted.Message < 0 || extracted.Message >= len(batch) || batch[extracted.Message].Role != "user" {
+			continue
+		}
+		seen[normalizeFact(statement)] = true
+		source := batch[extracted.Message]
+		facts = append(facts, memory.Fact{
+			ID:        s.newID("fact"),
+			Kind:      kind,
+			Statement: statement,
+			Status:    memory.FactPending,
+			Source: memory.FactSource{
+				ConversationID: conversationID,
+				Quote:          source.Content,
+				SaidAt:         source.Timestamp,
+			},
+			ExtractedAt: s.now(),
+		})
+	}
+	return facts, nil
+}
+
+// normalizeFact reduces a statement to compare it with known ones
+func normalizeFact(statement string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.TrimRight(statement, ". "))), " ")
+}
+
+// ListFacts returns the facts learned about userID with status, or all of
+// them if status is empty, oldest first
+func (s *MultiAgentService) ListFacts(ctx context.Context, userID string, status memory.FactStatus) ([]memory.Fact, error) {
+	return memory.LoadFacts(multiagent.WithUserID(ctx, userID), s.userMemory, status)
+}
+
+// ReviewFact approves a fact learned about userID, so agents use it, or
+// rejects it
+func (s *MultiAgentService) ReviewFact(ctx context.Context, userID, factID string, approve bool) (*memory.Fact, error) {
+	status := memory.FactRejected
+	if approve {
+		status = memory.FactApproved
+	}
+	fact, err := memory.ReviewFact(multiagent.WithUserID(ctx, userID), s.userMemory, factID, status, s.now())
+	if err != nil {
+		return nil, err
+	}
+	logger.InfoContext(ctx, "Reviewed fact", logging.KeyUserID, userID, "fact", factID, "status", status)
+	return &fact, nil
+}
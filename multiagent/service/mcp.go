@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/mcp"
+	"github.com/kbutz/wikillm/multiagent/tools"
+)
+
+// mcpUserID is the user MCP clients talk to the assistant as
+const mcpUserID = "mcp"
+
+// specialistTools are the agents MCP clients can ask directly
+var specialistTools = []struct {
+	name        string
+	agentID     multiagent.AgentID
+	description string
+}{
+	{"todo", "task_manager_agent", `Manage the user's personal to-dos in plain language, e.g. "add call the dentist due Friday", "what's overdue?", "complete task_123"`},
+	{"calendar", "scheduler_agent", `Read and change the user's calendar in plain language, e.g. "what's on my calendar tomorrow?", "schedule a 30 minute call with Sam on Monday at 10am"`},
+	{"research", "research_assistant_agent", `Start or review research in plain language, e.g. "research heat pumps for cold climates", "summarize my research on solar panels"`},
+}
+
+// mcpClientID is the agent MCP clients use memory as
+const mcpClientID multiagent.AgentID = "mcp_client"
+
+// MCPServer exposes the assistant's memory, tasks, to-dos, calendar, and
+// research to MCP clients such as desktop assistants and IDEs; token, if
+// set, is required of HTTP clients
+func (s *MultiAgentService) MCPServer(token string) *mcp.Server {
+	// MCP clients reach memory as an agent of their own, so they are held
+	// to the same namespaces as the built-in agents
+	var exposed []multiagent.Tool
+	for _, tool := range s.memoryTools(mcpClientID) {
+		if tool.Name() == "memory" || tool.Name() == "task" {
+			exposed = append(exposed, tool)
+		}
+	}
+	for _, specialist := range specialistTools {
+		if agent, ok := s.agents[specialist.agentID]; ok {
+			exposed = append(exposed, tools.NewAgentTool(specialist.name, specialist.description, agent))
+		}
+	}
+	exposed = append(exposed, &assistantTool{service: s})
+
+	return mcp.NewServer(mcp.ServerConfig{
+		Name:    "wikillm",
+		Version: "1.0.0",
+		Instructions: "wikillm is the user's personal assistant. Use todo, calendar, and research for those areas, " +
+			"memory to recall or store facts about the user, and assistant for anything else.",
+		Tools: exposed,
+		Token: token,
+		ToolContext: func(ctx context.Context) context.Context {
+			return multiagent.WithUserID(ctx, mcpUserID)
+		},
+	})
+}
+
+// assistantTool sends a message through the full multi-agent pipeline
+type assistantTool struct {
+	service *MultiAgentService
+}
+
+func (t *assistantTool) Name() string {
+	return "assistant"
+}
+
+func (t *assistantTool) Description() string {
+	return "Ask the personal assistant anything; the coordinator routes it to the right specialists and replies"
+}
+
+func (t *assistantTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"message": map[string]interface{}{
+				"type":        "string",
+				"description": "The message for the assistant",
+			},
+		},
+		"required": []string{"message"},
+	}
+}
+
+func (t *assistantTool) Execute(ctx context.Context, args string) (string, error) {
+	var params struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return "", fmt.Errorf("failed to parse JSON arguments: %w", err)
+	}
+	if strings.TrimSpace(params.Message) == "" {
+		return "", fmt.Errorf("message parameter is required")
+	}
+	return t.service.ProcessUserMessage(ctx, mcpUserID, params.Message)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+)
+
+// messageLogSize is how many routed messages RecentMessages can return
+const messageLogSize = 1000
+
+// messageContentLength is how much of a message's content is kept
+const messageContentLength = 500
+
+// MessageRecord is a message routed between agents, for following how they
+// coordinate
+type MessageRecord struct {
+	// Seq numbers messages in the order they were routed
+	Seq            int64                  `json:"seq"`
+	ID             string                 `json:"id"`
+	From           multiagent.AgentID     `json:"from"`
+	To             []multiagent.AgentID   `json:"to"`
+	Type           multiagent.MessageType `json:"type"`
+	ConversationID string                 `json:"conversation_id,omitempty"`
+	UserID         string                 `json:"user_id,omitempty"`
+	Hops           interface{}            `json:"hops,omitempty"`
+	// Content is cut to its first 500 bytes
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// messageLog keeps the most recently routed messages
+type messageLog struct {
+	mu      sync.Mutex
+	records []MessageRecord
+	seq     int64
+}
+
+func newMessageLog() *messageLog {
+	return &messageLog{records: make([]MessageRecord, 0, messageLogSize)}
+}
+
+// middleware records every message passed on to be queued
+func (l *messageLog) middleware(next orchestrator.RouteHandler) orchestrator.RouteHandler {
+	return func(ctx context.Context, msg *multiagent.Message) error {
+		if err := next(ctx, msg); err != nil {
+			return err
+		}
+		l.add(msg)
+		return nil
+	}
+}
+
+func (l *messageLog) add(msg *multiagent.Message) {
+	content := msg.Content
+	if len(content) > messageContentLength {
+		content = content[:messageContentLength]
+	}
+	conversationID, _ := msg.Context["conversation_id"].(string)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	record := MessageRecord{
+		Seq:            l.seq,
+		ID:             msg.ID,
+		From:           msg.From,
+		To:             append([]multiagent.AgentID(nil), msg.To...),
+		Type:           msg.Type,
+		ConversationID: conversationID,
+		UserID:         multiagent.UserIDFromMessage(msg),
+		Hops:           msg.Context[multiagent.ContextHops],
+		Content:        content,
+		Timestamp:      msg.Timestamp,
+	}
+	if len(l.records) == messageLogSize {
+		copy(l.records, l.records[1:])
+		l.records = l.records[:messageLogSize-1]
+	}
+	l.records = append(l.records, record)
+}
+
+// after returns up to limit records routed after seq, oldest first
+func (l *messageLog) after(seq int64, limit int) []MessageRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := make([]MessageRecord, 0)
+	for _, record := range l.records {
+		if record.Seq > seq {
+			records = append(records, record)
+		}
+	}
+	if limit > 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+	return records
+}
+
+// RecentMessages returns up to limit of the last 1000 messages routed,
+// those after seq, oldest first
+func (s *MultiAgentService) RecentMessages(after int64, limit int) []MessageRecord {
+	return s.messages.after(after, limit)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/llmprovider"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+	"github.com/kbutz/wikillm/multiagent/plugins"
+)
+
+// AgentConfig returns the configuration the built-in agents are created
+// with, for a plugin agent with id and agentType
+func (s *MultiAgentService) AgentConfig(id multiagent.AgentID, agentType multiagent.AgentType) agents.BaseAgentConfig {
+	return agents.BaseAgentConfig{
+		ID:                 id,
+		Type:               agentType,
+		Tools:              s.agentTools(id),
+		LLMProvider:        s.agentLLM(string(agentType)),
+		RoutingLLMProvider: s.agentLLM(llmprovider.RoleRouting),
+		MemoryStore:        s.agentMemory(id),
+		Orchestrator:       s.orchestrator,
+		Audit:              s.auditLog,
+		Reminders:          s.reminderEngine,
+		Confirmations:      s.confirmations,
+		Access:             s.access,
+		Usage:              s.usage,
+		Prompts:            s.prompts,
+		Clock:              s.clock,
+		IDs:                s.ids,
+	}
+}
+
+// initializePlugins adds the agents and message middleware of every plugin,
+// after the built-in agents
+func (s *MultiAgentService) initializePlugins(ctx context.Context, list []plugins.Plugin) error {
+	orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator)
+	if !ok && len(list) > 0 {
+		return fmt.Errorf("orchestrator does not support plugins")
+	}
+
+	for _, plugin := range list {
+		if plugin.OnMessage != nil {
+			orch.Use(plugin.OnMessage)
+		}
+		if plugin.Agents == nil {
+			continue
+		}
+
+		built, err := plugin.Agents(s)
+		if err != nil {
+			return fmt.Errorf("failed to create agents of plugin %s: %w", plugin.Name, err)
+		}
+		for _, agent := range built {
+			if _, exists := s.agents[agent.ID()]; exists {
+				return fmt.Errorf("plugin %s: agent with ID %s already exists", plugin.Name, agent.ID())
+			}
+			if err := s.orchestrator.RegisterAgent(agent); err != nil {
+				return fmt.Errorf("failed to register agent %s of plugin %s: %w", agent.ID(), plugin.Name, err)
+			}
+			s.agents[agent.ID()] = agent
+			s.pluginAgents[agent.ID()] = plugin
+
+			if descriptors, ok := plugin.Capabilities[agent.ID()]; ok {
+				if err := orch.PublishCapabilities(agent.ID(), descriptors); err != nil {
+					return fmt.Errorf("failed to publish capabilities of agent %s: %w", agent.ID(), err)
+				}
+			}
+			if plugin.OnRegister != nil {
+				if err := plugin.OnRegister(ctx, agent); err != nil {
+					return fmt.Errorf("plugin %s failed to register agent %s: %w", plugin.Name, agent.ID(), err)
+				}
+			}
+		}
+		logger.Info("Loaded plugin", "plugin", plugin.Name, "agents", len(built))
+	}
+	return nil
+}
+
+// startedPluginAgent runs the OnStart hook of a started plugin agent
+func (s *MultiAgentService) startedPluginAgent(ctx context.Context, agent multiagent.Agent) {
+	plugin, ok := s.pluginAgents[agent.ID()]
+	if !ok || plugin.OnStart == nil {
+		return
+	}
+	if err := plugin.OnStart(ctx, agent); err != nil {
+		logger.WarnContext(ctx, "Plugin failed to start agent", "plugin", plugin.Name, logging.KeyAgentID, agent.ID(), "error", err)
+	}
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kbutz/wikillm/multiagent/agents"
+)
+
+// ExportProjectTimeline returns the gantt chart of the project ref names,
+// by ID or name, of the user ctx acts for in format, one of
+// agents.TimelineFormatMermaid and agents.TimelineFormatCSV
+func (s *MultiAgentService) ExportProjectTimeline(ctx context.Context, ref, format string) ([]byte, error) {
+	for _, agent := range s.agents {
+		if exporter, ok := agent.(agents.ProjectExporter); ok {
+			return exporter.ExportProjectTimeline(ctx, ref, format)
+		}
+	}
+	return nil, fmt.Errorf("no agent manages projects")
+}
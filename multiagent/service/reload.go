@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/llmprovider"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/webhooks"
+)
+
+// ReloadConfig is configuration a running service switches to without a
+// restart; nil fields keep the current settings
+type ReloadConfig struct {
+	// LLMPool reassigns LLM backends and models to agent roles
+	LLMPool *llmprovider.PoolConfig
+	// Prompts re-reads the prompt directory
+	Prompts bool
+	// Notifications replaces the channels reminders and briefings go out on
+	Notifications *notify.DispatcherConfig
+	// Webhooks replaces the endpoints events are POSTed to
+	Webhooks *[]webhooks.Endpoint
+	// WorkingHours replaces the working hours of users without their own
+	WorkingHours *agents.WorkingHours
+}
+
+// ReloadResult reports what a reload switched to
+type ReloadResult struct {
+	// Reloaded names the parts reloaded: llm, prompts, notifications,
+	// webhooks, working_hours
+	Reloaded []string `json:"reloaded"`
+	// Errors describes the parts that failed and kept their settings
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Reload applies config to the running service. Parts are reloaded
+// independently: one that fails keeps its current settings without holding
+// back the others. Conversations in flight finish with the settings they
+// started with, and later messages use the new ones.
+func (s *MultiAgentService) Reload(config ReloadConfig) (*ReloadResult, error) {
+	result := &ReloadResult{Reloaded: []string{}}
+	var errs []error
+	apply := func(part string, err error) {
+		if err != nil {
+			errs = append(errs, err)
+			result.Errors = append(result.Errors, err.Error())
+			logger.Warn("Keeping current configuration", "part", part, "error", err)
+			return
+		}
+		result.Reloaded = append(result.Reloaded, part)
+		logger.Info("Reloaded configuration", "part", part)
+	}
+
+	if config.LLMPool != nil {
+		apply("llm", s.ReloadLLMPool(*config.LLMPool))
+	}
+	if config.Prompts {
+		apply("prompts", s.ReloadPrompts())
+	}
+	if config.Notifications != nil {
+		apply("notifications", s.ReloadNotifications(*config.Notifications))
+	}
+	if config.Webhooks != nil {
+		s.webhooks.Reconfigure(*config.Webhooks)
+		apply("webhooks", nil)
+	}
+	if config.WorkingHours != nil {
+		apply("working_hours", s.SetWorkingHours(*config.WorkingHours))
+	}
+	return result, errors.Join(errs...)
+}
+
+// ReloadNotifications replaces the default and per-user notification
+// channels, defaulting to the console as at startup; notifications already
+// being sent finish on the old ones
+func (s *MultiAgentService) ReloadNotifications(config notify.DispatcherConfig) error {
+	if len(config.Default) == 0 {
+		config.Default = []notify.Channel{notify.NewConsoleChannel(nil)}
+	}
+	s.notifier.Reconfigure(config)
+	return nil
+}
+
+// SetWorkingHours replaces the working hours the scheduler assumes for
+// users who have not set their own
+func (s *MultiAgentService) SetWorkingHours(hours agents.WorkingHours) error {
+	scheduler, ok := s.agents["scheduler_agent"].(*agents.SchedulerAgent)
+	if !ok {
+		return fmt.Errorf("failed to set working hours: scheduler agent not found")
+	}
+	scheduler.SetDefaultWorkingHours(hours)
+	return nil
+}
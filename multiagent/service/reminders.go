@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+// startReminders loads the reminders persisted for work without a user and
+// for every registered user, then starts firing them
+func (s *MultiAgentService) startReminders(ctx context.Context) {
+	userIDs := []string{""}
+	users, err := s.ListUsers(ctx)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to list users for reminders", "error", err)
+	}
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+
+	total := 0
+	for _, userID := range userIDs {
+		loaded, err := s.reminderEngine.Load(multiagent.WithUserID(ctx, userID))
+		if err != nil {
+			logger.WarnContext(ctx, "Failed to load reminders", logging.KeyUserID, userID, "error", err)
+			continue
+		}
+		total += loaded
+	}
+	s.reminderEngine.Start()
+	logger.InfoContext(ctx, "Started reminders", "loaded", total)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kbutz/wikillm/multiagent/agents"
+)
+
+// ListResearch returns the research sessions of the user ctx acts for that
+// match filter, newest first
+func (s *MultiAgentService) ListResearch(ctx context.Context, filter agents.ResearchFilter) ([]*agents.ResearchSession, error) {
+	library, err := s.researchLibrary()
+	if err != nil {
+		return nil, err
+	}
+	return library.ListResearch(ctx, filter)
+}
+
+// GetResearch returns one of the research sessions of the user ctx acts for
+func (s *MultiAgentService) GetResearch(ctx context.Context, id string) (*agents.ResearchSession, error) {
+	library, err := s.researchLibrary()
+	if err != nil {
+		return nil, err
+	}
+	return library.GetResearch(ctx, id)
+}
+
+// SearchResearchFindings returns the findings of the research of the user
+// ctx acts for that match filter
+func (s *MultiAgentService) SearchResearchFindings(ctx context.Context, filter agents.ResearchFilter) ([]agents.FindingMatch, error) {
+	library, err := s.researchLibrary()
+	if err != nil {
+		return nil, err
+	}
+	return library.SearchFindings(ctx, filter)
+}
+
+// AddResearchSource attaches a source to one of the user's research sessions
+func (s *MultiAgentService) AddResearchSource(ctx context.Context, id string, source agents.ResearchSource) (*agents.ResearchSession, error) {
+	library, err := s.researchLibrary()
+	if err != nil {
+		return nil, err
+	}
+	return library.AddResearchSource(ctx, id, source)
+}
+
+// TagResearch adds tags to one of the user's research sessions, or to one
+// of its findings if findingID is set
+func (s *MultiAgentService) TagResearch(ctx context.Context, id, findingID string, tags []string) (*agents.ResearchSession, error) {
+	library, err := s.researchLibrary()
+	if err != nil {
+		return nil, err
+	}
+	return library.TagResearch(ctx, id, findingID, tags)
+}
+
+// ExportBibliography renders the sources of one of the user's research
+// sessions as BibTeX or Markdown
+func (s *MultiAgentService) ExportBibliography(ctx context.Context, id, format string) ([]byte, error) {
+	library, err := s.researchLibrary()
+	if err != nil {
+		return nil, err
+	}
+	return library.ExportBibliography(ctx, id, format)
+}
+
+// researchLibrary returns the agent that keeps users' research
+func (s *MultiAgentService) researchLibrary() (agents.ResearchLibrary, error) {
+	for _, agent := range s.agents {
+		if library, ok := agent.(agents.ResearchLibrary); ok {
+			return library, nil
+		}
+	}
+	return nil, fmt.Errorf("no agent keeps research")
+}
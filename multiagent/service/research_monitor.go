@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/notify"
+)
+
+// defaultMonitorCheckInterval is how often monitors are checked for a due
+// re-run
+const defaultMonitorCheckInterval = 5 * time.Minute
+
+// ResearchMonitorConfig configures re-running the research topics users
+// monitor
+type ResearchMonitorConfig struct {
+	// Disabled turns the background checks off; CheckResearchMonitors
+	// still works
+	Disabled bool
+	// CheckInterval is how often every user's monitors are checked for a
+	// due re-run (default 5 minutes); each monitor has its own interval
+	CheckInterval time.Duration
+}
+
+// withDefaults fills in the defaults of unset fields
+func (c ResearchMonitorConfig) withDefaults() ResearchMonitorConfig {
+	if c.CheckInterval <= 0 {
+		c.CheckInterval = defaultMonitorCheckInterval
+	}
+	return c
+}
+
+// researchMonitorRunner re-runs every user's due research monitors in the
+// background
+type researchMonitorRunner struct {
+	service  *MultiAgentService
+	interval time.Duration
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+// Start checks for due monitors every interval until Stop
+func (r *researchMonitorRunner) Start(ctx context.Context) {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return
+	}
+	r.running = true
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.checkAll(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	logger.InfoContext(ctx, "Scheduled research monitoring", "interval", r.interval)
+}
+
+// Stop stops the runner, abandoning research under way
+func (r *researchMonitorRunner) Stop() {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return
+	}
+	r.running = false
+	r.cancel()
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+// checkAll re-runs the due monitors of every user, one user at a time so
+// the LLM is left to the users talking to it
+func (r *researchMonitorRunner) checkAll(ctx context.Context) {
+	users, err := r.service.ListUsers(ctx)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to list users for research monitoring", "error", err)
+		return
+	}
+	for _, user := range users {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := r.service.CheckResearchMonitors(ctx, user.ID); err != nil {
+			logger.WarnContext(ctx, "Failed to check research monitors", logging.KeyUserID, user.ID, "error", err)
+		}
+	}
+}
+
+// MonitorResearch re-runs one of the user's research sessions every
+// interval, notifying them when it finds something materially new
+func (s *MultiAgentService) MonitorResearch(ctx context.Context, id string, interval time.Duration) (*agents.ResearchMonitor, error) {
+	monitoring, err := s.researchMonitoring()
+	if err != nil {
+		return nil, err
+	}
+	return monitoring.MonitorResearch(ctx, id, interval)
+}
+
+// ListResearchMonitors returns the research monitors of the user ctx acts
+// for, next due first
+func (s *MultiAgentService) ListResearchMonitors(ctx context.Context) ([]*agents.ResearchMonitor, error) {
+	monitoring, err := s.researchMonitoring()
+	if err != nil {
+		return nil, err
+	}
+	return monitoring.ListMonitors(ctx)
+}
+
+// StopResearchMonitor stops one of the user's research monitors
+func (s *MultiAgentService) StopResearchMonitor(ctx context.Context, id string) error {
+	monitoring, err := s.researchMonitoring()
+	if err != nil {
+		return err
+	}
+	return monitoring.StopMonitor(ctx, id)
+}
+
+// CheckResearchMonitors re-runs userID's monitors that are due and
+// notifies them of the materially new information found
+func (s *MultiAgentService) CheckResearchMonitors(ctx context.Context, userID string) ([]agents.MonitorAlert, error) {
+	monitoring, err := s.researchMonitoring()
+	if err != nil {
+		return nil, err
+	}
+	ctx = multiagent.WithUserID(ctx, userID)
+	alerts, err := monitoring.RunDueMonitors(ctx)
+	for _, alert := range alerts {
+		var body strings.Builder
+		if alert.Update.Summary != "" {
+			body.WriteString(alert.Update.Summary + "\n\n")
+		}
+		for _, finding := range alert.Update.Findings {
+			fmt.Fprintf(&body, "- %s\n", finding)
+		}
+		notifyErr := s.notifier.Notify(ctx, notify.Notification{
+			UserID:   userID,
+			Kind:     notify.KindResearchUpdate,
+			Title:    "New on " + alert.Topic,
+			Body:     strings.TrimSuffix(body.String(), "\n"),
+			Priority: multiagent.PriorityMedium,
+			Subject:  alert.Update.SessionID,
+		})
+		if notifyErr != nil {
+			logger.WarnContext(ctx, "Failed to notify user of research update", logging.KeyUserID, userID, "monitor_id", alert.MonitorID, "error", notifyErr)
+		}
+	}
+	return alerts, err
+}
+
+// researchMonitoring returns the agent that monitors users' research
+func (s *MultiAgentService) researchMonitoring() (agents.ResearchMonitoring, error) {
+	for _, agent := range s.agents {
+		if monitoring, ok := agent.(agents.ResearchMonitoring); ok {
+			return monitoring, nil
+		}
+	}
+	return nil, fmt.Errorf("no agent monitors research")
+}
@@ -2,37 +2,205 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
-	"sync"
+	"sort"
 	"time"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/access"
 	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/caldav"
+	"github.com/kbutz/wikillm/multiagent/email"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/llmprovider"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/mcp"
 	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/metrics"
+	"github.com/kbutz/wikillm/multiagent/notes"
+	"github.com/kbutz/wikillm/multiagent/notify"
 	"github.com/kbutz/wikillm/multiagent/orchestrator"
+	"github.com/kbutz/wikillm/multiagent/plugins"
+	"github.com/kbutz/wikillm/multiagent/progress"
+	"github.com/kbutz/wikillm/multiagent/prompts"
+	"github.com/kbutz/wikillm/multiagent/reminders"
+	"github.com/kbutz/wikillm/multiagent/rpc"
+	"github.com/kbutz/wikillm/multiagent/rpc/multiagentpb"
 	"github.com/kbutz/wikillm/multiagent/tools"
+	"github.com/kbutz/wikillm/multiagent/usage"
+	"github.com/kbutz/wikillm/multiagent/webhooks"
+	"google.golang.org/grpc"
 )
 
+var logger = logging.For("service")
+
+// userRequestTimeout is how long ProcessUserMessage waits for the reply
+const userRequestTimeout = 10 * time.Minute
+
+// promptWatchInterval is how often the prompt directory is checked for edits
+const promptWatchInterval = 5 * time.Second
+
 // MultiAgentService provides a complete multi-agent system with memory, tools, and orchestration
 type MultiAgentService struct {
-	memoryStore     multiagent.MemoryStore
-	orchestrator    multiagent.Orchestrator
-	agents          map[multiagent.AgentID]multiagent.Agent
-	tools           map[string]multiagent.Tool
-	llmProvider     multiagent.LLMProvider
-	baseDir         string
-	pendingRequests map[string]chan string // Track pending user requests
-	requestsMutex   sync.RWMutex
+	memoryStore    multiagent.MemoryStore
+	vectorMemory   *memory.QdrantMemoryStore
+	userMemory     multiagent.MemoryStore
+	janitor        *memory.Janitor
+	orchestrator   multiagent.Orchestrator
+	agents         map[multiagent.AgentID]multiagent.Agent
+	tools          map[string]multiagent.Tool
+	llmProvider    multiagent.LLMProvider
+	llmPool        *llmprovider.Pool
+	llmGovernor    *llmprovider.Governor
+	baseDir        string
+	metrics        *metrics.Registry
+	auditLog       *audit.Log
+	progress       *progress.Hub
+	metricsAddr    string
+	metricsServer  *http.Server
+	grpcAddr       string
+	grpcServer     *grpc.Server
+	grpcToken      string
+	mcpServers     []mcp.ClientConfig
+	mcpClients     []*mcp.Client
+	caldavAccounts []caldav.Account
+	caldavSyncers  []*caldav.Syncer
+	mailer         email.Sender
+	emailAccounts  []email.Account
+	emailPollers   []*email.Poller
+	notifier       *notify.Dispatcher
+	reminderEngine *reminders.Engine
+	webhooks       *webhooks.Dispatcher
+	briefingConfig BriefingConfig
+	briefings      *briefingScheduler
+	facts          *factExtractor
+	monitors       *researchMonitorRunner
+	notesIndexer   *notes.Indexer
+	extraTools     []multiagent.Tool
+	confirmations  agents.ConfirmationPolicy
+	access         *access.Policy
+	usage          *usage.Tracker
+	llmCache       *llmprovider.ResponseCache
+	prompts        *prompts.Registry
+	clock          multiagent.Clock
+	ids            multiagent.IDGenerator
+	pluginAgents   map[multiagent.AgentID]plugins.Plugin
+	messages       *messageLog
 }
 
 // ServiceConfig holds configuration for creating a MultiAgentService
 type ServiceConfig struct {
 	BaseDir     string
 	LLMProvider multiagent.LLMProvider
+	// LLMPool, if set, gives each agent the model configured for its type
+	// and classifies requests with the routing model; LLMProvider, if also
+	// set, stays the provider for anything outside the agents
+	LLMPool *llmprovider.Pool
+	// LLMGovernor, if set, bounds LLMProvider's calls in flight; pool
+	// backends have governors of their own
+	LLMGovernor *llmprovider.Governor
+	// MemoryStore overrides the default file-based store (e.g. a SQLiteMemoryStore)
+	MemoryStore multiagent.MemoryStore
+	// VectorMemory, if set, indexes memory in Qdrant with its Embedder so
+	// agents recall memories by meaning; its Base is MemoryStore
+	VectorMemory *memory.QdrantMemoryStoreConfig
+	// MemoryQuotas caps entries per key prefix (defaults to memory.DefaultQuotas)
+	MemoryQuotas []memory.PrefixQuota
+	// MemoryCompactions thins out time-series prefixes (defaults to memory.DefaultCompactionRules)
+	MemoryCompactions []memory.CompactionRule
+	// JanitorInterval is how often the memory janitor sweeps (default 1 minute)
+	JanitorInterval time.Duration
+	// QueueOverloadPolicy controls low-priority messages when the orchestrator
+	// queue passes its high watermark (defaults to orchestrator.OverloadShed)
+	QueueOverloadPolicy orchestrator.OverloadPolicy
+	// AgentConcurrency is how many queued messages each agent handles at
+	// once (default 4)
+	AgentConcurrency int
+	// MetricsAddr, if set, serves Prometheus metrics on /metrics at this
+	// address (e.g. ":9090"); MetricsHandler works either way
+	MetricsAddr string
+	// GRPCAddr, if set, serves the orchestrator over gRPC at this address
+	// (e.g. ":9000") so out-of-process agents can route messages and tasks
+	GRPCAddr string
+	// GRPCToken, if set, is the bearer token gRPC clients must present
+	GRPCToken string
+	// RemoteAgents are out-of-process agents reached through rpc.AgentProxy
+	RemoteAgents []rpc.AgentProxyConfig
+	// MCPServers are Model Context Protocol servers whose tools are
+	// discovered at startup and given to every agent
+	MCPServers []mcp.ClientConfig
+	// CalDAVAccounts are users' CalDAV calendars kept in two-way sync with
+	// the scheduler's calendar
+	CalDAVAccounts []caldav.Account
+	// EmailAccounts are the accounts the communication manager sends users'
+	// composed email through, after they confirm, and whose IMAP mailboxes
+	// are polled for replies
+	EmailAccounts []email.Account
+	// Notifications configures how reminders reach users; without channels
+	// they are printed to the console
+	Notifications notify.DispatcherConfig
+	// Webhooks are URLs that reminders firing, tasks completed and events
+	// scheduled are POSTed to
+	Webhooks []webhooks.Endpoint
+	// Briefings configures the daily agenda briefing sent to every user
+	Briefings BriefingConfig
+	// FactExtraction configures mining conversations for facts about their
+	// users, which agents use once the user approves them
+	FactExtraction FactExtractionConfig
+	// ResearchMonitoring configures re-running the research topics users
+	// ask to monitor, to tell them what is new
+	ResearchMonitoring ResearchMonitorConfig
+	// Notes are users' directories of Markdown, text and PDF documents,
+	// indexed into their memory and rescanned for changes, that agents
+	// search with the notes tool
+	Notes []notes.Source
+	// NotesInterval is how often note directories are rescanned (default 1
+	// minute)
+	NotesInterval time.Duration
+	// Tools are more tools given to every agent, such as the web and
+	// Wikipedia search the research assistant gathers sources with (see the
+	// search package); one named like a built-in tool replaces it
+	Tools []multiagent.Tool
+	// Confirmations names the side-effecting actions agents ask the user to
+	// approve first; nil holds every one, an empty policy none
+	Confirmations agents.ConfirmationPolicy
+	// Access limits the tools and actions each agent, and each user role,
+	// may use; nil applies access.DefaultPolicy
+	Access *access.Policy
+	// TokenBudget caps the LLM tokens a conversation spends a day; past it
+	// the coordinator stops planning and, with an LLM pool, agents answer
+	// with the routing model. Zero is unlimited.
+	TokenBudget int
+	// LLMCache opts prompt classes (llmprovider.PromptClassIntent, ...) in
+	// to a shared cache of LLM responses; without classes nothing is cached
+	LLMCache llmprovider.CacheConfig
+	// PromptDir holds prompt templates that add versions of the built-in
+	// ones or replace them; it is watched and reloaded while running
+	PromptDir string
+	// PromptOverrides pins prompts to versions or splits conversations
+	// between them, e.g. {"intent.classify": "v1|v2"}
+	PromptOverrides map[string]string
+	// Clock stamps messages, tasks and records (default the system clock)
+	Clock multiagent.Clock
+	// IDs names messages, tasks and records (default ULIDs); tests set a
+	// sequence for predictable IDs
+	IDs multiagent.IDGenerator
+	// Plugins add agents and hooks besides the plugins registered with
+	// plugins.Register
+	Plugins []plugins.Plugin
+	// WorkingHours, if set, are the working hours the scheduler assumes for
+	// users who have not set their own (default weekdays 9:00-18:00)
+	WorkingHours *agents.WorkingHours
+	// RouteMiddleware logs, redacts or blocks messages as they are routed,
+	// e.g. as read by policy.LoadConfig
+	RouteMiddleware []orchestrator.RouteMiddleware
 }
 
 // NewMultiAgentService creates a new multi-agent service
@@ -43,27 +211,188 @@ func NewMultiAgentService(config ServiceConfig) (*MultiAgentService, error) {
 	}
 
 	// Initialize memory store
-	memoryDir := filepath.Join(config.BaseDir, "memory")
-	memoryStore, err := memory.NewFileMemoryStore(memoryDir)
+	memoryStore := config.MemoryStore
+	if memoryStore == nil {
+		memoryDir := filepath.Join(config.BaseDir, "memory")
+		fileStore, err := memory.NewFileMemoryStore(memoryDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize memory store: %w", err)
+		}
+		memoryStore = fileStore
+	}
+	var vectorMemory *memory.QdrantMemoryStore
+	if config.VectorMemory != nil {
+		vectorConfig := *config.VectorMemory
+		vectorConfig.Base = memoryStore
+		var err error
+		vectorMemory, err = memory.NewQdrantMemoryStore(vectorConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize vector memory: %w", err)
+		}
+		memoryStore = vectorMemory
+	}
+
+	accessPolicy := config.Access
+	if accessPolicy == nil {
+		accessPolicy = access.DefaultPolicy()
+	}
+
+	// Initialize memory janitor
+	if config.MemoryQuotas == nil {
+		config.MemoryQuotas = memory.DefaultQuotas()
+	}
+	if config.MemoryCompactions == nil {
+		config.MemoryCompactions = memory.DefaultCompactionRules()
+	}
+	janitor := memory.NewJanitor(memory.JanitorConfig{
+		Store:       memoryStore,
+		Interval:    config.JanitorInterval,
+		Quotas:      config.MemoryQuotas,
+		Compactions: config.MemoryCompactions,
+	})
+
+	// Initialize metrics; LLM calls are timed through an instrumented provider
+	registry := metrics.NewRegistry()
+	tokenUsage := usage.NewTracker(usage.TrackerConfig{
+		Store:   memoryStore,
+		Metrics: registry,
+		Budget:  usage.Budget{ConversationTokensPerDay: config.TokenBudget},
+		Clock:   config.Clock,
+	})
+	llm := config.LLMProvider
+	if llm != nil && config.LLMGovernor != nil {
+		llm = config.LLMGovernor.Provider(llm)
+	}
+	if llm == nil && config.LLMPool != nil {
+		llm = config.LLMPool.Provider("")
+	}
+	// Cached responses skip the instrumented provider: no LLM call is made
+	var llmCache *llmprovider.ResponseCache
+	if len(config.LLMCache.Classes) > 0 {
+		config.LLMCache.Metrics = registry
+		llmCache = llmprovider.NewResponseCache(config.LLMCache)
+	}
+	if llm != nil {
+		llm = llmprovider.NewInstrumentedProvider(llm, registry, llmprovider.WithUsageTracker(tokenUsage))
+		if llmCache != nil {
+			llm = llmCache.Provider(llm)
+		}
+	}
+
+	// Agents render their prompts from the registry
+	promptRegistry, err := prompts.NewRegistry(prompts.RegistryConfig{
+		Dir:       config.PromptDir,
+		Overrides: config.PromptOverrides,
+		Metrics:   registry,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize memory store: %w", err)
+		return nil, fmt.Errorf("failed to load prompts: %w", err)
+	}
+
+	// Every agent action is recorded in the append-only audit log, and the
+	// ones webhooks subscribe to are passed on
+	hooks := webhooks.NewDispatcher(webhooks.DispatcherConfig{Endpoints: config.Webhooks, Clock: config.Clock, IDs: config.IDs})
+	auditLog := audit.NewLog(audit.LogConfig{Store: memoryStore, OnRecord: hooks.RecordAudit})
+	progressHub := progress.NewHub()
+
+	// Reminders go out through each user's notification channels
+	if len(config.Notifications.Default) == 0 {
+		config.Notifications.Default = []notify.Channel{notify.NewConsoleChannel(nil)}
 	}
+	notifier := notify.NewDispatcher(config.Notifications)
+	userMemory := memory.PartitionByUser(memoryStore)
 
 	// Initialize orchestrator
 	orch := orchestrator.NewOrchestrator(orchestrator.OrchestratorConfig{
 		MemoryStore:      memoryStore,
 		MessageQueueSize: 1000,
 		EventQueueSize:   500,
+		MemoryStats:      janitor,
+		Metrics:          registry,
+		Audit:            auditLog,
+		Progress:         progressHub,
+		Clock:            config.Clock,
+		IDs:              config.IDs,
+
+		QueueOverloadPolicy: config.QueueOverloadPolicy,
+		AgentConcurrency:    config.AgentConcurrency,
+		RouteMiddleware:     config.RouteMiddleware,
 	})
 
 	service := &MultiAgentService{
-		memoryStore:     memoryStore,
-		orchestrator:    orch,
-		agents:          make(map[multiagent.AgentID]multiagent.Agent),
-		tools:           make(map[string]multiagent.Tool),
-		llmProvider:     config.LLMProvider,
-		baseDir:         config.BaseDir,
-		pendingRequests: make(map[string]chan string),
+		memoryStore:    memoryStore,
+		vectorMemory:   vectorMemory,
+		userMemory:     userMemory,
+		janitor:        janitor,
+		orchestrator:   orch,
+		agents:         make(map[multiagent.AgentID]multiagent.Agent),
+		tools:          make(map[string]multiagent.Tool),
+		llmProvider:    llm,
+		llmPool:        config.LLMPool,
+		llmGovernor:    config.LLMGovernor,
+		baseDir:        config.BaseDir,
+		metrics:        registry,
+		metricsAddr:    config.MetricsAddr,
+		grpcAddr:       config.GRPCAddr,
+		grpcToken:      config.GRPCToken,
+		mcpServers:     config.MCPServers,
+		extraTools:     config.Tools,
+		caldavAccounts: config.CalDAVAccounts,
+		notifier:       notifier,
+		reminderEngine: reminders.NewEngine(reminders.EngineConfig{Store: userMemory, Notifier: hooks.Reminders(notifier)}),
+		webhooks:       hooks,
+		briefingConfig: config.Briefings.withDefaults(),
+		auditLog:       auditLog,
+		progress:       progressHub,
+		confirmations:  config.Confirmations,
+		access:         accessPolicy,
+		usage:          tokenUsage,
+		llmCache:       llmCache,
+		prompts:        promptRegistry,
+		clock:          ids.ClockOrSystem(config.Clock),
+		ids:            ids.OrDefault(config.IDs),
+		pluginAgents:   make(map[multiagent.AgentID]plugins.Plugin),
+		messages:       newMessageLog(),
+	}
+	orch.UseRouting(service.messages.middleware)
+	service.registerLLMLoadMetrics()
+
+	// Composed email is sent through each user's own SMTP account
+	if len(config.EmailAccounts) > 0 {
+		mailer, err := email.NewMailer(email.MailerConfig{Accounts: config.EmailAccounts})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize email: %w", err)
+		}
+		service.mailer = mailer
+		service.emailAccounts = config.EmailAccounts
+	}
+
+	// Send each user a briefing every morning
+	if !service.briefingConfig.Disabled {
+		briefings, err := newBriefingScheduler(service, service.briefingConfig.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		service.briefings = briefings
+	}
+
+	// Learn facts about users from their conversations, for them to review
+	if facts := config.FactExtraction.withDefaults(); !facts.Disabled && llm != nil {
+		service.facts = &factExtractor{service: service, interval: facts.Interval}
+	}
+
+	// Re-run monitored research topics, telling users what is new
+	if monitors := config.ResearchMonitoring.withDefaults(); !monitors.Disabled && llm != nil {
+		service.monitors = &researchMonitorRunner{service: service, interval: monitors.CheckInterval}
+	}
+
+	// Index users' own notes for agents to search
+	if len(config.Notes) > 0 {
+		service.notesIndexer = notes.NewIndexer(notes.IndexerConfig{
+			Store:    service.userMemory,
+			Sources:  config.Notes,
+			Interval: config.NotesInterval,
+		})
 	}
 
 	// Initialize tools
@@ -75,6 +404,28 @@ func NewMultiAgentService(config ServiceConfig) (*MultiAgentService, error) {
 	if err := service.initializeAgents(); err != nil {
 		return nil, fmt.Errorf("failed to initialize agents: %w", err)
 	}
+	if config.WorkingHours != nil {
+		if err := service.SetWorkingHours(*config.WorkingHours); err != nil {
+			return nil, err
+		}
+	}
+
+	// Add the agents and hooks of plugins
+	if err := service.initializePlugins(context.Background(), append(plugins.Registered(), config.Plugins...)); err != nil {
+		return nil, fmt.Errorf("failed to initialize plugins: %w", err)
+	}
+
+	// Plug in out-of-process agents
+	for _, remote := range config.RemoteAgents {
+		remote.Orchestrator = orch
+		proxy, err := rpc.NewAgentProxy(remote)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create remote agent proxy: %w", err)
+		}
+		if err := service.AddAgent(proxy); err != nil {
+			return nil, fmt.Errorf("failed to add remote agent %s: %w", remote.ID, err)
+		}
+	}
 
 	return service, nil
 }
@@ -86,23 +437,92 @@ func (s *MultiAgentService) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start orchestrator: %w", err)
 	}
 
+	// Start memory maintenance
+	s.janitor.Start(ctx)
+
+	// Pick up edited prompts without a restart
+	go s.prompts.Watch(ctx, promptWatchInterval)
+
+	// Serve metrics for Prometheus
+	if s.metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", s.MetricsHandler())
+		s.metricsServer = &http.Server{Addr: s.metricsAddr, Handler: mux}
+		go func() {
+			if err := s.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("Metrics server failed", "error", err)
+			}
+		}()
+		logger.Info("Serving metrics", "addr", s.metricsAddr, "path", "/metrics")
+	}
+
+	// Serve the orchestrator to out-of-process agents
+	if s.grpcAddr != "" {
+		listener, err := net.Listen("tcp", s.grpcAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen for gRPC: %w", err)
+		}
+		var options []grpc.ServerOption
+		if s.grpcToken != "" {
+			options = append(options, grpc.UnaryInterceptor(rpc.TokenAuthInterceptor(s.grpcToken)))
+		}
+		s.grpcServer = grpc.NewServer(options...)
+		multiagentpb.RegisterOrchestratorServer(s.grpcServer, rpc.NewOrchestratorServer(s.orchestrator))
+		go func() {
+			if err := s.grpcServer.Serve(listener); err != nil {
+				logger.Error("gRPC server failed", "error", err)
+			}
+		}()
+		logger.Info("Serving gRPC", "addr", listener.Addr().String())
+	}
+
+	// Fire the reminders every user had scheduled before the restart
+	s.startReminders(ctx)
+
 	// Start all agents
 	for id, agent := range s.agents {
 		// Initialize agent first
 		if err := agent.Initialize(ctx); err != nil {
-			log.Printf("Warning: Failed to initialize agent %s: %v", id, err)
+			logger.WarnContext(ctx, "Failed to initialize agent", logging.KeyAgentID, id, "error", err)
 			continue
 		}
 
 		// Then start agent
 		if err := agent.Start(ctx); err != nil {
-			log.Printf("Warning: Failed to start agent %s: %v", id, err)
+			logger.WarnContext(ctx, "Failed to start agent", logging.KeyAgentID, id, "error", err)
 		} else {
-			log.Printf("✅ Started agent: %s (%s)", agent.Name(), id)
+			logger.InfoContext(ctx, "Started agent", logging.KeyAgentID, id, "name", agent.Name())
+			s.startedPluginAgent(ctx, agent)
 		}
 	}
 
-	log.Println("🚀 MultiAgentService started successfully with all specialist agents")
+	// Finish requests the previous run accepted but never answered
+	s.resumePendingRequests(ctx)
+
+	// Keep users' CalDAV calendars in sync
+	if err := s.startCalendarSync(ctx); err != nil {
+		return err
+	}
+
+	// Log the replies arriving in users' mailboxes
+	if err := s.startInboxPolling(ctx); err != nil {
+		return err
+	}
+
+	if s.briefings != nil {
+		s.briefings.Start(ctx)
+	}
+	if s.facts != nil {
+		s.facts.Start(ctx)
+	}
+	if s.monitors != nil {
+		s.monitors.Start(ctx)
+	}
+	if s.notesIndexer != nil {
+		s.notesIndexer.Start(ctx)
+	}
+
+	logger.InfoContext(ctx, "MultiAgentService started", "agents", len(s.agents))
 	return nil
 }
 
@@ -111,7 +531,7 @@ func (s *MultiAgentService) Stop(ctx context.Context) error {
 	// Stop all agents
 	for id, agent := range s.agents {
 		if err := agent.Stop(ctx); err != nil {
-			log.Printf("Warning: Failed to stop agent %s: %v", id, err)
+			logger.WarnContext(ctx, "Failed to stop agent", logging.KeyAgentID, id, "error", err)
 		}
 	}
 
@@ -120,248 +540,170 @@ func (s *MultiAgentService) Stop(ctx context.Context) error {
 		return fmt.Errorf("failed to stop orchestrator: %w", err)
 	}
 
-	// Close any pending request channels
-	s.requestsMutex.Lock()
-	for _, ch := range s.pendingRequests {
-		close(ch)
+	// Stop memory maintenance, reminders, briefings, fact extraction,
+	// research monitoring, notes indexing, calendar sync and mailbox polling
+	s.janitor.Stop()
+	s.reminderEngine.Stop()
+	if s.briefings != nil {
+		s.briefings.Stop()
+	}
+	if s.facts != nil {
+		s.facts.Stop()
+	}
+	if s.monitors != nil {
+		s.monitors.Stop()
+	}
+	if s.notesIndexer != nil {
+		s.notesIndexer.Stop()
+	}
+	for _, syncer := range s.caldavSyncers {
+		syncer.Stop()
 	}
-	s.pendingRequests = make(map[string]chan string)
-	s.requestsMutex.Unlock()
+	s.caldavSyncers = nil
+	for _, poller := range s.emailPollers {
+		poller.Stop()
+	}
+	s.emailPollers = nil
 
-	log.Println("🛑 MultiAgentService stopped successfully")
-	return nil
-}
+	// Let webhook requests under way finish
+	if err := s.webhooks.Stop(ctx); err != nil {
+		logger.WarnContext(ctx, "Webhook deliveries left unfinished", "error", err)
+	}
 
-// ProcessUserMessage processes a user message and returns a response
-func (s *MultiAgentService) ProcessUserMessage(ctx context.Context, userID string, message string) (string, error) {
-	conversationID := fmt.Sprintf("conv_%s", userID)
-	log.Printf("Service: Using consistent conversation ID: %s", conversationID)
-
-	responseKey := fmt.Sprintf("user_response_%s_%d", userID, time.Now().UnixNano())
-	responseChannel := make(chan string, 10) // Increased buffer
-
-	// Handler state tracking
-	var handlerState struct {
-		mutex      sync.RWMutex
-		registered bool
-		called     bool
-		response   string
-		timestamp  time.Time
-	}
-
-	// Create persistent handler
-	handler := func(response string) {
-		handlerState.mutex.Lock()
-		defer handlerState.mutex.Unlock()
-
-		// Always store the response, even if it's an acknowledgment
-		handlerState.response = response
-		handlerState.timestamp = time.Now()
-
-		log.Printf("Service: [HANDLER] ✅ Called for key %s at %v", responseKey, handlerState.timestamp)
-		log.Printf("Service: [HANDLER] Response length: %d", len(response))
-
-		// Check if this is an acknowledgment message from the conversation agent
-		if strings.Contains(response, "I'm working on your request and consulting with specialists") {
-			log.Printf("Service: [HANDLER] 🔄 Received acknowledgment message, waiting for final response")
-			// Don't mark as called yet and don't send to channel
-			// This ensures we'll still process the final response from the coordinator
-			return
+	// Stop serving metrics
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			logger.WarnContext(ctx, "Failed to stop metrics server", "error", err)
 		}
+		s.metricsServer = nil
+	}
+	if s.grpcServer != nil {
+		s.grpcServer.GracefulStop()
+		s.grpcServer = nil
+	}
 
-		// If we already processed a response (that wasn't an acknowledgment), ignore duplicates
-		if handlerState.called {
-			log.Printf("Service: [HANDLER] Already called for key %s, ignoring duplicate", responseKey)
-			return
+	// Disconnect from MCP servers
+	for _, client := range s.mcpClients {
+		if err := client.Close(); err != nil {
+			logger.WarnContext(ctx, "Failed to close MCP client", "server", client.ServerInfo().Name, "error", err)
 		}
+	}
+	s.mcpClients = nil
 
-		// Mark as called for non-acknowledgment responses
-		handlerState.called = true
-
-		// Send to channel with timeout
-		select {
-		case responseChannel <- response:
-			log.Printf("Service: [HANDLER] ✅ Sent to channel for key: %s", responseKey)
-		case <-time.After(10 * time.Second):
-			log.Printf("Service: [HANDLER] ⚠️ Channel send timeout for key: %s", responseKey)
-			// Still store the response for polling
+	if s.vectorMemory != nil {
+		if err := s.vectorMemory.Close(); err != nil {
+			logger.WarnContext(ctx, "Failed to close vector memory", "error", err)
 		}
 	}
 
-	// Register handler with orchestrator
-	if orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator); ok {
-		orch.RegisterUserResponseHandler(responseKey, handler)
-		handlerState.mutex.Lock()
-		handlerState.registered = true
-		handlerState.mutex.Unlock()
-		log.Printf("Service: [REGISTER] ✅ Handler registered for key: %s", responseKey)
-	} else {
-		return "", fmt.Errorf("orchestrator does not support user response handlers")
+	logger.InfoContext(ctx, "MultiAgentService stopped")
+	return nil
+}
+
+// ProcessUserMessage processes a user message and returns a response.
+// Progress on the request is published to SubscribeProgress(userID).
+func (s *MultiAgentService) ProcessUserMessage(ctx context.Context, userID string, message string) (string, error) {
+	conversationID := conversationIDForUser(userID)
+	ctx = logging.WithFields(ctx, logging.KeyConversationID, conversationID, logging.KeyUserID, userID)
+	ctx = multiagent.WithUserID(ctx, userID)
+	ctx = progress.WithHub(ctx, s.progress)
+	s.recordUser(ctx, userID)
+	s.appendTranscript(ctx, conversationID, "user", message)
+
+	// Journal the request until it is answered; if the process dies first,
+	// the next Start finishes it
+	request := PendingRequest{
+		ID:             s.newID(conversationID),
+		UserID:         userID,
+		ConversationID: conversationID,
+		Message:        message,
+		ReceivedAt:     s.now(),
 	}
+	s.trackPending(ctx, request)
+	defer s.finishPending(ctx, request.ID)
 
-	// *** CRITICAL: DO NOT SCHEDULE CLEANUP HERE ***
-	// The handler will remain registered until explicitly cleaned up after success
+	progress.Emit(ctx, progress.Event{Type: progress.RequestReceived, Detail: message})
+	response, err := s.processUserMessage(ctx, userID, conversationID, message)
+	if err != nil {
+		progress.Emit(ctx, progress.Event{Type: progress.Failed, Detail: err.Error()})
+		return "", err
+	}
+	s.appendTranscript(ctx, conversationID, "assistant", response)
+	progress.Emit(ctx, progress.Event{Type: progress.Completed, Detail: response})
+	return response, nil
+}
+
+// SubscribeProgress streams progress events for the conversation
+// ProcessUserMessage keeps for userID; call the returned function to stop
+func (s *MultiAgentService) SubscribeProgress(userID string) (<-chan progress.Event, func()) {
+	return s.progress.Subscribe(conversationIDForUser(userID))
+}
+
+// conversationIDForUser returns the conversation a user's messages belong to
+func conversationIDForUser(userID string) string {
+	return fmt.Sprintf("conv_%s", userID)
+}
 
-	// Create message
+// now returns the time on the service's clock
+func (s *MultiAgentService) now() time.Time {
+	return s.clock.Now()
+}
+
+// newID returns a new ID starting with prefix
+func (s *MultiAgentService) newID(prefix string) string {
+	return s.ids.NewID(prefix)
+}
+
+func (s *MultiAgentService) processUserMessage(ctx context.Context, userID, conversationID, message string) (string, error) {
+	orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator)
+	if !ok {
+		return "", fmt.Errorf("orchestrator does not support user requests")
+	}
+
+	// The reply to the user's message answers the request
+	request := orch.OpenRequest(conversationID, userRequestTimeout)
 	msg := &multiagent.Message{
-		ID:        fmt.Sprintf("msg_user_%d", time.Now().UnixNano()),
-		From:      multiagent.AgentID(responseKey),
+		ID:        s.newID("msg_user"),
+		From:      request.ID,
 		To:        []multiagent.AgentID{multiagent.AgentID("conversation_agent")},
 		Type:      multiagent.MessageTypeRequest,
 		Content:   message,
 		Priority:  multiagent.PriorityMedium,
-		Timestamp: time.Now(),
+		Timestamp: s.now(),
 		Context: map[string]interface{}{
 			"conversation_id": conversationID,
 			"source":          "user",
 			"user_id":         userID,
-			"response_key":    responseKey,
+			"response_key":    string(request.ID),
 		},
 	}
 
-	// Route message
-	if err := s.orchestrator.RouteMessage(ctx, msg); err != nil {
-		// Only cleanup on immediate routing failure
-		log.Printf("Service: [ERROR] Message routing failed, cleaning up handler")
-		if orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator); ok {
-			orch.UnregisterUserResponseHandler(responseKey)
-		}
+	// Route message, or resume the request an agent paused to ask the user
+	// a question, with this message as the answer
+	route := func() error { return s.orchestrator.RouteMessage(ctx, msg) }
+	if question, pending := orch.PendingQuestion(ctx, conversationID); pending {
+		logger.InfoContext(ctx, "Answering pending question", "question_id", question.ID, logging.KeyAgentID, question.AgentID)
+		route = func() error { return orch.AnswerQuestion(ctx, conversationID, request.ID, message) }
+	}
+	if err := route(); err != nil {
+		request.Cancel()
 		return "", fmt.Errorf("failed to route message: %w", err)
 	}
+	logger.DebugContext(ctx, "User message routed", logging.KeyMessageID, msg.ID, "request_id", request.ID)
 
-	log.Printf("Service: [ROUTE] ✅ Message routed successfully")
-
-	// Wait with enhanced monitoring and orphan recovery
 	startTime := time.Now()
-
-	// Status check every 2 seconds for more responsive recovery
-	statusTicker := time.NewTicker(2 * time.Second)
-	defer statusTicker.Stop()
-
-	// Main response wait loop
-	for {
-		select {
-		case response := <-responseChannel:
-			elapsed := time.Since(startTime)
-			log.Printf("Service: [SUCCESS] ✅ Response received via channel after %v", elapsed)
-
-			// Wait a short time before unregistering to ensure any in-flight responses are processed
-			// This helps prevent race conditions where the handler is unregistered too early
-			time.Sleep(500 * time.Millisecond)
-
-			// NOW we can cleanup since we got the response
-			if orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator); ok {
-				orch.UnregisterUserResponseHandler(responseKey)
-				log.Printf("Service: [CLEANUP] Handler unregistered after successful response")
-			}
-			return response, nil
-
-		case <-statusTicker.C:
-			elapsed := time.Since(startTime)
-
-			// FIRST: Check for orphaned responses immediately
-			if orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator); ok {
-				if orphanResponse, found := orch.GetOrphanedResponse(ctx, responseKey); found {
-					log.Printf("Service: [RECOVERY] ✅ Orphaned response recovered after %v", elapsed)
-					// Wait a short time before unregistering
-					time.Sleep(500 * time.Millisecond)
-					orch.UnregisterUserResponseHandler(responseKey)
-					return orphanResponse, nil
-				}
-			}
-
-			// Check handler state second
-			handlerState.mutex.RLock()
-			called := handlerState.called
-			response := handlerState.response
-			handlerState.mutex.RUnlock()
-
-			// If handler was called but response wasn't delivered via channel
-			if called && len(response) > 0 {
-				log.Printf("Service: [RECOVERY] ✅ Found response in handler state after %v", elapsed)
-				// Wait a short time before unregistering
-				time.Sleep(500 * time.Millisecond)
-				if orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator); ok {
-					orch.UnregisterUserResponseHandler(responseKey)
-				}
-				return response, nil
-			}
-
-			// Verify handler still exists and re-register if missing
-			if orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator); ok {
-				keys := orch.GetUserResponseHandlerKeys()
-				handlerExists := false
-				for _, key := range keys {
-					if key == responseKey {
-						handlerExists = true
-						break
-					}
-				}
-				totalHandlers := orch.GetUserResponseHandlerCount()
-
-				if !handlerExists {
-					log.Printf("Service: [ERROR] ❌ Handler disappeared after %v! Re-registering...", elapsed)
-					orch.RegisterUserResponseHandler(responseKey, handler)
-					handlerState.mutex.Lock()
-					handlerState.registered = true
-					handlerState.mutex.Unlock()
-				} else {
-					// Only log every 10 seconds to reduce noise
-					if int(elapsed.Seconds())%10 == 0 {
-						log.Printf("Service: [STATUS] ⏳ Waiting %v, handler exists, total: %d", elapsed, totalHandlers)
-					}
-				}
-			}
-
-			// Ultimate timeout - be more aggressive about recovery
-			if elapsed > 10*time.Minute {
-				log.Printf("Service: [TIMEOUT] ❌ Timeout reached after %v", elapsed)
-
-				// Final comprehensive check
-				handlerState.mutex.RLock()
-				finalResponse := handlerState.response
-				handlerState.mutex.RUnlock()
-
-				if len(finalResponse) > 0 {
-					log.Printf("Service: [SUCCESS] ✅ Final response found in handler state")
-					// Wait a short time before unregistering
-					time.Sleep(500 * time.Millisecond)
-					if orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator); ok {
-						orch.UnregisterUserResponseHandler(responseKey)
-					}
-					return finalResponse, nil
-				}
-
-				// Final orphan check
-				if orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator); ok {
-					if orphanResponse, found := orch.GetOrphanedResponse(ctx, responseKey); found {
-						log.Printf("Service: [SUCCESS] ✅ Final orphaned response recovered")
-						// Wait a short time before unregistering
-						time.Sleep(500 * time.Millisecond)
-						orch.UnregisterUserResponseHandler(responseKey)
-						return orphanResponse, nil
-					}
-				}
-
-				// Give up - but keep the handler registered for a bit longer
-				// This helps with race conditions where the response arrives just after the timeout
-				log.Printf("Service: [TIMEOUT] ⚠️ Keeping handler registered for potential late responses")
-
-				// Return timeout message but don't unregister the handler yet
-				// The handler will be garbage collected eventually
-				return fmt.Sprintf("Request timed out after %v. The system may still be processing your request. Please try again.", elapsed.Round(time.Second)), nil
-			}
-
-		case <-ctx.Done():
-			log.Printf("Service: [CANCELLED] Context cancelled")
-			// Wait a short time before unregistering
-			time.Sleep(500 * time.Millisecond)
-			if orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator); ok {
-				orch.UnregisterUserResponseHandler(responseKey)
-			}
-			return "", ctx.Err()
-		}
+	response, err := request.Wait(ctx)
+	switch {
+	case errors.Is(err, orchestrator.ErrRequestTimedOut):
+		elapsed := time.Since(startTime)
+		logger.WarnContext(ctx, "Timed out waiting for response", "request_id", request.ID, "elapsed", elapsed)
+		return fmt.Sprintf("Request timed out after %v. The system may still be processing your request. Please try again.", elapsed.Round(time.Second)), nil
+	case err != nil:
+		logger.InfoContext(ctx, "Request cancelled", "request_id", request.ID)
+		return "", err
 	}
+	logger.InfoContext(ctx, "Response received", "request_id", request.ID, "elapsed", time.Since(startTime))
+	return response, nil
 }
 
 // GetAgent returns an agent by ID
@@ -378,9 +720,142 @@ func (s *MultiAgentService) GetOrchestrator() multiagent.Orchestrator {
 	return s.orchestrator
 }
 
-// GetMemoryStore returns the memory store
+// SubmitWorkflow submits a DAG of tasks for the specialists to execute in dependency order
+func (s *MultiAgentService) SubmitWorkflow(ctx context.Context, workflow orchestrator.Workflow) (string, error) {
+	orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator)
+	if !ok {
+		return "", fmt.Errorf("orchestrator does not support workflows")
+	}
+	return orch.SubmitWorkflow(ctx, workflow)
+}
+
+// GetWorkflowStatus returns the progress of a submitted workflow
+func (s *MultiAgentService) GetWorkflowStatus(ctx context.Context, workflowID string) (*orchestrator.WorkflowStatus, error) {
+	orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator)
+	if !ok {
+		return nil, fmt.Errorf("orchestrator does not support workflows")
+	}
+	return orch.GetWorkflowStatus(ctx, workflowID)
+}
+
+// SubmitEvent queues an event from outside the assistant, publishing it to
+// the agents subscribed to its topic
+func (s *MultiAgentService) SubmitEvent(event *multiagent.Event) error {
+	orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator)
+	if !ok {
+		return fmt.Errorf("orchestrator does not accept events")
+	}
+	return orch.SubmitEvent(event)
+}
+
+// CancelTask cancels an unfinished task, interrupting it if it is running
+func (s *MultiAgentService) CancelTask(ctx context.Context, taskID string) error {
+	return s.orchestrator.CancelTask(ctx, taskID)
+}
+
+// ListDeadLetters returns undeliverable messages, newest first
+func (s *MultiAgentService) ListDeadLetters(ctx context.Context, limit int) ([]*orchestrator.DeadLetter, error) {
+	orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator)
+	if !ok {
+		return nil, fmt.Errorf("orchestrator does not support dead letters")
+	}
+	return orch.ListDeadLetters(ctx, limit)
+}
+
+// ReplayDeadLetter re-sends a dead letter to its original recipient
+func (s *MultiAgentService) ReplayDeadLetter(ctx context.Context, id string) error {
+	orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator)
+	if !ok {
+		return fmt.Errorf("orchestrator does not support dead letters")
+	}
+	return orch.ReplayDeadLetter(ctx, id)
+}
+
+// PurgeDeadLetters deletes dead letters older than olderThan, or all if zero
+func (s *MultiAgentService) PurgeDeadLetters(ctx context.Context, olderThan time.Duration) (int, error) {
+	orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator)
+	if !ok {
+		return 0, fmt.Errorf("orchestrator does not support dead letters")
+	}
+	return orch.PurgeDeadLetters(ctx, olderThan)
+}
+
+// GetTopicStats returns pub/sub delivery counters per topic
+func (s *MultiAgentService) GetTopicStats() map[string]multiagent.TopicStats {
+	orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator)
+	if !ok {
+		return nil
+	}
+	return orch.GetTopicStats()
+}
+
+// GetConversationContext returns the shared blackboard specialists built up for a conversation
+func (s *MultiAgentService) GetConversationContext(ctx context.Context, conversationID string) (memory.BlackboardSnapshot, error) {
+	return memory.NewBlackboard(s.userMemory, conversationID).Read(s.userContext(ctx, conversationID))
+}
+
+// GetStructuredOutputMetrics returns LLM JSON parse and repair counters per agent
+func (s *MultiAgentService) GetStructuredOutputMetrics() map[string]llmprovider.ParseStats {
+	return agents.StructuredOutputMetrics()
+}
+
+// QueryAudit returns audited agent actions matching filter, newest first
+func (s *MultiAgentService) QueryAudit(ctx context.Context, filter audit.Filter) ([]audit.Event, error) {
+	return s.auditLog.Query(ctx, filter)
+}
+
+// TokenUsage returns the LLM token usage of each day since since, oldest
+// first
+func (s *MultiAgentService) TokenUsage(ctx context.Context, since time.Time) ([]*usage.Day, error) {
+	return s.usage.Query(ctx, since)
+}
+
+// ListTasks returns the personal tasks of the user ctx acts for, oldest first
+func (s *MultiAgentService) ListTasks(ctx context.Context) ([]*agents.PersonalTask, error) {
+	keys, err := s.userMemory.List(ctx, "personal_task:", 10000)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	values, err := s.userMemory.GetMultiple(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tasks: %w", err)
+	}
+
+	tasks := make([]*agents.PersonalTask, 0, len(values))
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		var task agents.PersonalTask
+		if err := json.Unmarshal(data, &task); err != nil || task.ID == "" {
+			continue
+		}
+		tasks = append(tasks, &task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].CreatedAt.Before(tasks[j].CreatedAt) })
+	return tasks, nil
+}
+
+// MetricsHandler serves routing, queue, task, and LLM metrics in the
+// Prometheus text format
+func (s *MultiAgentService) MetricsHandler() http.Handler {
+	return s.metrics.Handler()
+}
+
+// RouteByCapability sends a message to the agent whose capabilities best match its content
+func (s *MultiAgentService) RouteByCapability(ctx context.Context, msg *multiagent.Message) (multiagent.AgentID, error) {
+	orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator)
+	if !ok {
+		return "", fmt.Errorf("orchestrator does not support capability routing")
+	}
+	return orch.RouteByCapability(ctx, msg)
+}
+
+// GetMemoryStore returns the memory store, partitioned by the user a
+// call's context acts for
 func (s *MultiAgentService) GetMemoryStore() multiagent.MemoryStore {
-	return s.memoryStore
+	return s.userMemory
 }
 
 // AgentInfo provides information about an agent for display purposes
@@ -394,14 +869,19 @@ type AgentInfo struct {
 
 // SystemHealthInfo provides extended health information for display purposes
 type SystemHealthInfo struct {
-	Status            string        `json:"status"`
-	ActiveAgents      int           `json:"active_agents"`
-	TotalAgents       int           `json:"total_agents"`
-	MessagesProcessed int           `json:"messages_processed"`
-	MessageQueueSize  int           `json:"message_queue_size"`
-	EventsProcessed   int           `json:"events_processed"`
-	EventQueueSize    int           `json:"event_queue_size"`
-	Uptime            time.Duration `json:"uptime"`
+	Status            string                  `json:"status"`
+	ActiveAgents      int                     `json:"active_agents"`
+	TotalAgents       int                     `json:"total_agents"`
+	MessagesProcessed int                     `json:"messages_processed"`
+	MessageQueueSize  int                     `json:"message_queue_size"`
+	EventsProcessed   int                     `json:"events_processed"`
+	EventQueueSize    int                     `json:"event_queue_size"`
+	Uptime            time.Duration           `json:"uptime"`
+	Memory            *multiagent.MemoryStats `json:"memory,omitempty"`
+	// LastHeartbeats is when each agent last answered the orchestrator's ping
+	LastHeartbeats map[multiagent.AgentID]time.Time `json:"last_heartbeats,omitempty"`
+	// LLMBackends is the load and loaded models of each governed LLM backend
+	LLMBackends []llmprovider.BackendStatus `json:"llm_backends,omitempty"`
 }
 
 // ListAgents returns information about all registered agents
@@ -457,116 +937,298 @@ func (s *MultiAgentService) GetSystemHealth() SystemHealthInfo {
 		EventsProcessed:   eventsProcessed,
 		EventQueueSize:    eventQueueSize,
 		Uptime:            health.Uptime,
+		Memory:            health.Memory,
+		LastHeartbeats:    health.LastHeartbeats,
+		LLMBackends:       s.LLMBackendStatus(context.Background()),
 	}
 }
 
 // initializeTools initializes all tools
 func (s *MultiAgentService) initializeTools() error {
-	// Create memory tool
-	memoryTool := tools.NewMemoryTool(s.memoryStore)
-	s.tools[memoryTool.Name()] = memoryTool
+	// The memory, task and notes tools are built for each agent over its
+	// scoped memory, by memoryTools
+
+	// Create HTTP tool
+	httpTool := tools.NewHTTPTool(nil)
+	s.tools[httpTool.Name()] = progress.WrapTool(httpTool)
 
-	// Create task tool
-	taskTool := tools.NewTaskTool(s.memoryStore, s.orchestrator)
-	s.tools[taskTool.Name()] = taskTool
+	for _, tool := range s.extraTools {
+		s.tools[tool.Name()] = progress.WrapTool(tool)
+	}
+
+	// Discover tools from MCP servers; one that can't be reached is skipped
+	// rather than keeping the assistant from starting
+	for _, server := range s.mcpServers {
+		if err := s.connectMCPServer(server); err != nil {
+			logger.Warn("Skipping MCP server", "server", server.Name, "error", err)
+		}
+	}
 
-	log.Printf("📚 Initialized %d tools", len(s.tools))
+	logger.Info("Initialized tools", "tools", len(s.tools))
 	return nil
 }
 
-// initializeAgents initializes ALL agents including new specialist agents
-func (s *MultiAgentService) initializeAgents() error {
-	// Create a list of tools for agents
-	agentTools := make([]multiagent.Tool, 0, len(s.tools))
-	for _, tool := range s.tools {
-		agentTools = append(agentTools, tool)
+// connectMCPServer connects to an MCP server and adds its tools
+func (s *MultiAgentService) connectMCPServer(config mcp.ClientConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mcp.NewClient(config)
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return err
+	}
+	tools, err := client.Tools(ctx)
+	if err != nil {
+		client.Close()
+		return err
+	}
+
+	s.mcpClients = append(s.mcpClients, client)
+	for _, tool := range tools {
+		if err := s.AddTool(tool); err != nil {
+			logger.Warn("Skipping MCP tool", "server", config.Name, "tool", tool.Name(), "error", err)
+		}
+	}
+	logger.Info("Added MCP tools", "server", config.Name, "tools", len(tools))
+	return nil
+}
+
+// agentLLM returns the provider for role, an agent type or
+// llmprovider.RoleRouting: the role's model in the LLM pool, or the shared
+// provider without a pool. Conversations over their token budget are
+// answered by the routing model.
+func (s *MultiAgentService) agentLLM(role string) multiagent.LLMProvider {
+	if s.llmPool == nil {
+		return s.llmProvider
+	}
+	var llm multiagent.LLMProvider = llmprovider.NewInstrumentedProvider(s.llmPool.Provider(role), s.metrics,
+		llmprovider.WithUsageTracker(s.usage),
+		llmprovider.WithBudgetFallback(s.llmPool.Provider(llmprovider.RoleRouting)))
+	if s.llmCache != nil {
+		llm = s.llmCache.Provider(llm)
+	}
+	return llm
+}
+
+// llmGovernors returns the governors of the LLM backends agents query
+func (s *MultiAgentService) llmGovernors() []*llmprovider.Governor {
+	var governors []*llmprovider.Governor
+	if s.llmGovernor != nil {
+		governors = append(governors, s.llmGovernor)
+	}
+	if s.llmPool != nil {
+		governors = append(governors, s.llmPool.Governors()...)
+	}
+	return governors
+}
+
+// LLMBackendStatus probes each governed LLM backend for its calls in flight
+// and waiting, and the models it has loaded
+func (s *MultiAgentService) LLMBackendStatus(ctx context.Context) []llmprovider.BackendStatus {
+	var statuses []llmprovider.BackendStatus
+	for _, governor := range s.llmGovernors() {
+		statuses = append(statuses, governor.Status(ctx))
+	}
+	return statuses
+}
+
+// registerLLMLoadMetrics exports the calls in flight and waiting per backend
+func (s *MultiAgentService) registerLLMLoadMetrics() {
+	inFlight := s.metrics.NewGauge("multiagent_llm_in_flight",
+		"LLM calls in flight per backend", "backend")
+	queued := s.metrics.NewGauge("multiagent_llm_queued",
+		"LLM calls waiting for a free slot on their backend", "backend")
+	s.metrics.OnScrape(func() {
+		inFlight.Reset()
+		queued.Reset()
+		for _, governor := range s.llmGovernors() {
+			load := governor.Load()
+			inFlight.Set(float64(load.InFlight), load.Name)
+			queued.Set(float64(load.Queued), load.Name)
+		}
+	})
+}
+
+// ReloadLLMPool switches agents to the models in config without a restart;
+// on error the current models are kept
+func (s *MultiAgentService) ReloadLLMPool(config llmprovider.PoolConfig) error {
+	if s.llmPool == nil {
+		return fmt.Errorf("service was not started with an LLM pool")
 	}
+	if err := s.llmPool.Reload(config); err != nil {
+		return fmt.Errorf("failed to reload LLM models: %w", err)
+	}
+	return nil
+}
+
+// ListPrompts describes every prompt agents render and its active versions
+func (s *MultiAgentService) ListPrompts() []prompts.Prompt {
+	return s.prompts.List()
+}
+
+// ReloadPrompts re-reads the prompt directory now; on error the current
+// prompts are kept
+func (s *MultiAgentService) ReloadPrompts() error {
+	if err := s.prompts.Reload(); err != nil {
+		return fmt.Errorf("failed to reload prompts: %w", err)
+	}
+	return nil
+}
 
-	log.Println("🤖 Initializing all specialist agents...")
+// initializeAgents initializes ALL agents including new specialist agents
+func (s *MultiAgentService) initializeAgents() error {
+	logger.Debug("Initializing specialist agents")
 
 	// 1. Create Project Manager Agent
 	projectManagerAgent := agents.NewProjectManagerAgent(agents.BaseAgentConfig{
-		ID:           "project_manager_agent",
-		Name:         "Project Manager",
-		Description:  "Specialized in project planning, task management, and progress tracking",
-		Tools:        agentTools,
-		LLMProvider:  s.llmProvider,
-		MemoryStore:  s.memoryStore,
-		Orchestrator: s.orchestrator,
+		ID:                 "project_manager_agent",
+		Name:               "Project Manager",
+		Description:        "Specialized in project planning, task management, and progress tracking",
+		Tools:              s.agentTools("project_manager_agent"),
+		LLMProvider:        s.agentLLM(string(multiagent.AgentTypeProjectManager)),
+		RoutingLLMProvider: s.agentLLM(llmprovider.RoleRouting),
+		MemoryStore:        s.agentMemory("project_manager_agent"),
+		Orchestrator:       s.orchestrator,
+		Audit:              s.auditLog,
+		Reminders:          s.reminderEngine,
+		Confirmations:      s.confirmations,
+		Access:             s.access,
+		Usage:              s.usage,
+		Prompts:            s.prompts,
+		Clock:              s.clock,
+		IDs:                s.ids,
 	})
 	s.agents[projectManagerAgent.ID()] = projectManagerAgent
 
 	// 2. Create Task Manager Agent
 	taskManagerAgent := agents.NewTaskManagerAgent(agents.BaseAgentConfig{
-		ID:           "task_manager_agent",
-		Name:         "Task Manager",
-		Description:  "Personal productivity specialist using GTD methodology",
-		Tools:        agentTools,
-		LLMProvider:  s.llmProvider,
-		MemoryStore:  s.memoryStore,
-		Orchestrator: s.orchestrator,
+		ID:                 "task_manager_agent",
+		Name:               "Task Manager",
+		Description:        "Personal productivity specialist using GTD methodology",
+		Tools:              s.agentTools("task_manager_agent"),
+		LLMProvider:        s.agentLLM(string(multiagent.AgentTypeTask)),
+		RoutingLLMProvider: s.agentLLM(llmprovider.RoleRouting),
+		MemoryStore:        s.agentMemory("task_manager_agent"),
+		Orchestrator:       s.orchestrator,
+		Audit:              s.auditLog,
+		Reminders:          s.reminderEngine,
+		Confirmations:      s.confirmations,
+		Access:             s.access,
+		Usage:              s.usage,
+		Prompts:            s.prompts,
+		Clock:              s.clock,
+		IDs:                s.ids,
 	})
 	s.agents[taskManagerAgent.ID()] = taskManagerAgent
 
 	// 3. Create Research Assistant Agent
 	researchAssistantAgent := agents.NewResearchAssistantAgent(agents.BaseAgentConfig{
-		ID:           "research_assistant_agent",
-		Name:         "Research Assistant",
-		Description:  "Information gathering, fact-checking, and knowledge synthesis specialist",
-		Tools:        agentTools,
-		LLMProvider:  s.llmProvider,
-		MemoryStore:  s.memoryStore,
-		Orchestrator: s.orchestrator,
+		ID:                 "research_assistant_agent",
+		Name:               "Research Assistant",
+		Description:        "Information gathering, fact-checking, and knowledge synthesis specialist",
+		Tools:              s.agentTools("research_assistant_agent"),
+		LLMProvider:        s.agentLLM(string(multiagent.AgentTypeResearch)),
+		RoutingLLMProvider: s.agentLLM(llmprovider.RoleRouting),
+		MemoryStore:        s.agentMemory("research_assistant_agent"),
+		Orchestrator:       s.orchestrator,
+		Audit:              s.auditLog,
+		Reminders:          s.reminderEngine,
+		Confirmations:      s.confirmations,
+		Access:             s.access,
+		Usage:              s.usage,
+		Prompts:            s.prompts,
+		Clock:              s.clock,
+		IDs:                s.ids,
 	})
 	s.agents[researchAssistantAgent.ID()] = researchAssistantAgent
 
 	// 4. Create Scheduler Agent
 	schedulerAgent := agents.NewSchedulerAgent(agents.BaseAgentConfig{
-		ID:           "scheduler_agent",
-		Name:         "Scheduler",
-		Description:  "Calendar management and appointment scheduling specialist",
-		Tools:        agentTools,
-		LLMProvider:  s.llmProvider,
-		MemoryStore:  s.memoryStore,
-		Orchestrator: s.orchestrator,
+		ID:                 "scheduler_agent",
+		Name:               "Scheduler",
+		Description:        "Calendar management and appointment scheduling specialist",
+		Tools:              s.agentTools("scheduler_agent"),
+		LLMProvider:        s.agentLLM(string(multiagent.AgentTypeScheduler)),
+		RoutingLLMProvider: s.agentLLM(llmprovider.RoleRouting),
+		MemoryStore:        s.agentMemory("scheduler_agent"),
+		Orchestrator:       s.orchestrator,
+		Audit:              s.auditLog,
+		Reminders:          s.reminderEngine,
+		Confirmations:      s.confirmations,
+		Access:             s.access,
+		Usage:              s.usage,
+		Prompts:            s.prompts,
+		Clock:              s.clock,
+		IDs:                s.ids,
 	})
 	s.agents[schedulerAgent.ID()] = schedulerAgent
 
 	// 5. Create Communication Manager Agent
 	communicationManagerAgent := agents.NewCommunicationManagerAgent(agents.BaseAgentConfig{
-		ID:           "communication_manager_agent",
-		Name:         "Communication Manager",
-		Description:  "Contact management and communication coordination specialist",
-		Tools:        agentTools,
-		LLMProvider:  s.llmProvider,
-		MemoryStore:  s.memoryStore,
-		Orchestrator: s.orchestrator,
+		ID:                 "communication_manager_agent",
+		Name:               "Communication Manager",
+		Description:        "Contact management and communication coordination specialist",
+		Tools:              s.agentTools("communication_manager_agent"),
+		LLMProvider:        s.agentLLM(string(multiagent.AgentTypeCommunicationManager)),
+		RoutingLLMProvider: s.agentLLM(llmprovider.RoleRouting),
+		MemoryStore:        s.agentMemory("communication_manager_agent"),
+		Orchestrator:       s.orchestrator,
+		Audit:              s.auditLog,
+		Reminders:          s.reminderEngine,
+		Confirmations:      s.confirmations,
+		Access:             s.access,
+		Usage:              s.usage,
+		Prompts:            s.prompts,
+		Clock:              s.clock,
+		IDs:                s.ids,
+		Email:              s.mailer,
 	})
 	s.agents[communicationManagerAgent.ID()] = communicationManagerAgent
 
 	// 6. Create Conversation Agent (handles routing to specialists)
 	conversationAgent := agents.NewConversationAgent(agents.BaseAgentConfig{
-		ID:           "conversation_agent",
-		Type:         multiagent.AgentTypeConversation,
-		Name:         "Conversation Agent",
-		Description:  "Natural language interface that routes requests to appropriate specialists",
-		Tools:        agentTools,
-		LLMProvider:  s.llmProvider,
-		MemoryStore:  s.memoryStore,
-		Orchestrator: s.orchestrator,
+		ID:                 "conversation_agent",
+		Type:               multiagent.AgentTypeConversation,
+		Name:               "Conversation Agent",
+		Description:        "Natural language interface that routes requests to appropriate specialists",
+		Tools:              s.agentTools("conversation_agent"),
+		LLMProvider:        s.agentLLM(string(multiagent.AgentTypeConversation)),
+		RoutingLLMProvider: s.agentLLM(llmprovider.RoleRouting),
+		MemoryStore:        s.agentMemory("conversation_agent"),
+		Orchestrator:       s.orchestrator,
+		Audit:              s.auditLog,
+		Reminders:          s.reminderEngine,
+		Confirmations:      s.confirmations,
+		Access:             s.access,
+		Usage:              s.usage,
+		Prompts:            s.prompts,
+		Clock:              s.clock,
+		IDs:                s.ids,
 	})
 	s.agents[conversationAgent.ID()] = conversationAgent
 
 	// 7. Create Coordinator Agent (manages multi-agent workflows)
 	coordinatorAgent := agents.NewCoordinatorAgent(agents.BaseAgentConfig{
-		ID:           "coordinator_agent",
-		Type:         multiagent.AgentTypeCoordinator,
-		Name:         "Coordinator Agent",
-		Description:  "Coordinates specialist agents to handle complex multi-step tasks",
-		Tools:        agentTools,
-		LLMProvider:  s.llmProvider,
-		MemoryStore:  s.memoryStore,
-		Orchestrator: s.orchestrator,
+		ID:                 "coordinator_agent",
+		Type:               multiagent.AgentTypeCoordinator,
+		Name:               "Coordinator Agent",
+		Description:        "Coordinates specialist agents to handle complex multi-step tasks",
+		Tools:              s.agentTools("coordinator_agent"),
+		LLMProvider:        s.agentLLM(string(multiagent.AgentTypeCoordinator)),
+		RoutingLLMProvider: s.agentLLM(llmprovider.RoleRouting),
+		MemoryStore:        s.agentMemory("coordinator_agent"),
+		Orchestrator:       s.orchestrator,
+		Audit:              s.auditLog,
+		Reminders:          s.reminderEngine,
+		Confirmations:      s.confirmations,
+		Access:             s.access,
+		Usage:              s.usage,
+		Prompts:            s.prompts,
+		Clock:              s.clock,
+		IDs:                s.ids,
 	})
 	s.agents[coordinatorAgent.ID()] = coordinatorAgent
 
@@ -577,10 +1239,64 @@ func (s *MultiAgentService) initializeAgents() error {
 		}
 	}
 
-	log.Printf("📋 Initialized %d specialist agents", len(s.agents))
+	logger.Info("Initialized specialist agents", "agents", len(s.agents))
 	return nil
 }
 
+// agentMemory returns the memory view for an agent, scoped so it can only
+// write its own private namespace plus shared and global keys, within the
+// partition of the user it is acting for
+func (s *MultiAgentService) agentMemory(agentID multiagent.AgentID) multiagent.MemoryStore {
+	policy := memory.DefaultNamespacePolicy()
+	policy.OnWrite = s.auditMemoryWrite
+	return memory.ScopeForAgent(s.userMemory, agentID, policy)
+}
+
+// agentTools returns the tools as agentID may use them under the access
+// policy, with those over memory held to its namespace
+func (s *MultiAgentService) agentTools(agentID multiagent.AgentID) []multiagent.Tool {
+	agentTools := s.memoryTools(agentID)
+	for _, tool := range s.tools {
+		agentTools = append(agentTools, tool)
+	}
+	for i, tool := range agentTools {
+		agentTools[i] = access.WrapTool(tool, agentID, s.access, s.auditLog)
+	}
+	return agentTools
+}
+
+// memoryTools returns the tools that read and write memory, over agentID's
+// scoped view of it so they cannot reach past its namespace
+func (s *MultiAgentService) memoryTools(agentID multiagent.AgentID) []multiagent.Tool {
+	memoryView := s.agentMemory(agentID)
+	memoryTools := []multiagent.Tool{
+		progress.WrapTool(tools.NewMemoryTool(memoryView)),
+		progress.WrapTool(tools.NewTaskTool(memoryView, s.orchestrator, s.ids, s.clock)),
+	}
+	// Only if users' notes are indexed
+	if s.notesIndexer != nil {
+		memoryTools = append(memoryTools, progress.WrapTool(tools.NewNotesSearchTool(memoryView)))
+	}
+	return memoryTools
+}
+
+// auditMemoryWrite records a memory write made by an agent
+func (s *MultiAgentService) auditMemoryWrite(ctx context.Context, agentID multiagent.AgentID, op memory.WriteOp, key string) {
+	eventType := audit.MemoryWritten
+	if op == memory.WriteDelete {
+		eventType = audit.MemoryDeleted
+	}
+	event := audit.Event{
+		Type:    eventType,
+		Actor:   agentID,
+		Subject: key,
+		Payload: map[string]interface{}{"op": op},
+	}
+	if err := s.auditLog.Record(ctx, event); err != nil {
+		logger.WarnContext(ctx, "Failed to audit memory write", logging.KeyAgentID, agentID, "key", key, "error", err)
+	}
+}
+
 // AddAgent adds a new agent to the service
 func (s *MultiAgentService) AddAgent(agent multiagent.Agent) error {
 	// Check if agent already exists
@@ -613,12 +1329,12 @@ func (s *MultiAgentService) AddAgent(agent multiagent.Agent) error {
 // AddTool adds a new tool to the service
 func (s *MultiAgentService) AddTool(tool multiagent.Tool) error {
 	// Check if tool already exists
-	if _, exists := s.tools[tool.Name()]; exists {
+	if _, exists := s.tools[tool.Name()]; exists || tool.Name() == "memory" || tool.Name() == "task" || tool.Name() == "notes" {
 		return fmt.Errorf("tool with name %s already exists", tool.Name())
 	}
 
 	// Add to tools map
-	s.tools[tool.Name()] = tool
+	s.tools[tool.Name()] = progress.WrapTool(tool)
 
 	return nil
 }
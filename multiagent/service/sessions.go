@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+const (
+	// transcriptPrefix holds each conversation's transcript, in its user's
+	// memory partition
+	transcriptPrefix = "transcript:"
+	// pendingRequestPrefix journals user messages that are still being
+	// answered, so a restart can finish them
+	pendingRequestPrefix = "session:pending:"
+	// maxTranscriptEntries bounds how much history a transcript keeps
+	maxTranscriptEntries = 500
+	// maxPendingRequestAge is how old an unanswered request may be and still
+	// be resumed after a restart
+	maxPendingRequestAge = time.Hour
+)
+
+// TranscriptEntry is one turn of a conversation as the user saw it
+type TranscriptEntry struct {
+	Role      string    `json:"role"` // "user" or "assistant"
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PendingRequest is a user message whose reply has not been delivered yet
+type PendingRequest struct {
+	ID             string    `json:"id"`
+	UserID         string    `json:"user_id"`
+	ConversationID string    `json:"conversation_id"`
+	Message        string    `json:"message"`
+	ReceivedAt     time.Time `json:"received_at"`
+}
+
+// ConversationHistory is a conversation's transcript plus the requests still
+// being answered
+type ConversationHistory struct {
+	ConversationID string            `json:"conversation_id"`
+	UserID         string            `json:"user_id"`
+	Messages       []TranscriptEntry `json:"messages"`
+	Pending        []PendingRequest  `json:"pending"`
+}
+
+// ConversationHistory returns the conversation ProcessUserMessage keeps for
+// userID, so a client can pick up where it left off
+func (s *MultiAgentService) ConversationHistory(ctx context.Context, userID string) (*ConversationHistory, error) {
+	conversationID := conversationIDForUser(userID)
+	ctx = multiagent.WithUserID(ctx, userID)
+
+	history := &ConversationHistory{ConversationID: conversationID, UserID: userID, Messages: []TranscriptEntry{}, Pending: []PendingRequest{}}
+	value, err := s.userMemory.Get(ctx, transcriptPrefix+conversationID)
+	if err != nil && !strings.Contains(err.Error(), "not found") {
+		return nil, fmt.Errorf("failed to load transcript: %w", err)
+	}
+	if err == nil {
+		if err := decodeJSON(value, &history.Messages); err != nil {
+			return nil, fmt.Errorf("failed to decode transcript: %w", err)
+		}
+	}
+
+	pending, err := s.journaledRequests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, request := range pending {
+		if request.UserID == userID {
+			history.Pending = append(history.Pending, request)
+		}
+	}
+	return history, nil
+}
+
+// appendTranscript adds a turn to a conversation's transcript
+func (s *MultiAgentService) appendTranscript(ctx context.Context, conversationID, role, content string) {
+	entry := TranscriptEntry{Role: role, Content: content, Timestamp: s.now()}
+	key := transcriptPrefix + conversationID
+	err := s.userMemory.Update(ctx, key, func(current interface{}) (interface{}, error) {
+		var entries []TranscriptEntry
+		if current != nil {
+			if err := decodeJSON(current, &entries); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+		if len(entries) > maxTranscriptEntries {
+			entries = entries[len(entries)-maxTranscriptEntries:]
+		}
+		return entries, nil
+	})
+	if err != nil && strings.Contains(err.Error(), "not found") {
+		err = s.userMemory.Store(ctx, key, []TranscriptEntry{entry})
+	}
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to update transcript", "role", role, "error", err)
+	}
+}
+
+// trackPending journals a request until it is answered
+func (s *MultiAgentService) trackPending(ctx context.Context, request PendingRequest) {
+	if err := s.memoryStore.Store(ctx, pendingRequestPrefix+request.ID, request); err != nil {
+		logger.WarnContext(ctx, "Failed to journal pending request", "request_id", request.ID, "error", err)
+	}
+}
+
+// finishPending removes a request from the journal
+func (s *MultiAgentService) finishPending(ctx context.Context, requestID string) {
+	// The request's own context may already be done
+	ctx = context.WithoutCancel(ctx)
+	if err := s.memoryStore.Delete(ctx, pendingRequestPrefix+requestID); err != nil && !strings.Contains(err.Error(), "not found") {
+		logger.WarnContext(ctx, "Failed to clear pending request", "request_id", requestID, "error", err)
+	}
+}
+
+// journaledRequests returns every journaled request, oldest first
+func (s *MultiAgentService) journaledRequests(ctx context.Context) ([]PendingRequest, error) {
+	keys, err := s.memoryStore.List(ctx, pendingRequestPrefix, 10000)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending requests: %w", err)
+	}
+	values, err := s.memoryStore.GetMultiple(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending requests: %w", err)
+	}
+
+	requests := make([]PendingRequest, 0, len(values))
+	for _, value := range values {
+		var request PendingRequest
+		if err := decodeJSON(value, &request); err != nil || request.ID == "" {
+			continue
+		}
+		requests = append(requests, request)
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].ReceivedAt.Before(requests[j].ReceivedAt) })
+	return requests, nil
+}
+
+// resumePendingRequests finishes requests a previous run accepted but never
+// answered; replies land in the conversation's transcript for the user to
+// find in their history
+func (s *MultiAgentService) resumePendingRequests(ctx context.Context) {
+	requests, err := s.journaledRequests(ctx)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to load pending requests", "error", err)
+		return
+	}
+
+	for _, request := range requests {
+		if age := s.now().Sub(request.ReceivedAt); age > maxPendingRequestAge {
+			logger.InfoContext(ctx, "Dropping stale pending request", "request_id", request.ID, logging.KeyUserID, request.UserID, "age", age.Round(time.Second))
+			s.finishPending(ctx, request.ID)
+			continue
+		}
+		logger.InfoContext(ctx, "Resuming pending request", "request_id", request.ID, logging.KeyUserID, request.UserID)
+		go s.resumeRequest(ctx, request)
+	}
+}
+
+func (s *MultiAgentService) resumeRequest(ctx context.Context, request PendingRequest) {
+	ctx = logging.WithFields(ctx, logging.KeyConversationID, request.ConversationID, logging.KeyUserID, request.UserID)
+	ctx = multiagent.WithUserID(ctx, request.UserID)
+
+	response, err := s.processUserMessage(ctx, request.UserID, request.ConversationID, request.Message)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			logger.WarnContext(ctx, "Failed to resume pending request", "request_id", request.ID, "error", err)
+			s.finishPending(ctx, request.ID)
+		}
+		return
+	}
+	s.appendTranscript(ctx, request.ConversationID, "assistant", response)
+	s.finishPending(ctx, request.ID)
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kbutz/wikillm/multiagent/agents"
+)
+
+// ExportTasks returns the tasks of the user ctx acts for in format, one of
+// agents.TaskFormatTodoist and agents.TaskFormatCSV
+func (s *MultiAgentService) ExportTasks(ctx context.Context, format string) ([]byte, error) {
+	exchanger, err := s.taskExchanger()
+	if err != nil {
+		return nil, err
+	}
+	return exchanger.ExportTasks(ctx, format)
+}
+
+// ImportTasks adds the tasks in a Todoist export or CSV file to the task
+// list of the user ctx acts for, updating tasks imported before
+func (s *MultiAgentService) ImportTasks(ctx context.Context, format string, data []byte) (*agents.TaskImportResult, error) {
+	exchanger, err := s.taskExchanger()
+	if err != nil {
+		return nil, err
+	}
+	return exchanger.ImportTasks(ctx, format, data)
+}
+
+// taskExchanger returns the agent that owns the task list
+func (s *MultiAgentService) taskExchanger() (agents.TaskExchanger, error) {
+	for _, agent := range s.agents {
+		if exchanger, ok := agent.(agents.TaskExchanger); ok {
+			return exchanger, nil
+		}
+	}
+	return nil, fmt.Errorf("no agent manages tasks")
+}
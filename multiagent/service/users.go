@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+)
+
+// userRegistryPrefix holds one UserInfo per user that has sent a message;
+// it lives in the shared keyspace so admins can list every user
+const userRegistryPrefix = "user_registry:"
+
+// UserInfo describes a user the assistant has talked to
+type UserInfo struct {
+	ID        string    `json:"id"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Messages  int       `json:"messages"`
+}
+
+// PurgeResult reports what PurgeUser removed
+type PurgeResult struct {
+	UserID        string `json:"user_id"`
+	MemoryEntries int    `json:"memory_entries"`
+	AgentEntities int    `json:"agent_entities"`
+}
+
+// recordUser notes that userID sent a message
+func (s *MultiAgentService) recordUser(ctx context.Context, userID string) {
+	key := userRegistryPrefix + userID
+	now := s.now()
+	err := s.memoryStore.Update(ctx, key, func(current interface{}) (interface{}, error) {
+		info := UserInfo{ID: userID, FirstSeen: now}
+		if current != nil {
+			if err := decodeJSON(current, &info); err != nil {
+				return nil, err
+			}
+		}
+		info.LastSeen = now
+		info.Messages++
+		return info, nil
+	})
+	if err != nil && strings.Contains(err.Error(), "not found") {
+		err = s.memoryStore.Store(ctx, key, UserInfo{ID: userID, FirstSeen: now, LastSeen: now, Messages: 1})
+	}
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to record user", logging.KeyUserID, userID, "error", err)
+	}
+}
+
+// ListUsers returns every user the assistant has talked to, most recently
+// active first
+func (s *MultiAgentService) ListUsers(ctx context.Context) ([]UserInfo, error) {
+	keys, err := s.memoryStore.List(ctx, userRegistryPrefix, 10000)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	values, err := s.memoryStore.GetMultiple(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load users: %w", err)
+	}
+
+	users := make([]UserInfo, 0, len(values))
+	for _, value := range values {
+		var info UserInfo
+		if err := decodeJSON(value, &info); err != nil || info.ID == "" {
+			continue
+		}
+		users = append(users, info)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].LastSeen.After(users[j].LastSeen) })
+	return users, nil
+}
+
+// PurgeUser deletes everything stored for userID: their memory partition,
+// the agents' in-memory copies of their tasks, events, contacts, projects
+// and research, and their registry entry
+func (s *MultiAgentService) PurgeUser(ctx context.Context, userID string) (*PurgeResult, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID is required")
+	}
+
+	purged, err := memory.PurgeUser(ctx, s.memoryStore, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge memory for user %s: %w", userID, err)
+	}
+	result := &PurgeResult{UserID: userID, MemoryEntries: purged}
+
+	for _, agent := range s.agents {
+		if purger, ok := agent.(agents.UserDataPurger); ok {
+			result.AgentEntities += purger.PurgeUser(userID)
+		}
+	}
+	result.AgentEntities += s.reminderEngine.PurgeUser(userID)
+	if orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator); ok {
+		orch.ForgetUser(userID)
+	}
+
+	pending, err := s.journaledRequests(ctx)
+	if err != nil {
+		return result, err
+	}
+	for _, request := range pending {
+		if request.UserID == userID {
+			s.finishPending(ctx, request.ID)
+		}
+	}
+
+	if err := s.memoryStore.Delete(ctx, userRegistryPrefix+userID); err != nil && !strings.Contains(err.Error(), "not found") {
+		return result, fmt.Errorf("failed to remove user %s from the registry: %w", userID, err)
+	}
+
+	logger.InfoContext(ctx, "Purged user", logging.KeyUserID, userID, "memory_entries", result.MemoryEntries, "agent_entities", result.AgentEntities)
+	return result, nil
+}
+
+// userContext returns ctx acting for the owner of conversationID when ctx
+// does not already act for a user
+func (s *MultiAgentService) userContext(ctx context.Context, conversationID string) context.Context {
+	if multiagent.UserIDFromContext(ctx) != "" {
+		return ctx
+	}
+	if orch, ok := s.orchestrator.(*orchestrator.DefaultOrchestrator); ok {
+		return multiagent.WithUserID(ctx, orch.UserForConversation(conversationID))
+	}
+	return ctx
+}
+
+// decodeJSON converts a value read back from memory into into
+func decodeJSON(value interface{}, into interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+	if err := json.Unmarshal(data, into); err != nil {
+		return fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+	return nil
+}
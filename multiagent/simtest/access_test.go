@@ -0,0 +1,159 @@
+package simtest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/access"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/search"
+	"github.com/kbutz/wikillm/multiagent/service"
+)
+
+func TestGuestsCannotDeleteTasks(t *testing.T) {
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "task", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent", "delete task buy milk").Reply(`{"intent": "delete_task", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "add_task", "confidence": 0.9}`)
+	llm.On("Extract task information").Reply(`{"title": "Buy milk", "priority": "medium"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		policy := access.DefaultPolicy()
+		policy.Users = map[string]string{"gus": access.RoleGuest}
+		config.Access = policy
+	}})
+
+	h.Send("gus", "add a task to buy milk")
+	h.Send("gus", "delete task buy milk")
+	answer := lastPrompt(llm, "synthesize responses")
+	if !strings.Contains(answer, "🚫 I can't delete the task 'Buy milk': access denied: users with the guest role may not delete_task.") {
+		t.Errorf("the guest was not told the deletion is not allowed:\n%s", answer)
+	}
+	tasks := h.Tasks("gus")
+	if len(tasks) != 1 || tasks[0].DeletedAt != nil {
+		t.Fatalf("gus's tasks %+v, want Buy milk kept", tasks)
+	}
+	denied := h.Audit(audit.Filter{Types: []audit.EventType{audit.AccessDenied}})
+	if len(denied) != 1 || denied[0].Actor != "task_manager_agent" || denied[0].Subject != tasks[0].ID || denied[0].Payload["role"] != access.RoleGuest {
+		t.Errorf("audited denials %+v", denied)
+	}
+
+	// Members may, once they confirm
+	h.Send("alice", "add a task to buy milk")
+	h.Send("alice", "delete task buy milk")
+	if proposed := h.Audit(audit.Filter{Types: []audit.EventType{audit.ActionProposed}}); len(proposed) != 1 {
+		t.Errorf("proposed actions %+v, want alice's deletion", proposed)
+	}
+}
+
+func TestGuestsCannotDeleteTemplatesMilestonesOrMonitors(t *testing.T) {
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents", "monitoring").Reply(`{"intents": [{"intent": "research", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into the intents", "check-in").Reply(`{"intents": [{"intent": "communication", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "project", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent", "check-in").Reply(`{"intent": "templates", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", "remove the beta milestone").Reply(`{"intent": "milestone", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "template", "confidence": 0.9}`)
+	llm.On("what to do with project templates").Reply(`{"action": "delete", "template": "launch"}`)
+	llm.On("what to do with its milestones").Reply(`{"project": "website", "action": "remove", "milestone": "beta"}`)
+	llm.On("Extract the message template request", "make a check-in").Reply(`{"action": "create", "name": "checkin", "content": "Hi {{first_name}}, any news?"}`)
+	llm.On("Extract the message template request", "delete the check-in").Reply(`{"action": "delete", "name": "checkin"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		policy := access.DefaultPolicy()
+		policy.Users = map[string]string{"gus": access.RoleGuest, "gil": access.RoleGuest, "gia": access.RoleGuest, "guy": access.RoleGuest}
+		config.Access = policy
+		config.ResearchMonitoring.Disabled = true
+	}})
+	for _, userID := range []string{"gus", "alice"} {
+		h.Seed(userID, "project_template:template_launch", &agents.ProjectTemplate{ID: "template_launch", Name: "launch", CreatedAt: SeededAt, UserID: userID})
+	}
+	storeProject(h, "gil", &agents.Project{
+		ID:         "project_site",
+		Name:       "Website relaunch",
+		Status:     agents.ProjectStatusActive,
+		Milestones: []agents.Milestone{{ID: "milestone_beta", Title: "Beta", Status: agents.MilestoneStatusPending}},
+	})
+	h.Seed("guy", "research_monitor:monitor_heat", &agents.ResearchMonitor{ID: "monitor_heat", Topic: "Heat pumps", Interval: 24 * time.Hour, CreatedAt: SeededAt, NextRun: SeededAt.Add(24 * time.Hour), UserID: "guy"})
+
+	denied := func(userID, message, want string) {
+		t.Helper()
+		h.Send(userID, message)
+		if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, want) {
+			t.Errorf("%s was not told %q:\n%s", userID, want, answer)
+		}
+	}
+	denied("gus", "delete the launch template", "🚫 I can't delete the template 'launch': access denied: users with the guest role may not delete_project_template.")
+	denied("gil", "remove the beta milestone from the website", "🚫 I can't remove the milestone 'beta': access denied: users with the guest role may not delete_milestone.")
+	h.Send("gia", "make a check-in template")
+	denied("gia", "delete the check-in template", "🚫 I can't delete the template 'checkin': access denied: users with the guest role may not delete_message_template.")
+	denied("guy", "stop monitoring heat pumps", "🚫 I can't stop monitoring Heat pumps: access denied: users with the guest role may not stop_research_monitor.")
+
+	if templates := h.values("gus", "project_template:"); len(templates) != 1 {
+		t.Errorf("gus's project templates %v, want launch kept", templates)
+	}
+	if project := findProject(t, h, "gil", "project_site"); len(project.Milestones) != 1 {
+		t.Errorf("gil's milestones %+v, want beta kept", project.Milestones)
+	}
+	if templates := h.values("gia", "message_template:"); len(templates) != 1 {
+		t.Errorf("gia's message templates %v, want checkin kept", templates)
+	}
+	if monitors, err := h.Service.ListResearchMonitors(h.Context("guy")); err != nil || len(monitors) != 1 {
+		t.Errorf("guy's monitors %+v (%v), want heat pumps kept", monitors, err)
+	}
+	actors := map[multiagent.AgentID]int{}
+	for _, event := range h.Audit(audit.Filter{Types: []audit.EventType{audit.AccessDenied}}) {
+		actors[event.Actor]++
+	}
+	if actors["project_manager_agent"] != 2 || actors["communication_manager_agent"] != 1 || actors["research_assistant_agent"] != 1 {
+		t.Errorf("audited denials by agent %v", actors)
+	}
+
+	// Members may
+	h.Send("alice", "delete the launch template")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "🗑️ Deleted template 'launch'.") {
+		t.Errorf("alice could not delete her template:\n%s", answer)
+	}
+}
+
+func TestAgentsOnlyCallAllowedTools(t *testing.T) {
+	web := searchFunc(func(query string) ([]search.Result, error) {
+		return []search.Result{{Title: "Heat pump blog", URL: "https://example.org/blog", Snippet: "Heat pumps", Source: search.SourceWeb}}, nil
+	})
+	wikipedia := searchFunc(func(query string) ([]search.Result, error) {
+		return []search.Result{{Title: "Heat pump", URL: "https://en.wikipedia.org/wiki/Heat_pump", Snippet: "A heat pump moves heat", Source: search.SourceWikipedia}}, nil
+	})
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "research", "confidence": 0.9}]}`)
+	llm.On("Extract research parameters").Reply(`{"topic": "Heat pumps", "query": "how do heat pumps work", "methodology": "quick"}`)
+	llm.On("Break this research question").Reply(`{"sub_queries": []}`)
+	llm.On("from the numbered sources below").Reply(`{"summary": "They move heat [1].", "findings": [{"finding": "Heat pumps move heat", "sources": [1]}]}`)
+	llm.On("synthesize responses from specialist agents").Reply("Research started.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Tools = []multiagent.Tool{
+			search.NewTool(search.WebToolName, "Search the web", web),
+			search.NewTool(search.WikipediaToolName, "Search Wikipedia", wikipedia),
+		}
+		policy := access.DefaultPolicy()
+		policy.Agents["research_assistant_agent"] = access.Rules{Tools: []string{search.WikipediaToolName}}
+		config.Access = policy
+	}})
+	ctx := h.Context("alice")
+
+	h.Send("alice", "research how heat pumps work")
+	var sessions []*agents.ResearchSession
+	h.WaitFor(func() bool {
+		sessions, _ = h.Service.ListResearch(ctx, agents.ResearchFilter{Status: agents.ResearchStatusCompleted})
+		return len(sessions) == 1
+	})
+	if sources := sessions[0].Sources; len(sources) != 1 || sources[0].Title != "Heat pump" {
+		t.Errorf("sources %+v, want only Wikipedia's", sources)
+	}
+	denied := h.Audit(audit.Filter{Types: []audit.EventType{audit.AccessDenied}})
+	if len(denied) != 1 || denied[0].Actor != "research_assistant_agent" || denied[0].Subject != search.WebToolName || denied[0].ConversationID != "conv_alice" {
+		t.Errorf("audited denials %+v, want the research assistant's web search", denied)
+	}
+}
@@ -0,0 +1,277 @@
+package simtest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/service"
+)
+
+func TestMessageTemplatesFillInTheRecipient(t *testing.T) {
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "communication", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "templates", "confidence": 0.9}`)
+	llm.On("Extract the message template request", "make a check-in").Reply(`{"action": "create", "name": "checkin", "category": "follow-up", "subject": "Checking in on {{topic}}", "content": "Hi {{first_name}}, any news on {{topic}}? {{signoff|Cheers}}"}`)
+	llm.On("Extract the message template request", "about the proposal").Reply(`{"action": "use", "name": "checkin", "recipient": "Bob", "values": "topic=the proposal"}`)
+	llm.On("Extract the message template request", "check in with Carol").Reply(`{"action": "use", "name": "checkin", "recipient": "Carol"}`)
+	llm.On("Extract the message template request", "which templates").Reply(`{"action": "list"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	storeContact(h, "alice", &agents.Contact{ID: "contact_bob", Name: "Bob Smith", Email: "bob@example.com", PreferredComm: agents.CommunicationMethodEmail})
+	storeContact(h, "alice", &agents.Contact{ID: "contact_carol", Name: "Carol Jones", Email: "carol@example.com", PreferredComm: agents.CommunicationMethodEmail})
+
+	h.Send("alice", "make a check-in template asking about a topic")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "📝 Saved template 'checkin'.") || !strings.Contains(answer, "🔤 Variables: topic, signoff (optional)") {
+		t.Errorf("the template was not saved with its variables:\n%s", answer)
+	}
+
+	h.Send("alice", "send Bob the check-in about the proposal")
+	answer := lastPrompt(llm, "synthesize responses")
+	if !strings.Contains(answer, "**To:** Bob Smith (bob@example.com)\n**Subject:** Checking in on the proposal\n\nHi Bob, any news on the proposal? Cheers") {
+		t.Errorf("the draft was not filled in for Bob:\n%s", answer)
+	}
+	if strings.Contains(answer, "Fill in") {
+		t.Errorf("a filled-in draft asked for more:\n%s", answer)
+	}
+
+	// Carol's draft leaves the topic to fill in
+	h.Send("alice", "check in with Carol using the template")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "Hi Carol, any news on [topic]? Cheers") || !strings.Contains(answer, "⚠️ Fill in: topic") {
+		t.Errorf("the missing topic was not flagged:\n%s", answer)
+	}
+
+	h.Send("alice", "which templates do I have?")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "**follow up**\n• checkin — used 2 time(s) — needs topic, signoff (optional)") {
+		t.Errorf("the template list does not count both uses:\n%s", answer)
+	}
+
+	var drafts []agents.CommunicationMessage
+	for _, value := range h.values("alice", "communication_message:") {
+		var message agents.CommunicationMessage
+		if decode(value, &message) == nil && message.Status == agents.MessageStatusDraft {
+			drafts = append(drafts, message)
+		}
+	}
+	if len(drafts) != 2 {
+		t.Errorf("stored drafts %+v, want one each for Bob and Carol", drafts)
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+}
+
+func TestFollowUpsCloseWhenTheReplyComes(t *testing.T) {
+	// Thursday morning
+	clock := ids.NewManualClock(time.Date(2026, 5, 7, 10, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "communication", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent", `Request: "I'm waiting`).Reply(`{"intent": "follow_up", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", `Request: "who owes me`).Reply(`{"intent": "follow_up", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", `Request: "Bob replied`).Reply(`{"intent": "log_inbound", "confidence": 0.9}`)
+	llm.On(`Extract the follow-up request from: "I'm waiting on`).Reply(`{"action": "track", "contact": "Bob", "subject": "the proposal", "since": "2026-05-04"}`)
+	llm.On(`Extract the follow-up request from: "I'm waiting to hear`).Reply(`{"action": "track", "contact": "Carol", "subject": "the venue"}`)
+	llm.On(`Extract the follow-up request from: "who owes me`).Reply(`{"action": "list"}`)
+	llm.On("Extract the message the user received").Reply(`{"contact": "Bob", "subject": "the proposal", "content": "Looks good, let's sign."}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	storeContact(h, "alice", &agents.Contact{ID: "contact_bob", Name: "Bob Smith", Email: "bob@example.com", PreferredComm: agents.CommunicationMethodEmail})
+	storeContact(h, "alice", &agents.Contact{ID: "contact_carol", Name: "Carol Jones", Email: "carol@example.com", PreferredComm: agents.CommunicationMethodEmail})
+
+	// Bob's reply is already overdue, so the reminder comes soon
+	h.Send("alice", "I'm waiting on a reply from Bob about the proposal since Monday")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "🔄 Waiting on a reply from Bob Smith about the proposal since Mon May 4.\n⏰ I'll remind you Thu May 7 11:00") {
+		t.Errorf("the overdue follow-up was not tracked:\n%s", answer)
+	}
+	h.Send("alice", "I'm waiting to hear from Carol about the venue")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "I'll remind you Sun May 10 09:00") {
+		t.Errorf("carol's follow-up does not wait three days:\n%s", answer)
+	}
+
+	h.Send("alice", "who owes me a reply?")
+	answer := lastPrompt(llm, "synthesize responses")
+	if !strings.Contains(answer, "🔄 **Follow-ups** (2 open, 1 stale)") ||
+		!strings.Contains(answer, "⚠️ Bob Smith about the proposal — waiting 3 day(s), since Mon May 4\n⏳ Carol Jones about the venue — waiting 0 day(s), since Thu May 7, reminder Sun May 10 09:00") {
+		t.Errorf("follow-ups are not listed longest waiting first:\n%s", answer)
+	}
+
+	h.Send("alice", "Bob replied, he says the proposal looks good")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "📥 Logged a message from Bob Smith.\n✅ Closed follow-up(s): Bob Smith about the proposal") {
+		t.Errorf("bob's reply did not close the follow-up:\n%s", answer)
+	}
+	h.Send("alice", "who owes me a reply now?")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "(1 open, 0 stale)") || strings.Contains(answer, "Bob Smith") {
+		t.Errorf("the closed follow-up is still listed:\n%s", answer)
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+}
+
+func TestCadencesSuggestWhoToReconnectWith(t *testing.T) {
+	// Monday morning, before the daily reconnect check
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 8, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "communication", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "relationships", "confidence": 0.9}`)
+	llm.On(`Extract the relationship request from: "who should`).Reply(`{"action": "suggest"}`)
+	llm.On(`Extract the relationship request from: "stay in touch with my mentors`).Reply(`{"action": "set_cadence", "relationship": "mentors", "frequency": "monthly"}`)
+	llm.On(`Extract the relationship request from: "keep in touch with Sam`).Reply(`{"action": "set_cadence", "contact": "Sam", "frequency": "weekly"}`)
+	llm.On(`Extract the relationship request from: "write to Jane`).Reply(`{"action": "draft", "contact": "Jane"}`)
+	llm.On("Write a short, warm message to reconnect with Jane Doe").Reply("Hi Jane, it's been a while — coffee soon?")
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	janeLast := time.Date(2026, 3, 20, 12, 0, 0, 0, time.UTC)
+	samLast := time.Date(2026, 4, 28, 12, 0, 0, 0, time.UTC)
+	storeContact(h, "alice", &agents.Contact{ID: "contact_jane", Name: "Jane Doe", Email: "jane@example.com", Relationship: agents.RelationshipTypeMentor, LastContact: &janeLast, PreferredComm: agents.CommunicationMethodEmail})
+	storeContact(h, "alice", &agents.Contact{ID: "contact_sam", Name: "Sam Lee", Email: "sam@example.com", Relationship: agents.RelationshipTypeMentor, LastContact: &samLast, PreferredComm: agents.CommunicationMethodEmail})
+	storeContact(h, "alice", &agents.Contact{ID: "contact_bob", Name: "Bob Smith", Email: "bob@example.com", Relationship: agents.RelationshipTypeClient, PreferredComm: agents.CommunicationMethodEmail})
+
+	h.Send("alice", "who should I reconnect with?")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "None of your contacts has a stay-in-touch cadence yet") {
+		t.Errorf("suggested reconnects without any cadence:\n%s", answer)
+	}
+
+	h.Send("alice", "stay in touch with my mentors monthly")
+	answer := lastPrompt(llm, "synthesize responses")
+	if !strings.Contains(answer, "🤝 I'll keep you in touch with your mentors (Jane Doe, Sam Lee) monthly.") ||
+		!strings.Contains(answer, "next on Mon May 4 at 09:00") {
+		t.Errorf("the mentors' cadence was not set:\n%s", answer)
+	}
+	for _, value := range h.values("alice", "contact:") {
+		var contact agents.Contact
+		if decode(value, &contact) != nil {
+			continue
+		}
+		if want := map[string]agents.ContactFrequency{"contact_jane": agents.ContactFrequencyMonthly, "contact_sam": agents.ContactFrequencyMonthly}[contact.ID]; contact.ContactFreq != want {
+			t.Errorf("%s keeps in touch %q, want %q", contact.Name, contact.ContactFreq, want)
+		}
+	}
+
+	// Only Jane is due; Sam isn't due for weeks
+	h.Send("alice", "who should I reconnect with?")
+	answer = lastPrompt(llm, "synthesize responses")
+	if !strings.Contains(answer, "**Overdue**\n• Jane Doe (mentor) — monthly, last contact 44 day(s) ago, 14 day(s) overdue") {
+		t.Errorf("jane is not overdue:\n%s", answer)
+	}
+	if strings.Contains(answer, "Sam Lee") || strings.Contains(answer, "Bob Smith") {
+		t.Errorf("suggested a contact who isn't due:\n%s", answer)
+	}
+
+	h.Send("alice", "keep in touch with Sam weekly")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "🤝 I'll keep you in touch with Sam Lee weekly.\n\nSam Lee (mentor) — weekly, last contact 5 day(s) ago, due Tue May 5.") {
+		t.Errorf("sam's cadence was not set:\n%s", answer)
+	}
+	h.Send("alice", "who should I reconnect with now?")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "**Coming up this week**\n• Sam Lee (mentor) — weekly, last contact 5 day(s) ago, due Tue May 5") {
+		t.Errorf("sam is not coming up this week:\n%s", answer)
+	}
+
+	h.Send("alice", "write to Jane to reconnect")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "**To:** Jane Doe (jane@example.com)\n**Subject:** Catching up\n\nHi Jane, it's been a while — coffee soon?") {
+		t.Errorf("no reconnect message was drafted:\n%s", answer)
+	}
+	cadences := h.Audit(audit.Filter{Types: []audit.EventType{audit.ContactCadenceSet}})
+	if len(cadences) != 3 {
+		t.Errorf("audited cadences %+v, want both mentors then Sam", cadences)
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+}
+
+func TestScheduledMessagesWaitOutQuietHours(t *testing.T) {
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "communication", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "schedule_message", "confidence": 0.9}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	storeContact(h, "alice", &agents.Contact{ID: "contact_bob", Name: "Bob Smith", Email: "bob@example.com", PreferredComm: agents.CommunicationMethodEmail})
+	draft := &agents.CommunicationMessage{
+		ID:        "msg_1001",
+		ContactID: "contact_bob",
+		Subject:   "Proposal",
+		Content:   "Here's the proposal.",
+		Method:    agents.CommunicationMethodSlack,
+		Direction: agents.MessageDirectionOutbound,
+		Status:    agents.MessageStatusDraft,
+		CreatedAt: clock.Now(),
+		UpdatedAt: clock.Now(),
+		UserID:    "alice",
+	}
+	h.Seed("alice", "communication_message:"+draft.ID, draft)
+
+	h.Send("alice", "quiet hours for Bob 10pm to 7am America/New_York")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "⏰ Updated Bob Smith: quiet hours 22:00–07:00, timezone America/New_York.") {
+		t.Errorf("bob's quiet hours were not set:\n%s", answer)
+	}
+	h.Send("alice", "set Bob's webhook to https://hooks.example.com/bob")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "⏰ Updated Bob Smith: webhook.") {
+		t.Errorf("bob's webhook was not set:\n%s", answer)
+	}
+
+	// 9am for alice is 5am for Bob, so it waits until 7am his time
+	h.Send("alice", "schedule draft msg_1001 for tomorrow at 9am")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "⏰ Scheduled 'Proposal' to Bob Smith via their webhook for Tue May 5 at 11:00 (Tue 07:00 their time). 09:00 falls in Bob Smith's quiet hours (22:00–07:00), so it waits until they end.") {
+		t.Errorf("the message was not held until bob's quiet hours end:\n%s", answer)
+	}
+	h.Send("alice", "show scheduled messages")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "• msg_1001 — 'Proposal' to Bob Smith, Tue May 5 at 11:00 (Tue 07:00 their time)") {
+		t.Errorf("the queue does not list the message:\n%s", answer)
+	}
+
+	h.Send("alice", "edit scheduled msg_1001: Here's the revised proposal.")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "📝 Updated msg_1001:\n\nHere's the revised proposal.\n\nIt still goes out Tue May 5 at 11:00.") {
+		t.Errorf("the edit moved or lost the send time:\n%s", answer)
+	}
+
+	h.Send("alice", "cancel scheduled msg_1001")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "⏰ Cancelled sending 'Proposal'. It's a draft again (msg_1001).") {
+		t.Errorf("the message was not taken off the queue:\n%s", answer)
+	}
+	for _, value := range h.values("alice", "communication_message:") {
+		var message agents.CommunicationMessage
+		if decode(value, &message) == nil && message.ID == "msg_1001" &&
+			(message.Status != agents.MessageStatusDraft || message.ScheduledFor != nil || message.Content != "Here's the revised proposal.") {
+			t.Errorf("cancelled message is %s for %v with %q, want the edited draft", message.Status, message.ScheduledFor, message.Content)
+		}
+	}
+	h.Send("alice", "show scheduled messages")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "Nothing is scheduled to send.") {
+		t.Errorf("the cancelled message is still queued:\n%s", answer)
+	}
+	scheduled := h.Audit(audit.Filter{Types: []audit.EventType{audit.MessageScheduled, audit.MessageScheduleCancelled}})
+	if len(scheduled) != 3 || scheduled[0].Type != audit.MessageScheduleCancelled {
+		t.Errorf("audited queue changes %+v, want cancel, edit, schedule", scheduled)
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+}
+
+// storeContact stores contact in userID's address book
+func storeContact(h *Harness, userID string, contact *agents.Contact) {
+	h.t.Helper()
+	if contact.CreatedAt.IsZero() {
+		contact.CreatedAt = SeededAt
+		contact.UpdatedAt = contact.CreatedAt
+	}
+	if contact.Status == "" {
+		contact.Status = agents.ContactStatusActive
+	}
+	contact.UserID = userID
+	h.Seed(userID, "contact:"+contact.ID, contact)
+}
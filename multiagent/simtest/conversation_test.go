@@ -0,0 +1,45 @@
+package simtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLongConversationIsSummarizedAndRecallsDetails(t *testing.T) {
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "chat", "confidence": 0.9}]}`)
+	llm.On("You are summarizing a conversation").Reply("The user is planning a trip to Lisbon on a fixed budget.")
+	llm.On("conversation agent designed to help users").Reply("Noted.")
+	h := New(t, Config{LLM: llm})
+
+	h.Send("alice", "The budget for the Lisbon trip is 1800 euros, flights included.")
+	for i := 1; i <= 12; i++ {
+		h.Send("alice", fmt.Sprintf("Here is fun fact number %d about Lisbon.", i))
+	}
+	h.Send("alice", "What exactly did I say about the budget?")
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+
+	summaries := llm.CallsContaining("You are summarizing a conversation")
+	if len(summaries) != 1 || !strings.Contains(summaries[0].Prompt, "1800 euros") {
+		t.Fatalf("summary prompts %+v, want one covering the budget", summaries)
+	}
+
+	replies := llm.CallsContaining("conversation agent designed to help users")
+	last := replies[len(replies)-1].Prompt
+	recalled, history, _ := strings.Cut(last, "Conversation history:")
+	if !strings.Contains(last, "Summary of the earlier conversation:\nThe user is planning a trip to Lisbon") {
+		t.Errorf("reply prompt lacks the summary:\n%s", last)
+	}
+	if strings.Contains(history, "fun fact number 1 about") || !strings.Contains(history, "fun fact number 12") {
+		t.Errorf("reply prompt history is not the messages past the summary:\n%s", history)
+	}
+	if strings.Contains(history, "1800 euros") || !strings.Contains(recalled, "The budget for the Lisbon trip is 1800 euros") {
+		t.Errorf("reply prompt does not recall the summarized budget message:\n%s", last)
+	}
+	if strings.Contains(recalled, "fun fact") {
+		t.Errorf("reply prompt recalled messages unrelated to the question:\n%s", recalled)
+	}
+}
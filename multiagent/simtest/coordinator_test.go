@@ -0,0 +1,141 @@
+package simtest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent/progress"
+)
+
+func TestSynthesisSettlesConflictingSpecialists(t *testing.T) {
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents", "quarterly report").Reply(`{"intents": [{"intent": "schedule", "confidence": 0.9}, {"intent": "task", "confidence": 0.8}]}`)
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "task", "confidence": 0.9}]}`)
+	llm.On("Plan how specialist agents will handle").Reply(`{"goal": "Plan the report around tomorrow's calendar", "steps": [
+		{"id": "step_1", "agent": "scheduler", "instruction": "What is on my calendar tomorrow afternoon?"},
+		{"id": "step_2", "agent": "task_manager", "instruction": "Plan writing the quarterly report tomorrow"}
+	]}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "general", "confidence": 0.9}`)
+	llm.On("scheduling and calendar management specialist").Reply("Tomorrow you have the quarterly review from 2pm to 4pm.")
+	llm.On("personal task management specialist").Reply("I've planned writing the quarterly report for tomorrow from 2pm to 4pm.")
+	llm.On("Check whether their responses contradict").Reply(`{"conflicts": [
+		{"agents": ["scheduler_agent", "task_manager_agent", "someone_else"], "description": "The report is planned for 2-4pm tomorrow, when the quarterly review is booked", "resolution": "Write the report in the morning instead"},
+		{"agents": ["scheduler_agent"], "description": " "}
+	]}`)
+	llm.On("synthesize responses from specialist agents").Reply("Your afternoon is taken by the quarterly review, so write the report in the morning.")
+	h := New(t, Config{LLM: llm})
+
+	reply := h.Send("alice", "when can I write the quarterly report tomorrow?")
+	if reply != "Your afternoon is taken by the quarterly review, so write the report in the morning." {
+		t.Errorf("reply %q", reply)
+	}
+	check := lastPrompt(llm, "Check whether their responses contradict")
+	if !strings.Contains(check, "--- scheduler_agent ---\nTomorrow you have the quarterly review") || !strings.Contains(check, "--- task_manager_agent ---\nI've planned") {
+		t.Errorf("conflict check does not hold both responses:\n%s", check)
+	}
+	synthesis := lastPrompt(llm, "synthesize responses")
+	if !strings.Contains(synthesis, "These responses contradict each other:\n- The report is planned for 2-4pm tomorrow, when the quarterly review is booked To settle it: Write the report in the morning instead\n\n") {
+		t.Errorf("synthesis prompt does not ask to settle the conflict:\n%s", synthesis)
+	}
+
+	// A single specialist's answer has nothing to contradict
+	h.Send("alice", "add a task to file my expenses")
+	if checks := llm.CallsContaining("Check whether their responses contradict"); len(checks) != 1 {
+		t.Errorf("responses checked for conflicts %d times, want only for the request two specialists answered", len(checks))
+	}
+	if synthesis := lastPrompt(llm, "synthesize responses"); strings.Contains(synthesis, "contradict each other") {
+		t.Errorf("single response synthesized as conflicting:\n%s", synthesis)
+	}
+}
+
+func TestPlanStepsBuildOnEarlierResults(t *testing.T) {
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "schedule", "confidence": 0.9}, {"intent": "task", "confidence": 0.8}]}`)
+	llm.On("Plan how specialist agents will handle", "next Friday").Reply(`{"goal": "Fit the report into next Friday", "steps": [
+		{"id": "step_1", "agent": "task", "instruction": "What else is due next Friday?"},
+		{"id": "step_2", "agent": "scheduler", "instruction": "When am I free next Friday?"},
+		{"id": "step_3", "agent": "task", "instruction": "Plan writing the report around next Friday's free time", "depends_on": ["step_2"]}
+	]}`)
+	llm.On("Plan how specialist agents will handle").Reply(`{"goal": "Fit the report into Friday", "steps": [
+		{"id": "step_1", "agent": "scheduler", "instruction": "When am I free on Friday?", "output": "free time on Friday"},
+		{"id": "step_2", "agent": "task", "instruction": "Plan writing the report in that free time", "depends_on": ["step_1"]}
+	]}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "general", "confidence": 0.9}`)
+	llm.On("scheduling and calendar management specialist", "next Friday").Fail(errors.New("calendar unavailable"))
+	llm.On("scheduling and calendar management specialist").Reply("Friday from 1pm to 5pm is free.")
+	llm.On("personal task management specialist", "What else is due").Reply("Nothing else is due next Friday.")
+	llm.On("personal task management specialist").Reply("Write the report Friday from 1pm to 3pm.")
+	llm.On("Check whether their responses contradict").Reply(`{"conflicts": []}`)
+	llm.On("synthesize responses from specialist agents").Reply("Write the report Friday afternoon.")
+	h := New(t, Config{LLM: llm})
+
+	var mu sync.Mutex
+	var events []progress.Event
+	stream, unsubscribe := h.Service.SubscribeProgress("alice")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range stream {
+			mu.Lock()
+			events = append(events, event)
+			mu.Unlock()
+		}
+	}()
+
+	h.Send("alice", "fit writing the report into my Friday")
+	// The task manager plans with the scheduler's answer
+	step := lastPrompt(llm, "personal task management specialist")
+	if !strings.Contains(step, "Plan writing the report in that free time\n\nThis is part of handling the user's request: \"fit writing the report into my Friday\"") ||
+		!strings.Contains(step, "Result of step_1 (When am I free on Friday?):\nFriday from 1pm to 5pm is free.") {
+		t.Errorf("the second step did not get the first step's result:\n%s", step)
+	}
+	if synthesis := lastPrompt(llm, "synthesize responses"); !strings.Contains(synthesis, "Write the report Friday from 1pm to 3pm.") {
+		t.Errorf("the plan's results were not synthesized:\n%s", synthesis)
+	}
+
+	// A step whose dependency failed is skipped rather than run blind
+	h.Send("alice", "fit writing the report into next Friday")
+	for _, call := range llm.CallsContaining("personal task management specialist") {
+		if strings.Contains(call.Prompt, "around next Friday's free time") {
+			t.Errorf("the task manager planned without the scheduler's answer:\n%s", call.Prompt)
+		}
+	}
+	synthesis := lastPrompt(llm, "synthesize responses")
+	if !strings.Contains(synthesis, "--- step_1 (task, completed): What else is due next Friday? ---\nNothing else is due next Friday.") ||
+		!strings.Contains(synthesis, "--- step_3 (task, skipped): Plan writing the report around next Friday's free time ---\nstep_2 did not complete") {
+		t.Errorf("the synthesis does not say which steps were done:\n%s", synthesis)
+	}
+
+	h.WaitFor(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		completed := 0
+		for _, event := range events {
+			if event.Type == progress.Completed {
+				completed++
+			}
+		}
+		return completed == 2
+	})
+	unsubscribe()
+	<-done
+	var plans int
+	var finished []string
+	for _, event := range events {
+		switch event.Type {
+		case progress.PlanCreated:
+			plans++
+		case progress.StepFinished:
+			finished = append(finished, fmt.Sprintf("%v %v", event.Data["step"], event.Data["status"]))
+		}
+	}
+	if want := []string{"step_1 completed", "step_2 completed", "step_1 completed", "step_2 failed", "step_3 skipped"}; plans != 2 || strings.Join(finished, ", ") != strings.Join(want, ", ") {
+		t.Errorf("%d plans with steps finished %q, want 2 plans with %q", plans, finished, want)
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+}
@@ -0,0 +1,53 @@
+package simtest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+func TestAssignedIssueBecomesFollowUpTask(t *testing.T) {
+	h := New(t, Config{})
+
+	submit := func(number int, title string) {
+		t.Helper()
+		reference := fmt.Sprintf("octo/app#%d", number)
+		err := h.Service.SubmitEvent(&multiagent.Event{
+			Type:   multiagent.EventExternalIssueAssigned,
+			Source: "github",
+			Data: map[string]interface{}{
+				multiagent.ContextUserID: "alice",
+				"title":                  title,
+				"url":                    fmt.Sprintf("https://github.com/octo/app/issues/%d", number),
+				"reference":              reference,
+				"external_id":            "github:" + reference,
+			},
+		})
+		if err != nil {
+			t.Fatalf("SubmitEvent: %v", err)
+		}
+	}
+
+	submit(12, "Login fails")
+	h.WaitFor(func() bool { return len(h.Tasks("alice")) == 1 })
+	task := h.Tasks("alice")[0]
+	if task.Title != "Follow up on octo/app#12: Login fails" || task.Description != "https://github.com/octo/app/issues/12" {
+		t.Errorf("unexpected task %+v", task)
+	}
+	if len(task.Tags) != 1 || task.Tags[0] != "github" {
+		t.Errorf("task tags %v, want the source", task.Tags)
+	}
+
+	// Assigning the issue again adds no second task; events are handled
+	// in order, so once the next issue's task exists the repeat was seen
+	submit(12, "Login fails")
+	submit(13, "Logout fails")
+	h.WaitFor(func() bool { return len(h.Writes("personal_task:")) == 2 })
+	if tasks := h.Tasks("alice"); len(tasks) != 2 {
+		t.Errorf("alice has %d tasks, want one per issue", len(tasks))
+	}
+	if tasks := h.Tasks("bob"); len(tasks) != 0 {
+		t.Errorf("bob got alice's follow-up: %+v", tasks)
+	}
+}
@@ -0,0 +1,85 @@
+package simtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/notify"
+)
+
+func TestFactsAreExtractedAndUsedOnceApproved(t *testing.T) {
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "chat", "confidence": 0.9}]}`)
+	llm.On("Find durable facts about the user").Reply(`{"facts": [
+		{"kind": "name", "statement": "Sister is named Maya", "message": 0},
+		{"kind": "preference", "statement": "Prefers meetings in the morning", "message": 0},
+		{"kind": "date", "statement": "Likes the assistant's jokes", "message": 1},
+		{"kind": "mood", "statement": "Is happy today", "message": 0}
+	]}`)
+	llm.On("conversation agent designed to help users").Reply("Nice to hear about Maya.")
+	h := New(t, Config{LLM: llm})
+	ctx := context.Background()
+
+	h.Send("alice", "My sister Maya is visiting, and I really prefer meetings in the morning.")
+	facts, err := h.Service.ExtractFacts(ctx, "alice")
+	if err != nil {
+		t.Fatalf("ExtractFacts: %v", err)
+	}
+	// Facts from the assistant's message or of unknown kinds are dropped
+	if len(facts) != 2 || facts[0].Statement != "Sister is named Maya" || facts[0].Status != memory.FactPending {
+		t.Fatalf("extracted facts %+v, want the two the user stated, pending", facts)
+	}
+	if source := facts[0].Source; source.ConversationID != "conv_alice" || !strings.Contains(source.Quote, "My sister Maya") {
+		t.Errorf("fact source %+v, want alice's message", source)
+	}
+	if notes := h.Notifications(); len(notes) != 1 || notes[0].Kind != notify.KindFactReview || !strings.Contains(notes[0].Body, "Sister is named Maya") {
+		t.Errorf("notifications %+v, want one asking to review the facts", notes)
+	}
+
+	// Pending facts stay out of the agents' prompts
+	h.Send("alice", "Any ideas for the weekend?")
+	if prompt := lastPrompt(llm, "conversation agent designed"); strings.Contains(prompt, "Sister is named Maya") {
+		t.Errorf("pending fact reached the reply prompt:\n%s", prompt)
+	}
+
+	if _, err := h.Service.ReviewFact(ctx, "alice", facts[0].ID, true); err != nil {
+		t.Fatalf("ReviewFact: %v", err)
+	}
+	if _, err := h.Service.ReviewFact(ctx, "alice", facts[1].ID, false); err != nil {
+		t.Fatalf("ReviewFact: %v", err)
+	}
+	h.Send("alice", "What should we do on Saturday?")
+	prompt := lastPrompt(llm, "conversation agent designed")
+	if !strings.Contains(prompt, "- Sister is named Maya") || strings.Contains(prompt, "Prefers meetings") {
+		t.Errorf("reply prompt does not hold just the approved fact:\n%s", prompt)
+	}
+
+	// Only messages since the last pass are mined, and known facts, even
+	// rejected ones, are not proposed again
+	again, err := h.Service.ExtractFacts(ctx, "alice")
+	if err != nil {
+		t.Fatalf("ExtractFacts: %v", err)
+	}
+	if len(again) != 0 {
+		t.Errorf("second pass extracted %+v, want nothing new", again)
+	}
+	extractions := llm.CallsContaining("Find durable facts")
+	last := extractions[len(extractions)-1].Prompt
+	if len(extractions) != 2 || strings.Contains(last, "My sister Maya is visiting") || !strings.Contains(last, "- Prefers meetings in the morning") {
+		t.Errorf("second extraction prompt:\n%s", last)
+	}
+	if pending, _ := h.Service.ListFacts(ctx, "alice", memory.FactPending); len(pending) != 0 {
+		t.Errorf("pending facts %+v, want none after review", pending)
+	}
+}
+
+// lastPrompt returns the latest prompt containing s
+func lastPrompt(llm *ScriptedLLM, s string) string {
+	calls := llm.CallsContaining(s)
+	if len(calls) == 0 {
+		return ""
+	}
+	return calls[len(calls)-1].Prompt
+}
@@ -0,0 +1,294 @@
+// Package simtest runs the multi-agent service against a scripted LLM and an
+// in-memory store, so tests can send user messages and assert on the tasks,
+// calendar events, memory writes and routing that result.
+//
+// A test scripts the prompts its flow sends and inspects the outcome:
+//
+//	llm := simtest.NewScriptedLLM()
+//	llm.On("Classify the user's request").ReplyJSON(...)
+//	llm.Default("Done.")
+//	h := simtest.New(t, simtest.Config{LLM: llm})
+//	reply := h.Send("alice", "add a task to buy milk")
+//	tasks := h.Tasks("alice")
+package simtest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/service"
+)
+
+// defaultTimeout bounds each Send and WaitFor
+const defaultTimeout = 10 * time.Second
+
+// SeededAt is the creation time to give records a test seeds without one,
+// so they predate the flow under test
+var SeededAt = time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+
+// Config holds configuration for creating a Harness
+type Config struct {
+	// LLM answers every agent's prompts (default a ScriptedLLM without
+	// rules, failing every prompt)
+	LLM *ScriptedLLM
+	// Timeout bounds each Send and WaitFor (default 10s)
+	Timeout time.Duration
+	// Configure, if set, adjusts the service configuration before the
+	// service is created, e.g. to set Confirmations
+	Configure func(*service.ServiceConfig)
+}
+
+// Harness is a started multi-agent service wired for a test
+type Harness struct {
+	// Service is the service under test
+	Service *service.MultiAgentService
+	// LLM answers the agents' prompts
+	LLM *ScriptedLLM
+	// Store is the memory store under every agent
+	Store *RecordingStore
+
+	t             testing.TB
+	timeout       time.Duration
+	notifications *notificationRecorder
+}
+
+// Route is a message the orchestrator routed
+type Route struct {
+	From multiagent.AgentID
+	To   []multiagent.AgentID
+	Type multiagent.MessageType
+}
+
+// New creates and starts a service for the test, stopping it when the test
+// ends. Briefings are off, notifications are recorded instead of sent and
+// IDs are numbered per prefix, so a flow names its records the same way
+// every run.
+func New(t testing.TB, config Config) *Harness {
+	t.Helper()
+	if config.LLM == nil {
+		config.LLM = NewScriptedLLM()
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	h := &Harness{
+		LLM:           config.LLM,
+		Store:         NewRecordingStore(),
+		t:             t,
+		timeout:       config.Timeout,
+		notifications: &notificationRecorder{},
+	}
+	serviceConfig := service.ServiceConfig{
+		BaseDir:       t.TempDir(),
+		LLMProvider:   config.LLM,
+		MemoryStore:   h.Store,
+		Notifications: notify.DispatcherConfig{Default: []notify.Channel{h.notifications}},
+		Briefings:     service.BriefingConfig{Disabled: true},
+		IDs:           ids.NewSequence(),
+	}
+	if config.Configure != nil {
+		config.Configure(&serviceConfig)
+	}
+
+	svc, err := service.NewMultiAgentService(serviceConfig)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := svc.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("failed to start service: %v", err)
+	}
+	t.Cleanup(func() {
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer stopCancel()
+		if err := svc.Stop(stopCtx); err != nil {
+			t.Logf("failed to stop service: %v", err)
+		}
+		cancel()
+	})
+	h.Service = svc
+	return h
+}
+
+// Send sends message as userID and returns the reply, failing the test if
+// the service returns an error
+func (h *Harness) Send(userID, message string) string {
+	h.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	reply, err := h.Service.ProcessUserMessage(ctx, userID, message)
+	if err != nil {
+		h.t.Fatalf("message %q from %s failed: %v", message, userID, err)
+	}
+	return reply
+}
+
+// Seed stores value under key in userID's memory, as an agent acting for
+// userID would, failing the test if it cannot
+func (h *Harness) Seed(userID, key string, value interface{}) {
+	h.t.Helper()
+	if err := h.Service.GetMemoryStore().Store(h.Context(userID), key, value); err != nil {
+		h.t.Fatalf("failed to seed %s: %v", key, err)
+	}
+}
+
+// Context returns a context acting for userID, as agents see it during
+// that user's requests
+func (h *Harness) Context(userID string) context.Context {
+	return multiagent.WithUserID(context.Background(), userID)
+}
+
+// Tasks returns userID's personal tasks, oldest first
+func (h *Harness) Tasks(userID string) []*agents.PersonalTask {
+	h.t.Helper()
+	tasks, err := h.Service.ListTasks(h.Context(userID))
+	if err != nil {
+		h.t.Fatalf("failed to list tasks: %v", err)
+	}
+	return tasks
+}
+
+// Events returns userID's calendar events
+func (h *Harness) Events(userID string) []*agents.CalendarEvent {
+	h.t.Helper()
+	var events []*agents.CalendarEvent
+	for _, value := range h.values(userID, "calendar_event:") {
+		var event agents.CalendarEvent
+		if decode(value, &event) == nil && event.ID != "" {
+			events = append(events, &event)
+		}
+	}
+	return events
+}
+
+// Writes returns the memory writes to keys starting with prefix, oldest
+// first; keys are matched as agents wrote them, without the user's namespace
+func (h *Harness) Writes(prefix string) []Write {
+	return h.Store.Writes(prefix)
+}
+
+// Audit returns the audited actions matching filter, newest first
+func (h *Harness) Audit(filter audit.Filter) []audit.Event {
+	h.t.Helper()
+	events, err := h.Service.QueryAudit(context.Background(), filter)
+	if err != nil {
+		h.t.Fatalf("failed to query audit log: %v", err)
+	}
+	return events
+}
+
+// Routes returns the messages routed for userID's conversation, oldest
+// first
+func (h *Harness) Routes(userID string) []Route {
+	h.t.Helper()
+	events := h.Audit(audit.Filter{Types: []audit.EventType{audit.MessageSent}, ConversationID: conversationID(userID)})
+	routes := make([]Route, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		var payload struct {
+			To   []multiagent.AgentID   `json:"to"`
+			Type multiagent.MessageType `json:"type"`
+		}
+		decode(events[i].Payload, &payload)
+		routes = append(routes, Route{From: events[i].Actor, To: payload.To, Type: payload.Type})
+	}
+	return routes
+}
+
+// RoutedTo reports whether a message for userID's conversation was routed
+// to agentID
+func (h *Harness) RoutedTo(userID string, agentID multiagent.AgentID) bool {
+	h.t.Helper()
+	for _, route := range h.Routes(userID) {
+		for _, to := range route.To {
+			if to == agentID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Notifications returns the notifications sent so far, oldest first
+func (h *Harness) Notifications() []notify.Notification {
+	return h.notifications.sent()
+}
+
+// WaitFor polls condition until it holds, failing the test if it does not
+// within the timeout; use it for work agents finish after replying
+func (h *Harness) WaitFor(condition func() bool) {
+	h.t.Helper()
+	deadline := time.Now().Add(h.timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			h.t.Fatalf("condition not met within %v", h.timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// values returns the stored values of userID's keys starting with prefix
+func (h *Harness) values(userID, prefix string) []interface{} {
+	h.t.Helper()
+	ctx := h.Context(userID)
+	store := h.Service.GetMemoryStore()
+	keys, err := store.List(ctx, prefix, 10000)
+	if err != nil {
+		h.t.Fatalf("failed to list %s: %v", prefix, err)
+	}
+	values, err := store.GetMultiple(ctx, keys)
+	if err != nil {
+		h.t.Fatalf("failed to load %s: %v", prefix, err)
+	}
+	result := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		if value, ok := values[key]; ok {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// conversationID is the conversation the service keeps for userID's messages
+func conversationID(userID string) string {
+	return "conv_" + userID
+}
+
+// decode converts a generic stored value into v
+func decode(value interface{}, v interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// notificationRecorder is a notify.Channel keeping what it is sent
+type notificationRecorder struct {
+	mu            sync.Mutex
+	notifications []notify.Notification
+}
+
+func (r *notificationRecorder) Name() string { return "simtest" }
+
+func (r *notificationRecorder) Send(ctx context.Context, notification notify.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+func (r *notificationRecorder) sent() []notify.Notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]notify.Notification(nil), r.notifications...)
+}
@@ -0,0 +1,102 @@
+package simtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+func TestTaskRequestIsRoutedToTaskManager(t *testing.T) {
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "task", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent", "- add_task").Reply(`{"intent": "add_task", "confidence": 0.9}`)
+	llm.On("Extract task information", "buy milk").Reply(`{"title": "Buy milk", "priority": "high", "category": "personal"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Added Buy milk to your tasks.")
+	h := New(t, Config{LLM: llm})
+
+	if reply := h.Send("alice", "add a task to buy milk"); reply != "Added Buy milk to your tasks." {
+		t.Errorf("reply %q", reply)
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+
+	if !h.RoutedTo("alice", "coordinator_agent") || !h.RoutedTo("alice", "task_manager_agent") {
+		t.Errorf("request was not routed through the coordinator to the task manager: %+v", h.Routes("alice"))
+	}
+	if h.RoutedTo("alice", "scheduler_agent") {
+		t.Error("task request was routed to the scheduler")
+	}
+
+	tasks := h.Tasks("alice")
+	if len(tasks) != 1 || tasks[0].Title != "Buy milk" {
+		t.Fatalf("alice's tasks %+v, want Buy milk", tasks)
+	}
+	if tasks[0].ID != "task_000001" {
+		t.Errorf("task ID %q, want the first sequence ID", tasks[0].ID)
+	}
+	if other := h.Tasks("bob"); len(other) != 0 {
+		t.Errorf("bob sees alice's tasks: %+v", other)
+	}
+	writes := h.Writes("personal_task:")
+	if len(writes) != 1 || writes[0].UserID != "alice" || writes[0].Op != memory.WriteStore {
+		t.Errorf("task writes %+v, want one store for alice", writes)
+	}
+	if created := h.Audit(audit.Filter{Types: []audit.EventType{audit.TaskCreated}}); len(created) != 1 || created[0].Actor != "task_manager_agent" {
+		t.Errorf("audited task creations %+v", created)
+	}
+
+	// The specialist's answer reached the synthesis prompt
+	synthesis := llm.CallsContaining("synthesize responses")
+	if len(synthesis) != 1 || !strings.Contains(synthesis[0].Prompt, "Buy milk") {
+		t.Errorf("synthesis prompts %+v", synthesis)
+	}
+}
+
+func TestUnclearRequestsAskWhichSpecialistWasMeant(t *testing.T) {
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents", `Request: "sort out Friday`).Reply(`{"intents": [{"intent": "task", "confidence": 0.35}, {"intent": "schedule", "confidence": 0.3}, {"intent": "chat", "confidence": 0.1}]}`)
+	llm.On("Classify the user's request into the intents", `Request: "what about the Dana thing`).Reply(`{"intents": [{"intent": "schedule", "confidence": 0.3}]}`)
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "chat", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent", `Request: "sort out Friday`).Reply(`{"intent": "view_calendar", "confidence": 0.9}`)
+	llm.On("synthesize responses from specialist agents").Reply("Here's Friday.")
+	llm.On("conversation agent designed to help users").Reply("Doing well, thanks!")
+	h := New(t, Config{LLM: llm})
+
+	reply := h.Send("alice", "sort out Friday with Dana")
+	if !strings.Contains(reply, "Do you mean:\n1. personal tasks, to-dos, and reminders\n2. calendar events, meetings, and availability\n\nReply with a number (or \"both\")") {
+		t.Errorf("the unclear request was not clarified:\n%s", reply)
+	}
+	if h.RoutedTo("alice", "coordinator_agent") {
+		t.Errorf("the unclear request was routed before alice chose: %+v", h.Routes("alice"))
+	}
+
+	// Picking the calendar hands the original request to the scheduler only
+	if reply := h.Send("alice", "2"); reply != "Here's Friday." {
+		t.Errorf("reply %q, want the scheduler's answer", reply)
+	}
+	if !h.RoutedTo("alice", "scheduler_agent") || h.RoutedTo("alice", "task_manager_agent") {
+		t.Errorf("the clarified request was not routed to the scheduler alone: %+v", h.Routes("alice"))
+	}
+	if synthesis := lastPrompt(llm, "synthesize responses"); !strings.Contains(synthesis, "User message: sort out Friday with Dana") {
+		t.Errorf("the scheduler did not get the original request:\n%s", synthesis)
+	}
+
+	// A single candidate is offered as a yes or no question, and an answer
+	// that picks nothing is a new message
+	reply = h.Send("alice", "what about the Dana thing?")
+	if !strings.Contains(reply, "Just to check — is this about calendar events, meetings, and availability? Say yes") {
+		t.Errorf("the single candidate was not offered:\n%s", reply)
+	}
+	if reply := h.Send("alice", "never mind, how are you?"); reply != "Doing well, thanks!" {
+		t.Errorf("reply %q, want a direct answer", reply)
+	}
+	if calls := llm.CallsContaining("synthesize responses"); len(calls) != 1 {
+		t.Errorf("%d requests reached the specialists, want only the clarified one", len(calls))
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+}
@@ -0,0 +1,224 @@
+package simtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kbutz/wikillm/multiagent"
+)
+
+// ErrUnscripted is returned for prompts no rule of a ScriptedLLM matches
+var ErrUnscripted = errors.New("no scripted response for prompt")
+
+// Call is one prompt a ScriptedLLM answered
+type Call struct {
+	Prompt   string
+	Response string
+	// Tools names the tools offered with QueryWithTools
+	Tools []string
+	// Matched is false when no rule matched and the default answered
+	Matched bool
+	Err     error
+}
+
+// ScriptedLLM is a multiagent.LLMProvider that answers prompts from rules.
+// The first rule whose substrings all appear in a prompt answers it; rules
+// are tried in the order they were added.
+type ScriptedLLM struct {
+	mu       sync.Mutex
+	rules    []*Rule
+	fallback *Rule
+	calls    []Call
+}
+
+// Rule answers the prompts containing all of its substrings
+type Rule struct {
+	llm       *ScriptedLLM
+	contains  []string
+	responses []string
+	respond   func(prompt string) (string, error)
+	limit     int
+	uses      int
+}
+
+// NewScriptedLLM creates a ScriptedLLM without rules
+func NewScriptedLLM() *ScriptedLLM {
+	return &ScriptedLLM{}
+}
+
+// On adds a rule for the prompts containing all of contains; without
+// substrings it matches every prompt
+func (l *ScriptedLLM) On(contains ...string) *Rule {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rule := &Rule{llm: l, contains: contains}
+	l.rules = append(l.rules, rule)
+	return rule
+}
+
+// Default answers the prompts no rule matches with response; without a
+// default they fail with ErrUnscripted
+func (l *ScriptedLLM) Default(response string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fallback = &Rule{llm: l, responses: []string{response}}
+}
+
+// Reply answers with responses in turn, repeating the last one
+func (r *Rule) Reply(responses ...string) *Rule {
+	r.llm.mu.Lock()
+	defer r.llm.mu.Unlock()
+	r.responses = responses
+	r.respond = nil
+	return r
+}
+
+// ReplyJSON answers with v encoded as JSON
+func (r *Rule) ReplyJSON(v interface{}) *Rule {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("simtest: failed to marshal reply: %v", err))
+	}
+	return r.Reply(string(data))
+}
+
+// ReplyFunc answers with fn's result for the prompt
+func (r *Rule) ReplyFunc(fn func(prompt string) (string, error)) *Rule {
+	r.llm.mu.Lock()
+	defer r.llm.mu.Unlock()
+	r.respond = fn
+	r.responses = nil
+	return r
+}
+
+// Fail answers with err, as a provider that is down would
+func (r *Rule) Fail(err error) *Rule {
+	return r.ReplyFunc(func(string) (string, error) { return "", err })
+}
+
+// Times stops the rule matching after n prompts, so later rules answer
+// repeats differently
+func (r *Rule) Times(n int) *Rule {
+	r.llm.mu.Lock()
+	defer r.llm.mu.Unlock()
+	r.limit = n
+	return r
+}
+
+// Name identifies the provider
+func (l *ScriptedLLM) Name() string {
+	return "scripted"
+}
+
+// Query answers prompt from the script
+func (l *ScriptedLLM) Query(ctx context.Context, prompt string) (string, error) {
+	return l.answer(prompt, nil)
+}
+
+// QueryWithTools answers prompt from the script; tools are recorded but
+// never called
+func (l *ScriptedLLM) QueryWithTools(ctx context.Context, prompt string, tools []multiagent.Tool) (string, error) {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name()
+	}
+	return l.answer(prompt, names)
+}
+
+// Calls returns every prompt answered so far, oldest first
+func (l *ScriptedLLM) Calls() []Call {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Call(nil), l.calls...)
+}
+
+// CallsContaining returns the prompts answered so far that contain s
+func (l *ScriptedLLM) CallsContaining(s string) []Call {
+	var calls []Call
+	for _, call := range l.Calls() {
+		if strings.Contains(call.Prompt, s) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Unmatched returns the prompts no rule matched
+func (l *ScriptedLLM) Unmatched() []string {
+	var prompts []string
+	for _, call := range l.Calls() {
+		if !call.Matched {
+			prompts = append(prompts, call.Prompt)
+		}
+	}
+	return prompts
+}
+
+func (l *ScriptedLLM) answer(prompt string, tools []string) (string, error) {
+	l.mu.Lock()
+	rule := l.match(prompt)
+	matched := rule != nil
+	if !matched {
+		rule = l.fallback
+	}
+	var (
+		respond  func(string) (string, error)
+		response string
+		err      error
+	)
+	switch {
+	case rule == nil:
+		err = fmt.Errorf("%w: %s", ErrUnscripted, truncate(prompt, 200))
+	case rule.respond != nil:
+		respond = rule.respond
+	case len(rule.responses) > 0:
+		response = rule.responses[min(rule.uses, len(rule.responses)-1)]
+	}
+	if rule != nil {
+		rule.uses++
+	}
+	l.mu.Unlock()
+
+	// ReplyFunc runs unlocked so it may inspect Calls
+	if respond != nil {
+		response, err = respond(prompt)
+	}
+
+	l.mu.Lock()
+	l.calls = append(l.calls, Call{Prompt: prompt, Response: response, Tools: tools, Matched: matched, Err: err})
+	l.mu.Unlock()
+	return response, err
+}
+
+// match returns the first rule matching prompt with uses left; the caller
+// holds l.mu
+func (l *ScriptedLLM) match(prompt string) *Rule {
+	for _, rule := range l.rules {
+		if rule.limit > 0 && rule.uses >= rule.limit {
+			continue
+		}
+		matches := true
+		for _, s := range rule.contains {
+			if !strings.Contains(prompt, s) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return rule
+		}
+	}
+	return nil
+}
+
+// truncate shortens s to at most n bytes for error messages
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
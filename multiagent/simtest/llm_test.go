@@ -0,0 +1,61 @@
+package simtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestScriptedLLMAnswersFromRules(t *testing.T) {
+	llm := NewScriptedLLM()
+	llm.On("classify", "milk").Reply(`{"intent": "add_task"}`)
+	llm.On("summarize").Reply("first", "second").Times(2)
+	llm.On("summarize").Reply("later")
+	ctx := context.Background()
+
+	if response, _ := llm.Query(ctx, "classify: buy milk"); response != `{"intent": "add_task"}` {
+		t.Errorf("classify answered %q", response)
+	}
+	var summaries []string
+	for i := 0; i < 3; i++ {
+		response, _ := llm.Query(ctx, "summarize this")
+		summaries = append(summaries, response)
+	}
+	if summaries[0] != "first" || summaries[1] != "second" || summaries[2] != "later" {
+		t.Errorf("summaries answered %v, want first, second, then the next rule", summaries)
+	}
+
+	// Only prompts with every substring match a rule
+	if _, err := llm.Query(ctx, "classify: buy bread"); !errors.Is(err, ErrUnscripted) {
+		t.Errorf("unscripted prompt returned %v, want ErrUnscripted", err)
+	}
+	llm.Default("fallback")
+	if response, err := llm.Query(ctx, "anything else"); err != nil || response != "fallback" {
+		t.Errorf("default answered %q, %v", response, err)
+	}
+
+	if calls := llm.Calls(); len(calls) != 6 {
+		t.Fatalf("recorded %d calls, want 6", len(calls))
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) != 2 || unmatched[0] != "classify: buy bread" {
+		t.Errorf("unmatched prompts %v", unmatched)
+	}
+	if calls := llm.CallsContaining("summarize"); len(calls) != 3 {
+		t.Errorf("found %d summarize calls, want 3", len(calls))
+	}
+}
+
+func TestScriptedLLMFailsAndReplyFunc(t *testing.T) {
+	llm := NewScriptedLLM()
+	down := errors.New("backend down")
+	llm.On("research").Fail(down)
+	llm.On("echo").ReplyFunc(func(prompt string) (string, error) { return "got " + prompt, nil })
+	ctx := context.Background()
+
+	if _, err := llm.Query(ctx, "research cats"); !errors.Is(err, down) {
+		t.Errorf("failing rule returned %v", err)
+	}
+	if response, _ := llm.QueryWithTools(ctx, "echo", nil); response != "got echo" {
+		t.Errorf("ReplyFunc answered %q", response)
+	}
+}
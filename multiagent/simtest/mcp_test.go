@@ -0,0 +1,46 @@
+package simtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+func TestMCPMemoryStaysInItsScope(t *testing.T) {
+	h := New(t, Config{})
+	secret := memory.UserKeyPrefix("mcp") + memory.PrivateKey("scheduler_agent", "calendar_token")
+	if err := h.Store.Store(context.Background(), secret, "shh"); err != nil {
+		t.Fatal(err)
+	}
+	server := h.Service.MCPServer("")
+
+	call := func(arguments string) (string, bool) {
+		t.Helper()
+		body := `{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": {"name": "memory", "arguments": ` + arguments + `}}`
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest("POST", "/mcp", strings.NewReader(body)))
+		var reply struct {
+			Result struct {
+				Content []struct {
+					Text string `json:"text"`
+				} `json:"content"`
+				IsError bool `json:"isError"`
+			} `json:"result"`
+		}
+		if err := json.NewDecoder(recorder.Body).Decode(&reply); err != nil || len(reply.Result.Content) == 0 {
+			t.Fatalf("tools/call %s: %v\n%s", arguments, err, recorder.Body.String())
+		}
+		return reply.Result.Content[0].Text, reply.Result.IsError
+	}
+
+	if text, failed := call(`{"command": "retrieve", "key": "private:scheduler_agent:calendar_token"}`); !failed || !strings.Contains(text, "memory scope violation") {
+		t.Errorf("MCP client read the scheduler's private memory: %s", text)
+	}
+	if text, failed := call(`{"command": "store", "content": "Prefers tea", "category": "preference"}`); failed {
+		t.Errorf("MCP client could not store a global memory: %s", text)
+	}
+}
@@ -0,0 +1,55 @@
+package simtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/memory"
+	"github.com/kbutz/wikillm/multiagent/notes"
+	"github.com/kbutz/wikillm/multiagent/service"
+)
+
+func TestResearchAnswersFromUsersNotes(t *testing.T) {
+	dir := t.TempDir()
+	note := "# Garden plans\n\nPlant the tomatoes along the south fence once the frost is over."
+	if err := os.WriteFile(filepath.Join(dir, "garden.md"), []byte(note), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "research", "confidence": 0.9}]}`)
+	llm.On("research assistant specialist").Reply("Your garden notes say along the south fence.")
+	llm.On("synthesize responses from specialist agents").Reply("Along the south fence, per your garden notes.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Notes = []notes.Source{{UserID: "alice", Dirs: []string{dir}}}
+	}})
+
+	// The indexer's first pass runs as the service starts
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		keys, _ := h.Store.List(context.Background(), memory.UserKeyPrefix("alice")+notes.KeyPrefix, 10)
+		if len(keys) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("alice's notes were not indexed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	h.Send("alice", "Where did I plan to plant the tomatoes?")
+	prompt := lastPrompt(llm, "research assistant specialist")
+	if !strings.Contains(prompt, "From the user's notes") || !strings.Contains(prompt, "south fence") || !strings.Contains(prompt, "garden.md") {
+		t.Errorf("research prompt does not hold the matching note:\n%s", prompt)
+	}
+
+	// Another user's questions do not see alice's notes
+	h.Send("bob", "Where did I plan to plant the tomatoes?")
+	if prompt := lastPrompt(llm, "research assistant specialist"); strings.Contains(prompt, "south fence") {
+		t.Errorf("bob's research prompt holds alice's note:\n%s", prompt)
+	}
+}
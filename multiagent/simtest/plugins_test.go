@@ -0,0 +1,82 @@
+package simtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/orchestrator"
+	"github.com/kbutz/wikillm/multiagent/plugins"
+	"github.com/kbutz/wikillm/multiagent/service"
+)
+
+func TestPluginAgentIsRegisteredStartedAndWrapped(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		hooks     []string
+		delivered []multiagent.AgentID
+	)
+	note := func(hook string) {
+		mu.Lock()
+		defer mu.Unlock()
+		hooks = append(hooks, hook)
+	}
+	plugin := plugins.Plugin{
+		Name: "expenses",
+		Agents: func(env plugins.Env) ([]multiagent.Agent, error) {
+			config := env.AgentConfig("expense_agent", "expenses")
+			config.Name = "Expense Tracker"
+			return []multiagent.Agent{agents.NewBaseAgent(config)}, nil
+		},
+		Capabilities: map[multiagent.AgentID][]multiagent.CapabilityDescriptor{
+			"expense_agent": {{Name: "expense_tracking", Domain: "finance", Keywords: []string{"receipt"}}},
+		},
+		OnRegister: func(ctx context.Context, agent multiagent.Agent) error {
+			note("register:" + string(agent.ID()))
+			return nil
+		},
+		OnStart: func(ctx context.Context, agent multiagent.Agent) error {
+			note("start:" + string(agent.ID()))
+			return nil
+		},
+		OnMessage: func(next orchestrator.MessageHandler) orchestrator.MessageHandler {
+			return func(ctx context.Context, agent multiagent.Agent, msg *multiagent.Message) (*multiagent.Message, error) {
+				mu.Lock()
+				delivered = append(delivered, agent.ID())
+				mu.Unlock()
+				return next(ctx, agent, msg)
+			}
+		},
+	}
+
+	llm := NewScriptedLLM()
+	llm.Default("Hello!")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Plugins = []plugins.Plugin{plugin}
+	}})
+
+	agent, err := h.Service.GetAgent("expense_agent")
+	if err != nil || agent.Name() != "Expense Tracker" {
+		t.Fatalf("plugin agent %v, %v", agent, err)
+	}
+	mu.Lock()
+	if len(hooks) != 2 || hooks[0] != "register:expense_agent" || hooks[1] != "start:expense_agent" {
+		t.Errorf("hooks %v, want register then start", hooks)
+	}
+	mu.Unlock()
+
+	orch := h.Service.GetOrchestrator().(*orchestrator.DefaultOrchestrator)
+	ranked := orch.RankAgentsForTask(multiagent.Task{Type: "expense_tracking", Description: "file this receipt"})
+	if len(ranked) == 0 || ranked[0].AgentID != "expense_agent" {
+		t.Errorf("expected the plugin agent ranked first for expenses, got %+v", ranked)
+	}
+
+	h.Send("alice", "hello")
+	mu.Lock()
+	defer mu.Unlock()
+	if len(delivered) == 0 || delivered[0] != "conversation_agent" {
+		t.Errorf("middleware saw deliveries to %v, want the conversation agent first", delivered)
+	}
+}
@@ -0,0 +1,298 @@
+package simtest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/service"
+)
+
+func TestMilestonesTrackProgressAndLateness(t *testing.T) {
+	// Monday morning
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "project", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "milestone", "confidence": 0.9}`)
+	llm.On("what to do with its milestones", "due friday").Reply(`{"project": "website", "action": "add", "milestone": "Beta", "due_date": "2026-05-08", "tasks": ["design", "build"]}`)
+	llm.On("what to do with its milestones", "progress by").Reply(`{"project": "website", "action": "track", "progress_mode": "milestones"}`)
+	llm.On("what to do with its milestones", "how are the").Reply(`{"project": "website", "action": "list"}`)
+	llm.On("what to do with its milestones", "reached").Reply(`{"project": "website", "action": "complete", "milestone": "beta"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	storeProject(h, "alice", &agents.Project{
+		ID:     "project_site",
+		Name:   "Website relaunch",
+		Status: agents.ProjectStatusActive,
+		Tasks: []agents.ProjectTask{
+			{ID: "ptask_design", Title: "Design", Status: agents.TaskStatusCompleted, Progress: 100},
+			{ID: "ptask_build", Title: "Build", Status: agents.TaskStatusInProgress, Progress: 50},
+			{ID: "ptask_launch", Title: "Launch", Status: agents.TaskStatusNotStarted},
+		},
+	})
+
+	h.Send("alice", "add a beta milestone to the website due friday, covering design and build")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "🎯 Added milestone 'Beta' to 'Website relaunch', due Fri 2026-05-08.") || !strings.Contains(answer, "🔗 Linked tasks: Design, Build") {
+		t.Errorf("the milestone was not added:\n%s", answer)
+	}
+
+	// Measured by milestones, the unlinked launch task no longer counts
+	h.Send("alice", "track the website's progress by milestones")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "📊 Project progress: 75.0%") {
+		t.Errorf("progress is not the beta milestone's:\n%s", answer)
+	}
+
+	clock.Advance(7 * 24 * time.Hour)
+	h.Send("alice", "how are the website milestones going?")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "• ⚠️ Beta - 2026-05-08 (overdue by 3 days, 75%) — 1/2 tasks done") {
+		t.Errorf("the late milestone was not flagged:\n%s", answer)
+	}
+
+	h.Send("alice", "we reached the beta milestone")
+	answer := lastPrompt(llm, "synthesize responses")
+	if !strings.Contains(answer, "✅ Milestone 'Beta' of 'Website relaunch' reached!") || !strings.Contains(answer, "It was due 2026-05-08, 3 days ago.") || !strings.Contains(answer, "📊 Project progress: 100.0%") {
+		t.Errorf("completing the late milestone was not reported:\n%s", answer)
+	}
+	project := findProject(t, h, "alice", "project_site")
+	if project.ProgressMode != agents.ProgressByMilestones || len(project.Milestones) != 1 || project.Milestones[0].Status != agents.MilestoneStatusCompleted {
+		t.Errorf("stored project tracks by %q with milestones %+v", project.ProgressMode, project.Milestones)
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+}
+
+func TestBudgetTracksSpendingAgainstAllocations(t *testing.T) {
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "project", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "budget", "confidence": 0.9}`)
+	llm.On("what to do with its budget", "10k").Reply(`{"project": "website", "action": "set", "amount": 10000, "categories": {"Design": 3000}}`)
+	llm.On("what to do with its budget", "mockups").Reply(`{"project": "website", "action": "expense", "amount": 2000, "description": "design mockups"}`)
+	llm.On("what to do with its budget", "icons").Reply(`{"project": "website", "action": "expense", "amount": 1500, "category": "design", "description": "icon set"}`)
+	llm.On("what to do with its budget", "where do we stand").Reply(`{"project": "website", "action": "report"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	storeProject(h, "alice", &agents.Project{
+		ID:      "project_site",
+		Name:    "Website relaunch",
+		Status:  agents.ProjectStatusActive,
+		DueDate: timePtr(time.Date(2026, 5, 31, 9, 0, 0, 0, time.UTC)),
+	})
+
+	h.Send("alice", "the website has a 10k budget, 3k of it for design")
+	h.Send("alice", "we spent 2000 on design mockups for the website")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "🧾 Logged 2000.00 USD for design mockups under design on 'Website relaunch'.") {
+		t.Errorf("the expense was not filed under design:\n%s", answer)
+	}
+
+	clock.Advance(4 * 24 * time.Hour)
+	h.Send("alice", "paid 1500 for icons on the website")
+	h.Send("alice", "where do we stand on the website budget?")
+	answer := lastPrompt(llm, "synthesize responses")
+	for _, want := range []string{
+		"• Spent: 3500.00 USD (35%)",
+		"• Remaining: 6500.00 USD",
+		"• Burn rate: 875.00 USD/day",
+		"• Projected by 2026-05-31: 23625.00 USD",
+		"• design: 3500.00 USD of 3000.00 USD",
+		"📈 At the current burn rate spending reaches 23625.00 USD by the due date, 13625.00 USD over budget",
+		"⚠️ design is over its 3000.00 USD allocation by 500.00 USD",
+	} {
+		if !strings.Contains(answer, want) {
+			t.Errorf("budget report is missing %q:\n%s", want, answer)
+		}
+	}
+	if budget := findProject(t, h, "alice", "project_site").Budget; budget == nil || len(budget.Expenses) != 2 || budget.SpentAmount != 3500 {
+		t.Errorf("stored budget %+v, want two expenses totalling 3500", budget)
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+}
+
+func TestTemplatesReplayAProjectsPlan(t *testing.T) {
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "project", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "template", "confidence": 0.9}`)
+	llm.On("what to do with project templates", "save the spring").Reply(`{"action": "save", "template": "launch", "project": "spring launch"}`)
+	llm.On("what to do with project templates", "kick off").Reply(`{"action": "instantiate", "template": "launch", "project": "Summer launch", "start_date": "2026-06-01"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	day := func(month time.Month, d int) *time.Time {
+		return timePtr(time.Date(2026, month, d, 0, 0, 0, 0, time.UTC))
+	}
+	storeProject(h, "alice", &agents.Project{
+		ID:        "project_spring",
+		Name:      "Spring launch",
+		Status:    agents.ProjectStatusCompleted,
+		StartDate: day(time.March, 2),
+		DueDate:   day(time.March, 20),
+		Tasks: []agents.ProjectTask{
+			{ID: "ptask_plan", Title: "Plan", Status: agents.TaskStatusCompleted, StartDate: day(time.March, 2), DueDate: day(time.March, 4)},
+			{ID: "ptask_build", Title: "Build", Status: agents.TaskStatusCompleted, StartDate: day(time.March, 5), DueDate: day(time.March, 12), Dependencies: []string{"ptask_plan"}},
+			{ID: "ptask_ship", Title: "Ship", Status: agents.TaskStatusCompleted, DueDate: day(time.March, 16), Dependencies: []string{"ptask_build"}},
+			{ID: "ptask_party", Title: "Launch party", Status: agents.TaskStatusCancelled},
+		},
+		Milestones: []agents.Milestone{
+			{ID: "milestone_beta", Title: "Beta", DueDate: *day(time.March, 12), Status: agents.MilestoneStatusCompleted, Tasks: []string{"ptask_build"}},
+		},
+	})
+
+	// Dates become days after the start; cancelled tasks are left out
+	h.Send("alice", "save the spring launch as a template called launch")
+	answer := lastPrompt(llm, "synthesize responses")
+	for _, want := range []string{
+		"📐 Created template 'launch' from project 'Spring launch'.",
+		"Duration: 18 days",
+		"• Plan — due day 2",
+		"• Build — days 3–10 (after Plan)",
+		"• Ship — due day 14 (after Build)",
+		"• Beta — day 10",
+	} {
+		if !strings.Contains(answer, want) {
+			t.Errorf("saved template is missing %q:\n%s", want, answer)
+		}
+	}
+	if strings.Contains(answer, "Launch party") {
+		t.Errorf("the cancelled task was saved in the template:\n%s", answer)
+	}
+
+	h.Send("alice", "kick off the summer launch from the launch template on June 1")
+	answer = lastPrompt(llm, "synthesize responses")
+	for _, want := range []string{
+		"✅ Project 'Summer launch' created from template 'launch', starting Mon 2026-06-01.",
+		"Due: Fri 2026-06-19",
+		"• Plan — due Wed 2026-06-03",
+		"• Build — due Thu 2026-06-11",
+		"• Ship — due Mon 2026-06-15",
+		"• 📅 Beta - 2026-06-11 (0%) — 0/1 tasks done",
+	} {
+		if !strings.Contains(answer, want) {
+			t.Errorf("new project is missing %q:\n%s", want, answer)
+		}
+	}
+
+	var summer *agents.Project
+	for _, value := range h.values("alice", "project:") {
+		var project agents.Project
+		if decode(value, &project) == nil && project.Name == "Summer launch" {
+			summer = &project
+		}
+	}
+	if summer == nil {
+		t.Fatal("the new project was not stored")
+	}
+	if len(summer.Tasks) != 3 || summer.Status != agents.ProjectStatusPlanning {
+		t.Fatalf("new project is %s with tasks %+v, want planning with 3", summer.Status, summer.Tasks)
+	}
+	if deps := summer.Tasks[1].Dependencies; len(deps) != 1 || deps[0] != summer.Tasks[0].ID {
+		t.Errorf("build depends on %v, want the new plan task %s", deps, summer.Tasks[0].ID)
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+}
+
+func TestAssignedProjectTasksCompleteBothWays(t *testing.T) {
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents", "I finished").Reply(`{"intents": [{"intent": "task", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "project", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent", "assign the").Reply(`{"intent": "assign_to_me", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", "I finished").Reply(`{"intent": "complete_task", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", "footer is done").Reply(`{"intent": "update_task", "confidence": 0.9}`)
+	llm.On("Identify the project task the user takes on", "homepage").Reply(`{"project": "website", "task": "homepage"}`)
+	llm.On("Identify the project task the user takes on", "footer").Reply(`{"project": "website", "task": "footer"}`)
+	llm.On("Extract task update information", "footer is done").Reply(`{"task_identifier": "footer", "status": "completed"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm})
+	storeProject(h, "alice", &agents.Project{
+		ID:     "project_site",
+		Name:   "Website relaunch",
+		Status: agents.ProjectStatusActive,
+		Tasks: []agents.ProjectTask{
+			{ID: "ptask_homepage", Title: "Homepage", Status: agents.TaskStatusInProgress},
+			{ID: "ptask_footer", Title: "Footer", Status: agents.TaskStatusNotStarted},
+		},
+	})
+	linked := func(projectTaskID string) *agents.PersonalTask {
+		for _, task := range h.Tasks("alice") {
+			if task.ProjectLink != nil && task.ProjectLink.TaskID == projectTaskID {
+				return task
+			}
+		}
+		t.Fatalf("no personal task is linked to %s", projectTaskID)
+		return nil
+	}
+
+	h.Send("alice", "assign the homepage task to me")
+	homepage := linked("ptask_homepage")
+	if homepage.Title != "Homepage" || homepage.Project != "Website relaunch" || homepage.ProjectLink.ProjectID != "project_site" {
+		t.Errorf("linked task %+v, want the homepage of the website", homepage)
+	}
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "📌 Assigned 'Homepage' (Website relaunch) to you — it's on your task list as "+homepage.ID) {
+		t.Errorf("the assignment was not confirmed:\n%s", answer)
+	}
+	if task := findProject(t, h, "alice", "project_site").Tasks[0]; task.PersonalTaskID != homepage.ID || task.Assignee != "alice" {
+		t.Errorf("project task is assigned to %q as %q, want alice as %s", task.Assignee, task.PersonalTaskID, homepage.ID)
+	}
+
+	// Assigning it again keeps the one link
+	h.Send("alice", "assign the homepage task to me")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "📌 'Homepage' is already on your task list ("+homepage.ID+").") {
+		t.Errorf("assigning twice was not caught:\n%s", answer)
+	}
+
+	// Finishing the personal task finishes the project's
+	h.Send("alice", "I finished homepage")
+	h.WaitFor(func() bool {
+		return findProject(t, h, "alice", "project_site").Tasks[0].Status == agents.TaskStatusCompleted
+	})
+	if progress := findProject(t, h, "alice", "project_site").Progress; progress != 50 {
+		t.Errorf("project progress is %.0f%%, want 50%%", progress)
+	}
+
+	// Finishing the project's task finishes the personal one
+	h.Send("alice", "assign the footer task to me")
+	footer := linked("ptask_footer")
+	h.Send("alice", "the footer is done")
+	h.WaitFor(func() bool {
+		return findTask(h, "alice", footer.ID).Status == agents.PersonalTaskStatusCompleted
+	})
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+}
+
+// storeProject stores project as one of userID's projects
+func storeProject(h *Harness, userID string, project *agents.Project) {
+	h.t.Helper()
+	if project.CreatedAt.IsZero() {
+		project.CreatedAt = SeededAt
+	}
+	project.UserID = userID
+	h.Seed(userID, "project:"+project.ID, project)
+}
+
+// findProject returns userID's stored project with the given ID
+func findProject(t *testing.T, h *Harness, userID, id string) *agents.Project {
+	t.Helper()
+	value, err := h.Service.GetMemoryStore().Get(h.Context(userID), "project:"+id)
+	if err != nil {
+		t.Fatalf("%s has no project %s: %v", userID, id, err)
+	}
+	var project agents.Project
+	if err := decode(value, &project); err != nil {
+		t.Fatalf("failed to decode %s: %v", id, err)
+	}
+	return &project
+}
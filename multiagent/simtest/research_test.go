@@ -0,0 +1,309 @@
+package simtest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/search"
+	"github.com/kbutz/wikillm/multiagent/service"
+)
+
+func TestCompletedResearchIsKeptAndCited(t *testing.T) {
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "research", "confidence": 0.9}]}`)
+	llm.On("Extract research parameters").Reply(`{"topic": "Heat pumps for cold climates", "query": "heat pumps cold climates", "methodology": "quick"}`)
+	llm.On("Conduct research on").Reply("## Executive Summary\nThey work.\n\n## Key Findings\n1. Cold-climate models keep working at -25C (confidence: high)\n2. Running costs beat oil heating\n\n## Supporting Evidence\n- Field trials")
+	llm.On("synthesize responses from specialist agents").Reply("Research started.")
+	h := New(t, Config{LLM: llm})
+	ctx := h.Context("alice")
+
+	h.Send("alice", "research heat pumps for cold climates")
+	var sessions []*agents.ResearchSession
+	h.WaitFor(func() bool {
+		sessions, _ = h.Service.ListResearch(ctx, agents.ResearchFilter{Status: agents.ResearchStatusCompleted})
+		return len(sessions) == 1
+	})
+	session := sessions[0]
+	if len(session.Findings) != 2 || session.Findings[0].Finding != "Cold-climate models keep working at -25C (confidence: high)" || session.Findings[0].Confidence != 0.9 {
+		t.Fatalf("findings %+v, want the report's two key findings", session.Findings)
+	}
+
+	if _, err := h.Service.AddResearchSource(ctx, session.ID, agents.ResearchSource{URL: "https://en.wikipedia.org/wiki/Heat_pump"}); err != nil {
+		t.Fatalf("AddResearchSource: %v", err)
+	}
+	matches, err := h.Service.SearchResearchFindings(ctx, agents.ResearchFilter{Query: "oil"})
+	if err != nil || len(matches) != 1 || matches[0].SessionID != session.ID {
+		t.Errorf("findings matching oil %+v (%v)", matches, err)
+	}
+	if others, _ := h.Service.ListResearch(h.Context("bob"), agents.ResearchFilter{}); len(others) != 0 {
+		t.Errorf("bob sees alice's research: %+v", others)
+	}
+
+	// Asking for citations in conversation exports the bibliography
+	h.Send("alice", "give me the bibtex citations for my heat pump research")
+	if prompt := lastPrompt(llm, "synthesize responses"); !strings.Contains(prompt, "@misc{wikipediaheat,") {
+		t.Errorf("the research assistant's answer does not hold the BibTeX bibliography:\n%s", prompt)
+	}
+}
+
+// searchFunc is a search backend answering from a function of the query
+type searchFunc func(query string) ([]search.Result, error)
+
+func (f searchFunc) Search(ctx context.Context, query string, limit int) ([]search.Result, error) {
+	return f(query)
+}
+
+func TestResearchGathersAndCitesSources(t *testing.T) {
+	web := searchFunc(func(query string) ([]search.Result, error) {
+		if strings.Contains(query, "costs") {
+			return nil, errors.New("search engine unavailable")
+		}
+		return []search.Result{
+			{Title: "Cold climate heat pumps field trial", URL: "https://example.org/trial", Snippet: "Heat pumps kept homes warm at -25C", Score: 4, Source: search.SourceWeb},
+			{Title: "Heat pump buyer's guide", URL: "https://www.example.org/guide/", Snippet: "What to look for", Score: 1, Source: search.SourceWeb},
+		}, nil
+	})
+	wikipedia := searchFunc(func(query string) ([]search.Result, error) {
+		return []search.Result{
+			{Title: "Heat pump", URL: "https://en.wikipedia.org/wiki/Heat_pump", Snippet: "A heat pump moves heat", Source: search.SourceWikipedia},
+			// The same page as the web search found, under another address
+			{Title: "Heat pump buyer's guide", URL: "http://example.org/guide", Source: search.SourceWeb},
+		}, nil
+	})
+
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "research", "confidence": 0.9}]}`)
+	llm.On("Extract research parameters").Reply(`{"topic": "Heat pumps for cold climates", "query": "do heat pumps work in cold climates", "methodology": "deep"}`)
+	llm.On("Break this research question").Reply(`{"sub_queries": ["heat pump cold weather performance", "heat pump running costs"]}`)
+	llm.On("from the numbered sources below").Reply(`{
+		"summary": "Modern heat pumps keep working well below freezing [1][2].",
+		"findings": [
+			{"finding": "Cold-climate models keep homes warm at -25C [1]", "confidence": 0.85, "sources": [1, 2], "evidence": ["field trial"]},
+			{"finding": "Unsupported claim", "confidence": 3, "sources": [9]}
+		]
+	}`)
+	llm.On("synthesize responses from specialist agents").Reply("Research started.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Tools = []multiagent.Tool{
+			search.NewTool(search.WebToolName, "Search the web", web),
+			search.NewTool(search.WikipediaToolName, "Search Wikipedia", wikipedia),
+		}
+	}})
+	ctx := h.Context("alice")
+
+	h.Send("alice", "research whether heat pumps work in cold climates")
+	var sessions []*agents.ResearchSession
+	h.WaitFor(func() bool {
+		sessions, _ = h.Service.ListResearch(ctx, agents.ResearchFilter{Status: agents.ResearchStatusCompleted})
+		return len(sessions) == 1
+	})
+	session := sessions[0]
+
+	// Both sub-queries were searched with both tools; the failed search is
+	// skipped and the page found twice is kept once, best scored first
+	if got := len(llm.CallsContaining("Break this research question")); got != 1 {
+		t.Errorf("question decomposed %d times, want once", got)
+	}
+	if len(session.Sources) != 3 {
+		t.Fatalf("sources %+v, want the 3 distinct pages found", session.Sources)
+	}
+	first := session.Sources[0]
+	if first.URL != "https://example.org/trial" || first.Type != agents.SourceTypeWeb || first.Relevance <= session.Sources[2].Relevance {
+		t.Errorf("best source %+v, want the top-ranked field trial", first)
+	}
+	var wiki *agents.ResearchSource
+	for i := range session.Sources {
+		if session.Sources[i].Title == "Heat pump" {
+			wiki = &session.Sources[i]
+		}
+	}
+	if wiki == nil || wiki.Type != agents.SourceTypeEncyclopedia || wiki.Summary != "A heat pump moves heat" {
+		t.Errorf("Wikipedia source %+v", wiki)
+	}
+
+	prompt := lastPrompt(llm, "from the numbered sources below")
+	if !strings.Contains(prompt, "[1] Cold climate heat pumps field trial (https://example.org/trial)") {
+		t.Errorf("synthesis prompt does not number the sources:\n%s", prompt)
+	}
+
+	if len(session.Findings) != 2 {
+		t.Fatalf("findings %+v, want the synthesized two", session.Findings)
+	}
+	cited := session.Findings[0]
+	if len(cited.Sources) != 2 || cited.Sources[0] != first.ID || cited.Sources[1] != session.Sources[1].ID || cited.Confidence != 0.85 {
+		t.Errorf("finding %+v, want it to cite sources 1 and 2", cited)
+	}
+	if unsupported := session.Findings[1]; len(unsupported.Sources) != 0 || unsupported.Confidence != 1 {
+		t.Errorf("finding %+v, want no sources and its confidence capped", unsupported)
+	}
+	if !strings.Contains(session.Summary, "[1][2]") || !strings.Contains(session.Summary, "[1] Cold climate heat pumps field trial - https://example.org/trial") {
+		t.Errorf("summary does not cite its sources:\n%s", session.Summary)
+	}
+}
+
+func TestMonitoredResearchAlertsOnlyOnMaterialChange(t *testing.T) {
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "research", "confidence": 0.9}]}`)
+	llm.On("Extract research parameters").Reply(`{"topic": "Heat pumps for cold climates", "query": "heat pumps cold climates", "methodology": "quick"}`)
+	llm.On("Conduct research on").Reply(
+		"## Key Findings\n1. Cold-climate models keep working at -25C\n2. Running costs beat oil heating",
+		// A day later nothing has changed
+		"## Key Findings\n1. Running costs beat oil heating\n2. Cold-climate models keep working at -25C",
+		// Then a new development
+		"## Key Findings\n1. Cold-climate models keep working at -25C\n2. New models now work down to -35C",
+		// Then a rewording of what is known
+		"## Key Findings\n1. Heat pumps now cost less to run than oil heating",
+	)
+	llm.On("You are monitoring the research topic").Reply(
+		`{"new": [1], "summary": "Newer heat pumps work in even colder weather."}`,
+		`{"new": [], "summary": ""}`,
+	)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+		config.ResearchMonitoring.Disabled = true
+	}})
+	ctx := h.Context("alice")
+
+	h.Send("alice", "research heat pumps for cold climates")
+	h.WaitFor(func() bool {
+		sessions, _ := h.Service.ListResearch(ctx, agents.ResearchFilter{Status: agents.ResearchStatusCompleted})
+		return len(sessions) == 1
+	})
+	h.Send("alice", "monitor this topic daily")
+	monitors, err := h.Service.ListResearchMonitors(ctx)
+	if err != nil || len(monitors) != 1 || monitors[0].Interval != 24*time.Hour || len(monitors[0].Known) != 2 {
+		t.Fatalf("monitors %+v (%v), want the heat pump research daily", monitors, err)
+	}
+
+	check := func() []agents.MonitorAlert {
+		t.Helper()
+		alerts, err := h.Service.CheckResearchMonitors(context.Background(), "alice")
+		if err != nil {
+			t.Fatalf("CheckResearchMonitors: %v", err)
+		}
+		return alerts
+	}
+	if alerts := check(); len(alerts) != 0 || len(llm.CallsContaining("Conduct research on")) != 1 {
+		t.Fatalf("a monitor that is not due ran: %+v", alerts)
+	}
+
+	clock.Advance(25 * time.Hour)
+	if alerts := check(); len(alerts) != 0 {
+		t.Errorf("alerted on the same findings: %+v", alerts)
+	}
+	if judged := len(llm.CallsContaining("You are monitoring the research topic")); judged != 0 {
+		t.Errorf("restated findings were judged %d times, want none", judged)
+	}
+
+	clock.Advance(24 * time.Hour)
+	alerts := check()
+	if len(alerts) != 1 || len(alerts[0].Update.Findings) != 1 || alerts[0].Update.Findings[0] != "New models now work down to -35C" {
+		t.Fatalf("alerts %+v, want the new development", alerts)
+	}
+	if prompt := lastPrompt(llm, "You are monitoring the research topic"); !strings.Contains(prompt, "1. New models now work down to -35C") || strings.Contains(prompt, "1. Cold-climate") {
+		t.Errorf("judged findings other than the new one:\n%s", prompt)
+	}
+	var sent []notify.Notification
+	for _, notification := range h.Notifications() {
+		if notification.Kind == notify.KindResearchUpdate {
+			sent = append(sent, notification)
+		}
+	}
+	if len(sent) != 1 || sent[0].UserID != "alice" || !strings.Contains(sent[0].Body, "-35C") || sent[0].Subject != alerts[0].Update.SessionID {
+		t.Errorf("research update notifications %+v", sent)
+	}
+	if kept, _ := h.Service.ListResearch(ctx, agents.ResearchFilter{Tag: "monitor"}); len(kept) != 1 || kept[0].ID != alerts[0].Update.SessionID {
+		t.Errorf("monitored runs kept %+v, want the one with news", kept)
+	}
+
+	clock.Advance(24 * time.Hour)
+	if alerts := check(); len(alerts) != 0 {
+		t.Errorf("alerted on a rewording: %+v", alerts)
+	}
+	monitors, _ = h.Service.ListResearchMonitors(ctx)
+	if len(monitors) != 1 || len(monitors[0].Updates) != 1 || !monitors[0].NextRun.Equal(clock.Now().Add(24*time.Hour)) {
+		t.Errorf("monitor after its runs %+v", monitors)
+	}
+
+	h.Send("alice", "stop monitoring heat pumps")
+	if monitors, _ := h.Service.ListResearchMonitors(ctx); len(monitors) != 0 {
+		t.Errorf("monitors after stopping %+v", monitors)
+	}
+}
+
+func TestFactCheckQuotesWikipedia(t *testing.T) {
+	wikipedia := searchFunc(func(query string) ([]search.Result, error) {
+		if strings.Contains(query, "tall") {
+			return []search.Result{
+				{Title: "Eiffel Tower", URL: "https://en.wikipedia.org/wiki/Eiffel_Tower", Snippet: "The tower is 330 metres (1,083 ft) tall,\nabout the same height as an 81-storey building.", Score: 0.9, Source: search.SourceWikipedia},
+				{Title: "List of tallest structures in Paris", URL: "https://en.wikipedia.org/wiki/List_of_tallest_structures_in_Paris", Snippet: "The Eiffel Tower, at 330 m, is the tallest structure in Paris.", Score: 0.45, Source: search.SourceWikipedia},
+			}, nil
+		}
+		return []search.Result{
+			{Title: "Eiffel Tower", URL: "https://en.wikipedia.org/wiki/Eiffel_Tower", Snippet: "Construction was completed in 1889 as the centerpiece of the World's Fair.", Score: 0.8, Source: search.SourceWikipedia},
+		}, nil
+	})
+
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "research", "confidence": 0.9}]}`)
+	llm.On("Identify factual claims to verify").Reply(`{"claims": [
+		{"claim": "The Eiffel Tower is 330 metres tall"},
+		{"claim": "The Eiffel Tower was completed in 1899"}
+	]}`)
+	llm.On("Fact-check the claims below against the Wikipedia passages").Reply(`{"verdicts": [
+		{"claim": 1, "verdict": "true", "explanation": "Both passages give 330 m [1][2].", "evidence": [
+			{"passage": 1, "quote": "The tower is 330 metres (1,083 ft) tall, about the same height", "stance": "supports"},
+			{"passage": 2, "quote": "at 330 m, is the tallest structure in Paris", "stance": "supports"},
+			{"passage": 2, "quote": "built of solid gold", "stance": "supports"}
+		]},
+		{"claim": 2, "verdict": "FALSE", "explanation": "It was completed in 1889 [3].", "evidence": [
+			{"passage": 3, "quote": "completed in 1889", "stance": "contradicts"},
+			{"passage": 1, "quote": "330 metres", "stance": "supports"}
+		]}
+	]}`)
+	llm.On("synthesize responses from specialist agents").Reply("Checked.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Tools = []multiagent.Tool{search.NewTool(search.WikipediaToolName, "Search Wikipedia", wikipedia)}
+	}})
+
+	h.Send("alice", "fact check: the Eiffel Tower is 330 metres tall and was completed in 1899")
+	if calls := llm.CallsContaining("Verify the following claims based on your knowledge"); len(calls) != 0 {
+		t.Errorf("claims were checked from the model's memory as well")
+	}
+	prompt := lastPrompt(llm, "Fact-check the claims below")
+	if !strings.Contains(prompt, "Claim 2: The Eiffel Tower was completed in 1899\n[3] Eiffel Tower\nConstruction was completed in 1889") {
+		t.Errorf("verification prompt does not number each claim's passages:\n%s", prompt)
+	}
+	answer := lastPrompt(llm, "synthesize responses")
+	if !strings.Contains(answer, `> "completed in 1889" (Eiffel Tower, contradicts)`) || strings.Contains(answer, "solid gold") {
+		t.Errorf("the fact-check does not quote just the evidence found in the passages:\n%s", answer)
+	}
+
+	sessions, err := h.Service.ListResearch(h.Context("alice"), agents.ResearchFilter{Tag: "fact-check"})
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("fact-check sessions %+v (%v)", sessions, err)
+	}
+	session := sessions[0]
+	if len(session.Sources) != 3 || len(session.Findings) != 2 {
+		t.Fatalf("sources %+v and findings %+v, want the 3 passages quoted and a verdict per claim", session.Sources, session.Findings)
+	}
+	// Agreement across two passages, the second ranked half as high, earns
+	// more confidence than a single passage; evidence quoted from another
+	// claim's passages does not count
+	height, year := session.Findings[0], session.Findings[1]
+	if height.Finding != "TRUE: The Eiffel Tower is 330 metres tall" || height.Confidence != 0.78 || len(height.Evidence) != 2 || len(height.Sources) != 2 {
+		t.Errorf("height verdict %+v", height)
+	}
+	if year.Finding != "FALSE: The Eiffel Tower was completed in 1899" || year.Confidence != 0.63 || len(year.Evidence) != 1 || year.Sources[0] != session.Sources[2].ID {
+		t.Errorf("completion verdict %+v", year)
+	}
+}
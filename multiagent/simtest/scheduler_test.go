@@ -0,0 +1,209 @@
+package simtest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/service"
+)
+
+func TestRescheduleMovesEventUnlessItConflicts(t *testing.T) {
+	day := time.Now().UTC().AddDate(0, 0, 7).Format("2006-01-02")
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "schedule", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "reschedule", "confidence": 0.9}`)
+	llm.On("wants to move", "onto the sync").Reply(`{"title": "dentist", "new_start_time": "` + day + ` 14:00"}`)
+	llm.On("wants to move").Reply(`{"title": "dentist", "new_start_time": "` + day + ` 11:00"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm})
+	seedEvent(t, h, "alice", "event_dentist", "Dentist", day+" 09:00", time.Hour)
+	seedEvent(t, h, "alice", "event_sync", "Team sync", day+" 14:00", time.Hour)
+
+	h.Send("alice", "move the dentist onto the sync slot")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "Scheduling Conflict Detected") || !strings.Contains(answer, "Team sync") {
+		t.Errorf("the conflict was not reported:\n%s", answer)
+	}
+	if event := findEvent(h, "alice", "event_dentist"); event.StartTime.Format("2006-01-02 15:04") != day+" 09:00" {
+		t.Errorf("conflicting move changed the event to %s", event.StartTime)
+	}
+
+	h.Send("alice", "move the dentist to 11")
+	event := findEvent(h, "alice", "event_dentist")
+	if event.StartTime.Format("2006-01-02 15:04") != day+" 11:00" || event.EndTime.Sub(event.StartTime) != time.Hour {
+		t.Errorf("dentist is at %s - %s, want %s 11:00 for an hour", event.StartTime, event.EndTime, day)
+	}
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "Event Rescheduled") {
+		t.Errorf("the move was not confirmed:\n%s", answer)
+	}
+	moved := h.Audit(audit.Filter{Types: []audit.EventType{audit.EventRescheduled}})
+	if len(moved) != 1 || moved[0].Subject != "event_dentist" || moved[0].Actor != "scheduler_agent" {
+		t.Errorf("audited reschedules %+v", moved)
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+}
+
+func TestCancelEventOnlyCancelsTheUsersOwn(t *testing.T) {
+	day := time.Now().UTC().AddDate(0, 0, 7).Format("2006-01-02")
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "schedule", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "cancel_event", "confidence": 0.9}`)
+	llm.On("wants to cancel").Reply(`{"title": "dentist"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm})
+	seedEvent(t, h, "alice", "event_dentist", "Dentist", day+" 09:00", time.Hour)
+	seedEvent(t, h, "bob", "event_bob_dentist", "Dentist", day+" 10:00", time.Hour)
+
+	// Cancelling waits for alice's approval
+	h.Send("alice", "cancel my dentist appointment")
+	if event := findEvent(h, "alice", "event_dentist"); event.Status != agents.EventStatusConfirmed {
+		t.Errorf("alice's dentist is %s before she approved", event.Status)
+	}
+	h.Send("alice", "yes")
+	if event := findEvent(h, "alice", "event_dentist"); event.Status != agents.EventStatusCancelled {
+		t.Errorf("alice's dentist is %s, want cancelled", event.Status)
+	}
+	if event := findEvent(h, "bob", "event_bob_dentist"); event.Status == agents.EventStatusCancelled {
+		t.Error("alice cancelled bob's appointment")
+	}
+	cancelled := h.Audit(audit.Filter{Types: []audit.EventType{audit.EventCancelled}})
+	if len(cancelled) != 1 || cancelled[0].Subject != "event_dentist" {
+		t.Errorf("audited cancellations %+v", cancelled)
+	}
+
+	// A cancelled event is no longer there to cancel
+	h.Send("alice", "cancel my dentist appointment")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "I couldn't find an event to cancel") {
+		t.Errorf("cancelling again was not refused:\n%s", answer)
+	}
+}
+
+// seedEvent stores a confirmed event for userID starting at start, a UTC
+// "YYYY-MM-DD HH:MM" time
+func seedEvent(t *testing.T, h *Harness, userID, id, title, start string, duration time.Duration) {
+	t.Helper()
+	startTime, err := time.Parse("2006-01-02 15:04", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeEvent(h, userID, &agents.CalendarEvent{
+		ID:        id,
+		Title:     title,
+		StartTime: startTime,
+		EndTime:   startTime.Add(duration),
+		Status:    agents.EventStatusConfirmed,
+		Timezone:  "UTC",
+	})
+}
+
+// storeEvent stores event in userID's calendar
+func storeEvent(h *Harness, userID string, event *agents.CalendarEvent) {
+	h.t.Helper()
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = SeededAt
+		event.UpdatedAt = event.CreatedAt
+	}
+	event.UserID = userID
+	h.Seed(userID, "calendar_event:"+event.ID, event)
+}
+
+// findEvent returns userID's event with the given ID, failing the test if
+// there is none
+func findEvent(h *Harness, userID, id string) *agents.CalendarEvent {
+	h.t.Helper()
+	for _, event := range h.Events(userID) {
+		if event.ID == id {
+			return event
+		}
+	}
+	h.t.Fatalf("%s has no event %s", userID, id)
+	return nil
+}
+
+func TestSuggestedSlotsFollowPreferences(t *testing.T) {
+	// Monday morning
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 8, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "schedule", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent", "focus time").Reply(`{"intent": "set_preferences", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "check_availability", "confidence": 0.9}`)
+	llm.On("Extract the working hours and scheduling preferences").Reply(`{"focus_blocks": ["09:00-11:00"], "preferred_times": ["14:00-16:00"], "max_meetings_per_day": 2}`)
+	llm.On("Extract availability check details").Reply(`{"start_date": "2026-05-04", "end_date": "2026-05-05", "duration": 60}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	// Tuesday already has as many meetings as alice wants
+	seedEvent(t, h, "alice", "event_review", "Design review", "2026-05-05 10:00", time.Hour)
+	seedEvent(t, h, "alice", "event_sync", "Team sync", "2026-05-05 15:00", time.Hour)
+
+	h.Send("alice", "my focus time is 9 to 11, I prefer meetings from 2 to 4pm and at most 2 a day")
+	h.Send("alice", "when could I meet for an hour on Monday or Tuesday?")
+
+	answer := lastPrompt(llm, "synthesize responses")
+	_, suggested, ok := strings.Cut(answer, "Suggested Times")
+	if !ok {
+		t.Fatalf("no times were suggested:\n%s", answer)
+	}
+	if !strings.Contains(suggested, "1. Mon 2026-05-04 14:00 - 15:00 — within your preferred meeting hours; nothing else scheduled that day") ||
+		!strings.Contains(suggested, "2. Mon 2026-05-04 15:00 - 16:00 — within your preferred meeting hours") {
+		t.Errorf("the preferred afternoon was not suggested first:\n%s", suggested)
+	}
+	if strings.Contains(suggested, "Tue") || strings.Contains(suggested, " 09:") || strings.Contains(suggested, " 10:") {
+		t.Errorf("suggested a full day or focus time:\n%s", suggested)
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+}
+
+func TestTravelCheckBlocksTravelAndFlagsShortGaps(t *testing.T) {
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 8, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "schedule", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent", "takes").Reply(`{"intent": "set_preferences", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "check_travel", "confidence": 0.9}`)
+	llm.On("Extract the working hours and scheduling preferences").Reply(`{"travel_routes": ["Office -> Client HQ: 45"]}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 5, 5, hour, minute, 0, 0, time.UTC)
+	}
+	for _, event := range []*agents.CalendarEvent{
+		{ID: "event_standup", Title: "Standup", Location: "Office", StartTime: at(9, 0), EndTime: at(10, 0)},
+		{ID: "event_visit", Title: "Client visit", Location: "Client HQ", StartTime: at(11, 0), EndTime: at(12, 0)},
+		{ID: "event_lunch", Title: "Lunch with Sam", Location: "Cafe Luna", StartTime: at(12, 10), EndTime: at(13, 0)},
+	} {
+		event.Status, event.Timezone = agents.EventStatusConfirmed, "UTC"
+		storeEvent(h, "alice", event)
+	}
+
+	h.Send("alice", "getting from the office to Client HQ takes 45 minutes")
+	h.Send("alice", "check my travel time this week")
+	answer := lastPrompt(llm, "synthesize responses")
+	if !strings.Contains(answer, "🚗 Blocked Tue 10:15 - 11:00 to travel to Client HQ") {
+		t.Errorf("no travel block before the client visit:\n%s", answer)
+	}
+	if !strings.Contains(answer, "⚠️ Only 10m between 'Client visit' (ends Tue 12:00) and 'Lunch with Sam' (starts 12:10), but you need 30m: 30m to get from Client HQ to Cafe Luna. Suggestion: move 'Lunch with Sam' to 12:30 - 13:20.") {
+		t.Errorf("the short gap before lunch was not flagged:\n%s", answer)
+	}
+
+	// Checking again keeps the block rather than adding another
+	h.Send("alice", "check my travel time this week")
+	var blocks []*agents.CalendarEvent
+	for _, event := range h.Events("alice") {
+		if event.Category == agents.EventCategoryTravel && event.Status != agents.EventStatusCancelled {
+			blocks = append(blocks, event)
+		}
+	}
+	if len(blocks) != 1 || !blocks[0].StartTime.Equal(at(10, 15)) || !blocks[0].EndTime.Equal(at(11, 0)) {
+		t.Errorf("travel blocks %+v, want one from 10:15 to 11:00", blocks)
+	}
+}
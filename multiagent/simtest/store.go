@@ -0,0 +1,100 @@
+package simtest
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+// Write is one write to a RecordingStore
+type Write struct {
+	Op memory.WriteOp
+	// UserID is the user the write was made for, "" outside a user's request
+	UserID string
+	// Key is the key as the agent wrote it, without the user's namespace
+	Key string
+	// Value is what was stored, decoded from JSON as a read would return
+	// it; nil for deletes and updates
+	Value interface{}
+	At    time.Time
+}
+
+// RecordingStore is an in-memory store that records every write made
+// through it
+type RecordingStore struct {
+	*memory.InMemoryStore
+
+	mu     sync.Mutex
+	writes []Write
+}
+
+// NewRecordingStore creates an empty recording store
+func NewRecordingStore() *RecordingStore {
+	return &RecordingStore{InMemoryStore: memory.NewInMemoryStore()}
+}
+
+// Store saves a value and records the write
+func (s *RecordingStore) Store(ctx context.Context, key string, value interface{}) error {
+	return s.StoreWithTTL(ctx, key, value, 0)
+}
+
+// StoreWithTTL saves a value with TTL and records the write
+func (s *RecordingStore) StoreWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := s.InMemoryStore.StoreWithTTL(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	// Keep a copy, since the caller may change value after storing it
+	var stored interface{}
+	if data, err := json.Marshal(value); err == nil {
+		json.Unmarshal(data, &stored)
+	}
+	s.record(ctx, memory.WriteStore, key, stored)
+	return nil
+}
+
+// Update updates an entry and records the write
+func (s *RecordingStore) Update(ctx context.Context, key string, updater func(interface{}) (interface{}, error)) error {
+	if err := s.InMemoryStore.Update(ctx, key, updater); err != nil {
+		return err
+	}
+	s.record(ctx, memory.WriteUpdate, key, nil)
+	return nil
+}
+
+// Delete removes an entry and records the write
+func (s *RecordingStore) Delete(ctx context.Context, key string) error {
+	if err := s.InMemoryStore.Delete(ctx, key); err != nil {
+		return err
+	}
+	s.record(ctx, memory.WriteDelete, key, nil)
+	return nil
+}
+
+// Writes returns the writes to keys starting with prefix, oldest first;
+// keys are matched without the user's namespace
+func (s *RecordingStore) Writes(prefix string) []Write {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var writes []Write
+	for _, write := range s.writes {
+		if strings.HasPrefix(write.Key, prefix) {
+			writes = append(writes, write)
+		}
+	}
+	return writes
+}
+
+func (s *RecordingStore) record(ctx context.Context, op memory.WriteOp, key string, value interface{}) {
+	userID := multiagent.UserIDFromContext(ctx)
+	if userID != "" {
+		key = strings.TrimPrefix(key, memory.UserKeyPrefix(userID))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes = append(s.writes, Write{Op: op, UserID: userID, Key: key, Value: value, At: time.Now()})
+}
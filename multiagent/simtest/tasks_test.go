@@ -0,0 +1,390 @@
+package simtest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/service"
+)
+
+func TestTaskUpdateDeleteAndOverdue(t *testing.T) {
+	// Monday morning
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "task", "confidence": 0.9}]}`)
+	classify := func(message, intent string) {
+		llm.On("Classify the user's request into exactly one intent", message).Reply(`{"intent": "` + intent + `", "confidence": 0.9}`)
+	}
+	classify("push the plumber", "update_task")
+	classify("what's overdue", "overdue")
+	classify("what's due today", "today")
+	classify("delete task pay rent", "delete_task")
+	classify(`Request: "undo"`, "restore_task")
+	llm.On("Identify the task this request wants to change").Reply(`{"title": "call plumber", "priority": "high", "due_date": "2026-05-05 10:00"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	storeTask(h, "alice", &agents.PersonalTask{ID: "task_rent", Title: "Pay rent", Status: agents.PersonalTaskStatusNext, Priority: multiagent.PriorityMedium, DueDate: timePtr(time.Date(2026, 5, 1, 17, 0, 0, 0, time.UTC))})
+	storeTask(h, "alice", &agents.PersonalTask{ID: "task_plumber", Title: "Call plumber", Status: agents.PersonalTaskStatusNext, Priority: multiagent.PriorityLow, DueDate: timePtr(time.Date(2026, 5, 4, 15, 0, 0, 0, time.UTC))})
+
+	h.Send("alice", "push the plumber call to tomorrow at 10 and make it high priority")
+	task := findTask(h, "alice", "task_plumber")
+	if task.Priority != multiagent.PriorityHigh || task.DueDate == nil || !task.DueDate.Equal(time.Date(2026, 5, 5, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("updated task %+v, want high priority due Tuesday 10:00", task)
+	}
+
+	h.Send("alice", "what's overdue?")
+	answer := lastPrompt(llm, "synthesize responses")
+	if !strings.Contains(answer, "1 Overdue Task(s)") || !strings.Contains(answer, "**Pay rent**") || strings.Contains(answer, "Call plumber") {
+		t.Errorf("overdue tasks are not just the rent:\n%s", answer)
+	}
+	h.Send("alice", "what's due today?")
+	if answer := lastPrompt(llm, "synthesize responses"); strings.Contains(answer, "Due today") || strings.Contains(answer, "Call plumber") {
+		t.Errorf("the moved task is still due today:\n%s", answer)
+	}
+
+	// Deleting waits for approval and can be undone
+	h.Send("alice", "delete task pay rent")
+	if findTask(h, "alice", "task_rent").DeletedAt != nil {
+		t.Fatal("the task was deleted before alice approved")
+	}
+	h.Send("alice", "yes")
+	if findTask(h, "alice", "task_rent").DeletedAt == nil {
+		t.Fatal("the task was not deleted once approved")
+	}
+	h.Send("alice", "undo")
+	if findTask(h, "alice", "task_rent").DeletedAt != nil {
+		t.Error("undo did not restore the task")
+	}
+	for _, eventType := range []audit.EventType{audit.TaskDeleted, audit.TaskRestored} {
+		if events := h.Audit(audit.Filter{Types: []audit.EventType{eventType}}); len(events) != 1 || events[0].Subject != "task_rent" {
+			t.Errorf("audited %s %+v", eventType, events)
+		}
+	}
+	if unmatched := llm.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("unscripted prompts: %q", unmatched)
+	}
+}
+
+func TestTimersTrackTimePerTask(t *testing.T) {
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "task", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent", "start working").Reply(`{"intent": "start_timer", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", "stop the timer").Reply(`{"intent": "stop_timer", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", "time report").Reply(`{"intent": "time_report", "confidence": 0.9}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	storeTask(h, "alice", &agents.PersonalTask{ID: "task_report", Title: "Write report", Category: "work", Status: agents.PersonalTaskStatusNext, EstimatedTime: time.Hour})
+	storeTask(h, "alice", &agents.PersonalTask{ID: "task_budget", Title: "Review budget", Category: "finance", Status: agents.PersonalTaskStatusNext})
+
+	h.Send("alice", "start working on write report")
+	clock.Advance(90 * time.Minute)
+	// Starting another task stops the first one's timer
+	h.Send("alice", "start working on review budget")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "⏹️ Stopped 'Write report' after 1h30m.") {
+		t.Errorf("switching tasks did not stop the first timer:\n%s", answer)
+	}
+	clock.Advance(30 * time.Minute)
+	h.Send("alice", "stop the timer")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "⏹️ Stopped 'Review budget' after 30m.") {
+		t.Errorf("stopping the timer was not confirmed:\n%s", answer)
+	}
+
+	if report := findTask(h, "alice", "task_report"); report.ActualTime != 90*time.Minute || report.Status != agents.PersonalTaskStatusInProgress {
+		t.Errorf("report task tracked %s and is %s, want 1h30m in progress", report.ActualTime, report.Status)
+	}
+
+	h.Send("alice", "show my time report")
+	answer := lastPrompt(llm, "synthesize responses")
+	for _, want := range []string{"Total: 2h", "• work: 1h30m (75%)", "• finance: 30m (25%)", "• Write report: 1h30m (75%)"} {
+		if !strings.Contains(answer, want) {
+			t.Errorf("time report is missing %q:\n%s", want, answer)
+		}
+	}
+}
+
+func TestProductivityStatsAndWeeklyReview(t *testing.T) {
+	// Friday evening
+	clock := ids.NewManualClock(time.Date(2026, 5, 8, 17, 0, 0, 0, time.UTC))
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "task", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent", "how productive").Reply(`{"intent": "productivity_stats", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", "walk me through").Reply(`{"intent": "weekly_review", "confidence": 0.9}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	for _, task := range []*agents.PersonalTask{
+		{ID: "task_release", Title: "Ship release", Status: agents.PersonalTaskStatusCompleted, CreatedAt: at(time.April, 27, 9), DueDate: timePtr(at(time.May, 7, 17)), CompletedAt: timePtr(at(time.May, 6, 10))},
+		{ID: "task_taxes", Title: "File taxes", Status: agents.PersonalTaskStatusCompleted, CreatedAt: at(time.April, 20, 9), DueDate: timePtr(at(time.April, 28, 17)), CompletedAt: timePtr(at(time.April, 29, 14))},
+		{ID: "task_rent", Title: "Pay rent", Status: agents.PersonalTaskStatusNext, DueDate: timePtr(at(time.May, 1, 17))},
+		{ID: "task_passport", Title: "Renew passport", Status: agents.PersonalTaskStatusWaiting, WaitingOn: "the consulate", CreatedAt: at(time.April, 28, 9)},
+		{ID: "task_trip", Title: "Plan trip", Status: agents.PersonalTaskStatusNext},
+	} {
+		task.UpdatedAt = task.CreatedAt
+		storeTask(h, "alice", task)
+	}
+
+	h.Send("alice", "how productive have I been lately?")
+	answer := lastPrompt(llm, "synthesize responses")
+	for _, want := range []string{
+		"• Added 3 tasks, finished 2",
+		"• Completion rate: 67% of the tasks added are done",
+		"• Overdue right now: 1",
+		"• Apr 27: 1 done, 0 of 2 due on time",
+		"• May 4: 1 done, 1 of 1 due on time",
+		"• Your most productive day is Wednesday (2 done)",
+	} {
+		if !strings.Contains(answer, want) {
+			t.Errorf("productivity stats are missing %q:\n%s", want, answer)
+		}
+	}
+
+	h.Send("alice", "walk me through my week")
+	answer = lastPrompt(llm, "synthesize responses")
+	for _, want := range []string{
+		"Weekly Review — week of Mon 4 May",
+		"✅ Finished this week: 1",
+		"⚠️ Overdue — reschedule, finish or drop:",
+		"1. **Renew passport** on the consulate (10 days)",
+		"🐢 Stalled next actions",
+	} {
+		if !strings.Contains(answer, want) {
+			t.Errorf("weekly review is missing %q:\n%s", want, answer)
+		}
+	}
+	_, current, _ := strings.Cut(answer, "**Get current**")
+	if !strings.Contains(current, "Pay rent") || !strings.Contains(current, "Plan trip") || strings.Contains(current, "File taxes") {
+		t.Errorf("weekly review lists the wrong tasks to get current on:\n%s", current)
+	}
+}
+
+func TestDependenciesBlockTasksUntilDone(t *testing.T) {
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "task", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent", "depends on").Reply(`{"intent": "set_dependency", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", "is blocked by").Reply(`{"intent": "set_dependency", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", "what should I").Reply(`{"intent": "next_actions", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", "I finished").Reply(`{"intent": "complete_task", "confidence": 0.9}`)
+	llm.On("Identify the two tasks", "publish post depends on").Reply(`{"task": "publish post", "depends_on": "write draft"}`)
+	llm.On("Identify the two tasks", "write draft is blocked by").Reply(`{"task": "write draft", "depends_on": "publish post"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm})
+	storeTask(h, "alice", &agents.PersonalTask{ID: "task_draft", Title: "Write draft", Status: agents.PersonalTaskStatusNext})
+	storeTask(h, "alice", &agents.PersonalTask{ID: "task_publish", Title: "Publish post", Status: agents.PersonalTaskStatusNext})
+
+	h.Send("alice", "publish post depends on write draft")
+	if deps := findTask(h, "alice", "task_publish").Dependencies; len(deps) != 1 || deps[0] != "task_draft" {
+		t.Fatalf("publish post depends on %v, want the draft", deps)
+	}
+
+	// The reverse dependency would leave both waiting forever
+	h.Send("alice", "write draft is blocked by publish post")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "♻️ I can't do that: 'Publish post' already waits for 'Write draft'") {
+		t.Errorf("the cycle was not refused:\n%s", answer)
+	}
+	if deps := findTask(h, "alice", "task_draft").Dependencies; len(deps) != 0 {
+		t.Errorf("write draft depends on %v after a refused cycle", deps)
+	}
+
+	h.Send("alice", "what should I work on next?")
+	answer := lastPrompt(llm, "synthesize responses")
+	actions, blocked, _ := strings.Cut(answer, "🔒 **Blocked**")
+	if !strings.Contains(actions, "Write draft") || strings.Contains(actions, "Publish post") {
+		t.Errorf("next actions include a blocked task:\n%s", answer)
+	}
+	if !strings.Contains(blocked, "**Publish post** — waiting on 'Write draft'") {
+		t.Errorf("the blocked task is not listed as blocked:\n%s", answer)
+	}
+
+	h.Send("alice", "I finished write draft")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "🔓 Now unblocked: 'Publish post'") {
+		t.Errorf("finishing the draft did not unblock the post:\n%s", answer)
+	}
+}
+
+func TestBoardShowsMovedTasks(t *testing.T) {
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "task", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent", "Request: \"move").Reply(`{"intent": "move_task", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", "my board").Reply(`{"intent": "list_tasks", "confidence": 0.9}`)
+	llm.On("Identify the task this request moves", "invoice").Reply(`{"title": "invoice client", "status": "waiting", "waiting_on": "Bob"}`)
+	llm.On("Identify the task this request moves", "report").Reply(`{"title": "write report", "status": "completed"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	storeTask(h, "alice", &agents.PersonalTask{ID: "task_invoice", Title: "Invoice client", Status: agents.PersonalTaskStatusNext})
+	storeTask(h, "alice", &agents.PersonalTask{ID: "task_report", Title: "Write report", Status: agents.PersonalTaskStatusInProgress})
+	storeTask(h, "alice", &agents.PersonalTask{ID: "task_bug", Title: "Fix bug", Status: agents.PersonalTaskStatusInbox})
+
+	h.Send("alice", "move invoice client to waiting on Bob")
+	clock.Advance(time.Hour)
+	h.Send("alice", "move write report to done")
+
+	invoice := findTask(h, "alice", "task_invoice")
+	if invoice.Status != agents.PersonalTaskStatusWaiting || invoice.WaitingOn != "Bob" {
+		t.Errorf("invoice is %s on %q, want waiting on Bob", invoice.Status, invoice.WaitingOn)
+	}
+	if len(invoice.Transitions) != 1 || invoice.Transitions[0].From != agents.PersonalTaskStatusNext || invoice.Transitions[0].To != agents.PersonalTaskStatusWaiting {
+		t.Errorf("invoice transitions %+v, want next to waiting", invoice.Transitions)
+	}
+	report := findTask(h, "alice", "task_report")
+	if report.CompletedAt == nil || !report.CompletedAt.Equal(clock.Now()) {
+		t.Errorf("report completed at %v, want when it was moved to done", report.CompletedAt)
+	}
+
+	h.Send("alice", "show my board")
+	answer := lastPrompt(llm, "synthesize responses")
+	for _, want := range []string{
+		"📥 Inbox 1 │ ➡️ Next 0 │ ⏳ In progress 0 │ ⏸️ Waiting 1 │ ✅ Done 1",
+		"Invoice client — on Bob",
+		"Write report — Mon",
+	} {
+		if !strings.Contains(answer, want) {
+			t.Errorf("board is missing %q:\n%s", want, answer)
+		}
+	}
+}
+
+func TestSubtasksRollUpIntoProgress(t *testing.T) {
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "task", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent").Reply(`{"intent": "subtask", "confidence": 0.9}`)
+	llm.On("Identify the parent task", "order cake to").Reply(`{"title": "plan party", "action": "add", "subtasks": ["book venue", "send invites", "order cake"]}`)
+	llm.On("Identify the parent task", "check off the venue").Reply(`{"title": "plan party", "action": "complete", "subtask": "venue"}`)
+	llm.On("Identify the parent task", "check off 2").Reply(`{"title": "plan party", "action": "complete", "subtask": "2"}`)
+	llm.On("Identify the parent task", "need the cake").Reply(`{"title": "plan party", "action": "remove", "subtask": "cake"}`)
+	llm.On("Identify the parent task", "the steps of").Reply(`{"title": "plan party", "action": "list"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	storeTask(h, "alice", &agents.PersonalTask{ID: "task_party", Title: "Plan party", Status: agents.PersonalTaskStatusNext})
+
+	h.Send("alice", "add book venue, send invites and order cake to plan party")
+	h.Send("alice", "check off the venue on plan party")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "✅ Checked off 'book venue' on 'Plan party'.") || !strings.Contains(answer, "📊 Progress: 33% (1/3 subtasks)") {
+		t.Errorf("checking off the venue was not reported:\n%s", answer)
+	}
+
+	h.Send("alice", "check off 2 on plan party")
+	h.Send("alice", "we don't need the cake for plan party")
+	answer := lastPrompt(llm, "synthesize responses")
+	if !strings.Contains(answer, "📊 Progress: 100% (2/2 subtasks)") || !strings.Contains(answer, "say \"mark Plan party done\" to close the task") {
+		t.Errorf("finishing every step did not suggest closing the task:\n%s", answer)
+	}
+	task := findTask(h, "alice", "task_party")
+	if task.Progress != 100 || task.Status != agents.PersonalTaskStatusNext || len(task.Subtasks) != 2 {
+		t.Errorf("task is %s at %.0f%% with %d subtasks, want still next at 100%% with 2", task.Status, task.Progress, len(task.Subtasks))
+	}
+
+	h.Send("alice", "show the steps of plan party")
+	answer = lastPrompt(llm, "synthesize responses")
+	for _, want := range []string{"☑️ **Plan party** — 100% (2/2)", "1. ✅ book venue — Mon May 4", "2. ✅ send invites — Mon May 4"} {
+		if !strings.Contains(answer, want) {
+			t.Errorf("checklist is missing %q:\n%s", want, answer)
+		}
+	}
+}
+
+func TestTimeBlocksFollowTheirTask(t *testing.T) {
+	clock := ids.NewManualClock(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+	llm := NewScriptedLLM()
+	llm.On("Classify the user's request into the intents").Reply(`{"intents": [{"intent": "task", "confidence": 0.9}]}`)
+	llm.On("Classify the user's request into exactly one intent", "hours for").Reply(`{"intent": "block_time", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", "push the report").Reply(`{"intent": "update_task", "confidence": 0.9}`)
+	llm.On("Classify the user's request into exactly one intent", "I finished").Reply(`{"intent": "complete_task", "confidence": 0.9}`)
+	llm.On("Identify the task this request blocks calendar time for", "afternoon").Reply(`{"title": "report", "start_time": "2026-05-05 14:00", "duration": 120}`)
+	llm.On("Identify the task this request blocks calendar time for").Reply(`{"title": "report", "start_time": "2026-05-05 09:00", "duration": 120}`)
+	llm.On("Identify the task this request wants to change").Reply(`{"title": "report", "due_date": "2026-05-07 17:00"}`)
+	llm.On("synthesize responses from specialist agents").Reply("Done.")
+	h := New(t, Config{LLM: llm, Configure: func(config *service.ServiceConfig) {
+		config.Clock = clock
+	}})
+	storeTask(h, "alice", &agents.PersonalTask{ID: "task_report", Title: "Write report", Status: agents.PersonalTaskStatusNext, DueDate: timePtr(time.Date(2026, 5, 6, 17, 0, 0, 0, time.UTC))})
+	seedEvent(t, h, "alice", "event_sync", "Team sync", "2026-05-05 10:00", time.Hour)
+
+	// The block may not overlap the sync
+	h.Send("alice", "block 2 hours for the report tomorrow morning")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "Scheduling Conflict Detected") || !strings.Contains(answer, "Team sync") {
+		t.Errorf("the conflict with the sync was not reported:\n%s", answer)
+	}
+	if blocks := findTask(h, "alice", "task_report").CalendarBlocks; len(blocks) != 0 {
+		t.Fatalf("a refused block was recorded: %+v", blocks)
+	}
+
+	h.Send("alice", "block 2 hours for the report tomorrow afternoon")
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "🔒 Blocked Tue 2026-05-05 14:00 - 16:00 UTC (2h) for 'Write report'.") {
+		t.Errorf("the block was not confirmed:\n%s", answer)
+	}
+	blocks := findTask(h, "alice", "task_report").CalendarBlocks
+	if len(blocks) != 1 {
+		t.Fatalf("task has blocks %+v, want one", blocks)
+	}
+	eventID := blocks[0].EventID
+	if event := findEvent(h, "alice", eventID); event.Category != agents.EventCategoryFocusTime || event.Title != "Write report" {
+		t.Errorf("block event %+v, want focus time for the report", event)
+	}
+
+	// Moving the due date a day moves the block with it
+	h.Send("alice", "push the report back a day")
+	next := time.Date(2026, 5, 6, 14, 0, 0, 0, time.UTC)
+	h.WaitFor(func() bool {
+		return findEvent(h, "alice", eventID).StartTime.Equal(next)
+	})
+	if blocks := findTask(h, "alice", "task_report").CalendarBlocks; len(blocks) != 1 || !blocks[0].Start.Equal(next) {
+		t.Errorf("task blocks %+v, want one starting %s", blocks, next)
+	}
+
+	// Finishing early frees the block
+	h.Send("alice", "I finished write report")
+	h.WaitFor(func() bool {
+		return findEvent(h, "alice", eventID).Status == agents.EventStatusCancelled
+	})
+	if answer := lastPrompt(llm, "synthesize responses"); !strings.Contains(answer, "🗓️ Calendar: cancelled 1 upcoming time block(s).") {
+		t.Errorf("completing did not mention the freed block:\n%s", answer)
+	}
+}
+
+// storeTask stores task in userID's task list
+func storeTask(h *Harness, userID string, task *agents.PersonalTask) {
+	h.t.Helper()
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = SeededAt
+		task.UpdatedAt = task.CreatedAt
+	}
+	task.UserID = userID
+	h.Seed(userID, "personal_task:"+task.ID, task)
+}
+
+// findTask returns userID's task with the given ID, failing the test if
+// there is none
+func findTask(h *Harness, userID, id string) *agents.PersonalTask {
+	h.t.Helper()
+	for _, task := range h.Tasks(userID) {
+		if task.ID == id {
+			return task
+		}
+	}
+	h.t.Fatalf("%s has no task %s", userID, id)
+	return nil
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,48 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config is how the assistant reaches a Slack workspace
+type Config struct {
+	// BotToken is the app's bot token (xoxb-...), with the chat:write,
+	// app_mentions:read and im:history scopes; it may reference environment
+	// variables, e.g. "$SLACK_BOT_TOKEN"
+	BotToken string `json:"bot_token"`
+	// SigningSecret verifies that event requests come from Slack; it may
+	// reference environment variables
+	SigningSecret string `json:"signing_secret"`
+	// Users maps Slack user IDs to assistant users; anyone else talks to
+	// the assistant as "slack-" and their Slack user ID
+	Users map[string]string `json:"users,omitempty"`
+	// Channels maps Slack channel IDs to assistant users, so everyone
+	// mentioning the assistant in the channel shares one user's data and
+	// conversation
+	Channels map[string]string `json:"channels,omitempty"`
+	// APIURL defaults to https://slack.com/api
+	APIURL string `json:"api_url,omitempty"`
+}
+
+// LoadConfig reads the Slack app's settings from a JSON file:
+//
+//	{"bot_token": "$SLACK_BOT_TOKEN", "signing_secret": "$SLACK_SIGNING_SECRET",
+//	 "users": {"U024BE7LH": "alice"}, "channels": {"C0G9QF9GW": "family"}}
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read slack config: %w", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse slack config: %w", err)
+	}
+	config.BotToken = os.ExpandEnv(config.BotToken)
+	config.SigningSecret = os.ExpandEnv(config.SigningSecret)
+	if config.BotToken == "" || config.SigningSecret == "" {
+		return config, fmt.Errorf("slack config needs a bot_token and a signing_secret")
+	}
+	return config, nil
+}
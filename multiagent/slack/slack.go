@@ -0,0 +1,492 @@
+// Package slack makes a Slack workspace a frontend for the assistant:
+// people message it directly or mention it in a channel, follow its
+// progress in the message's thread, and get their reminders as direct
+// messages.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/progress"
+)
+
+var logger = logging.For("slack")
+
+const (
+	// UserPrefix starts the assistant user IDs of Slack users without a
+	// mapping in Config.Users
+	UserPrefix = "slack-"
+
+	// maxRequestAge rejects replayed event requests
+	maxRequestAge = 5 * time.Minute
+	// progressInterval spaces out edits of a progress message, which
+	// Slack rate limits
+	progressInterval = time.Second
+	// progressLines is how many recent steps a progress message shows
+	progressLines = 5
+	// seenEvents is how long event IDs are kept to ignore Slack's retries
+	seenEvents = 10 * time.Minute
+)
+
+// mentionPattern matches user mentions such as <@U024BE7LH>
+var mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+>`)
+
+// Service is the part of MultiAgentService the adapter uses
+type Service interface {
+	ProcessUserMessage(ctx context.Context, userID string, message string) (string, error)
+	SubscribeProgress(userID string) (<-chan progress.Event, func())
+}
+
+// AdapterConfig holds configuration for creating an Adapter
+type AdapterConfig struct {
+	Service Service
+	Slack   Config
+	// MessageTimeout bounds how long a message waits for the assistant's
+	// reply (default 90 seconds)
+	MessageTimeout time.Duration
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+	// Clock defaults to the system clock
+	Clock multiagent.Clock
+}
+
+// Adapter relays Slack messages to the assistant and posts its replies. It
+// serves Slack's Events API as an http.Handler, and delivers notifications
+// as a notify.Channel.
+type Adapter struct {
+	service Service
+	config  Config
+	apiURL  string
+	timeout time.Duration
+	client  *http.Client
+	clock   multiagent.Clock
+
+	// users and channels reverse Config.Users and Config.Channels, for
+	// delivering notifications
+	users    map[string]string
+	channels map[string]string
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+	wg   sync.WaitGroup
+}
+
+// NewAdapter creates an adapter for config.Slack's workspace
+func NewAdapter(config AdapterConfig) *Adapter {
+	if config.MessageTimeout <= 0 {
+		config.MessageTimeout = 90 * time.Second
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	apiURL := config.Slack.APIURL
+	if apiURL == "" {
+		apiURL = "https://slack.com/api"
+	}
+
+	a := &Adapter{
+		service:  config.Service,
+		config:   config.Slack,
+		apiURL:   strings.TrimRight(apiURL, "/"),
+		timeout:  config.MessageTimeout,
+		client:   config.Client,
+		clock:    ids.ClockOrSystem(config.Clock),
+		users:    make(map[string]string, len(config.Slack.Users)),
+		channels: make(map[string]string, len(config.Slack.Channels)),
+		seen:     make(map[string]time.Time),
+	}
+	for slackUser, userID := range config.Slack.Users {
+		a.users[userID] = slackUser
+	}
+	for channel, userID := range config.Slack.Channels {
+		a.channels[userID] = channel
+	}
+	return a
+}
+
+// Wait blocks until messages being handled have been answered, or ctx ends
+func (a *Adapter) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// envelope is an Events API request
+type envelope struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	EventID   string `json:"event_id"`
+	Event     event  `json:"event"`
+}
+
+// event is a message or app_mention event
+type event struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	User        string `json:"user"`
+	BotID       string `json:"bot_id"`
+	Text        string `json:"text"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+}
+
+// ServeHTTP answers Slack's Events API: it acknowledges each event at once
+// and handles it in the background, as Slack retries events not
+// acknowledged within three seconds
+func (a *Adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	if err := a.verify(r.Header, body); err != nil {
+		logger.Warn("Rejected Slack request", "error", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req envelope
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "invalid event", http.StatusBadRequest)
+		return
+	}
+	switch req.Type {
+	case "url_verification":
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, req.Challenge)
+		return
+	case "event_callback":
+		if a.firstDelivery(req.EventID) {
+			if ev := req.Event; a.wanted(ev) {
+				a.wg.Add(1)
+				go func() {
+					defer a.wg.Done()
+					a.handle(ev)
+				}()
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify checks the request's signature against the signing secret
+func (a *Adapter) verify(header http.Header, body []byte) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("missing request timestamp")
+	}
+	if age := a.clock.Now().Sub(time.Unix(seconds, 0)); age > maxRequestAge || age < -maxRequestAge {
+		return errors.New("stale request timestamp")
+	}
+
+	mac := hmac.New(sha256.New, []byte(a.config.SigningSecret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(header.Get("X-Slack-Signature"))) {
+		return errors.New("invalid request signature")
+	}
+	return nil
+}
+
+// firstDelivery reports whether eventID has not been seen before
+func (a *Adapter) firstDelivery(eventID string) bool {
+	if eventID == "" {
+		return true
+	}
+	now := a.clock.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, at := range a.seen {
+		if now.Sub(at) > seenEvents {
+			delete(a.seen, id)
+		}
+	}
+	if _, ok := a.seen[eventID]; ok {
+		return false
+	}
+	a.seen[eventID] = now
+	return true
+}
+
+// wanted reports whether ev is a person messaging the assistant: a direct
+// message, or a mention in a channel. Bots, the assistant's own posts
+// included, and edits are ignored.
+func (a *Adapter) wanted(ev event) bool {
+	if ev.BotID != "" || ev.Subtype != "" || ev.User == "" {
+		return false
+	}
+	switch ev.Type {
+	case "app_mention":
+		return true
+	case "message":
+		return ev.ChannelType == "im"
+	default:
+		return false
+	}
+}
+
+// UserID returns the assistant user a Slack user talks as in channel
+func (a *Adapter) UserID(slackUser, channel string) string {
+	if userID, ok := a.config.Channels[channel]; ok {
+		return userID
+	}
+	if userID, ok := a.config.Users[slackUser]; ok {
+		return userID
+	}
+	return UserPrefix + slackUser
+}
+
+// handle relays ev to the assistant, keeping a progress message in the
+// thread of ev up to date until the reply is posted
+func (a *Adapter) handle(ev event) {
+	userID := a.UserID(ev.User, ev.Channel)
+	text := strings.TrimSpace(mentionPattern.ReplaceAllString(ev.Text, ""))
+	if text == "" {
+		return
+	}
+	thread := ev.ThreadTS
+	if thread == "" {
+		thread = ev.TS
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+	ctx = logging.WithFields(ctx, logging.KeyUserID, userID, "slack_channel", ev.Channel)
+
+	// Subscribe before sending so no early events are missed
+	events, unsubscribe := a.service.SubscribeProgress(userID)
+	statusTS, err := a.postMessage(ctx, ev.Channel, thread, "⏳ Working on it…")
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to post progress message", "error", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.followProgress(ctx, ev.Channel, statusTS, events)
+	}()
+
+	response, err := a.service.ProcessUserMessage(ctx, userID, text)
+	unsubscribe()
+	<-done
+
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to process Slack message", "error", err)
+		a.finishStatus(ctx, ev.Channel, statusTS, "⚠️ Sorry, something went wrong: "+err.Error())
+		return
+	}
+	a.finishStatus(ctx, ev.Channel, statusTS, "✅ Done")
+
+	// Direct messages read as a chat, channel replies stay in the thread
+	replyThread := thread
+	if ev.ChannelType == "im" && ev.ThreadTS == "" {
+		replyThread = ""
+	}
+	if _, err := a.postMessage(ctx, ev.Channel, replyThread, response); err != nil {
+		logger.WarnContext(ctx, "Failed to post reply", "error", err)
+	}
+}
+
+// followProgress edits the progress message to show the latest steps until
+// events is closed
+func (a *Adapter) followProgress(ctx context.Context, channel, ts string, events <-chan progress.Event) {
+	var lines []string
+	var lastUpdate time.Time
+	pending := false
+	flush := func() {
+		if ts == "" || !pending {
+			return
+		}
+		pending = false
+		lastUpdate = a.clock.Now()
+		if err := a.updateMessage(ctx, channel, ts, "⏳ Working on it…\n"+strings.Join(lines, "\n")); err != nil {
+			logger.DebugContext(ctx, "Failed to update progress message", "error", err)
+		}
+	}
+
+	for event := range events {
+		line := describe(event)
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+		if len(lines) > progressLines {
+			lines = lines[len(lines)-progressLines:]
+		}
+		pending = true
+		if a.clock.Now().Sub(lastUpdate) >= progressInterval {
+			flush()
+		}
+	}
+	flush()
+}
+
+// describe renders an event as a line of a progress message, or "" for
+// events not worth showing
+func describe(event progress.Event) string {
+	switch event.Type {
+	case progress.RequestReceived, progress.Completed, progress.Failed, progress.PartialResult:
+		return ""
+	}
+	line := "• " + strings.ReplaceAll(string(event.Type), "_", " ")
+	if event.AgentID != "" {
+		line += " · " + string(event.AgentID)
+	}
+	if detail := strings.TrimSpace(event.Detail); detail != "" {
+		if len(detail) > 120 {
+			detail = detail[:120] + "…"
+		}
+		line += ": " + detail
+	}
+	return line
+}
+
+func (a *Adapter) finishStatus(ctx context.Context, channel, ts, text string) {
+	if ts == "" {
+		return
+	}
+	if err := a.updateMessage(ctx, channel, ts, text); err != nil {
+		logger.WarnContext(ctx, "Failed to update progress message", "error", err)
+	}
+}
+
+// Name implements notify.Channel
+func (a *Adapter) Name() string { return "slack" }
+
+// Send implements notify.Channel, posting n to the Slack channel mapped to
+// its user, or else as a direct message to the user's Slack account
+func (a *Adapter) Send(ctx context.Context, n notify.Notification) error {
+	target, ok := a.channels[n.UserID]
+	if !ok {
+		target, ok = a.users[n.UserID]
+	}
+	if !ok {
+		target, ok = strings.CutPrefix(n.UserID, UserPrefix)
+	}
+	if !ok || target == "" {
+		return fmt.Errorf("user %q has no slack account", n.UserID)
+	}
+	_, err := a.postMessage(ctx, target, "", fmt.Sprintf("🔔 *%s*\n%s", n.Title, n.Body))
+	return err
+}
+
+// Route returns config with the adapter added to the notification channels
+// of every user it maps and of every Slack user without a mapping; those
+// with no channels of their own get it instead of the defaults
+func (a *Adapter) Route(config notify.DispatcherConfig) notify.DispatcherConfig {
+	users := make(map[string][]notify.Channel, len(config.Users))
+	for userID, channels := range config.Users {
+		users[userID] = channels
+	}
+	for _, mapping := range []map[string]string{a.config.Users, a.config.Channels} {
+		for _, userID := range mapping {
+			users[userID] = append(append([]notify.Channel(nil), users[userID]...), a)
+		}
+	}
+	config.Users = users
+
+	prefixes := make(map[string][]notify.Channel, len(config.Prefixes)+1)
+	for prefix, channels := range config.Prefixes {
+		prefixes[prefix] = channels
+	}
+	prefixes[UserPrefix] = append(append([]notify.Channel(nil), prefixes[UserPrefix]...), a)
+	config.Prefixes = prefixes
+	return config
+}
+
+// postMessage posts text to channel, in thread if it is set, and returns
+// the new message's timestamp
+func (a *Adapter) postMessage(ctx context.Context, channel, thread, text string) (string, error) {
+	body := map[string]string{"channel": channel, "text": text}
+	if thread != "" {
+		body["thread_ts"] = thread
+	}
+	var resp struct {
+		TS string `json:"ts"`
+	}
+	if err := a.call(ctx, "chat.postMessage", body, &resp); err != nil {
+		return "", err
+	}
+	return resp.TS, nil
+}
+
+func (a *Adapter) updateMessage(ctx context.Context, channel, ts, text string) error {
+	return a.call(ctx, "chat.update", map[string]string{"channel": channel, "ts": ts, "text": text}, nil)
+}
+
+// call invokes a Web API method and decodes its response into into
+func (a *Adapter) call(ctx context.Context, method string, body interface{}, into interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.apiURL+"/"+method, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+a.config.BotToken)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach slack: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack %s returned %s", method, resp.Status)
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", method, err)
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", method, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("slack %s failed: %s", method, status.Error)
+	}
+	if into != nil {
+		if err := json.Unmarshal(raw, into); err != nil {
+			return fmt.Errorf("failed to decode %s response: %w", method, err)
+		}
+	}
+	return nil
+}
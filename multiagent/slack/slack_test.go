@@ -0,0 +1,224 @@
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/progress"
+)
+
+const testSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+type fakeService struct {
+	hub *progress.Hub
+
+	mu       sync.Mutex
+	received map[string]string
+}
+
+func (f *fakeService) ProcessUserMessage(ctx context.Context, userID string, message string) (string, error) {
+	f.hub.Publish(progress.Event{Type: progress.AgentStarted, ConversationID: userID, AgentID: "task_manager_agent", Detail: "adding a task"})
+	f.mu.Lock()
+	f.received[userID] = message
+	f.mu.Unlock()
+	if message == "fail" {
+		return "", fmt.Errorf("no LLM")
+	}
+	return "Added it to your list.", nil
+}
+
+func (f *fakeService) SubscribeProgress(userID string) (<-chan progress.Event, func()) {
+	return f.hub.Subscribe(userID)
+}
+
+// call is a Web API request the fake Slack received
+type call struct {
+	method string
+	body   map[string]string
+}
+
+type fakeSlack struct {
+	mu    sync.Mutex
+	calls []call
+}
+
+func (f *fakeSlack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+		io.WriteString(w, `{"ok": false, "error": "invalid_auth"}`)
+		return
+	}
+	var body map[string]string
+	json.NewDecoder(r.Body).Decode(&body)
+	f.mu.Lock()
+	f.calls = append(f.calls, call{method: strings.TrimPrefix(r.URL.Path, "/"), body: body})
+	ts := strconv.Itoa(len(f.calls))
+	f.mu.Unlock()
+	fmt.Fprintf(w, `{"ok": true, "ts": "%s"}`, ts)
+}
+
+func (f *fakeSlack) recorded() []call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]call(nil), f.calls...)
+}
+
+type fixedClock struct{ now time.Time }
+
+func (c fixedClock) Now() time.Time { return c.now }
+
+var testNow = time.Unix(1700000000, 0)
+
+func newTestAdapter(t *testing.T) (*fakeService, *fakeSlack, *Adapter) {
+	t.Helper()
+	api := &fakeSlack{}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	fake := &fakeService{hub: progress.NewHub(), received: make(map[string]string)}
+	adapter := NewAdapter(AdapterConfig{
+		Service: fake,
+		Slack: Config{
+			BotToken:      "xoxb-test",
+			SigningSecret: testSecret,
+			Users:         map[string]string{"UALICE": "alice"},
+			Channels:      map[string]string{"CFAMILY": "family"},
+			APIURL:        server.URL,
+		},
+		Clock: fixedClock{testNow},
+	})
+	return fake, api, adapter
+}
+
+// deliver sends body to the adapter signed as Slack would sign it
+func deliver(adapter *Adapter, body string, secret string) *httptest.ResponseRecorder {
+	timestamp := strconv.FormatInt(testNow.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+
+	req := httptest.NewRequest(http.MethodPost, "/slack/events", strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	adapter.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestVerification(t *testing.T) {
+	_, _, adapter := newTestAdapter(t)
+
+	rec := deliver(adapter, `{"type": "url_verification", "challenge": "3eZbrw1aB"}`, testSecret)
+	if rec.Code != http.StatusOK || rec.Body.String() != "3eZbrw1aB" {
+		t.Errorf("url_verification: status %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	rec = deliver(adapter, `{"type": "url_verification", "challenge": "3eZbrw1aB"}`, "wrong secret")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestDirectMessage(t *testing.T) {
+	fake, api, adapter := newTestAdapter(t)
+
+	body := `{"type": "event_callback", "event_id": "Ev1", "event": {"type": "message", "channel_type": "im",
+		"user": "UBOB", "channel": "DBOB", "text": "remind me to call mom", "ts": "100.1"}}`
+	if rec := deliver(adapter, body, testSecret); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	// Slack retries events it thinks were not acknowledged
+	deliver(adapter, body, testSecret)
+	adapter.Wait(context.Background())
+
+	if got := fake.received[UserPrefix+"UBOB"]; got != "remind me to call mom" || len(fake.received) != 1 {
+		t.Fatalf("received %v", fake.received)
+	}
+
+	calls := api.recorded()
+	if len(calls) < 3 {
+		t.Fatalf("want a progress post, its updates and a reply, got %+v", calls)
+	}
+	status, reply := calls[0], calls[len(calls)-1]
+	if status.method != "chat.postMessage" || status.body["thread_ts"] != "100.1" {
+		t.Errorf("progress not posted in the message's thread: %+v", status)
+	}
+	if reply.method != "chat.postMessage" || reply.body["text"] != "Added it to your list." || reply.body["thread_ts"] != "" {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+	finished := calls[len(calls)-2]
+	if finished.method != "chat.update" || finished.body["ts"] != "1" || !strings.Contains(finished.body["text"], "Done") {
+		t.Errorf("progress message not finished: %+v", finished)
+	}
+	sawStep := false
+	for _, c := range calls {
+		sawStep = sawStep || strings.Contains(c.body["text"], "task_manager_agent: adding a task")
+	}
+	if !sawStep {
+		t.Errorf("progress never showed the agent's step: %+v", calls)
+	}
+}
+
+func TestChannelMention(t *testing.T) {
+	fake, api, adapter := newTestAdapter(t)
+
+	deliver(adapter, `{"type": "event_callback", "event_id": "Ev2", "event": {"type": "app_mention",
+		"user": "UALICE", "channel": "CFAMILY", "text": "<@UBOT> fail", "ts": "200.1"}}`, testSecret)
+	deliver(adapter, `{"type": "event_callback", "event_id": "Ev3", "event": {"type": "message",
+		"bot_id": "BBOT", "channel_type": "im", "channel": "DBOB", "text": "echo", "ts": "201.1"}}`, testSecret)
+	adapter.Wait(context.Background())
+
+	if got := fake.received["family"]; got != "fail" || len(fake.received) != 1 {
+		t.Fatalf("received %v", fake.received)
+	}
+	calls := api.recorded()
+	last := calls[len(calls)-1]
+	if last.method != "chat.update" || !strings.Contains(last.body["text"], "no LLM") {
+		t.Errorf("error not reported on the progress message: %+v", calls)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	_, api, adapter := newTestAdapter(t)
+	ctx := context.Background()
+
+	for userID, want := range map[string]string{"alice": "UALICE", "family": "CFAMILY", UserPrefix + "UBOB": "UBOB"} {
+		if err := adapter.Send(ctx, notify.Notification{UserID: userID, Title: "Reminder", Body: "Call mom"}); err != nil {
+			t.Fatalf("Send(%s): %v", userID, err)
+		}
+		calls := api.recorded()
+		if got := calls[len(calls)-1].body["channel"]; got != want {
+			t.Errorf("%s: posted to %q, want %q", userID, got, want)
+		}
+	}
+	if err := adapter.Send(ctx, notify.Notification{UserID: "bob"}); err == nil {
+		t.Error("Send to a user without a Slack account succeeded")
+	}
+
+	console := notify.NewConsoleChannel(io.Discard)
+	config := adapter.Route(notify.DispatcherConfig{
+		Default: []notify.Channel{console},
+		Users:   map[string][]notify.Channel{"alice": {console}},
+	})
+	dispatcher := notify.NewDispatcher(config)
+	if got := dispatcher.Channels("alice"); len(got) != 2 || got[1] != notify.Channel(adapter) {
+		t.Errorf("alice's channels = %v", got)
+	}
+	if got := dispatcher.Channels(UserPrefix + "UBOB"); len(got) != 1 || got[0] != notify.Channel(adapter) {
+		t.Errorf("Slack user's channels = %v", got)
+	}
+	if got := dispatcher.Channels("bob"); len(got) != 1 || got[0] != notify.Channel(console) {
+		t.Errorf("bob's channels = %v", got)
+	}
+}
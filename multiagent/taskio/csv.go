@@ -0,0 +1,297 @@
+package taskio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	csvDate     = "2006-01-02"
+	csvDateTime = "2006-01-02 15:04"
+)
+
+// csvHeader is the layout EncodeCSV writes
+var csvHeader = []string{
+	"id", "parent_id", "title", "description", "priority", "due", "all_day", "recurrence",
+	"labels", "project", "duration_minutes", "status", "completed_at", "created_at",
+}
+
+// csvAliases are the column names other apps use for each field, Todoist's
+// and TickTick's exports among them, after normalizing
+var csvAliases = map[string][]string{
+	"id":           {"id", "task_id", "taskid"},
+	"parent_id":    {"parent_id", "parentid", "parent"},
+	"title":        {"title", "name", "task", "content"},
+	"description":  {"description", "notes", "note", "content"},
+	"priority":     {"priority"},
+	"due":          {"due", "due_date", "date", "deadline"},
+	"all_day":      {"all_day", "is_all_day"},
+	"recurrence":   {"recurrence", "repeat", "recurring"},
+	"labels":       {"labels", "tags"},
+	"project":      {"project", "list", "list_name", "folder_name"},
+	"duration":     {"duration_minutes", "duration", "estimate"},
+	"status":       {"status", "completed"},
+	"completed_at": {"completed_at", "completed_time", "completion_date"},
+	"created_at":   {"created_at", "created_time", "created"},
+	"type":         {"type"},
+	"indent":       {"indent"},
+}
+
+// csvTimeLayouts are the date formats DecodeCSV reads, most specific first
+var csvTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	csvDateTime,
+}
+
+// priorityNames are the words EncodeCSV writes for priorities
+var priorityNames = map[Priority]string{
+	PriorityNormal: "normal",
+	PriorityMedium: "medium",
+	PriorityHigh:   "high",
+	PriorityUrgent: "urgent",
+}
+
+// EncodeCSV writes tasks as CSV with a header row. Due times are written in
+// their own location.
+func EncodeCSV(w io.Writer, tasks []Task) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+	for _, task := range tasks {
+		var due, allDay, recurrence, duration, completedAt, created string
+		if task.Due != nil {
+			due = task.Due.Format(csvDateTime)
+			if task.AllDay {
+				due, allDay = task.Due.Format(csvDate), "true"
+			}
+		}
+		if task.Recurrence != nil {
+			recurrence = task.Recurrence.String()
+		}
+		if task.Duration > 0 {
+			duration = strconv.Itoa(int(task.Duration.Minutes()))
+		}
+		status := "open"
+		if task.Completed {
+			status = "completed"
+		}
+		if task.CompletedAt != nil {
+			completedAt = task.CompletedAt.Format(time.RFC3339)
+		}
+		if !task.CreatedAt.IsZero() {
+			created = task.CreatedAt.Format(time.RFC3339)
+		}
+		priority := priorityNames[task.Priority]
+		if priority == "" {
+			priority = priorityNames[PriorityNormal]
+		}
+
+		record := []string{
+			task.ID, task.ParentID, task.Title, task.Description, priority, due, allDay, recurrence,
+			strings.Join(task.Labels, ", "), task.Project, duration, status, completedAt, created,
+		}
+		if err := out.Write(record); err != nil {
+			return fmt.Errorf("failed to write task %q: %w", task.Title, err)
+		}
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
+
+// DecodeCSV reads tasks from CSV with a header row, recognizing the column
+// names of EncodeCSV, Todoist's and TickTick's exports. Numeric priorities
+// follow Todoist's template when the file has a TYPE column (1 is the most
+// urgent) and TickTick's scale (0, 1, 3, 5) otherwise. Dates without a
+// zone are read in loc.
+func DecodeCSV(r io.Reader, loc *time.Location) ([]Task, error) {
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = -1
+	in.LazyQuotes = true
+
+	header, err := in.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	columns := csvColumns(header)
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("csv has no title column")
+	}
+	_, todoistTemplate := columns["type"]
+
+	var tasks []Task
+	var parents []string // By indent level, for Todoist's template
+	for line := 2; ; line++ {
+		record, err := in.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv line %d: %w", line, err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if kind := strings.ToLower(field("type")); kind != "" && kind != "task" {
+			continue
+		}
+		task := Task{
+			ID:          field("id"),
+			ParentID:    field("parent_id"),
+			Title:       field("title"),
+			Description: field("description"),
+			Priority:    parseCSVPriority(field("priority"), todoistTemplate),
+			Project:     field("project"),
+			Completed:   parseCompleted(field("status")),
+		}
+		if task.Title == "" {
+			continue
+		}
+		if task.ID == "" {
+			task.ID = fmt.Sprintf("csv_%d", line)
+		}
+
+		for _, label := range strings.Split(field("labels"), ",") {
+			if label = strings.TrimPrefix(strings.TrimSpace(label), "@"); label != "" {
+				task.Labels = append(task.Labels, label)
+			}
+		}
+		if due := field("due"); due != "" {
+			if at, allDay, ok := parseCSVTime(due, loc); ok {
+				task.Due, task.AllDay = &at, allDay || strings.EqualFold(field("all_day"), "true")
+			} else if rule, ok := ParseRecurrence(due); ok {
+				// Todoist's template keeps recurring dates as "every week"
+				task.Recurrence = rule
+			}
+		}
+		if rule, ok := ParseRecurrence(field("recurrence")); ok {
+			task.Recurrence = rule
+		}
+		if minutes, err := strconv.Atoi(field("duration")); err == nil && minutes > 0 {
+			task.Duration = time.Duration(minutes) * time.Minute
+		}
+		if at, _, ok := parseCSVTime(field("completed_at"), loc); ok {
+			task.CompletedAt = &at
+			task.Completed = true
+		}
+		if at, _, ok := parseCSVTime(field("created_at"), loc); ok {
+			task.CreatedAt = at
+		}
+
+		if indent, err := strconv.Atoi(field("indent")); err == nil && indent >= 1 {
+			if task.ParentID == "" && indent > 1 && indent-2 < len(parents) {
+				task.ParentID = parents[indent-2]
+			}
+			parents = append(parents[:min(indent-1, len(parents))], task.ID)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// csvColumns maps each field to its column in header, by the first alias
+// found. A column serves one field only, so "content" is the title in
+// Todoist's template but the description in TickTick's export, which has
+// a title column.
+func csvColumns(header []string) map[string]int {
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+
+	columns := make(map[string]int)
+	used := make(map[int]bool)
+	for _, field := range []string{"id", "parent_id", "title", "description", "priority", "due", "all_day", "recurrence", "labels", "project", "duration", "status", "completed_at", "created_at", "type", "indent"} {
+		for _, alias := range csvAliases[field] {
+			if i, ok := index[alias]; ok && !used[i] {
+				columns[field] = i
+				used[i] = true
+				break
+			}
+		}
+	}
+	return columns
+}
+
+// parseCSVPriority reads a priority word or number
+func parseCSVPriority(value string, todoistTemplate bool) Priority {
+	value = strings.ToLower(value)
+	for priority, name := range priorityNames {
+		if value == name {
+			return priority
+		}
+	}
+	switch value {
+	case "low", "none", "":
+		return PriorityNormal
+	case "critical":
+		return PriorityUrgent
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(value, "p"))
+	if err != nil {
+		return PriorityNormal
+	}
+	if todoistTemplate || strings.HasPrefix(value, "p") {
+		// Todoist's p1 is its most urgent
+		if n >= 1 && n <= 4 {
+			return Priority(5 - n)
+		}
+		return PriorityNormal
+	}
+	switch {
+	case n >= 5:
+		return PriorityHigh
+	case n >= 3:
+		return PriorityMedium
+	default:
+		return PriorityNormal
+	}
+}
+
+// parseCompleted reads a status column, where TickTick marks completed
+// tasks 1 or 2
+func parseCompleted(value string) bool {
+	switch strings.ToLower(value) {
+	case "completed", "complete", "done", "true", "yes", "x", "1", "2":
+		return true
+	}
+	return false
+}
+
+// parseCSVTime reads a date or date and time, reporting whether it was a
+// date alone
+func parseCSVTime(value string, loc *time.Location) (time.Time, bool, bool) {
+	if value == "" {
+		return time.Time{}, false, false
+	}
+	for _, layout := range csvTimeLayouts {
+		if at, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return at, false, true
+		}
+	}
+	if at, err := time.ParseInLocation(csvDate, value, loc); err == nil {
+		return at, true, true
+	}
+	return time.Time{}, false, false
+}
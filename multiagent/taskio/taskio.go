@@ -0,0 +1,124 @@
+// Package taskio reads and writes task lists in Todoist's REST format and
+// as CSV — the generic layout this package writes, plus the exports of
+// Todoist and TickTick — so tasks can move between the assistant and other
+// to-do apps with their priorities, due dates, labels and recurrence.
+package taskio
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Priority is a task's priority on Todoist's API scale, where higher is
+// more urgent
+type Priority int
+
+const (
+	PriorityNormal Priority = 1
+	PriorityMedium Priority = 2
+	PriorityHigh   Priority = 3
+	PriorityUrgent Priority = 4
+)
+
+// Task is a to-do in a form every supported format can carry
+type Task struct {
+	ID string
+	// ParentID is the ID of the task this is a subtask of
+	ParentID    string
+	Title       string
+	Description string
+	Priority    Priority
+	// Due is when the task is due; all-day due dates are midnight in the
+	// location they were read in
+	Due        *time.Time
+	AllDay     bool
+	Recurrence *Recurrence
+	Labels     []string
+	Project    string
+	// Duration is how long the task is expected to take
+	Duration    time.Duration
+	Completed   bool
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+}
+
+// Recurrence units
+const (
+	UnitDay   = "day"
+	UnitWeek  = "week"
+	UnitMonth = "month"
+	UnitYear  = "year"
+)
+
+// Recurrence is how often a task repeats: every Interval Units
+type Recurrence struct {
+	Interval int
+	Unit     string
+}
+
+var (
+	everyPattern = regexp.MustCompile(`^every\s+(?:(other|\d+)\s+)?(day|week|month|year)s?$`)
+	rrulePart    = regexp.MustCompile(`(?i)(FREQ|INTERVAL)=(\w+)`)
+)
+
+// String writes the recurrence the way Todoist reads it, e.g. "every 2
+// weeks"
+func (r Recurrence) String() string {
+	if r.Interval <= 1 {
+		return "every " + r.Unit
+	}
+	return fmt.Sprintf("every %d %ss", r.Interval, r.Unit)
+}
+
+// ParseRecurrence reads "daily", "every week", "every 2 months", "every
+// other day" or an RRULE such as TickTick's "FREQ=WEEKLY;INTERVAL=2"
+func ParseRecurrence(s string) (*Recurrence, bool) {
+	s = strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	switch s {
+	case "daily", "every day":
+		return &Recurrence{Interval: 1, Unit: UnitDay}, true
+	case "weekly", "every week":
+		return &Recurrence{Interval: 1, Unit: UnitWeek}, true
+	case "monthly", "every month":
+		return &Recurrence{Interval: 1, Unit: UnitMonth}, true
+	case "yearly", "annually", "every year":
+		return &Recurrence{Interval: 1, Unit: UnitYear}, true
+	}
+	if match := everyPattern.FindStringSubmatch(s); match != nil {
+		interval := 1
+		switch match[1] {
+		case "":
+		case "other":
+			interval = 2
+		default:
+			interval, _ = strconv.Atoi(match[1])
+		}
+		if interval < 1 {
+			return nil, false
+		}
+		return &Recurrence{Interval: interval, Unit: match[2]}, true
+	}
+
+	parts := rrulePart.FindAllStringSubmatch(s, -1)
+	if len(parts) == 0 {
+		return nil, false
+	}
+	rule := &Recurrence{Interval: 1}
+	for _, part := range parts {
+		switch strings.ToUpper(part[1]) {
+		case "FREQ":
+			rule.Unit = map[string]string{"daily": UnitDay, "weekly": UnitWeek, "monthly": UnitMonth, "yearly": UnitYear}[strings.ToLower(part[2])]
+		case "INTERVAL":
+			if interval, err := strconv.Atoi(part[2]); err == nil && interval > 0 {
+				rule.Interval = interval
+			}
+		}
+	}
+	if rule.Unit == "" {
+		return nil, false
+	}
+	return rule, true
+}
@@ -0,0 +1,191 @@
+package taskio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleTasks(loc *time.Location) []Task {
+	due := time.Date(2026, 3, 2, 17, 30, 0, 0, loc)
+	day := time.Date(2026, 3, 5, 0, 0, 0, 0, loc)
+	done := time.Date(2026, 2, 27, 9, 0, 0, 0, time.UTC)
+	return []Task{
+		{
+			ID:          "task_1",
+			Title:       "Write report, final",
+			Description: "Quarterly numbers\nand \"charts\"",
+			Priority:    PriorityUrgent,
+			Due:         &due,
+			Labels:      []string{"work", "writing"},
+			Project:     "Q1",
+			Duration:    90 * time.Minute,
+			CreatedAt:   time.Date(2026, 2, 20, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:         "task_2",
+			Title:      "Water plants",
+			Priority:   PriorityNormal,
+			Due:        &day,
+			AllDay:     true,
+			Recurrence: &Recurrence{Interval: 2, Unit: UnitWeek},
+		},
+		{ID: "task_1_sub", ParentID: "task_1", Title: "Collect numbers", Priority: PriorityNormal, Completed: true, CompletedAt: &done},
+	}
+}
+
+func checkRoundTrip(t *testing.T, got, want []Task) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d tasks, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.ID != w.ID || g.ParentID != w.ParentID || g.Title != w.Title || g.Description != w.Description ||
+			g.Priority != w.Priority || g.Project != w.Project || g.Duration != w.Duration ||
+			g.Completed != w.Completed || g.AllDay != w.AllDay || strings.Join(g.Labels, "|") != strings.Join(w.Labels, "|") {
+			t.Errorf("task %d: got %+v, want %+v", i, g, w)
+		}
+		if (g.Due == nil) != (w.Due == nil) || (w.Due != nil && !g.Due.Equal(*w.Due)) {
+			t.Errorf("task %d: due %v, want %v", i, g.Due, w.Due)
+		}
+		if (g.Recurrence == nil) != (w.Recurrence == nil) || (w.Recurrence != nil && *g.Recurrence != *w.Recurrence) {
+			t.Errorf("task %d: recurrence %v, want %v", i, g.Recurrence, w.Recurrence)
+		}
+		if (g.CompletedAt == nil) != (w.CompletedAt == nil) || (w.CompletedAt != nil && !g.CompletedAt.Equal(*w.CompletedAt)) {
+			t.Errorf("task %d: completed at %v, want %v", i, g.CompletedAt, w.CompletedAt)
+		}
+	}
+}
+
+func TestTodoist_RoundTrip(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	tasks := sampleTasks(loc)
+
+	var buf bytes.Buffer
+	if err := EncodeTodoist(&buf, tasks); err != nil {
+		t.Fatalf("EncodeTodoist: %v", err)
+	}
+	for _, want := range []string{`"priority": 4`, `"string": "every 2 weeks"`, `"is_recurring": true`, `"name": "Q1"`, `"parent_id": "task_1"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %s in:\n%s", want, buf.String())
+		}
+	}
+
+	got, err := DecodeTodoist(&buf, loc)
+	if err != nil {
+		t.Fatalf("DecodeTodoist: %v", err)
+	}
+	checkRoundTrip(t, got, tasks)
+}
+
+func TestDecodeTodoist_RESTArray(t *testing.T) {
+	data := `[
+	  {"id": "2995104339", "project_id": "2203306141", "content": "Buy Milk", "description": "", "is_completed": false,
+	   "labels": ["Food", "Shopping"], "parent_id": null, "priority": 3,
+	   "due": {"date": "2026-09-01", "is_recurring": false, "datetime": "2026-09-01T12:00:00.000000Z", "string": "tomorrow at 12", "timezone": "UTC"},
+	   "duration": {"amount": 15, "unit": "minute"}},
+	  {"id": "2995104340", "content": "Pay rent", "priority": 1,
+	   "due": {"date": "2026-09-01", "is_recurring": true, "string": "every month"}}
+	]`
+	tasks, err := DecodeTodoist(strings.NewReader(data), time.UTC)
+	if err != nil {
+		t.Fatalf("DecodeTodoist: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("got %d tasks, want 2", len(tasks))
+	}
+	milk := tasks[0]
+	if milk.Priority != PriorityHigh || milk.Duration != 15*time.Minute || milk.AllDay ||
+		!milk.Due.Equal(time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)) || len(milk.Labels) != 2 {
+		t.Errorf("unexpected task: %+v", milk)
+	}
+	rent := tasks[1]
+	if !rent.AllDay || rent.Recurrence == nil || *rent.Recurrence != (Recurrence{Interval: 1, Unit: UnitMonth}) {
+		t.Errorf("unexpected recurring task: %+v", rent)
+	}
+}
+
+func TestCSV_RoundTrip(t *testing.T) {
+	loc := time.FixedZone("UTC-5", -5*60*60)
+	tasks := sampleTasks(loc)
+
+	var buf bytes.Buffer
+	if err := EncodeCSV(&buf, tasks); err != nil {
+		t.Fatalf("EncodeCSV: %v", err)
+	}
+	got, err := DecodeCSV(&buf, loc)
+	if err != nil {
+		t.Fatalf("DecodeCSV: %v", err)
+	}
+	checkRoundTrip(t, got, tasks)
+}
+
+func TestDecodeCSV_OtherApps(t *testing.T) {
+	tickTick := "\"Folder Name\",\"List Name\",\"Title\",\"Tags\",\"Content\",\"Is All Day\",\"Due Date\",\"Repeat\",\"Priority\",\"Status\",\"Created Time\",\"Completed Time\",\"taskId\",\"parentId\"\n" +
+		"\"\",\"Home\",\"Clean gutters\",\"chores,outside\",\"Use the ladder\",\"true\",\"2026-04-11T00:00:00+0000\",\"FREQ=MONTHLY;INTERVAL=3\",\"5\",\"0\",\"2026-04-01T10:00:00+0000\",\"\",\"1\",\"\"\n" +
+		"\"\",\"Home\",\"Buy ladder\",\"\",\"\",\"false\",\"\",\"\",\"0\",\"2\",\"2026-04-01T10:00:00+0000\",\"2026-04-02T18:00:00+0000\",\"2\",\"1\"\n"
+	tasks, err := DecodeCSV(strings.NewReader(tickTick), time.UTC)
+	if err != nil {
+		t.Fatalf("DecodeCSV: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("got %d tasks, want 2", len(tasks))
+	}
+	gutters := tasks[0]
+	if gutters.Title != "Clean gutters" || gutters.Description != "Use the ladder" || gutters.Project != "Home" ||
+		gutters.Priority != PriorityHigh || !gutters.AllDay || gutters.Recurrence == nil ||
+		*gutters.Recurrence != (Recurrence{Interval: 3, Unit: UnitMonth}) || strings.Join(gutters.Labels, "|") != "chores|outside" {
+		t.Errorf("unexpected TickTick task: %+v", gutters)
+	}
+	if ladder := tasks[1]; !ladder.Completed || ladder.CompletedAt == nil || ladder.ParentID != "1" {
+		t.Errorf("unexpected TickTick subtask: %+v", ladder)
+	}
+
+	todoist := "TYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE\n" +
+		"section,Errands,,,,,,,,\n" +
+		"task,Plan trip @travel,Book early,1,1,,,2026-05-01,en,UTC\n" +
+		"task,Book hotel,,4,2,,,every week,en,UTC\n"
+	tasks, err = DecodeCSV(strings.NewReader(todoist), time.UTC)
+	if err != nil {
+		t.Fatalf("DecodeCSV: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("got %d tasks, want 2", len(tasks))
+	}
+	if trip := tasks[0]; trip.Priority != PriorityUrgent || trip.Description != "Book early" || !trip.AllDay {
+		t.Errorf("unexpected Todoist task: %+v", trip)
+	}
+	if hotel := tasks[1]; hotel.ParentID != tasks[0].ID || hotel.Priority != PriorityNormal || hotel.Recurrence == nil {
+		t.Errorf("unexpected Todoist subtask: %+v", hotel)
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	tests := map[string]*Recurrence{
+		"daily":                  {Interval: 1, Unit: UnitDay},
+		"Every 3 Months":         {Interval: 3, Unit: UnitMonth},
+		"every other week":       {Interval: 2, Unit: UnitWeek},
+		"RRULE:FREQ=YEARLY":      {Interval: 1, Unit: UnitYear},
+		"FREQ=DAILY;INTERVAL=10": {Interval: 10, Unit: UnitDay},
+		"every monday":           nil,
+		"tomorrow at 5pm":        nil,
+		"FREQ=HOURLY;INTERVAL=2": nil,
+	}
+	for input, want := range tests {
+		got, ok := ParseRecurrence(input)
+		if want == nil {
+			if ok {
+				t.Errorf("ParseRecurrence(%q) = %v, want none", input, got)
+			}
+			continue
+		}
+		if !ok || *got != *want {
+			t.Errorf("ParseRecurrence(%q) = %v, want %v", input, got, want)
+		}
+		if round, ok := ParseRecurrence(want.String()); !ok || *round != *want {
+			t.Errorf("ParseRecurrence(%q) = %v, want %v", want.String(), round, want)
+		}
+	}
+}
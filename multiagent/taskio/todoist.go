@@ -0,0 +1,241 @@
+package taskio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	todoistDate     = "2006-01-02"
+	todoistDateTime = "2006-01-02T15:04:05"
+)
+
+// TodoistExport is a task list in Todoist's REST (v2) format: the tasks,
+// plus the projects their project_id fields refer to
+type TodoistExport struct {
+	Projects []TodoistProject `json:"projects"`
+	Tasks    []TodoistTask    `json:"tasks"`
+}
+
+// TodoistProject is a Todoist project
+type TodoistProject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// TodoistTask is a task as Todoist's REST API returns it
+type TodoistTask struct {
+	ID          string           `json:"id"`
+	ProjectID   string           `json:"project_id,omitempty"`
+	ParentID    *string          `json:"parent_id"`
+	Content     string           `json:"content"`
+	Description string           `json:"description"`
+	IsCompleted bool             `json:"is_completed"`
+	Labels      []string         `json:"labels"`
+	Priority    int              `json:"priority"`
+	Due         *TodoistDue      `json:"due"`
+	Duration    *TodoistDuration `json:"duration"`
+	CreatedAt   string           `json:"created_at,omitempty"`
+	// CompletedAt is not part of Todoist's active task format, but its
+	// completed task export has it
+	CompletedAt string `json:"completed_at,omitempty"`
+}
+
+// TodoistDue is a Todoist due date. Date is always set; Datetime only for
+// tasks due at a time, in UTC unless Timezone is empty.
+type TodoistDue struct {
+	Date        string `json:"date"`
+	IsRecurring bool   `json:"is_recurring"`
+	Datetime    string `json:"datetime,omitempty"`
+	String      string `json:"string"`
+	Timezone    string `json:"timezone,omitempty"`
+}
+
+// TodoistDuration is how long a Todoist task takes
+type TodoistDuration struct {
+	Amount int    `json:"amount"`
+	Unit   string `json:"unit"`
+}
+
+// EncodeTodoist writes tasks in Todoist's REST format, naming projects by
+// the IDs it gives them
+func EncodeTodoist(w io.Writer, tasks []Task) error {
+	var names []string
+	projectIDs := make(map[string]string)
+	for _, task := range tasks {
+		if task.Project != "" && projectIDs[task.Project] == "" {
+			projectIDs[task.Project] = "-"
+			names = append(names, task.Project)
+		}
+	}
+	sort.Strings(names)
+
+	export := TodoistExport{Projects: []TodoistProject{}, Tasks: []TodoistTask{}}
+	for i, name := range names {
+		id := "project_" + strconv.Itoa(i+1)
+		projectIDs[name] = id
+		export.Projects = append(export.Projects, TodoistProject{ID: id, Name: name})
+	}
+	for _, task := range tasks {
+		export.Tasks = append(export.Tasks, toTodoist(task, projectIDs[task.Project]))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		return fmt.Errorf("failed to encode todoist tasks: %w", err)
+	}
+	return nil
+}
+
+// DecodeTodoist reads tasks in Todoist's REST format: either a
+// TodoistExport or a bare array of tasks as GET /tasks returns them.
+// Floating due times are read in loc.
+func DecodeTodoist(r io.Reader, loc *time.Location) ([]Task, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read todoist tasks: %w", err)
+	}
+
+	var export TodoistExport
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &export.Tasks)
+	} else {
+		err = json.Unmarshal(trimmed, &export)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode todoist tasks: %w", err)
+	}
+
+	projects := make(map[string]string)
+	for _, project := range export.Projects {
+		projects[project.ID] = project.Name
+	}
+	tasks := make([]Task, 0, len(export.Tasks))
+	for _, source := range export.Tasks {
+		if strings.TrimSpace(source.Content) == "" {
+			continue
+		}
+		task, err := fromTodoist(source, loc)
+		if err != nil {
+			return nil, err
+		}
+		task.Project = projects[source.ProjectID]
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// toTodoist converts a task to Todoist's format
+func toTodoist(task Task, projectID string) TodoistTask {
+	out := TodoistTask{
+		ID:          task.ID,
+		ProjectID:   projectID,
+		Content:     task.Title,
+		Description: task.Description,
+		IsCompleted: task.Completed,
+		Labels:      append([]string{}, task.Labels...),
+		Priority:    int(task.Priority),
+	}
+	if out.Priority < int(PriorityNormal) || out.Priority > int(PriorityUrgent) {
+		out.Priority = int(PriorityNormal)
+	}
+	if task.ParentID != "" {
+		parent := task.ParentID
+		out.ParentID = &parent
+	}
+	if !task.CreatedAt.IsZero() {
+		out.CreatedAt = task.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	if task.CompletedAt != nil {
+		out.CompletedAt = task.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	if task.Duration > 0 {
+		out.Duration = &TodoistDuration{Amount: int(task.Duration.Minutes()), Unit: "minute"}
+	}
+
+	if task.Due != nil {
+		due := &TodoistDue{Date: task.Due.Format(todoistDate), String: task.Due.Format("Jan 2")}
+		if !task.AllDay {
+			due.Datetime = task.Due.UTC().Format(time.RFC3339)
+			due.Timezone = task.Due.Location().String()
+			due.String = task.Due.Format("Jan 2 15:04")
+		}
+		if task.Recurrence != nil {
+			due.IsRecurring = true
+			due.String = task.Recurrence.String()
+		}
+		out.Due = due
+	}
+	return out
+}
+
+// fromTodoist converts a Todoist task, reading floating times in loc
+func fromTodoist(source TodoistTask, loc *time.Location) (Task, error) {
+	task := Task{
+		ID:          source.ID,
+		Title:       source.Content,
+		Description: source.Description,
+		Priority:    Priority(source.Priority),
+		Labels:      source.Labels,
+		Completed:   source.IsCompleted,
+	}
+	if task.Priority < PriorityNormal || task.Priority > PriorityUrgent {
+		task.Priority = PriorityNormal
+	}
+	if source.ParentID != nil {
+		task.ParentID = *source.ParentID
+	}
+	if created, err := time.Parse(time.RFC3339, source.CreatedAt); err == nil {
+		task.CreatedAt = created
+	}
+	if completed, err := time.Parse(time.RFC3339, source.CompletedAt); err == nil {
+		task.CompletedAt = &completed
+	}
+	if source.Duration != nil && source.Duration.Amount > 0 {
+		unit := time.Minute
+		if source.Duration.Unit == "day" {
+			unit = 24 * time.Hour
+		}
+		task.Duration = time.Duration(source.Duration.Amount) * unit
+	}
+
+	if due := source.Due; due != nil {
+		switch {
+		case due.Datetime != "":
+			at, err := parseTodoistTime(due.Datetime, due.Timezone, loc)
+			if err != nil {
+				return Task{}, fmt.Errorf("failed to read due time of task %q: %w", source.Content, err)
+			}
+			task.Due = &at
+		case due.Date != "":
+			at, err := time.ParseInLocation(todoistDate, due.Date, loc)
+			if err != nil {
+				return Task{}, fmt.Errorf("failed to read due date of task %q: %w", source.Content, err)
+			}
+			task.Due, task.AllDay = &at, true
+		}
+		if due.IsRecurring {
+			task.Recurrence, _ = ParseRecurrence(due.String)
+		}
+	}
+	return task, nil
+}
+
+// parseTodoistTime reads a due datetime: UTC when it ends in Z, otherwise
+// floating in loc
+func parseTodoistTime(value, zone string, loc *time.Location) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		if tz, err := time.LoadLocation(zone); zone != "" && err == nil {
+			return at.In(tz), nil
+		}
+		return at, nil
+	}
+	return time.ParseInLocation(todoistDateTime, value, loc)
+}
@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config is how the assistant reaches its Telegram bot
+type Config struct {
+	// BotToken is the token BotFather issued; it may reference environment
+	// variables, e.g. "$TELEGRAM_BOT_TOKEN"
+	BotToken string `json:"bot_token"`
+	// WebhookURL, if set, is the public URL of the server's
+	// /telegram/webhook, and updates are pushed to it; otherwise the bot
+	// long polls for them
+	WebhookURL string `json:"webhook_url,omitempty"`
+	// WebhookSecret is the token Telegram sends with each webhook request;
+	// required with WebhookURL, and it may reference environment variables
+	WebhookSecret string `json:"webhook_secret,omitempty"`
+	// Users maps Telegram user IDs to assistant users
+	Users map[string]string `json:"users,omitempty"`
+	// AllowAll lets Telegram users without a mapping talk to the assistant
+	// as "telegram-" and their Telegram user ID; otherwise they are turned
+	// away
+	AllowAll bool `json:"allow_all,omitempty"`
+	// APIURL defaults to https://api.telegram.org
+	APIURL string `json:"api_url,omitempty"`
+}
+
+// LoadConfig reads the Telegram bot's settings from a JSON file:
+//
+//	{"bot_token": "$TELEGRAM_BOT_TOKEN", "users": {"123456789": "alice"}}
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read telegram config: %w", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse telegram config: %w", err)
+	}
+	config.BotToken = os.ExpandEnv(config.BotToken)
+	config.WebhookSecret = os.ExpandEnv(config.WebhookSecret)
+	if config.BotToken == "" {
+		return config, fmt.Errorf("telegram config needs a bot_token")
+	}
+	if config.WebhookURL != "" && config.WebhookSecret == "" {
+		return config, fmt.Errorf("telegram config needs a webhook_secret with its webhook_url")
+	}
+	return config, nil
+}
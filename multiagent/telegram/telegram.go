@@ -0,0 +1,541 @@
+// Package telegram makes a Telegram bot a frontend for the assistant:
+// people chat with it privately, approve or cancel side-effecting actions
+// with inline buttons, and get their reminders as messages from the bot.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/progress"
+)
+
+var logger = logging.For("telegram")
+
+const (
+	// UserPrefix starts the assistant user IDs of Telegram users without a
+	// mapping in Config.Users
+	UserPrefix = "telegram-"
+
+	// pollTimeout is how long a getUpdates request waits for updates
+	pollTimeout = 30 * time.Second
+	// retryDelay spaces out getUpdates requests after a failure
+	retryDelay = 5 * time.Second
+	// typingInterval renews the typing indicator, which Telegram shows for
+	// five seconds
+	typingInterval = 4 * time.Second
+
+	confirmData = "confirm"
+	cancelData  = "cancel"
+)
+
+// allowedUpdates are the update types the bot asks Telegram for
+var allowedUpdates = []string{"message", "callback_query"}
+
+// Service is the part of MultiAgentService the adapter uses
+type Service interface {
+	ProcessUserMessage(ctx context.Context, userID string, message string) (string, error)
+	SubscribeProgress(userID string) (<-chan progress.Event, func())
+}
+
+// AdapterConfig holds configuration for creating an Adapter
+type AdapterConfig struct {
+	Service  Service
+	Telegram Config
+	// MessageTimeout bounds how long a message waits for the assistant's
+	// reply (default 90 seconds)
+	MessageTimeout time.Duration
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+}
+
+// Adapter relays Telegram messages to the assistant and sends its replies.
+// It receives updates by long polling (Poll) or as an http.Handler for the
+// bot's webhook, and delivers notifications as a notify.Channel.
+type Adapter struct {
+	service Service
+	config  Config
+	apiURL  string
+	timeout time.Duration
+	client  *http.Client
+
+	// users reverses Config.Users, for delivering notifications
+	users map[string]string
+
+	mu sync.Mutex
+	// confirmations holds, by chat, the question the last keyboard answers
+	confirmations map[int64]confirmation
+	wg            sync.WaitGroup
+}
+
+// confirmation is a question the user answers with the inline keyboard
+// under a message
+type confirmation struct {
+	questionID string
+	messageID  int64
+}
+
+// NewAdapter creates an adapter for config.Telegram's bot
+func NewAdapter(config AdapterConfig) *Adapter {
+	if config.MessageTimeout <= 0 {
+		config.MessageTimeout = 90 * time.Second
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	apiURL := config.Telegram.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.telegram.org"
+	}
+
+	a := &Adapter{
+		service:       config.Service,
+		config:        config.Telegram,
+		apiURL:        strings.TrimRight(apiURL, "/") + "/bot" + config.Telegram.BotToken,
+		timeout:       config.MessageTimeout,
+		client:        config.Client,
+		users:         make(map[string]string, len(config.Telegram.Users)),
+		confirmations: make(map[int64]confirmation),
+	}
+	for telegramUser, userID := range config.Telegram.Users {
+		a.users[userID] = telegramUser
+	}
+	return a
+}
+
+// Wait blocks until updates being handled have been answered, or ctx ends
+func (a *Adapter) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// update is an incoming update from the Bot API
+type update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *message       `json:"message"`
+	CallbackQuery *callbackQuery `json:"callback_query"`
+}
+
+type message struct {
+	MessageID int64  `json:"message_id"`
+	From      *user  `json:"from"`
+	Chat      chat   `json:"chat"`
+	Text      string `json:"text"`
+}
+
+type user struct {
+	ID    int64 `json:"id"`
+	IsBot bool  `json:"is_bot"`
+}
+
+type chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// callbackQuery is a press of an inline keyboard button
+type callbackQuery struct {
+	ID      string   `json:"id"`
+	From    user     `json:"from"`
+	Message *message `json:"message"`
+	Data    string   `json:"data"`
+}
+
+// Poll long polls for updates and handles them until ctx ends. It removes
+// any webhook first, as Telegram delivers updates one way or the other.
+func (a *Adapter) Poll(ctx context.Context) error {
+	if err := a.call(ctx, "deleteWebhook", map[string]interface{}{}, nil); err != nil {
+		return err
+	}
+	var offset int64
+	for {
+		var updates []update
+		err := a.call(ctx, "getUpdates", map[string]interface{}{
+			"offset":          offset,
+			"timeout":         int(pollTimeout.Seconds()),
+			"allowed_updates": allowedUpdates,
+		}, &updates)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			logger.WarnContext(ctx, "Failed to get Telegram updates", "error", err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			a.dispatch(u)
+		}
+	}
+}
+
+// SetWebhook points the bot's updates at Config.WebhookURL
+func (a *Adapter) SetWebhook(ctx context.Context) error {
+	return a.call(ctx, "setWebhook", map[string]interface{}{
+		"url":             a.config.WebhookURL,
+		"secret_token":    a.config.WebhookSecret,
+		"allowed_updates": allowedUpdates,
+	}, nil)
+}
+
+// ServeHTTP receives webhook updates: it acknowledges each at once and
+// handles it in the background, as Telegram waits for the response before
+// sending the chat's next update
+func (a *Adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	secret := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if a.config.WebhookSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(a.config.WebhookSecret)) != 1 {
+		logger.Warn("Rejected Telegram request with an invalid secret token")
+		http.Error(w, "invalid secret token", http.StatusUnauthorized)
+		return
+	}
+	var u update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&u); err != nil {
+		http.Error(w, "invalid update", http.StatusBadRequest)
+		return
+	}
+	a.dispatch(u)
+	w.WriteHeader(http.StatusOK)
+}
+
+// dispatch handles u in the background
+func (a *Adapter) dispatch(u update) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+		defer cancel()
+		switch {
+		case u.Message != nil:
+			a.handleMessage(ctx, u.Message)
+		case u.CallbackQuery != nil:
+			a.handleCallback(ctx, u.CallbackQuery)
+		}
+	}()
+}
+
+// UserID returns the assistant user a Telegram user talks as, and false if
+// they may not use the assistant
+func (a *Adapter) UserID(telegramUser int64) (string, bool) {
+	id := strconv.FormatInt(telegramUser, 10)
+	if userID, ok := a.config.Users[id]; ok {
+		return userID, true
+	}
+	if a.config.AllowAll {
+		return UserPrefix + id, true
+	}
+	return "", false
+}
+
+// handleMessage relays a private text message to the assistant. Group
+// chats are ignored, so one person's data is never shown to others.
+func (a *Adapter) handleMessage(ctx context.Context, msg *message) {
+	if msg.From == nil || msg.From.IsBot || msg.Chat.Type != "private" {
+		return
+	}
+	text := strings.TrimSpace(msg.Text)
+	if text == "" {
+		return
+	}
+	userID, ok := a.UserID(msg.From.ID)
+	if !ok {
+		logger.InfoContext(ctx, "Turned away unknown Telegram user", "telegram_user", msg.From.ID)
+		a.reply(ctx, msg.Chat.ID, "Sorry, this assistant is private.")
+		return
+	}
+	if text == "/start" {
+		a.reply(ctx, msg.Chat.ID, "Hi! Tell me what you need: tasks, reminders, your calendar, or a question to research.")
+		return
+	}
+
+	// A typed answer settles any question the keyboard was offered for
+	if pending, ok := a.takeConfirmation(msg.Chat.ID, ""); ok {
+		a.removeKeyboard(ctx, msg.Chat.ID, pending.messageID)
+	}
+	a.converse(ctx, msg.Chat.ID, userID, text)
+}
+
+// handleCallback answers a confirm or cancel button press on behalf of the
+// user
+func (a *Adapter) handleCallback(ctx context.Context, query *callbackQuery) {
+	if query.Message == nil {
+		a.answerCallback(ctx, query.ID, "")
+		return
+	}
+	chatID := query.Message.Chat.ID
+	userID, ok := a.UserID(query.From.ID)
+	choice, questionID, _ := strings.Cut(query.Data, ":")
+	if !ok || (choice != confirmData && choice != cancelData) {
+		a.answerCallback(ctx, query.ID, "")
+		return
+	}
+
+	if _, ok := a.takeConfirmation(chatID, questionID); !ok {
+		a.answerCallback(ctx, query.ID, "This was already answered.")
+		a.removeKeyboard(ctx, chatID, query.Message.MessageID)
+		return
+	}
+	answer, notice := "no", "Cancelled"
+	if choice == confirmData {
+		answer, notice = "yes", "Confirmed"
+	}
+	a.answerCallback(ctx, query.ID, notice)
+	a.removeKeyboard(ctx, chatID, query.Message.MessageID)
+	a.converse(ctx, chatID, userID, answer)
+}
+
+// takeConfirmation removes and returns the chat's pending confirmation, if
+// it is for questionID or questionID is empty
+func (a *Adapter) takeConfirmation(chatID int64, questionID string) (confirmation, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	pending, ok := a.confirmations[chatID]
+	if !ok || (questionID != "" && pending.questionID != questionID) {
+		return confirmation{}, false
+	}
+	delete(a.confirmations, chatID)
+	return pending, true
+}
+
+// converse sends text to the assistant as userID and replies in the chat,
+// with confirm and cancel buttons when the assistant asks to approve an
+// action
+func (a *Adapter) converse(ctx context.Context, chatID int64, userID, text string) {
+	ctx = logging.WithFields(ctx, logging.KeyUserID, userID, "telegram_chat", chatID)
+
+	// Subscribe before sending so no early events are missed
+	events, unsubscribe := a.service.SubscribeProgress(userID)
+	confirming := make(chan string, 1)
+	go func() { confirming <- a.watch(ctx, chatID, events) }()
+
+	response, err := a.service.ProcessUserMessage(ctx, userID, text)
+	unsubscribe()
+	questionID := <-confirming
+
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to process Telegram message", "error", err)
+		a.reply(ctx, chatID, "⚠️ Sorry, something went wrong: "+err.Error())
+		return
+	}
+	if questionID == "" {
+		a.reply(ctx, chatID, response)
+		return
+	}
+
+	messageID, err := a.sendMessage(ctx, chatID, response, confirmKeyboard(questionID))
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to send reply", "error", err)
+		return
+	}
+	a.mu.Lock()
+	a.confirmations[chatID] = confirmation{questionID: questionID, messageID: messageID}
+	a.mu.Unlock()
+}
+
+// watch keeps the chat's typing indicator on until events is closed, and
+// returns the ID of the last confirmation question asked meanwhile
+func (a *Adapter) watch(ctx context.Context, chatID int64, events <-chan progress.Event) string {
+	typing := time.NewTicker(typingInterval)
+	defer typing.Stop()
+	a.sendTyping(ctx, chatID)
+
+	var questionID string
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return questionID
+			}
+			if event.Type != progress.QuestionAsked || event.Data["action"] != "confirmation_required" {
+				continue
+			}
+			if id, ok := event.Data["question_id"].(string); ok {
+				questionID = id
+			}
+		case <-typing.C:
+			a.sendTyping(ctx, chatID)
+		}
+	}
+}
+
+// inlineButton is a button of an inline keyboard
+type inlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+type inlineKeyboard struct {
+	InlineKeyboard [][]inlineButton `json:"inline_keyboard"`
+}
+
+func confirmKeyboard(questionID string) *inlineKeyboard {
+	return &inlineKeyboard{InlineKeyboard: [][]inlineButton{{
+		{Text: "✅ Confirm", CallbackData: confirmData + ":" + questionID},
+		{Text: "✖️ Cancel", CallbackData: cancelData + ":" + questionID},
+	}}}
+}
+
+// Name implements notify.Channel
+func (a *Adapter) Name() string { return "telegram" }
+
+// Send implements notify.Channel, messaging n to its user's Telegram
+// account; a private chat's ID is its user's ID
+func (a *Adapter) Send(ctx context.Context, n notify.Notification) error {
+	target, ok := a.users[n.UserID]
+	if !ok {
+		target, ok = strings.CutPrefix(n.UserID, UserPrefix)
+	}
+	chatID, err := strconv.ParseInt(target, 10, 64)
+	if !ok || err != nil {
+		return fmt.Errorf("user %q has no telegram account", n.UserID)
+	}
+	_, err = a.sendMessage(ctx, chatID, fmt.Sprintf("🔔 %s\n%s", n.Title, n.Body), nil)
+	return err
+}
+
+// Route returns config with the adapter added to the notification channels
+// of every user it maps and of every Telegram user without a mapping;
+// those with no channels of their own get it instead of the defaults
+func (a *Adapter) Route(config notify.DispatcherConfig) notify.DispatcherConfig {
+	users := make(map[string][]notify.Channel, len(config.Users))
+	for userID, channels := range config.Users {
+		users[userID] = channels
+	}
+	for _, userID := range a.config.Users {
+		users[userID] = append(append([]notify.Channel(nil), users[userID]...), a)
+	}
+	config.Users = users
+
+	prefixes := make(map[string][]notify.Channel, len(config.Prefixes)+1)
+	for prefix, channels := range config.Prefixes {
+		prefixes[prefix] = channels
+	}
+	prefixes[UserPrefix] = append(append([]notify.Channel(nil), prefixes[UserPrefix]...), a)
+	config.Prefixes = prefixes
+	return config
+}
+
+func (a *Adapter) reply(ctx context.Context, chatID int64, text string) {
+	if _, err := a.sendMessage(ctx, chatID, text, nil); err != nil {
+		logger.WarnContext(ctx, "Failed to send reply", "error", err)
+	}
+}
+
+// sendMessage sends text to the chat, with keyboard under it if it is set,
+// and returns the new message's ID
+func (a *Adapter) sendMessage(ctx context.Context, chatID int64, text string, keyboard *inlineKeyboard) (int64, error) {
+	body := map[string]interface{}{"chat_id": chatID, "text": text}
+	if keyboard != nil {
+		body["reply_markup"] = keyboard
+	}
+	var sent message
+	if err := a.call(ctx, "sendMessage", body, &sent); err != nil {
+		return 0, err
+	}
+	return sent.MessageID, nil
+}
+
+func (a *Adapter) sendTyping(ctx context.Context, chatID int64) {
+	if err := a.call(ctx, "sendChatAction", map[string]interface{}{"chat_id": chatID, "action": "typing"}, nil); err != nil {
+		logger.DebugContext(ctx, "Failed to show typing indicator", "error", err)
+	}
+}
+
+func (a *Adapter) answerCallback(ctx context.Context, queryID, text string) {
+	body := map[string]interface{}{"callback_query_id": queryID}
+	if text != "" {
+		body["text"] = text
+	}
+	if err := a.call(ctx, "answerCallbackQuery", body, nil); err != nil {
+		logger.DebugContext(ctx, "Failed to answer button press", "error", err)
+	}
+}
+
+// removeKeyboard takes the inline keyboard off a message once it has been
+// answered
+func (a *Adapter) removeKeyboard(ctx context.Context, chatID, messageID int64) {
+	body := map[string]interface{}{
+		"chat_id":      chatID,
+		"message_id":   messageID,
+		"reply_markup": inlineKeyboard{InlineKeyboard: [][]inlineButton{}},
+	}
+	if err := a.call(ctx, "editMessageReplyMarkup", body, nil); err != nil {
+		logger.DebugContext(ctx, "Failed to remove keyboard", "error", err)
+	}
+}
+
+// call invokes a Bot API method and decodes its result into into
+func (a *Adapter) call(ctx context.Context, method string, body interface{}, into interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.apiURL+"/"+method, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		// The error's URL would include the bot token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to reach telegram: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", method, err)
+	}
+	var result struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return fmt.Errorf("telegram %s returned %s", method, resp.Status)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram %s failed: %s", method, result.Description)
+	}
+	if into != nil {
+		if err := json.Unmarshal(result.Result, into); err != nil {
+			return fmt.Errorf("failed to decode %s response: %w", method, err)
+		}
+	}
+	return nil
+}
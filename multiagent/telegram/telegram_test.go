@@ -0,0 +1,310 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/progress"
+)
+
+type fakeService struct {
+	hub *progress.Hub
+
+	mu       sync.Mutex
+	received []string
+}
+
+func (f *fakeService) ProcessUserMessage(ctx context.Context, userID string, message string) (string, error) {
+	f.mu.Lock()
+	f.received = append(f.received, userID+": "+message)
+	f.mu.Unlock()
+	switch message {
+	case "delete the taxes task":
+		f.hub.Publish(progress.Event{Type: progress.QuestionAsked, ConversationID: userID, Data: map[string]interface{}{
+			"question_id": "question_1", "action": "confirmation_required",
+		}})
+		return "Delete the task 'File taxes'? (yes/no)", nil
+	case "yes":
+		return "Deleted 'File taxes'.", nil
+	case "no":
+		return "Okay, I kept it.", nil
+	case "fail":
+		return "", fmt.Errorf("no LLM")
+	}
+	return "Added it to your list.", nil
+}
+
+func (f *fakeService) SubscribeProgress(userID string) (<-chan progress.Event, func()) {
+	return f.hub.Subscribe(userID)
+}
+
+func (f *fakeService) messages() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.received...)
+}
+
+// call is a Bot API request the fake Telegram received
+type call struct {
+	method string
+	body   map[string]interface{}
+}
+
+type fakeTelegram struct {
+	mu      sync.Mutex
+	calls   []call
+	updates []string
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method, ok := strings.CutPrefix(r.URL.Path, "/bottest-token/")
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"ok": false, "description": "Unauthorized"}`)
+		return
+	}
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	f.mu.Lock()
+	f.calls = append(f.calls, call{method: method, body: body})
+	count := len(f.calls)
+	updates := f.updates
+	if method == "getUpdates" {
+		f.updates = nil
+	}
+	f.mu.Unlock()
+
+	switch method {
+	case "sendMessage":
+		fmt.Fprintf(w, `{"ok": true, "result": {"message_id": %d}}`, count)
+	case "getUpdates":
+		if len(updates) == 0 {
+			// Stand in for the long poll
+			select {
+			case <-r.Context().Done():
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		fmt.Fprintf(w, `{"ok": true, "result": [%s]}`, strings.Join(updates, ","))
+	default:
+		io.WriteString(w, `{"ok": true, "result": true}`)
+	}
+}
+
+func (f *fakeTelegram) recorded(method string) []call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []call
+	for _, c := range f.calls {
+		if c.method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+func newTestAdapter(t *testing.T, allowAll bool) (*fakeService, *fakeTelegram, *Adapter) {
+	t.Helper()
+	api := &fakeTelegram{}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	fake := &fakeService{hub: progress.NewHub()}
+	adapter := NewAdapter(AdapterConfig{
+		Service: fake,
+		Telegram: Config{
+			BotToken:      "test-token",
+			WebhookSecret: "hook-secret",
+			Users:         map[string]string{"111": "alice"},
+			AllowAll:      allowAll,
+			APIURL:        server.URL,
+		},
+	})
+	return fake, api, adapter
+}
+
+func textUpdate(id int, from int64, text string) string {
+	return fmt.Sprintf(`{"update_id": %d, "message": {"message_id": %d, "from": {"id": %d},
+		"chat": {"id": %d, "type": "private"}, "text": %q}}`, id, id, from, from, text)
+}
+
+func buttonUpdate(id int, from int64, messageID int, data string) string {
+	return fmt.Sprintf(`{"update_id": %d, "callback_query": {"id": "cb%d", "from": {"id": %d},
+		"message": {"message_id": %d, "chat": {"id": %d, "type": "private"}}, "data": %q}}`, id, id, from, messageID, from, data)
+}
+
+// deliver posts update to the adapter's webhook and waits until it is handled
+func deliver(t *testing.T, adapter *Adapter, update string, secret string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader(update))
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+	rec := httptest.NewRecorder()
+	adapter.ServeHTTP(rec, req)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := adapter.Wait(ctx); err != nil {
+		t.Fatalf("update not handled: %v", err)
+	}
+	return rec.Code
+}
+
+func lastText(calls []call) string {
+	if len(calls) == 0 {
+		return ""
+	}
+	text, _ := calls[len(calls)-1].body["text"].(string)
+	return text
+}
+
+func TestWebhook(t *testing.T) {
+	fake, api, adapter := newTestAdapter(t, false)
+
+	if code := deliver(t, adapter, textUpdate(1, 111, "buy milk"), "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("bad secret: status = %d, want 401", code)
+	}
+	if code := deliver(t, adapter, textUpdate(2, 111, "buy milk"), "hook-secret"); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if got := fake.messages(); len(got) != 1 || got[0] != "alice: buy milk" {
+		t.Fatalf("received %v", got)
+	}
+	if len(api.recorded("sendChatAction")) == 0 {
+		t.Error("typing indicator not shown")
+	}
+	sent := api.recorded("sendMessage")
+	if lastText(sent) != "Added it to your list." || sent[0].body["chat_id"] != float64(111) {
+		t.Errorf("unexpected reply %+v", sent)
+	}
+
+	deliver(t, adapter, textUpdate(3, 111, "fail"), "hook-secret")
+	if got := lastText(api.recorded("sendMessage")); !strings.Contains(got, "no LLM") {
+		t.Errorf("error not reported: %q", got)
+	}
+}
+
+func TestUnknownUsers(t *testing.T) {
+	fake, api, adapter := newTestAdapter(t, false)
+	deliver(t, adapter, textUpdate(1, 222, "buy milk"), "hook-secret")
+	if got := fake.messages(); len(got) != 0 {
+		t.Fatalf("unknown user reached the assistant: %v", got)
+	}
+	if got := lastText(api.recorded("sendMessage")); !strings.Contains(got, "private") {
+		t.Errorf("unknown user not turned away: %q", got)
+	}
+
+	fake, _, adapter = newTestAdapter(t, true)
+	deliver(t, adapter, textUpdate(1, 222, "buy milk"), "hook-secret")
+	deliver(t, adapter, `{"update_id": 2, "message": {"message_id": 2, "from": {"id": 333},
+		"chat": {"id": -100, "type": "group"}, "text": "buy milk"}}`, "hook-secret")
+	if got := fake.messages(); len(got) != 1 || got[0] != UserPrefix+"222: buy milk" {
+		t.Fatalf("received %v", got)
+	}
+}
+
+func TestConfirmation(t *testing.T) {
+	fake, api, adapter := newTestAdapter(t, false)
+
+	deliver(t, adapter, textUpdate(1, 111, "delete the taxes task"), "hook-secret")
+	sent := api.recorded("sendMessage")
+	markup, _ := json.Marshal(sent[len(sent)-1].body["reply_markup"])
+	if !strings.Contains(string(markup), `"callback_data":"confirm:question_1"`) || !strings.Contains(string(markup), `"callback_data":"cancel:question_1"`) {
+		t.Fatalf("question sent without confirm and cancel buttons: %s", markup)
+	}
+
+	deliver(t, adapter, buttonUpdate(2, 111, 3, "confirm:question_1"), "hook-secret")
+	if got := fake.messages(); len(got) != 2 || got[1] != "alice: yes" {
+		t.Fatalf("received %v", got)
+	}
+	if got := lastText(api.recorded("sendMessage")); got != "Deleted 'File taxes'." {
+		t.Errorf("reply = %q", got)
+	}
+	if edits := api.recorded("editMessageReplyMarkup"); len(edits) != 1 || edits[0].body["message_id"] != float64(3) {
+		t.Errorf("keyboard not removed: %+v", edits)
+	}
+
+	// Pressing a button again does not answer twice
+	deliver(t, adapter, buttonUpdate(3, 111, 3, "cancel:question_1"), "hook-secret")
+	if got := fake.messages(); len(got) != 2 {
+		t.Fatalf("stale button answered again: %v", got)
+	}
+	answers := api.recorded("answerCallbackQuery")
+	if len(answers) != 2 || !strings.Contains(answers[1].body["text"].(string), "already") {
+		t.Errorf("unexpected button answers %+v", answers)
+	}
+
+	// Typing an answer instead retires the buttons
+	deliver(t, adapter, textUpdate(4, 111, "delete the taxes task"), "hook-secret")
+	deliver(t, adapter, textUpdate(5, 111, "no"), "hook-secret")
+	if got := fake.messages(); got[len(got)-1] != "alice: no" {
+		t.Fatalf("received %v", got)
+	}
+	if edits := api.recorded("editMessageReplyMarkup"); len(edits) != 3 {
+		t.Errorf("keyboard left on the question answered by text: %+v", edits)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	fake, api, adapter := newTestAdapter(t, false)
+	api.updates = []string{textUpdate(7, 111, "buy milk")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- adapter.Poll(ctx) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(api.recorded("sendMessage")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Poll returned %v", err)
+	}
+
+	if got := fake.messages(); len(got) != 1 || got[0] != "alice: buy milk" {
+		t.Fatalf("received %v", got)
+	}
+	polls := api.recorded("getUpdates")
+	if len(api.recorded("deleteWebhook")) != 1 || len(polls) < 2 || polls[1].body["offset"] != float64(8) {
+		t.Errorf("unexpected polling %+v", polls)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	_, api, adapter := newTestAdapter(t, true)
+	ctx := context.Background()
+
+	for userID, want := range map[string]float64{"alice": 111, UserPrefix + "222": 222} {
+		if err := adapter.Send(ctx, notify.Notification{UserID: userID, Title: "Reminder", Body: "Call mom"}); err != nil {
+			t.Fatalf("Send(%s): %v", userID, err)
+		}
+		sent := api.recorded("sendMessage")
+		if got := sent[len(sent)-1].body["chat_id"]; got != want {
+			t.Errorf("%s: sent to %v, want %v", userID, got, want)
+		}
+	}
+	if err := adapter.Send(ctx, notify.Notification{UserID: "bob"}); err == nil {
+		t.Error("Send to a user without a Telegram account succeeded")
+	}
+
+	console := notify.NewConsoleChannel(io.Discard)
+	dispatcher := notify.NewDispatcher(adapter.Route(notify.DispatcherConfig{Default: []notify.Channel{console}}))
+	if got := dispatcher.Channels("alice"); len(got) != 1 || got[0] != notify.Channel(adapter) {
+		t.Errorf("alice's channels = %v", got)
+	}
+	if got := dispatcher.Channels(UserPrefix + "222"); len(got) != 1 || got[0] != notify.Channel(adapter) {
+		t.Errorf("Telegram user's channels = %v", got)
+	}
+	if got := dispatcher.Channels("bob"); len(got) != 1 || got[0] != notify.Channel(console) {
+		t.Errorf("bob's channels = %v", got)
+	}
+}
@@ -0,0 +1,580 @@
+// Package timeparse resolves natural-language dates and times such as
+// "tomorrow at 2pm", "next Friday", or "in 45 minutes" against a reference
+// time, deterministically, so agents can check and repair the timestamps an
+// LLM extracts before acting on them.
+package timeparse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result is a resolved expression
+type Result struct {
+	// Time is the resolved instant in the reference time's location; when
+	// HasTime is false it is midnight of the resolved day
+	Time time.Time
+	// HasDate reports whether the expression named a day
+	HasDate bool
+	// HasTime reports whether the expression named a time of day
+	HasTime bool
+	// Text is the part of the input the expression was read from
+	Text string
+}
+
+// Parse resolves text, which must consist of a date and/or time expression
+// (filler words such as "at" or "on" aside), relative to now. Times are
+// read in now's location.
+func Parse(text string, now time.Time) (Result, error) {
+	words := tokenize(text)
+	s := &scan{now: now, words: words}
+	for s.pos < len(words) {
+		if s.match() {
+			continue
+		}
+		if fillers[words[s.pos]] {
+			s.pos++
+			continue
+		}
+		return Result{}, fmt.Errorf("unrecognized %q in %q", words[s.pos], text)
+	}
+	if !s.found() {
+		return Result{}, fmt.Errorf("no date or time in %q", text)
+	}
+	return s.result()
+}
+
+// Extract finds the first date and/or time expression in free text, such as
+// a user's request, and resolves it relative to now
+func Extract(text string, now time.Time) (Result, bool) {
+	words := tokenize(text)
+	s := &scan{now: now, words: words}
+	for s.pos < len(words) {
+		if s.match() {
+			continue
+		}
+		// Words between parts of one expression ("Friday at 3pm") are fine,
+		// but anything else ends it
+		if s.found() && !fillers[words[s.pos]] {
+			break
+		}
+		s.pos++
+	}
+	if !s.found() {
+		return Result{}, false
+	}
+	result, err := s.result()
+	return result, err == nil
+}
+
+// fillers may appear within an expression without changing it
+var fillers = map[string]bool{
+	"at": true, "on": true, "the": true, "by": true, "around": true, "about": true,
+	"of": true, "this": true, "for": true, "from": true, "starting": true,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// dayParts are the times of day vague words stand for
+var dayParts = map[string]int{
+	"morning":   9,
+	"noon":      12,
+	"midday":    12,
+	"afternoon": 14,
+	"evening":   18,
+	"night":     20,
+	"midnight":  0,
+}
+
+var (
+	clockPattern    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+	isoDatePattern  = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+	slashPattern    = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})(?:/(\d{2}|\d{4}))?$`)
+	ordinalPattern  = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+	yearPattern     = regexp.MustCompile(`^\d{4}$`)
+	quantityPattern = regexp.MustCompile(`^\d+$`)
+)
+
+// tokenize lowercases text and splits it into words, joining "2 pm" into
+// "2pm" and splitting "2026-03-05T14:00" into date and time
+func tokenize(text string) []string {
+	text = strings.ToLower(text)
+	text = strings.NewReplacer("a.m.", "am", "p.m.", "pm", ",", " ", ";", " ").Replace(text)
+	var words []string
+	for _, word := range strings.Fields(text) {
+		word = strings.Trim(word, ".!?\"'()[]")
+		if word == "" {
+			continue
+		}
+		if isoDatePattern.MatchString(word[:min(len(word), 10)]) && len(word) > 11 && word[10] == 't' {
+			words = append(words, word[:10], word[11:])
+			continue
+		}
+		if (word == "am" || word == "pm") && len(words) > 0 && clockPattern.MatchString(words[len(words)-1]) {
+			words[len(words)-1] += word
+			continue
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+// scan accumulates the parts of an expression
+type scan struct {
+	now   time.Time
+	words []string
+	pos   int
+
+	// first and last bound the words read so far, once started
+	first, last int
+	started     bool
+
+	date    time.Time // Midnight of the resolved day
+	hasDate bool
+	hour    int
+	minute  int
+	hasTime bool
+	instant *time.Time // Set by "in 45 minutes" and the like
+	tonight bool
+}
+
+func (s *scan) found() bool {
+	return s.hasDate || s.hasTime || s.instant != nil
+}
+
+func (s *scan) word(offset int) string {
+	if s.pos+offset < len(s.words) {
+		return s.words[s.pos+offset]
+	}
+	return ""
+}
+
+// consume marks n words from the current position as read
+func (s *scan) consume(n int) bool {
+	if !s.started {
+		s.first, s.started = s.pos, true
+	}
+	s.pos += n
+	s.last = s.pos
+	return true
+}
+
+func (s *scan) today() time.Time {
+	return time.Date(s.now.Year(), s.now.Month(), s.now.Day(), 0, 0, 0, 0, s.now.Location())
+}
+
+func (s *scan) setDate(date time.Time) {
+	s.date, s.hasDate = date, true
+}
+
+func (s *scan) setTime(hour, minute int) {
+	s.hour, s.minute, s.hasTime = hour, minute, true
+}
+
+// match reads one part of an expression at the current position
+func (s *scan) match() bool {
+	return s.matchRelative() || s.matchDay() || s.matchWeekday() || s.matchDate() || s.matchClock()
+}
+
+// matchRelative reads "in 45 minutes", "in an hour", "in half an hour",
+// "2 days from now", "3 weeks ago", and "in the morning"
+func (s *scan) matchRelative() bool {
+	if s.word(0) == "in" {
+		if s.word(1) == "half" && (s.word(2) == "an" || s.word(2) == "a") && s.word(3) == "hour" {
+			instant := s.now.Add(30 * time.Minute)
+			s.instant = &instant
+			return s.consume(4)
+		}
+		if hour, ok := dayParts[s.word(2)]; ok && s.word(1) == "the" {
+			if !s.hasTime {
+				s.setTime(hour, 0)
+			}
+			return s.consume(3)
+		}
+		if amount, ok := quantity(s.word(1)); ok && s.offset(amount, s.word(2)) {
+			return s.consume(3)
+		}
+		return false
+	}
+
+	amount, ok := quantity(s.word(0))
+	if !ok {
+		return false
+	}
+	switch {
+	case s.word(2) == "from" && s.word(3) == "now":
+		return s.offset(amount, s.word(1)) && s.consume(4)
+	case s.word(2) == "later":
+		return s.offset(amount, s.word(1)) && s.consume(3)
+	case s.word(2) == "ago":
+		return s.offset(-amount, s.word(1)) && s.consume(3)
+	}
+	return false
+}
+
+// offset moves amount units from now; minutes and hours give an instant,
+// longer units a day
+func (s *scan) offset(amount int, unit string) bool {
+	switch strings.TrimSuffix(unit, "s") {
+	case "minute", "min":
+		instant := s.now.Add(time.Duration(amount) * time.Minute)
+		s.instant = &instant
+	case "hour", "hr":
+		instant := s.now.Add(time.Duration(amount) * time.Hour)
+		s.instant = &instant
+	case "day":
+		s.setDate(s.today().AddDate(0, 0, amount))
+	case "week":
+		s.setDate(s.today().AddDate(0, 0, 7*amount))
+	case "month":
+		s.setDate(addMonths(s.today(), amount))
+	case "year":
+		s.setDate(addMonths(s.today(), 12*amount))
+	default:
+		return false
+	}
+	return true
+}
+
+// matchDay reads "today", "tonight", "tomorrow", "the day after tomorrow",
+// "yesterday", times of day, and "next week/month/year" or "this weekend"
+func (s *scan) matchDay() bool {
+	switch s.word(0) {
+	case "now":
+		now := s.now
+		s.instant = &now
+		return s.consume(1)
+	case "today":
+		s.setDate(s.today())
+		return s.consume(1)
+	case "tonight":
+		s.setDate(s.today())
+		s.tonight = true
+		if !s.hasTime {
+			s.setTime(dayParts["night"], 0)
+		}
+		return s.consume(1)
+	case "tomorrow", "tmrw", "tmr":
+		s.setDate(s.today().AddDate(0, 0, 1))
+		return s.consume(1)
+	case "yesterday":
+		s.setDate(s.today().AddDate(0, 0, -1))
+		return s.consume(1)
+	case "day":
+		if s.word(1) == "after" && s.word(2) == "tomorrow" {
+			s.setDate(s.today().AddDate(0, 0, 2))
+			return s.consume(3)
+		}
+	case "next":
+		switch s.word(1) {
+		case "week":
+			// Monday of next week
+			daysToMonday := (8 - int(s.now.Weekday())) % 7
+			if daysToMonday == 0 {
+				daysToMonday = 7
+			}
+			s.setDate(s.today().AddDate(0, 0, daysToMonday))
+			return s.consume(2)
+		case "month":
+			today := s.today()
+			s.setDate(time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location()))
+			return s.consume(2)
+		case "year":
+			s.setDate(time.Date(s.now.Year()+1, time.January, 1, 0, 0, 0, 0, s.now.Location()))
+			return s.consume(2)
+		}
+	case "weekend":
+		s.setDate(s.nextWeekday(time.Saturday, false))
+		return s.consume(1)
+	}
+
+	if hour, ok := dayParts[s.word(0)]; ok {
+		if !s.hasTime {
+			s.setTime(hour, 0)
+		}
+		return s.consume(1)
+	}
+	return false
+}
+
+// matchWeekday reads "friday", "this friday" and "on friday" (the next
+// Friday, today included) or "next friday" (the next Friday after today)
+func (s *scan) matchWeekday() bool {
+	if s.word(0) == "next" {
+		if weekday, ok := weekdays[s.word(1)]; ok {
+			s.setDate(s.nextWeekday(weekday, true))
+			return s.consume(2)
+		}
+		return false
+	}
+	if weekday, ok := weekdays[s.word(0)]; ok {
+		s.setDate(s.nextWeekday(weekday, false))
+		return s.consume(1)
+	}
+	return false
+}
+
+func (s *scan) nextWeekday(weekday time.Weekday, afterToday bool) time.Time {
+	days := (int(weekday) - int(s.now.Weekday()) + 7) % 7
+	if days == 0 && afterToday {
+		days = 7
+	}
+	return s.today().AddDate(0, 0, days)
+}
+
+// matchDate reads "2026-03-05", "3/5", "3/5/2026", "march 5th", "march 5
+// 2027", and "5 march"; a date without a year that has already passed
+// this year means next year
+func (s *scan) matchDate() bool {
+	loc := s.now.Location()
+	word := s.word(0)
+
+	if m := isoDatePattern.FindStringSubmatch(word); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		if !validDay(year, time.Month(month), day) {
+			return false
+		}
+		s.setDate(time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc))
+		return s.consume(1)
+	}
+
+	if m := slashPattern.FindStringSubmatch(word); m != nil {
+		month, _ := strconv.Atoi(m[1])
+		day, _ := strconv.Atoi(m[2])
+		year := 0
+		if m[3] != "" {
+			year, _ = strconv.Atoi(m[3])
+			if year < 100 {
+				year += 2000
+			}
+		}
+		return s.setMonthDay(year, time.Month(month), day) && s.consume(1)
+	}
+
+	// "march 5th [2027]"
+	if month, ok := months[word]; ok {
+		if m := ordinalPattern.FindStringSubmatch(s.word(1)); m != nil {
+			day, _ := strconv.Atoi(m[1])
+			year, words := s.year(2)
+			return s.setMonthDay(year, month, day) && s.consume(2+words)
+		}
+		return false
+	}
+
+	// "5th [of] march [2027]"
+	if m := ordinalPattern.FindStringSubmatch(word); m != nil {
+		next := 1
+		if s.word(1) == "of" {
+			next = 2
+		}
+		if month, ok := months[s.word(next)]; ok {
+			day, _ := strconv.Atoi(m[1])
+			year, words := s.year(next + 1)
+			return s.setMonthDay(year, month, day) && s.consume(next+1+words)
+		}
+	}
+	return false
+}
+
+// year reads an optional four-digit year at offset
+func (s *scan) year(offset int) (int, int) {
+	if yearPattern.MatchString(s.word(offset)) {
+		year, _ := strconv.Atoi(s.word(offset))
+		return year, 1
+	}
+	return 0, 0
+}
+
+func (s *scan) setMonthDay(year int, month time.Month, day int) bool {
+	explicitYear := year != 0
+	if !explicitYear {
+		year = s.now.Year()
+	}
+	if !validDay(year, month, day) {
+		return false
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, s.now.Location())
+	if !explicitYear && date.Before(s.today()) {
+		if !validDay(year+1, month, day) {
+			return false
+		}
+		date = date.AddDate(1, 0, 0)
+	}
+	s.setDate(date)
+	return true
+}
+
+// matchClock reads "2pm", "2:30pm", "14:30", and "at 3" (where 1–7 mean the
+// afternoon)
+func (s *scan) matchClock() bool {
+	word := s.word(0)
+	bare := false
+	if word == "at" && quantityPattern.MatchString(s.word(1)) {
+		// A bare number only reads as a time after "at"
+		word, bare = s.word(1), true
+	}
+
+	m := clockPattern.FindStringSubmatch(word)
+	if m == nil || (m[2] == "" && m[3] == "" && !bare) {
+		return false
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	switch m[3] {
+	case "am":
+		if hour < 1 || hour > 12 {
+			return false
+		}
+		if hour == 12 {
+			hour = 0
+		}
+	case "pm":
+		if hour < 1 || hour > 12 {
+			return false
+		}
+		if hour != 12 {
+			hour += 12
+		}
+	default:
+		if bare && hour >= 1 && hour <= 7 {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return false
+	}
+
+	// A following "in the morning/afternoon/evening" settles am or pm
+	s.setTime(hour, minute)
+	words := 1
+	if bare {
+		words = 2
+	}
+	s.consume(words)
+	if s.word(0) == "in" && s.word(1) == "the" {
+		switch s.word(2) {
+		case "morning":
+			if s.hour >= 12 {
+				s.hour -= 12
+			}
+			s.consume(3)
+		case "afternoon", "evening":
+			if s.hour < 12 {
+				s.hour += 12
+			}
+			s.consume(3)
+		}
+	}
+	return true
+}
+
+// result combines the parts read; a time of day without a day that has
+// already passed today means tomorrow, and one tonight that has already
+// passed is an error
+func (s *scan) result() (Result, error) {
+	result := Result{HasDate: s.hasDate, HasTime: s.hasTime, Text: strings.Join(s.words[s.first:s.last], " ")}
+
+	if s.instant != nil && !s.hasDate && !s.hasTime {
+		result.Time, result.HasDate, result.HasTime = *s.instant, true, true
+		return result, nil
+	}
+
+	date := s.today()
+	if s.hasDate {
+		date = s.date
+	} else if s.instant != nil {
+		date = time.Date(s.instant.Year(), s.instant.Month(), s.instant.Day(), 0, 0, 0, 0, s.now.Location())
+		result.HasDate = true
+	}
+	if !s.hasTime {
+		result.Time = date
+		return result, nil
+	}
+
+	result.Time = wallClock(date, s.hour, s.minute)
+	if !result.HasDate && result.Time.Before(s.now) {
+		result.Time = wallClock(date.AddDate(0, 0, 1), s.hour, s.minute)
+	}
+	if s.tonight && result.Time.Before(s.now) {
+		return Result{}, fmt.Errorf("%q has already passed", result.Text)
+	}
+	return result, nil
+}
+
+// wallClock returns hour:minute on date's day. A time the clocks skip when
+// daylight saving starts is moved forward past the gap, as 2:30 becomes 3:30.
+func wallClock(date time.Time, hour, minute int) time.Time {
+	t := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, date.Location())
+	if t.Hour() == hour && t.Minute() == minute {
+		return t
+	}
+	// Read the wall time with the offset from before the gap, the smaller
+	// one, which lands as far past the gap as the time was into it
+	_, before := t.Add(-12 * time.Hour).Zone()
+	_, after := t.Add(12 * time.Hour).Zone()
+	offset := time.Duration(min(before, after)) * time.Second
+	return time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, time.UTC).Add(-offset).In(date.Location())
+}
+
+// addMonths moves date by n months, keeping its day unless the target
+// month is shorter, when it is the month's last day (Jan 31 + 1 month is
+// Feb 28)
+func addMonths(date time.Time, n int) time.Time {
+	first := time.Date(date.Year(), date.Month()+time.Month(n), 1, 0, 0, 0, 0, date.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return time.Date(first.Year(), first.Month(), min(date.Day(), lastDay), 0, 0, 0, 0, date.Location())
+}
+
+func quantity(word string) (int, bool) {
+	switch word {
+	case "a", "an", "one":
+		return 1, true
+	case "two":
+		return 2, true
+	case "three":
+		return 3, true
+	}
+	if quantityPattern.MatchString(word) {
+		n, err := strconv.Atoi(word)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func validDay(year int, month time.Month, day int) bool {
+	if month < time.January || month > time.December || day < 1 {
+		return false
+	}
+	return day <= time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
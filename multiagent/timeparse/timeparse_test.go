@@ -0,0 +1,178 @@
+package timeparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone database unavailable: %v", err)
+	}
+	// Wednesday afternoon
+	now := time.Date(2026, 3, 4, 15, 20, 0, 0, ny)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, ny)
+	}
+
+	tests := []struct {
+		text    string
+		want    time.Time
+		hasTime bool
+	}{
+		{"tomorrow at 2pm", at(time.March, 5, 14, 0), true},
+		{"Tomorrow 2 p.m.", at(time.March, 5, 14, 0), true},
+		{"next Friday", at(time.March, 6, 0, 0), false},
+		{"wednesday", at(time.March, 4, 0, 0), false},
+		{"next wednesday", at(time.March, 11, 0, 0), false},
+		{"in 45 minutes", at(time.March, 4, 16, 5), true},
+		{"in half an hour", at(time.March, 4, 15, 50), true},
+		{"in 2 days", at(time.March, 6, 0, 0), false},
+		{"3 days from now at noon", at(time.March, 7, 12, 0), true},
+		{"2026-03-10 09:30", at(time.March, 10, 9, 30), true},
+		{"2026-03-10T09:30", at(time.March, 10, 9, 30), true},
+		{"March 12th at 10am", at(time.March, 12, 10, 0), true},
+		{"12 march", at(time.March, 12, 0, 0), false},
+		{"3/20 at 5", at(time.March, 20, 17, 0), true},
+		{"the day after tomorrow in the morning", at(time.March, 6, 9, 0), true},
+		{"tonight", at(time.March, 4, 20, 0), true},
+		{"next week", at(time.March, 9, 0, 0), false},
+		// A time that already passed today means tomorrow
+		{"at 9:15", at(time.March, 5, 9, 15), true},
+		{"8:30 in the evening", at(time.March, 4, 20, 30), true},
+		// A date that already passed this year means next year
+		{"Jan 2", time.Date(2027, time.January, 2, 0, 0, 0, 0, ny), false},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.text, now)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.text, err)
+			continue
+		}
+		if !got.Time.Equal(tt.want) || got.HasTime != tt.hasTime {
+			t.Errorf("Parse(%q) = %s (time %v), want %s (time %v)", tt.text, got.Time, got.HasTime, tt.want, tt.hasTime)
+		}
+	}
+
+	for _, text := range []string{"", "whenever", "tomorrow or so", "February 30", "25:00"} {
+		if got, err := Parse(text, now); err == nil {
+			t.Errorf("Parse(%q) = %s, want an error", text, got.Time)
+		}
+	}
+}
+
+func TestParse_DaylightSaving(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone database unavailable: %v", err)
+	}
+	// Clocks spring forward on 2026-03-08
+	now := time.Date(2026, 3, 7, 10, 0, 0, 0, ny)
+
+	got, err := Parse("tomorrow at 9am", now)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got.Time.Hour() != 9 || got.Time.Sub(now) != 22*time.Hour {
+		t.Errorf("expected 9:00 local, 22 real hours later, got %s", got.Time)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	now := time.Date(2026, 3, 4, 15, 20, 0, 0, time.UTC)
+
+	got, ok := Extract("Can you set up a call with Bob next Friday at 3pm to discuss the budget?", now)
+	if !ok {
+		t.Fatal("expected an expression to be found")
+	}
+	if want := time.Date(2026, 3, 6, 15, 0, 0, 0, time.UTC); !got.Time.Equal(want) || got.Text != "next friday at 3pm" {
+		t.Errorf("got %s from %q, want %s", got.Time, got.Text, want)
+	}
+
+	got, ok = Extract("remind me to call mom in 2 hours", now)
+	if !ok || !got.Time.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("got %s, %v", got.Time, ok)
+	}
+
+	if got, ok := Extract("schedule a meeting with the team about 3 topics", now); ok {
+		t.Errorf("expected nothing, got %s from %q", got.Time, got.Text)
+	}
+}
+
+func TestParse_MonthEnds(t *testing.T) {
+	// A month or year later is the same day, or the end of a shorter month
+	tests := []struct {
+		now  time.Time
+		text string
+		want time.Time
+	}{
+		{time.Date(2026, 1, 31, 10, 0, 0, 0, time.UTC), "in 1 month", time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2028, 1, 31, 10, 0, 0, 0, time.UTC), "in a month", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 31, 10, 0, 0, 0, time.UTC), "2 months ago", time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 10, 31, 10, 0, 0, 0, time.UTC), "in 4 months", time.Date(2027, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2028, 2, 29, 10, 0, 0, 0, time.UTC), "in 1 year", time.Date(2029, 2, 28, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.text, tt.now)
+		if err != nil {
+			t.Errorf("Parse(%q) on %s: %v", tt.text, tt.now.Format("Jan 2"), err)
+			continue
+		}
+		if !got.Time.Equal(tt.want) {
+			t.Errorf("Parse(%q) on %s = %s, want %s", tt.text, tt.now.Format("Jan 2"), got.Time, tt.want)
+		}
+	}
+}
+
+func TestParse_SkippedHour(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone database unavailable: %v", err)
+	}
+	// 2:00-3:00 doesn't happen on 2026-03-08; a time in it moves past the gap
+	now := time.Date(2026, 3, 4, 15, 20, 0, 0, ny)
+
+	for text, want := range map[string]time.Time{
+		"Mar 8 at 2:30am": time.Date(2026, 3, 8, 3, 30, 0, 0, ny),
+		"Mar 8 at 2am":    time.Date(2026, 3, 8, 3, 0, 0, 0, ny),
+		"Mar 8 at 3:15am": time.Date(2026, 3, 8, 3, 15, 0, 0, ny),
+		"Mar 8 at 1:45am": time.Date(2026, 3, 8, 1, 45, 0, 0, ny),
+	} {
+		got, err := Parse(text, now)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", text, err)
+			continue
+		}
+		if !got.Time.Equal(want) {
+			t.Errorf("Parse(%q) = %s, want %s", text, got.Time, want)
+		}
+	}
+
+	// A bare time rolled over to the day of the gap moves past it too
+	got, err := Parse("at 2:30am", time.Date(2026, 3, 7, 22, 0, 0, 0, ny))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want := time.Date(2026, 3, 8, 3, 30, 0, 0, ny); !got.Time.Equal(want) {
+		t.Errorf("got %s, want %s", got.Time, want)
+	}
+}
+
+func TestParse_TonightAfterItPassed(t *testing.T) {
+	lateNight := time.Date(2026, 3, 4, 23, 30, 0, 0, time.UTC)
+
+	if got, err := Parse("tonight", lateNight); err == nil {
+		t.Errorf("Parse(\"tonight\") at 23:30 = %s, want an error", got.Time)
+	}
+	if got, ok := Extract("remind me tonight to lock up", lateNight); ok {
+		t.Errorf("Extract found %s from %q, want nothing", got.Time, got.Text)
+	}
+	got, err := Parse("tonight at 11:45pm", lateNight)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want := time.Date(2026, 3, 4, 23, 45, 0, 0, time.UTC); !got.Time.Equal(want) {
+		t.Errorf("got %s, want %s", got.Time, want)
+	}
+}
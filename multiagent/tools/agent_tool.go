@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/ids"
+)
+
+// AgentTool lets a caller outside the orchestrator ask a specialist agent
+// for something in plain language, e.g. the scheduler for "what's on my
+// calendar tomorrow"; the agent's own handling keeps its data consistent
+type AgentTool struct {
+	name        string
+	description string
+	agent       multiagent.Agent
+}
+
+// NewAgentTool creates a tool that forwards requests to agent
+func NewAgentTool(name, description string, agent multiagent.Agent) *AgentTool {
+	return &AgentTool{
+		name:        name,
+		description: description,
+		agent:       agent,
+	}
+}
+
+// Name returns the name of the tool
+func (t *AgentTool) Name() string {
+	return t.name
+}
+
+// Description returns a description of what the tool does
+func (t *AgentTool) Description() string {
+	return t.description
+}
+
+// Parameters returns the parameter schema for the tool
+func (t *AgentTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"request": map[string]interface{}{
+				"type":        "string",
+				"description": "What to ask the agent, in plain language",
+			},
+		},
+		"required": []string{"request"},
+	}
+}
+
+// Execute sends the request to the agent and returns its reply
+func (t *AgentTool) Execute(ctx context.Context, args string) (string, error) {
+	request := strings.TrimSpace(args)
+	if strings.HasPrefix(request, "{") {
+		var params struct {
+			Request string `json:"request"`
+		}
+		if err := json.Unmarshal([]byte(request), &params); err != nil {
+			return "", fmt.Errorf("failed to parse JSON arguments: %w", err)
+		}
+		request = strings.TrimSpace(params.Request)
+	}
+	if request == "" {
+		return "", fmt.Errorf("request parameter is required")
+	}
+
+	msgContext := map[string]interface{}{"source": "tool"}
+	if userID := multiagent.UserIDFromContext(ctx); userID != "" {
+		msgContext[multiagent.ContextUserID] = userID
+	}
+	response, err := t.agent.HandleMessage(ctx, &multiagent.Message{
+		ID:        ids.New("tool_" + t.name),
+		From:      multiagent.AgentID("tool:" + t.name),
+		To:        []multiagent.AgentID{t.agent.ID()},
+		Type:      multiagent.MessageTypeRequest,
+		Content:   request,
+		Context:   msgContext,
+		Priority:  multiagent.PriorityMedium,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("agent %s failed: %w", t.agent.ID(), err)
+	}
+	if response == nil {
+		return "", fmt.Errorf("agent %s did not reply", t.agent.ID())
+	}
+	return response.Content, nil
+}
@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxHTTPResponse caps how much of a response body the HTTP tool returns
+const maxHTTPResponse = 64 * 1024
+
+// HTTPTool lets agents fetch web resources such as weather reports or APIs
+type HTTPTool struct {
+	name        string
+	description string
+	client      *http.Client
+}
+
+// NewHTTPTool creates a new HTTP tool; a nil client gets one with a 15s
+// timeout
+func NewHTTPTool(client *http.Client) *HTTPTool {
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &HTTPTool{
+		name:        "http",
+		description: "Fetch a web page or API over HTTP",
+		client:      client,
+	}
+}
+
+// Name returns the name of the tool
+func (t *HTTPTool) Name() string {
+	return t.name
+}
+
+// Description returns a description of what the tool does
+func (t *HTTPTool) Description() string {
+	return `HTTP tool for fetching web pages and APIs with GET requests.
+Arguments are a URL, or JSON with the url and optional headers.
+
+Examples:
+- https://wttr.in/Berlin?format=3
+- {"url": "https://api.example.com/items", "headers": {"Accept": "application/json"}}`
+}
+
+// Parameters returns the parameter schema for the tool
+func (t *HTTPTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "The http or https URL to fetch",
+			},
+			"headers": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+				"description":          "Request headers",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+// Execute fetches the URL and returns the response body, truncated to 64KB
+func (t *HTTPTool) Execute(ctx context.Context, args string) (string, error) {
+	var params struct {
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+	}
+	args = strings.TrimSpace(args)
+	if strings.HasPrefix(args, "{") {
+		if err := json.Unmarshal([]byte(args), &params); err != nil {
+			return "", fmt.Errorf("failed to parse JSON arguments: %w", err)
+		}
+	} else {
+		params.URL = args
+	}
+
+	target, err := url.Parse(params.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return "", fmt.Errorf("an http or https URL is required, got %q", params.URL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, value := range params.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", target.Host, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponse))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s returned %s: %s", target.Host, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/ids"
 )
 
 // MemoryTool provides agents with access to the memory store
@@ -207,7 +208,7 @@ func (t *MemoryTool) executeStore(ctx context.Context, params map[string]interfa
 
 	// Create memory entry
 	entry := multiagent.MemoryEntry{
-		Key:       fmt.Sprintf("%s:%d", category, time.Now().UnixNano()),
+		Key:       category + ":" + ids.New(""),
 		Value:     content,
 		Category:  category,
 		Tags:      tags,
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/notes"
+)
+
+// NotesSearchTool searches the acting user's indexed notes and documents
+type NotesSearchTool struct {
+	name        string
+	description string
+	memoryStore multiagent.MemoryStore
+}
+
+// NewNotesSearchTool creates a notes search tool over the user-partitioned
+// memory the notes indexer writes to
+func NewNotesSearchTool(memoryStore multiagent.MemoryStore) *NotesSearchTool {
+	return &NotesSearchTool{
+		name:        "notes",
+		description: "Search the user's own notes and documents",
+		memoryStore: memoryStore,
+	}
+}
+
+// Name returns the name of the tool
+func (t *NotesSearchTool) Name() string {
+	return t.name
+}
+
+// Description returns a description of what the tool does
+func (t *NotesSearchTool) Description() string {
+	return `Notes tool for searching the user's own Markdown, text and PDF documents.
+Returns the best matching passages with the file each came from.
+
+Examples:
+- garden planting schedule
+- {"query": "notes from the Lisbon trip", "limit": 3}`
+}
+
+// Parameters returns the parameter schema for the tool
+func (t *NotesSearchTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "What to look for in the user's notes",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of passages to return",
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+// Execute searches the notes with the given arguments and returns the
+// matching passages
+func (t *NotesSearchTool) Execute(ctx context.Context, args string) (string, error) {
+	var params struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	if strings.HasPrefix(strings.TrimSpace(args), "{") {
+		if err := json.Unmarshal([]byte(args), &params); err != nil {
+			return "", fmt.Errorf("failed to parse JSON arguments: %w", err)
+		}
+	} else {
+		params.Query = args
+	}
+	if params.Query = strings.TrimSpace(params.Query); params.Query == "" {
+		return "", fmt.Errorf("query parameter is required")
+	}
+
+	hits, err := notes.Search(ctx, t.memoryStore, params.Query, params.Limit)
+	if err != nil {
+		return "", err
+	}
+	if len(hits) == 0 {
+		return fmt.Sprintf("No notes found matching: %s", params.Query), nil
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "Found %d passages in your notes:\n", len(hits))
+	for i, hit := range hits {
+		fmt.Fprintf(&result, "\n%d. %s (%s)\n%s\n", i+1, hit.Title, hit.Path, hit.Text)
+	}
+	return result.String(), nil
+}
@@ -5,26 +5,34 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/logging"
 )
 
+var logger = logging.For("tools")
+
 // TaskTool provides agents with task management capabilities
 type TaskTool struct {
 	name        string
 	description string
 	memoryStore multiagent.MemoryStore
 	orchestrator multiagent.Orchestrator
+	ids         multiagent.IDGenerator
+	clock       multiagent.Clock
 }
 
-// NewTaskTool creates a new task management tool
-func NewTaskTool(memoryStore multiagent.MemoryStore, orchestrator multiagent.Orchestrator) *TaskTool {
+// NewTaskTool creates a new task management tool; task and message IDs come
+// from idGenerator and timestamps from clock, defaulting when nil
+func NewTaskTool(memoryStore multiagent.MemoryStore, orchestrator multiagent.Orchestrator, idGenerator multiagent.IDGenerator, clock multiagent.Clock) *TaskTool {
 	return &TaskTool{
 		name:        "task",
 		description: "Create and manage tasks",
 		memoryStore: memoryStore,
 		orchestrator: orchestrator,
+		ids:         ids.OrDefault(idGenerator),
+		clock:       ids.ClockOrSystem(clock),
 	}
 }
 
@@ -219,7 +227,7 @@ func (t *TaskTool) executeCreate(ctx context.Context, params map[string]interfac
 	}
 
 	// Create task ID
-	taskID := fmt.Sprintf("task_%s", time.Now().Format("20060102_150405"))
+	taskID := t.ids.NewID("task")
 
 	// Create task
 	task := multiagent.Task{
@@ -228,7 +236,7 @@ func (t *TaskTool) executeCreate(ctx context.Context, params map[string]interfac
 		Description: description,
 		Priority:    priority,
 		Status:      multiagent.TaskStatusPending,
-		CreatedAt:   time.Now(),
+		CreatedAt:   t.clock.Now(),
 		Input:       make(map[string]interface{}),
 		Output:      make(map[string]interface{}),
 	}
@@ -248,7 +256,7 @@ func (t *TaskTool) executeCreate(ctx context.Context, params map[string]interfac
 			assignCtx := context.Background()
 			if _, err := t.orchestrator.AssignTask(assignCtx, task); err != nil {
 				// Just log the error, don't fail the task creation
-				fmt.Printf("Failed to auto-assign task: %v\n", err)
+				logger.WarnContext(ctx, "Failed to auto-assign task", "task_id", taskID, "error", err)
 			}
 		}()
 	}
@@ -288,7 +296,7 @@ func (t *TaskTool) executeAssign(ctx context.Context, params map[string]interfac
 	// Update task
 	task.Assignee = multiagent.AgentID(agentID)
 	task.Status = multiagent.TaskStatusAssigned
-	now := time.Now()
+	now := t.clock.Now()
 	task.StartedAt = &now
 
 	// Store updated task
@@ -299,13 +307,13 @@ func (t *TaskTool) executeAssign(ctx context.Context, params map[string]interfac
 	// Notify agent about the task
 	if t.orchestrator != nil {
 		message := &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_%d", taskID, time.Now().UnixNano()),
+			ID:        t.ids.NewID("msg_" + taskID),
 			From:      multiagent.AgentID("task_tool"),
 			To:        []multiagent.AgentID{multiagent.AgentID(agentID)},
 			Type:      multiagent.MessageTypeCommand,
 			Content:   fmt.Sprintf("You have been assigned task %s: %s", taskID, task.Description),
 			Priority:  task.Priority,
-			Timestamp: time.Now(),
+			Timestamp: t.clock.Now(),
 			Context: map[string]interface{}{
 				"task_id":      taskID,
 				"task_type":    task.Type,
@@ -347,7 +355,7 @@ func (t *TaskTool) executeComplete(ctx context.Context, params map[string]interf
 
 	// Update task
 	task.Status = multiagent.TaskStatusCompleted
-	now := time.Now()
+	now := t.clock.Now()
 	task.CompletedAt = &now
 
 	// Add output if provided
@@ -363,13 +371,13 @@ func (t *TaskTool) executeComplete(ctx context.Context, params map[string]interf
 	// Notify requester about completion
 	if t.orchestrator != nil && task.Requester != "" {
 		message := &multiagent.Message{
-			ID:        fmt.Sprintf("msg_%s_complete_%d", taskID, time.Now().UnixNano()),
+			ID:        t.ids.NewID("msg_" + taskID + "_complete"),
 			From:      task.Assignee,
 			To:        []multiagent.AgentID{task.Requester},
 			Type:      multiagent.MessageTypeReport,
 			Content:   fmt.Sprintf("Task %s has been completed", taskID),
 			Priority:  multiagent.PriorityMedium,
-			Timestamp: time.Now(),
+			Timestamp: t.clock.Now(),
 			Context: map[string]interface{}{
 				"task_id":      taskID,
 				"task_status":  string(task.Status),
@@ -379,7 +387,7 @@ func (t *TaskTool) executeComplete(ctx context.Context, params map[string]interf
 
 		if err := t.orchestrator.RouteMessage(ctx, message); err != nil {
 			// Just log the error, don't fail the task completion
-			fmt.Printf("Failed to notify requester: %v\n", err)
+			logger.WarnContext(ctx, "Failed to notify requester", "task_id", taskID, "error", err)
 		}
 	}
 
@@ -0,0 +1,627 @@
+// Package tui is a terminal client for MultiAgentService, with panes for
+// the conversation, the agents' activity on it, and upcoming tasks and
+// events. Progress and notifications show up while a reply is pending.
+// With a voice configured, Ctrl+R records a message and replies can be
+// read aloud.
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/progress"
+	"github.com/kbutz/wikillm/multiagent/service"
+	"github.com/kbutz/wikillm/multiagent/voice"
+)
+
+const (
+	// activityLines is how many progress events the activity pane keeps
+	activityLines = 200
+	// agendaRefresh is how often upcoming tasks and events are reloaded
+	agendaRefresh = 30 * time.Second
+	// agendaDays is how far ahead the agenda looks for events
+	agendaDays = 7
+	// agendaItems is how many tasks and how many events the agenda lists
+	agendaItems = 8
+)
+
+// Service is the part of MultiAgentService the client uses
+type Service interface {
+	ProcessUserMessage(ctx context.Context, userID string, message string) (string, error)
+	SubscribeProgress(userID string) (<-chan progress.Event, func())
+	ListTasks(ctx context.Context) ([]*agents.PersonalTask, error)
+	ListEvents(ctx context.Context, from, to time.Time) ([]*agents.CalendarEvent, error)
+	ListAgents() []service.AgentInfo
+	GetSystemHealth() service.SystemHealthInfo
+}
+
+// Config holds configuration for running the client
+type Config struct {
+	Service Service
+	// UserID is who the client talks to the assistant as
+	UserID string
+	// Notifications, if set, are shown as they arrive; see NotificationChannel
+	Notifications <-chan notify.Notification
+	// Commands are extra words the input line answers itself, such as
+	// "usage", each returning the text to show
+	Commands map[string]func(ctx context.Context) (string, error)
+	// Voice, if set, lets the user talk to the assistant: Ctrl+R starts
+	// and stops recording, and Ctrl+T toggles reading replies aloud
+	Voice *voice.Voice
+	// Clock defaults to the system clock
+	Clock multiagent.Clock
+}
+
+// Run shows the client until the user quits or ctx ends
+func Run(ctx context.Context, config Config) error {
+	events, unsubscribe := config.Service.SubscribeProgress(config.UserID)
+	defer unsubscribe()
+
+	program := tea.NewProgram(newModel(ctx, config, events), tea.WithAltScreen(), tea.WithContext(ctx))
+	_, err := program.Run()
+	if err == tea.ErrProgramKilled && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// NotificationChannel is a notify.Channel showing notifications in the
+// client; pass C as Config.Notifications
+type NotificationChannel struct {
+	C chan notify.Notification
+}
+
+// NewNotificationChannel creates a channel holding up to 16 notifications
+// the client has not shown yet
+func NewNotificationChannel() *NotificationChannel {
+	return &NotificationChannel{C: make(chan notify.Notification, 16)}
+}
+
+// Name implements notify.Channel
+func (c *NotificationChannel) Name() string { return "tui" }
+
+// Send implements notify.Channel
+func (c *NotificationChannel) Send(ctx context.Context, n notify.Notification) error {
+	select {
+	case c.C <- n:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type (
+	progressMsg     progress.Event
+	notificationMsg notify.Notification
+	agendaTickMsg   struct{}
+	pendingTickMsg  struct{}
+
+	replyMsg struct {
+		response string
+		err      error
+		elapsed  time.Duration
+	}
+
+	agendaMsg struct {
+		tasks  []*agents.PersonalTask
+		events []*agents.CalendarEvent
+		err    error
+	}
+
+	transcriptMsg struct {
+		text string
+		err  error
+	}
+
+	spokenMsg struct {
+		err error
+	}
+)
+
+// turn is one entry of the conversation pane
+type turn struct {
+	role    string // "user", "assistant", "notice" or "error"
+	content string
+}
+
+type model struct {
+	ctx           context.Context
+	service       Service
+	userID        string
+	clock         multiagent.Clock
+	progress      <-chan progress.Event
+	notifications <-chan notify.Notification
+	commands      map[string]func(ctx context.Context) (string, error)
+	voice         *voice.Voice
+
+	// recording is the message being recorded, and stopSpeaking ends the
+	// reply being read aloud
+	recording    *voice.Recording
+	transcribing bool
+	speakReplies bool
+	stopSpeaking context.CancelFunc
+
+	turns    []turn
+	activity []string
+	agenda   agendaMsg
+	pending  bool
+	sentAt   time.Time
+
+	input        textinput.Model
+	conversation viewport.Model
+	width        int
+	height       int
+}
+
+var (
+	paneStyle   = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240")).Padding(0, 1)
+	titleStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("63"))
+	userStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+	noticeStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("178"))
+	errorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("160"))
+	dimStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+)
+
+func newModel(ctx context.Context, config Config, events <-chan progress.Event) *model {
+	input := textinput.New()
+	input.Placeholder = "Message the assistant, or: agents, health, exit"
+	input.Prompt = "> "
+	input.Focus()
+
+	return &model{
+		ctx:           multiagent.WithUserID(ctx, config.UserID),
+		service:       config.Service,
+		userID:        config.UserID,
+		clock:         ids.ClockOrSystem(config.Clock),
+		progress:      events,
+		notifications: config.Notifications,
+		commands:      config.Commands,
+		voice:         config.Voice,
+		speakReplies:  config.Voice != nil && config.Voice.SpeakReplies(),
+		input:         input,
+		conversation:  viewport.New(80, 20),
+	}
+}
+
+func (m *model) Init() tea.Cmd {
+	return tea.Batch(textinput.Blink, m.waitForProgress(), m.waitForNotification(), m.loadAgenda(), agendaTick())
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.layout()
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyCtrlC, tea.KeyEsc:
+			m.silence()
+			if m.recording != nil {
+				m.recording.Stop()
+			}
+			return m, tea.Quit
+		case tea.KeyEnter:
+			return m, m.submit()
+		case tea.KeyCtrlR:
+			return m, m.toggleRecording()
+		case tea.KeyCtrlT:
+			m.toggleSpeaking()
+			return m, nil
+		case tea.KeyPgUp, tea.KeyPgDown:
+			var cmd tea.Cmd
+			m.conversation, cmd = m.conversation.Update(msg)
+			return m, cmd
+		}
+
+	case progressMsg:
+		m.addActivity(progress.Event(msg))
+		return m, m.waitForProgress()
+
+	case notificationMsg:
+		m.addTurn("notice", fmt.Sprintf("🔔 %s: %s", msg.Title, msg.Body))
+		return m, tea.Batch(m.waitForNotification(), m.loadAgenda())
+
+	case replyMsg:
+		m.pending = false
+		if msg.err != nil {
+			m.addTurn("error", msg.err.Error())
+		} else {
+			m.addTurn("assistant", msg.response)
+		}
+		m.activity = append(m.activity, dimStyle.Render(fmt.Sprintf("replied in %v", msg.elapsed.Round(time.Millisecond))))
+		// A reply may have added tasks or events
+		if msg.err == nil && m.speakReplies {
+			return m, tea.Batch(m.loadAgenda(), m.speak(msg.response))
+		}
+		return m, m.loadAgenda()
+
+	case transcriptMsg:
+		m.transcribing = false
+		switch {
+		case msg.err != nil:
+			m.addTurn("error", msg.err.Error())
+		case msg.text == "":
+			m.addTurn("notice", "🎙 Heard nothing; press Ctrl+R to try again")
+		case m.pending:
+			// Kept for the user to send once the reply is in
+			m.input.SetValue(msg.text)
+		default:
+			return m, m.send(msg.text)
+		}
+		return m, nil
+
+	case spokenMsg:
+		if msg.err != nil && !errors.Is(msg.err, context.Canceled) {
+			m.addTurn("error", msg.err.Error())
+		}
+		return m, nil
+
+	case agendaMsg:
+		m.agenda = msg
+		return m, nil
+
+	case agendaTickMsg:
+		return m, tea.Batch(m.loadAgenda(), agendaTick())
+
+	case pendingTickMsg:
+		if !m.pending {
+			return m, nil
+		}
+		return m, pendingTick()
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// submit handles the input line: a local command, or a message to the
+// assistant while none is pending
+func (m *model) submit() tea.Cmd {
+	content := strings.TrimSpace(m.input.Value())
+	if content == "" {
+		return nil
+	}
+
+	switch strings.ToLower(content) {
+	case "exit", "quit":
+		return tea.Quit
+	case "agents":
+		m.input.Reset()
+		m.addTurn("notice", m.describeAgents())
+		return nil
+	case "health":
+		m.input.Reset()
+		m.addTurn("notice", m.describeHealth())
+		return nil
+	}
+	if command, ok := m.commands[strings.ToLower(content)]; ok {
+		m.input.Reset()
+		text, err := command(m.ctx)
+		if err != nil {
+			m.addTurn("error", err.Error())
+			return nil
+		}
+		m.addTurn("notice", text)
+		return nil
+	}
+
+	if m.pending {
+		// One request at a time keeps replies in order
+		return nil
+	}
+	m.input.Reset()
+	return m.send(content)
+}
+
+// send sends content to the assistant
+func (m *model) send(content string) tea.Cmd {
+	m.addTurn("user", content)
+	m.pending = true
+	m.sentAt = m.clock.Now()
+
+	ctx, service, userID, sentAt, clock := m.ctx, m.service, m.userID, m.sentAt, m.clock
+	return tea.Batch(func() tea.Msg {
+		response, err := service.ProcessUserMessage(ctx, userID, content)
+		return replyMsg{response: response, err: err, elapsed: clock.Now().Sub(sentAt)}
+	}, pendingTick())
+}
+
+// toggleRecording starts recording a message, or stops and transcribes
+// the one being recorded
+func (m *model) toggleRecording() tea.Cmd {
+	if m.voice == nil || m.transcribing {
+		return nil
+	}
+	if m.recording == nil {
+		recording, err := m.voice.Record(m.ctx)
+		if err != nil {
+			m.addTurn("error", err.Error())
+			return nil
+		}
+		// The user talking over a reply wants it to stop
+		m.silence()
+		m.recording = recording
+		return nil
+	}
+
+	recording, ctx, v := m.recording, m.ctx, m.voice
+	m.recording = nil
+	m.transcribing = true
+	return func() tea.Msg {
+		audio, err := recording.Stop()
+		if err != nil {
+			return transcriptMsg{err: err}
+		}
+		text, err := v.Transcribe(ctx, audio)
+		return transcriptMsg{text: text, err: err}
+	}
+}
+
+// toggleSpeaking turns reading replies aloud on or off
+func (m *model) toggleSpeaking() {
+	if m.voice == nil {
+		return
+	}
+	if !m.voice.CanSpeak() {
+		m.addTurn("error", voice.ErrNoSpeech.Error())
+		return
+	}
+	m.speakReplies = !m.speakReplies
+	if m.speakReplies {
+		m.addTurn("notice", "🔊 Replies will be read aloud")
+		return
+	}
+	m.silence()
+	m.addTurn("notice", "🔇 Replies won't be read aloud")
+}
+
+// speak reads response aloud, interrupting the previous reply
+func (m *model) speak(response string) tea.Cmd {
+	m.silence()
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.stopSpeaking = cancel
+	v := m.voice
+	return func() tea.Msg {
+		defer cancel()
+		return spokenMsg{err: v.Speak(ctx, response)}
+	}
+}
+
+// silence stops the reply being read aloud, if any
+func (m *model) silence() {
+	if m.stopSpeaking != nil {
+		m.stopSpeaking()
+		m.stopSpeaking = nil
+	}
+}
+
+func agendaTick() tea.Cmd {
+	return tea.Tick(agendaRefresh, func(time.Time) tea.Msg { return agendaTickMsg{} })
+}
+
+// pendingTick redraws the elapsed time of a pending request
+func pendingTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return pendingTickMsg{} })
+}
+
+func (m *model) waitForProgress() tea.Cmd {
+	events := m.progress
+	return func() tea.Msg {
+		event, ok := <-events
+		if !ok {
+			return nil
+		}
+		return progressMsg(event)
+	}
+}
+
+func (m *model) waitForNotification() tea.Cmd {
+	if m.notifications == nil {
+		return nil
+	}
+	notifications := m.notifications
+	return func() tea.Msg {
+		n, ok := <-notifications
+		if !ok {
+			return nil
+		}
+		return notificationMsg(n)
+	}
+}
+
+// loadAgenda reads the open tasks and the events of the next week
+func (m *model) loadAgenda() tea.Cmd {
+	ctx, service, now := m.ctx, m.service, m.clock.Now()
+	return func() tea.Msg {
+		tasks, err := service.ListTasks(ctx)
+		if err != nil {
+			return agendaMsg{err: err}
+		}
+		events, err := service.ListEvents(ctx, now, now.AddDate(0, 0, agendaDays))
+		if err != nil {
+			return agendaMsg{err: err}
+		}
+		return agendaMsg{tasks: upcomingTasks(tasks), events: events}
+	}
+}
+
+// upcomingTasks returns the open tasks, those due soonest first and
+// undated ones last
+func upcomingTasks(tasks []*agents.PersonalTask) []*agents.PersonalTask {
+	open := make([]*agents.PersonalTask, 0, len(tasks))
+	for _, task := range tasks {
+		if task.Status != agents.PersonalTaskStatusCompleted && task.Status != agents.PersonalTaskStatusCancelled {
+			open = append(open, task)
+		}
+	}
+	sort.SliceStable(open, func(i, j int) bool {
+		a, b := open[i].DueDate, open[j].DueDate
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.Before(*b)
+	})
+	return open
+}
+
+func (m *model) addTurn(role, content string) {
+	m.turns = append(m.turns, turn{role: role, content: content})
+	m.conversation.SetContent(m.renderTurns())
+	m.conversation.GotoBottom()
+}
+
+func (m *model) addActivity(event progress.Event) {
+	line := fmt.Sprintf("%s %s", event.Timestamp.Format("15:04:05"), event.Type)
+	if event.AgentID != "" {
+		line += " " + string(event.AgentID)
+	}
+	if event.Detail != "" {
+		line += ": " + event.Detail
+	}
+	m.activity = append(m.activity, line)
+	if len(m.activity) > activityLines {
+		m.activity = m.activity[len(m.activity)-activityLines:]
+	}
+}
+
+func (m *model) describeAgents() string {
+	infos := m.service.ListAgents()
+	lines := make([]string, 0, len(infos))
+	for _, info := range infos {
+		lines = append(lines, fmt.Sprintf("%s (%s): %s", info.Name, info.ID, info.Status))
+	}
+	return "Agents:\n" + strings.Join(lines, "\n")
+}
+
+func (m *model) describeHealth() string {
+	health := m.service.GetSystemHealth()
+	return fmt.Sprintf("Health: %s, %d/%d agents active, %d messages processed, %d queued, up %v",
+		health.Status, health.ActiveAgents, health.TotalAgents, health.MessagesProcessed, health.MessageQueueSize, health.Uptime.Round(time.Second))
+}
+
+// layout sizes the panes: the conversation over the activity log on the
+// left, the agenda on the right, and the input line below
+func (m *model) layout() {
+	left := m.leftWidth()
+	m.conversation.Width = left - 4
+	m.conversation.Height = max(m.height-activityHeight(m.height)-7, 3)
+	m.input.Width = m.width - 4
+	m.conversation.SetContent(m.renderTurns())
+}
+
+func (m *model) leftWidth() int {
+	return max(m.width*2/3, 20)
+}
+
+func activityHeight(height int) int {
+	return max(height/4, 3)
+}
+
+func (m *model) View() string {
+	if m.width == 0 {
+		return "Starting…"
+	}
+	left := m.leftWidth()
+	right := max(m.width-left, 10)
+
+	conversation := paneStyle.Width(left - 2).Render(titleStyle.Render("Conversation") + "\n" + m.conversation.View())
+	activity := paneStyle.Width(left - 2).Height(activityHeight(m.height)).Render(titleStyle.Render("Agent activity") + "\n" + m.renderActivity(activityHeight(m.height)-1))
+	agenda := paneStyle.Width(right - 2).Height(m.height - 5).Render(m.renderAgenda())
+
+	help := "Enter to send · PgUp/PgDn to scroll · Esc to quit"
+	if m.voice != nil {
+		help = "Enter to send · Ctrl+R to talk · Ctrl+T to toggle spoken replies · Esc to quit"
+	}
+	status := dimStyle.Render(help)
+	switch {
+	case m.recording != nil:
+		status = noticeStyle.Render("🎙 Recording… press Ctrl+R to send")
+	case m.transcribing:
+		status = noticeStyle.Render("🎙 Transcribing…")
+	case m.pending:
+		status = noticeStyle.Render(fmt.Sprintf("⏳ Working on it (%v)…", m.clock.Now().Sub(m.sentAt).Round(time.Second)))
+	}
+
+	panes := lipgloss.JoinHorizontal(lipgloss.Top, lipgloss.JoinVertical(lipgloss.Left, conversation, activity), agenda)
+	return lipgloss.JoinVertical(lipgloss.Left, panes, m.input.View(), status)
+}
+
+func (m *model) renderTurns() string {
+	width := max(m.conversation.Width, 10)
+	var b strings.Builder
+	for _, t := range m.turns {
+		text := lipgloss.NewStyle().Width(width).Render(t.content)
+		switch t.role {
+		case "user":
+			text = userStyle.Render("You: ") + "\n" + text
+		case "notice":
+			text = noticeStyle.Render(text)
+		case "error":
+			text = errorStyle.Render("Error: " + t.content)
+		}
+		b.WriteString(text + "\n\n")
+	}
+	return b.String()
+}
+
+func (m *model) renderActivity(lines int) string {
+	activity := m.activity
+	if len(activity) > lines {
+		activity = activity[len(activity)-lines:]
+	}
+	return strings.Join(activity, "\n")
+}
+
+func (m *model) renderAgenda() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Upcoming events") + "\n")
+	if m.agenda.err != nil {
+		return b.String() + errorStyle.Render(m.agenda.err.Error())
+	}
+	if len(m.agenda.events) == 0 {
+		b.WriteString(dimStyle.Render("Nothing this week") + "\n")
+	}
+	for i, event := range m.agenda.events {
+		if i == agendaItems {
+			break
+		}
+		when := event.StartTime.Format("Mon 15:04")
+		if event.AllDay {
+			when = event.StartTime.Format("Mon Jan 2")
+		}
+		fmt.Fprintf(&b, "%s %s\n", dimStyle.Render(when), event.Title)
+	}
+
+	b.WriteString("\n" + titleStyle.Render("Open tasks") + "\n")
+	if len(m.agenda.tasks) == 0 {
+		b.WriteString(dimStyle.Render("Nothing to do") + "\n")
+	}
+	for i, task := range m.agenda.tasks {
+		if i == agendaItems {
+			fmt.Fprintf(&b, "%s\n", dimStyle.Render(fmt.Sprintf("+%d more", len(m.agenda.tasks)-agendaItems)))
+			break
+		}
+		due := ""
+		if task.DueDate != nil {
+			due = dimStyle.Render(" due " + task.DueDate.Format("Mon Jan 2"))
+		}
+		fmt.Fprintf(&b, "• %s%s\n", task.Title, due)
+	}
+	return b.String()
+}
@@ -0,0 +1,234 @@
+package tui
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/agents"
+	"github.com/kbutz/wikillm/multiagent/notify"
+	"github.com/kbutz/wikillm/multiagent/progress"
+	"github.com/kbutz/wikillm/multiagent/service"
+	"github.com/kbutz/wikillm/multiagent/voice"
+)
+
+type fakeService struct {
+	hub      *progress.Hub
+	received []string
+	user     string
+	now      time.Time
+}
+
+func (f *fakeService) ProcessUserMessage(ctx context.Context, userID string, message string) (string, error) {
+	f.received = append(f.received, message)
+	return "Added " + message, nil
+}
+
+func (f *fakeService) SubscribeProgress(userID string) (<-chan progress.Event, func()) {
+	return f.hub.Subscribe(userID)
+}
+
+func (f *fakeService) ListTasks(ctx context.Context) ([]*agents.PersonalTask, error) {
+	f.user = multiagent.UserIDFromContext(ctx)
+	due := f.now.Add(2 * time.Hour)
+	return []*agents.PersonalTask{
+		{ID: "task_1", Title: "Someday", Status: agents.PersonalTaskStatusSomeday},
+		{ID: "task_2", Title: "Done", Status: agents.PersonalTaskStatusCompleted},
+		{ID: "task_3", Title: "File taxes", Status: agents.PersonalTaskStatusNext, DueDate: &due},
+	}, nil
+}
+
+func (f *fakeService) ListEvents(ctx context.Context, from, to time.Time) ([]*agents.CalendarEvent, error) {
+	return []*agents.CalendarEvent{{ID: "event_1", Title: "Dentist", StartTime: from.Add(time.Hour), EndTime: from.Add(2 * time.Hour)}}, nil
+}
+
+func (f *fakeService) ListAgents() []service.AgentInfo {
+	return []service.AgentInfo{{ID: "task_manager_agent", Name: "Task Manager", Status: "idle"}}
+}
+
+func (f *fakeService) GetSystemHealth() service.SystemHealthInfo {
+	return service.SystemHealthInfo{Status: "healthy", ActiveAgents: 1, TotalAgents: 1}
+}
+
+type fixedClock struct{ now time.Time }
+
+func (c fixedClock) Now() time.Time { return c.now }
+
+func newTestModel(t *testing.T) (*fakeService, *model, chan notify.Notification) {
+	t.Helper()
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	fake := &fakeService{hub: progress.NewHub(), now: now}
+	events, unsubscribe := fake.SubscribeProgress("alice")
+	t.Cleanup(unsubscribe)
+
+	notifications := make(chan notify.Notification, 1)
+	commands := map[string]func(ctx context.Context) (string, error){
+		"usage": func(ctx context.Context) (string, error) {
+			return "1200 tokens for " + multiagent.UserIDFromContext(ctx), nil
+		},
+	}
+	m := newModel(context.Background(), Config{Service: fake, UserID: "alice", Notifications: notifications, Commands: commands, Clock: fixedClock{now}}, events)
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	return fake, m, notifications
+}
+
+// reply runs the request submit started, skipping the tick batched with it
+func reply(t *testing.T, cmd tea.Cmd) tea.Msg {
+	t.Helper()
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok || len(batch) != 2 {
+		t.Fatalf("submit returned %v, want the request and a tick", batch)
+	}
+	return batch[0]()
+}
+
+func typeLine(m *model, line string) tea.Cmd {
+	m.input.SetValue(line)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return cmd
+}
+
+func TestSendMessage(t *testing.T) {
+	fake, m, _ := newTestModel(t)
+
+	msg := reply(t, typeLine(m, "buy milk"))
+	if !m.pending {
+		t.Fatal("no request pending after sending")
+	}
+	if typeLine(m, "another") != nil || m.input.Value() != "another" {
+		t.Error("a second message was sent while one was pending")
+	}
+
+	m.Update(msg)
+	if m.pending || len(fake.received) != 1 || fake.received[0] != "buy milk" {
+		t.Fatalf("pending = %v, received = %v", m.pending, fake.received)
+	}
+	if len(m.turns) != 2 || m.turns[1].role != "assistant" || m.turns[1].content != "Added buy milk" {
+		t.Errorf("unexpected turns %+v", m.turns)
+	}
+}
+
+func TestLocalCommands(t *testing.T) {
+	fake, m, _ := newTestModel(t)
+
+	if cmd := typeLine(m, "agents"); cmd != nil {
+		t.Error("agents sent a message")
+	}
+	if len(m.turns) != 1 || !strings.Contains(m.turns[0].content, "Task Manager") {
+		t.Errorf("unexpected turns %+v", m.turns)
+	}
+	typeLine(m, "health")
+	if len(fake.received) != 0 || !strings.Contains(m.turns[1].content, "healthy") {
+		t.Errorf("unexpected turns %+v", m.turns)
+	}
+	typeLine(m, "Usage")
+	if len(fake.received) != 0 || m.turns[2].content != "1200 tokens for alice" {
+		t.Errorf("unexpected turns %+v", m.turns)
+	}
+	if _, ok := typeLine(m, "exit")().(tea.QuitMsg); !ok {
+		t.Error("exit did not quit")
+	}
+}
+
+func TestProgressAndNotifications(t *testing.T) {
+	fake, m, notifications := newTestModel(t)
+
+	fake.hub.Publish(progress.Event{Type: progress.AgentStarted, ConversationID: "alice", AgentID: "task_manager_agent", Detail: "adding a task"})
+	m.Update(m.waitForProgress()())
+	if len(m.activity) != 1 || !strings.Contains(m.activity[0], "task_manager_agent: adding a task") {
+		t.Errorf("unexpected activity %v", m.activity)
+	}
+
+	notifications <- notify.Notification{Title: "Reminder", Body: "File taxes"}
+	m.Update(m.waitForNotification()())
+	if len(m.turns) != 1 || m.turns[0].role != "notice" || !strings.Contains(m.turns[0].content, "File taxes") {
+		t.Errorf("unexpected turns %+v", m.turns)
+	}
+}
+
+func TestAgenda(t *testing.T) {
+	fake, m, _ := newTestModel(t)
+
+	m.Update(m.loadAgenda()())
+	if fake.user != "alice" {
+		t.Errorf("tasks listed for %q, want alice", fake.user)
+	}
+	if len(m.agenda.tasks) != 2 || m.agenda.tasks[0].ID != "task_3" {
+		t.Errorf("want open tasks with dated ones first, got %+v", m.agenda.tasks)
+	}
+
+	view := m.View()
+	for _, want := range []string{"Dentist", "File taxes", "Someday"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view is missing %q", want)
+		}
+	}
+	if strings.Contains(view, "Done") {
+		t.Error("view lists a completed task")
+	}
+}
+
+func TestVoice(t *testing.T) {
+	speech := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/audio/speech" {
+			w.Write([]byte("RIFF-speech"))
+			return
+		}
+		w.Write([]byte(`{"text": "call mom"}`))
+	}))
+	defer speech.Close()
+	played := filepath.Join(t.TempDir(), "played.wav")
+
+	fake, m, _ := newTestModel(t)
+	m.voice = voice.New(voice.Config{
+		STT:           voice.ServiceConfig{Provider: voice.ProviderWhisperCpp, URL: speech.URL},
+		TTS:           &voice.ServiceConfig{Provider: voice.ProviderOpenAI, URL: speech.URL + "/v1"},
+		RecordCommand: []string{"sh", "-c", "printf RIFF-recorded; exec sleep 10"},
+		PlayCommand:   []string{"sh", "-c", "cat > " + played},
+	}, nil)
+
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlR}); cmd != nil || m.recording == nil {
+		t.Fatal("Ctrl+R did not start recording")
+	}
+	if !strings.Contains(m.View(), "Recording") {
+		t.Error("view does not show the recording")
+	}
+	// Give the recorder time to start before interrupting it
+	time.Sleep(200 * time.Millisecond)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if m.recording != nil || !m.transcribing {
+		t.Fatal("Ctrl+R did not stop recording")
+	}
+
+	// The transcript is sent like a typed message
+	_, cmd = m.Update(cmd())
+	m.Update(reply(t, cmd))
+	if len(fake.received) != 1 || fake.received[0] != "call mom" {
+		t.Fatalf("received %v", fake.received)
+	}
+
+	// Replies are read aloud once the user turns it on
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	if !m.speakReplies {
+		t.Fatal("Ctrl+T did not turn on spoken replies")
+	}
+	_, cmd = m.Update(replyMsg{response: "Added call mom"})
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok || len(batch) != 2 {
+		t.Fatalf("reply returned %v, want the agenda and speech", batch)
+	}
+	if msg, ok := batch[1]().(spokenMsg); !ok || msg.err != nil {
+		t.Fatalf("speaking returned %+v", msg)
+	}
+	if data, _ := os.ReadFile(played); string(data) != "RIFF-speech" {
+		t.Errorf("played %q", data)
+	}
+}
@@ -0,0 +1,306 @@
+// Package usage accounts for the tokens LLM calls spend, per agent,
+// conversation, model and day, and enforces a daily token budget per
+// conversation.
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/metrics"
+)
+
+const (
+	keyPrefix = "usage:"
+	// dateLayout names a day of usage; days are UTC
+	dateLayout = "2006-01-02"
+	// maxListedDays bounds a single Query scan
+	maxListedDays = 10000
+)
+
+var logger = logging.For("usage")
+
+// Call is the token usage of one LLM call
+type Call struct {
+	Agent            string `json:"agent,omitempty"`
+	ConversationID   string `json:"conversation_id,omitempty"`
+	Model            string `json:"model"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	// Estimated is set when the provider did not report usage and the
+	// counts were estimated from the text
+	Estimated bool      `json:"estimated,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// Totals adds up the usage of a set of calls
+type Totals struct {
+	Calls            int `json:"calls"`
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	// EstimatedCalls counts the calls whose usage was estimated
+	EstimatedCalls int `json:"estimated_calls,omitempty"`
+}
+
+// Tokens returns the prompt and completion tokens together
+func (t Totals) Tokens() int {
+	return t.PromptTokens + t.CompletionTokens
+}
+
+func (t *Totals) add(call Call) {
+	t.Calls++
+	t.PromptTokens += call.PromptTokens
+	t.CompletionTokens += call.CompletionTokens
+	if call.Estimated {
+		t.EstimatedCalls++
+	}
+}
+
+// Day is one day's usage, in total and per agent, conversation and model
+type Day struct {
+	Date          string            `json:"date"`
+	Total         Totals            `json:"total"`
+	Agents        map[string]Totals `json:"agents"`
+	Conversations map[string]Totals `json:"conversations"`
+	Models        map[string]Totals `json:"models"`
+}
+
+func newDay(date string) *Day {
+	return &Day{
+		Date:          date,
+		Agents:        make(map[string]Totals),
+		Conversations: make(map[string]Totals),
+		Models:        make(map[string]Totals),
+	}
+}
+
+func (d *Day) add(call Call) {
+	d.Total.add(call)
+	addTo(d.Agents, call.Agent, call)
+	addTo(d.Conversations, call.ConversationID, call)
+	addTo(d.Models, call.Model, call)
+}
+
+func addTo(totals map[string]Totals, key string, call Call) {
+	if key == "" {
+		key = "unknown"
+	}
+	entry := totals[key]
+	entry.add(call)
+	totals[key] = entry
+}
+
+func (d *Day) clone() *Day {
+	clone := newDay(d.Date)
+	clone.Total = d.Total
+	for key, totals := range d.Agents {
+		clone.Agents[key] = totals
+	}
+	for key, totals := range d.Conversations {
+		clone.Conversations[key] = totals
+	}
+	for key, totals := range d.Models {
+		clone.Models[key] = totals
+	}
+	return clone
+}
+
+// Budget caps token usage; zero fields are unlimited
+type Budget struct {
+	// ConversationTokensPerDay is the most tokens a conversation spends in a
+	// day before it is degraded to cheaper behavior
+	ConversationTokensPerDay int
+}
+
+// TrackerConfig holds configuration for creating a Tracker
+type TrackerConfig struct {
+	// Store persists each day's usage; without it usage is kept in memory
+	Store multiagent.MemoryStore
+	// Metrics, if set, receives token counts per agent
+	Metrics *metrics.Registry
+	Budget  Budget
+	// Clock decides which day is today (default the system clock)
+	Clock multiagent.Clock
+}
+
+// Tracker records LLM usage and reports conversations over budget
+type Tracker struct {
+	mu     sync.Mutex
+	store  multiagent.MemoryStore
+	budget Budget
+	clock  multiagent.Clock
+	// days caches today's usage; without a store it holds every day
+	days     map[string]*Day
+	tokens   *metrics.Counter
+	exceeded *metrics.Counter
+}
+
+// NewTracker creates a usage tracker
+func NewTracker(config TrackerConfig) *Tracker {
+	tracker := &Tracker{
+		store:  config.Store,
+		budget: config.Budget,
+		clock:  ids.ClockOrSystem(config.Clock),
+		days:   make(map[string]*Day),
+	}
+	if config.Metrics != nil {
+		tracker.tokens = config.Metrics.NewCounter("multiagent_llm_agent_tokens_total",
+			"LLM tokens by agent, as reported by the provider or estimated", "agent", "direction")
+		tracker.exceeded = config.Metrics.NewCounter("multiagent_llm_budget_exceeded_total",
+			"LLM calls made for conversations already over their token budget")
+	}
+	return tracker
+}
+
+// Budget returns the budget the tracker enforces
+func (t *Tracker) Budget() Budget {
+	return t.budget
+}
+
+// Record adds call to its day's usage
+func (t *Tracker) Record(ctx context.Context, call Call) error {
+	if call.Time.IsZero() {
+		call.Time = t.clock.Now()
+	}
+	if t.tokens != nil {
+		t.tokens.Add(float64(call.PromptTokens), call.Agent, "prompt")
+		t.tokens.Add(float64(call.CompletionTokens), call.Agent, "completion")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	day := t.load(ctx, call.Time.UTC().Format(dateLayout))
+	wasOver := t.over(day, call.ConversationID)
+	day.add(call)
+
+	if wasOver && t.exceeded != nil {
+		t.exceeded.Inc()
+	}
+	if !wasOver && t.over(day, call.ConversationID) {
+		logger.WarnContext(ctx, "Conversation exceeded its daily token budget", logging.KeyConversationID, call.ConversationID,
+			"tokens", day.Conversations[call.ConversationID].Tokens(), "budget", t.budget.ConversationTokensPerDay)
+	}
+	// Stored under the lock so a day's writes land in order
+	if t.store == nil {
+		return nil
+	}
+	if err := t.store.Store(ctx, keyPrefix+day.Date, day); err != nil {
+		return fmt.Errorf("failed to store usage: %w", err)
+	}
+	return nil
+}
+
+// Exceeded reports whether a conversation has spent today's token budget
+func (t *Tracker) Exceeded(ctx context.Context, conversationID string) bool {
+	if t.budget.ConversationTokensPerDay <= 0 || conversationID == "" {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.over(t.load(ctx, t.today()), conversationID)
+}
+
+func (t *Tracker) over(day *Day, conversationID string) bool {
+	if t.budget.ConversationTokensPerDay <= 0 || conversationID == "" {
+		return false
+	}
+	return day.Conversations[conversationID].Tokens() >= t.budget.ConversationTokensPerDay
+}
+
+// Day returns the usage on the day containing date
+func (t *Tracker) Day(ctx context.Context, date time.Time) *Day {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.load(ctx, date.UTC().Format(dateLayout)).clone()
+}
+
+// Query returns the days of usage since the day containing since, oldest
+// first
+func (t *Tracker) Query(ctx context.Context, since time.Time) ([]*Day, error) {
+	first := since.UTC().Format(dateLayout)
+	dates := map[string]bool{}
+	if t.store != nil {
+		keys, err := t.store.List(ctx, keyPrefix, maxListedDays)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list usage: %w", err)
+		}
+		for _, key := range keys {
+			dates[strings.TrimPrefix(key, keyPrefix)] = true
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for date := range t.days {
+		dates[date] = true
+	}
+
+	sorted := make([]string, 0, len(dates))
+	for date := range dates {
+		if date >= first {
+			sorted = append(sorted, date)
+		}
+	}
+	sort.Strings(sorted)
+	days := make([]*Day, 0, len(sorted))
+	for _, date := range sorted {
+		days = append(days, t.load(ctx, date).clone())
+	}
+	return days, nil
+}
+
+// today names the current day on the tracker's clock
+func (t *Tracker) today() string {
+	return t.clock.Now().UTC().Format(dateLayout)
+}
+
+// load returns the usage for date, reading it from the store unless it is
+// cached. Only today stays cached, so a long-running tracker does not keep
+// every day it has seen; the caller holds t.mu
+func (t *Tracker) load(ctx context.Context, date string) *Day {
+	if day, ok := t.days[date]; ok {
+		return day
+	}
+	day := newDay(date)
+	if t.store != nil {
+		if value, err := t.store.Get(ctx, keyPrefix+date); err == nil {
+			if data, err := json.Marshal(value); err == nil {
+				var stored Day
+				if err := json.Unmarshal(data, &stored); err == nil {
+					day = stored.clone()
+					day.Date = date
+				}
+			}
+		}
+	}
+	switch {
+	case t.store == nil:
+		t.days[date] = day
+	case date == t.today():
+		t.days = map[string]*Day{date: day}
+	}
+	return day
+}
+
+// Sum adds up the totals by picks out of each day, e.g. every day's Agents
+func Sum(days []*Day, by func(*Day) map[string]Totals) map[string]Totals {
+	sums := make(map[string]Totals)
+	for _, day := range days {
+		for key, totals := range by(day) {
+			sum := sums[key]
+			sum.Calls += totals.Calls
+			sum.PromptTokens += totals.PromptTokens
+			sum.CompletionTokens += totals.CompletionTokens
+			sum.EstimatedCalls += totals.EstimatedCalls
+			sums[key] = sum
+		}
+	}
+	return sums
+}
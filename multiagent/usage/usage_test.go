@@ -0,0 +1,123 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/memory"
+)
+
+func TestTrackerAggregatesAndPersistsUsage(t *testing.T) {
+	ctx := context.Background()
+	store, err := memory.NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	yesterday := time.Now().AddDate(0, 0, -1)
+
+	tracker := NewTracker(TrackerConfig{Store: store})
+	for _, call := range []Call{
+		{Agent: "task_manager_agent", ConversationID: "conv_alice", Model: "local/big", PromptTokens: 100, CompletionTokens: 20},
+		{Agent: "task_manager_agent", ConversationID: "conv_bob", Model: "fast/small", PromptTokens: 10, CompletionTokens: 5, Estimated: true},
+		{Agent: "scheduler_agent", ConversationID: "conv_alice", Model: "local/big", PromptTokens: 50, CompletionTokens: 30, Time: yesterday},
+	} {
+		if err := tracker.Record(ctx, call); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	// A fresh tracker reads the days back from the store
+	days, err := NewTracker(TrackerConfig{Store: store}).Query(ctx, yesterday)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(days) != 2 {
+		t.Fatalf("days = %d, want 2", len(days))
+	}
+	if today := days[1]; today.Total.Tokens() != 135 || today.Total.EstimatedCalls != 1 {
+		t.Errorf("today's total = %+v", today.Total)
+	}
+
+	conversations := Sum(days, func(d *Day) map[string]Totals { return d.Conversations })
+	if alice := conversations["conv_alice"]; alice.Calls != 2 || alice.Tokens() != 200 {
+		t.Errorf("conv_alice = %+v, want 2 calls and 200 tokens", alice)
+	}
+	agents := Sum(days, func(d *Day) map[string]Totals { return d.Agents })
+	if task := agents["task_manager_agent"]; task.PromptTokens != 110 || task.CompletionTokens != 25 {
+		t.Errorf("task_manager_agent = %+v", task)
+	}
+
+	// Only today's usage is left since today
+	days, err = tracker.Query(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(days) != 1 || days[0].Models["local/big"].Calls != 1 {
+		t.Errorf("usage since today = %+v", days)
+	}
+}
+
+func TestTrackerReportsConversationsOverBudget(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewTracker(TrackerConfig{Budget: Budget{ConversationTokensPerDay: 100}})
+
+	tracker.Record(ctx, Call{ConversationID: "conv_alice", Model: "m", PromptTokens: 60, CompletionTokens: 30})
+	if tracker.Exceeded(ctx, "conv_alice") {
+		t.Fatal("conv_alice is over budget at 90 of 100 tokens")
+	}
+	tracker.Record(ctx, Call{ConversationID: "conv_alice", Model: "m", PromptTokens: 10})
+	if !tracker.Exceeded(ctx, "conv_alice") {
+		t.Fatal("conv_alice is within budget at 100 of 100 tokens")
+	}
+	if tracker.Exceeded(ctx, "conv_bob") {
+		t.Fatal("conv_bob is over budget without spending anything")
+	}
+
+	// Yesterday's spending does not count against today
+	tracker.Record(ctx, Call{ConversationID: "conv_bob", Model: "m", PromptTokens: 500, Time: time.Now().AddDate(0, 0, -1)})
+	if tracker.Exceeded(ctx, "conv_bob") {
+		t.Fatal("conv_bob is over budget on yesterday's usage")
+	}
+}
+
+func TestTrackerFollowsItsClockAndCachesOnlyToday(t *testing.T) {
+	ctx := context.Background()
+	store, err := memory.NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := ids.NewManualClock(start)
+
+	tracker := NewTracker(TrackerConfig{Store: store, Clock: clock, Budget: Budget{ConversationTokensPerDay: 100}})
+	for day := 0; day < 3; day++ {
+		if err := tracker.Record(ctx, Call{ConversationID: "conv_alice", Model: "m", PromptTokens: 100}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+		if !tracker.Exceeded(ctx, "conv_alice") {
+			t.Fatalf("day %d: conv_alice is within budget after spending it on the clock's day", day)
+		}
+		clock.Advance(24 * time.Hour)
+		if tracker.Exceeded(ctx, "conv_alice") {
+			t.Fatalf("day %d: the previous day's spending counts against the next", day)
+		}
+	}
+	if len(tracker.days) != 1 {
+		t.Errorf("tracker caches %d days, want only today", len(tracker.days))
+	}
+
+	days, err := tracker.Query(ctx, start)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(days) != 4 || days[0].Date != "2026-03-01" || days[0].Total.Tokens() != 100 {
+		t.Errorf("usage since the start = %+v", days)
+	}
+	if len(tracker.days) != 1 {
+		t.Errorf("Query left %d days cached, want only today", len(tracker.days))
+	}
+}
@@ -0,0 +1,28 @@
+package multiagent
+
+import "context"
+
+type userIDKey struct{}
+
+// WithUserID returns ctx acting on behalf of userID
+func WithUserID(ctx context.Context, userID string) context.Context {
+	if userID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFromContext returns the user ctx acts for, or "" for system work
+func UserIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey{}).(string)
+	return userID
+}
+
+// UserIDFromMessage returns the user a message acts for, or ""
+func UserIDFromMessage(msg *Message) string {
+	if msg == nil {
+		return ""
+	}
+	userID, _ := msg.Context[ContextUserID].(string)
+	return userID
+}
@@ -0,0 +1,95 @@
+package voice
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Speech service providers
+const (
+	// ProviderWhisperCpp is a whisper.cpp server's /inference endpoint
+	ProviderWhisperCpp = "whisper.cpp"
+	// ProviderOpenAI is OpenAI's audio API, or a server compatible with it
+	ProviderOpenAI = "openai"
+)
+
+// ServiceConfig is a speech-to-text or text-to-speech service
+type ServiceConfig struct {
+	// Provider is ProviderWhisperCpp (speech-to-text only) or ProviderOpenAI
+	Provider string `json:"provider"`
+	// URL defaults to https://api.openai.com/v1 for OpenAI and is required
+	// for whisper.cpp, e.g. "http://localhost:8080"
+	URL string `json:"url,omitempty"`
+	// APIKey may reference environment variables, e.g. "$OPENAI_API_KEY"
+	APIKey string `json:"api_key,omitempty"`
+	// Model defaults to whisper-1 for speech-to-text and tts-1 for
+	// text-to-speech with OpenAI
+	Model string `json:"model,omitempty"`
+	// Voice is the text-to-speech voice (default alloy)
+	Voice string `json:"voice,omitempty"`
+}
+
+// Config is how a client listens and speaks
+type Config struct {
+	// STT transcribes what the user says
+	STT ServiceConfig `json:"stt"`
+	// TTS, if set, reads replies aloud
+	TTS *ServiceConfig `json:"tts,omitempty"`
+	// RecordCommand writes WAV audio from the microphone to stdout until it
+	// is interrupted (default arecord at 16kHz mono)
+	RecordCommand []string `json:"record_command,omitempty"`
+	// PlayCommand plays WAV audio from stdin (default aplay)
+	PlayCommand []string `json:"play_command,omitempty"`
+	// SpeakReplies reads every reply aloud from the start; clients can
+	// toggle it
+	SpeakReplies bool `json:"speak_replies,omitempty"`
+}
+
+// LoadConfig reads voice settings from a JSON file:
+//
+//	{"stt": {"provider": "whisper.cpp", "url": "http://localhost:8080"},
+//	 "tts": {"provider": "openai", "api_key": "$OPENAI_API_KEY"},
+//	 "play_command": ["ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet", "-"]}
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read voice config: %w", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse voice config: %w", err)
+	}
+	config.STT.URL = os.ExpandEnv(config.STT.URL)
+	config.STT.APIKey = os.ExpandEnv(config.STT.APIKey)
+	if err := config.STT.validate(); err != nil {
+		return config, fmt.Errorf("voice config stt: %w", err)
+	}
+	if config.TTS != nil {
+		config.TTS.URL = os.ExpandEnv(config.TTS.URL)
+		config.TTS.APIKey = os.ExpandEnv(config.TTS.APIKey)
+		if config.TTS.Provider != ProviderOpenAI {
+			return config, fmt.Errorf("voice config tts: provider must be %s", ProviderOpenAI)
+		}
+		if err := config.TTS.validate(); err != nil {
+			return config, fmt.Errorf("voice config tts: %w", err)
+		}
+	}
+	return config, nil
+}
+
+func (c *ServiceConfig) validate() error {
+	switch c.Provider {
+	case ProviderWhisperCpp:
+		if c.URL == "" {
+			return fmt.Errorf("whisper.cpp needs the server's url")
+		}
+	case ProviderOpenAI:
+		if c.URL == "" && c.APIKey == "" {
+			return fmt.Errorf("openai needs an api_key")
+		}
+	default:
+		return fmt.Errorf("provider must be %s or %s, got %q", ProviderWhisperCpp, ProviderOpenAI, c.Provider)
+	}
+	return nil
+}
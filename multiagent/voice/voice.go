@@ -0,0 +1,247 @@
+// Package voice lets the interactive clients be used hands-free: what the
+// user says is recorded and transcribed by a whisper.cpp server or OpenAI's
+// audio API, and replies can be read aloud with OpenAI's text-to-speech.
+// Audio is recorded and played by external commands, such as arecord and
+// aplay, sox or ffplay, so no audio libraries are needed.
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent/logging"
+)
+
+var logger = logging.For("voice")
+
+const (
+	defaultOpenAIURL = "https://api.openai.com/v1"
+	// requestTimeout bounds each transcription and synthesis
+	requestTimeout = 2 * time.Minute
+	// maxAudioSize bounds the synthesized audio read
+	maxAudioSize = 50 << 20
+)
+
+var (
+	defaultRecordCommand = []string{"arecord", "-q", "-f", "S16_LE", "-r", "16000", "-c", "1", "-t", "wav"}
+	defaultPlayCommand   = []string{"aplay", "-q"}
+)
+
+// ErrNoSpeech is returned by Speak when no text-to-speech is configured
+var ErrNoSpeech = errors.New("no text-to-speech service is configured")
+
+// Voice records, transcribes and speaks for a client
+type Voice struct {
+	stt           ServiceConfig
+	tts           *ServiceConfig
+	recordCommand []string
+	playCommand   []string
+	speakReplies  bool
+	client        *http.Client
+}
+
+// New creates a Voice from config; client defaults to http.DefaultClient
+func New(config Config, client *http.Client) *Voice {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(config.RecordCommand) == 0 {
+		config.RecordCommand = defaultRecordCommand
+	}
+	if len(config.PlayCommand) == 0 {
+		config.PlayCommand = defaultPlayCommand
+	}
+	return &Voice{
+		stt:           config.STT,
+		tts:           config.TTS,
+		recordCommand: config.RecordCommand,
+		playCommand:   config.PlayCommand,
+		speakReplies:  config.SpeakReplies && config.TTS != nil,
+		client:        client,
+	}
+}
+
+// CanSpeak reports whether replies can be read aloud
+func (v *Voice) CanSpeak() bool {
+	return v.tts != nil
+}
+
+// SpeakReplies reports whether replies should be read aloud from the start
+func (v *Voice) SpeakReplies() bool {
+	return v.speakReplies
+}
+
+// Recording is audio being recorded until Stop
+type Recording struct {
+	cmd    *exec.Cmd
+	audio  bytes.Buffer
+	stderr bytes.Buffer
+	// done is closed when the recorder exits
+	done chan struct{}
+	once sync.Once
+	err  error
+}
+
+// Record starts recording from the microphone; ctx ending abandons it
+func (v *Voice) Record(ctx context.Context) (*Recording, error) {
+	r := &Recording{done: make(chan struct{})}
+	r.cmd = exec.CommandContext(ctx, v.recordCommand[0], v.recordCommand[1:]...)
+	r.cmd.Stdout = &r.audio
+	r.cmd.Stderr = &r.stderr
+	if err := r.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start recording with %s: %w", v.recordCommand[0], err)
+	}
+	go func() {
+		r.cmd.Wait()
+		close(r.done)
+	}()
+	return r, nil
+}
+
+// Stop ends the recording and returns the audio recorded
+func (r *Recording) Stop() ([]byte, error) {
+	r.once.Do(func() {
+		// Recorders finish the file they are writing when interrupted
+		r.cmd.Process.Signal(os.Interrupt)
+		select {
+		case <-r.done:
+		case <-time.After(2 * time.Second):
+			r.cmd.Process.Kill()
+			<-r.done
+		}
+		if r.audio.Len() == 0 {
+			r.err = fmt.Errorf("nothing was recorded: %s", strings.TrimSpace(r.stderr.String()))
+		}
+	})
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.audio.Bytes(), nil
+}
+
+// Transcribe returns the text spoken in audio, a WAV recording
+func (v *Voice) Transcribe(ctx context.Context, audio []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "speech.wav")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audio: %w", err)
+	}
+	file.Write(audio)
+	form.WriteField("response_format", "json")
+
+	endpoint := strings.TrimSuffix(v.stt.URL, "/") + "/inference"
+	if v.stt.Provider == ProviderOpenAI {
+		endpoint = openAIURL(&v.stt) + "/audio/transcriptions"
+		form.WriteField("model", orDefault(v.stt.Model, "whisper-1"))
+	}
+	form.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	data, err := v.do(req, &v.stt, 1<<20)
+	if err != nil {
+		return "", fmt.Errorf("failed to transcribe: %w", err)
+	}
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("failed to parse transcription: %w", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// Speak reads text aloud, returning when it has been played or ctx ends
+func (v *Voice) Speak(ctx context.Context, text string) error {
+	if v.tts == nil {
+		return ErrNoSpeech
+	}
+	audio, err := v.synthesize(ctx, text)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, v.playCommand[0], v.playCommand[1:]...)
+	cmd.Stdin = bytes.NewReader(audio)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to play audio with %s: %w: %s", v.playCommand[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// synthesize returns text spoken as WAV audio
+func (v *Voice) synthesize(ctx context.Context, text string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	payload, err := json.Marshal(map[string]string{
+		"model":           orDefault(v.tts.Model, "tts-1"),
+		"voice":           orDefault(v.tts.Voice, "alloy"),
+		"input":           text,
+		"response_format": "wav",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode speech request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, openAIURL(v.tts)+"/audio/speech", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	audio, err := v.do(req, v.tts, maxAudioSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to synthesize speech: %w", err)
+	}
+	return audio, nil
+}
+
+// do sends req to service and returns the body of a 200 response
+func (v *Voice) do(req *http.Request, service *ServiceConfig, limit int64) ([]byte, error) {
+	if service.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+service.APIKey)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		logger.Debug("Speech service error", "url", req.URL.String(), "status", resp.StatusCode, "body", string(data))
+		return nil, fmt.Errorf("%s returned %s", service.Provider, resp.Status)
+	}
+	return data, nil
+}
+
+func openAIURL(service *ServiceConfig) string {
+	return strings.TrimSuffix(orDefault(service.URL, defaultOpenAIURL), "/")
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
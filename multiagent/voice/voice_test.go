@@ -0,0 +1,167 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSpeech is a whisper.cpp and OpenAI audio server
+type fakeSpeech struct {
+	paths  []string
+	fields map[string]string
+	audio  string
+	speech map[string]string
+	auth   string
+}
+
+func (f *fakeSpeech) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.paths = append(f.paths, r.URL.Path)
+	f.auth = r.Header.Get("Authorization")
+	if r.URL.Path == "/v1/audio/speech" {
+		json.NewDecoder(r.Body).Decode(&f.speech)
+		w.Write([]byte("RIFF-speech"))
+		return
+	}
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.fields = make(map[string]string)
+	for key, values := range r.MultipartForm.Value {
+		f.fields[key] = values[0]
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, _ := io.ReadAll(file)
+	f.audio = string(data)
+	w.Write([]byte(`{"text": " Remind me to call mom. "}`))
+}
+
+func TestTranscribe(t *testing.T) {
+	fake := &fakeSpeech{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	ctx := context.Background()
+
+	whisper := New(Config{STT: ServiceConfig{Provider: ProviderWhisperCpp, URL: server.URL}}, nil)
+	text, err := whisper.Transcribe(ctx, []byte("RIFF-audio"))
+	if err != nil {
+		t.Fatalf("Transcribe: %v", err)
+	}
+	if text != "Remind me to call mom." || fake.paths[0] != "/inference" || fake.audio != "RIFF-audio" {
+		t.Errorf("transcribed %q from %s with %q", text, fake.paths[0], fake.audio)
+	}
+
+	openai := New(Config{STT: ServiceConfig{Provider: ProviderOpenAI, URL: server.URL + "/v1", APIKey: "sk-test"}}, nil)
+	if _, err := openai.Transcribe(ctx, []byte("RIFF-audio")); err != nil {
+		t.Fatalf("Transcribe: %v", err)
+	}
+	if fake.paths[1] != "/v1/audio/transcriptions" || fake.fields["model"] != "whisper-1" || fake.auth != "Bearer sk-test" {
+		t.Errorf("unexpected OpenAI request to %s with %v, auth %q", fake.paths[1], fake.fields, fake.auth)
+	}
+	if openai.CanSpeak() {
+		t.Error("voice without tts can speak")
+	}
+	if err := openai.Speak(ctx, "hello"); err != ErrNoSpeech {
+		t.Errorf("Speak without tts = %v", err)
+	}
+}
+
+func TestSpeak(t *testing.T) {
+	fake := &fakeSpeech{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	played := filepath.Join(t.TempDir(), "played.wav")
+
+	v := New(Config{
+		STT:          ServiceConfig{Provider: ProviderWhisperCpp, URL: server.URL},
+		TTS:          &ServiceConfig{Provider: ProviderOpenAI, URL: server.URL + "/v1", Voice: "nova"},
+		PlayCommand:  []string{"sh", "-c", "cat > " + played},
+		SpeakReplies: true,
+	}, nil)
+	if !v.CanSpeak() || !v.SpeakReplies() {
+		t.Fatal("voice with tts cannot speak")
+	}
+	if err := v.Speak(context.Background(), "Added call mom."); err != nil {
+		t.Fatalf("Speak: %v", err)
+	}
+	if fake.speech["input"] != "Added call mom." || fake.speech["voice"] != "nova" || fake.speech["model"] != "tts-1" {
+		t.Errorf("unexpected speech request %v", fake.speech)
+	}
+	if data, _ := os.ReadFile(played); string(data) != "RIFF-speech" {
+		t.Errorf("played %q", data)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	v := New(Config{RecordCommand: []string{"sh", "-c", "printf RIFF-recorded; exec sleep 10"}}, nil)
+	recording, err := v.Record(context.Background())
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	// Give the recorder time to start before interrupting it
+	time.Sleep(200 * time.Millisecond)
+	start := time.Now()
+	audio, err := recording.Stop()
+	if err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if string(audio) != "RIFF-recorded" {
+		t.Errorf("recorded %q", audio)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stop took %v, want the recorder interrupted", elapsed)
+	}
+
+	silent := New(Config{RecordCommand: []string{"sh", "-c", "echo no microphone >&2"}}, nil)
+	recording, err = silent.Record(context.Background())
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	<-recording.done
+	if _, err := recording.Stop(); err == nil || !strings.Contains(err.Error(), "no microphone") {
+		t.Errorf("Stop = %v, want the recorder's complaint", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	load := func(content string) (Config, error) {
+		path := filepath.Join(dir, "voice.json")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return LoadConfig(path)
+	}
+
+	config, err := load(`{"stt": {"provider": "whisper.cpp", "url": "http://localhost:8080"}, "tts": {"provider": "openai", "api_key": "$OPENAI_API_KEY"}}`)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if config.TTS == nil || config.TTS.APIKey != "sk-test" {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	for _, invalid := range []string{
+		`{"stt": {"provider": "whisper.cpp"}}`,
+		`{"stt": {"provider": "openai"}}`,
+		`{"stt": {"provider": "vosk", "url": "http://localhost:2700"}}`,
+		`{"stt": {"provider": "whisper.cpp", "url": "http://localhost:8080"}, "tts": {"provider": "whisper.cpp", "url": "http://localhost:8080"}}`,
+	} {
+		if _, err := load(invalid); err == nil {
+			t.Errorf("LoadConfig accepted %s", invalid)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// Endpoint is a URL events are POSTed to
+type Endpoint struct {
+	URL string `json:"url"`
+	// Secret signs each request; it may reference environment variables,
+	// e.g. "$HOME_ASSISTANT_WEBHOOK_SECRET"
+	Secret string `json:"secret"`
+	// Events selects the event types sent (default all)
+	Events []EventType `json:"events,omitempty"`
+	// Users selects whose events are sent (default everyone's)
+	Users []string `json:"users,omitempty"`
+}
+
+// wants reports whether the endpoint subscribes to event
+func (e *Endpoint) wants(event *Event) bool {
+	if len(e.Events) > 0 && !containsType(e.Events, event.Type) {
+		return false
+	}
+	if len(e.Users) > 0 && !containsUser(e.Users, event.UserID) {
+		return false
+	}
+	return true
+}
+
+func containsType(types []EventType, eventType EventType) bool {
+	for _, t := range types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUser(users []string, userID string) bool {
+	for _, u := range users {
+		if u == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadConfig reads webhook endpoints from a JSON file:
+//
+//	{"endpoints": [{"url": "https://ha.example.com/api/webhook/wikillm",
+//	  "secret": "$HA_WEBHOOK_SECRET", "events": ["reminder.triggered"], "users": ["alice"]}]}
+func LoadConfig(path string) ([]Endpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook config: %w", err)
+	}
+	var file struct {
+		Endpoints []Endpoint `json:"endpoints"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook config: %w", err)
+	}
+	for i := range file.Endpoints {
+		endpoint := &file.Endpoints[i]
+		endpoint.URL = os.ExpandEnv(endpoint.URL)
+		endpoint.Secret = os.ExpandEnv(endpoint.Secret)
+		if err := endpoint.validate(); err != nil {
+			return nil, fmt.Errorf("webhook endpoint %d: %w", i+1, err)
+		}
+	}
+	return file.Endpoints, nil
+}
+
+func (e *Endpoint) validate() error {
+	target, err := url.Parse(e.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("needs an http or https url")
+	}
+	if e.Secret == "" {
+		return fmt.Errorf("needs a secret to sign requests with")
+	}
+	for _, eventType := range e.Events {
+		if !known(eventType) {
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+	return nil
+}
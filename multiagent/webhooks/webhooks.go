@@ -0,0 +1,324 @@
+// Package webhooks POSTs what happens in users' assistants, such as
+// reminders firing, tasks being completed and events being scheduled, to
+// URLs they configure, so automations in Home Assistant, Zapier and the
+// like can react. Requests are signed with each endpoint's secret and
+// retried with backoff until the endpoint accepts them.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/notify"
+)
+
+var logger = logging.For("webhooks")
+
+// EventType names a kind of event sent to webhooks
+type EventType string
+
+const (
+	ReminderTriggered EventType = "reminder.triggered"
+	TaskCompleted     EventType = "task.completed"
+	EventCreated      EventType = "event.created"
+)
+
+func known(eventType EventType) bool {
+	switch eventType {
+	case ReminderTriggered, TaskCompleted, EventCreated:
+		return true
+	}
+	return false
+}
+
+// Request headers; the signature is "sha256=" and the hex HMAC-SHA256 of
+// the timestamp, a dot and the body, keyed with the endpoint's secret
+const (
+	HeaderEvent     = "X-Wikillm-Event"
+	HeaderDelivery  = "X-Wikillm-Delivery"
+	HeaderTimestamp = "X-Wikillm-Timestamp"
+	HeaderSignature = "X-Wikillm-Signature"
+)
+
+const (
+	// requestTimeout bounds each delivery attempt
+	requestTimeout = 10 * time.Second
+	// maxRetryDelay caps the backoff between attempts
+	maxRetryDelay = 5 * time.Minute
+	// maxConcurrent bounds the deliveries in flight
+	maxConcurrent = 8
+)
+
+// Event is the JSON body POSTed to endpoints
+type Event struct {
+	// ID is the same on every attempt, so receivers can drop repeats
+	ID        string                 `json:"id"`
+	Type      EventType              `json:"type"`
+	UserID    string                 `json:"user_id,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// Sign returns the signature header value for body sent at timestamp
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DispatcherConfig holds configuration for creating a Dispatcher
+type DispatcherConfig struct {
+	Endpoints []Endpoint
+	// MaxAttempts is how often a delivery is tried (default 5)
+	MaxAttempts int
+	// RetryDelay is the wait before the first retry, doubling after each
+	// (default 2 seconds)
+	RetryDelay time.Duration
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+	// Clock defaults to the system clock
+	Clock multiagent.Clock
+	// IDs defaults to ULIDs
+	IDs multiagent.IDGenerator
+}
+
+// Dispatcher sends events to the endpoints subscribed to them, in the
+// background
+type Dispatcher struct {
+	maxAttempts int
+	retryDelay  time.Duration
+	client      *http.Client
+	clock       multiagent.Clock
+	ids         multiagent.IDGenerator
+
+	mu        sync.RWMutex
+	endpoints []Endpoint
+	stopped   bool
+
+	slots chan struct{}
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher for config.Endpoints
+func NewDispatcher(config DispatcherConfig) *Dispatcher {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = 2 * time.Second
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return &Dispatcher{
+		maxAttempts: config.MaxAttempts,
+		retryDelay:  config.RetryDelay,
+		client:      config.Client,
+		clock:       ids.ClockOrSystem(config.Clock),
+		ids:         ids.OrDefault(config.IDs),
+		endpoints:   config.Endpoints,
+		slots:       make(chan struct{}, maxConcurrent),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Reconfigure replaces the endpoints; deliveries under way finish on the
+// old ones
+func (d *Dispatcher) Reconfigure(endpoints []Endpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.endpoints = endpoints
+}
+
+// Stop abandons pending retries and waits for requests in flight, or for
+// ctx to end. Events published afterwards are dropped.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.stopped {
+		d.stopped = true
+		close(d.stop)
+	}
+	d.mu.Unlock()
+	return d.Wait(ctx)
+}
+
+// Wait blocks until every delivery has succeeded or given up, or ctx ends
+func (d *Dispatcher) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Publish sends event to every endpoint subscribed to it, filling in its ID
+// and timestamp when unset. It does not wait for the deliveries.
+func (d *Dispatcher) Publish(ctx context.Context, event Event) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.stopped {
+		return
+	}
+	var targets []Endpoint
+	for _, endpoint := range d.endpoints {
+		if endpoint.wants(&event) {
+			targets = append(targets, endpoint)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	if event.ID == "" {
+		event.ID = d.ids.NewID("whk")
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = d.clock.Now()
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to encode webhook event", "event_type", event.Type, "error", err)
+		return
+	}
+	for _, endpoint := range targets {
+		d.wg.Add(1)
+		go func(endpoint Endpoint) {
+			defer d.wg.Done()
+			d.deliver(endpoint, event, body)
+		}(endpoint)
+	}
+}
+
+// deliver POSTs body to endpoint until it is accepted, refused, or the
+// attempts run out
+func (d *Dispatcher) deliver(endpoint Endpoint, event Event, body []byte) {
+	delay := d.retryDelay
+	for attempt := 1; ; attempt++ {
+		d.slots <- struct{}{}
+		retry, err := d.post(endpoint, event, body)
+		<-d.slots
+		if err == nil {
+			return
+		}
+		if !retry || attempt == d.maxAttempts {
+			logger.Warn("Gave up on webhook delivery", "url", endpoint.URL, "event_id", event.ID, "event_type", event.Type, "attempts", attempt, "error", err)
+			return
+		}
+		logger.Debug("Retrying webhook delivery", "url", endpoint.URL, "event_id", event.ID, "attempt", attempt, "error", err)
+
+		select {
+		case <-time.After(delay):
+		case <-d.stop:
+			return
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth
+// retrying: network errors, timeouts, rate limits and server errors are
+func (d *Dispatcher) post(endpoint Endpoint, event Event, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	timestamp := strconv.FormatInt(d.clock.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wikillm-webhooks")
+	req.Header.Set(HeaderEvent, string(event.Type))
+	req.Header.Set(HeaderDelivery, event.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to reach %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	default:
+		return false, fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+}
+
+// RecordAudit publishes the audited actions webhooks subscribe to: tasks
+// completed and events scheduled. It suits audit.LogConfig.OnRecord.
+func (d *Dispatcher) RecordAudit(ctx context.Context, event audit.Event) {
+	var eventType EventType
+	var subjectKey string
+	switch event.Type {
+	case audit.TaskCompleted:
+		eventType, subjectKey = TaskCompleted, "task_id"
+	case audit.EventScheduled:
+		eventType, subjectKey = EventCreated, "event_id"
+	default:
+		return
+	}
+	data := make(map[string]interface{}, len(event.Payload)+1)
+	for key, value := range event.Payload {
+		data[key] = value
+	}
+	data[subjectKey] = event.Subject
+	d.Publish(ctx, Event{
+		Type:      eventType,
+		UserID:    multiagent.UserIDFromContext(ctx),
+		Timestamp: event.Timestamp,
+		Data:      data,
+	})
+}
+
+// Reminders wraps the notifier reminders are delivered through, publishing
+// each reminder before it is delivered
+func (d *Dispatcher) Reminders(next notify.Notifier) notify.Notifier {
+	return &reminderNotifier{next: next, dispatcher: d}
+}
+
+type reminderNotifier struct {
+	next       notify.Notifier
+	dispatcher *Dispatcher
+}
+
+func (r *reminderNotifier) Notify(ctx context.Context, n notify.Notification) error {
+	r.dispatcher.Publish(ctx, Event{
+		Type:      ReminderTriggered,
+		UserID:    n.UserID,
+		Timestamp: n.At,
+		Data: map[string]interface{}{
+			"kind":     n.Kind,
+			"title":    n.Title,
+			"body":     n.Body,
+			"priority": n.Priority,
+			"subject":  n.Subject,
+		},
+	})
+	return r.next.Notify(ctx, n)
+}
@@ -0,0 +1,244 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kbutz/wikillm/multiagent"
+	"github.com/kbutz/wikillm/multiagent/audit"
+	"github.com/kbutz/wikillm/multiagent/ids"
+	"github.com/kbutz/wikillm/multiagent/notify"
+)
+
+// receiver is an endpoint answering with statuses in turn, then 200
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+func (r *receiver) received() ([]*http.Request, [][]byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*http.Request(nil), r.requests...), append([][]byte(nil), r.bodies...)
+}
+
+var testNow = time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+func newTestDispatcher(t *testing.T, endpoints ...Endpoint) *Dispatcher {
+	t.Helper()
+	d := NewDispatcher(DispatcherConfig{
+		Endpoints:   endpoints,
+		MaxAttempts: 3,
+		RetryDelay:  time.Millisecond,
+		Clock:       ids.NewManualClock(testNow),
+		IDs:         ids.NewSequence(),
+	})
+	return d
+}
+
+// drain waits for the dispatcher's deliveries to finish
+func drain(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := d.Wait(ctx); err != nil {
+		t.Fatalf("deliveries did not finish: %v", err)
+	}
+}
+
+func TestDeliverySignedAndFiltered(t *testing.T) {
+	all, reminders := &receiver{}, &receiver{}
+	allServer, remindersServer := httptest.NewServer(all), httptest.NewServer(reminders)
+	defer allServer.Close()
+	defer remindersServer.Close()
+
+	d := newTestDispatcher(t,
+		Endpoint{URL: allServer.URL, Secret: "s3cret"},
+		Endpoint{URL: remindersServer.URL, Secret: "other", Events: []EventType{ReminderTriggered}, Users: []string{"alice"}},
+	)
+	ctx := context.Background()
+	d.Publish(ctx, Event{Type: TaskCompleted, UserID: "alice", Data: map[string]interface{}{"task_id": "task_1"}})
+	d.Publish(ctx, Event{Type: ReminderTriggered, UserID: "bob"})
+	d.Publish(ctx, Event{Type: ReminderTriggered, UserID: "alice"})
+	drain(t, d)
+
+	requests, bodies := all.received()
+	if len(requests) != 3 {
+		t.Fatalf("catch-all endpoint got %d requests, want 3", len(requests))
+	}
+	if got, _ := reminders.received(); len(got) != 1 {
+		t.Fatalf("filtered endpoint got %d requests, want alice's reminder", len(got))
+	}
+
+	for i, req := range requests {
+		var event Event
+		if err := json.Unmarshal(bodies[i], &event); err != nil {
+			t.Fatalf("invalid body %s: %v", bodies[i], err)
+		}
+		if req.Header.Get(HeaderEvent) != string(event.Type) || req.Header.Get(HeaderDelivery) != event.ID || event.ID == "" {
+			t.Errorf("headers %v do not match event %+v", req.Header, event)
+		}
+		if !event.Timestamp.Equal(testNow) {
+			t.Errorf("event timestamp = %v, want %v", event.Timestamp, testNow)
+		}
+		timestamp := req.Header.Get(HeaderTimestamp)
+		if got, want := req.Header.Get(HeaderSignature), Sign("s3cret", timestamp, bodies[i]); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestDeliveryRetries(t *testing.T) {
+	flaky := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	refusing := &receiver{statuses: []int{http.StatusBadRequest}}
+	down := &receiver{statuses: []int{500, 500, 500, 500}}
+	var endpoints []Endpoint
+	for _, r := range []*receiver{flaky, refusing, down} {
+		server := httptest.NewServer(r)
+		defer server.Close()
+		endpoints = append(endpoints, Endpoint{URL: server.URL, Secret: "s3cret"})
+	}
+
+	d := newTestDispatcher(t, endpoints...)
+	d.Publish(context.Background(), Event{Type: EventCreated, UserID: "alice"})
+	drain(t, d)
+
+	requests, _ := flaky.received()
+	if len(requests) != 3 {
+		t.Fatalf("flaky endpoint got %d attempts, want 3", len(requests))
+	}
+	if requests[0].Header.Get(HeaderDelivery) != requests[2].Header.Get(HeaderDelivery) {
+		t.Error("retries changed the delivery ID")
+	}
+	if got, _ := refusing.received(); len(got) != 1 {
+		t.Errorf("refused delivery was tried %d times, want once", len(got))
+	}
+	if got, _ := down.received(); len(got) != 3 {
+		t.Errorf("failing endpoint got %d attempts, want MaxAttempts", len(got))
+	}
+}
+
+func TestStopAbandonsRetries(t *testing.T) {
+	down := &receiver{statuses: []int{500, 500, 500}}
+	server := httptest.NewServer(down)
+	defer server.Close()
+
+	d := NewDispatcher(DispatcherConfig{Endpoints: []Endpoint{{URL: server.URL, Secret: "s3cret"}}, RetryDelay: time.Hour})
+	d.Publish(context.Background(), Event{Type: EventCreated})
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if got, _ := down.received(); len(got) > 0 {
+			break
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := d.Stop(ctx); err != nil {
+		t.Fatalf("Stop waited for the retry: %v", err)
+	}
+	d.Publish(context.Background(), Event{Type: EventCreated})
+	if got, _ := down.received(); len(got) != 1 {
+		t.Errorf("got %d attempts, want the first only", len(got))
+	}
+}
+
+func TestAuditAndReminders(t *testing.T) {
+	r := &receiver{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	d := newTestDispatcher(t, Endpoint{URL: server.URL, Secret: "s3cret"})
+
+	ctx := multiagent.WithUserID(context.Background(), "alice")
+	d.RecordAudit(ctx, audit.Event{Type: audit.TaskUpdated, Subject: "task_1"})
+	d.RecordAudit(ctx, audit.Event{Type: audit.TaskCompleted, Subject: "task_1", Timestamp: testNow, Payload: map[string]interface{}{"title": "File taxes"}})
+	d.RecordAudit(ctx, audit.Event{Type: audit.EventScheduled, Subject: "event_1", Timestamp: testNow})
+
+	console := &recordingNotifier{}
+	notifier := d.Reminders(console)
+	if err := notifier.Notify(ctx, notify.Notification{UserID: "alice", Kind: notify.KindTaskReminder, Title: "File taxes", At: testNow}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(console.sent) != 1 {
+		t.Error("reminder was not passed on to the notifier")
+	}
+	drain(t, d)
+
+	_, bodies := r.received()
+	got := make(map[EventType]Event)
+	for _, body := range bodies {
+		var event Event
+		json.Unmarshal(body, &event)
+		got[event.Type] = event
+	}
+	if len(bodies) != 3 || len(got) != 3 {
+		t.Fatalf("want one event of each type, got %s", bodies)
+	}
+	if completed := got[TaskCompleted]; completed.UserID != "alice" || completed.Data["task_id"] != "task_1" || completed.Data["title"] != "File taxes" {
+		t.Errorf("unexpected task.completed %+v", completed)
+	}
+	if created := got[EventCreated]; created.Data["event_id"] != "event_1" {
+		t.Errorf("unexpected event.created %+v", created)
+	}
+	if reminder := got[ReminderTriggered]; reminder.UserID != "alice" || reminder.Data["kind"] != notify.KindTaskReminder || reminder.Data["title"] != "File taxes" {
+		t.Errorf("unexpected reminder.triggered %+v", reminder)
+	}
+}
+
+type recordingNotifier struct{ sent []notify.Notification }
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification notify.Notification) error {
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOOK_SECRET", "s3cret")
+	load := func(content string) ([]Endpoint, error) {
+		path := filepath.Join(dir, "webhooks.json")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return LoadConfig(path)
+	}
+
+	endpoints, err := load(`{"endpoints": [{"url": "https://ha.example.com/hook", "secret": "$HOOK_SECRET", "events": ["task.completed"]}]}`)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(endpoints) != 1 || endpoints[0].Secret != "s3cret" || endpoints[0].Events[0] != TaskCompleted {
+		t.Errorf("unexpected endpoints %+v", endpoints)
+	}
+
+	for _, invalid := range []string{
+		`{"endpoints": [{"url": "https://ha.example.com/hook"}]}`,
+		`{"endpoints": [{"url": "ftp://ha.example.com", "secret": "s"}]}`,
+		`{"endpoints": [{"url": "https://ha.example.com/hook", "secret": "s", "events": ["task.deleted"]}]}`,
+	} {
+		if _, err := load(invalid); err == nil {
+			t.Errorf("LoadConfig accepted %s", invalid)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package rag
+
+import (
+	"context"
+	"flag"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// BatchOptions configures how Batch spreads questions over the LLM
+type BatchOptions struct {
+	Workers int     // Questions answered at once
+	Rate    float64 // Most questions started per second, unlimited if 0
+}
+
+// Register adds the -workers and -rate flags to fs
+func (o *BatchOptions) Register(fs *flag.FlagSet) {
+	fs.IntVar(&o.Workers, "workers", 4, "Number of questions to answer at once")
+	fs.Float64Var(&o.Rate, "rate", 0, "Most questions to start per second, to spare the LLM (0 for no limit)")
+}
+
+// BatchResult is the answer to one question of a batch
+type BatchResult struct {
+	Index     int      `json:"index"` // Position of the question in the batch
+	Query     string   `json:"query"`
+	Answer    string   `json:"answer,omitempty"`
+	Sources   []string `json:"sources,omitempty"`
+	LatencyMS int64    `json:"latency_ms"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// BatchSummary describes a finished batch
+type BatchSummary struct {
+	Answered      int
+	Failed        int
+	Elapsed       time.Duration
+	MedianLatency time.Duration
+	MaxLatency    time.Duration
+}
+
+// Batch answers questions concurrently, at most options.Workers at once, and
+// passes each result to emit in the order of the questions. A question that
+// fails is reported in its result's Error; Batch itself only fails if the
+// pipeline cannot be opened, ctx is done, or emit returns an error.
+func Batch(ctx context.Context, config Config, options BatchOptions, questions []string, emit func(BatchResult) error) (*BatchSummary, error) {
+	model, ragPipeline, err := open(config)
+	if err != nil {
+		return nil, err
+	}
+	defer ragPipeline.Close()
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	start := time.Now()
+
+	// Hand out questions, no faster than the rate allows
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		var tick <-chan time.Time
+		if options.Rate > 0 {
+			ticker := time.NewTicker(time.Duration(float64(time.Second) / options.Rate))
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for i := range questions {
+			if tick != nil && i > 0 {
+				select {
+				case <-tick:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make(chan BatchResult)
+	var wg sync.WaitGroup
+	for range max(options.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results <- answerBatchQuestion(ctx, model, ragPipeline, i, questions[i], config.SearchLimit)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Results arrive as they finish; hold them back until those before are out
+	summary := &BatchSummary{}
+	var latencies []time.Duration
+	var emitErr error
+	pending := make(map[int]BatchResult)
+	next := 0
+	for result := range results {
+		pending[result.Index] = result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			if result.Error != "" {
+				summary.Failed++
+			} else {
+				summary.Answered++
+			}
+			latencies = append(latencies, time.Duration(result.LatencyMS)*time.Millisecond)
+			if emitErr == nil {
+				if emitErr = emit(result); emitErr != nil {
+					cancel()
+				}
+			}
+		}
+	}
+
+	summary.Elapsed = time.Since(start)
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		summary.MedianLatency = latencies[len(latencies)/2]
+		summary.MaxLatency = latencies[len(latencies)-1]
+	}
+	if emitErr != nil {
+		return summary, emitErr
+	}
+	return summary, parent.Err()
+}
+
+// answerBatchQuestion answers question i of a batch and times it
+func answerBatchQuestion(ctx context.Context, model llms.Model, ragPipeline *RAGPipeline, i int, question string, limit int) BatchResult {
+	result := BatchResult{Index: i, Query: question}
+	start := time.Now()
+	answer, err := processQuery(ctx, model, ragPipeline, question, limit)
+	result.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Answer = answer.Answer
+	result.Sources = answer.Sources
+	return result
+}
@@ -1,4 +1,4 @@
-package main
+package rag
 
 import (
 	"bufio"
@@ -57,9 +57,9 @@ func NewDefaultLoaderConfig() LoaderConfig {
 	}
 }
 
-// Load loads the embeddings from the wiki_minilm.ndjson.gz file into Qdrant
+// LoadEmbeddings loads the embeddings from the wiki_minilm.ndjson.gz file into Qdrant
 // If a Config is provided, it will use the configuration from it
-func loadFromEmbeddings() {
+func LoadEmbeddings() {
 	loadWithConfig(NewDefaultLoaderConfig())
 }
 
@@ -1,4 +1,6 @@
-package main
+// Package rag answers questions about Wikipedia with Retrieval-Augmented
+// Generation over a Qdrant vector database. It is run by `wikillm rag`.
+package rag
 
 import (
 	"bufio"
@@ -19,63 +21,81 @@ type Config struct {
 	ModelProvider        string // Provider to use (lmstudio, ollama, openai)
 	EmbeddingModel       string // Name of the embedding model to use
 	EmbeddingProvider    string // Provider for embeddings (ollama, openai)
-	WikipediaPath        string // Path to the Wikipedia dump file
 	QdrantURL            string // URL for the Qdrant vector database
 	QdrantCollectionName string // Collection name for the Qdrant vector database
 	SearchLimit          int    // Maximum number of search results to return
 	OpenAIAPIKey         string // OpenAI API key for LM Studio compatibility
 	OllamaURL            string // Ollama server URL
 	ForceRecreate        bool   // Force recreate collection if dimensions mismatch
-	Load                 bool   // Load embeddings from file
 }
 
-// parseFlags parses command line flags and returns a Config struct
-func parseFlags() Config {
-	modelName := flag.String("model", "llama3.2", "Name of the LLM model to use")
-	modelProvider := flag.String("provider", "ollama", "Model provider to use (ollama, openai, lmstudio)")
+// Register adds the model, embedding and Qdrant flags to fs
+func (c *Config) Register(fs *flag.FlagSet) {
+	fs.StringVar(&c.ModelName, "model", "llama3.2", "Name of the LLM model to use")
+	fs.StringVar(&c.ModelProvider, "provider", "ollama", "Model provider to use (ollama, openai, lmstudio)")
 	// Previously nomic-embed-text, trying all-minilm
-	embeddingModel := flag.String("embedding-model", "all-minilm", "Name of the embedding model to use")
-	embeddingProvider := flag.String("embedding-provider", "", "Provider for embeddings (defaults to model provider)")
-	wikipediaPath := flag.String("wikipedia", "", "Path to the Wikipedia dump file")
-	qdrantURL := flag.String("qdrant-url", "http://localhost:6333", "URL for the Qdrant vector database")
+	fs.StringVar(&c.EmbeddingModel, "embedding-model", "all-minilm", "Name of the embedding model to use")
+	fs.StringVar(&c.EmbeddingProvider, "embedding-provider", "", "Provider for embeddings (defaults to model provider)")
+	fs.StringVar(&c.QdrantURL, "qdrant-url", "http://localhost:6333", "URL for the Qdrant vector database")
 	// value from load() is wiki_minilm, value from the original langchain embedder was wikipedia
-	qdrantCollection := flag.String("qdrant-collection", "wiki_minilm", "Collection name for Qdrant")
-	searchLimit := flag.Int("limit", 5, "Maximum number of search results")
-	openaiKey := flag.String("openai-key", "", "OpenAI API key (or set OPENAI_API_KEY env var)")
-	ollamaURL := flag.String("ollama-url", "http://localhost:11434", "Ollama server URL")
-	forceRecreate := flag.Bool("force-recreate", false, "Force recreate collection if dimensions mismatch")
-	load := flag.Bool("load", false, "Test loading the wiki_minilm.ndjson.gz file and exit")
-
-	flag.Parse()
-
-	// Get API key from environment if not provided
-	apiKey := *openaiKey
-	if apiKey == "" {
-		apiKey = os.Getenv("OPENAI_API_KEY")
+	fs.StringVar(&c.QdrantCollectionName, "qdrant-collection", "wiki_minilm", "Collection name for Qdrant")
+	fs.IntVar(&c.SearchLimit, "limit", 5, "Maximum number of search results")
+	fs.StringVar(&c.OpenAIAPIKey, "openai-key", os.Getenv("OPENAI_API_KEY"), "OpenAI API key (default $OPENAI_API_KEY)")
+	fs.StringVar(&c.OllamaURL, "ollama-url", "http://localhost:11434", "Ollama server URL")
+	fs.BoolVar(&c.ForceRecreate, "force-recreate", false, "Force recreate collection if dimensions mismatch")
+}
+
+// Index embeds the pages of a Wikipedia XML dump into the Qdrant collection.
+// This has to create the embeddings first and is extremely compute intensive.
+func Index(config Config, dumpPath string) error {
+	ragPipeline, err := NewRAGPipeline(config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize RAG pipeline: %w", err)
 	}
+	defer ragPipeline.Close()
 
-	config := Config{
-		ModelName:            *modelName,
-		ModelProvider:        *modelProvider,
-		EmbeddingModel:       *embeddingModel,
-		EmbeddingProvider:    *embeddingProvider,
-		WikipediaPath:        *wikipediaPath,
-		QdrantURL:            *qdrantURL,
-		QdrantCollectionName: *qdrantCollection,
-		SearchLimit:          *searchLimit,
-		OpenAIAPIKey:         apiKey,
-		OllamaURL:            *ollamaURL,
-		ForceRecreate:        *forceRecreate,
-		Load:                 *load,
+	log.Printf("Indexing Wikipedia dump: %s", dumpPath)
+	if err := ragPipeline.IndexWikipediaDump(dumpPath); err != nil {
+		return fmt.Errorf("failed to index Wikipedia: %w", err)
 	}
+	log.Println("✅ Indexing complete")
+	return nil
+}
 
-	return config
+// Answer is the reply to a question and the titles of the pages it drew on
+type Answer struct {
+	Answer  string   `json:"answer"`
+	Sources []string `json:"sources"`
+}
+
+// Ask answers a single question
+func Ask(ctx context.Context, config Config, question string) (*Answer, error) {
+	model, ragPipeline, err := open(config)
+	if err != nil {
+		return nil, err
+	}
+	defer ragPipeline.Close()
+	return processQuery(ctx, model, ragPipeline, question, config.SearchLimit)
 }
 
-func main() {
-	// Parse configuration
-	config := parseFlags()
+// Chat answers questions read from stdin until the user types exit
+func Chat(config Config) error {
+	model, ragPipeline, err := open(config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := ragPipeline.Close(); err != nil {
+			log.Printf("Closing RAG pipeline: %v", err)
+		}
+	}()
+
+	startInteractiveSession(model, ragPipeline, config)
+	return nil
+}
 
+// open creates the model and RAG pipeline config describes
+func open(config Config) (llms.Model, *RAGPipeline, error) {
 	// Get provider and create model
 	provider := GetProvider(config)
 
@@ -83,42 +103,16 @@ func main() {
 	log.Printf("Using embedding model: %s (%s)", config.EmbeddingModel, config.EmbeddingProvider)
 	model, err := provider.CreateLLM(config)
 	if err != nil {
-		log.Fatalf("Failed to initialize model: %v", err)
+		return nil, nil, fmt.Errorf("failed to initialize model: %w", err)
 	}
 
 	// Initialize RAG pipeline
 	log.Println("Initializing RAG pipeline...")
 	ragPipeline, err := NewRAGPipeline(config)
 	if err != nil {
-		log.Fatalf("Failed to initialize RAG pipeline: %v", err)
-	}
-	defer func() {
-		err = ragPipeline.Close()
-		if err != nil {
-			log.Printf("Closing RAG pipeline: %v", err)
-		}
-	}()
-
-	// Index from Wikipedia XML Dump if a path is provided
-	// This will have to create the embeddings first and is extremely compute intensive
-	if config.WikipediaPath != "" {
-		log.Printf("Indexing Wikipedia dump: %s", config.WikipediaPath)
-		if err := ragPipeline.IndexWikipediaDump(config.WikipediaPath); err != nil {
-			log.Fatalf("Failed to index Wikipedia: %v", err)
-		}
-		log.Println("✅ Indexing complete")
+		return nil, nil, fmt.Errorf("failed to initialize RAG pipeline: %w", err)
 	}
-
-	// Load embeddings directly from file if specified
-	if config.Load {
-		// Load embeddings from file
-		log.Println("Loading embeddings from file...")
-		loadFromEmbeddings()
-		log.Println("✅ Loading complete")
-	}
-
-	// Start an interactive session
-	startInteractiveSession(model, ragPipeline, config)
+	return model, ragPipeline, nil
 }
 
 // startInteractiveSession provides an interactive chat interface
@@ -167,22 +161,27 @@ func startInteractiveSession(model llms.Model, ragPipeline *RAGPipeline, config
 		}
 
 		elapsed := time.Since(startTime)
-		fmt.Printf("\n📝 Response (%.2fs):\n%s\n", elapsed.Seconds(), response)
+		fmt.Printf("\n📝 Response (%.2fs):\n%s\n", elapsed.Seconds(), response.Answer)
 	}
 }
 
 // ProcessQuery handles a user query with improved context formatting
-func processQuery(ctx context.Context, model llms.Model, ragPipeline *RAGPipeline, query string, limit int) (string, error) {
+func processQuery(ctx context.Context, model llms.Model, ragPipeline *RAGPipeline, query string, limit int) (*Answer, error) {
 	// Search for relevant documents
 	docs, err := ragPipeline.Search(ctx, query, limit)
 	if err != nil {
-		return "", fmt.Errorf("search error: %w", err)
+		return nil, fmt.Errorf("search error: %w", err)
 	}
 
+	answer := &Answer{Sources: []string{}}
 	if len(docs) == 0 {
 		log.Println("Debug: No results found from vector store, querying model directly...")
 		// If no results found, ask the model directly
-		return llms.GenerateFromSinglePrompt(ctx, model, query)
+		answer.Answer, err = llms.GenerateFromSinglePrompt(ctx, model, query)
+		if err != nil {
+			return nil, err
+		}
+		return answer, nil
 	}
 
 	// Build context from search results
@@ -194,6 +193,7 @@ func processQuery(ctx context.Context, model llms.Model, ragPipeline *RAGPipelin
 	for i, doc := range docs {
 		title, _ := doc.Metadata["title"].(string)
 		content := doc.PageContent
+		answer.Sources = append(answer.Sources, title)
 
 		// Truncate content if too long
 		if len(content) > 800 {
@@ -207,8 +207,12 @@ func processQuery(ctx context.Context, model llms.Model, ragPipeline *RAGPipelin
 	contextBuilder.WriteString("Please provide a comprehensive answer based on the context above. If the context doesn't contain enough information, mention that.")
 
 	// Generate response using the new API
-	return llms.GenerateFromSinglePrompt(ctx, model, contextBuilder.String(),
+	answer.Answer, err = llms.GenerateFromSinglePrompt(ctx, model, contextBuilder.String(),
 		llms.WithTemperature(0.7),
 		llms.WithMaxTokens(1000),
 	)
+	if err != nil {
+		return nil, err
+	}
+	return answer, nil
 }
@@ -1,4 +1,7 @@
-package main
+// Package tool is a to-do list agent with priorities, time estimates and
+// task analysis, used from the terminal or over HTTP. It is run by
+// `wikillm todo serve`.
+package tool
 
 import (
 	"bufio"
@@ -18,64 +21,56 @@ import (
 type Config struct {
 	ModelName     string // Name of the LLM model to use
 	ModelProvider string // Provider to use (lmstudio or ollama)
-	Port          int    // HTTP server port
 	TodoFilePath  string // Path to the to-do list file
 }
 
-func main() {
-	// Parse command line arguments
-	config := parseFlags()
+// Register adds the model and to-do list flags to fs
+func (c *Config) Register(fs *flag.FlagSet) {
+	fs.StringVar(&c.ModelName, "model", "default", "Name of the LLM model to use")
+	fs.StringVar(&c.ModelProvider, "provider", "lmstudio", "Model provider to use (lmstudio or ollama)")
+	fs.StringVar(&c.TodoFilePath, "todo-file", "todo.txt", "Path to the to-do list file")
+}
 
-	// Initialize the LLM model
+// NewTodoAgent creates an agent with direct access to the to-do list
+func NewTodoAgent(config Config) (*Agent, error) {
+	// Initialize the LLM model based on the selected provider
 	var model LLMModel
 	var err error
-
-	// Initialize the model based on the selected provider
 	switch strings.ToLower(config.ModelProvider) {
 	case "ollama":
 		model, err = NewOllamaModel(config.ModelName)
 		if err != nil {
-			log.Fatalf("Failed to initialize Ollama model: %v", err)
+			return nil, fmt.Errorf("failed to initialize Ollama model: %w", err)
 		}
 	default:
 		log.Printf("Using LM Studio as the model provider.")
 		model, err = NewLMStudioModel(config.ModelName)
 		if err != nil {
-			log.Fatalf("Failed to initialize LM Studio model: %v", err)
+			return nil, fmt.Errorf("failed to initialize LM Studio model: %w", err)
 		}
 	}
 
-	// Initialize the improved agent with direct TODO service access
-	// Create the todo tool
 	todoTool := tools.NewImprovedTodoListTool(config.TodoFilePath)
-
-	// Create base agent
-	agent := NewAgent(model, []Tool{todoTool})
-
-	// Start HTTP server if port is specified
-	if config.Port > 0 {
-		go startHTTPServer(config.Port, agent)
-	}
-
-	// Start the interactive session
-	startInteractiveSession(agent)
+	return NewAgent(model, []Tool{todoTool}), nil
 }
 
-// Parse command line flags
-func parseFlags() Config {
-	modelName := flag.String("model", "default", "Name of the LLM model to use")
-	modelProvider := flag.String("provider", "lmstudio", "Model provider to use (lmstudio or ollama)")
-	port := flag.Int("port", 0, "HTTP server port (0 to disable)")
-	todoFilePath := flag.String("todo-file", "todo.txt", "Path to the to-do list file")
-
-	flag.Parse()
-
-	return Config{
-		ModelName:     *modelName,
-		ModelProvider: *modelProvider,
-		Port:          *port,
-		TodoFilePath:  *todoFilePath,
+// Serve answers POST /query requests on port, and questions read from stdin
+// when interactive, until the server fails or the user types exit
+func Serve(config Config, port int, interactive bool) error {
+	agent, err := NewTodoAgent(config)
+	if err != nil {
+		return err
 	}
+	if !interactive {
+		return startHTTPServer(port, agent)
+	}
+	go func() {
+		if err := startHTTPServer(port, agent); err != nil {
+			log.Printf("HTTP server stopped: %v", err)
+		}
+	}()
+	startInteractiveSession(agent)
+	return nil
 }
 
 // Start an interactive session with the user
@@ -114,8 +109,9 @@ func startInteractiveSession(agent *Agent) {
 }
 
 // Start HTTP server
-func startHTTPServer(port int, agent *Agent) {
-	http.HandleFunc("/query", func(w http.ResponseWriter, r *http.Request) {
+func startHTTPServer(port int, agent *Agent) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/query", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -142,7 +138,8 @@ func startHTTPServer(port int, agent *Agent) {
 	})
 
 	log.Printf("Starting HTTP server on port %d", port)
-	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil); err != nil {
-		log.Fatalf("Failed to start HTTP server: %v", err)
+	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), mux); err != nil {
+		return fmt.Errorf("failed to start HTTP server: %w", err)
 	}
+	return nil
 }
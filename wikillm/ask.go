@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// maxQuestionSize bounds a question read from stdin
+const maxQuestionSize = 1 << 20
+
+// askResult is what an ask command prints, as JSON by default
+type askResult struct {
+	Query          string   `json:"query"`
+	Answer         string   `json:"answer,omitempty"`
+	Sources        []string `json:"sources,omitempty"`
+	ConversationID string   `json:"conversation_id,omitempty"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// newAskCommand answers one question for scripts: from --query, the
+// arguments, or stdin, e.g. `echo "question" | wikillm rag ask`. It prints
+// the answer as JSON, or just its text with --output text, and exits with
+// status 1 if the question could not be answered.
+func newAskCommand(short string, ask func(ctx context.Context, question string) (askResult, error)) *cobra.Command {
+	var query, output string
+	cmd := &cobra.Command{
+		Use:   "ask [question]",
+		Short: short,
+		Long:  short + ". The question is taken from --query, the arguments, or stdin, and the answer printed as JSON (or text with --output text); the exit status is 1 if it could not be answered.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "json" && output != "text" {
+				return fmt.Errorf("--output must be json or text, got %q", output)
+			}
+			question, err := readQuestion(cmd, query, args)
+			if err != nil {
+				return err
+			}
+
+			result, err := ask(cmd.Context(), question)
+			result.Query = question
+			if err != nil {
+				result.Error = err.Error()
+			}
+			out := cmd.OutOrStdout()
+			if output == "text" {
+				if err == nil {
+					fmt.Fprintln(out, result.Answer)
+				}
+			} else {
+				encoder := json.NewEncoder(out)
+				encoder.SetEscapeHTML(false)
+				if err := encoder.Encode(result); err != nil {
+					return err
+				}
+			}
+			return err
+		},
+		ValidArgsFunction: cobra.NoFileCompletions,
+	}
+	cmd.Flags().StringVarP(&query, "query", "q", "", "the question (default the arguments, or stdin)")
+	cmd.Flags().StringVarP(&output, "output", "o", "json", "output format: json or text")
+	cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+// readQuestion returns the question from query, args or stdin, in that order
+func readQuestion(cmd *cobra.Command, query string, args []string) (string, error) {
+	question := strings.TrimSpace(query)
+	if question == "" {
+		question = strings.TrimSpace(strings.Join(args, " "))
+	}
+	if question != "" {
+		return question, nil
+	}
+
+	in := cmd.InOrStdin()
+	if file, ok := in.(*os.File); ok {
+		if info, err := file.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			return "", errors.New("no question: pass --query or an argument, or pipe one on stdin")
+		}
+	}
+	data, err := io.ReadAll(io.LimitReader(in, maxQuestionSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read question: %w", err)
+	}
+	if question = strings.TrimSpace(string(data)); question == "" {
+		return "", errors.New("no question: stdin was empty")
+	}
+	return question, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kbutz/wikillm/multiagent/assistant"
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/kbutz/wikillm/multiagent/search"
+	"github.com/kbutz/wikillm/rag"
+	"github.com/spf13/cobra"
+)
+
+// newAssistantCommand runs the multiagent personal assistant
+func newAssistantCommand(logFlags *logging.Flags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "assistant",
+		Short: "Run the multi-agent personal assistant",
+	}
+
+	var serveOptions assistant.ServerOptions
+	var ragConfig rag.Config
+	var wikipediaRAG bool
+	serve := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the assistant's REST API and chat frontends",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if wikipediaRAG {
+				pipeline, err := rag.NewRAGPipeline(ragConfig)
+				if err != nil {
+					return fmt.Errorf("failed to open the Wikipedia index: %w", err)
+				}
+				defer pipeline.Close()
+				serveOptions.Tools = append(serveOptions.Tools, search.NewTool(search.WikipediaToolName, "Search Wikipedia articles", ragSearcher{pipeline}))
+			}
+			return assistant.Serve(cmd.Context(), serveOptions)
+		},
+	}
+	addGoFlags(serve.Flags(), serveOptions.Register)
+	serve.Flags().BoolVar(&wikipediaRAG, "wikipedia-rag", false, "research and fact-check from the Wikipedia embeddings indexed in Qdrant by \"wikillm rag\", in place of -wikipedia-url")
+	addRAGSearchFlags(serve.Flags(), &ragConfig)
+
+	var chatOptions assistant.ChatOptions
+	chat := &cobra.Command{
+		Use:   "chat",
+		Short: "Talk to the assistant in the terminal",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logConfig, err := logFlags.Config()
+			if err != nil {
+				return fmt.Errorf("invalid logging flags: %w", err)
+			}
+			chatOptions.Logging = logConfig
+			if err := assistant.Chat(cmd.Context(), chatOptions); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "👋 Goodbye! Thanks for using the Personal Assistant!")
+			return nil
+		},
+	}
+	addGoFlags(chat.Flags(), chatOptions.Register)
+
+	var askOptions assistant.AskOptions
+	ask := newAskCommand("Send the assistant a single message", func(ctx context.Context, message string) (askResult, error) {
+		reply, err := assistant.Ask(ctx, askOptions, message)
+		if err != nil {
+			return askResult{ConversationID: askOptions.UserID}, err
+		}
+		return askResult{Answer: reply.Response, ConversationID: reply.ConversationID}, nil
+	})
+	addGoFlags(ask.Flags(), askOptions.Register)
+
+	var mcpOptions assistant.MCPOptions
+	mcpCmd := &cobra.Command{
+		Use:   "mcp",
+		Short: "Serve the assistant's tools to MCP clients over stdio or HTTP",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return assistant.ServeMCP(cmd.Context(), mcpOptions)
+		},
+	}
+	addGoFlags(mcpCmd.Flags(), mcpOptions.Register)
+
+	var auditOptions assistant.AuditOptions
+	audit := &cobra.Command{
+		Use:   "audit",
+		Short: "Print what the agents did, from the audit log",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return assistant.Audit(cmd.Context(), auditOptions, cmd.OutOrStdout())
+		},
+	}
+	addGoFlags(audit.Flags(), auditOptions.Register)
+
+	var usageOptions assistant.UsageOptions
+	usage := &cobra.Command{
+		Use:   "usage",
+		Short: "Print the LLM tokens the agents spent",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return assistant.Usage(cmd.Context(), usageOptions, cmd.OutOrStdout())
+		},
+	}
+	addGoFlags(usage.Flags(), usageOptions.Register)
+
+	var ganttOptions assistant.GanttOptions
+	gantt := &cobra.Command{
+		Use:   "gantt",
+		Short: "Print a project's tasks and milestones as a Mermaid gantt diagram or CSV",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return assistant.Gantt(cmd.Context(), ganttOptions, cmd.OutOrStdout())
+		},
+	}
+	addGoFlags(gantt.Flags(), ganttOptions.Register)
+
+	var promptsOptions assistant.PromptsOptions
+	promptsCmd := &cobra.Command{
+		Use:   "prompts",
+		Short: "List, show or export the prompts agents render",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return assistant.Prompts(promptsOptions, cmd.OutOrStdout())
+		},
+	}
+	addGoFlags(promptsCmd.Flags(), promptsOptions.Register)
+
+	var migrateOptions assistant.MigrateOptions
+	migrate := &cobra.Command{
+		Use:   "migrate-memory",
+		Short: "Copy a file memory store into a SQLite one",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return assistant.MigrateMemory(cmd.Context(), migrateOptions, cmd.OutOrStdout())
+		},
+	}
+	addGoFlags(migrate.Flags(), migrateOptions.Register)
+
+	cmd.AddCommand(serve, chat, ask, mcpCmd, audit, usage, gantt, promptsCmd, migrate)
+	return cmd
+}
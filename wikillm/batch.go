@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kbutz/wikillm/rag"
+	"github.com/spf13/cobra"
+)
+
+// newRAGBatchCommand answers a file of questions, one per line, into JSON
+// lines of answers, sources and latencies, e.g. for building eval sets
+func newRAGBatchCommand(config *rag.Config) *cobra.Command {
+	var options rag.BatchOptions
+	var questionsPath, answersPath string
+	cmd := &cobra.Command{
+		Use:   "batch -f questions.txt [-o answers.jsonl]",
+		Short: "Answer a file of questions concurrently, writing JSON lines",
+		Long:  "Answer a file of questions, one per line (blank lines and lines starting with # are skipped), several at once. Each answer is written as a JSON line with its sources and latency, in the order of the questions; the exit status is 1 if any question failed.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var in io.Reader = cmd.InOrStdin()
+			if questionsPath != "-" {
+				file, err := os.Open(questionsPath)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				in = file
+			}
+			questions, err := readQuestions(in)
+			if err != nil {
+				return err
+			}
+			if len(questions) == 0 {
+				return errors.New("no questions in " + questionsPath)
+			}
+
+			var out io.Writer = cmd.OutOrStdout()
+			if answersPath != "-" {
+				file, err := os.Create(answersPath)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				out = file
+			}
+			writer := bufio.NewWriter(out)
+			encoder := json.NewEncoder(writer)
+			encoder.SetEscapeHTML(false)
+
+			summary, err := rag.Batch(cmd.Context(), *config, options, questions, func(result rag.BatchResult) error {
+				if result.Error != "" {
+					fmt.Fprintf(cmd.ErrOrStderr(), "question %d failed: %s\n", result.Index, result.Error)
+				}
+				if err := encoder.Encode(result); err != nil {
+					return err
+				}
+				// Keep the file useful if the batch is interrupted
+				return writer.Flush()
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Answered %d of %d questions in %s (median %s, slowest %s)\n",
+				summary.Answered, len(questions), summary.Elapsed.Round(time.Millisecond), summary.MedianLatency, summary.MaxLatency)
+			if summary.Failed > 0 {
+				return fmt.Errorf("%d of %d questions failed", summary.Failed, len(questions))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&questionsPath, "file", "f", "", "file of questions, one per line (- for stdin)")
+	cmd.Flags().StringVarP(&answersPath, "output", "o", "-", "file to write the answers to as JSON lines (- for stdout)")
+	cmd.MarkFlagRequired("file")
+	cmd.MarkFlagFilename("file", "txt")
+	cmd.MarkFlagFilename("output", "jsonl")
+	addGoFlags(cmd.Flags(), options.Register)
+	return cmd
+}
+
+// readQuestions returns the non-blank lines of r that are not # comments
+func readQuestions(r io.Reader) ([]string, error) {
+	var questions []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxQuestionSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		questions = append(questions, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read questions: %w", err)
+	}
+	return questions, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// fileConfig is the shared config file: flag defaults for every command
+type fileConfig map[string]interface{}
+
+// loadConfig reads flag defaults from a JSON file. Top-level values apply
+// to every command with a flag of that name; objects named after a command
+// apply to it and its subcommands, overriding those above them, e.g.
+//
+//	{"provider": "ollama", "model": "llama3.2",
+//	 "todo": {"todo-file": "$HOME/todo.json"},
+//	 "assistant": {"serve": {"addr": ":9090", "admin-token": "$WIKILLM_ADMIN_TOKEN"}}}
+//
+// Without a path, $WIKILLM_CONFIG or wikillm/config.json in the user config
+// directory is read if it exists.
+func loadConfig(path string) (fileConfig, error) {
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv("WIKILLM_CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(dir, "wikillm", "config.json")
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var config fileConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// apply sets the flags of cmd that were not given on the command line to
+// the values configured for it. String values may reference environment
+// variables.
+func (c fileConfig) apply(cmd *cobra.Command) error {
+	// Sections from the root down, so the most specific is applied last
+	var path []string
+	for parent := cmd; parent.HasParent(); parent = parent.Parent() {
+		path = append([]string{parent.Name()}, path...)
+	}
+	values := map[string]string{}
+	section := map[string]interface{}(c)
+	for i := 0; section != nil; i++ {
+		for key, value := range section {
+			switch value := value.(type) {
+			case map[string]interface{}:
+			case string:
+				values[key] = os.ExpandEnv(value)
+			default:
+				values[key] = fmt.Sprint(value)
+			}
+		}
+		if i == len(path) {
+			break
+		}
+		section, _ = section[path[i]].(map[string]interface{})
+	}
+
+	var errs []error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		value, ok := values[f.Name]
+		if !ok || f.Changed {
+			return
+		}
+		if err := f.Value.Set(value); err != nil {
+			errs = append(errs, fmt.Errorf("config %s: %w", f.Name, err))
+		}
+	})
+	return errors.Join(errs...)
+}
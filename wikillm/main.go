@@ -0,0 +1,75 @@
+// Command wikillm runs every part of wikillm from one binary:
+//
+//	wikillm rag index simplewiki.xml      # embed a dump into Qdrant
+//	wikillm rag chat                      # ask Wikipedia questions with RAG
+//	wikillm naivelocal chat               # ... or with a local full-text index
+//	wikillm todo add Call dentist priority:high
+//	wikillm todo chat                     # manage the list in natural language
+//	wikillm assistant serve --addr :8080  # the multi-agent personal assistant
+//	wikillm assistant audit --from ./wikillm_memory/memory
+//	wikillm completion bash               # shell completion script
+//
+// Flags can also be set in a shared JSON config file; see loadConfig.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kbutz/wikillm/multiagent/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// newRootCommand builds the command tree
+func newRootCommand() *cobra.Command {
+	var configPath string
+	var logFlags logging.Flags
+	root := &cobra.Command{
+		Use:   "wikillm",
+		Short: "Local LLM experiments: Wikipedia question answering, to-do agents and a personal assistant",
+		// Errors are printed once by Execute's caller, without usage
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadConfig(configPath)
+			if err != nil {
+				return err
+			}
+			if err := config.apply(cmd); err != nil {
+				return err
+			}
+			logConfig, err := logFlags.Config()
+			if err != nil {
+				return fmt.Errorf("invalid logging flags: %w", err)
+			}
+			logging.Configure(logConfig)
+			return nil
+		},
+	}
+	root.PersistentFlags().StringVar(&configPath, "config", "", "JSON file of flag defaults (default $WIKILLM_CONFIG, then wikillm/config.json in the user config directory)")
+	root.MarkPersistentFlagFilename("config", "json")
+	addGoFlags(root.PersistentFlags(), logFlags.Register)
+
+	root.AddCommand(
+		newRAGCommand(),
+		newNaiveLocalCommand(),
+		newTodoCommand(),
+		newAssistantCommand(&logFlags),
+	)
+	return root
+}
+
+// addGoFlags adds the flags register defines on a standard library FlagSet,
+// as each package's options do, to a command's flags
+func addGoFlags(flags *pflag.FlagSet, register func(*flag.FlagSet)) {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	register(fs)
+	flags.AddGoFlagSet(fs)
+}
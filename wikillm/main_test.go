@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kbutz/wikillm/multiagent/api"
+)
+
+// run executes the command line args and returns what it printed
+func run(t *testing.T, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	root := newRootCommand()
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetArgs(args)
+	if err := root.Execute(); err != nil {
+		t.Fatalf("wikillm %s: %v\n%s", strings.Join(args, " "), err, out.String())
+	}
+	return out.String()
+}
+
+// noConfig keeps the user's own config file from applying
+func noConfig(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(config, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("WIKILLM_CONFIG", config)
+}
+
+func TestTodo(t *testing.T) {
+	noConfig(t)
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	run(t, "todo", "add", "Call", "dentist", "priority:high", "--todo-file", todoFile)
+	run(t, "todo", "add", "Buy milk", "--todo-file", todoFile)
+	run(t, "todo", "complete", "2", "--todo-file", todoFile)
+
+	list := run(t, "todo", "list", "--todo-file", todoFile)
+	if !strings.Contains(list, "Call dentist") || strings.Contains(list, "Buy milk") {
+		t.Errorf("active tasks:\n%s", list)
+	}
+	if all := run(t, "todo", "list", "all", "--todo-file", todoFile); !strings.Contains(all, "Buy milk") {
+		t.Errorf("all tasks:\n%s", all)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TODO_DIR", dir)
+	config := filepath.Join(dir, "config.json")
+	err := os.WriteFile(config, []byte(`{"todo-file": "ignored.json", "log-level": "warn",
+		"todo": {"todo-file": "$TODO_DIR/todo.json", "add": {"unknown": 1}}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("WIKILLM_CONFIG", config)
+
+	// The todo section's file wins over the top-level one
+	run(t, "todo", "add", "Call dentist")
+	if _, err := os.Stat(filepath.Join(dir, "todo.json")); err != nil {
+		t.Errorf("task not added to the configured file: %v", err)
+	}
+
+	// Flags on the command line win over the config
+	other := filepath.Join(dir, "other.json")
+	run(t, "todo", "add", "Buy milk", "--todo-file", other)
+	if list := run(t, "todo", "list", "--todo-file", other); strings.Contains(list, "Call dentist") {
+		t.Errorf("--todo-file ignored:\n%s", list)
+	}
+
+	os.WriteFile(config, []byte(`{"todo": {"list": {"todo-file": 7, "log-level": "loud"}}}`), 0644)
+	root := newRootCommand()
+	root.SetArgs([]string{"todo", "list"})
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "log level") {
+		t.Errorf("invalid config log level = %v", err)
+	}
+
+	t.Setenv("WIKILLM_CONFIG", filepath.Join(dir, "missing.json"))
+	root = newRootCommand()
+	root.SetArgs([]string{"todo", "list"})
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	if err := root.Execute(); err == nil {
+		t.Error("a missing $WIKILLM_CONFIG was ignored")
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	noConfig(t)
+	out := run(t, "__complete", "todo", "list", "")
+	if !strings.Contains(out, "all") || !strings.Contains(out, "priority") {
+		t.Errorf("completions for todo list:\n%s", out)
+	}
+	if script := run(t, "completion", "bash"); !strings.Contains(script, "__start_wikillm") {
+		t.Error("no bash completion script")
+	}
+}
+
+func TestAssistantRecords(t *testing.T) {
+	noConfig(t)
+	dir := t.TempDir()
+
+	prompts := filepath.Join(dir, "prompts")
+	run(t, "assistant", "prompts", "--export", prompts)
+	if list := run(t, "assistant", "prompts", "--dir", prompts); !strings.Contains(list, "intent.classify") {
+		t.Errorf("prompts:\n%s", list)
+	}
+
+	db := filepath.Join(dir, "memory.db")
+	if out := run(t, "assistant", "migrate-memory", "--from", filepath.Join(dir, "memory"), "--to", db); !strings.Contains(out, "Migrated 0 entries") {
+		t.Errorf("migrate-memory:\n%s", out)
+	}
+	if out := run(t, "assistant", "audit", "--sqlite", db); !strings.HasPrefix(out, "TIME") {
+		t.Errorf("audit:\n%s", out)
+	}
+	if out := run(t, "assistant", "usage", "--sqlite", db, "--by", "model"); !strings.HasPrefix(out, "MODEL") {
+		t.Errorf("usage:\n%s", out)
+	}
+}
+
+func TestAsk(t *testing.T) {
+	noConfig(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request api.MessageRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		if request.Content == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(api.Error{Error: "model unavailable"})
+			return
+		}
+		json.NewEncoder(w).Encode(api.MessageResponse{ConversationID: "alice", Response: "You asked: " + request.Content})
+	}))
+	defer server.Close()
+
+	ask := func(stdin string, args ...string) (askResult, string, error) {
+		var out bytes.Buffer
+		root := newRootCommand()
+		root.SetIn(strings.NewReader(stdin))
+		root.SetOut(&out)
+		root.SetErr(&bytes.Buffer{})
+		root.SetArgs(append([]string{"assistant", "ask", "--server", server.URL, "--user", "alice"}, args...))
+		err := root.Execute()
+		var result askResult
+		json.Unmarshal(out.Bytes(), &result)
+		return result, out.String(), err
+	}
+
+	if result, _, err := ask("what is on my list?\n"); err != nil || result.Answer != "You asked: what is on my list?" || result.ConversationID != "alice" {
+		t.Errorf("from stdin: %+v, %v", result, err)
+	}
+	if result, _, err := ask("ignored", "--query", "from the flag"); err != nil || result.Query != "from the flag" {
+		t.Errorf("--query: %+v, %v", result, err)
+	}
+	if _, out, err := ask("", "from", "args", "--output", "text"); err != nil || out != "You asked: from args\n" {
+		t.Errorf("--output text = %q, %v", out, err)
+	}
+	if _, _, err := ask("  \n"); err == nil {
+		t.Error("empty stdin was accepted")
+	}
+	if result, _, err := ask("fail"); err == nil || result.Error != "500 Internal Server Error: model unavailable" {
+		t.Errorf("failure: %+v, %v", result, err)
+	}
+}
+
+func TestReadQuestions(t *testing.T) {
+	questions, err := readQuestions(strings.NewReader("# capitals\nWhat is the capital of France?\n\n  Who was Ada Lovelace?  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(questions) != 2 || questions[0] != "What is the capital of France?" || questions[1] != "Who was Ada Lovelace?" {
+		t.Errorf("questions = %q", questions)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+
+	"github.com/kbutz/wikillm/inmemory"
+	"github.com/spf13/cobra"
+)
+
+// newNaiveLocalCommand runs the inmemory module's full-text search and LLM
+func newNaiveLocalCommand() *cobra.Command {
+	var config inmemory.Config
+	cmd := &cobra.Command{
+		Use:   "naivelocal",
+		Short: "Answer questions from a local full-text index of Wikipedia",
+	}
+	addGoFlags(cmd.PersistentFlags(), config.Register)
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "index <wikipedia-dump.xml>",
+			Short: "Add the pages of a Wikipedia XML dump to the search index",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return inmemory.Index(config, args[0])
+			},
+		},
+		&cobra.Command{
+			Use:   "chat",
+			Short: "Ask questions interactively",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return inmemory.Chat(config)
+			},
+		},
+		newAskCommand("Answer a single question", func(ctx context.Context, question string) (askResult, error) {
+			answer, err := inmemory.Ask(ctx, config, question)
+			if err != nil {
+				return askResult{}, err
+			}
+			return askResult{Answer: answer.Answer, Sources: answer.Sources}, nil
+		}),
+	)
+	return cmd
+}
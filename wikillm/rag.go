@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strings"
+
+	"github.com/kbutz/wikillm/multiagent/search"
+	"github.com/kbutz/wikillm/rag"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// newRAGCommand runs the qdrant module's Retrieval-Augmented Generation
+func newRAGCommand() *cobra.Command {
+	var config rag.Config
+	cmd := &cobra.Command{
+		Use:   "rag",
+		Short: "Answer questions from Wikipedia embeddings in a Qdrant vector database",
+	}
+	addGoFlags(cmd.PersistentFlags(), config.Register)
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "index <wikipedia-dump.xml>",
+			Short: "Embed the pages of a Wikipedia XML dump into the Qdrant collection",
+			Long:  "Embed the pages of a Wikipedia XML dump into the Qdrant collection. Creating the embeddings is extremely compute intensive; see `rag load` for precomputed ones.",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return rag.Index(config, args[0])
+			},
+		},
+		&cobra.Command{
+			Use:   "load",
+			Short: "Load precomputed embeddings from wiki_minilm.ndjson.gz into Qdrant",
+			Args:  cobra.NoArgs,
+			Run: func(cmd *cobra.Command, args []string) {
+				rag.LoadEmbeddings()
+			},
+		},
+		&cobra.Command{
+			Use:   "chat",
+			Short: "Ask questions interactively",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return rag.Chat(config)
+			},
+		},
+		newAskCommand("Answer a single question", func(ctx context.Context, question string) (askResult, error) {
+			answer, err := rag.Ask(ctx, config, question)
+			if err != nil {
+				return askResult{}, err
+			}
+			return askResult{Answer: answer.Answer, Sources: answer.Sources}, nil
+		}),
+		newRAGBatchCommand(&config),
+	)
+	return cmd
+}
+
+// ragSearchFlags are the rag flags that pick the Qdrant collection and how
+// queries are embedded
+var ragSearchFlags = map[string]bool{
+	"qdrant-url":         true,
+	"qdrant-collection":  true,
+	"embedding-model":    true,
+	"embedding-provider": true,
+	"ollama-url":         true,
+	"openai-key":         true,
+}
+
+// addRAGSearchFlags adds just the flags searching the Wikipedia embeddings
+// needs, for commands that do not answer with the RAG's own model
+func addRAGSearchFlags(flags *pflag.FlagSet, config *rag.Config) {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	config.Register(fs)
+	fs.VisitAll(func(f *flag.Flag) {
+		if ragSearchFlags[f.Name] {
+			flags.AddGoFlag(f)
+		}
+	})
+}
+
+// maxSnippetLength bounds how much of a Wikipedia passage a search result
+// carries
+const maxSnippetLength = 500
+
+// ragSearcher searches the Wikipedia embeddings in Qdrant, so the
+// assistant's research can cite Wikipedia without going online
+type ragSearcher struct {
+	pipeline *rag.RAGPipeline
+}
+
+// Search returns the Wikipedia passages most similar to query
+func (s ragSearcher) Search(ctx context.Context, query string, limit int) ([]search.Result, error) {
+	docs, err := s.pipeline.Search(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]search.Result, 0, len(docs))
+	for _, doc := range docs {
+		title, _ := doc.Metadata["title"].(string)
+		snippet := strings.Join(strings.Fields(doc.PageContent), " ")
+		if runes := []rune(snippet); len(runes) > maxSnippetLength {
+			snippet = string(runes[:maxSnippetLength]) + "..."
+		}
+		results = append(results, search.Result{
+			Title:   title,
+			URL:     search.WikipediaURL(search.DefaultWikipediaURL, title),
+			Snippet: snippet,
+			Score:   float64(doc.Score),
+			Source:  search.SourceWikipedia,
+		})
+	}
+	return results, nil
+}
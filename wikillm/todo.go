@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kbutz/wikillm/agents"
+	"github.com/kbutz/wikillm/agents/tools"
+	"github.com/kbutz/wikillm/tool"
+	"github.com/spf13/cobra"
+)
+
+// newTodoCommand edits the to-do list directly, or through the agents
+// module's agent (chat) or the tool module's (serve)
+func newTodoCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "todo",
+		Short: "Manage a to-do list, directly or in natural language",
+	}
+	cmd.AddCommand(
+		newTodoListCommand("add <task> [priority:low|medium|high|critical] [time:30m|2h]", "Add a task", cobra.MinimumNArgs(1), nil),
+		newTodoListCommand("list [all|priority]", "Show active tasks, all tasks or tasks by priority", cobra.MaximumNArgs(1), []string{"all", "priority"}),
+		newTodoListCommand("complete <number>", "Mark a task as completed", cobra.ExactArgs(1), nil),
+		newTodoListCommand("remove <number>", "Remove a task", cobra.ExactArgs(1), nil),
+		newTodoListCommand("clear [completed]", "Clear all tasks, or only completed ones", cobra.MaximumNArgs(1), []string{"completed"}),
+		newTodoChatCommand(),
+		newTodoServeCommand(),
+	)
+	return cmd
+}
+
+// newTodoListCommand runs one of the to-do list tool's commands, named by
+// the first word of use, without a model
+func newTodoListCommand(use, short string, args cobra.PositionalArgs, validArgs []string) *cobra.Command {
+	var todoFile string
+	cmd := &cobra.Command{
+		Use:       use,
+		Short:     short,
+		Args:      args,
+		ValidArgs: validArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			command := strings.TrimSpace(cmd.Name() + " " + strings.Join(args, " "))
+			result, err := tools.NewTodoListTool(todoFile).Execute(cmd.Context(), command)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), result)
+			return nil
+		},
+	}
+	if validArgs == nil {
+		cmd.ValidArgsFunction = cobra.NoFileCompletions
+	}
+	cmd.Flags().StringVar(&todoFile, "todo-file", "todo.txt", "Path to the to-do list file")
+	cmd.MarkFlagFilename("todo-file")
+	return cmd
+}
+
+func newTodoChatCommand() *cobra.Command {
+	var config agents.Config
+	cmd := &cobra.Command{
+		Use:   "chat",
+		Short: "Manage the list in natural language with an agent that remembers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return agents.Chat(config)
+		},
+	}
+	addGoFlags(cmd.Flags(), config.Register)
+	return cmd
+}
+
+func newTodoServeCommand() *cobra.Command {
+	var config tool.Config
+	var port int
+	var interactive bool
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Answer to-do list requests POSTed to /query",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return tool.Serve(config, port, interactive)
+		},
+	}
+	addGoFlags(cmd.Flags(), config.Register)
+	cmd.Flags().IntVar(&port, "port", 8080, "HTTP server port")
+	cmd.Flags().BoolVar(&interactive, "interactive", false, "also answer requests typed in the terminal")
+	return cmd
+}